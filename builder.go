@@ -0,0 +1,237 @@
+package guardian
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Well-known middleware names used by the default ordering rules. A rule also applies
+// to names qualified with a dot, e.g. NameAuth matches "auth.oauth2".
+const (
+	NameRecovery    = "recovery"
+	NameTracing     = "tracing"
+	NameLogging     = "logging"
+	NameMetrics     = "metrics"
+	NameRateLimit   = "ratelimit"
+	NameAuth        = "auth"
+	NameAuthz       = "authz"
+	NameRequireRole = "require_role"
+	NameCache       = "cache"
+	NameTimeout     = "timeout"
+	NameRetry       = "retry"
+)
+
+// OrderingRule requires the First middleware to run before the Then middleware when
+// both are part of a chain
+type OrderingRule struct {
+	First  string
+	Then   string
+	Reason string
+}
+
+// DefaultOrderingRules are the ordering hazards checked by ChainBuilder.Build
+var DefaultOrderingRules = []OrderingRule{
+	{First: NameAuth, Then: NameAuthz, Reason: "authorization needs the authenticated identity"},
+	{First: NameAuth, Then: NameRequireRole, Reason: "role checks need the authenticated identity"},
+	{First: NameAuth, Then: NameCache, Reason: "cached responses would be served to unauthenticated callers"},
+	{First: NameAuthz, Then: NameCache, Reason: "cached responses would bypass authorization"},
+	{First: NameTracing, Then: NameLogging, Reason: "log entries would miss the trace and span IDs"},
+	{First: NameTimeout, Then: NameRetry, Reason: "retries would not share the overall deadline"},
+}
+
+// MiddlewareDescription describes one middleware of a chain
+type MiddlewareDescription struct {
+	Position int    `json:"position"`
+	Name     string `json:"name,omitempty"`
+	Priority int    `json:"priority"`
+	Unary    bool   `json:"unary"`
+	Stream   bool   `json:"stream"`
+	Methods  string `json:"methods,omitempty"`
+}
+
+// chainEntry records the identity of a middleware in a chain
+type chainEntry struct {
+	name     string
+	priority int
+	unary    bool
+	stream   bool
+	methods  string
+}
+
+// builderEntry is a registered middleware pair
+type builderEntry struct {
+	name     string
+	priority int
+	unary    Middleware
+	stream   StreamMiddleware
+}
+
+// ChainBuilder assembles a chain from named middleware. Middleware run in ascending
+// priority order (lower priorities run first, i.e. outermost); equal priorities keep
+// their registration order. Build validates the order against DefaultOrderingRules.
+type ChainBuilder struct {
+	entries  []builderEntry
+	rules    []OrderingRule
+	validate bool
+}
+
+// NewChainBuilder creates a builder with the default ordering rules
+func NewChainBuilder() *ChainBuilder {
+	return &ChainBuilder{
+		rules:    append([]OrderingRule(nil), DefaultOrderingRules...),
+		validate: true,
+	}
+}
+
+// Use registers a unary middleware
+func (b *ChainBuilder) Use(name string, priority int, middleware Middleware) *ChainBuilder {
+	return b.Add(name, priority, middleware, nil)
+}
+
+// UseStream registers a stream middleware
+func (b *ChainBuilder) UseStream(name string, priority int, middleware StreamMiddleware) *ChainBuilder {
+	return b.Add(name, priority, nil, middleware)
+}
+
+// Add registers a unary and stream middleware pair under one name. Either may be nil
+// when a component has no unary or stream variant.
+func (b *ChainBuilder) Add(name string, priority int, unary Middleware, stream StreamMiddleware) *ChainBuilder {
+	b.entries = append(b.entries, builderEntry{name: name, priority: priority, unary: unary, stream: stream})
+	return b
+}
+
+// Rule adds an ordering rule: first must run before then
+func (b *ChainBuilder) Rule(first, then, reason string) *ChainBuilder {
+	b.rules = append(b.rules, OrderingRule{First: first, Then: then, Reason: reason})
+	return b
+}
+
+// SkipValidation disables the ordering rules, e.g. for a deliberate cache in front of auth
+func (b *ChainBuilder) SkipValidation() *ChainBuilder {
+	b.validate = false
+	return b
+}
+
+// Build orders the middleware and returns the chain. It fails if a name is empty or
+// registered twice, or if the order violates an ordering rule.
+//
+// Example usage:
+//
+//	chain, err := guardian.NewChainBuilder().
+//	    Use(guardian.NameRecovery, 0, middleware.Recovery()).
+//	    Use(guardian.NameTracing, 10, middleware.Tracing()).
+//	    Use(guardian.NameLogging, 20, middleware.Logging()).
+//	    Use(guardian.NameAuth, 30, middleware.Auth(validator)).
+//	    Use(guardian.NameCache, 40, middleware.Cache()).
+//	    Build()
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+func (b *ChainBuilder) Build() (*Chain, error) {
+	ordered := make([]builderEntry, len(b.entries))
+	copy(ordered, b.entries)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return ordered[i].priority < ordered[j].priority
+	})
+
+	seen := make(map[string]bool, len(ordered))
+	for _, entry := range ordered {
+		if entry.name == "" {
+			return nil, fmt.Errorf("guardian: middleware name must not be empty")
+		}
+		if seen[entry.name] {
+			return nil, fmt.Errorf("guardian: middleware %q registered twice", entry.name)
+		}
+		seen[entry.name] = true
+	}
+
+	if b.validate {
+		if err := checkOrdering(ordered, b.rules); err != nil {
+			return nil, err
+		}
+	}
+
+	chain := &Chain{}
+	for _, entry := range ordered {
+		if entry.unary != nil {
+			chain.middlewares = append(chain.middlewares, entry.unary)
+		}
+		if entry.stream != nil {
+			chain.streamMiddlewares = append(chain.streamMiddlewares, entry.stream)
+		}
+		chain.entries = append(chain.entries, chainEntry{
+			name:     entry.name,
+			priority: entry.priority,
+			unary:    entry.unary != nil,
+			stream:   entry.stream != nil,
+		})
+	}
+	return chain, nil
+}
+
+// checkOrdering returns every rule violated by the ordered entries. A rule is violated
+// when a "then" middleware runs before the first "first" middleware.
+func checkOrdering(ordered []builderEntry, rules []OrderingRule) error {
+	var errs []error
+
+	for _, rule := range rules {
+		first, then := indexOfName(ordered, rule.First), indexOfName(ordered, rule.Then)
+		if first < 0 || then < 0 || first < then {
+			continue
+		}
+		errs = append(errs, fmt.Errorf("guardian: %q runs before %q: %s\nHint: give %q a lower priority than %q",
+			ordered[then].name, ordered[first].name, rule.Reason, ordered[first].name, ordered[then].name))
+	}
+
+	return errors.Join(errs...)
+}
+
+// indexOfName returns the position of the first entry matching a rule name or -1
+func indexOfName(entries []builderEntry, ruleName string) int {
+	for i, entry := range entries {
+		if matchesName(entry.name, ruleName) {
+			return i
+		}
+	}
+	return -1
+}
+
+// matchesName reports whether a middleware name is the rule name or qualified by it
+func matchesName(name, ruleName string) bool {
+	return name == ruleName || strings.HasPrefix(name, ruleName+".")
+}
+
+// Describe returns the middleware of the chain in execution order. Middleware added
+// with NewChain, Append or Prepend have no name or priority.
+func (c *Chain) Describe() []MiddlewareDescription {
+	descriptions := make([]MiddlewareDescription, len(c.entries))
+	for i, entry := range c.entries {
+		descriptions[i] = MiddlewareDescription{
+			Position: i,
+			Name:     entry.name,
+			Priority: entry.priority,
+			Unary:    entry.unary,
+			Stream:   entry.stream,
+			Methods:  entry.methods,
+		}
+	}
+	return descriptions
+}
+
+// DescribeHandler returns an HTTP handler that serves Describe as JSON
+func (c *Chain) DescribeHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, err := json.MarshalIndent(c.Describe(), "", "  ")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(data)
+	})
+}
@@ -0,0 +1,102 @@
+package guardian_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	guardian "github.com/grpc-guardian/grpc-guardian"
+	"github.com/grpc-guardian/grpc-guardian/middleware"
+	"google.golang.org/grpc"
+)
+
+// recordingMiddleware appends its name to calls when it runs
+func recordingMiddleware(name string, calls *[]string) guardian.Middleware {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		*calls = append(*calls, name)
+		return handler(ctx, req)
+	}
+}
+
+func TestChainBuilder_Order(t *testing.T) {
+	var calls []string
+	chain, err := guardian.NewChainBuilder().
+		Use(guardian.NameCache, 40, recordingMiddleware("cache", &calls)).
+		Use("auth.jwt", 30, recordingMiddleware("auth", &calls)).
+		Use(guardian.NameRecovery, 0, recordingMiddleware("recovery", &calls)).
+		Add(guardian.NameLogging, 20, recordingMiddleware("logging", &calls), middleware.StreamLogging()).
+		Use(guardian.NameTracing, 20, recordingMiddleware("tracing", &calls)).
+		SkipValidation().
+		Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	_, err = chain.UnaryInterceptor()(context.Background(), "req", &grpc.UnaryServerInfo{FullMethod: "/svc/M"},
+		func(ctx context.Context, req interface{}) (interface{}, error) { return "ok", nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"recovery", "logging", "tracing", "auth", "cache"}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("execution order = %v, want %v", calls, want)
+	}
+
+	desc := chain.Describe()
+	if len(desc) != 5 || desc[1].Name != guardian.NameLogging || !desc[1].Stream || desc[3].Name != "auth.jwt" || desc[3].Priority != 30 {
+		t.Errorf("Unexpected description: %+v", desc)
+	}
+
+	rec := httptest.NewRecorder()
+	chain.DescribeHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/guardian/chain", nil))
+	var served []guardian.MiddlewareDescription
+	if err := json.Unmarshal(rec.Body.Bytes(), &served); err != nil || !reflect.DeepEqual(served, desc) {
+		t.Errorf("DescribeHandler served %s (err %v)", rec.Body.String(), err)
+	}
+}
+
+func TestChainBuilder_Validation(t *testing.T) {
+	noop := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		return handler(ctx, req)
+	}
+
+	// Cache and role check before auth
+	_, err := guardian.NewChainBuilder().
+		Use(guardian.NameCache, 10, noop).
+		Use(guardian.NameRequireRole, 20, noop).
+		Use("auth.oauth2", 30, noop).
+		Build()
+	if err == nil {
+		t.Fatal("Expected ordering errors")
+	}
+	for _, want := range []string{`"cache" runs before "auth.oauth2"`, `"require_role" runs before "auth.oauth2"`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not contain %q", err, want)
+		}
+	}
+
+	// Custom rules
+	_, err = guardian.NewChainBuilder().
+		Use("validate", 10, noop).
+		Use("decompress", 20, noop).
+		Rule("decompress", "validate", "validation needs the decoded payload").
+		Build()
+	if err == nil || !strings.Contains(err.Error(), "decoded payload") {
+		t.Errorf("Expected custom rule violation, got %v", err)
+	}
+
+	// Duplicate names
+	if _, err := guardian.NewChainBuilder().Use("auth", 0, noop).Use("auth", 1, noop).Build(); err == nil {
+		t.Error("Expected duplicate name error")
+	}
+
+	// Plain chains are described without names
+	chain := guardian.NewChain(noop).Append(noop)
+	if desc := chain.Describe(); len(desc) != 2 || desc[1].Position != 1 || desc[1].Name != "" {
+		t.Errorf("Unexpected description: %+v", desc)
+	}
+}
@@ -0,0 +1,52 @@
+package guardian_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	guardian "github.com/grpc-guardian/grpc-guardian"
+	"github.com/grpc-guardian/grpc-guardian/pkg/classify"
+	"google.golang.org/grpc"
+)
+
+func TestChain_Routing(t *testing.T) {
+	var calls []string
+	chain := guardian.NewChain(recordingMiddleware("logging", &calls)).
+		ExceptService("grpc.health.v1.Health", recordingMiddleware("auth", &calls)).
+		When(classify.New().IsRead, recordingMiddleware("cache", &calls)).
+		ForMethods("/shop.Orders/Create", recordingMiddleware("idempotency", &calls)).
+		ForService("shop.Admin", recordingMiddleware("audit", &calls)).
+		Prepend(recordingMiddleware("recovery", &calls))
+
+	interceptor := chain.UnaryInterceptor()
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) { return "ok", nil }
+
+	tests := []struct {
+		method string
+		want   []string
+	}{
+		{"/grpc.health.v1.Health/Check", []string{"recovery", "logging", "cache"}},
+		{"/shop.Orders/GetOrder", []string{"recovery", "logging", "auth", "cache"}},
+		{"/shop.Orders/Create", []string{"recovery", "logging", "auth", "idempotency"}},
+		{"/shop.Admin/Reindex", []string{"recovery", "logging", "auth", "audit"}},
+	}
+
+	for _, tt := range tests {
+		// Twice to exercise the per-method cache
+		for i := 0; i < 2; i++ {
+			calls = nil
+			if _, err := interceptor(context.Background(), "req", &grpc.UnaryServerInfo{FullMethod: tt.method}, handler); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(calls, tt.want) {
+				t.Errorf("%s: ran %v, want %v", tt.method, calls, tt.want)
+			}
+		}
+	}
+
+	desc := chain.Describe()
+	if len(desc) != 6 || desc[0].Methods != "" || desc[2].Methods != "!/grpc.health.v1.Health/*" || desc[5].Methods != "/shop.Admin/*" {
+		t.Errorf("Unexpected description: %+v", desc)
+	}
+}
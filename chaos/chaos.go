@@ -3,9 +3,13 @@ package chaos
 
 import (
 	"context"
-	"math/rand"
+	"strings"
 	"time"
 
+	"github.com/grpc-guardian/grpc-guardian/pkg/classify"
+	"github.com/grpc-guardian/grpc-guardian/pkg/condition"
+	"github.com/grpc-guardian/grpc-guardian/pkg/metrics"
+	"github.com/grpc-guardian/grpc-guardian/pkg/randutil"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -33,13 +37,54 @@ type ChaosConfig struct {
 	CircuitBreakerEnabled bool
 	CircuitBreakerWindow  time.Duration
 
+	// Bandwidth throttling of stream messages
+	ThrottleEnabled        bool
+	ThrottleBytesPerSecond int
+	ThrottleProbability    float64
+
+	// Response corruption
+	CorruptionEnabled     bool
+	Corrupter             Corrupter
+	CorruptionProbability float64
+
+	// Response metadata tampering (nil MetadataMangler drops the keys)
+	MetadataEnabled     bool
+	MetadataKeys        []string
+	MetadataMangler     func(key, value string) string
+	MetadataProbability float64
+
 	// Conditional enabling
 	EnableCondition func() bool
+
+	// Request targeting (nil targets every request) and the evaluator filling its principal
+	// and geo variables (nil leaves them empty)
+	TargetIf        condition.Condition
+	TargetEvaluator *condition.Evaluator
+
+	// Method classification; when set, writes are excluded unless IncludeWrites is set
+	Classifier    *classify.Classifier
+	IncludeWrites bool
+
+	// Random seed for reproducible experiments (Seeded is false: seeded from the clock)
+	Seed   int64
+	Seeded bool
+
+	// Injected fault metrics (nil disables)
+	Collector *metrics.ChaosCollector
+
+	// Fraction of unique clients affected (0 affects all) and how clients are identified
+	BlastRadius     float64
+	ClientExtractor func(ctx context.Context) string
 }
 
 // ChaosOption is a functional option for chaos configuration
 type ChaosOption func(*ChaosConfig)
 
+// Corrupter turns a response into a corrupted one. It should return a response of the same
+// type that still serializes, e.g. with a field zeroed or out of range, so clients see valid
+// but wrong data rather than a decoding error.
+type Corrupter func(resp interface{}) interface{}
+
 // WithLatency enables latency injection
 func WithLatency(min, max time.Duration, probability float64) ChaosOption {
 	return func(c *ChaosConfig) {
@@ -68,6 +113,49 @@ func WithTimeout(duration time.Duration, probability float64) ChaosOption {
 	}
 }
 
+// WithBandwidthThrottle throttles the messages of a fraction of streams to bytesPerSecond,
+// in both directions. Message sizes are taken from their protobuf encoding.
+func WithBandwidthThrottle(bytesPerSecond int, probability float64) ChaosOption {
+	return func(c *ChaosConfig) {
+		c.ThrottleEnabled = true
+		c.ThrottleBytesPerSecond = bytesPerSecond
+		c.ThrottleProbability = probability
+	}
+}
+
+// WithCorruption replaces responses with the corrupter's result: unary responses and
+// stream messages sent by the server are each corrupted with the given probability
+//
+// Example usage:
+//
+//	chaos.WithCorruption(func(resp interface{}) interface{} {
+//	    if order, ok := resp.(*pb.Order); ok {
+//	        order.Total = -order.Total
+//	    }
+//	    return resp
+//	}, 0.01)
+func WithCorruption(corrupter Corrupter, probability float64) ChaosOption {
+	return func(c *ChaosConfig) {
+		c.CorruptionEnabled = true
+		c.Corrupter = corrupter
+		c.CorruptionProbability = probability
+	}
+}
+
+// WithMetadataTampering tampers with the given keys of the response headers and trailers:
+// each value is replaced with the mangler's result, or the key is dropped when mangler is nil
+func WithMetadataTampering(keys []string, mangler func(key, value string) string, probability float64) ChaosOption {
+	return func(c *ChaosConfig) {
+		c.MetadataEnabled = true
+		c.MetadataKeys = make([]string, len(keys))
+		for i, key := range keys {
+			c.MetadataKeys[i] = strings.ToLower(key)
+		}
+		c.MetadataMangler = mangler
+		c.MetadataProbability = probability
+	}
+}
+
 // WithCondition sets a condition for enabling chaos
 func WithCondition(condition func() bool) ChaosOption {
 	return func(c *ChaosConfig) {
@@ -75,56 +163,85 @@ func WithCondition(condition func() bool) ChaosOption {
 	}
 }
 
-// New creates a new chaos engineering middleware
-func New(opts ...ChaosOption) func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
-	config := &ChaosConfig{
-		EnableCondition: func() bool { return true },
+// WithTargetIf limits chaos to requests matching a condition expression (see pkg/condition),
+// e.g. `metadata["x-chaos"] == "on" && service == "shop.Orders"`.
+// Requests whose condition fails to evaluate are left alone.
+func WithTargetIf(cond condition.Condition) ChaosOption {
+	return func(c *ChaosConfig) {
+		c.TargetIf = cond
 	}
+}
 
-	for _, opt := range opts {
-		opt(config)
+// WithTargetEvaluator sets the evaluator of WithTargetIf conditions, e.g.
+// middleware.ConditionEvaluator() to target by principal or geo
+// Default: principal and geo are empty
+func WithTargetEvaluator(evaluator *condition.Evaluator) ChaosOption {
+	return func(c *ChaosConfig) {
+		c.TargetEvaluator = evaluator
 	}
+}
 
-	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
-		// Check if chaos is enabled
-		if !config.EnableCondition() {
-			return handler(ctx, req)
-		}
+// WithClassifier excludes methods the classifier does not mark as reads, so injected
+// failures never leave a mutation half-applied
+func WithClassifier(classifier *classify.Classifier) ChaosOption {
+	return func(c *ChaosConfig) {
+		c.Classifier = classifier
+	}
+}
 
-		// Latency injection
-		if config.LatencyEnabled && shouldInject(config.LatencyProbability) {
-			delay := randomDuration(config.LatencyMin, config.LatencyMax)
+// WithWritesIncluded also injects chaos into writes when a classifier is set
+func WithWritesIncluded() ChaosOption {
+	return func(c *ChaosConfig) {
+		c.IncludeWrites = true
+	}
+}
 
-			select {
-			case <-time.After(delay):
-				// Continue after delay
-			case <-ctx.Done():
-				return nil, status.Errorf(codes.Canceled, "request canceled during chaos latency injection")
-			}
-		}
+// WithSeed makes fault injection deterministic: experiments with the same seed and the
+// same sequence of requests inject the same faults, which keeps chaos tests reproducible
+func WithSeed(seed int64) ChaosOption {
+	return func(c *ChaosConfig) {
+		c.Seed = seed
+		c.Seeded = true
+	}
+}
 
-		// Error injection
-		if config.ErrorEnabled && shouldInject(config.ErrorProbability) {
-			code := config.ErrorCodes[rand.Intn(len(config.ErrorCodes))]
-			return nil, status.Errorf(code, "chaos engineering: injected error")
-		}
+// WithMetrics exports injected faults through the collector
+func WithMetrics(collector *metrics.ChaosCollector) ChaosOption {
+	return func(c *ChaosConfig) {
+		c.Collector = collector
+	}
+}
 
-		// Timeout simulation
-		if config.TimeoutEnabled && shouldInject(config.TimeoutProbability) {
-			newCtx, cancel := context.WithTimeout(ctx, config.TimeoutDuration)
-			defer cancel()
-			return handler(newCtx, req)
-		}
+// WithBlastRadius limits chaos to a fraction of unique clients, so most users never see
+// an experiment. Clients are identified by the client extractor.
+func WithBlastRadius(fraction float64) ChaosOption {
+	return func(c *ChaosConfig) {
+		c.BlastRadius = fraction
+	}
+}
 
-		return handler(ctx, req)
+// WithClientExtractor sets how clients are identified for the blast radius
+// Default: PeerClient (the peer IP address)
+func WithClientExtractor(extractor func(ctx context.Context) string) ChaosOption {
+	return func(c *ChaosConfig) {
+		if extractor != nil {
+			c.ClientExtractor = extractor
+		}
 	}
 }
 
+// New creates a new chaos engineering middleware. Use NewChaos to toggle the experiment
+// at runtime or read its stats.
+func New(opts ...ChaosOption) func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	return NewChaos(opts...).UnaryServerInterceptor()
+}
+
 // LatencyInjector creates latency injection middleware
 func LatencyInjector(min, max time.Duration, probability float64) func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	rng := randutil.NewTimeSeeded()
 	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
-		if shouldInject(probability) {
-			delay := randomDuration(min, max)
+		if shouldInject(rng, probability) {
+			delay := randomDuration(rng, min, max)
 
 			select {
 			case <-time.After(delay):
@@ -139,9 +256,10 @@ func LatencyInjector(min, max time.Duration, probability float64) func(ctx conte
 
 // ErrorInjector creates error injection middleware
 func ErrorInjector(errorCodes []codes.Code, probability float64) func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	rng := randutil.NewTimeSeeded()
 	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
-		if shouldInject(probability) {
-			code := errorCodes[rand.Intn(len(errorCodes))]
+		if shouldInject(rng, probability) {
+			code := errorCodes[rng.Intn(len(errorCodes))]
 			return nil, status.Errorf(code, "chaos: injected error with code %s", code.String())
 		}
 
@@ -175,8 +293,9 @@ func RandomErrorInjector(probability float64) func(ctx context.Context, req inte
 
 // TimeoutInjector creates timeout injection middleware
 func TimeoutInjector(timeout time.Duration, probability float64) func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	rng := randutil.NewTimeSeeded()
 	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
-		if shouldInject(probability) {
+		if shouldInject(rng, probability) {
 			newCtx, cancel := context.WithTimeout(ctx, timeout)
 			defer cancel()
 			return handler(newCtx, req)
@@ -215,8 +334,9 @@ type PercentageBasedChaos struct {
 
 // NewPercentageBasedChaos creates chaos that affects a percentage of requests
 func NewPercentageBasedChaos(percentage float64, chaosFunc func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error)) func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	rng := randutil.NewTimeSeeded()
 	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
-		if rand.Float64() < percentage {
+		if rng.Float64() < percentage {
 			return chaosFunc(ctx, req, info, handler)
 		}
 		return handler(ctx, req)
@@ -224,16 +344,16 @@ func NewPercentageBasedChaos(percentage float64, chaosFunc func(ctx context.Cont
 }
 
 // shouldInject determines if chaos should be injected based on probability
-func shouldInject(probability float64) bool {
-	return rand.Float64() < probability
+func shouldInject(rng *randutil.Rand, probability float64) bool {
+	return rng.Float64() < probability
 }
 
 // randomDuration returns a random duration between min and max
-func randomDuration(min, max time.Duration) time.Duration {
+func randomDuration(rng *randutil.Rand, min, max time.Duration) time.Duration {
 	if min >= max {
 		return min
 	}
-	return min + time.Duration(rand.Int63n(int64(max-min)))
+	return min + time.Duration(rng.Int63n(int64(max-min)))
 }
 
 // Presets for common chaos scenarios
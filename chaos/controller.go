@@ -0,0 +1,392 @@
+package chaos
+
+import (
+	"context"
+	"hash/fnv"
+	"math"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/grpc-guardian/grpc-guardian/pkg/condition"
+	"github.com/grpc-guardian/grpc-guardian/pkg/metrics"
+	"github.com/grpc-guardian/grpc-guardian/pkg/randutil"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// Fault is a kind of injected failure
+type Fault int
+
+const (
+	// FaultLatency delays requests
+	FaultLatency Fault = iota
+	// FaultError fails requests with an error code
+	FaultError
+	// FaultTimeout shortens the deadline of requests
+	FaultTimeout
+	// FaultThrottle limits the bandwidth of streams
+	FaultThrottle
+	// FaultCorruption replaces responses with corrupted ones
+	FaultCorruption
+	// FaultMetadata drops or mangles response metadata
+	FaultMetadata
+
+	numFaults
+)
+
+// String returns the fault name
+func (f Fault) String() string {
+	switch f {
+	case FaultLatency:
+		return metrics.ChaosFaultLatency
+	case FaultError:
+		return metrics.ChaosFaultError
+	case FaultTimeout:
+		return metrics.ChaosFaultTimeout
+	case FaultThrottle:
+		return metrics.ChaosFaultThrottle
+	case FaultCorruption:
+		return metrics.ChaosFaultCorruption
+	case FaultMetadata:
+		return metrics.ChaosFaultMetadata
+	}
+	return "unknown"
+}
+
+// Stats counts what an experiment injected
+type Stats struct {
+	Requests        uint64        // Requests the experiment applied to
+	Latencies       uint64        // Requests delayed
+	Errors          uint64        // Requests failed with an injected error
+	Timeouts        uint64        // Requests run with a shortened deadline
+	Throttled       uint64        // Streams throttled
+	Corrupted       uint64        // Responses and stream messages corrupted
+	Tampered        uint64        // Requests whose response metadata was tampered with
+	InjectedLatency time.Duration // Total delay added, including throttling
+}
+
+// Chaos is a chaos experiment that can be turned on and off at runtime, fault by fault,
+// and reports what it injected
+type Chaos struct {
+	config *ChaosConfig
+
+	enabled     atomic.Bool
+	faults      [numFaults]atomic.Bool
+	intensity   atomic.Uint64 // float64 bits
+	blastRadius atomic.Uint64 // float64 bits
+
+	rng *randutil.Rand
+
+	requests        atomic.Uint64
+	latencies       atomic.Uint64
+	errors          atomic.Uint64
+	timeouts        atomic.Uint64
+	throttled       atomic.Uint64
+	corrupted       atomic.Uint64
+	tampered        atomic.Uint64
+	injectedLatency atomic.Int64
+}
+
+// NewChaos creates a chaos experiment. It starts enabled, with every configured fault on.
+//
+// Example usage:
+//
+//	collector, _ := metrics.NewChaosCollector(nil)
+//	experiment := chaos.NewChaos(
+//	    chaos.WithLatency(100*time.Millisecond, 500*time.Millisecond, 0.2),
+//	    chaos.WithErrors([]codes.Code{codes.Unavailable}, 0.05),
+//	    chaos.WithMetrics(collector),
+//	)
+//	chain := guardian.NewChain(experiment.UnaryServerInterceptor())
+//
+//	// Later, e.g. when the error budget burns too fast
+//	experiment.SetFaultEnabled(chaos.FaultError, false)
+func NewChaos(opts ...ChaosOption) *Chaos {
+	config := &ChaosConfig{
+		EnableCondition: func() bool { return true },
+		ClientExtractor: PeerClient,
+	}
+
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	rng := randutil.NewTimeSeeded()
+	if config.Seeded {
+		rng = randutil.New(config.Seed)
+	}
+
+	c := &Chaos{
+		config: config,
+		rng:    rng,
+	}
+	c.enabled.Store(true)
+	for fault := range c.faults {
+		c.faults[fault].Store(true)
+	}
+	c.SetIntensity(1)
+	c.SetBlastRadius(config.BlastRadius)
+	return c
+}
+
+// SetEnabled turns the whole experiment on or off
+func (c *Chaos) SetEnabled(enabled bool) {
+	c.enabled.Store(enabled)
+}
+
+// Enabled reports whether the experiment is on
+func (c *Chaos) Enabled() bool {
+	return c.enabled.Load()
+}
+
+// SetFaultEnabled turns a single fault on or off; a fault that was not configured stays off
+func (c *Chaos) SetFaultEnabled(fault Fault, enabled bool) {
+	if fault >= 0 && fault < numFaults {
+		c.faults[fault].Store(enabled)
+	}
+}
+
+// FaultEnabled reports whether a fault is configured and turned on
+func (c *Chaos) FaultEnabled(fault Fault) bool {
+	if fault < 0 || fault >= numFaults || !c.faults[fault].Load() {
+		return false
+	}
+
+	switch fault {
+	case FaultLatency:
+		return c.config.LatencyEnabled
+	case FaultError:
+		return c.config.ErrorEnabled && len(c.config.ErrorCodes) > 0
+	case FaultTimeout:
+		return c.config.TimeoutEnabled
+	case FaultThrottle:
+		return c.config.ThrottleEnabled && c.config.ThrottleBytesPerSecond > 0
+	case FaultCorruption:
+		return c.config.CorruptionEnabled && c.config.Corrupter != nil
+	case FaultMetadata:
+		return c.config.MetadataEnabled && len(c.config.MetadataKeys) > 0
+	}
+	return false
+}
+
+// SetIntensity scales the probability of every fault by a factor in [0, 1], e.g. to ramp
+// an experiment up gradually
+func (c *Chaos) SetIntensity(intensity float64) {
+	c.intensity.Store(math.Float64bits(math.Max(0, math.Min(1, intensity))))
+}
+
+// Intensity returns the factor fault probabilities are scaled by
+func (c *Chaos) Intensity() float64 {
+	return math.Float64frombits(c.intensity.Load())
+}
+
+// SetBlastRadius limits the experiment to a fraction of unique clients in (0, 1];
+// values outside the range affect every client
+func (c *Chaos) SetBlastRadius(fraction float64) {
+	if fraction <= 0 || fraction > 1 {
+		fraction = 1
+	}
+	c.blastRadius.Store(math.Float64bits(fraction))
+}
+
+// BlastRadius returns the fraction of unique clients the experiment affects
+func (c *Chaos) BlastRadius() float64 {
+	return math.Float64frombits(c.blastRadius.Load())
+}
+
+// Stats returns what the experiment injected so far
+func (c *Chaos) Stats() Stats {
+	return Stats{
+		Requests:        c.requests.Load(),
+		Latencies:       c.latencies.Load(),
+		Errors:          c.errors.Load(),
+		Timeouts:        c.timeouts.Load(),
+		Throttled:       c.throttled.Load(),
+		Corrupted:       c.corrupted.Load(),
+		Tampered:        c.tampered.Load(),
+		InjectedLatency: time.Duration(c.injectedLatency.Load()),
+	}
+}
+
+// UnaryServerInterceptor returns the chaos middleware
+func (c *Chaos) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	config := c.config
+
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if !c.applies(ctx, info.FullMethod, req) {
+			return handler(ctx, req)
+		}
+
+		c.requests.Add(1)
+
+		// Latency injection
+		if err := c.injectLatency(ctx); err != nil {
+			return nil, err
+		}
+
+		// Error injection
+		if err := c.injectError(); err != nil {
+			return nil, err
+		}
+
+		// Metadata tampering
+		if c.FaultEnabled(FaultMetadata) && c.shouldInject(config.MetadataProbability) {
+			if stream := grpc.ServerTransportStreamFromContext(ctx); stream != nil {
+				c.record(FaultMetadata)
+				ctx = grpc.NewContextWithServerTransportStream(ctx, &tamperingTransportStream{
+					ServerTransportStream: stream,
+					chaos:                 c,
+				})
+			}
+		}
+
+		// Timeout simulation
+		if c.FaultEnabled(FaultTimeout) && c.shouldInject(config.TimeoutProbability) {
+			c.record(FaultTimeout)
+			newCtx, cancel := context.WithTimeout(ctx, config.TimeoutDuration)
+			defer cancel()
+			ctx = newCtx
+		}
+
+		resp, err := handler(ctx, req)
+
+		// Response corruption
+		if err == nil && c.FaultEnabled(FaultCorruption) && c.shouldInject(config.CorruptionProbability) {
+			c.record(FaultCorruption)
+			resp = config.Corrupter(resp)
+		}
+
+		return resp, err
+	}
+}
+
+// applies reports whether the experiment applies to a request
+func (c *Chaos) applies(ctx context.Context, method string, req interface{}) bool {
+	config := c.config
+
+	// Check if chaos is enabled
+	if !c.enabled.Load() || !config.EnableCondition() {
+		return false
+	}
+
+	if config.Classifier != nil && !config.IncludeWrites && config.Classifier.IsWrite(method) {
+		return false
+	}
+
+	if config.TargetIf != nil {
+		evaluator := config.TargetEvaluator
+		if evaluator == nil {
+			evaluator = condition.NewEvaluator()
+		}
+		if targeted, err := evaluator.EvalRequest(ctx, config.TargetIf, method, req); err != nil || !targeted {
+			return false
+		}
+	}
+
+	if radius := c.BlastRadius(); radius < 1 && clientBucket(config.ClientExtractor(ctx)) >= radius {
+		return false
+	}
+	return true
+}
+
+// injectLatency delays a request, failing with Canceled when the request is canceled first
+func (c *Chaos) injectLatency(ctx context.Context) error {
+	if !c.FaultEnabled(FaultLatency) || !c.shouldInject(c.config.LatencyProbability) {
+		return nil
+	}
+
+	delay := c.randomDuration(c.config.LatencyMin, c.config.LatencyMax)
+	c.record(FaultLatency)
+	c.addLatency(delay)
+
+	select {
+	case <-time.After(delay):
+		// Continue after delay
+		return nil
+	case <-ctx.Done():
+		return status.Errorf(codes.Canceled, "request canceled during chaos latency injection")
+	}
+}
+
+// injectError returns an injected error, or nil when no error is injected
+func (c *Chaos) injectError() error {
+	if !c.FaultEnabled(FaultError) || !c.shouldInject(c.config.ErrorProbability) {
+		return nil
+	}
+
+	code := c.config.ErrorCodes[c.intn(len(c.config.ErrorCodes))]
+	c.record(FaultError)
+	return status.Errorf(code, "chaos engineering: injected error")
+}
+
+// addLatency counts injected delay
+func (c *Chaos) addLatency(delay time.Duration) {
+	c.injectedLatency.Add(int64(delay))
+	if c.config.Collector != nil {
+		c.config.Collector.RecordLatency(delay)
+	}
+}
+
+// record counts an injected fault
+func (c *Chaos) record(fault Fault) {
+	switch fault {
+	case FaultLatency:
+		c.latencies.Add(1)
+	case FaultError:
+		c.errors.Add(1)
+	case FaultTimeout:
+		c.timeouts.Add(1)
+	case FaultThrottle:
+		c.throttled.Add(1)
+	case FaultCorruption:
+		c.corrupted.Add(1)
+	case FaultMetadata:
+		c.tampered.Add(1)
+	}
+	if c.config.Collector != nil {
+		c.config.Collector.RecordInjected(fault.String())
+	}
+}
+
+// shouldInject draws whether a fault with the given probability is injected
+func (c *Chaos) shouldInject(probability float64) bool {
+	probability *= c.Intensity()
+	return c.rng.Float64() < probability
+}
+
+// randomDuration draws a duration between min and max
+func (c *Chaos) randomDuration(min, max time.Duration) time.Duration {
+	if min >= max {
+		return min
+	}
+	return min + time.Duration(c.rng.Int63n(int64(max-min)))
+}
+
+// intn draws an integer in [0, n)
+func (c *Chaos) intn(n int) int {
+	return c.rng.Intn(n)
+}
+
+// clientBucket maps a client to a stable position in [0, 1), so the same clients stay
+// inside the blast radius for the whole experiment
+func clientBucket(client string) float64 {
+	h := fnv.New64a()
+	h.Write([]byte(client))
+	return float64(h.Sum64()>>11) / (1 << 53)
+}
+
+// PeerClient identifies the client of a request by its peer IP address
+func PeerClient(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
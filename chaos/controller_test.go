@@ -0,0 +1,113 @@
+package chaos
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grpc-guardian/grpc-guardian/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// chaosOutcomes runs n requests through an experiment and returns the resulting codes
+func chaosOutcomes(experiment *Chaos, n int) []codes.Code {
+	interceptor := experiment.UnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/svc/M"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) { return "ok", nil }
+
+	outcomes := make([]codes.Code, n)
+	for i := range outcomes {
+		_, err := interceptor(context.Background(), "req", info, handler)
+		outcomes[i] = status.Code(err)
+	}
+	return outcomes
+}
+
+func TestChaos_Seed(t *testing.T) {
+	opts := []ChaosOption{
+		WithErrors([]codes.Code{codes.Unavailable, codes.Internal, codes.Aborted}, 0.5),
+		WithSeed(42),
+	}
+
+	first := chaosOutcomes(NewChaos(opts...), 50)
+	second := chaosOutcomes(NewChaos(opts...), 50)
+	for i := range first {
+		if first[i] != second[i] {
+			t.Fatalf("request %d: seeded experiments diverged (%v vs %v)", i, first[i], second[i])
+		}
+	}
+
+	failed := 0
+	for _, code := range first {
+		if code != codes.OK {
+			failed++
+		}
+	}
+	if failed == 0 || failed == len(first) {
+		t.Errorf("Expected roughly half of the requests to fail, got %d of %d", failed, len(first))
+	}
+}
+
+func TestChaos_Toggles(t *testing.T) {
+	experiment := NewChaos(
+		WithErrors([]codes.Code{codes.Unavailable}, 1.0),
+		WithLatency(time.Millisecond, 2*time.Millisecond, 1.0),
+	)
+
+	if got := chaosOutcomes(experiment, 1)[0]; got != codes.Unavailable {
+		t.Fatalf("Expected an injected error, got %v", got)
+	}
+
+	experiment.SetEnabled(false)
+	if got := chaosOutcomes(experiment, 1)[0]; got != codes.OK {
+		t.Errorf("Expected no chaos while disabled, got %v", got)
+	}
+
+	experiment.SetEnabled(true)
+	experiment.SetFaultEnabled(FaultError, false)
+	if got := chaosOutcomes(experiment, 1)[0]; got != codes.OK {
+		t.Errorf("Expected the error fault to be off, got %v", got)
+	}
+	if !experiment.FaultEnabled(FaultLatency) || experiment.FaultEnabled(FaultTimeout) {
+		t.Error("Expected only configured faults to be enabled")
+	}
+
+	stats := experiment.Stats()
+	if stats.Requests != 2 || stats.Errors != 1 || stats.Latencies != 2 || stats.Timeouts != 0 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+	if stats.InjectedLatency < 2*time.Millisecond {
+		t.Errorf("Expected at least 2ms of injected latency, got %v", stats.InjectedLatency)
+	}
+}
+
+func TestChaos_Metrics(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	collector, err := metrics.NewChaosCollector(registry)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	experiment := NewChaos(
+		WithErrors([]codes.Code{codes.Unavailable}, 1.0),
+		WithMetrics(collector),
+	)
+	chaosOutcomes(experiment, 3)
+
+	expected := `
+		# HELP grpc_chaos_injected_total Total number of faults injected by chaos experiments
+		# TYPE grpc_chaos_injected_total counter
+		grpc_chaos_injected_total{fault="error"} 3
+	`
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(expected), "grpc_chaos_injected_total"); err != nil {
+		t.Errorf("Expected 3 injected errors: %v", err)
+	}
+	if count := testutil.CollectAndCount(registry, "grpc_chaos_injected_latency_seconds_total"); count != 0 {
+		t.Errorf("Expected no latency series without latency injection, got %d", count)
+	}
+}
@@ -0,0 +1,296 @@
+package chaos
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ExperimentState is the lifecycle state of a scheduled experiment
+type ExperimentState string
+
+const (
+	// StatePending experiments have not reached their start time
+	StatePending ExperimentState = "pending"
+	// StateRunning experiments are injecting faults
+	StateRunning ExperimentState = "running"
+	// StateCompleted experiments reached their stop time
+	StateCompleted ExperimentState = "completed"
+	// StateRolledBack experiments were stopped because their health probe failed
+	StateRolledBack ExperimentState = "rolled_back"
+)
+
+// Schedule describes when and how aggressively an experiment runs
+type Schedule struct {
+	// Name identifies the experiment in states and callbacks
+	Name string
+
+	// Experiment is the chaos experiment the schedule controls
+	Experiment *Chaos
+
+	// Start and Stop bound the experiment window; a zero Start starts immediately and a
+	// zero Stop runs until the experiment is rolled back or removed
+	Start time.Time
+	Stop  time.Time
+
+	// RampUp grows fault probabilities linearly from zero to their configured values
+	// over this period after Start
+	RampUp time.Duration
+
+	// BlastRadius is the fraction of unique clients affected; 0 keeps the experiment's own
+	BlastRadius float64
+
+	// HealthProbe is checked while the experiment runs; an error rolls it back for good
+	HealthProbe func(ctx context.Context) error
+}
+
+// ExperimentStatus is the current status of a scheduled experiment
+type ExperimentStatus struct {
+	State     ExperimentState
+	Intensity float64 // Current probability factor
+	Err       error   // Health probe error for rolled back experiments
+}
+
+// Scheduler runs chaos experiments in time windows. Experiments are off outside their
+// window, ramp up after the start and are rolled back as soon as their health probe fails.
+type Scheduler struct {
+	interval      time.Duration
+	now           func() time.Time
+	onStateChange func(name string, state ExperimentState, err error)
+
+	mu          sync.Mutex
+	experiments map[string]*scheduled
+}
+
+// scheduled is an experiment with its schedule and state
+type scheduled struct {
+	schedule Schedule
+	state    ExperimentState
+	err      error
+}
+
+// SchedulerOption configures a Scheduler
+type SchedulerOption func(*Scheduler)
+
+// WithScheduleInterval sets how often schedules and health probes are evaluated
+// Default: 1s
+func WithScheduleInterval(interval time.Duration) SchedulerOption {
+	return func(s *Scheduler) {
+		if interval > 0 {
+			s.interval = interval
+		}
+	}
+}
+
+// WithOnStateChange sets a callback for experiment state changes; err is the health
+// probe error when an experiment is rolled back
+func WithOnStateChange(fn func(name string, state ExperimentState, err error)) SchedulerOption {
+	return func(s *Scheduler) {
+		s.onStateChange = fn
+	}
+}
+
+// WithSchedulerClock sets the time source schedules are evaluated against, e.g. a fake
+// clock in tests
+// Default: time.Now
+func WithSchedulerClock(now func() time.Time) SchedulerOption {
+	return func(s *Scheduler) {
+		if now != nil {
+			s.now = now
+		}
+	}
+}
+
+// NewScheduler creates a chaos experiment scheduler.
+//
+// Example usage:
+//
+//	experiment := chaos.NewChaos(chaos.WithErrors([]codes.Code{codes.Unavailable}, 0.1))
+//	scheduler := chaos.NewScheduler()
+//	_ = scheduler.Add(chaos.Schedule{
+//	    Name:        "orders-unavailable",
+//	    Experiment:  experiment,
+//	    Start:       time.Date(2024, 5, 14, 14, 0, 0, 0, time.UTC),
+//	    Stop:        time.Date(2024, 5, 14, 15, 0, 0, 0, time.UTC),
+//	    RampUp:      15 * time.Minute,
+//	    BlastRadius: 0.05,
+//	    HealthProbe: func(ctx context.Context) error {
+//	        if errorRate() > 0.02 {
+//	            return errors.New("error rate above 2%")
+//	        }
+//	        return nil
+//	    },
+//	})
+//	go scheduler.Run(ctx)
+//	chain := guardian.NewChain(experiment.UnaryServerInterceptor())
+func NewScheduler(opts ...SchedulerOption) *Scheduler {
+	s := &Scheduler{
+		interval:    time.Second,
+		now:         time.Now,
+		experiments: make(map[string]*scheduled),
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// Add schedules an experiment. The experiment is turned off until its window starts.
+func (s *Scheduler) Add(schedule Schedule) error {
+	if schedule.Name == "" {
+		return fmt.Errorf("chaos: schedule name is required")
+	}
+	if schedule.Experiment == nil {
+		return fmt.Errorf("chaos: schedule %q has no experiment", schedule.Name)
+	}
+	if !schedule.Stop.IsZero() && !schedule.Stop.After(schedule.Start) {
+		return fmt.Errorf("chaos: schedule %q stops before it starts", schedule.Name)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.experiments[schedule.Name]; exists {
+		return fmt.Errorf("chaos: schedule %q already exists", schedule.Name)
+	}
+
+	if schedule.BlastRadius > 0 {
+		schedule.Experiment.SetBlastRadius(schedule.BlastRadius)
+	}
+	schedule.Experiment.SetEnabled(false)
+	s.experiments[schedule.Name] = &scheduled{schedule: schedule, state: StatePending}
+	return nil
+}
+
+// Remove unschedules an experiment and turns it off
+func (s *Scheduler) Remove(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if exp, ok := s.experiments[name]; ok {
+		exp.schedule.Experiment.SetEnabled(false)
+		delete(s.experiments, name)
+	}
+}
+
+// Run evaluates the schedules every interval until the context is done; experiments are
+// turned off when it returns
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	s.Tick(ctx)
+	for {
+		select {
+		case <-ticker.C:
+			s.Tick(ctx)
+		case <-ctx.Done():
+			s.mu.Lock()
+			for _, exp := range s.experiments {
+				exp.schedule.Experiment.SetEnabled(false)
+			}
+			s.mu.Unlock()
+			return
+		}
+	}
+}
+
+// Tick evaluates every schedule once: experiments are started, ramped, stopped or rolled
+// back according to the current time and their health probes
+func (s *Scheduler) Tick(ctx context.Context) {
+	s.mu.Lock()
+	names := make([]string, 0, len(s.experiments))
+	for name := range s.experiments {
+		names = append(names, name)
+	}
+	s.mu.Unlock()
+	sort.Strings(names)
+
+	for _, name := range names {
+		s.evaluate(ctx, name)
+	}
+}
+
+// Status returns the status of every scheduled experiment
+func (s *Scheduler) Status() map[string]ExperimentStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status := make(map[string]ExperimentStatus, len(s.experiments))
+	for name, exp := range s.experiments {
+		status[name] = ExperimentStatus{
+			State:     exp.state,
+			Intensity: exp.schedule.Experiment.Intensity(),
+			Err:       exp.err,
+		}
+	}
+	return status
+}
+
+// evaluate moves one experiment to the state its schedule and health call for
+func (s *Scheduler) evaluate(ctx context.Context, name string) {
+	s.mu.Lock()
+	exp, ok := s.experiments[name]
+	if !ok || exp.state == StateCompleted || exp.state == StateRolledBack {
+		s.mu.Unlock()
+		return
+	}
+	schedule := exp.schedule
+	s.mu.Unlock()
+
+	now := s.now()
+	state := StateRunning
+	switch {
+	case now.Before(schedule.Start):
+		state = StatePending
+	case !schedule.Stop.IsZero() && !now.Before(schedule.Stop):
+		state = StateCompleted
+	}
+
+	// The probe runs without the lock; it may call out to a metrics backend
+	var probeErr error
+	if state == StateRunning && schedule.HealthProbe != nil {
+		if probeErr = schedule.HealthProbe(ctx); probeErr != nil {
+			state = StateRolledBack
+		}
+	}
+
+	experiment := schedule.Experiment
+	if state == StateRunning {
+		experiment.SetIntensity(rampIntensity(now.Sub(schedule.Start), schedule.RampUp))
+		experiment.SetEnabled(true)
+	} else {
+		experiment.SetEnabled(false)
+	}
+
+	s.mu.Lock()
+	if current, ok := s.experiments[name]; !ok || current != exp {
+		// Removed while the probe ran
+		s.mu.Unlock()
+		experiment.SetEnabled(false)
+		return
+	}
+	changed := exp.state != state
+	exp.state = state
+	exp.err = probeErr
+	s.mu.Unlock()
+
+	if changed && s.onStateChange != nil {
+		s.onStateChange(name, state, probeErr)
+	}
+}
+
+// rampIntensity returns the probability factor after elapsed time of a ramp-up
+func rampIntensity(elapsed, rampUp time.Duration) float64 {
+	if rampUp <= 0 || elapsed >= rampUp {
+		return 1
+	}
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(elapsed) / float64(rampUp)
+}
@@ -0,0 +1,130 @@
+package chaos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// testClientKey carries the client of a test request
+type testClientKey struct{}
+
+// fakeClock is a manually advanced clock
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func (c *fakeClock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+
+func TestChaos_BlastRadius(t *testing.T) {
+	experiment := NewChaos(
+		WithErrors([]codes.Code{codes.Unavailable}, 1.0),
+		WithBlastRadius(0.25),
+		WithClientExtractor(func(ctx context.Context) string {
+			return ctx.Value(testClientKey{}).(string)
+		}),
+	)
+	interceptor := experiment.UnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/svc/M"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) { return "ok", nil }
+
+	affected := 0
+	for i := 0; i < 400; i++ {
+		ctx := context.WithValue(context.Background(), testClientKey{}, fmt.Sprintf("user-%d", i))
+		_, first := interceptor(ctx, "req", info, handler)
+		_, second := interceptor(ctx, "req", info, handler)
+		if status.Code(first) != status.Code(second) {
+			t.Fatalf("user-%d: expected clients to stay inside or outside the blast radius", i)
+		}
+		if first != nil {
+			affected++
+		}
+	}
+	if affected < 60 || affected > 140 {
+		t.Errorf("Expected about 25%% of 400 clients to be affected, got %d", affected)
+	}
+}
+
+func TestChaos_Scheduler(t *testing.T) {
+	start := time.Date(2024, 5, 14, 14, 0, 0, 0, time.UTC)
+	clock := &fakeClock{now: start.Add(-time.Minute)}
+
+	var transitions []string
+	scheduler := NewScheduler(
+		WithSchedulerClock(clock.Now),
+		WithOnStateChange(func(name string, state ExperimentState, err error) {
+			transitions = append(transitions, name+":"+string(state))
+		}),
+	)
+
+	windowed := NewChaos(WithErrors([]codes.Code{codes.Unavailable}, 1.0))
+	if err := scheduler.Add(Schedule{
+		Name:       "windowed",
+		Experiment: windowed,
+		Start:      start,
+		Stop:       start.Add(time.Hour),
+		RampUp:     10 * time.Minute,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	healthy := true
+	probed := NewChaos(WithErrors([]codes.Code{codes.Unavailable}, 1.0))
+	if err := scheduler.Add(Schedule{
+		Name:       "probed",
+		Experiment: probed,
+		HealthProbe: func(ctx context.Context) error {
+			if !healthy {
+				return errors.New("error rate above 2%")
+			}
+			return nil
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	scheduler.Tick(ctx)
+	if windowed.Enabled() || !probed.Enabled() {
+		t.Fatal("Expected only the experiment without a start time to run before the window")
+	}
+
+	clock.Advance(6 * time.Minute) // 5 minutes into a 10 minute ramp-up
+	scheduler.Tick(ctx)
+	if !windowed.Enabled() || windowed.Intensity() != 0.5 {
+		t.Errorf("Expected the experiment to run at half intensity, got enabled=%v intensity=%v",
+			windowed.Enabled(), windowed.Intensity())
+	}
+
+	healthy = false
+	scheduler.Tick(ctx)
+	status := scheduler.Status()
+	if probed.Enabled() || status["probed"].State != StateRolledBack || status["probed"].Err == nil {
+		t.Errorf("Expected a failing probe to roll the experiment back, got %+v", status["probed"])
+	}
+
+	// Rolled back experiments stay off when the probe recovers
+	healthy = true
+	clock.Advance(time.Hour)
+	scheduler.Tick(ctx)
+	if probed.Enabled() || windowed.Enabled() {
+		t.Error("Expected both experiments to be off")
+	}
+
+	want := []string{"probed:running", "windowed:running", "probed:rolled_back", "windowed:completed"}
+	if fmt.Sprint(transitions) != fmt.Sprint(want) {
+		t.Errorf("transitions = %v, want %v", transitions, want)
+	}
+
+	if err := scheduler.Add(Schedule{Name: "windowed", Experiment: windowed}); err == nil {
+		t.Error("Expected duplicate names to be rejected")
+	}
+}
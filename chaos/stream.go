@@ -0,0 +1,186 @@
+package chaos
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// StreamServerInterceptor returns the chaos middleware for streams. Latency and errors are
+// injected when the stream opens; throttling, timeouts and metadata tampering are decided
+// once per stream, corruption once per message sent by the server.
+func (c *Chaos) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	config := c.config
+
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx := ss.Context()
+		if !c.applies(ctx, info.FullMethod, nil) {
+			return handler(srv, ss)
+		}
+
+		c.requests.Add(1)
+
+		if err := c.injectLatency(ctx); err != nil {
+			return err
+		}
+		if err := c.injectError(); err != nil {
+			return err
+		}
+
+		stream := &chaosServerStream{ServerStream: ss, chaos: c, ctx: ctx}
+
+		if c.FaultEnabled(FaultThrottle) && c.shouldInject(config.ThrottleProbability) {
+			c.record(FaultThrottle)
+			stream.throttle = true
+		}
+
+		if c.FaultEnabled(FaultMetadata) && c.shouldInject(config.MetadataProbability) {
+			c.record(FaultMetadata)
+			stream.tamper = true
+		}
+
+		if c.FaultEnabled(FaultTimeout) && c.shouldInject(config.TimeoutProbability) {
+			c.record(FaultTimeout)
+			newCtx, cancel := context.WithTimeout(ctx, config.TimeoutDuration)
+			defer cancel()
+			stream.ctx = newCtx
+		}
+
+		return handler(srv, stream)
+	}
+}
+
+// chaosServerStream injects faults into the messages and metadata of a stream
+type chaosServerStream struct {
+	grpc.ServerStream
+	chaos    *Chaos
+	ctx      context.Context
+	throttle bool
+	tamper   bool
+}
+
+// Context returns the stream context, with the shortened deadline of an injected timeout
+func (s *chaosServerStream) Context() context.Context {
+	return s.ctx
+}
+
+// SendMsg sends a message, corrupted or throttled when the experiment says so
+func (s *chaosServerStream) SendMsg(m interface{}) error {
+	config := s.chaos.config
+	if s.chaos.FaultEnabled(FaultCorruption) && s.chaos.shouldInject(config.CorruptionProbability) {
+		s.chaos.record(FaultCorruption)
+		m = config.Corrupter(m)
+	}
+
+	if err := s.wait(m); err != nil {
+		return err
+	}
+	return s.ServerStream.SendMsg(m)
+}
+
+// RecvMsg receives a message, throttled when the experiment says so
+func (s *chaosServerStream) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	return s.wait(m)
+}
+
+// SetHeader sets header metadata, tampered with when the experiment says so
+func (s *chaosServerStream) SetHeader(md metadata.MD) error {
+	return s.ServerStream.SetHeader(s.metadata(md))
+}
+
+// SendHeader sends header metadata, tampered with when the experiment says so
+func (s *chaosServerStream) SendHeader(md metadata.MD) error {
+	return s.ServerStream.SendHeader(s.metadata(md))
+}
+
+// SetTrailer sets trailer metadata, tampered with when the experiment says so
+func (s *chaosServerStream) SetTrailer(md metadata.MD) {
+	s.ServerStream.SetTrailer(s.metadata(md))
+}
+
+// metadata returns the metadata to send on the stream
+func (s *chaosServerStream) metadata(md metadata.MD) metadata.MD {
+	if !s.tamper {
+		return md
+	}
+	return s.chaos.tamper(md)
+}
+
+// wait holds a message of a throttled stream for as long as it takes to transfer at the
+// configured bandwidth
+func (s *chaosServerStream) wait(m interface{}) error {
+	if !s.throttle || !s.chaos.FaultEnabled(FaultThrottle) {
+		return nil
+	}
+
+	delay := transferTime(m, s.chaos.config.ThrottleBytesPerSecond)
+	if delay <= 0 {
+		return nil
+	}
+	s.chaos.addLatency(delay)
+
+	select {
+	case <-time.After(delay):
+		return nil
+	case <-s.ctx.Done():
+		return status.Errorf(codes.Canceled, "stream canceled during chaos bandwidth throttling")
+	}
+}
+
+// tamperingTransportStream tampers with the metadata a unary handler sets
+type tamperingTransportStream struct {
+	grpc.ServerTransportStream
+	chaos *Chaos
+}
+
+// SetHeader sets tampered header metadata
+func (s *tamperingTransportStream) SetHeader(md metadata.MD) error {
+	return s.ServerTransportStream.SetHeader(s.chaos.tamper(md))
+}
+
+// SendHeader sends tampered header metadata
+func (s *tamperingTransportStream) SendHeader(md metadata.MD) error {
+	return s.ServerTransportStream.SendHeader(s.chaos.tamper(md))
+}
+
+// SetTrailer sets tampered trailer metadata
+func (s *tamperingTransportStream) SetTrailer(md metadata.MD) error {
+	return s.ServerTransportStream.SetTrailer(s.chaos.tamper(md))
+}
+
+// tamper returns a copy of md with the configured keys dropped or mangled
+func (c *Chaos) tamper(md metadata.MD) metadata.MD {
+	tampered := md.Copy()
+	for _, key := range c.config.MetadataKeys {
+		values, ok := tampered[key]
+		if !ok {
+			continue
+		}
+		if c.config.MetadataMangler == nil {
+			delete(tampered, key)
+			continue
+		}
+		for i, value := range values {
+			values[i] = c.config.MetadataMangler(key, value)
+		}
+	}
+	return tampered
+}
+
+// transferTime returns how long a message takes to transfer at bytesPerSecond; messages
+// that are not protobuf messages are not throttled
+func transferTime(m interface{}, bytesPerSecond int) time.Duration {
+	msg, ok := m.(proto.Message)
+	if !ok || bytesPerSecond <= 0 {
+		return 0
+	}
+	return time.Duration(float64(proto.Size(msg)) / float64(bytesPerSecond) * float64(time.Second))
+}
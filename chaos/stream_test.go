@@ -0,0 +1,128 @@
+package chaos
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// chaosTransportStream records the metadata a unary handler sets
+type chaosTransportStream struct {
+	header  metadata.MD
+	trailer metadata.MD
+}
+
+func (s *chaosTransportStream) Method() string { return "/svc/M" }
+
+func (s *chaosTransportStream) SetHeader(md metadata.MD) error {
+	s.header = metadata.Join(s.header, md)
+	return nil
+}
+
+func (s *chaosTransportStream) SendHeader(md metadata.MD) error { return s.SetHeader(md) }
+
+func (s *chaosTransportStream) SetTrailer(md metadata.MD) error {
+	s.trailer = metadata.Join(s.trailer, md)
+	return nil
+}
+
+func TestChaos_CorruptionAndMetadata(t *testing.T) {
+	experiment := NewChaos(
+		WithCorruption(func(resp interface{}) interface{} {
+			return wrapperspb.String("corrupted")
+		}, 1.0),
+		WithMetadataTampering([]string{"X-Request-Id", "x-version"}, nil, 1.0),
+	)
+	interceptor := experiment.UnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/svc/M"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		grpc.SetHeader(ctx, metadata.Pairs("x-request-id", "r-1", "x-other", "kept"))
+		grpc.SetTrailer(ctx, metadata.Pairs("x-version", "v2"))
+		return wrapperspb.String("ok"), nil
+	}
+
+	stream := &chaosTransportStream{}
+	ctx := grpc.NewContextWithServerTransportStream(context.Background(), stream)
+	resp, err := interceptor(ctx, "req", info, handler)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := resp.(*wrapperspb.StringValue).GetValue(); got != "corrupted" {
+		t.Errorf("Expected a corrupted response, got %q", got)
+	}
+	if _, ok := stream.header["x-request-id"]; ok || stream.header.Get("x-other")[0] != "kept" {
+		t.Errorf("Expected only x-request-id to be dropped from the header, got %v", stream.header)
+	}
+	if len(stream.trailer.Get("x-version")) != 0 {
+		t.Errorf("Expected x-version to be dropped from the trailer, got %v", stream.trailer)
+	}
+
+	mangled := NewChaos(WithMetadataTampering([]string{"x-version"}, func(key, value string) string {
+		return "garbage"
+	}, 1.0))
+	stream = &chaosTransportStream{}
+	ctx = grpc.NewContextWithServerTransportStream(context.Background(), stream)
+	mangled.UnaryServerInterceptor()(ctx, "req", info, handler)
+	if got := stream.trailer.Get("x-version"); len(got) != 1 || got[0] != "garbage" {
+		t.Errorf("Expected x-version to be mangled, got %v", got)
+	}
+
+	stats := experiment.Stats()
+	if stats.Corrupted != 1 || stats.Tampered != 1 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+}
+
+// chaosTestStream is a server stream that sends and receives string values
+type chaosTestStream struct {
+	grpc.ServerStream
+	ctx  context.Context
+	sent []interface{}
+}
+
+func (s *chaosTestStream) Context() context.Context { return s.ctx }
+
+func (s *chaosTestStream) SendMsg(m interface{}) error {
+	s.sent = append(s.sent, m)
+	return nil
+}
+
+func (s *chaosTestStream) RecvMsg(m interface{}) error {
+	m.(*wrapperspb.StringValue).Value = strings.Repeat("x", 98)
+	return nil
+}
+
+func TestChaos_StreamThrottle(t *testing.T) {
+	experiment := NewChaos(WithBandwidthThrottle(10000, 1.0)) // 100 byte messages take 10ms
+	interceptor := experiment.StreamServerInterceptor()
+	info := &grpc.StreamServerInfo{FullMethod: "/svc/Stream"}
+
+	stream := &chaosTestStream{ctx: context.Background()}
+	start := time.Now()
+	err := interceptor(nil, stream, info, func(srv interface{}, ss grpc.ServerStream) error {
+		msg := &wrapperspb.StringValue{}
+		if err := ss.RecvMsg(msg); err != nil {
+			return err
+		}
+		return ss.SendMsg(msg)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Errorf("Expected two throttled messages to take at least 20ms, took %v", elapsed)
+	}
+	if len(stream.sent) != 1 {
+		t.Errorf("Expected the message to be sent, got %d messages", len(stream.sent))
+	}
+
+	stats := experiment.Stats()
+	if stats.Throttled != 1 || stats.InjectedLatency < 20*time.Millisecond {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+}
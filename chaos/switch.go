@@ -0,0 +1,36 @@
+package chaos
+
+import (
+	"sync/atomic"
+)
+
+// Switch turns a chaos experiment on and off at runtime, e.g. from pkg/admin
+type Switch struct {
+	enabled atomic.Bool
+}
+
+// NewSwitch creates a switch in the given state
+//
+// Example usage:
+//
+//	experiment := chaos.NewSwitch(false)
+//	chain := guardian.NewChain(chaos.New(
+//	    chaos.WithErrors([]codes.Code{codes.Unavailable}, 0.1),
+//	    chaos.WithCondition(experiment.Enabled),
+//	))
+//	experiment.Set(true)
+func NewSwitch(enabled bool) *Switch {
+	s := &Switch{}
+	s.enabled.Store(enabled)
+	return s
+}
+
+// Enabled reports whether the experiment is on; pass it to WithCondition
+func (s *Switch) Enabled() bool {
+	return s.enabled.Load()
+}
+
+// Set turns the experiment on or off
+func (s *Switch) Set(enabled bool) {
+	s.enabled.Store(enabled)
+}
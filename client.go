@@ -0,0 +1,118 @@
+package guardian
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+)
+
+// ClientMiddleware is a unary client middleware. It is an alias of
+// grpc.UnaryClientInterceptor, so the client interceptors of the middleware package can be
+// used directly.
+type ClientMiddleware = grpc.UnaryClientInterceptor
+
+// ClientStreamMiddleware is a streaming client middleware
+type ClientStreamMiddleware = grpc.StreamClientInterceptor
+
+// ClientChain is a chain of client middleware, the client-side counterpart of Chain. The
+// first middleware is the outermost: it sees the call first and the result last.
+type ClientChain struct {
+	middlewares       []ClientMiddleware
+	streamMiddlewares []ClientStreamMiddleware
+}
+
+// NewClientChain creates a new client middleware chain
+//
+// Example usage:
+//
+//	retry := middleware.NewRetry(middleware.WithMaxAttempts(3))
+//	breaker := middleware.NewCircuitBreaker(middleware.WithBreakerName("inventory"))
+//
+//	chain := guardian.NewClientChain().
+//	    Use(middleware.MetricsUnaryClientInterceptor(collector), middleware.MetricsStreamClientInterceptor(collector)).
+//	    Use(breaker.UnaryClientInterceptor(), breaker.StreamClientInterceptor()).
+//	    Use(retry.UnaryClientInterceptor(), retry.StreamClientInterceptor()).
+//	    Use(middleware.TracingUnaryClientInterceptor(), middleware.TracingStreamClientInterceptor()) // a span per attempt
+//
+//	conn, err := grpc.Dial(target, append(chain.DialOptions(),
+//	    grpc.WithTransportCredentials(creds))...)
+func NewClientChain(middlewares ...ClientMiddleware) *ClientChain {
+	return &ClientChain{middlewares: middlewares}
+}
+
+// Append adds unary middleware to the end of the chain
+func (c *ClientChain) Append(middlewares ...ClientMiddleware) *ClientChain {
+	c.middlewares = append(c.middlewares, middlewares...)
+	return c
+}
+
+// Prepend adds unary middleware to the beginning of the chain
+func (c *ClientChain) Prepend(middlewares ...ClientMiddleware) *ClientChain {
+	c.middlewares = append(middlewares, c.middlewares...)
+	return c
+}
+
+// AppendStream adds streaming middleware to the end of the chain
+func (c *ClientChain) AppendStream(middlewares ...ClientStreamMiddleware) *ClientChain {
+	c.streamMiddlewares = append(c.streamMiddlewares, middlewares...)
+	return c
+}
+
+// Use adds the unary and streaming variants of a middleware to the end of the chain.
+// Either may be nil for middleware that only handles one kind of call.
+func (c *ClientChain) Use(unary ClientMiddleware, stream ClientStreamMiddleware) *ClientChain {
+	if unary != nil {
+		c.middlewares = append(c.middlewares, unary)
+	}
+	if stream != nil {
+		c.streamMiddlewares = append(c.streamMiddlewares, stream)
+	}
+	return c
+}
+
+// UnaryClientInterceptor returns a gRPC UnaryClientInterceptor that executes the chain
+func (c *ClientChain) UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		currentInvoker := invoker
+
+		// Apply middleware in reverse order so they execute in the correct order
+		for i := len(c.middlewares) - 1; i >= 0; i-- {
+			middleware := c.middlewares[i]
+			next := currentInvoker
+
+			currentInvoker = func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+				return middleware(ctx, method, req, reply, cc, next, opts...)
+			}
+		}
+
+		return currentInvoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// StreamClientInterceptor returns a gRPC StreamClientInterceptor that executes the chain
+func (c *ClientChain) StreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		currentStreamer := streamer
+
+		// Apply middleware in reverse order
+		for i := len(c.streamMiddlewares) - 1; i >= 0; i-- {
+			middleware := c.streamMiddlewares[i]
+			next := currentStreamer
+
+			currentStreamer = func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+				return middleware(ctx, desc, cc, method, next, opts...)
+			}
+		}
+
+		return currentStreamer(ctx, desc, cc, method, opts...)
+	}
+}
+
+// DialOptions returns gRPC DialOptions installing both the unary and stream interceptors.
+// They are added with WithChain*Interceptor, so they compose with other interceptors.
+func (c *ClientChain) DialOptions() []grpc.DialOption {
+	return []grpc.DialOption{
+		grpc.WithChainUnaryInterceptor(c.UnaryClientInterceptor()),
+		grpc.WithChainStreamInterceptor(c.StreamClientInterceptor()),
+	}
+}
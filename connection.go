@@ -0,0 +1,293 @@
+package guardian
+
+import (
+	"fmt"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/keepalive"
+)
+
+// ConnectionProfile is a preset of keepalive, connection age and flow control settings
+type ConnectionProfile int
+
+const (
+	// ProfileBalanced suits most internal services: dead connections are found within a
+	// minute and connections are recycled every 10 minutes so new replicas get traffic
+	ProfileBalanced ConnectionProfile = iota
+	// ProfileLowLatency finds dead connections within seconds, for latency-critical calls
+	// that can't wait for TCP timeouts
+	ProfileLowLatency
+	// ProfileHighThroughput uses large flow control windows and long-lived connections,
+	// for bulk transfers and long streams
+	ProfileHighThroughput
+	// ProfileMobileClients avoids pings on idle connections, which wake radios and drain
+	// batteries, and closes connections clients abandoned
+	ProfileMobileClients
+)
+
+// String returns the name of the profile
+func (p ConnectionProfile) String() string {
+	switch p {
+	case ProfileBalanced:
+		return "balanced"
+	case ProfileLowLatency:
+		return "low-latency"
+	case ProfileHighThroughput:
+		return "high-throughput"
+	case ProfileMobileClients:
+		return "mobile-clients"
+	default:
+		return fmt.Sprintf("ConnectionProfile(%d)", int(p))
+	}
+}
+
+// minClientKeepaliveTime is the shortest client keepalive gRPC honors; shorter ones are
+// silently raised to it
+const minClientKeepaliveTime = 10 * time.Second
+
+// minWindowSize is the smallest flow control window gRPC honors; smaller ones are ignored
+const minWindowSize = 64 * 1024
+
+// ConnectionSettings are the connection-level settings of clients and servers. Zero
+// durations and window sizes keep the gRPC defaults.
+type ConnectionSettings struct {
+	// Client pings after ClientKeepaliveTime without activity and closes the connection
+	// when no ack arrives within ClientKeepaliveTimeout
+	ClientKeepaliveTime    time.Duration
+	ClientKeepaliveTimeout time.Duration
+	// Server pings after ServerKeepaliveTime without activity and closes the connection
+	// when no ack arrives within ServerKeepaliveTimeout
+	ServerKeepaliveTime    time.Duration
+	ServerKeepaliveTimeout time.Duration
+	// MinPingInterval is the shortest interval between client pings the server accepts;
+	// clients pinging more often are sent GOAWAY "too_many_pings"
+	MinPingInterval time.Duration
+	// PermitWithoutStream allows pings on connections without active calls
+	PermitWithoutStream bool
+
+	// MaxConnectionIdle closes connections without calls for this long
+	MaxConnectionIdle time.Duration
+	// MaxConnectionAge sends GOAWAY to connections older than this, so clients reconnect
+	// and rebalance; calls in flight get MaxConnectionAgeGrace to finish
+	MaxConnectionAge      time.Duration
+	MaxConnectionAgeGrace time.Duration
+
+	// InitialWindowSize and InitialConnWindowSize set the per-stream and per-connection
+	// flow control windows, disabling gRPC's dynamic window sizing
+	InitialWindowSize     int32
+	InitialConnWindowSize int32
+}
+
+// ConnectionOption adjusts the settings of a profile
+type ConnectionOption func(*ConnectionSettings)
+
+// WithClientKeepalive sets how often clients ping idle connections and how long they
+// wait for the ack
+func WithClientKeepalive(interval, timeout time.Duration) ConnectionOption {
+	return func(s *ConnectionSettings) {
+		s.ClientKeepaliveTime = interval
+		s.ClientKeepaliveTimeout = timeout
+	}
+}
+
+// WithServerKeepalive sets how often servers ping idle connections and how long they
+// wait for the ack
+func WithServerKeepalive(interval, timeout time.Duration) ConnectionOption {
+	return func(s *ConnectionSettings) {
+		s.ServerKeepaliveTime = interval
+		s.ServerKeepaliveTimeout = timeout
+	}
+}
+
+// WithKeepaliveEnforcement sets the shortest ping interval servers accept and whether
+// pings without active calls are allowed
+func WithKeepaliveEnforcement(minInterval time.Duration, permitWithoutStream bool) ConnectionOption {
+	return func(s *ConnectionSettings) {
+		s.MinPingInterval = minInterval
+		s.PermitWithoutStream = permitWithoutStream
+	}
+}
+
+// WithMaxConnectionAge sets how long servers keep connections before draining them with
+// GOAWAY, and how long calls in flight may take to finish
+func WithMaxConnectionAge(age, grace time.Duration) ConnectionOption {
+	return func(s *ConnectionSettings) {
+		s.MaxConnectionAge = age
+		s.MaxConnectionAgeGrace = grace
+	}
+}
+
+// WithMaxConnectionIdle sets how long servers keep connections without calls
+func WithMaxConnectionIdle(idle time.Duration) ConnectionOption {
+	return func(s *ConnectionSettings) {
+		s.MaxConnectionIdle = idle
+	}
+}
+
+// WithWindowSize sets the per-stream and per-connection flow control windows
+func WithWindowSize(stream, conn int32) ConnectionOption {
+	return func(s *ConnectionSettings) {
+		s.InitialWindowSize = stream
+		s.InitialConnWindowSize = conn
+	}
+}
+
+// Connection returns the settings of a profile, adjusted by opts
+func Connection(profile ConnectionProfile, opts ...ConnectionOption) ConnectionSettings {
+	var s ConnectionSettings
+	switch profile {
+	case ProfileLowLatency:
+		s = ConnectionSettings{
+			ClientKeepaliveTime:    10 * time.Second,
+			ClientKeepaliveTimeout: 2 * time.Second,
+			ServerKeepaliveTime:    15 * time.Second,
+			ServerKeepaliveTimeout: 2 * time.Second,
+			MinPingInterval:        5 * time.Second,
+			PermitWithoutStream:    true,
+			MaxConnectionAge:       5 * time.Minute,
+			MaxConnectionAgeGrace:  30 * time.Second,
+		}
+	case ProfileHighThroughput:
+		s = ConnectionSettings{
+			ClientKeepaliveTime:    30 * time.Second,
+			ClientKeepaliveTimeout: 10 * time.Second,
+			ServerKeepaliveTime:    time.Minute,
+			ServerKeepaliveTimeout: 20 * time.Second,
+			MinPingInterval:        15 * time.Second,
+			PermitWithoutStream:    true,
+			MaxConnectionAge:       30 * time.Minute,
+			MaxConnectionAgeGrace:  5 * time.Minute,
+			InitialWindowSize:      4 << 20,
+			InitialConnWindowSize:  16 << 20,
+		}
+	case ProfileMobileClients:
+		s = ConnectionSettings{
+			ClientKeepaliveTime:    5 * time.Minute,
+			ClientKeepaliveTimeout: 20 * time.Second,
+			ServerKeepaliveTime:    2 * time.Hour,
+			ServerKeepaliveTimeout: 20 * time.Second,
+			MinPingInterval:        time.Minute,
+			PermitWithoutStream:    false,
+			MaxConnectionIdle:      5 * time.Minute,
+			MaxConnectionAge:       time.Hour,
+			MaxConnectionAgeGrace:  time.Minute,
+		}
+	default:
+		s = ConnectionSettings{
+			ClientKeepaliveTime:    30 * time.Second,
+			ClientKeepaliveTimeout: 10 * time.Second,
+			ServerKeepaliveTime:    2 * time.Minute,
+			ServerKeepaliveTimeout: 20 * time.Second,
+			MinPingInterval:        20 * time.Second,
+			PermitWithoutStream:    true,
+			MaxConnectionAge:       10 * time.Minute,
+			MaxConnectionAgeGrace:  time.Minute,
+		}
+	}
+
+	for _, opt := range opts {
+		opt(&s)
+	}
+	return s
+}
+
+// Validate checks that clients and servers using the settings agree: clients must not
+// ping more often than servers accept, or servers close their connections with GOAWAY
+// "too_many_pings"
+func (s ConnectionSettings) Validate() error {
+	if s.ClientKeepaliveTime > 0 && s.ClientKeepaliveTime < minClientKeepaliveTime {
+		return fmt.Errorf("client keepalive time %v is below the gRPC minimum of %v", s.ClientKeepaliveTime, minClientKeepaliveTime)
+	}
+	if s.ClientKeepaliveTime > 0 && s.ClientKeepaliveTime < s.MinPingInterval {
+		return fmt.Errorf("client keepalive time %v is below the server's minimum ping interval %v", s.ClientKeepaliveTime, s.MinPingInterval)
+	}
+	if s.ClientKeepaliveTime > 0 && s.ClientKeepaliveTimeout >= s.ClientKeepaliveTime {
+		return fmt.Errorf("client keepalive timeout %v must be shorter than the keepalive time %v", s.ClientKeepaliveTimeout, s.ClientKeepaliveTime)
+	}
+	if s.ServerKeepaliveTime > 0 && s.ServerKeepaliveTimeout >= s.ServerKeepaliveTime {
+		return fmt.Errorf("server keepalive timeout %v must be shorter than the keepalive time %v", s.ServerKeepaliveTimeout, s.ServerKeepaliveTime)
+	}
+	if s.MaxConnectionAgeGrace > 0 && s.MaxConnectionAge == 0 {
+		return fmt.Errorf("max connection age grace %v needs a max connection age", s.MaxConnectionAgeGrace)
+	}
+	if s.InitialWindowSize != 0 && s.InitialWindowSize < minWindowSize {
+		return fmt.Errorf("initial window size %d is below the gRPC minimum of %d", s.InitialWindowSize, minWindowSize)
+	}
+	if s.InitialConnWindowSize != 0 && s.InitialConnWindowSize < minWindowSize {
+		return fmt.Errorf("initial connection window size %d is below the gRPC minimum of %d", s.InitialConnWindowSize, minWindowSize)
+	}
+	return nil
+}
+
+// ServerOptions returns the keepalive, enforcement and flow control options of a profile
+// for grpc.NewServer. Connections older than the max connection age are drained with GOAWAY,
+// which a middleware.Redispatcher on clients turns into transparent re-dispatches.
+//
+// Example usage:
+//
+//	connOpts, err := guardian.ServerOptions(guardian.ProfileLowLatency)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	server := grpc.NewServer(append(connOpts, chain.ServerOption()...)...)
+func ServerOptions(profile ConnectionProfile, opts ...ConnectionOption) ([]grpc.ServerOption, error) {
+	s := Connection(profile, opts...)
+	if err := s.Validate(); err != nil {
+		return nil, fmt.Errorf("%s connection profile: %w", profile, err)
+	}
+
+	serverOpts := []grpc.ServerOption{
+		grpc.KeepaliveParams(keepalive.ServerParameters{
+			MaxConnectionIdle:     s.MaxConnectionIdle,
+			MaxConnectionAge:      s.MaxConnectionAge,
+			MaxConnectionAgeGrace: s.MaxConnectionAgeGrace,
+			Time:                  s.ServerKeepaliveTime,
+			Timeout:               s.ServerKeepaliveTimeout,
+		}),
+		grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
+			MinTime:             s.MinPingInterval,
+			PermitWithoutStream: s.PermitWithoutStream,
+		}),
+	}
+	if s.InitialWindowSize > 0 {
+		serverOpts = append(serverOpts, grpc.InitialWindowSize(s.InitialWindowSize))
+	}
+	if s.InitialConnWindowSize > 0 {
+		serverOpts = append(serverOpts, grpc.InitialConnWindowSize(s.InitialConnWindowSize))
+	}
+	return serverOpts, nil
+}
+
+// ClientDialOptions returns the keepalive and flow control options of a profile for
+// grpc.Dial. Use the same profile and options as the server, so clients never ping more
+// often than the server accepts.
+//
+// Example usage:
+//
+//	connOpts, err := guardian.ClientDialOptions(guardian.ProfileLowLatency)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	conn, err := grpc.Dial(target, append(connOpts, chain.DialOptions()...)...)
+func ClientDialOptions(profile ConnectionProfile, opts ...ConnectionOption) ([]grpc.DialOption, error) {
+	s := Connection(profile, opts...)
+	if err := s.Validate(); err != nil {
+		return nil, fmt.Errorf("%s connection profile: %w", profile, err)
+	}
+
+	dialOpts := []grpc.DialOption{
+		grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			Time:                s.ClientKeepaliveTime,
+			Timeout:             s.ClientKeepaliveTimeout,
+			PermitWithoutStream: s.PermitWithoutStream,
+		}),
+	}
+	if s.InitialWindowSize > 0 {
+		dialOpts = append(dialOpts, grpc.WithInitialWindowSize(s.InitialWindowSize))
+	}
+	if s.InitialConnWindowSize > 0 {
+		dialOpts = append(dialOpts, grpc.WithInitialConnWindowSize(s.InitialConnWindowSize))
+	}
+	return dialOpts, nil
+}
@@ -0,0 +1,127 @@
+package guardian_test
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	guardian "github.com/grpc-guardian/grpc-guardian"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestConnectionProfiles_Valid(t *testing.T) {
+	profiles := []guardian.ConnectionProfile{
+		guardian.ProfileBalanced,
+		guardian.ProfileLowLatency,
+		guardian.ProfileHighThroughput,
+		guardian.ProfileMobileClients,
+	}
+	for _, profile := range profiles {
+		t.Run(profile.String(), func(t *testing.T) {
+			settings := guardian.Connection(profile)
+			if err := settings.Validate(); err != nil {
+				t.Fatal(err)
+			}
+			if settings.ClientKeepaliveTime < settings.MinPingInterval {
+				t.Errorf("Expected clients to ping no more often than the server accepts, got %v < %v",
+					settings.ClientKeepaliveTime, settings.MinPingInterval)
+			}
+			if _, err := guardian.ServerOptions(profile); err != nil {
+				t.Error(err)
+			}
+			if _, err := guardian.ClientDialOptions(profile); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestConnectionProfiles_Overrides(t *testing.T) {
+	settings := guardian.Connection(guardian.ProfileHighThroughput,
+		guardian.WithMaxConnectionAge(time.Hour, 10*time.Minute),
+		guardian.WithWindowSize(1<<20, 8<<20),
+	)
+	if settings.MaxConnectionAge != time.Hour || settings.InitialWindowSize != 1<<20 {
+		t.Errorf("Expected the overrides to apply, got %+v", settings)
+	}
+	if settings.MinPingInterval != 15*time.Second {
+		t.Errorf("Expected the other settings of the profile to be kept, got %+v", settings)
+	}
+}
+
+func TestConnectionProfiles_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []guardian.ConnectionOption
+		want string
+	}{
+		{"ping below enforcement", []guardian.ConnectionOption{guardian.WithClientKeepalive(15*time.Second, time.Second), guardian.WithKeepaliveEnforcement(30*time.Second, true)}, "minimum ping interval"},
+		{"ping below gRPC minimum", []guardian.ConnectionOption{guardian.WithClientKeepalive(time.Second, 500*time.Millisecond), guardian.WithKeepaliveEnforcement(0, true)}, "gRPC minimum"},
+		{"timeout above interval", []guardian.ConnectionOption{guardian.WithServerKeepalive(time.Minute, 2*time.Minute)}, "server keepalive timeout"},
+		{"tiny window", []guardian.ConnectionOption{guardian.WithWindowSize(1024, 0)}, "window size"},
+		{"grace without age", []guardian.ConnectionOption{guardian.WithMaxConnectionAge(0, time.Minute)}, "needs a max connection age"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := guardian.ServerOptions(guardian.ProfileBalanced, tt.opts...)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected an error containing %q, got %v", tt.want, err)
+			}
+			if _, err := guardian.ClientDialOptions(guardian.ProfileBalanced, tt.opts...); err == nil {
+				t.Error("Expected the client options to be rejected too")
+			}
+		})
+	}
+}
+
+func TestConnectionProfiles_Dial(t *testing.T) {
+	serverOpts, err := guardian.ServerOptions(guardian.ProfileHighThroughput)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := grpc.NewServer(serverOpts...)
+	server.RegisterService(&grpc.ServiceDesc{
+		ServiceName: "test.Echo",
+		HandlerType: (*interface{})(nil),
+		Methods: []grpc.MethodDesc{{
+			MethodName: "Echo",
+			Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, _ grpc.UnaryServerInterceptor) (interface{}, error) {
+				req := &wrapperspb.StringValue{}
+				if err := dec(req); err != nil {
+					return nil, err
+				}
+				return req, nil
+			},
+		}},
+	}, struct{}{})
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go server.Serve(lis)
+	defer server.Stop()
+
+	dialOpts, err := guardian.ClientDialOptions(guardian.ProfileHighThroughput)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := grpc.Dial(lis.Addr().String(), append(dialOpts, grpc.WithTransportCredentials(insecure.NewCredentials()))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	payload := strings.Repeat("x", 1<<20)
+	reply := &wrapperspb.StringValue{}
+	if err := conn.Invoke(context.Background(), "/test.Echo/Echo", wrapperspb.String(payload), reply); err != nil {
+		t.Fatal(err)
+	}
+	if len(reply.GetValue()) != len(payload) {
+		t.Errorf("Expected a %d byte reply, got %d", len(payload), len(reply.GetValue()))
+	}
+}
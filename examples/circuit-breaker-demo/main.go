@@ -49,10 +49,10 @@ func main() {
 	// Create circuit breaker with custom settings
 	circuitBreaker := middleware.NewCircuitBreaker(
 		middleware.WithFailureThreshold(0.5),      // Open after 50% failure rate
-		middleware.WithTimeout(5*time.Second),      // Stay open for 5 seconds
-		middleware.WithInterval(10*time.Second),    // Count failures over 10 second window
-		middleware.WithMaxRequests(3),              // Allow 3 requests in half-open state
-		middleware.WithSuccessThreshold(2),         // Need 2 successes to close
+		middleware.WithOpenTimeout(5*time.Second), // Stay open for 5 seconds
+		middleware.WithInterval(10*time.Second),   // Count failures over 10 second window
+		middleware.WithMaxRequests(3),             // Allow 3 requests in half-open state
+		middleware.WithSuccessThreshold(2),        // Need 2 successes to close
 		middleware.WithOnStateChange(func(from, to middleware.State) {
 			log.Printf("🔄 Circuit Breaker State Changed: %s -> %s", from, to)
 		}),
@@ -63,7 +63,7 @@ func main() {
 		middleware.Logging(),
 		middleware.CircuitBreakerMiddleware(
 			middleware.WithFailureThreshold(0.5),
-			middleware.WithTimeout(5*time.Second),
+			middleware.WithOpenTimeout(5*time.Second),
 			middleware.WithInterval(10*time.Second),
 			middleware.WithMaxRequests(3),
 			middleware.WithSuccessThreshold(2),
@@ -11,6 +11,7 @@ import (
 
 	guardian "github.com/grpc-guardian/grpc-guardian"
 	"github.com/grpc-guardian/grpc-guardian/middleware"
+	"github.com/grpc-guardian/grpc-guardian/pkg/dashboard"
 	"github.com/grpc-guardian/grpc-guardian/pkg/metrics"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"google.golang.org/grpc"
@@ -55,8 +56,12 @@ func main() {
 		log.Fatalf("Failed to create metrics collector: %v", err)
 	}
 
+	// Live dashboard listing requests slower than 80ms
+	board := dashboard.New(dashboard.WithSlowThreshold(80 * time.Millisecond))
+
 	// Create middleware chain with metrics
 	chain := guardian.NewChain(
+		board.Middleware(),
 		middleware.Logging(),
 		middleware.MetricsMiddleware(collector),
 	)
@@ -79,6 +84,9 @@ func main() {
 			},
 		))
 
+		// Live guardian state as HTML and JSON
+		http.Handle("/dashboard/", http.StripPrefix("/dashboard", board.Handler()))
+
 		// Add a simple status page
 		http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 			w.Header().Set("Content-Type", "text/html")
@@ -104,6 +112,7 @@ func main() {
         <div class="section">
             <h2>📊 Prometheus Metrics</h2>
             <p>Access Prometheus metrics at: <a href="/metrics">/metrics</a></p>
+            <p>Live dashboard at: <a href="/dashboard/">/dashboard/</a> (JSON: <a href="/dashboard/state">/dashboard/state</a>)</p>
             <p>The server exposes the following metrics:</p>
             <ul>
                 <li><code>grpc_guardian_demo_requests_total</code> - Total number of requests</li>
@@ -154,6 +163,7 @@ done</code></pre>
 		log.Println("📊 Metrics server listening on :9090")
 		log.Println("   - Metrics endpoint: http://localhost:9090/metrics")
 		log.Println("   - Status page: http://localhost:9090/")
+		log.Println("   - Dashboard: http://localhost:9090/dashboard/")
 		if err := http.ListenAndServe(":9090", nil); err != nil {
 			log.Fatalf("Failed to start metrics server: %v", err)
 		}
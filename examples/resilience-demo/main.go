@@ -53,11 +53,11 @@ func main() {
 
 	// 2. Circuit Breaker - Protect against cascading failures
 	middlewares = append(middlewares, middleware.CircuitBreakerMiddleware(
-		middleware.WithFailureThreshold(0.5),   // Open after 50% failures
-		middleware.WithTimeout(30*time.Second),  // Stay open for 30 seconds
-		middleware.WithInterval(60*time.Second), // 60 second failure window
-		middleware.WithMaxRequests(5),           // Allow 5 test requests in half-open
-		middleware.WithSuccessThreshold(3),      // Need 3 successes to close
+		middleware.WithFailureThreshold(0.5),       // Open after 50% failures
+		middleware.WithOpenTimeout(30*time.Second), // Stay open for 30 seconds
+		middleware.WithInterval(60*time.Second),    // 60 second failure window
+		middleware.WithMaxRequests(5),              // Allow 5 test requests in half-open
+		middleware.WithSuccessThreshold(3),         // Need 3 successes to close
 		middleware.WithOnStateChange(func(from, to middleware.State) {
 			log.Printf("🔄 Circuit Breaker: %s -> %s", from, to)
 
@@ -35,8 +35,6 @@ func (s *UnstableService) UnaryMethod(ctx context.Context, req interface{}) (int
 }
 
 func main() {
-	rand.Seed(time.Now().UnixNano())
-
 	// Start server in background
 	go startServer()
 
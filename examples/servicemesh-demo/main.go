@@ -6,10 +6,9 @@ import (
 	"log"
 	"net"
 	"os"
-	"os/signal"
-	"syscall"
 	"time"
 
+	guardian "github.com/grpc-guardian/grpc-guardian"
 	"github.com/grpc-guardian/grpc-guardian/middleware"
 	"github.com/grpc-guardian/grpc-guardian/pkg/servicemesh"
 	"google.golang.org/grpc"
@@ -83,8 +82,16 @@ func main() {
 		log.Fatalf("Failed to create mesh middleware: %v", err)
 	}
 
-	// Create gRPC server with mesh middleware
-	server := grpc.NewServer(
+	// Graceful shutdown: NOT_SERVING, drain period, reject new requests, GracefulStop
+	healthServer := health.NewServer()
+	shutdown := guardian.NewServer(
+		guardian.WithHealthServer(healthServer),
+		guardian.WithDrainPeriod(5*time.Second),
+		guardian.WithStopTimeout(20*time.Second),
+	)
+
+	// Create gRPC server with mesh middleware; the shutdown interceptors run first
+	server := grpc.NewServer(append(shutdown.ServerOptions(),
 		grpc.ChainUnaryInterceptor(
 			middleware.Logging(),
 			meshMiddleware.UnaryServerInterceptor(),
@@ -92,13 +99,12 @@ func main() {
 		grpc.ChainStreamInterceptor(
 			meshMiddleware.StreamServerInterceptor(),
 		),
-	)
+	)...)
 
 	// Register services
 	// pb.RegisterGreeterServer(server, &greeterServer{})
 
 	// Register health check
-	healthServer := health.NewServer()
 	healthpb.RegisterHealthServer(server, healthServer)
 	healthServer.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
 
@@ -111,16 +117,6 @@ func main() {
 		log.Fatalf("Failed to listen: %v", err)
 	}
 
-	// Graceful shutdown
-	go func() {
-		sigChan := make(chan os.Signal, 1)
-		signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
-		<-sigChan
-
-		log.Println("Shutting down gracefully...")
-		server.GracefulStop()
-	}()
-
 	log.Printf("Server listening on :%s", port)
 	log.Println("Service mesh integration enabled!")
 	log.Println("\nExample requests:")
@@ -132,9 +128,11 @@ func main() {
 	log.Println("  ✓ Request logging with mesh context")
 	log.Println("  ✓ mTLS validation (if enabled)")
 
-	if err := server.Serve(lis); err != nil {
+	// Blocks until SIGINT/SIGTERM has been handled
+	if err := shutdown.Serve(server, lis); err != nil {
 		log.Fatalf("Failed to serve: %v", err)
 	}
+	log.Println("Server stopped")
 }
 
 // createIstioMiddleware creates Istio service mesh middleware
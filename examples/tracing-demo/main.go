@@ -105,9 +105,6 @@ func main() {
 
 	// Create middleware chain with tracing
 	chain := guardian.NewChain(
-		// Logging middleware (logs will include trace IDs)
-		middleware.Logging(),
-
 		// Tracing middleware - this is the key component
 		middleware.TracingWithServiceName(
 			"echo-service",
@@ -115,6 +112,9 @@ func main() {
 			middleware.WithRecordEvents(),
 		),
 
+		// Logging middleware runs inside the span, so logs include trace IDs
+		middleware.Logging(),
+
 		// Other middleware can be added here
 		middleware.Timeout(5 * time.Second),
 	)
@@ -148,7 +148,8 @@ func main() {
 	// Create client connection with tracing
 	conn, err := grpc.Dial("localhost:50051",
 		grpc.WithInsecure(),
-		grpc.WithUnaryInterceptor(tracingClientInterceptor()),
+		grpc.WithUnaryInterceptor(middleware.TracingUnaryClientInterceptor()),
+		grpc.WithStreamInterceptor(middleware.TracingStreamClientInterceptor()),
 	)
 
 	// Make RPC call
@@ -186,25 +187,3 @@ func main() {
 		log.Fatalf("Failed to serve: %v", err)
 	}
 }
-
-// Example of client-side tracing interceptor
-func tracingClientInterceptor() grpc.UnaryClientInterceptor {
-	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
-		// Inject trace context into outgoing request
-		ctx = middleware.InjectTraceContext(ctx)
-
-		// Start client span
-		ctx, span := middleware.StartSpan(ctx, method)
-		defer span.End()
-
-		// Call the actual RPC
-		err := invoker(ctx, method, req, reply, cc, opts...)
-
-		// Record error if any
-		if err != nil {
-			middleware.RecordError(ctx, err)
-		}
-
-		return err
-	}
-}
@@ -3,6 +3,7 @@ package guardian
 
 import (
 	"context"
+	"sync/atomic"
 
 	"google.golang.org/grpc"
 )
@@ -18,46 +19,57 @@ type StreamMiddleware func(srv interface{}, ss grpc.ServerStream, info *grpc.Str
 type Chain struct {
 	middlewares       []Middleware
 	streamMiddlewares []StreamMiddleware
+	configs           []namedConfig
+	entries           []chainEntry
+
+	// Per-method routing (see routing.go)
+	routes      []*methodRoute
+	routed      bool
+	methodCache *methodPlans // Compiled plan per method
+
+	plan atomic.Pointer[unaryPlan] // Compiled plan of a chain without routes
 }
 
 // NewChain creates a new middleware chain
 func NewChain(middlewares ...Middleware) *Chain {
 	return &Chain{
 		middlewares: middlewares,
+		entries:     unnamedEntries(len(middlewares)),
 	}
 }
 
 // Append adds middleware to the end of the chain
 func (c *Chain) Append(middlewares ...Middleware) *Chain {
 	c.middlewares = append(c.middlewares, middlewares...)
+	c.entries = append(c.entries, unnamedEntries(len(middlewares))...)
+	c.plan.Store(nil)
+	c.resetRoutes(0)
 	return c
 }
 
 // Prepend adds middleware to the beginning of the chain
 func (c *Chain) Prepend(middlewares ...Middleware) *Chain {
 	c.middlewares = append(middlewares, c.middlewares...)
+	c.entries = append(unnamedEntries(len(middlewares)), c.entries...)
+	c.plan.Store(nil)
+	c.resetRoutes(len(middlewares))
 	return c
 }
 
-// UnaryInterceptor returns a gRPC UnaryServerInterceptor that executes the middleware chain
+// unnamedEntries describes n unary middleware added without a builder
+func unnamedEntries(n int) []chainEntry {
+	entries := make([]chainEntry, n)
+	for i := range entries {
+		entries[i].unary = true
+	}
+	return entries
+}
+
+// UnaryInterceptor returns a gRPC UnaryServerInterceptor that executes the middleware chain.
+// The middleware that apply are resolved once per method, not on every call.
 func (c *Chain) UnaryInterceptor() grpc.UnaryServerInterceptor {
 	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
-		// Build the chain of handlers
-		currentHandler := handler
-
-		// Apply middleware in reverse order so they execute in the correct order
-		for i := len(c.middlewares) - 1; i >= 0; i-- {
-			middleware := c.middlewares[i]
-			next := currentHandler
-
-			// Wrap the handler with the middleware
-			currentHandler = func(ctx context.Context, req interface{}) (interface{}, error) {
-				return middleware(ctx, req, info, next)
-			}
-		}
-
-		// Execute the chain
-		return currentHandler(ctx, req)
+		return c.planFor(info.FullMethod).run(ctx, req, info, handler)
 	}
 }
 
@@ -93,20 +105,11 @@ func (c *Chain) ServerOption() []grpc.ServerOption {
 
 // ChainUnaryServer creates a single interceptor from multiple unary server interceptors
 func ChainUnaryServer(interceptors ...grpc.UnaryServerInterceptor) grpc.UnaryServerInterceptor {
-	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
-		currentHandler := handler
-
-		for i := len(interceptors) - 1; i >= 0; i-- {
-			interceptor := interceptors[i]
-			next := currentHandler
-
-			currentHandler = func(ctx context.Context, req interface{}) (interface{}, error) {
-				return interceptor(ctx, req, info, next)
-			}
-		}
-
-		return currentHandler(ctx, req)
+	middlewares := make([]Middleware, len(interceptors))
+	for i, interceptor := range interceptors {
+		middlewares[i] = Middleware(interceptor)
 	}
+	return compileUnary(middlewares).run
 }
 
 // ChainStreamServer creates a single interceptor from multiple stream server interceptors
@@ -0,0 +1,178 @@
+package guardian
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// tagging returns middleware appending name to the request trace on the way in
+func tagging(name string) Middleware {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		*req.(*[]string) = append(*req.(*[]string), name+":"+info.FullMethod)
+		return handler(ctx, req)
+	}
+}
+
+// echo is a final handler returning its request
+func echo(ctx context.Context, req interface{}) (interface{}, error) {
+	return req, nil
+}
+
+func invoke(interceptor grpc.UnaryServerInterceptor, method string) ([]string, error) {
+	trace := []string{}
+	_, err := interceptor(context.Background(), &trace, &grpc.UnaryServerInfo{FullMethod: method}, echo)
+	return trace, err
+}
+
+func TestChain_Order(t *testing.T) {
+	chain := NewChain(tagging("b")).Prepend(tagging("a"))
+	interceptor := chain.UnaryInterceptor()
+
+	trace, err := invoke(interceptor, "/svc.S/One")
+	if err != nil || fmt.Sprint(trace) != "[a:/svc.S/One b:/svc.S/One]" {
+		t.Errorf("Unexpected trace %v, %v", trace, err)
+	}
+
+	// Changing the chain recompiles it
+	chain.Append(tagging("c"))
+	trace, _ = invoke(interceptor, "/svc.S/Two")
+	if fmt.Sprint(trace) != "[a:/svc.S/Two b:/svc.S/Two c:/svc.S/Two]" {
+		t.Errorf("Unexpected trace after Append %v", trace)
+	}
+
+	if trace, _ := invoke(NewChain().UnaryInterceptor(), "/svc.S/One"); len(trace) != 0 {
+		t.Errorf("Expected an empty chain to call the handler directly, got %v", trace)
+	}
+}
+
+func TestChain_Routed(t *testing.T) {
+	interceptor := NewChain(tagging("all")).
+		ForService("svc.Reads", tagging("reads")).
+		UnaryInterceptor()
+
+	for i := 0; i < 2; i++ { // Second round uses the cached plans
+		if trace, _ := invoke(interceptor, "/svc.Reads/Get"); len(trace) != 2 {
+			t.Errorf("Expected both middleware, got %v", trace)
+		}
+		if trace, _ := invoke(interceptor, "/svc.Writes/Put"); len(trace) != 1 {
+			t.Errorf("Expected the routed middleware to be skipped, got %v", trace)
+		}
+	}
+
+	// Methods beyond the cache bound are routed without being cached
+	chain := NewChain(tagging("all")).ForService("svc.Reads", tagging("reads"))
+	for i := 0; i < maxMethodPlans+10; i++ {
+		chain.planFor(fmt.Sprintf("/svc.Unknown/M%d", i))
+	}
+	if size := chain.methodCache.size.Load(); size != maxMethodPlans {
+		t.Errorf("Expected %d cached plans, got %d", maxMethodPlans, size)
+	}
+	if trace, _ := invoke(chain.UnaryInterceptor(), "/svc.Reads/List"); len(trace) != 2 {
+		t.Errorf("Expected an uncached method to be routed, got %v", trace)
+	}
+}
+
+// attemptKey is a context key set by the test retry middleware
+type attemptKey struct{}
+
+func TestChain_NextCalls(t *testing.T) {
+	calls := 0
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		calls++
+		return nil, status.Error(codes.Unavailable, "down")
+	}
+	retry := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, next grpc.UnaryHandler) (interface{}, error) {
+		_, _ = next(ctx, req)
+		return next(context.WithValue(ctx, attemptKey{}, 2), req)
+	}
+	inner := NewChain(tagging("inner")).UnaryInterceptor()
+
+	interceptor := ChainUnaryServer(grpc.UnaryServerInterceptor(retry), inner)
+	trace := []string{}
+	if _, err := interceptor(context.Background(), &trace, &grpc.UnaryServerInfo{FullMethod: "/svc.S/M"}, handler); status.Code(err) != codes.Unavailable {
+		t.Errorf("Expected the handler's error, got %v", err)
+	}
+	if calls != 2 || len(trace) != 2 {
+		t.Errorf("Expected next to run the rest of the chain twice, got %d calls, trace %v", calls, trace)
+	}
+
+	// next is still usable after the call returned, e.g. from a background refresh
+	var later grpc.UnaryHandler
+	var laterCtx context.Context
+	keep := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, next grpc.UnaryHandler) (interface{}, error) {
+		later, laterCtx = next, context.WithoutCancel(ctx)
+		return next(ctx, req)
+	}
+	_, _ = NewChain(keep).UnaryInterceptor()(context.Background(), nil, &grpc.UnaryServerInfo{}, handler)
+	if _, err := later(laterCtx, nil); status.Code(err) != codes.Unavailable || calls != 4 {
+		t.Errorf("Expected the kept handler to reach the final handler, got %v", err)
+	}
+}
+
+func TestChain_FreshContext(t *testing.T) {
+	type key struct{}
+	detach := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, next grpc.UnaryHandler) (interface{}, error) {
+		return next(context.WithValue(context.Background(), key{}, "fresh"), req)
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return ctx.Value(key{}), nil
+	}
+
+	interceptor := NewChain(detach, passThrough, tagging("last")).UnaryInterceptor()
+	trace := []string{}
+	resp, err := interceptor(context.Background(), &trace, &grpc.UnaryServerInfo{FullMethod: "/svc.S/M"}, handler)
+	if err != nil || resp != "fresh" {
+		t.Errorf("Expected the fresh context to reach the handler, got %v, %v", resp, err)
+	}
+	if fmt.Sprint(trace) != "[last:/svc.S/M]" {
+		t.Errorf("Expected the rest of the chain to see the call's info, got %v", trace)
+	}
+}
+
+// passThrough is middleware doing nothing but calling next
+func passThrough(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	return handler(ctx, req)
+}
+
+func BenchmarkChain_Unary(b *testing.B) {
+	for _, n := range []int{1, 5, 10, 20} {
+		b.Run(fmt.Sprintf("middleware=%d", n), func(b *testing.B) {
+			chain := NewChain()
+			for i := 0; i < n; i++ {
+				chain.Append(passThrough)
+			}
+			benchmarkInterceptor(b, chain.UnaryInterceptor())
+		})
+	}
+}
+
+func BenchmarkChain_Routed(b *testing.B) {
+	chain := NewChain(passThrough, passThrough).
+		ForService("svc.S", passThrough, passThrough).
+		ExceptMethods("/svc.S/Other", passThrough)
+	benchmarkInterceptor(b, chain.UnaryInterceptor())
+}
+
+func BenchmarkChainUnaryServer(b *testing.B) {
+	interceptors := make([]grpc.UnaryServerInterceptor, 10)
+	for i := range interceptors {
+		interceptors[i] = passThrough
+	}
+	benchmarkInterceptor(b, ChainUnaryServer(interceptors...))
+}
+
+func benchmarkInterceptor(b *testing.B, interceptor grpc.UnaryServerInterceptor) {
+	ctx := context.Background()
+	info := &grpc.UnaryServerInfo{FullMethod: "/svc.S/Method"}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = interceptor(ctx, nil, info, echo)
+	}
+}
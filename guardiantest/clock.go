@@ -0,0 +1,134 @@
+package guardiantest
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Clock is a manually advanced clock. Pass Now to options taking a time source (e.g.
+// middleware.WithBreakerClock) and After to options taking a timer (e.g.
+// middleware.WithRetryTimer). It is safe for concurrent use.
+type Clock struct {
+	mu      sync.Mutex
+	now     time.Time
+	auto    bool
+	timers  []*timer
+	changed chan struct{} // Closed and replaced when a timer is added
+}
+
+// timer is a pending After
+type timer struct {
+	at time.Time
+	ch chan time.Time
+}
+
+// NewClock returns a clock starting at start
+func NewClock(start time.Time) *Clock {
+	return &Clock{now: start, changed: make(chan struct{})}
+}
+
+// AutoAdvance makes every After fire at once, moving the clock forward by its duration, so
+// backoff waits take no real time and Now reflects the total time waited
+func (c *Clock) AutoAdvance() *Clock {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.auto = true
+	return c
+}
+
+// Now returns the current time of the clock
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Since returns the time elapsed on the clock since t
+func (c *Clock) Since(t time.Time) time.Duration {
+	return c.Now().Sub(t)
+}
+
+// After returns a channel that receives the clock's time once it has advanced by d
+func (c *Clock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- c.now
+		return ch
+	}
+	if c.auto {
+		c.now = c.now.Add(d)
+		c.fire()
+		ch <- c.now
+		return ch
+	}
+
+	c.timers = append(c.timers, &timer{at: c.now.Add(d), ch: ch})
+	close(c.changed)
+	c.changed = make(chan struct{})
+	return ch
+}
+
+// Advance moves the clock forward by d, firing the timers that are due
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	c.fire()
+}
+
+// Set moves the clock to t, firing the timers that are due
+func (c *Clock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = t
+	c.fire()
+}
+
+// fire sends on the timers that are due, earliest first; c.mu must be held
+func (c *Clock) fire() {
+	sort.SliceStable(c.timers, func(i, j int) bool { return c.timers[i].at.Before(c.timers[j].at) })
+	pending := c.timers[:0]
+	for _, t := range c.timers {
+		if t.at.After(c.now) {
+			pending = append(pending, t)
+			continue
+		}
+		t.ch <- c.now
+	}
+	c.timers = pending
+}
+
+// Timers returns the number of pending timers
+func (c *Clock) Timers() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.timers)
+}
+
+// WaitForTimers blocks until at least n timers are pending or the real-time timeout
+// passes, and reports whether they are. Use it to advance the clock only once the code
+// under test is waiting on it, e.g. in retry backoff.
+func (c *Clock) WaitForTimers(n int, timeout time.Duration) bool {
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+
+	for {
+		c.mu.Lock()
+		if len(c.timers) >= n {
+			c.mu.Unlock()
+			return true
+		}
+		changed := c.changed
+		c.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-deadline.C:
+			return false
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package guardiantest
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	guardian "github.com/grpc-guardian/grpc-guardian"
+	"github.com/grpc-guardian/grpc-guardian/pkg/logging"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// requireTenant rejects calls without an x-tenant-id header
+func requireTenant(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if len(md.Get("x-tenant-id")) == 0 {
+		return nil, status.Error(codes.PermissionDenied, "tenant required")
+	}
+	return handler(ctx, req)
+}
+
+func TestHarness_Unary(t *testing.T) {
+	h := New(t, guardian.NewChain(requireTenant))
+	h.HandleUnary("/orders.Orders/Get", &wrapperspb.StringValue{}, Sequence(
+		Fail(codes.Unavailable, "warming up"),
+		Echo(),
+	))
+	h.HandleUnary("/orders.Orders/Delete", &wrapperspb.StringValue{}, Respond(nil))
+
+	err := h.Invoke(context.Background(), "/orders.Orders/Get", wrapperspb.String("o-1"), &wrapperspb.StringValue{})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Expected the chain to reject the call, got %v", err)
+	}
+	if calls := h.Calls("/orders.Orders/Get"); len(calls) != 0 {
+		t.Errorf("Expected the handler not to be reached, got %v", calls)
+	}
+
+	ctx := WithPeer(metadata.AppendToOutgoingContext(context.Background(), "x-tenant-id", "acme"), "10.0.0.7:4000")
+	err = h.Invoke(ctx, "/orders.Orders/Get", wrapperspb.String("o-1"), &wrapperspb.StringValue{})
+	if status.Code(err) != codes.Unavailable {
+		t.Errorf("Expected the first scripted step, got %v", err)
+	}
+	resp := &wrapperspb.StringValue{}
+	if err := h.Invoke(ctx, "/orders.Orders/Get", wrapperspb.String("o-1"), resp); err != nil || resp.GetValue() != "o-1" {
+		t.Errorf("Expected the echoed request, got %v, %v", resp, err)
+	}
+	if err := h.Invoke(ctx, "/orders.Orders/Delete", wrapperspb.String("o-1"), &wrapperspb.StringValue{}); err != nil {
+		t.Errorf("Expected an empty response to be accepted, got %v", err)
+	}
+
+	calls := h.Calls("/orders.Orders/Get")
+	if len(calls) != 2 {
+		t.Fatalf("Expected 2 calls to reach the handler, got %d", len(calls))
+	}
+	if calls[0].Peer != "10.0.0.7:4000" || calls[0].Metadata.Get("x-tenant-id")[0] != "acme" {
+		t.Errorf("Expected the simulated caller, got %+v", calls[0])
+	}
+	if len(calls[0].Metadata.Get(PeerHeader)) != 0 {
+		t.Error("Expected the peer header to be removed")
+	}
+	if calls[1].Request.(*wrapperspb.StringValue).GetValue() != "o-1" {
+		t.Errorf("Expected the recorded request, got %v", calls[1].Request)
+	}
+	if len(h.Calls("")) != 3 {
+		t.Errorf("Expected 3 calls in total, got %d", len(h.Calls("")))
+	}
+}
+
+func TestHarness_Stream(t *testing.T) {
+	var seenPeer string
+	h := New(t, nil)
+	h.HandleStream("/chat.Chat/Talk", func(srv interface{}, stream grpc.ServerStream) error {
+		if p, ok := peer.FromContext(stream.Context()); ok {
+			seenPeer = p.Addr.String()
+		}
+		for {
+			msg := &wrapperspb.StringValue{}
+			if err := stream.RecvMsg(msg); err == io.EOF {
+				return nil
+			} else if err != nil {
+				return err
+			}
+			if err := stream.SendMsg(msg); err != nil {
+				return err
+			}
+		}
+	})
+
+	ctx := WithPeer(context.Background(), "192.168.1.2:1234")
+	stream, err := h.Conn().NewStream(ctx, &grpc.StreamDesc{ServerStreams: true, ClientStreams: true}, "/chat.Chat/Talk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := stream.SendMsg(wrapperspb.String("hello")); err != nil {
+		t.Fatal(err)
+	}
+	reply := &wrapperspb.StringValue{}
+	if err := stream.RecvMsg(reply); err != nil || reply.GetValue() != "hello" {
+		t.Errorf("Expected the echoed message, got %v, %v", reply, err)
+	}
+	_ = stream.CloseSend()
+	if err := stream.RecvMsg(reply); err != io.EOF {
+		t.Errorf("Expected the stream to end, got %v", err)
+	}
+
+	if seenPeer != "192.168.1.2:1234" {
+		t.Errorf("Expected the simulated peer, got %q", seenPeer)
+	}
+}
+
+func TestIncomingContext(t *testing.T) {
+	ctx := IncomingContext(context.Background(), "10.1.1.1:80", "authorization", "Bearer x")
+	if p, ok := peer.FromContext(ctx); !ok || p.Addr.String() != "10.1.1.1:80" || p.Addr.Network() != "tcp" {
+		t.Errorf("Unexpected peer %v", p)
+	}
+	if md, _ := metadata.FromIncomingContext(ctx); md.Get("authorization")[0] != "Bearer x" {
+		t.Errorf("Unexpected metadata %v", md)
+	}
+	if p, _ := peer.FromContext(IncomingContext(context.Background(), "spiffe://prod/orders")); p.Addr.String() != "spiffe://prod/orders" {
+		t.Errorf("Expected non host:port addresses to be kept, got %v", p.Addr)
+	}
+}
+
+func TestClock(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewClock(start)
+
+	late := clock.After(2 * time.Second)
+	early := clock.After(time.Second)
+	if clock.Timers() != 2 {
+		t.Errorf("Expected 2 pending timers, got %d", clock.Timers())
+	}
+
+	clock.Advance(time.Second)
+	select {
+	case at := <-early:
+		if !at.Equal(start.Add(time.Second)) {
+			t.Errorf("Unexpected fire time %v", at)
+		}
+	default:
+		t.Error("Expected the due timer to fire")
+	}
+	select {
+	case <-late:
+		t.Error("Expected the later timer to wait")
+	default:
+	}
+
+	clock.Advance(time.Second)
+	<-late
+	if clock.Since(start) != 2*time.Second || clock.Timers() != 0 {
+		t.Errorf("Unexpected clock state: %s elapsed, %d timers", clock.Since(start), clock.Timers())
+	}
+
+	go func() { <-clock.After(time.Minute) }()
+	if !clock.WaitForTimers(1, time.Second) {
+		t.Error("Expected the waiting goroutine's timer")
+	}
+	clock.Advance(time.Minute)
+
+	auto := NewClock(start).AutoAdvance()
+	<-auto.After(3 * time.Second)
+	if auto.Since(start) != 3*time.Second {
+		t.Errorf("Expected the auto clock to advance by the wait, got %s", auto.Since(start))
+	}
+
+	delayed := Delay(clock, time.Second, Respond(wrapperspb.String("late")))
+	done := make(chan error, 1)
+	go func() {
+		_, err := delayed(context.Background(), nil)
+		done <- err
+	}()
+	clock.WaitForTimers(1, time.Second)
+	clock.Advance(time.Second)
+	if err := <-done; err != nil {
+		t.Errorf("Expected the delayed handler to answer, got %v", err)
+	}
+}
+
+func TestObservability(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	requests := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "test_requests_total"}, []string{"method", "code"})
+	registry.MustRegister(requests)
+
+	recorder := RecordSpans(t)
+	logs := NewLogs()
+
+	observe := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, span := otel.Tracer("test").Start(ctx, info.FullMethod)
+		defer span.End()
+		resp, err := handler(ctx, req)
+		requests.WithLabelValues(info.FullMethod, status.Code(err).String()).Inc()
+		logs.Info("request finished", logging.String("method", info.FullMethod))
+		return resp, err
+	}
+
+	h := New(t, guardian.NewChain(observe))
+	h.HandleUnary("/svc.S/Ok", &wrapperspb.StringValue{}, Echo())
+	h.HandleUnary("/svc.S/Fail", &wrapperspb.StringValue{}, Fail(codes.Internal, "boom"))
+	for i := 0; i < 2; i++ {
+		_ = h.Invoke(context.Background(), "/svc.S/Ok", wrapperspb.String("x"), &wrapperspb.StringValue{})
+	}
+	_ = h.Invoke(context.Background(), "/svc.S/Fail", wrapperspb.String("x"), &wrapperspb.StringValue{})
+
+	if got := MetricValue(t, registry, "test_requests_total", map[string]string{"method": "/svc.S/Ok"}); got != 2 {
+		t.Errorf("Expected 2 successful requests, got %v", got)
+	}
+	if got := MetricValue(t, registry, "test_requests_total", nil); got != 3 {
+		t.Errorf("Expected 3 requests in total, got %v", got)
+	}
+	if got := MetricValue(t, registry, "test_requests_total", map[string]string{"code": "NotFound"}); got != 0 {
+		t.Errorf("Expected no NotFound requests, got %v", got)
+	}
+
+	if names := SpanNames(recorder); len(names) != 3 || names[2] != "/svc.S/Fail" {
+		t.Errorf("Unexpected spans %v", names)
+	}
+
+	entries := logs.Find("request finished")
+	if len(entries) != 3 || entries[0].Level != "info" || entries[0].Fields["method"] != "/svc.S/Ok" {
+		t.Errorf("Unexpected log entries %+v", entries)
+	}
+}
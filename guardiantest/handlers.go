@@ -0,0 +1,81 @@
+package guardiantest
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// Handler is a scripted unary handler
+type Handler func(ctx context.Context, req proto.Message) (proto.Message, error)
+
+// Respond returns a handler answering every call with resp
+func Respond(resp proto.Message) Handler {
+	return func(ctx context.Context, req proto.Message) (proto.Message, error) {
+		return resp, nil
+	}
+}
+
+// Fail returns a handler failing every call with a status
+func Fail(code codes.Code, msg string) Handler {
+	return func(ctx context.Context, req proto.Message) (proto.Message, error) {
+		return nil, status.Error(code, msg)
+	}
+}
+
+// Echo returns a handler answering every call with its request
+func Echo() Handler {
+	return func(ctx context.Context, req proto.Message) (proto.Message, error) {
+		return req, nil
+	}
+}
+
+// Sequence returns a handler answering the n-th call with the n-th step; the last step
+// answers every call after it. For example, two failures and then a success exercise retry:
+//
+//	guardiantest.Sequence(
+//	    guardiantest.Fail(codes.Unavailable, "down"),
+//	    guardiantest.Fail(codes.Unavailable, "down"),
+//	    guardiantest.Respond(&pb.Order{Id: "o-1"}),
+//	)
+func Sequence(steps ...Handler) Handler {
+	var (
+		mu   sync.Mutex
+		next int
+	)
+	return func(ctx context.Context, req proto.Message) (proto.Message, error) {
+		mu.Lock()
+		step := steps[next]
+		if next < len(steps)-1 {
+			next++
+		}
+		mu.Unlock()
+		return step(ctx, req)
+	}
+}
+
+// Delay returns a handler that waits for d on clock, or until the call is cancelled,
+// before calling next. A nil clock waits in real time.
+func Delay(clock *Clock, d time.Duration, next Handler) Handler {
+	return func(ctx context.Context, req proto.Message) (proto.Message, error) {
+		var wait <-chan time.Time
+		if clock != nil {
+			wait = clock.After(d)
+		} else {
+			timer := time.NewTimer(d)
+			defer timer.Stop()
+			wait = timer.C
+		}
+
+		select {
+		case <-wait:
+			return next(ctx, req)
+		case <-ctx.Done():
+			return nil, status.FromContextError(ctx.Err()).Err()
+		}
+	}
+}
@@ -0,0 +1,325 @@
+// Package guardiantest runs guardian middleware chains in memory for unit tests. A Harness
+// serves scripted handlers behind a Chain over bufconn, so calls pass through real gRPC
+// encoding, metadata and status handling without opening a port. Helpers simulate callers
+// (metadata and peer addresses), capture metrics, spans and logs, and provide a fake Clock
+// for time-dependent middleware such as retry backoff and circuit breakers.
+package guardiantest
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"strings"
+	"sync"
+	"testing"
+
+	guardian "github.com/grpc-guardian/grpc-guardian"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// PeerHeader carries the simulated peer address set by WithPeer
+const PeerHeader = "x-guardiantest-peer"
+
+// Call is a call that reached a scripted handler
+type Call struct {
+	Method   string
+	Metadata metadata.MD
+	Peer     string
+	Request  proto.Message // nil for streams
+}
+
+// Harness serves scripted handlers behind a middleware chain
+type Harness struct {
+	t          testing.TB
+	chain      *guardian.Chain
+	serverOpts []grpc.ServerOption
+	dialOpts   []grpc.DialOption
+	services   map[string]*grpc.ServiceDesc
+
+	mu    sync.Mutex
+	calls []Call
+
+	start  sync.Once
+	server *grpc.Server
+	conn   *grpc.ClientConn
+}
+
+// Option configures a Harness
+type Option func(*Harness)
+
+// WithServerOptions adds gRPC server options, e.g. a stats handler
+func WithServerOptions(opts ...grpc.ServerOption) Option {
+	return func(h *Harness) {
+		h.serverOpts = append(h.serverOpts, opts...)
+	}
+}
+
+// WithDialOptions adds gRPC dial options to the client connection
+func WithDialOptions(opts ...grpc.DialOption) Option {
+	return func(h *Harness) {
+		h.dialOpts = append(h.dialOpts, opts...)
+	}
+}
+
+// WithClientChain installs client middleware on the client connection, e.g. retry and
+// circuit breakers under test
+func WithClientChain(chain *guardian.ClientChain) Option {
+	return func(h *Harness) {
+		h.dialOpts = append(h.dialOpts, chain.DialOptions()...)
+	}
+}
+
+// New creates a harness serving through chain; a nil chain serves without middleware.
+// Register handlers, then call Conn or Invoke, which start the server. Everything is
+// stopped when the test ends.
+//
+// Example usage:
+//
+//	h := guardiantest.New(t, guardian.NewChain(middleware.Auth(validator)))
+//	h.HandleUnary("/orders.Orders/Get", &pb.GetOrderRequest{}, guardiantest.Respond(&pb.Order{Id: "o-1"}))
+//
+//	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer bad")
+//	err := h.Invoke(ctx, "/orders.Orders/Get", &pb.GetOrderRequest{Id: "o-1"}, &pb.Order{})
+//	if status.Code(err) != codes.Unauthenticated {
+//	    t.Errorf("Expected Unauthenticated, got %v", err)
+//	}
+//	if len(h.Calls("/orders.Orders/Get")) != 0 {
+//	    t.Error("Expected the handler not to be called")
+//	}
+func New(t testing.TB, chain *guardian.Chain, opts ...Option) *Harness {
+	h := &Harness{
+		t:        t,
+		chain:    chain,
+		services: make(map[string]*grpc.ServiceDesc),
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// service returns the descriptor of the service of a method, creating it if needed
+func (h *Harness) service(method string) (*grpc.ServiceDesc, string) {
+	h.t.Helper()
+
+	if h.server != nil {
+		h.t.Fatalf("guardiantest: handler for %s registered after the harness started", method)
+	}
+	name, methodName, ok := strings.Cut(strings.TrimPrefix(method, "/"), "/")
+	if !ok || name == "" || methodName == "" {
+		h.t.Fatalf("guardiantest: invalid method %q, want /pkg.Service/Method", method)
+	}
+
+	desc, ok := h.services[name]
+	if !ok {
+		desc = &grpc.ServiceDesc{ServiceName: name, HandlerType: (*interface{})(nil)}
+		h.services[name] = desc
+	}
+	return desc, methodName
+}
+
+// HandleUnary serves a unary method. request is an instance of the request type; its
+// contents are ignored.
+func (h *Harness) HandleUnary(method string, request proto.Message, handler Handler) {
+	h.t.Helper()
+
+	desc, name := h.service(method)
+	desc.Methods = append(desc.Methods, grpc.MethodDesc{
+		MethodName: name,
+		Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+			req := request.ProtoReflect().New().Interface()
+			if err := dec(req); err != nil {
+				return nil, err
+			}
+			run := func(ctx context.Context, req interface{}) (interface{}, error) {
+				msg := req.(proto.Message)
+				h.record(ctx, method, msg)
+				resp, err := handler(ctx, msg)
+				if err == nil && resp == nil {
+					// An empty message decodes as any response type
+					resp = &emptypb.Empty{}
+				}
+				return resp, err
+			}
+			if interceptor == nil {
+				return run(ctx, req)
+			}
+			return interceptor(ctx, req, &grpc.UnaryServerInfo{Server: srv, FullMethod: method}, run)
+		},
+	})
+}
+
+// HandleStream serves a streaming method, in both directions
+func (h *Harness) HandleStream(method string, handler grpc.StreamHandler) {
+	h.t.Helper()
+
+	desc, name := h.service(method)
+	desc.Streams = append(desc.Streams, grpc.StreamDesc{
+		StreamName: name,
+		Handler: func(srv interface{}, stream grpc.ServerStream) error {
+			h.record(stream.Context(), method, nil)
+			return handler(srv, stream)
+		},
+		ServerStreams: true,
+		ClientStreams: true,
+	})
+}
+
+// record remembers a call that reached a handler
+func (h *Harness) record(ctx context.Context, method string, req proto.Message) {
+	call := Call{Method: method, Request: req}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		call.Metadata = md.Copy()
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		call.Peer = p.Addr.String()
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.calls = append(h.calls, call)
+}
+
+// Calls returns the calls that reached the handler of a method, oldest first; an empty
+// method returns every call
+func (h *Harness) Calls(method string) []Call {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var calls []Call
+	for _, call := range h.calls {
+		if method == "" || call.Method == method {
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+// Conn returns a client connection to the harness, starting it on first use
+func (h *Harness) Conn() *grpc.ClientConn {
+	h.t.Helper()
+	h.start.Do(h.serve)
+	if h.conn == nil {
+		h.t.FailNow()
+	}
+	return h.conn
+}
+
+// Invoke sends a unary call through the client connection
+func (h *Harness) Invoke(ctx context.Context, method string, req, resp proto.Message) error {
+	h.t.Helper()
+	return h.Conn().Invoke(ctx, method, req, resp)
+}
+
+// serve starts the server and dials it
+func (h *Harness) serve() {
+	h.t.Helper()
+
+	unary := []grpc.UnaryServerInterceptor{simulatePeerUnary}
+	stream := []grpc.StreamServerInterceptor{simulatePeerStream}
+	if h.chain != nil {
+		unary = append(unary, h.chain.UnaryInterceptor())
+		stream = append(stream, h.chain.StreamInterceptor())
+	}
+	opts := append([]grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(unary...),
+		grpc.ChainStreamInterceptor(stream...),
+	}, h.serverOpts...)
+
+	h.server = grpc.NewServer(opts...)
+	for _, desc := range h.services {
+		h.server.RegisterService(desc, struct{}{})
+	}
+
+	lis := bufconn.Listen(1 << 20)
+	go func() { _ = h.server.Serve(lis) }()
+
+	dialOpts := append([]grpc.DialOption{
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	}, h.dialOpts...)
+	conn, err := grpc.Dial("bufnet", dialOpts...)
+	if err != nil {
+		h.server.Stop()
+		h.t.Errorf("guardiantest: dial failed: %v", err)
+		return
+	}
+	h.conn = conn
+
+	h.t.Cleanup(func() {
+		_ = conn.Close()
+		h.server.Stop()
+	})
+}
+
+// WithPeer makes a call through a Harness appear to come from addr, e.g. "10.0.0.1:5000"
+func WithPeer(ctx context.Context, addr string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, PeerHeader, addr)
+}
+
+// simulatePeerUnary applies the peer set by WithPeer
+func simulatePeerUnary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	return handler(simulatePeer(ctx), req)
+}
+
+// simulatePeerStream applies the peer set by WithPeer
+func simulatePeerStream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx := simulatePeer(ss.Context())
+	if ctx == ss.Context() {
+		return handler(srv, ss)
+	}
+	return handler(srv, &peerServerStream{ServerStream: ss, ctx: ctx})
+}
+
+// peerServerStream overrides the context of a stream
+type peerServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *peerServerStream) Context() context.Context {
+	return s.ctx
+}
+
+// simulatePeer replaces the peer of ctx with the one in PeerHeader and removes the header
+func simulatePeer(ctx context.Context) context.Context {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok || len(md.Get(PeerHeader)) == 0 {
+		return ctx
+	}
+	addr := md.Get(PeerHeader)[0]
+	md = md.Copy()
+	delete(md, PeerHeader)
+	ctx = metadata.NewIncomingContext(ctx, md)
+	return peer.NewContext(ctx, &peer.Peer{Addr: parseAddr(addr)})
+}
+
+// IncomingContext returns a server-side context for calling interceptors directly, with a
+// peer address and incoming metadata given as key/value pairs
+func IncomingContext(ctx context.Context, peerAddr string, kv ...string) context.Context {
+	ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(kv...))
+	if peerAddr != "" {
+		ctx = peer.NewContext(ctx, &peer.Peer{Addr: parseAddr(peerAddr)})
+	}
+	return ctx
+}
+
+// parseAddr parses "host:port" into a TCP address, keeping other forms as they are
+func parseAddr(addr string) net.Addr {
+	if ap, err := netip.ParseAddrPort(addr); err == nil {
+		return net.TCPAddrFromAddrPort(ap)
+	}
+	return textAddr(addr)
+}
+
+// textAddr is an address that is not host:port
+type textAddr string
+
+func (a textAddr) Network() string { return "guardiantest" }
+func (a textAddr) String() string  { return string(a) }
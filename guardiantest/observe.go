@@ -0,0 +1,151 @@
+package guardiantest
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/grpc-guardian/grpc-guardian/pkg/logging"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// MetricValue returns the sum of the series of a metric whose labels include labels: the
+// value of counters and gauges, the sample count of histograms and summaries. A metric
+// without matching series is 0, as counters of a vector only appear once incremented.
+func MetricValue(t testing.TB, gatherer prometheus.Gatherer, name string, labels map[string]string) float64 {
+	t.Helper()
+
+	families, err := gatherer.Gather()
+	if err != nil {
+		t.Fatalf("guardiantest: gathering metrics failed: %v", err)
+	}
+
+	var sum float64
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			if !hasLabels(metric, labels) {
+				continue
+			}
+			switch family.GetType() {
+			case dto.MetricType_COUNTER:
+				sum += metric.GetCounter().GetValue()
+			case dto.MetricType_GAUGE:
+				sum += metric.GetGauge().GetValue()
+			case dto.MetricType_HISTOGRAM:
+				sum += float64(metric.GetHistogram().GetSampleCount())
+			case dto.MetricType_SUMMARY:
+				sum += float64(metric.GetSummary().GetSampleCount())
+			case dto.MetricType_UNTYPED:
+				sum += metric.GetUntyped().GetValue()
+			}
+		}
+	}
+	return sum
+}
+
+// hasLabels reports whether a series has every label
+func hasLabels(metric *dto.Metric, labels map[string]string) bool {
+	for name, value := range labels {
+		found := false
+		for _, pair := range metric.GetLabel() {
+			if pair.GetName() == name && pair.GetValue() == value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// RecordSpans installs a span recorder as the global tracer provider for the rest of the
+// test, restoring the previous provider afterwards. Tests using it must not run in
+// parallel with other tests that trace.
+func RecordSpans(t testing.TB) *tracetest.SpanRecorder {
+	t.Helper()
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previous)
+		_ = provider.Shutdown(context.Background())
+	})
+	return recorder
+}
+
+// SpanNames returns the names of the finished spans, in the order they ended
+func SpanNames(recorder *tracetest.SpanRecorder) []string {
+	var names []string
+	for _, span := range recorder.Ended() {
+		names = append(names, span.Name())
+	}
+	return names
+}
+
+// LogEntry is a captured log entry
+type LogEntry struct {
+	Level   string // "debug", "info", "warn" or "error"
+	Message string
+	Fields  map[string]interface{}
+}
+
+// Logs is a logging.Logger that captures entries, for middleware taking a structured
+// logger (e.g. middleware.WithStructuredLogger). It is safe for concurrent use.
+type Logs struct {
+	mu      sync.Mutex
+	entries []LogEntry
+}
+
+var _ logging.Logger = (*Logs)(nil)
+
+// NewLogs returns an empty log capture
+func NewLogs() *Logs {
+	return &Logs{}
+}
+
+func (l *Logs) Debug(msg string, fields ...logging.Field) { l.add("debug", msg, fields) }
+func (l *Logs) Info(msg string, fields ...logging.Field)  { l.add("info", msg, fields) }
+func (l *Logs) Warn(msg string, fields ...logging.Field)  { l.add("warn", msg, fields) }
+func (l *Logs) Error(msg string, fields ...logging.Field) { l.add("error", msg, fields) }
+
+// add captures an entry
+func (l *Logs) add(level, msg string, fields []logging.Field) {
+	entry := LogEntry{Level: level, Message: msg, Fields: make(map[string]interface{}, len(fields))}
+	for _, field := range fields {
+		entry.Fields[field.Key] = field.Value
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = append(l.entries, entry)
+}
+
+// Entries returns the captured entries, oldest first
+func (l *Logs) Entries() []LogEntry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]LogEntry(nil), l.entries...)
+}
+
+// Find returns the captured entries with a message
+func (l *Logs) Find(msg string) []LogEntry {
+	var found []LogEntry
+	for _, entry := range l.Entries() {
+		if entry.Message == msg {
+			found = append(found, entry)
+		}
+	}
+	return found
+}
@@ -0,0 +1,230 @@
+package guardian
+
+import (
+	"fmt"
+
+	"google.golang.org/grpc"
+)
+
+// ChainServerOptions returns gRPC ServerOptions that register the chain with
+// grpc.ChainUnaryInterceptor and grpc.ChainStreamInterceptor. Unlike ServerOption,
+// they can be combined with other chained interceptors on the same server; the
+// chain runs at the position its options appear in grpc.NewServer.
+//
+// Example usage:
+//
+//	server := grpc.NewServer(
+//	    grpc.ChainUnaryInterceptor(otelgrpc.UnaryServerInterceptor()),
+//	    chain.ChainServerOptions()...,
+//	)
+func (c *Chain) ChainServerOptions() []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(c.UnaryInterceptor()),
+		grpc.ChainStreamInterceptor(c.StreamInterceptor()),
+	}
+}
+
+// interceptorPosition describes where an interceptor is placed relative to an anchor
+type interceptorPosition int
+
+const (
+	positionEnd interceptorPosition = iota
+	positionBefore
+	positionAfter
+)
+
+// namedInterceptor is a registered interceptor pair with its placement
+type namedInterceptor struct {
+	name     string
+	unary    grpc.UnaryServerInterceptor
+	stream   grpc.StreamServerInterceptor
+	position interceptorPosition
+	anchor   string
+}
+
+// InterceptorOrder arranges guardian chains and third-party interceptors into a single
+// ordered list for grpc.ChainUnaryInterceptor / grpc.ChainStreamInterceptor. Entries are
+// named so others can be positioned before or after them, regardless of registration order.
+// Either interceptor of a pair may be nil when a component has no unary or stream variant.
+type InterceptorOrder struct {
+	entries []namedInterceptor
+}
+
+// NewInterceptorOrder creates an empty interceptor order
+func NewInterceptorOrder() *InterceptorOrder {
+	return &InterceptorOrder{}
+}
+
+// Add appends named interceptors to the end of the order
+func (o *InterceptorOrder) Add(name string, unary grpc.UnaryServerInterceptor, stream grpc.StreamServerInterceptor) *InterceptorOrder {
+	return o.add(namedInterceptor{name: name, unary: unary, stream: stream, position: positionEnd})
+}
+
+// AddChain appends a guardian chain to the end of the order
+func (o *InterceptorOrder) AddChain(name string, chain *Chain) *InterceptorOrder {
+	return o.Add(name, chain.UnaryInterceptor(), chain.StreamInterceptor())
+}
+
+// Before places named interceptors immediately before the anchor, so they run first
+func (o *InterceptorOrder) Before(anchor, name string, unary grpc.UnaryServerInterceptor, stream grpc.StreamServerInterceptor) *InterceptorOrder {
+	return o.add(namedInterceptor{name: name, unary: unary, stream: stream, position: positionBefore, anchor: anchor})
+}
+
+// After places named interceptors immediately after the anchor, so they run later
+func (o *InterceptorOrder) After(anchor, name string, unary grpc.UnaryServerInterceptor, stream grpc.StreamServerInterceptor) *InterceptorOrder {
+	return o.add(namedInterceptor{name: name, unary: unary, stream: stream, position: positionAfter, anchor: anchor})
+}
+
+// add records an entry; placement is resolved when the order is built
+func (o *InterceptorOrder) add(entry namedInterceptor) *InterceptorOrder {
+	o.entries = append(o.entries, entry)
+	return o
+}
+
+// Names returns the interceptor names in execution order
+func (o *InterceptorOrder) Names() ([]string, error) {
+	resolved, err := o.resolve()
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, len(resolved))
+	for i, entry := range resolved {
+		names[i] = entry.name
+	}
+	return names, nil
+}
+
+// UnaryInterceptors returns the unary interceptors in execution order
+func (o *InterceptorOrder) UnaryInterceptors() ([]grpc.UnaryServerInterceptor, error) {
+	resolved, err := o.resolve()
+	if err != nil {
+		return nil, err
+	}
+
+	var interceptors []grpc.UnaryServerInterceptor
+	for _, entry := range resolved {
+		if entry.unary != nil {
+			interceptors = append(interceptors, entry.unary)
+		}
+	}
+	return interceptors, nil
+}
+
+// StreamInterceptors returns the stream interceptors in execution order
+func (o *InterceptorOrder) StreamInterceptors() ([]grpc.StreamServerInterceptor, error) {
+	resolved, err := o.resolve()
+	if err != nil {
+		return nil, err
+	}
+
+	var interceptors []grpc.StreamServerInterceptor
+	for _, entry := range resolved {
+		if entry.stream != nil {
+			interceptors = append(interceptors, entry.stream)
+		}
+	}
+	return interceptors, nil
+}
+
+// ServerOptions resolves the order and returns grpc.ChainUnaryInterceptor and
+// grpc.ChainStreamInterceptor options for grpc.NewServer.
+// It fails if a name is registered twice or an anchor does not exist.
+//
+// Example usage:
+//
+//	order := guardian.NewInterceptorOrder().
+//	    Add("otel", otelgrpc.UnaryServerInterceptor(), otelgrpc.StreamServerInterceptor()).
+//	    AddChain("guardian", chain).
+//	    Before("guardian", "recovery", recovery.UnaryServerInterceptor(), recovery.StreamServerInterceptor()).
+//	    After("guardian", "validator", validator.UnaryServerInterceptor(), nil)
+//
+//	opts, err := order.ServerOptions()
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	server := grpc.NewServer(opts...)
+func (o *InterceptorOrder) ServerOptions() ([]grpc.ServerOption, error) {
+	unary, err := o.UnaryInterceptors()
+	if err != nil {
+		return nil, err
+	}
+	stream, err := o.StreamInterceptors()
+	if err != nil {
+		return nil, err
+	}
+
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(unary...),
+		grpc.ChainStreamInterceptor(stream...),
+	}, nil
+}
+
+// resolve computes the final order. Appended entries keep their registration order;
+// positioned entries are then placed next to their anchors, which may themselves be
+// positioned entries. An entry moves together with everything positioned relative to it,
+// and several entries anchored on the same side of one anchor keep their registration
+// order, so the result does not depend on the order constraints were registered in.
+func (o *InterceptorOrder) resolve() ([]namedInterceptor, error) {
+	seen := make(map[string]bool, len(o.entries))
+	before := make(map[string][]namedInterceptor)
+	after := make(map[string][]namedInterceptor)
+	var roots []namedInterceptor
+
+	for _, entry := range o.entries {
+		if entry.name == "" {
+			return nil, fmt.Errorf("guardian: interceptor name must not be empty")
+		}
+		if seen[entry.name] {
+			return nil, fmt.Errorf("guardian: interceptor %q registered twice", entry.name)
+		}
+		seen[entry.name] = true
+
+		switch entry.position {
+		case positionBefore:
+			before[entry.anchor] = append(before[entry.anchor], entry)
+		case positionAfter:
+			after[entry.anchor] = append(after[entry.anchor], entry)
+		default:
+			roots = append(roots, entry)
+		}
+	}
+
+	resolved := make([]namedInterceptor, 0, len(o.entries))
+	var place func(entry namedInterceptor)
+	place = func(entry namedInterceptor) {
+		for _, b := range before[entry.name] {
+			place(b)
+		}
+		resolved = append(resolved, entry)
+		for _, a := range after[entry.name] {
+			place(a)
+		}
+	}
+	for _, entry := range roots {
+		place(entry)
+	}
+
+	// Entries not reached from an appended entry have an unknown anchor or form a cycle
+	if len(resolved) < len(o.entries) {
+		placed := make(map[string]bool, len(resolved))
+		for _, entry := range resolved {
+			placed[entry.name] = true
+		}
+		var cyclic string
+		for _, entry := range o.entries {
+			if placed[entry.name] {
+				continue
+			}
+			if !seen[entry.anchor] {
+				return nil, fmt.Errorf("guardian: interceptor %q is anchored to unknown interceptor %q", entry.name, entry.anchor)
+			}
+			if cyclic == "" {
+				cyclic = entry.name
+			}
+		}
+		return nil, fmt.Errorf("guardian: interceptor %q has a circular position constraint", cyclic)
+	}
+
+	return resolved, nil
+}
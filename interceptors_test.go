@@ -0,0 +1,175 @@
+package guardian_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	guardian "github.com/grpc-guardian/grpc-guardian"
+	"github.com/grpc-guardian/grpc-guardian/guardiantest"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// recorder collects the names of the interceptors a call passed through
+type recorder struct {
+	mu    sync.Mutex
+	trace []string
+}
+
+func (r *recorder) unary(name string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		r.mu.Lock()
+		r.trace = append(r.trace, name)
+		r.mu.Unlock()
+		return handler(ctx, req)
+	}
+}
+
+func (r *recorder) String() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return fmt.Sprint(r.trace)
+}
+
+// invoke serves one unary method with the server options and calls it
+func invoke(t *testing.T, opts ...grpc.ServerOption) {
+	t.Helper()
+
+	h := guardiantest.New(t, nil, guardiantest.WithServerOptions(opts...))
+	h.HandleUnary("/svc.S/Get", &wrapperspb.StringValue{}, guardiantest.Echo())
+	if err := h.Invoke(context.Background(), "/svc.S/Get", wrapperspb.String("x"), &wrapperspb.StringValue{}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestChainServerOptions(t *testing.T) {
+	r := &recorder{}
+	chain := guardian.NewChain(guardian.Middleware(r.unary("guardian")))
+
+	opts := []grpc.ServerOption{grpc.ChainUnaryInterceptor(r.unary("first"))}
+	opts = append(opts, chain.ChainServerOptions()...)
+	opts = append(opts, grpc.ChainUnaryInterceptor(r.unary("last")))
+	invoke(t, opts...)
+
+	if r.String() != "[first guardian last]" {
+		t.Errorf("Expected the chain to run where its options appear, got %s", r)
+	}
+}
+
+func TestInterceptorOrder_Names(t *testing.T) {
+	tests := []struct {
+		name  string
+		build func(o *guardian.InterceptorOrder)
+		want  string
+	}{
+		{
+			name: "appended",
+			build: func(o *guardian.InterceptorOrder) {
+				o.Add("a", nil, nil).Add("b", nil, nil)
+			},
+			want: "[a b]",
+		},
+		{
+			name: "before and after keep registration order",
+			build: func(o *guardian.InterceptorOrder) {
+				o.Add("g", nil, nil).
+					Before("g", "b1", nil, nil).Before("g", "b2", nil, nil).
+					After("g", "a1", nil, nil).After("g", "a2", nil, nil)
+			},
+			want: "[b1 b2 g a1 a2]",
+		},
+		{
+			name: "anchored before its anchor is registered",
+			build: func(o *guardian.InterceptorOrder) {
+				o.After("x", "y", nil, nil).Before("a", "x", nil, nil).Add("a", nil, nil)
+			},
+			want: "[x y a]",
+		},
+		{
+			name: "nested anchors",
+			build: func(o *guardian.InterceptorOrder) {
+				o.Add("a", nil, nil).Add("z", nil, nil).
+					After("a", "x", nil, nil).After("x", "y", nil, nil).After("a", "w", nil, nil)
+			},
+			want: "[a x y w z]",
+		},
+		{
+			name: "nested anchors registered in another order",
+			build: func(o *guardian.InterceptorOrder) {
+				o.After("x", "y", nil, nil).Add("a", nil, nil).After("a", "x", nil, nil).
+					After("a", "w", nil, nil).Add("z", nil, nil)
+			},
+			want: "[a x y w z]",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order := guardian.NewInterceptorOrder()
+			tt.build(order)
+			names, err := order.Names()
+			if err != nil || fmt.Sprint(names) != tt.want {
+				t.Errorf("Names() = %v, %v, want %s", names, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestInterceptorOrder_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		build   func(o *guardian.InterceptorOrder)
+		wantErr string
+	}{
+		{"empty name", func(o *guardian.InterceptorOrder) { o.Add("", nil, nil) }, "must not be empty"},
+		{"duplicate", func(o *guardian.InterceptorOrder) { o.Add("a", nil, nil).After("a", "a", nil, nil) }, `"a" registered twice`},
+		{"unknown anchor", func(o *guardian.InterceptorOrder) { o.Add("a", nil, nil).After("missing", "b", nil, nil) }, `unknown interceptor "missing"`},
+		{"cycle", func(o *guardian.InterceptorOrder) {
+			o.Add("a", nil, nil).After("c", "b", nil, nil).Before("b", "c", nil, nil)
+		}, "circular position constraint"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order := guardian.NewInterceptorOrder()
+			tt.build(order)
+			if _, err := order.ServerOptions(); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ServerOptions() error = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestInterceptorOrder_ServerOptions(t *testing.T) {
+	r := &recorder{}
+	chain := guardian.NewChain(guardian.Middleware(r.unary("guardian")))
+
+	order := guardian.NewInterceptorOrder().
+		Add("otel", r.unary("otel"), nil).
+		AddChain("guardian", chain).
+		After("guardian", "validator", r.unary("validator"), nil).
+		Before("guardian", "recovery", r.unary("recovery"), nil).
+		Add("stream-only", nil, func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			return handler(srv, ss)
+		})
+
+	unary, err := order.UnaryInterceptors()
+	if err != nil || len(unary) != 4 {
+		t.Fatalf("Expected 4 unary interceptors, got %d, %v", len(unary), err)
+	}
+	if stream, _ := order.StreamInterceptors(); len(stream) != 2 {
+		t.Errorf("Expected the chain's and the stream-only interceptors, got %d", len(stream))
+	}
+
+	opts, err := order.ServerOptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	invoke(t, opts...)
+	if r.String() != "[otel recovery guardian validator]" {
+		t.Errorf("Unexpected execution order %s", r)
+	}
+}
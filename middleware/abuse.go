@@ -0,0 +1,420 @@
+package middleware
+
+import (
+	"context"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Ban is a client temporarily blocked by an AbuseDetector
+type Ban struct {
+	Client string    `json:"client"`
+	Reason string    `json:"reason"`
+	Since  time.Time `json:"since"`
+	Until  time.Time `json:"until"`
+}
+
+// AbuseDetector tracks request, error and connection rates per client and bans clients
+// that misbehave for a cooldown period. Banned clients get PermissionDenied. Unlike
+// per-client rate limiting, which only caps QPS, it also catches clients that keep
+// failing authentication, sending invalid requests or hammering rate limits.
+type AbuseDetector struct {
+	window         time.Duration
+	banDuration    time.Duration
+	maxRequests    int
+	maxConnections int
+	errorRate      float64
+	minRequests    int
+	errorCodes     map[codes.Code]bool
+	allowlist      map[string]bool
+	extractor      func(context.Context) string
+	onBan          func(Ban)
+
+	mu        sync.Mutex
+	clients   map[string]*abuseClient
+	bans      map[string]Ban
+	lastSweep time.Time
+}
+
+// abuseClient counts the activity of a client in the current window
+type abuseClient struct {
+	start       time.Time
+	requests    int
+	errors      int
+	connections int
+}
+
+// AbuseOption configures an AbuseDetector
+type AbuseOption func(*AbuseDetector)
+
+// WithAbuseWindow sets the window rates are measured over
+// Default: 1m
+func WithAbuseWindow(d time.Duration) AbuseOption {
+	return func(a *AbuseDetector) {
+		if d > 0 {
+			a.window = d
+		}
+	}
+}
+
+// WithBanDuration sets how long abusive clients are banned
+// Default: 5m
+func WithBanDuration(d time.Duration) AbuseOption {
+	return func(a *AbuseDetector) {
+		if d > 0 {
+			a.banDuration = d
+		}
+	}
+}
+
+// WithAbuseMaxRequests bans clients sending more than n requests per window; 0 disables
+// the check
+// Default: 0
+func WithAbuseMaxRequests(n int) AbuseOption {
+	return func(a *AbuseDetector) {
+		a.maxRequests = n
+	}
+}
+
+// WithAbuseMaxConnections bans clients opening more than n connections per window through
+// Listener; 0 disables the check
+// Default: 0
+func WithAbuseMaxConnections(n int) AbuseOption {
+	return func(a *AbuseDetector) {
+		a.maxConnections = n
+	}
+}
+
+// WithAbuseErrorRate bans clients whose share of failed requests reaches rate once they
+// sent at least minRequests in the window
+// Default: 0.5 after 20 requests
+func WithAbuseErrorRate(rate float64, minRequests int) AbuseOption {
+	return func(a *AbuseDetector) {
+		if rate > 0 && rate <= 1 && minRequests > 0 {
+			a.errorRate = rate
+			a.minRequests = minRequests
+		}
+	}
+}
+
+// WithAbuseErrorCodes sets the status codes that count as client misbehavior
+// Default: Unauthenticated, PermissionDenied, InvalidArgument, ResourceExhausted
+func WithAbuseErrorCodes(errorCodes ...codes.Code) AbuseOption {
+	return func(a *AbuseDetector) {
+		a.errorCodes = make(map[codes.Code]bool, len(errorCodes))
+		for _, code := range errorCodes {
+			a.errorCodes[code] = true
+		}
+	}
+}
+
+// WithAbuseAllowlist sets clients that are never banned, as returned by the client
+// extractor (e.g. "ip:10.0.0.5" or "user:monitoring")
+func WithAbuseAllowlist(clients ...string) AbuseOption {
+	return func(a *AbuseDetector) {
+		for _, client := range clients {
+			a.allowlist[client] = true
+		}
+	}
+}
+
+// WithAbuseClientExtractor sets how clients are identified
+// Default: ExtractStreamClient
+func WithAbuseClientExtractor(extractor func(context.Context) string) AbuseOption {
+	return func(a *AbuseDetector) {
+		if extractor != nil {
+			a.extractor = extractor
+		}
+	}
+}
+
+// WithOnBan sets a callback invoked when a client is banned automatically
+func WithOnBan(fn func(Ban)) AbuseOption {
+	return func(a *AbuseDetector) {
+		a.onBan = fn
+	}
+}
+
+// NewAbuseDetector creates an abuse detector. Place its interceptor before authentication
+// so failed logins are counted; clients are then identified by peer IP.
+//
+// Example usage:
+//
+//	abuse := middleware.NewAbuseDetector(
+//	    middleware.WithAbuseErrorRate(0.5, 20),
+//	    middleware.WithAbuseMaxRequests(6000),
+//	    middleware.WithBanDuration(10*time.Minute),
+//	    middleware.WithOnBan(func(ban middleware.Ban) { log.Printf("banned %s: %s", ban.Client, ban.Reason) }),
+//	)
+//	lis, _ := net.Listen("tcp", ":50051")
+//	server := grpc.NewServer(grpc.ChainUnaryInterceptor(abuse.UnaryServerInterceptor(), authMiddleware))
+//	server.Serve(abuse.Listener(lis))
+//
+//	adminServer.AddAbuseDetector("public-api", abuse)
+func NewAbuseDetector(opts ...AbuseOption) *AbuseDetector {
+	a := &AbuseDetector{
+		window:      time.Minute,
+		banDuration: 5 * time.Minute,
+		errorRate:   0.5,
+		minRequests: 20,
+		errorCodes: map[codes.Code]bool{
+			codes.Unauthenticated:   true,
+			codes.PermissionDenied:  true,
+			codes.InvalidArgument:   true,
+			codes.ResourceExhausted: true,
+		},
+		allowlist: make(map[string]bool),
+		extractor: ExtractStreamClient,
+		clients:   make(map[string]*abuseClient),
+		bans:      make(map[string]Ban),
+	}
+
+	for _, opt := range opts {
+		opt(a)
+	}
+
+	return a
+}
+
+// UnaryServerInterceptor returns a unary server interceptor that rejects banned clients
+// and records the outcome of every request
+func (a *AbuseDetector) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		client := a.extractor(ctx)
+		if err := a.admit(client); err != nil {
+			return nil, err
+		}
+
+		resp, err := handler(ctx, req)
+		a.record(client, err)
+
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor returns a stream server interceptor that rejects banned clients
+// and records the outcome of every stream
+func (a *AbuseDetector) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		client := a.extractor(ss.Context())
+		if err := a.admit(client); err != nil {
+			return err
+		}
+
+		err := handler(srv, ss)
+		a.record(client, err)
+
+		return err
+	}
+}
+
+// Listener wraps a listener to close connections from banned IPs as soon as they are
+// accepted, and to count connections for WithAbuseMaxConnections. Connections are keyed
+// "ip:<address>", like unauthenticated requests with the default extractor.
+func (a *AbuseDetector) Listener(l net.Listener) net.Listener {
+	return &abuseListener{Listener: l, detector: a}
+}
+
+// Ban bans a client for d; 0 uses the configured ban duration
+func (a *AbuseDetector) Ban(client string, d time.Duration, reason string) {
+	if d <= 0 {
+		d = a.banDuration
+	}
+	now := time.Now()
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.bans[client] = Ban{Client: client, Reason: reason, Since: now, Until: now.Add(d)}
+	delete(a.clients, client)
+}
+
+// Unban lifts the ban of a client, reporting whether it was banned
+func (a *AbuseDetector) Unban(client string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	ban, ok := a.bans[client]
+	delete(a.bans, client)
+	return ok && time.Now().Before(ban.Until)
+}
+
+// IsBanned reports whether a client is currently banned
+func (a *AbuseDetector) IsBanned(client string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	ban, ok := a.bans[client]
+	return ok && time.Now().Before(ban.Until)
+}
+
+// Bans returns the active bans, soonest to expire first
+func (a *AbuseDetector) Bans() []Ban {
+	now := time.Now()
+
+	a.mu.Lock()
+	bans := make([]Ban, 0, len(a.bans))
+	for _, ban := range a.bans {
+		if now.Before(ban.Until) {
+			bans = append(bans, ban)
+		}
+	}
+	a.mu.Unlock()
+
+	sort.Slice(bans, func(i, j int) bool { return bans[i].Until.Before(bans[j].Until) })
+	return bans
+}
+
+// admit rejects banned clients and counts the request. The request that exceeds the
+// request limit is rejected too, since it gets the client banned.
+func (a *AbuseDetector) admit(client string) error {
+	now := time.Now()
+
+	a.mu.Lock()
+	if ban, ok := a.bans[client]; ok {
+		if now.Before(ban.Until) {
+			a.mu.Unlock()
+			return banError(ban)
+		}
+		delete(a.bans, client)
+	}
+
+	c := a.current(client, now)
+	c.requests++
+	var ban *Ban
+	if a.maxRequests > 0 && c.requests > a.maxRequests {
+		ban = a.banLocked(client, "request rate", now)
+	}
+	a.mu.Unlock()
+
+	a.notify(ban)
+	if ban != nil {
+		return banError(*ban)
+	}
+	return nil
+}
+
+// banError is the status returned to a banned client
+func banError(ban Ban) error {
+	return status.Errorf(codes.PermissionDenied, "client banned until %s\nHint: Too many failed or excessive requests; contact the service owner if this is unexpected", ban.Until.UTC().Format(time.RFC3339))
+}
+
+// record counts the outcome of a request and bans the client if its error rate is too high
+func (a *AbuseDetector) record(client string, err error) {
+	if !a.errorCodes[status.Code(err)] {
+		return
+	}
+	now := time.Now()
+
+	a.mu.Lock()
+	c := a.current(client, now)
+	c.errors++
+	var ban *Ban
+	if c.requests >= a.minRequests && float64(c.errors)/float64(c.requests) >= a.errorRate {
+		ban = a.banLocked(client, "error rate", now)
+	}
+	a.mu.Unlock()
+
+	a.notify(ban)
+}
+
+// admitConnection reports whether a connection from client may be served
+func (a *AbuseDetector) admitConnection(client string) bool {
+	now := time.Now()
+
+	a.mu.Lock()
+	if ban, ok := a.bans[client]; ok && now.Before(ban.Until) {
+		a.mu.Unlock()
+		return false
+	}
+
+	c := a.current(client, now)
+	c.connections++
+	var ban *Ban
+	if a.maxConnections > 0 && c.connections > a.maxConnections {
+		ban = a.banLocked(client, "connection rate", now)
+	}
+	a.mu.Unlock()
+
+	a.notify(ban)
+	return ban == nil
+}
+
+// current returns the counters of a client's current window, starting a new window when
+// the last one ended. Callers must hold a.mu.
+func (a *AbuseDetector) current(client string, now time.Time) *abuseClient {
+	if now.Sub(a.lastSweep) >= a.window {
+		a.sweep(now)
+	}
+
+	c, ok := a.clients[client]
+	if !ok || now.Sub(c.start) >= a.window {
+		c = &abuseClient{start: now}
+		a.clients[client] = c
+	}
+	return c
+}
+
+// sweep forgets finished windows and expired bans. Callers must hold a.mu.
+func (a *AbuseDetector) sweep(now time.Time) {
+	for client, c := range a.clients {
+		if now.Sub(c.start) >= a.window {
+			delete(a.clients, client)
+		}
+	}
+	for client, ban := range a.bans {
+		if !now.Before(ban.Until) {
+			delete(a.bans, client)
+		}
+	}
+	a.lastSweep = now
+}
+
+// banLocked bans a client unless it is allowlisted. Callers must hold a.mu.
+func (a *AbuseDetector) banLocked(client, reason string, now time.Time) *Ban {
+	if a.allowlist[client] {
+		return nil
+	}
+	ban := Ban{Client: client, Reason: reason, Since: now, Until: now.Add(a.banDuration)}
+	a.bans[client] = ban
+	delete(a.clients, client)
+	return &ban
+}
+
+// notify calls the ban callback outside the lock
+func (a *AbuseDetector) notify(ban *Ban) {
+	if ban != nil && a.onBan != nil {
+		a.onBan(*ban)
+	}
+}
+
+// abuseListener drops connections from banned IPs
+type abuseListener struct {
+	net.Listener
+	detector *AbuseDetector
+}
+
+// Accept returns the next connection from a client that is not banned
+func (l *abuseListener) Accept() (net.Conn, error) {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+
+		host, _, err := net.SplitHostPort(conn.RemoteAddr().String())
+		if err != nil {
+			host = conn.RemoteAddr().String()
+		}
+		if l.detector.admitConnection("ip:" + host) {
+			return conn, nil
+		}
+		conn.Close()
+	}
+}
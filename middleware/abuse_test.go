@@ -0,0 +1,136 @@
+package middleware
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestAbuseDetector_ErrorRate(t *testing.T) {
+	var banned []Ban
+	abuse := NewAbuseDetector(
+		WithAbuseErrorRate(0.5, 4),
+		WithAbuseAllowlist("ip:10.0.0.9"),
+		WithOnBan(func(ban Ban) { banned = append(banned, ban) }),
+	)
+	interceptor := abuse.UnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/test.Service/Login"}
+	unauthenticated := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, status.Error(codes.Unauthenticated, "bad password")
+	}
+	ok := func(ctx context.Context, req interface{}) (interface{}, error) { return "ok", nil }
+	notFound := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, status.Error(codes.NotFound, "no such user")
+	}
+
+	// Errors that aren't client misbehavior don't count
+	for i := 0; i < 10; i++ {
+		interceptor(peerContext("10.0.0.2:40000"), nil, info, notFound)
+	}
+	for i := 0; i < 4; i++ {
+		interceptor(peerContext("10.0.0.1:40000"), nil, info, unauthenticated)
+		interceptor(peerContext("10.0.0.9:40000"), nil, info, unauthenticated)
+	}
+
+	if len(banned) != 1 || banned[0].Client != "ip:10.0.0.1" || banned[0].Reason != "error rate" {
+		t.Fatalf("Expected only 10.0.0.1 to be banned, got %+v", banned)
+	}
+	if _, err := interceptor(peerContext("10.0.0.1:40000"), nil, info, ok); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Expected PermissionDenied for a banned client, got %v", err)
+	}
+	if _, err := interceptor(peerContext("10.0.0.2:40000"), nil, info, ok); err != nil {
+		t.Errorf("Expected other clients to be served, got %v", err)
+	}
+
+	if bans := abuse.Bans(); len(bans) != 1 || bans[0].Until.Sub(bans[0].Since) != 5*time.Minute {
+		t.Errorf("Expected one 5 minute ban, got %+v", bans)
+	}
+	if !abuse.Unban("ip:10.0.0.1") || abuse.Unban("ip:10.0.0.1") {
+		t.Error("Expected Unban to lift the ban once")
+	}
+	if _, err := interceptor(peerContext("10.0.0.1:40000"), nil, info, ok); err != nil {
+		t.Errorf("Expected an unbanned client to be served, got %v", err)
+	}
+}
+
+func TestAbuseDetector_RequestRateAndExpiry(t *testing.T) {
+	abuse := NewAbuseDetector(WithAbuseMaxRequests(3), WithBanDuration(50*time.Millisecond))
+	interceptor := abuse.UnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/test.Service/Get"}
+	ok := func(ctx context.Context, req interface{}) (interface{}, error) { return "ok", nil }
+
+	calls := 0
+	counted := func(ctx context.Context, req interface{}) (interface{}, error) {
+		calls++
+		return "ok", nil
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := interceptor(peerContext("10.0.0.1:40000"), nil, info, counted); err != nil {
+			t.Fatalf("Expected request %d to be served, got %v", i+1, err)
+		}
+	}
+	// The request over the limit is rejected along with the ones after it
+	if _, err := interceptor(peerContext("10.0.0.1:40000"), nil, info, counted); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Expected the request that triggers the ban to be rejected, got %v", err)
+	}
+	if !abuse.IsBanned("ip:10.0.0.1") || calls != 3 {
+		t.Fatalf("Expected the client to be banned for its request rate after 3 calls, got %d", calls)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if _, err := interceptor(peerContext("10.0.0.1:40000"), nil, info, ok); err != nil {
+		t.Errorf("Expected the ban to expire, got %v", err)
+	}
+}
+
+func TestAbuseDetector_Listener(t *testing.T) {
+	abuse := NewAbuseDetector(WithAbuseMaxConnections(2))
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrapped := abuse.Listener(lis)
+	defer wrapped.Close()
+
+	accepted := make(chan net.Conn, 10)
+	go func() {
+		for {
+			conn, err := wrapped.Accept()
+			if err != nil {
+				close(accepted)
+				return
+			}
+			accepted <- conn
+		}
+	}()
+
+	for i := 0; i < 3; i++ {
+		conn, err := net.Dial("tcp", lis.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+	}
+
+	for i := 0; i < 2; i++ {
+		select {
+		case <-accepted:
+		case <-time.After(time.Second):
+			t.Fatalf("Expected connection %d to be accepted", i+1)
+		}
+	}
+	select {
+	case <-accepted:
+		t.Error("Expected the third connection to be dropped")
+	case <-time.After(50 * time.Millisecond):
+	}
+	if !abuse.IsBanned("ip:127.0.0.1") {
+		t.Error("Expected the client to be banned for its connection rate")
+	}
+}
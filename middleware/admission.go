@@ -0,0 +1,402 @@
+package middleware
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/grpc-guardian/grpc-guardian/pkg/metrics"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// AdmissionController queues requests while the server is saturated instead of rejecting
+// them outright. Queued requests are admitted by priority (critical, default, sheddable)
+// and then by earliest deadline; requests whose deadline would expire while waiting are
+// rejected early so the caller can retry elsewhere.
+type AdmissionController struct {
+	limit         int
+	queueSize     int
+	maxWait       time.Duration
+	minProcessing time.Duration
+	collector     *metrics.AdmissionCollector
+
+	mu       sync.Mutex
+	inFlight int
+	queue    admissionQueue
+	depth    map[Priority]int
+	seq      uint64
+	latency  time.Duration // Moving average of handler latency
+}
+
+// AdmissionOption configures an AdmissionController
+type AdmissionOption func(*AdmissionController)
+
+// WithAdmissionLimit sets how many requests are handled concurrently before queueing
+// Default: 100
+func WithAdmissionLimit(n int) AdmissionOption {
+	return func(a *AdmissionController) {
+		if n > 0 {
+			a.limit = n
+		}
+	}
+}
+
+// WithAdmissionQueueSize sets how many requests may wait. A full queue evicts its
+// lowest-priority, latest-deadline request for a more urgent one, otherwise it rejects
+// the new request.
+// Default: 1000
+func WithAdmissionQueueSize(n int) AdmissionOption {
+	return func(a *AdmissionController) {
+		if n >= 0 {
+			a.queueSize = n
+		}
+	}
+}
+
+// WithAdmissionMaxWait sets the longest a request waits, also when it has no deadline
+// Default: 5s
+func WithAdmissionMaxWait(d time.Duration) AdmissionOption {
+	return func(a *AdmissionController) {
+		if d > 0 {
+			a.maxWait = d
+		}
+	}
+}
+
+// WithAdmissionMinProcessing sets the time a request needs after admission. Requests are
+// rejected once less than this is left before their deadline.
+// Default: 0
+func WithAdmissionMinProcessing(d time.Duration) AdmissionOption {
+	return func(a *AdmissionController) {
+		if d >= 0 {
+			a.minProcessing = d
+		}
+	}
+}
+
+// WithAdmissionMetrics records queue depth, wait time and rejections
+func WithAdmissionMetrics(collector *metrics.AdmissionCollector) AdmissionOption {
+	return func(a *AdmissionController) {
+		a.collector = collector
+	}
+}
+
+// NewAdmissionController creates an admission controller
+//
+// Example usage:
+//
+//	collector, _ := metrics.NewAdmissionCollector(registry)
+//	admission := middleware.NewAdmissionController(
+//	    middleware.WithAdmissionLimit(200),
+//	    middleware.WithAdmissionQueueSize(500),
+//	    middleware.WithAdmissionMinProcessing(20*time.Millisecond),
+//	    middleware.WithAdmissionMetrics(collector),
+//	)
+//	chain := guardian.NewChain(admission.UnaryServerInterceptor())
+func NewAdmissionController(opts ...AdmissionOption) *AdmissionController {
+	a := &AdmissionController{
+		limit:     100,
+		queueSize: 1000,
+		maxWait:   5 * time.Second,
+		depth:     make(map[Priority]int),
+	}
+
+	for _, opt := range opts {
+		opt(a)
+	}
+
+	return a
+}
+
+// AdmissionControl returns a middleware that queues requests above a concurrency limit
+func AdmissionControl(opts ...AdmissionOption) func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	return NewAdmissionController(opts...).UnaryServerInterceptor()
+}
+
+// UnaryServerInterceptor returns a unary server interceptor that admits requests through
+// the queue
+func (a *AdmissionController) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := a.admit(ctx); err != nil {
+			return nil, err
+		}
+
+		// A panicking request still frees its slot, but its duration is no sample
+		start := time.Now()
+		completed := false
+		defer func() { a.release(time.Since(start), completed) }()
+
+		resp, err := handler(ctx, req)
+		completed = true
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor returns a stream server interceptor that admits streams through
+// the queue. A stream holds its slot until it ends; stream durations are not used to
+// estimate queue waits.
+func (a *AdmissionController) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := a.admit(ss.Context()); err != nil {
+			return err
+		}
+		defer a.release(0, false)
+
+		return handler(srv, ss)
+	}
+}
+
+// InFlight returns the number of admitted requests being handled
+func (a *AdmissionController) InFlight() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.inFlight
+}
+
+// QueueDepth returns the number of waiting requests
+func (a *AdmissionController) QueueDepth() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return len(a.queue)
+}
+
+// admit returns once the request may run, or with the error to fail it with
+func (a *AdmissionController) admit(ctx context.Context) error {
+	priority := RequestPriority(ctx)
+	deadline, hasDeadline := ctx.Deadline()
+
+	a.mu.Lock()
+	if a.inFlight < a.limit && len(a.queue) == 0 {
+		a.inFlight++
+		a.mu.Unlock()
+		a.recordAdmitted(priority, 0)
+		return nil
+	}
+
+	w := &admissionWaiter{
+		priority: priority,
+		deadline: deadline,
+		seq:      a.seq,
+		ready:    make(chan struct{}),
+	}
+	a.seq++
+
+	if hasDeadline && time.Until(deadline) < a.estimatedWait(w)+a.minProcessing {
+		a.mu.Unlock()
+		return a.reject(priority, metrics.AdmissionRejectDeadline, "server overloaded: deadline would expire while queued")
+	}
+
+	if len(a.queue) >= a.queueSize {
+		worst := a.queue.worst()
+		if worst == nil || !w.before(worst) {
+			a.mu.Unlock()
+			return a.reject(priority, metrics.AdmissionRejectQueueFull, "server overloaded: admission queue full")
+		}
+		heap.Remove(&a.queue, worst.index)
+		a.setDepth(worst.priority, -1)
+		worst.evicted = true
+		close(worst.ready)
+	}
+
+	heap.Push(&a.queue, w)
+	a.setDepth(priority, 1)
+	a.mu.Unlock()
+
+	wait, reason := a.maxWait, metrics.AdmissionRejectTimeout
+	if hasDeadline {
+		if d := time.Until(deadline) - a.minProcessing; d < wait {
+			wait, reason = d, metrics.AdmissionRejectDeadline
+		}
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	start := time.Now()
+	select {
+	case <-w.ready:
+		reason = ""
+	case <-timer.C:
+	case <-ctx.Done():
+		reason = metrics.AdmissionRejectCanceled
+	}
+
+	a.mu.Lock()
+	if w.index >= 0 {
+		heap.Remove(&a.queue, w.index)
+		a.setDepth(priority, -1)
+		a.mu.Unlock()
+		return a.rejectWaiter(ctx, priority, reason, wait)
+	}
+	a.mu.Unlock()
+
+	switch {
+	case w.evicted:
+		return a.reject(priority, metrics.AdmissionRejectEvicted, "server overloaded: evicted from the admission queue by a more urgent request")
+	case reason != "":
+		// Admitted as the wait ended; hand the slot on
+		a.release(0, false)
+		return a.rejectWaiter(ctx, priority, reason, wait)
+	}
+
+	a.recordAdmitted(priority, time.Since(start))
+	return nil
+}
+
+// release frees the slot of a finished request, handing it to the next waiter
+func (a *AdmissionController) release(latency time.Duration, sample bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if sample {
+		if a.latency == 0 {
+			a.latency = latency
+		} else {
+			a.latency = (a.latency*4 + latency) / 5
+		}
+	}
+
+	if len(a.queue) == 0 {
+		a.inFlight--
+		return
+	}
+
+	// The slot stays taken and passes to the most urgent waiter
+	w := heap.Pop(&a.queue).(*admissionWaiter)
+	a.setDepth(w.priority, -1)
+	close(w.ready)
+}
+
+// estimatedWait estimates how long a new waiter would queue: the requests ahead of it
+// need to finish, limit at a time. Callers must hold a.mu.
+func (a *AdmissionController) estimatedWait(w *admissionWaiter) time.Duration {
+	if a.latency == 0 {
+		return 0
+	}
+	ahead := 0
+	for _, other := range a.queue {
+		if other.before(w) {
+			ahead++
+		}
+	}
+	return a.latency * time.Duration(ahead+1) / time.Duration(a.limit)
+}
+
+// setDepth updates the queue depth of a priority band. Callers must hold a.mu.
+func (a *AdmissionController) setDepth(priority Priority, delta int) {
+	a.depth[priority] += delta
+	if a.collector != nil {
+		a.collector.SetQueueDepth(priority.String(), a.depth[priority])
+	}
+}
+
+// rejectWaiter fails a request whose wait ended without admission
+func (a *AdmissionController) rejectWaiter(ctx context.Context, priority Priority, reason string, wait time.Duration) error {
+	switch reason {
+	case metrics.AdmissionRejectCanceled:
+		a.recordRejected(priority, reason)
+		return status.FromContextError(ctx.Err()).Err()
+	case metrics.AdmissionRejectDeadline:
+		return a.reject(priority, reason, "server overloaded: deadline would expire while queued")
+	}
+	return a.reject(priority, reason, "server overloaded: not admitted within %v", wait)
+}
+
+// reject records a rejection and returns its ResourceExhausted error
+func (a *AdmissionController) reject(priority Priority, reason, format string, args ...interface{}) error {
+	a.recordRejected(priority, reason)
+	return status.Errorf(codes.ResourceExhausted, format+"\nHint: Retry with backoff or against another instance", args...)
+}
+
+func (a *AdmissionController) recordAdmitted(priority Priority, wait time.Duration) {
+	if a.collector != nil {
+		a.collector.RecordAdmitted(priority.String(), wait)
+	}
+}
+
+func (a *AdmissionController) recordRejected(priority Priority, reason string) {
+	if a.collector != nil {
+		a.collector.RecordRejected(priority.String(), reason)
+	}
+}
+
+// admissionWaiter is a queued request
+type admissionWaiter struct {
+	priority Priority
+	deadline time.Time // Zero without a deadline
+	seq      uint64
+	index    int
+	evicted  bool
+	ready    chan struct{} // Closed on admission or eviction
+}
+
+// priorityRank orders priority bands, most urgent first
+func priorityRank(p Priority) int {
+	switch p {
+	case PriorityCritical:
+		return 0
+	case PrioritySheddable:
+		return 2
+	}
+	return 1
+}
+
+// before reports whether w is admitted before o: by priority, then earliest deadline
+// (requests without a deadline last), then arrival
+func (w *admissionWaiter) before(o *admissionWaiter) bool {
+	if rw, ro := priorityRank(w.priority), priorityRank(o.priority); rw != ro {
+		return rw < ro
+	}
+	if !w.deadline.Equal(o.deadline) {
+		switch {
+		case w.deadline.IsZero():
+			return false
+		case o.deadline.IsZero():
+			return true
+		}
+		return w.deadline.Before(o.deadline)
+	}
+	return w.seq < o.seq
+}
+
+// admissionQueue is a heap of waiters, most urgent first
+type admissionQueue []*admissionWaiter
+
+func (q admissionQueue) Len() int           { return len(q) }
+func (q admissionQueue) Less(i, j int) bool { return q[i].before(q[j]) }
+
+func (q admissionQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *admissionQueue) Push(x interface{}) {
+	w := x.(*admissionWaiter)
+	w.index = len(*q)
+	*q = append(*q, w)
+}
+
+func (q *admissionQueue) Pop() interface{} {
+	old := *q
+	w := old[len(old)-1]
+	old[len(old)-1] = nil
+	w.index = -1
+	*q = old[:len(old)-1]
+	return w
+}
+
+// worst returns the waiter admitted last
+func (q admissionQueue) worst() *admissionWaiter {
+	var worst *admissionWaiter
+	for _, w := range q {
+		if worst == nil || worst.before(w) {
+			worst = w
+		}
+	}
+	return worst
+}
@@ -0,0 +1,207 @@
+package middleware
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/grpc-guardian/grpc-guardian/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// waitForQueue polls until n requests are queued
+func waitForQueue(t *testing.T, a *AdmissionController, n int) {
+	t.Helper()
+	for start := time.Now(); a.QueueDepth() != n; time.Sleep(time.Millisecond) {
+		if time.Since(start) > time.Second {
+			t.Fatalf("Expected %d queued requests, got %d", n, a.QueueDepth())
+		}
+	}
+}
+
+func TestAdmissionController_Order(t *testing.T) {
+	admission := NewAdmissionController(WithAdmissionLimit(1))
+	interceptor := admission.UnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/test.Service/Method"}
+
+	block := make(chan struct{})
+	var mu sync.Mutex
+	var order []string
+	handler := func(name string) grpc.UnaryHandler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			if name == "blocker" {
+				<-block
+			}
+			mu.Lock()
+			order = append(order, name)
+			mu.Unlock()
+			return nil, nil
+		}
+	}
+
+	var wg sync.WaitGroup
+	call := func(ctx context.Context, name string) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := interceptor(ctx, nil, info, handler(name)); err != nil {
+				t.Errorf("%s: unexpected error %v", name, err)
+			}
+		}()
+	}
+
+	call(context.Background(), "blocker")
+	waitForQueue(t, admission, 0)
+	for admission.InFlight() != 1 {
+		time.Sleep(time.Millisecond)
+	}
+
+	late, cancelLate := context.WithTimeout(priorityContext("default"), time.Minute)
+	defer cancelLate()
+	early, cancelEarly := context.WithTimeout(priorityContext("default"), 30*time.Second)
+	defer cancelEarly()
+
+	call(priorityContext("sheddable"), "sheddable")
+	waitForQueue(t, admission, 1)
+	call(late, "default-late")
+	waitForQueue(t, admission, 2)
+	call(early, "default-early")
+	waitForQueue(t, admission, 3)
+	call(priorityContext("critical"), "critical")
+	waitForQueue(t, admission, 4)
+
+	close(block)
+	wg.Wait()
+
+	want := []string{"blocker", "critical", "default-early", "default-late", "sheddable"}
+	for i := range want {
+		if i >= len(order) || order[i] != want[i] {
+			t.Fatalf("Expected admission order %v, got %v", want, order)
+		}
+	}
+	if admission.InFlight() != 0 {
+		t.Errorf("Expected all slots to be released, got %d in flight", admission.InFlight())
+	}
+}
+
+func TestAdmissionController_Rejections(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	collector, err := metrics.NewAdmissionCollector(registry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	admission := NewAdmissionController(
+		WithAdmissionLimit(1),
+		WithAdmissionQueueSize(1),
+		WithAdmissionMinProcessing(10*time.Millisecond),
+		WithAdmissionMetrics(collector),
+	)
+	interceptor := admission.UnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/test.Service/Method"}
+	ok := func(ctx context.Context, req interface{}) (interface{}, error) { return "ok", nil }
+
+	block := make(chan struct{})
+	blocked := make(chan struct{})
+	go interceptor(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		close(blocked)
+		<-block
+		return nil, nil
+	})
+	<-blocked
+
+	t.Run("deadline expires while queued", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		_, err := interceptor(ctx, nil, info, ok)
+		if status.Code(err) != codes.ResourceExhausted {
+			t.Fatalf("Expected ResourceExhausted, got %v", err)
+		}
+		if waited := time.Since(start); waited >= 30*time.Millisecond {
+			t.Errorf("Expected the request to be rejected before its deadline, waited %v", waited)
+		}
+	})
+
+	t.Run("deadline too short to queue", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+		defer cancel()
+		if _, err := interceptor(ctx, nil, info, ok); status.Code(err) != codes.ResourceExhausted {
+			t.Errorf("Expected ResourceExhausted, got %v", err)
+		}
+	})
+
+	t.Run("critical request evicts sheddable one", func(t *testing.T) {
+		evicted := make(chan error, 1)
+		go func() {
+			_, err := interceptor(priorityContext("sheddable"), nil, info, ok)
+			evicted <- err
+		}()
+		waitForQueue(t, admission, 1)
+
+		admitted := make(chan error, 1)
+		go func() {
+			_, err := interceptor(priorityContext("critical"), nil, info, ok)
+			admitted <- err
+		}()
+
+		if err := <-evicted; status.Code(err) != codes.ResourceExhausted {
+			t.Fatalf("Expected the sheddable request to be evicted, got %v", err)
+		}
+		if _, err := interceptor(priorityContext("default"), nil, info, ok); status.Code(err) != codes.ResourceExhausted {
+			t.Errorf("Expected a full queue to reject a default request, got %v", err)
+		}
+
+		close(block)
+		if err := <-admitted; err != nil {
+			t.Errorf("Expected the critical request to be admitted, got %v", err)
+		}
+	})
+
+	expected := `
+# HELP grpc_admission_queue_depth Number of requests waiting for admission by priority band
+# TYPE grpc_admission_queue_depth gauge
+grpc_admission_queue_depth{priority="critical"} 0
+grpc_admission_queue_depth{priority="default"} 0
+grpc_admission_queue_depth{priority="sheddable"} 0
+# HELP grpc_admission_rejected_total Total number of requests rejected by the admission controller
+# TYPE grpc_admission_rejected_total counter
+grpc_admission_rejected_total{priority="default",reason="deadline"} 2
+grpc_admission_rejected_total{priority="default",reason="queue_full"} 1
+grpc_admission_rejected_total{priority="sheddable",reason="evicted"} 1
+`
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(expected), "grpc_admission_queue_depth", "grpc_admission_rejected_total"); err != nil {
+		t.Errorf("Unexpected admission metrics: %v", err)
+	}
+}
+
+func TestAdmissionController_HandlerPanicReleasesSlot(t *testing.T) {
+	admission := NewAdmissionController(WithAdmissionLimit(1), WithAdmissionMaxWait(50*time.Millisecond))
+	interceptor := admission.UnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/svc/M"}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("Expected the panic to propagate")
+			}
+		}()
+		interceptor(context.Background(), "req", info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			panic("boom")
+		})
+	}()
+
+	if got := admission.InFlight(); got != 0 {
+		t.Fatalf("Expected the panicking request to release its slot, got %d in flight", got)
+	}
+	if _, err := interceptor(context.Background(), "req", info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "ok", nil
+	}); err != nil {
+		t.Errorf("Expected the next request to be admitted, got %v", err)
+	}
+}
@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"time"
+
+	"github.com/grpc-guardian/grpc-guardian/pkg/annotations"
+)
+
+// WithCacheAnnotations configures caching from (guardian.cache) method options. Methods
+// with a ttl are cached for that long and, as with WithOnlyMethod, become the only cached
+// methods; methods with disabled set are never cached.
+//
+// Example usage:
+//
+//	methods := annotations.FromServer(server)
+//	cacheMiddleware := middleware.Cache(middleware.WithCacheAnnotations(methods...))
+func WithCacheAnnotations(methods ...annotations.MethodOptions) CacheOption {
+	return func(c *CacheConfig) {
+		for _, m := range methods {
+			if m.Cache == nil {
+				continue
+			}
+			if m.Cache.Disabled {
+				WithSkipMethod(m.Method)(c)
+				continue
+			}
+			if m.Cache.TTL > 0 {
+				WithOnlyMethod(m.Method)(c)
+				WithMethodTTL(m.Method, m.Cache.TTL)(c)
+			}
+		}
+	}
+}
+
+// WithTimeoutAnnotations sets per-method timeouts from (guardian.timeout) method options
+func WithTimeoutAnnotations(methods ...annotations.MethodOptions) TimeoutOption {
+	return func(c *TimeoutConfig) {
+		for _, m := range methods {
+			if m.Timeout <= 0 {
+				continue
+			}
+			if c.PerMethod == nil {
+				c.PerMethod = make(map[string]time.Duration)
+			}
+			c.PerMethod[m.Method] = m.Timeout
+		}
+	}
+}
+
+// WithAuthorizationAnnotations sets method policies from (guardian.auth) method options.
+// An annotation replaces any policy set earlier for the same method.
+func WithAuthorizationAnnotations(methods ...annotations.MethodOptions) AuthorizationOption {
+	return func(c *AuthorizationConfig) {
+		for _, m := range methods {
+			if m.Auth == nil {
+				continue
+			}
+			c.Policies[m.Method] = MethodPolicy{
+				Public:      m.Auth.Public,
+				Roles:       m.Auth.Roles,
+				Permissions: m.Auth.Permissions,
+			}
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package middleware
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/grpc-guardian/grpc-guardian/pkg/annotations"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// annotatedMethod builds method options the way generated code sees them before the
+// extensions are known: as unknown fields
+func annotatedMethod(name string, options ...[]byte) *descriptorpb.MethodDescriptorProto {
+	opts := &descriptorpb.MethodOptions{}
+	var raw []byte
+	for _, o := range options {
+		raw = append(raw, o...)
+	}
+	opts.ProtoReflect().SetUnknown(raw)
+	return &descriptorpb.MethodDescriptorProto{
+		Name:       proto.String(name),
+		InputType:  proto.String(".google.protobuf.StringValue"),
+		OutputType: proto.String(".google.protobuf.StringValue"),
+		Options:    opts,
+	}
+}
+
+// messageOption encodes a message-typed extension
+func messageOption(number protowire.Number, fields []byte) []byte {
+	b := protowire.AppendTag(nil, number, protowire.BytesType)
+	return protowire.AppendBytes(b, fields)
+}
+
+// durationFields encodes a google.protobuf.Duration of whole seconds
+func durationFields(seconds int) []byte {
+	b := protowire.AppendTag(nil, 1, protowire.VarintType)
+	return protowire.AppendVarint(b, uint64(seconds))
+}
+
+func TestAnnotations(t *testing.T) {
+	roles := protowire.AppendString(protowire.AppendTag(nil, 1, protowire.BytesType), "admin")
+	public := protowire.AppendVarint(protowire.AppendTag(nil, 3, protowire.VarintType), 1)
+	disabled := protowire.AppendVarint(protowire.AppendTag(nil, 2, protowire.VarintType), 1)
+	ttl := messageOption(1, durationFields(60))
+
+	fdp := &descriptorpb.FileDescriptorProto{
+		Name:       proto.String("annotationstest/users.proto"),
+		Package:    proto.String("annotationstest"),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"google/protobuf/wrappers.proto", annotations.File},
+		Service: []*descriptorpb.ServiceDescriptorProto{{
+			Name: proto.String("Users"),
+			Method: []*descriptorpb.MethodDescriptorProto{
+				annotatedMethod("Get",
+					messageOption(annotations.CacheFieldNumber, ttl),
+					messageOption(annotations.TimeoutFieldNumber, durationFields(2)),
+					messageOption(annotations.AuthFieldNumber, public)),
+				annotatedMethod("Delete",
+					messageOption(annotations.CacheFieldNumber, disabled),
+					messageOption(annotations.AuthFieldNumber, roles)),
+				annotatedMethod("Ping"),
+			},
+		}},
+	}
+	file, err := protodesc.NewFile(fdp, protoregistry.GlobalFiles)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := protoregistry.GlobalFiles.RegisterFile(file); err != nil {
+		t.Fatal(err)
+	}
+
+	server := grpc.NewServer()
+	server.RegisterService(&grpc.ServiceDesc{
+		ServiceName: "annotationstest.Users",
+		HandlerType: (*interface{})(nil),
+	}, struct{}{})
+
+	methods := annotations.FromServer(server)
+	if len(methods) != 2 {
+		t.Fatalf("Expected the 2 annotated methods, got %+v", methods)
+	}
+	get, del := methods[0], methods[1]
+	if get.Method != "/annotationstest.Users/Get" || get.Cache.TTL != time.Minute || get.Timeout != 2*time.Second || !get.Auth.Public {
+		t.Errorf("Unexpected options for Get: %+v", get)
+	}
+	if del.Method != "/annotationstest.Users/Delete" || !del.Cache.Disabled || del.Timeout != 0 || len(del.Auth.Roles) != 1 || del.Auth.Roles[0] != "admin" {
+		t.Errorf("Unexpected options for Delete: %+v", del)
+	}
+
+	t.Run("cache", func(t *testing.T) {
+		config := &CacheConfig{SkipMethods: map[string]bool{}, OnlyMethods: map[string]bool{}}
+		WithCacheAnnotations(methods...)(config)
+		if config.MethodTTLs["/annotationstest.Users/Get"] != time.Minute || !config.OnlyMethods["/annotationstest.Users/Get"] || !config.SkipMethods["/annotationstest.Users/Delete"] {
+			t.Errorf("Unexpected cache config: %+v", config)
+		}
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		config := &TimeoutConfig{}
+		WithTimeoutAnnotations(methods...)(config)
+		if len(config.PerMethod) != 1 || config.PerMethod["/annotationstest.Users/Get"] != 2*time.Second {
+			t.Errorf("Unexpected timeouts: %v", config.PerMethod)
+		}
+	})
+
+	t.Run("authorization", func(t *testing.T) {
+		interceptor := Authorization(WithDenyByDefault(), WithAuthorizationAnnotations(methods...))
+		if got := callAuthz(interceptor, context.Background(), "/annotationstest.Users/Get"); got != codes.OK {
+			t.Errorf("Expected the public method to be allowed, got %v", got)
+		}
+		if got := callAuthz(interceptor, authzContext([]string{"viewer"}, nil), "/annotationstest.Users/Delete"); got != codes.PermissionDenied {
+			t.Errorf("Expected a viewer to be denied, got %v", got)
+		}
+		if got := callAuthz(interceptor, authzContext([]string{"admin"}, nil), "/annotationstest.Users/Delete"); got != codes.OK {
+			t.Errorf("Expected an admin to be allowed, got %v", got)
+		}
+	})
+}
@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+
+	"github.com/grpc-guardian/grpc-guardian/pkg/apikeys"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// APIKeyAuth creates middleware that authenticates requests with keys issued by an apikeys.Manager.
+// The key's owner becomes the user ID, its scopes are available to RequireScope and Authorization,
+// and per-key rate limits are enforced with codes.ResourceExhausted.
+//
+// Example usage:
+//
+//	manager := apikeys.NewManager(apikeys.NewMemoryStore())
+//	raw, _, _ := manager.Create(ctx, apikeys.CreateOptions{
+//	    Owner:     "billing-service",
+//	    Scopes:    []string{"invoices:read"},
+//	    RateLimit: 50,
+//	})
+//	chain := guardian.NewChain(
+//	    middleware.APIKeyAuth(manager),
+//	    middleware.RequireScope("invoices:read"),
+//	)
+func APIKeyAuth(manager *apikeys.Manager) func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		raw, err := extractToken(ctx)
+		if err != nil {
+			return nil, ErrMissingToken()
+		}
+
+		key, err := manager.Validate(ctx, raw)
+		switch {
+		case err == nil:
+		case errors.Is(err, apikeys.ErrRateLimited):
+			return nil, status.Errorf(codes.ResourceExhausted,
+				"rate limit exceeded for API key %s\nHint: Reduce request rate or request a higher limit for this key", key.ID)
+		case errors.Is(err, apikeys.ErrInvalidKey), errors.Is(err, apikeys.ErrKeyExpired), errors.Is(err, apikeys.ErrKeyRevoked):
+			return nil, ErrInvalidToken(err.Error())
+		default:
+			return nil, status.Errorf(codes.Unavailable, "failed to validate API key: %v", err)
+		}
+
+		ctx = context.WithValue(ctx, contextKeyAPIKey, key.ID)
+		ctx = context.WithValue(ctx, contextKeyAPIKeyInfo, key)
+		ctx = context.WithValue(ctx, contextKeyUserID, key.Owner)
+		ctx = context.WithValue(ctx, contextKeyClientID, key.ID)
+		if len(key.Scopes) > 0 {
+			ctx = context.WithValue(ctx, contextKeyScopes, key.Scopes)
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// GetAPIKey retrieves the API key record used to authenticate the request
+func GetAPIKey(ctx context.Context) (*apikeys.Key, bool) {
+	key, ok := ctx.Value(contextKeyAPIKeyInfo).(*apikeys.Key)
+	return key, ok
+}
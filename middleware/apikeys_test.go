@@ -0,0 +1,121 @@
+package middleware
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/grpc-guardian/grpc-guardian/pkg/apikeys"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func callWithAPIKey(interceptor func(context.Context, interface{}, *grpc.UnaryServerInfo, grpc.UnaryHandler) (interface{}, error), key string) (context.Context, error) {
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-api-key", key))
+
+	var handlerCtx context.Context
+	_, err := interceptor(ctx, "req", &grpc.UnaryServerInfo{FullMethod: "/test.Service/Method"},
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			handlerCtx = ctx
+			return "ok", nil
+		})
+	return handlerCtx, err
+}
+
+func TestAPIKeyAuth(t *testing.T) {
+	ctx := context.Background()
+	manager := apikeys.NewManager(apikeys.NewMemoryStore())
+
+	raw, key, err := manager.Create(ctx, apikeys.CreateOptions{
+		Owner:  "billing-service",
+		Scopes: []string{"invoices:read"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	if key.Hash == "" || key.Hash == raw {
+		t.Fatal("Expected only the key hash to be stored")
+	}
+
+	interceptor := APIKeyAuth(manager)
+
+	handlerCtx, err := callWithAPIKey(interceptor, raw)
+	if err != nil {
+		t.Fatalf("Expected valid key to be accepted, got %v", err)
+	}
+	if userID, _ := GetUserID(handlerCtx); userID != "billing-service" {
+		t.Errorf("Expected owner as user ID, got %q", userID)
+	}
+	if scopes, _ := GetScopes(handlerCtx); len(scopes) != 1 || scopes[0] != "invoices:read" {
+		t.Errorf("Unexpected scopes: %v", scopes)
+	}
+	if info, ok := GetAPIKey(handlerCtx); !ok || info.ID != key.ID {
+		t.Error("Expected key record in context")
+	}
+
+	if _, err := callWithAPIKey(interceptor, raw+"x"); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Expected tampered key to be rejected, got %v", err)
+	}
+
+	if err := manager.Revoke(ctx, key.ID); err != nil {
+		t.Fatalf("Failed to revoke key: %v", err)
+	}
+	if _, err := callWithAPIKey(interceptor, raw); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Expected revoked key to be rejected, got %v", err)
+	}
+}
+
+func TestAPIKeyAuth_RateLimitAndRotation(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "keys.json")
+	store, err := apikeys.NewFileStore(path)
+	if err != nil {
+		t.Fatalf("Failed to open file store: %v", err)
+	}
+	manager := apikeys.NewManager(store)
+	interceptor := APIKeyAuth(manager)
+
+	raw, key, err := manager.Create(ctx, apikeys.CreateOptions{Owner: "svc", RateLimit: 1, Burst: 2})
+	if err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := callWithAPIKey(interceptor, raw); err != nil {
+			t.Fatalf("Request %d: expected success, got %v", i, err)
+		}
+	}
+	if _, err := callWithAPIKey(interceptor, raw); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("Expected ResourceExhausted, got %v", err)
+	}
+
+	// The key file survives a restart
+	reopened, err := apikeys.NewFileStore(path)
+	if err != nil {
+		t.Fatalf("Failed to reopen file store: %v", err)
+	}
+	if _, err := reopened.Get(ctx, key.ID); err != nil {
+		t.Errorf("Expected key to be persisted, got %v", err)
+	}
+
+	newRaw, _, err := manager.Rotate(ctx, key.ID, time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to rotate key: %v", err)
+	}
+	if _, err := manager.Validate(ctx, newRaw); err != nil {
+		t.Errorf("Expected rotated key to be valid, got %v", err)
+	}
+	old, _ := store.Get(ctx, key.ID)
+	if old.ExpiresAt.IsZero() || old.IsRevoked() {
+		t.Error("Expected old key to expire after the grace period")
+	}
+
+	expiredRaw, _, _ := manager.Create(ctx, apikeys.CreateOptions{Owner: "svc", TTL: time.Nanosecond})
+	time.Sleep(time.Millisecond)
+	if _, err := callWithAPIKey(interceptor, expiredRaw); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Expected expired key to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/grpc-guardian/grpc-guardian/pkg/audit"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// flakySink fails the first writes, then keeps every event it is given
+type flakySink struct {
+	mu       sync.Mutex
+	failures int
+	events   []audit.Event
+}
+
+func (s *flakySink) Write(ctx context.Context, events []audit.Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failures > 0 {
+		s.failures--
+		return errors.New("sink down")
+	}
+	s.events = append(s.events, events...)
+	return nil
+}
+
+func (s *flakySink) Close() error { return nil }
+
+func TestAudit_Middleware(t *testing.T) {
+	sink := &flakySink{failures: 2}
+	recorder := audit.NewRecorder(sink, audit.WithBatchSize(3), audit.WithFlushInterval(10*time.Millisecond))
+
+	interceptor := Audit(recorder,
+		WithAuditMethods("/orders.Orders/*"),
+		WithAuditRequestMapper(AuditProtoFields("value")),
+	)
+
+	ctx := context.WithValue(context.Background(), contextKeyUserID, "user-1")
+	ctx = context.WithValue(ctx, contextKeyTenantID, "acme")
+	for i := 0; i < 10; i++ {
+		method := "/orders.Orders/Cancel"
+		if i%5 == 4 {
+			method = "/orders.Orders/Get"
+		}
+		_, _ = interceptor(ctx, wrapperspb.String("o-1"), &grpc.UnaryServerInfo{FullMethod: method},
+			func(ctx context.Context, req interface{}) (interface{}, error) {
+				return nil, status.Error(codes.NotFound, "no such order")
+			})
+	}
+	_, _ = interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/health.Health/Check"},
+		func(ctx context.Context, req interface{}) (interface{}, error) { return "ok", nil })
+
+	if err := recorder.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(sink.events) != 10 {
+		t.Fatalf("Expected 10 audited calls, got %d", len(sink.events))
+	}
+	for i, event := range sink.events {
+		if event.Sequence != uint64(i+1) {
+			t.Fatalf("Event %d has sequence %d: events out of order", i, event.Sequence)
+		}
+	}
+
+	event := sink.events[0]
+	if event.Actor.Principal() != "user-1" || event.Actor.TenantID != "acme" {
+		t.Errorf("Unexpected actor %+v", event.Actor)
+	}
+	if event.Resource["value"] != "o-1" || event.Outcome.Success || event.Outcome.Code != "NotFound" {
+		t.Errorf("Unexpected event %+v", event)
+	}
+}
@@ -33,6 +33,20 @@ const (
 	contextKeyOAuth2  contextKey = "oauth2_introspection"
 )
 
+// Context keys of the other middleware
+const (
+	contextKeyAPIKeyInfo        contextKey = "api_key_info"
+	contextKeySchemaVersion     contextKey = "schema_version"
+	contextKeySPIFFEID          contextKey = "spiffe_id"
+	contextKeyPropagatedHeaders contextKey = "propagated_headers"
+	contextKeyJWTClaims         contextKey = "jwt_claims"
+	contextKeyTenantID          contextKey = "tenant_id"
+	contextKeyGeoLocation       contextKey = "geo_location"
+	contextKeyShadowRequest     contextKey = "shadow_request"
+	contextKeyFeatureFlags      contextKey = "feature_flags"
+	contextKeyExperiments       contextKey = "experiments"
+)
+
 // AuthValidator defines the interface for authentication validation
 type AuthValidator func(ctx context.Context, token string) (context.Context, error)
 
@@ -72,46 +86,19 @@ func Auth(validator AuthValidator) func(ctx context.Context, req interface{}, in
 	}
 }
 
-// JWTValidator creates a JWT token validator
+// JWTValidator creates a JWT token validator for HMAC-signed tokens (HS256/HS384/HS512)
+// For RSA/ECDSA keys see RSAJWTValidator, ECDSAJWTValidator, JWKSValidator and OIDCValidator
 func JWTValidator(secret string) AuthValidator {
-	return func(ctx context.Context, tokenString string) (context.Context, error) {
-		// Parse JWT token
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			// Validate signing method
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, errors.New("invalid signing method")
-			}
-			return []byte(secret), nil
-		})
-
-		if err != nil {
-			return ctx, err
-		}
-
-		if !token.Valid {
-			return ctx, errors.New("invalid token")
-		}
-
-		// Extract claims and add to context
-		if claims, ok := token.Claims.(jwt.MapClaims); ok {
-			// Add user ID to context
-			if userID, ok := claims["sub"].(string); ok {
-				ctx = context.WithValue(ctx, contextKeyUserID, userID)
-			}
-
-			// Add roles to context
-			if roles, ok := claims["roles"].([]interface{}); ok {
-				roleStrings := make([]string, len(roles))
-				for i, role := range roles {
-					if roleStr, ok := role.(string); ok {
-						roleStrings[i] = roleStr
-					}
-				}
-				ctx = context.WithValue(ctx, contextKeyRoles, roleStrings)
-			}
+	keyFunc := func(token *jwt.Token) (interface{}, error) {
+		// Validate signing method
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("invalid signing method")
 		}
+		return []byte(secret), nil
+	}
 
-		return ctx, nil
+	return func(ctx context.Context, tokenString string) (context.Context, error) {
+		return validateJWT(ctx, tokenString, keyFunc)
 	}
 }
 
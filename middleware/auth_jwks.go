@@ -0,0 +1,573 @@
+package middleware
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// defaultAsymmetricMethods are the signing algorithms accepted by the JWKS and OIDC validators
+var defaultAsymmetricMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}
+
+// RSAJWTValidator creates a JWT validator for tokens signed with RSA (RS256/RS384/RS512, PS*)
+func RSAJWTValidator(publicKey *rsa.PublicKey) AuthValidator {
+	keyFunc := func(token *jwt.Token) (interface{}, error) {
+		switch token.Method.(type) {
+		case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS:
+			return publicKey, nil
+		default:
+			return nil, errors.New("invalid signing method")
+		}
+	}
+
+	return func(ctx context.Context, tokenString string) (context.Context, error) {
+		return validateJWT(ctx, tokenString, keyFunc)
+	}
+}
+
+// ECDSAJWTValidator creates a JWT validator for tokens signed with ECDSA (ES256/ES384/ES512)
+func ECDSAJWTValidator(publicKey *ecdsa.PublicKey) AuthValidator {
+	keyFunc := func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodECDSA); !ok {
+			return nil, errors.New("invalid signing method")
+		}
+		return publicKey, nil
+	}
+
+	return func(ctx context.Context, tokenString string) (context.Context, error) {
+		return validateJWT(ctx, tokenString, keyFunc)
+	}
+}
+
+// validateJWT parses and validates a token and adds its claims to the context
+func validateJWT(ctx context.Context, tokenString string, keyFunc jwt.Keyfunc, opts ...jwt.ParserOption) (context.Context, error) {
+	token, err := jwt.Parse(tokenString, keyFunc, opts...)
+	if err != nil {
+		return ctx, err
+	}
+
+	if !token.Valid {
+		return ctx, errors.New("invalid token")
+	}
+
+	if claims, ok := token.Claims.(jwt.MapClaims); ok {
+		ctx = contextWithJWTClaims(ctx, claims)
+	}
+
+	return ctx, nil
+}
+
+// contextWithJWTClaims adds the claims, and the user ID, roles and scopes found in them, to
+// the context
+func contextWithJWTClaims(ctx context.Context, claims jwt.MapClaims) context.Context {
+	ctx = context.WithValue(ctx, contextKeyJWTClaims, map[string]interface{}(claims))
+
+	// Add user ID to context
+	if userID, ok := claims["sub"].(string); ok {
+		ctx = context.WithValue(ctx, contextKeyUserID, userID)
+	}
+
+	// Add roles to context
+	if roles, ok := claims["roles"].([]interface{}); ok {
+		roleStrings := make([]string, len(roles))
+		for i, role := range roles {
+			if roleStr, ok := role.(string); ok {
+				roleStrings[i] = roleStr
+			}
+		}
+		ctx = context.WithValue(ctx, contextKeyRoles, roleStrings)
+	}
+
+	// Add scopes to context ("scope" is space-separated, "scp" is an array in some providers)
+	if scope, ok := claims["scope"].(string); ok && scope != "" {
+		ctx = context.WithValue(ctx, contextKeyScopes, strings.Fields(scope))
+	} else if scp, ok := claims["scp"].([]interface{}); ok {
+		scopes := make([]string, 0, len(scp))
+		for _, s := range scp {
+			if str, ok := s.(string); ok {
+				scopes = append(scopes, str)
+			}
+		}
+		ctx = context.WithValue(ctx, contextKeyScopes, scopes)
+	}
+
+	// Add OAuth 2.0 client ID if present
+	if clientID, ok := claims["azp"].(string); ok {
+		ctx = context.WithValue(ctx, contextKeyClientID, clientID)
+	} else if clientID, ok := claims["client_id"].(string); ok {
+		ctx = context.WithValue(ctx, contextKeyClientID, clientID)
+	}
+
+	return ctx
+}
+
+// GetJWTClaims retrieves the claims of the JWT validated by the Auth middleware from context
+func GetJWTClaims(ctx context.Context) (map[string]interface{}, bool) {
+	claims, ok := ctx.Value(contextKeyJWTClaims).(map[string]interface{})
+	return claims, ok
+}
+
+// JWKSConfig holds the configuration for validating JWTs against a JSON Web Key Set
+type JWKSConfig struct {
+	// URL is the JWKS endpoint (e.g. https://tenant.auth0.com/.well-known/jwks.json)
+	URL string
+
+	// HTTPClient is the HTTP client used to fetch the key set
+	// If nil, http.DefaultClient will be used
+	HTTPClient *http.Client
+
+	// RefreshInterval is how often the key set is refreshed in the background of requests
+	// Default: 1 hour
+	RefreshInterval time.Duration
+
+	// MinRefreshInterval rate-limits refreshes triggered by unknown key IDs
+	// Default: 1 minute
+	MinRefreshInterval time.Duration
+
+	// Timeout is the timeout for fetching the key set
+	// Default: 5 seconds
+	Timeout time.Duration
+
+	// ValidMethods restricts the accepted signing algorithms
+	// Default: RS*, PS* and ES* algorithms
+	ValidMethods []string
+}
+
+// JWKS fetches and caches the keys of a JSON Web Key Set, refreshing them when they
+// become stale or when a token references an unknown key ID (key rotation)
+type JWKS struct {
+	config JWKSConfig
+
+	mu          sync.RWMutex
+	keys        map[string]interface{}
+	fetchedAt   time.Time
+	lastAttempt time.Time
+	refreshing  chan struct{} // Closed when the refresh in progress ends
+	refreshErr  error         // Result of the last refresh
+}
+
+// jsonWebKey is a single key of a JWKS document (RFC 7517)
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// NewJWKS creates a new JWKS key cache. Keys are fetched lazily on first use.
+func NewJWKS(config JWKSConfig) *JWKS {
+	if config.HTTPClient == nil {
+		config.HTTPClient = http.DefaultClient
+	}
+	if config.RefreshInterval == 0 {
+		config.RefreshInterval = 1 * time.Hour
+	}
+	if config.MinRefreshInterval == 0 {
+		config.MinRefreshInterval = 1 * time.Minute
+	}
+	if config.Timeout == 0 {
+		config.Timeout = 5 * time.Second
+	}
+	if len(config.ValidMethods) == 0 {
+		config.ValidMethods = defaultAsymmetricMethods
+	}
+
+	return &JWKS{
+		config: config,
+		keys:   make(map[string]interface{}),
+	}
+}
+
+// Keyfunc resolves the verification key for a token by its "kid" header.
+// It can be passed directly to jwt.Parse.
+func (j *JWKS) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	j.mu.RLock()
+	key, found := j.lookup(kid)
+	stale := time.Since(j.fetchedAt) > j.config.RefreshInterval
+	j.mu.RUnlock()
+
+	if found && !stale {
+		return key, nil
+	}
+
+	// Unknown key ID or stale key set - refresh (rate limited)
+	if err := j.refreshIfAllowed(); err != nil && !found {
+		return nil, err
+	}
+
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+
+	if key, found := j.lookup(kid); found {
+		return key, nil
+	}
+	return nil, fmt.Errorf("no key found in JWKS for kid %q", kid)
+}
+
+// lookup finds a key by ID; tokens without a kid match a key set with a single key
+func (j *JWKS) lookup(kid string) (interface{}, bool) {
+	if kid == "" && len(j.keys) == 1 {
+		for _, key := range j.keys {
+			return key, true
+		}
+	}
+	key, ok := j.keys[kid]
+	return key, ok
+}
+
+// refreshIfAllowed refreshes the key set unless a refresh was attempted too recently.
+// Callers arriving during a refresh wait for it and share its result.
+func (j *JWKS) refreshIfAllowed() error {
+	j.mu.Lock()
+	if wait := j.refreshing; wait != nil {
+		j.mu.Unlock()
+		<-wait
+
+		j.mu.RLock()
+		defer j.mu.RUnlock()
+		return j.refreshErr
+	}
+	if time.Since(j.lastAttempt) < j.config.MinRefreshInterval {
+		j.mu.Unlock()
+		return errors.New("JWKS refresh rate limited")
+	}
+	done := make(chan struct{})
+	j.refreshing = done
+	j.lastAttempt = time.Now()
+	j.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), j.config.Timeout)
+	defer cancel()
+	err := j.Refresh(ctx)
+
+	j.mu.Lock()
+	j.refreshing, j.refreshErr = nil, err
+	close(done)
+	j.mu.Unlock()
+	return err
+}
+
+// Refresh fetches the key set from the JWKS endpoint and replaces the cached keys
+func (j *JWKS) Refresh(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, j.config.URL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create JWKS request: %w", err)
+	}
+
+	resp, err := j.config.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("JWKS request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("JWKS endpoint returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var doc struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return fmt.Errorf("failed to parse JWKS response: %w", err)
+	}
+
+	keys := make(map[string]interface{}, len(doc.Keys))
+	for _, jwk := range doc.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			// Skip keys we cannot use rather than failing the whole set
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+
+	if len(keys) == 0 {
+		return errors.New("JWKS contains no usable signing keys")
+	}
+
+	j.mu.Lock()
+	j.keys = keys
+	j.fetchedAt = time.Now()
+	j.mu.Unlock()
+
+	return nil
+}
+
+// publicKey converts a JWK into an *rsa.PublicKey or *ecdsa.PublicKey
+func (k jsonWebKey) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid RSA modulus: %w", err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("invalid RSA exponent: %w", err)
+		}
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}, nil
+
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported EC curve: %s", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, fmt.Errorf("invalid EC x coordinate: %w", err)
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, fmt.Errorf("invalid EC y coordinate: %w", err)
+		}
+		return &ecdsa.PublicKey{
+			Curve: curve,
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported key type: %s", k.Kty)
+	}
+}
+
+// JWKSValidator creates a JWT validator that verifies signatures with keys from a JWKS endpoint
+//
+// Example usage:
+//
+//	chain := guardian.NewChain(
+//	    middleware.Auth(middleware.JWKSValidator(middleware.JWKSConfig{
+//	        URL: "https://tenant.auth0.com/.well-known/jwks.json",
+//	    })),
+//	)
+func JWKSValidator(config JWKSConfig) AuthValidator {
+	jwks := NewJWKS(config)
+
+	return func(ctx context.Context, tokenString string) (context.Context, error) {
+		return validateJWT(ctx, tokenString, jwks.Keyfunc,
+			jwt.WithValidMethods(jwks.config.ValidMethods),
+		)
+	}
+}
+
+// OIDCConfig holds the configuration for OpenID Connect ID/access token validation
+type OIDCConfig struct {
+	// Issuer is the expected "iss" claim, e.g. https://accounts.example.com/ (required)
+	Issuer string
+
+	// Audience is the expected "aud" claim (usually the client ID or API identifier)
+	Audience string
+
+	// JWKSURL overrides the key set location
+	// If empty, it is discovered from {Issuer}/.well-known/openid-configuration
+	JWKSURL string
+
+	// HTTPClient is the HTTP client used for discovery and key fetching
+	// If nil, http.DefaultClient will be used
+	HTTPClient *http.Client
+
+	// Leeway is the allowed clock skew when validating exp/nbf/iat
+	// Default: 30 seconds
+	Leeway time.Duration
+
+	// RefreshInterval is how often the key set is refreshed
+	// Default: 1 hour
+	RefreshInterval time.Duration
+
+	// MinRefreshInterval rate-limits discovery retries after a failure and key set
+	// refreshes triggered by unknown key IDs
+	// Default: 1 minute
+	MinRefreshInterval time.Duration
+
+	// Timeout is the timeout for discovery and key fetching
+	// Default: 5 seconds
+	Timeout time.Duration
+}
+
+// OIDCValidator creates a validator for tokens issued by an OpenID Connect provider
+// (Auth0, Keycloak, Okta, ...). It verifies the signature using the provider's JWKS and
+// enforces the issuer, audience and expiration claims. It panics if Issuer is empty.
+//
+// Example usage:
+//
+//	chain := guardian.NewChain(
+//	    middleware.Auth(middleware.OIDCValidator(middleware.OIDCConfig{
+//	        Issuer:   "https://keycloak.example.com/realms/prod",
+//	        Audience: "orders-api",
+//	    })),
+//	)
+func OIDCValidator(config OIDCConfig) AuthValidator {
+	if config.Issuer == "" {
+		panic("middleware: OIDCValidator: Issuer must be set")
+	}
+	if config.HTTPClient == nil {
+		config.HTTPClient = http.DefaultClient
+	}
+	if config.Leeway == 0 {
+		config.Leeway = 30 * time.Second
+	}
+	if config.MinRefreshInterval == 0 {
+		config.MinRefreshInterval = 1 * time.Minute
+	}
+	if config.Timeout == 0 {
+		config.Timeout = 5 * time.Second
+	}
+
+	var (
+		mu          sync.Mutex
+		jwks        *JWKS
+		lastErr     error
+		lastAttempt time.Time
+		discovering chan struct{} // Closed when the discovery in progress ends
+	)
+
+	// Discover the JWKS location on first use so construction never blocks on the network.
+	// One request discovers while the others wait for its result without holding the lock.
+	// A failed discovery is remembered and only retried once MinRefreshInterval has passed,
+	// so an unreachable provider is not queried on every request.
+	getJWKS := func(ctx context.Context) (*JWKS, error) {
+		for {
+			mu.Lock()
+			if jwks != nil {
+				mu.Unlock()
+				return jwks, nil
+			}
+			if lastErr != nil && time.Since(lastAttempt) < config.MinRefreshInterval {
+				err := lastErr
+				mu.Unlock()
+				return nil, err
+			}
+			if wait := discovering; wait != nil {
+				mu.Unlock()
+				select {
+				case <-wait:
+					continue
+				case <-ctx.Done():
+					return nil, ctx.Err()
+				}
+			}
+			done := make(chan struct{})
+			discovering = done
+			lastAttempt = time.Now()
+			mu.Unlock()
+
+			var err error
+			jwksURL := config.JWKSURL
+			if jwksURL == "" {
+				jwksURL, err = discoverJWKSURL(config)
+			}
+
+			mu.Lock()
+			discovering = nil
+			close(done)
+			if err != nil {
+				lastErr = err
+				mu.Unlock()
+				return nil, err
+			}
+			jwks = NewJWKS(JWKSConfig{
+				URL:                jwksURL,
+				HTTPClient:         config.HTTPClient,
+				RefreshInterval:    config.RefreshInterval,
+				MinRefreshInterval: config.MinRefreshInterval,
+				Timeout:            config.Timeout,
+			})
+			lastErr = nil
+			keySet := jwks
+			mu.Unlock()
+			return keySet, nil
+		}
+	}
+
+	parserOpts := []jwt.ParserOption{
+		jwt.WithValidMethods(defaultAsymmetricMethods),
+		jwt.WithIssuer(config.Issuer),
+		jwt.WithLeeway(config.Leeway),
+		jwt.WithExpirationRequired(),
+	}
+	if config.Audience != "" {
+		parserOpts = append(parserOpts, jwt.WithAudience(config.Audience))
+	}
+
+	return func(ctx context.Context, tokenString string) (context.Context, error) {
+		keySet, err := getJWKS(ctx)
+		if err != nil {
+			return ctx, fmt.Errorf("OIDC discovery failed: %w", err)
+		}
+
+		return validateJWT(ctx, tokenString, keySet.Keyfunc, parserOpts...)
+	}
+}
+
+// discoverJWKSURL reads the jwks_uri from the provider's OpenID configuration document
+func discoverJWKSURL(config OIDCConfig) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), config.Timeout)
+	defer cancel()
+
+	discoveryURL := strings.TrimSuffix(config.Issuer, "/") + "/.well-known/openid-configuration"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, discoveryURL, nil)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := config.HTTPClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("discovery endpoint returned status %d", resp.StatusCode)
+	}
+
+	var doc struct {
+		Issuer  string `json:"issuer"`
+		JWKSURI string `json:"jwks_uri"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return "", fmt.Errorf("failed to parse discovery document: %w", err)
+	}
+
+	if doc.JWKSURI == "" {
+		return "", errors.New("discovery document has no jwks_uri")
+	}
+	if doc.Issuer != "" && strings.TrimSuffix(doc.Issuer, "/") != strings.TrimSuffix(config.Issuer, "/") {
+		return "", fmt.Errorf("discovery issuer mismatch: %s", doc.Issuer)
+	}
+
+	return doc.JWKSURI, nil
+}
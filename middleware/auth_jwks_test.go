@@ -0,0 +1,344 @@
+package middleware
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// rsaJWK encodes an RSA public key as a JWK
+func rsaJWK(kid string, key *rsa.PublicKey) map[string]string {
+	return map[string]string{
+		"kty": "RSA",
+		"kid": kid,
+		"use": "sig",
+		"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
+
+// signRS256 creates an RS256 token with the given kid and claims
+func signRS256(t *testing.T, key *rsa.PrivateKey, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("Failed to sign token: %v", err)
+	}
+	return signed
+}
+
+func TestRSAJWTValidator(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	validator := RSAJWTValidator(&key.PublicKey)
+
+	token := signRS256(t, key, "", jwt.MapClaims{
+		"sub":   "user-1",
+		"roles": []string{"admin"},
+		"exp":   time.Now().Add(time.Hour).Unix(),
+	})
+
+	ctx, err := validator(context.Background(), token)
+	if err != nil {
+		t.Fatalf("Expected valid token, got %v", err)
+	}
+
+	if userID, _ := GetUserID(ctx); userID != "user-1" {
+		t.Errorf("Expected user-1, got %q", userID)
+	}
+	if roles, _ := GetRoles(ctx); len(roles) != 1 || roles[0] != "admin" {
+		t.Errorf("Expected [admin], got %v", roles)
+	}
+
+	// HMAC token must be rejected even if signed with the public modulus bytes
+	hmacToken, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"sub": "x"}).SignedString(key.N.Bytes())
+	if _, err := validator(context.Background(), hmacToken); err == nil {
+		t.Error("Expected HMAC token to be rejected")
+	}
+}
+
+func TestECDSAJWTValidator(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{
+		"sub":   "user-2",
+		"scope": "read write",
+	}).SignedString(key)
+	if err != nil {
+		t.Fatalf("Failed to sign token: %v", err)
+	}
+
+	ctx, err := ECDSAJWTValidator(&key.PublicKey)(context.Background(), token)
+	if err != nil {
+		t.Fatalf("Expected valid token, got %v", err)
+	}
+
+	if scopes, _ := GetScopes(ctx); len(scopes) != 2 {
+		t.Errorf("Expected 2 scopes, got %v", scopes)
+	}
+}
+
+func TestJWKSValidator_KeyRotation(t *testing.T) {
+	oldKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	newKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	var rotated atomic.Bool
+	var fetches atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		keys := []map[string]string{rsaJWK("old", &oldKey.PublicKey)}
+		if rotated.Load() {
+			keys = append(keys, rsaJWK("new", &newKey.PublicKey))
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": keys})
+	}))
+	defer server.Close()
+
+	validator := JWKSValidator(JWKSConfig{
+		URL:                server.URL,
+		MinRefreshInterval: time.Millisecond,
+	})
+
+	claims := jwt.MapClaims{"sub": "user-3", "exp": time.Now().Add(time.Hour).Unix()}
+
+	if _, err := validator(context.Background(), signRS256(t, oldKey, "old", claims)); err != nil {
+		t.Fatalf("Expected token signed with old key to be valid, got %v", err)
+	}
+
+	// Second validation with a known kid must be served from cache
+	if _, err := validator(context.Background(), signRS256(t, oldKey, "old", claims)); err != nil {
+		t.Fatalf("Expected cached key to validate, got %v", err)
+	}
+	if fetches.Load() != 1 {
+		t.Errorf("Expected 1 JWKS fetch, got %d", fetches.Load())
+	}
+
+	// Provider rotates in a new key; unknown kid triggers a refresh
+	rotated.Store(true)
+	time.Sleep(2 * time.Millisecond)
+
+	ctx, err := validator(context.Background(), signRS256(t, newKey, "new", claims))
+	if err != nil {
+		t.Fatalf("Expected token signed with rotated key to be valid, got %v", err)
+	}
+	if userID, _ := GetUserID(ctx); userID != "user-3" {
+		t.Errorf("Expected user-3, got %q", userID)
+	}
+
+	// Unknown kid that never appears fails
+	otherKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	if _, err := validator(context.Background(), signRS256(t, otherKey, "unknown", claims)); err == nil {
+		t.Error("Expected token with unknown kid to be rejected")
+	}
+}
+
+func TestOIDCValidator(t *testing.T) {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	var issuer string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":   issuer,
+			"jwks_uri": issuer + "/keys",
+		})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{rsaJWK("k1", &key.PublicKey)},
+		})
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	issuer = server.URL
+
+	validator := OIDCValidator(OIDCConfig{
+		Issuer:   issuer,
+		Audience: "orders-api",
+	})
+
+	tests := []struct {
+		name    string
+		claims  jwt.MapClaims
+		wantErr bool
+	}{
+		{
+			name: "valid token",
+			claims: jwt.MapClaims{
+				"iss": issuer, "aud": "orders-api", "sub": "user-4",
+				"exp": time.Now().Add(time.Hour).Unix(),
+			},
+		},
+		{
+			name: "wrong issuer",
+			claims: jwt.MapClaims{
+				"iss": "https://evil.example.com", "aud": "orders-api",
+				"exp": time.Now().Add(time.Hour).Unix(),
+			},
+			wantErr: true,
+		},
+		{
+			name: "wrong audience",
+			claims: jwt.MapClaims{
+				"iss": issuer, "aud": "billing-api",
+				"exp": time.Now().Add(time.Hour).Unix(),
+			},
+			wantErr: true,
+		},
+		{
+			name: "expired",
+			claims: jwt.MapClaims{
+				"iss": issuer, "aud": "orders-api",
+				"exp": time.Now().Add(-time.Hour).Unix(),
+			},
+			wantErr: true,
+		},
+		{
+			name:    "missing expiration",
+			claims:  jwt.MapClaims{"iss": issuer, "aud": "orders-api"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := validator(context.Background(), signRS256(t, key, "k1", tt.claims))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("wantErr %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestOIDCValidator_DiscoveryFailure(t *testing.T) {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	var issuer string
+	var up atomic.Bool
+	var discoveries atomic.Int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		discoveries.Add(1)
+		if !up.Load() {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"issuer": issuer, "jwks_uri": issuer + "/keys"})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{rsaJWK("k1", &key.PublicKey)},
+		})
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	issuer = server.URL
+
+	interval := 50 * time.Millisecond
+	validator := OIDCValidator(OIDCConfig{Issuer: issuer, MinRefreshInterval: interval})
+	token := signRS256(t, key, "k1", jwt.MapClaims{"iss": issuer, "exp": time.Now().Add(time.Hour).Unix()})
+
+	// Requests within the interval get the cached failure
+	for i := 0; i < 10; i++ {
+		if _, err := validator(context.Background(), token); err == nil {
+			t.Fatal("Expected validation to fail while discovery fails")
+		}
+	}
+	if n := discoveries.Load(); n != 1 {
+		t.Errorf("Expected 1 discovery call within the interval, got %d", n)
+	}
+
+	// Once the interval has passed discovery is retried and the result kept
+	up.Store(true)
+	time.Sleep(interval + 10*time.Millisecond)
+	for i := 0; i < 3; i++ {
+		if _, err := validator(context.Background(), token); err != nil {
+			t.Fatalf("Expected the provider to be discovered after recovering, got %v", err)
+		}
+	}
+	if n := discoveries.Load(); n != 2 {
+		t.Errorf("Expected 2 discovery calls in total, got %d", n)
+	}
+}
+
+func TestOIDCValidator_ConcurrentDiscovery(t *testing.T) {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	var issuer string
+	var discoveries atomic.Int32
+	release := make(chan struct{})
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		discoveries.Add(1)
+		<-release
+		json.NewEncoder(w).Encode(map[string]string{"issuer": issuer, "jwks_uri": issuer + "/keys"})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{rsaJWK("k1", &key.PublicKey)},
+		})
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	issuer = server.URL
+
+	validator := OIDCValidator(OIDCConfig{Issuer: issuer})
+	token := signRS256(t, key, "k1", jwt.MapClaims{"iss": issuer, "exp": time.Now().Add(time.Hour).Unix()})
+
+	// A request whose context ends stops waiting for the discovery in progress
+	errs := make(chan error, 5)
+	for i := 0; i < 5; i++ {
+		go func() {
+			_, err := validator(context.Background(), token)
+			errs <- err
+		}()
+	}
+	for discoveries.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := validator(ctx, token); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the waiting request to end with its context, got %v", err)
+	}
+
+	close(release)
+	for i := 0; i < 5; i++ {
+		if err := <-errs; err != nil {
+			t.Errorf("Expected the waiting requests to share the discovery, got %v", err)
+		}
+	}
+	if n := discoveries.Load(); n != 1 {
+		t.Errorf("Expected 1 discovery call, got %d", n)
+	}
+}
+
+func TestOIDCValidator_EmptyIssuer(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected OIDCValidator to panic without an issuer")
+		}
+	}()
+	OIDCValidator(OIDCConfig{JWKSURL: "https://id.example.com/keys"})
+}
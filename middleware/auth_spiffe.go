@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"context"
+	"strings"
+
+	"github.com/grpc-guardian/grpc-guardian/pkg/spiffe"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// SPIFFEAuth creates an authentication middleware for mTLS callers identified by SPIFFE ID.
+// The caller's ID must match one of the patterns, where "*" matches one path segment and a
+// final "**" the rest of the path. The ID is available through GetSPIFFEID and, as a
+// string, GetUserID and GetClientID, so RequireRole-style middleware can follow.
+//
+// Example usage:
+//
+//	source, _ := spiffe.NewSource(ctx)
+//	creds, _ := source.ServerCredentials()
+//	server := grpc.NewServer(
+//	    grpc.Creds(creds),
+//	    grpc.UnaryInterceptor(guardian.NewChain(
+//	        middleware.SPIFFEAuth(
+//	            spiffe.MustParsePattern("spiffe://example.org/ns/*/sa/payments"),
+//	            spiffe.MustParsePattern("spiffe://example.org/ns/billing/**"),
+//	        ),
+//	    ).UnaryInterceptor()),
+//	)
+func SPIFFEAuth(patterns ...spiffe.Pattern) func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		id, err := spiffe.PeerID(ctx)
+		if err != nil {
+			return nil, ErrMissingSPIFFEID(err.Error())
+		}
+
+		if !spiffe.MatchAny(id, patterns...) {
+			return nil, ErrUnauthorizedSPIFFEID(id, patterns)
+		}
+
+		ctx = context.WithValue(ctx, contextKeySPIFFEID, id)
+		ctx = context.WithValue(ctx, contextKeyUserID, id.String())
+		ctx = context.WithValue(ctx, contextKeyClientID, id.String())
+
+		return handler(ctx, req)
+	}
+}
+
+// GetSPIFFEID retrieves the caller's SPIFFE ID from context
+func GetSPIFFEID(ctx context.Context) (spiffe.ID, bool) {
+	id, ok := ctx.Value(contextKeySPIFFEID).(spiffe.ID)
+	return id, ok
+}
+
+// ErrMissingSPIFFEID returns an error when the caller has no verified SPIFFE ID
+func ErrMissingSPIFFEID(reason string) error {
+	return status.Errorf(codes.Unauthenticated,
+		"missing SPIFFE ID: %s\n"+
+			"Hint: Serve with mTLS credentials that verify client certificates, e.g. spiffe.Source.ServerCredentials", reason)
+}
+
+// ErrUnauthorizedSPIFFEID returns an error when the caller's SPIFFE ID matches no pattern
+func ErrUnauthorizedSPIFFEID(id spiffe.ID, patterns []spiffe.Pattern) error {
+	allowed := make([]string, len(patterns))
+	for i, p := range patterns {
+		allowed[i] = p.String()
+	}
+	return status.Errorf(codes.PermissionDenied,
+		"SPIFFE ID %s is not authorized\n"+
+			"Allowed: [%s]\n"+
+			"Hint: Add a matching pattern or register the workload with an authorized SPIFFE ID", id, strings.Join(allowed, ", "))
+}
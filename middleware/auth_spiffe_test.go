@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net/url"
+	"testing"
+
+	"github.com/grpc-guardian/grpc-guardian/pkg/spiffe"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+func spiffeContext(t *testing.T, id string) context.Context {
+	t.Helper()
+	uri, err := url.Parse(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert := &x509.Certificate{URIs: []*url.URL{uri}}
+	return peer.NewContext(context.Background(), &peer.Peer{
+		AuthInfo: credentials.TLSInfo{State: tls.ConnectionState{
+			VerifiedChains: [][]*x509.Certificate{{cert}},
+		}},
+	})
+}
+
+func TestSPIFFEAuth(t *testing.T) {
+	auth := SPIFFEAuth(
+		spiffe.MustParsePattern("spiffe://example.org/ns/*/sa/payments"),
+		spiffe.MustParsePattern("spiffe://example.org/ns/billing/**"),
+	)
+
+	var got spiffe.ID
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		got, _ = GetSPIFFEID(ctx)
+		return "ok", nil
+	}
+	info := &grpc.UnaryServerInfo{FullMethod: "/pay.Payments/Charge"}
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want codes.Code
+	}{
+		{"namespace wildcard", spiffeContext(t, "spiffe://example.org/ns/shop/sa/payments"), codes.OK},
+		{"subtree", spiffeContext(t, "spiffe://example.org/ns/billing/sa/invoices"), codes.OK},
+		{"other service account", spiffeContext(t, "spiffe://example.org/ns/shop/sa/orders"), codes.PermissionDenied},
+		{"other trust domain", spiffeContext(t, "spiffe://evil.org/ns/shop/sa/payments"), codes.PermissionDenied},
+		{"no peer", context.Background(), codes.Unauthenticated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := auth(tt.ctx, "req", info, handler)
+			if status.Code(err) != tt.want {
+				t.Fatalf("code = %v, want %v (%v)", status.Code(err), tt.want, err)
+			}
+		})
+	}
+
+	if got.ServiceAccount() != "invoices" || got.Namespace() != "billing" {
+		t.Errorf("SPIFFE ID in context = %v", got)
+	}
+}
@@ -0,0 +1,359 @@
+package middleware
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/grpc-guardian/grpc-guardian/pkg/condition"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// MethodPolicy describes who may call the methods matched by a pattern
+type MethodPolicy struct {
+	// Public allows unauthenticated callers; roles, permissions and the evaluator are skipped
+	Public bool
+
+	// Roles requires the caller to have at least one of these roles
+	Roles []string
+
+	// Permissions requires the caller to have all of these permissions, either as
+	// OAuth 2.0 scopes or granted through one of their roles (see WithRolePermissions)
+	Permissions []string
+
+	// AllowIf additionally requires the request to satisfy a condition expression
+	// (see pkg/condition), e.g. `request.account_id == principal.user_id`
+	AllowIf condition.Condition
+}
+
+// AuthzInput is the information handed to a PolicyEvaluator for a single request
+type AuthzInput struct {
+	FullMethod  string
+	Service     string
+	Method      string
+	UserID      string
+	ClientID    string
+	Roles       []string
+	Scopes      []string
+	Permissions []string // Scopes plus permissions granted by roles
+	Metadata    metadata.MD
+	PeerAddress string
+	PeerSubject string // Client certificate SPIFFE ID or common name when mTLS is used
+	Request     interface{}
+}
+
+// PolicyEvaluator makes attribute-based authorization decisions (e.g. backed by OPA or custom ABAC logic).
+// Returning an error fails the request; status errors are passed through to the caller unchanged.
+type PolicyEvaluator interface {
+	Evaluate(ctx context.Context, input *AuthzInput) (allowed bool, err error)
+}
+
+// PolicyEvaluatorFunc adapts a function to the PolicyEvaluator interface
+type PolicyEvaluatorFunc func(ctx context.Context, input *AuthzInput) (bool, error)
+
+// Evaluate calls f(ctx, input)
+func (f PolicyEvaluatorFunc) Evaluate(ctx context.Context, input *AuthzInput) (bool, error) {
+	return f(ctx, input)
+}
+
+// AuthorizationConfig holds configuration for the authorization middleware
+type AuthorizationConfig struct {
+	Policies        map[string]MethodPolicy // Method pattern -> policy
+	RolePermissions map[string][]string     // Role -> permissions granted by that role
+	DenyByDefault   bool                    // Reject methods without a matching policy
+	Evaluator       PolicyEvaluator         // Consulted after the static policy allows a request
+}
+
+// AuthorizationOption is a functional option for authorization configuration
+type AuthorizationOption func(*AuthorizationConfig)
+
+// WithMethodPolicy sets the policy for a method pattern.
+// Patterns are exact methods ("/pkg.Service/Method"), prefixes ending in "*" ("/pkg.Service/*")
+// or "*" for every method. Exact matches win, then the longest matching prefix.
+func WithMethodPolicy(pattern string, policy MethodPolicy) AuthorizationOption {
+	return func(c *AuthorizationConfig) {
+		c.Policies[pattern] = policy
+	}
+}
+
+// WithRequiredRoles requires one of the given roles for a method pattern
+func WithRequiredRoles(pattern string, roles ...string) AuthorizationOption {
+	return func(c *AuthorizationConfig) {
+		policy := c.Policies[pattern]
+		policy.Roles = roles
+		c.Policies[pattern] = policy
+	}
+}
+
+// WithRequiredPermissions requires all of the given permissions for a method pattern
+func WithRequiredPermissions(pattern string, permissions ...string) AuthorizationOption {
+	return func(c *AuthorizationConfig) {
+		policy := c.Policies[pattern]
+		policy.Permissions = permissions
+		c.Policies[pattern] = policy
+	}
+}
+
+// WithAllowIf requires requests to a method pattern to satisfy a condition, in addition
+// to any roles or permissions of the policy
+func WithAllowIf(pattern string, cond condition.Condition) AuthorizationOption {
+	return func(c *AuthorizationConfig) {
+		policy := c.Policies[pattern]
+		policy.AllowIf = cond
+		c.Policies[pattern] = policy
+	}
+}
+
+// WithPublicMethod allows unauthenticated access to a method pattern
+func WithPublicMethod(pattern string) AuthorizationOption {
+	return func(c *AuthorizationConfig) {
+		c.Policies[pattern] = MethodPolicy{Public: true}
+	}
+}
+
+// WithRolePermissions grants permissions to every caller with the given role
+func WithRolePermissions(role string, permissions ...string) AuthorizationOption {
+	return func(c *AuthorizationConfig) {
+		c.RolePermissions[role] = append(c.RolePermissions[role], permissions...)
+	}
+}
+
+// WithDenyByDefault rejects every method that has no matching policy
+// Default: false (methods without a policy are allowed)
+func WithDenyByDefault() AuthorizationOption {
+	return func(c *AuthorizationConfig) {
+		c.DenyByDefault = true
+	}
+}
+
+// WithPolicyEvaluator sets a pluggable evaluator for attribute-based decisions.
+// It is consulted for every non-public request, including methods without a static policy.
+func WithPolicyEvaluator(evaluator PolicyEvaluator) AuthorizationOption {
+	return func(c *AuthorizationConfig) {
+		c.Evaluator = evaluator
+	}
+}
+
+// Authorization creates middleware that enforces per-method authorization policies.
+// It must run after Auth so that roles, scopes and the user ID are available in the context.
+//
+// Example usage:
+//
+//	chain := guardian.NewChain(
+//	    middleware.Auth(middleware.JWTValidator("secret")),
+//	    middleware.Authorization(
+//	        middleware.WithPublicMethod("/grpc.health.v1.Health/*"),
+//	        middleware.WithRequiredRoles("/admin.AdminService/*", "admin"),
+//	        middleware.WithRequiredPermissions("/shop.Orders/Delete", "orders:delete"),
+//	        middleware.WithRolePermissions("support", "orders:delete"),
+//	        middleware.WithDenyByDefault(),
+//	    ),
+//	)
+func Authorization(opts ...AuthorizationOption) func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	config := &AuthorizationConfig{
+		Policies:        make(map[string]MethodPolicy),
+		RolePermissions: make(map[string][]string),
+	}
+
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	matcher := newMethodMatcher(config.Policies)
+
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		policy, found := matcher.match(info.FullMethod)
+		if found && policy.Public {
+			return handler(ctx, req)
+		}
+
+		if !found && config.Evaluator == nil {
+			if config.DenyByDefault {
+				return nil, status.Errorf(codes.PermissionDenied,
+					"no authorization policy for %s\n"+
+						"Hint: Add a policy with WithMethodPolicy or WithPublicMethod, or disable WithDenyByDefault", info.FullMethod)
+			}
+			return handler(ctx, req)
+		}
+
+		input := newAuthzInput(ctx, req, info.FullMethod, config.RolePermissions)
+
+		if found {
+			if err := checkMethodPolicy(policy, input); err != nil {
+				return nil, err
+			}
+			if err := checkAllowIf(ctx, policy, req, info.FullMethod); err != nil {
+				return nil, err
+			}
+		}
+
+		if config.Evaluator != nil {
+			allowed, err := config.Evaluator.Evaluate(ctx, input)
+			if err != nil {
+				if _, ok := status.FromError(err); ok {
+					return nil, err
+				}
+				return nil, status.Errorf(codes.Internal, "authorization evaluation failed: %v", err)
+			}
+			if !allowed {
+				return nil, status.Errorf(codes.PermissionDenied, "access to %s denied by policy", info.FullMethod)
+			}
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// checkMethodPolicy enforces the static roles and permissions of a policy
+func checkMethodPolicy(policy MethodPolicy, input *AuthzInput) error {
+	if len(policy.Roles) > 0 {
+		if input.Roles == nil {
+			return ErrNoRolesInContext()
+		}
+		if !containsAny(input.Roles, policy.Roles) {
+			return ErrInsufficientPermissions(policy.Roles, input.Roles)
+		}
+	}
+
+	for _, required := range policy.Permissions {
+		if !containsAny(input.Permissions, []string{required}) {
+			return status.Errorf(codes.PermissionDenied,
+				"missing permission %q for %s\n"+
+					"Hint: Grant the permission as a token scope or through a role with WithRolePermissions", required, input.FullMethod)
+		}
+	}
+
+	return nil
+}
+
+// checkAllowIf enforces the condition of a policy. Evaluation errors deny the request.
+func checkAllowIf(ctx context.Context, policy MethodPolicy, req interface{}, fullMethod string) error {
+	if policy.AllowIf == nil {
+		return nil
+	}
+
+	allowed, err := conditionEvaluator.EvalRequest(ctx, policy.AllowIf, fullMethod, req)
+	if err != nil {
+		return status.Errorf(codes.PermissionDenied,
+			"access to %s denied: %v\n"+
+				"Hint: Check that the allow-if condition only references fields present in every request", fullMethod, err)
+	}
+	if !allowed {
+		return status.Errorf(codes.PermissionDenied, "access to %s denied by condition", fullMethod)
+	}
+	return nil
+}
+
+// newAuthzInput collects the caller attributes for an authorization decision
+func newAuthzInput(ctx context.Context, req interface{}, fullMethod string, rolePermissions map[string][]string) *AuthzInput {
+	input := &AuthzInput{
+		FullMethod: fullMethod,
+		Request:    req,
+	}
+
+	if parts := strings.SplitN(strings.TrimPrefix(fullMethod, "/"), "/", 2); len(parts) == 2 {
+		input.Service, input.Method = parts[0], parts[1]
+	}
+
+	input.UserID, _ = GetUserID(ctx)
+	input.ClientID, _ = GetClientID(ctx)
+	input.Roles, _ = GetRoles(ctx)
+	input.Scopes, _ = GetScopes(ctx)
+	input.Metadata, _ = metadata.FromIncomingContext(ctx)
+	input.PeerAddress, input.PeerSubject = peerIdentity(ctx)
+
+	input.Permissions = append(input.Permissions, input.Scopes...)
+	for _, role := range input.Roles {
+		input.Permissions = append(input.Permissions, rolePermissions[role]...)
+	}
+
+	return input
+}
+
+// peerIdentity returns the peer address and, for mTLS connections, the client certificate identity
+func peerIdentity(ctx context.Context) (string, string) {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return "", ""
+	}
+
+	var address string
+	if p.Addr != nil {
+		address = p.Addr.String()
+	}
+
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(tlsInfo.State.PeerCertificates) == 0 {
+		return address, ""
+	}
+
+	cert := tlsInfo.State.PeerCertificates[0]
+	for _, uri := range cert.URIs {
+		if uri.Scheme == "spiffe" {
+			return address, uri.String()
+		}
+	}
+	return address, cert.Subject.CommonName
+}
+
+// containsAny reports whether have contains at least one of want
+func containsAny(have, want []string) bool {
+	for _, h := range have {
+		for _, w := range want {
+			if h == w {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// methodMatcher resolves a full method name to the value of the most specific pattern
+type methodMatcher[T any] struct {
+	exact    map[string]T
+	prefixes []string // Sorted longest first
+	byPrefix map[string]T
+}
+
+// newMethodMatcher precompiles method patterns
+func newMethodMatcher[T any](patterns map[string]T) *methodMatcher[T] {
+	m := &methodMatcher[T]{
+		exact:    make(map[string]T),
+		byPrefix: make(map[string]T),
+	}
+
+	for pattern, value := range patterns {
+		if strings.HasSuffix(pattern, "*") {
+			prefix := strings.TrimSuffix(pattern, "*")
+			m.prefixes = append(m.prefixes, prefix)
+			m.byPrefix[prefix] = value
+			continue
+		}
+		m.exact[pattern] = value
+	}
+
+	sort.Slice(m.prefixes, func(i, j int) bool {
+		return len(m.prefixes[i]) > len(m.prefixes[j])
+	})
+
+	return m
+}
+
+// match returns the value for a method, preferring exact matches over the longest prefix
+func (m *methodMatcher[T]) match(fullMethod string) (T, bool) {
+	if value, ok := m.exact[fullMethod]; ok {
+		return value, true
+	}
+	for _, prefix := range m.prefixes {
+		if strings.HasPrefix(fullMethod, prefix) {
+			return m.byPrefix[prefix], true
+		}
+	}
+	var zero T
+	return zero, false
+}
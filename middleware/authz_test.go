@@ -0,0 +1,125 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func authzContext(roles, scopes []string) context.Context {
+	ctx := context.Background()
+	if roles != nil {
+		ctx = context.WithValue(ctx, contextKeyRoles, roles)
+	}
+	if scopes != nil {
+		ctx = context.WithValue(ctx, contextKeyScopes, scopes)
+	}
+	return context.WithValue(ctx, contextKeyUserID, "user-123")
+}
+
+func callAuthz(interceptor func(context.Context, interface{}, *grpc.UnaryServerInfo, grpc.UnaryHandler) (interface{}, error), ctx context.Context, method string) codes.Code {
+	_, err := interceptor(ctx, "req", &grpc.UnaryServerInfo{FullMethod: method},
+		func(ctx context.Context, req interface{}) (interface{}, error) { return "ok", nil })
+	return status.Code(err)
+}
+
+func TestAuthorization_MethodPatterns(t *testing.T) {
+	interceptor := Authorization(
+		WithPublicMethod("/grpc.health.v1.Health/*"),
+		WithRequiredRoles("/admin.AdminService/*", "admin"),
+		WithRequiredRoles("/admin.AdminService/GetStatus", "admin", "viewer"),
+		WithRequiredPermissions("/shop.Orders/Delete", "orders:delete"),
+		WithRolePermissions("support", "orders:delete"),
+	)
+
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		method string
+		want   codes.Code
+	}{
+		{"public method", context.Background(), "/grpc.health.v1.Health/Check", codes.OK},
+		{"wildcard role match", authzContext([]string{"admin"}, nil), "/admin.AdminService/DeleteUser", codes.OK},
+		{"wildcard role mismatch", authzContext([]string{"viewer"}, nil), "/admin.AdminService/DeleteUser", codes.PermissionDenied},
+		{"exact overrides wildcard", authzContext([]string{"viewer"}, nil), "/admin.AdminService/GetStatus", codes.OK},
+		{"no roles in context", context.Background(), "/admin.AdminService/DeleteUser", codes.PermissionDenied},
+		{"permission from scope", authzContext(nil, []string{"orders:delete"}), "/shop.Orders/Delete", codes.OK},
+		{"permission from role", authzContext([]string{"support"}, nil), "/shop.Orders/Delete", codes.OK},
+		{"missing permission", authzContext([]string{"viewer"}, []string{"orders:read"}), "/shop.Orders/Delete", codes.PermissionDenied},
+		{"no policy allowed", context.Background(), "/shop.Orders/List", codes.OK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := callAuthz(interceptor, tt.ctx, tt.method); got != tt.want {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestAuthorization_DenyByDefault(t *testing.T) {
+	interceptor := Authorization(
+		WithRequiredRoles("*", "user"),
+		WithPublicMethod("/auth.Login/*"),
+		WithDenyByDefault(),
+	)
+
+	if got := callAuthz(interceptor, context.Background(), "/auth.Login/SignIn"); got != codes.OK {
+		t.Errorf("Expected public method to be allowed, got %v", got)
+	}
+	if got := callAuthz(interceptor, authzContext([]string{"user"}, nil), "/shop.Orders/List"); got != codes.OK {
+		t.Errorf("Expected catch-all policy to allow user, got %v", got)
+	}
+
+	strict := Authorization(WithRequiredRoles("/shop.Orders/*", "user"), WithDenyByDefault())
+	if got := callAuthz(strict, authzContext([]string{"user"}, nil), "/shop.Payments/Charge"); got != codes.PermissionDenied {
+		t.Errorf("Expected unmatched method to be denied, got %v", got)
+	}
+}
+
+func TestAuthorization_PolicyEvaluator(t *testing.T) {
+	var seen *AuthzInput
+	evaluator := PolicyEvaluatorFunc(func(ctx context.Context, input *AuthzInput) (bool, error) {
+		seen = input
+		switch input.Method {
+		case "Broken":
+			return false, errors.New("engine unavailable")
+		case "Throttled":
+			return false, status.Error(codes.ResourceExhausted, "quota exceeded")
+		}
+		return input.UserID == "user-123", nil
+	})
+
+	interceptor := Authorization(
+		WithRequiredRoles("/shop.Orders/*", "user"),
+		WithPolicyEvaluator(evaluator),
+	)
+
+	if got := callAuthz(interceptor, authzContext([]string{"user"}, nil), "/shop.Orders/Get"); got != codes.OK {
+		t.Errorf("Expected evaluator to allow, got %v", got)
+	}
+	if seen == nil || seen.Service != "shop.Orders" || seen.Method != "Get" {
+		t.Errorf("Unexpected evaluator input: %+v", seen)
+	}
+
+	if got := callAuthz(interceptor, context.WithValue(context.Background(), contextKeyRoles, []string{"user"}), "/shop.Orders/Get"); got != codes.PermissionDenied {
+		t.Errorf("Expected evaluator to deny, got %v", got)
+	}
+
+	seen = nil
+	if got := callAuthz(interceptor, authzContext([]string{"viewer"}, nil), "/shop.Orders/Get"); got != codes.PermissionDenied || seen != nil {
+		t.Errorf("Expected static policy to deny before evaluator, got %v", got)
+	}
+
+	if got := callAuthz(interceptor, authzContext([]string{"user"}, nil), "/shop.Orders/Broken"); got != codes.Internal {
+		t.Errorf("Expected Internal on evaluator error, got %v", got)
+	}
+	if got := callAuthz(interceptor, authzContext([]string{"user"}, nil), "/shop.Orders/Throttled"); got != codes.ResourceExhausted {
+		t.Errorf("Expected status error to pass through, got %v", got)
+	}
+}
@@ -4,9 +4,15 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/grpc-guardian/grpc-guardian/pkg/cache"
+	"github.com/grpc-guardian/grpc-guardian/pkg/classify"
+	"github.com/grpc-guardian/grpc-guardian/pkg/condition"
+	"github.com/grpc-guardian/grpc-guardian/pkg/metrics"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -14,14 +20,23 @@ import (
 
 // CacheConfig holds configuration for caching middleware
 type CacheConfig struct {
-	Backend      cache.Backend      // Cache backend
-	KeyGenerator cache.KeyGenerator // Key generation strategy
-	TTL          time.Duration      // Default TTL for cache entries
-	MethodTTLs   map[string]time.Duration // Per-method TTL overrides
-	SkipMethods  map[string]bool    // Methods to skip caching
-	OnlyMethods  map[string]bool    // Only cache these methods (if set)
-	CacheErrors  bool               // Whether to cache error responses
-	SkipAuth     bool               // Skip caching for authenticated requests
+	Backend              cache.Backend                                 // Cache backend
+	KeyGenerator         cache.KeyGenerator                            // Key generation strategy
+	Codec                cache.Codec                                   // Response serialization
+	TTL                  time.Duration                                 // Default TTL for cache entries
+	MethodTTLs           map[string]time.Duration                      // Per-method TTL overrides
+	SkipMethods          map[string]bool                               // Methods to skip caching
+	OnlyMethods          map[string]bool                               // Only cache these methods (if set)
+	CacheErrors          bool                                          // Whether to cache error responses (see NegativeTTL)
+	SkipAuth             bool                                          // Skip caching for authenticated requests
+	AdaptiveTTL          *AdaptiveTTLConfig                            // Adapt TTLs to observed volatility (nil disables)
+	Metrics              metrics.CacheMetricsCollector                 // Hit/miss/set/eviction metrics (nil disables)
+	SkipIf               condition.Condition                           // Bypass the cache for requests matching the condition
+	Classifier           *classify.Classifier                          // Only cache methods classified as reads (nil caches all)
+	Tags                 func(method string, req interface{}) []string // Tags to group cached responses under (nil disables)
+	StaleWhileRevalidate time.Duration                                 // How long expired entries are served while refreshing (0 disables)
+	NegativeTTL          time.Duration                                 // TTL for errors with a NegativeCodes code (0 disables)
+	NegativeCodes        map[codes.Code]bool                           // Error codes cached with NegativeTTL
 }
 
 // CacheOption is a functional option for cache configuration
@@ -41,6 +56,14 @@ func WithKeyGenerator(gen cache.KeyGenerator) CacheOption {
 	}
 }
 
+// WithCacheCodec sets how responses are serialized in the backend
+// Default: cache.NewDefaultCodec() (proto wire format for proto messages, JSON otherwise)
+func WithCacheCodec(codec cache.Codec) CacheOption {
+	return func(c *CacheConfig) {
+		c.Codec = codec
+	}
+}
+
 // WithTTL sets the default TTL for cached responses
 func WithTTL(ttl time.Duration) CacheOption {
 	return func(c *CacheConfig) {
@@ -78,13 +101,43 @@ func WithOnlyMethod(method string) CacheOption {
 	}
 }
 
-// WithCacheErrors enables caching of error responses
+// WithCacheErrors enables caching of all error responses with the regular TTL.
+//
+// Deprecated: Use WithNegativeTTL to cache only deterministic errors, for a shorter time.
 func WithCacheErrors() CacheOption {
 	return func(c *CacheConfig) {
 		c.CacheErrors = true
 	}
 }
 
+// WithNegativeTTL caches errors with one of the given codes for ttl, usually much shorter
+// than the TTL of successful responses, so repeated lookups of a missing resource do not
+// reach the handler while the resource is soon visible once it is created. Other errors
+// are never cached.
+// Default codes: codes.NotFound and codes.InvalidArgument
+func WithNegativeTTL(ttl time.Duration, errorCodes ...codes.Code) CacheOption {
+	return func(c *CacheConfig) {
+		if len(errorCodes) == 0 {
+			errorCodes = []codes.Code{codes.NotFound, codes.InvalidArgument}
+		}
+		c.NegativeTTL = ttl
+		c.NegativeCodes = make(map[codes.Code]bool, len(errorCodes))
+		for _, code := range errorCodes {
+			c.NegativeCodes[code] = true
+		}
+	}
+}
+
+// WithStaleWhileRevalidate keeps serving an expired entry for up to window after its TTL
+// while a single background call refreshes it, so callers never wait for a recomputation
+// of a hot key. The refresh runs with the values of the request that found the stale entry,
+// without its cancellation, and is bounded by the window.
+func WithStaleWhileRevalidate(window time.Duration) CacheOption {
+	return func(c *CacheConfig) {
+		c.StaleWhileRevalidate = window
+	}
+}
+
 // WithSkipAuth skips caching for authenticated requests
 func WithSkipAuth() CacheOption {
 	return func(c *CacheConfig) {
@@ -92,10 +145,77 @@ func WithSkipAuth() CacheOption {
 	}
 }
 
+// WithAdaptiveTTL adapts the TTL of each key to how often its recomputed value changes,
+// within [min, max]. Keys that keep producing the same value are cached longer; keys
+// whose value changes are cached for shorter periods. The configured TTL is the start value.
+func WithAdaptiveTTL(min, max time.Duration) CacheOption {
+	return func(c *CacheConfig) {
+		adaptive := DefaultAdaptiveTTLConfig()
+		adaptive.MinTTL = min
+		adaptive.MaxTTL = max
+		c.AdaptiveTTL = &adaptive
+	}
+}
+
+// WithAdaptiveTTLConfig enables adaptive TTLs with full control over the tuning
+func WithAdaptiveTTLConfig(config AdaptiveTTLConfig) CacheOption {
+	return func(c *CacheConfig) {
+		c.AdaptiveTTL = &config
+	}
+}
+
+// WithCacheMetrics records hits, misses, sets and evictions per method.
+// The collector must implement metrics.CacheMetricsCollector (PrometheusCollector, Multi and
+// Noop do); other collectors are ignored.
+func WithCacheMetrics(collector metrics.MetricsCollector) CacheOption {
+	return func(c *CacheConfig) {
+		if cc, ok := collector.(metrics.CacheMetricsCollector); ok {
+			c.Metrics = cc
+		}
+	}
+}
+
+// WithCacheSkipIf bypasses the cache for requests matching a condition expression
+// (see pkg/condition), e.g. `metadata["cache-control"] == "no-cache"`.
+// Requests whose condition fails to evaluate are not cached either.
+func WithCacheSkipIf(cond condition.Condition) CacheOption {
+	return func(c *CacheConfig) {
+		c.SkipIf = cond
+	}
+}
+
+// WithCacheClassifier only caches methods the classifier marks as reads, so responses of
+// writes and unclassified methods are never replayed from the cache. Methods listed with
+// WithOnlyMethod are cached regardless of their classification.
+func WithCacheClassifier(classifier *classify.Classifier) CacheOption {
+	return func(c *CacheConfig) {
+		c.Classifier = classifier
+	}
+}
+
+// WithCacheTags groups cached responses under the tags returned for each request, so a
+// write can invalidate every cached read of a resource with InvalidateTag. Tags are
+// ignored by backends that do not implement cache.TaggedBackend.
+//
+// Example usage:
+//
+//	middleware.WithCacheTags(func(method string, req interface{}) []string {
+//	    if r, ok := req.(interface{ GetCustomerId() string }); ok {
+//	        return []string{"customer:" + r.GetCustomerId()}
+//	    }
+//	    return nil
+//	})
+func WithCacheTags(tags func(method string, req interface{}) []string) CacheOption {
+	return func(c *CacheConfig) {
+		c.Tags = tags
+	}
+}
+
 // cachedResponse wraps a response for caching
 type cachedResponse struct {
-	Response interface{} `json:"response,omitempty"`
-	Error    *cachedError `json:"error,omitempty"`
+	Response   []byte       `json:"response,omitempty"` // Serialized by the codec
+	Error      *cachedError `json:"error,omitempty"`
+	FreshUntil int64        `json:"fresh_until,omitempty"` // Unix nanoseconds; set with stale-while-revalidate
 }
 
 // cachedError represents a cached error
@@ -110,6 +230,7 @@ func Cache(opts ...CacheOption) func(ctx context.Context, req interface{}, info
 	config := &CacheConfig{
 		Backend:      cache.NewMemoryBackend(cache.DefaultMemoryConfig()),
 		KeyGenerator: cache.NewDefaultKeyGenerator(),
+		Codec:        cache.NewDefaultCodec(),
 		TTL:          5 * time.Minute, // Default 5 minute TTL
 		SkipMethods:  make(map[string]bool),
 		OnlyMethods:  make(map[string]bool),
@@ -122,6 +243,83 @@ func Cache(opts ...CacheOption) func(ctx context.Context, req interface{}, info
 		opt(config)
 	}
 
+	var adaptive *adaptiveTTL
+	if config.AdaptiveTTL != nil {
+		adaptive = newAdaptiveTTL(*config.AdaptiveTTL)
+	}
+
+	// store caches a handler result if it is cacheable
+	store := func(ctx context.Context, method, cacheKey string, req, resp interface{}, err error) {
+		ttl, ok := cacheTTL(config, method, err)
+		if !ok {
+			return
+		}
+
+		// Prepare cached response
+		var cachedResp cachedResponse
+		if err == nil {
+			data, codecErr := config.Codec.Marshal(resp)
+			if codecErr != nil {
+				return
+			}
+			cachedResp.Response = data
+		} else {
+			st := status.Convert(err)
+			cachedResp.Error = &cachedError{
+				Code:    st.Code(),
+				Message: st.Message(),
+			}
+		}
+
+		// Serialize response
+		data, marshalErr := json.Marshal(cachedResp)
+		if marshalErr != nil {
+			return
+		}
+		if adaptive != nil && err == nil {
+			ttl = adaptive.observe(method, cacheKey, data, ttl)
+		}
+
+		// Expired entries stay in the backend for the stale window
+		if config.StaleWhileRevalidate > 0 && ttl > 0 {
+			cachedResp.FreshUntil = time.Now().Add(ttl).UnixNano()
+			if data, marshalErr = json.Marshal(cachedResp); marshalErr != nil {
+				return
+			}
+			ttl += config.StaleWhileRevalidate
+		}
+
+		var tags []string
+		if config.Tags != nil {
+			tags = config.Tags(method, req)
+		}
+
+		// Store in cache
+		storeCached(ctx, config, method, cacheKey, data, ttl, tags)
+	}
+
+	// revalidate refreshes a stale entry in the background, once per key at a time
+	var refreshing sync.Map
+	revalidate := func(ctx context.Context, method, cacheKey string, req interface{}, handler grpc.UnaryHandler) {
+		if _, busy := refreshing.LoadOrStore(cacheKey, struct{}{}); busy {
+			return
+		}
+
+		if span := trace.SpanFromContext(ctx); span.IsRecording() {
+			span.AddEvent("cache.revalidate", trace.WithAttributes(attribute.String("rpc.method", method)))
+		}
+
+		go func() {
+			defer refreshing.Delete(cacheKey)
+
+			ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), config.StaleWhileRevalidate)
+			defer cancel()
+
+			resp, err := handler(ctx, req)
+			store(ctx, method, cacheKey, req, resp, err)
+		}()
+	}
+
 	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
 		method := info.FullMethod
 
@@ -138,6 +336,12 @@ func Cache(opts ...CacheOption) func(ctx context.Context, req interface{}, info
 			}
 		}
 
+		if config.SkipIf != nil {
+			if skip, err := conditionEvaluator.EvalRequest(ctx, config.SkipIf, method, req); err != nil || skip {
+				return handler(ctx, req)
+			}
+		}
+
 		// Generate cache key
 		cacheKey, err := config.KeyGenerator.GenerateKey(method, req)
 		if err != nil {
@@ -149,55 +353,109 @@ func Cache(opts ...CacheOption) func(ctx context.Context, req interface{}, info
 		cached, found, err := config.Backend.Get(ctx, cacheKey)
 		if err == nil && found {
 			// Cache hit - deserialize and return
-			var cachedResp cachedResponse
-			if err := json.Unmarshal(cached, &cachedResp); err == nil {
+			if cachedResp, resp, ok := decodeCached(config, cached); ok {
+				recordCacheLookup(ctx, config, method, true)
+				if cachedResp.FreshUntil != 0 && time.Now().UnixNano() > cachedResp.FreshUntil {
+					revalidate(ctx, method, cacheKey, req, handler)
+				}
 				if cachedResp.Error != nil {
 					// Return cached error
 					return nil, status.Error(cachedResp.Error.Code, cachedResp.Error.Message)
 				}
 				// Return cached response
-				return cachedResp.Response, nil
+				return resp, nil
 			}
 		}
 
 		// Cache miss - call handler
+		recordCacheLookup(ctx, config, method, false)
 		resp, err := handler(ctx, req)
+		store(ctx, method, cacheKey, req, resp, err)
+
+		return resp, err
+	}
+}
+
+// decodeCached decodes a cache entry; entries that cannot be decoded (e.g. written by an
+// older version or for a type that is no longer registered) count as misses
+func decodeCached(config *CacheConfig, data []byte) (cachedResponse, interface{}, bool) {
+	var cachedResp cachedResponse
+	if err := json.Unmarshal(data, &cachedResp); err != nil {
+		return cachedResponse{}, nil, false
+	}
+	if cachedResp.Error != nil {
+		return cachedResp, nil, true
+	}
+
+	resp, err := config.Codec.Unmarshal(cachedResp.Response)
+	if err != nil {
+		return cachedResponse{}, nil, false
+	}
+	return cachedResp, resp, true
+}
 
-		// Determine if we should cache this response
-		shouldCacheResp := true
-		if err != nil && !config.CacheErrors {
-			shouldCacheResp = false
+// cacheTTL returns how long a handler result is cached, or false if it is not cacheable
+func cacheTTL(config *CacheConfig, method string, err error) (time.Duration, bool) {
+	if err != nil {
+		if code := status.Code(err); config.NegativeCodes[code] && config.NegativeTTL > 0 {
+			return config.NegativeTTL, true
 		}
+		if !config.CacheErrors {
+			return 0, false
+		}
+	}
 
-		if shouldCacheResp {
-			// Prepare cached response
-			cachedResp := cachedResponse{
-				Response: resp,
-			}
+	if methodTTL, ok := config.MethodTTLs[method]; ok {
+		return methodTTL, true
+	}
+	return config.TTL, true
+}
 
-			if err != nil {
-				st := status.Convert(err)
-				cachedResp.Error = &cachedError{
-					Code:    st.Code(),
-					Message: st.Message(),
-				}
-			}
+// recordCacheLookup reports a hit or miss to the metrics collector and the active span
+func recordCacheLookup(ctx context.Context, config *CacheConfig, method string, hit bool) {
+	event := "cache.miss"
+	if hit {
+		event = "cache.hit"
+	}
 
-			// Serialize response
-			data, marshalErr := json.Marshal(cachedResp)
-			if marshalErr == nil {
-				// Get TTL for this method
-				ttl := config.TTL
-				if methodTTL, ok := config.MethodTTLs[method]; ok {
-					ttl = methodTTL
-				}
+	if span := trace.SpanFromContext(ctx); span.IsRecording() {
+		span.AddEvent(event, trace.WithAttributes(attribute.String("rpc.method", method)))
+	}
 
-				// Store in cache
-				_ = config.Backend.Set(ctx, cacheKey, data, ttl)
-			}
+	if config.Metrics == nil {
+		return
+	}
+	if hit {
+		config.Metrics.RecordCacheHit(method)
+	} else {
+		config.Metrics.RecordCacheMiss(method)
+	}
+}
+
+// storeCached writes a response to the backend and reports the set and any evictions it caused
+func storeCached(ctx context.Context, config *CacheConfig, method, key string, data []byte, ttl time.Duration, tags []string) {
+	set := func() error {
+		if tagged, ok := config.Backend.(cache.TaggedBackend); ok && len(tags) > 0 {
+			return tagged.SetWithTags(ctx, key, data, ttl, tags)
 		}
+		return config.Backend.Set(ctx, key, data, ttl)
+	}
 
-		return resp, err
+	if config.Metrics == nil {
+		_ = set()
+		return
+	}
+
+	evictionsBefore := config.Backend.Stats().Evictions
+	if err := set(); err != nil {
+		return
+	}
+	config.Metrics.RecordCacheSet(method)
+
+	// Evictions are tracked per backend; the difference is attributed to the method
+	// whose response needed the space
+	if evicted := config.Backend.Stats().Evictions - evictionsBefore; evicted > 0 {
+		config.Metrics.RecordCacheEvictions(method, int(evicted))
 	}
 }
 
@@ -208,6 +466,10 @@ func shouldCache(method string, config *CacheConfig) bool {
 		return config.OnlyMethods[method]
 	}
 
+	if config.Classifier != nil && !config.Classifier.IsRead(method) {
+		return false
+	}
+
 	// Otherwise, cache everything except skip methods
 	return !config.SkipMethods[method]
 }
@@ -223,6 +485,42 @@ func InvalidateCache(ctx context.Context, backend cache.Backend, method string,
 	return backend.Delete(ctx, key)
 }
 
+// InvalidateMethod invalidates all cached responses of a method. Keys must be generated by
+// the default or simple key generator, which prefix keys with the method name; the backend
+// must implement cache.PrefixBackend.
+func InvalidateMethod(ctx context.Context, backend cache.Backend, method string) error {
+	prefixed, ok := backend.(cache.PrefixBackend)
+	if !ok {
+		return fmt.Errorf("cache backend %T does not support deletion by prefix", backend)
+	}
+
+	// SimpleKeyGenerator uses the bare method name as the key
+	if err := backend.Delete(ctx, method); err != nil {
+		return err
+	}
+	return prefixed.DeleteByPrefix(ctx, method+":")
+}
+
+// InvalidateTag invalidates all cached responses grouped under a tag (see WithCacheTags).
+// The backend must implement cache.TaggedBackend.
+//
+// Example usage:
+//
+//	func (s *server) UpdateCustomer(ctx context.Context, req *pb.UpdateCustomerRequest) (*pb.Customer, error) {
+//	    customer, err := s.store.Update(ctx, req)
+//	    if err == nil {
+//	        _ = middleware.InvalidateTag(ctx, s.cache, "customer:"+req.CustomerId)
+//	    }
+//	    return customer, err
+//	}
+func InvalidateTag(ctx context.Context, backend cache.Backend, tag string) error {
+	tagged, ok := backend.(cache.TaggedBackend)
+	if !ok {
+		return fmt.Errorf("cache backend %T does not support tags", backend)
+	}
+	return tagged.DeleteByTag(ctx, tag)
+}
+
 // ClearCache clears all cache entries
 func ClearCache(ctx context.Context, backend cache.Backend) error {
 	return backend.Clear(ctx)
@@ -0,0 +1,132 @@
+package middleware
+
+import (
+	"hash/fnv"
+	"sync"
+	"time"
+)
+
+// AdaptiveTTLConfig holds configuration for volatility-based cache TTLs
+type AdaptiveTTLConfig struct {
+	MinTTL         time.Duration // Lower bound for keys whose value keeps changing
+	MaxTTL         time.Duration // Upper bound for keys whose value never changes
+	GrowthFactor   float64       // TTL multiplier when a recomputed value is unchanged
+	ShrinkFactor   float64       // TTL multiplier when a recomputed value differs
+	MaxTrackedKeys int           // Maximum number of keys with volatility state
+}
+
+// DefaultAdaptiveTTLConfig returns the default adaptive TTL configuration
+func DefaultAdaptiveTTLConfig() AdaptiveTTLConfig {
+	return AdaptiveTTLConfig{
+		MinTTL:         1 * time.Second,
+		MaxTTL:         1 * time.Hour,
+		GrowthFactor:   2.0,
+		ShrinkFactor:   0.5,
+		MaxTrackedKeys: 10000,
+	}
+}
+
+// adaptiveEntry is the volatility state of a single cache key
+type adaptiveEntry struct {
+	hash uint64
+	ttl  time.Duration
+}
+
+// adaptiveTTL learns per-key and per-method TTLs from recomputed values.
+// Every time a key is recomputed after expiry, its value is compared with the
+// previous one: unchanged values extend the TTL, changed values shorten it.
+type adaptiveTTL struct {
+	config AdaptiveTTLConfig
+
+	mu      sync.Mutex
+	keys    map[string]*adaptiveEntry
+	methods map[string]time.Duration // Start TTL for keys not seen before
+}
+
+// newAdaptiveTTL creates a tracker, filling in defaults for unset fields
+func newAdaptiveTTL(config AdaptiveTTLConfig) *adaptiveTTL {
+	defaults := DefaultAdaptiveTTLConfig()
+	if config.MinTTL <= 0 {
+		config.MinTTL = defaults.MinTTL
+	}
+	if config.MaxTTL < config.MinTTL {
+		config.MaxTTL = config.MinTTL
+	}
+	if config.GrowthFactor <= 1 {
+		config.GrowthFactor = defaults.GrowthFactor
+	}
+	if config.ShrinkFactor <= 0 || config.ShrinkFactor >= 1 {
+		config.ShrinkFactor = defaults.ShrinkFactor
+	}
+	if config.MaxTrackedKeys <= 0 {
+		config.MaxTrackedKeys = defaults.MaxTrackedKeys
+	}
+
+	return &adaptiveTTL{
+		config:  config,
+		keys:    make(map[string]*adaptiveEntry),
+		methods: make(map[string]time.Duration),
+	}
+}
+
+// observe records a freshly computed value for a key and returns the TTL to store it with
+func (a *adaptiveTTL) observe(method, key string, value []byte, baseTTL time.Duration) time.Duration {
+	h := fnv.New64a()
+	_, _ = h.Write(value)
+	sum := h.Sum64()
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	entry, ok := a.keys[key]
+	if !ok {
+		ttl, known := a.methods[method]
+		if !known {
+			ttl = a.clamp(baseTTL)
+		}
+
+		if len(a.keys) >= a.config.MaxTrackedKeys {
+			// Drop an arbitrary key; it restarts from the method TTL when seen again
+			for k := range a.keys {
+				delete(a.keys, k)
+				break
+			}
+		}
+
+		a.keys[key] = &adaptiveEntry{hash: sum, ttl: ttl}
+		return ttl
+	}
+
+	methodTTL, known := a.methods[method]
+	if !known {
+		methodTTL = a.clamp(baseTTL)
+	}
+
+	if entry.hash == sum {
+		entry.ttl = a.scale(entry.ttl, a.config.GrowthFactor)
+		methodTTL = a.scale(methodTTL, a.config.GrowthFactor)
+	} else {
+		entry.ttl = a.scale(entry.ttl, a.config.ShrinkFactor)
+		methodTTL = a.scale(methodTTL, a.config.ShrinkFactor)
+	}
+	entry.hash = sum
+	a.methods[method] = methodTTL
+
+	return entry.ttl
+}
+
+// scale multiplies a TTL and keeps it within bounds
+func (a *adaptiveTTL) scale(ttl time.Duration, factor float64) time.Duration {
+	return a.clamp(time.Duration(float64(ttl) * factor))
+}
+
+// clamp keeps a TTL within [MinTTL, MaxTTL]
+func (a *adaptiveTTL) clamp(ttl time.Duration) time.Duration {
+	if ttl < a.config.MinTTL {
+		return a.config.MinTTL
+	}
+	if ttl > a.config.MaxTTL {
+		return a.config.MaxTTL
+	}
+	return ttl
+}
@@ -1,15 +1,27 @@
 package middleware
 
 import (
+	"bytes"
 	"context"
+	"fmt"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/grpc-guardian/grpc-guardian/pkg/cache"
+	"github.com/grpc-guardian/grpc-guardian/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
 )
 
 // mockRequest for testing
@@ -253,6 +265,155 @@ func TestCache_ErrorCaching(t *testing.T) {
 	assert.Equal(t, 1, callCount, "Handler should not be called on cache hit for error")
 }
 
+func TestCache_NegativeTTL(t *testing.T) {
+	backend := cache.NewMemoryBackend(cache.DefaultMemoryConfig())
+	defer backend.Close()
+
+	middleware := Cache(
+		WithCacheBackend(backend),
+		WithTTL(time.Minute),
+		WithNegativeTTL(50*time.Millisecond),
+	)
+	info := mockInfo("/test.Service/Method")
+
+	for code, wantCalls := range map[codes.Code]int{
+		codes.NotFound:    1,
+		codes.Unavailable: 2, // Transient errors are never cached
+	} {
+		calls := 0
+		handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+			calls++
+			return nil, status.Error(code, "failed")
+		}
+		req := &mockRequest{ID: int(code)}
+		for i := 0; i < 2; i++ {
+			_, err := middleware(context.Background(), req, info, handler)
+			assert.Equal(t, code, status.Code(err))
+		}
+		assert.Equal(t, wantCalls, calls, "handler calls for %v", code)
+	}
+
+	// Negative entries expire long before successful ones would
+	calls := 0
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		calls++
+		return nil, status.Error(codes.NotFound, "not found")
+	}
+	req := &mockRequest{ID: 100}
+	_, _ = middleware(context.Background(), req, info, handler)
+	time.Sleep(80 * time.Millisecond)
+	_, _ = middleware(context.Background(), req, info, handler)
+	assert.Equal(t, 2, calls, "Expired negative entry should be recomputed")
+}
+
+func TestCache_StaleWhileRevalidate(t *testing.T) {
+	backend := cache.NewMemoryBackend(cache.DefaultMemoryConfig())
+	defer backend.Close()
+
+	middleware := Cache(
+		WithCacheBackend(backend),
+		WithTTL(50*time.Millisecond),
+		WithStaleWhileRevalidate(time.Minute),
+	)
+	req := &mockRequest{ID: 1}
+	info := mockInfo("/test.Service/Method")
+
+	refreshed := make(chan struct{}, 1)
+	release := make(chan struct{})
+	calls := 0
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		calls++
+		if calls == 1 {
+			return "v1", nil
+		}
+		<-release
+		refreshed <- struct{}{}
+		return "v2", nil
+	}
+
+	resp, _ := middleware(context.Background(), req, info, handler)
+	assert.Equal(t, "v1", resp)
+	time.Sleep(80 * time.Millisecond)
+
+	// Expired: the stale value is served immediately while a single refresh runs
+	ctx, cancel := context.WithCancel(context.Background())
+	for i := 0; i < 3; i++ {
+		resp, err := middleware(ctx, req, info, handler)
+		assert.NoError(t, err)
+		assert.Equal(t, "v1", resp)
+	}
+	cancel() // The refresh outlives the request that triggered it
+	close(release)
+
+	select {
+	case <-refreshed:
+	case <-time.After(time.Second):
+		t.Fatal("stale entry was not refreshed")
+	}
+	assert.Eventually(t, func() bool {
+		resp, _ := middleware(context.Background(), req, info, handler)
+		return resp == "v2"
+	}, time.Second, 5*time.Millisecond)
+	assert.Equal(t, 2, calls, "Concurrent stale hits should trigger one refresh")
+}
+
+func TestCache_ProtoResponses(t *testing.T) {
+	backend := cache.NewMemoryBackend(cache.DefaultMemoryConfig())
+	defer backend.Close()
+
+	middleware := Cache(WithCacheBackend(backend))
+	info := mockInfo("/test.Service/Method")
+
+	want := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("orders.proto"),
+		Options: &descriptorpb.FileOptions{GoPackage: proto.String("example.com/orders")},
+	}
+	oneof := structpb.NewBoolValue(true) // Oneof field
+
+	for _, expected := range []proto.Message{want, oneof, wrapperspb.Bytes([]byte{0, 1, 0xff})} {
+		calls := 0
+		handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+			calls++
+			return expected, nil
+		}
+		req := &mockRequest{Data: string(expected.ProtoReflect().Descriptor().FullName())}
+
+		_, _ = middleware(context.Background(), req, info, handler)
+		resp, err := middleware(context.Background(), req, info, handler)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, calls, "Second call should be a cache hit")
+
+		msg, ok := resp.(proto.Message)
+		if !ok {
+			t.Fatalf("Expected a proto message on cache hit, got %T", resp)
+		}
+		assert.True(t, proto.Equal(expected, msg), "got %v, want %v", msg, expected)
+	}
+}
+
+func TestCacheCodecs(t *testing.T) {
+	codec := cache.NewDefaultCodec()
+
+	data, err := codec.Marshal(map[string]interface{}{"id": 1.0})
+	assert.NoError(t, err)
+	v, err := codec.Unmarshal(data)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"id": 1.0}, v)
+
+	_, err = codec.Unmarshal([]byte("xgarbage"))
+	assert.Error(t, err)
+
+	_, err = cache.NewProtoCodec().Marshal(&mockResponse{})
+	assert.Error(t, err, "ProtoCodec should reject non-proto values")
+
+	// Unregistered message types cannot be reconstructed
+	data, err = cache.NewProtoCodec().Marshal(wrapperspb.String("x"))
+	assert.NoError(t, err)
+	data = bytes.Replace(data, []byte("google.protobuf.StringValue"), []byte("google.protobuf.MissingValue"), 1)
+	_, err = cache.NewProtoCodec().Unmarshal(data)
+	assert.Error(t, err)
+}
+
 func TestCache_DifferentRequests(t *testing.T) {
 	backend := cache.NewMemoryBackend(cache.DefaultMemoryConfig())
 	defer backend.Close()
@@ -337,3 +498,457 @@ func TestClearCache(t *testing.T) {
 	stats = backend.Stats()
 	assert.Equal(t, 0, stats.Size, "Cache should be empty")
 }
+
+func TestCache_TagInvalidation(t *testing.T) {
+	backend := cache.NewMemoryBackend(cache.DefaultMemoryConfig())
+	defer backend.Close()
+
+	middleware := Cache(
+		WithCacheBackend(backend),
+		WithCacheTags(func(method string, req interface{}) []string {
+			return []string{"customer:" + req.(*mockRequest).Data}
+		}),
+	)
+
+	calls := 0
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		calls++
+		return &mockResponse{Result: "ok"}, nil
+	}
+
+	ctx := context.Background()
+	requests := []struct {
+		method string
+		req    *mockRequest
+	}{
+		{"/shop.Customers/GetCustomer", &mockRequest{ID: 1, Data: "c1"}},
+		{"/shop.Orders/ListOrders", &mockRequest{ID: 2, Data: "c1"}},
+		{"/shop.Orders/ListOrders", &mockRequest{ID: 3, Data: "c2"}},
+	}
+	for _, r := range requests {
+		_, _ = middleware(ctx, r.req, mockInfo(r.method), handler)
+	}
+	assert.Equal(t, 3, calls)
+
+	// A write for customer c1 drops every cached read of c1, across methods
+	assert.NoError(t, InvalidateTag(ctx, backend, "customer:c1"))
+	for _, r := range requests {
+		_, _ = middleware(ctx, r.req, mockInfo(r.method), handler)
+	}
+	assert.Equal(t, 5, calls, "Only the c1 reads should be recomputed")
+
+	plain := struct{ cache.Backend }{backend}
+	assert.Error(t, InvalidateTag(ctx, plain, "customer:c1"), "Backends without tag support should be reported")
+}
+
+func TestInvalidateMethod(t *testing.T) {
+	backend := cache.NewMemoryBackend(cache.DefaultMemoryConfig())
+	defer backend.Close()
+
+	ctx := context.Background()
+	gen := cache.NewDefaultKeyGenerator()
+	for _, method := range []string{"/test.Service/Get", "/test.Service/GetAll"} {
+		for id := 0; id < 3; id++ {
+			key, _ := gen.GenerateKey(method, &mockRequest{ID: id})
+			_ = backend.Set(ctx, key, []byte("data"), time.Minute)
+		}
+	}
+	_ = backend.Set(ctx, "/test.Service/Get", []byte("data"), time.Minute) // SimpleKeyGenerator key
+
+	assert.NoError(t, InvalidateMethod(ctx, backend, "/test.Service/Get"))
+	assert.Equal(t, 3, backend.Stats().Size, "Only /test.Service/GetAll entries should remain")
+
+	plain := struct{ cache.Backend }{backend}
+	assert.Error(t, InvalidateMethod(ctx, plain, "/test.Service/Get"), "Backends without prefix support should be reported")
+}
+
+func TestMemoryBackend_Tags(t *testing.T) {
+	backend := cache.NewMemoryBackend(&cache.MemoryConfig{MaxSize: 2, CleanupInterval: time.Minute})
+	defer backend.Close()
+
+	ctx := context.Background()
+	_ = backend.SetWithTags(ctx, "a", []byte("1"), time.Minute, []string{"t1"})
+	_ = backend.SetWithTags(ctx, "b", []byte("2"), time.Minute, []string{"t1", "t2"})
+
+	// Replacing an entry drops its old tags
+	_ = backend.SetWithTags(ctx, "a", []byte("3"), time.Minute, []string{"t2"})
+	assert.NoError(t, backend.DeleteByTag(ctx, "t1"))
+	_, found, _ := backend.Get(ctx, "a")
+	assert.True(t, found, "Replaced entry should no longer carry t1")
+	_, found, _ = backend.Get(ctx, "b")
+	assert.False(t, found)
+
+	// Evicted entries leave their tags
+	_ = backend.Set(ctx, "c", []byte("4"), time.Minute)
+	_ = backend.Set(ctx, "d", []byte("5"), time.Minute)
+	assert.NoError(t, backend.DeleteByTag(ctx, "t2"))
+	assert.Equal(t, 2, backend.Stats().Size)
+}
+
+func TestMemoryBackend_LRU(t *testing.T) {
+	backend := cache.NewMemoryBackend(&cache.MemoryConfig{MaxSize: 3, CleanupInterval: time.Minute})
+	defer backend.Close()
+
+	ctx := context.Background()
+	for _, key := range []string{"a", "b", "c"} {
+		_ = backend.Set(ctx, key, []byte("v"), time.Minute)
+	}
+	_, _, _ = backend.Get(ctx, "a") // "b" is now least recently used
+	_ = backend.Set(ctx, "d", []byte("v"), time.Minute)
+
+	_, found, _ := backend.Get(ctx, "b")
+	assert.False(t, found, "Least recently used entry should be evicted")
+	for _, key := range []string{"a", "c", "d"} {
+		_, found, _ := backend.Get(ctx, key)
+		assert.True(t, found, "%s should still be cached", key)
+	}
+	assert.Equal(t, uint64(1), backend.Stats().Evictions)
+}
+
+func TestMemoryBackend_MaxBytes(t *testing.T) {
+	backend := cache.NewMemoryBackend(&cache.MemoryConfig{MaxBytes: 100, CleanupInterval: time.Minute})
+	defer backend.Close()
+
+	ctx := context.Background()
+	value := make([]byte, 39) // 40 bytes with a one-byte key
+	_ = backend.Set(ctx, "a", value, time.Minute)
+	_ = backend.Set(ctx, "b", value, time.Minute)
+	assert.Equal(t, int64(80), backend.Stats().Bytes)
+
+	// A third entry only fits after evicting "a"
+	_ = backend.Set(ctx, "c", value, time.Minute)
+	stats := backend.Stats()
+	assert.Equal(t, 2, stats.Size)
+	assert.Equal(t, int64(80), stats.Bytes)
+	assert.Equal(t, int64(100), stats.MaxBytes)
+	_, found, _ := backend.Get(ctx, "a")
+	assert.False(t, found)
+
+	// Replacing an entry accounts for the new size only
+	_ = backend.Set(ctx, "b", []byte("small"), time.Minute)
+	assert.Equal(t, int64(46), backend.Stats().Bytes)
+
+	assert.ErrorIs(t, backend.Set(ctx, "huge", make([]byte, 200), time.Minute), cache.ErrEntryTooLarge)
+
+	_ = backend.Delete(ctx, "b")
+	assert.Equal(t, int64(40), backend.Stats().Bytes)
+	_ = backend.Clear(ctx)
+	assert.Equal(t, int64(0), backend.Stats().Bytes)
+}
+
+func TestMemoryBackend_Stats(t *testing.T) {
+	backend := cache.NewMemoryBackend(&cache.MemoryConfig{MaxSize: 100, CleanupInterval: time.Minute})
+	defer backend.Close()
+
+	ctx := context.Background()
+	_ = backend.Set(ctx, "short", []byte("v"), 10*time.Millisecond)
+	_ = backend.Set(ctx, "long", []byte("v"), time.Minute)
+	time.Sleep(20 * time.Millisecond)
+
+	// Reading an expired entry removes it: it is a miss and an expiration, not an eviction
+	_, found, _ := backend.Get(ctx, "short")
+	assert.False(t, found)
+	_, _, _ = backend.Get(ctx, "long")
+
+	stats := backend.Stats()
+	assert.Equal(t, 1, stats.Size)
+	assert.Equal(t, uint64(1), stats.Expirations)
+	assert.Equal(t, uint64(0), stats.Evictions)
+	assert.Equal(t, uint64(1), stats.Hits)
+	assert.Equal(t, uint64(1), stats.Misses)
+	assert.Equal(t, 0.5, stats.HitRate)
+	assert.Equal(t, int64(len("long")+1), stats.Bytes)
+
+	// Stats can be scraped while the cache is in use (run with -race)
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				key := fmt.Sprintf("k%d-%d", i, j%20)
+				_ = backend.Set(ctx, key, []byte("v"), time.Minute)
+				_, _, _ = backend.Get(ctx, key)
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				stats := backend.Stats()
+				assert.LessOrEqual(t, stats.Size, stats.MaxSize)
+			}
+		}()
+	}
+	wg.Wait()
+
+	stats = backend.Stats()
+	assert.Equal(t, uint64(802), stats.Sets)
+	assert.Equal(t, 81, stats.Size)
+}
+
+// fakeMemcached is an in-memory MemcachedClient
+type fakeMemcached struct {
+	items       map[string][]byte
+	expirations map[string]int32
+	gets        int
+}
+
+func newFakeMemcached() *fakeMemcached {
+	return &fakeMemcached{items: make(map[string][]byte), expirations: make(map[string]int32)}
+}
+
+func (f *fakeMemcached) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	f.gets++
+	value, ok := f.items[key]
+	return value, ok, nil
+}
+
+func (f *fakeMemcached) Set(ctx context.Context, key string, value []byte, expiration int32) error {
+	f.items[key] = value
+	f.expirations[key] = expiration
+	return nil
+}
+
+func (f *fakeMemcached) Delete(ctx context.Context, key string) error {
+	delete(f.items, key)
+	return nil
+}
+
+func (f *fakeMemcached) FlushAll(ctx context.Context) error {
+	f.items = make(map[string][]byte)
+	return nil
+}
+
+func TestMemcachedBackend(t *testing.T) {
+	client := newFakeMemcached()
+	backend := cache.NewMemcachedBackend(client, "orders")
+	ctx := context.Background()
+
+	assert.NoError(t, backend.Set(ctx, "/shop.Orders/Get:abc", []byte("v"), 90*time.Second))
+	assert.Equal(t, int32(90), client.expirations["orders:/shop.Orders/Get:abc"])
+
+	value, found, err := backend.Get(ctx, "/shop.Orders/Get:abc")
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, []byte("v"), value)
+
+	// Keys memcached would reject are hashed
+	long := strings.Repeat("k", 300)
+	assert.NoError(t, backend.Set(ctx, long, []byte("v"), 500*time.Millisecond))
+	assert.NoError(t, backend.Set(ctx, "with space", []byte("v"), 0))
+	subSecond := 0
+	for key, expiration := range client.expirations {
+		assert.LessOrEqual(t, len(key), 250)
+		assert.NotContains(t, key, " ")
+		if expiration == 1 {
+			subSecond++
+		}
+	}
+	assert.Equal(t, 1, subSecond, "Sub-second TTLs must be rounded up instead of never expiring")
+	_, found, _ = backend.Get(ctx, long)
+	assert.True(t, found)
+
+	assert.NoError(t, backend.Delete(ctx, long))
+	_, found, _ = backend.Get(ctx, long)
+	assert.False(t, found)
+
+	stats := backend.Stats()
+	assert.Equal(t, uint64(2), stats.Hits)
+	assert.Equal(t, uint64(1), stats.Misses)
+}
+
+func TestTieredBackend(t *testing.T) {
+	l1 := cache.NewMemoryBackend(cache.DefaultMemoryConfig())
+	defer l1.Close()
+	remote := newFakeMemcached()
+	l2 := cache.NewMemcachedBackend(remote, "")
+
+	backend := cache.NewTieredBackend(l1, l2, cache.WithL1TTL(time.Minute), cache.WithTTLJitter(0.2))
+	ctx := context.Background()
+
+	// Writes go to both tiers, with jittered TTLs
+	assert.NoError(t, backend.Set(ctx, "k", []byte("v"), 100*time.Second))
+	expiration := remote.expirations["grpc-cache:k"]
+	assert.True(t, expiration >= 80 && expiration <= 100, "jittered TTL out of range: %d", expiration)
+
+	_, found, _ := backend.Get(ctx, "k")
+	assert.True(t, found)
+	assert.Equal(t, 0, remote.gets, "L1 hits should not reach L2")
+
+	// Entries written by another replica are read from L2 and written back to L1
+	other := cache.NewTieredBackend(cache.NewMemoryBackend(nil), l2)
+	value, found, err := other.Get(ctx, "k")
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, []byte("v"), value)
+	_, _, _ = other.Get(ctx, "k")
+	assert.Equal(t, 1, remote.gets, "L2 hits should be written back to L1")
+	assert.Equal(t, 1, other.Stats().Size)
+
+	assert.NoError(t, backend.Delete(ctx, "k"))
+	_, found, _ = backend.Get(ctx, "k")
+	assert.False(t, found)
+
+	stats := backend.Stats()
+	assert.Equal(t, uint64(1), stats.Hits)
+	assert.Equal(t, uint64(1), stats.Misses)
+}
+
+func TestTieredBackend_TagsAndPrefix(t *testing.T) {
+	ctx := context.Background()
+	l1, l2 := cache.NewMemoryBackend(nil), cache.NewMemoryBackend(nil)
+	defer l1.Close()
+	defer l2.Close()
+	backend := cache.NewTieredBackend(l1, l2)
+
+	assert.NoError(t, InvalidateTag(ctx, backend, "customer:1"))
+	assert.NoError(t, backend.SetWithTags(ctx, "/svc/Get:1", []byte("a"), time.Minute, []string{"customer:1"}))
+	assert.NoError(t, backend.SetWithTags(ctx, "/svc/Get:2", []byte("b"), time.Minute, []string{"customer:2"}))
+	assert.NoError(t, backend.Set(ctx, "/svc/List:1", []byte("c"), time.Minute))
+
+	assert.NoError(t, InvalidateTag(ctx, backend, "customer:1"))
+	for _, tier := range []cache.Backend{l1, l2} {
+		_, found, _ := tier.Get(ctx, "/svc/Get:1")
+		assert.False(t, found, "tagged entry should be deleted from every tier")
+		_, found, _ = tier.Get(ctx, "/svc/Get:2")
+		assert.True(t, found, "entries with other tags should be kept")
+	}
+
+	assert.NoError(t, InvalidateMethod(ctx, backend, "/svc/Get"))
+	for _, tier := range []cache.Backend{l1, l2} {
+		_, found, _ := tier.Get(ctx, "/svc/Get:2")
+		assert.False(t, found, "prefixed entry should be deleted from every tier")
+		_, found, _ = tier.Get(ctx, "/svc/List:1")
+		assert.True(t, found, "entries of other methods should be kept")
+	}
+
+	// A local tier without tag support is cleared; a remote one cannot be invalidated
+	local := cache.NewTieredBackend(cache.NewMemcachedBackend(newFakeMemcached(), ""), l2)
+	assert.NoError(t, local.Set(ctx, "/svc/List:2", []byte("d"), time.Minute))
+	assert.NoError(t, local.DeleteByTag(ctx, "customer:2"))
+	_, found, _ := local.L1().Get(ctx, "/svc/List:2")
+	assert.False(t, found)
+
+	remote := cache.NewTieredBackend(l1, cache.NewMemcachedBackend(newFakeMemcached(), ""))
+	assert.Error(t, remote.DeleteByTag(ctx, "customer:2"))
+	assert.Error(t, remote.DeleteByPrefix(ctx, "/svc/"))
+}
+
+// ttlRecordingBackend records the TTL of every Set and never returns hits,
+// so each call recomputes the value
+type ttlRecordingBackend struct {
+	cache.NoopBackend
+	ttls []time.Duration
+}
+
+func (b *ttlRecordingBackend) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	b.ttls = append(b.ttls, ttl)
+	return nil
+}
+
+func TestCache_AdaptiveTTL(t *testing.T) {
+	backend := &ttlRecordingBackend{}
+
+	middleware := Cache(
+		WithCacheBackend(backend),
+		WithTTL(10*time.Second),
+		WithAdaptiveTTL(5*time.Second, 30*time.Second),
+	)
+
+	req := &mockRequest{ID: 1}
+	info := mockInfo("/test.Service/Method")
+
+	// Stable value: TTL grows up to the maximum
+	for i := 0; i < 4; i++ {
+		_, err := middleware(context.Background(), req, info, mockHandler(&mockResponse{Result: "stable"}, nil))
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, []time.Duration{10 * time.Second, 20 * time.Second, 30 * time.Second, 30 * time.Second}, backend.ttls)
+
+	// Changing value: TTL shrinks down to the minimum
+	backend.ttls = nil
+	for i := 0; i < 4; i++ {
+		_, err := middleware(context.Background(), req, info, mockHandler(&mockResponse{Result: string(rune('a' + i))}, nil))
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, []time.Duration{15 * time.Second, 7500 * time.Millisecond, 5 * time.Second, 5 * time.Second}, backend.ttls)
+}
+
+func TestAdaptiveTTL_NewKeysStartFromMethodTTL(t *testing.T) {
+	a := newAdaptiveTTL(AdaptiveTTLConfig{MinTTL: time.Second, MaxTTL: time.Minute})
+
+	assert.Equal(t, 10*time.Second, a.observe("/svc/Get", "k1", []byte("v"), 10*time.Second))
+	assert.Equal(t, 20*time.Second, a.observe("/svc/Get", "k1", []byte("v"), 10*time.Second))
+
+	// A new key of the same method starts from the learned method TTL
+	assert.Equal(t, 20*time.Second, a.observe("/svc/Get", "k2", []byte("v"), 10*time.Second))
+
+	// Other methods are unaffected
+	assert.Equal(t, 10*time.Second, a.observe("/svc/List", "k3", []byte("v"), 10*time.Second))
+}
+
+func TestAdaptiveTTL_MaxTrackedKeys(t *testing.T) {
+	a := newAdaptiveTTL(AdaptiveTTLConfig{MinTTL: time.Second, MaxTTL: time.Minute, MaxTrackedKeys: 2})
+
+	for _, key := range []string{"k1", "k2", "k3", "k4"} {
+		a.observe("/svc/Get", key, []byte("v"), 10*time.Second)
+	}
+	assert.Len(t, a.keys, 2)
+}
+
+func TestCache_Metrics(t *testing.T) {
+	backend := cache.NewMemoryBackend(&cache.MemoryConfig{MaxSize: 1, CleanupInterval: time.Minute})
+	defer backend.Close()
+
+	collector, err := metrics.NewPrometheusCollector()
+	assert.NoError(t, err)
+
+	middleware := Cache(
+		WithCacheBackend(backend),
+		WithCacheMetrics(collector),
+	)
+
+	info := mockInfo("/test.Service/Get")
+	handler := mockHandler(&mockResponse{Result: "ok"}, nil)
+
+	_, _ = middleware(context.Background(), &mockRequest{ID: 1}, info, handler) // miss + set
+	_, _ = middleware(context.Background(), &mockRequest{ID: 1}, info, handler) // hit
+	_, _ = middleware(context.Background(), &mockRequest{ID: 2}, info, handler) // miss + set, evicts ID 1
+
+	expected := `
+# HELP grpc_cache_evictions_total Total number of cache entries evicted to store new responses
+# TYPE grpc_cache_evictions_total counter
+grpc_cache_evictions_total{method="/test.Service/Get"} 1
+# HELP grpc_cache_hits_total Total number of responses served from the cache
+# TYPE grpc_cache_hits_total counter
+grpc_cache_hits_total{method="/test.Service/Get"} 1
+# HELP grpc_cache_misses_total Total number of cache lookups that called the handler
+# TYPE grpc_cache_misses_total counter
+grpc_cache_misses_total{method="/test.Service/Get"} 2
+# HELP grpc_cache_sets_total Total number of responses stored in the cache
+# TYPE grpc_cache_sets_total counter
+grpc_cache_sets_total{method="/test.Service/Get"} 2
+`
+	assert.NoError(t, testutil.GatherAndCompare(collector.GetRegistry(), strings.NewReader(expected),
+		"grpc_cache_evictions_total", "grpc_cache_hits_total", "grpc_cache_misses_total", "grpc_cache_sets_total"))
+}
+
+func TestCache_SpanEvents(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+
+	middleware := Cache(WithCacheBackend(cache.NewMemoryBackend(cache.DefaultMemoryConfig())))
+	info := mockInfo("/test.Service/Get")
+	handler := mockHandler(&mockResponse{Result: "ok"}, nil)
+
+	for i := 0; i < 2; i++ {
+		ctx, span := tp.Tracer("test").Start(context.Background(), "call")
+		_, _ = middleware(ctx, &mockRequest{ID: 1}, info, handler)
+		span.End()
+	}
+
+	spans := sr.Ended()
+	assert.Len(t, spans, 2)
+	assert.Equal(t, "cache.miss", spans[0].Events()[0].Name)
+	assert.Equal(t, "cache.hit", spans[1].Events()[0].Name)
+}
@@ -6,6 +6,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/grpc-guardian/grpc-guardian/pkg/metrics"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -47,33 +48,47 @@ type CircuitBreaker struct {
 	mu sync.RWMutex
 
 	// Configuration
-	maxRequests        uint32        // Max requests allowed in half-open state
-	interval           time.Duration // Time window for counting failures
-	timeout            time.Duration // Time to wait in open state before trying half-open
-	failureThreshold   float64       // Percentage of failures to trigger open state (0.0-1.0)
-	successThreshold   uint32        // Consecutive successes needed to close from half-open
+	maxRequests       uint32        // Max requests allowed in half-open state
+	interval          time.Duration // Length of the time-based sliding window; 0 never drops calls
+	windowCalls       int           // Size of the count-based sliding window; 0 uses the time-based one
+	minimumCalls      uint32        // Calls in the window needed before the failure and slow-call rates count
+	timeout           time.Duration // Time to wait in open state before trying half-open
+	failureThreshold  float64       // Percentage of failures to trigger open state (0.0-1.0)
+	slowCallThreshold time.Duration // Calls taking at least this long are slow; 0 disables slow-call detection
+	slowRateThreshold float64       // Percentage of slow calls to trigger open state (0.0-1.0)
+	successThreshold  uint32        // Consecutive successes needed to close from half-open
 
 	// State tracking
-	state            State
-	generation       uint64
-	stateChangedAt   time.Time
+	state          State
+	generation     uint64
+	stateChangedAt time.Time
 
 	// Counters
-	counts           Counts
+	window           slidingWindow
+	counts           Counts // Consecutive outcomes; totals come from the window
 	halfOpenRequests uint32
 
 	// Callbacks
 	onStateChange func(from, to State)
 	isFailure     func(err error) bool
+	fallback      Fallback
+
+	// Metrics
+	name      string
+	collector *metrics.CircuitBreakerCollector
+
+	now func() time.Time
 }
 
-// Counts holds the statistics for the circuit breaker
+// Counts holds the statistics for the circuit breaker. Requests and the totals cover the
+// calls in the sliding window.
 type Counts struct {
 	Requests             uint32
 	TotalSuccesses       uint32
 	TotalFailures        uint32
 	ConsecutiveSuccesses uint32
 	ConsecutiveFailures  uint32
+	SlowCalls            uint32 // Calls that took at least the slow-call threshold, failed or not
 }
 
 // CircuitBreakerOption configures a CircuitBreaker
@@ -86,15 +101,54 @@ func WithMaxRequests(n uint32) CircuitBreakerOption {
 	}
 }
 
-// WithInterval sets the time window for counting failures
+// WithInterval sets the length of the time-based sliding window the failure rate is
+// computed over. 0 keeps every call until the breaker changes state, like the counts of
+// breakers without a window.
+// Default: 60s
 func WithInterval(d time.Duration) CircuitBreakerOption {
+	if d == 0 {
+		return func(cb *CircuitBreaker) {
+			cb.interval = 0
+			cb.windowCalls = 0
+		}
+	}
+	return WithTimeBasedWindow(d)
+}
+
+// WithTimeBasedWindow computes the failure rate over the calls of the last d. The window
+// slides in steps of d/10.
+// Default: 60s
+func WithTimeBasedWindow(d time.Duration) CircuitBreakerOption {
 	return func(cb *CircuitBreaker) {
-		cb.interval = d
+		if d > 0 {
+			cb.interval = d
+			cb.windowCalls = 0
+		}
 	}
 }
 
-// WithTimeout sets the time to wait in open state before half-open
-func WithTimeout(d time.Duration) CircuitBreakerOption {
+// WithCountBasedWindow computes the failure rate over the last n calls, however long ago
+// they were made
+func WithCountBasedWindow(n int) CircuitBreakerOption {
+	return func(cb *CircuitBreaker) {
+		if n > 0 {
+			cb.windowCalls = n
+		}
+	}
+}
+
+// WithMinimumCalls sets the number of calls the window must hold before the breaker can
+// open, so a handful of early failures doesn't trip it. It is capped at the size of a
+// count-based window.
+// Default: 10
+func WithMinimumCalls(n uint32) CircuitBreakerOption {
+	return func(cb *CircuitBreaker) {
+		cb.minimumCalls = n
+	}
+}
+
+// WithOpenTimeout sets the time to wait in open state before half-open
+func WithOpenTimeout(d time.Duration) CircuitBreakerOption {
 	return func(cb *CircuitBreaker) {
 		cb.timeout = d
 	}
@@ -109,6 +163,26 @@ func WithFailureThreshold(threshold float64) CircuitBreakerOption {
 	}
 }
 
+// WithSlowCallThreshold counts calls taking at least d as slow, whether they succeed or not,
+// so a dependency that answers slowly but successfully can still trip the breaker. A slow
+// call in half-open state reopens the circuit.
+// Default: 0 (slow-call detection disabled)
+func WithSlowCallThreshold(d time.Duration) CircuitBreakerOption {
+	return func(cb *CircuitBreaker) {
+		cb.slowCallThreshold = d
+	}
+}
+
+// WithSlowCallRateThreshold sets the share of slow calls in the window that opens the circuit
+// Default: 1.0 (every call in the window is slow)
+func WithSlowCallRateThreshold(ratio float64) CircuitBreakerOption {
+	return func(cb *CircuitBreaker) {
+		if ratio > 0 && ratio <= 1.0 {
+			cb.slowRateThreshold = ratio
+		}
+	}
+}
+
 // WithSuccessThreshold sets the consecutive successes needed to close
 func WithSuccessThreshold(n uint32) CircuitBreakerOption {
 	return func(cb *CircuitBreaker) {
@@ -130,23 +204,76 @@ func WithIsFailure(fn func(err error) bool) CircuitBreakerOption {
 	}
 }
 
+// WithBreakerFallback serves calls the breaker rejects, while the circuit is open or the
+// half-open probes are taken, with a degraded response instead of Unavailable. Calls that
+// reach the handler and fail are not affected.
+func WithBreakerFallback(fallback Fallback) CircuitBreakerOption {
+	return func(cb *CircuitBreaker) {
+		cb.fallback = fallback
+	}
+}
+
+// WithBreakerName sets the name that identifies the breaker in metrics
+// Default: "default"
+func WithBreakerName(name string) CircuitBreakerOption {
+	return func(cb *CircuitBreaker) {
+		cb.name = name
+	}
+}
+
+// WithMetricsCollector exports the breaker state, transitions and request outcomes
+// through a metrics.CircuitBreakerCollector
+func WithMetricsCollector(collector *metrics.CircuitBreakerCollector) CircuitBreakerOption {
+	return func(cb *CircuitBreaker) {
+		cb.collector = collector
+	}
+}
+
+// WithBreakerClock sets the time source of the sliding window, the open timeout and
+// slow-call detection, e.g. a fake clock in tests
+// Default: time.Now
+func WithBreakerClock(now func() time.Time) CircuitBreakerOption {
+	return func(cb *CircuitBreaker) {
+		cb.now = now
+	}
+}
+
 // NewCircuitBreaker creates a new circuit breaker with default settings
 func NewCircuitBreaker(opts ...CircuitBreakerOption) *CircuitBreaker {
 	cb := &CircuitBreaker{
-		maxRequests:      1,
-		interval:         60 * time.Second,
-		timeout:          60 * time.Second,
-		failureThreshold: 0.6, // 60% failure rate
-		successThreshold: 1,
-		state:            StateClosed,
-		stateChangedAt:   time.Now(),
-		isFailure:        defaultIsFailure,
+		maxRequests:       1,
+		interval:          60 * time.Second,
+		timeout:           60 * time.Second,
+		minimumCalls:      10,
+		failureThreshold:  0.6, // 60% failure rate
+		slowRateThreshold: 1.0,
+		successThreshold:  1,
+		state:             StateClosed,
+		now:               time.Now,
+		isFailure:         defaultIsFailure,
+		name:              "default",
 	}
 
 	// Apply options
 	for _, opt := range opts {
 		opt(cb)
 	}
+	cb.stateChangedAt = cb.now()
+
+	if cb.windowCalls > 0 {
+		cb.window = newCountWindow(cb.windowCalls)
+		if cb.minimumCalls > uint32(cb.windowCalls) {
+			cb.minimumCalls = uint32(cb.windowCalls)
+		}
+	} else if cb.interval == 0 {
+		cb.window = &cumulativeWindow{}
+	} else {
+		cb.window = newTimeWindow(cb.interval)
+	}
+
+	if cb.collector != nil {
+		cb.collector.SetState(cb.name, stateLabel(cb.state))
+	}
 
 	return cb
 }
@@ -176,54 +303,105 @@ func defaultIsFailure(err error) bool {
 
 // CircuitBreaker returns a middleware that implements the circuit breaker pattern
 func CircuitBreakerMiddleware(opts ...CircuitBreakerOption) func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
-	cb := NewCircuitBreaker(opts...)
+	return NewCircuitBreaker(opts...).UnaryServerInterceptor()
+}
 
+// UnaryServerInterceptor returns a unary server interceptor guarded by this breaker, for
+// breakers that are also inspected or reset elsewhere (e.g. through pkg/admin)
+func (cb *CircuitBreaker) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
 	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
 		// Check if request is allowed
 		generation, err := cb.beforeRequest()
 		if err != nil {
-			return nil, status.Errorf(codes.Unavailable, "circuit breaker: %v", err)
+			err = status.Errorf(codes.Unavailable, "circuit breaker: %v", err)
+			if cb.fallback != nil {
+				return cb.fallback(ctx, req, err)
+			}
+			return nil, err
 		}
 
 		// Execute the request
+		start := cb.now()
 		resp, err := handler(ctx, req)
 
 		// Record the result
-		cb.afterRequest(generation, err)
+		cb.afterRequestTimed(generation, err, cb.now().Sub(start))
 
 		return resp, err
 	}
 }
 
+// UnaryClientInterceptor returns a unary client interceptor guarded by this breaker. While
+// the circuit is open, calls fail fast with Unavailable without reaching the server.
+func (cb *CircuitBreaker) UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		generation, err := cb.beforeRequest()
+		if err != nil {
+			err = status.Errorf(codes.Unavailable, "circuit breaker: %v", err)
+			if cb.fallback != nil {
+				return invokeFallback(ctx, cb.fallback, req, reply, err)
+			}
+			return err
+		}
+
+		start := cb.now()
+		err = invoker(ctx, method, req, reply, cc, opts...)
+		cb.afterRequestTimed(generation, err, cb.now().Sub(start))
+		return err
+	}
+}
+
+// StreamClientInterceptor returns a stream client interceptor guarded by this breaker.
+// Only establishing the stream counts towards the breaker, and fallbacks don't apply.
+func (cb *CircuitBreaker) StreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		generation, err := cb.beforeRequest()
+		if err != nil {
+			return nil, status.Errorf(codes.Unavailable, "circuit breaker: %v", err)
+		}
+
+		start := cb.now()
+		stream, err := streamer(ctx, desc, cc, method, opts...)
+		cb.afterRequestTimed(generation, err, cb.now().Sub(start))
+		return stream, err
+	}
+}
+
 // beforeRequest checks if the request is allowed based on circuit breaker state
 func (cb *CircuitBreaker) beforeRequest() (uint64, error) {
 	cb.mu.Lock()
 	defer cb.mu.Unlock()
 
-	now := time.Now()
+	now := cb.now()
 	state, generation := cb.currentState(now)
 
 	if state == StateOpen {
+		cb.recordRejected()
 		return generation, ErrCircuitOpen
 	}
 
 	if state == StateHalfOpen {
 		if cb.halfOpenRequests >= cb.maxRequests {
+			cb.recordRejected()
 			return generation, ErrTooManyRequests
 		}
 		cb.halfOpenRequests++
 	}
 
-	cb.counts.Requests++
 	return generation, nil
 }
 
-// afterRequest records the result of a request
+// afterRequest records the result of a request of unknown duration
 func (cb *CircuitBreaker) afterRequest(generation uint64, err error) {
+	cb.afterRequestTimed(generation, err, 0)
+}
+
+// afterRequestTimed records the result and duration of a request
+func (cb *CircuitBreaker) afterRequestTimed(generation uint64, err error, elapsed time.Duration) {
 	cb.mu.Lock()
 	defer cb.mu.Unlock()
 
-	now := time.Now()
+	now := cb.now()
 	state, currentGeneration := cb.currentState(now)
 
 	// Ignore if generation doesn't match (state changed during request)
@@ -231,62 +409,72 @@ func (cb *CircuitBreaker) afterRequest(generation uint64, err error) {
 		return
 	}
 
-	if cb.isFailure(err) {
-		cb.counts.TotalFailures++
-		cb.counts.ConsecutiveFailures++
-		cb.counts.ConsecutiveSuccesses = 0
+	failed := cb.isFailure(err)
+	slow := cb.slowCallThreshold > 0 && elapsed >= cb.slowCallThreshold
+	cb.window.record(now, callOutcome{failure: failed, slow: slow})
 
-		if state == StateHalfOpen {
-			// Failed during half-open, go back to open
-			cb.setState(StateOpen, now)
-		} else if state == StateClosed {
-			// Check if we should open the circuit
-			if cb.shouldOpen() {
-				cb.setState(StateOpen, now)
-			}
+	if cb.collector != nil {
+		if failed {
+			cb.collector.RecordFailure(cb.name)
+		} else {
+			cb.collector.RecordSuccess(cb.name)
+		}
+		if slow {
+			cb.collector.RecordSlowCall(cb.name)
 		}
+	}
+
+	if failed {
+		cb.counts.ConsecutiveFailures++
+		cb.counts.ConsecutiveSuccesses = 0
 	} else {
-		cb.counts.TotalSuccesses++
 		cb.counts.ConsecutiveSuccesses++
 		cb.counts.ConsecutiveFailures = 0
+	}
 
-		if state == StateHalfOpen {
-			// Check if we should close the circuit
-			if cb.counts.ConsecutiveSuccesses >= cb.successThreshold {
-				cb.setState(StateClosed, now)
-			}
+	switch state {
+	case StateHalfOpen:
+		if failed || slow {
+			// Failed or slow during half-open, go back to open
+			cb.setState(StateOpen, now)
+		} else if cb.counts.ConsecutiveSuccesses >= cb.successThreshold {
+			// Enough successes, close the circuit
+			cb.setState(StateClosed, now)
+		}
+	case StateClosed:
+		// Check if we should open the circuit
+		if (failed || slow) && cb.shouldOpen(now) {
+			cb.setState(StateOpen, now)
 		}
 	}
 }
 
 // currentState returns the current state and generation
 func (cb *CircuitBreaker) currentState(now time.Time) (State, uint64) {
-	switch cb.state {
-	case StateClosed:
-		// Check if interval has expired, reset counts
-		if cb.interval > 0 && now.Sub(cb.stateChangedAt) > cb.interval {
-			cb.resetCounts()
-			cb.stateChangedAt = now
-		}
-	case StateOpen:
-		// Check if timeout has expired, transition to half-open
-		if now.Sub(cb.stateChangedAt) >= cb.timeout {
-			cb.setState(StateHalfOpen, now)
-		}
+	// Check if timeout has expired, transition to half-open
+	if cb.state == StateOpen && now.Sub(cb.stateChangedAt) >= cb.timeout {
+		cb.setState(StateHalfOpen, now)
 	}
 
 	return cb.state, cb.generation
 }
 
-// shouldOpen determines if the circuit should open based on failure threshold
-func (cb *CircuitBreaker) shouldOpen() bool {
-	if cb.counts.Requests < 10 {
-		// Need minimum number of requests before opening
+// shouldOpen determines if the circuit should open based on the failure or slow-call rate
+// in the window
+func (cb *CircuitBreaker) shouldOpen(now time.Time) bool {
+	counts := cb.window.counts(now)
+	if counts.calls == 0 || counts.calls < cb.minimumCalls {
+		// Need minimum number of calls before opening
 		return false
 	}
 
-	failureRate := float64(cb.counts.TotalFailures) / float64(cb.counts.Requests)
-	return failureRate >= cb.failureThreshold
+	failureRate := float64(counts.failures) / float64(counts.calls)
+	if failureRate >= cb.failureThreshold {
+		return true
+	}
+
+	slowRate := float64(counts.slow) / float64(counts.calls)
+	return cb.slowCallThreshold > 0 && slowRate >= cb.slowRateThreshold
 }
 
 // setState changes the circuit breaker state
@@ -307,15 +495,50 @@ func (cb *CircuitBreaker) setState(newState State, now time.Time) {
 		cb.halfOpenRequests = 0
 	}
 
+	if cb.collector != nil {
+		cb.collector.RecordTransition(cb.name, stateLabel(oldState), stateLabel(newState))
+	}
+
 	// Call state change callback
 	if cb.onStateChange != nil {
 		cb.onStateChange(oldState, newState)
 	}
 }
 
-// resetCounts resets all counters
+// recordRejected reports a request rejected by the breaker
+func (cb *CircuitBreaker) recordRejected() {
+	if cb.collector != nil {
+		cb.collector.RecordRejected(cb.name)
+	}
+}
+
+// stateLabel converts a State into its metrics label value
+func stateLabel(s State) string {
+	switch s {
+	case StateOpen:
+		return metrics.BreakerStateOpen
+	case StateHalfOpen:
+		return metrics.BreakerStateHalfOpen
+	default:
+		return metrics.BreakerStateClosed
+	}
+}
+
+// resetCounts resets all counters and empties the window
 func (cb *CircuitBreaker) resetCounts() {
 	cb.counts = Counts{}
+	cb.window.reset()
+}
+
+// snapshotCounts combines the window totals with the consecutive counts
+func (cb *CircuitBreaker) snapshotCounts(now time.Time) Counts {
+	window := cb.window.counts(now)
+	counts := cb.counts
+	counts.Requests = window.calls
+	counts.TotalFailures = window.failures
+	counts.TotalSuccesses = window.calls - window.failures
+	counts.SlowCalls = window.slow
+	return counts
 }
 
 // State returns the current circuit breaker state
@@ -328,28 +551,28 @@ func (cb *CircuitBreaker) State() State {
 
 // Counts returns the current circuit breaker counts
 func (cb *CircuitBreaker) GetCounts() Counts {
-	cb.mu.RLock()
-	defer cb.mu.RUnlock()
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
 
-	return cb.counts
+	return cb.snapshotCounts(cb.now())
 }
 
 // Stats returns detailed statistics about the circuit breaker
 type Stats struct {
-	State                State
-	Counts               Counts
-	StateChangedAt       time.Time
-	Generation           uint64
+	State          State
+	Counts         Counts
+	StateChangedAt time.Time
+	Generation     uint64
 }
 
 // GetStats returns current statistics
 func (cb *CircuitBreaker) GetStats() Stats {
-	cb.mu.RLock()
-	defer cb.mu.RUnlock()
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
 
 	return Stats{
 		State:          cb.state,
-		Counts:         cb.counts,
+		Counts:         cb.snapshotCounts(cb.now()),
 		StateChangedAt: cb.stateChangedAt,
 		Generation:     cb.generation,
 	}
@@ -360,5 +583,5 @@ func (cb *CircuitBreaker) Reset() {
 	cb.mu.Lock()
 	defer cb.mu.Unlock()
 
-	cb.setState(StateClosed, time.Now())
+	cb.setState(StateClosed, cb.now())
 }
@@ -3,9 +3,14 @@ package middleware
 import (
 	"context"
 	"errors"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/grpc-guardian/grpc-guardian/guardiantest"
+	"github.com/grpc-guardian/grpc-guardian/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -14,7 +19,7 @@ import (
 func TestCircuitBreakerStateMachine(t *testing.T) {
 	cb := NewCircuitBreaker(
 		WithFailureThreshold(0.5),
-		WithTimeout(100*time.Millisecond),
+		WithOpenTimeout(100*time.Millisecond),
 		WithMaxRequests(2),
 		WithSuccessThreshold(2),
 	)
@@ -76,7 +81,7 @@ func TestCircuitBreakerStateMachine(t *testing.T) {
 func TestCircuitBreakerMiddleware(t *testing.T) {
 	cbMiddleware := CircuitBreakerMiddleware(
 		WithFailureThreshold(0.5),
-		WithTimeout(100*time.Millisecond),
+		WithOpenTimeout(100*time.Millisecond),
 	)
 
 	// Successful handler
@@ -160,7 +165,7 @@ func TestCircuitBreakerStateCallback(t *testing.T) {
 
 	cb := NewCircuitBreaker(
 		WithFailureThreshold(0.5),
-		WithTimeout(50*time.Millisecond),
+		WithOpenTimeout(50*time.Millisecond),
 		WithOnStateChange(func(from, to State) {
 			stateChanges = append(stateChanges, struct {
 				from State
@@ -243,8 +248,175 @@ func TestCircuitBreakerReset(t *testing.T) {
 	}
 }
 
+func TestCircuitBreakerMetricsCollector(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	collector, err := metrics.NewCircuitBreakerCollector(registry)
+	if err != nil {
+		t.Fatalf("Failed to create collector: %v", err)
+	}
+
+	cb := NewCircuitBreaker(
+		WithBreakerName("payments"),
+		WithMetricsCollector(collector),
+		WithFailureThreshold(0.5),
+	)
+
+	// Trip the circuit: 10 failures open it, the next request is rejected
+	for i := 0; i < 11; i++ {
+		gen, err := cb.beforeRequest()
+		if err != nil {
+			break
+		}
+		cb.afterRequest(gen, errors.New("simulated failure"))
+	}
+	if _, err := cb.beforeRequest(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Expected ErrCircuitOpen, got %v", err)
+	}
+
+	expected := `
+# HELP grpc_circuit_breaker_requests_total Total number of requests seen by circuit breakers by result
+# TYPE grpc_circuit_breaker_requests_total counter
+grpc_circuit_breaker_requests_total{breaker="payments",result="failure"} 10
+grpc_circuit_breaker_requests_total{breaker="payments",result="rejected"} 2
+# HELP grpc_circuit_breaker_state Current circuit breaker state (1 for the active state)
+# TYPE grpc_circuit_breaker_state gauge
+grpc_circuit_breaker_state{breaker="payments",state="closed"} 0
+grpc_circuit_breaker_state{breaker="payments",state="half_open"} 0
+grpc_circuit_breaker_state{breaker="payments",state="open"} 1
+# HELP grpc_circuit_breaker_transitions_total Total number of circuit breaker state transitions
+# TYPE grpc_circuit_breaker_transitions_total counter
+grpc_circuit_breaker_transitions_total{breaker="payments",from="closed",to="open"} 1
+`
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(expected)); err != nil {
+		t.Errorf("Unexpected circuit breaker metrics: %v", err)
+	}
+
+	// A second collector on the same registry shares the metrics
+	if _, err := metrics.NewCircuitBreakerCollector(registry); err != nil {
+		t.Errorf("Expected re-registration to reuse metrics, got %v", err)
+	}
+}
+
+func TestCircuitBreakerCountBasedWindow(t *testing.T) {
+	cb := NewCircuitBreaker(
+		WithCountBasedWindow(4),
+		WithMinimumCalls(20), // capped at the window size
+		WithFailureThreshold(0.5),
+	)
+
+	// Old failures slide out of the window instead of adding up
+	outcomes := []bool{true, false, false, false, false, true}
+	for _, failed := range outcomes {
+		gen, err := cb.beforeRequest()
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if failed {
+			cb.afterRequest(gen, errors.New("failure"))
+		} else {
+			cb.afterRequest(gen, nil)
+		}
+	}
+	if counts := cb.GetCounts(); counts.Requests != 4 || counts.TotalFailures != 1 || cb.State() != StateClosed {
+		t.Fatalf("Expected 1 failure in the last 4 calls, got %+v in %v", counts, cb.State())
+	}
+
+	gen, _ := cb.beforeRequest()
+	cb.afterRequest(gen, errors.New("failure"))
+	if cb.State() != StateOpen {
+		t.Errorf("Expected 2 failures in the last 4 calls to open the circuit, got %v", cb.State())
+	}
+}
+
+func TestCircuitBreakerTimeBasedWindow(t *testing.T) {
+	cb := NewCircuitBreaker(
+		WithTimeBasedWindow(time.Second),
+		WithMinimumCalls(4),
+		WithFailureThreshold(0.5),
+	)
+	now := time.Now()
+
+	// Failures just before the window slides still count together with later ones
+	cb.mu.Lock()
+	cb.window.record(now, callOutcome{failure: true})
+	cb.window.record(now, callOutcome{})
+	cb.window.record(now.Add(500*time.Millisecond), callOutcome{})
+	cb.mu.Unlock()
+	if counts := cb.snapshotCounts(now.Add(900 * time.Millisecond)); counts.Requests != 3 {
+		t.Errorf("Expected 3 calls in the window, got %+v", counts)
+	}
+	if counts := cb.snapshotCounts(now.Add(1200 * time.Millisecond)); counts.Requests != 1 {
+		t.Errorf("Expected calls older than the window to expire, got %+v", counts)
+	}
+
+	cb.mu.Lock()
+	cb.window.record(now.Add(1200*time.Millisecond), callOutcome{failure: true})
+	cb.mu.Unlock()
+	if cb.shouldOpen(now.Add(1200 * time.Millisecond)) {
+		t.Error("Expected the breaker to wait for the minimum number of calls")
+	}
+	cb.mu.Lock()
+	cb.window.record(now.Add(1300*time.Millisecond), callOutcome{failure: true})
+	cb.window.record(now.Add(1300*time.Millisecond), callOutcome{})
+	cb.mu.Unlock()
+	if !cb.shouldOpen(now.Add(1300 * time.Millisecond)) {
+		t.Error("Expected 2 failures in 4 calls to open the circuit")
+	}
+}
+
+func TestCircuitBreakerSlowCalls(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	collector, err := metrics.NewCircuitBreakerCollector(registry)
+	if err != nil {
+		t.Fatalf("Failed to create collector: %v", err)
+	}
+
+	cb := NewCircuitBreaker(
+		WithBreakerName("search"),
+		WithMetricsCollector(collector),
+		WithCountBasedWindow(10),
+		WithMinimumCalls(4),
+		WithSlowCallThreshold(100*time.Millisecond),
+		WithSlowCallRateThreshold(0.5),
+		WithOpenTimeout(50*time.Millisecond),
+	)
+
+	// Successful but slow calls trip the breaker once half of the window is slow
+	for i, elapsed := range []time.Duration{10 * time.Millisecond, 200 * time.Millisecond, 10 * time.Millisecond, 150 * time.Millisecond} {
+		gen, err := cb.beforeRequest()
+		if err != nil {
+			t.Fatalf("Call %d: unexpected error %v", i, err)
+		}
+		cb.afterRequestTimed(gen, nil, elapsed)
+	}
+	if cb.State() != StateOpen {
+		t.Fatalf("Expected slow calls to open the circuit, got %v", cb.State())
+	}
+	expected := `
+# HELP grpc_circuit_breaker_slow_calls_total Total number of calls that took at least the slow-call threshold
+# TYPE grpc_circuit_breaker_slow_calls_total counter
+grpc_circuit_breaker_slow_calls_total{breaker="search"} 2
+`
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(expected), "grpc_circuit_breaker_slow_calls_total"); err != nil {
+		t.Error(err)
+	}
+	if counts := cb.GetCounts(); counts.SlowCalls != 0 {
+		t.Errorf("Expected the window to be emptied on opening, got %+v", counts)
+	}
+
+	// A slow probe in half-open state reopens the circuit
+	time.Sleep(60 * time.Millisecond)
+	gen, err := cb.beforeRequest()
+	if err != nil {
+		t.Fatalf("Expected a half-open probe, got %v", err)
+	}
+	cb.afterRequestTimed(gen, nil, time.Second)
+	if cb.State() != StateOpen {
+		t.Errorf("Expected a slow probe to reopen the circuit, got %v", cb.State())
+	}
+}
+
 func BenchmarkCircuitBreakerClosed(b *testing.B) {
-	cb := NewCircuitBreaker()
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
 		return "ok", nil
 	}
@@ -283,3 +455,83 @@ func BenchmarkCircuitBreakerOpen(b *testing.B) {
 		})
 	}
 }
+
+func TestCircuitBreakerFakeClock(t *testing.T) {
+	clock := guardiantest.NewClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	cb := NewCircuitBreaker(
+		WithBreakerClock(clock.Now),
+		WithOpenTimeout(30*time.Second),
+		WithCountBasedWindow(4),
+		WithMinimumCalls(4),
+		WithFailureThreshold(0.5),
+		WithSlowCallThreshold(time.Second),
+	)
+	interceptor := cb.UnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/test.Service/Get"}
+	failing := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, status.Error(codes.Unavailable, "down")
+	}
+
+	for i := 0; i < 4; i++ {
+		_, _ = interceptor(context.Background(), nil, info, failing)
+	}
+	if cb.State() != StateOpen {
+		t.Fatalf("Expected the breaker to open, got %v", cb.State())
+	}
+
+	clock.Advance(29 * time.Second)
+	if _, err := interceptor(context.Background(), nil, info, failing); status.Code(err) != codes.Unavailable || !strings.Contains(err.Error(), "circuit breaker") {
+		t.Errorf("Expected the breaker to reject calls before the timeout, got %v", err)
+	}
+
+	// A probe taking 2s on the fake clock is slow and reopens the circuit
+	clock.Advance(time.Second)
+	_, _ = interceptor(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		clock.Advance(2 * time.Second)
+		return "ok", nil
+	})
+	if cb.State() != StateOpen {
+		t.Errorf("Expected the slow probe to reopen the breaker, got %v", cb.State())
+	}
+	if stats := cb.GetStats(); !stats.StateChangedAt.Equal(clock.Now()) {
+		t.Errorf("Expected the state change at the fake time, got %v", stats.StateChangedAt)
+	}
+}
+
+func TestCircuitBreakerZeroInterval(t *testing.T) {
+	cb := NewCircuitBreaker(
+		WithInterval(0),
+		WithMinimumCalls(4),
+		WithFailureThreshold(0.5),
+	)
+	now := time.Now()
+
+	// Without an interval calls are never dropped while the breaker stays closed
+	cb.mu.Lock()
+	cb.window.record(now, callOutcome{failure: true})
+	cb.window.record(now.Add(time.Hour), callOutcome{})
+	cb.window.record(now.Add(24*time.Hour), callOutcome{failure: true})
+	cb.mu.Unlock()
+	if counts := cb.snapshotCounts(now.Add(48 * time.Hour)); counts.Requests != 3 || counts.TotalFailures != 2 {
+		t.Errorf("Expected all calls to be kept, got %+v", counts)
+	}
+
+	cb.mu.Lock()
+	cb.window.record(now.Add(48*time.Hour), callOutcome{})
+	cb.mu.Unlock()
+	if !cb.shouldOpen(now.Add(48 * time.Hour)) {
+		t.Error("Expected 2 failures in 4 calls to open the circuit")
+	}
+
+	cb.mu.Lock()
+	cb.resetCounts()
+	cb.mu.Unlock()
+	if counts := cb.snapshotCounts(now.Add(48 * time.Hour)); counts.Requests != 0 {
+		t.Errorf("Expected a state change to clear the counts, got %+v", counts)
+	}
+
+	// A negative interval is ignored
+	if cb := NewCircuitBreaker(WithInterval(-time.Second)); cb.interval != 60*time.Second {
+		t.Errorf("Expected the default interval, got %v", cb.interval)
+	}
+}
@@ -0,0 +1,154 @@
+package middleware
+
+import "time"
+
+// timeWindowBuckets is the number of buckets a time-based window is split into. Calls
+// leave the window one bucket at a time, so it slides in steps of a tenth of its length.
+const timeWindowBuckets = 10
+
+// windowCounts aggregates the outcomes of the calls in a window
+type windowCounts struct {
+	calls    uint32
+	failures uint32
+	slow     uint32
+}
+
+// callOutcome is the result of one call
+type callOutcome struct {
+	failure bool
+	slow    bool
+}
+
+func (c *windowCounts) add(outcome callOutcome) {
+	c.calls++
+	if outcome.failure {
+		c.failures++
+	}
+	if outcome.slow {
+		c.slow++
+	}
+}
+
+func (c *windowCounts) sub(other windowCounts) {
+	c.calls -= other.calls
+	c.failures -= other.failures
+	c.slow -= other.slow
+}
+
+// slidingWindow holds the outcomes a circuit breaker decides on. Unlike a counter reset
+// every interval, it never forgets all calls at once, so there is no blind spot after a
+// reset.
+type slidingWindow interface {
+	record(now time.Time, outcome callOutcome)
+	counts(now time.Time) windowCounts
+	reset()
+}
+
+// countWindow keeps the outcomes of the last size calls in a ring buffer
+type countWindow struct {
+	outcomes []callOutcome
+	next     int
+	filled   bool
+	totals   windowCounts
+}
+
+func newCountWindow(size int) *countWindow {
+	return &countWindow{outcomes: make([]callOutcome, size)}
+}
+
+func (w *countWindow) record(now time.Time, outcome callOutcome) {
+	if w.filled {
+		var evicted windowCounts
+		evicted.add(w.outcomes[w.next])
+		w.totals.sub(evicted)
+	}
+
+	w.outcomes[w.next] = outcome
+	w.totals.add(outcome)
+
+	w.next++
+	if w.next == len(w.outcomes) {
+		w.next = 0
+		w.filled = true
+	}
+}
+
+func (w *countWindow) counts(now time.Time) windowCounts {
+	return w.totals
+}
+
+func (w *countWindow) reset() {
+	w.next, w.filled, w.totals = 0, false, windowCounts{}
+}
+
+// cumulativeWindow keeps the outcomes of all calls until it is reset, which happens when
+// the breaker changes state
+type cumulativeWindow struct {
+	totals windowCounts
+}
+
+func (w *cumulativeWindow) record(now time.Time, outcome callOutcome) {
+	w.totals.add(outcome)
+}
+
+func (w *cumulativeWindow) counts(now time.Time) windowCounts {
+	return w.totals
+}
+
+func (w *cumulativeWindow) reset() {
+	w.totals = windowCounts{}
+}
+
+// timeWindow keeps the outcomes of the calls made in the last size, in buckets
+type timeWindow struct {
+	width   time.Duration
+	buckets [timeWindowBuckets]timeBucket
+	totals  windowCounts
+}
+
+// timeBucket holds the calls made in one bucket-wide slice of time
+type timeBucket struct {
+	epoch  int64 // Start of the slice, in bucket widths since the Unix epoch
+	counts windowCounts
+}
+
+func newTimeWindow(size time.Duration) *timeWindow {
+	width := size / timeWindowBuckets
+	if width <= 0 {
+		width = 1
+	}
+	return &timeWindow{width: width}
+}
+
+func (w *timeWindow) record(now time.Time, outcome callOutcome) {
+	epoch := now.UnixNano() / int64(w.width)
+	w.expire(epoch)
+
+	bucket := &w.buckets[epoch%timeWindowBuckets]
+	if bucket.epoch != epoch {
+		w.totals.sub(bucket.counts)
+		*bucket = timeBucket{epoch: epoch}
+	}
+	bucket.counts.add(outcome)
+	w.totals.add(outcome)
+}
+
+func (w *timeWindow) counts(now time.Time) windowCounts {
+	w.expire(now.UnixNano() / int64(w.width))
+	return w.totals
+}
+
+// expire drops the buckets that slid out of the window
+func (w *timeWindow) expire(epoch int64) {
+	for i := range w.buckets {
+		bucket := &w.buckets[i]
+		if bucket.counts.calls > 0 && bucket.epoch <= epoch-timeWindowBuckets {
+			w.totals.sub(bucket.counts)
+			bucket.counts = windowCounts{}
+		}
+	}
+}
+
+func (w *timeWindow) reset() {
+	w.buckets, w.totals = [timeWindowBuckets]timeBucket{}, windowCounts{}
+}
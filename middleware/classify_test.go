@@ -0,0 +1,173 @@
+package middleware
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/grpc-guardian/grpc-guardian/chaos"
+	"github.com/grpc-guardian/grpc-guardian/pkg/classify"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// registerClassifyTestService registers a service whose methods carry idempotency_level options
+func registerClassifyTestService(t *testing.T) {
+	t.Helper()
+
+	if _, err := protoregistry.GlobalFiles.FindDescriptorByName("classifytest.Ledger"); err == nil {
+		return
+	}
+
+	method := func(name string, level descriptorpb.MethodOptions_IdempotencyLevel) *descriptorpb.MethodDescriptorProto {
+		return &descriptorpb.MethodDescriptorProto{
+			Name:       proto.String(name),
+			InputType:  proto.String(".classifytest.Empty"),
+			OutputType: proto.String(".classifytest.Empty"),
+			Options:    &descriptorpb.MethodOptions{IdempotencyLevel: level.Enum()},
+		}
+	}
+
+	file, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:        proto.String("classifytest/ledger.proto"),
+		Package:     proto.String("classifytest"),
+		Syntax:      proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{{Name: proto.String("Empty")}},
+		Service: []*descriptorpb.ServiceDescriptorProto{{
+			Name: proto.String("Ledger"),
+			Method: []*descriptorpb.MethodDescriptorProto{
+				method("Balance", descriptorpb.MethodOptions_NO_SIDE_EFFECTS),
+				method("GetAndIncrement", descriptorpb.MethodOptions_IDEMPOTENT),
+				method("Transfer", descriptorpb.MethodOptions_IDEMPOTENCY_UNKNOWN),
+			},
+		}},
+	}, protoregistry.GlobalFiles)
+	if err != nil {
+		t.Fatalf("failed to build descriptor: %v", err)
+	}
+	if err := protoregistry.GlobalFiles.RegisterFile(file); err != nil {
+		t.Fatalf("failed to register descriptor: %v", err)
+	}
+}
+
+func TestClassifier(t *testing.T) {
+	registerClassifyTestService(t)
+
+	classifier := classify.New(
+		classify.WithReads("/shop.Orders/Preview"),
+		classify.WithWrites("/shop.Orders/GetOrCreateCart", "/admin.Jobs/*"),
+	)
+
+	tests := []struct {
+		method string
+		want   classify.Kind
+	}{
+		{"/shop.Orders/GetOrder", classify.Read},
+		{"/shop.Orders/ListOrders", classify.Read},
+		{"/shop.Orders/Get", classify.Read},
+		{"/shop.Orders/CreateOrder", classify.Write},
+		{"/shop.Orders/DeleteOrder", classify.Write},
+		{"/shop.Orders/Getaway", classify.Unknown},
+		{"/shop.Orders/Checkout", classify.Unknown},
+		// Configured patterns win over heuristics
+		{"/shop.Orders/Preview", classify.Read},
+		{"/shop.Orders/GetOrCreateCart", classify.Write},
+		{"/admin.Jobs/GetStatus", classify.Write},
+		// Proto options win over heuristics
+		{"/classifytest.Ledger/Balance", classify.Read},
+		{"/classifytest.Ledger/GetAndIncrement", classify.Write},
+		{"/classifytest.Ledger/Transfer", classify.Unknown},
+	}
+
+	for _, tt := range tests {
+		if got := classifier.Classify(tt.method); got != tt.want {
+			t.Errorf("Classify(%s) = %v, want %v", tt.method, got, tt.want)
+		}
+	}
+
+	if !classifier.IsWrite("/shop.Orders/Checkout") {
+		t.Error("Expected unknown methods to count as writes")
+	}
+
+	strict := classify.New(classify.WithoutNamingHeuristics(), classify.WithoutProtoOptions())
+	if got := strict.Classify("/classifytest.Ledger/Balance"); got != classify.Unknown {
+		t.Errorf("Expected Unknown without heuristics and proto options, got %v", got)
+	}
+}
+
+func TestRetry_Classifier(t *testing.T) {
+	retry := NewRetry(
+		WithMaxAttempts(3),
+		WithInitialBackoff(time.Millisecond),
+		WithRetryClassifier(classify.New()),
+	)
+	interceptor := retry.UnaryServerInterceptor()
+
+	for method, want := range map[string]int{
+		"/shop.Orders/GetOrder":    3,
+		"/shop.Orders/CreateOrder": 1,
+		"/shop.Orders/Checkout":    1,
+	} {
+		calls := 0
+		_, err := interceptor(context.Background(), "req", &grpc.UnaryServerInfo{FullMethod: method},
+			func(ctx context.Context, req interface{}) (interface{}, error) {
+				calls++
+				return nil, status.Error(codes.Unavailable, "unavailable")
+			})
+		if status.Code(err) != codes.Unavailable {
+			t.Errorf("%s: expected Unavailable, got %v", method, err)
+		}
+		if calls != want {
+			t.Errorf("%s: expected %d attempts, got %d", method, want, calls)
+		}
+	}
+}
+
+func TestCache_Classifier(t *testing.T) {
+	interceptor := Cache(WithCacheClassifier(classify.New()))
+
+	for method, want := range map[string]int{
+		"/shop.Orders/GetOrder":    1,
+		"/shop.Orders/CreateOrder": 2,
+	} {
+		calls := 0
+		handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+			calls++
+			return "ok", nil
+		}
+		for i := 0; i < 2; i++ {
+			if _, err := interceptor(context.Background(), "req", &grpc.UnaryServerInfo{FullMethod: method}, handler); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if calls != want {
+			t.Errorf("%s: expected %d handler calls, got %d", method, want, calls)
+		}
+	}
+}
+
+func TestChaos_Classifier(t *testing.T) {
+	inject := []chaos.ChaosOption{
+		chaos.WithErrors([]codes.Code{codes.Unavailable}, 1.0),
+		chaos.WithClassifier(classify.New()),
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) { return "ok", nil }
+
+	interceptor := chaos.New(inject...)
+	if _, err := interceptor(context.Background(), "req", &grpc.UnaryServerInfo{FullMethod: "/shop.Orders/GetOrder"}, handler); status.Code(err) != codes.Unavailable {
+		t.Errorf("Expected chaos on reads, got %v", err)
+	}
+	if _, err := interceptor(context.Background(), "req", &grpc.UnaryServerInfo{FullMethod: "/shop.Orders/CreateOrder"}, handler); err != nil {
+		t.Errorf("Expected writes to be excluded, got %v", err)
+	}
+
+	interceptor = chaos.New(append(inject, chaos.WithWritesIncluded())...)
+	if _, err := interceptor(context.Background(), "req", &grpc.UnaryServerInfo{FullMethod: "/shop.Orders/CreateOrder"}, handler); status.Code(err) != codes.Unavailable {
+		t.Errorf("Expected chaos on writes with WithWritesIncluded, got %v", err)
+	}
+}
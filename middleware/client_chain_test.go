@@ -0,0 +1,125 @@
+package middleware
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	guardian "github.com/grpc-guardian/grpc-guardian"
+	"github.com/grpc-guardian/grpc-guardian/pkg/metrics"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestClientChain_Order(t *testing.T) {
+	var calls []string
+	record := func(name string) guardian.ClientMiddleware {
+		return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+			calls = append(calls, name)
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+	}
+
+	chain := guardian.NewClientChain(record("a")).Append(record("b")).Prepend(record("first"))
+	err := chain.UnaryClientInterceptor()(context.Background(), "/test.Service/Method", nil, nil, nil,
+		func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+			calls = append(calls, "invoker")
+			return nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []string{"first", "a", "b", "invoker"}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("Expected %v, got %v", want, calls)
+	}
+}
+
+func TestClientChain_RetryAndMetrics(t *testing.T) {
+	var attempts atomic.Int32
+	server := grpc.NewServer()
+	server.RegisterService(&grpc.ServiceDesc{
+		ServiceName: "test.Flaky",
+		HandlerType: (*interface{})(nil),
+		Methods: []grpc.MethodDesc{{
+			MethodName: "Get",
+			Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, _ grpc.UnaryServerInterceptor) (interface{}, error) {
+				if attempts.Add(1) < 3 {
+					return nil, status.Error(codes.Unavailable, "warming up")
+				}
+				return wrapperspb.String("ok"), nil
+			},
+		}},
+	}, struct{}{})
+
+	lis := bufconn.Listen(1 << 20)
+	go server.Serve(lis)
+	defer server.Stop()
+
+	collector, err := metrics.NewPrometheusCollector(metrics.WithSubsystem("client"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	retry := NewRetry(WithMaxAttempts(3), WithInitialBackoff(time.Millisecond), WithJitter(false))
+	breaker := NewCircuitBreaker()
+
+	chain := guardian.NewClientChain().
+		Use(MetricsUnaryClientInterceptor(collector), MetricsStreamClientInterceptor(collector)).
+		Use(breaker.UnaryClientInterceptor(), breaker.StreamClientInterceptor()).
+		Use(retry.UnaryClientInterceptor(), retry.StreamClientInterceptor())
+
+	conn, err := grpc.Dial("bufnet", append(chain.DialOptions(),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	reply := &wrapperspb.StringValue{}
+	if err := conn.Invoke(context.Background(), "/test.Flaky/Get", wrapperspb.String("key"), reply); err != nil {
+		t.Fatalf("Expected the retried call to succeed, got %v", err)
+	}
+	if attempts.Load() != 3 || reply.GetValue() != "ok" {
+		t.Errorf("Expected 3 attempts and a reply, got %d attempts and %q", attempts.Load(), reply.GetValue())
+	}
+
+	// Metrics wrap the retries, so the call counts once
+	if got, _ := getMetricValue(collector.GetRegistry(), "grpc_client_requests_total"); got != 1 {
+		t.Errorf("Expected 1 client request, got %v", got)
+	}
+	if counts := breaker.GetCounts(); counts.Requests != 1 || counts.TotalFailures != 0 {
+		t.Errorf("Expected the breaker to see one successful call, got %+v", counts)
+	}
+}
+
+func TestCircuitBreaker_Client(t *testing.T) {
+	breaker := NewCircuitBreaker(WithFailureThreshold(0.5), WithOpenTimeout(time.Minute))
+	interceptor := breaker.UnaryClientInterceptor()
+
+	var invoked int
+	failing := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		invoked++
+		return status.Error(codes.Unavailable, "down")
+	}
+
+	for i := 0; i < 10; i++ {
+		_ = interceptor(context.Background(), "/test.Service/Method", nil, nil, nil, failing)
+	}
+	if breaker.State() != StateOpen {
+		t.Fatalf("Expected the breaker to open, got %v", breaker.State())
+	}
+
+	err := interceptor(context.Background(), "/test.Service/Method", nil, nil, nil, failing)
+	if status.Code(err) != codes.Unavailable || invoked != 10 {
+		t.Errorf("Expected an open breaker to fail fast without calling the server, got %v after %d calls", err, invoked)
+	}
+}
@@ -0,0 +1,314 @@
+package middleware
+
+import (
+	"context"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// LimitAlgorithm selects how the concurrency limit adapts to measured load
+type LimitAlgorithm int
+
+const (
+	// AIMD grows the limit by one while requests succeed within the latency threshold and
+	// multiplies it by the backoff ratio when requests are dropped or slow
+	AIMD LimitAlgorithm = iota
+
+	// Gradient scales the limit by the ratio of the no-load latency to the current latency,
+	// so the limit shrinks as soon as requests start to queue
+	Gradient
+)
+
+// String returns the algorithm name
+func (a LimitAlgorithm) String() string {
+	if a == Gradient {
+		return "gradient"
+	}
+	return "aimd"
+}
+
+// ConcurrencyLimiter sheds requests above an adaptive concurrency limit. It tracks the
+// number of in-flight requests and their latency and adjusts the limit with AIMD or a
+// latency gradient, in the style of Netflix concurrency-limits.
+type ConcurrencyLimiter struct {
+	algorithm        LimitAlgorithm
+	initialLimit     int
+	minLimit         int
+	maxLimit         int
+	latencyThreshold time.Duration
+	backoffRatio     float64
+	smoothing        float64
+	probeInterval    int
+	lowPriority      map[string]bool
+	matcher          *methodMatcher[bool]
+	lowPriorityShare float64
+	onLimitChange    func(from, to int)
+
+	mu       sync.Mutex
+	limit    float64
+	inFlight int
+	minRTT   time.Duration
+	samples  int
+
+	lastShed atomic.Int64
+}
+
+// ConcurrencyLimitOption configures a ConcurrencyLimiter
+type ConcurrencyLimitOption func(*ConcurrencyLimiter)
+
+// WithLimitAlgorithm sets how the limit adapts
+// Default: AIMD
+func WithLimitAlgorithm(algorithm LimitAlgorithm) ConcurrencyLimitOption {
+	return func(l *ConcurrencyLimiter) {
+		l.algorithm = algorithm
+	}
+}
+
+// WithInitialLimit sets the concurrency limit used before any latency is measured
+// Default: 20
+func WithInitialLimit(n int) ConcurrencyLimitOption {
+	return func(l *ConcurrencyLimiter) {
+		if n > 0 {
+			l.initialLimit = n
+		}
+	}
+}
+
+// WithLimitBounds sets the range the limit adapts within
+// Default: 1 to 1000
+func WithLimitBounds(min, max int) ConcurrencyLimitOption {
+	return func(l *ConcurrencyLimiter) {
+		if min > 0 && max >= min {
+			l.minLimit = min
+			l.maxLimit = max
+		}
+	}
+}
+
+// WithLatencyThreshold sets the latency above which AIMD treats a request as dropped
+// Default: 1s
+func WithLatencyThreshold(d time.Duration) ConcurrencyLimitOption {
+	return func(l *ConcurrencyLimiter) {
+		if d > 0 {
+			l.latencyThreshold = d
+		}
+	}
+}
+
+// WithBackoffRatio sets the factor the limit is multiplied by when requests are dropped
+// Default: 0.9
+func WithBackoffRatio(ratio float64) ConcurrencyLimitOption {
+	return func(l *ConcurrencyLimiter) {
+		if ratio > 0 && ratio < 1 {
+			l.backoffRatio = ratio
+		}
+	}
+}
+
+// WithLowPriorityMethods marks method patterns that are shed first: they are only admitted
+// while fewer than the low-priority share of the limit is in use.
+// Patterns follow the Authorization rules: "/pkg.Service/Method", "/pkg.Service/*" or "*".
+func WithLowPriorityMethods(patterns ...string) ConcurrencyLimitOption {
+	return func(l *ConcurrencyLimiter) {
+		for _, pattern := range patterns {
+			l.lowPriority[pattern] = true
+		}
+	}
+}
+
+// WithLowPriorityShare sets the share of the limit available to low-priority methods and
+// sheddable requests (see PriorityHeader)
+// Default: 0.8
+func WithLowPriorityShare(share float64) ConcurrencyLimitOption {
+	return func(l *ConcurrencyLimiter) {
+		if share > 0 && share <= 1 {
+			l.lowPriorityShare = share
+		}
+	}
+}
+
+// WithOnLimitChange sets a callback invoked when the limit changes
+func WithOnLimitChange(fn func(from, to int)) ConcurrencyLimitOption {
+	return func(l *ConcurrencyLimiter) {
+		l.onLimitChange = fn
+	}
+}
+
+// NewConcurrencyLimiter creates an adaptive concurrency limiter.
+//
+// Example usage:
+//
+//	limiter := middleware.NewConcurrencyLimiter(
+//	    middleware.WithLimitAlgorithm(middleware.Gradient),
+//	    middleware.WithLimitBounds(10, 500),
+//	    middleware.WithLowPriorityMethods("/reports.Exports/*"),
+//	)
+//	chain := guardian.NewChain(limiter.UnaryServerInterceptor())
+//
+//	// Take the instance out of rotation while it sheds load
+//	reporter := health.NewReporter(healthServer, health.WithShedding(limiter.Shedding))
+func NewConcurrencyLimiter(opts ...ConcurrencyLimitOption) *ConcurrencyLimiter {
+	l := &ConcurrencyLimiter{
+		algorithm:        AIMD,
+		initialLimit:     20,
+		minLimit:         1,
+		maxLimit:         1000,
+		latencyThreshold: time.Second,
+		backoffRatio:     0.9,
+		smoothing:        0.2,
+		probeInterval:    1000,
+		lowPriority:      make(map[string]bool),
+		lowPriorityShare: 0.8,
+	}
+
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	l.matcher = newMethodMatcher(l.lowPriority)
+	l.limit = math.Max(float64(l.minLimit), math.Min(float64(l.initialLimit), float64(l.maxLimit)))
+
+	return l
+}
+
+// LoadShedding returns a middleware that sheds requests above an adaptive concurrency limit
+func LoadShedding(opts ...ConcurrencyLimitOption) func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	return NewConcurrencyLimiter(opts...).UnaryServerInterceptor()
+}
+
+// UnaryServerInterceptor returns a unary server interceptor that sheds excess requests
+// with ResourceExhausted
+func (l *ConcurrencyLimiter) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		limit, ok := l.acquire(info.FullMethod, RequestPriority(ctx))
+		if !ok {
+			return nil, status.Errorf(codes.ResourceExhausted, "server overloaded: concurrency limit of %d reached\nHint: Retry with backoff or against another instance", limit)
+		}
+
+		start := time.Now()
+		completed := false
+		defer func() {
+			if !completed {
+				err = status.Error(codes.Internal, "handler panicked")
+			}
+			l.release(time.Since(start), err)
+		}()
+
+		resp, err = handler(ctx, req)
+		completed = true
+		return resp, err
+	}
+}
+
+// Limit returns the current concurrency limit
+func (l *ConcurrencyLimiter) Limit() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return int(l.limit)
+}
+
+// InFlight returns the number of requests being handled
+func (l *ConcurrencyLimiter) InFlight() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.inFlight
+}
+
+// Shedding reports whether a request was shed within the last second; pass it to
+// health.WithShedding
+func (l *ConcurrencyLimiter) Shedding() bool {
+	last := l.lastShed.Load()
+	return last != 0 && time.Since(time.Unix(0, last)) < time.Second
+}
+
+// acquire admits a request if it fits under the limit. Sheddable requests and
+// low-priority methods only get the low-priority share; critical requests always get the
+// full limit.
+func (l *ConcurrencyLimiter) acquire(method string, priority Priority) (int, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	limit := int(l.limit)
+	capacity := limit
+	if priority == PrioritySheddable {
+		capacity = int(float64(limit) * l.lowPriorityShare)
+	} else if lowPriority, ok := l.matcher.match(method); ok && lowPriority && priority != PriorityCritical {
+		capacity = int(float64(limit) * l.lowPriorityShare)
+	}
+
+	if l.inFlight >= capacity {
+		l.lastShed.Store(time.Now().UnixNano())
+		return limit, false
+	}
+
+	l.inFlight++
+	return limit, true
+}
+
+// release records the outcome of a request and adapts the limit
+func (l *ConcurrencyLimiter) release(latency time.Duration, err error) {
+	l.mu.Lock()
+
+	inFlight := l.inFlight
+	l.inFlight--
+
+	from := int(l.limit)
+	switch {
+	case isOverloadError(err) || (l.algorithm == AIMD && latency > l.latencyThreshold):
+		l.limit *= l.backoffRatio
+	case err != nil:
+		// Application errors say nothing about load
+	case l.algorithm == Gradient:
+		l.gradientSample(latency)
+	default:
+		// Only grow while the limit is actually being used
+		if float64(inFlight)*2 >= l.limit {
+			l.limit++
+		}
+	}
+	l.limit = math.Max(float64(l.minLimit), math.Min(l.limit, float64(l.maxLimit)))
+	to := int(l.limit)
+
+	l.mu.Unlock()
+
+	if from != to && l.onLimitChange != nil {
+		l.onLimitChange(from, to)
+	}
+}
+
+// gradientSample applies one latency sample to the gradient limit
+func (l *ConcurrencyLimiter) gradientSample(latency time.Duration) {
+	if latency <= 0 {
+		latency = time.Nanosecond
+	}
+
+	// Periodically forget the no-load latency so it can follow real changes
+	l.samples++
+	if l.minRTT == 0 || latency < l.minRTT || l.samples >= l.probeInterval {
+		l.minRTT = latency
+		l.samples = 0
+	}
+
+	gradient := math.Max(0.5, math.Min(1.0, float64(l.minRTT)/float64(latency)))
+	queueSize := math.Sqrt(l.limit)
+	target := l.limit*gradient + queueSize
+	l.limit = l.limit*(1-l.smoothing) + target*l.smoothing
+}
+
+// isOverloadError reports whether an error signals that the server or its dependencies
+// are overloaded
+func isOverloadError(err error) bool {
+	switch status.Code(err) {
+	case codes.ResourceExhausted, codes.Unavailable, codes.DeadlineExceeded:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,181 @@
+package middleware
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestConcurrencyLimiter_Shedding(t *testing.T) {
+	limiter := NewConcurrencyLimiter(
+		WithInitialLimit(4),
+		WithLimitBounds(4, 4),
+		WithLowPriorityMethods("/reports.Exports/*"),
+		WithLowPriorityShare(0.5),
+	)
+	interceptor := limiter.UnaryServerInterceptor()
+
+	release := make(chan struct{})
+	blocking := func(ctx context.Context, req interface{}) (interface{}, error) {
+		<-release
+		return "ok", nil
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) { return "ok", nil }
+
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			interceptor(context.Background(), "req", &grpc.UnaryServerInfo{FullMethod: "/shop.Orders/Get"}, blocking)
+		}()
+	}
+	waitForInFlight(t, limiter, 2)
+
+	// Low-priority methods only get half of the limit
+	_, err := interceptor(context.Background(), "req", &grpc.UnaryServerInfo{FullMethod: "/reports.Exports/Run"}, handler)
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("Expected low-priority request to be shed, got %v", err)
+	}
+	if !limiter.Shedding() {
+		t.Error("Expected Shedding() after a shed request")
+	}
+
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			interceptor(context.Background(), "req", &grpc.UnaryServerInfo{FullMethod: "/shop.Orders/Get"}, blocking)
+		}()
+	}
+	waitForInFlight(t, limiter, 4)
+
+	_, err = interceptor(context.Background(), "req", &grpc.UnaryServerInfo{FullMethod: "/shop.Orders/Get"}, handler)
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("Expected request above the limit to be shed, got %v", err)
+	}
+
+	close(release)
+	wg.Wait()
+
+	if _, err := interceptor(context.Background(), "req", &grpc.UnaryServerInfo{FullMethod: "/shop.Orders/Get"}, handler); err != nil {
+		t.Errorf("Expected request to be admitted after release, got %v", err)
+	}
+}
+
+func waitForInFlight(t *testing.T, limiter *ConcurrencyLimiter, n int) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for limiter.InFlight() != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected %d in-flight requests, got %d", n, limiter.InFlight())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestConcurrencyLimiter_AIMD(t *testing.T) {
+	var changes int
+	limiter := NewConcurrencyLimiter(
+		WithInitialLimit(10),
+		WithLimitBounds(5, 100),
+		WithBackoffRatio(0.5),
+		WithLatencyThreshold(20*time.Millisecond),
+		WithOnLimitChange(func(from, to int) { changes++ }),
+	)
+	info := &grpc.UnaryServerInfo{FullMethod: "/svc/M"}
+
+	// Dropped requests back off, down to the lower bound
+	for i := 0; i < 5; i++ {
+		limiter.UnaryServerInterceptor()(context.Background(), "req", info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			return nil, status.Error(codes.Unavailable, "overloaded")
+		})
+	}
+	if got := limiter.Limit(); got != 5 {
+		t.Errorf("Expected limit to back off to 5, got %d", got)
+	}
+
+	// Slow requests count as drops
+	limiter.inFlight = 5
+	limiter.release(50*time.Millisecond, nil)
+	if got := limiter.Limit(); got != 5 {
+		t.Errorf("Expected limit to stay at the lower bound, got %d", got)
+	}
+
+	// Application errors do not change the limit
+	limiter.inFlight = 5
+	limiter.release(time.Millisecond, status.Error(codes.InvalidArgument, "bad request"))
+	if got := limiter.Limit(); got != 5 {
+		t.Errorf("Expected application errors to leave the limit alone, got %d", got)
+	}
+
+	// Fast successes at high utilisation grow the limit additively
+	for i := 0; i < 3; i++ {
+		limiter.inFlight = limiter.Limit()
+		limiter.release(time.Millisecond, nil)
+	}
+	if got := limiter.Limit(); got != 8 {
+		t.Errorf("Expected limit to grow to 8, got %d", got)
+	}
+	if changes == 0 {
+		t.Error("Expected limit change callbacks")
+	}
+}
+
+func TestConcurrencyLimiter_Gradient(t *testing.T) {
+	limiter := NewConcurrencyLimiter(WithLimitAlgorithm(Gradient), WithInitialLimit(100))
+
+	// Establish the no-load latency, then report queueing
+	limiter.inFlight = 1
+	limiter.release(10*time.Millisecond, nil)
+	before := limiter.Limit()
+
+	for i := 0; i < 20; i++ {
+		limiter.inFlight = 1
+		limiter.release(40*time.Millisecond, nil)
+	}
+	if got := limiter.Limit(); got >= before {
+		t.Errorf("Expected limit to shrink when latency rises, got %d (was %d)", got, before)
+	}
+
+	shrunk := limiter.Limit()
+	for i := 0; i < 20; i++ {
+		limiter.inFlight = 1
+		limiter.release(10*time.Millisecond, nil)
+	}
+	if got := limiter.Limit(); got <= shrunk {
+		t.Errorf("Expected limit to recover when latency drops, got %d (was %d)", got, shrunk)
+	}
+}
+
+func TestConcurrencyLimiter_HandlerPanicReleasesSlot(t *testing.T) {
+	limiter := NewConcurrencyLimiter(WithInitialLimit(1), WithLimitBounds(1, 1))
+	interceptor := limiter.UnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/svc/M"}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("Expected the panic to propagate")
+			}
+		}()
+		interceptor(context.Background(), "req", info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			panic("boom")
+		})
+	}()
+
+	if got := limiter.InFlight(); got != 0 {
+		t.Fatalf("Expected the panicking request to release its slot, got %d in flight", got)
+	}
+	if _, err := interceptor(context.Background(), "req", info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "ok", nil
+	}); err != nil {
+		t.Errorf("Expected the next request to be admitted, got %v", err)
+	}
+}
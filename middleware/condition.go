@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"context"
+
+	"github.com/grpc-guardian/grpc-guardian/pkg/condition"
+	"google.golang.org/grpc"
+)
+
+// conditionEvaluator fills the principal variable of condition expressions from the
+// identity stored by Auth, and the geo variable from the location stored by GeoIP
+var conditionEvaluator = condition.NewEvaluator(
+	condition.WithPrincipalFunc(conditionPrincipal),
+	condition.WithGeoFunc(conditionGeo),
+)
+
+// ConditionEvaluator returns the evaluator the middleware use for condition expressions,
+// e.g. for chaos.WithTargetEvaluator
+func ConditionEvaluator() *condition.Evaluator {
+	return conditionEvaluator
+}
+
+// conditionPrincipal reads the authenticated caller from the context
+func conditionPrincipal(ctx context.Context) (condition.Principal, bool) {
+	var p condition.Principal
+	userID, hasUser := GetUserID(ctx)
+	clientID, hasClient := GetClientID(ctx)
+
+	p.UserID = userID
+	p.ClientID = clientID
+	p.Roles, _ = GetRoles(ctx)
+	p.Scopes, _ = GetScopes(ctx)
+	_, p.Subject = peerIdentity(ctx)
+
+	return p, hasUser || hasClient
+}
+
+// RateLimitIf applies a rate limiting middleware only to requests matching the condition;
+// other requests bypass the limiter. If the condition fails to evaluate, the limiter applies.
+//
+// Example usage:
+//
+//	chain := guardian.NewChain(
+//	    middleware.Auth(middleware.JWTValidator("secret")),
+//	    middleware.RateLimitIf(
+//	        condition.MustCompile(`!("premium" in principal.roles)`),
+//	        middleware.RateLimit(10, 20),
+//	    ),
+//	)
+func RateLimitIf(cond condition.Condition, limiter func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error)) func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if applies, err := conditionEvaluator.EvalRequest(ctx, cond, info.FullMethod, req); err == nil && !applies {
+			return handler(ctx, req)
+		}
+		return limiter(ctx, req, info, handler)
+	}
+}
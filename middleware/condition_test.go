@@ -0,0 +1,223 @@
+package middleware
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/grpc-guardian/grpc-guardian/pkg/condition"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestCondition_Expressions(t *testing.T) {
+	vars := condition.Vars{
+		"method":    "/shop.Orders/Create",
+		"service":   "shop.Orders",
+		"rpc":       "Create",
+		"metadata":  map[string]interface{}{"x-tenant": "beta-acme"},
+		"principal": map[string]interface{}{"roles": []string{"admin", "viewer"}, "user_id": "u1"},
+		"peer":      map[string]interface{}{"address": "10.0.0.1:5000"},
+		"request":   map[string]interface{}{"page_size": int64(250), "tags": []interface{}{"a", "b"}},
+	}
+
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{`service == "shop.Orders"`, true},
+		{`rpc != "Create"`, false},
+		{`"admin" in principal.roles`, true},
+		{`"owner" in principal.roles`, false},
+		{`has(metadata["x-tenant"]) && metadata["x-tenant"].startsWith("beta-")`, true},
+		{`has(metadata.missing)`, false},
+		{`"x-tenant" in metadata`, true},
+		{`request.page_size > 100 && request.page_size <= 250`, true},
+		{`request.page_size * 2 == 500.0`, true},
+		{`size(request.tags) == 2 && request.tags[1] == "b"`, true},
+		{`method.matches("^/shop\\.[A-Z]")`, true},
+		{`peer.address.endsWith(":5000") ? principal.user_id == "u1" : false`, true},
+		{`!(service.contains("Orders")) || int("7") + 1 == 8`, true},
+		{`[1, 2, 3] == [1, 2, 3] && {"a": 1}.a == 1`, true},
+		{`-request.page_size < 0 && 7 % 4 == 3 && double(1) / 2 == 0.5`, true},
+		// The decisive operand wins even if the other fails
+		{`metadata.missing == "x" || true`, true},
+		{`false && metadata.missing == "x"`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			expr, err := condition.Compile(tt.expr)
+			if err != nil {
+				t.Fatalf("Compile() error = %v", err)
+			}
+			got, err := expr.Eval(vars)
+			if err != nil {
+				t.Fatalf("Eval() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Eval() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCondition_CompileErrors(t *testing.T) {
+	tests := []struct {
+		expr    string
+		wantErr string
+	}{
+		{``, "empty expression"},
+		{`user == "x"`, `undeclared variable "user"`},
+		{`lower(method)`, `unknown function "lower"`},
+		{`startsWith(method, "/")`, "must be called as a method"},
+		{`method.matches("(")`, "invalid matches() pattern"},
+		{`method == `, "unexpected end of expression"},
+		{`(method == "x"`, `expected ")"`},
+		{`method == "x`, "unterminated string"},
+		{`has(method)`, "field selection"},
+		{`size(method, rpc)`, "expects 1 argument"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			_, err := condition.Compile(tt.expr)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Compile() error = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCondition_EvalErrors(t *testing.T) {
+	vars := conditionEvaluator.Vars(context.Background(), "/svc/M", nil, true)
+
+	for _, source := range []string{`metadata.missing == "x"`, `method + 1 == 2`, `method`, `request.id == 1`} {
+		if _, err := condition.MustCompile(source).Eval(vars); err == nil {
+			t.Errorf("Eval(%s) expected error", source)
+		}
+	}
+}
+
+func TestCondition_RequestVars(t *testing.T) {
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("X-Tenant", "acme"))
+	ctx = context.WithValue(ctx, contextKeyUserID, "u1")
+	ctx = context.WithValue(ctx, contextKeyRoles, []string{"admin"})
+
+	req := &descriptorpb.FieldDescriptorProto{
+		Name:   proto.String("id"),
+		Number: proto.Int32(3),
+		Type:   descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+	}
+
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{`service == "shop.Orders" && rpc == "Get"`, true},
+		{`metadata["x-tenant"] == "acme"`, true},
+		{`principal.authenticated && principal.user_id == "u1" && "admin" in principal.roles`, true},
+		{`request.name == "id" && request.number == 3 && request.type == 9`, true},
+		{`has(request.options)`, false},
+		{`request.json_name == ""`, true},
+	}
+
+	for _, tt := range tests {
+		got, err := conditionEvaluator.EvalRequest(ctx, condition.MustCompile(tt.expr), "/shop.Orders/Get", req)
+		if err != nil {
+			t.Fatalf("EvalRequest(%s) error = %v", tt.expr, err)
+		}
+		if got != tt.want {
+			t.Errorf("EvalRequest(%s) = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+
+	// Anonymous callers
+	got, err := conditionEvaluator.EvalRequest(context.Background(), condition.MustCompile(`principal.authenticated`), "/svc/M", nil)
+	if err != nil || got {
+		t.Errorf("EvalRequest(principal.authenticated) = %v, %v, want false", got, err)
+	}
+}
+
+func TestRateLimitIf(t *testing.T) {
+	limiter := RateLimitIf(condition.MustCompile(`!("premium" in principal.roles)`), RateLimit(1, 1))
+	info := &grpc.UnaryServerInfo{FullMethod: "/svc/M"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) { return "ok", nil }
+
+	premium := context.WithValue(context.Background(), contextKeyRoles, []string{"premium"})
+	for i := 0; i < 5; i++ {
+		if _, err := limiter(premium, "req", info, handler); err != nil {
+			t.Fatalf("premium request %d: unexpected error %v", i, err)
+		}
+	}
+
+	basic := context.WithValue(context.Background(), contextKeyRoles, []string{"basic"})
+	if _, err := limiter(basic, "req", info, handler); err != nil {
+		t.Fatalf("first basic request: unexpected error %v", err)
+	}
+	if _, err := limiter(basic, "req", info, handler); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("second basic request: expected ResourceExhausted, got %v", err)
+	}
+}
+
+func TestAuthorization_AllowIf(t *testing.T) {
+	interceptor := Authorization(
+		WithRequiredRoles("/shop.Orders/*", "customer"),
+		WithAllowIf("/shop.Orders/*", condition.MustCompile(`metadata["x-account"] == principal.user_id`)),
+	)
+
+	ctx := authzContext([]string{"customer"}, nil) // user-123
+	own := metadata.NewIncomingContext(ctx, metadata.Pairs("x-account", "user-123"))
+	other := metadata.NewIncomingContext(ctx, metadata.Pairs("x-account", "user-456"))
+
+	if code := callAuthz(interceptor, own, "/shop.Orders/Get"); code != codes.OK {
+		t.Errorf("own account: expected OK, got %v", code)
+	}
+	if code := callAuthz(interceptor, other, "/shop.Orders/Get"); code != codes.PermissionDenied {
+		t.Errorf("other account: expected PermissionDenied, got %v", code)
+	}
+	// Missing header fails evaluation, which denies
+	if code := callAuthz(interceptor, ctx, "/shop.Orders/Get"); code != codes.PermissionDenied {
+		t.Errorf("missing header: expected PermissionDenied, got %v", code)
+	}
+	// Roles are still enforced
+	viewer := metadata.NewIncomingContext(authzContext([]string{"viewer"}, nil), metadata.Pairs("x-account", "user-123"))
+	if code := callAuthz(interceptor, viewer, "/shop.Orders/Get"); code != codes.PermissionDenied {
+		t.Errorf("wrong role: expected PermissionDenied, got %v", code)
+	}
+}
+
+func TestCache_SkipIf(t *testing.T) {
+	interceptor := Cache(WithCacheSkipIf(condition.MustCompile(`has(metadata["cache-control"]) && metadata["cache-control"] == "no-cache"`)))
+	info := &grpc.UnaryServerInfo{FullMethod: "/svc/Get"}
+
+	calls := 0
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		calls++
+		return "ok", nil
+	}
+
+	noCache := metadata.NewIncomingContext(context.Background(), metadata.Pairs("cache-control", "no-cache"))
+	for i := 0; i < 2; i++ {
+		if _, err := interceptor(noCache, "req", info, handler); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if calls != 2 {
+		t.Errorf("expected skipped requests to reach the handler twice, got %d", calls)
+	}
+
+	calls = 0
+	for i := 0; i < 2; i++ {
+		if _, err := interceptor(context.Background(), "req", info, handler); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("expected cached requests to reach the handler once, got %d", calls)
+	}
+}
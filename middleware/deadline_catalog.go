@@ -0,0 +1,248 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+)
+
+// DeadlineCatalog declares timeout ceilings for upstream dependencies in one place and
+// clamps any larger deadline set by the application on outgoing calls. This keeps individual
+// callers from holding connections to shared dependencies open with absurd timeouts.
+type DeadlineCatalog struct {
+	mu      sync.RWMutex
+	matcher *methodMatcher[time.Duration]
+
+	enforceMissing bool
+	onClamp        func(method string, requested, ceiling time.Duration)
+	clamped        *prometheus.CounterVec
+}
+
+// DeadlineCatalogOption configures a DeadlineCatalog
+type DeadlineCatalogOption func(*deadlineCatalogConfig)
+
+// deadlineCatalogConfig collects options before the catalog is built
+type deadlineCatalogConfig struct {
+	ceilings       map[string]time.Duration
+	enforceMissing bool
+	onClamp        func(method string, requested, ceiling time.Duration)
+	registerer     prometheus.Registerer
+}
+
+// WithDeadlineCeiling sets the maximum timeout for a target method pattern.
+// Patterns follow the Authorization rules: "/pkg.Service/Method", "/pkg.Service/*" or "*".
+func WithDeadlineCeiling(pattern string, ceiling time.Duration) DeadlineCatalogOption {
+	return func(c *deadlineCatalogConfig) {
+		c.ceilings[pattern] = ceiling
+	}
+}
+
+// WithEnforceMissingDeadline applies the ceiling as the deadline when the caller set none
+// Default: false (calls without a deadline are left untouched)
+func WithEnforceMissingDeadline() DeadlineCatalogOption {
+	return func(c *deadlineCatalogConfig) {
+		c.enforceMissing = true
+	}
+}
+
+// WithClampCallback sets a callback invoked whenever a deadline is clamped.
+// requested is zero when the caller did not set a deadline.
+func WithClampCallback(fn func(method string, requested, ceiling time.Duration)) DeadlineCatalogOption {
+	return func(c *deadlineCatalogConfig) {
+		c.onClamp = fn
+	}
+}
+
+// WithClampMetrics registers a grpc_client_deadline_clamped_total counter with the given registerer
+func WithClampMetrics(registerer prometheus.Registerer) DeadlineCatalogOption {
+	return func(c *deadlineCatalogConfig) {
+		c.registerer = registerer
+	}
+}
+
+// NewDeadlineCatalog creates a deadline catalog
+//
+// Example usage:
+//
+//	catalog := middleware.NewDeadlineCatalog(
+//	    middleware.WithDeadlineCeiling("*", 30*time.Second),
+//	    middleware.WithDeadlineCeiling("/inventory.Stock/*", 500*time.Millisecond),
+//	    middleware.WithClampMetrics(prometheus.DefaultRegisterer),
+//	)
+//	conn, err := grpc.Dial(addr, grpc.WithUnaryInterceptor(catalog.UnaryClientInterceptor()))
+func NewDeadlineCatalog(opts ...DeadlineCatalogOption) *DeadlineCatalog {
+	config := &deadlineCatalogConfig{
+		ceilings: make(map[string]time.Duration),
+	}
+
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	c := &DeadlineCatalog{
+		matcher:        newMethodMatcher(config.ceilings),
+		enforceMissing: config.enforceMissing,
+		onClamp:        config.onClamp,
+	}
+
+	if config.registerer != nil {
+		c.clamped = prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "grpc",
+			Subsystem: "client",
+			Name:      "deadline_clamped_total",
+			Help:      "Total number of outgoing calls whose deadline was clamped by the deadline catalog",
+		}, []string{"method", "reason"})
+		if err := config.registerer.Register(c.clamped); err != nil {
+			if are, ok := err.(prometheus.AlreadyRegisteredError); ok {
+				c.clamped = are.ExistingCollector.(*prometheus.CounterVec)
+			}
+		}
+	}
+
+	return c
+}
+
+// deadlineCatalogFile is the on-disk catalog format
+type deadlineCatalogFile struct {
+	EnforceMissing bool              `json:"enforce_missing"`
+	Ceilings       map[string]string `json:"ceilings"`
+}
+
+// ParseDeadlineCatalog builds a catalog from JSON configuration:
+//
+//	{
+//	    "enforce_missing": true,
+//	    "ceilings": {
+//	        "*": "30s",
+//	        "/inventory.Stock/*": "500ms",
+//	        "/payments.Charges/Create": "5s"
+//	    }
+//	}
+//
+// Options are applied after the file and may add or override ceilings.
+func ParseDeadlineCatalog(data []byte, opts ...DeadlineCatalogOption) (*DeadlineCatalog, error) {
+	fileOpts, err := parseDeadlineCatalogOptions(data)
+	if err != nil {
+		return nil, err
+	}
+	return NewDeadlineCatalog(append(fileOpts, opts...)...), nil
+}
+
+// LoadDeadlineCatalog reads a JSON catalog from a file (see ParseDeadlineCatalog)
+func LoadDeadlineCatalog(path string, opts ...DeadlineCatalogOption) (*DeadlineCatalog, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read deadline catalog: %w", err)
+	}
+	return ParseDeadlineCatalog(data, opts...)
+}
+
+// parseDeadlineCatalogOptions converts the JSON catalog into options
+func parseDeadlineCatalogOptions(data []byte) ([]DeadlineCatalogOption, error) {
+	var file deadlineCatalogFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("invalid deadline catalog: %w", err)
+	}
+
+	var opts []DeadlineCatalogOption
+	for pattern, value := range file.Ceilings {
+		ceiling, err := time.ParseDuration(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ceiling %q for %s: %w", value, pattern, err)
+		}
+		if ceiling <= 0 {
+			return nil, fmt.Errorf("ceiling for %s must be positive, got %s", pattern, value)
+		}
+		opts = append(opts, WithDeadlineCeiling(pattern, ceiling))
+	}
+	if file.EnforceMissing {
+		opts = append(opts, WithEnforceMissingDeadline())
+	}
+
+	return opts, nil
+}
+
+// SetCeilings atomically replaces all ceilings (e.g. after a config reload)
+func (c *DeadlineCatalog) SetCeilings(ceilings map[string]time.Duration) {
+	matcher := newMethodMatcher(ceilings)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.matcher = matcher
+}
+
+// Ceiling returns the timeout ceiling for a target method
+func (c *DeadlineCatalog) Ceiling(method string) (time.Duration, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.matcher.match(method)
+}
+
+// clamp returns a context whose deadline does not exceed the method's ceiling
+func (c *DeadlineCatalog) clamp(ctx context.Context, method string) (context.Context, context.CancelFunc) {
+	ceiling, ok := c.Ceiling(method)
+	if !ok || ceiling <= 0 {
+		return ctx, func() {}
+	}
+
+	var requested time.Duration
+	reason := "exceeded"
+	if deadline, hasDeadline := ctx.Deadline(); hasDeadline {
+		requested = time.Until(deadline)
+		if requested <= ceiling {
+			return ctx, func() {}
+		}
+	} else {
+		if !c.enforceMissing {
+			return ctx, func() {}
+		}
+		reason = "missing"
+	}
+
+	if c.clamped != nil {
+		c.clamped.WithLabelValues(method, reason).Inc()
+	}
+	if c.onClamp != nil {
+		c.onClamp(method, requested, ceiling)
+	}
+
+	return context.WithTimeout(ctx, ceiling)
+}
+
+// UnaryClientInterceptor returns a unary client interceptor that enforces the catalog
+func (c *DeadlineCatalog) UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		ctx, cancel := c.clamp(ctx, method)
+		defer cancel()
+
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// StreamClientInterceptor returns a stream client interceptor that enforces the catalog.
+// The ceiling bounds the lifetime of the whole stream.
+func (c *DeadlineCatalog) StreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		ctx, cancel := c.clamp(ctx, method)
+
+		stream, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+
+		// The clamped context is released when the stream's context finishes
+		go func() {
+			<-stream.Context().Done()
+			cancel()
+		}()
+
+		return stream, nil
+	}
+}
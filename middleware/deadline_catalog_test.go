@@ -0,0 +1,110 @@
+package middleware
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"google.golang.org/grpc"
+)
+
+// deadlineInvoker records the remaining time of the outgoing call
+func deadlineInvoker(remaining *time.Duration, hasDeadline *bool) grpc.UnaryInvoker {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		var deadline time.Time
+		deadline, *hasDeadline = ctx.Deadline()
+		*remaining = time.Until(deadline)
+		return nil
+	}
+}
+
+func TestDeadlineCatalog_Clamping(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	var clamps []string
+
+	catalog := NewDeadlineCatalog(
+		WithDeadlineCeiling("*", 30*time.Second),
+		WithDeadlineCeiling("/inventory.Stock/*", 500*time.Millisecond),
+		WithClampCallback(func(method string, requested, ceiling time.Duration) {
+			clamps = append(clamps, method)
+		}),
+		WithClampMetrics(registry),
+	)
+	interceptor := catalog.UnaryClientInterceptor()
+
+	var (
+		remaining   time.Duration
+		hasDeadline bool
+	)
+
+	// Larger application deadline is clamped to the ceiling
+	ctx, cancel := context.WithTimeout(context.Background(), time.Hour)
+	defer cancel()
+	interceptor(ctx, "/inventory.Stock/Reserve", nil, nil, nil, deadlineInvoker(&remaining, &hasDeadline))
+	if !hasDeadline || remaining > 500*time.Millisecond {
+		t.Errorf("Expected deadline clamped to 500ms, got %v", remaining)
+	}
+
+	// Shorter deadlines are left untouched
+	shortCtx, shortCancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer shortCancel()
+	interceptor(shortCtx, "/inventory.Stock/Reserve", nil, nil, nil, deadlineInvoker(&remaining, &hasDeadline))
+	if remaining > 100*time.Millisecond {
+		t.Errorf("Expected short deadline to be kept, got %v", remaining)
+	}
+
+	// Calls without a deadline are not modified unless enforcement is enabled
+	interceptor(context.Background(), "/users.Profile/Get", nil, nil, nil, deadlineInvoker(&remaining, &hasDeadline))
+	if hasDeadline {
+		t.Error("Expected no deadline to be added")
+	}
+
+	if len(clamps) != 1 || clamps[0] != "/inventory.Stock/Reserve" {
+		t.Errorf("Unexpected clamp callbacks: %v", clamps)
+	}
+
+	count := testutil.ToFloat64(catalog.clamped.WithLabelValues("/inventory.Stock/Reserve", "exceeded"))
+	if count != 1 {
+		t.Errorf("Expected clamp counter 1, got %v", count)
+	}
+}
+
+func TestDeadlineCatalog_LoadFromConfig(t *testing.T) {
+	catalog, err := ParseDeadlineCatalog([]byte(`{
+		"enforce_missing": true,
+		"ceilings": {
+			"*": "30s",
+			"/payments.Charges/Create": "5s"
+		}
+	}`))
+	if err != nil {
+		t.Fatalf("Failed to parse catalog: %v", err)
+	}
+
+	if ceiling, _ := catalog.Ceiling("/payments.Charges/Create"); ceiling != 5*time.Second {
+		t.Errorf("Expected 5s ceiling, got %v", ceiling)
+	}
+	if ceiling, _ := catalog.Ceiling("/other.Service/Method"); ceiling != 30*time.Second {
+		t.Errorf("Expected 30s default ceiling, got %v", ceiling)
+	}
+
+	var (
+		remaining   time.Duration
+		hasDeadline bool
+	)
+	catalog.UnaryClientInterceptor()(context.Background(), "/payments.Charges/Create", nil, nil, nil, deadlineInvoker(&remaining, &hasDeadline))
+	if !hasDeadline || remaining > 5*time.Second {
+		t.Errorf("Expected missing deadline to be enforced, got %v (deadline=%v)", remaining, hasDeadline)
+	}
+
+	catalog.SetCeilings(map[string]time.Duration{"/payments.Charges/Create": time.Second})
+	if ceiling, ok := catalog.Ceiling("/other.Service/Method"); ok {
+		t.Errorf("Expected reload to replace ceilings, got %v", ceiling)
+	}
+
+	if _, err := ParseDeadlineCatalog([]byte(`{"ceilings": {"*": "forever"}}`)); err == nil {
+		t.Error("Expected invalid duration to be rejected")
+	}
+}
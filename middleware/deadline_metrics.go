@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/grpc-guardian/grpc-guardian/pkg/metrics"
+	"google.golang.org/grpc"
+)
+
+// DeadlineMetrics creates middleware that records how much time clients leave for each
+// request, and which requests the client abandoned. The regular metrics count Canceled and
+// DeadlineExceeded codes whether the server or the client caused them; this tells the
+// client-initiated ones apart, and shows when clients send hopelessly short deadlines.
+//
+// It must run before Timeout and other middleware that shorten the deadline, so that it
+// sees the client's own deadline.
+//
+// Example usage:
+//
+//	collector, _ := metrics.NewDeadlineCollector(registry)
+//	chain := guardian.NewChain(
+//	    middleware.DeadlineMetrics(collector),
+//	    middleware.TimeoutSimple(5*time.Second),
+//	)
+func DeadlineMetrics(collector *metrics.DeadlineCollector) func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		recordArrival(ctx, collector, info.FullMethod)
+		resp, err := handler(ctx, req)
+		recordAbandoned(ctx, collector, info.FullMethod)
+		return resp, err
+	}
+}
+
+// StreamDeadlineMetrics records the deadline and cancellation of streams
+func StreamDeadlineMetrics(collector *metrics.DeadlineCollector) func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		recordArrival(ss.Context(), collector, info.FullMethod)
+		err := handler(srv, ss)
+		recordAbandoned(ss.Context(), collector, info.FullMethod)
+		return err
+	}
+}
+
+// recordArrival records the remaining deadline of a new request
+func recordArrival(ctx context.Context, collector *metrics.DeadlineCollector, method string) {
+	if deadline, ok := ctx.Deadline(); ok {
+		collector.RecordDeadline(method, time.Until(deadline))
+	} else {
+		collector.RecordNoDeadline(method)
+	}
+}
+
+// recordAbandoned records a request whose client context ended before the handler returned
+func recordAbandoned(ctx context.Context, collector *metrics.DeadlineCollector, method string) {
+	switch err := ctx.Err(); {
+	case errors.Is(err, context.DeadlineExceeded):
+		collector.RecordCancellation(method, metrics.CancellationDeadlineExceeded)
+	case errors.Is(err, context.Canceled):
+		collector.RecordCancellation(method, metrics.CancellationCanceled)
+	}
+}
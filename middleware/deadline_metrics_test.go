@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grpc-guardian/grpc-guardian/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"google.golang.org/grpc"
+)
+
+func TestDeadlineMetrics(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	collector, err := metrics.NewDeadlineCollector(registry)
+	if err != nil {
+		t.Fatalf("Failed to create collector: %v", err)
+	}
+	interceptor := DeadlineMetrics(collector)
+	info := &grpc.UnaryServerInfo{FullMethod: "/test.Service/Method"}
+	ok := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "ok", nil
+	}
+
+	// No deadline
+	interceptor(context.Background(), nil, info, ok)
+
+	// Deadline with time to spare
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	interceptor(ctx, nil, info, ok)
+	cancel()
+
+	// Client deadline exhausted while the handler runs
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	interceptor(ctx, nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	cancel()
+
+	// Client cancels while the handler runs
+	ctx, cancel = context.WithCancel(context.Background())
+	interceptor(ctx, nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		cancel()
+		return nil, ctx.Err()
+	})
+
+	// A server-side timeout is not a client cancellation
+	interceptor(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		inner, cancel := context.WithTimeout(ctx, time.Millisecond)
+		defer cancel()
+		<-inner.Done()
+		return nil, inner.Err()
+	})
+
+	expected := `
+# HELP grpc_server_client_cancellations_total Total number of requests abandoned by the client, by cancellation or an exhausted deadline
+# TYPE grpc_server_client_cancellations_total counter
+grpc_server_client_cancellations_total{method="/test.Service/Method",reason="canceled"} 1
+grpc_server_client_cancellations_total{method="/test.Service/Method",reason="deadline_exceeded"} 1
+# HELP grpc_server_requests_without_deadline_total Total number of requests that arrived without a deadline
+# TYPE grpc_server_requests_without_deadline_total counter
+grpc_server_requests_without_deadline_total{method="/test.Service/Method"} 3
+`
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(expected),
+		"grpc_server_client_cancellations_total", "grpc_server_requests_without_deadline_total"); err != nil {
+		t.Error(err)
+	}
+
+	if count := testutil.CollectAndCount(registry, "grpc_server_deadline_remaining_seconds"); count != 1 {
+		t.Errorf("Expected one deadline histogram, got %d", count)
+	}
+}
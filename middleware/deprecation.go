@@ -0,0 +1,265 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/grpc-guardian/grpc-guardian/pkg/logging"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// DeprecationTrailer carries a human-readable warning on calls to deprecated methods
+	DeprecationTrailer = "x-deprecation-warning"
+
+	// SunsetTrailer carries the date a deprecated method stops working, as an HTTP date
+	// (RFC 8594)
+	SunsetTrailer = "sunset"
+)
+
+// Deprecation describes a deprecated method
+type Deprecation struct {
+	Replacement string    // Method or API to use instead, e.g. "/orders.v2.Orders/Get"
+	Sunset      time.Time // When calls start failing with Unimplemented; zero never
+	Message     string    // Extra guidance appended to the warning, e.g. a migration guide URL
+}
+
+// deprecationConfig holds the configuration of Deprecations
+type deprecationConfig struct {
+	methods    map[string]Deprecation
+	domain     string
+	logger     logging.Logger
+	registerer prometheus.Registerer
+	callback   func(ctx context.Context, method string, deprecation Deprecation, rejected bool)
+	now        func() time.Time
+}
+
+// DeprecationOption configures Deprecations
+type DeprecationOption func(*deprecationConfig)
+
+// WithDeprecatedMethod deprecates methods matching pattern: "/pkg.Service/Method",
+// "/pkg.Service/*" or "*"; the most specific pattern wins
+func WithDeprecatedMethod(pattern string, deprecation Deprecation) DeprecationOption {
+	return func(c *deprecationConfig) {
+		c.methods[pattern] = deprecation
+	}
+}
+
+// WithDeprecationDomain sets the google.rpc.ErrorInfo domain of sunset errors
+// Default: "grpc-guardian"
+func WithDeprecationDomain(domain string) DeprecationOption {
+	return func(c *deprecationConfig) {
+		c.domain = domain
+	}
+}
+
+// WithDeprecationLogger logs a warning for every call to a deprecated method
+func WithDeprecationLogger(logger logging.Logger) DeprecationOption {
+	return func(c *deprecationConfig) {
+		c.logger = logger
+	}
+}
+
+// WithDeprecationMetrics registers a grpc_server_deprecated_calls_total counter, labeled by
+// method and result ("warned" or "rejected"), to see who still calls deprecated methods
+func WithDeprecationMetrics(registerer prometheus.Registerer) DeprecationOption {
+	return func(c *deprecationConfig) {
+		c.registerer = registerer
+	}
+}
+
+// WithDeprecationCallback is called for every call to a deprecated method, e.g. to record
+// the caller's identity
+func WithDeprecationCallback(fn func(ctx context.Context, method string, deprecation Deprecation, rejected bool)) DeprecationOption {
+	return func(c *deprecationConfig) {
+		c.callback = fn
+	}
+}
+
+// deprecations enforces the deprecation of methods
+type deprecations struct {
+	config  *deprecationConfig
+	matcher *methodMatcher[Deprecation]
+	calls   *prometheus.CounterVec
+}
+
+// Deprecations creates middleware for deprecated methods. Until the sunset date calls are
+// served normally, with x-deprecation-warning and sunset trailers telling clients to
+// migrate; from the sunset date on they fail with Unimplemented and a google.rpc.ErrorInfo
+// (reason METHOD_SUNSET) naming the replacement.
+//
+// Example usage:
+//
+//	chain := guardian.NewChain(
+//	    middleware.Deprecations(
+//	        middleware.WithDeprecatedMethod("/orders.v1.Orders/*", middleware.Deprecation{
+//	            Replacement: "orders.v2.Orders",
+//	            Sunset:      time.Date(2025, 6, 30, 0, 0, 0, 0, time.UTC),
+//	            Message:     "see https://example.com/orders-v2-migration",
+//	        }),
+//	        middleware.WithDeprecationMetrics(collector.GetRegistry()),
+//	    ),
+//	)
+func Deprecations(opts ...DeprecationOption) func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	d := newDeprecations(opts)
+
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		trailer, err := d.check(ctx, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		if trailer != nil {
+			// Fails only outside a gRPC server, e.g. in unit tests of the handler
+			_ = grpc.SetTrailer(ctx, trailer)
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamDeprecations enforces deprecations on streaming methods
+func StreamDeprecations(opts ...DeprecationOption) func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	d := newDeprecations(opts)
+
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		trailer, err := d.check(ss.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+		if trailer != nil {
+			ss.SetTrailer(trailer)
+		}
+		return handler(srv, ss)
+	}
+}
+
+func newDeprecations(opts []DeprecationOption) *deprecations {
+	config := &deprecationConfig{
+		methods: make(map[string]Deprecation),
+		domain:  "grpc-guardian",
+		now:     time.Now,
+	}
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	d := &deprecations{
+		config:  config,
+		matcher: newMethodMatcher(config.methods),
+	}
+	if config.registerer != nil {
+		d.calls = prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "grpc",
+			Subsystem: "server",
+			Name:      "deprecated_calls_total",
+			Help:      "Total number of calls to deprecated methods",
+		}, []string{"method", "result"})
+		if err := config.registerer.Register(d.calls); err != nil {
+			if are, ok := err.(prometheus.AlreadyRegisteredError); ok {
+				d.calls = are.ExistingCollector.(*prometheus.CounterVec)
+			}
+		}
+	}
+	return d
+}
+
+// check returns the trailer of a call to a deprecated method, or the error of a call to a
+// method past its sunset; both are nil for other methods
+func (d *deprecations) check(ctx context.Context, method string) (metadata.MD, error) {
+	deprecation, ok := d.matcher.match(method)
+	if !ok {
+		return nil, nil
+	}
+
+	rejected := !deprecation.Sunset.IsZero() && !d.config.now().Before(deprecation.Sunset)
+	result := "warned"
+	if rejected {
+		result = "rejected"
+	}
+	if d.calls != nil {
+		d.calls.WithLabelValues(method, result).Inc()
+	}
+	if d.config.logger != nil {
+		d.config.logger.Warn("deprecated method called",
+			logging.String("method", method),
+			logging.String("replacement", deprecation.Replacement),
+			logging.Bool("rejected", rejected),
+		)
+	}
+	if d.config.callback != nil {
+		d.config.callback(ctx, method, deprecation, rejected)
+	}
+
+	if rejected {
+		return nil, d.sunsetError(method, deprecation)
+	}
+
+	trailer := metadata.Pairs(DeprecationTrailer, deprecationWarning(method, deprecation))
+	if !deprecation.Sunset.IsZero() {
+		trailer.Set(SunsetTrailer, deprecation.Sunset.UTC().Format(http.TimeFormat))
+	}
+	return trailer, nil
+}
+
+// deprecationWarning describes the deprecation of a method for clients
+func deprecationWarning(method string, deprecation Deprecation) string {
+	warning := method + " is deprecated"
+	if !deprecation.Sunset.IsZero() {
+		warning += " and will be removed on " + deprecation.Sunset.UTC().Format("2006-01-02")
+	}
+	if deprecation.Replacement != "" {
+		warning += "; use " + deprecation.Replacement
+	}
+	if deprecation.Message != "" {
+		warning += "; " + deprecation.Message
+	}
+	return warning
+}
+
+// sunsetError is the error of a call to a method past its sunset
+func (d *deprecations) sunsetError(method string, deprecation Deprecation) error {
+	message := fmt.Sprintf("%s was removed on %s", method, deprecation.Sunset.UTC().Format("2006-01-02"))
+	if deprecation.Replacement != "" {
+		message += "; use " + deprecation.Replacement
+	}
+
+	info := &errdetails.ErrorInfo{
+		Reason: "METHOD_SUNSET",
+		Domain: d.config.domain,
+		Metadata: map[string]string{
+			"method": method,
+			"sunset": deprecation.Sunset.UTC().Format(time.RFC3339),
+		},
+	}
+	if deprecation.Replacement != "" {
+		info.Metadata["replacement"] = deprecation.Replacement
+	}
+
+	st := status.New(codes.Unimplemented, message)
+	if detailed, err := st.WithDetails(info); err == nil {
+		st = detailed
+	}
+	return st.Err()
+}
+
+// DeprecationWarning returns the deprecation warning of a call, from trailer metadata
+// captured with grpc.Trailer
+//
+//	var trailer metadata.MD
+//	resp, err := client.Get(ctx, req, grpc.Trailer(&trailer))
+//	if warning, ok := middleware.DeprecationWarning(trailer); ok {
+//	    log.Println(warning)
+//	}
+func DeprecationWarning(trailer metadata.MD) (string, bool) {
+	values := trailer.Get(DeprecationTrailer)
+	if len(values) == 0 {
+		return "", false
+	}
+	return values[0], true
+}
@@ -0,0 +1,151 @@
+package middleware
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// dialDeprecations serves test.Orders/Get and test.Orders/List through Deprecations
+func dialDeprecations(t *testing.T, opts ...DeprecationOption) *grpc.ClientConn {
+	t.Helper()
+	server := grpc.NewServer(grpc.UnaryInterceptor(Deprecations(opts...)))
+	handler := func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+		req := &wrapperspb.StringValue{}
+		if err := dec(req); err != nil {
+			return nil, err
+		}
+		info := &grpc.UnaryServerInfo{FullMethod: grpc.ServerTransportStreamFromContext(ctx).Method()}
+		return interceptor(ctx, req, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			return req, nil
+		})
+	}
+	server.RegisterService(&grpc.ServiceDesc{
+		ServiceName: "test.Orders",
+		HandlerType: (*interface{})(nil),
+		Methods:     []grpc.MethodDesc{{MethodName: "Get", Handler: handler}, {MethodName: "List", Handler: handler}},
+	}, struct{}{})
+
+	lis := bufconn.Listen(1 << 20)
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestDeprecations_Warns(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	sunset := time.Now().Add(30 * 24 * time.Hour).UTC().Truncate(time.Second)
+	conn := dialDeprecations(t,
+		WithDeprecatedMethod("/test.Orders/Get", Deprecation{Replacement: "/test.OrdersV2/Get", Sunset: sunset, Message: "see the v2 guide"}),
+		WithDeprecationMetrics(registry),
+	)
+
+	var trailer metadata.MD
+	reply := &wrapperspb.StringValue{}
+	if err := conn.Invoke(context.Background(), "/test.Orders/Get", wrapperspb.String("42"), reply, grpc.Trailer(&trailer)); err != nil {
+		t.Fatalf("Expected a deprecated method to keep working before its sunset, got %v", err)
+	}
+
+	warning, ok := DeprecationWarning(trailer)
+	if !ok || !strings.Contains(warning, "use /test.OrdersV2/Get") || !strings.Contains(warning, "see the v2 guide") {
+		t.Errorf("Expected a warning naming the replacement, got %q", warning)
+	}
+	if got := trailer.Get(SunsetTrailer); len(got) != 1 || got[0] != sunset.Format("Mon, 02 Jan 2006 15:04:05 GMT") {
+		t.Errorf("Expected the sunset date as an HTTP date, got %v", got)
+	}
+
+	trailer = nil
+	if err := conn.Invoke(context.Background(), "/test.Orders/List", wrapperspb.String("42"), reply, grpc.Trailer(&trailer)); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := DeprecationWarning(trailer); ok {
+		t.Error("Expected no warning for a method that is not deprecated")
+	}
+
+	expected := `
+# HELP grpc_server_deprecated_calls_total Total number of calls to deprecated methods
+# TYPE grpc_server_deprecated_calls_total counter
+grpc_server_deprecated_calls_total{method="/test.Orders/Get",result="warned"} 1
+`
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(expected)); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestDeprecations_RejectsAfterSunset(t *testing.T) {
+	var rejected bool
+	conn := dialDeprecations(t,
+		WithDeprecatedMethod("/test.Orders/*", Deprecation{
+			Replacement: "test.OrdersV2",
+			Sunset:      time.Now().Add(-time.Hour),
+		}),
+		WithDeprecationDomain("orders.example.com"),
+		WithDeprecationCallback(func(ctx context.Context, method string, deprecation Deprecation, r bool) {
+			rejected = r
+		}),
+	)
+
+	err := conn.Invoke(context.Background(), "/test.Orders/List", wrapperspb.String("42"), &wrapperspb.StringValue{})
+	if status.Code(err) != codes.Unimplemented {
+		t.Fatalf("Expected Unimplemented after the sunset, got %v", err)
+	}
+	if !rejected {
+		t.Error("Expected the callback to report the rejection")
+	}
+
+	reason, domain, ok := ErrorReason(err)
+	if !ok || reason != "METHOD_SUNSET" || domain != "orders.example.com" {
+		t.Errorf("Expected a METHOD_SUNSET ErrorInfo, got %q %q", reason, domain)
+	}
+	for _, detail := range status.Convert(err).Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok && info.GetMetadata()["replacement"] != "test.OrdersV2" {
+			t.Errorf("Expected the replacement in the ErrorInfo metadata, got %v", info.GetMetadata())
+		}
+	}
+}
+
+func TestStreamDeprecations(t *testing.T) {
+	stream := &trailerServerStream{ctx: context.Background()}
+	err := StreamDeprecations(WithDeprecatedMethod("/test.Orders/Watch", Deprecation{}))(nil, stream,
+		&grpc.StreamServerInfo{FullMethod: "/test.Orders/Watch"},
+		func(srv interface{}, ss grpc.ServerStream) error { return nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if warning, ok := DeprecationWarning(stream.trailer); !ok || warning != "/test.Orders/Watch is deprecated" {
+		t.Errorf("Expected a warning trailer on the stream, got %q", warning)
+	}
+}
+
+// trailerServerStream captures trailers set on a stream
+type trailerServerStream struct {
+	grpc.ServerStream
+	ctx     context.Context
+	trailer metadata.MD
+}
+
+func (s *trailerServerStream) Context() context.Context { return s.ctx }
+
+func (s *trailerServerStream) SetTrailer(md metadata.MD) { s.trailer = metadata.Join(s.trailer, md) }
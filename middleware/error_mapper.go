@@ -0,0 +1,330 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// ErrorMapping is the public status an internal error is converted to
+type ErrorMapping struct {
+	Code     codes.Code        // Status code sent to the client
+	Message  string            // Safe message sent to the client; empty keeps the error's message
+	Reason   string            // google.rpc.ErrorInfo reason, e.g. "ORDER_NOT_FOUND"; empty adds no ErrorInfo
+	Metadata map[string]string // google.rpc.ErrorInfo metadata
+}
+
+// errorRule maps errors matched by match
+type errorRule struct {
+	match   func(error) bool
+	mapping ErrorMapping
+}
+
+// errorMapperConfig holds the configuration of ErrorMapper
+type errorMapperConfig struct {
+	rules    []errorRule
+	domain   string
+	hide     bool
+	debug    debugInfoMode
+	onMapped func(ctx context.Context, method string, err error, st *status.Status)
+}
+
+// debugInfoMode says what ErrorMapper does with google.rpc.DebugInfo details
+type debugInfoMode int
+
+const (
+	debugInfoUntouched debugInfoMode = iota // DebugInfo set by handlers is passed through
+	debugInfoAttach                         // DebugInfo is added to every mapped status
+	debugInfoStrip                          // DebugInfo is removed from every status
+)
+
+// ErrorMapperOption configures ErrorMapper
+type ErrorMapperOption func(*errorMapperConfig)
+
+// MapError maps errors matching target with errors.Is, e.g. sentinel errors of a repository
+func MapError(target error, mapping ErrorMapping) ErrorMapperOption {
+	return MapErrorFunc(func(err error) bool { return errors.Is(err, target) }, mapping)
+}
+
+// MapErrorType maps errors of type T found with errors.As
+//
+//	middleware.MapErrorType[*store.ConflictError](middleware.ErrorMapping{Code: codes.Aborted})
+func MapErrorType[T error](mapping ErrorMapping) ErrorMapperOption {
+	return MapErrorFunc(func(err error) bool {
+		var target T
+		return errors.As(err, &target)
+	}, mapping)
+}
+
+// MapErrorFunc maps errors for which match returns true
+func MapErrorFunc(match func(error) bool, mapping ErrorMapping) ErrorMapperOption {
+	return func(c *errorMapperConfig) {
+		c.rules = append(c.rules, errorRule{match: match, mapping: mapping})
+	}
+}
+
+// WithErrorDomain sets the google.rpc.ErrorInfo domain, usually the service's DNS name
+// Default: "grpc-guardian"
+func WithErrorDomain(domain string) ErrorMapperOption {
+	return func(c *errorMapperConfig) {
+		c.domain = domain
+	}
+}
+
+// WithHiddenInternalErrors replaces the message of unmapped errors with a generic one, so
+// internal details (queries, hostnames, stack traces) never reach clients. Use it in
+// production; errors that already carry a deliberate client-facing status are kept.
+// Default: false
+func WithHiddenInternalErrors() ErrorMapperOption {
+	return func(c *errorMapperConfig) {
+		c.hide = true
+	}
+}
+
+// WithDebugInfo adds a google.rpc.DebugInfo detail to error statuses unless environment is
+// "production" or "prod", in which case DebugInfo details are removed from every status,
+// including ones set by handlers. The detail carries the stack where the error reached
+// ErrorMapper, the rule that matched, whether the code is retryable and the original error.
+//
+//	middleware.WithDebugInfo(os.Getenv("ENVIRONMENT"))
+func WithDebugInfo(environment string) ErrorMapperOption {
+	return func(c *errorMapperConfig) {
+		switch strings.ToLower(strings.TrimSpace(environment)) {
+		case "production", "prod":
+			c.debug = debugInfoStrip
+		default:
+			c.debug = debugInfoAttach
+		}
+	}
+}
+
+// WithErrorMapperCallback is called with the original error and the status sent instead,
+// e.g. to log what clients don't see
+func WithErrorMapperCallback(fn func(ctx context.Context, method string, err error, st *status.Status)) ErrorMapperOption {
+	return func(c *errorMapperConfig) {
+		c.onMapped = fn
+	}
+}
+
+// ErrorMapper creates middleware that converts errors returned by handlers into stable
+// public statuses. Rules are checked in order and the first match wins; context.Canceled and
+// context.DeadlineExceeded map to their codes unless a rule says otherwise. Errors that
+// already carry a gRPC status and match no rule are passed through, except for Unknown and
+// Internal ones when internal errors are hidden.
+//
+// Example usage:
+//
+//	chain := guardian.NewChain(
+//	    middleware.ErrorMapper(
+//	        middleware.WithErrorDomain("orders.example.com"),
+//	        middleware.MapError(sql.ErrNoRows, middleware.ErrorMapping{
+//	            Code: codes.NotFound, Message: "order not found", Reason: "ORDER_NOT_FOUND",
+//	        }),
+//	        middleware.MapErrorType[*store.ConflictError](middleware.ErrorMapping{
+//	            Code: codes.Aborted, Message: "order was modified concurrently", Reason: "ORDER_CONFLICT",
+//	        }),
+//	        middleware.WithHiddenInternalErrors(),
+//	    ),
+//	)
+func ErrorMapper(opts ...ErrorMapperOption) func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	config := newErrorMapperConfig(opts)
+
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		resp, err := handler(ctx, req)
+		if err != nil {
+			return resp, config.mapError(ctx, info.FullMethod, err)
+		}
+		return resp, nil
+	}
+}
+
+// StreamErrorMapper converts errors returned by streaming handlers
+func StreamErrorMapper(opts ...ErrorMapperOption) func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	config := newErrorMapperConfig(opts)
+
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := handler(srv, ss); err != nil {
+			return config.mapError(ss.Context(), info.FullMethod, err)
+		}
+		return nil
+	}
+}
+
+func newErrorMapperConfig(opts []ErrorMapperOption) *errorMapperConfig {
+	config := &errorMapperConfig{domain: "grpc-guardian"}
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	config.rules = append(config.rules,
+		errorRule{match: func(err error) bool { return errors.Is(err, context.Canceled) }, mapping: ErrorMapping{Code: codes.Canceled}},
+		errorRule{match: func(err error) bool { return errors.Is(err, context.DeadlineExceeded) }, mapping: ErrorMapping{Code: codes.DeadlineExceeded}},
+	)
+	return config
+}
+
+// mapError returns the public error for err
+func (c *errorMapperConfig) mapError(ctx context.Context, method string, err error) error {
+	st, rule := c.publicStatus(err)
+	switch c.debug {
+	case debugInfoAttach:
+		st = withDebugInfo(st, debugInfo(method, rule, err, st.Code()))
+	case debugInfoStrip:
+		st = withoutDebugInfo(st)
+	}
+	if c.onMapped != nil {
+		c.onMapped(ctx, method, err, st)
+	}
+	return st.Err()
+}
+
+// publicStatus finds the status sent to the client and the index of the rule that
+// matched, or -1
+func (c *errorMapperConfig) publicStatus(err error) (*status.Status, int) {
+	for i, rule := range c.rules {
+		if rule.match(err) {
+			return c.mappedStatus(err, rule.mapping), i
+		}
+	}
+
+	st, ok := status.FromError(err)
+	if !c.hide {
+		return st, -1
+	}
+	if !ok || st.Code() == codes.Unknown || st.Code() == codes.Internal {
+		return status.New(codes.Internal, "internal server error"), -1
+	}
+	return st, -1
+}
+
+// mappedStatus builds the status of a matched rule
+func (c *errorMapperConfig) mappedStatus(err error, mapping ErrorMapping) *status.Status {
+	message := mapping.Message
+	if message == "" {
+		if c.hide {
+			message = mapping.Code.String()
+		} else {
+			message = status.Convert(err).Message()
+		}
+	}
+
+	st := status.New(mapping.Code, message)
+	if mapping.Reason == "" {
+		return st
+	}
+	detailed, detailErr := st.WithDetails(&errdetails.ErrorInfo{
+		Reason:   mapping.Reason,
+		Domain:   c.domain,
+		Metadata: mapping.Metadata,
+	})
+	if detailErr != nil {
+		return st
+	}
+	return detailed
+}
+
+// debugInfo describes where and how err was mapped
+func debugInfo(method string, rule int, err error, code codes.Code) *errdetails.DebugInfo {
+	position := "unmapped"
+	if rule >= 0 {
+		position = fmt.Sprintf("rule %d", rule+1)
+	}
+	return &errdetails.DebugInfo{
+		StackEntries: callerStack(3),
+		Detail: fmt.Sprintf("method=%s position=%s retryable=%t error=%q",
+			method, position, retryableCode(code), err.Error()),
+	}
+}
+
+// callerStack formats the goroutine's stack, skipping the innermost skip frames and the
+// runtime
+func callerStack(skip int) []string {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(skip+1, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+
+	var stack []string
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, "runtime.") {
+			stack = append(stack, fmt.Sprintf("%s (%s:%d)", frame.Function, frame.File, frame.Line))
+		}
+		if !more {
+			return stack
+		}
+	}
+}
+
+// retryableCode reports whether clients may retry a call that failed with code, using
+// the same defaults as Retry
+func retryableCode(code codes.Code) bool {
+	switch code {
+	case codes.Unavailable, codes.ResourceExhausted, codes.Aborted, codes.DeadlineExceeded:
+		return true
+	}
+	return false
+}
+
+// withDebugInfo replaces any DebugInfo of st with info
+func withDebugInfo(st *status.Status, info *errdetails.DebugInfo) *status.Status {
+	st = withoutDebugInfo(st)
+	detailed, err := st.WithDetails(info)
+	if err != nil {
+		return st
+	}
+	return detailed
+}
+
+// withoutDebugInfo returns st without DebugInfo details
+func withoutDebugInfo(st *status.Status) *status.Status {
+	pb := st.Proto()
+	details := pb.Details[:0]
+	for _, detail := range pb.Details {
+		if !detail.MessageIs((*errdetails.DebugInfo)(nil)) {
+			details = append(details, detail)
+		}
+	}
+	if len(details) == len(pb.Details) {
+		return st
+	}
+	pb.Details = details
+	return status.FromProto(pb)
+}
+
+// ErrorDebugInfo returns the google.rpc.DebugInfo detail of an error, as set by ErrorMapper
+// outside production
+func ErrorDebugInfo(err error) (*errdetails.DebugInfo, bool) {
+	st, isStatus := status.FromError(err)
+	if !isStatus {
+		return nil, false
+	}
+	for _, detail := range st.Details() {
+		if info, isInfo := detail.(*errdetails.DebugInfo); isInfo {
+			return proto.Clone(info).(*errdetails.DebugInfo), true
+		}
+	}
+	return nil, false
+}
+
+// ErrorReason returns the google.rpc.ErrorInfo reason and domain of an error, as set by
+// ErrorMapper
+func ErrorReason(err error) (reason, domain string, ok bool) {
+	st, isStatus := status.FromError(err)
+	if !isStatus {
+		return "", "", false
+	}
+	for _, detail := range st.Details() {
+		if info, isInfo := detail.(*errdetails.ErrorInfo); isInfo {
+			return info.GetReason(), info.GetDomain(), true
+		}
+	}
+	return "", "", false
+}
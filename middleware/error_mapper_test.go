@@ -0,0 +1,144 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var errOrderNotFound = errors.New("select * from orders where id = 42: no rows")
+
+type conflictError struct{ version int }
+
+func (e *conflictError) Error() string { return fmt.Sprintf("version conflict at %d", e.version) }
+
+func TestErrorMapper(t *testing.T) {
+	var original error
+	interceptor := ErrorMapper(
+		WithErrorDomain("orders.example.com"),
+		MapError(errOrderNotFound, ErrorMapping{Code: codes.NotFound, Message: "order not found", Reason: "ORDER_NOT_FOUND"}),
+		MapErrorType[*conflictError](ErrorMapping{Code: codes.Aborted, Reason: "ORDER_CONFLICT", Metadata: map[string]string{"resource": "order"}}),
+		WithHiddenInternalErrors(),
+		WithErrorMapperCallback(func(ctx context.Context, method string, err error, st *status.Status) {
+			original = err
+		}),
+	)
+	info := &grpc.UnaryServerInfo{FullMethod: "/shop.v1.Orders/Get"}
+	call := func(err error) error {
+		_, got := interceptor(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			return nil, err
+		})
+		return got
+	}
+
+	tests := []struct {
+		name    string
+		err     error
+		code    codes.Code
+		message string
+		reason  string
+	}{
+		{"sentinel", fmt.Errorf("get order: %w", errOrderNotFound), codes.NotFound, "order not found", "ORDER_NOT_FOUND"},
+		{"type", fmt.Errorf("update: %w", &conflictError{version: 3}), codes.Aborted, "Aborted", "ORDER_CONFLICT"},
+		{"context", context.DeadlineExceeded, codes.DeadlineExceeded, "DeadlineExceeded", ""},
+		{"unmapped", errors.New("dial tcp 10.0.0.5:5432: connection refused"), codes.Internal, "internal server error", ""},
+		{"internal status", status.Error(codes.Internal, "pq: deadlock detected"), codes.Internal, "internal server error", ""},
+		{"public status", status.Error(codes.InvalidArgument, "id is required"), codes.InvalidArgument, "id is required", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := call(tt.err)
+			st := status.Convert(err)
+			if st.Code() != tt.code || st.Message() != tt.message {
+				t.Errorf("Expected %s %q, got %s %q", tt.code, tt.message, st.Code(), st.Message())
+			}
+			reason, domain, ok := ErrorReason(err)
+			if tt.reason == "" {
+				if ok {
+					t.Errorf("Expected no ErrorInfo, got %s", reason)
+				}
+			} else if reason != tt.reason || domain != "orders.example.com" {
+				t.Errorf("Expected ErrorInfo %s/orders.example.com, got %s/%s", tt.reason, reason, domain)
+			}
+			if original != tt.err {
+				t.Errorf("Expected the callback to see the original error, got %v", original)
+			}
+		})
+	}
+
+	if err := call(nil); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+}
+
+func TestErrorMapper_KeepsMessages(t *testing.T) {
+	interceptor := ErrorMapper(MapError(errOrderNotFound, ErrorMapping{Code: codes.NotFound}))
+	info := &grpc.UnaryServerInfo{FullMethod: "/shop.v1.Orders/Get"}
+
+	_, err := interceptor(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, errOrderNotFound
+	})
+	if st := status.Convert(err); st.Code() != codes.NotFound || st.Message() != errOrderNotFound.Error() {
+		t.Errorf("Expected NotFound with the original message, got %v", err)
+	}
+
+	_, err = interceptor(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, errors.New("boom")
+	})
+	if st := status.Convert(err); st.Code() != codes.Unknown || st.Message() != "boom" {
+		t.Errorf("Expected unmapped errors to pass through, got %v", err)
+	}
+}
+
+func TestErrorMapper_DebugInfo(t *testing.T) {
+	info := &grpc.UnaryServerInfo{FullMethod: "/shop.v1.Orders/Get"}
+	handlerErr := func(err error) grpc.UnaryHandler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			return nil, err
+		}
+	}
+
+	staging := ErrorMapper(
+		WithDebugInfo("staging"),
+		MapError(errOrderNotFound, ErrorMapping{Code: codes.NotFound, Message: "order not found"}),
+		WithHiddenInternalErrors(),
+	)
+	_, err := staging(context.Background(), nil, info, handlerErr(fmt.Errorf("get: %w", errOrderNotFound)))
+	debug, ok := ErrorDebugInfo(err)
+	if !ok {
+		t.Fatalf("Expected DebugInfo outside production, got %v", err)
+	}
+	if !strings.Contains(debug.GetDetail(), "position=rule 1") || !strings.Contains(debug.GetDetail(), "retryable=false") ||
+		!strings.Contains(debug.GetDetail(), errOrderNotFound.Error()) {
+		t.Errorf("Unexpected detail %q", debug.GetDetail())
+	}
+	if len(debug.GetStackEntries()) == 0 || !strings.Contains(debug.GetStackEntries()[0], "ErrorMapper") {
+		t.Errorf("Expected the stack to start at ErrorMapper, got %v", debug.GetStackEntries())
+	}
+	if st := status.Convert(err); st.Code() != codes.NotFound || st.Message() != "order not found" {
+		t.Errorf("Expected the public status to be unchanged, got %v", err)
+	}
+
+	_, err = staging(context.Background(), nil, info, handlerErr(status.Error(codes.Unavailable, "replica down")))
+	if debug, ok := ErrorDebugInfo(err); !ok || !strings.Contains(debug.GetDetail(), "position=unmapped retryable=true") {
+		t.Errorf("Expected unmapped retryable DebugInfo, got %v", debug)
+	}
+
+	production := ErrorMapper(WithDebugInfo("Production"))
+	withDebug, _ := status.New(codes.Internal, "boom").WithDetails(&errdetails.DebugInfo{Detail: "db=primary"})
+	_, err = production(context.Background(), nil, info, handlerErr(withDebug.Err()))
+	if _, ok := ErrorDebugInfo(err); ok {
+		t.Errorf("Expected production to strip DebugInfo, got %v", err)
+	}
+	if st := status.Convert(err); st.Code() != codes.Internal || st.Message() != "boom" {
+		t.Errorf("Expected the status to be kept, got %v", err)
+	}
+}
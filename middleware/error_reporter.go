@@ -0,0 +1,245 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"runtime/debug"
+	"time"
+
+	"github.com/grpc-guardian/grpc-guardian/pkg/errtrack"
+	"github.com/grpc-guardian/grpc-guardian/pkg/randutil"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// errorReporterConfig holds the configuration of ErrorReporter
+type errorReporterConfig struct {
+	codes       map[codes.Code]bool
+	sampleRate  float64
+	fingerprint func(method string, err error) []string
+	timeout     time.Duration
+	inflight    chan struct{}
+	onError     func(error)
+	rng         *randutil.Rand
+}
+
+// ErrorReporterOption configures ErrorReporter
+type ErrorReporterOption func(*errorReporterConfig)
+
+// WithReportedCodes sets which status codes are reported
+// Default: Internal, Unknown, DataLoss
+func WithReportedCodes(reported ...codes.Code) ErrorReporterOption {
+	return func(c *errorReporterConfig) {
+		c.codes = make(map[codes.Code]bool, len(reported))
+		for _, code := range reported {
+			c.codes[code] = true
+		}
+	}
+}
+
+// WithReportSampleRate sets the fraction of errors reported, between 0 and 1. Panics are
+// always reported.
+// Default: 1
+func WithReportSampleRate(rate float64) ErrorReporterOption {
+	return func(c *errorReporterConfig) {
+		c.sampleRate = rate
+	}
+}
+
+// WithReportSeed seeds the sampling of reported errors, so the same seed and sequence of
+// errors report the same errors, e.g. for reproducible tests
+// Default: seeded from the clock
+func WithReportSeed(seed int64) ErrorReporterOption {
+	return func(c *errorReporterConfig) {
+		c.rng = randutil.New(seed)
+	}
+}
+
+// WithReportFingerprint sets how events are grouped in the tracker
+// Default: DefaultErrorFingerprint
+func WithReportFingerprint(fn func(method string, err error) []string) ErrorReporterOption {
+	return func(c *errorReporterConfig) {
+		c.fingerprint = fn
+	}
+}
+
+// WithReportTimeout bounds each call to the reporter
+// Default: 10s
+func WithReportTimeout(timeout time.Duration) ErrorReporterOption {
+	return func(c *errorReporterConfig) {
+		c.timeout = timeout
+	}
+}
+
+// WithMaxPendingReports caps the reports sent at the same time; events beyond it are dropped,
+// so an error storm doesn't pile up goroutines
+// Default: 64
+func WithMaxPendingReports(n int) ErrorReporterOption {
+	return func(c *errorReporterConfig) {
+		if n > 0 {
+			c.inflight = make(chan struct{}, n)
+		}
+	}
+}
+
+// WithReportErrorHandler is called when an event can't be reported or is dropped
+// Default: errors are ignored
+func WithReportErrorHandler(fn func(error)) ErrorReporterOption {
+	return func(c *errorReporterConfig) {
+		c.onError = fn
+	}
+}
+
+// ErrorReporter creates middleware that sends server errors and panics to an error tracker.
+// Events carry the method, the authenticated user and tenant, the trace ID and a fingerprint
+// for grouping. They are sent in the background, so the tracker never slows down requests.
+// Panics are reported with their stack and then re-raised, so place ErrorReporter after
+// Recovery.
+//
+// Example usage:
+//
+//	sentry, _ := errtrack.NewSentryReporter(os.Getenv("SENTRY_DSN"),
+//	    errtrack.WithSentryEnvironment("production"),
+//	)
+//	chain := guardian.NewChain(
+//	    middleware.Recovery(),
+//	    middleware.ErrorReporter(sentry, middleware.WithReportSampleRate(0.5)),
+//	)
+func ErrorReporter(reporter errtrack.Reporter, opts ...ErrorReporterOption) func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	config := newErrorReporterConfig(opts)
+
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		defer config.reportPanic(ctx, reporter, info.FullMethod)
+
+		resp, err := handler(ctx, req)
+		if err != nil {
+			config.reportError(ctx, reporter, info.FullMethod, err)
+		}
+		return resp, err
+	}
+}
+
+// StreamErrorReporter reports errors and panics of streaming handlers
+func StreamErrorReporter(reporter errtrack.Reporter, opts ...ErrorReporterOption) func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	config := newErrorReporterConfig(opts)
+
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		defer config.reportPanic(ss.Context(), reporter, info.FullMethod)
+
+		err := handler(srv, ss)
+		if err != nil {
+			config.reportError(ss.Context(), reporter, info.FullMethod, err)
+		}
+		return err
+	}
+}
+
+func newErrorReporterConfig(opts []ErrorReporterOption) *errorReporterConfig {
+	config := &errorReporterConfig{
+		codes:       map[codes.Code]bool{codes.Internal: true, codes.Unknown: true, codes.DataLoss: true},
+		sampleRate:  1,
+		fingerprint: DefaultErrorFingerprint,
+		timeout:     10 * time.Second,
+		inflight:    make(chan struct{}, 64),
+	}
+
+	for _, opt := range opts {
+		opt(config)
+	}
+	if config.rng == nil {
+		config.rng = randutil.NewTimeSeeded()
+	}
+
+	return config
+}
+
+// sampled makes the per-error reporting decision
+func (c *errorReporterConfig) sampled() bool {
+	return c.sampleRate >= 1 || (c.sampleRate > 0 && c.rng.Float64() < c.sampleRate)
+}
+
+// reportError reports err if its code is reported and it is sampled
+func (c *errorReporterConfig) reportError(ctx context.Context, reporter errtrack.Reporter, method string, err error) {
+	st := status.Convert(err)
+	if !c.codes[st.Code()] || !c.sampled() {
+		return
+	}
+
+	event := c.event(ctx, method, st.Code(), st.Message())
+	event.Fingerprint = c.fingerprint(method, err)
+	c.send(reporter, event)
+}
+
+// reportPanic reports a panic in progress and re-raises it. It must be deferred.
+func (c *errorReporterConfig) reportPanic(ctx context.Context, reporter errtrack.Reporter, method string) {
+	p := recover()
+	if p == nil {
+		return
+	}
+
+	event := c.event(ctx, method, codes.Internal, fmt.Sprint(p))
+	event.Panic = true
+	event.Stack = debug.Stack()
+	event.Fingerprint = []string{method, "panic", normalizeErrorMessage(fmt.Sprint(p))}
+	c.send(reporter, event)
+
+	panic(p)
+}
+
+// event describes a failed call
+func (c *errorReporterConfig) event(ctx context.Context, method string, code codes.Code, message string) errtrack.Event {
+	event := errtrack.Event{
+		Time:    time.Now(),
+		Method:  method,
+		Code:    code.String(),
+		Message: message,
+	}
+	event.UserID, _ = GetUserID(ctx)
+	event.TenantID, _ = GetTenantID(ctx)
+	if sc := trace.SpanContextFromContext(ctx); sc.HasTraceID() {
+		event.TraceID = sc.TraceID().String()
+	}
+	return event
+}
+
+// send reports event in the background, dropping it if too many reports are pending
+func (c *errorReporterConfig) send(reporter errtrack.Reporter, event errtrack.Event) {
+	select {
+	case c.inflight <- struct{}{}:
+	default:
+		if c.onError != nil {
+			c.onError(fmt.Errorf("error report for %s dropped: too many pending reports", event.Method))
+		}
+		return
+	}
+
+	go func() {
+		defer func() { <-c.inflight }()
+
+		ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+		defer cancel()
+		if err := reporter.Report(ctx, event); err != nil && c.onError != nil {
+			c.onError(err)
+		}
+	}()
+}
+
+// variableParts matches the parts of error messages that differ between occurrences of the
+// same error: UUIDs, hex IDs, numbers and quoted values
+var variableParts = regexp.MustCompile(`[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}|0x[0-9a-fA-F]+|\b[0-9a-fA-F]{16,}\b|\d+|"[^"]*"|'[^']*'`)
+
+// normalizeErrorMessage replaces the variable parts of an error message with placeholders
+func normalizeErrorMessage(message string) string {
+	return variableParts.ReplaceAllString(message, "?")
+}
+
+// DefaultErrorFingerprint groups errors by method, status code and message, with IDs,
+// numbers and quoted values masked, so "order 42 not found" and "order 43 not found" are
+// one issue
+func DefaultErrorFingerprint(method string, err error) []string {
+	st := status.Convert(err)
+	return []string{method, st.Code().String(), normalizeErrorMessage(st.Message())}
+}
@@ -0,0 +1,95 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/grpc-guardian/grpc-guardian/pkg/errtrack"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestErrorReporter(t *testing.T) {
+	events := make(chan errtrack.Event, 10)
+	reporter := errtrack.ReporterFunc(func(ctx context.Context, event errtrack.Event) error {
+		events <- event
+		return nil
+	})
+	interceptor := ErrorReporter(reporter)
+	info := &grpc.UnaryServerInfo{FullMethod: "/shop.v1.Orders/Get"}
+	ctx := context.WithValue(context.Background(), contextKeyUserID, "user-7")
+
+	for _, err := range []error{
+		status.Error(codes.NotFound, "order 42 not found"),
+		status.Error(codes.Internal, "order 42: deadlock"),
+		errors.New("order 43: deadlock"),
+	} {
+		_, got := interceptor(ctx, nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			return nil, err
+		})
+		if got != err {
+			t.Errorf("Expected the error to be returned unchanged, got %v", got)
+		}
+	}
+
+	// Reports are sent concurrently, so they may arrive in any order
+	reported := make(map[string]errtrack.Event)
+	for i := 0; i < 2; i++ {
+		event := receiveEvent(t, events)
+		reported[event.Code] = event
+	}
+	internal, unknown := reported["Internal"], reported["Unknown"]
+	if internal.Method != info.FullMethod || internal.UserID != "user-7" {
+		t.Errorf("Unexpected event %+v", internal)
+	}
+	if unknown.Code != "Unknown" {
+		t.Errorf("Expected Internal and Unknown events, got %v", reported)
+	}
+	if internal.Fingerprint[2] != unknown.Fingerprint[2] {
+		t.Errorf("Expected IDs to be masked in fingerprints, got %v and %v", internal.Fingerprint, unknown.Fingerprint)
+	}
+	select {
+	case event := <-events:
+		t.Errorf("Expected NotFound not to be reported, got %+v", event)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestErrorReporter_Panic(t *testing.T) {
+	events := make(chan errtrack.Event, 1)
+	interceptor := ErrorReporter(errtrack.ReporterFunc(func(ctx context.Context, event errtrack.Event) error {
+		events <- event
+		return nil
+	}), WithReportSampleRate(0))
+	info := &grpc.UnaryServerInfo{FullMethod: "/shop.v1.Orders/Get"}
+
+	func() {
+		defer func() {
+			if p := recover(); p != "boom" {
+				t.Errorf("Expected the panic to be re-raised, got %v", p)
+			}
+		}()
+		interceptor(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			panic("boom")
+		})
+	}()
+
+	event := receiveEvent(t, events)
+	if !event.Panic || event.Message != "boom" || len(event.Stack) == 0 {
+		t.Errorf("Expected a panic event with a stack, got %+v", event)
+	}
+}
+
+func receiveEvent(t *testing.T, events <-chan errtrack.Event) errtrack.Event {
+	t.Helper()
+	select {
+	case event := <-events:
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("Expected an error report")
+		return errtrack.Event{}
+	}
+}
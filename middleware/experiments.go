@@ -0,0 +1,273 @@
+package middleware
+
+import (
+	"context"
+	"hash/fnv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// ExperimentHeaderPrefix prefixes the metadata carrying the variant of an experiment, e.g.
+// "x-experiment-checkout-button: green"
+const ExperimentHeaderPrefix = "x-experiment-"
+
+// Variant is an arm of an experiment
+type Variant struct {
+	Name   string
+	Weight uint // Relative share of callers; when every weight is zero the split is even
+}
+
+// Experiment splits callers between variants
+type Experiment struct {
+	Name     string
+	Salt     string    // Mixed into the assignment hash; default Name. Change it to reshuffle callers.
+	Variants []Variant // The first variant is usually the control
+	Methods  []string  // Method patterns the experiment runs on; empty means every method
+}
+
+// AssignVariant returns the variant of an experiment for a caller key. The assignment is
+// deterministic: the same key always gets the same variant while the salt and weights are
+// kept, on every replica and in offline analysis.
+func AssignVariant(experiment Experiment, key string) string {
+	if len(experiment.Variants) == 0 {
+		return ""
+	}
+
+	var total uint64
+	for _, variant := range experiment.Variants {
+		total += uint64(variant.Weight)
+	}
+
+	salt := experiment.Salt
+	if salt == "" {
+		salt = experiment.Name
+	}
+	h := fnv.New64a()
+	h.Write([]byte(salt))
+	h.Write([]byte{':'})
+	h.Write([]byte(key))
+	sum := h.Sum64()
+
+	if total == 0 {
+		return experiment.Variants[sum%uint64(len(experiment.Variants))].Name
+	}
+	bucket := sum % total
+	for _, variant := range experiment.Variants {
+		if bucket < uint64(variant.Weight) {
+			return variant.Name
+		}
+		bucket -= uint64(variant.Weight)
+	}
+	return experiment.Variants[len(experiment.Variants)-1].Name
+}
+
+// experimentsConfig holds the configuration of Experiments
+type experimentsConfig struct {
+	experiments []Experiment
+	key         func(ctx context.Context) (string, bool)
+	trustHeader bool
+	registerer  prometheus.Registerer
+}
+
+// ExperimentsOption configures Experiments
+type ExperimentsOption func(*experimentsConfig)
+
+// WithExperiment runs an experiment
+func WithExperiment(experiment Experiment) ExperimentsOption {
+	return func(c *experimentsConfig) {
+		c.experiments = append(c.experiments, experiment)
+	}
+}
+
+// WithExperimentKey sets the caller key that is hashed into a variant. Callers without a
+// key are not enrolled.
+// Default: the user ID (GetUserID)
+func WithExperimentKey(fn func(ctx context.Context) (string, bool)) ExperimentsOption {
+	return func(c *experimentsConfig) {
+		if fn != nil {
+			c.key = fn
+		}
+	}
+}
+
+// WithTrustedExperimentHeaders keeps the variants assigned by an upstream service, read
+// from incoming x-experiment-* metadata, so every hop of a call serves the same arm. Only
+// enable it behind a gateway that strips these headers from external clients.
+func WithTrustedExperimentHeaders() ExperimentsOption {
+	return func(c *experimentsConfig) {
+		c.trustHeader = true
+	}
+}
+
+// WithExperimentMetrics registers a grpc_server_experiment_exposures_total counter, labeled
+// by experiment and variant, to compare the traffic of each arm
+func WithExperimentMetrics(registerer prometheus.Registerer) ExperimentsOption {
+	return func(c *experimentsConfig) {
+		c.registerer = registerer
+	}
+}
+
+// experiments assigns requests to experiment variants
+type experiments struct {
+	config    *experimentsConfig
+	methods   []*methodMatcher[bool] // Per experiment; nil runs on every method
+	variants  []map[string]bool      // Per experiment, to validate trusted headers
+	exposures *prometheus.CounterVec
+}
+
+// Experiments creates middleware that assigns callers to experiment variants. The variant is
+// a hash of the caller's user ID and the experiment's salt, so a user sees the same arm on
+// every request and replica. Assignments are stored in the context for handlers (see
+// GetExperimentVariant) and propagated to downstream calls as x-experiment-<name> metadata
+// by the HeaderPropagation client interceptors.
+//
+// Example usage:
+//
+//	chain := guardian.NewChain(
+//	    middleware.Auth(middleware.JWTValidator(secret)),
+//	    middleware.Experiments(
+//	        middleware.WithExperiment(middleware.Experiment{
+//	            Name:     "checkout-button",
+//	            Variants: []middleware.Variant{{Name: "control", Weight: 90}, {Name: "green", Weight: 10}},
+//	            Methods:  []string{"/orders.Orders/*"},
+//	        }),
+//	        middleware.WithExperimentMetrics(collector.GetRegistry()),
+//	    ),
+//	)
+//
+//	func (s *server) Checkout(ctx context.Context, req *pb.CheckoutRequest) (*pb.Order, error) {
+//	    if variant, _ := middleware.GetExperimentVariant(ctx, "checkout-button"); variant == "green" {
+//	        ...
+//	    }
+//	}
+func Experiments(opts ...ExperimentsOption) func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	e := newExperiments(opts)
+
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		return handler(e.assign(ctx, info.FullMethod), req)
+	}
+}
+
+// StreamExperiments assigns streams to experiment variants
+func StreamExperiments(opts ...ExperimentsOption) func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	e := newExperiments(opts)
+
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx := e.assign(ss.Context(), info.FullMethod)
+		return handler(srv, &experimentsServerStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+func newExperiments(opts []ExperimentsOption) *experiments {
+	config := &experimentsConfig{
+		key: func(ctx context.Context) (string, bool) {
+			userID, ok := GetUserID(ctx)
+			return userID, ok && userID != ""
+		},
+	}
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	e := &experiments{
+		config:   config,
+		methods:  make([]*methodMatcher[bool], len(config.experiments)),
+		variants: make([]map[string]bool, len(config.experiments)),
+	}
+	for i, experiment := range config.experiments {
+		if len(experiment.Methods) > 0 {
+			patterns := make(map[string]bool, len(experiment.Methods))
+			for _, pattern := range experiment.Methods {
+				patterns[pattern] = true
+			}
+			e.methods[i] = newMethodMatcher(patterns)
+		}
+		e.variants[i] = make(map[string]bool, len(experiment.Variants))
+		for _, variant := range experiment.Variants {
+			e.variants[i][variant.Name] = true
+		}
+	}
+
+	if config.registerer != nil {
+		e.exposures = prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "grpc",
+			Subsystem: "server",
+			Name:      "experiment_exposures_total",
+			Help:      "Total number of requests exposed to an experiment variant",
+		}, []string{"experiment", "variant"})
+		if err := config.registerer.Register(e.exposures); err != nil {
+			if are, ok := err.(prometheus.AlreadyRegisteredError); ok {
+				e.exposures = are.ExistingCollector.(*prometheus.CounterVec)
+			}
+		}
+	}
+	return e
+}
+
+// assign returns the context holding the variants of the experiments running on method
+func (e *experiments) assign(ctx context.Context, method string) context.Context {
+	key, enrolled := e.config.key(ctx)
+	var incoming metadata.MD
+	if e.config.trustHeader {
+		incoming, _ = metadata.FromIncomingContext(ctx)
+	}
+
+	var assigned map[string]string
+	for i, experiment := range e.config.experiments {
+		if e.methods[i] != nil {
+			if _, ok := e.methods[i].match(method); !ok {
+				continue
+			}
+		}
+
+		variant := ""
+		if values := incoming.Get(ExperimentHeaderPrefix + strings.ToLower(experiment.Name)); len(values) > 0 && e.variants[i][values[0]] {
+			variant = values[0]
+		} else if enrolled {
+			variant = AssignVariant(experiment, key)
+		}
+		if variant == "" {
+			continue
+		}
+
+		if assigned == nil {
+			assigned = make(map[string]string, len(e.config.experiments))
+		}
+		assigned[experiment.Name] = variant
+		ctx = SetPropagatedHeader(ctx, ExperimentHeaderPrefix+strings.ToLower(experiment.Name), variant)
+		if e.exposures != nil {
+			e.exposures.WithLabelValues(experiment.Name, variant).Inc()
+		}
+	}
+
+	if assigned == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, contextKeyExperiments, assigned)
+}
+
+// GetExperiments retrieves the variants assigned by the Experiments middleware, keyed by
+// experiment name
+func GetExperiments(ctx context.Context) (map[string]string, bool) {
+	assigned, ok := ctx.Value(contextKeyExperiments).(map[string]string)
+	return assigned, ok
+}
+
+// GetExperimentVariant retrieves the variant of an experiment assigned to the request
+func GetExperimentVariant(ctx context.Context, experiment string) (string, bool) {
+	assigned, _ := GetExperiments(ctx)
+	variant, ok := assigned[experiment]
+	return variant, ok
+}
+
+type experimentsServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *experimentsServerStream) Context() context.Context {
+	return s.ctx
+}
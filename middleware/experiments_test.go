@@ -0,0 +1,151 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+var checkoutExperiment = Experiment{
+	Name:     "checkout-button",
+	Variants: []Variant{{Name: "control", Weight: 75}, {Name: "green", Weight: 25}},
+	Methods:  []string{"/orders.Orders/*"},
+}
+
+func TestAssignVariant(t *testing.T) {
+	counts := map[string]int{}
+	for i := 0; i < 4000; i++ {
+		key := fmt.Sprintf("user-%d", i)
+		variant := AssignVariant(checkoutExperiment, key)
+		assert.Equal(t, variant, AssignVariant(checkoutExperiment, key), "assignment must be deterministic")
+		counts[variant]++
+	}
+	assert.InDelta(t, 1000, counts["green"], 200)
+	assert.Equal(t, 4000, counts["control"]+counts["green"])
+
+	// A new salt reshuffles callers
+	resalted := checkoutExperiment
+	resalted.Salt = "checkout-button-v2"
+	moved := 0
+	for i := 0; i < 1000; i++ {
+		key := fmt.Sprintf("user-%d", i)
+		if AssignVariant(resalted, key) != AssignVariant(checkoutExperiment, key) {
+			moved++
+		}
+	}
+	assert.Greater(t, moved, 100)
+
+	even := Experiment{Name: "even", Variants: []Variant{{Name: "a"}, {Name: "b"}}}
+	assert.Contains(t, []string{"a", "b"}, AssignVariant(even, "alice"))
+	assert.Equal(t, "", AssignVariant(Experiment{Name: "empty"}, "alice"))
+}
+
+func TestExperiments_AssignsAndPropagates(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	interceptor := Experiments(
+		WithExperiment(checkoutExperiment),
+		WithExperiment(Experiment{Name: "Search", Variants: []Variant{{Name: "v1"}, {Name: "v2"}}}),
+		WithExperimentMetrics(registry),
+	)
+
+	ctx := context.WithValue(context.Background(), contextKeyUserID, "alice")
+	var assigned map[string]string
+	var propagated metadata.MD
+	_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/orders.Orders/Checkout"},
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			assigned, _ = GetExperiments(ctx)
+			propagated, _ = GetPropagatedHeaders(ctx)
+			return nil, nil
+		})
+	assert.NoError(t, err)
+
+	checkout := AssignVariant(checkoutExperiment, "alice")
+	assert.Equal(t, checkout, assigned["checkout-button"])
+	assert.NotEmpty(t, assigned["Search"])
+	assert.Equal(t, []string{checkout}, propagated.Get("x-experiment-checkout-button"))
+	assert.Equal(t, []string{assigned["Search"]}, propagated.Get("x-experiment-search"))
+
+	// Scoped experiments don't run on other methods
+	_, err = interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/users.Users/Get"},
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			_, ok := GetExperimentVariant(ctx, "checkout-button")
+			assert.False(t, ok)
+			_, ok = GetExperimentVariant(ctx, "Search")
+			assert.True(t, ok)
+			return nil, nil
+		})
+	assert.NoError(t, err)
+
+	expected := fmt.Sprintf(`
+		# HELP grpc_server_experiment_exposures_total Total number of requests exposed to an experiment variant
+		# TYPE grpc_server_experiment_exposures_total counter
+		grpc_server_experiment_exposures_total{experiment="Search",variant="%s"} 2
+		grpc_server_experiment_exposures_total{experiment="checkout-button",variant="%s"} 1
+	`, assigned["Search"], checkout)
+	assert.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(expected), "grpc_server_experiment_exposures_total"))
+}
+
+func TestExperiments_AnonymousNotEnrolled(t *testing.T) {
+	interceptor := Experiments(WithExperiment(checkoutExperiment))
+
+	_, err := interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/orders.Orders/Checkout"},
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			_, ok := GetExperiments(ctx)
+			assert.False(t, ok)
+			return nil, nil
+		})
+	assert.NoError(t, err)
+}
+
+func TestExperiments_TrustedHeaders(t *testing.T) {
+	incoming := metadata.Pairs("x-experiment-checkout-button", "green")
+	ctx := metadata.NewIncomingContext(context.Background(), incoming)
+	info := &grpc.UnaryServerInfo{FullMethod: "/orders.Orders/Checkout"}
+
+	// Untrusted headers are ignored, so anonymous callers stay unenrolled
+	_, _ = Experiments(WithExperiment(checkoutExperiment))(ctx, nil, info,
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			_, ok := GetExperimentVariant(ctx, "checkout-button")
+			assert.False(t, ok)
+			return nil, nil
+		})
+
+	trusted := Experiments(WithExperiment(checkoutExperiment), WithTrustedExperimentHeaders())
+	_, _ = trusted(ctx, nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		variant, _ := GetExperimentVariant(ctx, "checkout-button")
+		assert.Equal(t, "green", variant)
+		return nil, nil
+	})
+
+	// Unknown variants fall back to the local assignment
+	ctx = metadata.NewIncomingContext(context.WithValue(context.Background(), contextKeyUserID, "alice"),
+		metadata.Pairs("x-experiment-checkout-button", "purple"))
+	_, _ = trusted(ctx, nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		variant, _ := GetExperimentVariant(ctx, "checkout-button")
+		assert.Equal(t, AssignVariant(checkoutExperiment, "alice"), variant)
+		return nil, nil
+	})
+}
+
+func TestStreamExperiments(t *testing.T) {
+	interceptor := StreamExperiments(
+		WithExperiment(checkoutExperiment),
+		WithExperimentKey(func(ctx context.Context) (string, bool) { return "tenant-acme", true }),
+	)
+
+	err := interceptor(nil, &recvServerStream{ctx: context.Background()}, &grpc.StreamServerInfo{FullMethod: "/orders.Orders/Watch"},
+		func(srv interface{}, ss grpc.ServerStream) error {
+			variant, ok := GetExperimentVariant(ss.Context(), "checkout-button")
+			assert.True(t, ok)
+			assert.Equal(t, AssignVariant(checkoutExperiment, "tenant-acme"), variant)
+			return nil
+		})
+	assert.NoError(t, err)
+}
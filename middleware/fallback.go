@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"context"
+	"reflect"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// Fallback produces a degraded response, e.g. a cached, stale or default value, for a call
+// that can't be served. err is the error the call would otherwise fail with; returning it
+// (or another error) gives up.
+type Fallback func(ctx context.Context, req interface{}, err error) (interface{}, error)
+
+// invokeFallback runs fallback for a client call and copies its response into reply
+func invokeFallback(ctx context.Context, fallback Fallback, req, reply interface{}, err error) error {
+	resp, err := fallback(ctx, req, err)
+	if err != nil {
+		return err
+	}
+	return copyReply(reply, resp)
+}
+
+// copyReply copies a fallback response into the reply of a client call
+func copyReply(reply, resp interface{}) error {
+	if resp == nil || reply == resp {
+		return nil
+	}
+
+	if dst, ok := reply.(proto.Message); ok {
+		if src, ok := resp.(proto.Message); ok && dst.ProtoReflect().Descriptor() == src.ProtoReflect().Descriptor() {
+			proto.Reset(dst)
+			proto.Merge(dst, src)
+			return nil
+		}
+	}
+
+	dst, src := reflect.ValueOf(reply), reflect.ValueOf(resp)
+	if dst.Kind() == reflect.Pointer && !dst.IsNil() && src.Type() == dst.Type() && !src.IsNil() {
+		dst.Elem().Set(src.Elem())
+		return nil
+	}
+	return status.Errorf(codes.Internal, "fallback response of type %T doesn't match reply of type %T", resp, reply)
+}
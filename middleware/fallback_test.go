@@ -0,0 +1,91 @@
+package middleware
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestCircuitBreaker_Fallback(t *testing.T) {
+	var fallbackErr error
+	cb := NewCircuitBreaker(
+		WithOpenTimeout(time.Minute),
+		WithBreakerFallback(func(ctx context.Context, req interface{}, err error) (interface{}, error) {
+			fallbackErr = err
+			return wrapperspb.String("cached"), nil
+		}),
+	)
+	cb.mu.Lock()
+	cb.setState(StateOpen, time.Now())
+	cb.mu.Unlock()
+
+	// Server
+	info := &grpc.UnaryServerInfo{FullMethod: "/test.Catalog/Get"}
+	resp, err := cb.UnaryServerInterceptor()(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		t.Error("Expected the handler not to be called while the circuit is open")
+		return nil, nil
+	})
+	if err != nil || resp.(*wrapperspb.StringValue).GetValue() != "cached" {
+		t.Errorf("Expected the fallback response, got %v, %v", resp, err)
+	}
+	if status.Code(fallbackErr) != codes.Unavailable {
+		t.Errorf("Expected the fallback to see Unavailable, got %v", fallbackErr)
+	}
+
+	// Client: the fallback response is copied into the reply
+	reply := &wrapperspb.StringValue{}
+	err = cb.UnaryClientInterceptor()(context.Background(), "/test.Catalog/Get", nil, reply, nil,
+		func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+			t.Error("Expected the call not to be made while the circuit is open")
+			return nil
+		})
+	if err != nil || reply.GetValue() != "cached" {
+		t.Errorf("Expected the fallback reply, got %q, %v", reply.GetValue(), err)
+	}
+}
+
+func TestRetry_Fallback(t *testing.T) {
+	fallback := func(ctx context.Context, req interface{}, err error) (interface{}, error) {
+		return "stale", nil
+	}
+	interceptor := NewRetry(
+		WithMaxAttempts(2),
+		WithInitialBackoff(time.Millisecond),
+		WithRetryFallback(fallback),
+	).UnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/test.Catalog/Get"}
+
+	attempts := 0
+	resp, err := interceptor(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		attempts++
+		return nil, status.Error(codes.Unavailable, "down")
+	})
+	if err != nil || resp != "stale" || attempts != 2 {
+		t.Errorf("Expected the fallback after 2 attempts, got %v, %v after %d", resp, err, attempts)
+	}
+
+	// Errors that aren't retried are returned as is
+	_, err = interceptor(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, status.Error(codes.NotFound, "missing")
+	})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("Expected NotFound, got %v", err)
+	}
+}
+
+func TestCopyReply(t *testing.T) {
+	type reply struct{ Value string }
+
+	dst := &reply{}
+	if err := copyReply(dst, &reply{Value: "default"}); err != nil || dst.Value != "default" {
+		t.Errorf("Expected the response to be copied, got %+v, %v", dst, err)
+	}
+	if err := copyReply(&wrapperspb.StringValue{}, &wrapperspb.Int64Value{}); status.Code(err) != codes.Internal {
+		t.Errorf("Expected mismatched types to fail, got %v", err)
+	}
+}
@@ -0,0 +1,180 @@
+package middleware
+
+import (
+	"context"
+
+	"github.com/grpc-guardian/grpc-guardian/pkg/featureflags"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+)
+
+// featureFlagsConfig holds the configuration of FeatureFlags
+type featureFlagsConfig struct {
+	flags      []string
+	methods    map[string][]string
+	attributes func(context.Context) map[string]string
+	onError    func(context.Context, error)
+}
+
+// FeatureFlagsOption configures FeatureFlags
+type FeatureFlagsOption func(*featureFlagsConfig)
+
+// WithFlags evaluates flags for every method
+func WithFlags(flags ...string) FeatureFlagsOption {
+	return func(c *featureFlagsConfig) {
+		c.flags = append(c.flags, flags...)
+	}
+}
+
+// WithMethodFlags evaluates flags for methods matching pattern, in addition to the flags of
+// WithFlags. Patterns are "/pkg.Service/Method" or end in "*"; the most specific one wins.
+func WithMethodFlags(pattern string, flags ...string) FeatureFlagsOption {
+	return func(c *featureFlagsConfig) {
+		c.methods[pattern] = append(c.methods[pattern], flags...)
+	}
+}
+
+// WithFlagAttributes adds targeting attributes to the evaluation context, e.g. the plan of
+// the tenant or the caller's country
+func WithFlagAttributes(fn func(ctx context.Context) map[string]string) FeatureFlagsOption {
+	return func(c *featureFlagsConfig) {
+		c.attributes = fn
+	}
+}
+
+// WithFeatureFlagsErrorHandler is called when the provider fails. The request is served
+// anyway, with every flag off.
+func WithFeatureFlagsErrorHandler(fn func(ctx context.Context, err error)) FeatureFlagsOption {
+	return func(c *featureFlagsConfig) {
+		c.onError = fn
+	}
+}
+
+// featureFlags evaluates the flags of requests
+type featureFlags struct {
+	provider featureflags.Provider
+	config   *featureFlagsConfig
+	methods  *methodMatcher[[]string]
+}
+
+// FeatureFlags creates middleware that evaluates feature flags once per request for the
+// caller (the user and tenant stored by the authentication and tenant middleware) and
+// stores the decisions in the context for handlers (see FeatureEnabled and
+// GetFeatureFlags). Each evaluated flag is added to the active span as a
+// "feature_flag.<flag>" attribute, so traces show which code path a request took.
+//
+// Example usage:
+//
+//	provider := featureflags.NewFileProvider("/etc/guardian/flags.json")
+//	chain := guardian.NewChain(
+//	    middleware.Auth(middleware.JWTValidator(secret)),
+//	    middleware.FeatureFlags(provider,
+//	        middleware.WithFlags("new-pricing"),
+//	        middleware.WithMethodFlags("/orders.Orders/*", "new-checkout"),
+//	    ),
+//	)
+//
+//	func (s *server) Checkout(ctx context.Context, req *pb.CheckoutRequest) (*pb.Order, error) {
+//	    if middleware.FeatureEnabled(ctx, "new-checkout") {
+//	        return s.checkoutV2(ctx, req)
+//	    }
+//	    return s.checkoutV1(ctx, req)
+//	}
+func FeatureFlags(provider featureflags.Provider, opts ...FeatureFlagsOption) func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	f := newFeatureFlags(provider, opts)
+
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		return handler(f.evaluate(ctx, info.FullMethod), req)
+	}
+}
+
+// StreamFeatureFlags evaluates feature flags once per stream
+func StreamFeatureFlags(provider featureflags.Provider, opts ...FeatureFlagsOption) func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	f := newFeatureFlags(provider, opts)
+
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx := f.evaluate(ss.Context(), info.FullMethod)
+		return handler(srv, &featureFlagsServerStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+func newFeatureFlags(provider featureflags.Provider, opts []FeatureFlagsOption) *featureFlags {
+	config := &featureFlagsConfig{methods: make(map[string][]string)}
+	for _, opt := range opts {
+		opt(config)
+	}
+	return &featureFlags{
+		provider: provider,
+		config:   config,
+		methods:  newMethodMatcher(config.methods),
+	}
+}
+
+// evaluate evaluates the flags of a method and returns the context holding the decisions
+func (f *featureFlags) evaluate(ctx context.Context, method string) context.Context {
+	flags := f.config.flags
+	if methodFlags, ok := f.methods.match(method); ok {
+		flags = append(append(make([]string, 0, len(flags)+len(methodFlags)), flags...), methodFlags...)
+	}
+	if len(flags) == 0 {
+		return ctx
+	}
+
+	evalCtx := featureflags.EvaluationContext{}
+	evalCtx.UserID, _ = GetUserID(ctx)
+	evalCtx.TenantID, _ = GetTenantID(ctx)
+	evalCtx.Key = evalCtx.UserID
+	if evalCtx.Key == "" {
+		evalCtx.Key = evalCtx.TenantID
+	}
+	if f.config.attributes != nil {
+		evalCtx.Attributes = f.config.attributes(ctx)
+	}
+
+	evaluations, err := f.provider.Evaluate(ctx, evalCtx, flags)
+	if err != nil {
+		if f.config.onError != nil {
+			f.config.onError(ctx, err)
+		}
+		evaluations = nil
+	}
+
+	decisions := make(featureflags.Decisions, len(evaluations))
+	attrs := make([]attribute.KeyValue, 0, len(evaluations))
+	for _, flag := range flags {
+		evaluation, ok := evaluations[flag]
+		if !ok {
+			continue
+		}
+		decisions[flag] = evaluation
+		attrs = append(attrs, attribute.String("feature_flag."+flag, evaluation.Format()))
+	}
+	if len(attrs) > 0 {
+		trace.SpanFromContext(ctx).SetAttributes(attrs...)
+	}
+
+	return context.WithValue(ctx, contextKeyFeatureFlags, decisions)
+}
+
+// GetFeatureFlags retrieves the flags evaluated by the FeatureFlags middleware
+func GetFeatureFlags(ctx context.Context) (featureflags.Decisions, bool) {
+	decisions, ok := ctx.Value(contextKeyFeatureFlags).(featureflags.Decisions)
+	return decisions, ok
+}
+
+// FeatureEnabled reports whether a boolean flag is on for the request. Flags that weren't
+// evaluated, or whose provider failed, are off.
+func FeatureEnabled(ctx context.Context, flag string) bool {
+	decisions, _ := GetFeatureFlags(ctx)
+	return decisions.Enabled(flag)
+}
+
+type featureFlagsServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *featureFlagsServerStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,136 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/grpc-guardian/grpc-guardian/pkg/featureflags"
+	"github.com/stretchr/testify/assert"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"google.golang.org/grpc"
+)
+
+// staticFlags serves true for flags of the listed users and records evaluation contexts
+type staticFlags struct {
+	users    map[string]bool
+	contexts []featureflags.EvaluationContext
+	flags    [][]string
+}
+
+func (s *staticFlags) Evaluate(ctx context.Context, evalCtx featureflags.EvaluationContext, flags []string) (map[string]featureflags.Evaluation, error) {
+	s.contexts = append(s.contexts, evalCtx)
+	s.flags = append(s.flags, flags)
+
+	evaluations := make(map[string]featureflags.Evaluation, len(flags))
+	for _, flag := range flags {
+		evaluations[flag] = featureflags.Evaluation{Flag: flag, Value: s.users[evalCtx.UserID], Reason: "TEST"}
+	}
+	return evaluations, nil
+}
+
+func TestFeatureFlags_StoresDecisions(t *testing.T) {
+	provider := &staticFlags{users: map[string]bool{"alice": true}}
+	interceptor := FeatureFlags(provider,
+		WithFlags("new-pricing"),
+		WithMethodFlags("/orders.Orders/*", "new-checkout"),
+		WithFlagAttributes(func(ctx context.Context) map[string]string {
+			return map[string]string{"plan": "pro"}
+		}),
+	)
+
+	ctx := context.WithValue(context.Background(), contextKeyUserID, "alice")
+	ctx = context.WithValue(ctx, contextKeyTenantID, "acme")
+
+	var enabled, checkout bool
+	_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/orders.Orders/Checkout"},
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			enabled = FeatureEnabled(ctx, "new-pricing")
+			checkout = FeatureEnabled(ctx, "new-checkout")
+			return nil, nil
+		})
+	assert.NoError(t, err)
+	assert.True(t, enabled)
+	assert.True(t, checkout)
+
+	assert.Equal(t, []string{"new-pricing", "new-checkout"}, provider.flags[0])
+	assert.Equal(t, featureflags.EvaluationContext{
+		Key:        "alice",
+		UserID:     "alice",
+		TenantID:   "acme",
+		Attributes: map[string]string{"plan": "pro"},
+	}, provider.contexts[0])
+
+	_, err = interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/users.Users/Get"},
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			decisions, ok := GetFeatureFlags(ctx)
+			assert.True(t, ok)
+			assert.Len(t, decisions, 1)
+			assert.False(t, decisions.Enabled("new-pricing"))
+			return nil, nil
+		})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"new-pricing"}, provider.flags[1])
+}
+
+func TestFeatureFlags_ProviderErrorServesFlagsOff(t *testing.T) {
+	failing := featureflags.ProviderFunc(func(ctx context.Context, evalCtx featureflags.EvaluationContext, flags []string) (map[string]featureflags.Evaluation, error) {
+		return nil, errors.New("flag service down")
+	})
+	var handled error
+	interceptor := FeatureFlags(failing,
+		WithFlags("new-pricing"),
+		WithFeatureFlagsErrorHandler(func(ctx context.Context, err error) { handled = err }),
+	)
+
+	called := false
+	_, err := interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/orders.Orders/Get"},
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			called = true
+			assert.False(t, FeatureEnabled(ctx, "new-pricing"))
+			return nil, nil
+		})
+	assert.NoError(t, err)
+	assert.True(t, called)
+	assert.EqualError(t, handled, "flag service down")
+}
+
+func TestFeatureFlags_SpanAttributes(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+
+	provider := &staticFlags{users: map[string]bool{"alice": true}}
+	interceptor := FeatureFlags(provider, WithFlags("new-pricing"))
+
+	ctx, span := tp.Tracer("test").Start(context.WithValue(context.Background(), contextKeyUserID, "alice"), "call")
+	_, _ = interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/orders.Orders/Get"},
+		func(ctx context.Context, req interface{}) (interface{}, error) { return nil, nil })
+	span.End()
+
+	spans := sr.Ended()
+	assert.Len(t, spans, 1)
+	found := false
+	for _, attr := range spans[0].Attributes() {
+		if attr.Key == "feature_flag.new-pricing" {
+			found = true
+			assert.Equal(t, "true", attr.Value.AsString())
+		}
+	}
+	assert.True(t, found, "expected a feature_flag.new-pricing span attribute")
+}
+
+func TestStreamFeatureFlags(t *testing.T) {
+	provider := &staticFlags{users: map[string]bool{"alice": true}}
+	interceptor := StreamFeatureFlags(provider, WithFlags("live-updates"))
+
+	ctx := context.WithValue(context.Background(), contextKeyUserID, "alice")
+	enabled := false
+	err := interceptor(nil, &recvServerStream{ctx: ctx}, &grpc.StreamServerInfo{FullMethod: "/orders.Orders/Watch"},
+		func(srv interface{}, ss grpc.ServerStream) error {
+			enabled = FeatureEnabled(ss.Context(), "live-updates")
+			return nil
+		})
+	assert.NoError(t, err)
+	assert.True(t, enabled)
+}
@@ -0,0 +1,157 @@
+package middleware
+
+import (
+	"context"
+
+	"github.com/grpc-guardian/grpc-guardian/pkg/fieldcrypt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// FieldEncryption encrypts or tokenizes designated fields of responses and decrypts them
+// in requests, so the handler works with plaintext while clients, logs and caches only
+// ever see ciphertexts and tokens.
+//
+// Register it as the innermost middleware: middlewares after it see plaintext requests,
+// and middlewares before it (logging, caching) see protected responses.
+type FieldEncryption struct {
+	protector *fieldcrypt.Protector
+	methods   *methodMatcher[bool]
+}
+
+// FieldEncryptionOption configures the field encryption middleware
+type FieldEncryptionOption func(*fieldEncryptionConfig)
+
+// fieldEncryptionConfig holds the field encryption middleware settings
+type fieldEncryptionConfig struct {
+	methods map[string]bool
+}
+
+// WithFieldEncryptionMethods only protects methods matching the patterns
+// ("/pkg.Service/Method", "/pkg.Service/*")
+// Default: every method
+func WithFieldEncryptionMethods(patterns ...string) FieldEncryptionOption {
+	return func(c *fieldEncryptionConfig) {
+		for _, pattern := range patterns {
+			c.methods[pattern] = true
+		}
+	}
+}
+
+// NewFieldEncryption creates a field encryption middleware
+//
+// Example usage:
+//
+//	kms, _ := fieldcrypt.NewLocalKMS("2024-06", key)
+//	fields := middleware.NewFieldEncryption(fieldcrypt.NewProtector(kms,
+//	    fieldcrypt.WithEncryptedFields("ssn", "card.number"),
+//	))
+//
+//	server := grpc.NewServer(
+//	    grpc.ChainUnaryInterceptor(logging, cache, fields.UnaryServerInterceptor()),
+//	    grpc.ChainStreamInterceptor(fields.StreamServerInterceptor()),
+//	)
+func NewFieldEncryption(protector *fieldcrypt.Protector, opts ...FieldEncryptionOption) *FieldEncryption {
+	config := &fieldEncryptionConfig{methods: make(map[string]bool)}
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	f := &FieldEncryption{protector: protector}
+	if len(config.methods) > 0 {
+		f.methods = newMethodMatcher(config.methods)
+	}
+	return f
+}
+
+// applies reports whether a method is protected
+func (f *FieldEncryption) applies(method string) bool {
+	if f.methods == nil {
+		return true
+	}
+	_, ok := f.methods.match(method)
+	return ok
+}
+
+// UnaryServerInterceptor returns the unary server interceptor
+func (f *FieldEncryption) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if !f.applies(info.FullMethod) {
+			return handler(ctx, req)
+		}
+
+		if err := f.unprotect(ctx, req); err != nil {
+			return nil, err
+		}
+
+		resp, err := handler(ctx, req)
+		if err != nil {
+			return resp, err
+		}
+		return f.protect(ctx, resp)
+	}
+}
+
+// StreamServerInterceptor returns the stream server interceptor
+func (f *FieldEncryption) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if !f.applies(info.FullMethod) {
+			return handler(srv, ss)
+		}
+		return handler(srv, &fieldEncryptionStream{ServerStream: ss, fields: f})
+	}
+}
+
+// unprotect decrypts the protected fields of a request
+func (f *FieldEncryption) unprotect(ctx context.Context, req interface{}) error {
+	msg, ok := req.(proto.Message)
+	if !ok {
+		return nil
+	}
+	if err := f.protector.Unprotect(ctx, msg); err != nil {
+		return status.Errorf(codes.InvalidArgument,
+			"invalid protected field: %v\nHint: Send encrypted fields as returned by the server, or as plaintext", err)
+	}
+	return nil
+}
+
+// protect returns a copy of the response with its fields encrypted. The handler's message
+// is left untouched, as it may be shared.
+func (f *FieldEncryption) protect(ctx context.Context, resp interface{}) (interface{}, error) {
+	msg, ok := resp.(proto.Message)
+	if !ok {
+		return resp, nil
+	}
+
+	msg = proto.Clone(msg)
+	if err := f.protector.Protect(ctx, msg); err != nil {
+		// Never fall back to sending the plaintext
+		return nil, status.Errorf(codes.Internal, "failed to protect response fields: %v", err)
+	}
+	return msg, nil
+}
+
+// fieldEncryptionStream decrypts received and encrypts sent messages
+type fieldEncryptionStream struct {
+	grpc.ServerStream
+	fields *FieldEncryption
+}
+
+// RecvMsg receives a message and decrypts its protected fields
+func (s *fieldEncryptionStream) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	return s.fields.unprotect(s.Context(), m)
+}
+
+// SendMsg encrypts the protected fields of a message and sends it
+func (s *fieldEncryptionStream) SendMsg(m interface{}) error {
+	protected, err := s.fields.protect(s.Context(), m)
+	if err != nil {
+		return err
+	}
+	return s.ServerStream.SendMsg(protected)
+}
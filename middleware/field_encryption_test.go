@@ -0,0 +1,85 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"strings"
+	"testing"
+
+	"github.com/grpc-guardian/grpc-guardian/pkg/fieldcrypt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+func TestFieldEncryption(t *testing.T) {
+	key := make([]byte, 32)
+	rand.Read(key)
+	kms, err := fieldcrypt.NewLocalKMS("k1", key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokenizer := fieldcrypt.NewMemoryTokenizer()
+
+	fields := NewFieldEncryption(fieldcrypt.NewProtector(kms,
+		fieldcrypt.WithEncryptedFields("profile.ssn", "password"),
+		fieldcrypt.WithTokenizedFields(tokenizer, "name"),
+	))
+	interceptor := fields.UnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/users.Users/Get"}
+
+	user := redactTestMessage(t)
+	get := func(m protoreflect.Message, path ...string) string {
+		for _, name := range path[:len(path)-1] {
+			m = m.Get(m.Descriptor().Fields().ByName(protoreflect.Name(name))).Message()
+		}
+		return m.Get(m.Descriptor().Fields().ByName(protoreflect.Name(path[len(path)-1]))).String()
+	}
+
+	resp, err := interceptor(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return user, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	protected := resp.(proto.Message).ProtoReflect()
+
+	encryptedSSN := get(protected, "profile", "ssn")
+	if !strings.HasPrefix(encryptedSSN, fieldcrypt.EncryptedPrefix) {
+		t.Errorf("Expected profile.ssn to be encrypted, got %q", encryptedSSN)
+	}
+	if name := get(protected, "name"); !strings.HasPrefix(name, "tok_") {
+		t.Errorf("Expected name to be tokenized, got %q", name)
+	}
+	if city := get(protected, "profile", "city"); city != "Berlin" {
+		t.Errorf("Expected profile.city to be untouched, got %q", city)
+	}
+	if get(user.ProtoReflect(), "profile", "ssn") != "123-45-6789" {
+		t.Error("Expected the handler's message to be left untouched")
+	}
+
+	// Protected values sent back by the client reach the handler as plaintext
+	_, err = interceptor(context.Background(), resp, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		m := req.(proto.Message).ProtoReflect()
+		if get(m, "profile", "ssn") != "123-45-6789" || get(m, "password") != "hunter2" || get(m, "name") != "alice" {
+			t.Errorf("Expected decrypted request, got ssn=%q name=%q", get(m, "profile", "ssn"), get(m, "name"))
+		}
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A ciphertext is bound to its field
+	swapped := redactTestMessage(t).ProtoReflect()
+	swapped.Set(swapped.Descriptor().Fields().ByName("password"), protoreflect.ValueOfString(encryptedSSN))
+	_, err = interceptor(context.Background(), swapped.Interface(), info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		t.Error("Handler should not be called with a moved ciphertext")
+		return nil, nil
+	})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument, got %v", err)
+	}
+}
@@ -0,0 +1,200 @@
+package middleware
+
+import (
+	"context"
+	"strings"
+
+	"github.com/grpc-guardian/grpc-guardian/pkg/fieldmask"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// FieldMaskHeader is the default metadata key carrying a comma-separated field mask
+const FieldMaskHeader = "x-field-mask"
+
+// fieldMaskConfig configures the field mask middlewares
+type fieldMaskConfig struct {
+	header string
+	fields map[protoreflect.Name]bool
+}
+
+// FieldMaskOption configures FieldMask
+type FieldMaskOption func(*fieldMaskConfig)
+
+// WithFieldMaskHeader sets the metadata key the mask is read from
+// Default: FieldMaskHeader
+func WithFieldMaskHeader(name string) FieldMaskOption {
+	return func(c *fieldMaskConfig) {
+		if name != "" {
+			c.header = strings.ToLower(name)
+		}
+	}
+}
+
+// WithFieldMaskRequestFields sets the names of the top-level google.protobuf.FieldMask
+// request fields the mask is read from
+// Default: "read_mask", "field_mask"
+func WithFieldMaskRequestFields(names ...string) FieldMaskOption {
+	return func(c *fieldMaskConfig) {
+		c.fields = make(map[protoreflect.Name]bool, len(names))
+		for _, name := range names {
+			c.fields[protoreflect.Name(name)] = true
+		}
+	}
+}
+
+// FieldMask creates middleware that prunes responses to the fields the caller asked for,
+// with google.protobuf.FieldMask semantics. The mask comes from a FieldMask field of the
+// request (e.g. read_mask) or, failing that, from the x-field-mask header
+// ("name,address.city"). Without a mask the response is returned unchanged. Invalid paths
+// fail with InvalidArgument.
+//
+// The response is copied before it is pruned, so handlers and caches can keep returning
+// shared messages.
+//
+// Example usage:
+//
+//	chain := guardian.NewChain(
+//	    middleware.Logging(),
+//	    middleware.FieldMask(), // Outside Cache, so cached responses stay complete
+//	    middleware.Cache(),
+//	)
+//
+//	// Client side
+//	ctx = metadata.AppendToOutgoingContext(ctx, "x-field-mask", "name,address.city")
+func FieldMask(opts ...FieldMaskOption) func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	config := newFieldMaskConfig(opts)
+
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		paths := config.paths(ctx, req)
+		if len(paths) == 0 {
+			return handler(ctx, req)
+		}
+
+		resp, err := handler(ctx, req)
+		if err != nil {
+			return resp, err
+		}
+		return pruneResponse(resp, paths)
+	}
+}
+
+// StreamFieldMask prunes every message sent on a stream. The mask is read from the header,
+// or from the first request message of client-to-server streams.
+func StreamFieldMask(opts ...FieldMaskOption) func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	config := newFieldMaskConfig(opts)
+
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &fieldMaskServerStream{
+			ServerStream: ss,
+			config:       config,
+			paths:        config.paths(ss.Context(), nil),
+		})
+	}
+}
+
+func newFieldMaskConfig(opts []FieldMaskOption) *fieldMaskConfig {
+	config := &fieldMaskConfig{header: FieldMaskHeader}
+	WithFieldMaskRequestFields("read_mask", "field_mask")(config)
+	for _, opt := range opts {
+		opt(config)
+	}
+	return config
+}
+
+// paths returns the mask of a request: its FieldMask field, else the header
+func (c *fieldMaskConfig) paths(ctx context.Context, req interface{}) []string {
+	if paths := c.requestPaths(req); len(paths) > 0 {
+		return paths
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	var paths []string
+	for _, value := range md.Get(c.header) {
+		paths = append(paths, fieldmask.Parse(value)...)
+	}
+	return paths
+}
+
+// requestPaths reads the paths of a top-level google.protobuf.FieldMask field
+func (c *fieldMaskConfig) requestPaths(req interface{}) []string {
+	msg, ok := req.(proto.Message)
+	if !ok {
+		return nil
+	}
+
+	m := msg.ProtoReflect()
+	fields := m.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if !c.fields[fd.Name()] || fd.Message() == nil || fd.Message().FullName() != "google.protobuf.FieldMask" || !m.Has(fd) {
+			continue
+		}
+
+		mask := m.Get(fd).Message()
+		list := mask.Get(mask.Descriptor().Fields().ByName("paths")).List()
+		paths := make([]string, 0, list.Len())
+		for j := 0; j < list.Len(); j++ {
+			paths = append(paths, list.Get(j).String())
+		}
+		return paths
+	}
+	return nil
+}
+
+// pruneResponse returns a copy of resp with only the masked fields
+func pruneResponse(resp interface{}, paths []string) (interface{}, error) {
+	msg, ok := resp.(proto.Message)
+	if !ok || msg == nil {
+		return resp, nil
+	}
+
+	mask, err := fieldmask.Compile(msg.ProtoReflect().Descriptor(), paths)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if mask.IsAll() {
+		return resp, nil
+	}
+
+	pruned := proto.Clone(msg)
+	mask.Prune(pruned)
+	return pruned, nil
+}
+
+// fieldMaskServerStream prunes sent messages
+type fieldMaskServerStream struct {
+	grpc.ServerStream
+	config *fieldMaskConfig
+	paths  []string
+}
+
+// RecvMsg reads the mask from the first request when the header has none
+func (s *fieldMaskServerStream) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	if s.paths == nil {
+		s.paths = s.config.requestPaths(m)
+		if s.paths == nil {
+			s.paths = []string{} // Only the first message counts
+		}
+	}
+	return nil
+}
+
+// SendMsg prunes the message before sending it
+func (s *fieldMaskServerStream) SendMsg(m interface{}) error {
+	if len(s.paths) == 0 {
+		return s.ServerStream.SendMsg(m)
+	}
+	pruned, err := pruneResponse(m, s.paths)
+	if err != nil {
+		return err
+	}
+	return s.ServerStream.SendMsg(pruned)
+}
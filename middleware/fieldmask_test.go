@@ -0,0 +1,191 @@
+package middleware
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+// fieldMaskResponse is a nested message to prune
+func fieldMaskResponse() *descriptorpb.FileDescriptorProto {
+	return &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("shop.proto"),
+		Package: proto.String("shop"),
+		MessageType: []*descriptorpb.DescriptorProto{
+			{Name: proto.String("Order"), Field: []*descriptorpb.FieldDescriptorProto{{Name: proto.String("id")}}},
+			{Name: proto.String("Item"), Field: []*descriptorpb.FieldDescriptorProto{{Name: proto.String("sku")}}},
+		},
+		Options: &descriptorpb.FileOptions{GoPackage: proto.String("shoppb"), JavaPackage: proto.String("com.shop")},
+	}
+}
+
+// readMaskRequest builds a request message with a read_mask field
+func readMaskRequest(t *testing.T, paths ...string) proto.Message {
+	t.Helper()
+
+	file, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:       proto.String("fieldmask_test.proto"),
+		Package:    proto.String("test"),
+		Dependency: []string{"google/protobuf/field_mask.proto"},
+		Syntax:     proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("GetRequest"),
+			Field: []*descriptorpb.FieldDescriptorProto{{
+				Name:     proto.String("read_mask"),
+				Number:   proto.Int32(1),
+				Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+				Type:     descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum(),
+				TypeName: proto.String(".google.protobuf.FieldMask"),
+				JsonName: proto.String("readMask"),
+			}},
+		}},
+	}, protoregistry.GlobalFiles)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	desc := file.Messages().ByName("GetRequest")
+	req := dynamicpb.NewMessage(desc)
+	req.Set(desc.Fields().ByName("read_mask"), protoreflect.ValueOfMessage((&fieldmaskpb.FieldMask{Paths: paths}).ProtoReflect()))
+	return req
+}
+
+func TestFieldMask(t *testing.T) {
+	interceptor := FieldMask()
+	info := &grpc.UnaryServerInfo{FullMethod: "/shop.Files/Get"}
+	original := fieldMaskResponse()
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) { return original, nil }
+	withMask := func(mask string) context.Context {
+		return metadata.NewIncomingContext(context.Background(), metadata.Pairs(FieldMaskHeader, mask))
+	}
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		req  interface{}
+		want *descriptorpb.FileDescriptorProto
+		code codes.Code
+	}{
+		{
+			name: "no mask",
+			ctx:  context.Background(),
+			want: fieldMaskResponse(),
+		},
+		{
+			name: "header mask",
+			ctx:  withMask("name, message_type.name"),
+			want: &descriptorpb.FileDescriptorProto{
+				Name:        proto.String("shop.proto"),
+				MessageType: []*descriptorpb.DescriptorProto{{Name: proto.String("Order")}, {Name: proto.String("Item")}},
+			},
+		},
+		{
+			name: "JSON names and whole sub-messages",
+			ctx:  withMask("options,messageType.name,options.go_package"),
+			want: &descriptorpb.FileDescriptorProto{
+				MessageType: []*descriptorpb.DescriptorProto{{Name: proto.String("Order")}, {Name: proto.String("Item")}},
+				Options:     &descriptorpb.FileOptions{GoPackage: proto.String("shoppb"), JavaPackage: proto.String("com.shop")},
+			},
+		},
+		{
+			name: "request read_mask wins over the header",
+			ctx:  withMask("package"),
+			req:  readMaskRequest(t, "options.java_package"),
+			want: &descriptorpb.FileDescriptorProto{
+				Options: &descriptorpb.FileOptions{JavaPackage: proto.String("com.shop")},
+			},
+		},
+		{
+			name: "wildcard",
+			ctx:  withMask("*"),
+			want: fieldMaskResponse(),
+		},
+		{
+			name: "unknown field",
+			ctx:  withMask("name,price"),
+			code: codes.InvalidArgument,
+		},
+		{
+			name: "path through a scalar",
+			ctx:  withMask("name.length"),
+			code: codes.InvalidArgument,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := interceptor(tt.ctx, tt.req, info, handler)
+			if status.Code(err) != tt.code {
+				t.Fatalf("Expected %v, got %v", tt.code, err)
+			}
+			if tt.code != codes.OK {
+				return
+			}
+			if !proto.Equal(resp.(proto.Message), tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, resp)
+			}
+			if !proto.Equal(original, fieldMaskResponse()) {
+				t.Error("Expected the handler's response not to be modified")
+			}
+		})
+	}
+}
+
+// fieldMaskTestStream records sent messages
+type fieldMaskTestStream struct {
+	grpc.ServerStream
+	ctx  context.Context
+	recv []proto.Message
+	sent []interface{}
+}
+
+func (s *fieldMaskTestStream) Context() context.Context { return s.ctx }
+
+func (s *fieldMaskTestStream) RecvMsg(m interface{}) error {
+	proto.Merge(m.(proto.Message), s.recv[0])
+	s.recv = s.recv[1:]
+	return nil
+}
+
+func (s *fieldMaskTestStream) SendMsg(m interface{}) error {
+	s.sent = append(s.sent, m)
+	return nil
+}
+
+func TestStreamFieldMask(t *testing.T) {
+	req := readMaskRequest(t, "name")
+	stream := &fieldMaskTestStream{ctx: context.Background(), recv: []proto.Message{req, req}}
+	info := &grpc.StreamServerInfo{FullMethod: "/shop.Files/Watch"}
+
+	err := StreamFieldMask()(nil, stream, info, func(srv interface{}, ss grpc.ServerStream) error {
+		in := dynamicpb.NewMessage(req.ProtoReflect().Descriptor())
+		if err := ss.RecvMsg(in); err != nil {
+			return err
+		}
+		for i := 0; i < 2; i++ {
+			if err := ss.SendMsg(fieldMaskResponse()); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := &descriptorpb.FileDescriptorProto{Name: proto.String("shop.proto")}
+	if len(stream.sent) != 2 || !proto.Equal(stream.sent[0].(proto.Message), want) || !proto.Equal(stream.sent[1].(proto.Message), want) {
+		t.Errorf("Expected pruned messages, got %v", stream.sent)
+	}
+}
@@ -0,0 +1,239 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"strings"
+
+	"github.com/grpc-guardian/grpc-guardian/pkg/condition"
+	"github.com/grpc-guardian/grpc-guardian/pkg/geoip"
+	"github.com/grpc-guardian/grpc-guardian/pkg/metrics"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// GeoIP annotates requests with the country and autonomous system of the caller. The
+// location is stored in the context (see GetGeoLocation), added to the active span and to
+// log entries, exposed as the geo variable of condition expressions, and can be used to
+// block countries or networks.
+type GeoIP struct {
+	provider         geoip.Provider
+	address          func(context.Context) (netip.Addr, bool)
+	allowedCountries map[string]bool
+	deniedCountries  map[string]bool
+	deniedASNs       map[uint32]bool
+	collector        *metrics.GeoCollector
+}
+
+// GeoIPOption configures a GeoIP middleware
+type GeoIPOption func(*GeoIP)
+
+// WithGeoIPAddress sets how the caller address is resolved, e.g. IPFilter.ClientAddr to
+// honor x-forwarded-for from trusted proxies
+// Default: the connection's peer address
+func WithGeoIPAddress(address func(context.Context) (netip.Addr, bool)) GeoIPOption {
+	return func(g *GeoIP) {
+		if address != nil {
+			g.address = address
+		}
+	}
+}
+
+// WithAllowedCountries only serves callers from the given countries (ISO 3166-1 alpha-2
+// codes). Callers whose country is unknown are blocked too.
+func WithAllowedCountries(countries ...string) GeoIPOption {
+	return func(g *GeoIP) {
+		for _, country := range countries {
+			g.allowedCountries[strings.ToUpper(country)] = true
+		}
+	}
+}
+
+// WithDeniedCountries blocks callers from the given countries
+func WithDeniedCountries(countries ...string) GeoIPOption {
+	return func(g *GeoIP) {
+		for _, country := range countries {
+			g.deniedCountries[strings.ToUpper(country)] = true
+		}
+	}
+}
+
+// WithDeniedASNs blocks callers from the given autonomous systems, e.g. hosting providers
+// or networks running Tor exit nodes
+func WithDeniedASNs(asns ...uint32) GeoIPOption {
+	return func(g *GeoIP) {
+		for _, asn := range asns {
+			g.deniedASNs[asn] = true
+		}
+	}
+}
+
+// WithGeoIPMetrics records requests by country and blocked requests
+func WithGeoIPMetrics(collector *metrics.GeoCollector) GeoIPOption {
+	return func(g *GeoIP) {
+		g.collector = collector
+	}
+}
+
+// NewGeoIP creates a GeoIP middleware. Place it after Tracing, so the location is added
+// to the server span, and before Logging and rate limiters that use it.
+//
+// Example usage:
+//
+//	countryDB, _ := maxminddb.Open("GeoLite2-Country.mmdb")
+//	asnDB, _ := maxminddb.Open("GeoLite2-ASN.mmdb")
+//	geo := middleware.NewGeoIP(geoip.NewMaxMind(countryDB, asnDB),
+//	    middleware.WithDeniedASNs(torExitASNs...),
+//	)
+//
+//	chain := guardian.NewChain(
+//	    middleware.Tracing(),
+//	    geo.UnaryServerInterceptor(),
+//	    middleware.Logging(),
+//	    middleware.RateLimitPerClient(100, 200, middleware.GeoCountryKey), // Per country
+//	)
+func NewGeoIP(provider geoip.Provider, opts ...GeoIPOption) *GeoIP {
+	g := &GeoIP{
+		provider:         provider,
+		address:          peerAddr,
+		allowedCountries: make(map[string]bool),
+		deniedCountries:  make(map[string]bool),
+		deniedASNs:       make(map[uint32]bool),
+	}
+
+	for _, opt := range opts {
+		opt(g)
+	}
+
+	return g
+}
+
+// UnaryServerInterceptor returns a unary server interceptor that annotates requests and
+// blocks denied locations with PermissionDenied
+func (g *GeoIP) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := g.annotate(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor returns a stream server interceptor that annotates streams and
+// blocks denied locations
+func (g *GeoIP) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := g.annotate(ss.Context())
+		if err != nil {
+			return err
+		}
+		return handler(srv, &geoServerStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// annotate looks up the caller and applies the country and ASN rules
+func (g *GeoIP) annotate(ctx context.Context) (context.Context, error) {
+	var location geoip.Location
+	if addr, ok := g.address(ctx); ok {
+		// Unknown addresses (private ranges, missing database entries) stay unannotated
+		location, _ = g.provider.Lookup(addr)
+	}
+
+	if !location.IsZero() {
+		ctx = context.WithValue(ctx, contextKeyGeoLocation, location)
+
+		attrs := make([]attribute.KeyValue, 0, 3)
+		if location.Country != "" {
+			attrs = append(attrs, attribute.String("client.geo.country_iso_code", location.Country))
+		}
+		if location.ASN != 0 {
+			attrs = append(attrs, attribute.Int64("client.as.number", int64(location.ASN)))
+		}
+		if location.ASOrg != "" {
+			attrs = append(attrs, attribute.String("client.as.organization.name", location.ASOrg))
+		}
+		trace.SpanFromContext(ctx).SetAttributes(attrs...)
+	}
+
+	if g.collector != nil {
+		g.collector.RecordRequest(location.Country)
+	}
+
+	switch {
+	case location.ASN != 0 && g.deniedASNs[location.ASN]:
+		return ctx, g.block(metrics.GeoBlockedASN, "requests from AS%d are not allowed", location.ASN)
+	case location.Country != "" && g.deniedCountries[location.Country]:
+		return ctx, g.block(metrics.GeoBlockedCountry, "requests from %s are not allowed", location.Country)
+	case len(g.allowedCountries) > 0 && !g.allowedCountries[location.Country]:
+		country := location.Country
+		if country == "" {
+			country = "an unknown country"
+		}
+		return ctx, g.block(metrics.GeoBlockedCountry, "requests from %s are not allowed", country)
+	}
+	return ctx, nil
+}
+
+// block records a blocked request and returns its error
+func (g *GeoIP) block(reason, format string, args ...interface{}) error {
+	if g.collector != nil {
+		g.collector.RecordBlocked(reason)
+	}
+	return status.Error(codes.PermissionDenied, fmt.Sprintf(format, args...))
+}
+
+// GetGeoLocation retrieves the caller location stored by the GeoIP middleware
+func GetGeoLocation(ctx context.Context) (geoip.Location, bool) {
+	location, ok := ctx.Value(contextKeyGeoLocation).(geoip.Location)
+	return location, ok
+}
+
+// GeoCountryKey returns "country:<code>" for the caller, or "country:unknown", to rate
+// limit per country with RateLimitPerClient
+func GeoCountryKey(ctx context.Context) string {
+	if location, ok := GetGeoLocation(ctx); ok && location.Country != "" {
+		return "country:" + location.Country
+	}
+	return "country:unknown"
+}
+
+// GeoASNKey returns "asn:<number>" for the caller, or "asn:unknown", to rate limit per
+// network with RateLimitPerClient
+func GeoASNKey(ctx context.Context) string {
+	if location, ok := GetGeoLocation(ctx); ok && location.ASN != 0 {
+		return fmt.Sprintf("asn:%d", location.ASN)
+	}
+	return "asn:unknown"
+}
+
+// conditionGeo exposes the stored location as the geo variable of condition expressions
+func conditionGeo(ctx context.Context) (condition.Geo, bool) {
+	location, ok := GetGeoLocation(ctx)
+	return condition.Geo{Country: location.Country, ASN: location.ASN, ASOrg: location.ASOrg}, ok
+}
+
+// peerAddr returns the address of the connection's peer
+func peerAddr(ctx context.Context) (netip.Addr, bool) {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return netip.Addr{}, false
+	}
+	return parseAddr(p.Addr.String())
+}
+
+// geoServerStream wraps grpc.ServerStream with the annotated context
+type geoServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context returns the context with the location
+func (s *geoServerStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,140 @@
+package middleware
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/grpc-guardian/grpc-guardian/pkg/condition"
+	"github.com/grpc-guardian/grpc-guardian/pkg/geoip"
+	"github.com/grpc-guardian/grpc-guardian/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestGeoIP(t *testing.T) {
+	provider, err := geoip.NewStaticProvider(map[string]geoip.Location{
+		"203.0.113.0/24":   {Country: "US", ASN: 64500, ASOrg: "Example Hosting"},
+		"203.0.113.128/25": {Country: "CA", ASN: 64501},
+		"198.51.100.0/24":  {Country: "RU", ASN: 64502},
+		"192.0.2.0/24":     {Country: "DE", ASN: 64666},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	registry := prometheus.NewRegistry()
+	collector, err := metrics.NewGeoCollector(registry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	geo := NewGeoIP(provider,
+		WithDeniedCountries("ru"),
+		WithDeniedASNs(64666),
+		WithGeoIPMetrics(collector),
+	)
+	interceptor := geo.UnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/test.Service/Method"}
+
+	var got geoip.Location
+	var key string
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		got, _ = GetGeoLocation(ctx)
+		key = GeoCountryKey(ctx)
+		return "ok", nil
+	}
+
+	tests := []struct {
+		name     string
+		addr     string
+		want     codes.Code
+		location geoip.Location
+		key      string
+	}{
+		{"annotated", "203.0.113.7:5000", codes.OK, geoip.Location{Country: "US", ASN: 64500, ASOrg: "Example Hosting"}, "country:US"},
+		{"most specific range", "203.0.113.200:5000", codes.OK, geoip.Location{Country: "CA", ASN: 64501}, "country:CA"},
+		{"unknown address", "10.0.0.1:5000", codes.OK, geoip.Location{}, "country:unknown"},
+		{"denied country", "198.51.100.1:5000", codes.PermissionDenied, geoip.Location{}, ""},
+		{"denied ASN", "192.0.2.1:5000", codes.PermissionDenied, geoip.Location{}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, key = geoip.Location{}, ""
+			if _, err := interceptor(peerContext(tt.addr), nil, info, handler); status.Code(err) != tt.want {
+				t.Fatalf("Expected %v, got %v", tt.want, err)
+			}
+			if got != tt.location || key != tt.key {
+				t.Errorf("Expected %+v (%q), got %+v (%q)", tt.location, tt.key, got, key)
+			}
+		})
+	}
+
+	expected := `
+# HELP grpc_geo_blocked_total Total number of requests blocked by country or ASN rules
+# TYPE grpc_geo_blocked_total counter
+grpc_geo_blocked_total{reason="asn"} 1
+grpc_geo_blocked_total{reason="country"} 1
+# HELP grpc_geo_requests_total Total number of requests by caller country
+# TYPE grpc_geo_requests_total counter
+grpc_geo_requests_total{country="CA"} 1
+grpc_geo_requests_total{country="DE"} 1
+grpc_geo_requests_total{country="RU"} 1
+grpc_geo_requests_total{country="US"} 1
+grpc_geo_requests_total{country="unknown"} 1
+`
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(expected)); err != nil {
+		t.Errorf("Unexpected geo metrics: %v", err)
+	}
+}
+
+func TestGeoIP_AllowedCountries(t *testing.T) {
+	provider, err := geoip.NewStaticProvider(map[string]geoip.Location{
+		"203.0.113.0/24": {Country: "US", ASN: 64500},
+		"198.51.100.7":   {ASN: 64502},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	interceptor := NewGeoIP(provider, WithAllowedCountries("US", "CA")).UnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/test.Service/Method"}
+	ok := func(ctx context.Context, req interface{}) (interface{}, error) { return "ok", nil }
+
+	for addr, want := range map[string]codes.Code{
+		"203.0.113.7:5000":  codes.OK,
+		"198.51.100.7:5000": codes.PermissionDenied, // Country unknown
+		"10.0.0.1:5000":     codes.PermissionDenied,
+	} {
+		if _, err := interceptor(peerContext(addr), nil, info, ok); status.Code(err) != want {
+			t.Errorf("%s: expected %v, got %v", addr, want, err)
+		}
+	}
+}
+
+func TestGeoIP_Condition(t *testing.T) {
+	provider, err := geoip.NewStaticProvider(map[string]geoip.Location{
+		"203.0.113.0/24": {Country: "US", ASN: 64500},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expr := condition.MustCompile(`geo.known && geo.country in ["US", "CA"] && geo.asn == 64500`)
+
+	var matched bool
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		matched, err = expr.Eval(conditionEvaluator.Vars(ctx, "/test.Service/Method", req, false))
+		return "ok", err
+	}
+	interceptor := NewGeoIP(provider).UnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/test.Service/Method"}
+
+	if _, err := interceptor(peerContext("203.0.113.7:5000"), nil, info, handler); err != nil || !matched {
+		t.Errorf("Expected the geo condition to match, got %v, %v", matched, err)
+	}
+	if _, err := interceptor(peerContext("10.0.0.1:5000"), nil, info, handler); err != nil || matched {
+		t.Errorf("Expected the geo condition not to match unknown callers, got %v, %v", matched, err)
+	}
+}
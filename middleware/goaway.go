@@ -0,0 +1,166 @@
+package middleware
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// connectionDrainMessages are fragments of the Unavailable errors grpc-go reports when a
+// server sends GOAWAY (graceful stop, MaxConnectionAge) or closes the transport under an RPC
+var connectionDrainMessages = []string{
+	"the connection is draining",
+	"received prior goaway",
+	"goaway",
+	"transport is closing",
+	"error reading from server: eof",
+	"connection reset by peer",
+}
+
+// IsConnectionDrain reports whether err was caused by the server draining or closing the
+// connection rather than by the request itself
+func IsConnectionDrain(err error) bool {
+	st, ok := status.FromError(err)
+	if !ok || st.Code() != codes.Unavailable {
+		return false
+	}
+
+	msg := strings.ToLower(st.Message())
+	for _, fragment := range connectionDrainMessages {
+		if strings.Contains(msg, fragment) {
+			return true
+		}
+	}
+	return false
+}
+
+// Redispatcher transparently re-sends idempotent requests that failed because the upstream
+// connection was drained (GOAWAY during rolling restarts, connection-age limits). The new
+// attempt waits for a ready connection instead of failing fast on the closing one.
+type Redispatcher struct {
+	maxRedispatches int
+	idempotent      map[string]bool
+	matcher         *methodMatcher[bool]
+	onRedispatch    func(method string, attempt int, err error)
+}
+
+// RedispatchOption configures a Redispatcher
+type RedispatchOption func(*Redispatcher)
+
+// WithIdempotentMethods marks method patterns as safe to re-send.
+// Patterns follow the Authorization rules: "/pkg.Service/Method", "/pkg.Service/*" or "*".
+func WithIdempotentMethods(patterns ...string) RedispatchOption {
+	return func(r *Redispatcher) {
+		for _, pattern := range patterns {
+			r.idempotent[pattern] = true
+		}
+	}
+}
+
+// WithMaxRedispatches sets how many times a single request may be re-sent after a drain
+// Default: 2
+func WithMaxRedispatches(n int) RedispatchOption {
+	return func(r *Redispatcher) {
+		if n > 0 {
+			r.maxRedispatches = n
+		}
+	}
+}
+
+// WithOnRedispatch sets a callback invoked before each re-dispatch
+func WithOnRedispatch(callback func(method string, attempt int, err error)) RedispatchOption {
+	return func(r *Redispatcher) {
+		r.onRedispatch = callback
+	}
+}
+
+// NewRedispatcher creates a GOAWAY-aware re-dispatcher.
+// Only methods registered with WithIdempotentMethods are re-sent.
+//
+// Example usage:
+//
+//	redispatcher := middleware.NewRedispatcher(
+//	    middleware.WithIdempotentMethods("/catalog.Products/*", "/users.Profile/Get"),
+//	)
+//	conn, err := grpc.Dial(addr,
+//	    grpc.WithUnaryInterceptor(redispatcher.UnaryClientInterceptor()),
+//	)
+func NewRedispatcher(opts ...RedispatchOption) *Redispatcher {
+	r := &Redispatcher{
+		maxRedispatches: 2,
+		idempotent:      make(map[string]bool),
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	r.matcher = newMethodMatcher(r.idempotent)
+
+	return r
+}
+
+// isIdempotent reports whether a method may be re-sent
+func (r *Redispatcher) isIdempotent(method string) bool {
+	allowed, ok := r.matcher.match(method)
+	return ok && allowed
+}
+
+// waitForReady returns a copy of opts that waits for a ready connection instead of failing fast
+func waitForReady(opts []grpc.CallOption) []grpc.CallOption {
+	redispatchOpts := make([]grpc.CallOption, 0, len(opts)+1)
+	redispatchOpts = append(redispatchOpts, opts...)
+	return append(redispatchOpts, grpc.WaitForReady(true))
+}
+
+// invoke calls the invoker, re-dispatching drained idempotent requests
+func (r *Redispatcher) invoke(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	err := invoker(ctx, method, req, reply, cc, opts...)
+
+	for attempt := 1; attempt <= r.maxRedispatches; attempt++ {
+		if err == nil || ctx.Err() != nil || !IsConnectionDrain(err) || !r.isIdempotent(method) {
+			return err
+		}
+
+		if r.onRedispatch != nil {
+			r.onRedispatch(method, attempt, err)
+		}
+
+		err = invoker(ctx, method, req, reply, cc, waitForReady(opts)...)
+	}
+
+	return err
+}
+
+// UnaryClientInterceptor returns a unary client interceptor that re-dispatches drained requests
+func (r *Redispatcher) UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return r.invoke(ctx, method, req, reply, cc, invoker, opts...)
+	}
+}
+
+// StreamClientInterceptor returns a stream client interceptor that re-establishes streams
+// whose creation failed because the connection was drained. Streams that already exchanged
+// messages are never re-sent.
+func (r *Redispatcher) StreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		stream, err := streamer(ctx, desc, cc, method, opts...)
+
+		for attempt := 1; attempt <= r.maxRedispatches; attempt++ {
+			if err == nil || ctx.Err() != nil || !IsConnectionDrain(err) || !r.isIdempotent(method) {
+				return stream, err
+			}
+
+			if r.onRedispatch != nil {
+				r.onRedispatch(method, attempt, err)
+			}
+
+			stream, err = streamer(ctx, desc, cc, method, waitForReady(opts)...)
+		}
+
+		return stream, err
+	}
+}
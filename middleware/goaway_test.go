@@ -0,0 +1,108 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestIsConnectionDrain(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{status.Error(codes.Unavailable, "the connection is draining"), true},
+		{status.Error(codes.Unavailable, "closing transport due to: connection error: desc = \"error reading from server: EOF\", received prior goaway: code: NO_ERROR"), true},
+		{status.Error(codes.Unavailable, "transport is closing"), true},
+		{status.Error(codes.Unavailable, "service overloaded"), false},
+		{status.Error(codes.Internal, "transport is closing"), false},
+		{errors.New("the connection is draining"), false},
+		{nil, false},
+	}
+
+	for _, tt := range tests {
+		if got := IsConnectionDrain(tt.err); got != tt.want {
+			t.Errorf("IsConnectionDrain(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+// drainingInvoker fails the first n calls with a GOAWAY drain error
+func drainingInvoker(n int, calls *int, waitForReady *bool) grpc.UnaryInvoker {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		*calls++
+		*waitForReady = len(opts) > 0
+		if *calls <= n {
+			return status.Error(codes.Unavailable, "the connection is draining")
+		}
+		return nil
+	}
+}
+
+func TestRedispatcher(t *testing.T) {
+	var redispatched []string
+	redispatcher := NewRedispatcher(
+		WithIdempotentMethods("/catalog.Products/*"),
+		WithOnRedispatch(func(method string, attempt int, err error) {
+			redispatched = append(redispatched, method)
+		}),
+	)
+	interceptor := redispatcher.UnaryClientInterceptor()
+
+	var (
+		calls        int
+		waitForReady bool
+	)
+	err := interceptor(context.Background(), "/catalog.Products/Get", nil, nil, nil, drainingInvoker(1, &calls, &waitForReady))
+	if err != nil || calls != 2 {
+		t.Errorf("Expected idempotent request to be re-dispatched once, got err=%v calls=%d", err, calls)
+	}
+	if !waitForReady {
+		t.Error("Expected re-dispatch to wait for a ready connection")
+	}
+
+	calls = 0
+	err = interceptor(context.Background(), "/orders.Orders/Create", nil, nil, nil, drainingInvoker(1, &calls, &waitForReady))
+	if status.Code(err) != codes.Unavailable || calls != 1 {
+		t.Errorf("Expected non-idempotent request not to be re-dispatched, got err=%v calls=%d", err, calls)
+	}
+
+	calls = 0
+	err = interceptor(context.Background(), "/catalog.Products/List", nil, nil, nil, drainingInvoker(5, &calls, &waitForReady))
+	if status.Code(err) != codes.Unavailable || calls != 3 {
+		t.Errorf("Expected re-dispatch to stop after 2 attempts, got err=%v calls=%d", err, calls)
+	}
+
+	if len(redispatched) != 3 {
+		t.Errorf("Expected 3 re-dispatch callbacks, got %d", len(redispatched))
+	}
+}
+
+func TestRetry_WithRedispatcher(t *testing.T) {
+	var backoffs int
+	retry := NewRetry(
+		WithMaxAttempts(2),
+		WithInitialBackoff(time.Millisecond),
+		WithRedispatcher(NewRedispatcher(WithIdempotentMethods("*"))),
+		WithOnRetry(func(attempt int, err error, nextBackoff time.Duration) {
+			backoffs++
+		}),
+	)
+
+	var (
+		calls        int
+		waitForReady bool
+	)
+	err := retry.UnaryClientInterceptor()(context.Background(), "/catalog.Products/Get", nil, nil, nil, drainingInvoker(2, &calls, &waitForReady))
+	if err != nil {
+		t.Errorf("Expected success, got %v", err)
+	}
+	if calls != 3 || backoffs != 0 {
+		t.Errorf("Expected drains to be re-dispatched without retry backoff, got calls=%d backoffs=%d", calls, backoffs)
+	}
+}
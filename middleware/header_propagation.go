@@ -0,0 +1,208 @@
+package middleware
+
+import (
+	"context"
+	"net/url"
+	"strings"
+
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/propagation"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// HeaderPropagation carries business metadata (tenant, user, feature flags) from incoming
+// requests to the calls a service makes while handling them. The server interceptors
+// capture the allow-listed headers into the context; the client interceptors re-inject
+// them into outgoing metadata, so the headers survive every hop without handler code.
+type HeaderPropagation struct {
+	keys     map[string]bool
+	prefixes []string
+	baggage  bool
+}
+
+// HeaderPropagationOption configures HeaderPropagation
+type HeaderPropagationOption func(*HeaderPropagation)
+
+// WithPropagatedHeaders adds header names to the allow-list
+func WithPropagatedHeaders(keys ...string) HeaderPropagationOption {
+	return func(p *HeaderPropagation) {
+		for _, key := range keys {
+			p.keys[strings.ToLower(key)] = true
+		}
+	}
+}
+
+// WithPropagatedPrefix allows every header starting with a prefix, e.g. "x-tenant-"
+func WithPropagatedPrefix(prefix string) HeaderPropagationOption {
+	return func(p *HeaderPropagation) {
+		p.prefixes = append(p.prefixes, strings.ToLower(prefix))
+	}
+}
+
+// WithBaggage also carries the headers as OpenTelemetry baggage. Incoming W3C baggage
+// fills allow-listed headers that are missing from the metadata, and outgoing calls send
+// the captured headers, and any other baggage in the context, in the baggage header.
+func WithBaggage() HeaderPropagationOption {
+	return func(p *HeaderPropagation) {
+		p.baggage = true
+	}
+}
+
+// NewHeaderPropagation creates a header propagation client/server pair. Install the server
+// interceptors on the server and the client interceptors on the connections it uses to
+// call other services.
+//
+// Example usage:
+//
+//	propagation := middleware.NewHeaderPropagation(
+//	    middleware.WithPropagatedHeaders("tenant-id", "x-user-id"),
+//	    middleware.WithPropagatedPrefix("x-feature-"),
+//	    middleware.WithBaggage(),
+//	)
+//	server := grpc.NewServer(grpc.ChainUnaryInterceptor(propagation.UnaryServerInterceptor()))
+//	conn, _ := grpc.Dial("inventory:50051",
+//	    grpc.WithChainUnaryInterceptor(propagation.UnaryClientInterceptor()),
+//	)
+//
+//	// In a handler, calls made with ctx carry tenant-id
+//	stock, err := inventory.NewInventoryClient(conn).Get(ctx, req)
+func NewHeaderPropagation(opts ...HeaderPropagationOption) *HeaderPropagation {
+	p := &HeaderPropagation{keys: make(map[string]bool)}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// allowed reports whether a header is on the allow-list
+func (p *HeaderPropagation) allowed(key string) bool {
+	if p.keys[key] {
+		return true
+	}
+	for _, prefix := range p.prefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetPropagatedHeaders retrieves the headers captured for propagation from context
+func GetPropagatedHeaders(ctx context.Context) (metadata.MD, bool) {
+	md, ok := ctx.Value(contextKeyPropagatedHeaders).(metadata.MD)
+	return md, ok
+}
+
+// SetPropagatedHeader sets a header to propagate on every outgoing call made with the
+// returned context, whether or not it is on the allow-list
+func SetPropagatedHeader(ctx context.Context, key, value string) context.Context {
+	md, _ := GetPropagatedHeaders(ctx)
+	md = md.Copy()
+	md.Set(key, value)
+	return context.WithValue(ctx, contextKeyPropagatedHeaders, md)
+}
+
+// capture stores the allow-listed incoming headers in the context
+func (p *HeaderPropagation) capture(ctx context.Context) context.Context {
+	incoming, _ := metadata.FromIncomingContext(ctx)
+
+	captured, _ := GetPropagatedHeaders(ctx)
+	captured = captured.Copy()
+	for key, values := range incoming {
+		if p.allowed(key) {
+			captured[key] = append([]string(nil), values...)
+		}
+	}
+
+	if p.baggage {
+		ctx = propagation.Baggage{}.Extract(ctx, &metadataCarrier{md: incoming})
+		for _, member := range baggage.FromContext(ctx).Members() {
+			key := strings.ToLower(member.Key())
+			if _, ok := captured[key]; !ok && p.allowed(key) {
+				captured.Set(key, member.Value())
+			}
+		}
+	}
+
+	return context.WithValue(ctx, contextKeyPropagatedHeaders, captured)
+}
+
+// inject adds the captured headers to the outgoing metadata. Headers the caller set
+// explicitly are kept.
+func (p *HeaderPropagation) inject(ctx context.Context) context.Context {
+	captured, _ := GetPropagatedHeaders(ctx)
+	if len(captured) == 0 && !p.baggage {
+		return ctx
+	}
+
+	md, ok := metadata.FromOutgoingContext(ctx)
+	if ok {
+		md = md.Copy()
+	} else {
+		md = metadata.New(nil)
+	}
+	for key, values := range captured {
+		if len(md.Get(key)) == 0 {
+			md.Set(key, values...)
+		}
+	}
+
+	if p.baggage {
+		bag := baggage.FromContext(ctx)
+		for key, values := range captured {
+			if len(values) == 0 || strings.HasSuffix(key, "-bin") {
+				continue
+			}
+			member, err := baggage.NewMember(key, url.PathEscape(values[0]))
+			if err != nil {
+				continue
+			}
+			if updated, err := bag.SetMember(member); err == nil {
+				bag = updated
+			}
+		}
+		propagation.Baggage{}.Inject(baggage.ContextWithBaggage(ctx, bag), &metadataCarrier{md: md})
+	}
+
+	return metadata.NewOutgoingContext(ctx, md)
+}
+
+// UnaryServerInterceptor returns a unary server interceptor that captures headers
+func (p *HeaderPropagation) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		return handler(p.capture(ctx), req)
+	}
+}
+
+// StreamServerInterceptor returns a stream server interceptor that captures headers
+func (p *HeaderPropagation) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &propagationServerStream{ServerStream: ss, ctx: p.capture(ss.Context())})
+	}
+}
+
+// UnaryClientInterceptor returns a unary client interceptor that re-injects captured headers
+func (p *HeaderPropagation) UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return invoker(p.inject(ctx), method, req, reply, cc, opts...)
+	}
+}
+
+// StreamClientInterceptor returns a stream client interceptor that re-injects captured headers
+func (p *HeaderPropagation) StreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return streamer(p.inject(ctx), desc, cc, method, opts...)
+	}
+}
+
+// propagationServerStream wraps grpc.ServerStream with the capturing context
+type propagationServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context returns the context with the captured headers
+func (s *propagationServerStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel/baggage"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// propagate runs a request through the server interceptor and returns the outgoing
+// metadata of a call the handler makes through the client interceptor
+func propagate(t *testing.T, p *HeaderPropagation, incoming metadata.MD, call func(ctx context.Context) context.Context) metadata.MD {
+	t.Helper()
+
+	var outgoing metadata.MD
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		outgoing, _ = metadata.FromOutgoingContext(ctx)
+		return nil
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, p.UnaryClientInterceptor()(call(ctx), "/inventory.Inventory/Get", nil, nil, nil, invoker)
+	}
+
+	ctx := metadata.NewIncomingContext(context.Background(), incoming)
+	if _, err := p.UnaryServerInterceptor()(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/orders.Orders/Create"}, handler); err != nil {
+		t.Fatal(err)
+	}
+	return outgoing
+}
+
+func TestHeaderPropagation(t *testing.T) {
+	p := NewHeaderPropagation(
+		WithPropagatedHeaders("Tenant-ID"),
+		WithPropagatedPrefix("x-feature-"),
+	)
+
+	outgoing := propagate(t, p, metadata.Pairs(
+		"tenant-id", "acme",
+		"x-feature-checkout", "v2",
+		"authorization", "Bearer secret",
+	), func(ctx context.Context) context.Context {
+		if md, _ := GetPropagatedHeaders(ctx); len(md) != 2 {
+			t.Errorf("Expected 2 captured headers, got %v", md)
+		}
+		// Explicit outgoing headers win over captured ones
+		ctx = metadata.AppendToOutgoingContext(ctx, "x-feature-checkout", "v3")
+		return SetPropagatedHeader(ctx, "x-request-source", "orders")
+	})
+
+	if got := outgoing.Get("tenant-id"); len(got) != 1 || got[0] != "acme" {
+		t.Errorf("Expected tenant-id to be propagated, got %v", got)
+	}
+	if got := outgoing.Get("x-feature-checkout"); len(got) != 1 || got[0] != "v3" {
+		t.Errorf("Expected the explicit header to be kept, got %v", got)
+	}
+	if got := outgoing.Get("x-request-source"); len(got) != 1 {
+		t.Errorf("Expected the header set by the handler to be propagated, got %v", got)
+	}
+	if got := outgoing.Get("authorization"); len(got) != 0 {
+		t.Errorf("Expected headers off the allow-list to stay, got %v", got)
+	}
+}
+
+func TestHeaderPropagation_Baggage(t *testing.T) {
+	p := NewHeaderPropagation(WithPropagatedHeaders("tenant-id", "x-user-id"), WithBaggage())
+
+	outgoing := propagate(t, p, metadata.Pairs(
+		"baggage", "tenant-id=acme,region=eu-west",
+		"x-user-id", "user-42",
+	), func(ctx context.Context) context.Context { return ctx })
+
+	if got := outgoing.Get("tenant-id"); len(got) != 1 || got[0] != "acme" {
+		t.Errorf("Expected tenant-id from baggage, got %v", got)
+	}
+
+	values := outgoing.Get("baggage")
+	if len(values) != 1 {
+		t.Fatalf("Expected a baggage header, got %v", values)
+	}
+	bag, err := baggage.Parse(values[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bag.Member("region").Value() != "eu-west" || bag.Member("x-user-id").Value() != "user-42" {
+		t.Errorf("Unexpected outgoing baggage %q", values[0])
+	}
+}
@@ -0,0 +1,380 @@
+package middleware
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/grpc-guardian/grpc-guardian/pkg/metrics"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// IPFilter allows or blocks requests by caller address. The deny list wins over the allow
+// list; with a non-empty allow list, only addresses in it are served. Lists can be
+// replaced at runtime without restarting the server.
+type IPFilter struct {
+	trusted   []netip.Prefix
+	header    string
+	collector *metrics.IPFilterCollector
+
+	lists  atomic.Pointer[ipLists]
+	config ipFilterConfig
+
+	watchMu sync.Mutex
+	modTime time.Time
+}
+
+// ipLists is one generation of the allow and deny lists
+type ipLists struct {
+	allow []netip.Prefix
+	deny  []netip.Prefix
+}
+
+// ipFilterConfig holds the unparsed options
+type ipFilterConfig struct {
+	allow   []string
+	deny    []string
+	trusted []string
+}
+
+// IPFilterOption configures an IPFilter
+type IPFilterOption func(*IPFilter)
+
+// WithAllowedCIDRs only serves callers in the given ranges. Entries are CIDRs
+// ("10.0.0.0/8", "2001:db8::/32") or single addresses.
+func WithAllowedCIDRs(cidrs ...string) IPFilterOption {
+	return func(f *IPFilter) {
+		f.config.allow = append(f.config.allow, cidrs...)
+	}
+}
+
+// WithDeniedCIDRs blocks callers in the given ranges
+func WithDeniedCIDRs(cidrs ...string) IPFilterOption {
+	return func(f *IPFilter) {
+		f.config.deny = append(f.config.deny, cidrs...)
+	}
+}
+
+// WithTrustedProxies sets the proxies and load balancers whose forwarding header is
+// believed. The header of any other peer is ignored, since clients can set it to any
+// address.
+func WithTrustedProxies(cidrs ...string) IPFilterOption {
+	return func(f *IPFilter) {
+		f.config.trusted = append(f.config.trusted, cidrs...)
+	}
+}
+
+// WithForwardedHeader sets the metadata key carrying the forwarding chain
+// Default: "x-forwarded-for"
+func WithForwardedHeader(name string) IPFilterOption {
+	return func(f *IPFilter) {
+		if name != "" {
+			f.header = strings.ToLower(name)
+		}
+	}
+}
+
+// WithIPFilterMetrics records blocked requests and list sizes
+func WithIPFilterMetrics(collector *metrics.IPFilterCollector) IPFilterOption {
+	return func(f *IPFilter) {
+		f.collector = collector
+	}
+}
+
+// NewIPFilter creates an IP filter
+//
+// Example usage:
+//
+//	collector, _ := metrics.NewIPFilterCollector(registry)
+//	filter, err := middleware.NewIPFilter(
+//	    middleware.WithAllowedCIDRs("10.0.0.0/8", "192.168.0.0/16"),
+//	    middleware.WithDeniedCIDRs("10.13.0.0/16"),
+//	    middleware.WithTrustedProxies("10.0.0.1/32"), // The load balancer
+//	    middleware.WithIPFilterMetrics(collector),
+//	)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	chain := guardian.NewChain(filter.UnaryServerInterceptor())
+func NewIPFilter(opts ...IPFilterOption) (*IPFilter, error) {
+	f := &IPFilter{header: "x-forwarded-for"}
+
+	for _, opt := range opts {
+		opt(f)
+	}
+
+	trusted, err := parsePrefixes(f.config.trusted)
+	if err != nil {
+		return nil, fmt.Errorf("invalid trusted proxy: %w", err)
+	}
+	f.trusted = trusted
+
+	if err := f.SetLists(f.config.allow, f.config.deny); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// UnaryServerInterceptor returns a unary server interceptor that blocks filtered callers
+// with PermissionDenied
+func (f *IPFilter) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := f.check(ctx); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor returns a stream server interceptor that blocks filtered callers
+func (f *IPFilter) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := f.check(ss.Context()); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
+
+// SetLists atomically replaces the allow and deny lists. On error the current lists are
+// kept.
+func (f *IPFilter) SetLists(allow, deny []string) error {
+	allowed, err := parsePrefixes(allow)
+	if err != nil {
+		return fmt.Errorf("invalid allowed CIDR: %w", err)
+	}
+	denied, err := parsePrefixes(deny)
+	if err != nil {
+		return fmt.Errorf("invalid denied CIDR: %w", err)
+	}
+
+	f.lists.Store(&ipLists{allow: allowed, deny: denied})
+	if f.collector != nil {
+		f.collector.SetRules(len(allowed), len(denied))
+	}
+	return nil
+}
+
+// Lists returns the current allow and deny lists
+func (f *IPFilter) Lists() (allow, deny []string) {
+	lists := f.lists.Load()
+	return formatPrefixes(lists.allow), formatPrefixes(lists.deny)
+}
+
+// LoadFile replaces the lists with the contents of a file with one rule per line:
+//
+//	# office and VPN
+//	allow 203.0.113.0/24
+//	allow 2001:db8::/32
+//	deny  203.0.113.66
+func (f *IPFilter) LoadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read IP filter file: %w", err)
+	}
+
+	var allow, deny []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if i := strings.IndexByte(text, '#'); i >= 0 {
+			text = strings.TrimSpace(text[:i])
+		}
+		if text == "" {
+			continue
+		}
+
+		fields := strings.Fields(text)
+		if len(fields) != 2 {
+			return fmt.Errorf("%s:%d: expected \"allow <cidr>\" or \"deny <cidr>\"", path, line)
+		}
+		switch fields[0] {
+		case "allow":
+			allow = append(allow, fields[1])
+		case "deny":
+			deny = append(deny, fields[1])
+		default:
+			return fmt.Errorf("%s:%d: unknown action %q", path, line, fields[0])
+		}
+	}
+
+	return f.SetLists(allow, deny)
+}
+
+// WatchFile loads a list file and reloads it whenever its modification time changes,
+// until ctx is done. Failed reloads keep the current lists and are reported to onError,
+// which may be nil.
+func (f *IPFilter) WatchFile(ctx context.Context, path string, interval time.Duration, onError func(error)) error {
+	if interval <= 0 {
+		return fmt.Errorf("invalid IP filter watch interval %v: must be positive", interval)
+	}
+	if err := f.reloadIfChanged(path); err != nil {
+		return err
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := f.reloadIfChanged(path); err != nil && onError != nil {
+					onError(err)
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// reloadIfChanged loads the file if it changed since the last load
+func (f *IPFilter) reloadIfChanged(path string) error {
+	f.watchMu.Lock()
+	defer f.watchMu.Unlock()
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to read IP filter file: %w", err)
+	}
+	if info.ModTime().Equal(f.modTime) {
+		return nil
+	}
+	if err := f.LoadFile(path); err != nil {
+		return err
+	}
+	f.modTime = info.ModTime()
+	return nil
+}
+
+// ClientAddr returns the address of the caller: the peer address, or for trusted proxies
+// the last untrusted address of the forwarding chain
+func (f *IPFilter) ClientAddr(ctx context.Context) (netip.Addr, bool) {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return netip.Addr{}, false
+	}
+	addr, ok := parseAddr(p.Addr.String())
+	if !ok || !f.isTrusted(addr) {
+		return addr, ok
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	var hops []string
+	for _, value := range md.Get(f.header) {
+		hops = append(hops, strings.Split(value, ",")...)
+	}
+
+	// Walk back from the proxy: each trusted hop vouches for the one before it
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop, ok := parseAddr(strings.TrimSpace(hops[i]))
+		if !ok {
+			break
+		}
+		addr = hop
+		if !f.isTrusted(hop) {
+			break
+		}
+	}
+	return addr, true
+}
+
+// check blocks filtered callers
+func (f *IPFilter) check(ctx context.Context) error {
+	lists := f.lists.Load()
+	if len(lists.allow) == 0 && len(lists.deny) == 0 {
+		return nil
+	}
+
+	addr, ok := f.ClientAddr(ctx)
+	switch {
+	case !ok:
+		if len(lists.allow) == 0 {
+			return nil
+		}
+		return f.block(metrics.IPFilterUnknown, "caller address unknown")
+	case containsAddr(lists.deny, addr):
+		return f.block(metrics.IPFilterDenied, "address %s is denied", addr)
+	case len(lists.allow) > 0 && !containsAddr(lists.allow, addr):
+		return f.block(metrics.IPFilterNotAllowed, "address %s is not allowed", addr)
+	}
+	return nil
+}
+
+// block records a blocked request and returns its error
+func (f *IPFilter) block(reason, format string, args ...interface{}) error {
+	if f.collector != nil {
+		f.collector.RecordBlocked(reason)
+	}
+	return status.Errorf(codes.PermissionDenied, format, args...)
+}
+
+func (f *IPFilter) isTrusted(addr netip.Addr) bool {
+	return containsAddr(f.trusted, addr)
+}
+
+// parseAddr parses an address with or without a port
+func parseAddr(s string) (netip.Addr, bool) {
+	if host, _, err := net.SplitHostPort(s); err == nil {
+		s = host
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
+
+// parsePrefixes parses CIDRs and single addresses
+func parsePrefixes(cidrs []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		cidr = strings.TrimSpace(cidr)
+		if !strings.Contains(cidr, "/") {
+			addr, err := netip.ParseAddr(cidr)
+			if err != nil {
+				return nil, fmt.Errorf("%q: %w", cidr, err)
+			}
+			addr = addr.Unmap()
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("%q: %w", cidr, err)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+func formatPrefixes(prefixes []netip.Prefix) []string {
+	out := make([]string, len(prefixes))
+	for i, prefix := range prefixes {
+		out[i] = prefix.String()
+	}
+	return out
+}
+
+func containsAddr(prefixes []netip.Prefix, addr netip.Addr) bool {
+	for _, prefix := range prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,156 @@
+package middleware
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grpc-guardian/grpc-guardian/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func forwardedContext(peerAddr, forwardedFor string) context.Context {
+	ctx := peerContext(peerAddr)
+	if forwardedFor != "" {
+		ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("x-forwarded-for", forwardedFor))
+	}
+	return ctx
+}
+
+func TestIPFilter(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	collector, err := metrics.NewIPFilterCollector(registry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	filter, err := NewIPFilter(
+		WithAllowedCIDRs("10.0.0.0/8", "2001:db8::/32"),
+		WithDeniedCIDRs("10.13.0.0/16", "10.0.0.66"),
+		WithTrustedProxies("10.0.0.1", "10.0.0.2"),
+		WithIPFilterMetrics(collector),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	interceptor := filter.UnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/test.Service/Method"}
+	ok := func(ctx context.Context, req interface{}) (interface{}, error) { return "ok", nil }
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want codes.Code
+	}{
+		{"allowed peer", forwardedContext("10.1.2.3:5000", ""), codes.OK},
+		{"allowed IPv6 peer", forwardedContext("[2001:db8::1]:5000", ""), codes.OK},
+		{"peer outside allow list", forwardedContext("203.0.113.7:5000", ""), codes.PermissionDenied},
+		{"denied range", forwardedContext("10.13.0.9:5000", ""), codes.PermissionDenied},
+		{"denied address", forwardedContext("10.0.0.66:5000", ""), codes.PermissionDenied},
+		{"untrusted peer's header ignored", forwardedContext("203.0.113.7:5000", "10.1.2.3"), codes.PermissionDenied},
+		{"untrusted peer can't spoof", forwardedContext("10.1.2.3:5000", "10.13.0.9"), codes.OK},
+		{"client behind trusted proxy", forwardedContext("10.0.0.1:5000", "203.0.113.7"), codes.PermissionDenied},
+		{"client behind proxy chain", forwardedContext("10.0.0.1:5000", "10.13.0.9, 10.0.0.2"), codes.PermissionDenied},
+		{"spoofed hop before untrusted client", forwardedContext("10.0.0.1:5000", "10.13.0.9, 10.1.2.3"), codes.OK},
+		{"no peer", context.Background(), codes.PermissionDenied},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := interceptor(tt.ctx, nil, info, ok); status.Code(err) != tt.want {
+				t.Errorf("Expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+
+	expected := `
+# HELP grpc_ip_filter_blocked_total Total number of requests blocked by the IP filter by reason
+# TYPE grpc_ip_filter_blocked_total counter
+grpc_ip_filter_blocked_total{reason="denied"} 3
+grpc_ip_filter_blocked_total{reason="not_allowed"} 3
+grpc_ip_filter_blocked_total{reason="unknown_address"} 1
+`
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(expected), "grpc_ip_filter_blocked_total"); err != nil {
+		t.Errorf("Unexpected IP filter metrics: %v", err)
+	}
+}
+
+func TestIPFilter_Reload(t *testing.T) {
+	filter, err := NewIPFilter(WithDeniedCIDRs("203.0.113.0/24"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	interceptor := filter.UnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/test.Service/Method"}
+	ok := func(ctx context.Context, req interface{}) (interface{}, error) { return "ok", nil }
+	call := func(addr string) codes.Code {
+		_, err := interceptor(peerContext(addr), nil, info, ok)
+		return status.Code(err)
+	}
+
+	if call("203.0.113.7:5000") != codes.PermissionDenied || call("198.51.100.1:5000") != codes.OK {
+		t.Fatal("Expected the initial deny list to apply")
+	}
+
+	path := filepath.Join(t.TempDir(), "ipfilter.txt")
+	rules := "# partners\nallow 198.51.100.0/24\nallow 203.0.113.7 # office\n\ndeny 198.51.100.66\n"
+	if err := os.WriteFile(path, []byte(rules), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := filter.LoadFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	if call("203.0.113.7:5000") != codes.OK || call("198.51.100.66:5000") != codes.PermissionDenied || call("192.0.2.1:5000") != codes.PermissionDenied {
+		t.Error("Expected the reloaded lists to apply")
+	}
+	allow, deny := filter.Lists()
+	if !reflect.DeepEqual(allow, []string{"198.51.100.0/24", "203.0.113.7/32"}) || !reflect.DeepEqual(deny, []string{"198.51.100.66/32"}) {
+		t.Errorf("Unexpected lists: allow=%v deny=%v", allow, deny)
+	}
+
+	// Invalid lists keep the current ones
+	if err := filter.SetLists([]string{"not-a-cidr"}, nil); err == nil {
+		t.Error("Expected an invalid CIDR to be rejected")
+	}
+	if call("203.0.113.7:5000") != codes.OK {
+		t.Error("Expected the previous lists to stay in effect")
+	}
+}
+
+func TestIPFilter_WatchFileInterval(t *testing.T) {
+	filter, err := NewIPFilter()
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "ipfilter.txt")
+	if err := os.WriteFile(path, []byte("deny 203.0.113.0/24\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, interval := range []time.Duration{0, -time.Second} {
+		if err := filter.WatchFile(context.Background(), path, interval, nil); err == nil || !strings.Contains(err.Error(), "interval") {
+			t.Errorf("Expected interval %v to be rejected, got %v", interval, err)
+		}
+	}
+	if _, deny := filter.Lists(); len(deny) != 0 {
+		t.Errorf("Expected the file not to be loaded with an invalid interval, got %v", deny)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := filter.WatchFile(ctx, path, time.Hour, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, deny := filter.Lists(); len(deny) != 1 {
+		t.Errorf("Expected the file to be loaded, got %v", deny)
+	}
+}
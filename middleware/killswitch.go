@@ -0,0 +1,104 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/grpc-guardian/grpc-guardian/pkg/killswitch"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// killSwitchConfig holds the configuration of KillSwitch
+type killSwitchConfig struct {
+	exempt map[string]bool
+}
+
+// KillSwitchOption configures KillSwitch
+type KillSwitchOption func(*killSwitchConfig)
+
+// WithKillSwitchExempt keeps methods matching the patterns reachable even when a switch
+// covers them, e.g. admin APIs needed to recover
+// Default: "/grpc.health.v1.Health/*", "/grpc.reflection.*"
+func WithKillSwitchExempt(patterns ...string) KillSwitchOption {
+	return func(c *killSwitchConfig) {
+		for _, pattern := range patterns {
+			c.exempt[pattern] = true
+		}
+	}
+}
+
+// KillSwitch creates middleware that rejects calls to methods disabled by a switch of the
+// watcher with Unavailable, the switch's message and a google.rpc.RetryInfo detail when
+// the switch has a retry hint. Flip switches in the watcher's provider to disable a method,
+// a service or, with target "*", the whole server, without a deploy.
+//
+// Example usage:
+//
+//	watcher := killswitch.NewWatcher(killswitch.NewRedisProvider(redisClient, "guardian:killswitch"),
+//	    killswitch.WithAuditRecorder(recorder),
+//	)
+//	go watcher.Run(ctx)
+//
+//	chain := guardian.NewChain(
+//	    middleware.KillSwitch(watcher),
+//	)
+func KillSwitch(watcher *killswitch.Watcher, opts ...KillSwitchOption) func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	exempt := newKillSwitchExempt(opts)
+
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := killSwitchCheck(watcher, exempt, info.FullMethod); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamKillSwitch rejects streams to disabled methods
+func StreamKillSwitch(watcher *killswitch.Watcher, opts ...KillSwitchOption) func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	exempt := newKillSwitchExempt(opts)
+
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := killSwitchCheck(watcher, exempt, info.FullMethod); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
+
+func newKillSwitchExempt(opts []KillSwitchOption) *methodMatcher[bool] {
+	config := &killSwitchConfig{exempt: make(map[string]bool)}
+	WithKillSwitchExempt("/grpc.health.v1.Health/*", "/grpc.reflection.*")(config)
+	for _, opt := range opts {
+		opt(config)
+	}
+	return newMethodMatcher(config.exempt)
+}
+
+// killSwitchCheck returns the error of a call to a disabled method
+func killSwitchCheck(watcher *killswitch.Watcher, exempt *methodMatcher[bool], method string) error {
+	sw, disabled := watcher.Match(method)
+	if !disabled {
+		return nil
+	}
+	if _, ok := exempt.match(method); ok {
+		return nil
+	}
+
+	message := sw.Message
+	if message == "" {
+		message = fmt.Sprintf("%s is temporarily disabled", method)
+	}
+	st := status.New(codes.Unavailable, message)
+	if sw.RetryAfter <= 0 {
+		return st.Err()
+	}
+	detailed, err := st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(sw.RetryAfter)})
+	if err != nil {
+		return st.Err()
+	}
+	return detailed.Err()
+}
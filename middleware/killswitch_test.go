@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/grpc-guardian/grpc-guardian/pkg/killswitch"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestKillSwitch(t *testing.T) {
+	provider := killswitch.NewMemoryProvider()
+	watcher := killswitch.NewWatcher(provider)
+	interceptor := KillSwitch(watcher, WithKillSwitchExempt("/admin.Admin/*"))
+
+	call := func(method string) error {
+		_, err := interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: method},
+			func(ctx context.Context, req interface{}) (interface{}, error) { return "ok", nil })
+		return err
+	}
+
+	if err := call("/orders.Orders/Get"); err != nil {
+		t.Fatalf("Expected calls to pass without switches, got %v", err)
+	}
+
+	provider.Set(killswitch.Switch{Target: "*", Message: "down for maintenance", RetryAfter: 5 * time.Minute})
+	if err := watcher.Refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	err := call("/orders.Orders/Get")
+	if status.Code(err) != codes.Unavailable || status.Convert(err).Message() != "down for maintenance" {
+		t.Errorf("Expected Unavailable with the switch message, got %v", err)
+	}
+	if delay, ok := RetryDelay(err); !ok || delay != 5*time.Minute {
+		t.Errorf("Expected a 5m retry hint, got %v", delay)
+	}
+
+	for _, method := range []string{"/grpc.health.v1.Health/Check", "/admin.Admin/ClearSwitch"} {
+		if err := call(method); err != nil {
+			t.Errorf("Expected %s to stay reachable, got %v", method, err)
+		}
+	}
+}
+
+func TestStreamKillSwitch(t *testing.T) {
+	provider := killswitch.NewMemoryProvider()
+	provider.Set(killswitch.Switch{Target: "/orders.Orders/Watch"})
+	watcher := killswitch.NewWatcher(provider)
+	if err := watcher.Refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	err := StreamKillSwitch(watcher)(nil, &trailerServerStream{ctx: context.Background()},
+		&grpc.StreamServerInfo{FullMethod: "/orders.Orders/Watch"},
+		func(srv interface{}, ss grpc.ServerStream) error { return nil })
+	if status.Code(err) != codes.Unavailable || status.Convert(err).Message() != "/orders.Orders/Watch is temporarily disabled" {
+		t.Errorf("Expected Unavailable with the default message, got %v", err)
+	}
+	if _, ok := RetryDelay(err); ok {
+		t.Error("Expected no retry hint without RetryAfter")
+	}
+}
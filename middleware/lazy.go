@@ -0,0 +1,130 @@
+package middleware
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	guardian "github.com/grpc-guardian/grpc-guardian"
+	"google.golang.org/grpc"
+)
+
+// LazyConfig holds configuration for lazily initialized middleware
+type LazyConfig struct {
+	InitialBackoff time.Duration // Wait before retrying a failed initialization
+	MaxBackoff     time.Duration // Upper bound for the retry wait
+	OnError        func(error)   // Called whenever initialization fails
+}
+
+// LazyOption is a functional option for lazy middleware configuration
+type LazyOption func(*LazyConfig)
+
+// WithLazyRetryBackoff sets the backoff between initialization attempts
+// Default: 1s initial, doubling up to 1m
+func WithLazyRetryBackoff(initial, max time.Duration) LazyOption {
+	return func(c *LazyConfig) {
+		if initial > 0 {
+			c.InitialBackoff = initial
+		}
+		if max >= initial {
+			c.MaxBackoff = max
+		}
+	}
+}
+
+// WithLazyInitError sets a callback invoked when initialization fails
+func WithLazyInitError(fn func(error)) LazyOption {
+	return func(c *LazyConfig) {
+		c.OnError = fn
+	}
+}
+
+// Lazy defers building a heavy middleware (metrics registries, tracing exporters, cache
+// connections) until the first request. While initialization fails, requests pass straight
+// to the handler and initialization is retried with exponential backoff, so a backend that
+// is briefly unreachable at startup never blocks or fails traffic.
+//
+// Example usage:
+//
+//	chain := guardian.NewChain(
+//	    middleware.Lazy(func() (guardian.Middleware, error) {
+//	        return middleware.TracingE(middleware.WithTracerProviderSetup(setupJaeger))
+//	    }),
+//	    middleware.Lazy(func() (guardian.Middleware, error) {
+//	        return middleware.MetricsE()
+//	    }),
+//	)
+func Lazy(init func() (guardian.Middleware, error), opts ...LazyOption) guardian.Middleware {
+	config := &LazyConfig{
+		InitialBackoff: 1 * time.Second,
+		MaxBackoff:     1 * time.Minute,
+	}
+
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	l := &lazyMiddleware{
+		init:    init,
+		config:  config,
+		backoff: config.InitialBackoff,
+	}
+
+	return l.intercept
+}
+
+// lazyMiddleware holds the state of a lazily initialized middleware
+type lazyMiddleware struct {
+	init   func() (guardian.Middleware, error)
+	config *LazyConfig
+	ready  atomic.Pointer[guardian.Middleware]
+
+	mu          sync.Mutex
+	nextAttempt time.Time
+	backoff     time.Duration
+}
+
+// intercept runs the initialized middleware or passes through until it is available
+func (l *lazyMiddleware) intercept(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if m := l.get(); m != nil {
+		return m(ctx, req, info, handler)
+	}
+	return handler(ctx, req)
+}
+
+// get returns the middleware, attempting initialization when the backoff has elapsed
+func (l *lazyMiddleware) get() guardian.Middleware {
+	if m := l.ready.Load(); m != nil {
+		return *m
+	}
+
+	// Only one request initializes; others pass through instead of waiting
+	if !l.mu.TryLock() {
+		return nil
+	}
+	defer l.mu.Unlock()
+
+	if m := l.ready.Load(); m != nil {
+		return *m
+	}
+	if time.Now().Before(l.nextAttempt) {
+		return nil
+	}
+
+	m, err := l.init()
+	if err != nil || m == nil {
+		if err != nil && l.config.OnError != nil {
+			l.config.OnError(err)
+		}
+		l.nextAttempt = time.Now().Add(l.backoff)
+		l.backoff *= 2
+		if l.backoff > l.config.MaxBackoff {
+			l.backoff = l.config.MaxBackoff
+		}
+		return nil
+	}
+
+	l.ready.Store(&m)
+	return m
+}
@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	guardian "github.com/grpc-guardian/grpc-guardian"
+	"github.com/grpc-guardian/grpc-guardian/pkg/metrics"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+)
+
+func TestLazy_RetriesInitialization(t *testing.T) {
+	attempts := 0
+	var initErrors int
+
+	m := Lazy(func() (guardian.Middleware, error) {
+		attempts++
+		if attempts == 1 {
+			return nil, errors.New("backend unreachable")
+		}
+		return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			return "wrapped", nil
+		}, nil
+	}, WithLazyRetryBackoff(20*time.Millisecond, time.Second), WithLazyInitError(func(error) { initErrors++ }))
+
+	info := &grpc.UnaryServerInfo{FullMethod: "/test.Service/Method"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) { return "direct", nil }
+
+	// Failed initialization passes requests through to the handler
+	if resp, _ := m(context.Background(), nil, info, handler); resp != "direct" {
+		t.Errorf("Expected pass-through while uninitialized, got %v", resp)
+	}
+	// Retries wait for the backoff
+	if resp, _ := m(context.Background(), nil, info, handler); resp != "direct" || attempts != 1 {
+		t.Errorf("Expected no retry before backoff, got %v after %d attempts", resp, attempts)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if resp, _ := m(context.Background(), nil, info, handler); resp != "wrapped" {
+		t.Errorf("Expected initialized middleware, got %v", resp)
+	}
+	if resp, _ := m(context.Background(), nil, info, handler); resp != "wrapped" || attempts != 2 {
+		t.Errorf("Expected initialization to run once more, got %d attempts", attempts)
+	}
+	if initErrors != 1 {
+		t.Errorf("Expected 1 init error, got %d", initErrors)
+	}
+}
+
+func TestTracingE_SetupError(t *testing.T) {
+	setupErr := errors.New("collector unreachable")
+	setup := func() (trace.TracerProvider, error) { return nil, setupErr }
+
+	if _, err := TracingE(WithTracerProviderSetup(setup)); !errors.Is(err, setupErr) {
+		t.Errorf("Expected setup error, got %v", err)
+	}
+
+	// Tracing degrades to a no-op tracer instead of failing
+	interceptor := Tracing(WithTracerProviderSetup(setup))
+	resp, err := interceptor(context.Background(), "req", &grpc.UnaryServerInfo{FullMethod: "/test.Service/Method"},
+		func(ctx context.Context, req interface{}) (interface{}, error) { return "ok", nil })
+	if err != nil || resp != "ok" {
+		t.Errorf("Expected request to succeed with no-op tracer, got %v %v", resp, err)
+	}
+}
+
+func TestMetricsE(t *testing.T) {
+	if _, err := MetricsE(); err != nil {
+		t.Fatalf("Expected metrics middleware, got %v", err)
+	}
+
+	m := MetricsMiddleware(metrics.Noop())
+	resp, err := m(context.Background(), "req", &grpc.UnaryServerInfo{FullMethod: "/test.Service/Method"},
+		func(ctx context.Context, req interface{}) (interface{}, error) { return "ok", nil })
+	if err != nil || resp != "ok" {
+		t.Errorf("Expected request to succeed with no-op collector, got %v %v", resp, err)
+	}
+}
@@ -0,0 +1,242 @@
+package middleware
+
+import (
+	"context"
+	"math"
+	"runtime"
+	runtimemetrics "runtime/metrics"
+	"sync"
+	"time"
+
+	"github.com/grpc-guardian/grpc-guardian/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/time/rate"
+)
+
+// LoadSample is one load monitor decision. Signals are normalized to 0-1, where 1 means
+// the signal is at its configured maximum.
+type LoadSample struct {
+	Time       time.Time
+	CPU        float64
+	Goroutines int
+	P95        time.Duration
+	Signals    map[string]float64
+	LoadFactor float64
+	Rate       rate.Limit
+}
+
+// LoadMonitor samples CPU usage, goroutine count and p95 latency and feeds the resulting
+// load factor to an AdaptiveRateLimiter. The load factor is the highest normalized signal.
+type LoadMonitor struct {
+	limiter       *AdaptiveRateLimiter
+	interval      time.Duration
+	cpuSampler    func() (float64, bool)
+	maxGoroutines int
+	latency       *metrics.HistogramWindow
+	latencyTarget time.Duration
+	collector     *metrics.AdaptiveRateCollector
+	onAdjust      func(LoadSample)
+
+	mu   sync.Mutex
+	last LoadSample
+}
+
+// LoadMonitorOption configures a LoadMonitor
+type LoadMonitorOption func(*LoadMonitor)
+
+// WithMonitorInterval sets how often load is sampled
+// Default: 5s
+func WithMonitorInterval(interval time.Duration) LoadMonitorOption {
+	return func(m *LoadMonitor) {
+		if interval > 0 {
+			m.interval = interval
+		}
+	}
+}
+
+// WithCPUSampler replaces the CPU usage sampler; it returns the usage (0-1) since the
+// previous call, or false when unknown. The default sampler uses the Go runtime's
+// estimate of busy GOMAXPROCS time.
+func WithCPUSampler(sampler func() (float64, bool)) LoadMonitorOption {
+	return func(m *LoadMonitor) {
+		m.cpuSampler = sampler
+	}
+}
+
+// WithMaxGoroutines sets the goroutine count considered full load; 0 ignores goroutines
+// Default: 10000
+func WithMaxGoroutines(n int) LoadMonitorOption {
+	return func(m *LoadMonitor) {
+		if n >= 0 {
+			m.maxGoroutines = n
+		}
+	}
+}
+
+// WithLatencyHistogram reads p95 latency from a Prometheus histogram, e.g.
+// "grpc_server_request_duration_seconds" of a PrometheusCollector registry
+func WithLatencyHistogram(gatherer prometheus.Gatherer, name string) LoadMonitorOption {
+	return func(m *LoadMonitor) {
+		m.latency = metrics.NewHistogramWindow(gatherer, name)
+	}
+}
+
+// WithLatencyFromCollector reads p95 latency from the request duration histogram of a
+// collector created with the default namespace and subsystem
+func WithLatencyFromCollector(collector metrics.MetricsCollector) LoadMonitorOption {
+	return WithLatencyHistogram(collector.GetRegistry(), "grpc_server_request_duration_seconds")
+}
+
+// WithLatencyTarget sets the p95 latency considered full load
+// Default: 500ms
+func WithLatencyTarget(target time.Duration) LoadMonitorOption {
+	return func(m *LoadMonitor) {
+		if target > 0 {
+			m.latencyTarget = target
+		}
+	}
+}
+
+// WithAdaptiveRateCollector exports every decision to Prometheus
+func WithAdaptiveRateCollector(collector *metrics.AdaptiveRateCollector) LoadMonitorOption {
+	return func(m *LoadMonitor) {
+		m.collector = collector
+	}
+}
+
+// WithOnAdjust sets a callback invoked after every sample
+func WithOnAdjust(fn func(LoadSample)) LoadMonitorOption {
+	return func(m *LoadMonitor) {
+		m.onAdjust = fn
+	}
+}
+
+// NewLoadMonitor creates a monitor that drives limiter.AdjustRate.
+//
+// Example usage:
+//
+//	limiter := middleware.NewAdaptiveRateLimiter(500, 100)
+//	monitor := middleware.NewLoadMonitor(limiter,
+//	    middleware.WithLatencyFromCollector(collector),
+//	    middleware.WithLatencyTarget(200*time.Millisecond),
+//	    middleware.WithOnAdjust(func(s middleware.LoadSample) {
+//	        log.Printf("load %.2f -> %.0f req/s", s.LoadFactor, s.Rate)
+//	    }),
+//	)
+//	go monitor.Run(ctx)
+//
+//	chain := guardian.NewChain(limiter.UnaryServerInterceptor())
+func NewLoadMonitor(limiter *AdaptiveRateLimiter, opts ...LoadMonitorOption) *LoadMonitor {
+	m := &LoadMonitor{
+		limiter:       limiter,
+		interval:      5 * time.Second,
+		cpuSampler:    newRuntimeCPUSampler(),
+		maxGoroutines: 10000,
+		latencyTarget: 500 * time.Millisecond,
+	}
+
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	return m
+}
+
+// Run samples load every interval until ctx is done
+func (m *LoadMonitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.Sample()
+		}
+	}
+}
+
+// Sample measures load once and adjusts the limiter
+func (m *LoadMonitor) Sample() LoadSample {
+	sample := LoadSample{
+		Time:       time.Now(),
+		Goroutines: runtime.NumGoroutine(),
+		Signals:    make(map[string]float64, 3),
+	}
+
+	if m.cpuSampler != nil {
+		if cpu, ok := m.cpuSampler(); ok {
+			sample.CPU = cpu
+			sample.Signals["cpu"] = clamp01(cpu)
+		}
+	}
+	if m.maxGoroutines > 0 {
+		sample.Signals["goroutines"] = clamp01(float64(sample.Goroutines) / float64(m.maxGoroutines))
+	}
+	if m.latency != nil {
+		if p95, ok, err := m.latency.Quantile(0.95); err == nil && ok {
+			sample.P95 = time.Duration(p95 * float64(time.Second))
+			sample.Signals["latency"] = clamp01(float64(sample.P95) / float64(m.latencyTarget))
+		}
+	}
+
+	for _, value := range sample.Signals {
+		sample.LoadFactor = math.Max(sample.LoadFactor, value)
+	}
+
+	m.limiter.AdjustRate(sample.LoadFactor)
+	sample.Rate = m.limiter.CurrentRate()
+
+	m.mu.Lock()
+	m.last = sample
+	m.mu.Unlock()
+
+	if m.collector != nil {
+		m.collector.Record(float64(sample.Rate), sample.LoadFactor, sample.Signals)
+	}
+	if m.onAdjust != nil {
+		m.onAdjust(sample)
+	}
+
+	return sample
+}
+
+// Last returns the most recent sample
+func (m *LoadMonitor) Last() LoadSample {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.last
+}
+
+// newRuntimeCPUSampler estimates CPU usage from the share of GOMAXPROCS time the Go
+// runtime was busy since the previous call
+func newRuntimeCPUSampler() func() (float64, bool) {
+	samples := []runtimemetrics.Sample{
+		{Name: "/cpu/classes/idle:cpu-seconds"},
+		{Name: "/cpu/classes/total:cpu-seconds"},
+	}
+	var lastIdle, lastTotal float64
+
+	return func() (float64, bool) {
+		runtimemetrics.Read(samples)
+		if samples[0].Value.Kind() != runtimemetrics.KindFloat64 || samples[1].Value.Kind() != runtimemetrics.KindFloat64 {
+			return 0, false
+		}
+
+		idle, total := samples[0].Value.Float64(), samples[1].Value.Float64()
+		deltaIdle, deltaTotal := idle-lastIdle, total-lastTotal
+		lastIdle, lastTotal = idle, total
+
+		if deltaTotal <= 0 {
+			return 0, false
+		}
+		return clamp01(1 - deltaIdle/deltaTotal), true
+	}
+}
+
+// clamp01 limits a value to [0, 1]
+func clamp01(v float64) float64 {
+	return math.Max(0, math.Min(1, v))
+}
@@ -0,0 +1,124 @@
+package middleware
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grpc-guardian/grpc-guardian/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"golang.org/x/time/rate"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestHistogramWindow(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	histogram := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "latency_seconds",
+		Buckets: []float64{0.1, 0.2, 0.4},
+	}, []string{"method"})
+	registry.MustRegister(histogram)
+
+	window := metrics.NewHistogramWindow(registry, "latency_seconds")
+	if _, ok, err := window.Quantile(0.95); err != nil || ok {
+		t.Errorf("Expected no quantile without observations, got ok=%v err=%v", ok, err)
+	}
+
+	for i := 0; i < 100; i++ {
+		histogram.WithLabelValues("/svc/A").Observe(0.05)
+	}
+	if p95, ok, _ := window.Quantile(0.95); !ok || p95 < 0.09 || p95 > 0.1 {
+		t.Errorf("Expected p95 in the first bucket, got %v (%v)", p95, ok)
+	}
+
+	// Only observations since the previous call count
+	for i := 0; i < 100; i++ {
+		histogram.WithLabelValues("/svc/B").Observe(0.3)
+	}
+	if p95, ok, _ := window.Quantile(0.95); !ok || p95 < 0.2 || p95 > 0.4 {
+		t.Errorf("Expected p95 in the third bucket, got %v (%v)", p95, ok)
+	}
+}
+
+func TestLoadMonitor(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	histogram := prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "grpc_server_request_duration_seconds",
+		Buckets: prometheus.DefBuckets,
+	})
+	registry.MustRegister(histogram)
+
+	collector, err := metrics.NewAdaptiveRateCollector(registry)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cpu := 0.2
+	var samples []LoadSample
+	limiter := NewAdaptiveRateLimiter(100, 10)
+	monitor := NewLoadMonitor(limiter,
+		WithCPUSampler(func() (float64, bool) { return cpu, true }),
+		WithMaxGoroutines(0),
+		WithLatencyHistogram(registry, "grpc_server_request_duration_seconds"),
+		WithLatencyTarget(100*time.Millisecond),
+		WithAdaptiveRateCollector(collector),
+		WithOnAdjust(func(s LoadSample) { samples = append(samples, s) }),
+	)
+
+	// Low load raises the rate
+	sample := monitor.Sample()
+	if sample.LoadFactor != 0.2 || sample.Rate <= 100 {
+		t.Errorf("Expected low load to raise the rate, got %+v", sample)
+	}
+
+	// Slow requests dominate the load factor and lower the rate
+	for i := 0; i < 20; i++ {
+		histogram.Observe(2)
+	}
+	sample = monitor.Sample()
+	if sample.LoadFactor != 1 || sample.P95 < time.Second || sample.Rate >= 120 {
+		t.Errorf("Expected slow requests to lower the rate, got %+v", sample)
+	}
+	if sample.Signals["latency"] != 1 || sample.Signals["cpu"] != 0.2 {
+		t.Errorf("Unexpected signals %v", sample.Signals)
+	}
+
+	if len(samples) != 2 || monitor.Last().Rate != limiter.CurrentRate() {
+		t.Errorf("Expected callbacks and Last() to report the decisions, got %d samples", len(samples))
+	}
+
+	expected := `
+# HELP grpc_adaptive_load_factor Load factor fed to the adaptive rate limiter
+# TYPE grpc_adaptive_load_factor gauge
+grpc_adaptive_load_factor 1
+`
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(expected), "grpc_adaptive_load_factor"); err != nil {
+		t.Errorf("Unexpected adaptive metrics: %v", err)
+	}
+
+	// The interceptor enforces the adjusted rate
+	exhausted := NewAdaptiveRateLimiter(1, 1)
+	interceptor := exhausted.UnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/svc/M"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) { return "ok", nil }
+	interceptor(context.Background(), "req", info, handler)
+	if _, err := interceptor(context.Background(), "req", info, handler); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("Expected ResourceExhausted, got %v", err)
+	}
+	if exhausted.CurrentRate() != rate.Limit(1) {
+		t.Errorf("Expected base rate, got %v", exhausted.CurrentRate())
+	}
+}
+
+func TestRuntimeCPUSampler(t *testing.T) {
+	sampler := newRuntimeCPUSampler()
+	sampler()
+	time.Sleep(10 * time.Millisecond)
+	if cpu, ok := sampler(); ok && (cpu < 0 || cpu > 1) {
+		t.Errorf("Expected CPU usage within [0, 1], got %v", cpu)
+	}
+}
@@ -2,8 +2,12 @@ package middleware
 
 import (
 	"context"
+	"sync/atomic"
 	"time"
 
+	"github.com/grpc-guardian/grpc-guardian/pkg/logging"
+	"github.com/grpc-guardian/grpc-guardian/pkg/servicemesh"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 	"google.golang.org/grpc"
@@ -13,11 +17,17 @@ import (
 
 // LoggingConfig holds configuration for logging middleware
 type LoggingConfig struct {
-	Logger          *zap.Logger
+	Logger          logging.Logger
 	Level           zapcore.Level
 	LogRequestBody  bool
 	LogResponseBody bool
 	ExtraFields     map[string]interface{}
+
+	// Redactor masks sensitive fields of logged request and response bodies
+	Redactor *logging.Redactor
+
+	// DisableCorrelation omits the trace_id, span_id, request_id, tenant_id and geo fields
+	DisableCorrelation bool
 }
 
 // LoggingOption is a functional option for logging configuration
@@ -25,6 +35,15 @@ type LoggingOption func(*LoggingConfig)
 
 // WithLogger sets a custom zap logger
 func WithLogger(logger *zap.Logger) LoggingOption {
+	return func(c *LoggingConfig) {
+		c.Logger = logging.NewZap(logger)
+	}
+}
+
+// WithStructuredLogger sets the logger through the pkg/logging interface, e.g. an slog or
+// logrus adapter
+// Default: zap.NewExample() for Logging, zap.NewProduction() for AccessLog and PerformanceLog
+func WithStructuredLogger(logger logging.Logger) LoggingOption {
 	return func(c *LoggingConfig) {
 		c.Logger = logger
 	}
@@ -51,6 +70,14 @@ func WithResponseBody() LoggingOption {
 	}
 }
 
+// WithRedaction configures how request and response bodies are redacted before logging
+// Default: only proto fields declared with [debug_redact = true] are masked
+func WithRedaction(opts ...logging.RedactOption) LoggingOption {
+	return func(c *LoggingConfig) {
+		c.Redactor = logging.NewRedactor(opts...)
+	}
+}
+
 // WithExtraFields adds extra fields to all log entries
 func WithExtraFields(fields map[string]interface{}) LoggingOption {
 	return func(c *LoggingConfig) {
@@ -58,41 +85,75 @@ func WithExtraFields(fields map[string]interface{}) LoggingOption {
 	}
 }
 
-// Logging creates a logging middleware with the provided options
-func Logging(opts ...LoggingOption) func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
-	// Default configuration
+// WithoutCorrelationFields disables the trace_id, span_id, request_id, tenant_id and geo fields
+// Default: enabled
+func WithoutCorrelationFields() LoggingOption {
+	return func(c *LoggingConfig) {
+		c.DisableCorrelation = true
+	}
+}
+
+// newLoggingConfig applies the options, falling back to defaultLogger when no logger is set
+func newLoggingConfig(opts []LoggingOption, defaultLogger func(...zap.Option) *zap.Logger) *LoggingConfig {
 	config := &LoggingConfig{
-		Logger: zap.NewExample(),
-		Level:  zapcore.InfoLevel,
+		Level: zapcore.InfoLevel,
 	}
 
-	// Apply options
 	for _, opt := range opts {
 		opt(config)
 	}
 
+	if config.Logger == nil {
+		config.Logger = logging.NewZap(defaultLogger())
+	}
+	if config.Redactor == nil {
+		config.Redactor = logging.NewRedactor()
+	}
+
+	return config
+}
+
+// Logging creates a logging middleware with the provided options.
+//
+// Example usage:
+//
+//	// zap (default)
+//	middleware.Logging(middleware.WithLogger(zapLogger))
+//
+//	// log/slog
+//	middleware.Logging(middleware.WithStructuredLogger(logging.NewSlog(slog.Default())))
+func Logging(opts ...LoggingOption) func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	config := newLoggingConfig(opts, zap.NewExample)
+
 	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
 		start := time.Now()
 
 		// Build base fields
-		fields := []zap.Field{
-			zap.String("method", info.FullMethod),
-			zap.Time("start_time", start),
+		fields := []logging.Field{
+			logging.String("method", info.FullMethod),
+			logging.Time("start_time", start),
+		}
+
+		// Add trace and request IDs so logs can be joined with traces
+		var correlation []logging.Field
+		if !config.DisableCorrelation {
+			correlation = correlationFields(ctx)
+			fields = append(fields, correlation...)
 		}
 
 		// Add extra fields
 		for k, v := range config.ExtraFields {
-			fields = append(fields, zap.Any(k, v))
+			fields = append(fields, logging.Any(k, v))
 		}
 
 		// Add request body if enabled
 		if config.LogRequestBody {
-			fields = append(fields, zap.Any("request", req))
+			fields = append(fields, logging.Any("request", config.Redactor.Redact(info.FullMethod, req)))
 		}
 
 		// Add user context if available
 		if userID, ok := GetUserID(ctx); ok {
-			fields = append(fields, zap.String("user_id", userID))
+			fields = append(fields, logging.String("user_id", userID))
 		}
 
 		config.Logger.Info("gRPC request started", fields...)
@@ -104,57 +165,205 @@ func Logging(opts ...LoggingOption) func(ctx context.Context, req interface{}, i
 		duration := time.Since(start)
 
 		// Build response fields
-		responseFields := []zap.Field{
-			zap.String("method", info.FullMethod),
-			zap.Duration("duration", duration),
-			zap.Int64("duration_ms", duration.Milliseconds()),
+		responseFields := []logging.Field{
+			logging.String("method", info.FullMethod),
+			logging.Duration("duration", duration),
+			logging.Int64("duration_ms", duration.Milliseconds()),
 		}
+		responseFields = append(responseFields, correlation...)
 
 		// Add extra fields
 		for k, v := range config.ExtraFields {
-			responseFields = append(responseFields, zap.Any(k, v))
+			responseFields = append(responseFields, logging.Any(k, v))
 		}
 
 		// Add user context if available
 		if userID, ok := GetUserID(ctx); ok {
-			responseFields = append(responseFields, zap.String("user_id", userID))
+			responseFields = append(responseFields, logging.String("user_id", userID))
 		}
 
 		// Add response body if enabled and no error
 		if config.LogResponseBody && err == nil {
-			responseFields = append(responseFields, zap.Any("response", resp))
+			responseFields = append(responseFields, logging.Any("response", config.Redactor.Redact(info.FullMethod, resp)))
 		}
 
-		// Log based on error status
-		if err != nil {
-			st := status.Convert(err)
-			responseFields = append(responseFields,
-				zap.String("grpc_code", st.Code().String()),
-				zap.String("error", st.Message()),
-			)
+		logCompletion(config.Logger, "request", responseFields, err)
+
+		return resp, err
+	}
+}
 
-			// Log level based on error code
-			switch st.Code() {
-			case codes.Internal, codes.Unknown, codes.DataLoss:
-				config.Logger.Error("gRPC request failed", responseFields...)
-			case codes.InvalidArgument, codes.NotFound, codes.AlreadyExists,
-				codes.PermissionDenied, codes.Unauthenticated:
-				config.Logger.Warn("gRPC request rejected", responseFields...)
-			default:
-				config.Logger.Info("gRPC request completed with error", responseFields...)
-			}
-		} else {
-			responseFields = append(responseFields, zap.String("grpc_code", codes.OK.String()))
-			config.Logger.Info("gRPC request completed", responseFields...)
+// StreamLogging creates a stream logging middleware with the same options as Logging. It
+// logs stream start and end with duration, status and the number of messages sent and
+// received. With WithRequestBody/WithResponseBody every received/sent message is logged
+// at debug level.
+//
+// Example usage:
+//
+//	server := grpc.NewServer(
+//	    grpc.StreamInterceptor(middleware.StreamLogging(middleware.WithLogger(zapLogger))),
+//	)
+func StreamLogging(opts ...LoggingOption) func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	config := newLoggingConfig(opts, zap.NewExample)
+
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx := ss.Context()
+		start := time.Now()
+
+		// Fields shared by every entry of the stream
+		common := []logging.Field{
+			logging.String("method", info.FullMethod),
+		}
+		if !config.DisableCorrelation {
+			common = append(common, correlationFields(ctx)...)
+		}
+		for k, v := range config.ExtraFields {
+			common = append(common, logging.Any(k, v))
+		}
+		if userID, ok := GetUserID(ctx); ok {
+			common = append(common, logging.String("user_id", userID))
 		}
 
-		return resp, err
+		startFields := append(common[:len(common):len(common)],
+			logging.Time("start_time", start),
+			logging.Bool("client_stream", info.IsClientStream),
+			logging.Bool("server_stream", info.IsServerStream),
+		)
+		config.Logger.Info("gRPC stream started", startFields...)
+
+		wrapped := &loggingServerStream{
+			ServerStream: ss,
+			config:       config,
+			method:       info.FullMethod,
+			fields:       common,
+		}
+		err := handler(srv, wrapped)
+
+		duration := time.Since(start)
+		endFields := append(common[:len(common):len(common)],
+			logging.Duration("duration", duration),
+			logging.Int64("duration_ms", duration.Milliseconds()),
+			logging.Int64("messages_sent", atomic.LoadInt64(&wrapped.sent)),
+			logging.Int64("messages_received", atomic.LoadInt64(&wrapped.received)),
+		)
+		logCompletion(config.Logger, "stream", endFields, err)
+
+		return err
+	}
+}
+
+// logCompletion logs the end of a request or stream at a level based on its status
+func logCompletion(logger logging.Logger, kind string, fields []logging.Field, err error) {
+	if err == nil {
+		fields = append(fields, logging.String("grpc_code", codes.OK.String()))
+		logger.Info("gRPC "+kind+" completed", fields...)
+		return
+	}
+
+	st := status.Convert(err)
+	fields = append(fields,
+		logging.String("grpc_code", st.Code().String()),
+		logging.String("error", st.Message()),
+	)
+
+	// Log level based on error code
+	switch st.Code() {
+	case codes.Internal, codes.Unknown, codes.DataLoss:
+		logger.Error("gRPC "+kind+" failed", fields...)
+	case codes.InvalidArgument, codes.NotFound, codes.AlreadyExists,
+		codes.PermissionDenied, codes.Unauthenticated:
+		logger.Warn("gRPC "+kind+" rejected", fields...)
+	default:
+		logger.Info("gRPC "+kind+" completed with error", fields...)
+	}
+}
+
+// loggingServerStream counts the messages of a stream
+type loggingServerStream struct {
+	grpc.ServerStream
+	config   *LoggingConfig
+	method   string
+	fields   []logging.Field
+	sent     int64
+	received int64
+}
+
+// SendMsg sends a message and counts it
+func (s *loggingServerStream) SendMsg(m interface{}) error {
+	err := s.ServerStream.SendMsg(m)
+	if err == nil {
+		atomic.AddInt64(&s.sent, 1)
+		if s.config.LogResponseBody {
+			s.config.Logger.Debug("gRPC stream message sent",
+				append(s.fields[:len(s.fields):len(s.fields)], logging.Any("response", s.config.Redactor.Redact(s.method, m)))...)
+		}
+	}
+	return err
+}
+
+// RecvMsg receives a message and counts it
+func (s *loggingServerStream) RecvMsg(m interface{}) error {
+	err := s.ServerStream.RecvMsg(m)
+	if err == nil {
+		atomic.AddInt64(&s.received, 1)
+		if s.config.LogRequestBody {
+			s.config.Logger.Debug("gRPC stream message received",
+				append(s.fields[:len(s.fields):len(s.fields)], logging.Any("request", s.config.Redactor.Redact(s.method, m)))...)
+		}
+	}
+	return err
+}
+
+// correlationFields returns the trace_id and span_id of the active span, the request ID
+// set by the service mesh (x-request-id, or l5d-ctx-trace for Linkerd), the tenant and the
+// caller location. The span is only available when the Tracing middleware runs before
+// Logging, the tenant when Tenants does and the location when GeoIP does.
+func correlationFields(ctx context.Context) []logging.Field {
+	var fields []logging.Field
+
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		fields = append(fields,
+			logging.String("trace_id", sc.TraceID().String()),
+			logging.String("span_id", sc.SpanID().String()),
+		)
+	}
+
+	requestID := servicemesh.ExtractHeader(ctx, servicemesh.HeaderKeys.XRequestID)
+	if requestID == "" {
+		requestID = servicemesh.ExtractHeader(ctx, servicemesh.HeaderKeys.LinkerdContextID)
+	}
+	if requestID != "" {
+		fields = append(fields, logging.String("request_id", requestID))
+	}
+
+	if tenant, ok := GetTenantID(ctx); ok {
+		fields = append(fields, logging.String("tenant_id", tenant))
+	}
+
+	if location, ok := GetGeoLocation(ctx); ok {
+		if location.Country != "" {
+			fields = append(fields, logging.String("geo_country", location.Country))
+		}
+		if location.ASN != 0 {
+			fields = append(fields, logging.Int64("geo_asn", int64(location.ASN)))
+		}
+	}
+
+	return fields
+}
+
+// productionLogger returns zap's production logger, or a no-op logger if it cannot be built
+func productionLogger(options ...zap.Option) *zap.Logger {
+	logger, err := zap.NewProduction(options...)
+	if err != nil {
+		return zap.NewNop()
 	}
+	return logger
 }
 
 // AccessLog creates a simple access log middleware (Apache/Nginx style)
-func AccessLog() func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
-	logger, _ := zap.NewProduction()
+func AccessLog(opts ...LoggingOption) func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	logger := newLoggingConfig(opts, productionLogger).Logger
 
 	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
 		start := time.Now()
@@ -173,10 +382,10 @@ func AccessLog() func(ctx context.Context, req interface{}, info *grpc.UnaryServ
 
 		// Log in access log format
 		logger.Info("access",
-			zap.String("method", info.FullMethod),
-			zap.String("code", code.String()),
-			zap.Duration("duration", duration),
-			zap.Time("time", start),
+			logging.String("method", info.FullMethod),
+			logging.String("code", code.String()),
+			logging.Duration("duration", duration),
+			logging.Time("time", start),
 		)
 
 		return resp, err
@@ -184,8 +393,8 @@ func AccessLog() func(ctx context.Context, req interface{}, info *grpc.UnaryServ
 }
 
 // PerformanceLog logs performance metrics for slow requests
-func PerformanceLog(threshold time.Duration) func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
-	logger, _ := zap.NewProduction()
+func PerformanceLog(threshold time.Duration, opts ...LoggingOption) func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	logger := newLoggingConfig(opts, productionLogger).Logger
 
 	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
 		start := time.Now()
@@ -199,9 +408,9 @@ func PerformanceLog(threshold time.Duration) func(ctx context.Context, req inter
 		// Log if request exceeded threshold
 		if duration > threshold {
 			logger.Warn("slow request detected",
-				zap.String("method", info.FullMethod),
-				zap.Duration("duration", duration),
-				zap.Duration("threshold", threshold),
+				logging.String("method", info.FullMethod),
+				logging.Duration("duration", duration),
+				logging.Duration("threshold", threshold),
 			)
 		}
 
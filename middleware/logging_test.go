@@ -0,0 +1,294 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grpc-guardian/grpc-guardian/pkg/logging"
+	"go.opentelemetry.io/otel/sdk/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+func TestLogging_CorrelationFields(t *testing.T) {
+	tp := trace.NewTracerProvider()
+	ctx, span := tp.Tracer("test").Start(context.Background(), "rpc")
+	defer span.End()
+	ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("x-request-id", "req-42"))
+
+	info := &grpc.UnaryServerInfo{FullMethod: "/test.Service/Get"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) { return "ok", nil }
+
+	core, logs := observer.New(zapcore.InfoLevel)
+	if _, err := Logging(WithLogger(zap.New(core)))(ctx, "req", info, handler); err != nil {
+		t.Fatal(err)
+	}
+
+	entries := logs.All()
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 log entries, got %d", len(entries))
+	}
+	sc := span.SpanContext()
+	for _, entry := range entries {
+		fields := entry.ContextMap()
+		if fields["trace_id"] != sc.TraceID().String() {
+			t.Errorf("%s: trace_id = %v, want %s", entry.Message, fields["trace_id"], sc.TraceID())
+		}
+		if fields["span_id"] != sc.SpanID().String() {
+			t.Errorf("%s: span_id = %v, want %s", entry.Message, fields["span_id"], sc.SpanID())
+		}
+		if fields["request_id"] != "req-42" {
+			t.Errorf("%s: request_id = %v, want req-42", entry.Message, fields["request_id"])
+		}
+	}
+
+	core, logs = observer.New(zapcore.InfoLevel)
+	if _, err := Logging(WithLogger(zap.New(core)), WithoutCorrelationFields())(ctx, "req", info, handler); err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range logs.All() {
+		for _, key := range []string{"trace_id", "span_id", "request_id"} {
+			if _, ok := entry.ContextMap()[key]; ok {
+				t.Errorf("%s: unexpected %s field with correlation disabled", entry.Message, key)
+			}
+		}
+	}
+}
+
+// fakeLogrusEntry mimics *logrus.Entry
+type fakeLogrusEntry struct {
+	fields  map[string]interface{}
+	entries *[]string
+}
+
+func (e *fakeLogrusEntry) WithField(key string, value interface{}) *fakeLogrusEntry {
+	fields := make(map[string]interface{}, len(e.fields)+1)
+	for k, v := range e.fields {
+		fields[k] = v
+	}
+	fields[key] = value
+	return &fakeLogrusEntry{fields: fields, entries: e.entries}
+}
+
+func (e *fakeLogrusEntry) log(level string, args ...interface{}) {
+	*e.entries = append(*e.entries, fmt.Sprintf("%s %v method=%v", level, args[0], e.fields["method"]))
+}
+
+func (e *fakeLogrusEntry) Debug(args ...interface{}) { e.log("debug", args...) }
+func (e *fakeLogrusEntry) Info(args ...interface{})  { e.log("info", args...) }
+func (e *fakeLogrusEntry) Warn(args ...interface{})  { e.log("warn", args...) }
+func (e *fakeLogrusEntry) Error(args ...interface{}) { e.log("error", args...) }
+
+func TestLogging_Adapters(t *testing.T) {
+	info := &grpc.UnaryServerInfo{FullMethod: "/test.Service/Get"}
+	failing := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, status.Error(codes.NotFound, "missing")
+	}
+
+	// log/slog
+	var buf bytes.Buffer
+	slogger := slog.New(slog.NewJSONHandler(&buf, nil))
+	if _, err := Logging(WithStructuredLogger(logging.NewSlog(slogger)))(context.Background(), "req", info, failing); err == nil {
+		t.Fatal("Expected error")
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 slog lines, got %d: %s", len(lines), buf.String())
+	}
+	var entry map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[1]), &entry); err != nil {
+		t.Fatal(err)
+	}
+	if entry["level"] != "WARN" || entry["method"] != info.FullMethod || entry["grpc_code"] != "NotFound" {
+		t.Errorf("Unexpected slog entry: %v", entry)
+	}
+
+	// logrus
+	var entries []string
+	logrusLogger := logging.NewLogrus(&fakeLogrusEntry{entries: &entries})
+	if _, err := AccessLog(WithStructuredLogger(logrusLogger))(context.Background(), "req", info, failing); err == nil {
+		t.Fatal("Expected error")
+	}
+	if len(entries) != 1 || entries[0] != "info access method=/test.Service/Get" {
+		t.Errorf("Unexpected logrus entries: %v", entries)
+	}
+
+	// PerformanceLog only logs slow requests
+	core, logs := observer.New(zapcore.InfoLevel)
+	slow := func(ctx context.Context, req interface{}) (interface{}, error) {
+		time.Sleep(5 * time.Millisecond)
+		return "ok", nil
+	}
+	PerformanceLog(time.Millisecond, WithLogger(zap.New(core)))(context.Background(), "req", info, slow)
+	PerformanceLog(time.Hour, WithLogger(zap.New(core)))(context.Background(), "req", info, slow)
+	if logs.Len() != 1 || logs.All()[0].Level != zapcore.WarnLevel {
+		t.Errorf("Expected one slow request warning, got %d entries", logs.Len())
+	}
+}
+
+// redactTestMessage builds a dynamic message with a debug_redact field
+func redactTestMessage(t *testing.T) *dynamicpb.Message {
+	t.Helper()
+
+	field := func(name string, number int32, typ descriptorpb.FieldDescriptorProto_Type, typeName string, redact bool) *descriptorpb.FieldDescriptorProto {
+		fd := &descriptorpb.FieldDescriptorProto{
+			Name:   proto.String(name),
+			Number: proto.Int32(number),
+			Label:  descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+			Type:   typ.Enum(),
+		}
+		if typeName != "" {
+			fd.TypeName = proto.String(typeName)
+		}
+		if redact {
+			fd.Options = &descriptorpb.FieldOptions{DebugRedact: proto.Bool(true)}
+		}
+		return fd
+	}
+
+	file, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:    proto.String("redacttest/user.proto"),
+		Package: proto.String("redacttest"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{
+			{
+				Name: proto.String("Profile"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					field("ssn", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING, "", false),
+					field("city", 2, descriptorpb.FieldDescriptorProto_TYPE_STRING, "", false),
+				},
+			},
+			{
+				Name: proto.String("User"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					field("name", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING, "", false),
+					field("password", 2, descriptorpb.FieldDescriptorProto_TYPE_STRING, "", false),
+					field("api_token", 3, descriptorpb.FieldDescriptorProto_TYPE_STRING, "", true),
+					field("profile", 4, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, ".redacttest.Profile", false),
+				},
+			},
+		},
+	}, protoregistry.GlobalFiles)
+	if err != nil {
+		t.Fatalf("failed to build descriptor: %v", err)
+	}
+
+	userDesc := file.Messages().ByName("User")
+	profileDesc := file.Messages().ByName("Profile")
+
+	profile := dynamicpb.NewMessage(profileDesc)
+	profile.Set(profileDesc.Fields().ByName("ssn"), protoreflect.ValueOfString("123-45-6789"))
+	profile.Set(profileDesc.Fields().ByName("city"), protoreflect.ValueOfString("Berlin"))
+
+	user := dynamicpb.NewMessage(userDesc)
+	user.Set(userDesc.Fields().ByName("name"), protoreflect.ValueOfString("alice"))
+	user.Set(userDesc.Fields().ByName("password"), protoreflect.ValueOfString("hunter2"))
+	user.Set(userDesc.Fields().ByName("api_token"), protoreflect.ValueOfString("tok-1"))
+	user.Set(userDesc.Fields().ByName("profile"), protoreflect.ValueOfMessage(profile))
+	return user
+}
+
+func TestLogging_RedactsBodies(t *testing.T) {
+	user := redactTestMessage(t)
+	core, logs := observer.New(zapcore.InfoLevel)
+
+	interceptor := Logging(
+		WithLogger(zap.New(core)),
+		WithRequestBody(),
+		WithResponseBody(),
+		WithRedaction(logging.WithRedactedFields("password")),
+	)
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) { return req, nil }
+	if _, err := interceptor(context.Background(), user, &grpc.UnaryServerInfo{FullMethod: "/users.Users/Create"}, handler); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, entry := range logs.All() {
+		data, _ := json.Marshal(entry.ContextMap())
+		if strings.Contains(string(data), "hunter2") || strings.Contains(string(data), "tok-1") {
+			t.Errorf("%s: secret leaked into log entry: %s", entry.Message, data)
+		}
+	}
+}
+
+// loggingTestStream is a bidi stream that receives a fixed number of messages
+type loggingTestStream struct {
+	grpc.ServerStream
+	ctx     context.Context
+	pending int
+}
+
+func (s *loggingTestStream) Context() context.Context    { return s.ctx }
+func (s *loggingTestStream) SendMsg(m interface{}) error { return nil }
+
+func (s *loggingTestStream) RecvMsg(m interface{}) error {
+	if s.pending == 0 {
+		return io.EOF
+	}
+	s.pending--
+	return nil
+}
+
+func TestStreamLogging(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	interceptor := StreamLogging(WithLogger(zap.New(core)), WithRequestBody())
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-request-id", "req-7"))
+	stream := &loggingTestStream{ctx: ctx, pending: 3}
+	info := &grpc.StreamServerInfo{FullMethod: "/chat.Chat/Talk", IsClientStream: true, IsServerStream: true}
+
+	err := interceptor(nil, stream, info, func(srv interface{}, ss grpc.ServerStream) error {
+		for {
+			if err := ss.RecvMsg(nil); err == io.EOF {
+				break
+			}
+			if err := ss.SendMsg("reply"); err != nil {
+				return err
+			}
+		}
+		return status.Error(codes.NotFound, "room closed")
+	})
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("Expected NotFound, got %v", err)
+	}
+
+	if n := logs.FilterMessage("gRPC stream message received").Len(); n != 3 {
+		t.Errorf("Expected 3 received message entries, got %d", n)
+	}
+	if logs.FilterMessage("gRPC stream started").Len() != 1 {
+		t.Fatal("Expected a stream start entry")
+	}
+
+	end := logs.FilterMessage("gRPC stream rejected").All()
+	if len(end) != 1 {
+		t.Fatalf("Expected one stream rejected entry, got %v", logs.All())
+	}
+	fields := end[0].ContextMap()
+	if end[0].Level != zapcore.WarnLevel {
+		t.Errorf("Expected warn level, got %v", end[0].Level)
+	}
+	if fields["messages_sent"] != int64(3) || fields["messages_received"] != int64(3) {
+		t.Errorf("Unexpected counters: sent=%v received=%v", fields["messages_sent"], fields["messages_received"])
+	}
+	if fields["grpc_code"] != "NotFound" || fields["request_id"] != "req-7" {
+		t.Errorf("Unexpected end fields: %v", fields)
+	}
+}
@@ -2,17 +2,135 @@ package middleware
 
 import (
 	"context"
+	"fmt"
+	"io"
+	"sync"
 	"time"
 
 	guardian "github.com/grpc-guardian/grpc-guardian"
 	"github.com/grpc-guardian/grpc-guardian/pkg/metrics"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
 )
 
-// MetricsMiddleware creates a middleware that collects metrics
-func MetricsMiddleware(collector metrics.MetricsCollector) guardian.Middleware {
+// MessageSizeLimits are payload sizes, in bytes, above which the size alert fires.
+// Zero disables the alert for that direction.
+type MessageSizeLimits struct {
+	Request  int
+	Response int
+}
+
+// MessageSizeAlertFunc is called for a message larger than its limit. direction is
+// metrics.DirectionReceived for requests and metrics.DirectionSent for responses.
+type MessageSizeAlertFunc func(ctx context.Context, method, direction string, size, limit int)
+
+// MetricsOption configures the metrics middleware
+type MetricsOption func(*metricsConfig)
+
+// metricsConfig holds the metrics middleware settings
+type metricsConfig struct {
+	sizes        bool
+	limits       MessageSizeLimits
+	methodLimits map[string]MessageSizeLimits
+	alert        MessageSizeAlertFunc
+}
+
+// WithoutMessageSizes stops recording payload sizes, e.g. when a metrics.SizeStatsHandler
+// records them at the codec level instead
+func WithoutMessageSizes() MetricsOption {
+	return func(c *metricsConfig) {
+		c.sizes = false
+	}
+}
+
+// WithMessageSizeAlert calls alert for requests and responses larger than the limits
+func WithMessageSizeAlert(limits MessageSizeLimits, alert MessageSizeAlertFunc) MetricsOption {
+	return func(c *metricsConfig) {
+		c.limits = limits
+		c.alert = alert
+	}
+}
+
+// WithMethodMessageSizeLimits overrides the alert limits for methods matching a pattern
+// ("/pkg.Service/Method", "/pkg.Service/*"), e.g. for upload methods
+func WithMethodMessageSizeLimits(pattern string, limits MessageSizeLimits) MetricsOption {
+	return func(c *metricsConfig) {
+		c.methodLimits[pattern] = limits
+	}
+}
+
+// messageSizes records payload sizes and raises size alerts
+type messageSizes struct {
+	collector metrics.MetricsCollector
+	config    *metricsConfig
+	limits    *methodMatcher[MessageSizeLimits]
+}
+
+// newMessageSizes applies options
+func newMessageSizes(collector metrics.MetricsCollector, opts []MetricsOption) *messageSizes {
+	config := &metricsConfig{
+		sizes:        true,
+		methodLimits: make(map[string]MessageSizeLimits),
+	}
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	m := &messageSizes{collector: collector, config: config}
+	if len(config.methodLimits) > 0 {
+		m.limits = newMethodMatcher(config.methodLimits)
+	}
+	return m
+}
+
+// record records the size of a proto message; other messages are ignored
+func (m *messageSizes) record(ctx context.Context, method, direction string, msg interface{}) {
+	if !m.config.sizes {
+		return
+	}
+	pm, ok := msg.(proto.Message)
+	if !ok || pm == nil {
+		return
+	}
+
+	size := proto.Size(pm)
+	m.collector.RecordMessageSize(method, direction, size)
+
+	if m.config.alert == nil {
+		return
+	}
+	limits := m.config.limits
+	if m.limits != nil {
+		if override, ok := m.limits.match(method); ok {
+			limits = override
+		}
+	}
+	limit := limits.Request
+	if direction == metrics.DirectionSent {
+		limit = limits.Response
+	}
+	if limit > 0 && size > limit {
+		m.config.alert(ctx, method, direction, size, limit)
+	}
+}
+
+// MetricsMiddleware creates a middleware that collects metrics, including the sizes of
+// proto requests and responses
+//
+// Example usage:
+//
+//	chain := guardian.NewChain(middleware.MetricsMiddleware(collector,
+//	    middleware.WithMessageSizeAlert(middleware.MessageSizeLimits{Response: 1 << 20},
+//	        func(ctx context.Context, method, direction string, size, limit int) {
+//	            log.Printf("%s: %s message of %d bytes exceeds %d", method, direction, size, limit)
+//	        }),
+//	))
+func MetricsMiddleware(collector metrics.MetricsCollector, opts ...MetricsOption) guardian.Middleware {
+	sizes := newMessageSizes(collector, opts)
+
 	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
 		method := info.FullMethod
 		start := time.Now()
@@ -21,8 +139,13 @@ func MetricsMiddleware(collector metrics.MetricsCollector) guardian.Middleware {
 		collector.RecordActiveRequests(method, 1)
 		defer collector.RecordActiveRequests(method, -1)
 
+		sizes.record(ctx, method, metrics.DirectionReceived, req)
+
 		// Call the handler
 		resp, err := handler(ctx, req)
+		if err == nil {
+			sizes.record(ctx, method, metrics.DirectionSent, resp)
+		}
 
 		// Record duration and status
 		duration := time.Since(start)
@@ -37,24 +160,51 @@ func MetricsMiddleware(collector metrics.MetricsCollector) guardian.Middleware {
 			collector.RecordError(method, code.String())
 		}
 
-		collector.RecordRequest(method, code.String(), duration)
+		recordRequest(ctx, collector, method, code.String(), duration)
 
 		return resp, err
 	}
 }
 
-// Metrics creates a metrics middleware with a new Prometheus collector
+// recordRequest records a finished request, attaching the trace ID of a sampled span as an
+// exemplar when the collector supports it so dashboards can jump from latency to the trace
+func recordRequest(ctx context.Context, collector metrics.MetricsCollector, method, code string, duration time.Duration) {
+	if ec, ok := collector.(metrics.ExemplarMetricsCollector); ok {
+		if sc := trace.SpanContextFromContext(ctx); sc.IsValid() && sc.IsSampled() {
+			ec.RecordRequestWithTraceID(method, code, duration, sc.TraceID().String())
+			return
+		}
+	}
+	collector.RecordRequest(method, code, duration)
+}
+
+// Metrics creates a metrics middleware with a new Prometheus collector.
+// It panics if the collector cannot be created; use MetricsE to handle the error.
 func Metrics(opts ...metrics.ConfigOption) guardian.Middleware {
-	collector, err := metrics.NewPrometheusCollector(opts...)
+	middleware, err := MetricsE(opts...)
 	if err != nil {
 		panic(err) // Should not happen with valid options
 	}
 
-	return MetricsMiddleware(collector)
+	return middleware
+}
+
+// MetricsE creates a metrics middleware with a new Prometheus collector, returning
+// an error instead of panicking when the collector cannot be created
+func MetricsE(opts ...metrics.ConfigOption) (guardian.Middleware, error) {
+	collector, err := metrics.NewPrometheusCollector(opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create metrics collector: %w", err)
+	}
+
+	return MetricsMiddleware(collector), nil
 }
 
-// StreamMetricsMiddleware creates a streaming middleware that collects metrics
-func StreamMetricsMiddleware(collector metrics.MetricsCollector) guardian.StreamMiddleware {
+// StreamMetricsMiddleware creates a streaming middleware that collects metrics, including
+// the size of every message received and sent
+func StreamMetricsMiddleware(collector metrics.MetricsCollector, opts ...MetricsOption) guardian.StreamMiddleware {
+	sizes := newMessageSizes(collector, opts)
+
 	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
 		method := info.FullMethod
 		start := time.Now()
@@ -64,7 +214,11 @@ func StreamMetricsMiddleware(collector metrics.MetricsCollector) guardian.Stream
 		defer collector.RecordActiveRequests(method, -1)
 
 		// Call the handler
-		err := handler(srv, ss)
+		stream := ss
+		if sizes.config.sizes {
+			stream = &sizeRecordingStream{ServerStream: ss, sizes: sizes, method: method}
+		}
+		err := handler(srv, stream)
 
 		// Record duration and status
 		duration := time.Since(start)
@@ -79,7 +233,7 @@ func StreamMetricsMiddleware(collector metrics.MetricsCollector) guardian.Stream
 			collector.RecordError(method, code.String())
 		}
 
-		collector.RecordRequest(method, code.String(), duration)
+		recordRequest(ss.Context(), collector, method, code.String(), duration)
 
 		return err
 	}
@@ -94,3 +248,95 @@ func StreamMetrics(opts ...metrics.ConfigOption) guardian.StreamMiddleware {
 
 	return StreamMetricsMiddleware(collector)
 }
+
+// MetricsUnaryClientInterceptor creates a client interceptor recording outgoing calls:
+// count, latency, errors and in-flight calls. Use a collector with the "client" subsystem
+// so client and server metrics stay apart.
+//
+// Example usage:
+//
+//	collector, _ := metrics.NewPrometheusCollector(metrics.WithSubsystem("client"))
+//	conn, err := grpc.Dial(target, grpc.WithChainUnaryInterceptor(middleware.MetricsUnaryClientInterceptor(collector)))
+func MetricsUnaryClientInterceptor(collector metrics.MetricsCollector) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		start := time.Now()
+		collector.RecordActiveRequests(method, 1)
+		defer collector.RecordActiveRequests(method, -1)
+
+		err := invoker(ctx, method, req, reply, cc, opts...)
+
+		code := status.Code(err)
+		if err != nil {
+			collector.RecordError(method, code.String())
+		}
+		recordRequest(ctx, collector, method, code.String(), time.Since(start))
+		return err
+	}
+}
+
+// MetricsStreamClientInterceptor creates a client interceptor recording outgoing streams.
+// A stream is recorded when it ends: when RecvMsg returns an error, including io.EOF.
+func MetricsStreamClientInterceptor(collector metrics.MetricsCollector) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		start := time.Now()
+		collector.RecordActiveRequests(method, 1)
+
+		stream, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			collector.RecordActiveRequests(method, -1)
+			collector.RecordError(method, status.Code(err).String())
+			recordRequest(ctx, collector, method, status.Code(err).String(), time.Since(start))
+			return nil, err
+		}
+
+		return &metricsClientStream{ClientStream: stream, collector: collector, method: method, start: start}, nil
+	}
+}
+
+// metricsClientStream records a client stream once it ends
+type metricsClientStream struct {
+	grpc.ClientStream
+	collector metrics.MetricsCollector
+	method    string
+	start     time.Time
+	once      sync.Once
+}
+
+// RecvMsg receives a message and records the stream when it ends
+func (s *metricsClientStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err != nil {
+		s.once.Do(func() {
+			code := codes.OK
+			if err != io.EOF {
+				code = status.Code(err)
+				s.collector.RecordError(s.method, code.String())
+			}
+			s.collector.RecordActiveRequests(s.method, -1)
+			recordRequest(s.Context(), s.collector, s.method, code.String(), time.Since(s.start))
+		})
+	}
+	return err
+}
+
+// sizeRecordingStream records the size of each message of a stream
+type sizeRecordingStream struct {
+	grpc.ServerStream
+	sizes  *messageSizes
+	method string
+}
+
+// RecvMsg receives a message and records its size
+func (s *sizeRecordingStream) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	s.sizes.record(s.Context(), s.method, metrics.DirectionReceived, m)
+	return nil
+}
+
+// SendMsg records the size of a message and sends it
+func (s *sizeRecordingStream) SendMsg(m interface{}) error {
+	s.sizes.record(s.Context(), s.method, metrics.DirectionSent, m)
+	return s.ServerStream.SendMsg(m)
+}
@@ -0,0 +1,162 @@
+package middleware
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/grpc-guardian/grpc-guardian/pkg/metrics"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// recordingMeter sums measurements per instrument name and attribute set
+type recordingMeter struct {
+	noop.Meter
+
+	mu      sync.Mutex
+	values  map[string]float64
+	buckets map[string][]float64
+}
+
+func newRecordingMeter() *recordingMeter {
+	return &recordingMeter{values: make(map[string]float64), buckets: make(map[string][]float64)}
+}
+
+func (m *recordingMeter) add(name string, attrs attribute.Set, v float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.values[name+"{"+attrs.Encoded(attribute.DefaultEncoder())+"}"] += v
+}
+
+func (m *recordingMeter) value(key string) float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.values[key]
+}
+
+func (m *recordingMeter) Int64Counter(name string, _ ...metric.Int64CounterOption) (metric.Int64Counter, error) {
+	return &recordingInt64Counter{meter: m, name: name}, nil
+}
+
+func (m *recordingMeter) Int64UpDownCounter(name string, _ ...metric.Int64UpDownCounterOption) (metric.Int64UpDownCounter, error) {
+	return &recordingInt64UpDownCounter{meter: m, name: name}, nil
+}
+
+func (m *recordingMeter) Int64Histogram(name string, _ ...metric.Int64HistogramOption) (metric.Int64Histogram, error) {
+	return &recordingInt64Histogram{meter: m, name: name}, nil
+}
+
+func (m *recordingMeter) Float64Histogram(name string, opts ...metric.Float64HistogramOption) (metric.Float64Histogram, error) {
+	m.buckets[name] = metric.NewFloat64HistogramConfig(opts...).ExplicitBucketBoundaries()
+	return &recordingFloat64Histogram{meter: m, name: name}, nil
+}
+
+type recordingInt64Counter struct {
+	noop.Int64Counter
+	meter *recordingMeter
+	name  string
+}
+
+func (c *recordingInt64Counter) Add(_ context.Context, v int64, opts ...metric.AddOption) {
+	c.meter.add(c.name, metric.NewAddConfig(opts).Attributes(), float64(v))
+}
+
+type recordingInt64UpDownCounter struct {
+	noop.Int64UpDownCounter
+	meter *recordingMeter
+	name  string
+}
+
+func (c *recordingInt64UpDownCounter) Add(_ context.Context, v int64, opts ...metric.AddOption) {
+	c.meter.add(c.name, metric.NewAddConfig(opts).Attributes(), float64(v))
+}
+
+type recordingInt64Histogram struct {
+	noop.Int64Histogram
+	meter *recordingMeter
+	name  string
+}
+
+func (h *recordingInt64Histogram) Record(_ context.Context, v int64, opts ...metric.RecordOption) {
+	h.meter.add(h.name, metric.NewRecordConfig(opts).Attributes(), float64(v))
+}
+
+type recordingFloat64Histogram struct {
+	noop.Float64Histogram
+	meter *recordingMeter
+	name  string
+}
+
+func (h *recordingFloat64Histogram) Record(_ context.Context, v float64, opts ...metric.RecordOption) {
+	h.meter.add(h.name+"_count", metric.NewRecordConfig(opts).Attributes(), 1)
+}
+
+// recordingMeterProvider hands out a single recording meter
+type recordingMeterProvider struct {
+	noop.MeterProvider
+	meter *recordingMeter
+}
+
+func (p *recordingMeterProvider) Meter(string, ...metric.MeterOption) metric.Meter {
+	return p.meter
+}
+
+func TestOTelCollector(t *testing.T) {
+	meter := newRecordingMeter()
+	collector, err := metrics.NewOTelCollector(&recordingMeterProvider{meter: meter},
+		metrics.WithConstLabels(map[string]string{"service": "orders"}))
+	if err != nil {
+		t.Fatalf("NewOTelCollector() error = %v", err)
+	}
+
+	middleware := MetricsMiddleware(collector)
+	info := &grpc.UnaryServerInfo{FullMethod: "/test.Service/Method"}
+
+	middleware(context.Background(), "req", info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "ok", nil
+	})
+	middleware(context.Background(), "req", info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, status.Error(codes.NotFound, "missing")
+	})
+	collector.RecordMessageSize("/test.Service/Method", "sent", 128)
+
+	tests := []struct {
+		key  string
+		want float64
+	}{
+		{"grpc.server.requests{code=OK,method=/test.Service/Method,service=orders}", 1},
+		{"grpc.server.requests{code=NotFound,method=/test.Service/Method,service=orders}", 1},
+		{"grpc.server.request.duration_count{code=OK,method=/test.Service/Method,service=orders}", 1},
+		{"grpc.server.errors{error_type=NotFound,method=/test.Service/Method,service=orders}", 1},
+		{"grpc.server.active_requests{method=/test.Service/Method,service=orders}", 0},
+		{"grpc.server.message.size{direction=sent,method=/test.Service/Method,service=orders}", 128},
+	}
+	for _, tt := range tests {
+		if got := meter.value(tt.key); got != tt.want {
+			t.Errorf("%s = %v, want %v", tt.key, got, tt.want)
+		}
+	}
+
+	if got := meter.buckets["grpc.server.request.duration"]; len(got) != len(metrics.DefaultConfig().HistogramBuckets) {
+		t.Errorf("Expected the configured histogram buckets, got %v", got)
+	}
+}
+
+func TestOTelCollector_WithoutPerMethodMetrics(t *testing.T) {
+	meter := newRecordingMeter()
+	collector, err := metrics.NewOTelCollector(&recordingMeterProvider{meter: meter},
+		metrics.WithNamespace("guardian"), metrics.WithSubsystem(""), metrics.WithoutPerMethodMetrics())
+	if err != nil {
+		t.Fatalf("NewOTelCollector() error = %v", err)
+	}
+
+	collector.RecordRequest("/test.Service/Method", "OK", 0)
+	if got := meter.value("guardian.requests{code=OK}"); got != 1 {
+		t.Errorf("Expected a request without the method attribute, got %v (recorded: %v)", got, meter.values)
+	}
+}
@@ -3,15 +3,20 @@ package middleware
 import (
 	"context"
 	"errors"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/grpc-guardian/grpc-guardian/pkg/metrics"
 	"github.com/prometheus/client_golang/prometheus"
 	dto "github.com/prometheus/client_model/go"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/stats"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
 )
 
 func TestMetricsMiddleware(t *testing.T) {
@@ -285,3 +290,227 @@ func getMetricValue(registry *prometheus.Registry, name string) (float64, error)
 
 	return 0, errors.New("metric not found")
 }
+
+// countingCollector counts requests, standing in for a non-Prometheus backend such as StatsD
+type countingCollector struct {
+	metrics.NoopCollector
+	requests int
+	errors   int
+}
+
+func (c *countingCollector) RecordRequest(method string, code string, duration time.Duration) {
+	c.requests++
+}
+
+func (c *countingCollector) RecordError(method string, errorType string) {
+	c.errors++
+}
+
+func TestMultiCollector(t *testing.T) {
+	prom, err := metrics.NewPrometheusCollector()
+	if err != nil {
+		t.Fatalf("Failed to create metrics collector: %v", err)
+	}
+	statsd := &countingCollector{}
+
+	collector := metrics.Multi(statsd, nil, prom)
+	if collector.GetRegistry() != prom.GetRegistry() {
+		t.Error("Expected the Prometheus registry to be exposed")
+	}
+
+	middleware := MetricsMiddleware(collector)
+	info := &grpc.UnaryServerInfo{FullMethod: "/test.Service/Method"}
+
+	middleware(context.Background(), "req", info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "ok", nil
+	})
+	middleware(context.Background(), "req", info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, status.Error(codes.NotFound, "missing")
+	})
+
+	if statsd.requests != 2 || statsd.errors != 1 {
+		t.Errorf("Expected 2 requests and 1 error, got %d and %d", statsd.requests, statsd.errors)
+	}
+
+	total, err := getMetricValue(prom.GetRegistry(), "grpc_server_errors_total")
+	if err != nil || total != 1 {
+		t.Errorf("Expected Prometheus errors_total 1, got %v (%v)", total, err)
+	}
+}
+
+func BenchmarkMetricsMiddleware_Noop(b *testing.B) {
+	middleware := MetricsMiddleware(metrics.Noop())
+	info := &grpc.UnaryServerInfo{FullMethod: "/test.Service/Method"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) { return "ok", nil }
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		middleware(context.Background(), "req", info, handler)
+	}
+}
+
+func BenchmarkMetricsMiddleware_Prometheus(b *testing.B) {
+	collector, err := metrics.NewPrometheusCollector()
+	if err != nil {
+		b.Fatalf("Failed to create metrics collector: %v", err)
+	}
+	middleware := MetricsMiddleware(collector)
+	info := &grpc.UnaryServerInfo{FullMethod: "/test.Service/Method"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) { return "ok", nil }
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		middleware(context.Background(), "req", info, handler)
+	}
+}
+
+func TestMetricsMiddleware_Exemplars(t *testing.T) {
+	collector, err := metrics.NewPrometheusCollector()
+	if err != nil {
+		t.Fatalf("Failed to create metrics collector: %v", err)
+	}
+
+	middleware := MetricsMiddleware(collector)
+	info := &grpc.UnaryServerInfo{FullMethod: "/test.Service/Method"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) { return "ok", nil }
+
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	sampled := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+	}))
+	unsampled := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: traceID,
+		SpanID:  spanID,
+	}))
+
+	middleware(unsampled, "req", info, handler)
+	if got := histogramExemplars(t, collector.GetRegistry()); len(got) != 0 {
+		t.Fatalf("Expected no exemplars for unsampled spans, got %v", got)
+	}
+
+	middleware(sampled, "req", info, handler)
+	got := histogramExemplars(t, collector.GetRegistry())
+	if len(got) != 1 || got[0] != traceID.String() {
+		t.Errorf("Expected exemplar with trace ID %s, got %v", traceID, got)
+	}
+}
+
+// histogramExemplars returns the trace IDs of all exemplars on the duration histogram
+func histogramExemplars(t *testing.T, registry *prometheus.Registry) []string {
+	t.Helper()
+
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("Failed to gather metrics: %v", err)
+	}
+
+	var traceIDs []string
+	for _, family := range families {
+		if family.GetName() != "grpc_server_request_duration_seconds" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			for _, bucket := range metric.GetHistogram().GetBucket() {
+				for _, label := range bucket.GetExemplar().GetLabel() {
+					if label.GetName() == "trace_id" {
+						traceIDs = append(traceIDs, label.GetValue())
+					}
+				}
+			}
+		}
+	}
+	return traceIDs
+}
+
+func TestMetricsMiddleware_MessageSizes(t *testing.T) {
+	collector, err := metrics.NewPrometheusCollector()
+	if err != nil {
+		t.Fatalf("Failed to create metrics collector: %v", err)
+	}
+
+	type alert struct {
+		method, direction string
+		size, limit       int
+	}
+	var alerts []alert
+	middleware := MetricsMiddleware(collector,
+		WithMessageSizeAlert(MessageSizeLimits{Response: 1024}, func(ctx context.Context, method, direction string, size, limit int) {
+			alerts = append(alerts, alert{method, direction, size, limit})
+		}),
+		WithMethodMessageSizeLimits("/test.Service/Upload", MessageSizeLimits{Request: 10}),
+	)
+
+	req := wrapperspb.String(strings.Repeat("x", 100))
+	resp := wrapperspb.Bytes(make([]byte, 2000))
+	middleware(context.Background(), req, &grpc.UnaryServerInfo{FullMethod: "/test.Service/Get"},
+		func(ctx context.Context, req interface{}) (interface{}, error) { return resp, nil })
+	middleware(context.Background(), req, &grpc.UnaryServerInfo{FullMethod: "/test.Service/Upload"},
+		func(ctx context.Context, req interface{}) (interface{}, error) { return resp, nil })
+
+	if got := histogramSum(t, collector.GetRegistry(), "grpc_server_message_received_bytes"); got != float64(2*proto.Size(req)) {
+		t.Errorf("Expected received bytes %d, got %v", 2*proto.Size(req), got)
+	}
+	if got := histogramSum(t, collector.GetRegistry(), "grpc_server_message_sent_bytes"); got != float64(2*proto.Size(resp)) {
+		t.Errorf("Expected sent bytes %d, got %v", 2*proto.Size(resp), got)
+	}
+
+	want := []alert{
+		{"/test.Service/Get", metrics.DirectionSent, proto.Size(resp), 1024},
+		{"/test.Service/Upload", metrics.DirectionReceived, proto.Size(req), 10},
+	}
+	if len(alerts) != len(want) {
+		t.Fatalf("Expected alerts %v, got %v", want, alerts)
+	}
+	for i := range want {
+		if alerts[i] != want[i] {
+			t.Errorf("Alert %d = %v, want %v", i, alerts[i], want[i])
+		}
+	}
+}
+
+func TestSizeStatsHandler(t *testing.T) {
+	collector, err := metrics.NewPrometheusCollector()
+	if err != nil {
+		t.Fatalf("Failed to create metrics collector: %v", err)
+	}
+
+	handler := metrics.NewSizeStatsHandler(collector, metrics.WithUncompressedSizes())
+	ctx := handler.TagRPC(context.Background(), &stats.RPCTagInfo{FullMethodName: "/test.Service/Get"})
+	handler.HandleRPC(ctx, &stats.InPayload{Length: 50, WireLength: 55})
+	handler.HandleRPC(ctx, &stats.OutPayload{Length: 4000, WireLength: 300})
+
+	tests := map[string]float64{
+		"grpc_server_message_received_wire_bytes": 55,
+		"grpc_server_message_sent_wire_bytes":     300,
+		"grpc_server_message_sent_bytes":          4000,
+	}
+	for name, want := range tests {
+		if got := histogramSum(t, collector.GetRegistry(), name); got != want {
+			t.Errorf("%s = %v, want %v", name, got, want)
+		}
+	}
+}
+
+// histogramSum returns the sum of all observations of a histogram
+func histogramSum(t *testing.T, registry *prometheus.Registry, name string) float64 {
+	t.Helper()
+
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("Failed to gather metrics: %v", err)
+	}
+
+	var sum float64
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			sum += metric.GetHistogram().GetSampleSum()
+		}
+	}
+	return sum
+}
@@ -0,0 +1,198 @@
+package middleware
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/grpc-guardian/grpc-guardian/pkg/metrics"
+	"github.com/grpc-guardian/grpc-guardian/pkg/randutil"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// MirroredHeader is set to "true" on calls duplicated by Mirror, so the secondary backend
+// can tell mirrored traffic apart and skip side effects
+const MirroredHeader = "x-mirrored"
+
+// Mirror duplicates outgoing unary calls to a second connection, e.g. a staging
+// environment, so it receives realistic production traffic. Mirrored calls are
+// fire-and-forget: they run in the background with bounded concurrency, their responses
+// are discarded and their failures only show in metrics. The primary call is never
+// delayed or failed by the mirror.
+type Mirror struct {
+	conn      grpc.ClientConnInterface
+	percent   float64
+	timeout   time.Duration
+	filter    func(method string) bool
+	inFlight  chan struct{}
+	collector *metrics.MirrorCollector
+	rng       *randutil.Rand
+
+	wg sync.WaitGroup
+}
+
+// MirrorOption configures a Mirror
+type MirrorOption func(*Mirror)
+
+// WithMirrorPercentage sets the percentage (0-100) of calls that are mirrored
+// Default: 100
+func WithMirrorPercentage(percent float64) MirrorOption {
+	return func(m *Mirror) {
+		m.percent = percent
+	}
+}
+
+// WithMirrorTimeout bounds each mirrored call
+// Default: 5s
+func WithMirrorTimeout(timeout time.Duration) MirrorOption {
+	return func(m *Mirror) {
+		if timeout > 0 {
+			m.timeout = timeout
+		}
+	}
+}
+
+// WithMirrorMaxInFlight caps the number of concurrent mirrored calls; calls beyond it are
+// not mirrored, so a slow secondary backend can't pile up goroutines
+// Default: 100
+func WithMirrorMaxInFlight(n int) MirrorOption {
+	return func(m *Mirror) {
+		if n > 0 {
+			m.inFlight = make(chan struct{}, n)
+		}
+	}
+}
+
+// WithMirrorFilter only mirrors the methods the function accepts, e.g. a
+// classify.Classifier's IsRead
+// Default: all methods
+func WithMirrorFilter(filter func(method string) bool) MirrorOption {
+	return func(m *Mirror) {
+		m.filter = filter
+	}
+}
+
+// WithMirrorMetrics records mirrored and dropped calls
+func WithMirrorMetrics(collector *metrics.MirrorCollector) MirrorOption {
+	return func(m *Mirror) {
+		m.collector = collector
+	}
+}
+
+// WithMirrorSeed seeds the choice of mirrored calls, so the same seed and sequence of calls
+// mirror the same calls, e.g. for reproducible tests
+// Default: seeded from the clock
+func WithMirrorSeed(seed int64) MirrorOption {
+	return func(m *Mirror) {
+		m.rng = randutil.New(seed)
+	}
+}
+
+// NewMirror creates a client interceptor mirroring calls to conn. The mirror connection
+// must not use the Mirror interceptor itself.
+//
+// Example usage:
+//
+//	staging, _ := grpc.Dial("orders.staging:9090", grpc.WithTransportCredentials(creds))
+//	collector, _ := metrics.NewMirrorCollector(registry)
+//	mirror := middleware.NewMirror(staging,
+//	    middleware.WithMirrorPercentage(10),
+//	    middleware.WithMirrorFilter(classifier.IsRead),
+//	    middleware.WithMirrorMetrics(collector),
+//	)
+//	defer mirror.Wait()
+//
+//	chain := guardian.NewClientChain(mirror.UnaryClientInterceptor())
+//	conn, err := grpc.Dial("orders:9090", append(chain.DialOptions(),
+//	    grpc.WithTransportCredentials(creds))...)
+func NewMirror(conn grpc.ClientConnInterface, opts ...MirrorOption) *Mirror {
+	m := &Mirror{
+		conn:     conn,
+		percent:  100,
+		timeout:  5 * time.Second,
+		inFlight: make(chan struct{}, 100),
+	}
+
+	for _, opt := range opts {
+		opt(m)
+	}
+	if m.rng == nil {
+		m.rng = randutil.NewTimeSeeded()
+	}
+
+	return m
+}
+
+// UnaryClientInterceptor returns a unary client interceptor that mirrors sampled calls.
+// Streams are not mirrored.
+func (m *Mirror) UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		m.dispatch(ctx, method, req)
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// Wait blocks until all in-flight mirrored calls are done, e.g. before shutting down
+func (m *Mirror) Wait() {
+	m.wg.Wait()
+}
+
+// sampled makes the per-call mirroring decision
+func (m *Mirror) sampled(method string) bool {
+	if m.percent <= 0 || (m.filter != nil && !m.filter(method)) {
+		return false
+	}
+	return m.rng.Float64()*100 < m.percent
+}
+
+// dispatch starts a mirrored call for sampled requests
+func (m *Mirror) dispatch(ctx context.Context, method string, req interface{}) {
+	if !m.sampled(method) {
+		return
+	}
+
+	// The caller may reuse the request once the primary call returns
+	msg, ok := req.(proto.Message)
+	if !ok {
+		m.drop("marshal")
+		return
+	}
+	msg = proto.Clone(msg)
+
+	select {
+	case m.inFlight <- struct{}{}:
+	default:
+		m.drop("max_in_flight")
+		return
+	}
+
+	md, _ := metadata.FromOutgoingContext(ctx)
+	md = md.Copy()
+	md.Set(MirroredHeader, "true")
+	// Detached from the primary call's cancellation and deadline
+	mirrorCtx := metadata.NewOutgoingContext(context.WithoutCancel(ctx), md)
+
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		defer func() { <-m.inFlight }()
+
+		ctx, cancel := context.WithTimeout(mirrorCtx, m.timeout)
+		defer cancel()
+
+		var reply discardReply
+		err := m.conn.Invoke(ctx, method, msg, &reply, grpc.ForceCodec(discardCodec{}))
+		if m.collector != nil {
+			m.collector.RecordRequest(method, status.Code(err).String())
+		}
+	}()
+}
+
+func (m *Mirror) drop(reason string) {
+	if m.collector != nil {
+		m.collector.RecordDropped(reason)
+	}
+}
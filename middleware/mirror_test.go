@@ -0,0 +1,139 @@
+package middleware
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/grpc-guardian/grpc-guardian/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+func TestMirror(t *testing.T) {
+	primary := dialQuotaServer(t, func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if md, _ := metadata.FromIncomingContext(ctx); len(md.Get(MirroredHeader)) > 0 {
+			t.Error("Expected the primary call not to be tagged")
+		}
+		return handler(ctx, req)
+	})
+
+	var mu sync.Mutex
+	var mirrored []metadata.MD
+	staging := dialQuotaServer(t, func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		mu.Lock()
+		mirrored = append(mirrored, md)
+		mu.Unlock()
+		if len(md.Get("x-fail")) > 0 {
+			return nil, status.Error(codes.Unavailable, "staging is down")
+		}
+		return handler(ctx, req)
+	})
+
+	registry := prometheus.NewRegistry()
+	collector, err := metrics.NewMirrorCollector(registry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mirror := NewMirror(staging, WithMirrorMetrics(collector))
+	interceptor := mirror.UnaryClientInterceptor()
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		return primary.Invoke(ctx, method, req, reply, opts...)
+	}
+	call := func(md metadata.MD) error {
+		ctx, cancel := context.WithCancel(metadata.NewOutgoingContext(context.Background(), md))
+		defer cancel() // The mirrored call outlives the primary one
+		return interceptor(ctx, "/quota.Test/Call", &emptypb.Empty{}, &emptypb.Empty{}, nil, invoker)
+	}
+
+	if err := call(metadata.Pairs("x-tenant-id", "acme")); err != nil {
+		t.Fatalf("Unexpected primary error: %v", err)
+	}
+	if err := call(metadata.Pairs("x-fail", "1")); err != nil {
+		t.Fatalf("Mirror failures must not affect the primary call, got %v", err)
+	}
+	mirror.Wait()
+
+	mu.Lock()
+	if len(mirrored) != 2 {
+		t.Fatalf("Expected 2 mirrored calls, got %d", len(mirrored))
+	}
+	tenants := 0
+	for _, md := range mirrored {
+		if got := md.Get(MirroredHeader); len(got) != 1 || got[0] != "true" {
+			t.Errorf("Expected the mirrored call to be tagged, got %v", md)
+		}
+		if got := md.Get("x-tenant-id"); len(got) == 1 && got[0] == "acme" {
+			tenants++
+		}
+	}
+	if tenants != 1 {
+		t.Errorf("Expected the outgoing metadata to be copied, got %v", mirrored)
+	}
+	mu.Unlock()
+
+	expected := `
+# HELP grpc_mirror_requests_total Total number of mirrored calls by method and status code
+# TYPE grpc_mirror_requests_total counter
+grpc_mirror_requests_total{code="OK",method="/quota.Test/Call"} 1
+grpc_mirror_requests_total{code="Unavailable",method="/quota.Test/Call"} 1
+`
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(expected), "grpc_mirror_requests_total"); err != nil {
+		t.Errorf("Unexpected mirror metrics: %v", err)
+	}
+}
+
+func TestMirror_Sampling(t *testing.T) {
+	var calls int
+	var mu sync.Mutex
+	release := make(chan struct{})
+	staging := dialQuotaServer(t, func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		mu.Lock()
+		calls++
+		mu.Unlock()
+		<-release
+		return handler(ctx, req)
+	})
+
+	registry := prometheus.NewRegistry()
+	collector, err := metrics.NewMirrorCollector(registry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ok := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		return nil
+	}
+
+	filtered := NewMirror(staging, WithMirrorFilter(func(method string) bool { return false }))
+	none := NewMirror(staging, WithMirrorPercentage(0))
+	bounded := NewMirror(staging, WithMirrorMaxInFlight(1), WithMirrorMetrics(collector))
+
+	for _, mirror := range []*Mirror{filtered, none, bounded, bounded, bounded} {
+		if err := mirror.UnaryClientInterceptor()(context.Background(), "/quota.Test/Call", &emptypb.Empty{}, &emptypb.Empty{}, nil, ok); err != nil {
+			t.Fatal(err)
+		}
+	}
+	close(release)
+	bounded.Wait()
+
+	mu.Lock()
+	if calls != 1 {
+		t.Errorf("Expected 1 mirrored call, got %d", calls)
+	}
+	mu.Unlock()
+	expected := `
+# HELP grpc_mirror_dropped_total Total number of sampled calls that were not mirrored
+# TYPE grpc_mirror_dropped_total counter
+grpc_mirror_dropped_total{reason="max_in_flight"} 2
+`
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(expected), "grpc_mirror_dropped_total"); err != nil {
+		t.Errorf("Unexpected mirror metrics: %v", err)
+	}
+}
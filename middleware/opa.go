@@ -0,0 +1,299 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/grpc-guardian/grpc-guardian/pkg/cache"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// OPAInput is the document sent to Open Policy Agent as `input`
+type OPAInput struct {
+	Method  string              `json:"method"`
+	Service string              `json:"service"`
+	RPC     string              `json:"rpc"`
+	Headers map[string][]string `json:"headers,omitempty"`
+	User    OPAUser             `json:"user"`
+	Peer    OPAPeer             `json:"peer"`
+}
+
+// OPAUser holds the authenticated caller claims
+type OPAUser struct {
+	ID       string   `json:"id,omitempty"`
+	ClientID string   `json:"client_id,omitempty"`
+	Roles    []string `json:"roles,omitempty"`
+	Scopes   []string `json:"scopes,omitempty"`
+}
+
+// OPAPeer holds connection information about the caller
+type OPAPeer struct {
+	Address string `json:"address,omitempty"`
+	Subject string `json:"subject,omitempty"`
+}
+
+// OPAEngine evaluates a policy in-process, e.g. an embedded Rego engine
+// (rego.PreparedEvalQuery wrapped in a small adapter)
+type OPAEngine interface {
+	Eval(ctx context.Context, input *OPAInput) (allowed bool, err error)
+}
+
+// OPAConfig holds the configuration for Open Policy Agent authorization
+type OPAConfig struct {
+	// URL is the base URL of the OPA server (e.g. "http://localhost:8181")
+	// Ignored when Engine is set
+	URL string
+
+	// Path is the decision path queried through the Data API (e.g. "grpc/authz/allow").
+	// The decision may be a boolean or an object with an "allow" field.
+	Path string
+
+	// Engine evaluates policies in-process instead of calling an OPA server
+	Engine OPAEngine
+
+	// HTTPClient is the HTTP client to use for OPA requests
+	// If nil, http.DefaultClient will be used
+	HTTPClient *http.Client
+
+	// Timeout is the timeout for a single policy decision
+	// Default: 1 second
+	Timeout time.Duration
+
+	// Headers limits which request headers are sent to OPA. When empty, all headers are
+	// sent except credentials (authorization, cookie, x-api-key).
+	// Restricting headers improves the decision cache hit rate.
+	Headers []string
+
+	// CacheTTL is how long decisions are cached for identical inputs (0 disables caching)
+	// Default: 0
+	CacheTTL time.Duration
+
+	// CacheBackend stores cached decisions
+	// If nil and CacheTTL is set, an in-memory backend with 10000 entries is used
+	CacheBackend cache.Backend
+
+	// FailOpen allows requests when OPA cannot be reached or returns an error.
+	// Default: false (fail closed with codes.Unavailable)
+	FailOpen bool
+
+	// OnError is called for every evaluation error, including ones hidden by FailOpen
+	OnError func(error)
+}
+
+// opaSensitiveHeaders are never forwarded to OPA unless explicitly listed in OPAConfig.Headers
+var opaSensitiveHeaders = map[string]bool{
+	"authorization": true,
+	"cookie":        true,
+	"x-api-key":     true,
+}
+
+// OPAEvaluator is a PolicyEvaluator backed by Open Policy Agent.
+// It can be used on its own through OPA or combined with static rules in Authorization.
+type OPAEvaluator struct {
+	config   OPAConfig
+	endpoint string
+}
+
+// NewOPAEvaluator creates a PolicyEvaluator that queries Open Policy Agent
+func NewOPAEvaluator(config OPAConfig) *OPAEvaluator {
+	if config.HTTPClient == nil {
+		config.HTTPClient = http.DefaultClient
+	}
+	if config.Timeout == 0 {
+		config.Timeout = 1 * time.Second
+	}
+	if config.CacheTTL > 0 && config.CacheBackend == nil {
+		config.CacheBackend = cache.NewMemoryBackend(&cache.MemoryConfig{
+			MaxSize:         10000,
+			CleanupInterval: time.Minute,
+		})
+	}
+
+	return &OPAEvaluator{
+		config:   config,
+		endpoint: strings.TrimSuffix(config.URL, "/") + "/v1/data/" + strings.Trim(config.Path, "/"),
+	}
+}
+
+// OPA creates middleware that enforces allow/deny decisions from Open Policy Agent
+//
+// Example usage:
+//
+//	chain := guardian.NewChain(
+//	    middleware.Auth(middleware.JWTValidator("secret")),
+//	    middleware.OPA(middleware.OPAConfig{
+//	        URL:      "http://localhost:8181",
+//	        Path:     "grpc/authz/allow",
+//	        CacheTTL: 30 * time.Second,
+//	    }),
+//	)
+func OPA(config OPAConfig) func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	evaluator := NewOPAEvaluator(config)
+
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		allowed, err := evaluator.Evaluate(ctx, newAuthzInput(ctx, req, info.FullMethod, nil))
+		if err != nil {
+			return nil, err
+		}
+		if !allowed {
+			return nil, status.Errorf(codes.PermissionDenied, "access to %s denied by policy", info.FullMethod)
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// Evaluate asks OPA for a decision, consulting the decision cache first
+func (e *OPAEvaluator) Evaluate(ctx context.Context, input *AuthzInput) (bool, error) {
+	opaInput := e.buildInput(input)
+
+	var key string
+	if e.config.CacheBackend != nil {
+		data, err := json.Marshal(opaInput)
+		if err == nil {
+			sum := sha256.Sum256(data)
+			key = "opa:" + hex.EncodeToString(sum[:])
+			if cached, found, _ := e.config.CacheBackend.Get(ctx, key); found && len(cached) == 1 {
+				return cached[0] == 1, nil
+			}
+		}
+	}
+
+	evalCtx, cancel := context.WithTimeout(ctx, e.config.Timeout)
+	defer cancel()
+
+	var (
+		allowed bool
+		err     error
+	)
+	if e.config.Engine != nil {
+		allowed, err = e.config.Engine.Eval(evalCtx, opaInput)
+	} else {
+		allowed, err = e.query(evalCtx, opaInput)
+	}
+
+	if err != nil {
+		if e.config.OnError != nil {
+			e.config.OnError(err)
+		}
+		if e.config.FailOpen {
+			return true, nil
+		}
+		return false, status.Errorf(codes.Unavailable,
+			"authorization policy unavailable: %v\nHint: Check that OPA is reachable or enable FailOpen", err)
+	}
+
+	if key != "" {
+		value := []byte{0}
+		if allowed {
+			value[0] = 1
+		}
+		_ = e.config.CacheBackend.Set(ctx, key, value, e.config.CacheTTL)
+	}
+
+	return allowed, nil
+}
+
+// buildInput converts the authorization input into the document sent to OPA
+func (e *OPAEvaluator) buildInput(input *AuthzInput) *OPAInput {
+	opaInput := &OPAInput{
+		Method:  input.FullMethod,
+		Service: input.Service,
+		RPC:     input.Method,
+		User: OPAUser{
+			ID:       input.UserID,
+			ClientID: input.ClientID,
+			Roles:    input.Roles,
+			Scopes:   input.Scopes,
+		},
+		Peer: OPAPeer{
+			Address: input.PeerAddress,
+			Subject: input.PeerSubject,
+		},
+	}
+
+	if len(input.Metadata) == 0 {
+		return opaInput
+	}
+
+	opaInput.Headers = make(map[string][]string)
+	if len(e.config.Headers) > 0 {
+		for _, name := range e.config.Headers {
+			if values := input.Metadata.Get(name); len(values) > 0 {
+				opaInput.Headers[strings.ToLower(name)] = values
+			}
+		}
+		return opaInput
+	}
+
+	for name, values := range input.Metadata {
+		if !opaSensitiveHeaders[name] {
+			opaInput.Headers[name] = values
+		}
+	}
+	return opaInput
+}
+
+// query calls the OPA Data API
+func (e *OPAEvaluator) query(ctx context.Context, input *OPAInput) (bool, error) {
+	body, err := json.Marshal(map[string]interface{}{"input": input})
+	if err != nil {
+		return false, fmt.Errorf("failed to encode OPA input: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("failed to create OPA request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := e.config.HTTPClient.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("OPA request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return false, fmt.Errorf("OPA returned status %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	var decision struct {
+		Result json.RawMessage `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&decision); err != nil {
+		return false, fmt.Errorf("failed to decode OPA response: %w", err)
+	}
+
+	return parseOPAResult(decision.Result)
+}
+
+// parseOPAResult interprets a decision; an undefined result denies the request
+func parseOPAResult(result json.RawMessage) (bool, error) {
+	if len(result) == 0 || string(result) == "null" {
+		return false, nil
+	}
+
+	var allowed bool
+	if err := json.Unmarshal(result, &allowed); err == nil {
+		return allowed, nil
+	}
+
+	var object struct {
+		Allow *bool `json:"allow"`
+	}
+	if err := json.Unmarshal(result, &object); err != nil || object.Allow == nil {
+		return false, fmt.Errorf("unexpected OPA decision %s: expected a boolean or an object with an \"allow\" field", string(result))
+	}
+	return *object.Allow, nil
+}
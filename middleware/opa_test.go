@@ -0,0 +1,163 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func newMockOPAServer(t *testing.T, calls *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(calls, 1)
+
+		if r.URL.Path != "/v1/data/grpc/authz/allow" {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+
+		var body struct {
+			Input OPAInput `json:"input"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+
+		if _, ok := body.Input.Headers["authorization"]; ok {
+			t.Error("authorization header must not be sent to OPA")
+		}
+
+		allowed := body.Input.User.ID == "user-123" && body.Input.RPC == "Get"
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"result": map[string]interface{}{"allow": allowed},
+		})
+	}))
+}
+
+func callOPA(interceptor func(context.Context, interface{}, *grpc.UnaryServerInfo, grpc.UnaryHandler) (interface{}, error), ctx context.Context, method string) error {
+	_, err := interceptor(ctx, "req", &grpc.UnaryServerInfo{FullMethod: method},
+		func(ctx context.Context, req interface{}) (interface{}, error) { return "ok", nil })
+	return err
+}
+
+func TestOPA_Decisions(t *testing.T) {
+	var calls int32
+	server := newMockOPAServer(t, &calls)
+	defer server.Close()
+
+	interceptor := OPA(OPAConfig{
+		URL:      server.URL,
+		Path:     "grpc/authz/allow",
+		CacheTTL: time.Minute,
+	})
+
+	ctx := context.WithValue(context.Background(), contextKeyUserID, "user-123")
+	ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", "Bearer secret", "x-tenant", "acme"))
+
+	if err := callOPA(interceptor, ctx, "/shop.Orders/Get"); err != nil {
+		t.Errorf("Expected request to be allowed, got %v", err)
+	}
+	if err := callOPA(interceptor, ctx, "/shop.Orders/Delete"); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Expected PermissionDenied, got %v", err)
+	}
+
+	// Identical inputs are served from the decision cache
+	if err := callOPA(interceptor, ctx, "/shop.Orders/Get"); err != nil {
+		t.Errorf("Expected cached allow, got %v", err)
+	}
+	if got := atomic.LoadInt32(&calls); got != 2 {
+		t.Errorf("Expected 2 OPA calls, got %d", got)
+	}
+}
+
+func TestOPA_FailureModes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "boom", http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	var reported int32
+	closed := OPA(OPAConfig{
+		URL:     server.URL,
+		Path:    "grpc/authz/allow",
+		OnError: func(error) { atomic.AddInt32(&reported, 1) },
+	})
+	if err := callOPA(closed, context.Background(), "/shop.Orders/Get"); status.Code(err) != codes.Unavailable {
+		t.Errorf("Expected Unavailable when failing closed, got %v", err)
+	}
+
+	open := OPA(OPAConfig{
+		URL:      server.URL,
+		Path:     "grpc/authz/allow",
+		FailOpen: true,
+		OnError:  func(error) { atomic.AddInt32(&reported, 1) },
+	})
+	if err := callOPA(open, context.Background(), "/shop.Orders/Get"); err != nil {
+		t.Errorf("Expected request to be allowed when failing open, got %v", err)
+	}
+
+	if got := atomic.LoadInt32(&reported); got != 2 {
+		t.Errorf("Expected 2 reported errors, got %d", got)
+	}
+}
+
+type staticOPAEngine struct {
+	input *OPAInput
+}
+
+func (e *staticOPAEngine) Eval(ctx context.Context, input *OPAInput) (bool, error) {
+	e.input = input
+	return input.Headers["x-tenant"] != nil, nil
+}
+
+func TestOPA_EmbeddedEngineWithAuthorization(t *testing.T) {
+	engine := &staticOPAEngine{}
+	interceptor := Authorization(
+		WithRequiredRoles("/shop.Orders/*", "user"),
+		WithPolicyEvaluator(NewOPAEvaluator(OPAConfig{
+			Engine:  engine,
+			Headers: []string{"X-Tenant"},
+		})),
+	)
+
+	ctx := context.WithValue(context.Background(), contextKeyRoles, []string{"user"})
+	ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("x-tenant", "acme", "x-request-id", "1"))
+
+	if err := callOPA(interceptor, ctx, "/shop.Orders/Get"); err != nil {
+		t.Errorf("Expected request to be allowed, got %v", err)
+	}
+	if len(engine.input.Headers) != 1 || engine.input.User.Roles[0] != "user" {
+		t.Errorf("Unexpected engine input: %+v", engine.input)
+	}
+}
+
+func TestParseOPAResult(t *testing.T) {
+	tests := []struct {
+		result  string
+		want    bool
+		wantErr bool
+	}{
+		{`true`, true, false},
+		{`false`, false, false},
+		{`null`, false, false},
+		{`{"allow": true, "reason": "ok"}`, true, false},
+		{`{"reason": "missing"}`, false, true},
+		{`"yes"`, false, true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseOPAResult(json.RawMessage(tt.result))
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("parseOPAResult(%s) = %v, %v", tt.result, got, err)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"context"
+	"time"
+
+	"github.com/grpc-guardian/grpc-guardian/pkg/profiling"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// Outliers creates middleware that feeds every request to a profiling.Recorder, which
+// keeps the slowest requests per method with their status, trace ID and peer. Unlike
+// PerformanceLog it needs no fixed threshold: the recorder always holds the current tail.
+// The trace ID is only available when the Tracing middleware runs before Outliers.
+//
+// Example usage:
+//
+//	recorder := profiling.NewRecorder(profiling.WithTopN(20))
+//	chain := guardian.NewChain(
+//	    middleware.Tracing(),
+//	    middleware.Outliers(recorder),
+//	)
+//
+//	adminServer.AddOutliers("api", recorder)
+func Outliers(recorder *profiling.Recorder) func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		recordOutlier(ctx, recorder, info.FullMethod, time.Since(start), err)
+		return resp, err
+	}
+}
+
+// StreamOutliers records the duration of whole streams
+func StreamOutliers(recorder *profiling.Recorder) func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, ss)
+		recordOutlier(ss.Context(), recorder, info.FullMethod, time.Since(start), err)
+		return err
+	}
+}
+
+func recordOutlier(ctx context.Context, recorder *profiling.Recorder, method string, duration time.Duration, err error) {
+	sample := profiling.Sample{
+		Method:   method,
+		Duration: duration,
+		Code:     status.Code(err).String(),
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		sample.TraceID = sc.TraceID().String()
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		sample.Peer = p.Addr.String()
+	}
+	recorder.Record(sample)
+}
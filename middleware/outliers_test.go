@@ -0,0 +1,117 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/grpc-guardian/grpc-guardian/pkg/profiling"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestOutliers(t *testing.T) {
+	recorder := profiling.NewRecorder(profiling.WithTopN(2))
+	interceptor := Outliers(recorder)
+
+	traceID := trace.TraceID{1, 2, 3}
+	ctx := trace.ContextWithSpanContext(peerContext("10.0.0.1:4000"), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: traceID,
+		SpanID:  trace.SpanID{1},
+	}))
+	info := &grpc.UnaryServerInfo{FullMethod: "/shop.v1.Orders/List"}
+
+	for _, delay := range []time.Duration{0, 30 * time.Millisecond, 0, 15 * time.Millisecond} {
+		interceptor(ctx, nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			time.Sleep(delay)
+			if delay == 30*time.Millisecond {
+				return nil, status.Error(codes.Unavailable, "db timeout")
+			}
+			return nil, nil
+		})
+	}
+
+	top := recorder.Top("/shop.v1.Orders/List")
+	if len(top) != 2 || top[0].Duration < 30*time.Millisecond || top[1].Duration < 15*time.Millisecond || top[1].Duration >= 30*time.Millisecond {
+		t.Fatalf("Expected the two slowest requests, slowest first, got %+v", top)
+	}
+	if got := top[0]; got.Code != "Unavailable" || got.TraceID != traceID.String() || got.Peer != "10.0.0.1:4000" {
+		t.Errorf("Unexpected sample %+v", got)
+	}
+	if got := recorder.Snapshot(); len(got) != 1 || len(got["/shop.v1.Orders/List"]) != 2 {
+		t.Errorf("Unexpected snapshot %v", got)
+	}
+}
+
+func TestOutliers_Window(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	var mu sync.Mutex
+	clock := func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return now
+	}
+	advance := func(d time.Duration) {
+		mu.Lock()
+		now = now.Add(d)
+		mu.Unlock()
+	}
+
+	recorder := profiling.NewRecorder(profiling.WithTopN(1), profiling.WithWindow(5*time.Minute), profiling.WithClock(clock))
+	record := func(d time.Duration) {
+		recorder.Record(profiling.Sample{Method: "/m", Duration: d})
+	}
+
+	record(time.Second)
+	advance(2 * time.Minute)
+	record(500 * time.Millisecond)
+	if top := recorder.Top("/m"); len(top) != 1 || top[0].Duration != time.Second {
+		t.Fatalf("Expected the slowest request of the window, got %+v", top)
+	}
+
+	// Once the slowest request leaves the window, the next slowest shows up
+	advance(4 * time.Minute)
+	if top := recorder.Top("/m"); len(top) != 1 || top[0].Duration != 500*time.Millisecond {
+		t.Fatalf("Expected the next slowest request, got %+v", top)
+	}
+
+	advance(10 * time.Minute)
+	if top := recorder.Top("/m"); len(top) != 0 {
+		t.Errorf("Expected no requests in the window, got %+v", top)
+	}
+	record(time.Millisecond)
+	if top := recorder.Top("/m"); len(top) != 1 || top[0].Duration != time.Millisecond {
+		t.Errorf("Expected stale slots to be reused, got %+v", top)
+	}
+}
+
+func TestOutliers_Concurrent(t *testing.T) {
+	recorder := profiling.NewRecorder(profiling.WithTopN(5))
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				recorder.Record(profiling.Sample{Method: fmt.Sprintf("/m%d", i%3), Duration: time.Duration(g*1000 + i)})
+				recorder.Top("/m0")
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	top := recorder.Top("/m0")
+	if len(top) != 5 {
+		t.Fatalf("Expected 5 requests, got %+v", top)
+	}
+	for i := 1; i < len(top); i++ {
+		if top[i].Duration > top[i-1].Duration {
+			t.Errorf("Expected the slowest requests first, got %+v", top)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package middleware
+
+import (
+	"context"
+	"runtime/pprof"
+	"strings"
+
+	"google.golang.org/grpc"
+)
+
+// pprof label keys set by PprofLabels
+const (
+	PprofLabelService = "grpc_service"
+	PprofLabelMethod  = "grpc_method"
+	PprofLabelTenant  = "tenant"
+)
+
+// PprofDoFunc runs fn with profiler labels attached, e.g. pprof.Do or pyroscope.TagWrapper
+type PprofDoFunc func(ctx context.Context, labels pprof.LabelSet, fn func(context.Context))
+
+// pprofConfig configures the pprof label middlewares
+type pprofConfig struct {
+	labels func(ctx context.Context, fullMethod string) []string
+	do     PprofDoFunc
+}
+
+// PprofOption configures PprofLabels
+type PprofOption func(*pprofConfig)
+
+// WithPprofLabels adds labels to every request, as key/value pairs, e.g. the API version or
+// the caller's plan
+func WithPprofLabels(labels func(ctx context.Context, fullMethod string) []string) PprofOption {
+	return func(c *pprofConfig) {
+		c.labels = labels
+	}
+}
+
+// WithPprofDo sets the function that attaches the labels, so continuous profilers that
+// wrap pprof.Do can see them too (pyroscope.TagWrapper has the right signature)
+// Default: pprof.Do
+func WithPprofDo(do PprofDoFunc) PprofOption {
+	return func(c *pprofConfig) {
+		if do != nil {
+			c.do = do
+		}
+	}
+}
+
+// PprofLabels creates middleware that sets runtime/pprof labels for the duration of each
+// handler: grpc_service, grpc_method and, when the Tenants middleware runs first, tenant.
+// CPU and goroutine profiles can then be sliced by RPC, e.g.
+// `go tool pprof -tagfocus=grpc_method=/shop.v1.Orders/List`. Goroutines started by the
+// handler inherit the labels. The overhead is one label set allocation per request.
+//
+// Example usage:
+//
+//	tenants := middleware.NewTenants()
+//	chain := guardian.NewChain(
+//	    guardian.Middleware(tenants.UnaryServerInterceptor()),
+//	    middleware.PprofLabels(),
+//	)
+//
+//	// Pyroscope push profiling
+//	middleware.PprofLabels(middleware.WithPprofDo(pyroscope.TagWrapper))
+func PprofLabels(opts ...PprofOption) func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	config := newPprofConfig(opts)
+
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		config.do(ctx, config.labelSet(ctx, info.FullMethod), func(ctx context.Context) {
+			resp, err = handler(ctx, req)
+		})
+		return resp, err
+	}
+}
+
+// StreamPprofLabels sets the labels for the duration of each stream
+func StreamPprofLabels(opts ...PprofOption) func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	config := newPprofConfig(opts)
+
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		config.do(ss.Context(), config.labelSet(ss.Context(), info.FullMethod), func(ctx context.Context) {
+			err = handler(srv, &pprofServerStream{ServerStream: ss, ctx: ctx})
+		})
+		return err
+	}
+}
+
+// pprofServerStream wraps grpc.ServerStream with the labelled context
+type pprofServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context returns the labelled context
+func (s *pprofServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func newPprofConfig(opts []PprofOption) *pprofConfig {
+	config := &pprofConfig{do: pprof.Do}
+	for _, opt := range opts {
+		opt(config)
+	}
+	return config
+}
+
+// labelSet builds the labels of a request
+func (c *pprofConfig) labelSet(ctx context.Context, fullMethod string) pprof.LabelSet {
+	service := strings.TrimPrefix(fullMethod, "/")
+	if i := strings.LastIndex(service, "/"); i >= 0 {
+		service = service[:i]
+	}
+
+	labels := []string{PprofLabelService, service, PprofLabelMethod, fullMethod}
+	if tenant, ok := GetTenantID(ctx); ok && tenant != "" {
+		labels = append(labels, PprofLabelTenant, tenant)
+	}
+	if c.labels != nil {
+		if extra := c.labels(ctx, fullMethod); len(extra)%2 == 0 {
+			labels = append(labels, extra...)
+		}
+	}
+	return pprof.Labels(labels...)
+}
@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"context"
+	"runtime/pprof"
+	"testing"
+
+	"google.golang.org/grpc"
+)
+
+func TestPprofLabels(t *testing.T) {
+	interceptor := PprofLabels(WithPprofLabels(func(ctx context.Context, fullMethod string) []string {
+		return []string{"api_version", "v2"}
+	}))
+	ctx := context.WithValue(context.Background(), contextKeyTenantID, "acme")
+	info := &grpc.UnaryServerInfo{FullMethod: "/shop.v1.Orders/List"}
+
+	labels := make(chan map[string]string, 1)
+	_, err := interceptor(ctx, nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		// Goroutines started by the handler inherit the labels
+		go func() {
+			got := map[string]string{}
+			pprof.ForLabels(ctx, func(key, value string) bool {
+				got[key] = value
+				return true
+			})
+			labels <- got
+		}()
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{
+		PprofLabelService: "shop.v1.Orders",
+		PprofLabelMethod:  "/shop.v1.Orders/List",
+		PprofLabelTenant:  "acme",
+		"api_version":     "v2",
+	}
+	got := <-labels
+	if len(got) != len(want) {
+		t.Fatalf("Expected labels %v, got %v", want, got)
+	}
+	for key, value := range want {
+		if got[key] != value {
+			t.Errorf("Expected label %s=%s, got %q", key, value, got[key])
+		}
+	}
+}
+
+func TestPprofLabels_CustomDo(t *testing.T) {
+	var wrapped bool
+	do := func(ctx context.Context, labels pprof.LabelSet, fn func(context.Context)) {
+		wrapped = true
+		pprof.Do(ctx, labels, fn)
+	}
+
+	stream := &fieldMaskTestStream{ctx: context.Background()}
+	info := &grpc.StreamServerInfo{FullMethod: "/shop.v1.Orders/Watch"}
+	err := StreamPprofLabels(WithPprofDo(do))(nil, stream, info, func(srv interface{}, ss grpc.ServerStream) error {
+		if method, _ := pprof.Label(ss.Context(), PprofLabelMethod); method != "/shop.v1.Orders/Watch" {
+			t.Errorf("Expected the stream context to carry the labels, got %q", method)
+		}
+		return nil
+	})
+	if err != nil || !wrapped {
+		t.Errorf("Expected the custom wrapper to run the handler, got %v", err)
+	}
+}
@@ -0,0 +1,237 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"strings"
+	"time"
+
+	"github.com/grpc-guardian/grpc-guardian/pkg/metrics"
+	"golang.org/x/time/rate"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// PriorityHeader is the metadata key carrying the request criticality
+const PriorityHeader = "x-request-priority"
+
+// Priority is the criticality of a request. Under pressure, sheddable requests are
+// dropped first and critical requests last.
+type Priority int
+
+const (
+	// PriorityDefault is used for requests without (or with an unknown) priority
+	PriorityDefault Priority = iota
+	// PriorityCritical requests are user-facing and shed last
+	PriorityCritical
+	// PrioritySheddable requests (batch jobs, prefetches, retries) are shed first
+	PrioritySheddable
+)
+
+// String returns the header value of the priority
+func (p Priority) String() string {
+	switch p {
+	case PriorityCritical:
+		return "critical"
+	case PrioritySheddable:
+		return "sheddable"
+	}
+	return "default"
+}
+
+// ParsePriority parses a header value; unknown values report false
+func ParsePriority(value string) (Priority, bool) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "critical":
+		return PriorityCritical, true
+	case "default":
+		return PriorityDefault, true
+	case "sheddable":
+		return PrioritySheddable, true
+	}
+	return PriorityDefault, false
+}
+
+// priorityKey stores the priority accepted by TrustedPriority
+type priorityKey struct{}
+
+// RequestPriority returns the priority of an incoming request as accepted by
+// TrustedPriority. Requests it did not accept, including every request when it is not in
+// the chain, have PriorityDefault, so clients cannot make their own requests critical.
+func RequestPriority(ctx context.Context) Priority {
+	priority, _ := ctx.Value(priorityKey{}).(Priority)
+	return priority
+}
+
+// headerPriority parses the priority header of an incoming request
+func headerPriority(ctx context.Context) (Priority, bool) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if values := md.Get(PriorityHeader); len(values) > 0 {
+		return ParsePriority(values[0])
+	}
+	return PriorityDefault, false
+}
+
+// trustedPriority returns the context with the priority header of a trusted peer accepted
+func trustedPriority(ctx context.Context, trusted []netip.Prefix) context.Context {
+	if addr, ok := peerAddr(ctx); !ok || !containsAddr(trusted, addr) {
+		return ctx
+	}
+	if priority, ok := headerPriority(ctx); ok {
+		return context.WithValue(ctx, priorityKey{}, priority)
+	}
+	return ctx
+}
+
+// TrustedPriority creates middleware that accepts the priority header of trusted peers,
+// e.g. internal services and the gateway, given as CIDRs or addresses. The header of any
+// other peer is ignored. Place it before the rate limiter, load shedder and admission
+// controller.
+//
+// Example usage:
+//
+//	priority, err := middleware.TrustedPriority("10.0.0.0/8")
+//	chain := guardian.NewChain(priority, middleware.RateLimitWithPriority(100, 200, 0.3))
+func TrustedPriority(trustedPeers ...string) (func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error), error) {
+	trusted, err := parseTrustedPriorityPeers(trustedPeers)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		return handler(trustedPriority(ctx, trusted), req)
+	}, nil
+}
+
+// StreamTrustedPriority creates a stream interceptor that accepts the priority header of
+// trusted peers (see TrustedPriority)
+func StreamTrustedPriority(trustedPeers ...string) (grpc.StreamServerInterceptor, error) {
+	trusted, err := parseTrustedPriorityPeers(trustedPeers)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &priorityServerStream{ServerStream: ss, ctx: trustedPriority(ss.Context(), trusted)})
+	}, nil
+}
+
+// parseTrustedPriorityPeers parses the peers whose priority header is accepted
+func parseTrustedPriorityPeers(trustedPeers []string) ([]netip.Prefix, error) {
+	if len(trustedPeers) == 0 {
+		return nil, errors.New("priority: no trusted peers for the priority header")
+	}
+	trusted, err := parsePrefixes(trustedPeers)
+	if err != nil {
+		return nil, fmt.Errorf("priority: invalid trusted peer %w", err)
+	}
+	return trusted, nil
+}
+
+// priorityServerStream wraps grpc.ServerStream with the accepted priority
+type priorityServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context returns the context with the priority
+func (s *priorityServerStream) Context() context.Context {
+	return s.ctx
+}
+
+// WithRequestPriority sets the priority of outgoing calls made with the returned context
+//
+// Example usage:
+//
+//	ctx = middleware.WithRequestPriority(ctx, middleware.PrioritySheddable)
+//	resp, err := client.Prefetch(ctx, req)
+func WithRequestPriority(ctx context.Context, priority Priority) context.Context {
+	md, _ := metadata.FromOutgoingContext(ctx)
+	md = md.Copy()
+	md.Set(PriorityHeader, priority.String())
+	return metadata.NewOutgoingContext(ctx, md)
+}
+
+// outgoingPriority sets the priority of an outgoing call unless the caller set one. The
+// priority TrustedPriority accepted for the request being served wins over the fallback,
+// so criticality propagates through a call graph.
+func outgoingPriority(ctx context.Context, fallback Priority) context.Context {
+	if md, ok := metadata.FromOutgoingContext(ctx); ok && len(md.Get(PriorityHeader)) > 0 {
+		return ctx
+	}
+	if priority, ok := ctx.Value(priorityKey{}).(Priority); ok {
+		return WithRequestPriority(ctx, priority)
+	}
+	return WithRequestPriority(ctx, fallback)
+}
+
+// PriorityUnaryClientInterceptor sets the priority of outgoing unary calls that don't
+// carry one, propagating the priority of the request being served when there is one
+func PriorityUnaryClientInterceptor(fallback Priority) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return invoker(outgoingPriority(ctx, fallback), method, req, reply, cc, opts...)
+	}
+}
+
+// PriorityStreamClientInterceptor sets the priority of outgoing streams that don't carry one
+func PriorityStreamClientInterceptor(fallback Priority) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return streamer(outgoingPriority(ctx, fallback), desc, cc, method, opts...)
+	}
+}
+
+// RateLimitWithPriority creates a rate limiting middleware that keeps a share of the burst
+// for critical and default requests: sheddable requests are rejected once fewer than
+// sheddableReserve*burst tokens are left.
+//
+// Example usage:
+//
+//	// Sheddable traffic can't use the last 30% of the bucket
+//	chain := guardian.NewChain(middleware.RateLimitWithPriority(100, 200, 0.3))
+func RateLimitWithPriority(ratePerSec int, burst int, sheddableReserve float64) func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	limiter := rate.NewLimiter(rate.Limit(ratePerSec), burst)
+	reserve := sheddableReserve * float64(burst)
+
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		priority := RequestPriority(ctx)
+
+		if priority == PrioritySheddable && limiter.Tokens() < reserve+1 {
+			setRateLimitTrailer(ctx, limiter)
+			var retryDelay time.Duration
+			if limit := float64(limiter.Limit()); limit > 0 {
+				retryDelay = secondsToDuration((reserve + 1 - limiter.Tokens()) / limit)
+			}
+			return nil, rateLimitError(retryDelay, "rate limit exceeded for %s requests", priority)
+		}
+		if err := allowWithQuota(ctx, limiter, "rate limit exceeded"); err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// PriorityMetrics returns a middleware that records request results per priority band.
+// Place it before the rate limiter and load shedder so shed requests are counted;
+// ResourceExhausted errors count as shed.
+func PriorityMetrics(collector *metrics.PriorityCollector) func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+
+		result := metrics.PriorityResultOK
+		switch {
+		case status.Code(err) == codes.ResourceExhausted:
+			result = metrics.PriorityResultShed
+		case err != nil:
+			result = metrics.PriorityResultError
+		}
+		collector.RecordRequest(RequestPriority(ctx).String(), result, time.Since(start))
+
+		return resp, err
+	}
+}
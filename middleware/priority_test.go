@@ -0,0 +1,171 @@
+package middleware
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"strings"
+	"testing"
+
+	"github.com/grpc-guardian/grpc-guardian/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// priorityContext returns the context of a request whose priority header was accepted
+func priorityContext(priority string) context.Context {
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(PriorityHeader, priority))
+	accepted, _ := ParsePriority(priority)
+	return context.WithValue(ctx, priorityKey{}, accepted)
+}
+
+func TestTrustedPriority(t *testing.T) {
+	interceptor, err := TrustedPriority("10.0.0.0/8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := &grpc.UnaryServerInfo{FullMethod: "/svc/M"}
+
+	tests := []struct {
+		name   string
+		peer   string
+		header string
+		want   Priority
+	}{
+		{"trusted peer", "10.1.2.3:5000", "critical", PriorityCritical},
+		{"trusted peer mixed case", "10.1.2.3:5000", "Sheddable", PrioritySheddable},
+		{"trusted peer unknown value", "10.1.2.3:5000", "urgent", PriorityDefault},
+		{"untrusted peer", "203.0.113.7:5000", "critical", PriorityDefault},
+		{"no peer", "", "critical", PriorityDefault},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(PriorityHeader, tt.header))
+			if tt.peer != "" {
+				ctx = peer.NewContext(ctx, &peer.Peer{Addr: net.TCPAddrFromAddrPort(netip.MustParseAddrPort(tt.peer))})
+			}
+			var got Priority
+			interceptor(ctx, "req", info, func(ctx context.Context, req interface{}) (interface{}, error) {
+				got = RequestPriority(ctx)
+				return nil, nil
+			})
+			if got != tt.want {
+				t.Errorf("RequestPriority() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// Without TrustedPriority the header is ignored
+	if got := RequestPriority(metadata.NewIncomingContext(context.Background(), metadata.Pairs(PriorityHeader, "critical"))); got != PriorityDefault {
+		t.Errorf("Expected an unaccepted header to be ignored, got %v", got)
+	}
+
+	for _, peers := range [][]string{nil, {"not-a-cidr"}} {
+		if _, err := TrustedPriority(peers...); err == nil {
+			t.Errorf("TrustedPriority(%q) succeeded", peers)
+		}
+	}
+}
+
+func TestPriorityClientInterceptor(t *testing.T) {
+	var got []string
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		md, _ := metadata.FromOutgoingContext(ctx)
+		got = append(got, md.Get(PriorityHeader)...)
+		return nil
+	}
+	interceptor := PriorityUnaryClientInterceptor(PrioritySheddable)
+
+	interceptor(context.Background(), "/svc/M", nil, nil, nil, invoker)
+	interceptor(WithRequestPriority(context.Background(), PriorityCritical), "/svc/M", nil, nil, nil, invoker)
+	interceptor(priorityContext("critical"), "/svc/M", nil, nil, nil, invoker)
+	interceptor(metadata.NewIncomingContext(context.Background(), metadata.Pairs(PriorityHeader, "critical")), "/svc/M", nil, nil, nil, invoker)
+
+	want := []string{"sheddable", "critical", "critical", "sheddable"}
+	if len(got) != len(want) {
+		t.Fatalf("Expected priorities %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("call %d: expected %s, got %s", i, want[i], got[i])
+		}
+	}
+}
+
+func TestRateLimitWithPriority(t *testing.T) {
+	limiter := RateLimitWithPriority(1, 4, 0.5)
+	info := &grpc.UnaryServerInfo{FullMethod: "/svc/M"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) { return "ok", nil }
+
+	// Sheddable requests stop once only the reserved half of the bucket is left
+	for i := 0; i < 2; i++ {
+		if _, err := limiter(priorityContext("sheddable"), "req", info, handler); err != nil {
+			t.Fatalf("sheddable request %d: unexpected error %v", i, err)
+		}
+	}
+	if _, err := limiter(priorityContext("sheddable"), "req", info, handler); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("Expected sheddable request to be rejected, got %v", err)
+	}
+
+	// The reserve is still available to critical traffic
+	for i := 0; i < 2; i++ {
+		if _, err := limiter(priorityContext("critical"), "req", info, handler); err != nil {
+			t.Fatalf("critical request %d: unexpected error %v", i, err)
+		}
+	}
+	if _, err := limiter(priorityContext("critical"), "req", info, handler); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("Expected empty bucket to reject critical requests, got %v", err)
+	}
+}
+
+func TestConcurrencyLimiter_Priority(t *testing.T) {
+	limiter := NewConcurrencyLimiter(
+		WithLimitBounds(4, 4),
+		WithLowPriorityShare(0.5),
+		WithLowPriorityMethods("/reports.Exports/*"),
+	)
+
+	limiter.inFlight = 2
+	if _, ok := limiter.acquire("/shop.Orders/Get", PrioritySheddable); ok {
+		t.Error("Expected sheddable request to be shed at half the limit")
+	}
+	if _, ok := limiter.acquire("/reports.Exports/Run", PriorityCritical); !ok {
+		t.Error("Expected critical request to a low-priority method to be admitted")
+	}
+	if _, ok := limiter.acquire("/reports.Exports/Run", PriorityDefault); ok {
+		t.Error("Expected default request to a low-priority method to be shed")
+	}
+}
+
+func TestPriorityMetrics(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	collector, err := metrics.NewPriorityCollector(registry)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	interceptor := PriorityMetrics(collector)
+	info := &grpc.UnaryServerInfo{FullMethod: "/svc/M"}
+
+	interceptor(priorityContext("critical"), "req", info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "ok", nil
+	})
+	interceptor(priorityContext("sheddable"), "req", info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, status.Error(codes.ResourceExhausted, "shed")
+	})
+
+	expected := `
+# HELP grpc_priority_requests_total Total number of requests by priority band and result
+# TYPE grpc_priority_requests_total counter
+grpc_priority_requests_total{priority="critical",result="ok"} 1
+grpc_priority_requests_total{priority="sheddable",result="shed"} 1
+`
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(expected), "grpc_priority_requests_total"); err != nil {
+		t.Errorf("Unexpected priority metrics: %v", err)
+	}
+}
@@ -3,7 +3,9 @@ package middleware
 import (
 	"context"
 	"sync"
+	"time"
 
+	"github.com/grpc-guardian/grpc-guardian/pkg/metrics"
 	"golang.org/x/time/rate"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
@@ -24,27 +26,111 @@ func RateLimit(ratePerSec int, burst int) func(ctx context.Context, req interfac
 	limiter := rate.NewLimiter(rate.Limit(ratePerSec), burst)
 
 	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
-		if !limiter.Allow() {
-			return nil, status.Errorf(codes.ResourceExhausted, "rate limit exceeded")
+		if err := allowWithQuota(ctx, limiter, "rate limit exceeded"); err != nil {
+			return nil, err
 		}
 
 		return handler(ctx, req)
 	}
 }
 
-// RateLimitWithWait creates a rate limiting middleware that waits instead of rejecting
-func RateLimitWithWait(ratePerSec int, burst int) func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+// RateLimitWithLimiter creates a rate limiting middleware around an existing limiter, e.g. a
+// *rate.Limiter that is also reported through pkg/admin or a sliding window limiter
+func RateLimitWithLimiter(limiter RateLimiter) func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := allowLimiter(ctx, limiter, "rate limit exceeded"); err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// rateLimitWaitConfig holds the configuration of RateLimitWithWait
+type rateLimitWaitConfig struct {
+	collector *metrics.RateLimitWaitCollector
+	margin    time.Duration
+}
+
+// RateLimitWaitOption configures RateLimitWithWait
+type RateLimitWaitOption func(*rateLimitWaitConfig)
+
+// WithRateLimitWaitCollector records wait durations and rejections
+func WithRateLimitWaitCollector(collector *metrics.RateLimitWaitCollector) RateLimitWaitOption {
+	return func(c *rateLimitWaitConfig) {
+		c.collector = collector
+	}
+}
+
+// WithRateLimitWaitMargin reserves time for the handler: calls are rejected unless at least
+// margin is left before their deadline once the wait is over
+// Default: 0
+func WithRateLimitWaitMargin(margin time.Duration) RateLimitWaitOption {
+	return func(c *rateLimitWaitConfig) {
+		c.margin = margin
+	}
+}
+
+// RateLimitWithWait creates a rate limiting middleware that waits instead of rejecting.
+// The wait is reserved up front: when it would outlast the caller's deadline, the call fails
+// immediately with ResourceExhausted and a google.rpc.RetryInfo detail instead of waiting
+// for nothing, and the token is handed back.
+func RateLimitWithWait(ratePerSec int, burst int, opts ...RateLimitWaitOption) func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
 	limiter := rate.NewLimiter(rate.Limit(ratePerSec), burst)
+	config := &rateLimitWaitConfig{}
+	for _, opt := range opts {
+		opt(config)
+	}
 
 	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
-		if err := limiter.Wait(ctx); err != nil {
-			return nil, status.Errorf(codes.ResourceExhausted, "rate limit wait failed: %v", err)
+		if err := config.wait(ctx, limiter, info.FullMethod); err != nil {
+			return nil, err
 		}
 
 		return handler(ctx, req)
 	}
 }
 
+// wait reserves a token and waits for it, unless the wait can't finish before the deadline
+func (c *rateLimitWaitConfig) wait(ctx context.Context, limiter *rate.Limiter, method string) error {
+	reservation := limiter.Reserve()
+	if !reservation.OK() {
+		return status.Error(codes.ResourceExhausted, "rate limit wait failed: burst is zero")
+	}
+
+	delay := reservation.Delay()
+	if deadline, ok := ctx.Deadline(); ok && delay+c.margin > time.Until(deadline) {
+		reservation.Cancel()
+		c.rejected(method, metrics.WaitRejectedDeadline)
+		return rateLimitError(delay, "rate limit wait of %s exceeds the remaining deadline", delay.Round(time.Millisecond))
+	}
+
+	if delay > 0 {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			reservation.Cancel()
+			c.rejected(method, metrics.WaitRejectedCanceled)
+			return status.Errorf(codes.ResourceExhausted, "rate limit wait failed: %v", ctx.Err())
+		}
+	}
+
+	if c.collector != nil {
+		c.collector.RecordWait(method, delay)
+	}
+	return nil
+}
+
+// rejected records a call that did not wait for its token
+func (c *rateLimitWaitConfig) rejected(method, reason string) {
+	if c.collector != nil {
+		c.collector.RecordRejected(method, reason)
+	}
+}
+
 // PerClientRateLimiter manages rate limiters for individual clients
 type PerClientRateLimiter struct {
 	limiters map[string]*rate.Limiter
@@ -87,6 +173,19 @@ func (p *PerClientRateLimiter) GetLimiter(clientID string) *rate.Limiter {
 	return limiter
 }
 
+// Clients returns the number of clients with a limiter
+func (p *PerClientRateLimiter) Clients() int {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return len(p.limiters)
+}
+
+// Limit returns the per-client rate and burst
+func (p *PerClientRateLimiter) Limit() (rate.Limit, int) {
+	return p.rate, p.burst
+}
+
 // RateLimitPerClient creates a per-client rate limiting middleware
 // clientIDExtractor: function to extract client ID from context
 func RateLimitPerClient(ratePerSec int, burst int, clientIDExtractor func(context.Context) string) func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
@@ -99,8 +198,8 @@ func RateLimitPerClient(ratePerSec int, burst int, clientIDExtractor func(contex
 		}
 
 		limiter := perClientLimiter.GetLimiter(clientID)
-		if !limiter.Allow() {
-			return nil, status.Errorf(codes.ResourceExhausted, "rate limit exceeded for client: %s", clientID)
+		if err := allowWithQuota(ctx, limiter, "rate limit exceeded for client: %s", clientID); err != nil {
+			return nil, err
 		}
 
 		return handler(ctx, req)
@@ -182,8 +281,8 @@ func RateLimitPerMethod(defaultRate int, defaultBurst int, methodLimits map[stri
 	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
 		limiter := perMethodLimiter.GetLimiter(info.FullMethod)
 
-		if !limiter.Allow() {
-			return nil, status.Errorf(codes.ResourceExhausted, "rate limit exceeded for method: %s", info.FullMethod)
+		if err := allowWithQuota(ctx, limiter, "rate limit exceeded for method: %s", info.FullMethod); err != nil {
+			return nil, err
 		}
 
 		return handler(ctx, req)
@@ -250,3 +349,23 @@ func (a *AdaptiveRateLimiter) AdjustRate(loadFactor float64) {
 func (a *AdaptiveRateLimiter) Allow() bool {
 	return a.limiter.Allow()
 }
+
+// CurrentRate returns the rate currently enforced
+func (a *AdaptiveRateLimiter) CurrentRate() rate.Limit {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	return a.currentRate
+}
+
+// UnaryServerInterceptor returns a rate limiting interceptor enforcing the adaptive rate;
+// use NewLoadMonitor to adjust it automatically
+func (a *AdaptiveRateLimiter) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := allowWithQuota(ctx, a.limiter, "rate limit exceeded"); err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
@@ -0,0 +1,116 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"golang.org/x/time/rate"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// Trailing metadata keys describing the rate limit quota of a request
+const (
+	// RateLimitLimitHeader is the bucket size (or the requests allowed per window)
+	RateLimitLimitHeader = "x-ratelimit-limit"
+	// RateLimitRemainingHeader is the number of requests allowed right now
+	RateLimitRemainingHeader = "x-ratelimit-remaining"
+	// RateLimitResetHeader is the number of seconds until the bucket is full again (or the
+	// window ends)
+	RateLimitResetHeader = "x-ratelimit-reset"
+)
+
+// rateLimitQuota is a snapshot of a token bucket
+type rateLimitQuota struct {
+	limit      int
+	remaining  int
+	reset      time.Duration
+	retryDelay time.Duration
+}
+
+// quotaOf reads the state of a limiter
+func quotaOf(limiter *rate.Limiter) rateLimitQuota {
+	tokens := limiter.Tokens()
+	limit := float64(limiter.Limit())
+	burst := limiter.Burst()
+
+	q := rateLimitQuota{
+		limit:     burst,
+		remaining: int(math.Max(0, math.Floor(tokens))),
+	}
+	if limiter.Limit() == rate.Inf || limit <= 0 {
+		return q
+	}
+
+	q.reset = secondsToDuration(math.Max(0, float64(burst)-tokens) / limit)
+	q.retryDelay = secondsToDuration(math.Max(0, 1-tokens) / limit)
+	return q
+}
+
+// secondsToDuration converts fractional seconds to a duration
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// setRateLimitTrailer reports the quota of limiter in the trailing metadata of the call
+func setRateLimitTrailer(ctx context.Context, limiter *rate.Limiter) rateLimitQuota {
+	q := quotaOf(limiter)
+	setQuotaTrailer(ctx, q)
+	return q
+}
+
+// setQuotaTrailer reports a quota in the trailing metadata of the call
+func setQuotaTrailer(ctx context.Context, q rateLimitQuota) {
+	// Fails outside of a gRPC server call (e.g. in unit tests); the quota is informational
+	_ = grpc.SetTrailer(ctx, metadata.Pairs(
+		RateLimitLimitHeader, strconv.Itoa(q.limit),
+		RateLimitRemainingHeader, strconv.Itoa(q.remaining),
+		RateLimitResetHeader, strconv.FormatInt(int64(math.Ceil(q.reset.Seconds())), 10),
+	))
+}
+
+// allowWithQuota takes a token from limiter and reports the quota in trailing metadata.
+// Rejections carry a google.rpc.RetryInfo detail with the time until a token is available.
+func allowWithQuota(ctx context.Context, limiter *rate.Limiter, format string, args ...interface{}) error {
+	allowed := limiter.Allow()
+	q := setRateLimitTrailer(ctx, limiter)
+	if allowed {
+		return nil
+	}
+	return rateLimitError(q.retryDelay, format, args...)
+}
+
+// rateLimitError builds a ResourceExhausted error with a suggested retry delay
+func rateLimitError(retryDelay time.Duration, format string, args ...interface{}) error {
+	st := status.New(codes.ResourceExhausted, fmt.Sprintf(format, args...))
+	if retryDelay <= 0 {
+		return st.Err()
+	}
+
+	detailed, err := st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(retryDelay)})
+	if err != nil {
+		return st.Err()
+	}
+	return detailed.Err()
+}
+
+// RetryDelay returns the retry delay suggested by a google.rpc.RetryInfo error detail
+func RetryDelay(err error) (time.Duration, bool) {
+	st, ok := status.FromError(err)
+	if !ok {
+		return 0, false
+	}
+	for _, detail := range st.Details() {
+		if info, ok := detail.(*errdetails.RetryInfo); ok && info.GetRetryDelay() != nil {
+			return info.GetRetryDelay().AsDuration(), true
+		}
+	}
+	return 0, false
+}
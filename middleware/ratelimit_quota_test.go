@@ -0,0 +1,110 @@
+package middleware
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// dialQuotaServer serves a single unary method behind interceptor
+func dialQuotaServer(t *testing.T, interceptor grpc.UnaryServerInterceptor) *grpc.ClientConn {
+	t.Helper()
+
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer(grpc.UnaryInterceptor(interceptor))
+	server.RegisterService(&grpc.ServiceDesc{
+		ServiceName: "quota.Test",
+		HandlerType: (*interface{})(nil),
+		Methods: []grpc.MethodDesc{{
+			MethodName: "Call",
+			Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+				in := new(emptypb.Empty)
+				if err := dec(in); err != nil {
+					return nil, err
+				}
+				info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/quota.Test/Call"}
+				return interceptor(ctx, in, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+					return &emptypb.Empty{}, nil
+				})
+			},
+		}},
+	}, struct{}{})
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestRateLimit_QuotaMetadata(t *testing.T) {
+	conn := dialQuotaServer(t, RateLimit(2, 2))
+
+	call := func() (metadata.MD, error) {
+		var trailer metadata.MD
+		err := conn.Invoke(context.Background(), "/quota.Test/Call", &emptypb.Empty{}, &emptypb.Empty{}, grpc.Trailer(&trailer))
+		return trailer, err
+	}
+
+	trailer, err := call()
+	if err != nil {
+		t.Fatalf("first call: %v", err)
+	}
+	if got := trailer.Get(RateLimitLimitHeader); len(got) != 1 || got[0] != "2" {
+		t.Errorf("Expected %s=2, got %v", RateLimitLimitHeader, got)
+	}
+	if got := trailer.Get(RateLimitRemainingHeader); len(got) != 1 || got[0] != "1" {
+		t.Errorf("Expected %s=1, got %v", RateLimitRemainingHeader, got)
+	}
+	if got := trailer.Get(RateLimitResetHeader); len(got) != 1 || got[0] != "1" {
+		t.Errorf("Expected %s=1, got %v", RateLimitResetHeader, got)
+	}
+
+	call()
+	trailer, err = call()
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("Expected ResourceExhausted, got %v", err)
+	}
+	if got := trailer.Get(RateLimitRemainingHeader); len(got) != 1 || got[0] != "0" {
+		t.Errorf("Expected %s=0 on rejection, got %v", RateLimitRemainingHeader, got)
+	}
+
+	delay, ok := RetryDelay(err)
+	if !ok {
+		t.Fatal("Expected RetryInfo detail on rejection")
+	}
+	if delay <= 0 || delay > 500*time.Millisecond {
+		t.Errorf("Expected a retry delay up to one token interval, got %v", delay)
+	}
+}
+
+func TestRateLimitWithPriority_RetryInfo(t *testing.T) {
+	limiter := RateLimitWithPriority(10, 10, 0.5)
+	info := &grpc.UnaryServerInfo{FullMethod: "/svc/M"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) { return "ok", nil }
+
+	var err error
+	for i := 0; i < 10 && err == nil; i++ {
+		_, err = limiter(priorityContext("sheddable"), "req", info, handler)
+	}
+
+	// Five tokens are left for critical traffic; a sheddable request needs one more
+	delay, ok := RetryDelay(err)
+	if !ok || delay < 50*time.Millisecond || delay > 100*time.Millisecond {
+		t.Errorf("Expected a retry delay of about one token interval, got %v (%v)", delay, ok)
+	}
+}
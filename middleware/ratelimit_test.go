@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grpc-guardian/grpc-guardian/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestRateLimitWithWait_Deadline(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	collector, err := metrics.NewRateLimitWaitCollector(registry)
+	if err != nil {
+		t.Fatalf("Failed to create collector: %v", err)
+	}
+
+	// One token, refilled every 500ms
+	interceptor := RateLimitWithWait(2, 1, WithRateLimitWaitCollector(collector))
+	info := &grpc.UnaryServerInfo{FullMethod: "/test.Service/Method"}
+	calls := 0
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		calls++
+		return "ok", nil
+	}
+
+	if _, err := interceptor(context.Background(), nil, info, handler); err != nil {
+		t.Fatalf("Expected the first call to pass, got %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = interceptor(ctx, nil, info, handler)
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+		t.Errorf("Expected to fail fast, took %s", elapsed)
+	}
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("Expected ResourceExhausted, got %v", err)
+	}
+	if delay, ok := RetryDelay(err); !ok || delay <= 100*time.Millisecond {
+		t.Errorf("Expected a RetryInfo delay beyond the deadline, got %s", delay)
+	}
+
+	// The rejected call handed its token back, so the next one waits a single interval
+	start = time.Now()
+	if _, err := interceptor(context.Background(), nil, info, handler); err != nil {
+		t.Fatalf("Expected a call without deadline to wait, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 400*time.Millisecond || elapsed > 700*time.Millisecond {
+		t.Errorf("Expected to wait about 500ms, waited %s", elapsed)
+	}
+	if calls != 2 {
+		t.Errorf("Expected 2 handler calls, got %d", calls)
+	}
+
+	expected := `
+# HELP grpc_ratelimit_wait_rejected_total Total number of calls rejected instead of waiting for a rate limit token
+# TYPE grpc_ratelimit_wait_rejected_total counter
+grpc_ratelimit_wait_rejected_total{method="/test.Service/Method",reason="deadline"} 1
+`
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(expected), "grpc_ratelimit_wait_rejected_total"); err != nil {
+		t.Error(err)
+	}
+	if count := testutil.CollectAndCount(registry, "grpc_ratelimit_wait_seconds"); count != 1 {
+		t.Errorf("Expected one wait histogram, got %d", count)
+	}
+}
@@ -0,0 +1,347 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// quotaLimiter is a RateLimiter that can report its quota along with a decision
+type quotaLimiter interface {
+	allowQuota() (bool, rateLimitQuota)
+}
+
+// FixedWindowLimiter allows up to limit requests per window; the count resets at every
+// window boundary. Bursts of up to twice the limit are possible around a boundary.
+type FixedWindowLimiter struct {
+	limit  int
+	window time.Duration
+	now    func() time.Time
+
+	mu    sync.Mutex
+	start time.Time
+	count int
+}
+
+var _ RateLimiter = (*FixedWindowLimiter)(nil)
+
+// NewFixedWindowLimiter creates a limiter allowing limit requests per window, e.g. 600
+// requests per minute. It panics if limit or window is not positive.
+func NewFixedWindowLimiter(limit int, window time.Duration) *FixedWindowLimiter {
+	checkWindowLimit("NewFixedWindowLimiter", limit, window)
+	return &FixedWindowLimiter{
+		limit:  limit,
+		window: window,
+		now:    time.Now,
+	}
+}
+
+// Allow reports whether a request may happen now and counts it if so
+func (l *FixedWindowLimiter) Allow() bool {
+	allowed, _ := l.allowQuota()
+	return allowed
+}
+
+// Wait blocks until a request may happen or ctx is done
+func (l *FixedWindowLimiter) Wait(ctx context.Context) error {
+	return waitForQuota(ctx, l)
+}
+
+// allowQuota takes a slot in the current window
+func (l *FixedWindowLimiter) allowQuota() (bool, rateLimitQuota) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if l.start.IsZero() || now.Sub(l.start) >= l.window {
+		l.start = now.Truncate(l.window)
+		l.count = 0
+	}
+	reset := l.start.Add(l.window).Sub(now)
+
+	if l.count >= l.limit {
+		return false, rateLimitQuota{limit: l.limit, reset: reset, retryDelay: reset}
+	}
+
+	l.count++
+	return true, rateLimitQuota{limit: l.limit, remaining: l.limit - l.count, reset: reset}
+}
+
+// SlidingWindowLimiter allows up to limit requests in any window-long interval. It uses
+// the sliding window counter approximation: the previous window's count is weighted by
+// how much of it still overlaps the sliding window, which smooths out the boundary bursts
+// of a fixed window with constant memory.
+type SlidingWindowLimiter struct {
+	limit  int
+	window time.Duration
+	now    func() time.Time
+
+	mu       sync.Mutex
+	start    time.Time
+	current  int
+	previous int
+}
+
+var _ RateLimiter = (*SlidingWindowLimiter)(nil)
+
+// NewSlidingWindowLimiter creates a limiter allowing limit requests per sliding window. It
+// panics if limit or window is not positive.
+func NewSlidingWindowLimiter(limit int, window time.Duration) *SlidingWindowLimiter {
+	checkWindowLimit("NewSlidingWindowLimiter", limit, window)
+	return &SlidingWindowLimiter{
+		limit:  limit,
+		window: window,
+		now:    time.Now,
+	}
+}
+
+// Allow reports whether a request may happen now and counts it if so
+func (l *SlidingWindowLimiter) Allow() bool {
+	allowed, _ := l.allowQuota()
+	return allowed
+}
+
+// Wait blocks until a request may happen or ctx is done
+func (l *SlidingWindowLimiter) Wait(ctx context.Context) error {
+	return waitForQuota(ctx, l)
+}
+
+// allowQuota takes a slot if the weighted count is under the limit
+func (l *SlidingWindowLimiter) allowQuota() (bool, rateLimitQuota) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.advance(now)
+
+	elapsed := now.Sub(l.start)
+	overlap := 1 - float64(elapsed)/float64(l.window)
+	estimate := float64(l.previous)*overlap + float64(l.current)
+	reset := l.start.Add(l.window).Sub(now)
+
+	if estimate+1 > float64(l.limit) {
+		return false, rateLimitQuota{limit: l.limit, reset: reset, retryDelay: l.retryDelay(estimate, elapsed)}
+	}
+
+	l.current++
+	remaining := int(float64(l.limit) - estimate - 1)
+	if remaining < 0 {
+		remaining = 0
+	}
+	return true, rateLimitQuota{limit: l.limit, remaining: remaining, reset: reset}
+}
+
+// advance moves the windows forward to the one containing now
+func (l *SlidingWindowLimiter) advance(now time.Time) {
+	start := now.Truncate(l.window)
+	switch {
+	case l.start.IsZero():
+	case start.Sub(l.start) == l.window:
+		l.previous = l.current
+		l.current = 0
+	case start.After(l.start):
+		l.previous = 0
+		l.current = 0
+	default:
+		return
+	}
+	l.start = start
+}
+
+// retryDelay estimates when the weighted count drops enough to admit a request: the
+// previous window's weight shrinks linearly, and at the next window the current count
+// becomes the previous one
+func (l *SlidingWindowLimiter) retryDelay(estimate float64, elapsed time.Duration) time.Duration {
+	excess := estimate + 1 - float64(l.limit)
+	if l.previous > 0 {
+		// Each request of the previous window stops counting after window/previous
+		delay := time.Duration(excess / float64(l.previous) * float64(l.window))
+		if elapsed+delay < l.window {
+			return delay
+		}
+	}
+	return l.window - elapsed
+}
+
+// checkWindowLimit panics on a window limiter configuration that would never admit a
+// request or divide by zero
+func checkWindowLimit(constructor string, limit int, window time.Duration) {
+	if limit <= 0 || window <= 0 {
+		panic(fmt.Sprintf("middleware: %s: limit and window must be positive, got %d per %v", constructor, limit, window))
+	}
+}
+
+// waitForQuota polls a quota limiter until it admits a request
+func waitForQuota(ctx context.Context, limiter quotaLimiter) error {
+	for {
+		allowed, quota := limiter.allowQuota()
+		if allowed {
+			return nil
+		}
+
+		delay := quota.retryDelay
+		if delay <= 0 {
+			delay = time.Millisecond
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// allowLimiter takes a request from any RateLimiter, reporting the quota in trailing
+// metadata and a RetryInfo detail on rejection when the limiter supports it
+func allowLimiter(ctx context.Context, limiter RateLimiter, format string, args ...interface{}) error {
+	switch l := limiter.(type) {
+	case quotaLimiter:
+		allowed, quota := l.allowQuota()
+		setQuotaTrailer(ctx, quota)
+		if !allowed {
+			return rateLimitError(quota.retryDelay, format, args...)
+		}
+		return nil
+	case *rate.Limiter:
+		return allowWithQuota(ctx, l, format, args...)
+	}
+
+	if !limiter.Allow() {
+		return status.Errorf(codes.ResourceExhausted, format, args...)
+	}
+	return nil
+}
+
+// KeyedRateLimiter holds one limiter per key (client, method, tenant, ...) created on
+// first use
+type KeyedRateLimiter struct {
+	newLimiter func(key string) RateLimiter
+	maxKeys    int
+
+	mu       sync.RWMutex
+	limiters map[string]RateLimiter
+}
+
+// KeyedRateLimiterOption configures a KeyedRateLimiter
+type KeyedRateLimiterOption func(*KeyedRateLimiter)
+
+// WithMaxKeys caps the keys whose limiter is kept. When the cap is reached, the limiter
+// of an arbitrary key is dropped, so that key starts over with a fresh limiter.
+// Default: 10000
+func WithMaxKeys(n int) KeyedRateLimiterOption {
+	return func(k *KeyedRateLimiter) {
+		if n > 0 {
+			k.maxKeys = n
+		}
+	}
+}
+
+// NewKeyedRateLimiter creates a keyed limiter; newLimiter is called once per key
+func NewKeyedRateLimiter(newLimiter func(key string) RateLimiter, opts ...KeyedRateLimiterOption) *KeyedRateLimiter {
+	k := &KeyedRateLimiter{
+		newLimiter: newLimiter,
+		maxKeys:    10000,
+		limiters:   make(map[string]RateLimiter),
+	}
+
+	for _, opt := range opts {
+		opt(k)
+	}
+
+	return k
+}
+
+// GetLimiter returns the limiter for a key
+func (k *KeyedRateLimiter) GetLimiter(key string) RateLimiter {
+	k.mu.RLock()
+	limiter, exists := k.limiters[key]
+	k.mu.RUnlock()
+
+	if exists {
+		return limiter
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if limiter, exists := k.limiters[key]; exists {
+		return limiter
+	}
+
+	if len(k.limiters) >= k.maxKeys {
+		for evicted := range k.limiters {
+			delete(k.limiters, evicted)
+			break
+		}
+	}
+
+	limiter = k.newLimiter(key)
+	k.limiters[key] = limiter
+	return limiter
+}
+
+// Keys returns the number of keys with a limiter
+func (k *KeyedRateLimiter) Keys() int {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	return len(k.limiters)
+}
+
+// RateLimitPerClientWith creates a per-client rate limiting middleware with any limiter
+// algorithm; newLimiter is called once per client. Requests without a client ID share the
+// "unknown" client's limiter.
+//
+// Example usage:
+//
+//	// 600 requests per minute per user
+//	limiter := middleware.RateLimitPerClientWith(func() middleware.RateLimiter {
+//	    return middleware.NewSlidingWindowLimiter(600, time.Minute)
+//	}, middleware.ExtractUserIDForRateLimit)
+func RateLimitPerClientWith(newLimiter func() RateLimiter, clientIDExtractor func(context.Context) string, opts ...KeyedRateLimiterOption) func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	limiters := NewKeyedRateLimiter(func(string) RateLimiter { return newLimiter() }, opts...)
+
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		clientID := clientIDExtractor(ctx)
+		if clientID == "" {
+			clientID = "unknown"
+		}
+
+		if err := allowLimiter(ctx, limiters.GetLimiter(clientID), "rate limit exceeded for client: %s", clientID); err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// RateLimitPerMethodWith creates a per-method rate limiting middleware with any limiter
+// algorithm; newLimiter is called once per method, so limits can differ per method.
+//
+// Example usage:
+//
+//	limiter := middleware.RateLimitPerMethodWith(func(method string) middleware.RateLimiter {
+//	    if method == "/shop.Orders/Export" {
+//	        return middleware.NewFixedWindowLimiter(10, time.Hour)
+//	    }
+//	    return middleware.NewSlidingWindowLimiter(6000, time.Minute)
+//	})
+func RateLimitPerMethodWith(newLimiter func(method string) RateLimiter, opts ...KeyedRateLimiterOption) func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	limiters := NewKeyedRateLimiter(newLimiter, opts...)
+
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := allowLimiter(ctx, limiters.GetLimiter(info.FullMethod), "rate limit exceeded for method: %s", info.FullMethod); err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
@@ -0,0 +1,199 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// fakeClock is a manually advanced clock for window limiters
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func (c *fakeClock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+
+func TestFixedWindowLimiter(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+	limiter := NewFixedWindowLimiter(3, time.Minute)
+	limiter.now = clock.Now
+
+	for i := 0; i < 3; i++ {
+		if !limiter.Allow() {
+			t.Fatalf("request %d: expected to be allowed", i)
+		}
+	}
+	allowed, quota := limiter.allowQuota()
+	if allowed {
+		t.Fatal("Expected the fourth request in the window to be rejected")
+	}
+	if quota.retryDelay != time.Minute || quota.remaining != 0 {
+		t.Errorf("Expected retry at the window end, got %+v", quota)
+	}
+
+	// The count resets at the window boundary
+	clock.Advance(time.Minute)
+	if allowed, quota := limiter.allowQuota(); !allowed || quota.remaining != 2 {
+		t.Errorf("Expected a fresh window, got allowed=%v %+v", allowed, quota)
+	}
+}
+
+func TestSlidingWindowLimiter(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+	limiter := NewSlidingWindowLimiter(10, time.Minute)
+	limiter.now = clock.Now
+
+	// Fill the first window late, so the requests still overlap the next one
+	clock.Advance(50 * time.Second)
+	for i := 0; i < 10; i++ {
+		if !limiter.Allow() {
+			t.Fatalf("request %d: expected to be allowed", i)
+		}
+	}
+	if limiter.Allow() {
+		t.Fatal("Expected the window to be full")
+	}
+
+	// 15s into the next window 75% of the previous window still counts: 7.5 of 10
+	clock.Advance(25 * time.Second)
+	for i := 0; i < 2; i++ {
+		if !limiter.Allow() {
+			t.Fatalf("request %d in the next window: expected to be allowed", i)
+		}
+	}
+	allowed, quota := limiter.allowQuota()
+	if allowed {
+		t.Fatal("Expected the weighted count to reject a fixed window's boundary burst")
+	}
+	if quota.retryDelay <= 0 || quota.retryDelay > 45*time.Second {
+		t.Errorf("Expected a retry delay within the window, got %v", quota.retryDelay)
+	}
+
+	clock.Advance(quota.retryDelay + time.Millisecond)
+	if !limiter.Allow() {
+		t.Error("Expected a request to be allowed after the suggested retry delay")
+	}
+
+	// Two idle windows forget everything
+	clock.Advance(2 * time.Minute)
+	if allowed, quota := limiter.allowQuota(); !allowed || quota.remaining != 9 {
+		t.Errorf("Expected an empty window, got allowed=%v %+v", allowed, quota)
+	}
+}
+
+func TestWindowLimiter_Wait(t *testing.T) {
+	limiter := NewFixedWindowLimiter(1, 20*time.Millisecond)
+	limiter.Allow()
+
+	start := time.Now()
+	if err := limiter.Wait(context.Background()); err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Error("Expected Wait to return at the next window")
+	}
+
+	limiter.Allow()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := limiter.Wait(ctx); err != context.Canceled {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
+
+func TestRateLimitPerClientWith(t *testing.T) {
+	interceptor := RateLimitPerClientWith(func() RateLimiter {
+		return NewSlidingWindowLimiter(1, time.Minute)
+	}, ExtractClientIP)
+	info := &grpc.UnaryServerInfo{FullMethod: "/svc/M"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) { return "ok", nil }
+
+	alice := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-real-ip", "10.0.0.1"))
+	bob := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-real-ip", "10.0.0.2"))
+
+	if _, err := interceptor(alice, "req", info, handler); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := interceptor(bob, "req", info, handler); err != nil {
+		t.Errorf("Expected separate windows per client, got %v", err)
+	}
+	_, err := interceptor(alice, "req", info, handler)
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("Expected ResourceExhausted, got %v", err)
+	}
+	if _, ok := RetryDelay(err); !ok {
+		t.Error("Expected RetryInfo on window limiter rejections")
+	}
+
+	// Requests without a client ID share one limiter
+	anonymous := RateLimitPerClientWith(func() RateLimiter {
+		return NewFixedWindowLimiter(1, time.Minute)
+	}, func(context.Context) string { return "" })
+	anonymous(context.Background(), "req", info, handler)
+	if _, err := anonymous(context.Background(), "req", info, handler); err == nil || !strings.Contains(err.Error(), "client: unknown") {
+		t.Errorf("Expected the unknown client to be limited, got %v", err)
+	}
+}
+
+func TestKeyedRateLimiter_MaxKeys(t *testing.T) {
+	limiters := NewKeyedRateLimiter(func(string) RateLimiter {
+		return NewFixedWindowLimiter(1, time.Minute)
+	}, WithMaxKeys(2))
+
+	for i := 0; i < 10; i++ {
+		limiters.GetLimiter(fmt.Sprintf("client-%d", i))
+		if keys := limiters.Keys(); keys > 2 {
+			t.Fatalf("Expected at most 2 keys, got %d", keys)
+		}
+	}
+}
+
+func TestWindowLimiter_InvalidConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		create func()
+	}{
+		{"fixed zero limit", func() { NewFixedWindowLimiter(0, time.Minute) }},
+		{"fixed zero window", func() { NewFixedWindowLimiter(10, 0) }},
+		{"sliding negative limit", func() { NewSlidingWindowLimiter(-1, time.Minute) }},
+		{"sliding negative window", func() { NewSlidingWindowLimiter(10, -time.Second) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("Expected a panic")
+				}
+			}()
+			tt.create()
+		})
+	}
+}
+
+func TestRateLimitPerMethodWith(t *testing.T) {
+	interceptor := RateLimitPerMethodWith(func(method string) RateLimiter {
+		if method == "/svc/Export" {
+			return NewFixedWindowLimiter(1, time.Hour)
+		}
+		return NewFixedWindowLimiter(100, time.Minute)
+	})
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) { return "ok", nil }
+
+	export := &grpc.UnaryServerInfo{FullMethod: "/svc/Export"}
+	interceptor(context.Background(), "req", export, handler)
+	if _, err := interceptor(context.Background(), "req", export, handler); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("Expected the export limit to apply, got %v", err)
+	}
+	if _, err := interceptor(context.Background(), "req", &grpc.UnaryServerInfo{FullMethod: "/svc/Get"}, handler); err != nil {
+		t.Errorf("Expected other methods to have their own limit, got %v", err)
+	}
+}
@@ -0,0 +1,329 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grpc-guardian/grpc-guardian/pkg/logging"
+	"github.com/grpc-guardian/grpc-guardian/pkg/randutil"
+	"github.com/grpc-guardian/grpc-guardian/pkg/replay"
+	"golang.org/x/time/rate"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+)
+
+// recordingConfig holds the configuration of a TrafficRecorder
+type recordingConfig struct {
+	rate          float64
+	methods       map[string]bool
+	maxPerSecond  float64
+	burst         int
+	bufferSize    int
+	batchSize     int
+	flushInterval time.Duration
+	redactor      *logging.Redactor
+	metadataKeys  []string
+	onError       func(error)
+	rng           *randutil.Rand
+}
+
+// RecordingOption configures a TrafficRecorder
+type RecordingOption func(*recordingConfig)
+
+// WithRecordingRate sets the fraction of requests recorded
+// Default: 0.01
+func WithRecordingRate(fraction float64) RecordingOption {
+	return func(c *recordingConfig) {
+		c.rate = fraction
+	}
+}
+
+// WithRecordedMethods records only methods matching the patterns ("/pkg.Service/Method" or
+// ending in "*"). Prefer methods without side effects: replaying a write repeats it.
+func WithRecordedMethods(patterns ...string) RecordingOption {
+	return func(c *recordingConfig) {
+		for _, pattern := range patterns {
+			c.methods[pattern] = true
+		}
+	}
+}
+
+// WithRecordingRateCap caps the number of recorded requests per second
+// Default: 10 per second, burst 10
+func WithRecordingRateCap(perSecond float64, burst int) RecordingOption {
+	return func(c *recordingConfig) {
+		c.maxPerSecond = perSecond
+		c.burst = burst
+	}
+}
+
+// WithRecordingBatching sets the batch size and flush interval of sink writes
+// Default: 100 records, 5s
+func WithRecordingBatching(size int, flushInterval time.Duration) RecordingOption {
+	return func(c *recordingConfig) {
+		c.batchSize = size
+		c.flushInterval = flushInterval
+	}
+}
+
+// WithRecordingBufferSize sets how many records may be queued before new ones are dropped
+// Default: 1000
+func WithRecordingBufferSize(n int) RecordingOption {
+	return func(c *recordingConfig) {
+		c.bufferSize = n
+	}
+}
+
+// WithRecordingRedactor masks sensitive fields of recorded messages. Unlike logging,
+// recordings keep the message type, so masked fields hold the mask (strings and bytes) or
+// their zero value (other kinds).
+func WithRecordingRedactor(redactor *logging.Redactor) RecordingOption {
+	return func(c *recordingConfig) {
+		c.redactor = redactor
+	}
+}
+
+// WithRecordedMetadata records the given request headers, so they are sent again on
+// replay (e.g. "x-tenant-id"). No headers are recorded by default; avoid credentials.
+func WithRecordedMetadata(keys ...string) RecordingOption {
+	return func(c *recordingConfig) {
+		for _, key := range keys {
+			c.metadataKeys = append(c.metadataKeys, strings.ToLower(key))
+		}
+	}
+}
+
+// WithRecordingErrorCallback sets a callback invoked when a request can't be encoded or the
+// sink fails
+func WithRecordingErrorCallback(fn func(error)) RecordingOption {
+	return func(c *recordingConfig) {
+		c.onError = fn
+	}
+}
+
+// WithRecordingSeed seeds the recording decisions, so the same seed and sequence of
+// requests record the same requests, e.g. for reproducible tests
+// Default: seeded from the clock
+func WithRecordingSeed(seed int64) RecordingOption {
+	return func(c *recordingConfig) {
+		c.rng = randutil.New(seed)
+	}
+}
+
+// TrafficRecorder records a sample of unary request/response pairs to a replay.Sink, for
+// regression testing with replay.Replayer. Records are written on a background goroutine
+// and recording never blocks or fails the RPC.
+type TrafficRecorder struct {
+	sink    replay.Sink
+	config  *recordingConfig
+	methods *methodMatcher[bool]
+	limiter *rate.Limiter
+	queue   chan replay.Record
+	done    chan struct{}
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+// NewTrafficRecorder creates a recorder and starts its background writer
+//
+// Example usage:
+//
+//	sink, err := replay.NewFileSink("/var/lib/guardian/orders.jsonl")
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	recorder := middleware.NewTrafficRecorder(sink,
+//	    middleware.WithRecordedMethods("/orders.Orders/Get*"),
+//	    middleware.WithRecordingRedactor(logging.NewRedactor(logging.WithRedactedFields("email"))),
+//	)
+//	defer recorder.Close()
+//	chain := guardian.NewChain(recorder.UnaryServerInterceptor())
+func NewTrafficRecorder(sink replay.Sink, opts ...RecordingOption) *TrafficRecorder {
+	config := &recordingConfig{
+		rate:          0.01,
+		methods:       make(map[string]bool),
+		maxPerSecond:  10,
+		burst:         10,
+		bufferSize:    1000,
+		batchSize:     100,
+		flushInterval: 5 * time.Second,
+	}
+
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	if config.burst <= 0 {
+		config.burst = 1
+	}
+	if config.batchSize <= 0 {
+		config.batchSize = 1
+	}
+	if config.flushInterval <= 0 {
+		config.flushInterval = 5 * time.Second
+	}
+	if config.rng == nil {
+		config.rng = randutil.NewTimeSeeded()
+	}
+
+	r := &TrafficRecorder{
+		sink:    sink,
+		config:  config,
+		limiter: rate.NewLimiter(rate.Limit(config.maxPerSecond), config.burst),
+		queue:   make(chan replay.Record, config.bufferSize),
+		done:    make(chan struct{}),
+	}
+	if len(config.methods) > 0 {
+		r.methods = newMethodMatcher(config.methods)
+	}
+
+	go r.run()
+
+	return r
+}
+
+// UnaryServerInterceptor returns a unary server interceptor that records requests
+func (r *TrafficRecorder) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if !r.shouldRecord(info.FullMethod) {
+			return handler(ctx, req)
+		}
+		reqMsg, ok := req.(proto.Message)
+		if !ok {
+			return handler(ctx, req)
+		}
+		// Clone before the handler runs, which may modify the request
+		reqMsg = proto.Clone(reqMsg)
+
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		duration := time.Since(start)
+
+		// Enforce the cap after the sampling decision so the fraction stays representative
+		if !r.limiter.Allow() {
+			return resp, err
+		}
+
+		var respMsg proto.Message
+		if err == nil {
+			respMsg, _ = resp.(proto.Message)
+		}
+		if r.config.redactor != nil {
+			reqMsg = r.config.redactor.RedactProto(info.FullMethod, reqMsg)
+			respMsg = r.config.redactor.RedactProto(info.FullMethod, respMsg)
+		}
+
+		record, recErr := replay.NewRecord(info.FullMethod, r.recordedMetadata(ctx), reqMsg, respMsg, err, start, duration)
+		if recErr != nil {
+			r.reportError(recErr)
+			return resp, err
+		}
+		r.enqueue(record)
+
+		return resp, err
+	}
+}
+
+// Close flushes buffered records and stops the background writer
+func (r *TrafficRecorder) Close() {
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return
+	}
+	r.closed = true
+	close(r.queue)
+	r.mu.Unlock()
+
+	<-r.done
+}
+
+// shouldRecord makes the per-request recording decision
+func (r *TrafficRecorder) shouldRecord(method string) bool {
+	if r.methods != nil {
+		if _, ok := r.methods.match(method); !ok {
+			return false
+		}
+	}
+	return r.config.rate > 0 && r.config.rng.Float64() < r.config.rate
+}
+
+// recordedMetadata returns the configured request headers
+func (r *TrafficRecorder) recordedMetadata(ctx context.Context) metadata.MD {
+	if len(r.config.metadataKeys) == 0 {
+		return nil
+	}
+	incoming, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return nil
+	}
+	md := metadata.MD{}
+	for _, key := range r.config.metadataKeys {
+		if values := incoming.Get(key); len(values) > 0 {
+			md[key] = append([]string(nil), values...)
+		}
+	}
+	return md
+}
+
+// enqueue hands a record to the background writer without blocking
+func (r *TrafficRecorder) enqueue(record replay.Record) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.closed {
+		return
+	}
+
+	select {
+	case r.queue <- record:
+	default:
+	}
+}
+
+// reportError passes an error to the error callback
+func (r *TrafficRecorder) reportError(err error) {
+	if r.config.onError != nil {
+		r.config.onError(err)
+	}
+}
+
+// run batches queued records and writes them to the sink
+func (r *TrafficRecorder) run() {
+	defer close(r.done)
+
+	ticker := time.NewTicker(r.config.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]replay.Record, 0, r.config.batchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := r.sink.Write(context.Background(), batch); err != nil {
+			r.reportError(fmt.Errorf("recording sink write failed: %w", err))
+		}
+		batch = make([]replay.Record, 0, r.config.batchSize)
+	}
+
+	for {
+		select {
+		case record, ok := <-r.queue:
+			if !ok {
+				flush()
+				return
+			}
+			batch = append(batch, record)
+			if len(batch) >= r.config.batchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
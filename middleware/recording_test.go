@@ -0,0 +1,127 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/grpc-guardian/grpc-guardian/pkg/logging"
+	"github.com/grpc-guardian/grpc-guardian/pkg/replay"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// memoryRecordSink collects records in memory
+type memoryRecordSink struct {
+	mu      sync.Mutex
+	records []replay.Record
+	err     error
+}
+
+func (m *memoryRecordSink) Write(ctx context.Context, records []replay.Record) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.records = append(m.records, records...)
+	return m.err
+}
+
+func (m *memoryRecordSink) all() []replay.Record {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]replay.Record(nil), m.records...)
+}
+
+func TestTrafficRecorder(t *testing.T) {
+	sink := &memoryRecordSink{}
+	recorder := NewTrafficRecorder(sink,
+		WithRecordingRate(1),
+		WithRecordedMethods("/users.Users/*"),
+		WithRecordingRateCap(1000, 1000),
+		WithRecordingRedactor(logging.NewRedactor(logging.WithRedactedFields("password"))),
+		WithRecordedMetadata("X-Tenant-ID"),
+	)
+	interceptor := recorder.UnaryServerInterceptor()
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+		"x-tenant-id", "acme",
+		"authorization", "Bearer secret",
+	))
+	user := redactTestMessage(t)
+
+	_, _ = interceptor(ctx, user, &grpc.UnaryServerInfo{FullMethod: "/users.Users/Update"},
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			return wrapperspb.String("updated"), nil
+		})
+	_, _ = interceptor(ctx, user, &grpc.UnaryServerInfo{FullMethod: "/users.Users/Get"},
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			return nil, status.Error(codes.NotFound, "no such user")
+		})
+	_, _ = interceptor(ctx, user, &grpc.UnaryServerInfo{FullMethod: "/orders.Orders/Get"},
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			return wrapperspb.String("order"), nil
+		})
+	// Non-proto requests can't be replayed and are skipped
+	_, _ = interceptor(ctx, map[string]string{"id": "1"}, &grpc.UnaryServerInfo{FullMethod: "/users.Users/Get"},
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			return nil, nil
+		})
+
+	recorder.Close()
+	recorder.Close()
+
+	records := sink.all()
+	if len(records) != 2 {
+		t.Fatalf("Expected 2 records, got %d", len(records))
+	}
+
+	ok := records[0]
+	if ok.Method != "/users.Users/Update" || ok.Code != "OK" || ok.Schema != replay.SchemaVersion {
+		t.Errorf("Unexpected record %+v", ok)
+	}
+	if req := string(ok.Request); strings.Contains(req, "hunter2") || !strings.Contains(req, logging.DefaultRedactionMask) || !strings.Contains(req, "alice") {
+		t.Errorf("Expected the password to be masked in %s", req)
+	}
+	resp, err := ok.DecodeResponse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.(*wrapperspb.StringValue).GetValue() != "updated" {
+		t.Errorf("Expected the recorded response, got %v", resp)
+	}
+	if len(ok.Metadata) != 1 || ok.Metadata["x-tenant-id"][0] != "acme" {
+		t.Errorf("Expected only the configured headers, got %v", ok.Metadata)
+	}
+
+	failed := records[1]
+	if failed.Code != "NotFound" || failed.Message != "no such user" || failed.Response != nil {
+		t.Errorf("Unexpected failed record %+v", failed)
+	}
+}
+
+func TestTrafficRecorder_SinkError(t *testing.T) {
+	sink := &memoryRecordSink{err: errors.New("bucket unavailable")}
+	var errs []error
+	recorder := NewTrafficRecorder(sink,
+		WithRecordingRate(1),
+		WithRecordingErrorCallback(func(err error) { errs = append(errs, err) }),
+	)
+
+	resp, err := recorder.UnaryServerInterceptor()(context.Background(), wrapperspb.String("req"),
+		&grpc.UnaryServerInfo{FullMethod: "/svc.S/M"},
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			return wrapperspb.String("resp"), nil
+		})
+	if err != nil || resp.(*wrapperspb.StringValue).GetValue() != "resp" {
+		t.Errorf("Expected the call to be unaffected, got %v, %v", resp, err)
+	}
+
+	recorder.Close()
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "bucket unavailable") {
+		t.Errorf("Expected the sink error to be reported, got %v", errs)
+	}
+}
@@ -0,0 +1,168 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// RecoveryConfig holds configuration for panic recovery middleware
+type RecoveryConfig struct {
+	Logger        *zap.Logger                                                           // Logger for recovered panics
+	OnPanic       func(ctx context.Context, method string, p interface{}, stack []byte) // Called after every recovered panic
+	Registerer    prometheus.Registerer                                                 // Registers grpc_server_panics_total when set
+	ExposeDetails bool                                                                  // Include the panic value in the error returned to clients
+}
+
+// RecoveryOption is a functional option for recovery configuration
+type RecoveryOption func(*RecoveryConfig)
+
+// WithRecoveryLogger sets the logger used to report recovered panics
+func WithRecoveryLogger(logger *zap.Logger) RecoveryOption {
+	return func(c *RecoveryConfig) {
+		c.Logger = logger
+	}
+}
+
+// WithPanicCallback sets a callback invoked with the panic value and stack trace
+// (e.g. to report to an error tracker)
+func WithPanicCallback(fn func(ctx context.Context, method string, p interface{}, stack []byte)) RecoveryOption {
+	return func(c *RecoveryConfig) {
+		c.OnPanic = fn
+	}
+}
+
+// WithPanicMetrics registers a grpc_server_panics_total counter with the given registerer
+func WithPanicMetrics(registerer prometheus.Registerer) RecoveryOption {
+	return func(c *RecoveryConfig) {
+		c.Registerer = registerer
+	}
+}
+
+// WithPanicDetails includes the panic value in the error message sent to clients.
+// Default: false (clients only see a generic internal error)
+func WithPanicDetails() RecoveryOption {
+	return func(c *RecoveryConfig) {
+		c.ExposeDetails = true
+	}
+}
+
+// Recovery creates middleware that recovers handler panics and converts them to codes.Internal.
+// Place it first in the chain so it also covers panics in other middleware.
+//
+// Example usage:
+//
+//	chain := guardian.NewChain(
+//	    middleware.Recovery(
+//	        middleware.WithRecoveryLogger(logger),
+//	        middleware.WithPanicMetrics(collector.GetRegistry()),
+//	    ),
+//	    middleware.Logging(),
+//	)
+func Recovery(opts ...RecoveryOption) func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	r := newPanicRecoverer(opts)
+
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		defer func() {
+			if p := recover(); p != nil {
+				resp, err = nil, r.recovered(ctx, info.FullMethod, p)
+			}
+		}()
+
+		return handler(ctx, req)
+	}
+}
+
+// StreamRecovery creates middleware that recovers panics in streaming handlers
+func StreamRecovery(opts ...RecoveryOption) func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	r := newPanicRecoverer(opts)
+
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		defer func() {
+			if p := recover(); p != nil {
+				err = r.recovered(ss.Context(), info.FullMethod, p)
+			}
+		}()
+
+		return handler(srv, ss)
+	}
+}
+
+// panicRecoverer reports recovered panics
+type panicRecoverer struct {
+	config *RecoveryConfig
+	panics *prometheus.CounterVec
+}
+
+// newPanicRecoverer applies options and registers metrics
+func newPanicRecoverer(opts []RecoveryOption) *panicRecoverer {
+	config := &RecoveryConfig{
+		Logger: zap.NewExample(),
+	}
+
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	r := &panicRecoverer{config: config}
+
+	if config.Registerer != nil {
+		r.panics = prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "grpc",
+			Subsystem: "server",
+			Name:      "panics_total",
+			Help:      "Total number of panics recovered in gRPC handlers",
+		}, []string{"method"})
+		if err := config.Registerer.Register(r.panics); err != nil {
+			if are, ok := err.(prometheus.AlreadyRegisteredError); ok {
+				r.panics = are.ExistingCollector.(*prometheus.CounterVec)
+			}
+		}
+	}
+
+	return r
+}
+
+// recovered records a panic and returns the error sent to the client
+func (r *panicRecoverer) recovered(ctx context.Context, method string, p interface{}) error {
+	stack := debug.Stack()
+
+	if r.config.Logger != nil {
+		r.config.Logger.Error("recovered from panic in gRPC handler",
+			zap.String("method", method),
+			zap.Any("panic", p),
+			zap.ByteString("stack", stack),
+		)
+	}
+
+	if span := trace.SpanFromContext(ctx); span.IsRecording() {
+		span.AddEvent("panic", trace.WithAttributes(
+			attribute.String("exception.type", fmt.Sprintf("%T", p)),
+			attribute.String("exception.message", fmt.Sprint(p)),
+			attribute.String("exception.stacktrace", string(stack)),
+		))
+		span.SetStatus(otelcodes.Error, "panic recovered")
+	}
+
+	if r.panics != nil {
+		r.panics.WithLabelValues(method).Inc()
+	}
+
+	if r.config.OnPanic != nil {
+		r.config.OnPanic(ctx, method, p, stack)
+	}
+
+	if r.config.ExposeDetails {
+		return status.Errorf(codes.Internal, "panic in %s: %v", method, p)
+	}
+	return status.Error(codes.Internal, "internal server error")
+}
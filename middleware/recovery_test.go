@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestRecovery(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	var (
+		recoveredValue interface{}
+		recoveredStack []byte
+	)
+
+	interceptor := Recovery(
+		WithRecoveryLogger(zap.NewNop()),
+		WithPanicMetrics(registry),
+		WithPanicCallback(func(ctx context.Context, method string, p interface{}, stack []byte) {
+			recoveredValue, recoveredStack = p, stack
+		}),
+	)
+
+	resp, err := interceptor(context.Background(), "req", &grpc.UnaryServerInfo{FullMethod: "/test.Service/Panic"},
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			panic("boom")
+		})
+
+	if resp != nil || status.Code(err) != codes.Internal {
+		t.Fatalf("Expected Internal error, got %v %v", resp, err)
+	}
+	if strings.Contains(err.Error(), "boom") {
+		t.Error("Expected panic value to be hidden from clients")
+	}
+	if recoveredValue != "boom" || !strings.Contains(string(recoveredStack), "recovery_test.go") {
+		t.Errorf("Expected panic value and stack in callback, got %v", recoveredValue)
+	}
+
+	expected := `
+# HELP grpc_server_panics_total Total number of panics recovered in gRPC handlers
+# TYPE grpc_server_panics_total counter
+grpc_server_panics_total{method="/test.Service/Panic"} 1
+`
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(expected), "grpc_server_panics_total"); err != nil {
+		t.Errorf("Unexpected panics_total: %v", err)
+	}
+
+	// Handlers that don't panic are unaffected
+	resp, err = interceptor(context.Background(), "req", &grpc.UnaryServerInfo{FullMethod: "/test.Service/OK"},
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			return "ok", nil
+		})
+	if resp != "ok" || err != nil {
+		t.Errorf("Expected normal response, got %v %v", resp, err)
+	}
+}
+
+func TestStreamRecovery(t *testing.T) {
+	interceptor := StreamRecovery(WithRecoveryLogger(zap.NewNop()), WithPanicDetails())
+
+	err := interceptor(nil, &recoveryServerStream{ctx: context.Background()}, &grpc.StreamServerInfo{FullMethod: "/test.Service/Stream"},
+		func(srv interface{}, stream grpc.ServerStream) error {
+			panic("stream boom")
+		})
+
+	if status.Code(err) != codes.Internal || !strings.Contains(err.Error(), "stream boom") {
+		t.Errorf("Expected Internal error with panic details, got %v", err)
+	}
+}
+
+// recoveryServerStream is a minimal grpc.ServerStream for stream recovery tests
+type recoveryServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *recoveryServerStream) Context() context.Context {
+	return s.ctx
+}
@@ -3,9 +3,13 @@ package middleware
 import (
 	"context"
 	"math"
-	"math/rand"
+	"sort"
+	"sync"
 	"time"
 
+	"github.com/grpc-guardian/grpc-guardian/pkg/classify"
+	"github.com/grpc-guardian/grpc-guardian/pkg/metrics"
+	"github.com/grpc-guardian/grpc-guardian/pkg/randutil"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -20,6 +24,12 @@ type Retry struct {
 	jitter           bool
 	retryableErrors  map[codes.Code]bool
 	onRetry          func(attempt int, err error, nextBackoff time.Duration)
+	redispatcher     *Redispatcher
+	metrics          metrics.RetryMetricsCollector
+	classifier       *classify.Classifier
+	fallback         Fallback
+	after            func(time.Duration) <-chan time.Time
+	rng              *randutil.Rand
 }
 
 // RetryOption configures a Retry middleware
@@ -75,10 +85,10 @@ func WithJitter(enabled bool) RetryOption {
 
 // WithRetryableCodes sets which gRPC status codes should trigger a retry
 // Default: Unavailable, ResourceExhausted, Aborted, DeadlineExceeded
-func WithRetryableCodes(codes ...codes.Code) RetryOption {
+func WithRetryableCodes(retryable ...codes.Code) RetryOption {
 	return func(r *Retry) {
 		r.retryableErrors = make(map[codes.Code]bool)
-		for _, code := range codes {
+		for _, code := range retryable {
 			r.retryableErrors[code] = true
 		}
 	}
@@ -91,6 +101,60 @@ func WithOnRetry(callback func(attempt int, err error, nextBackoff time.Duration
 	}
 }
 
+// WithRedispatcher re-sends idempotent requests that hit a draining connection immediately,
+// without consuming a retry attempt or waiting for backoff
+func WithRedispatcher(redispatcher *Redispatcher) RetryOption {
+	return func(r *Retry) {
+		r.redispatcher = redispatcher
+	}
+}
+
+// WithRetryMetrics records attempts, exhausted retries, recovered calls and attempts per call.
+// The collector must implement metrics.RetryMetricsCollector (PrometheusCollector, Multi and
+// Noop do); other collectors are ignored.
+func WithRetryMetrics(collector metrics.MetricsCollector) RetryOption {
+	return func(r *Retry) {
+		if rc, ok := collector.(metrics.RetryMetricsCollector); ok {
+			r.metrics = rc
+		}
+	}
+}
+
+// WithRetryClassifier only retries methods the classifier marks as reads. Writes and
+// unclassified methods are attempted once, since repeating them may apply a change twice.
+// Default: every method is retried
+func WithRetryClassifier(classifier *classify.Classifier) RetryOption {
+	return func(r *Retry) {
+		r.classifier = classifier
+	}
+}
+
+// WithRetryFallback serves unary calls whose retries are exhausted, i.e. that still fail
+// with a retryable code after the last attempt, with a degraded response. Metrics record the
+// underlying failure.
+func WithRetryFallback(fallback Fallback) RetryOption {
+	return func(r *Retry) {
+		r.fallback = fallback
+	}
+}
+
+// WithRetryTimer sets how backoff waits are timed, e.g. with a fake clock in tests
+// Default: time.After
+func WithRetryTimer(after func(d time.Duration) <-chan time.Time) RetryOption {
+	return func(r *Retry) {
+		r.after = after
+	}
+}
+
+// WithRetrySeed seeds the jitter source, so the same seed gives the same backoff
+// sequence, e.g. for reproducible tests
+// Default: seeded from the clock
+func WithRetrySeed(seed int64) RetryOption {
+	return func(r *Retry) {
+		r.rng = randutil.New(seed)
+	}
+}
+
 // NewRetry creates a new Retry middleware with default configuration
 func NewRetry(opts ...RetryOption) *Retry {
 	r := &Retry{
@@ -99,6 +163,8 @@ func NewRetry(opts ...RetryOption) *Retry {
 		maxBackoff:        10 * time.Second,
 		backoffMultiplier: 2.0,
 		jitter:            true,
+		after:             time.After,
+		rng:               randutil.NewTimeSeeded(),
 		retryableErrors: map[codes.Code]bool{
 			codes.Unavailable:       true,
 			codes.ResourceExhausted: true,
@@ -114,8 +180,53 @@ func NewRetry(opts ...RetryOption) *Retry {
 	return r
 }
 
+// RetryPolicy is the effective configuration of a Retry, e.g. for generating an equivalent
+// gRPC service config
+type RetryPolicy struct {
+	MaxAttempts       int
+	InitialBackoff    time.Duration
+	MaxBackoff        time.Duration
+	BackoffMultiplier float64
+	Jitter            bool
+	RetryableCodes    []codes.Code // Sorted by code
+}
+
+// Policy returns the effective retry configuration
+func (r *Retry) Policy() RetryPolicy {
+	policy := RetryPolicy{
+		MaxAttempts:       r.maxAttempts,
+		InitialBackoff:    r.initialBackoff,
+		MaxBackoff:        r.maxBackoff,
+		BackoffMultiplier: r.backoffMultiplier,
+		Jitter:            r.jitter,
+	}
+	for code, retryable := range r.retryableErrors {
+		if retryable {
+			policy.RetryableCodes = append(policy.RetryableCodes, code)
+		}
+	}
+	sort.Slice(policy.RetryableCodes, func(i, j int) bool { return policy.RetryableCodes[i] < policy.RetryableCodes[j] })
+	return policy
+}
+
 // UnaryClientInterceptor returns a unary client interceptor with retry logic
 func (r *Retry) UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	interceptor := r.unaryClientInterceptor()
+	if r.fallback == nil {
+		return interceptor
+	}
+
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		err := interceptor(ctx, method, req, reply, cc, invoker, opts...)
+		if err != nil && r.isRetryable(err) {
+			return invokeFallback(ctx, r.fallback, req, reply, err)
+		}
+		return err
+	}
+}
+
+// unaryClientInterceptor retries unary client calls
+func (r *Retry) unaryClientInterceptor() grpc.UnaryClientInterceptor {
 	return func(
 		ctx context.Context,
 		method string,
@@ -123,17 +234,30 @@ func (r *Retry) UnaryClientInterceptor() grpc.UnaryClientInterceptor {
 		cc *grpc.ClientConn,
 		invoker grpc.UnaryInvoker,
 		opts ...grpc.CallOption,
-	) error {
+	) (finalErr error) {
 		var lastErr error
 
-		for attempt := 1; attempt <= r.maxAttempts; attempt++ {
+		attempts := 0
+		if r.metrics != nil {
+			defer func() { r.recordResult(method, finalErr, attempts) }()
+		}
+
+		maxAttempts := r.attemptsFor(method)
+		for attempt := 1; attempt <= maxAttempts; attempt++ {
 			// Check if context is already cancelled
 			if ctx.Err() != nil {
 				return ctx.Err()
 			}
 
-			// Make the call
-			err := invoker(ctx, method, req, reply, cc, opts...)
+			// Make the call, re-dispatching connection drains when configured
+			var err error
+			if r.redispatcher != nil {
+				err = r.redispatcher.invoke(ctx, method, req, reply, cc, invoker, opts...)
+			} else {
+				err = invoker(ctx, method, req, reply, cc, opts...)
+			}
+			attempts = attempt
+			r.recordAttempt(method, err)
 
 			// Success - no retry needed
 			if err == nil {
@@ -148,7 +272,7 @@ func (r *Retry) UnaryClientInterceptor() grpc.UnaryClientInterceptor {
 			}
 
 			// Don't retry if this was the last attempt
-			if attempt >= r.maxAttempts {
+			if attempt >= maxAttempts {
 				break
 			}
 
@@ -162,7 +286,7 @@ func (r *Retry) UnaryClientInterceptor() grpc.UnaryClientInterceptor {
 
 			// Wait for backoff duration or context cancellation
 			select {
-			case <-time.After(backoff):
+			case <-r.after(backoff):
 				// Continue to next attempt
 			case <-ctx.Done():
 				return ctx.Err()
@@ -176,16 +300,38 @@ func (r *Retry) UnaryClientInterceptor() grpc.UnaryClientInterceptor {
 // UnaryServerInterceptor returns a unary server interceptor with retry logic
 // Note: Server-side retry is less common but can be useful for retrying downstream calls
 func (r *Retry) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	interceptor := r.unaryServerInterceptor()
+	if r.fallback == nil {
+		return interceptor
+	}
+
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		resp, err := interceptor(ctx, req, info, handler)
+		if err != nil && r.isRetryable(err) {
+			return r.fallback(ctx, req, err)
+		}
+		return resp, err
+	}
+}
+
+// unaryServerInterceptor retries unary handlers
+func (r *Retry) unaryServerInterceptor() grpc.UnaryServerInterceptor {
 	return func(
 		ctx context.Context,
 		req interface{},
 		info *grpc.UnaryServerInfo,
 		handler grpc.UnaryHandler,
-	) (interface{}, error) {
+	) (_ interface{}, finalErr error) {
 		var lastErr error
 		var resp interface{}
 
-		for attempt := 1; attempt <= r.maxAttempts; attempt++ {
+		attempts := 0
+		if r.metrics != nil {
+			defer func() { r.recordResult(info.FullMethod, finalErr, attempts) }()
+		}
+
+		maxAttempts := r.attemptsFor(info.FullMethod)
+		for attempt := 1; attempt <= maxAttempts; attempt++ {
 			// Check if context is already cancelled
 			if ctx.Err() != nil {
 				return nil, ctx.Err()
@@ -193,6 +339,8 @@ func (r *Retry) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
 
 			// Call the handler
 			resp, lastErr = handler(ctx, req)
+			attempts = attempt
+			r.recordAttempt(info.FullMethod, lastErr)
 
 			// Success - no retry needed
 			if lastErr == nil {
@@ -205,7 +353,7 @@ func (r *Retry) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
 			}
 
 			// Don't retry if this was the last attempt
-			if attempt >= r.maxAttempts {
+			if attempt >= maxAttempts {
 				break
 			}
 
@@ -219,7 +367,7 @@ func (r *Retry) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
 
 			// Wait for backoff duration or context cancellation
 			select {
-			case <-time.After(backoff):
+			case <-r.after(backoff):
 				// Continue to next attempt
 			case <-ctx.Done():
 				return nil, ctx.Err()
@@ -239,11 +387,17 @@ func (r *Retry) StreamClientInterceptor() grpc.StreamClientInterceptor {
 		method string,
 		streamer grpc.Streamer,
 		opts ...grpc.CallOption,
-	) (grpc.ClientStream, error) {
+	) (_ grpc.ClientStream, finalErr error) {
 		var lastErr error
 		var stream grpc.ClientStream
 
-		for attempt := 1; attempt <= r.maxAttempts; attempt++ {
+		attempts := 0
+		if r.metrics != nil {
+			defer func() { r.recordResult(method, finalErr, attempts) }()
+		}
+
+		maxAttempts := r.attemptsFor(method)
+		for attempt := 1; attempt <= maxAttempts; attempt++ {
 			// Check if context is already cancelled
 			if ctx.Err() != nil {
 				return nil, ctx.Err()
@@ -251,6 +405,8 @@ func (r *Retry) StreamClientInterceptor() grpc.StreamClientInterceptor {
 
 			// Establish stream
 			stream, lastErr = streamer(ctx, desc, cc, method, opts...)
+			attempts = attempt
+			r.recordAttempt(method, lastErr)
 
 			// Success
 			if lastErr == nil {
@@ -263,7 +419,7 @@ func (r *Retry) StreamClientInterceptor() grpc.StreamClientInterceptor {
 			}
 
 			// Don't retry if this was the last attempt
-			if attempt >= r.maxAttempts {
+			if attempt >= maxAttempts {
 				break
 			}
 
@@ -277,7 +433,7 @@ func (r *Retry) StreamClientInterceptor() grpc.StreamClientInterceptor {
 
 			// Wait for backoff duration or context cancellation
 			select {
-			case <-time.After(backoff):
+			case <-r.after(backoff):
 				// Continue to next attempt
 			case <-ctx.Done():
 				return nil, ctx.Err()
@@ -299,6 +455,30 @@ func (r *Retry) isRetryable(err error) bool {
 	return r.retryableErrors[st.Code()]
 }
 
+// attemptsFor returns the maximum number of attempts for a method
+func (r *Retry) attemptsFor(method string) int {
+	if r.classifier != nil && !r.classifier.IsRead(method) {
+		return 1
+	}
+	return r.maxAttempts
+}
+
+// recordAttempt reports the result of a single attempt
+func (r *Retry) recordAttempt(method string, err error) {
+	if r.metrics != nil {
+		r.metrics.RecordRetryAttempt(method, status.Code(err).String())
+	}
+}
+
+// recordResult reports the outcome of a call once no further attempts will be made
+func (r *Retry) recordResult(method string, err error, attempts int) {
+	if attempts == 0 || r.metrics == nil {
+		return // context ended before the first attempt, or no metrics
+	}
+	exhausted := err != nil && attempts >= r.maxAttempts && r.isRetryable(err)
+	r.metrics.RecordRetryResult(method, status.Code(err).String(), attempts, exhausted)
+}
+
 // calculateBackoff calculates the backoff duration for the given attempt
 // Uses exponential backoff with optional jitter
 func (r *Retry) calculateBackoff(attempt int) time.Duration {
@@ -312,7 +492,7 @@ func (r *Retry) calculateBackoff(attempt int) time.Duration {
 
 	// Add jitter if enabled (randomize between 0 and calculated backoff)
 	if r.jitter {
-		backoff = rand.Float64() * backoff
+		backoff = r.rng.Float64() * backoff
 	}
 
 	return time.Duration(backoff)
@@ -330,22 +510,75 @@ type RetryStats struct {
 // RetryWithStats wraps Retry with statistics tracking
 type RetryWithStats struct {
 	*Retry
-	stats RetryStats
+	stats *retryStatsRecorder
 }
 
-// NewRetryWithStats creates a new Retry middleware with statistics tracking
+// NewRetryWithStats creates a new Retry middleware with statistics tracking. Metrics
+// configured with WithRetryMetrics are still recorded.
 func NewRetryWithStats(opts ...RetryOption) *RetryWithStats {
-	return &RetryWithStats{
-		Retry: NewRetry(opts...),
-	}
+	r := NewRetry(opts...)
+	stats := &retryStatsRecorder{next: r.metrics}
+	r.metrics = stats
+	return &RetryWithStats{Retry: r, stats: stats}
 }
 
 // GetStats returns the current retry statistics
 func (r *RetryWithStats) GetStats() RetryStats {
-	return r.stats
+	return r.stats.get()
 }
 
 // ResetStats resets the retry statistics
 func (r *RetryWithStats) ResetStats() {
-	r.stats = RetryStats{}
+	r.stats.reset()
+}
+
+// retryStatsRecorder counts finished calls and forwards to the configured collector
+type retryStatsRecorder struct {
+	next metrics.RetryMetricsCollector
+
+	mu            sync.Mutex
+	stats         RetryStats
+	totalAttempts uint64
+}
+
+func (s *retryStatsRecorder) RecordRetryAttempt(method string, code string) {
+	if s.next != nil {
+		s.next.RecordRetryAttempt(method, code)
+	}
+}
+
+func (s *retryStatsRecorder) RecordRetryResult(method string, code string, attempts int, exhausted bool) {
+	if s.next != nil {
+		s.next.RecordRetryResult(method, code, attempts, exhausted)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stats.TotalRequests++
+	s.totalAttempts += uint64(attempts)
+	if attempts > 1 {
+		s.stats.TotalRetries += uint64(attempts - 1)
+		if code == codes.OK.String() {
+			s.stats.SuccessfulRetries++
+		} else {
+			s.stats.FailedRetries++
+		}
+	}
+}
+
+func (s *retryStatsRecorder) get() RetryStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats := s.stats
+	if stats.TotalRequests > 0 {
+		stats.AverageAttempts = float64(s.totalAttempts) / float64(stats.TotalRequests)
+	}
+	return stats
+}
+
+func (s *retryStatsRecorder) reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stats = RetryStats{}
+	s.totalAttempts = 0
 }
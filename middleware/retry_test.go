@@ -2,13 +2,18 @@ package middleware
 
 import (
 	"context"
-	"errors"
+	"strings"
 	"testing"
 	"time"
 
+	guardian "github.com/grpc-guardian/grpc-guardian"
+	"github.com/grpc-guardian/grpc-guardian/guardiantest"
+	"github.com/grpc-guardian/grpc-guardian/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/wrapperspb"
 )
 
 func TestRetry_Success(t *testing.T) {
@@ -198,7 +203,7 @@ func TestRetry_CustomRetryableCodes(t *testing.T) {
 		return "success", nil
 	}
 
-	resp, err := retry.UnaryServerInterceptor()(
+	_, err := retry.UnaryServerInterceptor()(
 		context.Background(),
 		"request",
 		&grpc.UnaryServerInfo{},
@@ -394,3 +399,102 @@ func BenchmarkRetry_WithRetries(b *testing.B) {
 		interceptor(context.Background(), "request", &grpc.UnaryServerInfo{}, handler)
 	}
 }
+
+func TestRetry_Metrics(t *testing.T) {
+	collector, err := metrics.NewPrometheusCollector()
+	if err != nil {
+		t.Fatalf("Failed to create collector: %v", err)
+	}
+
+	retry := NewRetry(
+		WithMaxAttempts(3),
+		WithInitialBackoff(time.Millisecond),
+		WithRetryMetrics(collector),
+	)
+	interceptor := retry.UnaryServerInterceptor()
+
+	// Recovered: fails once, then succeeds
+	calls := 0
+	_, _ = interceptor(context.Background(), "request", &grpc.UnaryServerInfo{FullMethod: "/test.Service/Flaky"},
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			calls++
+			if calls == 1 {
+				return nil, status.Error(codes.Unavailable, "unavailable")
+			}
+			return "ok", nil
+		})
+
+	// Exhausted: always fails
+	_, _ = interceptor(context.Background(), "request", &grpc.UnaryServerInfo{FullMethod: "/test.Service/Down"},
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			return nil, status.Error(codes.Unavailable, "unavailable")
+		})
+
+	expected := `
+# HELP grpc_retry_attempts_total Total number of attempts made by the retry middleware, by attempt result
+# TYPE grpc_retry_attempts_total counter
+grpc_retry_attempts_total{code="OK",method="/test.Service/Flaky"} 1
+grpc_retry_attempts_total{code="Unavailable",method="/test.Service/Down"} 3
+grpc_retry_attempts_total{code="Unavailable",method="/test.Service/Flaky"} 1
+# HELP grpc_retry_exhausted_total Total number of calls that failed after using all retry attempts
+# TYPE grpc_retry_exhausted_total counter
+grpc_retry_exhausted_total{code="Unavailable",method="/test.Service/Down"} 1
+# HELP grpc_retry_recovered_total Total number of calls that succeeded after at least one retry
+# TYPE grpc_retry_recovered_total counter
+grpc_retry_recovered_total{method="/test.Service/Flaky"} 1
+`
+	if err := testutil.GatherAndCompare(collector.GetRegistry(), strings.NewReader(expected),
+		"grpc_retry_attempts_total", "grpc_retry_exhausted_total", "grpc_retry_recovered_total"); err != nil {
+		t.Errorf("Unexpected retry metrics: %v", err)
+	}
+
+	if count := testutil.CollectAndCount(collector.GetRegistry(), "grpc_retry_call_attempts"); count != 2 {
+		t.Errorf("Expected attempts histogram for 2 method/code pairs, got %d", count)
+	}
+}
+
+func TestRetry_FakeClock(t *testing.T) {
+	clock := guardiantest.NewClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)).AutoAdvance()
+	start := clock.Now()
+	retry := NewRetry(
+		WithMaxAttempts(4),
+		WithInitialBackoff(time.Second),
+		WithJitter(false),
+		WithRetryTimer(clock.After),
+	)
+
+	h := guardiantest.New(t, nil, guardiantest.WithClientChain(guardian.NewClientChain(retry.UnaryClientInterceptor())))
+	h.HandleUnary("/test.Flaky/Get", &wrapperspb.StringValue{}, guardiantest.Sequence(
+		guardiantest.Fail(codes.Unavailable, "down"),
+		guardiantest.Fail(codes.Unavailable, "down"),
+		guardiantest.Fail(codes.Unavailable, "down"),
+		guardiantest.Respond(wrapperspb.String("ok")),
+	))
+
+	reply := &wrapperspb.StringValue{}
+	if err := h.Invoke(context.Background(), "/test.Flaky/Get", wrapperspb.String("key"), reply); err != nil || reply.GetValue() != "ok" {
+		t.Fatalf("Expected the retried call to succeed, got %v, %v", reply, err)
+	}
+	if calls := len(h.Calls("/test.Flaky/Get")); calls != 4 {
+		t.Errorf("Expected 4 attempts, got %d", calls)
+	}
+	// Backoff doubles: 1s + 2s + 4s, without waiting in real time
+	if waited := clock.Since(start); waited != 7*time.Second {
+		t.Errorf("Expected 7s of backoff, got %s", waited)
+	}
+}
+
+func TestRetry_Seed(t *testing.T) {
+	a := NewRetry(WithRetrySeed(7), WithInitialBackoff(time.Second))
+	b := NewRetry(WithRetrySeed(7), WithInitialBackoff(time.Second))
+
+	for attempt := 1; attempt <= 5; attempt++ {
+		x, y := a.calculateBackoff(attempt), b.calculateBackoff(attempt)
+		if x != y {
+			t.Errorf("Attempt %d: expected the same jittered backoff, got %v and %v", attempt, x, y)
+		}
+		if x < 0 || x > a.maxBackoff {
+			t.Errorf("Attempt %d: backoff %v out of range", attempt, x)
+		}
+	}
+}
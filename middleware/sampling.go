@@ -0,0 +1,403 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/grpc-guardian/grpc-guardian/pkg/logging"
+	"github.com/grpc-guardian/grpc-guardian/pkg/randutil"
+	"golang.org/x/time/rate"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// SampleSchemaVersion identifies the layout of exported Sample records
+const SampleSchemaVersion = "guardian.sample.v1"
+
+// Sample is a single exported request/response pair
+type Sample struct {
+	Schema     string          `json:"schema"`
+	Method     string          `json:"method"`
+	Timestamp  time.Time       `json:"timestamp"`
+	DurationMs float64         `json:"duration_ms"`
+	Code       string          `json:"code"`
+	Request    json.RawMessage `json:"request,omitempty"`
+	Response   json.RawMessage `json:"response,omitempty"`
+	UserID     string          `json:"user_id,omitempty"`
+}
+
+// SampleSink receives batches of samples. Implementations can upload to GCS/S3,
+// produce to Kafka, or write to local files.
+type SampleSink interface {
+	Write(ctx context.Context, samples []Sample) error
+}
+
+// SamplingConfig holds configuration for the sampling exporter
+type SamplingConfig struct {
+	Sink          SampleSink                                       // Destination for samples
+	Rate          float64                                          // Fraction of requests to sample (0.0-1.0)
+	MethodRates   map[string]float64                               // Per-method fractions; if set, only these methods are sampled
+	MaxPerSecond  float64                                          // Hard cap on exported samples per second
+	Burst         int                                              // Burst allowed above MaxPerSecond
+	BufferSize    int                                              // Samples buffered before dropping
+	BatchSize     int                                              // Samples per sink write
+	FlushInterval time.Duration                                    // Max time a sample waits in a partial batch
+	Redact        func(method string, msg interface{}) interface{} // Redacts messages before serialization
+	OnDrop        func(method string, reason string)               // Called when a sample is dropped
+	OnError       func(error)                                      // Called when the sink fails
+	Seed          int64                                            // Seed for sampling decisions, used when Seeded
+	Seeded        bool                                             // Whether Seed is set; otherwise seeded from the clock
+}
+
+// SamplingOption is a functional option for sampling configuration
+type SamplingOption func(*SamplingConfig)
+
+// WithSampleSink sets the sink samples are exported to
+func WithSampleSink(sink SampleSink) SamplingOption {
+	return func(c *SamplingConfig) {
+		c.Sink = sink
+	}
+}
+
+// WithSampleRate sets the default fraction of requests to sample
+func WithSampleRate(fraction float64) SamplingOption {
+	return func(c *SamplingConfig) {
+		c.Rate = fraction
+	}
+}
+
+// WithSampledMethod samples only the given methods, each with its own fraction
+func WithSampledMethod(method string, fraction float64) SamplingOption {
+	return func(c *SamplingConfig) {
+		if c.MethodRates == nil {
+			c.MethodRates = make(map[string]float64)
+		}
+		c.MethodRates[method] = fraction
+	}
+}
+
+// WithSampleRateCap caps the number of exported samples per second
+func WithSampleRateCap(perSecond float64, burst int) SamplingOption {
+	return func(c *SamplingConfig) {
+		c.MaxPerSecond = perSecond
+		c.Burst = burst
+	}
+}
+
+// WithSampleBatching sets the batch size and flush interval for sink writes
+// Default: 100 samples, 5s
+func WithSampleBatching(size int, flushInterval time.Duration) SamplingOption {
+	return func(c *SamplingConfig) {
+		c.BatchSize = size
+		c.FlushInterval = flushInterval
+	}
+}
+
+// WithSampleBufferSize sets how many samples may be queued before new ones are dropped
+// Default: 1000
+func WithSampleBufferSize(n int) SamplingOption {
+	return func(c *SamplingConfig) {
+		c.BufferSize = n
+	}
+}
+
+// WithSampleRedactor sets a function that redacts messages before they are serialized,
+// replacing the default redaction
+func WithSampleRedactor(fn func(method string, msg interface{}) interface{}) SamplingOption {
+	return func(c *SamplingConfig) {
+		c.Redact = fn
+	}
+}
+
+// WithSampleRedaction configures how messages are redacted before they are serialized.
+// Proto messages keep their type, so masked fields hold the mask (strings and bytes) or
+// their zero value (other kinds).
+// Default: only proto fields declared with [debug_redact = true] are masked
+func WithSampleRedaction(opts ...logging.RedactOption) SamplingOption {
+	return func(c *SamplingConfig) {
+		c.Redact = sampleRedactor(logging.NewRedactor(opts...))
+	}
+}
+
+// sampleRedactor adapts a Redactor to SamplingConfig.Redact
+func sampleRedactor(redactor *logging.Redactor) func(method string, msg interface{}) interface{} {
+	return func(method string, msg interface{}) interface{} {
+		if pm, ok := msg.(proto.Message); ok {
+			return redactor.RedactProto(method, pm)
+		}
+		return redactor.Redact(method, msg)
+	}
+}
+
+// WithSampleDropCallback sets a callback invoked when a sample is dropped
+func WithSampleDropCallback(fn func(method string, reason string)) SamplingOption {
+	return func(c *SamplingConfig) {
+		c.OnDrop = fn
+	}
+}
+
+// WithSampleErrorCallback sets a callback invoked when the sink returns an error
+func WithSampleErrorCallback(fn func(error)) SamplingOption {
+	return func(c *SamplingConfig) {
+		c.OnError = fn
+	}
+}
+
+// WithSampleSeed seeds the sampling decisions, so the same seed and sequence of requests
+// sample the same requests, e.g. for reproducible tests
+// Default: seeded from the clock
+func WithSampleSeed(seed int64) SamplingOption {
+	return func(c *SamplingConfig) {
+		c.Seed = seed
+		c.Seeded = true
+	}
+}
+
+// Sampler exports a fraction of request/response pairs to a SampleSink.
+// Export happens on a background goroutine and never blocks or fails the RPC.
+type Sampler struct {
+	config  *SamplingConfig
+	limiter *rate.Limiter
+	rng     *randutil.Rand
+	queue   chan Sample
+	done    chan struct{}
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+// NewSampler creates a sampling exporter and starts its background writer
+//
+// Example usage:
+//
+//	sampler := middleware.NewSampler(
+//	    middleware.WithSampleSink(kafkaSink),
+//	    middleware.WithSampledMethod("/shop.Catalog/Search", 0.01),
+//	    middleware.WithSampleRateCap(50, 10),
+//	)
+//	defer sampler.Close()
+//	chain := guardian.NewChain(sampler.UnaryServerInterceptor())
+func NewSampler(opts ...SamplingOption) *Sampler {
+	config := &SamplingConfig{
+		Rate:          0.01,
+		MaxPerSecond:  10,
+		Burst:         10,
+		BufferSize:    1000,
+		BatchSize:     100,
+		FlushInterval: 5 * time.Second,
+	}
+
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	if config.Burst <= 0 {
+		config.Burst = 1
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = 1
+	}
+	if config.BufferSize <= 0 {
+		config.BufferSize = 1000
+	}
+	if config.FlushInterval <= 0 {
+		config.FlushInterval = 5 * time.Second
+	}
+	if config.Redact == nil {
+		config.Redact = sampleRedactor(logging.NewRedactor())
+	}
+
+	s := &Sampler{
+		config:  config,
+		limiter: rate.NewLimiter(rate.Limit(config.MaxPerSecond), config.Burst),
+		rng:     randutil.NewTimeSeeded(),
+		queue:   make(chan Sample, config.BufferSize),
+		done:    make(chan struct{}),
+	}
+	if config.Seeded {
+		s.rng = randutil.New(config.Seed)
+	}
+
+	go s.run()
+
+	return s
+}
+
+// UnaryServerInterceptor returns a unary server interceptor that samples requests
+func (s *Sampler) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if s.config.Sink == nil || !s.shouldSample(info.FullMethod) {
+			return handler(ctx, req)
+		}
+
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		duration := time.Since(start)
+
+		// Enforce the export cap after the sampling decision so the fraction stays representative
+		if !s.limiter.Allow() {
+			s.drop(info.FullMethod, "rate_capped")
+			return resp, err
+		}
+
+		sample := Sample{
+			Schema:     SampleSchemaVersion,
+			Method:     info.FullMethod,
+			Timestamp:  start,
+			DurationMs: float64(duration) / float64(time.Millisecond),
+			Code:       status.Code(err).String(),
+			Request:    s.encode(info.FullMethod, req),
+		}
+		if err == nil {
+			sample.Response = s.encode(info.FullMethod, resp)
+		}
+		if userID, ok := GetUserID(ctx); ok {
+			sample.UserID = userID
+		}
+
+		s.enqueue(sample)
+
+		return resp, err
+	}
+}
+
+// Close flushes buffered samples and stops the background writer
+func (s *Sampler) Close() {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return
+	}
+	s.closed = true
+	close(s.queue)
+	s.mu.Unlock()
+
+	<-s.done
+}
+
+// enqueue hands a sample to the background writer without blocking
+func (s *Sampler) enqueue(sample Sample) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		s.drop(sample.Method, "closed")
+		return
+	}
+
+	select {
+	case s.queue <- sample:
+	default:
+		s.drop(sample.Method, "buffer_full")
+	}
+}
+
+// shouldSample makes the per-request sampling decision
+func (s *Sampler) shouldSample(method string) bool {
+	fraction := s.config.Rate
+	if len(s.config.MethodRates) > 0 {
+		methodRate, ok := s.config.MethodRates[method]
+		if !ok {
+			return false
+		}
+		fraction = methodRate
+	}
+	return fraction > 0 && s.rng.Float64() < fraction
+}
+
+// encode redacts and serializes a message, preferring protojson for proto messages
+func (s *Sampler) encode(method string, msg interface{}) json.RawMessage {
+	if msg == nil {
+		return nil
+	}
+	if s.config.Redact != nil {
+		msg = s.config.Redact(method, msg)
+	}
+
+	var (
+		data []byte
+		err  error
+	)
+	if pm, ok := msg.(proto.Message); ok {
+		data, err = protojson.Marshal(pm)
+	} else {
+		data, err = json.Marshal(msg)
+	}
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+// drop reports a dropped sample
+func (s *Sampler) drop(method, reason string) {
+	if s.config.OnDrop != nil {
+		s.config.OnDrop(method, reason)
+	}
+}
+
+// run batches queued samples and writes them to the sink
+func (s *Sampler) run() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(s.config.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]Sample, 0, s.config.BatchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := s.config.Sink.Write(context.Background(), batch); err != nil && s.config.OnError != nil {
+			s.config.OnError(fmt.Errorf("sample sink write failed: %w", err))
+		}
+		batch = make([]Sample, 0, s.config.BatchSize)
+	}
+
+	for {
+		select {
+		case sample, ok := <-s.queue:
+			if !ok {
+				flush()
+				return
+			}
+			batch = append(batch, sample)
+			if len(batch) >= s.config.BatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+// WriterSink writes samples as JSON lines to an io.Writer (files, stdout, pipes)
+type WriterSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewWriterSink creates a JSON-lines sample sink
+func NewWriterSink(w io.Writer) *WriterSink {
+	return &WriterSink{w: w}
+}
+
+// Write encodes each sample as a single JSON line
+func (ws *WriterSink) Write(ctx context.Context, samples []Sample) error {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	enc := json.NewEncoder(ws.w)
+	for _, sample := range samples {
+		if err := enc.Encode(sample); err != nil {
+			return err
+		}
+	}
+	return nil
+}
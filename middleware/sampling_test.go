@@ -0,0 +1,283 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/grpc-guardian/grpc-guardian/pkg/logging"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// memorySampleSink collects samples in memory
+type memorySampleSink struct {
+	mu      sync.Mutex
+	samples []Sample
+}
+
+func (m *memorySampleSink) Write(ctx context.Context, samples []Sample) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.samples = append(m.samples, samples...)
+	return nil
+}
+
+func (m *memorySampleSink) all() []Sample {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Sample(nil), m.samples...)
+}
+
+func TestSampler_SelectedMethods(t *testing.T) {
+	sink := &memorySampleSink{}
+	sampler := NewSampler(
+		WithSampleSink(sink),
+		WithSampledMethod("/test.Service/Sampled", 1.0),
+		WithSampleRateCap(1000, 1000),
+	)
+	interceptor := sampler.UnaryServerInterceptor()
+
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return map[string]string{"result": "ok"}, nil
+	}
+
+	for i := 0; i < 5; i++ {
+		interceptor(context.Background(), map[string]int{"id": i}, &grpc.UnaryServerInfo{FullMethod: "/test.Service/Sampled"}, handler)
+		interceptor(context.Background(), map[string]int{"id": i}, &grpc.UnaryServerInfo{FullMethod: "/test.Service/Other"}, handler)
+	}
+
+	sampler.Close()
+
+	samples := sink.all()
+	if len(samples) != 5 {
+		t.Fatalf("Expected 5 samples, got %d", len(samples))
+	}
+
+	for _, s := range samples {
+		if s.Method != "/test.Service/Sampled" {
+			t.Errorf("Unexpected sampled method %s", s.Method)
+		}
+		if s.Schema != SampleSchemaVersion {
+			t.Errorf("Expected schema %s, got %s", SampleSchemaVersion, s.Schema)
+		}
+		if s.Code != codes.OK.String() {
+			t.Errorf("Expected OK code, got %s", s.Code)
+		}
+		if string(s.Response) != `{"result":"ok"}` {
+			t.Errorf("Unexpected response payload %s", s.Response)
+		}
+	}
+}
+
+func TestSampler_RateCapAndRedaction(t *testing.T) {
+	sink := &memorySampleSink{}
+	var mu sync.Mutex
+	drops := 0
+
+	sampler := NewSampler(
+		WithSampleSink(sink),
+		WithSampleRate(1.0),
+		WithSampleRateCap(0.001, 2),
+		WithSampleRedactor(func(method string, msg interface{}) interface{} {
+			return map[string]string{"password": "***"}
+		}),
+		WithSampleDropCallback(func(method, reason string) {
+			mu.Lock()
+			drops++
+			mu.Unlock()
+		}),
+	)
+	interceptor := sampler.UnaryServerInterceptor()
+
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, status.Error(codes.NotFound, "missing")
+	}
+
+	for i := 0; i < 10; i++ {
+		interceptor(context.Background(), map[string]string{"password": "hunter2"}, &grpc.UnaryServerInfo{FullMethod: "/test.Service/Method"}, handler)
+	}
+
+	sampler.Close()
+
+	samples := sink.all()
+	if len(samples) != 2 {
+		t.Fatalf("Expected rate cap to allow 2 samples, got %d", len(samples))
+	}
+	if drops != 8 {
+		t.Errorf("Expected 8 dropped samples, got %d", drops)
+	}
+	if bytes.Contains(samples[0].Request, []byte("hunter2")) {
+		t.Error("Expected request to be redacted")
+	}
+	if samples[0].Code != codes.NotFound.String() || samples[0].Response != nil {
+		t.Errorf("Expected NotFound without response, got %s %s", samples[0].Code, samples[0].Response)
+	}
+
+	// Calls after Close must not panic
+	interceptor(context.Background(), "req", &grpc.UnaryServerInfo{FullMethod: "/test.Service/Method"}, handler)
+}
+
+func TestWriterSink(t *testing.T) {
+	var buf bytes.Buffer
+	sink := NewWriterSink(&buf)
+
+	err := sink.Write(context.Background(), []Sample{
+		{Schema: SampleSchemaVersion, Method: "/a/B", Timestamp: time.Unix(0, 0)},
+		{Schema: SampleSchemaVersion, Method: "/a/C", Timestamp: time.Unix(0, 0)},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 JSON lines, got %d", len(lines))
+	}
+
+	var decoded Sample
+	if err := json.Unmarshal(lines[1], &decoded); err != nil || decoded.Method != "/a/C" {
+		t.Errorf("Failed to decode sample line: %v %+v", err, decoded)
+	}
+}
+
+// failingSampleSink always fails
+type failingSampleSink struct{}
+
+func (failingSampleSink) Write(ctx context.Context, samples []Sample) error {
+	return errors.New("sink unavailable")
+}
+
+func TestSampler_SinkErrorsDoNotAffectRPC(t *testing.T) {
+	var sinkErr error
+	sampler := NewSampler(
+		WithSampleSink(failingSampleSink{}),
+		WithSampleRate(1.0),
+		WithSampleErrorCallback(func(err error) { sinkErr = err }),
+	)
+
+	resp, err := sampler.UnaryServerInterceptor()(context.Background(), "req",
+		&grpc.UnaryServerInfo{FullMethod: "/test.Service/Method"},
+		func(ctx context.Context, req interface{}) (interface{}, error) { return "ok", nil })
+	sampler.Close()
+
+	if err != nil || resp != "ok" {
+		t.Errorf("Expected RPC to succeed, got %v %v", resp, err)
+	}
+	if sinkErr == nil {
+		t.Error("Expected sink error to be reported")
+	}
+}
+
+func TestSeededSampling(t *testing.T) {
+	const method = "/test.Service/Method"
+	newSampler := func(seed int64) func() bool {
+		s := NewSampler(WithSampleRate(0.5), WithSampleSeed(seed))
+		t.Cleanup(s.Close)
+		return func() bool { return s.shouldSample(method) }
+	}
+	newRecorder := func(seed int64) func() bool {
+		r := NewTrafficRecorder(&memoryRecordSink{}, WithRecordingRate(0.5), WithRecordingSeed(seed))
+		t.Cleanup(r.Close)
+		return func() bool { return r.shouldRecord(method) }
+	}
+	newShadow := func(seed int64) func() bool {
+		return NewShadow(nil, WithShadowPercentage(50), WithShadowSeed(seed)).sampled
+	}
+	newMirror := func(seed int64) func() bool {
+		m := NewMirror(nil, WithMirrorPercentage(50), WithMirrorSeed(seed))
+		return func() bool { return m.sampled(method) }
+	}
+	newReporter := func(seed int64) func() bool {
+		return newErrorReporterConfig([]ErrorReporterOption{WithReportSampleRate(0.5), WithReportSeed(seed)}).sampled
+	}
+
+	tests := map[string]func(seed int64) func() bool{
+		"sampler":        newSampler,
+		"recorder":       newRecorder,
+		"shadow":         newShadow,
+		"mirror":         newMirror,
+		"error reporter": newReporter,
+	}
+	for name, build := range tests {
+		t.Run(name, func(t *testing.T) {
+			decisions := func(seed int64) string {
+				sampled := build(seed)
+				var out []byte
+				for i := 0; i < 64; i++ {
+					if sampled() {
+						out = append(out, '1')
+					} else {
+						out = append(out, '0')
+					}
+				}
+				return string(out)
+			}
+
+			first := decisions(42)
+			if again := decisions(42); again != first {
+				t.Errorf("Expected the same seed to make the same decisions:\n%s\n%s", first, again)
+			}
+			if other := decisions(43); other == first {
+				t.Error("Expected another seed to make other decisions")
+			}
+			if n := bytes.Count([]byte(first), []byte("1")); n == 0 || n == 64 {
+				t.Errorf("Expected about half of the requests to be sampled, got %d of 64", n)
+			}
+		})
+	}
+}
+
+func TestSampler_DefaultRedaction(t *testing.T) {
+	sink := &memorySampleSink{}
+	sampler := NewSampler(WithSampleSink(sink), WithSampleRate(1.0))
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) { return nil, nil }
+
+	// api_token is declared with debug_redact, so it is masked without any configuration
+	sampler.UnaryServerInterceptor()(context.Background(), redactTestMessage(t), &grpc.UnaryServerInfo{FullMethod: "/users.Users/Get"}, handler)
+	sampler.Close()
+
+	samples := sink.all()
+	if len(samples) != 1 {
+		t.Fatalf("Expected 1 sample, got %d", len(samples))
+	}
+	if bytes.Contains(samples[0].Request, []byte("tok-1")) || !bytes.Contains(samples[0].Request, []byte("alice")) {
+		t.Errorf("Expected only the debug_redact field to be masked, got %s", samples[0].Request)
+	}
+
+	sink = &memorySampleSink{}
+	sampler = NewSampler(WithSampleSink(sink), WithSampleRate(1.0), WithSampleRedaction(logging.WithRedactedFields("password")))
+	sampler.UnaryServerInterceptor()(context.Background(), map[string]string{"password": "hunter2", "user": "bob"}, &grpc.UnaryServerInfo{FullMethod: "/users.Users/Get"}, handler)
+	sampler.Close()
+
+	if samples := sink.all(); len(samples) != 1 || bytes.Contains(samples[0].Request, []byte("hunter2")) {
+		t.Errorf("Expected the configured field to be masked, got %+v", samples)
+	}
+}
+
+func TestSampler_InvalidConfig(t *testing.T) {
+	sink := &memorySampleSink{}
+
+	// A zero flush interval and a negative buffer size fall back to the defaults
+	sampler := NewSampler(
+		WithSampleSink(sink),
+		WithSampleRate(1.0),
+		WithSampleBatching(10, 0),
+		WithSampleBufferSize(-1),
+	)
+	if sampler.config.FlushInterval != 5*time.Second || sampler.config.BufferSize != 1000 {
+		t.Errorf("Expected the defaults, got flush interval %v and buffer size %d", sampler.config.FlushInterval, sampler.config.BufferSize)
+	}
+
+	sampler.UnaryServerInterceptor()(context.Background(), "req", &grpc.UnaryServerInfo{FullMethod: "/test.Service/Method"},
+		func(ctx context.Context, req interface{}) (interface{}, error) { return "ok", nil })
+	sampler.Close()
+	if got := len(sink.all()); got != 1 {
+		t.Errorf("Expected the sample to be flushed on Close, got %d", got)
+	}
+}
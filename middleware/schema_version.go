@@ -0,0 +1,241 @@
+package middleware
+
+import (
+	"context"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// SchemaVersionsHeader carries the payload schema versions a client supports
+	SchemaVersionsHeader = "x-schema-versions"
+
+	// SchemaVersionHeader carries the schema version the server selected
+	SchemaVersionHeader = "x-schema-version"
+)
+
+// SchemaVersions negotiates payload schema versions per method. On the client it advertises
+// the versions the client understands; on the server it selects the preferred version both
+// sides support, stores it in the context for handlers (GetSchemaVersion) and returns it in
+// the response header.
+type SchemaVersions struct {
+	matcher        *methodMatcher[[]string]
+	defaultVersion string
+	selected       *prometheus.CounterVec
+}
+
+// SchemaVersionOption configures SchemaVersions
+type SchemaVersionOption func(*schemaVersionConfig)
+
+// schemaVersionConfig collects options before the negotiator is built
+type schemaVersionConfig struct {
+	versions       map[string][]string
+	defaultVersion string
+	registerer     prometheus.Registerer
+}
+
+// WithSchemaVersions sets the versions supported for a method pattern, in order of preference
+// (newest first). Patterns follow the Authorization rules: "/pkg.Service/Method",
+// "/pkg.Service/*" or "*".
+func WithSchemaVersions(pattern string, versions ...string) SchemaVersionOption {
+	return func(c *schemaVersionConfig) {
+		c.versions[pattern] = versions
+	}
+}
+
+// WithDefaultSchemaVersion sets the version used for clients that advertise no versions
+// Default: the last (oldest) version listed for the method
+func WithDefaultSchemaVersion(version string) SchemaVersionOption {
+	return func(c *schemaVersionConfig) {
+		c.defaultVersion = version
+	}
+}
+
+// WithSchemaVersionMetrics registers a grpc_server_schema_version_selected_total counter,
+// labeled by method and version, to track version adoption
+func WithSchemaVersionMetrics(registerer prometheus.Registerer) SchemaVersionOption {
+	return func(c *schemaVersionConfig) {
+		c.registerer = registerer
+	}
+}
+
+// NewSchemaVersions creates a schema version negotiator.
+//
+// Example usage:
+//
+//	versions := middleware.NewSchemaVersions(
+//	    middleware.WithSchemaVersions("/orders.Orders/*", "v3", "v2"),
+//	    middleware.WithSchemaVersionMetrics(collector.GetRegistry()),
+//	)
+//	server := grpc.NewServer(grpc.ChainUnaryInterceptor(versions.UnaryServerInterceptor()))
+//
+//	// In the handler
+//	if v, _ := middleware.GetSchemaVersion(ctx); v == "v2" {
+//	    return legacyResponse(order), nil
+//	}
+func NewSchemaVersions(opts ...SchemaVersionOption) *SchemaVersions {
+	config := &schemaVersionConfig{versions: make(map[string][]string)}
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	s := &SchemaVersions{
+		matcher:        newMethodMatcher(config.versions),
+		defaultVersion: config.defaultVersion,
+	}
+
+	if config.registerer != nil {
+		s.selected = prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "grpc",
+			Subsystem: "server",
+			Name:      "schema_version_selected_total",
+			Help:      "Total number of requests served per negotiated payload schema version",
+		}, []string{"method", "version"})
+		if err := config.registerer.Register(s.selected); err != nil {
+			if are, ok := err.(prometheus.AlreadyRegisteredError); ok {
+				s.selected = are.ExistingCollector.(*prometheus.CounterVec)
+			}
+		}
+	}
+
+	return s
+}
+
+// GetSchemaVersion retrieves the negotiated schema version from context
+func GetSchemaVersion(ctx context.Context) (string, bool) {
+	version, ok := ctx.Value(contextKeySchemaVersion).(string)
+	return version, ok
+}
+
+// SchemaVersionFromHeader returns the version the server selected, from response header
+// metadata captured with grpc.Header
+func SchemaVersionFromHeader(md metadata.MD) (string, bool) {
+	values := md.Get(SchemaVersionHeader)
+	if len(values) == 0 {
+		return "", false
+	}
+	return values[0], true
+}
+
+// UnaryServerInterceptor returns a unary server interceptor that negotiates the schema version
+func (s *SchemaVersions) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := s.negotiate(ctx, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor returns a stream server interceptor that negotiates the schema version
+func (s *SchemaVersions) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := s.negotiate(ss.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &schemaVersionStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// UnaryClientInterceptor returns a unary client interceptor that advertises supported versions
+func (s *SchemaVersions) UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return invoker(s.advertise(ctx, method), method, req, reply, cc, opts...)
+	}
+}
+
+// StreamClientInterceptor returns a stream client interceptor that advertises supported versions
+func (s *SchemaVersions) StreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return streamer(s.advertise(ctx, method), desc, cc, method, opts...)
+	}
+}
+
+// advertise adds the versions supported for method to the outgoing metadata
+func (s *SchemaVersions) advertise(ctx context.Context, method string) context.Context {
+	versions, ok := s.matcher.match(method)
+	if !ok || len(versions) == 0 {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, SchemaVersionsHeader, strings.Join(versions, ","))
+}
+
+// negotiate selects the schema version for a request and records it
+func (s *SchemaVersions) negotiate(ctx context.Context, method string) (context.Context, error) {
+	supported, ok := s.matcher.match(method)
+	if !ok || len(supported) == 0 {
+		return ctx, nil // Method is not versioned
+	}
+
+	advertised := advertisedSchemaVersions(ctx)
+
+	var version string
+	if len(advertised) == 0 {
+		version = s.defaultVersion
+		if version == "" {
+			version = supported[len(supported)-1]
+		}
+	} else {
+		accepted := make(map[string]bool, len(advertised))
+		for _, v := range advertised {
+			accepted[v] = true
+		}
+		for _, candidate := range supported {
+			if accepted[candidate] {
+				version = candidate
+				break
+			}
+		}
+		if version == "" {
+			return ctx, status.Errorf(codes.FailedPrecondition,
+				"no common schema version for %s: client supports %s, server supports %s\nHint: Upgrade the client or keep an older schema version enabled on the server",
+				method, strings.Join(advertised, ", "), strings.Join(supported, ", "))
+		}
+	}
+
+	// Header errors only occur outside a real transport (e.g. in unit tests)
+	_ = grpc.SetHeader(ctx, metadata.Pairs(SchemaVersionHeader, version))
+
+	if s.selected != nil {
+		s.selected.WithLabelValues(method, version).Inc()
+	}
+
+	return context.WithValue(ctx, contextKeySchemaVersion, version), nil
+}
+
+// advertisedSchemaVersions reads the client's supported versions from incoming metadata.
+// Multiple header values and comma-separated lists are both accepted.
+func advertisedSchemaVersions(ctx context.Context) []string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return nil
+	}
+
+	var versions []string
+	for _, value := range md.Get(SchemaVersionsHeader) {
+		for _, v := range strings.Split(value, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				versions = append(versions, v)
+			}
+		}
+	}
+	return versions
+}
+
+// schemaVersionStream carries the negotiated version in the stream context
+type schemaVersionStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context returns the context with the negotiated schema version
+func (s *schemaVersionStream) Context() context.Context {
+	return s.ctx
+}
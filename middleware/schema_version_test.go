@@ -0,0 +1,113 @@
+package middleware
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestSchemaVersions_Negotiation(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	versions := NewSchemaVersions(
+		WithSchemaVersions("/orders.Orders/*", "v3", "v2", "v1"),
+		WithSchemaVersionMetrics(registry),
+	)
+	interceptor := versions.UnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/orders.Orders/Get"}
+
+	tests := []struct {
+		name       string
+		advertised []string
+		want       string
+		wantCode   codes.Code
+	}{
+		{name: "newest common version", advertised: []string{"v1,v2"}, want: "v2"},
+		{name: "multiple header values", advertised: []string{"v1", "v3"}, want: "v3"},
+		{name: "no advertisement uses oldest", want: "v1"},
+		{name: "no common version", advertised: []string{"v4"}, wantCode: codes.FailedPrecondition},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if len(tt.advertised) > 0 {
+				md := metadata.MD{}
+				md.Append(SchemaVersionsHeader, tt.advertised...)
+				ctx = metadata.NewIncomingContext(ctx, md)
+			}
+
+			var got string
+			_, err := interceptor(ctx, "req", info, func(ctx context.Context, req interface{}) (interface{}, error) {
+				got, _ = GetSchemaVersion(ctx)
+				return "ok", nil
+			})
+
+			if tt.wantCode != codes.OK {
+				if status.Code(err) != tt.wantCode {
+					t.Fatalf("Expected %v, got %v", tt.wantCode, err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("Expected version %q, got %q (err: %v)", tt.want, got, err)
+			}
+		})
+	}
+
+	expected := `
+# HELP grpc_server_schema_version_selected_total Total number of requests served per negotiated payload schema version
+# TYPE grpc_server_schema_version_selected_total counter
+grpc_server_schema_version_selected_total{method="/orders.Orders/Get",version="v1"} 1
+grpc_server_schema_version_selected_total{method="/orders.Orders/Get",version="v2"} 1
+grpc_server_schema_version_selected_total{method="/orders.Orders/Get",version="v3"} 1
+`
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(expected)); err != nil {
+		t.Errorf("Unexpected adoption metrics: %v", err)
+	}
+}
+
+func TestSchemaVersions_UnversionedMethod(t *testing.T) {
+	versions := NewSchemaVersions(WithSchemaVersions("/orders.Orders/*", "v2", "v1"))
+
+	_, err := versions.UnaryServerInterceptor()(context.Background(), "req", &grpc.UnaryServerInfo{FullMethod: "/users.Users/Get"},
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			if _, ok := GetSchemaVersion(ctx); ok {
+				t.Error("Expected no schema version for unversioned method")
+			}
+			return "ok", nil
+		})
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+}
+
+func TestSchemaVersions_ClientAdvertises(t *testing.T) {
+	versions := NewSchemaVersions(
+		WithSchemaVersions("/orders.Orders/*", "v2", "v1"),
+		WithDefaultSchemaVersion("v2"),
+	)
+
+	var advertised []string
+	err := versions.UnaryClientInterceptor()(context.Background(), "/orders.Orders/Get", "req", nil, nil,
+		func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+			md, _ := metadata.FromOutgoingContext(ctx)
+			advertised = md.Get(SchemaVersionsHeader)
+			return nil
+		})
+
+	if err != nil || len(advertised) != 1 || advertised[0] != "v2,v1" {
+		t.Errorf("Expected advertised versions v2,v1, got %v (err: %v)", advertised, err)
+	}
+
+	version, ok := SchemaVersionFromHeader(metadata.Pairs(SchemaVersionHeader, "v2"))
+	if !ok || version != "v2" {
+		t.Errorf("Expected v2 from response header, got %q", version)
+	}
+}
@@ -296,6 +296,38 @@ func Linkerd(meshConfig *servicemesh.Config, opts ...ServiceMeshOption) (*Servic
 	return NewServiceMeshMiddleware(mesh, opts...), nil
 }
 
+// Consul creates a new service mesh middleware for Consul Connect. The Consul agent is
+// found through CONSUL_HTTP_ADDR and CONSUL_HTTP_TOKEN; use servicemesh.NewConsulMesh with
+// NewServiceMeshMiddleware to configure it explicitly.
+func Consul(meshConfig *servicemesh.Config, opts ...ServiceMeshOption) (*ServiceMeshMiddleware, error) {
+	mesh, err := servicemesh.NewConsulMesh(meshConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewServiceMeshMiddleware(mesh, opts...), nil
+}
+
+// Envoy creates a new service mesh middleware for a mesh built on Envoy sidecars
+func Envoy(meshConfig *servicemesh.Config, opts ...ServiceMeshOption) (*ServiceMeshMiddleware, error) {
+	mesh, err := servicemesh.NewEnvoyMesh(meshConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewServiceMeshMiddleware(mesh, opts...), nil
+}
+
+// Kuma creates a new service mesh middleware for Kuma
+func Kuma(meshConfig *servicemesh.Config, opts ...ServiceMeshOption) (*ServiceMeshMiddleware, error) {
+	mesh, err := servicemesh.NewKumaMesh(meshConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewServiceMeshMiddleware(mesh, opts...), nil
+}
+
 // IstioSimple creates a simple Istio middleware with default settings
 func IstioSimple(serviceName, namespace string) (*ServiceMeshMiddleware, error) {
 	config := &servicemesh.Config{
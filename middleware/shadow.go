@@ -0,0 +1,329 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grpc-guardian/grpc-guardian/pkg/metrics"
+	"github.com/grpc-guardian/grpc-guardian/pkg/randutil"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// ShadowRequestHeader is set to "true" on requests mirrored to a ShadowConn target, so the
+// target can skip side effects such as sending emails or charging cards (see
+// IsShadowRequest)
+const ShadowRequestHeader = "x-shadow-request"
+
+// ShadowTarget receives mirrored requests. Its responses are discarded; only the status
+// and latency are compared with the primary.
+type ShadowTarget interface {
+	Invoke(ctx context.Context, method string, req interface{}) error
+}
+
+// ShadowTargetFunc adapts a function to the ShadowTarget interface
+type ShadowTargetFunc func(ctx context.Context, method string, req interface{}) error
+
+// Invoke calls f(ctx, method, req)
+func (f ShadowTargetFunc) Invoke(ctx context.Context, method string, req interface{}) error {
+	return f(ctx, method, req)
+}
+
+// ShadowHandler mirrors requests to an in-process handler, e.g. the new implementation of
+// a service method
+func ShadowHandler(handler grpc.UnaryHandler) ShadowTarget {
+	return ShadowTargetFunc(func(ctx context.Context, method string, req interface{}) error {
+		_, err := handler(ctx, req)
+		return err
+	})
+}
+
+// ShadowConn mirrors requests to the same method on another server, e.g. a canary
+// deployment. The incoming metadata is forwarded along with ShadowRequestHeader, and the
+// response is read as raw bytes and dropped without decoding.
+func ShadowConn(conn grpc.ClientConnInterface) ShadowTarget {
+	return ShadowTargetFunc(func(ctx context.Context, method string, req interface{}) error {
+		md := metadata.MD{}
+		if incoming, ok := metadata.FromIncomingContext(ctx); ok {
+			for key, values := range incoming {
+				// Transport headers are set by the client connection
+				if strings.HasPrefix(key, ":") || strings.HasPrefix(key, "grpc-") || key == "content-type" || key == "user-agent" {
+					continue
+				}
+				md[key] = values
+			}
+		}
+		md.Set(ShadowRequestHeader, "true")
+		ctx = metadata.NewOutgoingContext(ctx, md)
+
+		var reply discardReply
+		return conn.Invoke(ctx, method, req, &reply, grpc.ForceCodec(discardCodec{}))
+	})
+}
+
+// IsShadowRequest reports whether the request is a mirrored one, either handled in-process
+// by a ShadowHandler or received with ShadowRequestHeader from a ShadowConn
+func IsShadowRequest(ctx context.Context) bool {
+	if shadow, ok := ctx.Value(contextKeyShadowRequest).(bool); ok && shadow {
+		return true
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get(ShadowRequestHeader)
+	return len(values) > 0 && values[0] == "true"
+}
+
+// discardReply receives responses that are not decoded
+type discardReply struct{}
+
+// discardCodec encodes proto requests and ignores response bytes, for calls whose
+// response type is unknown and unneeded (shadow and mirrored calls)
+type discardCodec struct{}
+
+func (discardCodec) Marshal(v interface{}) ([]byte, error) {
+	msg, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("cannot marshal %T", v)
+	}
+	return proto.Marshal(msg)
+}
+
+func (discardCodec) Unmarshal(data []byte, v interface{}) error {
+	return nil
+}
+
+func (discardCodec) Name() string {
+	return "proto"
+}
+
+// ShadowResult compares a primary request with its shadow
+type ShadowResult struct {
+	Method          string
+	PrimaryCode     codes.Code
+	ShadowCode      codes.Code
+	PrimaryDuration time.Duration
+	ShadowDuration  time.Duration
+	ShadowErr       error
+}
+
+// Match reports whether both sides returned the same status code
+func (r ShadowResult) Match() bool {
+	return r.PrimaryCode == r.ShadowCode
+}
+
+// LatencyDelta is how much slower (positive) or faster (negative) the shadow was
+func (r ShadowResult) LatencyDelta() time.Duration {
+	return r.ShadowDuration - r.PrimaryDuration
+}
+
+// Shadow mirrors a percentage of unary requests to a secondary target (shadow traffic) to
+// validate a new implementation with production requests. The client only ever sees the
+// primary response: shadows run in the background, detached from the request's
+// cancellation, and never delay or fail it.
+type Shadow struct {
+	target    ShadowTarget
+	percent   float64
+	timeout   time.Duration
+	inFlight  chan struct{}
+	collector *metrics.ShadowCollector
+	onResult  func(ShadowResult)
+	rng       *randutil.Rand
+
+	wg sync.WaitGroup
+}
+
+// ShadowOption configures a Shadow
+type ShadowOption func(*Shadow)
+
+// WithShadowPercentage sets the percentage (0-100) of requests that are mirrored
+// Default: 1
+func WithShadowPercentage(percent float64) ShadowOption {
+	return func(s *Shadow) {
+		s.percent = percent
+	}
+}
+
+// WithShadowTimeout bounds each shadow request
+// Default: 5s
+func WithShadowTimeout(timeout time.Duration) ShadowOption {
+	return func(s *Shadow) {
+		if timeout > 0 {
+			s.timeout = timeout
+		}
+	}
+}
+
+// WithShadowMaxInFlight caps the number of concurrent shadow requests; sampled requests
+// beyond it are not mirrored, so a slow shadow can't pile up goroutines
+// Default: 100
+func WithShadowMaxInFlight(n int) ShadowOption {
+	return func(s *Shadow) {
+		if n > 0 {
+			s.inFlight = make(chan struct{}, n)
+		}
+	}
+}
+
+// WithShadowMetrics records comparison results, latencies and dropped shadows
+func WithShadowMetrics(collector *metrics.ShadowCollector) ShadowOption {
+	return func(s *Shadow) {
+		s.collector = collector
+	}
+}
+
+// WithShadowResultCallback sets a callback invoked with every comparison, e.g. to log
+// mismatches. It runs on the shadow's goroutine.
+func WithShadowResultCallback(fn func(ShadowResult)) ShadowOption {
+	return func(s *Shadow) {
+		s.onResult = fn
+	}
+}
+
+// WithShadowSeed seeds the choice of mirrored requests, so the same seed and sequence of
+// requests mirror the same requests, e.g. for reproducible tests
+// Default: seeded from the clock
+func WithShadowSeed(seed int64) ShadowOption {
+	return func(s *Shadow) {
+		s.rng = randutil.New(seed)
+	}
+}
+
+// NewShadow creates a shadow traffic middleware. Scope it to the methods under test with
+// Chain.ForMethods. Streams are not mirrored.
+//
+// Example usage:
+//
+//	canary, _ := grpc.Dial("orders-canary:9090", grpc.WithTransportCredentials(creds))
+//	collector, _ := metrics.NewShadowCollector(registry)
+//	shadow := middleware.NewShadow(middleware.ShadowConn(canary),
+//	    middleware.WithShadowPercentage(5),
+//	    middleware.WithShadowMetrics(collector),
+//	)
+//	defer shadow.Wait()
+//
+//	chain := guardian.NewChain(middleware.Logging()).
+//	    ForMethods("/shop.Orders/Get*", shadow.UnaryServerInterceptor())
+func NewShadow(target ShadowTarget, opts ...ShadowOption) *Shadow {
+	s := &Shadow{
+		target:   target,
+		percent:  1,
+		timeout:  5 * time.Second,
+		inFlight: make(chan struct{}, 100),
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.rng == nil {
+		s.rng = randutil.NewTimeSeeded()
+	}
+
+	return s
+}
+
+// UnaryServerInterceptor returns a unary server interceptor that mirrors sampled requests
+func (s *Shadow) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		if !s.sampled() {
+			return handler(ctx, req)
+		}
+
+		select {
+		case s.inFlight <- struct{}{}:
+		default:
+			if s.collector != nil {
+				s.collector.RecordDropped("max_in_flight")
+			}
+			return handler(ctx, req)
+		}
+
+		// The primary handler may modify its request
+		shadowReq := req
+		if msg, ok := req.(proto.Message); ok {
+			shadowReq = proto.Clone(msg)
+		}
+
+		primary := make(chan ShadowResult, 1)
+		s.wg.Add(1)
+		go s.mirror(ctx, info.FullMethod, shadowReq, primary)
+
+		start := time.Now()
+		completed := false
+		defer func() {
+			code := status.Code(err)
+			if !completed {
+				code = codes.Internal // The handler panicked
+			}
+			primary <- ShadowResult{PrimaryCode: code, PrimaryDuration: time.Since(start)}
+		}()
+
+		resp, err = handler(ctx, req)
+		completed = true
+		return resp, err
+	}
+}
+
+// Wait blocks until all in-flight shadow requests are done, e.g. before shutting down
+func (s *Shadow) Wait() {
+	s.wg.Wait()
+}
+
+// sampled makes the per-request mirroring decision
+func (s *Shadow) sampled() bool {
+	return s.percent > 0 && s.rng.Float64()*100 < s.percent
+}
+
+// mirror sends the shadow request and compares it with the primary once both are done
+func (s *Shadow) mirror(ctx context.Context, method string, req interface{}, primary <-chan ShadowResult) {
+	defer s.wg.Done()
+	defer func() { <-s.inFlight }()
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), s.timeout)
+	defer cancel()
+	ctx = context.WithValue(ctx, contextKeyShadowRequest, true)
+
+	start := time.Now()
+	err := s.invoke(ctx, method, req)
+	duration := time.Since(start)
+
+	result := <-primary
+	result.Method = method
+	result.ShadowCode = status.Code(err)
+	result.ShadowDuration = duration
+	result.ShadowErr = err
+
+	if s.collector != nil {
+		s.collector.RecordComparison(method, shadowComparison(result), result.PrimaryDuration, result.ShadowDuration)
+	}
+	if s.onResult != nil {
+		s.onResult(result)
+	}
+}
+
+// invoke calls the target, turning panics of in-process handlers into errors
+func (s *Shadow) invoke(ctx context.Context, method string, req interface{}) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = status.Errorf(codes.Internal, "shadow panic: %v", r)
+		}
+	}()
+	return s.target.Invoke(ctx, method, req)
+}
+
+// shadowComparison classifies a result for metrics
+func shadowComparison(r ShadowResult) string {
+	switch {
+	case r.Match():
+		return metrics.ShadowResultMatch
+	case r.PrimaryCode == codes.OK:
+		return metrics.ShadowResultShadowError
+	case r.ShadowCode == codes.OK:
+		return metrics.ShadowResultPrimaryError
+	}
+	return metrics.ShadowResultCodeMismatch
+}
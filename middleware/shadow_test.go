@@ -0,0 +1,172 @@
+package middleware
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/grpc-guardian/grpc-guardian/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestShadow(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	collector, err := metrics.NewShadowCollector(registry)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	var results []ShadowResult
+	var seen []string
+	target := ShadowHandler(func(ctx context.Context, req interface{}) (interface{}, error) {
+		value := req.(*wrapperspb.StringValue).GetValue()
+		mu.Lock()
+		seen = append(seen, value)
+		mu.Unlock()
+		if !IsShadowRequest(ctx) {
+			t.Error("Expected the shadow context to be marked")
+		}
+		if value == "broken" {
+			return nil, status.Error(codes.Unavailable, "new implementation failed")
+		}
+		return &emptypb.Empty{}, nil
+	})
+	shadow := NewShadow(target,
+		WithShadowPercentage(100),
+		WithShadowMetrics(collector),
+		WithShadowResultCallback(func(r ShadowResult) {
+			mu.Lock()
+			results = append(results, r)
+			mu.Unlock()
+		}),
+	)
+	interceptor := shadow.UnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/test.Service/Method"}
+	primary := func(ctx context.Context, req interface{}) (interface{}, error) {
+		msg := req.(*wrapperspb.StringValue)
+		if IsShadowRequest(ctx) {
+			t.Error("Expected the primary context not to be marked")
+		}
+		if msg.Value == "invalid" {
+			return nil, status.Error(codes.InvalidArgument, "invalid")
+		}
+		msg.Value = "modified by primary"
+		return &emptypb.Empty{}, nil
+	}
+
+	for _, value := range []string{"ok", "broken", "invalid"} {
+		ctx, cancel := context.WithCancel(context.Background())
+		_, err := interceptor(ctx, wrapperspb.String(value), info, primary)
+		cancel() // The shadow outlives the request
+		if value != "invalid" && err != nil {
+			t.Errorf("%s: the primary response should be returned, got %v", value, err)
+		}
+	}
+	shadow.Wait()
+
+	if len(results) != 3 {
+		t.Fatalf("Expected 3 results, got %d", len(results))
+	}
+	for _, value := range seen {
+		if value == "modified by primary" {
+			t.Error("Expected the shadow to get an unmodified copy of the request")
+		}
+	}
+
+	expected := `
+# HELP grpc_shadow_requests_total Total number of shadowed requests by method and comparison result
+# TYPE grpc_shadow_requests_total counter
+grpc_shadow_requests_total{method="/test.Service/Method",result="match"} 1
+grpc_shadow_requests_total{method="/test.Service/Method",result="primary_error"} 1
+grpc_shadow_requests_total{method="/test.Service/Method",result="shadow_error"} 1
+`
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(expected), "grpc_shadow_requests_total"); err != nil {
+		t.Errorf("Unexpected shadow metrics: %v", err)
+	}
+}
+
+func TestShadow_Conn(t *testing.T) {
+	var mu sync.Mutex
+	var shadowMD metadata.MD
+	conn := dialQuotaServer(t, func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		mu.Lock()
+		shadowMD, _ = metadata.FromIncomingContext(ctx)
+		mu.Unlock()
+		if !IsShadowRequest(ctx) {
+			t.Error("Expected the canary to see a shadow request")
+		}
+		return handler(ctx, req)
+	})
+
+	var result ShadowResult
+	shadow := NewShadow(ShadowConn(conn),
+		WithShadowPercentage(100),
+		WithShadowResultCallback(func(r ShadowResult) { result = r }),
+	)
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-tenant-id", "acme", ":authority", "primary"))
+	info := &grpc.UnaryServerInfo{FullMethod: "/quota.Test/Call"}
+	ok := func(ctx context.Context, req interface{}) (interface{}, error) { return &emptypb.Empty{}, nil }
+
+	if _, err := shadow.UnaryServerInterceptor()(ctx, &emptypb.Empty{}, info, ok); err != nil {
+		t.Fatal(err)
+	}
+	shadow.Wait()
+
+	if !result.Match() || result.ShadowErr != nil {
+		t.Errorf("Expected matching results, got %+v", result)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if got := shadowMD.Get("x-tenant-id"); len(got) != 1 || got[0] != "acme" {
+		t.Errorf("Expected the incoming metadata to be forwarded, got %v", shadowMD)
+	}
+}
+
+func TestShadow_MaxInFlight(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	collector, err := metrics.NewShadowCollector(registry)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	release := make(chan struct{})
+	var calls int
+	target := ShadowTargetFunc(func(ctx context.Context, method string, req interface{}) error {
+		calls++
+		<-release
+		return nil
+	})
+	shadow := NewShadow(target, WithShadowPercentage(100), WithShadowMaxInFlight(1), WithShadowMetrics(collector))
+	interceptor := shadow.UnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/test.Service/Method"}
+	ok := func(ctx context.Context, req interface{}) (interface{}, error) { return "ok", nil }
+
+	for i := 0; i < 3; i++ {
+		if _, err := interceptor(context.Background(), nil, info, ok); err != nil {
+			t.Fatal(err)
+		}
+	}
+	close(release)
+	shadow.Wait()
+
+	if calls != 1 {
+		t.Errorf("Expected 1 shadow request, got %d", calls)
+	}
+	expected := `
+# HELP grpc_shadow_dropped_total Total number of sampled requests that were not shadowed
+# TYPE grpc_shadow_dropped_total counter
+grpc_shadow_dropped_total{reason="max_in_flight"} 2
+`
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(expected), "grpc_shadow_dropped_total"); err != nil {
+		t.Errorf("Unexpected shadow metrics: %v", err)
+	}
+}
@@ -0,0 +1,280 @@
+package middleware
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// GoAwayFactory builds the application-level "going away" message sent on a stream when
+// draining starts. The message must match the stream's response type; return nil to skip
+// the stream.
+type GoAwayFactory func(info *grpc.StreamServerInfo) interface{}
+
+// StreamDrainer coordinates the shutdown of long-lived server streams. Drain tells every
+// active stream that the server is going away, gives clients a grace period to reconnect
+// elsewhere, and then closes the remaining streams with a configurable status instead of
+// letting them be reset all at once.
+type StreamDrainer struct {
+	goAway       GoAwayFactory
+	gracePeriod  time.Duration
+	closeCode    codes.Code
+	closeMessage string
+
+	mu       sync.Mutex
+	streams  map[*drainableStream]struct{}
+	draining bool
+	wg       sync.WaitGroup
+}
+
+// StreamDrainOption configures a StreamDrainer
+type StreamDrainOption func(*StreamDrainer)
+
+// WithGoAwayMessage sets the factory for the message sent on each stream when draining starts
+func WithGoAwayMessage(factory GoAwayFactory) StreamDrainOption {
+	return func(d *StreamDrainer) {
+		d.goAway = factory
+	}
+}
+
+// WithDrainGracePeriod sets how long clients get to close their streams before they are closed
+// Default: 30s
+func WithDrainGracePeriod(grace time.Duration) StreamDrainOption {
+	return func(d *StreamDrainer) {
+		if grace >= 0 {
+			d.gracePeriod = grace
+		}
+	}
+}
+
+// WithDrainStatus sets the status used to close streams after the grace period and to
+// reject streams opened while draining
+// Default: codes.Unavailable
+func WithDrainStatus(code codes.Code, message string) StreamDrainOption {
+	return func(d *StreamDrainer) {
+		d.closeCode = code
+		d.closeMessage = message
+	}
+}
+
+// NewStreamDrainer creates a stream drainer.
+//
+// Example usage:
+//
+//	drainer := middleware.NewStreamDrainer(
+//	    middleware.WithGoAwayMessage(func(info *grpc.StreamServerInfo) interface{} {
+//	        return &pb.Event{Type: pb.Event_RECONNECT}
+//	    }),
+//	    middleware.WithDrainGracePeriod(20*time.Second),
+//	)
+//	server := grpc.NewServer(grpc.ChainStreamInterceptor(drainer.StreamServerInterceptor()))
+//
+//	// On SIGTERM
+//	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+//	defer cancel()
+//	if err := drainer.Drain(ctx); err != nil {
+//	    server.Stop()
+//	} else {
+//	    server.GracefulStop()
+//	}
+func NewStreamDrainer(opts ...StreamDrainOption) *StreamDrainer {
+	d := &StreamDrainer{
+		gracePeriod:  30 * time.Second,
+		closeCode:    codes.Unavailable,
+		closeMessage: "server is shutting down\nHint: Reconnect to continue streaming from another instance",
+		streams:      make(map[*drainableStream]struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(d)
+	}
+
+	return d
+}
+
+// StreamServerInterceptor returns a stream server interceptor that tracks active streams.
+// Closing a stream cancels the handler's context and fails its further sends and
+// receives; the stream ends with the drain status once the handler returns. A handler
+// blocked in RecvMsg returns when the client sends, cancels or the server stops.
+func (d *StreamDrainer) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		stream, ok := d.track(ss, info)
+		if !ok {
+			return d.closeError()
+		}
+		defer d.untrack(stream)
+
+		err := handler(srv, stream)
+		if stream.isClosed() {
+			return d.closeError()
+		}
+		return err
+	}
+}
+
+// Drain sends the going-away message on every active stream, waits for the grace period
+// or until all streams have finished, then closes the remaining streams. New streams are
+// rejected once draining starts. It returns ctx.Err() if ctx ends before all streams closed.
+func (d *StreamDrainer) Drain(ctx context.Context) error {
+	d.mu.Lock()
+	d.draining = true
+	streams := make([]*drainableStream, 0, len(d.streams))
+	for s := range d.streams {
+		streams = append(streams, s)
+	}
+	d.mu.Unlock()
+
+	// Sends run concurrently so one slow client cannot hold up the others
+	if d.goAway != nil {
+		for _, s := range streams {
+			if msg := d.goAway(s.info); msg != nil {
+				go func(s *drainableStream, msg interface{}) {
+					_ = s.SendMsg(msg)
+				}(s, msg)
+			}
+		}
+	}
+
+	finished := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(finished)
+	}()
+
+	grace := time.NewTimer(d.gracePeriod)
+	defer grace.Stop()
+
+	select {
+	case <-finished:
+		return nil
+	case <-grace.C:
+	case <-ctx.Done():
+	}
+
+	// Close whatever is still open, then wait for the handlers to return
+	d.mu.Lock()
+	for s := range d.streams {
+		s.close()
+	}
+	d.mu.Unlock()
+
+	select {
+	case <-finished:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// ActiveStreams returns the number of streams currently tracked
+func (d *StreamDrainer) ActiveStreams() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return len(d.streams)
+}
+
+// Draining reports whether Drain has been called
+func (d *StreamDrainer) Draining() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.draining
+}
+
+// track registers a new stream unless the drainer is draining
+func (d *StreamDrainer) track(ss grpc.ServerStream, info *grpc.StreamServerInfo) (*drainableStream, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.draining {
+		return nil, false
+	}
+
+	ctx, cancel := context.WithCancel(ss.Context())
+	stream := &drainableStream{
+		ServerStream: ss,
+		ctx:          ctx,
+		cancel:       cancel,
+		info:         info,
+		closedErr:    d.closeError(),
+	}
+	d.streams[stream] = struct{}{}
+	d.wg.Add(1)
+
+	return stream, true
+}
+
+// untrack removes a stream whose handler returned
+func (d *StreamDrainer) untrack(stream *drainableStream) {
+	stream.cancel()
+
+	d.mu.Lock()
+	delete(d.streams, stream)
+	d.mu.Unlock()
+
+	d.wg.Done()
+}
+
+// closeError returns the status used to close or reject streams
+func (d *StreamDrainer) closeError() error {
+	return status.Error(d.closeCode, d.closeMessage)
+}
+
+// drainableStream serializes sends so the drainer can write the going-away message
+// alongside the handler, and can be closed from outside the handler
+type drainableStream struct {
+	grpc.ServerStream
+	ctx       context.Context
+	cancel    context.CancelFunc
+	info      *grpc.StreamServerInfo
+	closedErr error
+
+	sendMu sync.Mutex
+	closed atomic.Bool
+}
+
+// Context returns a context that is canceled when the stream is closed by the drainer
+func (s *drainableStream) Context() context.Context {
+	return s.ctx
+}
+
+// SendMsg sends a message unless the stream was closed by the drainer
+func (s *drainableStream) SendMsg(m interface{}) error {
+	s.sendMu.Lock()
+	defer s.sendMu.Unlock()
+
+	if s.closed.Load() {
+		return s.closedErr
+	}
+	return s.ServerStream.SendMsg(m)
+}
+
+// RecvMsg receives a message unless the stream was closed by the drainer, including
+// while the receive was blocked
+func (s *drainableStream) RecvMsg(m interface{}) error {
+	if s.isClosed() {
+		return s.closedErr
+	}
+	if err := s.ServerStream.RecvMsg(m); err != nil || !s.isClosed() {
+		return err
+	}
+	return s.closedErr
+}
+
+// close stops further sends and receives and cancels the handler context.
+// It does not wait for an in-flight send, which may be blocked by flow control.
+func (s *drainableStream) close() {
+	s.closed.Store(true)
+	s.cancel()
+}
+
+// isClosed reports whether the drainer closed the stream
+func (s *drainableStream) isClosed() bool {
+	return s.closed.Load()
+}
@@ -0,0 +1,216 @@
+package middleware
+
+import (
+	"context"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// drainTestStream is a server stream whose client stops sending once it receives
+// the going-away message (when reconnect is true)
+type drainTestStream struct {
+	grpc.ServerStream
+	ctx       context.Context
+	reconnect bool
+
+	mu   sync.Mutex
+	sent []interface{}
+	eof  chan struct{}
+	once sync.Once
+}
+
+func newDrainTestStream(reconnect bool) *drainTestStream {
+	return &drainTestStream{ctx: context.Background(), reconnect: reconnect, eof: make(chan struct{})}
+}
+
+func (s *drainTestStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *drainTestStream) SendMsg(m interface{}) error {
+	s.mu.Lock()
+	s.sent = append(s.sent, m)
+	s.mu.Unlock()
+
+	if m == "going-away" && s.reconnect {
+		s.once.Do(func() { close(s.eof) })
+	}
+	return nil
+}
+
+func (s *drainTestStream) RecvMsg(m interface{}) error {
+	<-s.eof
+	return io.EOF
+}
+
+func (s *drainTestStream) sentMessages() []interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]interface{}(nil), s.sent...)
+}
+
+// receiveUntilEOF is a handler that reads until the client closes the stream
+func receiveUntilEOF(srv interface{}, stream grpc.ServerStream) error {
+	for {
+		var msg interface{}
+		if err := stream.RecvMsg(&msg); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+// watchUntilCanceled is a handler that pushes to the client until its context ends
+func watchUntilCanceled(srv interface{}, stream grpc.ServerStream) error {
+	<-stream.Context().Done()
+	return stream.Context().Err()
+}
+
+// startDrainTestStream runs the interceptor in the background and waits until the stream is tracked
+func startDrainTestStream(t *testing.T, drainer *StreamDrainer, stream *drainTestStream, handler grpc.StreamHandler) <-chan error {
+	t.Helper()
+	result := make(chan error, 1)
+	go func() {
+		result <- drainer.StreamServerInterceptor()(nil, stream, &grpc.StreamServerInfo{FullMethod: "/test.Service/Watch"}, handler)
+	}()
+
+	deadline := time.Now().Add(time.Second)
+	for drainer.ActiveStreams() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("Stream was not tracked")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return result
+}
+
+func TestStreamDrainer_ClientReconnects(t *testing.T) {
+	drainer := NewStreamDrainer(
+		WithGoAwayMessage(func(info *grpc.StreamServerInfo) interface{} { return "going-away" }),
+		WithDrainGracePeriod(10*time.Second),
+	)
+
+	stream := newDrainTestStream(true)
+	result := startDrainTestStream(t, drainer, stream, receiveUntilEOF)
+
+	start := time.Now()
+	if err := drainer.Drain(context.Background()); err != nil {
+		t.Fatalf("Drain failed: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected drain to finish once the client left, took %v", elapsed)
+	}
+
+	if err := <-result; err != nil {
+		t.Errorf("Expected clean stream end, got %v", err)
+	}
+	if sent := stream.sentMessages(); len(sent) != 1 || sent[0] != "going-away" {
+		t.Errorf("Expected going-away message, got %v", sent)
+	}
+}
+
+func TestStreamDrainer_ClosesAfterGracePeriod(t *testing.T) {
+	drainer := NewStreamDrainer(
+		WithGoAwayMessage(func(info *grpc.StreamServerInfo) interface{} { return "going-away" }),
+		WithDrainGracePeriod(50*time.Millisecond),
+		WithDrainStatus(codes.Aborted, "deploy in progress"),
+	)
+
+	// This client ignores the going-away message
+	stream := newDrainTestStream(false)
+	result := startDrainTestStream(t, drainer, stream, watchUntilCanceled)
+
+	if err := drainer.Drain(context.Background()); err != nil {
+		t.Fatalf("Drain failed: %v", err)
+	}
+
+	err := <-result
+	if st := status.Convert(err); st.Code() != codes.Aborted || st.Message() != "deploy in progress" {
+		t.Errorf("Expected Aborted drain status, got %v", err)
+	}
+	if drainer.ActiveStreams() != 0 {
+		t.Errorf("Expected no active streams, got %d", drainer.ActiveStreams())
+	}
+}
+
+func TestStreamDrainer_RejectsNewStreams(t *testing.T) {
+	drainer := NewStreamDrainer(WithDrainGracePeriod(0))
+	if err := drainer.Drain(context.Background()); err != nil {
+		t.Fatalf("Drain failed: %v", err)
+	}
+	if !drainer.Draining() {
+		t.Error("Expected drainer to report draining")
+	}
+
+	called := false
+	err := drainer.StreamServerInterceptor()(nil, newDrainTestStream(true), &grpc.StreamServerInfo{FullMethod: "/test.Service/Watch"},
+		func(srv interface{}, stream grpc.ServerStream) error {
+			called = true
+			return nil
+		})
+
+	if called || status.Code(err) != codes.Unavailable {
+		t.Errorf("Expected new stream to be rejected with Unavailable, got %v (handler called: %v)", err, called)
+	}
+}
+
+func TestStreamDrainer_WaitsForHandler(t *testing.T) {
+	drainer := NewStreamDrainer(WithDrainGracePeriod(0))
+
+	// The handler keeps using the stream for a while after its context is canceled
+	returned := make(chan struct{})
+	var sendErr error
+	result := startDrainTestStream(t, drainer, newDrainTestStream(false), func(srv interface{}, stream grpc.ServerStream) error {
+		defer close(returned)
+		<-stream.Context().Done()
+		time.Sleep(20 * time.Millisecond)
+		sendErr = stream.SendMsg("late")
+		return nil
+	})
+
+	if err := drainer.Drain(context.Background()); err != nil {
+		t.Fatalf("Drain failed: %v", err)
+	}
+	select {
+	case <-returned:
+	default:
+		t.Fatal("Expected Drain to wait for the handler to return")
+	}
+	if status.Code(sendErr) != codes.Unavailable {
+		t.Errorf("Expected sends after the close to fail, got %v", sendErr)
+	}
+	if err := <-result; status.Code(err) != codes.Unavailable {
+		t.Errorf("Expected the drain status, got %v", err)
+	}
+}
+
+func TestStreamDrainer_HandlerPanic(t *testing.T) {
+	drainer := NewStreamDrainer()
+
+	func() {
+		defer func() {
+			if recover() != "boom" {
+				t.Fatal("Expected the panic to propagate on the caller goroutine")
+			}
+		}()
+		drainer.StreamServerInterceptor()(nil, newDrainTestStream(true), &grpc.StreamServerInfo{FullMethod: "/test.Service/Watch"},
+			func(srv interface{}, stream grpc.ServerStream) error {
+				panic("boom")
+			})
+	}()
+
+	if drainer.ActiveStreams() != 0 {
+		t.Errorf("Expected the stream to be untracked, got %d active", drainer.ActiveStreams())
+	}
+	if err := drainer.Drain(context.Background()); err != nil {
+		t.Errorf("Expected nothing left to drain, got %v", err)
+	}
+}
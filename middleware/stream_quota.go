@@ -0,0 +1,177 @@
+package middleware
+
+import (
+	"context"
+	"net"
+	"sync"
+
+	"github.com/grpc-guardian/grpc-guardian/pkg/metrics"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// StreamQuota limits the number of streams a client can keep open at the same time.
+// Request rate limits do not protect against long-lived streams: a single client opening
+// thousands of watch streams holds server resources without ever exceeding a request rate.
+type StreamQuota struct {
+	maxStreams int
+	limits     map[string]int
+	extractor  func(context.Context) string
+	collector  *metrics.StreamQuotaCollector
+
+	mu   sync.Mutex
+	open map[string]int
+}
+
+// StreamQuotaOption configures a StreamQuota
+type StreamQuotaOption func(*StreamQuota)
+
+// WithStreamClientExtractor sets how the client of a stream is identified
+// Default: ExtractStreamClient
+func WithStreamClientExtractor(extractor func(context.Context) string) StreamQuotaOption {
+	return func(q *StreamQuota) {
+		if extractor != nil {
+			q.extractor = extractor
+		}
+	}
+}
+
+// WithStreamClientLimit overrides the stream limit of a single client, as returned by the
+// client extractor (e.g. "user:batch-exporter")
+func WithStreamClientLimit(client string, maxStreams int) StreamQuotaOption {
+	return func(q *StreamQuota) {
+		q.limits[client] = maxStreams
+	}
+}
+
+// WithStreamQuotaCollector exports open and rejected streams by client
+func WithStreamQuotaCollector(collector *metrics.StreamQuotaCollector) StreamQuotaOption {
+	return func(q *StreamQuota) {
+		q.collector = collector
+	}
+}
+
+// NewStreamQuota creates a quota of maxStreams concurrently open streams per client.
+//
+// Example usage:
+//
+//	collector, _ := metrics.NewStreamQuotaCollector(nil)
+//	quota := middleware.NewStreamQuota(20,
+//	    middleware.WithStreamClientLimit("user:batch-exporter", 200),
+//	    middleware.WithStreamQuotaCollector(collector),
+//	)
+//	server := grpc.NewServer(grpc.ChainStreamInterceptor(quota.StreamServerInterceptor()))
+func NewStreamQuota(maxStreams int, opts ...StreamQuotaOption) *StreamQuota {
+	q := &StreamQuota{
+		maxStreams: maxStreams,
+		limits:     make(map[string]int),
+		extractor:  ExtractStreamClient,
+		open:       make(map[string]int),
+	}
+
+	for _, opt := range opts {
+		opt(q)
+	}
+
+	return q
+}
+
+// StreamServerInterceptor returns a stream server interceptor that rejects streams over
+// the client's quota with ResourceExhausted
+func (q *StreamQuota) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		client := q.extractor(ss.Context())
+
+		limit, ok := q.acquire(client)
+		if !ok {
+			return status.Errorf(codes.ResourceExhausted,
+				"too many open streams for client: %s (limit %d)\nHint: Close idle streams or reuse an existing stream before opening a new one",
+				client, limit)
+		}
+		defer q.release(client)
+
+		return handler(srv, ss)
+	}
+}
+
+// OpenStreams returns the number of open streams of a client
+func (q *StreamQuota) OpenStreams(client string) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.open[client]
+}
+
+// Clients returns the number of open streams of every client with at least one open stream
+func (q *StreamQuota) Clients() map[string]int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	clients := make(map[string]int, len(q.open))
+	for client, open := range q.open {
+		clients[client] = open
+	}
+	return clients
+}
+
+// acquire reserves a stream for the client, returning the client's limit
+func (q *StreamQuota) acquire(client string) (int, bool) {
+	limit, ok := q.limits[client]
+	if !ok {
+		limit = q.maxStreams
+	}
+
+	q.mu.Lock()
+	open := q.open[client]
+	if open >= limit {
+		q.mu.Unlock()
+		if q.collector != nil {
+			q.collector.RecordRejected(client)
+		}
+		return limit, false
+	}
+	q.open[client] = open + 1
+	if q.collector != nil {
+		q.collector.SetOpenStreams(client, open+1)
+	}
+	q.mu.Unlock()
+
+	return limit, true
+}
+
+// release returns a stream of the client; clients without open streams are forgotten
+func (q *StreamQuota) release(client string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	open := q.open[client] - 1
+	if open <= 0 {
+		delete(q.open, client)
+	} else {
+		q.open[client] = open
+	}
+	if q.collector != nil {
+		q.collector.SetOpenStreams(client, open)
+	}
+}
+
+// ExtractStreamClient identifies the client of a stream: "user:<id>" for authenticated
+// callers, otherwise "ip:<address>" from the connection's peer address. The peer address is
+// used rather than forwarding headers, which a client could set to spread its streams over
+// any number of quotas.
+func ExtractStreamClient(ctx context.Context) string {
+	if userID, ok := GetUserID(ctx); ok && userID != "" {
+		return "user:" + userID
+	}
+
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return "unknown"
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		host = p.Addr.String()
+	}
+	return "ip:" + host
+}
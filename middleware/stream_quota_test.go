@@ -0,0 +1,117 @@
+package middleware
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/grpc-guardian/grpc-guardian/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// quotaTestStream is a server stream with a fixed context
+type quotaTestStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *quotaTestStream) Context() context.Context {
+	return s.ctx
+}
+
+func peerContext(addr string) context.Context {
+	tcpAddr, _ := net.ResolveTCPAddr("tcp", addr)
+	return peer.NewContext(context.Background(), &peer.Peer{Addr: tcpAddr})
+}
+
+func TestStreamQuota(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	collector, err := metrics.NewStreamQuotaCollector(registry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	quota := NewStreamQuota(2,
+		WithStreamClientLimit("ip:10.0.0.9", 0),
+		WithStreamQuotaCollector(collector),
+	)
+	interceptor := quota.StreamServerInterceptor()
+	info := &grpc.StreamServerInfo{FullMethod: "/svc/Watch"}
+
+	release := make(chan struct{})
+	started := make(chan struct{}, 4)
+	handler := func(srv interface{}, ss grpc.ServerStream) error {
+		started <- struct{}{}
+		<-release
+		return nil
+	}
+
+	alice := &quotaTestStream{ctx: peerContext("10.0.0.1:5000")}
+	aliceOtherConn := &quotaTestStream{ctx: peerContext("10.0.0.1:5001")}
+	results := make(chan error, 2)
+	for _, stream := range []*quotaTestStream{alice, aliceOtherConn} {
+		go func(stream *quotaTestStream) { results <- interceptor(nil, stream, info, handler) }(stream)
+	}
+	for i := 0; i < 2; i++ {
+		select {
+		case <-started:
+		case <-time.After(time.Second):
+			t.Fatal("stream was not started")
+		}
+	}
+
+	if got := quota.OpenStreams("ip:10.0.0.1"); got != 2 {
+		t.Errorf("Expected 2 open streams, got %d", got)
+	}
+	if got, err := getMetricValue(registry, "grpc_streams_open"); err != nil || got != 2 {
+		t.Errorf("Expected the open streams gauge at 2, got %v (%v)", got, err)
+	}
+
+	// Third stream from the same address, on any connection, is over the quota
+	err = interceptor(nil, &quotaTestStream{ctx: peerContext("10.0.0.1:5002")}, info, handler)
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("Expected ResourceExhausted, got %v", err)
+	}
+
+	// Other clients have their own quota; overrides apply per client
+	go func() {
+		results <- interceptor(nil, &quotaTestStream{ctx: peerContext("10.0.0.2:5000")}, info, handler)
+	}()
+	<-started
+	if err := interceptor(nil, &quotaTestStream{ctx: peerContext("10.0.0.9:5000")}, info, handler); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("Expected the client override to reject, got %v", err)
+	}
+
+	close(release)
+	for i := 0; i < 3; i++ {
+		if err := <-results; err != nil {
+			t.Errorf("stream error = %v", err)
+		}
+	}
+	if clients := quota.Clients(); len(clients) != 0 {
+		t.Errorf("Expected no open streams after release, got %v", clients)
+	}
+	if count := testutil.CollectAndCount(registry, "grpc_streams_open"); count != 0 {
+		t.Errorf("Expected closed clients to be removed from the gauge, got %d series", count)
+	}
+}
+
+func TestExtractStreamClient(t *testing.T) {
+	if got := ExtractStreamClient(peerContext("192.168.1.7:41234")); got != "ip:192.168.1.7" {
+		t.Errorf("ExtractStreamClient() = %q, want ip:192.168.1.7", got)
+	}
+
+	ctx := context.WithValue(peerContext("192.168.1.7:41234"), contextKeyUserID, "u1")
+	if got := ExtractStreamClient(ctx); got != "user:u1" {
+		t.Errorf("ExtractStreamClient() = %q, want user:u1", got)
+	}
+
+	if got := ExtractStreamClient(context.Background()); got != "unknown" {
+		t.Errorf("ExtractStreamClient() = %q, want unknown", got)
+	}
+}
@@ -0,0 +1,443 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/time/rate"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// TenantHeader is the conventional metadata key carrying the tenant
+const TenantHeader = "x-tenant-id"
+
+// OtherTenantLabel labels the metrics of tenants without their own limits, which would
+// otherwise give every tenant ID a client sends its own series
+const OtherTenantLabel = "other"
+
+// TenantResolver resolves the tenant of a request. An empty tenant without error means
+// the resolver does not know the tenant.
+type TenantResolver interface {
+	ResolveTenant(ctx context.Context) (string, error)
+}
+
+// TenantResolverFunc adapts a function to the TenantResolver interface
+type TenantResolverFunc func(ctx context.Context) (string, error)
+
+// ResolveTenant calls f(ctx)
+func (f TenantResolverFunc) ResolveTenant(ctx context.Context) (string, error) {
+	return f(ctx)
+}
+
+// MetadataTenantResolver reads the tenant from a metadata header. Any caller can set the
+// header, so only use it when a proxy in front of the server sets it, or use
+// TrustedMetadataTenantResolver.
+func MetadataTenantResolver(header string) TenantResolver {
+	return TenantResolverFunc(func(ctx context.Context) (string, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		if values := md.Get(header); len(values) > 0 {
+			return values[0], nil
+		}
+		return "", nil
+	})
+}
+
+// TrustedMetadataTenantResolver reads the tenant from a metadata header sent by a trusted
+// peer, e.g. an internal gateway, given as CIDRs or addresses. The header of any other
+// peer is ignored.
+func TrustedMetadataTenantResolver(header string, trustedPeers ...string) (TenantResolver, error) {
+	if len(trustedPeers) == 0 {
+		return nil, errors.New("tenant: no trusted peers for the tenant header")
+	}
+	prefixes, err := parsePrefixes(trustedPeers)
+	if err != nil {
+		return nil, fmt.Errorf("tenant: invalid trusted peer %w", err)
+	}
+
+	resolver := MetadataTenantResolver(header)
+	return TenantResolverFunc(func(ctx context.Context) (string, error) {
+		if addr, ok := peerAddr(ctx); !ok || !containsAddr(prefixes, addr) {
+			return "", nil
+		}
+		return resolver.ResolveTenant(ctx)
+	}), nil
+}
+
+// JWTClaimTenantResolver reads the tenant from a claim of the JWT validated by the Auth
+// middleware, which must run first
+func JWTClaimTenantResolver(claim string) TenantResolver {
+	return TenantResolverFunc(func(ctx context.Context) (string, error) {
+		claims, ok := GetJWTClaims(ctx)
+		if !ok {
+			return "", nil
+		}
+		switch value := claims[claim].(type) {
+		case nil:
+			return "", nil
+		case string:
+			return value, nil
+		default:
+			return "", fmt.Errorf("claim %q is not a string", claim)
+		}
+	})
+}
+
+// FirstTenantResolver tries resolvers in order and returns the first tenant found
+func FirstTenantResolver(resolvers ...TenantResolver) TenantResolver {
+	return TenantResolverFunc(func(ctx context.Context) (string, error) {
+		for _, resolver := range resolvers {
+			tenant, err := resolver.ResolveTenant(ctx)
+			if err != nil || tenant != "" {
+				return tenant, err
+			}
+		}
+		return "", nil
+	})
+}
+
+// TenantLimits caps the traffic of a tenant. Zero values disable a limit.
+type TenantLimits struct {
+	// RatePerSec and Burst configure a token bucket per tenant
+	RatePerSec int
+	Burst      int
+
+	// MaxConcurrent caps the requests of the tenant in flight at once
+	MaxConcurrent int
+}
+
+// Tenants isolates tenants from each other: it resolves the tenant of every request,
+// stores it in the context, enforces per-tenant rate limits and concurrency caps, and
+// labels metrics, spans and logs with the tenant.
+type Tenants struct {
+	resolver      TenantResolver
+	required      bool
+	defaultLimits TenantLimits
+	maxTenants    int
+
+	mu      sync.Mutex
+	limits  map[string]TenantLimits
+	tenants map[string]*tenantState
+
+	requests *prometheus.CounterVec
+	rejected *prometheus.CounterVec
+}
+
+// tenantState holds the limiters of a tenant
+type tenantState struct {
+	limiter  *rate.Limiter // nil without a rate limit
+	limit    int           // Concurrency cap, 0 for none
+	inFlight int
+}
+
+// TenantOption configures Tenants
+type TenantOption func(*tenantConfig)
+
+// tenantConfig collects options before Tenants is built
+type tenantConfig struct {
+	resolver      TenantResolver
+	required      bool
+	defaultLimits TenantLimits
+	limits        map[string]TenantLimits
+	maxTenants    int
+	registerer    prometheus.Registerer
+}
+
+// WithTenantResolver sets how tenants are resolved
+// Default: the tenant_id JWT claim
+func WithTenantResolver(resolver TenantResolver) TenantOption {
+	return func(c *tenantConfig) {
+		c.resolver = resolver
+	}
+}
+
+// WithTenantRequired rejects requests without a tenant with Unauthenticated
+func WithTenantRequired() TenantOption {
+	return func(c *tenantConfig) {
+		c.required = true
+	}
+}
+
+// WithDefaultTenantLimits sets the limits of tenants without their own limits
+func WithDefaultTenantLimits(limits TenantLimits) TenantOption {
+	return func(c *tenantConfig) {
+		c.defaultLimits = limits
+	}
+}
+
+// WithTenantLimits sets the limits of a tenant
+func WithTenantLimits(tenant string, limits TenantLimits) TenantOption {
+	return func(c *tenantConfig) {
+		c.limits[tenant] = limits
+	}
+}
+
+// WithMaxTrackedTenants caps the tenants whose limiter state is kept. When the cap is
+// reached, the state of an idle tenant without its own limits is dropped; if every
+// tracked tenant has requests in flight, requests of new tenants are rejected.
+// Default: 10000
+func WithMaxTrackedTenants(n int) TenantOption {
+	return func(c *tenantConfig) {
+		if n > 0 {
+			c.maxTenants = n
+		}
+	}
+}
+
+// WithTenantMetrics registers grpc_server_tenant_requests_total (labeled by tenant, method
+// and code) and grpc_server_tenant_rejected_total (labeled by tenant and reason) counters.
+// Tenants without their own limits share the OtherTenantLabel.
+func WithTenantMetrics(registerer prometheus.Registerer) TenantOption {
+	return func(c *tenantConfig) {
+		c.registerer = registerer
+	}
+}
+
+// NewTenants creates the tenant isolation middleware.
+//
+// Example usage:
+//
+//	tenants := middleware.NewTenants(
+//	    middleware.WithTenantResolver(middleware.JWTClaimTenantResolver("org_id")),
+//	    middleware.WithTenantRequired(),
+//	    middleware.WithDefaultTenantLimits(middleware.TenantLimits{RatePerSec: 50, Burst: 100, MaxConcurrent: 20}),
+//	    middleware.WithTenantLimits("acme", middleware.TenantLimits{RatePerSec: 500, Burst: 1000}),
+//	    middleware.WithTenantMetrics(collector.GetRegistry()),
+//	)
+//	chain := guardian.NewChain(
+//	    middleware.Auth(middleware.JWTValidator(secret)),
+//	    tenants.UnaryServerInterceptor(),
+//	)
+//
+//	// In the handler
+//	tenant, _ := middleware.GetTenantID(ctx)
+func NewTenants(opts ...TenantOption) *Tenants {
+	config := &tenantConfig{limits: make(map[string]TenantLimits), maxTenants: 10000}
+	for _, opt := range opts {
+		opt(config)
+	}
+	if config.resolver == nil {
+		config.resolver = JWTClaimTenantResolver("tenant_id")
+	}
+
+	t := &Tenants{
+		resolver:      config.resolver,
+		required:      config.required,
+		defaultLimits: config.defaultLimits,
+		maxTenants:    config.maxTenants,
+		limits:        config.limits,
+		tenants:       make(map[string]*tenantState),
+	}
+
+	if config.registerer != nil {
+		t.requests = registerCounterVec(config.registerer, prometheus.CounterOpts{
+			Namespace: "grpc",
+			Subsystem: "server",
+			Name:      "tenant_requests_total",
+			Help:      "Total number of requests handled per tenant",
+		}, []string{"tenant", "method", "code"})
+		t.rejected = registerCounterVec(config.registerer, prometheus.CounterOpts{
+			Namespace: "grpc",
+			Subsystem: "server",
+			Name:      "tenant_rejected_total",
+			Help:      "Total number of requests rejected by tenant limits",
+		}, []string{"tenant", "reason"})
+	}
+
+	return t
+}
+
+// registerCounterVec registers a counter vector, reusing an identical one already registered
+func registerCounterVec(registerer prometheus.Registerer, opts prometheus.CounterOpts, labels []string) *prometheus.CounterVec {
+	counter := prometheus.NewCounterVec(opts, labels)
+	if err := registerer.Register(counter); err != nil {
+		if are, ok := err.(prometheus.AlreadyRegisteredError); ok {
+			return are.ExistingCollector.(*prometheus.CounterVec)
+		}
+	}
+	return counter
+}
+
+// SetTenantLimits changes the limits of a tenant at runtime
+func (t *Tenants) SetTenantLimits(tenant string, limits TenantLimits) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.limits[tenant] = limits
+	if state, ok := t.tenants[tenant]; ok {
+		state.limiter, state.limit = newTenantLimiter(limits), limits.MaxConcurrent
+	}
+}
+
+// GetTenantID retrieves the tenant of the request from context
+func GetTenantID(ctx context.Context) (string, bool) {
+	tenant, ok := ctx.Value(contextKeyTenantID).(string)
+	return tenant, ok
+}
+
+// UnaryServerInterceptor returns a unary server interceptor that isolates tenants
+func (t *Tenants) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		ctx, release, err := t.admit(ctx, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+
+		completed := false
+		defer func() {
+			if !completed {
+				err = status.Error(codes.Internal, "handler panicked")
+			}
+			release(err)
+		}()
+
+		resp, err = handler(ctx, req)
+		completed = true
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor returns a stream server interceptor that isolates tenants. A
+// stream counts against the concurrency cap until it ends.
+func (t *Tenants) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		ctx, release, err := t.admit(ss.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+
+		completed := false
+		defer func() {
+			if !completed {
+				err = status.Error(codes.Internal, "handler panicked")
+			}
+			release(err)
+		}()
+
+		err = handler(srv, &tenantServerStream{ServerStream: ss, ctx: ctx})
+		completed = true
+		return err
+	}
+}
+
+// admit resolves the tenant and takes a slot of its limits. release must be called with
+// the result of the request.
+func (t *Tenants) admit(ctx context.Context, method string) (context.Context, func(error), error) {
+	tenant, err := t.resolver.ResolveTenant(ctx)
+	if err != nil {
+		return ctx, nil, status.Errorf(codes.Unauthenticated,
+			"failed to resolve tenant: %v\nHint: Check the tenant header or token claim", err)
+	}
+	if tenant == "" {
+		if t.required {
+			return ctx, nil, ErrMissingTenant()
+		}
+		return ctx, func(error) {}, nil
+	}
+
+	ctx = context.WithValue(ctx, contextKeyTenantID, tenant)
+	trace.SpanFromContext(ctx).SetAttributes(attribute.String("tenant.id", tenant))
+
+	state, label, reason, err := t.acquire(ctx, tenant)
+	if err != nil {
+		if t.rejected != nil {
+			t.rejected.WithLabelValues(label, reason).Inc()
+		}
+		return ctx, nil, err
+	}
+
+	return ctx, func(err error) {
+		t.mu.Lock()
+		state.inFlight--
+		t.mu.Unlock()
+
+		if t.requests != nil {
+			t.requests.WithLabelValues(label, method, status.Code(err).String()).Inc()
+		}
+	}, nil
+}
+
+// acquire takes a rate limit token and a concurrency slot of a tenant, returning its
+// state and metric label, and the rejection reason on failure
+func (t *Tenants) acquire(ctx context.Context, tenant string) (*tenantState, string, string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	limits, configured := t.limits[tenant]
+	label := tenant
+	if !configured {
+		limits, label = t.defaultLimits, OtherTenantLabel
+	}
+
+	state, ok := t.tenants[tenant]
+	if !ok {
+		if len(t.tenants) >= t.maxTenants && !t.evictIdleTenant() {
+			return nil, label, "capacity", status.Errorf(codes.ResourceExhausted,
+				"too many tenants with requests in flight (limit %d)", t.maxTenants)
+		}
+		state = &tenantState{limiter: newTenantLimiter(limits), limit: limits.MaxConcurrent}
+		t.tenants[tenant] = state
+	}
+
+	if state.limit > 0 && state.inFlight >= state.limit {
+		return nil, label, "concurrency", status.Errorf(codes.ResourceExhausted,
+			"tenant %s has too many requests in flight (limit %d)", tenant, state.limit)
+	}
+	if state.limiter != nil {
+		if err := allowWithQuota(ctx, state.limiter, "rate limit exceeded for tenant %s", tenant); err != nil {
+			return nil, label, "rate_limit", err
+		}
+	}
+
+	state.inFlight++
+	return state, label, "", nil
+}
+
+// evictIdleTenant drops the state of an arbitrary idle tenant without its own limits.
+// t.mu must be held.
+func (t *Tenants) evictIdleTenant() bool {
+	for tenant, state := range t.tenants {
+		if _, configured := t.limits[tenant]; !configured && state.inFlight == 0 {
+			delete(t.tenants, tenant)
+			return true
+		}
+	}
+	return false
+}
+
+// newTenantLimiter creates the token bucket of a tenant, or nil without a rate limit
+func newTenantLimiter(limits TenantLimits) *rate.Limiter {
+	if limits.RatePerSec <= 0 {
+		return nil
+	}
+	burst := limits.Burst
+	if burst <= 0 {
+		burst = limits.RatePerSec
+	}
+	return rate.NewLimiter(rate.Limit(limits.RatePerSec), burst)
+}
+
+// ErrMissingTenant returns an error for requests without a tenant
+func ErrMissingTenant() error {
+	return status.Error(codes.Unauthenticated,
+		"missing tenant\n"+
+			"Hint: Use a token with a tenant claim, or send the tenant header through a trusted gateway")
+}
+
+// tenantServerStream wraps grpc.ServerStream with the tenant context
+type tenantServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context returns the context with the tenant
+func (s *tenantServerStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,184 @@
+package middleware
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+func tenantContext(tenant string) context.Context {
+	return metadata.NewIncomingContext(context.Background(), metadata.Pairs(TenantHeader, tenant))
+}
+
+func TestTenants_Limits(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	tenants := NewTenants(
+		WithTenantResolver(MetadataTenantResolver(TenantHeader)),
+		WithDefaultTenantLimits(TenantLimits{RatePerSec: 1, Burst: 2}),
+		WithTenantLimits("acme", TenantLimits{MaxConcurrent: 1}),
+		WithTenantMetrics(registry),
+	)
+	interceptor := tenants.UnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/orders.Orders/Get"}
+
+	var seen string
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		seen, _ = GetTenantID(ctx)
+		return "ok", nil
+	}
+
+	// Default limits: the burst of 2 is used up by globex, initech is unaffected
+	for i, want := range []codes.Code{codes.OK, codes.OK, codes.ResourceExhausted} {
+		if _, err := interceptor(tenantContext("globex"), "req", info, handler); status.Code(err) != want {
+			t.Fatalf("globex request %d: code = %v, want %v", i, status.Code(err), want)
+		}
+	}
+	if _, err := interceptor(tenantContext("initech"), "req", info, handler); err != nil || seen != "initech" {
+		t.Fatalf("Expected initech to be served, got %v (tenant %q)", err, seen)
+	}
+
+	// acme: one request in flight at a time
+	blocked := func(ctx context.Context, req interface{}) (interface{}, error) {
+		_, err := interceptor(tenantContext("acme"), "req", info, handler)
+		if status.Code(err) != codes.ResourceExhausted {
+			t.Errorf("Expected the nested acme request to be rejected, got %v", err)
+		}
+		return "ok", nil
+	}
+	if _, err := interceptor(tenantContext("acme"), "req", info, blocked); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := interceptor(tenantContext("acme"), "req", info, handler); err != nil {
+		t.Fatalf("Expected acme to be served after the slot was released, got %v", err)
+	}
+
+	// globex has no limits of its own, so it shares the other label
+	if got := testutil.ToFloat64(tenants.rejected.WithLabelValues(OtherTenantLabel, "rate_limit")); got != 1 {
+		t.Errorf("globex rate limit rejections = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(tenants.requests.WithLabelValues("acme", info.FullMethod, "OK")); got != 2 {
+		t.Errorf("acme requests = %v, want 2", got)
+	}
+}
+
+func TestTenants_Resolvers(t *testing.T) {
+	info := &grpc.UnaryServerInfo{FullMethod: "/orders.Orders/Get"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		tenant, _ := GetTenantID(ctx)
+		return tenant, nil
+	}
+
+	claims := context.WithValue(context.Background(), contextKeyJWTClaims, map[string]interface{}{"org_id": "acme", "sub": "user-1"})
+	tenant, err := NewTenants(WithTenantResolver(JWTClaimTenantResolver("org_id"))).UnaryServerInterceptor()(claims, "req", info, handler)
+	if err != nil || tenant != "acme" {
+		t.Errorf("Expected tenant from JWT claim, got %v (%v)", tenant, err)
+	}
+
+	required := NewTenants(WithTenantRequired()).UnaryServerInterceptor()
+	if _, err := required(context.Background(), "req", info, handler); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Expected Unauthenticated without a tenant, got %v", err)
+	}
+
+	optional := NewTenants().UnaryServerInterceptor()
+	if tenant, err := optional(context.Background(), "req", info, handler); err != nil || tenant != "" {
+		t.Errorf("Expected requests without a tenant to pass, got %v (%v)", tenant, err)
+	}
+
+	// The default resolver ignores the header, which any caller can set
+	if tenant, err := optional(tenantContext("acme"), "req", info, handler); err != nil || tenant != "" {
+		t.Errorf("Expected the tenant header to be ignored by default, got %v (%v)", tenant, err)
+	}
+
+	resolver, err := TrustedMetadataTenantResolver(TenantHeader, "10.0.0.0/8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	trusted := NewTenants(WithTenantResolver(resolver)).UnaryServerInterceptor()
+	for ip, want := range map[string]string{"10.1.2.3": "acme", "192.0.2.1": ""} {
+		ctx := peer.NewContext(tenantContext("acme"), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP(ip), Port: 5000}})
+		if tenant, err := trusted(ctx, "req", info, handler); err != nil || tenant != want {
+			t.Errorf("Peer %s: got tenant %v (%v), want %q", ip, tenant, err, want)
+		}
+	}
+	if _, err := TrustedMetadataTenantResolver(TenantHeader); err == nil {
+		t.Error("Expected an error without trusted peers")
+	}
+}
+
+func TestTenants_HandlerPanicReleasesSlot(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	tenants := NewTenants(
+		WithTenantResolver(MetadataTenantResolver(TenantHeader)),
+		WithTenantLimits("acme", TenantLimits{MaxConcurrent: 1}),
+		WithTenantMetrics(registry),
+	)
+	interceptor := tenants.UnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/orders.Orders/Get"}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("Expected the panic to propagate")
+			}
+		}()
+		interceptor(tenantContext("acme"), "req", info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			panic("boom")
+		})
+	}()
+
+	if _, err := interceptor(tenantContext("acme"), "req", info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "ok", nil
+	}); err != nil {
+		t.Fatalf("Expected the slot to be released after the panic, got %v", err)
+	}
+	if got := testutil.ToFloat64(tenants.requests.WithLabelValues("acme", info.FullMethod, "Internal")); got != 1 {
+		t.Errorf("Panicked requests = %v, want 1", got)
+	}
+}
+
+func TestTenants_MaxTrackedTenants(t *testing.T) {
+	tenants := NewTenants(
+		WithTenantResolver(MetadataTenantResolver(TenantHeader)),
+		WithTenantLimits("acme", TenantLimits{MaxConcurrent: 10}),
+		WithMaxTrackedTenants(2),
+	)
+	interceptor := tenants.UnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/orders.Orders/Get"}
+	ok := func(ctx context.Context, req interface{}) (interface{}, error) { return "ok", nil }
+
+	// Idle tenants without their own limits make room for new ones
+	for _, tenant := range []string{"acme", "t1", "t2", "t3"} {
+		if _, err := interceptor(tenantContext(tenant), "req", info, ok); err != nil {
+			t.Fatalf("Tenant %s: %v", tenant, err)
+		}
+	}
+	tenants.mu.Lock()
+	_, kept := tenants.tenants["acme"]
+	tracked := len(tenants.tenants)
+	tenants.mu.Unlock()
+	if !kept || tracked != 2 {
+		t.Errorf("Expected acme and one other tenant to be tracked, got %d (acme kept: %v)", tracked, kept)
+	}
+
+	// A busy tenant is never evicted
+	busy := func(ctx context.Context, req interface{}) (interface{}, error) {
+		if _, err := interceptor(tenantContext("t4"), "req", info, ok); status.Code(err) != codes.ResourceExhausted {
+			t.Errorf("Expected a new tenant to be rejected while the others are busy, got %v", err)
+		}
+		return "ok", nil
+	}
+	outer := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return interceptor(tenantContext("t5"), "req", info, busy)
+	}
+	if _, err := interceptor(tenantContext("acme"), "req", info, outer); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -3,12 +3,16 @@ package middleware
 import (
 	"context"
 	"fmt"
+	"io"
+	"sync"
 
+	"github.com/grpc-guardian/grpc-guardian/pkg/tracing"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/propagation"
 	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
@@ -19,9 +23,11 @@ type TracingConfig struct {
 	Tracer       trace.Tracer
 	TracerName   string
 	Propagator   propagation.TextMapPropagator
+	Formats      []propagation.TextMapPropagator // Extra formats accepted alongside Propagator
 	RecordErrors bool
 	RecordEvents bool
 	ExtraAttrs   []attribute.KeyValue
+	Setup        func() (trace.TracerProvider, error)
 }
 
 // TracingOption is a functional option for tracing configuration
@@ -48,6 +54,26 @@ func WithPropagator(propagator propagation.TextMapPropagator) TracingOption {
 	}
 }
 
+// WithB3Propagation also accepts Zipkin B3 headers (single and multiple header format),
+// as emitted by Istio/Envoy. Combine with WithJaegerPropagation and WithPropagator as needed.
+// The encoding is only used when the propagator injects, e.g. via Propagator on a client.
+func WithB3Propagation(encoding ...tracing.B3Encoding) TracingOption {
+	return func(c *TracingConfig) {
+		b3 := tracing.B3{}
+		for _, e := range encoding {
+			b3.InjectEncoding |= e
+		}
+		c.Formats = append(c.Formats, b3)
+	}
+}
+
+// WithJaegerPropagation also accepts the Jaeger uber-trace-id header
+func WithJaegerPropagation() TracingOption {
+	return func(c *TracingConfig) {
+		c.Formats = append(c.Formats, tracing.Jaeger{})
+	}
+}
+
 // WithRecordErrors enables error recording in spans
 func WithRecordErrors() TracingOption {
 	return func(c *TracingConfig) {
@@ -62,6 +88,21 @@ func WithRecordEvents() TracingOption {
 	}
 }
 
+// WithTracerProviderSetup creates the tracer provider when the middleware is built
+// instead of at program start (e.g. connecting an exporter). Ignored when WithTracer is set.
+func WithTracerProviderSetup(setup func() (trace.TracerProvider, error)) TracingOption {
+	return func(c *TracingConfig) {
+		c.Setup = setup
+	}
+}
+
+// WithNoopTracer disables span recording while keeping the middleware in the chain
+func WithNoopTracer() TracingOption {
+	return func(c *TracingConfig) {
+		c.Tracer = noop.NewTracerProvider().Tracer("")
+	}
+}
+
 // WithExtraAttributes adds extra attributes to all spans
 func WithExtraAttributes(attrs ...attribute.KeyValue) TracingOption {
 	return func(c *TracingConfig) {
@@ -69,8 +110,26 @@ func WithExtraAttributes(attrs ...attribute.KeyValue) TracingOption {
 	}
 }
 
-// Tracing creates a distributed tracing middleware with OpenTelemetry
+// Tracing creates a distributed tracing middleware with OpenTelemetry.
+// If the tracer provider setup fails, spans are dropped; use TracingE to handle the error.
 func Tracing(opts ...TracingOption) func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	config, _ := newTracingConfig(opts)
+	return tracingInterceptor(config)
+}
+
+// TracingE creates a distributed tracing middleware, returning an error if the tracer
+// provider configured with WithTracerProviderSetup cannot be created
+func TracingE(opts ...TracingOption) (func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error), error) {
+	config, err := newTracingConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	return tracingInterceptor(config), nil
+}
+
+// newTracingConfig applies options and creates the tracer. On setup failure it returns
+// the error together with a configuration that uses a no-op tracer.
+func newTracingConfig(opts []TracingOption) (*TracingConfig, error) {
 	// Default configuration
 	config := &TracingConfig{
 		TracerName:   "grpc-guardian",
@@ -84,11 +143,36 @@ func Tracing(opts ...TracingOption) func(ctx context.Context, req interface{}, i
 		opt(config)
 	}
 
+	// Accept extra formats next to the configured propagator; when several formats carry
+	// a trace context, the one added last wins
+	if len(config.Formats) > 0 {
+		propagators := make([]propagation.TextMapPropagator, 0, len(config.Formats)+1)
+		if config.Propagator != nil {
+			propagators = append(propagators, config.Propagator)
+		}
+		config.Propagator = propagation.NewCompositeTextMapPropagator(append(propagators, config.Formats...)...)
+	}
+
+	// Create the tracer provider on demand
+	if config.Tracer == nil && config.Setup != nil {
+		provider, err := config.Setup()
+		if err != nil {
+			config.Tracer = noop.NewTracerProvider().Tracer(config.TracerName)
+			return config, fmt.Errorf("failed to set up tracer provider: %w", err)
+		}
+		config.Tracer = provider.Tracer(config.TracerName)
+	}
+
 	// Get or create tracer
 	if config.Tracer == nil {
 		config.Tracer = otel.Tracer(config.TracerName)
 	}
 
+	return config, nil
+}
+
+// tracingInterceptor creates the unary tracing interceptor for a configuration
+func tracingInterceptor(config *TracingConfig) func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
 	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
 		// Extract trace context from incoming metadata
 		md, ok := metadata.FromIncomingContext(ctx)
@@ -165,23 +249,7 @@ func TracingWithServiceName(serviceName string, opts ...TracingOption) func(ctx
 
 // StreamTracing creates a distributed tracing middleware for streaming RPCs
 func StreamTracing(opts ...TracingOption) func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
-	// Default configuration
-	config := &TracingConfig{
-		TracerName:   "grpc-guardian",
-		Propagator:   otel.GetTextMapPropagator(),
-		RecordErrors: true,
-		RecordEvents: true,
-	}
-
-	// Apply options
-	for _, opt := range opts {
-		opt(config)
-	}
-
-	// Get or create tracer
-	if config.Tracer == nil {
-		config.Tracer = otel.Tracer(config.TracerName)
-	}
+	config, _ := newTracingConfig(opts)
 
 	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
 		ctx := ss.Context()
@@ -256,6 +324,160 @@ func StreamTracing(opts ...TracingOption) func(srv interface{}, ss grpc.ServerSt
 	}
 }
 
+// TracingUnaryClientInterceptor creates a client interceptor that starts a SpanKindClient
+// span for each call, injects the trace context into the outgoing metadata and records
+// the resulting status. It accepts the same options as the server middleware.
+//
+// Example usage:
+//
+//	conn, err := grpc.Dial(target,
+//	    grpc.WithTransportCredentials(insecure.NewCredentials()),
+//	    grpc.WithUnaryInterceptor(middleware.TracingUnaryClientInterceptor()),
+//	    grpc.WithStreamInterceptor(middleware.TracingStreamClientInterceptor()),
+//	)
+func TracingUnaryClientInterceptor(opts ...TracingOption) grpc.UnaryClientInterceptor {
+	config, _ := newTracingConfig(opts)
+
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, callOpts ...grpc.CallOption) error {
+		ctx, span := startClientSpan(ctx, config, method)
+		defer span.End()
+
+		if config.RecordEvents {
+			span.AddEvent("grpc.request.sent")
+		}
+
+		err := invoker(ctx, method, req, reply, cc, callOpts...)
+
+		if config.RecordEvents && err == nil {
+			span.AddEvent("grpc.response.received")
+		}
+		setSpanStatus(span, err, config.RecordErrors)
+
+		return err
+	}
+}
+
+// TracingStreamClientInterceptor creates a stream client interceptor that traces each
+// stream with a SpanKindClient span. The span ends when the stream finishes: when RecvMsg
+// returns an error (io.EOF counts as success) or, for streams without server streaming,
+// after the response was received.
+func TracingStreamClientInterceptor(opts ...TracingOption) grpc.StreamClientInterceptor {
+	config, _ := newTracingConfig(opts)
+
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, callOpts ...grpc.CallOption) (grpc.ClientStream, error) {
+		ctx, span := startClientSpan(ctx, config, method)
+		span.SetAttributes(
+			attribute.Bool("rpc.stream.client_streaming", desc.ClientStreams),
+			attribute.Bool("rpc.stream.server_streaming", desc.ServerStreams),
+		)
+
+		cs, err := streamer(ctx, desc, cc, method, callOpts...)
+		if err != nil {
+			setSpanStatus(span, err, config.RecordErrors)
+			span.End()
+			return nil, err
+		}
+
+		if config.RecordEvents {
+			span.AddEvent("grpc.stream.started")
+		}
+
+		return &tracedClientStream{
+			ClientStream:  cs,
+			span:          span,
+			config:        config,
+			serverStreams: desc.ServerStreams,
+		}, nil
+	}
+}
+
+// startClientSpan starts a client span and injects its context into the outgoing metadata
+func startClientSpan(ctx context.Context, config *TracingConfig, method string) (context.Context, trace.Span) {
+	ctx, span := config.Tracer.Start(ctx, method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(config.ExtraAttrs...),
+	)
+
+	span.SetAttributes(
+		attribute.String("rpc.system", "grpc"),
+		attribute.String("rpc.service", extractServiceName(method)),
+		attribute.String("rpc.method", extractMethodName(method)),
+	)
+
+	md, ok := metadata.FromOutgoingContext(ctx)
+	if ok {
+		md = md.Copy()
+	} else {
+		md = metadata.New(nil)
+	}
+	config.Propagator.Inject(ctx, &metadataCarrier{md: md})
+
+	return metadata.NewOutgoingContext(ctx, md), span
+}
+
+// setSpanStatus records the gRPC status of a finished call on the span
+func setSpanStatus(span trace.Span, err error, recordErrors bool) {
+	if err == nil {
+		span.SetStatus(codes.Ok, "")
+		span.SetAttributes(attribute.String("rpc.grpc.status_code", "OK"))
+		return
+	}
+
+	st := status.Convert(err)
+	span.SetStatus(codes.Error, st.Message())
+	span.SetAttributes(
+		attribute.String("rpc.grpc.status_code", st.Code().String()),
+		attribute.String("error.message", st.Message()),
+	)
+	if recordErrors {
+		span.RecordError(err)
+	}
+}
+
+// tracedClientStream ends the client span when the stream finishes
+type tracedClientStream struct {
+	grpc.ClientStream
+	span          trace.Span
+	config        *TracingConfig
+	serverStreams bool
+	endOnce       sync.Once
+}
+
+// RecvMsg receives a message and ends the span when the stream is done
+func (s *tracedClientStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+
+	switch {
+	case err == io.EOF:
+		s.end(nil)
+	case err != nil:
+		s.end(err)
+	case !s.serverStreams:
+		s.end(nil)
+	}
+	return err
+}
+
+// Header waits for the response header and ends the span if the stream failed
+func (s *tracedClientStream) Header() (metadata.MD, error) {
+	md, err := s.ClientStream.Header()
+	if err != nil {
+		s.end(err)
+	}
+	return md, err
+}
+
+// end records the final status once
+func (s *tracedClientStream) end(err error) {
+	s.endOnce.Do(func() {
+		if s.config.RecordEvents {
+			s.span.AddEvent("grpc.stream.completed")
+		}
+		setSpanStatus(s.span, err, s.config.RecordErrors)
+		s.span.End()
+	})
+}
+
 // metadataCarrier adapts grpc metadata to be a TextMapCarrier
 type metadataCarrier struct {
 	md metadata.MD
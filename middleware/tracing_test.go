@@ -3,13 +3,19 @@ package middleware
 import (
 	"context"
 	"errors"
+	"io"
 	"testing"
 
+	"github.com/grpc-guardian/grpc-guardian/pkg/tracing"
 	"go.opentelemetry.io/otel"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
 	"go.opentelemetry.io/otel/sdk/trace"
 	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	oteltrace "go.opentelemetry.io/otel/trace"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 )
 
@@ -54,8 +60,8 @@ func TestTracing(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			// Reset span recorder
-			sr.Reset()
+			// Only count the spans this case ends
+			ended := len(sr.Ended())
 
 			// Create mock server info
 			info := &grpc.UnaryServerInfo{
@@ -75,7 +81,7 @@ func TestTracing(t *testing.T) {
 			_ = tp.ForceFlush(context.Background())
 
 			// Check span count
-			spans := sr.Ended()
+			spans := sr.Ended()[ended:]
 			if len(spans) != tt.wantSpanCount {
 				t.Errorf("Expected %d spans, got %d", tt.wantSpanCount, len(spans))
 			}
@@ -181,3 +187,225 @@ func TestSpanHelpers(t *testing.T) {
 		}
 	}
 }
+
+func TestTracingPropagation_Formats(t *testing.T) {
+	const (
+		traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+		spanID  = "00f067aa0ba902b7"
+	)
+
+	tests := []struct {
+		name        string
+		opts        []TracingOption
+		md          metadata.MD
+		wantTraceID string
+		wantSampled bool
+	}{
+		{
+			name:        "b3 multiple headers",
+			opts:        []TracingOption{WithB3Propagation()},
+			md:          metadata.Pairs("x-b3-traceid", traceID, "x-b3-spanid", spanID, "x-b3-sampled", "1"),
+			wantTraceID: traceID,
+			wantSampled: true,
+		},
+		{
+			name:        "b3 single header with 64-bit trace ID",
+			opts:        []TracingOption{WithB3Propagation()},
+			md:          metadata.Pairs("b3", "a3ce929d0e0e4736-"+spanID+"-d"),
+			wantTraceID: "0000000000000000a3ce929d0e0e4736",
+			wantSampled: true,
+		},
+		{
+			name:        "jaeger",
+			opts:        []TracingOption{WithJaegerPropagation()},
+			md:          metadata.Pairs("uber-trace-id", traceID+"%3A"+spanID+"%3A0%3A1"),
+			wantTraceID: traceID,
+			wantSampled: true,
+		},
+		{
+			name:        "w3c and b3 composed",
+			opts:        []TracingOption{WithPropagator(propagation.TraceContext{}), WithB3Propagation(), WithJaegerPropagation()},
+			md:          metadata.Pairs("traceparent", "00-"+traceID+"-"+spanID+"-01"),
+			wantTraceID: traceID,
+			wantSampled: true,
+		},
+		{
+			name: "b3 ignored without the option",
+			opts: []TracingOption{WithPropagator(propagation.TraceContext{})},
+			md:   metadata.Pairs("x-b3-traceid", traceID, "x-b3-spanid", spanID, "x-b3-sampled", "1"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sr := tracetest.NewSpanRecorder()
+			tp := trace.NewTracerProvider(trace.WithSpanProcessor(sr))
+
+			interceptor := Tracing(append(tt.opts, WithTracer(tp.Tracer("test")))...)
+			ctx := metadata.NewIncomingContext(context.Background(), tt.md)
+			_, _ = interceptor(ctx, "req", &grpc.UnaryServerInfo{FullMethod: "/test.Service/Method"},
+				func(ctx context.Context, req interface{}) (interface{}, error) { return "ok", nil })
+
+			spans := sr.Ended()
+			if len(spans) != 1 {
+				t.Fatalf("Expected 1 span, got %d", len(spans))
+			}
+			parent := spans[0].Parent()
+			if tt.wantTraceID == "" {
+				if parent.IsValid() {
+					t.Errorf("Expected a root span, got parent %v", parent.TraceID())
+				}
+				return
+			}
+			if got := parent.TraceID().String(); got != tt.wantTraceID {
+				t.Errorf("Parent trace ID = %s, want %s", got, tt.wantTraceID)
+			}
+			if parent.IsSampled() != tt.wantSampled {
+				t.Errorf("Parent sampled = %v, want %v", parent.IsSampled(), tt.wantSampled)
+			}
+			if !parent.IsRemote() {
+				t.Error("Expected a remote parent")
+			}
+		})
+	}
+}
+
+func TestTracingPropagation_Inject(t *testing.T) {
+	traceID, _ := oteltrace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := oteltrace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := oteltrace.ContextWithSpanContext(context.Background(), oteltrace.NewSpanContext(oteltrace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: oteltrace.FlagsSampled,
+	}))
+
+	carrier := propagation.MapCarrier{}
+	tracing.B3{InjectEncoding: tracing.B3SingleHeader | tracing.B3MultipleHeader}.Inject(ctx, carrier)
+	tracing.Jaeger{}.Inject(ctx, carrier)
+
+	want := map[string]string{
+		"b3":            "4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-1",
+		"x-b3-traceid":  "4bf92f3577b34da6a3ce929d0e0e4736",
+		"x-b3-spanid":   "00f067aa0ba902b7",
+		"x-b3-sampled":  "1",
+		"uber-trace-id": "4bf92f3577b34da6a3ce929d0e0e4736:00f067aa0ba902b7:0:1",
+	}
+	for k, v := range want {
+		if carrier[k] != v {
+			t.Errorf("%s = %q, want %q", k, carrier[k], v)
+		}
+	}
+
+	// Round trip
+	for _, p := range []propagation.TextMapPropagator{tracing.B3{}, tracing.Jaeger{}} {
+		sc := oteltrace.SpanContextFromContext(p.Extract(context.Background(), carrier))
+		if sc.TraceID() != traceID || sc.SpanID() != spanID || !sc.IsSampled() {
+			t.Errorf("%T: round trip mismatch: %v", p, sc)
+		}
+	}
+}
+
+func TestTracingClientInterceptor_Unary(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	tp := trace.NewTracerProvider(trace.WithSpanProcessor(sr))
+
+	interceptor := TracingUnaryClientInterceptor(
+		WithTracer(tp.Tracer("test")),
+		WithPropagator(propagation.TraceContext{}),
+		WithRecordErrors(),
+	)
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-tenant", "acme")
+	var sent metadata.MD
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		sent, _ = metadata.FromOutgoingContext(ctx)
+		return status.Error(codes.Unavailable, "backend down")
+	}
+
+	err := interceptor(ctx, "/test.Service/Get", "req", nil, nil, invoker)
+	if status.Code(err) != codes.Unavailable {
+		t.Fatalf("Expected Unavailable, got %v", err)
+	}
+
+	spans := sr.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("Expected 1 span, got %d", len(spans))
+	}
+	span := spans[0]
+	if span.SpanKind() != oteltrace.SpanKindClient {
+		t.Errorf("Expected client span, got %v", span.SpanKind())
+	}
+	if len(span.Events()) == 0 {
+		t.Error("Expected the error to be recorded")
+	}
+
+	if got := sent.Get("x-tenant"); len(got) != 1 || got[0] != "acme" {
+		t.Errorf("Expected existing metadata to be kept, got %v", got)
+	}
+	traceparent := sent.Get("traceparent")
+	if len(traceparent) != 1 {
+		t.Fatalf("Expected traceparent header, got %v", sent)
+	}
+	sc := span.SpanContext()
+	if want := "00-" + sc.TraceID().String() + "-" + sc.SpanID().String() + "-01"; traceparent[0] != want {
+		t.Errorf("traceparent = %s, want %s", traceparent[0], want)
+	}
+}
+
+type fakeClientStream struct {
+	grpc.ClientStream
+	recv []error
+}
+
+func (s *fakeClientStream) RecvMsg(m interface{}) error {
+	err := s.recv[0]
+	s.recv = s.recv[1:]
+	return err
+}
+
+func TestTracingClientInterceptor_Stream(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	tp := trace.NewTracerProvider(trace.WithSpanProcessor(sr))
+	interceptor := TracingStreamClientInterceptor(WithTracer(tp.Tracer("test")), WithRecordEvents())
+
+	desc := &grpc.StreamDesc{ServerStreams: true}
+	streamer := func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return &fakeClientStream{recv: []error{nil, nil, io.EOF}}, nil
+	}
+
+	cs, err := interceptor(context.Background(), desc, nil, "/test.Service/Watch", streamer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := cs.RecvMsg(nil); err != nil {
+			t.Fatal(err)
+		}
+		if len(sr.Ended()) != 0 {
+			t.Fatal("Span ended before the stream finished")
+		}
+	}
+	if err := cs.RecvMsg(nil); err != io.EOF {
+		t.Fatalf("Expected io.EOF, got %v", err)
+	}
+
+	spans := sr.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("Expected 1 span, got %d", len(spans))
+	}
+	if spans[0].Status().Code != otelcodes.Ok {
+		t.Errorf("Expected Ok status, got %v", spans[0].Status())
+	}
+
+	// Failing to open the stream ends the span right away
+	failing := func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return nil, status.Error(codes.Unavailable, "no connection")
+	}
+	if _, err := interceptor(context.Background(), desc, nil, "/test.Service/Watch", failing); err == nil {
+		t.Fatal("Expected error")
+	}
+	spans = sr.Ended()
+	if len(spans) != 2 || spans[1].Status().Code != otelcodes.Error {
+		t.Errorf("Expected a second span with error status, got %d spans", len(spans))
+	}
+}
@@ -0,0 +1,400 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Transformer mutates a request in place before the handler sees it. Returning a status
+// error fails the call with it; other errors fail it with InvalidArgument.
+type Transformer func(ctx context.Context, req proto.Message) error
+
+// transformRule applies transformers to methods matching pattern
+type transformRule struct {
+	pattern      string
+	transformers []Transformer
+}
+
+// transformConfig holds the configuration of Transform
+type transformConfig struct {
+	rules []transformRule
+}
+
+// TransformOption configures Transform
+type TransformOption func(*transformConfig)
+
+// WithTransform registers transformers for methods matching pattern: "/pkg.Service/Method",
+// "/pkg.Service/*" or "*". Transformers of every matching pattern run, the least specific
+// pattern first, and in the order they were registered.
+func WithTransform(pattern string, transformers ...Transformer) TransformOption {
+	return func(c *transformConfig) {
+		c.rules = append(c.rules, transformRule{pattern: pattern, transformers: transformers})
+	}
+}
+
+// Transform creates middleware that normalizes incoming requests before the handler runs:
+// defaults for unset fields, trimmed strings, clamped page sizes. Put it before Validate,
+// so requests are validated in their normalized form. Requests that are not protobuf
+// messages are passed through.
+//
+// Example usage:
+//
+//	chain := guardian.NewChain(
+//	    middleware.Transform(
+//	        middleware.WithTransform("*", middleware.TrimStrings()),
+//	        middleware.WithTransform("/catalog.Products/List",
+//	            middleware.SetDefault("page_size", 50),
+//	            middleware.ClampInt("page_size", 1, 500),
+//	            middleware.NormalizeString("filter.category", strings.ToLower),
+//	        ),
+//	    ),
+//	    middleware.Validate(),
+//	)
+func Transform(opts ...TransformOption) func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	config := newTransformConfig(opts)
+
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := config.transform(ctx, info.FullMethod, req); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamTransform creates middleware that normalizes every message received on a stream
+func StreamTransform(opts ...TransformOption) func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	config := newTransformConfig(opts)
+
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if len(config.transformers(info.FullMethod)) == 0 {
+			return handler(srv, ss)
+		}
+		return handler(srv, &transformingServerStream{ServerStream: ss, config: config, method: info.FullMethod})
+	}
+}
+
+func newTransformConfig(opts []TransformOption) *transformConfig {
+	config := &transformConfig{}
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	// Stable, so transformers of equally specific patterns keep their registration order
+	sort.SliceStable(config.rules, func(i, j int) bool {
+		return patternSpecificity(config.rules[i].pattern) < patternSpecificity(config.rules[j].pattern)
+	})
+	return config
+}
+
+// patternSpecificity orders patterns from "*" to prefixes (shortest first) to exact methods
+func patternSpecificity(pattern string) int {
+	if strings.HasSuffix(pattern, "*") {
+		return len(pattern)
+	}
+	return 1 << 30
+}
+
+// transformers returns the transformers of a method, least specific first
+func (c *transformConfig) transformers(method string) []Transformer {
+	var transformers []Transformer
+	for _, rule := range c.rules {
+		if strings.HasSuffix(rule.pattern, "*") {
+			if !strings.HasPrefix(method, strings.TrimSuffix(rule.pattern, "*")) {
+				continue
+			}
+		} else if rule.pattern != method {
+			continue
+		}
+		transformers = append(transformers, rule.transformers...)
+	}
+	return transformers
+}
+
+// transform applies the transformers of method to req
+func (c *transformConfig) transform(ctx context.Context, method string, req interface{}) error {
+	msg, ok := req.(proto.Message)
+	if !ok {
+		return nil
+	}
+
+	for _, transformer := range c.transformers(method) {
+		if err := transformer(ctx, msg); err != nil {
+			if _, isStatus := status.FromError(err); isStatus {
+				return err
+			}
+			return status.Errorf(codes.InvalidArgument, "%v", err)
+		}
+	}
+	return nil
+}
+
+// transformingServerStream transforms each received message
+type transformingServerStream struct {
+	grpc.ServerStream
+	config *transformConfig
+	method string
+}
+
+// RecvMsg receives a message and transforms it
+func (s *transformingServerStream) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	return s.config.transform(s.Context(), s.method, m)
+}
+
+// TransformFunc adapts a function of a concrete request type; requests of other types are
+// left alone, so it can be registered for wildcard patterns
+//
+//	middleware.TransformFunc(func(ctx context.Context, req *pb.SearchRequest) error {
+//	    req.Query = strings.Join(strings.Fields(req.Query), " ")
+//	    return nil
+//	})
+func TransformFunc[T proto.Message](fn func(ctx context.Context, req T) error) Transformer {
+	return func(ctx context.Context, req proto.Message) error {
+		typed, ok := req.(T)
+		if !ok {
+			return nil
+		}
+		return fn(ctx, typed)
+	}
+}
+
+// SetDefault sets a singular field, e.g. "page_size" or "filter.region", to value when it
+// is unset. value is converted to the field's kind: Go integers and floats for numeric
+// fields, a name or number for enums, a message of the field's type for message fields.
+// Requests without the field are left alone.
+func SetDefault(field string, value interface{}) Transformer {
+	return func(ctx context.Context, req proto.Message) error {
+		m, fd := resolveTransformField(req.ProtoReflect(), field, true)
+		if fd == nil || m.Has(fd) {
+			return nil
+		}
+		if fd.IsList() || fd.IsMap() {
+			return status.Errorf(codes.Internal, "transform: %s is not a singular field", fd.FullName())
+		}
+
+		v, err := transformValue(fd, value)
+		if err != nil {
+			return status.Errorf(codes.Internal, "transform: %s: %v", fd.FullName(), err)
+		}
+		m.Set(fd, v)
+		return nil
+	}
+}
+
+// ClampInt keeps an integer field, e.g. "page_size", within [min, max]. Unset fields count
+// as 0, so run SetDefault first when 0 means "server default". Requests without the field
+// are left alone.
+func ClampInt(field string, min, max int64) Transformer {
+	return func(ctx context.Context, req proto.Message) error {
+		m, fd := resolveTransformField(req.ProtoReflect(), field, false)
+		if fd == nil || fd.IsList() || fd.IsMap() {
+			return nil
+		}
+
+		switch fd.Kind() {
+		case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+			m.Set(fd, protoreflect.ValueOfInt32(int32(clampInt64(int64(m.Get(fd).Int()), min, max))))
+		case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+			m.Set(fd, protoreflect.ValueOfInt64(clampInt64(m.Get(fd).Int(), min, max)))
+		case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+			m.Set(fd, protoreflect.ValueOfUint32(uint32(clampInt64(int64(m.Get(fd).Uint()), min, max))))
+		case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+			value := m.Get(fd).Uint()
+			if value > uint64(1<<63-1) {
+				value = uint64(1<<63 - 1)
+			}
+			m.Set(fd, protoreflect.ValueOfUint64(uint64(clampInt64(int64(value), min, max))))
+		default:
+			return status.Errorf(codes.Internal, "transform: %s is not an integer field", fd.FullName())
+		}
+		return nil
+	}
+}
+
+// clampInt64 keeps value within [min, max]
+func clampInt64(value, min, max int64) int64 {
+	if value < min {
+		return min
+	}
+	if value > max {
+		return max
+	}
+	return value
+}
+
+// NormalizeString applies fn to a string field, or to every element of a repeated string
+// field, e.g. NormalizeString("email", strings.ToLower). Requests without the field are
+// left alone.
+func NormalizeString(field string, fn func(string) string) Transformer {
+	return func(ctx context.Context, req proto.Message) error {
+		m, fd := resolveTransformField(req.ProtoReflect(), field, false)
+		if fd == nil || fd.Kind() != protoreflect.StringKind || fd.IsMap() {
+			return nil
+		}
+
+		if fd.IsList() {
+			list := m.Mutable(fd).List()
+			for i := 0; i < list.Len(); i++ {
+				list.Set(i, protoreflect.ValueOfString(fn(list.Get(i).String())))
+			}
+			return nil
+		}
+		if m.Has(fd) {
+			m.Set(fd, protoreflect.ValueOfString(fn(m.Get(fd).String())))
+		}
+		return nil
+	}
+}
+
+// TrimStrings trims leading and trailing whitespace from every string of a request,
+// including repeated fields, map values and nested messages
+func TrimStrings() Transformer {
+	return func(ctx context.Context, req proto.Message) error {
+		trimStrings(req.ProtoReflect())
+		return nil
+	}
+}
+
+// trimStrings trims the strings of m and its nested messages
+func trimStrings(m protoreflect.Message) {
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		switch {
+		case fd.IsList():
+			list := v.List()
+			for i := 0; i < list.Len(); i++ {
+				switch fd.Kind() {
+				case protoreflect.StringKind:
+					list.Set(i, protoreflect.ValueOfString(strings.TrimSpace(list.Get(i).String())))
+				case protoreflect.MessageKind, protoreflect.GroupKind:
+					trimStrings(list.Get(i).Message())
+				}
+			}
+		case fd.IsMap():
+			mapValue := fd.MapValue()
+			v.Map().Range(func(key protoreflect.MapKey, value protoreflect.Value) bool {
+				switch mapValue.Kind() {
+				case protoreflect.StringKind:
+					v.Map().Set(key, protoreflect.ValueOfString(strings.TrimSpace(value.String())))
+				case protoreflect.MessageKind, protoreflect.GroupKind:
+					trimStrings(value.Message())
+				}
+				return true
+			})
+		case fd.Kind() == protoreflect.StringKind:
+			m.Set(fd, protoreflect.ValueOfString(strings.TrimSpace(v.String())))
+		case fd.Kind() == protoreflect.MessageKind || fd.Kind() == protoreflect.GroupKind:
+			trimStrings(v.Message())
+		}
+		return true
+	})
+}
+
+// resolveTransformField walks a dotted field path and returns the message holding the last
+// field and its descriptor, or a nil descriptor when the path does not exist. With create,
+// unset intermediate messages are created; otherwise a nil descriptor is returned for them.
+func resolveTransformField(m protoreflect.Message, path string, create bool) (protoreflect.Message, protoreflect.FieldDescriptor) {
+	names := strings.Split(path, ".")
+	for i, name := range names {
+		fd := m.Descriptor().Fields().ByName(protoreflect.Name(name))
+		if fd == nil {
+			return nil, nil
+		}
+		if i == len(names)-1 {
+			return m, fd
+		}
+		if fd.Message() == nil || fd.IsList() || fd.IsMap() {
+			return nil, nil
+		}
+		if !m.Has(fd) && !create {
+			return nil, nil
+		}
+		m = m.Mutable(fd).Message()
+	}
+	return nil, nil
+}
+
+// transformValue converts a Go value to the value of a field
+func transformValue(fd protoreflect.FieldDescriptor, value interface{}) (protoreflect.Value, error) {
+	rv := reflect.ValueOf(value)
+
+	switch fd.Kind() {
+	case protoreflect.StringKind:
+		if s, ok := value.(string); ok {
+			return protoreflect.ValueOfString(s), nil
+		}
+	case protoreflect.BytesKind:
+		if b, ok := value.([]byte); ok {
+			return protoreflect.ValueOfBytes(append([]byte(nil), b...)), nil
+		}
+	case protoreflect.BoolKind:
+		if b, ok := value.(bool); ok {
+			return protoreflect.ValueOfBool(b), nil
+		}
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		if n, ok := integerValue(rv); ok {
+			return protoreflect.ValueOfInt32(int32(n)), nil
+		}
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		if n, ok := integerValue(rv); ok {
+			return protoreflect.ValueOfInt64(n), nil
+		}
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		if n, ok := integerValue(rv); ok && n >= 0 {
+			return protoreflect.ValueOfUint32(uint32(n)), nil
+		}
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		if n, ok := integerValue(rv); ok && n >= 0 {
+			return protoreflect.ValueOfUint64(uint64(n)), nil
+		}
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		if n, ok := integerValue(rv); ok {
+			return protoreflect.ValueOfFloat64(float64(n)), nil
+		}
+		if rv.IsValid() && (rv.Kind() == reflect.Float32 || rv.Kind() == reflect.Float64) {
+			if fd.Kind() == protoreflect.FloatKind {
+				return protoreflect.ValueOfFloat32(float32(rv.Float())), nil
+			}
+			return protoreflect.ValueOfFloat64(rv.Float()), nil
+		}
+	case protoreflect.EnumKind:
+		if name, ok := value.(string); ok {
+			if ev := fd.Enum().Values().ByName(protoreflect.Name(name)); ev != nil {
+				return protoreflect.ValueOfEnum(ev.Number()), nil
+			}
+			return protoreflect.Value{}, fmt.Errorf("enum %s has no value %q", fd.Enum().FullName(), name)
+		}
+		if n, ok := integerValue(rv); ok {
+			return protoreflect.ValueOfEnum(protoreflect.EnumNumber(n)), nil
+		}
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		if msg, ok := value.(proto.Message); ok && msg.ProtoReflect().Descriptor().FullName() == fd.Message().FullName() {
+			return protoreflect.ValueOfMessage(proto.Clone(msg).ProtoReflect()), nil
+		}
+	}
+	return protoreflect.Value{}, fmt.Errorf("cannot use %T as %s", value, fd.Kind())
+}
+
+// integerValue returns the value of a Go integer
+func integerValue(rv reflect.Value) (int64, bool) {
+	if !rv.IsValid() {
+		return 0, false
+	}
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int(), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(rv.Uint()), true
+	}
+	return 0, false
+}
@@ -0,0 +1,192 @@
+package middleware
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func runTransform(t *testing.T, method string, req interface{}, opts ...TransformOption) (interface{}, error) {
+	t.Helper()
+	var seen interface{}
+	_, err := Transform(opts...)(context.Background(), req, &grpc.UnaryServerInfo{FullMethod: method},
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			seen = req
+			return nil, nil
+		})
+	return seen, err
+}
+
+func TestTransform_DefaultsAndClamping(t *testing.T) {
+	opts := []TransformOption{
+		WithTransform("/test.Fields/Create",
+			SetDefault("number", 50),
+			ClampInt("number", 1, 100),
+			SetDefault("label", "LABEL_REPEATED"),
+			SetDefault("options.deprecated", true),
+			SetDefault("name", "unnamed"),
+		),
+	}
+
+	seen, err := runTransform(t, "/test.Fields/Create", &descriptorpb.FieldDescriptorProto{Name: proto.String("id")}, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	field := seen.(*descriptorpb.FieldDescriptorProto)
+	if field.GetNumber() != 50 || field.GetLabel() != descriptorpb.FieldDescriptorProto_LABEL_REPEATED || !field.GetOptions().GetDeprecated() {
+		t.Errorf("Expected defaults for unset fields, got %v", field)
+	}
+	if field.GetName() != "id" {
+		t.Errorf("Expected set fields to be kept, got %q", field.GetName())
+	}
+
+	seen, err = runTransform(t, "/test.Fields/Create", &descriptorpb.FieldDescriptorProto{Number: proto.Int32(5000)}, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := seen.(*descriptorpb.FieldDescriptorProto).GetNumber(); got != 100 {
+		t.Errorf("Expected the number to be clamped to 100, got %d", got)
+	}
+}
+
+func TestTransform_TrimAndNormalize(t *testing.T) {
+	req := &descriptorpb.FileDescriptorProto{
+		Name:        proto.String("  orders.proto \n"),
+		Package:     proto.String(" Shop.Orders "),
+		Dependency:  []string{" common.proto"},
+		MessageType: []*descriptorpb.DescriptorProto{{Name: proto.String(" Order ")}},
+	}
+
+	seen, err := runTransform(t, "/test.Files/Upload", req,
+		WithTransform("/test.Files/Upload", NormalizeString("package", strings.ToLower)),
+		WithTransform("*", TrimStrings()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := seen.(*descriptorpb.FileDescriptorProto)
+	if file.GetName() != "orders.proto" || file.GetDependency()[0] != "common.proto" || file.GetMessageType()[0].GetName() != "Order" {
+		t.Errorf("Expected every string to be trimmed, got %v", file)
+	}
+	// The wildcard pattern runs first, so the package is trimmed before it is lowercased
+	if file.GetPackage() != "shop.orders" {
+		t.Errorf("Expected a trimmed, lowercased package, got %q", file.GetPackage())
+	}
+}
+
+func TestTransform_TrimMapValues(t *testing.T) {
+	req, err := structpb.NewStruct(map[string]interface{}{"city": " Tokyo ", "nested": map[string]interface{}{"zip": "100 "}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	seen, err := runTransform(t, "/test.Struct/Put", req, WithTransform("*", TrimStrings()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields := seen.(*structpb.Struct).AsMap()
+	if fields["city"] != "Tokyo" || fields["nested"].(map[string]interface{})["zip"] != "100" {
+		t.Errorf("Expected map values to be trimmed, got %v", fields)
+	}
+}
+
+func TestTransform_SkipsOtherMethodsAndFields(t *testing.T) {
+	opts := []TransformOption{
+		WithTransform("/test.Fields/*", SetDefault("page_size", 20), ClampInt("number", 1, 10)),
+	}
+
+	// Requests without the field are left alone
+	seen, err := runTransform(t, "/test.Fields/Get", &descriptorpb.FieldDescriptorProto{Number: proto.Int32(50)}, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := seen.(*descriptorpb.FieldDescriptorProto).GetNumber(); got != 10 {
+		t.Errorf("Expected the matching method to be clamped, got %d", got)
+	}
+
+	seen, err = runTransform(t, "/test.Other/Get", &descriptorpb.FieldDescriptorProto{Number: proto.Int32(50)}, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := seen.(*descriptorpb.FieldDescriptorProto).GetNumber(); got != 50 {
+		t.Errorf("Expected other methods to be left alone, got %d", got)
+	}
+}
+
+func TestTransform_Errors(t *testing.T) {
+	_, err := runTransform(t, "/test.Fields/Create", &descriptorpb.FieldDescriptorProto{},
+		WithTransform("*", TransformFunc(func(ctx context.Context, req *descriptorpb.FieldDescriptorProto) error {
+			if req.GetName() == "" {
+				return status.Error(codes.FailedPrecondition, "name required")
+			}
+			return nil
+		})),
+	)
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("Expected the transformer's status, got %v", err)
+	}
+
+	_, err = runTransform(t, "/test.Fields/Create", &descriptorpb.FieldDescriptorProto{},
+		WithTransform("*", func(ctx context.Context, req proto.Message) error { return context.Canceled }),
+	)
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected plain errors to become InvalidArgument, got %v", err)
+	}
+
+	_, err = runTransform(t, "/test.Fields/Create", &descriptorpb.FieldDescriptorProto{},
+		WithTransform("*", SetDefault("label", "LABEL_UNKNOWN")),
+	)
+	if status.Code(err) != codes.Internal {
+		t.Errorf("Expected an unknown enum value to be a programming error, got %v", err)
+	}
+}
+
+func TestStreamTransform(t *testing.T) {
+	stream := &recvServerStream{ctx: context.Background(), msgs: []proto.Message{
+		&descriptorpb.FieldDescriptorProto{Name: proto.String(" a ")},
+		&descriptorpb.FieldDescriptorProto{Name: proto.String("b  ")},
+	}}
+
+	var names []string
+	err := StreamTransform(WithTransform("/test.Fields/Upload", TrimStrings()))(nil, stream,
+		&grpc.StreamServerInfo{FullMethod: "/test.Fields/Upload"},
+		func(srv interface{}, ss grpc.ServerStream) error {
+			for range stream.msgs {
+				field := &descriptorpb.FieldDescriptorProto{}
+				if err := ss.RecvMsg(field); err != nil {
+					return err
+				}
+				names = append(names, field.GetName())
+			}
+			return nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(names, ",") != "a,b" {
+		t.Errorf("Expected every message to be trimmed, got %q", names)
+	}
+}
+
+// recvServerStream replays messages to RecvMsg
+type recvServerStream struct {
+	grpc.ServerStream
+	ctx  context.Context
+	msgs []proto.Message
+	next int
+}
+
+func (s *recvServerStream) Context() context.Context { return s.ctx }
+
+func (s *recvServerStream) RecvMsg(m interface{}) error {
+	proto.Merge(m.(proto.Message), s.msgs[s.next])
+	s.next++
+	return nil
+}
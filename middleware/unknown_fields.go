@@ -0,0 +1,250 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/grpc-guardian/grpc-guardian/pkg/logging"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// UnknownField is a field of a request the server's schema does not know, usually sent by
+// a client built from a newer version of the proto
+type UnknownField struct {
+	Path   string // Path of the message holding the field, e.g. "items[2].address"; empty for the request itself
+	Number int32  // Field number
+}
+
+// String formats the field as its path and number, e.g. "items[2].address.#12"
+func (f UnknownField) String() string {
+	if f.Path == "" {
+		return "#" + strconv.Itoa(int(f.Number))
+	}
+	return f.Path + ".#" + strconv.Itoa(int(f.Number))
+}
+
+// unknownFieldsConfig holds the configuration of UnknownFields
+type unknownFieldsConfig struct {
+	reject     bool
+	logger     logging.Logger
+	registerer prometheus.Registerer
+	callback   func(ctx context.Context, method string, fields []UnknownField)
+}
+
+// UnknownFieldsOption configures UnknownFields
+type UnknownFieldsOption func(*unknownFieldsConfig)
+
+// WithRejectUnknownFields fails requests with unknown fields with InvalidArgument and a
+// google.rpc.BadRequest detail listing them, instead of silently dropping the data
+func WithRejectUnknownFields() UnknownFieldsOption {
+	return func(c *unknownFieldsConfig) {
+		c.reject = true
+	}
+}
+
+// WithUnknownFieldsLogger logs a warning with the method and unknown field numbers of
+// every request that has any
+func WithUnknownFieldsLogger(logger logging.Logger) UnknownFieldsOption {
+	return func(c *unknownFieldsConfig) {
+		c.logger = logger
+	}
+}
+
+// WithUnknownFieldsMetrics registers a grpc_server_unknown_fields_total counter, labeled by
+// method, counting requests with unknown fields
+func WithUnknownFieldsMetrics(registerer prometheus.Registerer) UnknownFieldsOption {
+	return func(c *unknownFieldsConfig) {
+		c.registerer = registerer
+	}
+}
+
+// WithUnknownFieldsCallback is called with the unknown fields of every request that has any
+func WithUnknownFieldsCallback(fn func(ctx context.Context, method string, fields []UnknownField)) UnknownFieldsOption {
+	return func(c *unknownFieldsConfig) {
+		c.callback = fn
+	}
+}
+
+// unknownFieldsDetector inspects requests for unknown fields
+type unknownFieldsDetector struct {
+	config   *unknownFieldsConfig
+	requests *prometheus.CounterVec
+}
+
+// UnknownFields creates middleware that detects request fields unknown to the server's
+// schema. They mean a client runs a newer version of the API than the server, and their
+// data would otherwise be dropped without anyone noticing. Rolling out servers before
+// clients keeps the counter at zero; a rising counter points at version skew in the fleet.
+//
+// Example usage:
+//
+//	chain := guardian.NewChain(
+//	    middleware.UnknownFields(
+//	        middleware.WithUnknownFieldsMetrics(collector.GetRegistry()),
+//	        middleware.WithUnknownFieldsLogger(logger),
+//	    ),
+//	)
+func UnknownFields(opts ...UnknownFieldsOption) func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	d := newUnknownFieldsDetector(opts)
+
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := d.inspect(ctx, info.FullMethod, req); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamUnknownFields inspects every message received on a stream
+func StreamUnknownFields(opts ...UnknownFieldsOption) func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	d := newUnknownFieldsDetector(opts)
+
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &unknownFieldsServerStream{ServerStream: ss, detector: d, method: info.FullMethod})
+	}
+}
+
+func newUnknownFieldsDetector(opts []UnknownFieldsOption) *unknownFieldsDetector {
+	config := &unknownFieldsConfig{}
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	d := &unknownFieldsDetector{config: config}
+	if config.registerer != nil {
+		d.requests = prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "grpc",
+			Subsystem: "server",
+			Name:      "unknown_fields_total",
+			Help:      "Total number of requests with fields unknown to the server's schema",
+		}, []string{"method"})
+		if err := config.registerer.Register(d.requests); err != nil {
+			if are, ok := err.(prometheus.AlreadyRegisteredError); ok {
+				d.requests = are.ExistingCollector.(*prometheus.CounterVec)
+			}
+		}
+	}
+	return d
+}
+
+// inspect reports the unknown fields of req and returns an error when they are rejected
+func (d *unknownFieldsDetector) inspect(ctx context.Context, method string, req interface{}) error {
+	msg, ok := req.(proto.Message)
+	if !ok {
+		return nil
+	}
+	fields := FindUnknownFields(msg)
+	if len(fields) == 0 {
+		return nil
+	}
+
+	if d.requests != nil {
+		d.requests.WithLabelValues(method).Inc()
+	}
+	if d.config.logger != nil {
+		names := make([]string, len(fields))
+		for i, field := range fields {
+			names[i] = field.String()
+		}
+		d.config.logger.Warn("request has unknown fields",
+			logging.String("method", method),
+			logging.Any("unknown_fields", names),
+		)
+	}
+	if d.config.callback != nil {
+		d.config.callback(ctx, method, fields)
+	}
+
+	if !d.config.reject {
+		return nil
+	}
+	violations := make([]FieldViolation, len(fields))
+	for i, field := range fields {
+		violations[i] = FieldViolation{
+			Field:       field.String(),
+			Description: fmt.Sprintf("field number %d is unknown to this server", field.Number),
+		}
+	}
+	return invalidArgument(method, violations)
+}
+
+// FindUnknownFields returns the unknown fields of a message and its nested messages, in
+// path and number order
+func FindUnknownFields(msg proto.Message) []UnknownField {
+	var fields []UnknownField
+	collectUnknownFields(msg.ProtoReflect(), "", &fields)
+	sort.Slice(fields, func(i, j int) bool {
+		if fields[i].Path != fields[j].Path {
+			return fields[i].Path < fields[j].Path
+		}
+		return fields[i].Number < fields[j].Number
+	})
+	return fields
+}
+
+// collectUnknownFields appends the unknown fields of m, found at path, and its nested
+// messages to fields
+func collectUnknownFields(m protoreflect.Message, path string, fields *[]UnknownField) {
+	seen := make(map[protowire.Number]bool)
+	for raw := m.GetUnknown(); len(raw) > 0; {
+		number, _, n := protowire.ConsumeField(raw)
+		if n < 0 {
+			break
+		}
+		if !seen[number] {
+			seen[number] = true
+			*fields = append(*fields, UnknownField{Path: path, Number: int32(number)})
+		}
+		raw = raw[n:]
+	}
+
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		name := string(fd.Name())
+		if path != "" {
+			name = path + "." + name
+		}
+
+		switch {
+		case fd.IsList():
+			if fd.Message() == nil {
+				return true
+			}
+			list := v.List()
+			for i := 0; i < list.Len(); i++ {
+				collectUnknownFields(list.Get(i).Message(), fmt.Sprintf("%s[%d]", name, i), fields)
+			}
+		case fd.IsMap():
+			if fd.MapValue().Message() == nil {
+				return true
+			}
+			v.Map().Range(func(key protoreflect.MapKey, value protoreflect.Value) bool {
+				collectUnknownFields(value.Message(), fmt.Sprintf("%s[%v]", name, key.Interface()), fields)
+				return true
+			})
+		case fd.Message() != nil:
+			collectUnknownFields(v.Message(), name, fields)
+		}
+		return true
+	})
+}
+
+// unknownFieldsServerStream inspects each received message
+type unknownFieldsServerStream struct {
+	grpc.ServerStream
+	detector *unknownFieldsDetector
+	method   string
+}
+
+// RecvMsg receives a message and inspects it
+func (s *unknownFieldsServerStream) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	return s.detector.inspect(s.Context(), s.method, m)
+}
@@ -0,0 +1,147 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/grpc-guardian/grpc-guardian/pkg/logging"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// withUnknown appends varint fields with the given numbers to the unknown fields of msg,
+// as a newer client would send them
+func withUnknown[T proto.Message](msg T, numbers ...protowire.Number) T {
+	m := msg.ProtoReflect()
+	raw := m.GetUnknown()
+	for _, number := range numbers {
+		raw = protowire.AppendTag(raw, number, protowire.VarintType)
+		raw = protowire.AppendVarint(raw, 1)
+	}
+	m.SetUnknown(raw)
+	return msg
+}
+
+func TestFindUnknownFields(t *testing.T) {
+	req := withUnknown(&descriptorpb.FileDescriptorProto{
+		Name: proto.String("orders.proto"),
+		MessageType: []*descriptorpb.DescriptorProto{
+			{Name: proto.String("Order")},
+			withUnknown(&descriptorpb.DescriptorProto{Name: proto.String("Item")}, 30),
+		},
+		Options: withUnknown(&descriptorpb.FileOptions{}, 1001),
+	}, 99, 98, 99)
+
+	want := []UnknownField{
+		{Path: "", Number: 98},
+		{Path: "", Number: 99},
+		{Path: "message_type[1]", Number: 30},
+		{Path: "options", Number: 1001},
+	}
+	if got := FindUnknownFields(req); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+	if got := FindUnknownFields(&descriptorpb.FileDescriptorProto{Name: proto.String("a.proto")}); len(got) != 0 {
+		t.Errorf("Expected no unknown fields, got %v", got)
+	}
+}
+
+func TestUnknownFields_ReportsAndPasses(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	var logs bytes.Buffer
+	var reported []UnknownField
+
+	interceptor := UnknownFields(
+		WithUnknownFieldsMetrics(registry),
+		WithUnknownFieldsLogger(logging.NewSlog(slog.New(slog.NewJSONHandler(&logs, nil)))),
+		WithUnknownFieldsCallback(func(ctx context.Context, method string, fields []UnknownField) {
+			reported = fields
+		}),
+	)
+
+	info := &grpc.UnaryServerInfo{FullMethod: "/test.Fields/Create"}
+	handled := 0
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		handled++
+		return nil, nil
+	}
+
+	if _, err := interceptor(context.Background(), withUnknown(&descriptorpb.FieldDescriptorProto{}, 42), info, handler); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := interceptor(context.Background(), &descriptorpb.FieldDescriptorProto{}, info, handler); err != nil {
+		t.Fatal(err)
+	}
+
+	if handled != 2 {
+		t.Errorf("Expected both requests to be handled, got %d", handled)
+	}
+	if want := []UnknownField{{Number: 42}}; !reflect.DeepEqual(reported, want) {
+		t.Errorf("Expected %v to be reported, got %v", want, reported)
+	}
+	if !strings.Contains(logs.String(), "request has unknown fields") || !strings.Contains(logs.String(), "#42") {
+		t.Errorf("Expected a warning with the field number, got %s", logs.String())
+	}
+
+	expected := `
+# HELP grpc_server_unknown_fields_total Total number of requests with fields unknown to the server's schema
+# TYPE grpc_server_unknown_fields_total counter
+grpc_server_unknown_fields_total{method="/test.Fields/Create"} 1
+`
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(expected)); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestUnknownFields_Reject(t *testing.T) {
+	interceptor := UnknownFields(WithRejectUnknownFields())
+	req := &descriptorpb.FileDescriptorProto{Options: withUnknown(&descriptorpb.FileOptions{}, 1001)}
+
+	_, err := interceptor(context.Background(), req, &grpc.UnaryServerInfo{FullMethod: "/test.Files/Upload"},
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			t.Error("Expected the handler not to run")
+			return nil, nil
+		})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("Expected InvalidArgument, got %v", err)
+	}
+
+	for _, detail := range status.Convert(err).Details() {
+		if badRequest, ok := detail.(*errdetails.BadRequest); ok {
+			if got := badRequest.GetFieldViolations()[0].GetField(); got != "options.#1001" {
+				t.Errorf("Expected the violation to name options.#1001, got %q", got)
+			}
+			return
+		}
+	}
+	t.Error("Expected a BadRequest detail")
+}
+
+func TestStreamUnknownFields(t *testing.T) {
+	stream := &recvServerStream{ctx: context.Background(), msgs: []proto.Message{
+		&descriptorpb.FieldDescriptorProto{Name: proto.String("a")},
+		withUnknown(&descriptorpb.FieldDescriptorProto{Name: proto.String("b")}, 7),
+	}}
+
+	err := StreamUnknownFields(WithRejectUnknownFields())(nil, stream, &grpc.StreamServerInfo{FullMethod: "/test.Fields/Upload"},
+		func(srv interface{}, ss grpc.ServerStream) error {
+			if err := ss.RecvMsg(&descriptorpb.FieldDescriptorProto{}); err != nil {
+				t.Fatalf("Expected the first message to pass, got %v", err)
+			}
+			return ss.RecvMsg(&descriptorpb.FieldDescriptorProto{})
+		})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected the second message to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,183 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/grpc-guardian/grpc-guardian/pkg/quota"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// Trailing metadata keys describing the usage quota closest to running out
+const (
+	// QuotaLimitHeader is the number of requests allowed in the period
+	QuotaLimitHeader = "x-quota-limit"
+	// QuotaRemainingHeader is the number of requests left in the period
+	QuotaRemainingHeader = "x-quota-remaining"
+	// QuotaResetHeader is the number of seconds until the period ends
+	QuotaResetHeader = "x-quota-reset"
+	// QuotaPeriodHeader is the period of the reported quota ("day" or "month")
+	QuotaPeriodHeader = "x-quota-period"
+)
+
+// usageQuotaConfig configures the usage quota middlewares
+type usageQuotaConfig struct {
+	subject    func(context.Context) string
+	failClosed bool
+}
+
+// UsageQuotaOption configures UsageQuota
+type UsageQuotaOption func(*usageQuotaConfig)
+
+// WithQuotaSubject sets how the quota subject of a request is identified. Requests with an
+// empty subject are not counted.
+// Default: QuotaSubject
+func WithQuotaSubject(subject func(context.Context) string) UsageQuotaOption {
+	return func(c *usageQuotaConfig) {
+		if subject != nil {
+			c.subject = subject
+		}
+	}
+}
+
+// WithQuotaFailClosed rejects requests with Unavailable when the quota store fails
+// Default: requests are served when the store fails
+func WithQuotaFailClosed() UsageQuotaOption {
+	return func(c *usageQuotaConfig) {
+		c.failClosed = true
+	}
+}
+
+// UsageQuota creates middleware that counts requests against daily and monthly quotas of
+// a quota.Manager. Over-quota calls fail with ResourceExhausted, carrying QuotaFailure and
+// RetryInfo details; every call reports the quota closest to running out in the
+// x-quota-* trailers. Place it after authentication so the caller is known.
+//
+// Example usage:
+//
+//	manager, err := quota.NewManager(quota.NewRedisStore(client, "quota"),
+//	    quota.WithLimits(quota.Daily(10_000), quota.Monthly(200_000)),
+//	)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	chain := guardian.NewChain(
+//	    middleware.APIKeyAuth(keys),
+//	    middleware.UsageQuota(manager),
+//	)
+func UsageQuota(manager *quota.Manager, opts ...UsageQuotaOption) func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	config := newUsageQuotaConfig(opts)
+
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := consumeQuota(ctx, manager, config); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamUsageQuota counts each stream as one request against the quotas of its caller
+func StreamUsageQuota(manager *quota.Manager, opts ...UsageQuotaOption) func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	config := newUsageQuotaConfig(opts)
+
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := consumeQuota(ss.Context(), manager, config); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
+
+// QuotaSubject identifies the caller as "key:<id>" for API keys issued by APIKeyAuth or
+// "user:<id>" for authenticated users, and returns "" for anonymous callers
+func QuotaSubject(ctx context.Context) string {
+	if key, ok := GetAPIKey(ctx); ok {
+		return "key:" + key.ID
+	}
+	if userID, ok := GetUserID(ctx); ok && userID != "" {
+		return "user:" + userID
+	}
+	return ""
+}
+
+func newUsageQuotaConfig(opts []UsageQuotaOption) *usageQuotaConfig {
+	config := &usageQuotaConfig{subject: QuotaSubject}
+	for _, opt := range opts {
+		opt(config)
+	}
+	return config
+}
+
+// consumeQuota counts the request and reports the tightest quota in trailing metadata
+func consumeQuota(ctx context.Context, manager *quota.Manager, config *usageQuotaConfig) error {
+	subject := config.subject(ctx)
+	if subject == "" {
+		return nil
+	}
+
+	usage, err := manager.Consume(ctx, subject)
+	if err != nil && !errors.Is(err, quota.ErrExceeded) {
+		if config.failClosed {
+			return status.Errorf(codes.Unavailable, "failed to check quota: %v", err)
+		}
+		return nil
+	}
+	if len(usage) == 0 {
+		return nil
+	}
+
+	tightest := usage[0]
+	for _, u := range usage[1:] {
+		if u.Remaining < tightest.Remaining {
+			tightest = u
+		}
+	}
+	reset := time.Until(tightest.ResetAt)
+	// Fails outside of a gRPC server call (e.g. in unit tests); the quota is informational
+	_ = grpc.SetTrailer(ctx, metadata.Pairs(
+		QuotaLimitHeader, strconv.FormatInt(tightest.Limit, 10),
+		QuotaRemainingHeader, strconv.FormatInt(tightest.Remaining, 10),
+		QuotaResetHeader, strconv.FormatInt(int64(reset.Round(time.Second).Seconds()), 10),
+		QuotaPeriodHeader, string(tightest.Period),
+	))
+
+	if err != nil {
+		return quotaExceededError(subject, usage)
+	}
+	return nil
+}
+
+// quotaExceededError builds a ResourceExhausted error listing the exhausted quotas, with
+// a retry delay until the last of them resets
+func quotaExceededError(subject string, usage []quota.Usage) error {
+	failure := &errdetails.QuotaFailure{}
+	var resetAt time.Time
+	for _, u := range usage {
+		if !u.Exceeded() {
+			continue
+		}
+		failure.Violations = append(failure.Violations, &errdetails.QuotaFailure_Violation{
+			Subject:     subject,
+			Description: fmt.Sprintf("%d requests per %s", u.Limit, u.Period),
+		})
+		if u.ResetAt.After(resetAt) {
+			resetAt = u.ResetAt
+		}
+	}
+
+	st := status.Newf(codes.ResourceExhausted,
+		"quota exceeded for %s\nHint: Wait for the quota to reset or request a higher quota", subject)
+	detailed, err := st.WithDetails(failure, &errdetails.RetryInfo{RetryDelay: durationpb.New(time.Until(resetAt))})
+	if err != nil {
+		return st.Err()
+	}
+	return detailed.Err()
+}
@@ -0,0 +1,172 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grpc-guardian/grpc-guardian/pkg/quota"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// failingQuotaStore is a quota store that is down
+type failingQuotaStore struct{}
+
+func (failingQuotaStore) Increment(ctx context.Context, key string, n int64, expireAt time.Time) (int64, error) {
+	return 0, errors.New("connection refused")
+}
+
+func (failingQuotaStore) Get(ctx context.Context, key string) (int64, error) {
+	return 0, errors.New("connection refused")
+}
+
+func (failingQuotaStore) Delete(ctx context.Context, key string) error {
+	return errors.New("connection refused")
+}
+
+func TestUsageQuota(t *testing.T) {
+	// 23:00 on the 11th of next month, so that the store doesn't expire the counters
+	year, month, _ := time.Now().UTC().Date()
+	monthStart := time.Date(year, month+1, 1, 0, 0, 0, 0, time.UTC)
+	now := monthStart.AddDate(0, 0, 10).Add(23 * time.Hour)
+	manager, err := quota.NewManager(quota.NewMemoryStore(),
+		quota.WithLimits(quota.Daily(2), quota.Monthly(3)),
+		quota.WithSubjectLimits(func(ctx context.Context, subject string) []quota.Limit {
+			if subject == "user:vip" {
+				return []quota.Limit{} // Unlimited
+			}
+			return nil
+		}),
+		quota.WithClock(func() time.Time { return now }),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	interceptor := UsageQuota(manager)
+	info := &grpc.UnaryServerInfo{FullMethod: "/test.Service/Method"}
+	ok := func(ctx context.Context, req interface{}) (interface{}, error) { return "ok", nil }
+	call := func(user string) error {
+		ctx := context.Background()
+		if user != "" {
+			ctx = context.WithValue(ctx, contextKeyUserID, user)
+		}
+		_, err := interceptor(ctx, nil, info, ok)
+		return err
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := call("alice"); err != nil {
+			t.Fatalf("Request %d: unexpected error %v", i+1, err)
+		}
+	}
+
+	// The daily quota is used up
+	err = call("alice")
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("Expected ResourceExhausted, got %v", err)
+	}
+	var failure *errdetails.QuotaFailure
+	for _, detail := range status.Convert(err).Details() {
+		if f, ok := detail.(*errdetails.QuotaFailure); ok {
+			failure = f
+		}
+	}
+	if failure == nil || len(failure.Violations) != 1 || failure.Violations[0].Subject != "user:alice" {
+		t.Errorf("Expected a daily quota violation, got %v", failure)
+	}
+	if _, ok := RetryDelay(err); !ok {
+		t.Error("Expected a retry delay until the quota resets")
+	}
+
+	// Rejected requests aren't counted
+	usage, err := manager.Usage(context.Background(), "user:alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usage[0].Used != 2 || usage[0].Remaining != 0 || usage[1].Used != 2 || usage[1].Remaining != 1 {
+		t.Errorf("Unexpected usage: %+v", usage)
+	}
+	if !usage[0].ResetAt.Equal(now.Add(time.Hour)) || !usage[1].ResetAt.Equal(monthStart.AddDate(0, 1, 0)) {
+		t.Errorf("Unexpected reset times: %+v", usage)
+	}
+
+	// Other subjects, unlimited subjects and anonymous callers are unaffected
+	for _, user := range []string{"bob", "vip", "vip", "vip", ""} {
+		if err := call(user); err != nil {
+			t.Errorf("%q: unexpected error %v", user, err)
+		}
+	}
+
+	// A new day starts a new daily window, but the month is still counted
+	now = now.Add(2 * time.Hour)
+	if err := call("alice"); err != nil {
+		t.Fatalf("Expected a new daily quota, got %v", err)
+	}
+	if err := call("alice"); status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("Expected the monthly quota to be used up, got %v", err)
+	}
+
+	// Resetting the monthly quota lets alice continue
+	if err := manager.Reset(context.Background(), "user:alice", quota.Month); err != nil {
+		t.Fatal(err)
+	}
+	if err := call("alice"); err != nil {
+		t.Errorf("Expected the reset quota to allow the request, got %v", err)
+	}
+}
+
+func TestUsageQuota_StoreFailure(t *testing.T) {
+	manager, err := quota.NewManager(failingQuotaStore{}, quota.WithLimits(quota.Daily(10)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := &grpc.UnaryServerInfo{FullMethod: "/test.Service/Method"}
+	ok := func(ctx context.Context, req interface{}) (interface{}, error) { return "ok", nil }
+	ctx := context.WithValue(context.Background(), contextKeyUserID, "alice")
+
+	if _, err := UsageQuota(manager)(ctx, nil, info, ok); err != nil {
+		t.Errorf("Expected the request to be served when the store fails, got %v", err)
+	}
+	if _, err := UsageQuota(manager, WithQuotaFailClosed())(ctx, nil, info, ok); status.Code(err) != codes.Unavailable {
+		t.Errorf("Expected Unavailable when failing closed, got %v", err)
+	}
+}
+
+func TestUsageQuota_Handler(t *testing.T) {
+	manager, err := quota.NewManager(quota.NewMemoryStore(), quota.WithLimits(quota.Daily(5)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := manager.Consume(context.Background(), "key:k1"); err != nil {
+		t.Fatal(err)
+	}
+	handler := quota.Handler(manager)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/usage?subject=key:k1", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"used":1`) || !strings.Contains(rec.Body.String(), `"remaining":4`) {
+		t.Errorf("Unexpected usage response %d: %s", rec.Code, rec.Body)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/reset?subject=key:k1&period=day", nil))
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("Expected 204 from reset, got %d: %s", rec.Code, rec.Body)
+	}
+	if usage, _ := manager.Usage(context.Background(), "key:k1"); usage[0].Used != 0 {
+		t.Errorf("Expected the usage to be reset, got %+v", usage)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/reset?subject=key:k1&period=week", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an unknown period, got %d", rec.Code)
+	}
+}
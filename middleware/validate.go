@@ -0,0 +1,301 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// FieldViolation describes a single invalid field of a request message
+type FieldViolation struct {
+	Field       string
+	Description string
+}
+
+// ValidationError is returned by custom validators to report field violations
+type ValidationError struct {
+	Violations []FieldViolation
+}
+
+// NewValidationError creates a ValidationError from field violations
+func NewValidationError(violations ...FieldViolation) *ValidationError {
+	return &ValidationError{Violations: violations}
+}
+
+// Error joins all violations into a single message
+func (e *ValidationError) Error() string {
+	parts := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		parts = append(parts, v.Field+": "+v.Description)
+	}
+	return strings.Join(parts, "; ")
+}
+
+// RequestValidator validates a request message. Return a *ValidationError to report
+// field violations; any other error is reported as a single violation.
+type RequestValidator func(ctx context.Context, method string, req interface{}) error
+
+// ValidateConfig holds configuration for request validation middleware
+type ValidateConfig struct {
+	SkipMethods []string           // Method patterns that are not validated
+	Validators  []RequestValidator // Custom validators run after the generated rules
+	FailFast    bool               // Stop at the first violation instead of collecting all
+}
+
+// ValidateOption is a functional option for validation configuration
+type ValidateOption func(*ValidateConfig)
+
+// WithValidationSkip disables validation for method patterns ("/pkg.Service/Method", "/pkg.Service/*")
+func WithValidationSkip(patterns ...string) ValidateOption {
+	return func(c *ValidateConfig) {
+		c.SkipMethods = append(c.SkipMethods, patterns...)
+	}
+}
+
+// WithRequestValidator adds a custom validator, e.g. a protovalidate.Validator:
+//
+//	v, _ := protovalidate.New()
+//	middleware.WithRequestValidator(func(ctx context.Context, method string, req interface{}) error {
+//	    return v.Validate(req.(proto.Message))
+//	})
+func WithRequestValidator(validator RequestValidator) ValidateOption {
+	return func(c *ValidateConfig) {
+		c.Validators = append(c.Validators, validator)
+	}
+}
+
+// WithValidateFailFast reports only the first violation (protoc-gen-validate's Validate instead of ValidateAll)
+// Default: false (all violations are reported)
+func WithValidateFailFast() ValidateOption {
+	return func(c *ValidateConfig) {
+		c.FailFast = true
+	}
+}
+
+// pgvValidator is implemented by messages generated with protoc-gen-validate
+type pgvValidator interface {
+	Validate() error
+}
+
+// pgvAllValidator is implemented by messages generated with protoc-gen-validate (all=true)
+type pgvAllValidator interface {
+	ValidateAll() error
+}
+
+// pgvFieldError is implemented by protoc-gen-validate field errors
+type pgvFieldError interface {
+	Field() string
+	Reason() string
+}
+
+// pgvMultiError is implemented by protoc-gen-validate multi errors
+type pgvMultiError interface {
+	AllErrors() []error
+}
+
+// protovalidateViolation matches the violations carried by protovalidate errors
+type protovalidateViolation interface {
+	GetFieldPath() string
+	GetMessage() string
+}
+
+// Validate creates middleware that validates incoming requests before the handler runs.
+// Messages generated by protoc-gen-validate are validated automatically; protovalidate and
+// other rule engines are plugged in with WithRequestValidator. Invalid requests fail with
+// codes.InvalidArgument and a google.rpc.BadRequest detail listing every field violation.
+//
+// Example usage:
+//
+//	chain := guardian.NewChain(
+//	    middleware.Validate(
+//	        middleware.WithValidationSkip("/grpc.health.v1.Health/*"),
+//	    ),
+//	)
+func Validate(opts ...ValidateOption) func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	v := newRequestValidation(opts)
+
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := v.validate(ctx, info.FullMethod, req); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamValidate creates middleware that validates every message received on a stream
+func StreamValidate(opts ...ValidateOption) func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	v := newRequestValidation(opts)
+
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if v.skip(info.FullMethod) {
+			return handler(srv, ss)
+		}
+		return handler(srv, &validatingServerStream{ServerStream: ss, validation: v, method: info.FullMethod})
+	}
+}
+
+// requestValidation holds the compiled validation configuration
+type requestValidation struct {
+	config  *ValidateConfig
+	skipped *methodMatcher[bool]
+}
+
+// newRequestValidation applies options
+func newRequestValidation(opts []ValidateOption) *requestValidation {
+	config := &ValidateConfig{}
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	skip := make(map[string]bool, len(config.SkipMethods))
+	for _, pattern := range config.SkipMethods {
+		skip[pattern] = true
+	}
+
+	return &requestValidation{
+		config:  config,
+		skipped: newMethodMatcher(skip),
+	}
+}
+
+// skip reports whether validation is disabled for a method
+func (v *requestValidation) skip(method string) bool {
+	skipped, ok := v.skipped.match(method)
+	return ok && skipped
+}
+
+// validate runs generated and custom rules and converts violations into a status error
+func (v *requestValidation) validate(ctx context.Context, method string, req interface{}) error {
+	if v.skip(method) {
+		return nil
+	}
+
+	var violations []FieldViolation
+
+	if err := v.generated(req); err != nil {
+		violations = append(violations, fieldViolations(err)...)
+	}
+
+	for _, validator := range v.config.Validators {
+		if v.config.FailFast && len(violations) > 0 {
+			break
+		}
+		if err := validator(ctx, method, req); err != nil {
+			violations = append(violations, fieldViolations(err)...)
+		}
+	}
+
+	if len(violations) == 0 {
+		return nil
+	}
+	if v.config.FailFast {
+		violations = violations[:1]
+	}
+
+	return invalidArgument(method, violations)
+}
+
+// generated runs protoc-gen-validate rules if the message has them
+func (v *requestValidation) generated(req interface{}) error {
+	if !v.config.FailFast {
+		if m, ok := req.(pgvAllValidator); ok {
+			return m.ValidateAll()
+		}
+	}
+	if m, ok := req.(pgvValidator); ok {
+		return m.Validate()
+	}
+	return nil
+}
+
+// fieldViolations extracts field violations from validation errors of the supported engines
+func fieldViolations(err error) []FieldViolation {
+	switch e := err.(type) {
+	case *ValidationError:
+		return e.Violations
+	case pgvMultiError:
+		var violations []FieldViolation
+		for _, fieldErr := range e.AllErrors() {
+			violations = append(violations, fieldViolations(fieldErr)...)
+		}
+		return violations
+	case pgvFieldError:
+		return []FieldViolation{{Field: e.Field(), Description: e.Reason()}}
+	}
+
+	if violations := protovalidateViolations(err); len(violations) > 0 {
+		return violations
+	}
+
+	return []FieldViolation{{Description: err.Error()}}
+}
+
+// protovalidateViolations reads the Violations field of protovalidate's ValidationError
+// without depending on the protovalidate module
+func protovalidateViolations(err error) []FieldViolation {
+	rv := reflect.ValueOf(err)
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil
+	}
+
+	field := rv.FieldByName("Violations")
+	if !field.IsValid() || field.Kind() != reflect.Slice {
+		return nil
+	}
+
+	var violations []FieldViolation
+	for i := 0; i < field.Len(); i++ {
+		if !field.Index(i).CanInterface() {
+			return nil
+		}
+		if violation, ok := field.Index(i).Interface().(protovalidateViolation); ok {
+			violations = append(violations, FieldViolation{Field: violation.GetFieldPath(), Description: violation.GetMessage()})
+		}
+	}
+	return violations
+}
+
+// invalidArgument builds an InvalidArgument status with google.rpc.BadRequest details
+func invalidArgument(method string, violations []FieldViolation) error {
+	badRequest := &errdetails.BadRequest{}
+	for _, v := range violations {
+		badRequest.FieldViolations = append(badRequest.FieldViolations, &errdetails.BadRequest_FieldViolation{
+			Field:       v.Field,
+			Description: v.Description,
+		})
+	}
+
+	st := status.New(codes.InvalidArgument,
+		fmt.Sprintf("invalid request for %s: %s", method, NewValidationError(violations...).Error()))
+	if detailed, err := st.WithDetails(badRequest); err == nil {
+		st = detailed
+	}
+	return st.Err()
+}
+
+// validatingServerStream validates each received message
+type validatingServerStream struct {
+	grpc.ServerStream
+	validation *requestValidation
+	method     string
+}
+
+// RecvMsg receives a message and validates it
+func (s *validatingServerStream) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	return s.validation.validate(s.Context(), s.method, m)
+}
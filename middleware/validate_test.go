@@ -0,0 +1,156 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// pgvTestFieldError mimics a protoc-gen-validate field error
+type pgvTestFieldError struct {
+	field  string
+	reason string
+}
+
+func (e pgvTestFieldError) Error() string  { return e.field + ": " + e.reason }
+func (e pgvTestFieldError) Field() string  { return e.field }
+func (e pgvTestFieldError) Reason() string { return e.reason }
+
+// pgvTestMultiError mimics a protoc-gen-validate multi error
+type pgvTestMultiError []error
+
+func (m pgvTestMultiError) Error() string      { return "multiple errors" }
+func (m pgvTestMultiError) AllErrors() []error { return m }
+
+// pgvTestRequest mimics a message generated with protoc-gen-validate
+type pgvTestRequest struct {
+	Name  string
+	Email string
+}
+
+func (r *pgvTestRequest) ValidateAll() error {
+	var errs pgvTestMultiError
+	if r.Name == "" {
+		errs = append(errs, pgvTestFieldError{"name", "value is required"})
+	}
+	if !strings.Contains(r.Email, "@") {
+		errs = append(errs, pgvTestFieldError{"email", "value must be a valid email address"})
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func (r *pgvTestRequest) Validate() error {
+	if err := r.ValidateAll(); err != nil {
+		return err.(pgvTestMultiError)[0]
+	}
+	return nil
+}
+
+func callValidate(interceptor func(context.Context, interface{}, *grpc.UnaryServerInfo, grpc.UnaryHandler) (interface{}, error), method string, req interface{}) ([]*errdetails.BadRequest_FieldViolation, error) {
+	_, err := interceptor(context.Background(), req, &grpc.UnaryServerInfo{FullMethod: method},
+		func(ctx context.Context, req interface{}) (interface{}, error) { return "ok", nil })
+	if err == nil {
+		return nil, nil
+	}
+
+	for _, detail := range status.Convert(err).Details() {
+		if br, ok := detail.(*errdetails.BadRequest); ok {
+			return br.GetFieldViolations(), err
+		}
+	}
+	return nil, err
+}
+
+func TestValidate_GeneratedRules(t *testing.T) {
+	interceptor := Validate()
+
+	violations, err := callValidate(interceptor, "/users.Users/Create", &pgvTestRequest{})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("Expected InvalidArgument, got %v", err)
+	}
+	if len(violations) != 2 || violations[0].Field != "name" || violations[1].Field != "email" {
+		t.Errorf("Expected name and email violations, got %v", violations)
+	}
+
+	if _, err := callValidate(interceptor, "/users.Users/Create", &pgvTestRequest{Name: "a", Email: "a@b.c"}); err != nil {
+		t.Errorf("Expected valid request to pass, got %v", err)
+	}
+
+	// Messages without rules pass through
+	if _, err := callValidate(interceptor, "/users.Users/Create", "plain"); err != nil {
+		t.Errorf("Expected message without rules to pass, got %v", err)
+	}
+}
+
+func TestValidate_OptionsAndCustomValidators(t *testing.T) {
+	interceptor := Validate(
+		WithValidationSkip("/legacy.Service/*"),
+		WithRequestValidator(func(ctx context.Context, method string, req interface{}) error {
+			if r, ok := req.(*pgvTestRequest); ok && r.Name == "admin" {
+				return NewValidationError(FieldViolation{Field: "name", Description: "reserved name"})
+			}
+			return nil
+		}),
+		WithRequestValidator(func(ctx context.Context, method string, req interface{}) error {
+			if method == "/users.Users/Broken" {
+				return errors.New("request rejected")
+			}
+			return nil
+		}),
+	)
+
+	if _, err := callValidate(interceptor, "/legacy.Service/Create", &pgvTestRequest{}); err != nil {
+		t.Errorf("Expected skipped method to pass, got %v", err)
+	}
+
+	violations, err := callValidate(interceptor, "/users.Users/Create", &pgvTestRequest{Name: "admin", Email: "a@b.c"})
+	if status.Code(err) != codes.InvalidArgument || len(violations) != 1 || violations[0].Description != "reserved name" {
+		t.Errorf("Expected custom violation, got %v %v", violations, err)
+	}
+
+	violations, _ = callValidate(interceptor, "/users.Users/Broken", &pgvTestRequest{Name: "a", Email: "a@b.c"})
+	if len(violations) != 1 || violations[0].Description != "request rejected" {
+		t.Errorf("Expected plain error as violation, got %v", violations)
+	}
+
+	failFast := Validate(WithValidateFailFast())
+	violations, _ = callValidate(failFast, "/users.Users/Create", &pgvTestRequest{})
+	if len(violations) != 1 {
+		t.Errorf("Expected a single violation in fail-fast mode, got %v", violations)
+	}
+}
+
+// protovalidateTestViolation mimics protovalidate's violation message
+type protovalidateTestViolation struct {
+	path, message string
+}
+
+func (v *protovalidateTestViolation) GetFieldPath() string { return v.path }
+func (v *protovalidateTestViolation) GetMessage() string   { return v.message }
+
+// protovalidateTestError mimics protovalidate.ValidationError
+type protovalidateTestError struct {
+	Violations []*protovalidateTestViolation
+}
+
+func (e *protovalidateTestError) Error() string { return "validation error" }
+
+func TestValidate_ProtovalidateErrors(t *testing.T) {
+	interceptor := Validate(WithRequestValidator(func(ctx context.Context, method string, req interface{}) error {
+		return &protovalidateTestError{Violations: []*protovalidateTestViolation{{"user.id", "value must be greater than 0"}}}
+	}))
+
+	violations, _ := callValidate(interceptor, "/users.Users/Get", "req")
+	if len(violations) != 1 || violations[0].Field != "user.id" {
+		t.Errorf("Expected protovalidate violation, got %v", violations)
+	}
+}
@@ -0,0 +1,409 @@
+// Package admin exposes the runtime state of guardian components (circuit breakers,
+// caches, rate limiters, chaos experiments, latency outliers and the chain configuration)
+// over a small gRPC service and an HTTP JSON handler, with actions to reset breakers, clear
+// caches, toggle chaos and unban clients. Every call is authenticated independently of the
+// served API.
+package admin
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	guardian "github.com/grpc-guardian/grpc-guardian"
+	"github.com/grpc-guardian/grpc-guardian/chaos"
+	"github.com/grpc-guardian/grpc-guardian/middleware"
+	"github.com/grpc-guardian/grpc-guardian/pkg/cache"
+	"github.com/grpc-guardian/grpc-guardian/pkg/profiling"
+	"github.com/grpc-guardian/grpc-guardian/pkg/tap"
+	"golang.org/x/time/rate"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Authorizer checks the bearer token of an admin call
+type Authorizer func(ctx context.Context, token string) error
+
+// Server holds the components exposed by the admin endpoint
+type Server struct {
+	mu               sync.RWMutex
+	breakers         map[string]*middleware.CircuitBreaker
+	caches           map[string]cache.Backend
+	limiters         map[string]*rate.Limiter
+	perClientLimiter map[string]*middleware.PerClientRateLimiter
+	experiments      map[string]*chaos.Switch
+	abuse            map[string]*middleware.AbuseDetector
+	outliers         map[string]*profiling.Recorder
+	chain            *guardian.Chain
+	tap              *tap.Tap
+
+	authorize Authorizer
+	insecure  bool
+}
+
+// Option configures a Server
+type Option func(*Server)
+
+// WithToken only accepts calls carrying "authorization: Bearer <token>"
+func WithToken(token string) Option {
+	return func(s *Server) {
+		s.authorize = func(ctx context.Context, got string) error {
+			if token == "" || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+				return fmt.Errorf("invalid admin token")
+			}
+			return nil
+		}
+	}
+}
+
+// WithAuthorizer sets a custom check for the bearer token, e.g. a JWT validator
+func WithAuthorizer(authorize Authorizer) Option {
+	return func(s *Server) {
+		s.authorize = authorize
+	}
+}
+
+// WithoutAuth accepts unauthenticated calls; only use it when the admin endpoint listens
+// on a private interface
+func WithoutAuth() Option {
+	return func(s *Server) {
+		s.insecure = true
+	}
+}
+
+// WithChain includes the chain's configuration snapshot and middleware list in the state
+func WithChain(chain *guardian.Chain) Option {
+	return func(s *Server) {
+		s.chain = chain
+	}
+}
+
+// WithTap serves the live request feed of a tap (see Server.Tap)
+func WithTap(t *tap.Tap) Option {
+	return func(s *Server) {
+		s.tap = t
+	}
+}
+
+// New creates an admin server. Without WithToken, WithAuthorizer or WithoutAuth every
+// call is rejected.
+//
+// Example usage:
+//
+//	breaker := middleware.NewCircuitBreaker()
+//	experiment := chaos.NewSwitch(false)
+//
+//	adminServer := admin.New(admin.WithToken(os.Getenv("GUARDIAN_ADMIN_TOKEN")), admin.WithChain(chain)).
+//	    AddCircuitBreaker("payments", breaker).
+//	    AddCache("catalog", catalogBackend).
+//	    AddChaos("payments-errors", experiment)
+//
+//	adminServer.Register(grpcServer)
+//	http.Handle("/guardian/admin/", http.StripPrefix("/guardian/admin", adminServer.Handler()))
+func New(opts ...Option) *Server {
+	s := &Server{
+		breakers:         make(map[string]*middleware.CircuitBreaker),
+		caches:           make(map[string]cache.Backend),
+		limiters:         make(map[string]*rate.Limiter),
+		perClientLimiter: make(map[string]*middleware.PerClientRateLimiter),
+		experiments:      make(map[string]*chaos.Switch),
+		abuse:            make(map[string]*middleware.AbuseDetector),
+		outliers:         make(map[string]*profiling.Recorder),
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// AddCircuitBreaker exposes a circuit breaker
+func (s *Server) AddCircuitBreaker(name string, breaker *middleware.CircuitBreaker) *Server {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.breakers[name] = breaker
+	return s
+}
+
+// AddCache exposes a cache backend
+func (s *Server) AddCache(name string, backend cache.Backend) *Server {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.caches[name] = backend
+	return s
+}
+
+// AddRateLimiter exposes a rate limiter (see middleware.RateLimitWithLimiter)
+func (s *Server) AddRateLimiter(name string, limiter *rate.Limiter) *Server {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.limiters[name] = limiter
+	return s
+}
+
+// AddPerClientRateLimiter exposes a per-client rate limiter
+func (s *Server) AddPerClientRateLimiter(name string, limiter *middleware.PerClientRateLimiter) *Server {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.perClientLimiter[name] = limiter
+	return s
+}
+
+// AddChaos exposes a chaos experiment switch
+func (s *Server) AddChaos(name string, experiment *chaos.Switch) *Server {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.experiments[name] = experiment
+	return s
+}
+
+// AddAbuseDetector exposes the bans of an abuse detector
+func (s *Server) AddAbuseDetector(name string, detector *middleware.AbuseDetector) *Server {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.abuse[name] = detector
+	return s
+}
+
+// AddOutliers exposes the slowest requests kept by a profiling recorder
+func (s *Server) AddOutliers(name string, recorder *profiling.Recorder) *Server {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.outliers[name] = recorder
+	return s
+}
+
+// State is the runtime state served by the admin endpoint
+type State struct {
+	CircuitBreakers map[string]CircuitBreakerState           `json:"circuit_breakers"`
+	Caches          map[string]cache.Stats                   `json:"caches"`
+	RateLimiters    map[string]RateLimiterState              `json:"rate_limiters"`
+	Chaos           map[string]ChaosState                    `json:"chaos"`
+	Bans            map[string][]middleware.Ban              `json:"bans"`
+	Outliers        map[string]map[string][]profiling.Sample `json:"outliers"`
+	Chain           []guardian.MiddlewareDescription         `json:"chain,omitempty"`
+	Config          *guardian.ConfigSnapshot                 `json:"config,omitempty"`
+}
+
+// CircuitBreakerState describes a circuit breaker
+type CircuitBreakerState struct {
+	State                string    `json:"state"`
+	StateChangedAt       time.Time `json:"state_changed_at"`
+	Requests             uint32    `json:"requests"`
+	TotalFailures        uint32    `json:"total_failures"`
+	SlowCalls            uint32    `json:"slow_calls,omitempty"`
+	ConsecutiveFailures  uint32    `json:"consecutive_failures"`
+	ConsecutiveSuccesses uint32    `json:"consecutive_successes"`
+}
+
+// RateLimiterState describes a rate limiter. Tokens is only reported for global
+// limiters and Clients only for per-client limiters.
+type RateLimiterState struct {
+	Limit   float64 `json:"limit"`
+	Burst   int     `json:"burst"`
+	Tokens  float64 `json:"tokens,omitempty"`
+	Clients int     `json:"clients,omitempty"`
+}
+
+// ChaosState describes a chaos experiment
+type ChaosState struct {
+	Enabled bool `json:"enabled"`
+}
+
+// State collects the current state of every registered component
+func (s *Server) State() State {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	state := State{
+		CircuitBreakers: make(map[string]CircuitBreakerState, len(s.breakers)),
+		Caches:          make(map[string]cache.Stats, len(s.caches)),
+		RateLimiters:    make(map[string]RateLimiterState, len(s.limiters)+len(s.perClientLimiter)),
+		Chaos:           make(map[string]ChaosState, len(s.experiments)),
+		Bans:            make(map[string][]middleware.Ban, len(s.abuse)),
+		Outliers:        make(map[string]map[string][]profiling.Sample, len(s.outliers)),
+	}
+
+	for name, breaker := range s.breakers {
+		stats := breaker.GetStats()
+		state.CircuitBreakers[name] = CircuitBreakerState{
+			State:                stats.State.String(),
+			StateChangedAt:       stats.StateChangedAt,
+			Requests:             stats.Counts.Requests,
+			TotalFailures:        stats.Counts.TotalFailures,
+			SlowCalls:            stats.Counts.SlowCalls,
+			ConsecutiveFailures:  stats.Counts.ConsecutiveFailures,
+			ConsecutiveSuccesses: stats.Counts.ConsecutiveSuccesses,
+		}
+	}
+	for name, backend := range s.caches {
+		state.Caches[name] = backend.Stats()
+	}
+	for name, limiter := range s.limiters {
+		state.RateLimiters[name] = RateLimiterState{
+			Limit:  float64(limiter.Limit()),
+			Burst:  limiter.Burst(),
+			Tokens: limiter.Tokens(),
+		}
+	}
+	for name, limiter := range s.perClientLimiter {
+		limit, burst := limiter.Limit()
+		state.RateLimiters[name] = RateLimiterState{
+			Limit:   float64(limit),
+			Burst:   burst,
+			Clients: limiter.Clients(),
+		}
+	}
+	for name, experiment := range s.experiments {
+		state.Chaos[name] = ChaosState{Enabled: experiment.Enabled()}
+	}
+	for name, detector := range s.abuse {
+		state.Bans[name] = detector.Bans()
+	}
+	for name, recorder := range s.outliers {
+		state.Outliers[name] = recorder.Snapshot()
+	}
+	if s.chain != nil {
+		snapshot := s.chain.Snapshot()
+		state.Chain = s.chain.Describe()
+		state.Config = &snapshot
+	}
+
+	return state
+}
+
+// ResetCircuitBreaker closes a circuit breaker
+func (s *Server) ResetCircuitBreaker(name string) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	breaker, ok := s.breakers[name]
+	if !ok {
+		return notFound("circuit breaker", name, sortedNames(s.breakers))
+	}
+	breaker.Reset()
+	return nil
+}
+
+// ClearCache removes every entry of a cache
+func (s *Server) ClearCache(ctx context.Context, name string) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	backend, ok := s.caches[name]
+	if !ok {
+		return notFound("cache", name, sortedNames(s.caches))
+	}
+	if err := backend.Clear(ctx); err != nil {
+		return status.Errorf(codes.Internal, "admin: failed to clear cache %q: %v", name, err)
+	}
+	return nil
+}
+
+// SetChaos turns a chaos experiment on or off
+func (s *Server) SetChaos(name string, enabled bool) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	experiment, ok := s.experiments[name]
+	if !ok {
+		return notFound("chaos experiment", name, sortedNames(s.experiments))
+	}
+	experiment.Set(enabled)
+	return nil
+}
+
+// Unban lifts the ban of a client of an abuse detector
+func (s *Server) Unban(name, client string) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	detector, ok := s.abuse[name]
+	if !ok {
+		return notFound("abuse detector", name, sortedNames(s.abuse))
+	}
+	if !detector.Unban(client) {
+		return status.Errorf(codes.NotFound, "admin: client %q is not banned by %q", client, name)
+	}
+	return nil
+}
+
+// Tap sends summaries of live requests matching the filter until ctx is done or send
+// fails, starting with up to recent buffered requests
+func (s *Server) Tap(ctx context.Context, filter tap.Filter, recent int, send func(tap.Event) error) error {
+	if s.tap == nil {
+		return status.Errorf(codes.NotFound, "admin: no tap configured\nHint: use admin.WithTap")
+	}
+
+	subscription := s.tap.Subscribe(filter, recent)
+	defer subscription.Close()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event := <-subscription.Events():
+			if err := send(event); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// sortedNames returns the sorted keys of a registry
+func sortedNames[V any](registry map[string]V) []string {
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// notFound reports an unknown component
+func notFound(kind, name string, known []string) error {
+	return status.Errorf(codes.NotFound, "admin: unknown %s %q\nHint: registered names are [%s]", kind, name, strings.Join(known, ", "))
+}
+
+// authenticate checks the bearer token of a call
+func (s *Server) authenticate(ctx context.Context, token string) error {
+	if s.insecure {
+		return nil
+	}
+	if s.authorize == nil {
+		return status.Errorf(codes.Unauthenticated, "admin: no authentication configured\nHint: use admin.WithToken, admin.WithAuthorizer or admin.WithoutAuth")
+	}
+	if token == "" {
+		return status.Errorf(codes.Unauthenticated, "admin: missing bearer token")
+	}
+	if err := s.authorize(ctx, token); err != nil {
+		return status.Errorf(codes.Unauthenticated, "admin: %v", err)
+	}
+	return nil
+}
+
+// marshalState encodes the state as a generic JSON object
+func (s *Server) marshalState() (map[string]interface{}, error) {
+	data, err := json.Marshal(s.State())
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal admin state: %w", err)
+	}
+	var out map[string]interface{}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, fmt.Errorf("failed to marshal admin state: %w", err)
+	}
+	return out, nil
+}
@@ -0,0 +1,282 @@
+package admin
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grpc-guardian/grpc-guardian/chaos"
+	"github.com/grpc-guardian/grpc-guardian/middleware"
+	"github.com/grpc-guardian/grpc-guardian/pkg/cache"
+	"github.com/grpc-guardian/grpc-guardian/pkg/tap"
+	"golang.org/x/time/rate"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// newFixture registers one component of each kind with an admin server
+func newFixture(t *testing.T) (*Server, *middleware.CircuitBreaker, *cache.MemoryBackend, *chaos.Switch) {
+	t.Helper()
+
+	breaker := middleware.NewCircuitBreaker()
+	backend := cache.NewMemoryBackend(nil)
+	experiment := chaos.NewSwitch(false)
+
+	server := New(WithToken("secret")).
+		AddCircuitBreaker("payments", breaker).
+		AddCache("catalog", backend).
+		AddRateLimiter("global", rate.NewLimiter(100, 10)).
+		AddChaos("payments-errors", experiment)
+
+	return server, breaker, backend, experiment
+}
+
+func TestServer_GRPC(t *testing.T) {
+	server, breaker, backend, experiment := newFixture(t)
+	defer backend.Close()
+
+	listener := bufconn.Listen(1 << 20)
+	grpcServer := grpc.NewServer()
+	server.Register(grpcServer)
+	go grpcServer.Serve(listener)
+	defer grpcServer.Stop()
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	call := func(ctx context.Context, method string, fields map[string]interface{}) (*structpb.Struct, error) {
+		in, err := structpb.NewStruct(fields)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out := new(structpb.Struct)
+		return out, conn.Invoke(ctx, method, in, out)
+	}
+
+	if _, err := call(context.Background(), MethodGetState, nil); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Expected Unauthenticated without token, got %v", err)
+	}
+	bad := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer wrong")
+	if _, err := call(bad, MethodGetState, nil); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Expected Unauthenticated with wrong token, got %v", err)
+	}
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer secret")
+
+	// Trip the breaker, then reset it over the admin API
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, status.Error(codes.Unavailable, "down")
+	}
+	interceptor := breaker.UnaryServerInterceptor()
+	for i := 0; i < 10; i++ {
+		interceptor(context.Background(), "req", &grpc.UnaryServerInfo{FullMethod: "/svc/M"}, handler)
+	}
+
+	state, err := call(ctx, MethodGetState, nil)
+	if err != nil {
+		t.Fatalf("GetState: %v", err)
+	}
+	got := state.GetFields()["circuit_breakers"].GetStructValue().GetFields()["payments"].GetStructValue().GetFields()["state"].GetStringValue()
+	if got != middleware.StateOpen.String() {
+		t.Errorf("Expected open breaker in state, got %q", got)
+	}
+	if _, ok := state.GetFields()["rate_limiters"].GetStructValue().GetFields()["global"]; !ok {
+		t.Error("Expected rate limiter in state")
+	}
+
+	if _, err := call(ctx, MethodResetCircuitBreaker, map[string]interface{}{"name": "payments"}); err != nil {
+		t.Fatalf("ResetCircuitBreaker: %v", err)
+	}
+	if breaker.State() != middleware.StateClosed {
+		t.Errorf("Expected closed breaker after reset, got %v", breaker.State())
+	}
+
+	if _, err := call(ctx, MethodSetChaos, map[string]interface{}{"name": "payments-errors", "enabled": true}); err != nil {
+		t.Fatalf("SetChaos: %v", err)
+	}
+	if !experiment.Enabled() {
+		t.Error("Expected chaos experiment to be enabled")
+	}
+
+	if _, err := call(ctx, MethodClearCache, map[string]interface{}{"name": "missing"}); status.Code(err) != codes.NotFound {
+		t.Errorf("Expected NotFound for unknown cache, got %v", err)
+	}
+	if _, err := call(ctx, MethodSetChaos, map[string]interface{}{"name": "payments-errors"}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument without enabled, got %v", err)
+	}
+}
+
+func TestServer_HTTP(t *testing.T) {
+	server, _, backend, experiment := newFixture(t)
+	defer backend.Close()
+	handler := server.Handler()
+
+	do := func(method, path, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := do(http.MethodGet, "/state", ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without token, got %d", rec.Code)
+	}
+
+	rec := do(http.MethodGet, "/state", "secret")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"payments-errors":{"enabled":false}`) {
+		t.Errorf("Unexpected state response %d: %s", rec.Code, rec.Body.String())
+	}
+
+	backend.Set(context.Background(), "key", []byte("value"), 0)
+	if rec := do(http.MethodPost, "/caches/catalog/clear", "secret"); rec.Code != http.StatusOK {
+		t.Errorf("Expected 200 clearing cache, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if _, ok, _ := backend.Get(context.Background(), "key"); ok {
+		t.Error("Expected cache to be cleared")
+	}
+
+	if rec := do(http.MethodPost, "/chaos/payments-errors?enabled=true", "secret"); rec.Code != http.StatusOK || !experiment.Enabled() {
+		t.Errorf("Expected chaos to be enabled, got %d", rec.Code)
+	}
+	if rec := do(http.MethodPost, "/chaos/payments-errors?enabled=maybe", "secret"); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for invalid enabled, got %d", rec.Code)
+	}
+	if rec := do(http.MethodGet, "/circuitbreakers/payments/reset", "secret"); rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405 for GET reset, got %d", rec.Code)
+	}
+	if rec := do(http.MethodPost, "/circuitbreakers/missing/reset", "secret"); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for unknown breaker, got %d", rec.Code)
+	}
+
+	// No auth configured denies by default
+	rec = httptest.NewRecorder()
+	New().Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/state", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without auth configured, got %d", rec.Code)
+	}
+}
+
+func TestServer_Tap(t *testing.T) {
+	feed := tap.New()
+	server := New(WithToken("secret"), WithTap(feed))
+
+	listener := bufconn.Listen(1 << 20)
+	grpcServer := grpc.NewServer()
+	server.Register(grpcServer)
+	go grpcServer.Serve(listener)
+	defer grpcServer.Stop()
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	feed.Record(tap.Event{Method: "/orders.Orders/Get", Code: codes.Internal, Duration: time.Second})
+	feed.Record(tap.Event{Method: "/orders.Orders/Get", Code: codes.OK, Duration: time.Second})
+
+	ctx, cancel := context.WithCancel(metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer secret"))
+	defer cancel()
+	stream, err := conn.NewStream(ctx, &grpc.StreamDesc{ServerStreams: true}, MethodTap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	in, _ := structpb.NewStruct(map[string]interface{}{
+		"method": "/orders.Orders/*",
+		"codes":  []interface{}{"Internal"},
+		"recent": 5,
+	})
+	if err := stream.SendMsg(in); err != nil {
+		t.Fatal(err)
+	}
+	_ = stream.CloseSend()
+
+	out := new(structpb.Struct)
+	if err := stream.RecvMsg(out); err != nil {
+		t.Fatalf("Tap: %v", err)
+	}
+	if code := out.GetFields()["code"].GetStringValue(); code != "Internal" {
+		t.Errorf("Expected the buffered Internal request, got %v", out)
+	}
+
+	// Wait for the live subscription before recording
+	for feed.Subscribers() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	feed.Record(tap.Event{Method: "/users.Users/Get", Code: codes.Internal})
+	feed.Record(tap.Event{Method: "/orders.Orders/Create", Code: codes.Internal, Duration: 2 * time.Second})
+	if err := stream.RecvMsg(out); err != nil {
+		t.Fatalf("Tap: %v", err)
+	}
+	if method := out.GetFields()["method"].GetStringValue(); method != "/orders.Orders/Create" {
+		t.Errorf("Expected the live matching request, got %v", out)
+	}
+
+	// Unauthenticated and invalid requests are rejected
+	stream, err = conn.NewStream(context.Background(), &grpc.StreamDesc{ServerStreams: true}, MethodTap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = stream.SendMsg(&structpb.Struct{})
+	if err := stream.RecvMsg(out); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Expected Unauthenticated without token, got %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/tap?code=Bogus", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	server.Handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an unknown code, got %d", rec.Code)
+	}
+}
+
+func TestServer_TapHTTP(t *testing.T) {
+	feed := tap.New()
+	feed.Record(tap.Event{Method: "/orders.Orders/Get", Code: codes.Unavailable})
+	handler := New(WithoutAuth(), WithTap(feed)).Handler()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req := httptest.NewRequest(http.MethodGet, "/tap?recent=1", nil).WithContext(ctx)
+	rec := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		handler.ServeHTTP(rec, req)
+		close(done)
+	}()
+
+	for feed.Subscribers() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	if rec.Header().Get("Content-Type") != "application/x-ndjson" || !strings.Contains(rec.Body.String(), `"code":"Unavailable"`) {
+		t.Errorf("Unexpected tap response %s: %s", rec.Header().Get("Content-Type"), rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	New(WithoutAuth()).Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/tap", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 without a tap, got %d", rec.Code)
+	}
+}
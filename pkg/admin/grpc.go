@@ -0,0 +1,206 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/grpc-guardian/grpc-guardian/pkg/tap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// ServiceName is the fully qualified name of the admin gRPC service
+const ServiceName = "guardian.admin.v1.Admin"
+
+// Admin gRPC methods. Requests and responses are google.protobuf.Struct messages so the
+// service can be called without generated stubs:
+//
+//	GetState            {}                              -> State
+//	ResetCircuitBreaker {"name": "payments"}            -> {}
+//	ClearCache          {"name": "catalog"}             -> {}
+//	SetChaos            {"name": "x", "enabled": true}  -> {}
+//	Unban               {"name": "x", "client": "c"}    -> {}
+//	Tap                 {"method": "/pkg.Svc/*", "codes": ["Internal"], "min_duration": "100ms", "recent": 10}
+//	                                                    -> stream of request summaries
+const (
+	MethodGetState            = "/" + ServiceName + "/GetState"
+	MethodResetCircuitBreaker = "/" + ServiceName + "/ResetCircuitBreaker"
+	MethodClearCache          = "/" + ServiceName + "/ClearCache"
+	MethodSetChaos            = "/" + ServiceName + "/SetChaos"
+	MethodUnban               = "/" + ServiceName + "/Unban"
+	MethodTap                 = "/" + ServiceName + "/Tap"
+)
+
+// adminCall handles one admin method
+type adminCall func(s *Server, ctx context.Context, in *structpb.Struct) (*structpb.Struct, error)
+
+// serviceDesc describes the admin service for grpc.Server.RegisterService
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: ServiceName,
+	HandlerType: (*interface{})(nil),
+	Methods: []grpc.MethodDesc{
+		methodDesc("GetState", func(s *Server, ctx context.Context, in *structpb.Struct) (*structpb.Struct, error) {
+			state, err := s.marshalState()
+			if err != nil {
+				return nil, status.Errorf(codes.Internal, "admin: %v", err)
+			}
+			return structpb.NewStruct(state)
+		}),
+		methodDesc("ResetCircuitBreaker", func(s *Server, ctx context.Context, in *structpb.Struct) (*structpb.Struct, error) {
+			name, err := nameField(in)
+			if err != nil {
+				return nil, err
+			}
+			return &structpb.Struct{}, s.ResetCircuitBreaker(name)
+		}),
+		methodDesc("ClearCache", func(s *Server, ctx context.Context, in *structpb.Struct) (*structpb.Struct, error) {
+			name, err := nameField(in)
+			if err != nil {
+				return nil, err
+			}
+			return &structpb.Struct{}, s.ClearCache(ctx, name)
+		}),
+		methodDesc("SetChaos", func(s *Server, ctx context.Context, in *structpb.Struct) (*structpb.Struct, error) {
+			name, err := nameField(in)
+			if err != nil {
+				return nil, err
+			}
+			enabled, ok := in.GetFields()["enabled"].GetKind().(*structpb.Value_BoolValue)
+			if !ok {
+				return nil, status.Errorf(codes.InvalidArgument, "admin: missing boolean field \"enabled\"")
+			}
+			return &structpb.Struct{}, s.SetChaos(name, enabled.BoolValue)
+		}),
+		methodDesc("Unban", func(s *Server, ctx context.Context, in *structpb.Struct) (*structpb.Struct, error) {
+			name, err := nameField(in)
+			if err != nil {
+				return nil, err
+			}
+			client := in.GetFields()["client"].GetStringValue()
+			if client == "" {
+				return nil, status.Errorf(codes.InvalidArgument, "admin: missing field \"client\"")
+			}
+			return &structpb.Struct{}, s.Unban(name, client)
+		}),
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Tap",
+			ServerStreams: true,
+			Handler:       tapStream,
+		},
+	},
+	Metadata: "guardian/admin/v1/admin.proto",
+}
+
+// Register adds the admin service to a gRPC server
+func (s *Server) Register(registrar grpc.ServiceRegistrar) {
+	registrar.RegisterService(&serviceDesc, s)
+}
+
+// methodDesc wraps an admin call in a gRPC method handler that authenticates the caller
+// and runs the server's interceptors
+func methodDesc(name string, call adminCall) grpc.MethodDesc {
+	return grpc.MethodDesc{
+		MethodName: name,
+		Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+			in := new(structpb.Struct)
+			if err := dec(in); err != nil {
+				return nil, err
+			}
+
+			s := srv.(*Server)
+			handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+				if err := s.authenticate(ctx, bearerFromMetadata(ctx)); err != nil {
+					return nil, err
+				}
+				return call(s, ctx, req.(*structpb.Struct))
+			}
+
+			if interceptor == nil {
+				return handler(ctx, in)
+			}
+			info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/" + ServiceName + "/" + name}
+			return interceptor(ctx, in, info, handler)
+		},
+	}
+}
+
+// tapStream serves the live request feed. The server's stream interceptors run around it.
+func tapStream(srv interface{}, stream grpc.ServerStream) error {
+	s := srv.(*Server)
+	ctx := stream.Context()
+	if err := s.authenticate(ctx, bearerFromMetadata(ctx)); err != nil {
+		return err
+	}
+
+	in := new(structpb.Struct)
+	if err := stream.RecvMsg(in); err != nil {
+		return err
+	}
+	fields := in.GetFields()
+	var codeNames []string
+	for _, value := range fields["codes"].GetListValue().GetValues() {
+		codeNames = append(codeNames, value.GetStringValue())
+	}
+	filter, err := tap.ParseFilter(fields["method"].GetStringValue(), codeNames, fields["min_duration"].GetStringValue())
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "admin: %v", err)
+	}
+
+	return s.Tap(ctx, filter, int(fields["recent"].GetNumberValue()), func(event tap.Event) error {
+		out, err := eventStruct(event)
+		if err != nil {
+			return status.Errorf(codes.Internal, "admin: %v", err)
+		}
+		return stream.SendMsg(out)
+	})
+}
+
+// eventStruct encodes a request summary as a google.protobuf.Struct
+func eventStruct(event tap.Event) (*structpb.Struct, error) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	return structpb.NewStruct(fields)
+}
+
+// nameField reads the required "name" field of a request
+func nameField(in *structpb.Struct) (string, error) {
+	name := in.GetFields()["name"].GetStringValue()
+	if name == "" {
+		return "", status.Errorf(codes.InvalidArgument, "admin: missing field \"name\"")
+	}
+	return name, nil
+}
+
+// bearerFromMetadata extracts the bearer token from the incoming authorization header
+func bearerFromMetadata(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		return ""
+	}
+	return bearerToken(values[0])
+}
+
+// bearerToken strips the "Bearer " scheme from an authorization header
+func bearerToken(header string) string {
+	const prefix = "bearer "
+	if len(header) < len(prefix) || !strings.EqualFold(header[:len(prefix)], prefix) {
+		return ""
+	}
+	return strings.TrimSpace(header[len(prefix):])
+}
@@ -0,0 +1,169 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/grpc-guardian/grpc-guardian/pkg/tap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Handler returns an HTTP JSON handler for the admin endpoint:
+//
+//	GET  /state                          current State
+//	POST /circuitbreakers/{name}/reset   close a circuit breaker
+//	POST /caches/{name}/clear            clear a cache
+//	POST /chaos/{name}?enabled=true      toggle a chaos experiment
+//	POST /abuse/{name}/unban?client=x    lift the ban of a client
+//	GET  /tap?method=/pkg.Svc/*&code=Internal&min_duration=100ms&recent=10
+//	                                     stream request summaries as newline-delimited JSON
+//
+// Requests authenticate with "Authorization: Bearer <token>". Mount it under a prefix
+// with http.StripPrefix.
+func (s *Server) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := s.authenticate(r.Context(), bearerToken(r.Header.Get("Authorization"))); err != nil {
+			writeError(w, err)
+			return
+		}
+
+		parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+
+		switch {
+		case len(parts) == 1 && parts[0] == "state":
+			if r.Method != http.MethodGet {
+				methodNotAllowed(w, http.MethodGet)
+				return
+			}
+			writeJSON(w, http.StatusOK, s.State())
+
+		case len(parts) == 3 && parts[0] == "circuitbreakers" && parts[2] == "reset":
+			if r.Method != http.MethodPost {
+				methodNotAllowed(w, http.MethodPost)
+				return
+			}
+			writeResult(w, s.ResetCircuitBreaker(parts[1]))
+
+		case len(parts) == 3 && parts[0] == "caches" && parts[2] == "clear":
+			if r.Method != http.MethodPost {
+				methodNotAllowed(w, http.MethodPost)
+				return
+			}
+			writeResult(w, s.ClearCache(r.Context(), parts[1]))
+
+		case len(parts) == 2 && parts[0] == "chaos":
+			if r.Method != http.MethodPost {
+				methodNotAllowed(w, http.MethodPost)
+				return
+			}
+			enabled, err := strconv.ParseBool(r.URL.Query().Get("enabled"))
+			if err != nil {
+				writeError(w, status.Errorf(codes.InvalidArgument, "admin: invalid enabled parameter %q\nHint: use ?enabled=true or ?enabled=false", r.URL.Query().Get("enabled")))
+				return
+			}
+			writeResult(w, s.SetChaos(parts[1], enabled))
+
+		case len(parts) == 3 && parts[0] == "abuse" && parts[2] == "unban":
+			if r.Method != http.MethodPost {
+				methodNotAllowed(w, http.MethodPost)
+				return
+			}
+			client := r.URL.Query().Get("client")
+			if client == "" {
+				writeError(w, status.Errorf(codes.InvalidArgument, "admin: missing client parameter\nHint: use ?client=ip:203.0.113.7"))
+				return
+			}
+			writeResult(w, s.Unban(parts[1], client))
+
+		case len(parts) == 1 && parts[0] == "tap":
+			if r.Method != http.MethodGet {
+				methodNotAllowed(w, http.MethodGet)
+				return
+			}
+			s.serveTap(w, r)
+
+		default:
+			http.NotFound(w, r)
+		}
+	})
+}
+
+// serveTap streams request summaries as newline-delimited JSON until the client leaves
+func (s *Server) serveTap(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter, err := tap.ParseFilter(query.Get("method"), query["code"], query.Get("min_duration"))
+	if err != nil {
+		writeError(w, status.Errorf(codes.InvalidArgument, "admin: %v", err))
+		return
+	}
+	recent := 0
+	if value := query.Get("recent"); value != "" {
+		if recent, err = strconv.Atoi(value); err != nil {
+			writeError(w, status.Errorf(codes.InvalidArgument, "admin: invalid recent parameter %q", value))
+			return
+		}
+	}
+	if s.tap == nil {
+		writeError(w, status.Errorf(codes.NotFound, "admin: no tap configured\nHint: use admin.WithTap"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+	if flusher != nil {
+		flusher.Flush()
+	}
+
+	encoder := json.NewEncoder(w)
+	_ = s.Tap(r.Context(), filter, recent, func(event tap.Event) error {
+		if err := encoder.Encode(event); err != nil {
+			return err
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return nil
+	})
+}
+
+// writeResult writes the outcome of an action
+func writeResult(w http.ResponseWriter, err error) {
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// writeError maps a gRPC status error to its HTTP equivalent
+func writeError(w http.ResponseWriter, err error) {
+	code := http.StatusInternalServerError
+	switch status.Code(err) {
+	case codes.Unauthenticated:
+		code = http.StatusUnauthorized
+	case codes.PermissionDenied:
+		code = http.StatusForbidden
+	case codes.NotFound:
+		code = http.StatusNotFound
+	case codes.InvalidArgument:
+		code = http.StatusBadRequest
+	}
+	writeJSON(w, code, map[string]string{"error": status.Convert(err).Message()})
+}
+
+// methodNotAllowed rejects a request with the wrong HTTP method
+func methodNotAllowed(w http.ResponseWriter, allowed string) {
+	w.Header().Set("Allow", allowed)
+	writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+}
+
+// writeJSON writes a JSON response
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
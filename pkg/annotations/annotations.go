@@ -0,0 +1,288 @@
+// Package annotations reads grpc-guardian method options (guardian/options.proto) from
+// service descriptors, so cache, timeout and auth settings live next to the methods in the
+// .proto files instead of in maps keyed by "/pkg.Service/Method" strings.
+//
+// The package registers guardian/options.proto at init. Generated code of files importing
+// it blank-imports this package (through the go_package option), so the options are
+// available whenever the annotated services are linked in.
+package annotations
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+	_ "google.golang.org/protobuf/types/known/durationpb"
+)
+
+// File is the import path of the options file
+const File = "guardian/options.proto"
+
+// Extension field numbers on google.protobuf.MethodOptions
+const (
+	CacheFieldNumber   = 51230
+	TimeoutFieldNumber = 51231
+	AuthFieldNumber    = 51232
+)
+
+// Extension types of the method options, e.g. for proto.SetExtension in tools and tests.
+// Values are dynamic messages of guardian.CacheOptions, google.protobuf.Duration and
+// guardian.AuthOptions.
+var (
+	CacheExtension   protoreflect.ExtensionType
+	TimeoutExtension protoreflect.ExtensionType
+	AuthExtension    protoreflect.ExtensionType
+)
+
+// types resolves the extensions when re-parsing method options
+var types = new(protoregistry.Types)
+
+func init() {
+	file, err := protoregistry.GlobalFiles.FindFileByPath(File)
+	if err != nil {
+		if file, err = protodesc.NewFile(fileDescriptor(), protoregistry.GlobalFiles); err != nil {
+			panic(fmt.Sprintf("annotations: invalid %s: %v", File, err))
+		}
+		if err := protoregistry.GlobalFiles.RegisterFile(file); err != nil {
+			panic(fmt.Sprintf("annotations: failed to register %s: %v", File, err))
+		}
+	}
+
+	extensions := file.Extensions()
+	CacheExtension = dynamicpb.NewExtensionType(extensions.ByName("cache"))
+	TimeoutExtension = dynamicpb.NewExtensionType(extensions.ByName("timeout"))
+	AuthExtension = dynamicpb.NewExtensionType(extensions.ByName("auth"))
+
+	for _, xt := range []protoreflect.ExtensionType{CacheExtension, TimeoutExtension, AuthExtension} {
+		_ = types.RegisterExtension(xt)
+		if _, err := protoregistry.GlobalTypes.FindExtensionByNumber(xt.TypeDescriptor().ContainingMessage().FullName(), xt.TypeDescriptor().Number()); err != nil {
+			_ = protoregistry.GlobalTypes.RegisterExtension(xt)
+		}
+	}
+}
+
+// fileDescriptor describes guardian/options.proto; keep both in sync
+func fileDescriptor() *descriptorpb.FileDescriptorProto {
+	field := func(name string, number int32, label descriptorpb.FieldDescriptorProto_Label, typ descriptorpb.FieldDescriptorProto_Type, typeName string) *descriptorpb.FieldDescriptorProto {
+		fd := &descriptorpb.FieldDescriptorProto{
+			Name:     proto.String(name),
+			JsonName: proto.String(name),
+			Number:   proto.Int32(number),
+			Label:    label.Enum(),
+			Type:     typ.Enum(),
+		}
+		if typeName != "" {
+			fd.TypeName = proto.String(typeName)
+		}
+		return fd
+	}
+	optional := descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL
+	repeated := descriptorpb.FieldDescriptorProto_LABEL_REPEATED
+	message := descriptorpb.FieldDescriptorProto_TYPE_MESSAGE
+	extension := func(name string, number int32, typeName string) *descriptorpb.FieldDescriptorProto {
+		fd := field(name, number, optional, message, typeName)
+		fd.Extendee = proto.String(".google.protobuf.MethodOptions")
+		return fd
+	}
+
+	return &descriptorpb.FileDescriptorProto{
+		Name:       proto.String(File),
+		Package:    proto.String("guardian"),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"google/protobuf/descriptor.proto", "google/protobuf/duration.proto"},
+		Options: &descriptorpb.FileOptions{
+			GoPackage: proto.String("github.com/grpc-guardian/grpc-guardian/pkg/annotations;annotations"),
+		},
+		MessageType: []*descriptorpb.DescriptorProto{
+			{
+				Name: proto.String("CacheOptions"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					field("ttl", 1, optional, message, ".google.protobuf.Duration"),
+					field("disabled", 2, optional, descriptorpb.FieldDescriptorProto_TYPE_BOOL, ""),
+				},
+			},
+			{
+				Name: proto.String("AuthOptions"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					field("roles", 1, repeated, descriptorpb.FieldDescriptorProto_TYPE_STRING, ""),
+					field("permissions", 2, repeated, descriptorpb.FieldDescriptorProto_TYPE_STRING, ""),
+					field("public", 3, optional, descriptorpb.FieldDescriptorProto_TYPE_BOOL, ""),
+				},
+			},
+		},
+		Extension: []*descriptorpb.FieldDescriptorProto{
+			extension("cache", CacheFieldNumber, ".guardian.CacheOptions"),
+			extension("timeout", TimeoutFieldNumber, ".google.protobuf.Duration"),
+			extension("auth", AuthFieldNumber, ".guardian.AuthOptions"),
+		},
+	}
+}
+
+// CacheOptions is the (guardian.cache) option of a method
+type CacheOptions struct {
+	TTL      time.Duration
+	Disabled bool
+}
+
+// AuthOptions is the (guardian.auth) option of a method
+type AuthOptions struct {
+	Roles       []string
+	Permissions []string
+	Public      bool
+}
+
+// MethodOptions holds the guardian options annotated on one method. Options that are not
+// set are nil or zero.
+type MethodOptions struct {
+	// Method is the full method name, "/pkg.Service/Method"
+	Method  string
+	Cache   *CacheOptions
+	Timeout time.Duration
+	Auth    *AuthOptions
+}
+
+// IsZero reports whether the method has no guardian options
+func (o MethodOptions) IsZero() bool {
+	return o.Cache == nil && o.Timeout == 0 && o.Auth == nil
+}
+
+// ForMethod reads the guardian options of a method
+func ForMethod(md protoreflect.MethodDescriptor) MethodOptions {
+	options := MethodOptions{Method: fmt.Sprintf("/%s/%s", md.Parent().FullName(), md.Name())}
+
+	raw, ok := md.Options().(*descriptorpb.MethodOptions)
+	if !ok || raw == nil {
+		return options
+	}
+
+	// Re-parse with the extensions known: generated code may have parsed the options
+	// before this package was initialized, leaving them as unknown fields
+	data, err := proto.Marshal(raw)
+	if err != nil {
+		return options
+	}
+	parsed := &descriptorpb.MethodOptions{}
+	if err := (proto.UnmarshalOptions{Resolver: types}).Unmarshal(data, parsed); err != nil {
+		return options
+	}
+
+	if m, ok := extension(parsed, CacheExtension); ok {
+		options.Cache = &CacheOptions{
+			TTL:      duration(m.Get(field(m, "ttl")).Message()),
+			Disabled: m.Get(field(m, "disabled")).Bool(),
+		}
+	}
+	if m, ok := extension(parsed, TimeoutExtension); ok {
+		options.Timeout = duration(m)
+	}
+	if m, ok := extension(parsed, AuthExtension); ok {
+		options.Auth = &AuthOptions{
+			Roles:       strings(m.Get(field(m, "roles")).List()),
+			Permissions: strings(m.Get(field(m, "permissions")).List()),
+			Public:      m.Get(field(m, "public")).Bool(),
+		}
+	}
+	return options
+}
+
+// ForService reads the guardian options of every annotated method of a service
+func ForService(sd protoreflect.ServiceDescriptor) []MethodOptions {
+	var methods []MethodOptions
+	for i := 0; i < sd.Methods().Len(); i++ {
+		if options := ForMethod(sd.Methods().Get(i)); !options.IsZero() {
+			methods = append(methods, options)
+		}
+	}
+	return methods
+}
+
+// ServiceInfoProvider is implemented by *grpc.Server
+type ServiceInfoProvider interface {
+	GetServiceInfo() map[string]grpc.ServiceInfo
+}
+
+// FromServer reads the guardian options of every annotated method of the services
+// registered on a server. Services are looked up in protoregistry.GlobalFiles; services
+// without a registered descriptor (e.g. hand-written ServiceDescs) are skipped.
+//
+// Example usage:
+//
+//	server := grpc.NewServer(grpc.ChainUnaryInterceptor(lazyChain))
+//	userpb.RegisterUsersServer(server, users)
+//	methods := annotations.FromServer(server)
+func FromServer(server ServiceInfoProvider) []MethodOptions {
+	names := make([]string, 0)
+	for name := range server.GetServiceInfo() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var methods []MethodOptions
+	for _, name := range names {
+		d, err := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(name))
+		if err != nil {
+			continue
+		}
+		if sd, ok := d.(protoreflect.ServiceDescriptor); ok {
+			methods = append(methods, ForService(sd)...)
+		}
+	}
+	return methods
+}
+
+// ForServices reads the guardian options of the named services, e.g. "users.v1.Users"
+func ForServices(names ...string) ([]MethodOptions, error) {
+	var methods []MethodOptions
+	for _, name := range names {
+		d, err := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(name))
+		if err != nil {
+			return nil, fmt.Errorf("service %s not found: %w", name, err)
+		}
+		sd, ok := d.(protoreflect.ServiceDescriptor)
+		if !ok {
+			return nil, fmt.Errorf("%s is not a service", name)
+		}
+		methods = append(methods, ForService(sd)...)
+	}
+	return methods, nil
+}
+
+// extension returns a message-typed extension of the options if set
+func extension(options *descriptorpb.MethodOptions, xt protoreflect.ExtensionType) (protoreflect.Message, bool) {
+	if !proto.HasExtension(options, xt) {
+		return nil, false
+	}
+	m, ok := proto.GetExtension(options, xt).(proto.Message)
+	if !ok {
+		return nil, false
+	}
+	return m.ProtoReflect(), true
+}
+
+// field returns a field of a message by name
+func field(m protoreflect.Message, name protoreflect.Name) protoreflect.FieldDescriptor {
+	return m.Descriptor().Fields().ByName(name)
+}
+
+// duration converts a google.protobuf.Duration message
+func duration(m protoreflect.Message) time.Duration {
+	seconds := m.Get(field(m, "seconds")).Int()
+	nanos := m.Get(field(m, "nanos")).Int()
+	return time.Duration(seconds)*time.Second + time.Duration(nanos)
+}
+
+// strings converts a repeated string field
+func strings(list protoreflect.List) []string {
+	values := make([]string, list.Len())
+	for i := range values {
+		values[i] = list.Get(i).String()
+	}
+	return values
+}
@@ -0,0 +1,160 @@
+package annotations
+
+import (
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	_ "google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// annotatedMethod builds method options the way generated code sees them before the
+// extensions are known: as unknown fields
+func annotatedMethod(name string, options ...[]byte) *descriptorpb.MethodDescriptorProto {
+	opts := &descriptorpb.MethodOptions{}
+	var raw []byte
+	for _, o := range options {
+		raw = append(raw, o...)
+	}
+	opts.ProtoReflect().SetUnknown(raw)
+	return &descriptorpb.MethodDescriptorProto{
+		Name:       proto.String(name),
+		InputType:  proto.String(".google.protobuf.StringValue"),
+		OutputType: proto.String(".google.protobuf.StringValue"),
+		Options:    opts,
+	}
+}
+
+// messageOption encodes a message-typed extension
+func messageOption(number protowire.Number, fields []byte) []byte {
+	b := protowire.AppendTag(nil, number, protowire.BytesType)
+	return protowire.AppendBytes(b, fields)
+}
+
+// durationFields encodes a google.protobuf.Duration of whole seconds
+func durationFields(seconds int) []byte {
+	b := protowire.AppendTag(nil, 1, protowire.VarintType)
+	return protowire.AppendVarint(b, uint64(seconds))
+}
+
+// registerTestService registers annotationstest.Orders with annotated methods
+func registerTestService(t *testing.T) protoreflect.ServiceDescriptor {
+	t.Helper()
+	if file, err := protoregistry.GlobalFiles.FindFileByPath("annotationstest/orders.proto"); err == nil {
+		return file.Services().ByName("Orders")
+	}
+
+	roles := protowire.AppendString(protowire.AppendTag(nil, 1, protowire.BytesType), "admin")
+	permissions := protowire.AppendString(protowire.AppendTag(nil, 2, protowire.BytesType), "orders:delete")
+	public := protowire.AppendVarint(protowire.AppendTag(nil, 3, protowire.VarintType), 1)
+	disabled := protowire.AppendVarint(protowire.AppendTag(nil, 2, protowire.VarintType), 1)
+
+	fdp := &descriptorpb.FileDescriptorProto{
+		Name:       proto.String("annotationstest/orders.proto"),
+		Package:    proto.String("annotationstest"),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"google/protobuf/wrappers.proto", File},
+		Service: []*descriptorpb.ServiceDescriptorProto{{
+			Name: proto.String("Orders"),
+			Method: []*descriptorpb.MethodDescriptorProto{
+				annotatedMethod("Get",
+					messageOption(CacheFieldNumber, messageOption(1, durationFields(60))),
+					messageOption(TimeoutFieldNumber, durationFields(2)),
+					messageOption(AuthFieldNumber, public)),
+				annotatedMethod("Delete",
+					messageOption(CacheFieldNumber, disabled),
+					messageOption(AuthFieldNumber, append(roles, permissions...))),
+				annotatedMethod("Ping"),
+			},
+		}},
+	}
+	file, err := protodesc.NewFile(fdp, protoregistry.GlobalFiles)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := protoregistry.GlobalFiles.RegisterFile(file); err != nil {
+		t.Fatal(err)
+	}
+	return file.Services().ByName("Orders")
+}
+
+func TestForService(t *testing.T) {
+	sd := registerTestService(t)
+
+	methods := ForService(sd)
+	if len(methods) != 2 {
+		t.Fatalf("Expected the 2 annotated methods, got %+v", methods)
+	}
+	get, del := methods[0], methods[1]
+	if get.Method != "/annotationstest.Orders/Get" || get.Cache.TTL != time.Minute || get.Cache.Disabled || get.Timeout != 2*time.Second || !get.Auth.Public {
+		t.Errorf("Unexpected options for Get: %+v", get)
+	}
+	if del.Method != "/annotationstest.Orders/Delete" || !del.Cache.Disabled || del.Timeout != 0 || del.Auth.Public {
+		t.Errorf("Unexpected options for Delete: %+v", del)
+	}
+	if len(del.Auth.Roles) != 1 || del.Auth.Roles[0] != "admin" || len(del.Auth.Permissions) != 1 || del.Auth.Permissions[0] != "orders:delete" {
+		t.Errorf("Unexpected auth options for Delete: %+v", del.Auth)
+	}
+
+	ping := ForMethod(sd.Methods().ByName("Ping"))
+	if !ping.IsZero() || ping.Method != "/annotationstest.Orders/Ping" {
+		t.Errorf("Expected no options for Ping, got %+v", ping)
+	}
+
+	// The service is looked up by name, or through the services of a server
+	if methods, err := ForServices("annotationstest.Orders"); err != nil || len(methods) != 2 {
+		t.Errorf("ForServices() = %+v, %v", methods, err)
+	}
+	for _, name := range []string{"annotationstest.Missing", "google.protobuf.StringValue"} {
+		if _, err := ForServices(name); err == nil {
+			t.Errorf("ForServices(%q) succeeded", name)
+		}
+	}
+
+	server := grpc.NewServer()
+	for _, name := range []string{"annotationstest.Orders", "handwritten.Service"} {
+		server.RegisterService(&grpc.ServiceDesc{ServiceName: name, HandlerType: (*interface{})(nil)}, struct{}{})
+	}
+	if methods := FromServer(server); len(methods) != 2 {
+		t.Errorf("Expected services without descriptors to be skipped, got %+v", methods)
+	}
+}
+
+func TestExtensions(t *testing.T) {
+	// Tools set the options through the extension types
+	timeout := TimeoutExtension.New().Message()
+	timeout.Set(timeout.Descriptor().Fields().ByName("seconds"), protoreflect.ValueOfInt64(1))
+	timeout.Set(timeout.Descriptor().Fields().ByName("nanos"), protoreflect.ValueOfInt32(5e8))
+	options := &descriptorpb.MethodOptions{}
+	proto.SetExtension(options, TimeoutExtension, timeout.Interface())
+
+	file, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:       proto.String("annotationstest/tools.proto"),
+		Package:    proto.String("annotationstest.tools"),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"google/protobuf/wrappers.proto", File},
+		Service: []*descriptorpb.ServiceDescriptorProto{{
+			Name: proto.String("Tools"),
+			Method: []*descriptorpb.MethodDescriptorProto{{
+				Name:       proto.String("Run"),
+				InputType:  proto.String(".google.protobuf.StringValue"),
+				OutputType: proto.String(".google.protobuf.StringValue"),
+				Options:    options,
+			}},
+		}},
+	}, protoregistry.GlobalFiles)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := ForMethod(file.Services().ByName("Tools").Methods().ByName("Run"))
+	if got.Timeout != 1500*time.Millisecond || got.Cache != nil || got.Auth != nil {
+		t.Errorf("Unexpected options %+v", got)
+	}
+}
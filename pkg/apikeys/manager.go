@@ -0,0 +1,239 @@
+package apikeys
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// CreateOptions describes a new API key
+type CreateOptions struct {
+	Name      string
+	Owner     string // Identity attached to requests made with the key
+	Scopes    []string
+	RateLimit float64       // Requests per second, 0 = unlimited
+	Burst     int           // Defaults to max(1, RateLimit)
+	TTL       time.Duration // 0 = never expires
+	Metadata  map[string]string
+}
+
+// Manager issues, validates, rotates and revokes API keys
+type Manager struct {
+	store  KeyStore
+	prefix string
+	now    func() time.Time
+
+	mu       sync.Mutex
+	limiters map[string]*keyLimiter
+}
+
+// keyLimiter is a per-key token bucket along with the settings it was built from
+type keyLimiter struct {
+	limiter   *rate.Limiter
+	rateLimit float64
+	burst     int
+}
+
+// ManagerOption configures a Manager
+type ManagerOption func(*Manager)
+
+// WithKeyPrefix sets the prefix of generated keys, which makes leaked keys easy to detect
+// Default: "gk"
+func WithKeyPrefix(prefix string) ManagerOption {
+	return func(m *Manager) {
+		if prefix != "" {
+			m.prefix = prefix
+		}
+	}
+}
+
+// NewManager creates a key manager on top of a KeyStore
+func NewManager(store KeyStore, opts ...ManagerOption) *Manager {
+	m := &Manager{
+		store:    store,
+		prefix:   "gk",
+		now:      time.Now,
+		limiters: make(map[string]*keyLimiter),
+	}
+
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	return m
+}
+
+// Create issues a new key. The raw key is returned once and cannot be recovered later.
+func (m *Manager) Create(ctx context.Context, opts CreateOptions) (string, *Key, error) {
+	id, secret, raw, err := generateKey(m.prefix)
+	if err != nil {
+		return "", nil, err
+	}
+
+	now := m.now()
+	key := &Key{
+		ID:        id,
+		Name:      opts.Name,
+		Owner:     opts.Owner,
+		Hash:      hashSecret(secret),
+		Scopes:    opts.Scopes,
+		RateLimit: opts.RateLimit,
+		Burst:     opts.Burst,
+		Metadata:  opts.Metadata,
+		CreatedAt: now,
+	}
+	if opts.TTL > 0 {
+		key.ExpiresAt = now.Add(opts.TTL)
+	}
+
+	if err := m.store.Put(ctx, key); err != nil {
+		return "", nil, fmt.Errorf("failed to store key: %w", err)
+	}
+	return raw, key, nil
+}
+
+// Validate checks a raw key and returns its stored record.
+// It returns ErrInvalidKey, ErrKeyExpired, ErrKeyRevoked or ErrRateLimited.
+func (m *Manager) Validate(ctx context.Context, raw string) (*Key, error) {
+	id, secret, err := parseKey(m.prefix, raw)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := m.store.Get(ctx, id)
+	if errors.Is(err, ErrKeyNotFound) {
+		return nil, ErrInvalidKey
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if !verifySecret(secret, key.Hash) {
+		return nil, ErrInvalidKey
+	}
+	// Keys revoked through another replica and keys whose rotation grace period ended
+	// drop their limiter here
+	if key.IsRevoked() {
+		m.forget(id)
+		return nil, ErrKeyRevoked
+	}
+	if key.IsExpired(m.now()) {
+		m.forget(id)
+		return nil, ErrKeyExpired
+	}
+	if !m.allow(key) {
+		return key, ErrRateLimited
+	}
+
+	return key, nil
+}
+
+// Revoke immediately invalidates a key
+func (m *Manager) Revoke(ctx context.Context, id string) error {
+	key, err := m.store.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	key.RevokedAt = m.now()
+	if err := m.store.Put(ctx, key); err != nil {
+		return fmt.Errorf("failed to revoke key: %w", err)
+	}
+
+	m.forget(id)
+	return nil
+}
+
+// Rotate issues a replacement for a key with the same owner, scopes and limits.
+// The old key keeps working for the grace period so clients can switch over; a zero
+// grace period revokes it immediately.
+func (m *Manager) Rotate(ctx context.Context, id string, grace time.Duration) (string, *Key, error) {
+	old, err := m.store.Get(ctx, id)
+	if err != nil {
+		return "", nil, err
+	}
+	if old.IsRevoked() {
+		return "", nil, ErrKeyRevoked
+	}
+
+	var ttl time.Duration
+	if !old.ExpiresAt.IsZero() {
+		ttl = old.ExpiresAt.Sub(old.CreatedAt)
+	}
+
+	raw, key, err := m.Create(ctx, CreateOptions{
+		Name:      old.Name,
+		Owner:     old.Owner,
+		Scopes:    old.Scopes,
+		RateLimit: old.RateLimit,
+		Burst:     old.Burst,
+		TTL:       ttl,
+		Metadata:  old.Metadata,
+	})
+	if err != nil {
+		return "", nil, err
+	}
+
+	if grace <= 0 {
+		if err := m.Revoke(ctx, id); err != nil {
+			return "", nil, err
+		}
+		return raw, key, nil
+	}
+
+	graceEnd := m.now().Add(grace)
+	if old.ExpiresAt.IsZero() || graceEnd.Before(old.ExpiresAt) {
+		old.ExpiresAt = graceEnd
+		if err := m.store.Put(ctx, old); err != nil {
+			return "", nil, fmt.Errorf("failed to schedule old key expiry: %w", err)
+		}
+	}
+
+	return raw, key, nil
+}
+
+// List returns all keys, including expired and revoked ones
+func (m *Manager) List(ctx context.Context) ([]*Key, error) {
+	return m.store.List(ctx)
+}
+
+// forget drops the rate limiter of a key that can no longer be used
+func (m *Manager) forget(id string) {
+	m.mu.Lock()
+	delete(m.limiters, id)
+	m.mu.Unlock()
+}
+
+// allow applies the key's rate limit
+func (m *Manager) allow(key *Key) bool {
+	if key.RateLimit <= 0 {
+		return true
+	}
+
+	burst := key.Burst
+	if burst <= 0 {
+		burst = int(key.RateLimit)
+		if burst < 1 {
+			burst = 1
+		}
+	}
+
+	m.mu.Lock()
+	l, ok := m.limiters[key.ID]
+	// Rebuild the limiter when the stored limits change
+	if !ok || l.rateLimit != key.RateLimit || l.burst != burst {
+		l = &keyLimiter{
+			limiter:   rate.NewLimiter(rate.Limit(key.RateLimit), burst),
+			rateLimit: key.RateLimit,
+			burst:     burst,
+		}
+		m.limiters[key.ID] = l
+	}
+	m.mu.Unlock()
+
+	return l.limiter.Allow()
+}
@@ -0,0 +1,101 @@
+package apikeys
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseKey(t *testing.T) {
+	_, secret, raw, err := generateKey("gk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(raw, "gk_") {
+		t.Errorf("Expected the key to carry its prefix, got %q", raw)
+	}
+	if _, parsed, err := parseKey("gk", raw); err != nil || parsed != secret {
+		t.Errorf("parseKey() = %q, %v, want the generated secret", parsed, err)
+	}
+
+	for _, invalid := range []string{"", "gk", "gk_", "gk_id", "gk__secret", "other_id_secret"} {
+		if _, _, err := parseKey("gk", invalid); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("parseKey(%q) error = %v, want ErrInvalidKey", invalid, err)
+		}
+	}
+}
+
+func TestManager_Lifecycle(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	store := NewMemoryStore()
+	manager := NewManager(store, WithKeyPrefix("test"))
+	manager.now = func() time.Time { return now }
+
+	raw, key, err := manager.Create(ctx, CreateOptions{Owner: "svc", Scopes: []string{"orders:read"}, TTL: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored, _ := store.Get(ctx, key.ID); strings.Contains(raw, stored.Hash) || stored.Hash == "" {
+		t.Error("Expected only the hash of the secret to be stored")
+	}
+	if validated, err := manager.Validate(ctx, raw); err != nil || !validated.HasScope("orders:read") {
+		t.Fatalf("Validate() = %+v, %v", validated, err)
+	}
+
+	if _, err := manager.Validate(ctx, raw+"x"); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("Expected a wrong secret to be rejected, got %v", err)
+	}
+
+	now = now.Add(time.Hour)
+	if _, err := manager.Validate(ctx, raw); !errors.Is(err, ErrKeyExpired) {
+		t.Errorf("Expected the key to expire after its TTL, got %v", err)
+	}
+}
+
+func TestManager_PrunesLimiters(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	manager := NewManager(NewMemoryStore())
+	manager.now = func() time.Time { return now }
+
+	limited := CreateOptions{Owner: "svc", RateLimit: 10}
+	raw, key, _ := manager.Create(ctx, limited)
+	revokedRaw, revoked, _ := manager.Create(ctx, limited)
+	for _, r := range []string{raw, revokedRaw} {
+		if _, err := manager.Validate(ctx, r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(manager.limiters) != 2 {
+		t.Fatalf("Expected a limiter per key, got %d", len(manager.limiters))
+	}
+
+	if err := manager.Revoke(ctx, revoked.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := manager.limiters[revoked.ID]; ok {
+		t.Error("Expected Revoke to drop the key's limiter")
+	}
+
+	// The old key keeps its limiter during the grace period and drops it once expired
+	newRaw, _, err := manager.Rotate(ctx, key.ID, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := manager.Validate(ctx, newRaw); err != nil {
+		t.Fatal(err)
+	}
+	now = now.Add(time.Minute)
+	if _, err := manager.Validate(ctx, raw); !errors.Is(err, ErrKeyExpired) {
+		t.Fatalf("Expected the rotated key to expire after the grace period, got %v", err)
+	}
+	if _, ok := manager.limiters[key.ID]; ok {
+		t.Error("Expected the expired key's limiter to be dropped")
+	}
+	if len(manager.limiters) != 1 {
+		t.Errorf("Expected only the new key's limiter to remain, got %d", len(manager.limiters))
+	}
+}
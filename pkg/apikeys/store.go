@@ -0,0 +1,319 @@
+package apikeys
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// KeyStore persists API keys by ID
+type KeyStore interface {
+	// Get returns the key with the given ID or ErrKeyNotFound
+	Get(ctx context.Context, id string) (*Key, error)
+
+	// Put creates or replaces a key
+	Put(ctx context.Context, key *Key) error
+
+	// Delete removes a key
+	Delete(ctx context.Context, id string) error
+
+	// List returns all stored keys
+	List(ctx context.Context) ([]*Key, error)
+}
+
+// MemoryStore is an in-memory KeyStore, useful for tests and single-instance deployments
+type MemoryStore struct {
+	mu   sync.RWMutex
+	keys map[string]*Key
+}
+
+// NewMemoryStore creates an empty in-memory key store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		keys: make(map[string]*Key),
+	}
+}
+
+// Get returns the key with the given ID
+func (s *MemoryStore) Get(ctx context.Context, id string) (*Key, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	key, ok := s.keys[id]
+	if !ok {
+		return nil, ErrKeyNotFound
+	}
+	copied := *key
+	return &copied, nil
+}
+
+// Put creates or replaces a key
+func (s *MemoryStore) Put(ctx context.Context, key *Key) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	copied := *key
+	s.keys[key.ID] = &copied
+	return nil
+}
+
+// Delete removes a key
+func (s *MemoryStore) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.keys, id)
+	return nil
+}
+
+// List returns all stored keys ordered by creation time
+func (s *MemoryStore) List(ctx context.Context) ([]*Key, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	keys := make([]*Key, 0, len(s.keys))
+	for _, key := range s.keys {
+		copied := *key
+		keys = append(keys, &copied)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+	})
+	return keys, nil
+}
+
+// FileStore is a KeyStore backed by a JSON file. Every write rewrites the file atomically,
+// so it suits small key sets managed by a single process.
+type FileStore struct {
+	mu     sync.Mutex
+	path   string
+	memory *MemoryStore
+}
+
+// NewFileStore opens (or creates on first write) a JSON key file
+func NewFileStore(path string) (*FileStore, error) {
+	s := &FileStore{
+		path:   path,
+		memory: NewMemoryStore(),
+	}
+	if err := s.Reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Reload re-reads the key file, picking up changes made by other tools
+func (s *FileStore) Reload() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read key file: %w", err)
+	}
+
+	var keys []*Key
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &keys); err != nil {
+			return fmt.Errorf("failed to parse key file: %w", err)
+		}
+	}
+
+	memory := NewMemoryStore()
+	for _, key := range keys {
+		memory.keys[key.ID] = key
+	}
+	s.memory = memory
+	return nil
+}
+
+// Get returns the key with the given ID
+func (s *FileStore) Get(ctx context.Context, id string) (*Key, error) {
+	s.mu.Lock()
+	memory := s.memory
+	s.mu.Unlock()
+
+	return memory.Get(ctx, id)
+}
+
+// Put creates or replaces a key and persists the file
+func (s *FileStore) Put(ctx context.Context, key *Key) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.memory.Put(ctx, key); err != nil {
+		return err
+	}
+	return s.persist(ctx)
+}
+
+// Delete removes a key and persists the file
+func (s *FileStore) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.memory.Delete(ctx, id); err != nil {
+		return err
+	}
+	return s.persist(ctx)
+}
+
+// List returns all stored keys
+func (s *FileStore) List(ctx context.Context) ([]*Key, error) {
+	s.mu.Lock()
+	memory := s.memory
+	s.mu.Unlock()
+
+	return memory.List(ctx)
+}
+
+// persist writes all keys to a temporary file and renames it over the key file
+func (s *FileStore) persist(ctx context.Context) error {
+	keys, err := s.memory.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(keys, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode keys: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to write key file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write key file: %w", err)
+	}
+	if err := tmp.Chmod(0o600); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write key file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write key file: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("failed to write key file: %w", err)
+	}
+	return nil
+}
+
+// RedisClient is the subset of Redis commands used by RedisStore.
+// Adapt your Redis client (e.g. go-redis) to this interface.
+type RedisClient interface {
+	// Get returns the value of a key and whether it exists
+	Get(ctx context.Context, key string) (string, bool, error)
+	Set(ctx context.Context, key string, value string) error
+	Del(ctx context.Context, key string) error
+	SAdd(ctx context.Context, key string, member string) error
+	SRem(ctx context.Context, key string, member string) error
+	SMembers(ctx context.Context, key string) ([]string, error)
+}
+
+// RedisStore is a KeyStore backed by Redis, shared by every replica
+type RedisStore struct {
+	client RedisClient
+	prefix string
+}
+
+// NewRedisStore creates a Redis-backed key store. Keys are stored under
+// "<prefix>:key:<id>" and indexed in the "<prefix>:ids" set.
+func NewRedisStore(client RedisClient, prefix string) *RedisStore {
+	if prefix == "" {
+		prefix = "apikeys"
+	}
+	return &RedisStore{
+		client: client,
+		prefix: prefix,
+	}
+}
+
+// Get returns the key with the given ID
+func (s *RedisStore) Get(ctx context.Context, id string) (*Key, error) {
+	value, found, err := s.client.Get(ctx, s.keyName(id))
+	if err != nil {
+		return nil, fmt.Errorf("redis get failed: %w", err)
+	}
+	if !found {
+		return nil, ErrKeyNotFound
+	}
+
+	var key Key
+	if err := json.Unmarshal([]byte(value), &key); err != nil {
+		return nil, fmt.Errorf("failed to decode key %s: %w", id, err)
+	}
+	return &key, nil
+}
+
+// Put creates or replaces a key
+func (s *RedisStore) Put(ctx context.Context, key *Key) error {
+	data, err := json.Marshal(key)
+	if err != nil {
+		return fmt.Errorf("failed to encode key: %w", err)
+	}
+
+	if err := s.client.Set(ctx, s.keyName(key.ID), string(data)); err != nil {
+		return fmt.Errorf("redis set failed: %w", err)
+	}
+	if err := s.client.SAdd(ctx, s.indexName(), key.ID); err != nil {
+		return fmt.Errorf("redis sadd failed: %w", err)
+	}
+	return nil
+}
+
+// Delete removes a key
+func (s *RedisStore) Delete(ctx context.Context, id string) error {
+	if err := s.client.Del(ctx, s.keyName(id)); err != nil {
+		return fmt.Errorf("redis del failed: %w", err)
+	}
+	if err := s.client.SRem(ctx, s.indexName(), id); err != nil {
+		return fmt.Errorf("redis srem failed: %w", err)
+	}
+	return nil
+}
+
+// List returns all stored keys
+func (s *RedisStore) List(ctx context.Context) ([]*Key, error) {
+	ids, err := s.client.SMembers(ctx, s.indexName())
+	if err != nil {
+		return nil, fmt.Errorf("redis smembers failed: %w", err)
+	}
+
+	keys := make([]*Key, 0, len(ids))
+	for _, id := range ids {
+		key, err := s.Get(ctx, id)
+		if err == ErrKeyNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+	})
+	return keys, nil
+}
+
+// keyName returns the Redis key holding a single API key
+func (s *RedisStore) keyName(id string) string {
+	return s.prefix + ":key:" + id
+}
+
+// indexName returns the Redis set holding all key IDs
+func (s *RedisStore) indexName() string {
+	return s.prefix + ":ids"
+}
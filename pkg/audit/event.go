@@ -0,0 +1,64 @@
+// Package audit records who did what, when, and with which outcome, for compliance. Events
+// are buffered by a Recorder, which writes them in order to a durable Sink: a rotating
+// file, Kafka or a webhook.
+package audit
+
+import (
+	"context"
+	"time"
+)
+
+// Event is one audited call
+type Event struct {
+	// Sequence numbers events in the order they were recorded, starting at 1
+	Sequence uint64    `json:"sequence"`
+	Time     time.Time `json:"time"`
+
+	Actor    Actor             `json:"actor"`
+	Method   string            `json:"method"`
+	Resource map[string]string `json:"resource,omitempty"` // Identifiers of the resources acted on
+	Outcome  Outcome           `json:"outcome"`
+
+	DurationMs float64 `json:"duration_ms"`
+}
+
+// Actor identifies who made a call. Only the identities the authentication middlewares
+// established are set.
+type Actor struct {
+	UserID   string `json:"user_id,omitempty"`
+	APIKeyID string `json:"api_key_id,omitempty"`
+	SPIFFEID string `json:"spiffe_id,omitempty"`
+	ClientID string `json:"client_id,omitempty"`
+	TenantID string `json:"tenant_id,omitempty"`
+	Address  string `json:"address,omitempty"` // Peer address
+}
+
+// Principal returns the strongest identity of the actor, or "anonymous"
+func (a Actor) Principal() string {
+	switch {
+	case a.SPIFFEID != "":
+		return a.SPIFFEID
+	case a.UserID != "":
+		return a.UserID
+	case a.APIKeyID != "":
+		return "apikey:" + a.APIKeyID
+	case a.ClientID != "":
+		return a.ClientID
+	default:
+		return "anonymous"
+	}
+}
+
+// Outcome is the result of an audited call
+type Outcome struct {
+	Success bool   `json:"success"`
+	Code    string `json:"code"` // gRPC status code
+	Message string `json:"message,omitempty"`
+}
+
+// Sink stores audit events durably. Write is called with batches in recording order, one
+// batch at a time; a batch that fails is retried until it is written.
+type Sink interface {
+	Write(ctx context.Context, events []Event) error
+	Close() error
+}
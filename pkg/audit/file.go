@@ -0,0 +1,147 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// FileSink writes events as JSON lines to a file, rotating it by size. Every batch is
+// synced to disk before Write returns.
+type FileSink struct {
+	path       string
+	maxSize    int64
+	maxBackups int
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+// FileOption configures the file sink
+type FileOption func(*FileSink)
+
+// WithMaxSize sets the size in bytes at which the file is rotated
+// Default: 100 MiB
+func WithMaxSize(bytes int64) FileOption {
+	return func(f *FileSink) {
+		if bytes > 0 {
+			f.maxSize = bytes
+		}
+	}
+}
+
+// WithMaxBackups sets how many rotated files (path.1, path.2, ...) are kept
+// Default: 10
+func WithMaxBackups(n int) FileOption {
+	return func(f *FileSink) {
+		if n >= 0 {
+			f.maxBackups = n
+		}
+	}
+}
+
+// NewFileSink opens, or creates, the audit log at path
+func NewFileSink(path string, opts ...FileOption) (*FileSink, error) {
+	f := &FileSink{
+		path:       path,
+		maxSize:    100 << 20,
+		maxBackups: 10,
+	}
+
+	for _, opt := range opts {
+		opt(f)
+	}
+
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Write appends events to the file
+func (f *FileSink) Write(ctx context.Context, events []Event) error {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	for _, event := range events {
+		if err := encoder.Encode(event); err != nil {
+			return fmt.Errorf("failed to encode audit event: %w", err)
+		}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return ErrClosed
+	}
+	if f.size > 0 && f.size+int64(buf.Len()) > f.maxSize {
+		if err := f.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := f.file.Write(buf.Bytes())
+	f.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	return f.file.Sync()
+}
+
+// Close closes the file
+func (f *FileSink) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
+
+// open opens the file for appending
+func (f *FileSink) open() error {
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+
+	f.file, f.size = file, info.Size()
+	return nil
+}
+
+// rotate shifts path.N to path.N+1, dropping the oldest, moves the file to path.1 and
+// starts a new one
+func (f *FileSink) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return fmt.Errorf("failed to rotate audit log: %w", err)
+	}
+	f.file = nil
+
+	if f.maxBackups == 0 {
+		if err := os.Remove(f.path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to rotate audit log: %w", err)
+		}
+		return f.open()
+	}
+
+	os.Remove(fmt.Sprintf("%s.%d", f.path, f.maxBackups))
+	for i := f.maxBackups - 1; i >= 1; i-- {
+		os.Rename(fmt.Sprintf("%s.%d", f.path, i), fmt.Sprintf("%s.%d", f.path, i+1))
+	}
+	if err := os.Rename(f.path, f.path+".1"); err != nil {
+		return fmt.Errorf("failed to rotate audit log: %w", err)
+	}
+	return f.open()
+}
@@ -0,0 +1,79 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// KafkaMessage is a record produced to Kafka
+type KafkaMessage struct {
+	Key   []byte
+	Value []byte
+}
+
+// KafkaProducer is the producer used by KafkaSink. Adapt your Kafka client (e.g. franz-go,
+// sarama or segmentio/kafka-go) to this interface. Produce must return only once every
+// message is acknowledged, and keep the order of messages with the same key.
+type KafkaProducer interface {
+	Produce(ctx context.Context, topic string, messages []KafkaMessage) error
+	Close() error
+}
+
+// KafkaSink produces events to a Kafka topic as JSON
+type KafkaSink struct {
+	producer KafkaProducer
+	topic    string
+	key      func(Event) string
+}
+
+// KafkaOption configures the Kafka sink
+type KafkaOption func(*KafkaSink)
+
+// WithKafkaKey sets the message key of an event. Kafka only orders messages within a
+// partition, so events with the same key stay in order.
+// Default: the principal of the actor
+func WithKafkaKey(key func(Event) string) KafkaOption {
+	return func(k *KafkaSink) {
+		if key != nil {
+			k.key = key
+		}
+	}
+}
+
+// NewKafkaSink creates a sink producing to topic
+func NewKafkaSink(producer KafkaProducer, topic string, opts ...KafkaOption) *KafkaSink {
+	k := &KafkaSink{
+		producer: producer,
+		topic:    topic,
+		key:      func(e Event) string { return e.Actor.Principal() },
+	}
+
+	for _, opt := range opts {
+		opt(k)
+	}
+
+	return k
+}
+
+// Write produces events to the topic
+func (k *KafkaSink) Write(ctx context.Context, events []Event) error {
+	messages := make([]KafkaMessage, len(events))
+	for i, event := range events {
+		value, err := json.Marshal(event)
+		if err != nil {
+			return fmt.Errorf("failed to encode audit event: %w", err)
+		}
+		messages[i] = KafkaMessage{Key: []byte(k.key(event)), Value: value}
+	}
+
+	if err := k.producer.Produce(ctx, k.topic, messages); err != nil {
+		return fmt.Errorf("kafka produce failed: %w", err)
+	}
+	return nil
+}
+
+// Close closes the producer
+func (k *KafkaSink) Close() error {
+	return k.producer.Close()
+}
@@ -0,0 +1,269 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	// ErrBufferFull is returned by Record when the buffer is full and overflow is OverflowDrop
+	ErrBufferFull = errors.New("audit buffer full")
+
+	// ErrClosed is returned by Record after Close
+	ErrClosed = errors.New("audit recorder closed")
+)
+
+// Overflow decides what Record does when the buffer is full
+type Overflow int
+
+const (
+	// OverflowBlock waits for room in the buffer, slowing callers down to the speed of the
+	// sink, until the context of Record is done
+	OverflowBlock Overflow = iota
+
+	// OverflowDrop drops the event and returns ErrBufferFull
+	OverflowDrop
+)
+
+// Recorder buffers audit events and writes them to a sink from a single goroutine, so the
+// sink sees events in recording order. Failed writes are retried with backoff; while the
+// sink is down the buffer fills up and Record applies the overflow policy.
+type Recorder struct {
+	sink          Sink
+	queue         chan Event
+	overflow      Overflow
+	batchSize     int
+	flushInterval time.Duration
+	maxBackoff    time.Duration
+	onError       func(error)
+
+	mu       sync.Mutex // Serializes sequence numbers with enqueueing
+	sequence uint64
+	closed   bool
+
+	dropped atomic.Int64
+	stop    chan struct{} // Closed when Close gives up on pending events
+	done    chan struct{}
+}
+
+// RecorderOption configures the recorder
+type RecorderOption func(*Recorder)
+
+// WithBufferSize sets how many events can wait for the sink
+// Default: 10000
+func WithBufferSize(size int) RecorderOption {
+	return func(r *Recorder) {
+		if size > 0 {
+			r.queue = make(chan Event, size)
+		}
+	}
+}
+
+// WithBatchSize sets the most events written to the sink at once
+// Default: 100
+func WithBatchSize(size int) RecorderOption {
+	return func(r *Recorder) {
+		if size > 0 {
+			r.batchSize = size
+		}
+	}
+}
+
+// WithFlushInterval sets how long events wait for a batch to fill up
+// Default: 1s
+func WithFlushInterval(interval time.Duration) RecorderOption {
+	return func(r *Recorder) {
+		if interval > 0 {
+			r.flushInterval = interval
+		}
+	}
+}
+
+// WithOverflow sets what Record does when the buffer is full
+// Default: OverflowBlock
+func WithOverflow(overflow Overflow) RecorderOption {
+	return func(r *Recorder) {
+		r.overflow = overflow
+	}
+}
+
+// WithMaxBackoff caps the delay between retries of a failed write
+// Default: 30s
+func WithMaxBackoff(backoff time.Duration) RecorderOption {
+	return func(r *Recorder) {
+		if backoff > 0 {
+			r.maxBackoff = backoff
+		}
+	}
+}
+
+// WithErrorHandler is called with every failed sink write, e.g. to alert
+func WithErrorHandler(fn func(error)) RecorderOption {
+	return func(r *Recorder) {
+		r.onError = fn
+	}
+}
+
+// NewRecorder creates a recorder writing to sink and starts its writer goroutine. Close
+// the recorder to flush pending events.
+//
+// Example usage:
+//
+//	sink, err := audit.NewFileSink("/var/log/orders/audit.log", audit.WithMaxSize(100<<20))
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	recorder := audit.NewRecorder(sink, audit.WithBufferSize(50000))
+//	defer recorder.Close(context.Background())
+func NewRecorder(sink Sink, opts ...RecorderOption) *Recorder {
+	r := &Recorder{
+		sink:          sink,
+		queue:         make(chan Event, 10000),
+		batchSize:     100,
+		flushInterval: time.Second,
+		maxBackoff:    30 * time.Second,
+		onError:       func(error) {},
+		stop:          make(chan struct{}),
+		done:          make(chan struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	go r.run()
+	return r
+}
+
+// Record queues an event, assigning its sequence number and, when unset, its time
+func (r *Recorder) Record(ctx context.Context, event Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return ErrClosed
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	event.Sequence = r.sequence + 1
+
+	select {
+	case r.queue <- event:
+		r.sequence++
+		return nil
+	default:
+	}
+
+	if r.overflow == OverflowDrop {
+		r.dropped.Add(1)
+		return ErrBufferFull
+	}
+
+	select {
+	case r.queue <- event:
+		r.sequence++
+		return nil
+	case <-ctx.Done():
+		r.dropped.Add(1)
+		return fmt.Errorf("audit event not recorded: %w", ctx.Err())
+	}
+}
+
+// Dropped returns the number of events that could not be recorded
+func (r *Recorder) Dropped() int64 {
+	return r.dropped.Load()
+}
+
+// Pending returns the number of events waiting for the sink
+func (r *Recorder) Pending() int {
+	return len(r.queue)
+}
+
+// Close stops accepting events and waits until pending events are written, then closes
+// the sink. When ctx is done first, the remaining events are dropped.
+func (r *Recorder) Close(ctx context.Context) error {
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return ErrClosed
+	}
+	r.closed = true
+	close(r.queue)
+	r.mu.Unlock()
+
+	dropped := r.dropped.Load()
+	select {
+	case <-r.done:
+	case <-ctx.Done():
+		close(r.stop)
+		<-r.done
+		r.dropped.Add(int64(r.Pending()))
+	}
+
+	err := r.sink.Close()
+	if lost := r.dropped.Load() - dropped; lost > 0 {
+		return fmt.Errorf("audit recorder closed with %d events unwritten: %w", lost, ctx.Err())
+	}
+	return err
+}
+
+// run batches queued events and writes them until the queue is closed
+func (r *Recorder) run() {
+	defer close(r.done)
+
+	ticker := time.NewTicker(r.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]Event, 0, r.batchSize)
+	for {
+		select {
+		case event, ok := <-r.queue:
+			if !ok {
+				r.write(batch)
+				return
+			}
+			batch = append(batch, event)
+			if len(batch) < r.batchSize {
+				continue
+			}
+		case <-ticker.C:
+			if len(batch) == 0 {
+				continue
+			}
+		}
+
+		if !r.write(batch) {
+			return
+		}
+		batch = batch[:0]
+	}
+}
+
+// write writes a batch, retrying until it succeeds. It returns false when the recorder
+// gave up on it during Close; the batch then counts as dropped.
+func (r *Recorder) write(batch []Event) bool {
+	backoff := 100 * time.Millisecond
+	for len(batch) > 0 {
+		err := r.sink.Write(context.Background(), batch)
+		if err == nil {
+			return true
+		}
+		r.onError(err)
+
+		select {
+		case <-r.stop:
+			r.dropped.Add(int64(len(batch)))
+			return false
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > r.maxBackoff {
+			backoff = r.maxBackoff
+		}
+	}
+	return true
+}
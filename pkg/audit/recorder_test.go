@@ -0,0 +1,131 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// flakySink fails the first writes, then keeps every event it is given
+type flakySink struct {
+	mu       sync.Mutex
+	failures int
+	events   []Event
+	closed   bool
+}
+
+func (s *flakySink) Write(ctx context.Context, events []Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failures > 0 {
+		s.failures--
+		return errors.New("sink down")
+	}
+	s.events = append(s.events, events...)
+	return nil
+}
+
+func (s *flakySink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	return nil
+}
+
+func TestRecorder_RetriesInOrder(t *testing.T) {
+	sink := &flakySink{failures: 2}
+	var failed int
+	recorder := NewRecorder(sink,
+		WithBatchSize(3),
+		WithFlushInterval(10*time.Millisecond),
+		WithMaxBackoff(time.Millisecond),
+		WithErrorHandler(func(error) { failed++ }),
+	)
+
+	for i := 0; i < 10; i++ {
+		if err := recorder.Record(context.Background(), Event{Method: "/orders.Orders/Cancel"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := recorder.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(sink.events) != 10 || failed != 2 || !sink.closed {
+		t.Fatalf("Expected 10 events after 2 failed writes and a closed sink, got %d, %d, %v", len(sink.events), failed, sink.closed)
+	}
+	for i, event := range sink.events {
+		if event.Sequence != uint64(i+1) || event.Time.IsZero() {
+			t.Fatalf("Event %d has sequence %d: events out of order", i, event.Sequence)
+		}
+	}
+
+	if err := recorder.Record(context.Background(), Event{}); !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected ErrClosed after Close, got %v", err)
+	}
+}
+
+func TestRecorder_Backpressure(t *testing.T) {
+	sink := &flakySink{failures: 1 << 30}
+	recorder := NewRecorder(sink, WithBufferSize(1), WithBatchSize(1), WithOverflow(OverflowDrop))
+
+	// The writer holds one event, the buffer the next; later events are dropped
+	var dropped int
+	for i := 0; i < 5; i++ {
+		if errors.Is(recorder.Record(context.Background(), Event{Method: "/m"}), ErrBufferFull) {
+			dropped++
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if dropped < 3 {
+		t.Errorf("Expected events to be dropped while the sink is down, got %d", dropped)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := recorder.Close(ctx); err == nil {
+		t.Error("Expected Close to report unwritten events")
+	}
+	if recorder.Dropped() != 5 {
+		t.Errorf("Dropped = %d, want 5", recorder.Dropped())
+	}
+}
+
+func TestRecorder_BlockUntilContextDone(t *testing.T) {
+	recorder := NewRecorder(&flakySink{failures: 1 << 30}, WithBufferSize(1), WithBatchSize(1))
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		recorder.Close(ctx)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	var err error
+	for i := 0; i < 3 && err == nil; i++ {
+		err = recorder.Record(ctx, Event{Method: "/m"})
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected Record to block until the context is done, got %v", err)
+	}
+}
+
+func TestActor_Principal(t *testing.T) {
+	tests := []struct {
+		actor Actor
+		want  string
+	}{
+		{Actor{SPIFFEID: "spiffe://example.org/ns/shop/sa/orders", UserID: "u1"}, "spiffe://example.org/ns/shop/sa/orders"},
+		{Actor{UserID: "u1", APIKeyID: "k1"}, "u1"},
+		{Actor{APIKeyID: "k1", ClientID: "c1"}, "apikey:k1"},
+		{Actor{ClientID: "c1"}, "c1"},
+		{Actor{Address: "10.0.0.1:443"}, "anonymous"},
+	}
+	for _, tt := range tests {
+		if got := tt.actor.Principal(); got != tt.want {
+			t.Errorf("Principal() = %q, want %q", got, tt.want)
+		}
+	}
+}
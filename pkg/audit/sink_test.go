@@ -0,0 +1,130 @@
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileSink_Rotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	sink, err := NewFileSink(path, WithMaxSize(400), WithMaxBackups(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 1; i <= 12; i++ {
+		event := Event{Sequence: uint64(i), Method: "/orders.Orders/Cancel", Outcome: Outcome{Success: true, Code: "OK"}}
+		if err := sink.Write(context.Background(), []Event{event}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var last uint64
+	for _, name := range []string{path + ".2", path + ".1", path} {
+		file, err := os.Open(name)
+		if err != nil {
+			t.Fatalf("Expected rotated file %s: %v", name, err)
+		}
+		info, _ := file.Stat()
+		if info.Size() > 400 {
+			t.Errorf("%s has %d bytes, over the max size", name, info.Size())
+		}
+
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			var event Event
+			if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+				t.Fatal(err)
+			}
+			if event.Sequence <= last {
+				t.Errorf("Event %d follows %d across rotated files", event.Sequence, last)
+			}
+			last = event.Sequence
+		}
+		file.Close()
+	}
+	if last != 12 {
+		t.Errorf("Expected the newest event in the current file, got %d", last)
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Error("Expected only 2 backups to be kept")
+	}
+}
+
+// fakeProducer keeps the messages produced to each topic
+type fakeProducer struct {
+	topics map[string][]KafkaMessage
+}
+
+func (p *fakeProducer) Produce(ctx context.Context, topic string, messages []KafkaMessage) error {
+	p.topics[topic] = append(p.topics[topic], messages...)
+	return nil
+}
+
+func (p *fakeProducer) Close() error { return nil }
+
+func TestKafkaSink(t *testing.T) {
+	producer := &fakeProducer{topics: make(map[string][]KafkaMessage)}
+	events := []Event{
+		{Sequence: 1, Actor: Actor{UserID: "u1", TenantID: "acme"}},
+		{Sequence: 2, Actor: Actor{APIKeyID: "k1", TenantID: "globex"}},
+	}
+
+	if err := NewKafkaSink(producer, "audit").Write(context.Background(), events); err != nil {
+		t.Fatal(err)
+	}
+	byTenant := NewKafkaSink(producer, "audit-by-tenant", WithKafkaKey(func(e Event) string { return e.Actor.TenantID }))
+	if err := byTenant.Write(context.Background(), events); err != nil {
+		t.Fatal(err)
+	}
+
+	for topic, want := range map[string][]string{"audit": {"u1", "apikey:k1"}, "audit-by-tenant": {"acme", "globex"}} {
+		messages := producer.topics[topic]
+		if len(messages) != len(want) {
+			t.Fatalf("%s: expected %d messages, got %d", topic, len(want), len(messages))
+		}
+		for i, message := range messages {
+			var event Event
+			if err := json.Unmarshal(message.Value, &event); err != nil || event.Sequence != uint64(i+1) {
+				t.Errorf("%s: unexpected message value %s", topic, message.Value)
+			}
+			if string(message.Key) != want[i] {
+				t.Errorf("%s: key = %q, want %q", topic, message.Key, want[i])
+			}
+		}
+	}
+}
+
+func TestWebhookSink(t *testing.T) {
+	var received []Event
+	var token string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token = r.Header.Get("Authorization")
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil || len(received) == 0 {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	sink := NewWebhookSink(server.URL, WithWebhookHeader("Authorization", "Bearer secret"))
+	if err := sink.Write(context.Background(), []Event{{Sequence: 1, Method: "/orders.Orders/Cancel"}}); err != nil {
+		t.Fatal(err)
+	}
+	if len(received) != 1 || received[0].Method != "/orders.Orders/Cancel" || token != "Bearer secret" {
+		t.Errorf("Unexpected request: %+v, %q", received, token)
+	}
+
+	// Non-2xx responses fail the batch so the recorder retries it
+	if err := sink.Write(context.Background(), []Event{}); err == nil {
+		t.Error("Expected an error for a rejected batch")
+	}
+}
@@ -0,0 +1,86 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// WebhookSink posts batches of events as a JSON array to an HTTP endpoint, e.g. a SIEM
+// collector. Any status other than 2xx fails the batch, which the recorder retries.
+type WebhookSink struct {
+	url     string
+	headers http.Header
+	client  *http.Client
+}
+
+// WebhookOption configures the webhook sink
+type WebhookOption func(*WebhookSink)
+
+// WithWebhookHeader adds a header to every request, e.g. an authorization token
+func WithWebhookHeader(key, value string) WebhookOption {
+	return func(w *WebhookSink) {
+		w.headers.Add(key, value)
+	}
+}
+
+// WithWebhookHTTPClient sets the HTTP client, e.g. for mTLS
+// Default: a client with a 10s timeout
+func WithWebhookHTTPClient(client *http.Client) WebhookOption {
+	return func(w *WebhookSink) {
+		if client != nil {
+			w.client = client
+		}
+	}
+}
+
+// NewWebhookSink creates a sink posting to url
+func NewWebhookSink(url string, opts ...WebhookOption) *WebhookSink {
+	w := &WebhookSink{
+		url:     url,
+		headers: make(http.Header),
+		client:  &http.Client{Timeout: 10 * time.Second},
+	}
+
+	for _, opt := range opts {
+		opt(w)
+	}
+
+	return w
+}
+
+// Write posts events to the endpoint
+func (w *WebhookSink) Write(ctx context.Context, events []Event) error {
+	body, err := json.Marshal(events)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit events: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for key, values := range w.headers {
+		req.Header[key] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("audit webhook failed: %w", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("audit webhook failed: %s", resp.Status)
+	}
+	return nil
+}
+
+// Close does nothing; the webhook holds no resources
+func (w *WebhookSink) Close() error {
+	return nil
+}
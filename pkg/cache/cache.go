@@ -24,16 +24,39 @@ type Backend interface {
 	Stats() Stats
 }
 
+// TaggedBackend is implemented by backends that can group entries under tags and delete
+// a whole group at once, e.g. every cached read of one customer
+type TaggedBackend interface {
+	Backend
+
+	// SetWithTags stores a value in the cache with a TTL and associates it with the tags
+	SetWithTags(ctx context.Context, key string, value []byte, ttl time.Duration, tags []string) error
+
+	// DeleteByTag removes all values associated with the tag
+	DeleteByTag(ctx context.Context, tag string) error
+}
+
+// PrefixBackend is implemented by backends that can delete all keys sharing a prefix
+type PrefixBackend interface {
+	Backend
+
+	// DeleteByPrefix removes all values whose key starts with the prefix
+	DeleteByPrefix(ctx context.Context, prefix string) error
+}
+
 // Stats holds cache statistics
 type Stats struct {
-	Hits       uint64 // Number of cache hits
-	Misses     uint64 // Number of cache misses
-	Sets       uint64 // Number of cache sets
-	Deletes    uint64 // Number of cache deletes
-	Evictions  uint64 // Number of evictions
-	Size       int    // Current number of items in cache
-	MaxSize    int    // Maximum cache size
-	HitRate    float64 // Cache hit rate (0.0 - 1.0)
+	Hits        uint64  // Number of cache hits
+	Misses      uint64  // Number of cache misses
+	Sets        uint64  // Number of cache sets
+	Deletes     uint64  // Number of cache deletes
+	Evictions   uint64  // Number of entries removed to make room
+	Expirations uint64  // Number of entries removed because their TTL passed
+	Size        int     // Current number of items in cache
+	MaxSize     int     // Maximum cache size
+	Bytes       int64   // Memory used by keys and values (backends that track it)
+	MaxBytes    int64   // Maximum memory in bytes (0 = unlimited)
+	HitRate     float64 // Cache hit rate (0.0 - 1.0)
 }
 
 // Entry represents a cached entry
@@ -42,6 +65,7 @@ type Entry struct {
 	ExpiresAt  time.Time // Expiration time
 	CreatedAt  time.Time // Creation time
 	AccessedAt time.Time // Last access time
+	Tags       []string  // Tags the entry is grouped under
 }
 
 // IsExpired checks if the entry has expired
@@ -0,0 +1,131 @@
+package cache
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// Codec serializes responses for storage in a backend. Unmarshal must reconstruct a
+// response without being told its type, so codecs store whatever type information they
+// need alongside the payload.
+type Codec interface {
+	// Marshal serializes a response
+	Marshal(v interface{}) ([]byte, error)
+
+	// Unmarshal reconstructs a response serialized by Marshal
+	Unmarshal(data []byte) (interface{}, error)
+}
+
+// ProtoCodec serializes proto messages in binary wire format together with their type
+// name, and returns the concrete message type on Unmarshal. Message types are resolved
+// through protoregistry.GlobalTypes, where generated code registers them.
+type ProtoCodec struct{}
+
+// NewProtoCodec creates a codec for proto message responses
+func NewProtoCodec() *ProtoCodec {
+	return &ProtoCodec{}
+}
+
+// Marshal serializes a proto message; other values are rejected
+func (c *ProtoCodec) Marshal(v interface{}) ([]byte, error) {
+	msg, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("cache: %T is not a proto message", v)
+	}
+
+	// Deterministic output keeps equal responses byte-identical (adaptive TTLs compare them)
+	wrapped := &anypb.Any{TypeUrl: "type.googleapis.com/" + string(msg.ProtoReflect().Descriptor().FullName())}
+	var err error
+	if wrapped.Value, err = (proto.MarshalOptions{Deterministic: true}).Marshal(msg); err != nil {
+		return nil, fmt.Errorf("cache: failed to marshal %T: %w", v, err)
+	}
+	return proto.Marshal(wrapped)
+}
+
+// Unmarshal reconstructs the proto message
+func (c *ProtoCodec) Unmarshal(data []byte) (interface{}, error) {
+	var wrapped anypb.Any
+	if err := proto.Unmarshal(data, &wrapped); err != nil {
+		return nil, fmt.Errorf("cache: failed to unmarshal entry: %w", err)
+	}
+	msg, err := wrapped.UnmarshalNew()
+	if err != nil {
+		return nil, fmt.Errorf("cache: failed to unmarshal %s: %w", wrapped.GetTypeUrl(), err)
+	}
+	return msg, nil
+}
+
+// JSONCodec serializes responses with encoding/json. Unmarshal returns generic values
+// (map[string]interface{} for structs), so it only suits callers that do not need the
+// original type back.
+type JSONCodec struct{}
+
+// NewJSONCodec creates a JSON codec
+func NewJSONCodec() *JSONCodec {
+	return &JSONCodec{}
+}
+
+// Marshal serializes a value to JSON
+func (c *JSONCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+// Unmarshal decodes JSON into a generic value
+func (c *JSONCodec) Unmarshal(data []byte) (interface{}, error) {
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// Format markers of DefaultCodec entries
+const (
+	formatProto byte = 'p'
+	formatJSON  byte = 'j'
+)
+
+// DefaultCodec serializes proto messages with ProtoCodec and any other value with
+// JSONCodec, so gRPC responses come back as their concrete message type.
+type DefaultCodec struct {
+	proto ProtoCodec
+	json  JSONCodec
+}
+
+// NewDefaultCodec creates the default codec
+func NewDefaultCodec() *DefaultCodec {
+	return &DefaultCodec{}
+}
+
+// Marshal serializes a value, prefixed with the format it was written in
+func (c *DefaultCodec) Marshal(v interface{}) ([]byte, error) {
+	format, codec := formatJSON, Codec(&c.json)
+	if _, ok := v.(proto.Message); ok {
+		format, codec = formatProto, &c.proto
+	}
+
+	data, err := codec.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte{format}, data...), nil
+}
+
+// Unmarshal reconstructs a value in the format it was written in
+func (c *DefaultCodec) Unmarshal(data []byte) (interface{}, error) {
+	if len(data) == 0 {
+		return nil, errors.New("cache: empty entry")
+	}
+
+	switch data[0] {
+	case formatProto:
+		return c.proto.Unmarshal(data[1:])
+	case formatJSON:
+		return c.json.Unmarshal(data[1:])
+	}
+	return nil, fmt.Errorf("cache: unknown entry format %q", data[0])
+}
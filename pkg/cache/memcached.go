@@ -0,0 +1,143 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// maxMemcachedKeyLength is the longest key memcached accepts
+const maxMemcachedKeyLength = 250
+
+// maxMemcachedRelativeTTL is the longest expiration memcached treats as relative;
+// larger values are interpreted as a Unix timestamp
+const maxMemcachedRelativeTTL = 30 * 24 * time.Hour
+
+// MemcachedClient is the subset of memcached commands used by MemcachedBackend.
+// Adapt your memcached client (e.g. gomemcache) to this interface.
+type MemcachedClient interface {
+	// Get returns the value of a key and whether it exists
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+
+	// Set stores a value; expiration is in seconds, 0 never expires
+	Set(ctx context.Context, key string, value []byte, expiration int32) error
+
+	// Delete removes a key; deleting a missing key is not an error
+	Delete(ctx context.Context, key string) error
+
+	// FlushAll invalidates every item on the servers
+	FlushAll(ctx context.Context) error
+}
+
+// MemcachedBackend is a Backend stored in memcached, shared by every replica.
+// Statistics only cover the operations of this process.
+type MemcachedBackend struct {
+	client MemcachedClient
+	prefix string
+
+	mu    sync.Mutex
+	stats Stats
+}
+
+// NewMemcachedBackend creates a memcached backend. Keys are stored under "<prefix>:<key>";
+// keys that memcached would reject (too long, spaces or control characters) are hashed.
+func NewMemcachedBackend(client MemcachedClient, prefix string) *MemcachedBackend {
+	if prefix == "" {
+		prefix = "grpc-cache"
+	}
+	return &MemcachedBackend{
+		client: client,
+		prefix: prefix,
+	}
+}
+
+// Get retrieves a value from the cache
+func (m *MemcachedBackend) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	value, found, err := m.client.Get(ctx, m.keyName(key))
+	if err != nil {
+		return nil, false, fmt.Errorf("memcached get failed: %w", err)
+	}
+
+	m.mu.Lock()
+	if found {
+		m.stats.Hits++
+	} else {
+		m.stats.Misses++
+	}
+	m.stats.HitRate = float64(m.stats.Hits) / float64(m.stats.Hits+m.stats.Misses)
+	m.mu.Unlock()
+
+	return value, found, nil
+}
+
+// Set stores a value in the cache with a TTL
+func (m *MemcachedBackend) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if ttl > maxMemcachedRelativeTTL {
+		ttl = maxMemcachedRelativeTTL
+	}
+	expiration := int32(ttl / time.Second)
+	if ttl > 0 && expiration == 0 {
+		expiration = 1 // Sub-second TTLs would otherwise never expire
+	}
+
+	if err := m.client.Set(ctx, m.keyName(key), value, expiration); err != nil {
+		return fmt.Errorf("memcached set failed: %w", err)
+	}
+
+	m.mu.Lock()
+	m.stats.Sets++
+	m.mu.Unlock()
+	return nil
+}
+
+// Delete removes a value from the cache
+func (m *MemcachedBackend) Delete(ctx context.Context, key string) error {
+	if err := m.client.Delete(ctx, m.keyName(key)); err != nil {
+		return fmt.Errorf("memcached delete failed: %w", err)
+	}
+
+	m.mu.Lock()
+	m.stats.Deletes++
+	m.mu.Unlock()
+	return nil
+}
+
+// Clear flushes the memcached servers. Memcached cannot delete by prefix, so this also
+// removes items that other applications store on the same servers.
+func (m *MemcachedBackend) Clear(ctx context.Context) error {
+	if err := m.client.FlushAll(ctx); err != nil {
+		return fmt.Errorf("memcached flush_all failed: %w", err)
+	}
+	return nil
+}
+
+// Stats returns cache statistics of this process; Size is unknown and reported as 0
+func (m *MemcachedBackend) Stats() Stats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.stats
+}
+
+// keyName returns the memcached key for a cache key
+func (m *MemcachedBackend) keyName(key string) string {
+	name := m.prefix + ":" + key
+	if len(name) <= maxMemcachedKeyLength && validMemcachedKey(name) {
+		return name
+	}
+
+	hash := sha256.Sum256([]byte(key))
+	return m.prefix + ":sha256:" + hex.EncodeToString(hash[:])
+}
+
+// validMemcachedKey reports whether a key has no whitespace or control characters
+func validMemcachedKey(key string) bool {
+	for i := 0; i < len(key); i++ {
+		if key[i] <= ' ' || key[i] == 0x7f {
+			return false
+		}
+	}
+	return true
+}
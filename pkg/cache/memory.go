@@ -1,24 +1,53 @@
 package cache
 
 import (
+	"container/list"
 	"context"
+	"errors"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
-// MemoryBackend is an in-memory cache implementation
+// ErrEntryTooLarge is returned when a single value exceeds the byte limit of the cache
+var ErrEntryTooLarge = errors.New("cache: entry exceeds MaxBytes")
+
+// MemoryBackend is an in-memory cache implementation. Entries are kept in least recently
+// used order, so evictions take constant time.
 type MemoryBackend struct {
-	mu         sync.RWMutex
-	data       map[string]*Entry
-	maxSize    int
-	stats      Stats
+	mu              sync.Mutex
+	data            map[string]*list.Element       // Key -> element of lru holding a *memoryItem
+	lru             *list.List                     // Most recently used first
+	tags            map[string]map[string]struct{} // Tag -> keys
+	maxSize         int
+	maxBytes        int64
 	cleanupInterval time.Duration
-	stopCleanup chan struct{}
+	stopCleanup     chan struct{}
+
+	// Counters are atomic so that Stats never waits for the lock; size and bytes are
+	// only written while holding it
+	hits        atomic.Uint64
+	misses      atomic.Uint64
+	sets        atomic.Uint64
+	deletes     atomic.Uint64
+	evictions   atomic.Uint64
+	expirations atomic.Uint64
+	size        atomic.Int64
+	bytes       atomic.Int64
+}
+
+// memoryItem is a cache entry in the LRU list
+type memoryItem struct {
+	key   string
+	entry *Entry
+	size  int64
 }
 
 // MemoryConfig holds configuration for memory cache
 type MemoryConfig struct {
 	MaxSize         int           // Maximum number of entries (0 = unlimited)
+	MaxBytes        int64         // Maximum total size of keys and values in bytes (0 = unlimited)
 	CleanupInterval time.Duration // How often to clean expired entries
 }
 
@@ -37,14 +66,15 @@ func NewMemoryBackend(config *MemoryConfig) *MemoryBackend {
 	}
 
 	mb := &MemoryBackend{
-		data:            make(map[string]*Entry),
+		data:            make(map[string]*list.Element),
+		lru:             list.New(),
+		tags:            make(map[string]map[string]struct{}),
 		maxSize:         config.MaxSize,
+		maxBytes:        config.MaxBytes,
 		cleanupInterval: config.CleanupInterval,
 		stopCleanup:     make(chan struct{}),
 	}
 
-	mb.stats.MaxSize = config.MaxSize
-
 	// Start background cleanup goroutine
 	go mb.startCleanup()
 
@@ -56,38 +86,49 @@ func (m *MemoryBackend) Get(ctx context.Context, key string) ([]byte, bool, erro
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	entry, exists := m.data[key]
+	element, exists := m.data[key]
 	if !exists {
-		m.stats.Misses++
-		m.updateHitRate()
+		m.misses.Add(1)
 		return nil, false, nil
 	}
 
-	// Check if expired
+	// Expired entries are removed on read rather than waiting for the cleanup
+	entry := element.Value.(*memoryItem).entry
 	if entry.IsExpired() {
-		m.stats.Misses++
-		m.updateHitRate()
-		// Note: Actual deletion happens in cleanup goroutine
+		m.removeLocked(key)
+		m.expirations.Add(1)
+		m.misses.Add(1)
 		return nil, false, nil
 	}
 
-	// Update access time
+	// Update access time and LRU position
 	entry.AccessedAt = time.Now()
+	m.lru.MoveToFront(element)
 
-	m.stats.Hits++
-	m.updateHitRate()
+	m.hits.Add(1)
 
 	return entry.Value, true, nil
 }
 
 // Set stores a value in the cache with a TTL
 func (m *MemoryBackend) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return m.SetWithTags(ctx, key, value, ttl, nil)
+}
+
+// SetWithTags stores a value in the cache with a TTL and associates it with the tags.
+// Least recently used entries are evicted until the entry fits within MaxSize and MaxBytes.
+func (m *MemoryBackend) SetWithTags(ctx context.Context, key string, value []byte, ttl time.Duration, tags []string) error {
+	size := int64(len(key) + len(value))
+	if m.maxBytes > 0 && size > m.maxBytes {
+		return ErrEntryTooLarge
+	}
+
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	// Check if we need to evict entries
-	if m.maxSize > 0 && len(m.data) >= m.maxSize {
-		// Evict oldest entry (simple LRU)
+	// Replacing an entry drops its old tags
+	m.removeLocked(key)
+	for m.lru.Len() > 0 && ((m.maxSize > 0 && m.lru.Len() >= m.maxSize) || (m.maxBytes > 0 && m.bytes.Load()+size > m.maxBytes)) {
 		m.evictOldest()
 	}
 
@@ -97,15 +138,26 @@ func (m *MemoryBackend) Set(ctx context.Context, key string, value []byte, ttl t
 		expiresAt = now.Add(ttl)
 	}
 
-	m.data[key] = &Entry{
+	entry := &Entry{
 		Value:      value,
 		ExpiresAt:  expiresAt,
 		CreatedAt:  now,
 		AccessedAt: now,
+		Tags:       tags,
+	}
+	m.data[key] = m.lru.PushFront(&memoryItem{key: key, entry: entry, size: size})
+	m.size.Store(int64(len(m.data)))
+	m.bytes.Add(size)
+	for _, tag := range tags {
+		keys, ok := m.tags[tag]
+		if !ok {
+			keys = make(map[string]struct{})
+			m.tags[tag] = keys
+		}
+		keys[key] = struct{}{}
 	}
 
-	m.stats.Sets++
-	m.stats.Size = len(m.data)
+	m.sets.Add(1)
 
 	return nil
 }
@@ -115,10 +167,36 @@ func (m *MemoryBackend) Delete(ctx context.Context, key string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	if _, exists := m.data[key]; exists {
-		delete(m.data, key)
-		m.stats.Deletes++
-		m.stats.Size = len(m.data)
+	if m.removeLocked(key) {
+		m.deletes.Add(1)
+	}
+
+	return nil
+}
+
+// DeleteByTag removes all values associated with the tag
+func (m *MemoryBackend) DeleteByTag(ctx context.Context, tag string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for key := range m.tags[tag] {
+		if m.removeLocked(key) {
+			m.deletes.Add(1)
+		}
+	}
+
+	return nil
+}
+
+// DeleteByPrefix removes all values whose key starts with the prefix
+func (m *MemoryBackend) DeleteByPrefix(ctx context.Context, prefix string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for key := range m.data {
+		if strings.HasPrefix(key, prefix) && m.removeLocked(key) {
+			m.deletes.Add(1)
+		}
 	}
 
 	return nil
@@ -129,20 +207,36 @@ func (m *MemoryBackend) Clear(ctx context.Context) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	m.data = make(map[string]*Entry)
-	m.stats.Size = 0
+	m.data = make(map[string]*list.Element)
+	m.lru.Init()
+	m.tags = make(map[string]map[string]struct{})
+	m.size.Store(0)
+	m.bytes.Store(0)
 
 	return nil
 }
 
-// Stats returns cache statistics
+// Stats returns a snapshot of the cache statistics, including the memory used by keys and
+// values. It does not take the cache lock, so it is safe to scrape concurrently with traffic;
+// the counters are read one by one and may be off by in-flight operations. Size includes
+// expired entries that were not read since they expired, until the next cleanup.
 func (m *MemoryBackend) Stats() Stats {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
-
-	statsCopy := m.stats
-	statsCopy.Size = len(m.data)
-	return statsCopy
+	stats := Stats{
+		Hits:        m.hits.Load(),
+		Misses:      m.misses.Load(),
+		Sets:        m.sets.Load(),
+		Deletes:     m.deletes.Load(),
+		Evictions:   m.evictions.Load(),
+		Expirations: m.expirations.Load(),
+		Size:        int(m.size.Load()),
+		MaxSize:     m.maxSize,
+		Bytes:       m.bytes.Load(),
+		MaxBytes:    m.maxBytes,
+	}
+	if total := stats.Hits + stats.Misses; total > 0 {
+		stats.HitRate = float64(stats.Hits) / float64(total)
+	}
+	return stats
 }
 
 // Close stops the cleanup goroutine
@@ -150,22 +244,35 @@ func (m *MemoryBackend) Close() {
 	close(m.stopCleanup)
 }
 
-// evictOldest removes the oldest accessed entry (simple LRU)
+// evictOldest removes the least recently used entry
 func (m *MemoryBackend) evictOldest() {
-	var oldestKey string
-	var oldestTime time.Time
-
-	for key, entry := range m.data {
-		if oldestKey == "" || entry.AccessedAt.Before(oldestTime) {
-			oldestKey = key
-			oldestTime = entry.AccessedAt
-		}
+	if oldest := m.lru.Back(); oldest != nil {
+		m.removeLocked(oldest.Value.(*memoryItem).key)
+		m.evictions.Add(1)
 	}
+}
 
-	if oldestKey != "" {
-		delete(m.data, oldestKey)
-		m.stats.Evictions++
+// removeLocked deletes an entry and its tag associations, reporting whether it existed;
+// the caller holds the lock
+func (m *MemoryBackend) removeLocked(key string) bool {
+	element, exists := m.data[key]
+	if !exists {
+		return false
+	}
+	item := element.Value.(*memoryItem)
+	delete(m.data, key)
+	m.lru.Remove(element)
+	m.size.Store(int64(len(m.data)))
+	m.bytes.Add(-item.size)
+
+	for _, tag := range item.entry.Tags {
+		keys := m.tags[tag]
+		delete(keys, key)
+		if len(keys) == 0 {
+			delete(m.tags, tag)
+		}
 	}
+	return true
 }
 
 // startCleanup runs a background goroutine to clean expired entries
@@ -189,20 +296,11 @@ func (m *MemoryBackend) cleanup() {
 	defer m.mu.Unlock()
 
 	now := time.Now()
-	for key, entry := range m.data {
+	for key, element := range m.data {
+		entry := element.Value.(*memoryItem).entry
 		if !entry.ExpiresAt.IsZero() && now.After(entry.ExpiresAt) {
-			delete(m.data, key)
-			m.stats.Evictions++
+			m.removeLocked(key)
+			m.expirations.Add(1)
 		}
 	}
-
-	m.stats.Size = len(m.data)
-}
-
-// updateHitRate calculates the cache hit rate
-func (m *MemoryBackend) updateHitRate() {
-	total := m.stats.Hits + m.stats.Misses
-	if total > 0 {
-		m.stats.HitRate = float64(m.stats.Hits) / float64(total)
-	}
 }
@@ -0,0 +1,55 @@
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// NoopBackend is a Backend that never stores anything; every lookup is a miss.
+// Use it to disable caching without removing the middleware.
+type NoopBackend struct{}
+
+// NewNoopBackend creates a backend that caches nothing
+func NewNoopBackend() *NoopBackend {
+	return &NoopBackend{}
+}
+
+// Get always reports a miss
+func (n *NoopBackend) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	return nil, false, nil
+}
+
+// Set discards the value
+func (n *NoopBackend) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return nil
+}
+
+// SetWithTags discards the value
+func (n *NoopBackend) SetWithTags(ctx context.Context, key string, value []byte, ttl time.Duration, tags []string) error {
+	return nil
+}
+
+// Delete does nothing
+func (n *NoopBackend) Delete(ctx context.Context, key string) error {
+	return nil
+}
+
+// DeleteByTag does nothing
+func (n *NoopBackend) DeleteByTag(ctx context.Context, tag string) error {
+	return nil
+}
+
+// DeleteByPrefix does nothing
+func (n *NoopBackend) DeleteByPrefix(ctx context.Context, prefix string) error {
+	return nil
+}
+
+// Clear does nothing
+func (n *NoopBackend) Clear(ctx context.Context) error {
+	return nil
+}
+
+// Stats returns empty statistics
+func (n *NoopBackend) Stats() Stats {
+	return Stats{}
+}
@@ -0,0 +1,248 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/grpc-guardian/grpc-guardian/pkg/randutil"
+)
+
+// TieredBackend layers a fast local cache (L1) in front of a shared remote cache (L2).
+// Lookups try L1 first and fall back to L2; L2 hits are written back to L1. L1 entries
+// live at most the L1 TTL, which bounds how long a replica can serve a value that was
+// changed or invalidated through another replica.
+//
+// Tags and prefix deletion are passed on to both tiers (see DeleteByTag).
+//
+// TTLs are jittered so that entries written at the same time (e.g. after a deploy) do not
+// all expire, and hit the handler, at the same moment.
+type TieredBackend struct {
+	l1     Backend
+	l2     Backend
+	l1TTL  time.Duration
+	jitter float64
+	rng    *randutil.Rand
+
+	mu    sync.Mutex
+	stats Stats
+}
+
+// TieredOption configures a TieredBackend
+type TieredOption func(*TieredBackend)
+
+// WithL1TTL sets the longest time an entry stays in the local cache
+// Default: 30s
+func WithL1TTL(ttl time.Duration) TieredOption {
+	return func(t *TieredBackend) {
+		if ttl > 0 {
+			t.l1TTL = ttl
+		}
+	}
+}
+
+// WithTTLJitter sets the fraction by which TTLs are randomly shortened (0 disables)
+// Default: 0.1
+func WithTTLJitter(fraction float64) TieredOption {
+	return func(t *TieredBackend) {
+		if fraction >= 0 && fraction < 1 {
+			t.jitter = fraction
+		}
+	}
+}
+
+// WithTTLJitterSeed seeds the TTL jitter, so the same seed and sequence of writes give the
+// same TTLs, e.g. for reproducible tests
+// Default: seeded from the clock
+func WithTTLJitterSeed(seed int64) TieredOption {
+	return func(t *TieredBackend) {
+		t.rng = randutil.New(seed)
+	}
+}
+
+// NewTieredBackend creates a two-tier cache of a local and a remote backend.
+//
+// Example usage:
+//
+//	remote := cache.NewMemcachedBackend(memcachedAdapter{client}, "orders")
+//	backend := cache.NewTieredBackend(cache.NewMemoryBackend(nil), remote,
+//	    cache.WithL1TTL(10*time.Second),
+//	)
+//	chain := guardian.NewChain(middleware.Cache(middleware.WithCacheBackend(backend)))
+func NewTieredBackend(l1, l2 Backend, opts ...TieredOption) *TieredBackend {
+	t := &TieredBackend{
+		l1:     l1,
+		l2:     l2,
+		l1TTL:  30 * time.Second,
+		jitter: 0.1,
+	}
+
+	for _, opt := range opts {
+		opt(t)
+	}
+	if t.rng == nil {
+		t.rng = randutil.NewTimeSeeded()
+	}
+
+	return t
+}
+
+// Get retrieves a value from L1, then L2
+func (t *TieredBackend) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	if value, found, err := t.l1.Get(ctx, key); err == nil && found {
+		t.record(true)
+		return value, true, nil
+	}
+
+	value, found, err := t.l2.Get(ctx, key)
+	if err != nil {
+		return nil, false, err
+	}
+	t.record(found)
+	if found {
+		_ = t.l1.Set(ctx, key, value, t.jittered(t.l1TTL))
+	}
+	return value, found, nil
+}
+
+// Set stores a value in both tiers
+func (t *TieredBackend) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return t.SetWithTags(ctx, key, value, ttl, nil)
+}
+
+// SetWithTags stores a value in both tiers; tiers that do not implement TaggedBackend
+// store it without the tags
+func (t *TieredBackend) SetWithTags(ctx context.Context, key string, value []byte, ttl time.Duration, tags []string) error {
+	ttl = t.jittered(ttl)
+	if err := setTier(ctx, t.l2, key, value, ttl, tags); err != nil {
+		return err
+	}
+
+	l1TTL := t.jittered(t.l1TTL)
+	if ttl > 0 && ttl < l1TTL {
+		l1TTL = ttl
+	}
+	if err := setTier(ctx, t.l1, key, value, l1TTL, tags); err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	t.stats.Sets++
+	t.mu.Unlock()
+	return nil
+}
+
+// Delete removes a value from both tiers
+func (t *TieredBackend) Delete(ctx context.Context, key string) error {
+	if err := t.l2.Delete(ctx, key); err != nil {
+		return err
+	}
+	if err := t.l1.Delete(ctx, key); err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	t.stats.Deletes++
+	t.mu.Unlock()
+	return nil
+}
+
+// DeleteByTag removes the values associated with the tag from both tiers. The remote tier
+// must implement TaggedBackend; a local tier that does not is cleared instead.
+func (t *TieredBackend) DeleteByTag(ctx context.Context, tag string) error {
+	tagged, ok := t.l2.(TaggedBackend)
+	if !ok {
+		return fmt.Errorf("cache: L2 backend %T does not support tags", t.l2)
+	}
+	if err := tagged.DeleteByTag(ctx, tag); err != nil {
+		return err
+	}
+
+	if tagged, ok := t.l1.(TaggedBackend); ok {
+		return tagged.DeleteByTag(ctx, tag)
+	}
+	return t.l1.Clear(ctx)
+}
+
+// DeleteByPrefix removes the values whose key starts with the prefix from both tiers. The
+// remote tier must implement PrefixBackend; a local tier that does not is cleared instead.
+func (t *TieredBackend) DeleteByPrefix(ctx context.Context, prefix string) error {
+	prefixed, ok := t.l2.(PrefixBackend)
+	if !ok {
+		return fmt.Errorf("cache: L2 backend %T does not support deletion by prefix", t.l2)
+	}
+	if err := prefixed.DeleteByPrefix(ctx, prefix); err != nil {
+		return err
+	}
+
+	if prefixed, ok := t.l1.(PrefixBackend); ok {
+		return prefixed.DeleteByPrefix(ctx, prefix)
+	}
+	return t.l1.Clear(ctx)
+}
+
+// Clear removes all values from both tiers
+func (t *TieredBackend) Clear(ctx context.Context) error {
+	if err := t.l2.Clear(ctx); err != nil {
+		return err
+	}
+	return t.l1.Clear(ctx)
+}
+
+// Stats returns combined statistics: a hit in either tier counts as a hit. Size, Bytes,
+// their limits, Evictions and Expirations are those of the local tier.
+func (t *TieredBackend) Stats() Stats {
+	l1 := t.l1.Stats()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	stats := t.stats
+	stats.Size = l1.Size
+	stats.MaxSize = l1.MaxSize
+	stats.Evictions = l1.Evictions
+	stats.Expirations = l1.Expirations
+	stats.Bytes = l1.Bytes
+	stats.MaxBytes = l1.MaxBytes
+	return stats
+}
+
+// L1 returns the local tier
+func (t *TieredBackend) L1() Backend {
+	return t.l1
+}
+
+// L2 returns the remote tier
+func (t *TieredBackend) L2() Backend {
+	return t.l2
+}
+
+// record counts a lookup
+func (t *TieredBackend) record(hit bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if hit {
+		t.stats.Hits++
+	} else {
+		t.stats.Misses++
+	}
+	t.stats.HitRate = float64(t.stats.Hits) / float64(t.stats.Hits+t.stats.Misses)
+}
+
+// setTier stores a value in one tier, with the tags when the tier supports them
+func setTier(ctx context.Context, tier Backend, key string, value []byte, ttl time.Duration, tags []string) error {
+	if tagged, ok := tier.(TaggedBackend); ok && len(tags) > 0 {
+		return tagged.SetWithTags(ctx, key, value, ttl, tags)
+	}
+	return tier.Set(ctx, key, value, ttl)
+}
+
+// jittered shortens a TTL by a random fraction up to the jitter; 0 (no expiry) is kept
+func (t *TieredBackend) jittered(ttl time.Duration) time.Duration {
+	if ttl <= 0 || t.jitter == 0 {
+		return ttl
+	}
+	return ttl - time.Duration(t.rng.Float64()*t.jitter*float64(ttl))
+}
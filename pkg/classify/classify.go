@@ -0,0 +1,238 @@
+// Package classify marks gRPC methods as read-only or mutating so that middlewares can
+// pick safe defaults per method: retry and cache only reads, chaos leaves writes alone,
+// and idempotency-key handling only applies to writes.
+package classify
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// Kind is the classification of a method
+type Kind int
+
+const (
+	// Unknown methods could not be classified; consumers treat them like writes
+	Unknown Kind = iota
+	// Read methods have no side effects
+	Read
+	// Write methods mutate state
+	Write
+)
+
+// String returns the kind name
+func (k Kind) String() string {
+	switch k {
+	case Read:
+		return "read"
+	case Write:
+		return "write"
+	}
+	return "unknown"
+}
+
+// DefaultReadPrefixes are method name prefixes treated as reads by the naming heuristic
+var DefaultReadPrefixes = []string{
+	"Get", "List", "Search", "Find", "Query", "Lookup", "Fetch", "Read",
+	"Describe", "Count", "Check", "Exists", "Watch",
+}
+
+// DefaultWritePrefixes are method name prefixes treated as writes by the naming heuristic
+var DefaultWritePrefixes = []string{
+	"Create", "Update", "Delete", "Remove", "Set", "Put", "Patch", "Insert", "Upsert",
+	"Add", "Write", "Cancel", "Start", "Stop", "Send", "Publish", "Import", "Move",
+	"Purge", "Reset", "Undelete", "Execute", "Run",
+}
+
+// Classifier classifies methods. Sources are consulted in order: configured patterns,
+// the idempotency_level method option in the proto descriptor, then naming heuristics.
+type Classifier struct {
+	exact    map[string]Kind
+	prefixes []string // Sorted longest first
+	byPrefix map[string]Kind
+
+	protoOptions  bool
+	readPrefixes  []string
+	writePrefixes []string
+}
+
+// Option configures a Classifier
+type Option func(*Classifier)
+
+// WithReads marks method patterns as read-only. Patterns are exact methods
+// ("/pkg.Service/Method"), prefixes ending in "*" ("/pkg.Service/*") or "*".
+func WithReads(patterns ...string) Option {
+	return func(c *Classifier) {
+		for _, pattern := range patterns {
+			c.set(pattern, Read)
+		}
+	}
+}
+
+// WithWrites marks method patterns as mutating
+func WithWrites(patterns ...string) Option {
+	return func(c *Classifier) {
+		for _, pattern := range patterns {
+			c.set(pattern, Write)
+		}
+	}
+}
+
+// WithNamingHeuristics replaces the method name prefixes used to guess the kind
+// Default: DefaultReadPrefixes and DefaultWritePrefixes
+func WithNamingHeuristics(readPrefixes, writePrefixes []string) Option {
+	return func(c *Classifier) {
+		c.readPrefixes = readPrefixes
+		c.writePrefixes = writePrefixes
+	}
+}
+
+// WithoutNamingHeuristics only uses configured patterns and proto options
+func WithoutNamingHeuristics() Option {
+	return func(c *Classifier) {
+		c.readPrefixes = nil
+		c.writePrefixes = nil
+	}
+}
+
+// WithoutProtoOptions ignores the idempotency_level option of registered proto services
+func WithoutProtoOptions() Option {
+	return func(c *Classifier) {
+		c.protoOptions = false
+	}
+}
+
+// New creates a classifier.
+//
+// Example usage:
+//
+//	classifier := classify.New(
+//	    classify.WithReads("/shop.Orders/Preview"),
+//	    classify.WithWrites("/shop.Orders/GetOrCreateCart"),
+//	)
+//	retry := middleware.NewRetry(middleware.WithRetryClassifier(classifier))
+func New(opts ...Option) *Classifier {
+	c := &Classifier{
+		exact:         make(map[string]Kind),
+		byPrefix:      make(map[string]Kind),
+		protoOptions:  true,
+		readPrefixes:  DefaultReadPrefixes,
+		writePrefixes: DefaultWritePrefixes,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	sort.Slice(c.prefixes, func(i, j int) bool {
+		return len(c.prefixes[i]) > len(c.prefixes[j])
+	})
+
+	return c
+}
+
+// set records a configured pattern
+func (c *Classifier) set(pattern string, kind Kind) {
+	if !strings.HasSuffix(pattern, "*") {
+		c.exact[pattern] = kind
+		return
+	}
+
+	prefix := strings.TrimSuffix(pattern, "*")
+	if _, ok := c.byPrefix[prefix]; !ok {
+		c.prefixes = append(c.prefixes, prefix)
+	}
+	c.byPrefix[prefix] = kind
+}
+
+// Classify returns the kind of a full method name such as "/pkg.Service/Method"
+func (c *Classifier) Classify(fullMethod string) Kind {
+	if kind, ok := c.exact[fullMethod]; ok {
+		return kind
+	}
+	for _, prefix := range c.prefixes {
+		if strings.HasPrefix(fullMethod, prefix) {
+			return c.byPrefix[prefix]
+		}
+	}
+
+	service, method := splitMethod(fullMethod)
+
+	if c.protoOptions && service != "" {
+		if kind := fromProtoOptions(service, method); kind != Unknown {
+			return kind
+		}
+	}
+
+	if hasWordPrefix(method, c.readPrefixes) {
+		return Read
+	}
+	if hasWordPrefix(method, c.writePrefixes) {
+		return Write
+	}
+	return Unknown
+}
+
+// IsRead reports whether a method is known to be read-only
+func (c *Classifier) IsRead(fullMethod string) bool {
+	return c.Classify(fullMethod) == Read
+}
+
+// IsWrite reports whether a method may mutate state; unknown methods count as writes
+func (c *Classifier) IsWrite(fullMethod string) bool {
+	return c.Classify(fullMethod) != Read
+}
+
+// fromProtoOptions reads the idempotency_level option of a registered method:
+// NO_SIDE_EFFECTS is a read, IDEMPOTENT is an (idempotent) write
+func fromProtoOptions(service, method string) Kind {
+	desc, err := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(service + "." + method))
+	if err != nil {
+		return Unknown
+	}
+	md, ok := desc.(protoreflect.MethodDescriptor)
+	if !ok {
+		return Unknown
+	}
+	opts, ok := md.Options().(*descriptorpb.MethodOptions)
+	if !ok || opts == nil {
+		return Unknown
+	}
+
+	switch opts.GetIdempotencyLevel() {
+	case descriptorpb.MethodOptions_NO_SIDE_EFFECTS:
+		return Read
+	case descriptorpb.MethodOptions_IDEMPOTENT:
+		return Write
+	}
+	return Unknown
+}
+
+// splitMethod splits "/pkg.Service/Method" into service and method names
+func splitMethod(fullMethod string) (string, string) {
+	parts := strings.SplitN(strings.TrimPrefix(fullMethod, "/"), "/", 2)
+	if len(parts) != 2 {
+		return "", fullMethod
+	}
+	return parts[0], parts[1]
+}
+
+// hasWordPrefix reports whether name starts with one of the prefixes as a whole word,
+// so "Get" matches "Get" and "GetOrder" but not "Getaway"
+func hasWordPrefix(name string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		rest := name[len(prefix):]
+		if rest == "" || unicode.IsUpper(rune(rest[0])) || unicode.IsDigit(rune(rest[0])) || rest[0] == '_' {
+			return true
+		}
+	}
+	return false
+}
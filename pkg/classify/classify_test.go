@@ -0,0 +1,50 @@
+package classify
+
+import "testing"
+
+func TestClassifier_Classify(t *testing.T) {
+	classifier := New(
+		WithReads("/shop.Orders/Preview", "/shop.Reports/*"),
+		WithWrites("/shop.Orders/GetOrCreateCart", "/shop.Reports/Export*"),
+	)
+
+	tests := []struct {
+		method string
+		want   Kind
+	}{
+		{"/shop.Orders/GetOrder", Read},
+		{"/shop.Orders/ListOrders", Read},
+		{"/shop.Orders/Get", Read},
+		{"/shop.Orders/CreateOrder", Write},
+		{"/shop.Orders/Delete_v2", Write},
+		{"/shop.Orders/Getaway", Unknown},
+		{"/shop.Orders/Preview", Read},
+		{"/shop.Orders/GetOrCreateCart", Write},
+		{"/shop.Reports/Summarize", Read},
+		{"/shop.Reports/ExportCSV", Write}, // The longest pattern wins
+		{"Reindex", Unknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			if got := classifier.Classify(tt.method); got != tt.want {
+				t.Errorf("Classify() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if !classifier.IsWrite("/shop.Orders/Getaway") || classifier.IsRead("/shop.Orders/Getaway") {
+		t.Error("Expected unknown methods to count as writes")
+	}
+}
+
+func TestClassifier_NamingHeuristics(t *testing.T) {
+	custom := New(WithNamingHeuristics([]string{"Peek"}, []string{"Poke"}))
+	if custom.Classify("/svc.S/PeekQueue") != Read || custom.Classify("/svc.S/PokeQueue") != Write || custom.Classify("/svc.S/GetQueue") != Unknown {
+		t.Error("Expected the custom prefixes to replace the defaults")
+	}
+
+	patternsOnly := New(WithoutNamingHeuristics(), WithReads("/svc.S/Stats"))
+	if patternsOnly.Classify("/svc.S/GetQueue") != Unknown || patternsOnly.Classify("/svc.S/Stats") != Read {
+		t.Error("Expected only the configured patterns to classify methods")
+	}
+}
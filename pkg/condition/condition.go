@@ -0,0 +1,134 @@
+// Package condition provides a small expression language for request conditions.
+//
+// Expressions use a subset of CEL (Common Expression Language) syntax and are compiled
+// once, when configuration is loaded, then evaluated against the variables of each
+// request:
+//
+//	method      string  full method name, e.g. "/shop.Orders/Create"
+//	service     string  service name, e.g. "shop.Orders"
+//	rpc         string  method name, e.g. "Create"
+//	metadata    map     incoming metadata, lowercase keys, first value of each header
+//	principal   map     user_id, client_id, subject, roles, scopes, authenticated
+//	peer        map     address
+//	geo         map     country, asn, as_org, known
+//	request     map     request message fields by proto field name (protoreflect)
+//
+// Supported syntax: literals (int, double, string, bool, null, lists, maps), field
+// selection (a.b), indexing (a["b"], a[0]), the operators ! - * / % + < <= > >= == != in
+// && || ?: and the functions size, has, int, double, string and the string methods
+// startsWith, endsWith, contains, matches and size.
+//
+// Example conditions:
+//
+//	principal.authenticated && "admin" in principal.roles
+//	has(metadata["x-tenant"]) && metadata["x-tenant"].startsWith("beta-")
+//	request.page_size > 100 || service == "shop.Reports"
+//	geo.country in ["US", "CA"] && geo.asn != 64500
+//
+// As in CEL, int arithmetic that leaves the int64 range (including int(x) of a double
+// out of range) is an error rather than wrapping around, division and modulus by zero
+// are errors, && and || ignore an error on the side that does not decide the result, and
+// double division by zero yields ±Inf. The engine differs from CEL in that:
+//
+//   - expressions are not type-checked when compiled; type errors surface when evaluated
+//   - there are no uint, bytes, timestamp or duration types and no macros (all, exists,
+//     exists_one, map, filter); uint values in Vars become ints, or doubles above the
+//     int64 range
+//   - arithmetic and comparisons mix int and double, yielding a double
+//   - the lowest int cannot be written as a literal: use -9223372036854775807 - 1
+//   - double literals have no exponent (1e3) or hex (0x10) forms; use double("1e3")
+//
+// An Evaluator collects the variables of a request; its options decide how principal and
+// geo are filled. Use an implementation of Condition backed by cel-go where full CEL is
+// required.
+package condition
+
+import (
+	"fmt"
+)
+
+// Vars are the variables an expression is evaluated against
+type Vars map[string]interface{}
+
+// Condition decides whether a request matches. Expr implements it; other
+// implementations (e.g. backed by cel-go) can be used wherever a Condition is accepted.
+type Condition interface {
+	Eval(vars Vars) (bool, error)
+}
+
+// Func adapts a function to the Condition interface
+type Func func(vars Vars) (bool, error)
+
+// Eval calls f(vars)
+func (f Func) Eval(vars Vars) (bool, error) {
+	return f(vars)
+}
+
+// Expr is a compiled expression
+type Expr struct {
+	source string
+	root   node
+	refs   map[string]bool
+}
+
+// standardVars lists the variables every expression may reference
+var standardVars = map[string]bool{
+	"method":    true,
+	"service":   true,
+	"rpc":       true,
+	"metadata":  true,
+	"principal": true,
+	"peer":      true,
+	"geo":       true,
+	"request":   true,
+}
+
+// Compile parses an expression and checks that it only references standard variables
+// and known functions. Compile once and reuse the result for every request.
+func Compile(source string) (*Expr, error) {
+	p := &parser{lexer: newLexer(source)}
+	root, err := p.parse()
+	if err != nil {
+		return nil, fmt.Errorf("condition: %q: %w", source, err)
+	}
+
+	refs := make(map[string]bool)
+	if err := check(root, refs); err != nil {
+		return nil, fmt.Errorf("condition: %q: %w", source, err)
+	}
+
+	return &Expr{source: source, root: root, refs: refs}, nil
+}
+
+// MustCompile is like Compile but panics if the expression is invalid
+func MustCompile(source string) *Expr {
+	e, err := Compile(source)
+	if err != nil {
+		panic(err)
+	}
+	return e
+}
+
+// String returns the expression source
+func (e *Expr) String() string {
+	return e.source
+}
+
+// References reports whether the expression uses a top-level variable
+func (e *Expr) References(name string) bool {
+	return e.refs[name]
+}
+
+// Eval evaluates the expression. The result must be a bool.
+func (e *Expr) Eval(vars Vars) (bool, error) {
+	v, err := eval(e.root, vars)
+	if err != nil {
+		return false, fmt.Errorf("condition: %q: %w", e.source, err)
+	}
+
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("condition: %q: result is %s, not bool", e.source, typeName(v))
+	}
+	return b, nil
+}
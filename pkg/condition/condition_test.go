@@ -0,0 +1,173 @@
+package condition
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestParse_Precedence(t *testing.T) {
+	tests := []string{
+		`1 + 2 * 3 == 7`,
+		`(1 + 2) * 3 == 9`,
+		`10 - 4 - 3 == 3`,
+		`-2 * 3 == -6 && --2 == 2`,
+		`7 / 2 == 3 && 7 % 2 == 1 && 7.0 / 2 == 3.5`,
+		`!false && !!true`,
+		`true || false && false`,
+		`1 < 2 == true`,
+		`(false ? 1 : true ? 2 : 3) == 2 && (true ? false : true) == false`,
+		`"b" in ["a", "b"] && !("c" in {"a": 1})`,
+		`[1, [2, 3]][1][0] == 2 && {"a": {"b": "c"}}.a.b == "c"`,
+		`"a\"b\n".size() == 4 && 'it\'s' == "it's"`,
+		`1500.0 == 1500 && 0.25 + 0.25 == 0.5`,
+	}
+
+	for _, source := range tests {
+		t.Run(source, func(t *testing.T) {
+			expr, err := Compile(source)
+			if err != nil {
+				t.Fatalf("Compile() error = %v", err)
+			}
+			if got, err := expr.Eval(nil); err != nil || !got {
+				t.Errorf("Eval() = %v, %v, want true", got, err)
+			}
+		})
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		source  string
+		wantErr string
+	}{
+		{`9223372036854775808 > 0`, "invalid number"},
+		{`1e3 > 0`, `unexpected "e3"`},
+		{`1 +`, "unexpected end of expression"},
+		{`[1, 2`, "expected"},
+		{`{"a" 1}`, "expected"},
+		{`method.`, "expected"},
+		{`1 ? 2`, "expected"},
+		{`method == "x" extra`, "unexpected"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			_, err := Compile(tt.source)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Compile() error = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestEval_IntOverflow(t *testing.T) {
+	overflows := []string{
+		`9223372036854775807 + 1 > 0`,
+		`-9223372036854775807 - 2 < 0`,
+		`(-9223372036854775807 - 1) / -1 > 0`,
+		`(-9223372036854775807 - 1) % -1 == 0`,
+		`-(-9223372036854775807 - 1) > 0`,
+		`4611686018427387904 * 2 > 0`,
+		`-1 * (-9223372036854775807 - 1) > 0`,
+		`3037000500 * 3037000500 > 0`,
+		`int(9223372036854775807.0) > 0`,
+		`int(-10000000000000000000.0) < 0`,
+		`int(double("NaN")) == 0`,
+	}
+	for _, source := range overflows {
+		t.Run(source, func(t *testing.T) {
+			_, err := MustCompile(source).Eval(nil)
+			if !errors.Is(err, errOverflow) {
+				t.Errorf("Eval() error = %v, want integer overflow", err)
+			}
+		})
+	}
+
+	// Results at the edges of the range are fine
+	for _, source := range []string{
+		`9223372036854775806 + 1 == 9223372036854775807`,
+		`-9223372036854775807 - 1 < 0`,
+		`(-9223372036854775807 - 1) / 1 < 0`,
+		`3037000499 * 3037000499 == 9223372030926249001`,
+		`-4611686018427387904 * 2 < 0`,
+		`int(-9223372036854775808.0) < 0 && int(-2.9) == -2`,
+	} {
+		if got, err := MustCompile(source).Eval(nil); err != nil || !got {
+			t.Errorf("Eval(%s) = %v, %v, want true", source, got, err)
+		}
+	}
+}
+
+func TestEval_Errors(t *testing.T) {
+	tests := []struct {
+		source  string
+		wantErr string
+	}{
+		{`1 / 0 == 0`, "division by zero"},
+		{`1 % 0 == 0`, "division by zero"},
+		{`1.5 % 1.0 == 0.5`, "not defined for double and double"},
+		{`"a" < 1`, "cannot compare"},
+		{`[1][1] == 1`, "out of range"},
+		{`1 ? true : false`, "not bool"},
+		{`1 + 1`, "not bool"},
+		{`method == "x"`, `variable "method" is not set`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			_, err := MustCompile(tt.source).Eval(nil)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Eval() error = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	if got, err := MustCompile(`1.0 / 0 > double("1e308")`).Eval(nil); err != nil || !got {
+		t.Errorf("Expected double division by zero to yield +Inf, got %v, %v", got, err)
+	}
+}
+
+func TestEval_Normalize(t *testing.T) {
+	vars := Vars{
+		"request": map[string]interface{}{
+			"small": uint64(7),
+			"huge":  uint64(math.MaxUint64),
+			"count": int32(-3),
+			"ratio": float32(0.5),
+			"tags":  []string{"a", "b"},
+			"ids":   []int{1, 2},
+		},
+	}
+
+	expr := MustCompile(`request.small == 7 && request.huge > 9223372036854775807 && request.count < 0 && ` +
+		`request.ratio == 0.5 && "b" in request.tags && request.ids[1] == 2`)
+	if got, err := expr.Eval(vars); err != nil || !got {
+		t.Errorf("Eval() = %v, %v, want true", got, err)
+	}
+	if !expr.References("request") || expr.References("method") {
+		t.Error("Unexpected references")
+	}
+}
+
+func TestEvaluator_Options(t *testing.T) {
+	expr := MustCompile(`principal.authenticated && "admin" in principal.roles && geo.country == "JP"`)
+
+	if got, err := NewEvaluator().EvalRequest(context.Background(), expr, "/svc/M", nil); err != nil || got {
+		t.Errorf("Expected an evaluator without options to leave principal and geo empty, got %v, %v", got, err)
+	}
+
+	evaluator := NewEvaluator(
+		WithPrincipalFunc(func(ctx context.Context) (Principal, bool) {
+			return Principal{UserID: "u1", Roles: []string{"admin"}}, true
+		}),
+		WithGeoFunc(func(ctx context.Context) (Geo, bool) {
+			return Geo{Country: "JP"}, true
+		}),
+	)
+	if got, err := evaluator.EvalRequest(context.Background(), expr, "/svc/M", nil); err != nil || !got {
+		t.Errorf("EvalRequest() = %v, %v, want true", got, err)
+	}
+}
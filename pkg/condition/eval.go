@@ -0,0 +1,629 @@
+package condition
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// errOverflow is returned when int arithmetic leaves the int64 range, as in CEL
+var errOverflow = errors.New("integer overflow")
+
+// eval evaluates a node. Values are normalized to int64, float64, string, bool, nil,
+// []interface{} and map[string]interface{}.
+func eval(n node, vars Vars) (interface{}, error) {
+	switch n := n.(type) {
+	case *literalNode:
+		return n.value, nil
+
+	case *identNode:
+		v, ok := vars[n.name]
+		if !ok {
+			return nil, fmt.Errorf("variable %q is not set", n.name)
+		}
+		return normalize(v), nil
+
+	case *selectNode:
+		operand, err := eval(n.operand, vars)
+		if err != nil {
+			return nil, err
+		}
+		v, ok, err := lookup(operand, n.field)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, fmt.Errorf("no such key %q", n.field)
+		}
+		return v, nil
+
+	case *indexNode:
+		operand, index, err := evalPair(n.operand, n.index, vars)
+		if err != nil {
+			return nil, err
+		}
+		v, ok, err := lookup(operand, index)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, fmt.Errorf("no such key %v", formatValue(index))
+		}
+		return v, nil
+
+	case *hasNode:
+		var operand, key interface{}
+		var err error
+		switch target := n.operand.(type) {
+		case *selectNode:
+			operand, err = eval(target.operand, vars)
+			key = target.field
+		case *indexNode:
+			operand, key, err = evalPair(target.operand, target.index, vars)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if operand == nil {
+			return false, nil
+		}
+		_, ok, err := lookup(operand, key)
+		if err != nil {
+			return nil, err
+		}
+		return ok, nil
+
+	case *callNode:
+		return evalCall(n, vars)
+
+	case *unaryNode:
+		operand, err := eval(n.operand, vars)
+		if err != nil {
+			return nil, err
+		}
+		return evalUnary(n.op, operand)
+
+	case *binaryNode:
+		switch n.op {
+		case "&&":
+			return evalLogical(n, vars, false)
+		case "||":
+			return evalLogical(n, vars, true)
+		}
+		left, right, err := evalPair(n.left, n.right, vars)
+		if err != nil {
+			return nil, err
+		}
+		return evalBinary(n.op, left, right)
+
+	case *ternaryNode:
+		cond, err := eval(n.cond, vars)
+		if err != nil {
+			return nil, err
+		}
+		b, ok := cond.(bool)
+		if !ok {
+			return nil, fmt.Errorf("condition of ?: is %s, not bool", typeName(cond))
+		}
+		if b {
+			return eval(n.then, vars)
+		}
+		return eval(n.otherwise, vars)
+
+	case *listNode:
+		list := make([]interface{}, len(n.elems))
+		for i, elem := range n.elems {
+			v, err := eval(elem, vars)
+			if err != nil {
+				return nil, err
+			}
+			list[i] = v
+		}
+		return list, nil
+
+	case *mapNode:
+		m := make(map[string]interface{}, len(n.keys))
+		for i := range n.keys {
+			key, value, err := evalPair(n.keys[i], n.values[i], vars)
+			if err != nil {
+				return nil, err
+			}
+			s, ok := key.(string)
+			if !ok {
+				return nil, fmt.Errorf("map keys must be strings, got %s", typeName(key))
+			}
+			m[s] = value
+		}
+		return m, nil
+	}
+
+	return nil, fmt.Errorf("unsupported expression node %T", n)
+}
+
+// evalPair evaluates two nodes in order
+func evalPair(a, b node, vars Vars) (interface{}, interface{}, error) {
+	left, err := eval(a, vars)
+	if err != nil {
+		return nil, nil, err
+	}
+	right, err := eval(b, vars)
+	if err != nil {
+		return nil, nil, err
+	}
+	return left, right, nil
+}
+
+// evalLogical evaluates && (short = false) and || (short = true) with CEL semantics:
+// an operand that decides the result wins even if the other operand fails
+func evalLogical(n *binaryNode, vars Vars, short bool) (interface{}, error) {
+	left, leftErr := evalBool(n.left, vars)
+	if leftErr == nil && left == short {
+		return short, nil
+	}
+
+	right, rightErr := evalBool(n.right, vars)
+	if rightErr == nil && right == short {
+		return short, nil
+	}
+
+	if leftErr != nil {
+		return nil, leftErr
+	}
+	if rightErr != nil {
+		return nil, rightErr
+	}
+	return !short, nil
+}
+
+// evalBool evaluates a node that must produce a bool
+func evalBool(n node, vars Vars) (bool, error) {
+	v, err := eval(n, vars)
+	if err != nil {
+		return false, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("expected bool, got %s", typeName(v))
+	}
+	return b, nil
+}
+
+// evalUnary applies ! or unary -
+func evalUnary(op string, v interface{}) (interface{}, error) {
+	switch op {
+	case "!":
+		if b, ok := v.(bool); ok {
+			return !b, nil
+		}
+	case "-":
+		switch x := v.(type) {
+		case int64:
+			if x == math.MinInt64 {
+				return nil, errOverflow
+			}
+			return -x, nil
+		case float64:
+			return -x, nil
+		}
+	}
+	return nil, fmt.Errorf("operator %s is not defined for %s", op, typeName(v))
+}
+
+// evalBinary applies a non-logical binary operator
+func evalBinary(op string, left, right interface{}) (interface{}, error) {
+	switch op {
+	case "==":
+		return equal(left, right), nil
+	case "!=":
+		return !equal(left, right), nil
+	case "in":
+		return contains(right, left)
+	case "<", "<=", ">", ">=":
+		c, err := compare(left, right)
+		if err != nil {
+			return nil, fmt.Errorf("operator %s: %w", op, err)
+		}
+		switch op {
+		case "<":
+			return c < 0, nil
+		case "<=":
+			return c <= 0, nil
+		case ">":
+			return c > 0, nil
+		default:
+			return c >= 0, nil
+		}
+	}
+
+	if op == "+" {
+		if l, ok := left.(string); ok {
+			if r, ok := right.(string); ok {
+				return l + r, nil
+			}
+		}
+		if l, ok := left.([]interface{}); ok {
+			if r, ok := right.([]interface{}); ok {
+				return append(append([]interface{}{}, l...), r...), nil
+			}
+		}
+	}
+
+	return arithmetic(op, left, right)
+}
+
+// arithmetic applies + - * / % to numbers; mixing int and double yields a double. Int
+// results outside the int64 range are errors rather than wrapping around.
+func arithmetic(op string, left, right interface{}) (interface{}, error) {
+	li, lInt := left.(int64)
+	ri, rInt := right.(int64)
+	if lInt && rInt {
+		return intArithmetic(op, li, ri)
+	}
+
+	lf, lok := toFloat(left)
+	rf, rok := toFloat(right)
+	if lok && rok {
+		switch op {
+		case "+":
+			return lf + rf, nil
+		case "-":
+			return lf - rf, nil
+		case "*":
+			return lf * rf, nil
+		case "/":
+			return lf / rf, nil
+		}
+	}
+
+	return nil, fmt.Errorf("operator %s is not defined for %s and %s", op, typeName(left), typeName(right))
+}
+
+// intArithmetic applies + - * / % to ints, checking for overflow
+func intArithmetic(op string, l, r int64) (interface{}, error) {
+	switch op {
+	case "+":
+		sum := l + r
+		if (sum > l) != (r > 0) {
+			return nil, errOverflow
+		}
+		return sum, nil
+	case "-":
+		diff := l - r
+		if (diff < l) != (r > 0) {
+			return nil, errOverflow
+		}
+		return diff, nil
+	case "*":
+		if l == 0 || r == 0 {
+			return int64(0), nil
+		}
+		product := l * r
+		if (l == -1 && r == math.MinInt64) || (r == -1 && l == math.MinInt64) || product/r != l {
+			return nil, errOverflow
+		}
+		return product, nil
+	case "/", "%":
+		if r == 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		if l == math.MinInt64 && r == -1 {
+			return nil, errOverflow
+		}
+		if op == "/" {
+			return l / r, nil
+		}
+		return l % r, nil
+	}
+	return nil, fmt.Errorf("operator %s is not defined for int and int", op)
+}
+
+// equal compares values; numbers compare by value regardless of int or double
+func equal(left, right interface{}) bool {
+	if lf, ok := toFloat(left); ok {
+		if rf, ok := toFloat(right); ok {
+			return lf == rf
+		}
+	}
+
+	switch l := left.(type) {
+	case []interface{}:
+		r, ok := right.([]interface{})
+		if !ok || len(l) != len(r) {
+			return false
+		}
+		for i := range l {
+			if !equal(l[i], r[i]) {
+				return false
+			}
+		}
+		return true
+
+	case map[string]interface{}:
+		r, ok := right.(map[string]interface{})
+		if !ok || len(l) != len(r) {
+			return false
+		}
+		for k, v := range l {
+			rv, ok := r[k]
+			if !ok || !equal(v, rv) {
+				return false
+			}
+		}
+		return true
+	}
+
+	return left == right
+}
+
+// compare orders two numbers or two strings
+func compare(left, right interface{}) (int, error) {
+	if l, ok := left.(string); ok {
+		if r, ok := right.(string); ok {
+			return strings.Compare(l, r), nil
+		}
+	}
+
+	lf, lok := toFloat(left)
+	rf, rok := toFloat(right)
+	if lok && rok {
+		switch {
+		case lf < rf:
+			return -1, nil
+		case lf > rf:
+			return 1, nil
+		}
+		return 0, nil
+	}
+
+	return 0, fmt.Errorf("cannot compare %s and %s", typeName(left), typeName(right))
+}
+
+// contains implements "x in container" for lists (element) and maps (key)
+func contains(container, elem interface{}) (bool, error) {
+	switch c := container.(type) {
+	case []interface{}:
+		for _, v := range c {
+			if equal(v, elem) {
+				return true, nil
+			}
+		}
+		return false, nil
+
+	case map[string]interface{}:
+		key, ok := elem.(string)
+		if !ok {
+			return false, nil
+		}
+		_, found := c[key]
+		return found, nil
+	}
+
+	return false, fmt.Errorf("operator in is not defined for %s", typeName(container))
+}
+
+// lookup returns a map entry or list element
+func lookup(container, key interface{}) (interface{}, bool, error) {
+	switch c := container.(type) {
+	case map[string]interface{}:
+		s, ok := key.(string)
+		if !ok {
+			return nil, false, fmt.Errorf("map keys must be strings, got %s", typeName(key))
+		}
+		v, found := c[s]
+		return normalize(v), found, nil
+
+	case []interface{}:
+		i, ok := key.(int64)
+		if !ok {
+			return nil, false, fmt.Errorf("list index must be int, got %s", typeName(key))
+		}
+		if i < 0 || i >= int64(len(c)) {
+			return nil, false, fmt.Errorf("index %d out of range [0, %d)", i, len(c))
+		}
+		return normalize(c[i]), true, nil
+	}
+
+	return nil, false, fmt.Errorf("cannot select %v from %s", formatValue(key), typeName(container))
+}
+
+// evalCall evaluates a function or method call
+func evalCall(n *callNode, vars Vars) (interface{}, error) {
+	args := n.args
+	if n.target != nil {
+		args = append([]node{n.target}, args...)
+	}
+
+	values := make([]interface{}, len(args))
+	for i, arg := range args {
+		v, err := eval(arg, vars)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+	}
+
+	switch n.name {
+	case "size":
+		switch v := values[0].(type) {
+		case string:
+			return int64(len([]rune(v))), nil
+		case []interface{}:
+			return int64(len(v)), nil
+		case map[string]interface{}:
+			return int64(len(v)), nil
+		}
+
+	case "int":
+		switch v := values[0].(type) {
+		case int64:
+			return v, nil
+		case float64:
+			// Truncates toward zero; the range check also rejects NaN
+			if !(v >= -(1<<63) && v < 1<<63) {
+				return nil, fmt.Errorf("int(%s): %w", formatValue(v), errOverflow)
+			}
+			return int64(v), nil
+		case string:
+			i, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("int(%q): invalid integer", v)
+			}
+			return i, nil
+		}
+
+	case "double":
+		switch v := values[0].(type) {
+		case int64:
+			return float64(v), nil
+		case float64:
+			return v, nil
+		case string:
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return nil, fmt.Errorf("double(%q): invalid number", v)
+			}
+			return f, nil
+		}
+
+	case "string":
+		switch v := values[0].(type) {
+		case string:
+			return v, nil
+		case int64, float64, bool:
+			return formatValue(v), nil
+		}
+
+	case "startsWith", "endsWith", "contains", "matches":
+		s, ok := values[0].(string)
+		if !ok {
+			break
+		}
+		arg, ok := values[1].(string)
+		if !ok {
+			return nil, fmt.Errorf("%s() argument must be a string, got %s", n.name, typeName(values[1]))
+		}
+
+		switch n.name {
+		case "startsWith":
+			return strings.HasPrefix(s, arg), nil
+		case "endsWith":
+			return strings.HasSuffix(s, arg), nil
+		case "contains":
+			return strings.Contains(s, arg), nil
+		}
+
+		re := n.re
+		if re == nil {
+			var err error
+			if re, err = regexp.Compile(arg); err != nil {
+				return nil, fmt.Errorf("invalid matches() pattern: %w", err)
+			}
+		}
+		return re.MatchString(s), nil
+	}
+
+	return nil, fmt.Errorf("%s() is not defined for %s", n.name, typeName(values[0]))
+}
+
+// toFloat converts a number to float64
+func toFloat(v interface{}) (float64, bool) {
+	switch x := v.(type) {
+	case int64:
+		return float64(x), true
+	case float64:
+		return x, true
+	}
+	return 0, false
+}
+
+// normalize converts Go values supplied in Vars to the expression value types
+func normalize(v interface{}) interface{} {
+	switch x := v.(type) {
+	case nil, int64, float64, string, bool, []interface{}, map[string]interface{}:
+		return v
+	case Vars:
+		return map[string]interface{}(x)
+	case int:
+		return int64(x)
+	case int32:
+		return int64(x)
+	case uint32:
+		return int64(x)
+	case uint64:
+		if x > math.MaxInt64 {
+			return float64(x) // Would wrap around as an int
+		}
+		return int64(x)
+	case float32:
+		return float64(x)
+	case []string:
+		list := make([]interface{}, len(x))
+		for i, s := range x {
+			list[i] = s
+		}
+		return list
+	case map[string]string:
+		m := make(map[string]interface{}, len(x))
+		for k, s := range x {
+			m[k] = s
+		}
+		return m
+	}
+
+	// Fall back to reflection for other slices and string-keyed maps
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		list := make([]interface{}, rv.Len())
+		for i := range list {
+			list[i] = normalize(rv.Index(i).Interface())
+		}
+		return list
+	case reflect.Map:
+		if rv.Type().Key().Kind() == reflect.String {
+			m := make(map[string]interface{}, rv.Len())
+			iter := rv.MapRange()
+			for iter.Next() {
+				m[iter.Key().String()] = normalize(iter.Value().Interface())
+			}
+			return m
+		}
+	}
+	return v
+}
+
+// typeName names a value's type in expression terms
+func typeName(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case int64:
+		return "int"
+	case float64:
+		return "double"
+	case string:
+		return "string"
+	case bool:
+		return "bool"
+	case []interface{}:
+		return "list"
+	case map[string]interface{}:
+		return "map"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// formatValue renders a value for error messages and string()
+func formatValue(v interface{}) string {
+	switch x := v.(type) {
+	case string:
+		return strconv.Quote(x)
+	case float64:
+		return strconv.FormatFloat(x, 'g', -1, 64)
+	}
+	return fmt.Sprint(v)
+}
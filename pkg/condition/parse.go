@@ -0,0 +1,657 @@
+package condition
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// tokenKind classifies lexer tokens
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenInt
+	tokenFloat
+	tokenString
+	tokenPunct
+)
+
+// token is a single lexical token
+type token struct {
+	kind  tokenKind
+	text  string
+	value interface{} // Parsed value of literals
+	pos   int
+}
+
+// lexer splits an expression into tokens
+type lexer struct {
+	src string
+	pos int
+}
+
+func newLexer(src string) *lexer {
+	return &lexer{src: src}
+}
+
+// punctuation is ordered so that longer operators are matched first
+var punctuation = []string{
+	"&&", "||", "==", "!=", "<=", ">=",
+	"!", "<", ">", "+", "-", "*", "/", "%", "?", ":", ".", ",", "(", ")", "[", "]", "{", "}",
+}
+
+// next returns the next token
+func (l *lexer) next() (token, error) {
+	for l.pos < len(l.src) && strings.ContainsRune(" \t\r\n", rune(l.src[l.pos])) {
+		l.pos++
+	}
+	if l.pos >= len(l.src) {
+		return token{kind: tokenEOF, pos: l.pos}, nil
+	}
+
+	start := l.pos
+	c := l.src[l.pos]
+
+	switch {
+	case isIdentStart(c):
+		for l.pos < len(l.src) && isIdentPart(l.src[l.pos]) {
+			l.pos++
+		}
+		return token{kind: tokenIdent, text: l.src[start:l.pos], pos: start}, nil
+
+	case isDigit(c):
+		return l.number(start)
+
+	case c == '"' || c == '\'':
+		return l.str(start, c)
+	}
+
+	for _, p := range punctuation {
+		if strings.HasPrefix(l.src[l.pos:], p) {
+			l.pos += len(p)
+			return token{kind: tokenPunct, text: p, pos: start}, nil
+		}
+	}
+
+	return token{}, fmt.Errorf("unexpected character %q at offset %d", c, start)
+}
+
+// number lexes an int or double literal
+func (l *lexer) number(start int) (token, error) {
+	float := false
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		if isDigit(c) {
+			l.pos++
+			continue
+		}
+		if c == '.' && !float && l.pos+1 < len(l.src) && isDigit(l.src[l.pos+1]) {
+			float = true
+			l.pos++
+			continue
+		}
+		break
+	}
+
+	text := l.src[start:l.pos]
+	if float {
+		v, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return token{}, fmt.Errorf("invalid number %q at offset %d", text, start)
+		}
+		return token{kind: tokenFloat, text: text, value: v, pos: start}, nil
+	}
+
+	v, err := strconv.ParseInt(text, 10, 64)
+	if err != nil {
+		return token{}, fmt.Errorf("invalid number %q at offset %d", text, start)
+	}
+	return token{kind: tokenInt, text: text, value: v, pos: start}, nil
+}
+
+// str lexes a quoted string literal with backslash escapes
+func (l *lexer) str(start int, quote byte) (token, error) {
+	l.pos++ // Opening quote
+
+	var b strings.Builder
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		switch {
+		case c == quote:
+			l.pos++
+			return token{kind: tokenString, text: l.src[start:l.pos], value: b.String(), pos: start}, nil
+
+		case c == '\\' && l.pos+1 < len(l.src):
+			l.pos++
+			switch e := l.src[l.pos]; e {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case 'r':
+				b.WriteByte('\r')
+			default:
+				b.WriteByte(e)
+			}
+			l.pos++
+
+		default:
+			b.WriteByte(c)
+			l.pos++
+		}
+	}
+
+	return token{}, fmt.Errorf("unterminated string starting at offset %d", start)
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isIdentPart(c byte) bool {
+	return isIdentStart(c) || isDigit(c)
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// node is an expression tree node
+type node interface{}
+
+type (
+	literalNode struct{ value interface{} }
+	identNode   struct{ name string }
+	selectNode  struct {
+		operand node
+		field   string
+	}
+	indexNode struct {
+		operand node
+		index   node
+	}
+	callNode struct {
+		name   string
+		target node // Receiver for method calls, nil for global functions
+		args   []node
+		re     *regexp.Regexp // Precompiled pattern for matches() with a literal argument
+	}
+	hasNode   struct{ operand node }
+	unaryNode struct {
+		op      string
+		operand node
+	}
+	binaryNode struct {
+		op          string
+		left, right node
+	}
+	ternaryNode struct {
+		cond, then, otherwise node
+	}
+	listNode struct{ elems []node }
+	mapNode  struct{ keys, values []node }
+)
+
+// parser is a recursive descent parser, one method per precedence level
+type parser struct {
+	lexer *lexer
+	tok   token
+}
+
+// parse parses a complete expression
+func (p *parser) parse() (node, error) {
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	if p.tok.kind == tokenEOF {
+		return nil, fmt.Errorf("empty expression")
+	}
+
+	n, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	if p.tok.kind != tokenEOF {
+		return nil, p.unexpected()
+	}
+	return n, nil
+}
+
+func (p *parser) advance() error {
+	tok, err := p.lexer.next()
+	if err != nil {
+		return err
+	}
+	p.tok = tok
+	return nil
+}
+
+// accept consumes the current token if it is the given punctuation
+func (p *parser) accept(punct string) (bool, error) {
+	if p.tok.kind != tokenPunct || p.tok.text != punct {
+		return false, nil
+	}
+	return true, p.advance()
+}
+
+// expect consumes the given punctuation or fails
+func (p *parser) expect(punct string) error {
+	ok, err := p.accept(punct)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("expected %q at offset %d, found %s", punct, p.tok.pos, p.describe())
+	}
+	return nil
+}
+
+func (p *parser) unexpected() error {
+	return fmt.Errorf("unexpected %s at offset %d", p.describe(), p.tok.pos)
+}
+
+func (p *parser) describe() string {
+	if p.tok.kind == tokenEOF {
+		return "end of expression"
+	}
+	return strconv.Quote(p.tok.text)
+}
+
+// expr = or ["?" expr ":" expr]
+func (p *parser) expr() (node, error) {
+	cond, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+
+	if ok, err := p.accept("?"); err != nil || !ok {
+		return cond, err
+	}
+
+	then, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(":"); err != nil {
+		return nil, err
+	}
+	otherwise, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	return &ternaryNode{cond: cond, then: then, otherwise: otherwise}, nil
+}
+
+// binary parses a left-associative level of binary operators
+func (p *parser) binary(next func() (node, error), ops ...string) (node, error) {
+	left, err := next()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		op := ""
+		if p.tok.kind == tokenPunct || (p.tok.kind == tokenIdent && p.tok.text == "in") {
+			for _, candidate := range ops {
+				if p.tok.text == candidate {
+					op = candidate
+					break
+				}
+			}
+		}
+		if op == "" {
+			return left, nil
+		}
+
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		right, err := next()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: op, left: left, right: right}
+	}
+}
+
+func (p *parser) or() (node, error) {
+	return p.binary(p.and, "||")
+}
+
+func (p *parser) and() (node, error) {
+	return p.binary(p.relation, "&&")
+}
+
+func (p *parser) relation() (node, error) {
+	return p.binary(p.addition, "==", "!=", "<", "<=", ">", ">=", "in")
+}
+
+func (p *parser) addition() (node, error) {
+	return p.binary(p.multiplication, "+", "-")
+}
+
+func (p *parser) multiplication() (node, error) {
+	return p.binary(p.unary, "*", "/", "%")
+}
+
+// unary = ("!" | "-") unary | member
+func (p *parser) unary() (node, error) {
+	if p.tok.kind == tokenPunct && (p.tok.text == "!" || p.tok.text == "-") {
+		op := p.tok.text
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		operand, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryNode{op: op, operand: operand}, nil
+	}
+	return p.member()
+}
+
+// member = primary {"." ident ["(" args ")"] | "[" expr "]"}
+func (p *parser) member() (node, error) {
+	n, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		if ok, err := p.accept("."); err != nil {
+			return nil, err
+		} else if ok {
+			if p.tok.kind != tokenIdent {
+				return nil, fmt.Errorf("expected field name at offset %d, found %s", p.tok.pos, p.describe())
+			}
+			field := p.tok.text
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+
+			if ok, err := p.accept("("); err != nil {
+				return nil, err
+			} else if ok {
+				args, err := p.list(")")
+				if err != nil {
+					return nil, err
+				}
+				n = &callNode{name: field, target: n, args: args}
+				continue
+			}
+			n = &selectNode{operand: n, field: field}
+			continue
+		}
+
+		if ok, err := p.accept("["); err != nil {
+			return nil, err
+		} else if ok {
+			index, err := p.expr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			n = &indexNode{operand: n, index: index}
+			continue
+		}
+
+		return n, nil
+	}
+}
+
+// primary = literal | ident ["(" args ")"] | "(" expr ")" | "[" list "]" | "{" entries "}"
+func (p *parser) primary() (node, error) {
+	tok := p.tok
+
+	switch tok.kind {
+	case tokenInt, tokenFloat, tokenString:
+		return &literalNode{value: tok.value}, p.advance()
+
+	case tokenIdent:
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		switch tok.text {
+		case "true":
+			return &literalNode{value: true}, nil
+		case "false":
+			return &literalNode{value: false}, nil
+		case "null":
+			return &literalNode{value: nil}, nil
+		}
+
+		if ok, err := p.accept("("); err != nil {
+			return nil, err
+		} else if ok {
+			args, err := p.list(")")
+			if err != nil {
+				return nil, err
+			}
+			if tok.text == "has" {
+				if len(args) != 1 {
+					return nil, fmt.Errorf("has() takes exactly one argument")
+				}
+				switch args[0].(type) {
+				case *selectNode, *indexNode:
+				default:
+					return nil, fmt.Errorf("has() argument must be a field selection such as has(a.b) or has(a[\"b\"])")
+				}
+				return &hasNode{operand: args[0]}, nil
+			}
+			return &callNode{name: tok.text, args: args}, nil
+		}
+		return &identNode{name: tok.text}, nil
+
+	case tokenPunct:
+		switch tok.text {
+		case "(":
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			n, err := p.expr()
+			if err != nil {
+				return nil, err
+			}
+			return n, p.expect(")")
+
+		case "[":
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			elems, err := p.list("]")
+			if err != nil {
+				return nil, err
+			}
+			return &listNode{elems: elems}, nil
+
+		case "{":
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			return p.mapLiteral()
+		}
+	}
+
+	return nil, p.unexpected()
+}
+
+// list parses comma-separated expressions up to the closing punctuation
+func (p *parser) list(closing string) ([]node, error) {
+	var elems []node
+	if ok, err := p.accept(closing); err != nil || ok {
+		return elems, err
+	}
+
+	for {
+		elem, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		elems = append(elems, elem)
+
+		if ok, err := p.accept(","); err != nil {
+			return nil, err
+		} else if !ok {
+			return elems, p.expect(closing)
+		}
+	}
+}
+
+// mapLiteral parses "key: value" entries up to the closing brace
+func (p *parser) mapLiteral() (node, error) {
+	m := &mapNode{}
+	if ok, err := p.accept("}"); err != nil || ok {
+		return m, err
+	}
+
+	for {
+		key, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		value, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		m.keys = append(m.keys, key)
+		m.values = append(m.values, value)
+
+		if ok, err := p.accept(","); err != nil {
+			return nil, err
+		} else if !ok {
+			return m, p.expect("}")
+		}
+	}
+}
+
+// functionArity lists the supported functions and their argument counts. Method calls
+// count the receiver as the first argument.
+var functionArity = map[string]int{
+	"size":       1,
+	"int":        1,
+	"double":     1,
+	"string":     1,
+	"startsWith": 2,
+	"endsWith":   2,
+	"contains":   2,
+	"matches":    2,
+}
+
+// methodOnly lists functions that must be called on a receiver
+var methodOnly = map[string]bool{
+	"startsWith": true,
+	"endsWith":   true,
+	"contains":   true,
+}
+
+// check validates identifiers and function calls, records referenced variables and
+// precompiles literal regular expressions
+func check(n node, refs map[string]bool) error {
+	switch n := n.(type) {
+	case *literalNode:
+		return nil
+
+	case *identNode:
+		if !standardVars[n.name] {
+			return fmt.Errorf("undeclared variable %q (available: method, service, rpc, metadata, principal, peer, geo, request)", n.name)
+		}
+		refs[n.name] = true
+		return nil
+
+	case *selectNode:
+		return check(n.operand, refs)
+
+	case *indexNode:
+		if err := check(n.operand, refs); err != nil {
+			return err
+		}
+		return check(n.index, refs)
+
+	case *hasNode:
+		return check(n.operand, refs)
+
+	case *callNode:
+		arity, ok := functionArity[n.name]
+		if !ok {
+			return fmt.Errorf("unknown function %q", n.name)
+		}
+		if methodOnly[n.name] && n.target == nil {
+			return fmt.Errorf("%s() must be called as a method, e.g. s.%s(x)", n.name, n.name)
+		}
+
+		args := n.args
+		if n.target != nil {
+			args = append([]node{n.target}, args...)
+		}
+		if len(args) != arity {
+			return fmt.Errorf("%s() expects %d argument(s), got %d", n.name, arity, len(args))
+		}
+
+		for _, arg := range args {
+			if err := check(arg, refs); err != nil {
+				return err
+			}
+		}
+
+		if n.name == "matches" {
+			if lit, ok := args[1].(*literalNode); ok {
+				pattern, ok := lit.value.(string)
+				if !ok {
+					return fmt.Errorf("matches() pattern must be a string")
+				}
+				re, err := regexp.Compile(pattern)
+				if err != nil {
+					return fmt.Errorf("invalid matches() pattern: %w", err)
+				}
+				n.re = re
+			}
+		}
+		return nil
+
+	case *unaryNode:
+		return check(n.operand, refs)
+
+	case *binaryNode:
+		if err := check(n.left, refs); err != nil {
+			return err
+		}
+		return check(n.right, refs)
+
+	case *ternaryNode:
+		for _, child := range []node{n.cond, n.then, n.otherwise} {
+			if err := check(child, refs); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case *listNode:
+		for _, elem := range n.elems {
+			if err := check(elem, refs); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case *mapNode:
+		for i := range n.keys {
+			if err := check(n.keys[i], refs); err != nil {
+				return err
+			}
+			if err := check(n.values[i], refs); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	return fmt.Errorf("unsupported expression node %T", n)
+}
@@ -0,0 +1,230 @@
+package condition
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Principal is the authenticated caller exposed as the principal variable
+type Principal struct {
+	UserID   string
+	ClientID string
+	Subject  string // Client certificate SPIFFE ID or common name when mTLS is used
+	Roles    []string
+	Scopes   []string
+}
+
+// PrincipalFunc extracts the caller from a request context
+type PrincipalFunc func(ctx context.Context) (Principal, bool)
+
+// Geo is the caller location exposed as the geo variable
+type Geo struct {
+	Country string // ISO 3166-1 alpha-2 code
+	ASN     uint32
+	ASOrg   string
+}
+
+// GeoFunc extracts the caller location from a request context
+type GeoFunc func(ctx context.Context) (Geo, bool)
+
+// Evaluator evaluates conditions against the standard variables of requests. The
+// principal and geo variables are filled by the functions it is created with; without
+// them the caller is unauthenticated and its location unknown.
+type Evaluator struct {
+	principal PrincipalFunc
+	geo       GeoFunc
+}
+
+// EvaluatorOption configures an Evaluator
+type EvaluatorOption func(*Evaluator)
+
+// WithPrincipalFunc sets how the principal variable is filled, e.g. from the identity
+// stored by authentication middleware
+func WithPrincipalFunc(fn PrincipalFunc) EvaluatorOption {
+	return func(e *Evaluator) {
+		e.principal = fn
+	}
+}
+
+// WithGeoFunc sets how the geo variable is filled, e.g. from the location stored by
+// GeoIP middleware
+func WithGeoFunc(fn GeoFunc) EvaluatorOption {
+	return func(e *Evaluator) {
+		e.geo = fn
+	}
+}
+
+// NewEvaluator creates an evaluator. The middleware package provides one that reads
+// the identity stored by its Auth middleware (middleware.ConditionEvaluator).
+//
+// Example usage:
+//
+//	evaluator := condition.NewEvaluator(
+//	    condition.WithPrincipalFunc(func(ctx context.Context) (condition.Principal, bool) {
+//	        user, ok := myauth.FromContext(ctx)
+//	        return condition.Principal{UserID: user.ID, Roles: user.Roles}, ok
+//	    }),
+//	)
+//	matched, err := evaluator.EvalRequest(ctx, expr, info.FullMethod, req)
+func NewEvaluator(opts ...EvaluatorOption) *Evaluator {
+	e := &Evaluator{}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+// Vars collects the standard variables for a request. The request message is only
+// converted when includeRequest is set, since walking a large message is comparatively
+// expensive; EvalRequest decides this from the expression.
+func (e *Evaluator) Vars(ctx context.Context, fullMethod string, req interface{}, includeRequest bool) Vars {
+	vars := Vars{
+		"method":   fullMethod,
+		"service":  "",
+		"rpc":      "",
+		"metadata": map[string]interface{}{},
+		"peer":     map[string]interface{}{"address": ""},
+	}
+
+	if parts := strings.SplitN(strings.TrimPrefix(fullMethod, "/"), "/", 2); len(parts) == 2 {
+		vars["service"], vars["rpc"] = parts[0], parts[1]
+	}
+
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		headers := make(map[string]interface{}, len(md))
+		for key, values := range md {
+			if len(values) > 0 && !strings.HasSuffix(key, "-bin") {
+				headers[strings.ToLower(key)] = values[0]
+			}
+		}
+		vars["metadata"] = headers
+	}
+
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		vars["peer"] = map[string]interface{}{"address": p.Addr.String()}
+	}
+
+	vars["principal"] = principalVars(ctx, e.principal)
+	vars["geo"] = geoVars(ctx, e.geo)
+
+	if includeRequest {
+		if msg, ok := req.(proto.Message); ok {
+			vars["request"] = messageVars(msg.ProtoReflect())
+		} else {
+			vars["request"] = nil
+		}
+	}
+
+	return vars
+}
+
+// EvalRequest evaluates a condition against the standard variables of a request
+func (e *Evaluator) EvalRequest(ctx context.Context, cond Condition, fullMethod string, req interface{}) (bool, error) {
+	includeRequest := true
+	if expr, ok := cond.(*Expr); ok {
+		includeRequest = expr.References("request")
+	}
+	return cond.Eval(e.Vars(ctx, fullMethod, req, includeRequest))
+}
+
+// principalVars builds the principal variable
+func principalVars(ctx context.Context, fn PrincipalFunc) map[string]interface{} {
+	var p Principal
+	authenticated := false
+	if fn != nil {
+		p, authenticated = fn(ctx)
+	}
+
+	return map[string]interface{}{
+		"authenticated": authenticated,
+		"user_id":       p.UserID,
+		"client_id":     p.ClientID,
+		"subject":       p.Subject,
+		"roles":         normalize(p.Roles),
+		"scopes":        normalize(p.Scopes),
+	}
+}
+
+// geoVars builds the geo variable
+func geoVars(ctx context.Context, fn GeoFunc) map[string]interface{} {
+	var g Geo
+	known := false
+	if fn != nil {
+		g, known = fn(ctx)
+	}
+
+	return map[string]interface{}{
+		"known":   known,
+		"country": g.Country,
+		"asn":     int64(g.ASN),
+		"as_org":  g.ASOrg,
+	}
+}
+
+// messageVars converts a message to a map keyed by proto field name. Scalars, lists and
+// maps are always present (with their default values); nested messages only when set,
+// so has(request.field) reports message presence.
+func messageVars(m protoreflect.Message) map[string]interface{} {
+	fields := m.Descriptor().Fields()
+	vars := make(map[string]interface{}, fields.Len())
+
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if fd.Message() != nil && !fd.IsList() && !fd.IsMap() && !m.Has(fd) {
+			continue
+		}
+		vars[string(fd.Name())] = fieldVars(fd, m.Get(fd))
+	}
+	return vars
+}
+
+// fieldVars converts a field value
+func fieldVars(fd protoreflect.FieldDescriptor, v protoreflect.Value) interface{} {
+	switch {
+	case fd.IsList():
+		list := v.List()
+		values := make([]interface{}, list.Len())
+		for i := range values {
+			values[i] = scalarVars(fd, list.Get(i))
+		}
+		return values
+
+	case fd.IsMap():
+		values := make(map[string]interface{}, v.Map().Len())
+		v.Map().Range(func(k protoreflect.MapKey, mv protoreflect.Value) bool {
+			values[k.String()] = scalarVars(fd.MapValue(), mv)
+			return true
+		})
+		return values
+	}
+	return scalarVars(fd, v)
+}
+
+// scalarVars converts a singular value; enums become their numbers as in CEL
+func scalarVars(fd protoreflect.FieldDescriptor, v protoreflect.Value) interface{} {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		return v.Bool()
+	case protoreflect.EnumKind:
+		return int64(v.Enum())
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return v.Int()
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind, protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return int64(v.Uint())
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		return v.Float()
+	case protoreflect.StringKind:
+		return v.String()
+	case protoreflect.BytesKind:
+		return string(v.Bytes())
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return messageVars(v.Message())
+	}
+	return nil
+}
@@ -0,0 +1,357 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	guardian "github.com/grpc-guardian/grpc-guardian"
+	"github.com/grpc-guardian/grpc-guardian/chaos"
+	"github.com/grpc-guardian/grpc-guardian/middleware"
+	"github.com/grpc-guardian/grpc-guardian/pkg/classify"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+// BuildOption configures how a chain is built
+type BuildOption func(*buildOptions)
+
+type buildOptions struct {
+	validators map[string]middleware.AuthValidator
+	classifier *classify.Classifier
+}
+
+// WithValidator registers an auth validator that "auth.type: <name>" refers to, for
+// validators that cannot be described in the file (OAuth2, JWKS, custom)
+func WithValidator(name string, validator middleware.AuthValidator) BuildOption {
+	return func(o *buildOptions) {
+		o.validators[name] = validator
+	}
+}
+
+// WithClassifier sets the classifier used by reads_only and by chaos
+// Default: classify.New()
+func WithClassifier(classifier *classify.Classifier) BuildOption {
+	return func(o *buildOptions) {
+		o.classifier = classifier
+	}
+}
+
+// stage is one middleware kind in chain order
+type stage struct {
+	name     string
+	priority int
+	get      func(Middleware) interface{}
+	build    func(cfg interface{}, o *buildOptions) (guardian.Middleware, error)
+}
+
+// stages lists the middleware in the order they run: rejected requests are dropped
+// before any work, cached responses skip the breaker, and retries see injected faults
+var stages = []stage{
+	{guardian.NameRateLimit, 10, func(m Middleware) interface{} { return m.RateLimit }, buildRateLimit},
+	{guardian.NameAuth, 20, func(m Middleware) interface{} { return m.Auth }, buildAuth},
+	{guardian.NameTimeout, 30, func(m Middleware) interface{} { return m.Timeout }, buildTimeout},
+	{guardian.NameCache, 40, func(m Middleware) interface{} { return m.Cache }, buildCache},
+	{"circuitbreaker", 50, func(m Middleware) interface{} { return m.CircuitBreaker }, buildCircuitBreaker},
+	{guardian.NameRetry, 60, func(m Middleware) interface{} { return m.Retry }, buildRetry},
+	{"chaos", 70, func(m Middleware) interface{} { return m.Chaos }, buildChaos},
+}
+
+// Build constructs the chain described by the configuration. Each configured middleware
+// kind becomes one named chain entry; per-method overrides are resolved once per method
+// (exact pattern, then longest prefix, then "*", then the global setting).
+//
+// Example usage:
+//
+//	cfg, err := config.LoadFile("guardian.yaml")
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	chain, err := cfg.Build()
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	server := grpc.NewServer(chain.ServerOption()...)
+func (c *Config) Build(opts ...BuildOption) (*guardian.Chain, error) {
+	o := &buildOptions{validators: make(map[string]middleware.AuthValidator)}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.classifier == nil {
+		o.classifier = classify.New()
+	}
+
+	builder := guardian.NewChainBuilder()
+	var configs []func(*guardian.Chain)
+
+	for _, st := range stages {
+		st := st
+		d := &dispatch{byPattern: make(map[string]guardian.Middleware), cache: new(sync.Map)}
+		used := false
+
+		if cfg := st.get(c.Middleware); !isNil(cfg) {
+			mw, err := st.build(cfg, o)
+			if err != nil {
+				return nil, fmt.Errorf("guardian config: middleware.%s: %w", st.name, err)
+			}
+			d.fallback = mw
+			used = true
+			configs = append(configs, func(chain *guardian.Chain) { chain.WithConfig(st.name, cfg) })
+		}
+
+		for pattern, m := range c.Methods {
+			cfg := st.get(m)
+			if isNil(cfg) {
+				continue
+			}
+			mw, err := st.build(cfg, o)
+			if err != nil {
+				return nil, fmt.Errorf("guardian config: methods[%s].%s: %w", pattern, st.name, err)
+			}
+			d.add(pattern, mw)
+			used = true
+			pattern := pattern
+			configs = append(configs, func(chain *guardian.Chain) { chain.WithConfig(st.name+" "+pattern, cfg) })
+		}
+
+		if used {
+			builder.Use(st.name, st.priority, d.middleware)
+		}
+	}
+
+	chain, err := builder.Build()
+	if err != nil {
+		return nil, err
+	}
+	for _, record := range configs {
+		record(chain)
+	}
+	return chain, nil
+}
+
+// isNil reports whether a config pointer stored in an interface is nil
+func isNil(cfg interface{}) bool {
+	return cfg == nil || reflect.ValueOf(cfg).IsNil()
+}
+
+// dispatch selects the middleware instance of one kind for each method. A nil
+// instance means the middleware is disabled for the method.
+type dispatch struct {
+	fallback  guardian.Middleware
+	byPattern map[string]guardian.Middleware
+	prefixes  []string // Sorted longest first
+	cache     *sync.Map
+}
+
+// add registers the instance for a pattern
+func (d *dispatch) add(pattern string, mw guardian.Middleware) {
+	d.byPattern[pattern] = mw
+	if pattern != "*" && strings.HasSuffix(pattern, "*") {
+		d.prefixes = append(d.prefixes, pattern)
+		sort.Slice(d.prefixes, func(i, j int) bool { return len(d.prefixes[i]) > len(d.prefixes[j]) })
+	}
+}
+
+// resolve returns the instance for a method
+func (d *dispatch) resolve(fullMethod string) guardian.Middleware {
+	if mw, ok := d.byPattern[fullMethod]; ok {
+		return mw
+	}
+	for _, pattern := range d.prefixes {
+		if strings.HasPrefix(fullMethod, strings.TrimSuffix(pattern, "*")) {
+			return d.byPattern[pattern]
+		}
+	}
+	if mw, ok := d.byPattern["*"]; ok {
+		return mw
+	}
+	return d.fallback
+}
+
+// middleware runs the instance resolved for the method, caching the resolution
+func (d *dispatch) middleware(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	var mw guardian.Middleware
+	if cached, ok := d.cache.Load(info.FullMethod); ok {
+		mw, _ = cached.(guardian.Middleware)
+	} else {
+		mw = d.resolve(info.FullMethod)
+		d.cache.Store(info.FullMethod, mw)
+	}
+
+	if mw == nil {
+		return handler(ctx, req)
+	}
+	return mw(ctx, req, info, handler)
+}
+
+func buildRateLimit(cfg interface{}, o *buildOptions) (guardian.Middleware, error) {
+	c := cfg.(*RateLimitConfig)
+	if c.Disabled {
+		return nil, nil
+	}
+	burst := c.Burst
+	if burst == 0 {
+		burst = c.Rate
+	}
+	return middleware.RateLimit(c.Rate, burst), nil
+}
+
+func buildAuth(cfg interface{}, o *buildOptions) (guardian.Middleware, error) {
+	c := cfg.(*AuthConfig)
+	if c.Disabled {
+		return nil, nil
+	}
+
+	var validator middleware.AuthValidator
+	switch c.Type {
+	case "jwt":
+		validator = middleware.JWTValidator(c.Secret)
+	case "basic":
+		validator = middleware.BasicAuthValidator(c.Username, c.Password)
+	case "apikey":
+		keys := make(map[string]bool, len(c.Keys))
+		for _, key := range c.Keys {
+			keys[key] = true
+		}
+		validator = middleware.APIKeyValidator(func(key string) bool { return keys[key] })
+	default:
+		v, ok := o.validators[c.Type]
+		if !ok {
+			return nil, fmt.Errorf("unknown auth type %q\nHint: register it with config.WithValidator", c.Type)
+		}
+		validator = v
+	}
+
+	auth := middleware.Auth(validator)
+	if len(c.RequiredRoles) == 0 {
+		return auth, nil
+	}
+	requireRole := middleware.RequireRole(c.RequiredRoles...)
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		return auth(ctx, req, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			return requireRole(ctx, req, info, handler)
+		})
+	}, nil
+}
+
+func buildTimeout(cfg interface{}, o *buildOptions) (guardian.Middleware, error) {
+	c := cfg.(*TimeoutConfig)
+	if c.Disabled {
+		return nil, nil
+	}
+	return middleware.TimeoutSimple(time.Duration(c.Duration)), nil
+}
+
+func buildCache(cfg interface{}, o *buildOptions) (guardian.Middleware, error) {
+	c := cfg.(*CacheConfig)
+	if c.Disabled {
+		return nil, nil
+	}
+
+	var opts []middleware.CacheOption
+	if c.TTL > 0 {
+		opts = append(opts, middleware.WithTTL(time.Duration(c.TTL)))
+	}
+	if c.CacheErrors {
+		opts = append(opts, middleware.WithCacheErrors())
+	}
+	if c.NegativeTTL > 0 {
+		opts = append(opts, middleware.WithNegativeTTL(time.Duration(c.NegativeTTL)))
+	}
+	if c.StaleWhileRevalidate > 0 {
+		opts = append(opts, middleware.WithStaleWhileRevalidate(time.Duration(c.StaleWhileRevalidate)))
+	}
+	if c.CacheAuthenticated {
+		opts = append(opts, func(cc *middleware.CacheConfig) { cc.SkipAuth = false })
+	}
+	if c.ReadsOnly {
+		opts = append(opts, middleware.WithCacheClassifier(o.classifier))
+	}
+	return middleware.Cache(opts...), nil
+}
+
+func buildCircuitBreaker(cfg interface{}, o *buildOptions) (guardian.Middleware, error) {
+	c := cfg.(*CircuitBreakerConfig)
+	if c.Disabled {
+		return nil, nil
+	}
+
+	var opts []middleware.CircuitBreakerOption
+	if c.FailureThreshold > 0 {
+		opts = append(opts, middleware.WithFailureThreshold(c.FailureThreshold))
+	}
+	if c.SuccessThreshold > 0 {
+		opts = append(opts, middleware.WithSuccessThreshold(c.SuccessThreshold))
+	}
+	if c.MaxRequests > 0 {
+		opts = append(opts, middleware.WithMaxRequests(c.MaxRequests))
+	}
+	if c.Interval > 0 {
+		opts = append(opts, middleware.WithInterval(time.Duration(c.Interval)))
+	}
+	return middleware.CircuitBreakerMiddleware(opts...), nil
+}
+
+func buildRetry(cfg interface{}, o *buildOptions) (guardian.Middleware, error) {
+	c := cfg.(*RetryConfig)
+	if c.Disabled {
+		return nil, nil
+	}
+
+	var opts []middleware.RetryOption
+	if c.MaxAttempts > 0 {
+		opts = append(opts, middleware.WithMaxAttempts(c.MaxAttempts))
+	}
+	if c.InitialBackoff > 0 {
+		opts = append(opts, middleware.WithInitialBackoff(time.Duration(c.InitialBackoff)))
+	}
+	if c.MaxBackoff > 0 {
+		opts = append(opts, middleware.WithMaxBackoff(time.Duration(c.MaxBackoff)))
+	}
+	if c.BackoffMultiplier > 0 {
+		opts = append(opts, middleware.WithBackoffMultiplier(c.BackoffMultiplier))
+	}
+	if len(c.RetryableCodes) > 0 {
+		opts = append(opts, middleware.WithRetryableCodes(toCodes(c.RetryableCodes)...))
+	}
+	if c.ReadsOnly {
+		opts = append(opts, middleware.WithRetryClassifier(o.classifier))
+	}
+	return guardian.Middleware(middleware.NewRetry(opts...).UnaryServerInterceptor()), nil
+}
+
+func buildChaos(cfg interface{}, o *buildOptions) (guardian.Middleware, error) {
+	c := cfg.(*ChaosConfig)
+	if !c.Enabled {
+		return nil, nil
+	}
+
+	opts := []chaos.ChaosOption{chaos.WithClassifier(o.classifier)}
+	if c.IncludeWrites {
+		opts = append(opts, chaos.WithWritesIncluded())
+	}
+	if c.LatencyProbability > 0 {
+		opts = append(opts, chaos.WithLatency(time.Duration(c.LatencyMin), time.Duration(c.LatencyMax), c.LatencyProbability))
+	}
+	if c.ErrorProbability > 0 {
+		errorCodes := toCodes(c.ErrorCodes)
+		if len(errorCodes) == 0 {
+			errorCodes = []codes.Code{codes.Unavailable}
+		}
+		opts = append(opts, chaos.WithErrors(errorCodes, c.ErrorProbability))
+	}
+	return chaos.New(opts...), nil
+}
+
+// toCodes converts configured codes
+func toCodes(in []Code) []codes.Code {
+	out := make([]codes.Code, len(in))
+	for i, c := range in {
+		out[i] = codes.Code(c)
+	}
+	return out
+}
@@ -0,0 +1,282 @@
+// Package config loads a declarative guardian.yaml (or JSON) file describing middleware
+// policies and builds a ready-to-use guardian.Chain from it, so policies can be reviewed
+// and varied per environment without code changes.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"gopkg.in/yaml.v3"
+)
+
+// Version is the configuration schema version understood by this package
+const Version = 1
+
+// Config is the root of a guardian configuration file
+type Config struct {
+	// Version is the schema version (Version)
+	Version int `yaml:"version" json:"version"`
+
+	// Middleware holds the policies applied to every method
+	Middleware Middleware `yaml:"middleware" json:"middleware"`
+
+	// Methods holds per-method overrides keyed by pattern: exact methods
+	// ("/pkg.Service/Method"), prefixes ending in "*" ("/pkg.Service/*") or "*".
+	// A middleware set in an override replaces the global one for matching methods.
+	Methods map[string]Middleware `yaml:"methods" json:"methods"`
+}
+
+// Middleware lists the configurable middleware; nil entries are not installed
+type Middleware struct {
+	RateLimit      *RateLimitConfig      `yaml:"ratelimit" json:"ratelimit"`
+	Auth           *AuthConfig           `yaml:"auth" json:"auth"`
+	Timeout        *TimeoutConfig        `yaml:"timeout" json:"timeout"`
+	Cache          *CacheConfig          `yaml:"cache" json:"cache"`
+	CircuitBreaker *CircuitBreakerConfig `yaml:"circuitbreaker" json:"circuitbreaker"`
+	Retry          *RetryConfig          `yaml:"retry" json:"retry"`
+	Chaos          *ChaosConfig          `yaml:"chaos" json:"chaos"`
+}
+
+// AuthConfig configures authentication
+type AuthConfig struct {
+	Disabled bool `yaml:"disabled" json:"disabled"`
+
+	// Type is "jwt", "basic", "apikey" or the name of a validator registered with
+	// WithValidator
+	Type string `yaml:"type" json:"type"`
+
+	// Secret is the HMAC secret for "jwt"
+	Secret string `yaml:"secret" json:"secret"`
+
+	// Username and Password are the credentials for "basic"
+	Username string `yaml:"username" json:"username"`
+	Password string `yaml:"password" json:"password"`
+
+	// Keys are the accepted keys for "apikey"
+	Keys []string `yaml:"keys" json:"keys"`
+
+	// RequiredRoles are checked after authentication (any of them grants access)
+	RequiredRoles []string `yaml:"required_roles" json:"required_roles"`
+}
+
+// RateLimitConfig configures a token bucket rate limiter
+type RateLimitConfig struct {
+	Disabled bool `yaml:"disabled" json:"disabled"`
+	Rate     int  `yaml:"rate" json:"rate"`
+	Burst    int  `yaml:"burst" json:"burst"`
+}
+
+// TimeoutConfig configures request timeouts
+type TimeoutConfig struct {
+	Disabled bool     `yaml:"disabled" json:"disabled"`
+	Duration Duration `yaml:"duration" json:"duration"`
+}
+
+// CacheConfig configures response caching
+type CacheConfig struct {
+	Disabled    bool     `yaml:"disabled" json:"disabled"`
+	TTL         Duration `yaml:"ttl" json:"ttl"`
+	CacheErrors bool     `yaml:"cache_errors" json:"cache_errors"`
+
+	// NegativeTTL caches NotFound and InvalidArgument errors for a shorter duration
+	NegativeTTL Duration `yaml:"negative_ttl" json:"negative_ttl"`
+
+	// StaleWhileRevalidate serves expired entries for this long while refreshing them
+	StaleWhileRevalidate Duration `yaml:"stale_while_revalidate" json:"stale_while_revalidate"`
+
+	// CacheAuthenticated also caches requests of authenticated callers
+	CacheAuthenticated bool `yaml:"cache_authenticated" json:"cache_authenticated"`
+
+	// ReadsOnly only caches methods classified as reads (see pkg/classify)
+	ReadsOnly bool `yaml:"reads_only" json:"reads_only"`
+}
+
+// CircuitBreakerConfig configures a circuit breaker
+type CircuitBreakerConfig struct {
+	Disabled         bool     `yaml:"disabled" json:"disabled"`
+	FailureThreshold float64  `yaml:"failure_threshold" json:"failure_threshold"`
+	SuccessThreshold uint32   `yaml:"success_threshold" json:"success_threshold"`
+	MaxRequests      uint32   `yaml:"max_requests" json:"max_requests"`
+	Interval         Duration `yaml:"interval" json:"interval"`
+}
+
+// RetryConfig configures retries with exponential backoff
+type RetryConfig struct {
+	Disabled          bool     `yaml:"disabled" json:"disabled"`
+	MaxAttempts       int      `yaml:"max_attempts" json:"max_attempts"`
+	InitialBackoff    Duration `yaml:"initial_backoff" json:"initial_backoff"`
+	MaxBackoff        Duration `yaml:"max_backoff" json:"max_backoff"`
+	BackoffMultiplier float64  `yaml:"backoff_multiplier" json:"backoff_multiplier"`
+	RetryableCodes    []Code   `yaml:"retryable_codes" json:"retryable_codes"`
+
+	// ReadsOnly only retries methods classified as reads (see pkg/classify)
+	ReadsOnly bool `yaml:"reads_only" json:"reads_only"`
+}
+
+// ChaosConfig configures fault injection. Chaos is only installed when Enabled is set.
+type ChaosConfig struct {
+	Enabled bool `yaml:"enabled" json:"enabled"`
+
+	LatencyMin         Duration `yaml:"latency_min" json:"latency_min"`
+	LatencyMax         Duration `yaml:"latency_max" json:"latency_max"`
+	LatencyProbability float64  `yaml:"latency_probability" json:"latency_probability"`
+
+	ErrorCodes       []Code  `yaml:"error_codes" json:"error_codes"`
+	ErrorProbability float64 `yaml:"error_probability" json:"error_probability"`
+
+	// IncludeWrites also injects faults into methods classified as writes
+	IncludeWrites bool `yaml:"include_writes" json:"include_writes"`
+}
+
+// Duration is a time.Duration written as a string such as "250ms" or "5s"
+type Duration time.Duration
+
+// UnmarshalYAML parses a duration string
+func (d *Duration) UnmarshalYAML(node *yaml.Node) error {
+	parsed, err := time.ParseDuration(node.Value)
+	if err != nil {
+		return fmt.Errorf("line %d: invalid duration %q", node.Line, node.Value)
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// String returns the duration in time.Duration notation
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
+
+// Code is a gRPC status code written by name, e.g. "UNAVAILABLE" or "DeadlineExceeded"
+type Code codes.Code
+
+// UnmarshalYAML parses a status code name
+func (c *Code) UnmarshalYAML(node *yaml.Node) error {
+	code, ok := parseCode(node.Value)
+	if !ok {
+		return fmt.Errorf("line %d: unknown status code %q", node.Line, node.Value)
+	}
+	*c = Code(code)
+	return nil
+}
+
+// parseCode looks up a status code by name, ignoring case and underscores
+func parseCode(name string) (codes.Code, bool) {
+	normalized := strings.ToUpper(strings.ReplaceAll(name, "_", ""))
+	for c := codes.OK; c <= codes.Unauthenticated; c++ {
+		if strings.ToUpper(c.String()) == normalized {
+			return c, true
+		}
+	}
+	return 0, false
+}
+
+// Load parses a YAML or JSON configuration. ${VAR} and $VAR references are expanded
+// from the environment first, so secrets can stay out of the file. Unknown keys are
+// rejected.
+func Load(data []byte) (*Config, error) {
+	expanded := os.ExpandEnv(string(data))
+
+	decoder := yaml.NewDecoder(bytes.NewReader([]byte(expanded)))
+	decoder.KnownFields(true)
+
+	var config Config
+	if err := decoder.Decode(&config); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse guardian config: %w", err)
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+// LoadFile reads and parses a configuration file
+func LoadFile(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read guardian config: %w", err)
+	}
+	return Load(data)
+}
+
+// Validate checks the configuration for missing or out-of-range values
+func (c *Config) Validate() error {
+	if c.Version != Version {
+		return fmt.Errorf("guardian config: unsupported version %d\nHint: set \"version: %d\"", c.Version, Version)
+	}
+
+	var errs []error
+	errs = append(errs, c.Middleware.validate("middleware")...)
+	for pattern, m := range c.Methods {
+		if pattern == "" {
+			errs = append(errs, errors.New("guardian config: methods: empty pattern"))
+			continue
+		}
+		errs = append(errs, m.validate("methods["+pattern+"]")...)
+	}
+	return errors.Join(errs...)
+}
+
+// validate checks the middleware settings; scope prefixes the error messages
+func (m Middleware) validate(scope string) []error {
+	var errs []error
+	fail := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf("guardian config: "+scope+"."+format, args...))
+	}
+
+	if a := m.Auth; a != nil && !a.Disabled {
+		switch a.Type {
+		case "jwt":
+			if a.Secret == "" {
+				fail("auth: jwt requires a secret")
+			}
+		case "basic":
+			if a.Username == "" || a.Password == "" {
+				fail("auth: basic requires a username and password")
+			}
+		case "apikey":
+			if len(a.Keys) == 0 {
+				fail("auth: apikey requires at least one key")
+			}
+		case "":
+			fail("auth: type is required")
+		}
+	}
+
+	if r := m.RateLimit; r != nil && !r.Disabled && (r.Rate <= 0 || r.Burst < 0) {
+		fail("ratelimit: rate must be positive and burst not negative")
+	}
+
+	if t := m.Timeout; t != nil && !t.Disabled && t.Duration <= 0 {
+		fail("timeout: duration must be positive")
+	}
+
+	if cb := m.CircuitBreaker; cb != nil && !cb.Disabled && (cb.FailureThreshold < 0 || cb.FailureThreshold > 1) {
+		fail("circuitbreaker: failure_threshold must be between 0 and 1")
+	}
+
+	if r := m.Retry; r != nil && !r.Disabled && r.MaxAttempts < 0 {
+		fail("retry: max_attempts must not be negative")
+	}
+
+	if ch := m.Chaos; ch != nil && ch.Enabled {
+		for _, p := range []float64{ch.LatencyProbability, ch.ErrorProbability} {
+			if p < 0 || p > 1 {
+				fail("chaos: probabilities must be between 0 and 1")
+				break
+			}
+		}
+		if ch.LatencyMax < ch.LatencyMin {
+			fail("chaos: latency_max must not be lower than latency_min")
+		}
+	}
+
+	return errs
+}
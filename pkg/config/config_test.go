@@ -0,0 +1,149 @@
+package config
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grpc-guardian/grpc-guardian/middleware"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const testConfig = `
+version: 1
+middleware:
+  auth:
+    type: apikey
+    keys: ["${GUARDIAN_TEST_KEY}"]
+  timeout:
+    duration: 50ms
+  retry:
+    max_attempts: 3
+    initial_backoff: 1ms
+    retryable_codes: [UNAVAILABLE]
+methods:
+  "/grpc.health.v1.Health/*":
+    auth:
+      disabled: true
+  "/shop.Reports/Generate":
+    timeout:
+      duration: 1s
+`
+
+func TestLoadAndBuild(t *testing.T) {
+	t.Setenv("GUARDIAN_TEST_KEY", "k-123")
+
+	cfg, err := Load([]byte(testConfig))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Middleware.Auth.Keys[0] != "k-123" {
+		t.Errorf("Expected environment expansion, got %v", cfg.Middleware.Auth.Keys)
+	}
+	if time.Duration(cfg.Middleware.Timeout.Duration) != 50*time.Millisecond {
+		t.Errorf("timeout = %v, want 50ms", cfg.Middleware.Timeout.Duration)
+	}
+	if codes.Code(cfg.Middleware.Retry.RetryableCodes[0]) != codes.Unavailable {
+		t.Errorf("retryable code = %v, want Unavailable", cfg.Middleware.Retry.RetryableCodes[0])
+	}
+
+	chain, err := cfg.Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	var names []string
+	for _, d := range chain.Describe() {
+		names = append(names, d.Name)
+	}
+	if strings.Join(names, ",") != "auth,timeout,retry" {
+		t.Errorf("chain = %v, want auth,timeout,retry", names)
+	}
+
+	interceptor := chain.UnaryInterceptor()
+	call := func(ctx context.Context, method string, handler grpc.UnaryHandler) error {
+		_, err := interceptor(ctx, "req", &grpc.UnaryServerInfo{FullMethod: method}, handler)
+		return err
+	}
+	ok := func(ctx context.Context, req interface{}) (interface{}, error) { return "ok", nil }
+	withKey := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer k-123"))
+
+	// Global auth
+	if err := call(context.Background(), "/shop.Orders/Get", ok); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Expected Unauthenticated without a key, got %v", err)
+	}
+	if err := call(withKey, "/shop.Orders/Get", ok); err != nil {
+		t.Errorf("Expected success with a key, got %v", err)
+	}
+
+	// Auth disabled for health checks
+	if err := call(context.Background(), "/grpc.health.v1.Health/Check", ok); err != nil {
+		t.Errorf("Expected health checks to skip auth, got %v", err)
+	}
+
+	// Per-method timeout override
+	slow := func(ctx context.Context, req interface{}) (interface{}, error) {
+		select {
+		case <-time.After(100 * time.Millisecond):
+			return "ok", nil
+		case <-ctx.Done():
+			return nil, status.FromContextError(ctx.Err()).Err()
+		}
+	}
+	if err := call(withKey, "/shop.Orders/Get", slow); status.Code(err) != codes.DeadlineExceeded {
+		t.Errorf("Expected the global timeout, got %v", err)
+	}
+	if err := call(withKey, "/shop.Reports/Generate", slow); err != nil {
+		t.Errorf("Expected the longer method timeout, got %v", err)
+	}
+
+	// Retries
+	attempts := 0
+	flaky := func(ctx context.Context, req interface{}) (interface{}, error) {
+		attempts++
+		if attempts < 3 {
+			return nil, status.Error(codes.Unavailable, "try again")
+		}
+		return "ok", nil
+	}
+	if err := call(withKey, "/shop.Orders/Get", flaky); err != nil || attempts != 3 {
+		t.Errorf("Expected success after 3 attempts, got %v after %d", err, attempts)
+	}
+}
+
+func TestLoad_Errors(t *testing.T) {
+	tests := []struct {
+		config  string
+		wantErr string
+	}{
+		{"version: 2", "unsupported version"},
+		{"version: 1\nmiddleware:\n  timeout:\n    duration: soon", `invalid duration "soon"`},
+		{"version: 1\nmiddleware:\n  retry:\n    retryable_codes: [NOPE]", `unknown status code "NOPE"`},
+		{"version: 1\nmiddleware:\n  timout: {}", "field timout not found"},
+		{"version: 1\nmiddleware:\n  auth:\n    type: jwt", "jwt requires a secret"},
+		{"version: 1\nmethods:\n  \"/svc/*\":\n    ratelimit:\n      rate: 0", "methods[/svc/*].ratelimit"},
+		{`{"version": 1, "middleware": {"chaos": {"enabled": true, "error_probability": 2}}}`, "probabilities must be between 0 and 1"},
+	}
+
+	for _, tt := range tests {
+		_, err := Load([]byte(tt.config))
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("Load(%q) error = %v, want error containing %q", tt.config, err, tt.wantErr)
+		}
+	}
+
+	// Unknown auth types need a registered validator
+	cfg, err := Load([]byte("version: 1\nmiddleware:\n  auth:\n    type: oidc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cfg.Build(); err == nil || !strings.Contains(err.Error(), `unknown auth type "oidc"`) {
+		t.Errorf("Build() error = %v, want unknown auth type", err)
+	}
+	if _, err := cfg.Build(WithValidator("oidc", middleware.APIKeyValidator(func(string) bool { return true }))); err != nil {
+		t.Errorf("Build() with registered validator error = %v", err)
+	}
+}
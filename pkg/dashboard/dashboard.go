@@ -0,0 +1,328 @@
+// Package dashboard serves a read-only view of live guardian state: circuit breaker
+// states, rate limiter saturation, cache hit rates, retry statistics and recent slow
+// requests. It is served as JSON for tooling and as a minimal HTML page for humans, and
+// reads everything from the components' own stats APIs.
+package dashboard
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	guardian "github.com/grpc-guardian/grpc-guardian"
+	"github.com/grpc-guardian/grpc-guardian/middleware"
+	"github.com/grpc-guardian/grpc-guardian/pkg/cache"
+	"golang.org/x/time/rate"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// Dashboard holds the components shown on the dashboard
+type Dashboard struct {
+	mu               sync.RWMutex
+	breakers         map[string]*middleware.CircuitBreaker
+	limiters         map[string]*rate.Limiter
+	perClientLimiter map[string]*middleware.PerClientRateLimiter
+	caches           map[string]cache.Backend
+	retries          map[string]*middleware.RetryWithStats
+
+	slowThreshold time.Duration
+	slowLimit     int
+	refresh       time.Duration
+
+	slowMu sync.Mutex
+	slow   []SlowRequest // Ring buffer of the last slowLimit slow requests
+	next   int
+}
+
+// Option configures a Dashboard
+type Option func(*Dashboard)
+
+// WithSlowThreshold sets the duration above which requests are listed as slow
+// Default: 1s
+func WithSlowThreshold(threshold time.Duration) Option {
+	return func(d *Dashboard) {
+		if threshold > 0 {
+			d.slowThreshold = threshold
+		}
+	}
+}
+
+// WithSlowRequestLimit sets how many recent slow requests are kept
+// Default: 50
+func WithSlowRequestLimit(n int) Option {
+	return func(d *Dashboard) {
+		if n > 0 {
+			d.slowLimit = n
+		}
+	}
+}
+
+// WithRefreshInterval sets how often the HTML page reloads itself
+// Default: 5s
+func WithRefreshInterval(interval time.Duration) Option {
+	return func(d *Dashboard) {
+		if interval > 0 {
+			d.refresh = interval
+		}
+	}
+}
+
+// New creates a dashboard. The handler has no authentication of its own: serve it on a
+// private interface or behind an authenticating handler.
+//
+// Example usage:
+//
+//	breaker := middleware.NewCircuitBreaker()
+//	retry := middleware.NewRetryWithStats()
+//	board := dashboard.New(dashboard.WithSlowThreshold(500*time.Millisecond)).
+//	    AddCircuitBreaker("payments", breaker).
+//	    AddCache("catalog", catalogBackend).
+//	    AddRateLimiter("global", limiter).
+//	    AddRetry("inventory-client", retry)
+//
+//	chain := guardian.NewChain(board.Middleware(), middleware.Logging())
+//	http.Handle("/guardian/", http.StripPrefix("/guardian", board.Handler()))
+func New(opts ...Option) *Dashboard {
+	d := &Dashboard{
+		breakers:         make(map[string]*middleware.CircuitBreaker),
+		limiters:         make(map[string]*rate.Limiter),
+		perClientLimiter: make(map[string]*middleware.PerClientRateLimiter),
+		caches:           make(map[string]cache.Backend),
+		retries:          make(map[string]*middleware.RetryWithStats),
+		slowThreshold:    time.Second,
+		slowLimit:        50,
+		refresh:          5 * time.Second,
+	}
+
+	for _, opt := range opts {
+		opt(d)
+	}
+
+	return d
+}
+
+// AddCircuitBreaker shows a circuit breaker
+func (d *Dashboard) AddCircuitBreaker(name string, breaker *middleware.CircuitBreaker) *Dashboard {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.breakers[name] = breaker
+	return d
+}
+
+// AddRateLimiter shows a global rate limiter
+func (d *Dashboard) AddRateLimiter(name string, limiter *rate.Limiter) *Dashboard {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.limiters[name] = limiter
+	return d
+}
+
+// AddPerClientRateLimiter shows a per-client rate limiter
+func (d *Dashboard) AddPerClientRateLimiter(name string, limiter *middleware.PerClientRateLimiter) *Dashboard {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.perClientLimiter[name] = limiter
+	return d
+}
+
+// AddCache shows a cache backend
+func (d *Dashboard) AddCache(name string, backend cache.Backend) *Dashboard {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.caches[name] = backend
+	return d
+}
+
+// AddRetry shows the statistics of a retry middleware
+func (d *Dashboard) AddRetry(name string, retry *middleware.RetryWithStats) *Dashboard {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.retries[name] = retry
+	return d
+}
+
+// Middleware records requests slower than the slow threshold
+func (d *Dashboard) Middleware() guardian.Middleware {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		if duration := time.Since(start); duration >= d.slowThreshold {
+			d.recordSlow(ctx, info.FullMethod, start, duration, err)
+		}
+		return resp, err
+	}
+}
+
+// recordSlow adds a slow request to the ring buffer
+func (d *Dashboard) recordSlow(ctx context.Context, method string, start time.Time, duration time.Duration, err error) {
+	entry := SlowRequest{
+		Method:   method,
+		Code:     status.Code(err).String(),
+		Duration: duration,
+		Start:    start,
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		entry.Peer = p.Addr.String()
+	}
+
+	d.slowMu.Lock()
+	defer d.slowMu.Unlock()
+
+	if len(d.slow) < d.slowLimit {
+		d.slow = append(d.slow, entry)
+		return
+	}
+	d.slow[d.next] = entry
+	d.next = (d.next + 1) % d.slowLimit
+}
+
+// Snapshot is the dashboard data
+type Snapshot struct {
+	Time            time.Time                      `json:"time"`
+	CircuitBreakers map[string]CircuitBreakerStats `json:"circuit_breakers"`
+	RateLimiters    map[string]RateLimiterStats    `json:"rate_limiters"`
+	Caches          map[string]CacheStats          `json:"caches"`
+	Retries         map[string]RetryStats          `json:"retries"`
+	SlowRequests    []SlowRequest                  `json:"slow_requests"`
+}
+
+// CircuitBreakerStats describes a circuit breaker
+type CircuitBreakerStats struct {
+	State               string    `json:"state"`
+	StateChangedAt      time.Time `json:"state_changed_at"`
+	Requests            uint32    `json:"requests"`
+	Failures            uint32    `json:"failures"`
+	ConsecutiveFailures uint32    `json:"consecutive_failures"`
+	FailureRate         float64   `json:"failure_rate"`
+}
+
+// RateLimiterStats describes a rate limiter. Saturation is the share of the burst in
+// use (0-1) and is only reported for global limiters; Clients only for per-client limiters.
+type RateLimiterStats struct {
+	Limit      float64 `json:"limit"`
+	Burst      int     `json:"burst"`
+	Saturation float64 `json:"saturation"`
+	Clients    int     `json:"clients,omitempty"`
+}
+
+// CacheStats describes a cache backend
+type CacheStats struct {
+	HitRate   float64 `json:"hit_rate"`
+	Hits      uint64  `json:"hits"`
+	Misses    uint64  `json:"misses"`
+	Evictions uint64  `json:"evictions"`
+	Size      int     `json:"size"`
+	MaxSize   int     `json:"max_size"`
+}
+
+// RetryStats describes a retry middleware
+type RetryStats struct {
+	Requests          uint64  `json:"requests"`
+	Retries           uint64  `json:"retries"`
+	SuccessfulRetries uint64  `json:"successful_retries"`
+	FailedRetries     uint64  `json:"failed_retries"`
+	AverageAttempts   float64 `json:"average_attempts"`
+}
+
+// SlowRequest is a request slower than the slow threshold
+type SlowRequest struct {
+	Method   string        `json:"method"`
+	Code     string        `json:"code"`
+	Duration time.Duration `json:"duration_ns"`
+	Start    time.Time     `json:"start"`
+	Peer     string        `json:"peer,omitempty"`
+}
+
+// Snapshot collects the current state of every registered component. Slow requests are
+// listed newest first.
+func (d *Dashboard) Snapshot() Snapshot {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	snapshot := Snapshot{
+		Time:            time.Now(),
+		CircuitBreakers: make(map[string]CircuitBreakerStats, len(d.breakers)),
+		RateLimiters:    make(map[string]RateLimiterStats, len(d.limiters)+len(d.perClientLimiter)),
+		Caches:          make(map[string]CacheStats, len(d.caches)),
+		Retries:         make(map[string]RetryStats, len(d.retries)),
+		SlowRequests:    d.slowRequests(),
+	}
+
+	for name, breaker := range d.breakers {
+		stats := breaker.GetStats()
+		entry := CircuitBreakerStats{
+			State:               stats.State.String(),
+			StateChangedAt:      stats.StateChangedAt,
+			Requests:            stats.Counts.Requests,
+			Failures:            stats.Counts.TotalFailures,
+			ConsecutiveFailures: stats.Counts.ConsecutiveFailures,
+		}
+		if entry.Requests > 0 {
+			entry.FailureRate = float64(entry.Failures) / float64(entry.Requests)
+		}
+		snapshot.CircuitBreakers[name] = entry
+	}
+	for name, limiter := range d.limiters {
+		entry := RateLimiterStats{Limit: float64(limiter.Limit()), Burst: limiter.Burst()}
+		if entry.Burst > 0 {
+			entry.Saturation = clamp(1 - limiter.Tokens()/float64(entry.Burst))
+		}
+		snapshot.RateLimiters[name] = entry
+	}
+	for name, limiter := range d.perClientLimiter {
+		limit, burst := limiter.Limit()
+		snapshot.RateLimiters[name] = RateLimiterStats{Limit: float64(limit), Burst: burst, Clients: limiter.Clients()}
+	}
+	for name, backend := range d.caches {
+		stats := backend.Stats()
+		snapshot.Caches[name] = CacheStats{
+			HitRate:   stats.HitRate,
+			Hits:      stats.Hits,
+			Misses:    stats.Misses,
+			Evictions: stats.Evictions,
+			Size:      stats.Size,
+			MaxSize:   stats.MaxSize,
+		}
+	}
+	for name, retry := range d.retries {
+		stats := retry.GetStats()
+		snapshot.Retries[name] = RetryStats{
+			Requests:          stats.TotalRequests,
+			Retries:           stats.TotalRetries,
+			SuccessfulRetries: stats.SuccessfulRetries,
+			FailedRetries:     stats.FailedRetries,
+			AverageAttempts:   stats.AverageAttempts,
+		}
+	}
+
+	return snapshot
+}
+
+// slowRequests returns the recorded slow requests, newest first
+func (d *Dashboard) slowRequests() []SlowRequest {
+	d.slowMu.Lock()
+	defer d.slowMu.Unlock()
+
+	requests := append([]SlowRequest(nil), d.slow...)
+	sort.SliceStable(requests, func(i, j int) bool { return requests[i].Start.After(requests[j].Start) })
+	return requests
+}
+
+// clamp limits a ratio to 0-1
+func clamp(v float64) float64 {
+	if v < 0 {
+		return 0
+	}
+	if v > 1 {
+		return 1
+	}
+	return v
+}
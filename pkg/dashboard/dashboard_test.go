@@ -0,0 +1,104 @@
+package dashboard
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grpc-guardian/grpc-guardian/middleware"
+	"github.com/grpc-guardian/grpc-guardian/pkg/cache"
+	"golang.org/x/time/rate"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+func TestDashboard(t *testing.T) {
+	breaker := middleware.NewCircuitBreaker()
+	backend := cache.NewMemoryBackend(nil)
+	defer backend.Close()
+	limiter := rate.NewLimiter(1, 4)
+	retry := middleware.NewRetryWithStats(middleware.WithInitialBackoff(time.Millisecond))
+
+	board := New(WithSlowThreshold(20*time.Millisecond), WithSlowRequestLimit(2)).
+		AddCircuitBreaker("payments", breaker).
+		AddCache("catalog", backend).
+		AddRateLimiter("global", limiter).
+		AddRetry("inventory", retry)
+
+	// One retried call that recovers
+	attempts := 0
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		if attempts++; attempts == 1 {
+			return status.Error(codes.Unavailable, "try again")
+		}
+		return nil
+	}
+	if err := retry.UnaryClientInterceptor()(context.Background(), "/inventory.v1.Stock/Get", nil, nil, nil, invoker); err != nil {
+		t.Fatal(err)
+	}
+
+	limiter.AllowN(time.Now(), 3)
+	backend.Set(context.Background(), "key", []byte("value"), time.Minute)
+	backend.Get(context.Background(), "key")
+	backend.Get(context.Background(), "missing")
+
+	// Three slow requests and a fast one; only the last two slow ones are kept
+	interceptor := board.Middleware()
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.IPv4(10, 0, 0, 7), Port: 5000}})
+	for _, method := range []string{"/shop.v1.Orders/List", "/shop.v1.Orders/Get", "/shop.v1.Orders/Fast", "/shop.v1.Orders/Create"} {
+		info := &grpc.UnaryServerInfo{FullMethod: method}
+		interceptor(ctx, nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			if !strings.HasSuffix(method, "Fast") {
+				time.Sleep(25 * time.Millisecond)
+			}
+			return nil, status.Error(codes.Internal, "boom")
+		})
+	}
+
+	rec := httptest.NewRecorder()
+	board.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/state", nil))
+	var snapshot Snapshot
+	if err := json.NewDecoder(rec.Body).Decode(&snapshot); err != nil {
+		t.Fatalf("Expected a JSON snapshot: %v", err)
+	}
+
+	if got := snapshot.CircuitBreakers["payments"].State; got != "Closed" {
+		t.Errorf("Expected a closed breaker, got %q", got)
+	}
+	if got := snapshot.RateLimiters["global"].Saturation; got < 0.7 || got > 0.8 {
+		t.Errorf("Expected a saturation of about 0.75, got %v", got)
+	}
+	if got := snapshot.Caches["catalog"]; got.Hits != 1 || got.Misses != 1 || got.HitRate != 0.5 {
+		t.Errorf("Unexpected cache stats %+v", got)
+	}
+	if got := snapshot.Retries["inventory"]; got.Requests != 1 || got.Retries != 1 || got.SuccessfulRetries != 1 || got.AverageAttempts != 2 {
+		t.Errorf("Unexpected retry stats %+v", got)
+	}
+	if len(snapshot.SlowRequests) != 2 || snapshot.SlowRequests[0].Method != "/shop.v1.Orders/Create" || snapshot.SlowRequests[1].Method != "/shop.v1.Orders/Get" {
+		t.Fatalf("Expected the two latest slow requests, newest first, got %+v", snapshot.SlowRequests)
+	}
+	if got := snapshot.SlowRequests[0]; got.Code != "Internal" || got.Peer != "10.0.0.7:5000" || got.Duration < 20*time.Millisecond {
+		t.Errorf("Unexpected slow request %+v", got)
+	}
+
+	rec = httptest.NewRecorder()
+	board.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	for _, want := range []string{`<td class="Closed">Closed</td>`, "<td>global</td>", "50.0%", "/shop.v1.Orders/Create", `http-equiv="refresh" content="5"`} {
+		if !strings.Contains(rec.Body.String(), want) {
+			t.Errorf("Expected the HTML page to contain %q", want)
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	board.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/state", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405 for POST, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,138 @@
+package dashboard
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Handler returns the dashboard HTTP handler:
+//
+//	GET /        HTML page, reloading itself every refresh interval
+//	GET /state   Snapshot as JSON
+//
+// GET / also serves JSON to clients that only accept application/json. Mount it under a
+// prefix with http.StripPrefix.
+func (d *Dashboard) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		switch strings.Trim(r.URL.Path, "/") {
+		case "state":
+			d.writeJSON(w)
+		case "":
+			if accept := r.Header.Get("Accept"); strings.Contains(accept, "application/json") && !strings.Contains(accept, "text/html") {
+				d.writeJSON(w)
+				return
+			}
+			d.writeHTML(w)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+}
+
+// writeJSON writes the snapshot as JSON
+func (d *Dashboard) writeJSON(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	_ = json.NewEncoder(w).Encode(d.Snapshot())
+}
+
+// writeHTML renders the snapshot as an HTML page
+func (d *Dashboard) writeHTML(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	_ = page.Execute(w, struct {
+		Snapshot
+		Refresh int
+	}{d.Snapshot(), int(d.refresh.Seconds() + 0.5)})
+}
+
+var page = template.Must(template.New("dashboard").Funcs(template.FuncMap{
+	"percent": func(v float64) string { return fmt.Sprintf("%.1f%%", v*100) },
+	"ms":      func(d time.Duration) string { return fmt.Sprintf("%.1f ms", float64(d)/float64(time.Millisecond)) },
+	"clock":   func(t time.Time) string { return t.Format("15:04:05.000") },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+    <title>gRPC Guardian Dashboard</title>
+    <meta http-equiv="refresh" content="{{.Refresh}}">
+    <style>
+        body { font-family: Arial, sans-serif; margin: 40px; }
+        h1 { color: #333; }
+        .container { max-width: 1000px; margin: 0 auto; }
+        .section { background: #f5f5f5; padding: 20px; margin: 20px 0; border-radius: 5px; }
+        table { border-collapse: collapse; width: 100%; }
+        th, td { text-align: left; padding: 4px 12px 4px 0; }
+        .Open { color: #c00; font-weight: bold; }
+        .HalfOpen { color: #c80; font-weight: bold; }
+        .Closed { color: #080; }
+        a { color: #0066cc; text-decoration: none; }
+    </style>
+</head>
+<body>
+<div class="container">
+    <h1>🛡️ gRPC Guardian Dashboard</h1>
+    <p>Updated {{clock .Time}} · <a href="state">JSON</a></p>
+
+    <div class="section">
+        <h2>Circuit Breakers</h2>
+        {{with .CircuitBreakers}}<table>
+            <tr><th>Name</th><th>State</th><th>Since</th><th>Requests</th><th>Failure rate</th><th>Consecutive failures</th></tr>
+            {{range $name, $stats := .}}{{with $stats}}
+            <tr><td>{{$name}}</td><td class="{{.State}}">{{.State}}</td><td>{{clock .StateChangedAt}}</td><td>{{.Requests}}</td><td>{{percent .FailureRate}}</td><td>{{.ConsecutiveFailures}}</td></tr>
+            {{end}}{{end}}
+        </table>{{else}}<p>None registered</p>{{end}}
+    </div>
+
+    <div class="section">
+        <h2>Rate Limiters</h2>
+        {{with .RateLimiters}}<table>
+            <tr><th>Name</th><th>Limit (req/s)</th><th>Burst</th><th>Saturation</th><th>Clients</th></tr>
+            {{range $name, $stats := .}}{{with $stats}}
+            <tr><td>{{$name}}</td><td>{{.Limit}}</td><td>{{.Burst}}</td><td>{{if .Clients}}-{{else}}{{percent .Saturation}}{{end}}</td><td>{{if .Clients}}{{.Clients}}{{else}}-{{end}}</td></tr>
+            {{end}}{{end}}
+        </table>{{else}}<p>None registered</p>{{end}}
+    </div>
+
+    <div class="section">
+        <h2>Caches</h2>
+        {{with .Caches}}<table>
+            <tr><th>Name</th><th>Hit rate</th><th>Hits</th><th>Misses</th><th>Evictions</th><th>Size</th></tr>
+            {{range $name, $stats := .}}{{with $stats}}
+            <tr><td>{{$name}}</td><td>{{percent .HitRate}}</td><td>{{.Hits}}</td><td>{{.Misses}}</td><td>{{.Evictions}}</td><td>{{.Size}}{{if .MaxSize}} / {{.MaxSize}}{{end}}</td></tr>
+            {{end}}{{end}}
+        </table>{{else}}<p>None registered</p>{{end}}
+    </div>
+
+    <div class="section">
+        <h2>Retries</h2>
+        {{with .Retries}}<table>
+            <tr><th>Name</th><th>Requests</th><th>Retries</th><th>Recovered</th><th>Failed after retry</th><th>Avg attempts</th></tr>
+            {{range $name, $stats := .}}{{with $stats}}
+            <tr><td>{{$name}}</td><td>{{.Requests}}</td><td>{{.Retries}}</td><td>{{.SuccessfulRetries}}</td><td>{{.FailedRetries}}</td><td>{{printf "%.2f" .AverageAttempts}}</td></tr>
+            {{end}}{{end}}
+        </table>{{else}}<p>None registered</p>{{end}}
+    </div>
+
+    <div class="section">
+        <h2>Recent Slow Requests</h2>
+        {{with .SlowRequests}}<table>
+            <tr><th>Started</th><th>Method</th><th>Duration</th><th>Code</th><th>Peer</th></tr>
+            {{range .}}
+            <tr><td>{{clock .Start}}</td><td>{{.Method}}</td><td>{{ms .Duration}}</td><td>{{.Code}}</td><td>{{.Peer}}</td></tr>
+            {{end}}
+        </table>{{else}}<p>No slow requests</p>{{end}}
+    </div>
+</div>
+</body>
+</html>
+`))
@@ -0,0 +1,42 @@
+// Package errtrack sends server errors and panics to an error tracker such as Sentry, so they
+// are grouped, counted and alerted on next to the stack trace that caused them.
+package errtrack
+
+import (
+	"context"
+	"time"
+)
+
+// Event is one reported error or panic
+type Event struct {
+	Time    time.Time
+	Method  string // Full gRPC method name
+	Code    string // gRPC status code sent to the client
+	Message string // Error message, or the panic value
+
+	Panic bool   // The event is a recovered panic
+	Stack []byte // Stack trace of a panic, as returned by runtime/debug.Stack
+
+	UserID   string
+	TenantID string
+	TraceID  string // Active trace, to jump from the tracker to the trace
+
+	// Fingerprint groups events in the tracker; events with the same fingerprint are
+	// counted as one issue
+	Fingerprint []string
+	Tags        map[string]string
+}
+
+// Reporter sends events to an error tracker. Report is called from a background goroutine,
+// never on the request path.
+type Reporter interface {
+	Report(ctx context.Context, event Event) error
+}
+
+// ReporterFunc adapts a function to a Reporter
+type ReporterFunc func(ctx context.Context, event Event) error
+
+// Report calls f
+func (f ReporterFunc) Report(ctx context.Context, event Event) error {
+	return f(ctx, event)
+}
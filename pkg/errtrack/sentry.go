@@ -0,0 +1,199 @@
+package errtrack
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// SentryReporter sends events to Sentry's store endpoint. It speaks the HTTP protocol
+// directly, so the Sentry SDK isn't required.
+type SentryReporter struct {
+	endpoint    string
+	auth        string
+	environment string
+	release     string
+	serverName  string
+	client      *http.Client
+}
+
+// SentryOption configures the Sentry reporter
+type SentryOption func(*SentryReporter)
+
+// WithSentryEnvironment sets the environment events are filed under, e.g. "staging"
+func WithSentryEnvironment(environment string) SentryOption {
+	return func(s *SentryReporter) {
+		s.environment = environment
+	}
+}
+
+// WithSentryRelease sets the release events are attributed to, e.g. the build version
+func WithSentryRelease(release string) SentryOption {
+	return func(s *SentryReporter) {
+		s.release = release
+	}
+}
+
+// WithSentryServerName sets the server name sent with every event
+// Default: none
+func WithSentryServerName(name string) SentryOption {
+	return func(s *SentryReporter) {
+		s.serverName = name
+	}
+}
+
+// WithSentryHTTPClient sets the HTTP client
+// Default: a client with a 10s timeout
+func WithSentryHTTPClient(client *http.Client) SentryOption {
+	return func(s *SentryReporter) {
+		if client != nil {
+			s.client = client
+		}
+	}
+}
+
+// NewSentryReporter creates a reporter for a Sentry DSN, e.g.
+// "https://<key>@o123.ingest.sentry.io/456"
+func NewSentryReporter(dsn string, opts ...SentryOption) (*SentryReporter, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sentry DSN: %w", err)
+	}
+	if u.User == nil || u.User.Username() == "" {
+		return nil, fmt.Errorf("invalid sentry DSN: missing public key")
+	}
+	path := strings.Trim(u.Path, "/")
+	slash := strings.LastIndex(path, "/")
+	project := path[slash+1:]
+	if project == "" {
+		return nil, fmt.Errorf("invalid sentry DSN: missing project ID")
+	}
+	prefix := ""
+	if slash >= 0 {
+		prefix = "/" + path[:slash]
+	}
+
+	s := &SentryReporter{
+		endpoint: fmt.Sprintf("%s://%s%s/api/%s/store/", u.Scheme, u.Host, prefix, project),
+		auth:     fmt.Sprintf("Sentry sentry_version=7, sentry_client=grpc-guardian/1.0, sentry_key=%s", u.User.Username()),
+		client:   &http.Client{Timeout: 10 * time.Second},
+	}
+	if secret, ok := u.User.Password(); ok {
+		s.auth += ", sentry_secret=" + secret
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s, nil
+}
+
+// sentryEvent is the subset of Sentry's event payload the reporter fills in
+type sentryEvent struct {
+	EventID     string                       `json:"event_id"`
+	Timestamp   string                       `json:"timestamp"`
+	Level       string                       `json:"level"`
+	Platform    string                       `json:"platform"`
+	Logger      string                       `json:"logger"`
+	Transaction string                       `json:"transaction,omitempty"`
+	ServerName  string                       `json:"server_name,omitempty"`
+	Environment string                       `json:"environment,omitempty"`
+	Release     string                       `json:"release,omitempty"`
+	Message     string                       `json:"message,omitempty"`
+	Exception   []sentryException            `json:"exception,omitempty"`
+	Fingerprint []string                     `json:"fingerprint,omitempty"`
+	Tags        map[string]string            `json:"tags,omitempty"`
+	User        map[string]string            `json:"user,omitempty"`
+	Contexts    map[string]map[string]string `json:"contexts,omitempty"`
+	Extra       map[string]string            `json:"extra,omitempty"`
+}
+
+type sentryException struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// Report sends the event to Sentry
+func (s *SentryReporter) Report(ctx context.Context, event Event) error {
+	body, err := json.Marshal(s.payload(event))
+	if err != nil {
+		return fmt.Errorf("failed to encode sentry event: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Sentry-Auth", s.auth)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("sentry report failed: %w", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("sentry report failed: %s", resp.Status)
+	}
+	return nil
+}
+
+// payload converts an event to Sentry's format
+func (s *SentryReporter) payload(event Event) sentryEvent {
+	kind := "grpc." + event.Code
+	if event.Panic {
+		kind = "panic"
+	}
+
+	tags := map[string]string{"grpc.method": event.Method, "grpc.code": event.Code}
+	for key, value := range event.Tags {
+		tags[key] = value
+	}
+	if event.TenantID != "" {
+		tags["tenant"] = event.TenantID
+	}
+
+	payload := sentryEvent{
+		EventID:     newEventID(),
+		Timestamp:   event.Time.UTC().Format(time.RFC3339Nano),
+		Level:       "error",
+		Platform:    "go",
+		Logger:      "grpc-guardian",
+		Transaction: event.Method,
+		ServerName:  s.serverName,
+		Environment: s.environment,
+		Release:     s.release,
+		Exception:   []sentryException{{Type: kind, Value: event.Message}},
+		Fingerprint: event.Fingerprint,
+		Tags:        tags,
+	}
+	if event.Panic {
+		payload.Level = "fatal"
+	}
+	if event.UserID != "" {
+		payload.User = map[string]string{"id": event.UserID}
+	}
+	if event.TraceID != "" {
+		payload.Contexts = map[string]map[string]string{"trace": {"trace_id": event.TraceID}}
+	}
+	if len(event.Stack) > 0 {
+		payload.Extra = map[string]string{"stack": string(event.Stack)}
+	}
+	return payload
+}
+
+// newEventID returns a random 32 hex digit event ID
+func newEventID() string {
+	var id [16]byte
+	_, _ = rand.Read(id[:])
+	return hex.EncodeToString(id[:])
+}
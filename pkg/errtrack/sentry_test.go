@@ -0,0 +1,61 @@
+package errtrack
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSentryReporter(t *testing.T) {
+	var got sentryEvent
+	var auth, path string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth, path = r.Header.Get("X-Sentry-Auth"), r.URL.Path
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("Failed to decode event: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	dsn := strings.Replace(server.URL, "://", "://public@", 1) + "/sentry/42"
+	reporter, err := NewSentryReporter(dsn, WithSentryEnvironment("staging"))
+	if err != nil {
+		t.Fatalf("Failed to create reporter: %v", err)
+	}
+
+	err = reporter.Report(context.Background(), Event{
+		Time:        time.Now(),
+		Method:      "/shop.v1.Orders/Get",
+		Code:        "Internal",
+		Message:     "deadlock",
+		UserID:      "user-7",
+		TraceID:     "4bf92f3577b34da6a3ce929d0e0e4736",
+		Fingerprint: []string{"/shop.v1.Orders/Get", "Internal", "deadlock"},
+	})
+	if err != nil {
+		t.Fatalf("Report failed: %v", err)
+	}
+
+	if path != "/sentry/api/42/store/" {
+		t.Errorf("Expected the store endpoint, got %s", path)
+	}
+	if !strings.Contains(auth, "sentry_key=public") {
+		t.Errorf("Expected the public key in the auth header, got %q", auth)
+	}
+	if len(got.EventID) != 32 || got.Environment != "staging" || got.User["id"] != "user-7" ||
+		got.Contexts["trace"]["trace_id"] != "4bf92f3577b34da6a3ce929d0e0e4736" || len(got.Fingerprint) != 3 {
+		t.Errorf("Unexpected event %+v", got)
+	}
+}
+
+func TestNewSentryReporter_InvalidDSN(t *testing.T) {
+	for _, dsn := range []string{"https://o1.ingest.sentry.io/42", "https://key@o1.ingest.sentry.io/"} {
+		if _, err := NewSentryReporter(dsn); err == nil {
+			t.Errorf("Expected %q to be rejected", dsn)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+// Package featureflags evaluates feature flags for the caller of a request. Providers adapt
+// a flag service (a LaunchDarkly-style HTTP API) or a local file; the FeatureFlags
+// middleware evaluates the flags of each request once and hands the decisions to handlers.
+package featureflags
+
+import (
+	"context"
+	"fmt"
+)
+
+// EvaluationContext identifies who flags are evaluated for
+type EvaluationContext struct {
+	Key        string            // Stable key for percentage rollouts: the user ID, else the tenant ID
+	UserID     string            // Authenticated user, if any
+	TenantID   string            // Tenant, if any
+	Attributes map[string]string // Extra targeting attributes, e.g. country or plan
+}
+
+// Evaluation is the value of a flag for an evaluation context
+type Evaluation struct {
+	Flag    string
+	Value   interface{} // bool, string, float64 or a JSON object, as served by the provider
+	Variant string      // Name or index of the variation served, if the provider has one
+	Reason  string      // Why the value was served, e.g. "TARGET_MATCH" or "FALLTHROUGH"
+}
+
+// Provider evaluates flags for an evaluation context. Flags the provider doesn't know are
+// left out of the result.
+type Provider interface {
+	Evaluate(ctx context.Context, evalCtx EvaluationContext, flags []string) (map[string]Evaluation, error)
+}
+
+// ProviderFunc adapts a function to Provider
+type ProviderFunc func(ctx context.Context, evalCtx EvaluationContext, flags []string) (map[string]Evaluation, error)
+
+// Evaluate calls f
+func (f ProviderFunc) Evaluate(ctx context.Context, evalCtx EvaluationContext, flags []string) (map[string]Evaluation, error) {
+	return f(ctx, evalCtx, flags)
+}
+
+// Decisions are the flags evaluated for a request
+type Decisions map[string]Evaluation
+
+// Enabled reports whether a boolean flag is on; unknown flags are off
+func (d Decisions) Enabled(flag string) bool {
+	enabled, _ := d[flag].Value.(bool)
+	return enabled
+}
+
+// Value returns the value of a flag
+func (d Decisions) Value(flag string) (interface{}, bool) {
+	evaluation, ok := d[flag]
+	if !ok {
+		return nil, false
+	}
+	return evaluation.Value, true
+}
+
+// String returns the value of a string flag, or fallback when it is unknown or not a string
+func (d Decisions) String(flag, fallback string) string {
+	if value, ok := d[flag].Value.(string); ok {
+		return value
+	}
+	return fallback
+}
+
+// Format returns the value of a flag as text, e.g. for logs and span attributes
+func (e Evaluation) Format() string {
+	if e.Value == nil {
+		return ""
+	}
+	return fmt.Sprint(e.Value)
+}
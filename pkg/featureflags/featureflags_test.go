@@ -0,0 +1,205 @@
+package featureflags
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func writeFlags(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFileProvider_Rules(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "flags.json")
+	writeFlags(t, path, `{"flags": {
+		"everyone": {"enabled": true},
+		"disabled": {"enabled": false, "users": ["alice"]},
+		"targeted": {"enabled": true, "users": ["alice"], "tenants": ["acme"], "percentage": 0},
+		"backend":  {"enabled": true, "value": "v2", "default": "v1", "percentage": 0}
+	}}`)
+	provider := NewFileProvider(path)
+
+	flags := []string{"everyone", "disabled", "targeted", "backend", "unknown"}
+	decisions, err := provider.Evaluate(context.Background(), EvaluationContext{Key: "alice", UserID: "alice"}, flags)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := Decisions(decisions)
+
+	if !got.Enabled("everyone") || got["everyone"].Reason != ReasonFallthrough {
+		t.Errorf("Expected a flag without rules to be on, got %+v", got["everyone"])
+	}
+	if got.Enabled("disabled") || got["disabled"].Reason != ReasonDisabled {
+		t.Errorf("Expected a disabled flag to be off for targeted users, got %+v", got["disabled"])
+	}
+	if !got.Enabled("targeted") || got["targeted"].Reason != ReasonTargetMatch {
+		t.Errorf("Expected a targeted user to get the flag, got %+v", got["targeted"])
+	}
+	if v := got.String("backend", ""); v != "v1" {
+		t.Errorf("Expected the default value outside the rollout, got %q", v)
+	}
+	if _, ok := got.Value("unknown"); ok {
+		t.Error("Expected unknown flags to be left out")
+	}
+
+	decisions, err = provider.Evaluate(context.Background(), EvaluationContext{Key: "acme", TenantID: "acme"}, []string{"targeted"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !Decisions(decisions).Enabled("targeted") {
+		t.Error("Expected a targeted tenant to get the flag")
+	}
+}
+
+func TestFileProvider_Rollout(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "flags.json")
+	writeFlags(t, path, `{"flags": {"rollout": {"enabled": true, "percentage": 25}}}`)
+	provider := NewFileProvider(path)
+
+	on := 0
+	for i := 0; i < 4000; i++ {
+		evalCtx := EvaluationContext{Key: fmt.Sprintf("user-%d", i)}
+		first, _ := provider.Evaluate(context.Background(), evalCtx, []string{"rollout"})
+		second, _ := provider.Evaluate(context.Background(), evalCtx, []string{"rollout"})
+		if first["rollout"].Value != second["rollout"].Value {
+			t.Fatalf("Expected rollouts to be sticky for %s", evalCtx.Key)
+		}
+		if Decisions(first).Enabled("rollout") {
+			on++
+		}
+	}
+	if on < 800 || on > 1200 {
+		t.Errorf("Expected about 25%% of keys in the rollout, got %d of 4000", on)
+	}
+}
+
+func TestFileProvider_Reload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "flags.json")
+	provider := NewFileProvider(path)
+
+	decisions, err := provider.Evaluate(context.Background(), EvaluationContext{}, []string{"flag"})
+	if err != nil || len(decisions) != 0 {
+		t.Fatalf("Expected a missing file to define no flags, got %v, %v", decisions, err)
+	}
+
+	writeFlags(t, path, `{"flags": {"flag": {"enabled": true}}}`)
+	decisions, _ = provider.Evaluate(context.Background(), EvaluationContext{}, []string{"flag"})
+	if !Decisions(decisions).Enabled("flag") {
+		t.Error("Expected the new file to be read")
+	}
+
+	writeFlags(t, path, `{"flags": {"flag": {"enabled": false}}} `)
+	decisions, _ = provider.Evaluate(context.Background(), EvaluationContext{}, []string{"flag"})
+	if Decisions(decisions).Enabled("flag") {
+		t.Error("Expected the changed file to be read")
+	}
+
+	writeFlags(t, path, `{"flags": `)
+	if _, err := provider.Evaluate(context.Background(), EvaluationContext{}, []string{"flag"}); err == nil {
+		t.Error("Expected an invalid file to fail")
+	}
+}
+
+func TestHTTPProvider_Evaluate(t *testing.T) {
+	var calls atomic.Int32
+	var gotContext map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		prefix := "/sdk/evalx/env-123/contexts/"
+		if !strings.HasPrefix(r.URL.Path, prefix) || r.URL.Query().Get("withReasons") != "true" {
+			http.NotFound(w, r)
+			return
+		}
+		data, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(r.URL.Path, prefix))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		_ = json.Unmarshal(data, &gotContext)
+		_, _ = w.Write([]byte(`{
+			"new-checkout": {"value": true, "variation": 0, "version": 4, "reason": {"kind": "RULE_MATCH"}},
+			"search-backend": {"value": "v2", "variation": 1},
+			"other": {"value": false}
+		}`))
+	}))
+	defer server.Close()
+
+	provider := NewHTTPProvider(server.URL, "env-123")
+	evalCtx := EvaluationContext{Key: "alice", UserID: "alice", TenantID: "acme", Attributes: map[string]string{"plan": "pro"}}
+
+	decisions, err := provider.Evaluate(context.Background(), evalCtx, []string{"new-checkout", "search-backend", "missing"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(decisions) != 2 {
+		t.Errorf("Expected only the requested flags, got %v", decisions)
+	}
+	want := Evaluation{Flag: "new-checkout", Value: true, Variant: "0", Reason: "RULE_MATCH"}
+	if decisions["new-checkout"] != want {
+		t.Errorf("Expected %+v, got %+v", want, decisions["new-checkout"])
+	}
+	if got := Decisions(decisions).String("search-backend", ""); got != "v2" {
+		t.Errorf("Expected v2, got %q", got)
+	}
+
+	if gotContext["kind"] != "multi" {
+		t.Errorf("Expected a multi-context for a user of a tenant, got %v", gotContext)
+	}
+	user, _ := gotContext["user"].(map[string]interface{})
+	tenant, _ := gotContext["tenant"].(map[string]interface{})
+	if user["key"] != "alice" || user["plan"] != "pro" || tenant["key"] != "acme" {
+		t.Errorf("Unexpected context %v", gotContext)
+	}
+
+	if _, err := provider.Evaluate(context.Background(), evalCtx, []string{"other"}); err != nil {
+		t.Fatal(err)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("Expected the flags of a context to be cached, got %d calls", calls.Load())
+	}
+}
+
+func TestHTTPProvider_Errors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "sdk-key" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(`{"flag": {"value": true}}`))
+	}))
+	defer server.Close()
+
+	provider := NewHTTPProvider(server.URL, "", WithCacheTTL(0))
+	if _, err := provider.Evaluate(context.Background(), EvaluationContext{}, []string{"flag"}); err == nil {
+		t.Error("Expected an error for a rejected request")
+	}
+
+	provider = NewHTTPProvider(server.URL, "", WithAuthorization("sdk-key"), WithCacheTTL(time.Minute))
+	decisions, err := provider.Evaluate(context.Background(), EvaluationContext{}, []string{"flag"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !Decisions(decisions).Enabled("flag") {
+		t.Error("Expected the relay path with an SDK key to serve the flag")
+	}
+}
+
+func TestLDContext_Anonymous(t *testing.T) {
+	got := ldContext(EvaluationContext{})
+	if got["kind"] != "user" || got["key"] != "anonymous" || got["anonymous"] != true {
+		t.Errorf("Expected an anonymous user context, got %v", got)
+	}
+}
@@ -0,0 +1,149 @@
+package featureflags
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"sync"
+	"time"
+)
+
+// Evaluation reasons of FileProvider
+const (
+	ReasonDisabled    = "DISABLED"     // The flag is turned off
+	ReasonTargetMatch = "TARGET_MATCH" // The user or tenant is targeted
+	ReasonRollout     = "ROLLOUT"      // The key falls inside the rollout percentage
+	ReasonFallthrough = "FALLTHROUGH"  // No rule matched
+)
+
+// FileFlag is a flag of a FileProvider file
+type FileFlag struct {
+	Enabled    bool        `json:"enabled"`
+	Users      []string    `json:"users,omitempty"`      // Users who always get Value
+	Tenants    []string    `json:"tenants,omitempty"`    // Tenants who always get Value
+	Percentage *float64    `json:"percentage,omitempty"` // Share of keys (0-100) that get Value; unset means everyone
+	Value      interface{} `json:"value,omitempty"`      // Served when the flag applies; default true
+	Default    interface{} `json:"default,omitempty"`    // Served otherwise; default false
+}
+
+// FileProvider evaluates flags defined in a JSON file, e.g. a mounted ConfigMap:
+//
+//	{"flags": {
+//	    "new-checkout":   {"enabled": true, "tenants": ["acme"], "percentage": 20},
+//	    "search-backend": {"enabled": true, "value": "v2", "default": "v1", "percentage": 50}
+//	}}
+//
+// Rollouts are sticky: a key stays in or out of the rollout while the percentage is kept.
+// The file is parsed again only when it changes; a missing file defines no flags.
+type FileProvider struct {
+	path string
+
+	mu      sync.Mutex
+	modTime time.Time
+	size    int64
+	flags   map[string]FileFlag
+}
+
+// NewFileProvider creates a FileProvider reading path
+func NewFileProvider(path string) *FileProvider {
+	return &FileProvider{path: path}
+}
+
+// Evaluate evaluates flags against the rules of the file
+func (p *FileProvider) Evaluate(ctx context.Context, evalCtx EvaluationContext, flags []string) (map[string]Evaluation, error) {
+	definitions, err := p.load()
+	if err != nil {
+		return nil, err
+	}
+
+	evaluations := make(map[string]Evaluation, len(flags))
+	for _, flag := range flags {
+		definition, ok := definitions[flag]
+		if !ok {
+			continue
+		}
+		evaluations[flag] = definition.evaluate(flag, evalCtx)
+	}
+	return evaluations, nil
+}
+
+// load returns the flags of the file, parsing it when it changed
+func (p *FileProvider) load() (map[string]FileFlag, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	info, err := os.Stat(p.path)
+	if errors.Is(err, os.ErrNotExist) {
+		p.modTime, p.size, p.flags = time.Time{}, 0, nil
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if info.ModTime().Equal(p.modTime) && info.Size() == p.size {
+		return p.flags, nil
+	}
+
+	data, err := os.ReadFile(p.path)
+	if err != nil {
+		return nil, err
+	}
+	var file struct {
+		Flags map[string]FileFlag `json:"flags"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", p.path, err)
+	}
+
+	p.modTime, p.size, p.flags = info.ModTime(), info.Size(), file.Flags
+	return p.flags, nil
+}
+
+// evaluate applies the rules of a flag
+func (f FileFlag) evaluate(flag string, evalCtx EvaluationContext) Evaluation {
+	on, off := f.Value, f.Default
+	if on == nil {
+		on = true
+	}
+	if off == nil {
+		off = false
+	}
+
+	switch {
+	case !f.Enabled:
+		return Evaluation{Flag: flag, Value: off, Variant: "off", Reason: ReasonDisabled}
+	case contains(f.Users, evalCtx.UserID) || contains(f.Tenants, evalCtx.TenantID):
+		return Evaluation{Flag: flag, Value: on, Variant: "on", Reason: ReasonTargetMatch}
+	case f.Percentage == nil:
+		return Evaluation{Flag: flag, Value: on, Variant: "on", Reason: ReasonFallthrough}
+	case bucket(flag, evalCtx.Key) < *f.Percentage:
+		return Evaluation{Flag: flag, Value: on, Variant: "on", Reason: ReasonRollout}
+	default:
+		return Evaluation{Flag: flag, Value: off, Variant: "off", Reason: ReasonFallthrough}
+	}
+}
+
+// contains reports whether value is a non-empty member of list
+func contains(list []string, value string) bool {
+	if value == "" {
+		return false
+	}
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// bucket maps a key to [0, 100) for a flag, so every flag splits keys independently
+func bucket(flag, key string) float64 {
+	h := fnv.New32a()
+	h.Write([]byte(flag))
+	h.Write([]byte{'.'})
+	h.Write([]byte(key))
+	return float64(h.Sum32()%10000) / 100
+}
@@ -0,0 +1,247 @@
+package featureflags
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HTTPProvider evaluates flags with a LaunchDarkly-style evaluation API: it fetches every
+// flag of an evaluation context from
+//
+//	GET {baseURL}/sdk/evalx/{environmentID}/contexts/{base64url(context)}?withReasons=true
+//
+// which LaunchDarkly's client-side endpoint, the LaunchDarkly Relay Proxy and compatible
+// flag services serve. Responses are cached per evaluation context, so a burst of requests
+// from one user costs one HTTP call.
+type HTTPProvider struct {
+	baseURL       string
+	environmentID string
+	authorization string
+	client        *http.Client
+	ttl           time.Duration
+	maxEntries    int
+
+	mu    sync.Mutex
+	cache map[string]httpCacheEntry
+}
+
+// httpCacheEntry holds the flags of an evaluation context
+type httpCacheEntry struct {
+	flags   map[string]Evaluation
+	expires time.Time
+}
+
+// HTTPOption configures an HTTPProvider
+type HTTPOption func(*HTTPProvider)
+
+// WithHTTPClient sets the HTTP client, e.g. for mTLS
+func WithHTTPClient(client *http.Client) HTTPOption {
+	return func(p *HTTPProvider) {
+		if client != nil {
+			p.client = client
+		}
+	}
+}
+
+// WithAuthorization sets the Authorization header, e.g. the SDK key for a relay proxy
+func WithAuthorization(key string) HTTPOption {
+	return func(p *HTTPProvider) {
+		p.authorization = key
+	}
+}
+
+// WithCacheTTL sets how long the flags of an evaluation context are cached; zero disables
+// the cache
+// Default: 30s
+func WithCacheTTL(ttl time.Duration) HTTPOption {
+	return func(p *HTTPProvider) {
+		if ttl >= 0 {
+			p.ttl = ttl
+		}
+	}
+}
+
+// WithCacheSize bounds the number of cached evaluation contexts
+// Default: 10000
+func WithCacheSize(entries int) HTTPOption {
+	return func(p *HTTPProvider) {
+		if entries > 0 {
+			p.maxEntries = entries
+		}
+	}
+}
+
+// NewHTTPProvider creates an HTTPProvider for an environment of the flag service
+//
+// Example usage:
+//
+//	provider := featureflags.NewHTTPProvider("https://clientsdk.launchdarkly.com", clientSideID,
+//	    featureflags.WithCacheTTL(time.Minute),
+//	)
+func NewHTTPProvider(baseURL, environmentID string, opts ...HTTPOption) *HTTPProvider {
+	p := &HTTPProvider{
+		baseURL:       strings.TrimSuffix(baseURL, "/"),
+		environmentID: environmentID,
+		client:        &http.Client{Timeout: 5 * time.Second},
+		ttl:           30 * time.Second,
+		maxEntries:    10000,
+		cache:         make(map[string]httpCacheEntry),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// Evaluate returns the requested flags served for the evaluation context
+func (p *HTTPProvider) Evaluate(ctx context.Context, evalCtx EvaluationContext, flags []string) (map[string]Evaluation, error) {
+	encoded, err := json.Marshal(ldContext(evalCtx))
+	if err != nil {
+		return nil, err
+	}
+	key := string(encoded)
+
+	all, ok := p.cached(key)
+	if !ok {
+		all, err = p.fetch(ctx, encoded)
+		if err != nil {
+			return nil, err
+		}
+		p.store(key, all)
+	}
+
+	evaluations := make(map[string]Evaluation, len(flags))
+	for _, flag := range flags {
+		if evaluation, ok := all[flag]; ok {
+			evaluations[flag] = evaluation
+		}
+	}
+	return evaluations, nil
+}
+
+// fetch calls the evaluation API for an encoded context
+func (p *HTTPProvider) fetch(ctx context.Context, encoded []byte) (map[string]Evaluation, error) {
+	path := "/sdk/evalx/contexts/"
+	if p.environmentID != "" {
+		path = "/sdk/evalx/" + p.environmentID + "/contexts/"
+	}
+	url := p.baseURL + path + base64.RawURLEncoding.EncodeToString(encoded) + "?withReasons=true"
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if p.authorization != "" {
+		req.Header.Set("Authorization", p.authorization)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("flag evaluation failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("flag evaluation failed: unexpected status %s", resp.Status)
+	}
+
+	var body map[string]struct {
+		Value     interface{} `json:"value"`
+		Variation *int        `json:"variation"`
+		Reason    *struct {
+			Kind string `json:"kind"`
+		} `json:"reason"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("flag evaluation failed: %w", err)
+	}
+
+	flags := make(map[string]Evaluation, len(body))
+	for flag, result := range body {
+		evaluation := Evaluation{Flag: flag, Value: result.Value}
+		if result.Variation != nil {
+			evaluation.Variant = strconv.Itoa(*result.Variation)
+		}
+		if result.Reason != nil {
+			evaluation.Reason = result.Reason.Kind
+		}
+		flags[flag] = evaluation
+	}
+	return flags, nil
+}
+
+// cached returns the unexpired flags of a context
+func (p *HTTPProvider) cached(key string) (map[string]Evaluation, bool) {
+	if p.ttl == 0 {
+		return nil, false
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	entry, ok := p.cache[key]
+	if !ok || time.Now().After(entry.expires) {
+		return nil, false
+	}
+	return entry.flags, true
+}
+
+// store caches the flags of a context, dropping expired entries when the cache is full
+func (p *HTTPProvider) store(key string, flags map[string]Evaluation) {
+	if p.ttl == 0 {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	if len(p.cache) >= p.maxEntries {
+		for k, entry := range p.cache {
+			if now.After(entry.expires) {
+				delete(p.cache, k)
+			}
+		}
+		if len(p.cache) >= p.maxEntries {
+			p.cache = make(map[string]httpCacheEntry)
+		}
+	}
+	p.cache[key] = httpCacheEntry{flags: flags, expires: now.Add(p.ttl)}
+}
+
+// ldContext converts an evaluation context to a LaunchDarkly context: a user context with
+// the attributes, joined with a tenant context in a multi-context when there is a tenant
+func ldContext(evalCtx EvaluationContext) map[string]interface{} {
+	user := map[string]interface{}{"kind": "user", "key": evalCtx.UserID}
+	if evalCtx.UserID == "" {
+		key := evalCtx.Key
+		if key == "" {
+			key = "anonymous"
+		}
+		user["key"] = key
+		user["anonymous"] = true
+	}
+	for name, value := range evalCtx.Attributes {
+		if name != "kind" && name != "key" && name != "anonymous" {
+			user[name] = value
+		}
+	}
+
+	if evalCtx.TenantID == "" {
+		return user
+	}
+	delete(user, "kind")
+	return map[string]interface{}{
+		"kind":   "multi",
+		"user":   user,
+		"tenant": map[string]interface{}{"key": evalCtx.TenantID},
+	}
+}
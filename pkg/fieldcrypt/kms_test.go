@@ -0,0 +1,148 @@
+package fieldcrypt
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestLocalKMS(t *testing.T) {
+	ctx := context.Background()
+	oldKey, newKey := make([]byte, 16), make([]byte, 32)
+	rand.Read(oldKey)
+	rand.Read(newKey)
+
+	kms, err := NewLocalKMS("old", oldKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ciphertext, err := kms.Encrypt(ctx, []byte("123-45-6789"), []byte("users.Profile.ssn"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(ciphertext, []byte("123-45-6789")) {
+		t.Fatal("Expected the plaintext to be encrypted")
+	}
+
+	// A ciphertext is bound to its additional data
+	if _, err := kms.Decrypt(ctx, ciphertext, []byte("users.User.password")); !errors.Is(err, ErrDecrypt) {
+		t.Errorf("Expected ErrDecrypt for other additional data, got %v", err)
+	}
+	if _, err := kms.Decrypt(ctx, ciphertext[:5], []byte("users.Profile.ssn")); !errors.Is(err, ErrDecrypt) {
+		t.Errorf("Expected ErrDecrypt for a truncated ciphertext, got %v", err)
+	}
+
+	// Old keys keep decrypting after rotation
+	if err := kms.Rotate("new"); err == nil {
+		t.Error("Expected rotating to an unknown key to fail")
+	}
+	if err := kms.AddKey("new", newKey); err != nil {
+		t.Fatal(err)
+	}
+	if err := kms.Rotate("new"); err != nil {
+		t.Fatal(err)
+	}
+	if plaintext, err := kms.Decrypt(ctx, ciphertext, []byte("users.Profile.ssn")); err != nil || string(plaintext) != "123-45-6789" {
+		t.Errorf("Decrypt() = %q, %v, want the old key to decrypt", plaintext, err)
+	}
+	rotated, _ := kms.Encrypt(ctx, []byte("x"), nil)
+	if string(rotated[1:1+rotated[0]]) != "new" {
+		t.Errorf("Expected new ciphertexts to use the new key, got %q", rotated[1:1+rotated[0]])
+	}
+
+	if _, err := NewLocalKMS("bad", make([]byte, 10)); err == nil {
+		t.Error("Expected an invalid AES key to be rejected")
+	}
+	if err := kms.AddKey("", newKey); err == nil {
+		t.Error("Expected an empty key ID to be rejected")
+	}
+}
+
+// fakeAWSKMS prefixes plaintexts with the key and field of the encryption context
+type fakeAWSKMS struct {
+	contexts []map[string]string
+}
+
+func (f *fakeAWSKMS) Encrypt(ctx context.Context, keyID string, plaintext []byte, encryptionContext map[string]string) ([]byte, error) {
+	f.contexts = append(f.contexts, encryptionContext)
+	return append([]byte(keyID+":"+encryptionContext["field"]+":"), plaintext...), nil
+}
+
+func (f *fakeAWSKMS) Decrypt(ctx context.Context, ciphertext []byte, encryptionContext map[string]string) ([]byte, error) {
+	plaintext, ok := bytes.CutPrefix(ciphertext, []byte("alias/fields:"+encryptionContext["field"]+":"))
+	if !ok {
+		return nil, errors.New("InvalidCiphertextException")
+	}
+	return plaintext, nil
+}
+
+func TestAWSKMS(t *testing.T) {
+	ctx := context.Background()
+	client := &fakeAWSKMS{}
+	kms := NewAWSKMS(client, "alias/fields")
+
+	ciphertext, err := kms.Encrypt(ctx, []byte("secret"), []byte("users.User.password"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if client.contexts[0]["field"] != "users.User.password" {
+		t.Errorf("Expected the field in the encryption context, got %v", client.contexts[0])
+	}
+	if plaintext, err := kms.Decrypt(ctx, ciphertext, []byte("users.User.password")); err != nil || string(plaintext) != "secret" {
+		t.Errorf("Decrypt() = %q, %v", plaintext, err)
+	}
+	if _, err := kms.Decrypt(ctx, ciphertext, []byte("users.Profile.ssn")); !errors.Is(err, ErrDecrypt) {
+		t.Errorf("Expected ErrDecrypt, got %v", err)
+	}
+}
+
+func TestVaultTransit(t *testing.T) {
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.Path+" "+r.Header.Get("X-Vault-Token")+" "+r.Header.Get("X-Vault-Namespace"))
+
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		if body["context"] != base64.StdEncoding.EncodeToString([]byte("users.Profile.ssn")) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/secrets/encrypt/payments":
+			json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]string{"ciphertext": "vault:v1:" + body["plaintext"]}})
+		case "/v1/secrets/decrypt/payments":
+			json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]string{"plaintext": body["ciphertext"][len("vault:v1:"):]}})
+		}
+	}))
+	defer server.Close()
+
+	kms := NewVaultTransit("payments",
+		WithVaultAddress(server.URL+"/"),
+		WithVaultToken("s.token"),
+		WithVaultMount("/secrets/"),
+		WithVaultNamespace("team-a"),
+		WithVaultDerivedKey(),
+	)
+
+	ctx := context.Background()
+	ciphertext, err := kms.Encrypt(ctx, []byte("123-45-6789"), []byte("users.Profile.ssn"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plaintext, err := kms.Decrypt(ctx, ciphertext, []byte("users.Profile.ssn")); err != nil || string(plaintext) != "123-45-6789" {
+		t.Errorf("Decrypt() = %q, %v", plaintext, err)
+	}
+	if requests[0] != "/v1/secrets/encrypt/payments s.token team-a" {
+		t.Errorf("Unexpected request %q", requests[0])
+	}
+
+	if _, err := kms.Decrypt(ctx, ciphertext, []byte("users.User.password")); !errors.Is(err, ErrDecrypt) {
+		t.Errorf("Expected ErrDecrypt when Vault rejects the context, got %v", err)
+	}
+}
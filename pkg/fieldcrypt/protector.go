@@ -0,0 +1,288 @@
+package fieldcrypt
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// EncryptedPrefix marks encrypted field values. String fields carry it followed by the
+// base64url ciphertext; bytes fields followed by the raw ciphertext.
+const EncryptedPrefix = "enc:"
+
+// Protector encrypts and tokenizes proto fields selected by field path, following the
+// logging.Redactor rules: a single name ("ssn") matches that field at any depth; a dotted
+// path ("card.number", "*.ssn") matches from the message root, where "*" matches any one
+// field. List elements share the path of their field and map keys are path segments.
+//
+// Encrypted fields must be string or bytes fields; tokenized fields must be string fields.
+type Protector struct {
+	kms       KMS
+	encrypted [][]string
+	tokenized [][]string
+	tokenizer Tokenizer
+}
+
+// ProtectorOption configures a Protector
+type ProtectorOption func(*Protector)
+
+// WithEncryptedFields encrypts the given field paths with the KMS
+func WithEncryptedFields(paths ...string) ProtectorOption {
+	return func(p *Protector) {
+		p.encrypted = append(p.encrypted, parsePaths(paths)...)
+	}
+}
+
+// WithTokenizedFields replaces the given field paths with tokens. Tokens keep no
+// information about the value and, with a stable tokenizer, can still be joined on.
+func WithTokenizedFields(tokenizer Tokenizer, paths ...string) ProtectorOption {
+	return func(p *Protector) {
+		p.tokenizer = tokenizer
+		p.tokenized = append(p.tokenized, parsePaths(paths)...)
+	}
+}
+
+// NewProtector creates a protector encrypting with kms. kms may be nil when only
+// tokenized fields are configured.
+//
+// Example usage:
+//
+//	kms, _ := fieldcrypt.NewLocalKMS("2024-06", key)
+//	protector := fieldcrypt.NewProtector(kms,
+//	    fieldcrypt.WithEncryptedFields("ssn", "card.number"),
+//	    fieldcrypt.WithTokenizedFields(fieldcrypt.NewMemoryTokenizer(), "email"),
+//	)
+func NewProtector(kms KMS, opts ...ProtectorOption) *Protector {
+	p := &Protector{kms: kms}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	return p
+}
+
+// parsePaths splits field paths into segments
+func parsePaths(paths []string) [][]string {
+	rules := make([][]string, 0, len(paths))
+	for _, path := range paths {
+		if path != "" {
+			rules = append(rules, strings.Split(path, "."))
+		}
+	}
+	return rules
+}
+
+// Protect encrypts and tokenizes the configured fields of msg in place
+func (p *Protector) Protect(ctx context.Context, msg proto.Message) error {
+	return p.walk(ctx, msg.ProtoReflect(), nil, p.protect)
+}
+
+// Unprotect decrypts and detokenizes the configured fields of msg in place. Values that
+// are not encrypted or tokenized, e.g. plaintext sent by a client, are left as they are.
+func (p *Protector) Unprotect(ctx context.Context, msg proto.Message) error {
+	return p.walk(ctx, msg.ProtoReflect(), nil, p.unprotect)
+}
+
+// FieldError reports the field a value could not be protected or unprotected in
+type FieldError struct {
+	Path string
+	Err  error
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("field %s: %v", e.Path, e.Err)
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// transform rewrites one matched value
+type transform func(ctx context.Context, fd protoreflect.FieldDescriptor, v protoreflect.Value, encrypt bool) (protoreflect.Value, error)
+
+// walk applies fn to every populated value matching a rule
+func (p *Protector) walk(ctx context.Context, m protoreflect.Message, path []string, fn transform) error {
+	var err error
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		fieldPath := append(path[:len(path):len(path)], string(fd.Name()))
+
+		switch {
+		case fd.IsList():
+			encrypt, matched := p.match(fieldPath)
+			list := m.Mutable(fd).List()
+			for i := 0; i < list.Len() && err == nil; i++ {
+				if matched {
+					var out protoreflect.Value
+					if out, err = fn(ctx, fd, list.Get(i), encrypt); err == nil {
+						list.Set(i, out)
+					}
+				} else if isMessage(fd) {
+					err = p.walk(ctx, list.Get(i).Message(), fieldPath, fn)
+				}
+			}
+
+		case fd.IsMap():
+			mv := m.Mutable(fd).Map()
+			mv.Range(func(k protoreflect.MapKey, value protoreflect.Value) bool {
+				keyPath := append(fieldPath[:len(fieldPath):len(fieldPath)], k.String())
+				encrypt, matched := p.match(keyPath)
+				if !matched {
+					encrypt, matched = p.match(fieldPath)
+				}
+				switch {
+				case matched:
+					var out protoreflect.Value
+					if out, err = fn(ctx, fd.MapValue(), value, encrypt); err == nil {
+						mv.Set(k, out)
+					}
+				case isMessage(fd.MapValue()):
+					err = p.walk(ctx, value.Message(), keyPath, fn)
+				}
+				return err == nil
+			})
+
+		default:
+			if encrypt, matched := p.match(fieldPath); matched {
+				var out protoreflect.Value
+				if out, err = fn(ctx, fd, v, encrypt); err == nil {
+					m.Set(fd, out)
+				}
+			} else if isMessage(fd) {
+				err = p.walk(ctx, m.Mutable(fd).Message(), fieldPath, fn)
+			}
+		}
+
+		if err != nil && !errors.As(err, new(*FieldError)) {
+			err = &FieldError{Path: strings.Join(fieldPath, "."), Err: err}
+		}
+		return err == nil
+	})
+	return err
+}
+
+// match reports whether a path is protected, and whether by encryption or tokenization
+func (p *Protector) match(path []string) (encrypt bool, matched bool) {
+	if matches(p.encrypted, path) {
+		return true, true
+	}
+	if matches(p.tokenized, path) {
+		return false, true
+	}
+	return false, false
+}
+
+// protect encrypts or tokenizes a value
+func (p *Protector) protect(ctx context.Context, fd protoreflect.FieldDescriptor, v protoreflect.Value, encrypt bool) (protoreflect.Value, error) {
+	if !encrypt {
+		if fd.Kind() != protoreflect.StringKind {
+			return v, errors.New("only string fields can be tokenized")
+		}
+		token, err := p.tokenizer.Tokenize(ctx, v.String())
+		if err != nil {
+			return v, err
+		}
+		return protoreflect.ValueOfString(token), nil
+	}
+
+	switch fd.Kind() {
+	case protoreflect.StringKind:
+		ciphertext, err := p.kms.Encrypt(ctx, []byte(v.String()), []byte(fd.FullName()))
+		if err != nil {
+			return v, err
+		}
+		return protoreflect.ValueOfString(EncryptedPrefix + base64.RawURLEncoding.EncodeToString(ciphertext)), nil
+
+	case protoreflect.BytesKind:
+		ciphertext, err := p.kms.Encrypt(ctx, v.Bytes(), []byte(fd.FullName()))
+		if err != nil {
+			return v, err
+		}
+		return protoreflect.ValueOfBytes(append([]byte(EncryptedPrefix), ciphertext...)), nil
+	}
+	return v, fmt.Errorf("%s fields cannot be encrypted", fd.Kind())
+}
+
+// unprotect decrypts or detokenizes a value
+func (p *Protector) unprotect(ctx context.Context, fd protoreflect.FieldDescriptor, v protoreflect.Value, encrypt bool) (protoreflect.Value, error) {
+	if !encrypt {
+		if fd.Kind() != protoreflect.StringKind {
+			return v, nil
+		}
+		value, err := p.tokenizer.Detokenize(ctx, v.String())
+		if errors.Is(err, ErrNotToken) {
+			return v, nil
+		}
+		if err != nil {
+			return v, err
+		}
+		return protoreflect.ValueOfString(value), nil
+	}
+
+	switch fd.Kind() {
+	case protoreflect.StringKind:
+		encoded, ok := strings.CutPrefix(v.String(), EncryptedPrefix)
+		if !ok {
+			return v, nil
+		}
+		ciphertext, err := base64.RawURLEncoding.DecodeString(encoded)
+		if err != nil {
+			return v, ErrDecrypt
+		}
+		plaintext, err := p.kms.Decrypt(ctx, ciphertext, []byte(fd.FullName()))
+		if err != nil {
+			return v, err
+		}
+		return protoreflect.ValueOfString(string(plaintext)), nil
+
+	case protoreflect.BytesKind:
+		ciphertext, ok := bytes.CutPrefix(v.Bytes(), []byte(EncryptedPrefix))
+		if !ok {
+			return v, nil
+		}
+		plaintext, err := p.kms.Decrypt(ctx, ciphertext, []byte(fd.FullName()))
+		if err != nil {
+			return v, err
+		}
+		return protoreflect.ValueOfBytes(plaintext), nil
+	}
+	return v, nil
+}
+
+// isMessage reports whether a field holds messages
+func isMessage(fd protoreflect.FieldDescriptor) bool {
+	return fd.Kind() == protoreflect.MessageKind || fd.Kind() == protoreflect.GroupKind
+}
+
+// matches reports whether any rule matches the field path
+func matches(rules [][]string, path []string) bool {
+	for _, rule := range rules {
+		if len(rule) == 1 && rule[0] != "*" {
+			if rule[0] == path[len(path)-1] {
+				return true
+			}
+			continue
+		}
+
+		if len(rule) != len(path) {
+			continue
+		}
+		matched := true
+		for i, segment := range rule {
+			if segment != "*" && segment != path[i] {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,111 @@
+package fieldcrypt
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func newTestKMS(t *testing.T) *LocalKMS {
+	t.Helper()
+	key := make([]byte, 32)
+	rand.Read(key)
+	kms, err := NewLocalKMS("k1", key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return kms
+}
+
+func TestProtector(t *testing.T) {
+	ctx := context.Background()
+	tokenizer := NewMemoryTokenizer()
+	protector := NewProtector(newTestKMS(t),
+		WithEncryptedFields("fields.ssn.string_value"),
+		WithTokenizedFields(tokenizer, "fields.email.string_value"),
+	)
+
+	msg, _ := structpb.NewStruct(map[string]interface{}{"ssn": "123-45-6789", "email": "alice@example.org", "city": "Berlin"})
+	if err := protector.Protect(ctx, msg); err != nil {
+		t.Fatal(err)
+	}
+
+	fields := msg.GetFields()
+	if !strings.HasPrefix(fields["ssn"].GetStringValue(), EncryptedPrefix) {
+		t.Errorf("Expected ssn to be encrypted, got %q", fields["ssn"].GetStringValue())
+	}
+	token := fields["email"].GetStringValue()
+	if !strings.HasPrefix(token, "tok_") {
+		t.Errorf("Expected email to be tokenized, got %q", token)
+	}
+	if fields["city"].GetStringValue() != "Berlin" {
+		t.Errorf("Expected city to be untouched, got %q", fields["city"].GetStringValue())
+	}
+
+	// Tokens are stable
+	if again, _ := tokenizer.Tokenize(ctx, "alice@example.org"); again != token {
+		t.Errorf("Expected a stable token, got %q and %q", token, again)
+	}
+
+	if err := protector.Unprotect(ctx, msg); err != nil {
+		t.Fatal(err)
+	}
+	if fields["ssn"].GetStringValue() != "123-45-6789" || fields["email"].GetStringValue() != "alice@example.org" {
+		t.Errorf("Expected the values to be restored, got %v", msg.AsMap())
+	}
+
+	// Plaintext sent by a client is left as it is
+	if err := protector.Unprotect(ctx, msg); err != nil || fields["ssn"].GetStringValue() != "123-45-6789" {
+		t.Errorf("Expected plaintext to pass through Unprotect, got %v", err)
+	}
+}
+
+func TestProtector_Bytes(t *testing.T) {
+	ctx := context.Background()
+	protector := NewProtector(newTestKMS(t), WithEncryptedFields("value"))
+
+	msg := wrapperspb.Bytes([]byte("card-number"))
+	if err := protector.Protect(ctx, msg); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(msg.Value), EncryptedPrefix) || strings.Contains(string(msg.Value), "card-number") {
+		t.Fatalf("Expected the bytes to be encrypted, got %q", msg.Value)
+	}
+	if err := protector.Unprotect(ctx, msg); err != nil || string(msg.Value) != "card-number" {
+		t.Errorf("Unprotect() = %q, %v", msg.Value, err)
+	}
+}
+
+func TestProtector_Errors(t *testing.T) {
+	ctx := context.Background()
+	kms := newTestKMS(t)
+
+	// A ciphertext is bound to its field
+	moved := wrapperspb.String("secret")
+	if err := NewProtector(kms, WithEncryptedFields("value")).Protect(ctx, moved); err != nil {
+		t.Fatal(err)
+	}
+	msg, _ := structpb.NewStruct(map[string]interface{}{"ssn": moved.Value})
+	err := NewProtector(kms, WithEncryptedFields("string_value")).Unprotect(ctx, msg)
+	var fieldErr *FieldError
+	if !errors.As(err, &fieldErr) || !errors.Is(err, ErrDecrypt) || fieldErr.Path != "fields.ssn.string_value" {
+		t.Errorf("Expected a FieldError wrapping ErrDecrypt, got %v", err)
+	}
+
+	// Only string and bytes fields can be encrypted, and only string fields tokenized
+	if err := NewProtector(kms, WithEncryptedFields("value")).Protect(ctx, wrapperspb.Int64(42)); err == nil {
+		t.Error("Expected encrypting an int64 field to fail")
+	}
+	if err := NewProtector(nil, WithTokenizedFields(NewMemoryTokenizer(), "value")).Protect(ctx, wrapperspb.Bytes([]byte("x"))); err == nil {
+		t.Error("Expected tokenizing a bytes field to fail")
+	}
+
+	if _, err := NewMemoryTokenizer().Detokenize(ctx, "tok_unknown"); err == nil || errors.Is(err, ErrNotToken) {
+		t.Errorf("Expected an unknown token error, got %v", err)
+	}
+}
@@ -0,0 +1,82 @@
+package fieldcrypt
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"sync"
+)
+
+// ErrNotToken is returned by Detokenize for values that are not tokens, e.g. plaintext
+// sent by a client
+var ErrNotToken = errors.New("not a token")
+
+// Tokenizer replaces values with opaque tokens and resolves tokens back to values.
+// Unlike ciphertexts, tokens carry no information about the value.
+type Tokenizer interface {
+	Tokenize(ctx context.Context, value string) (string, error)
+	Detokenize(ctx context.Context, token string) (string, error)
+}
+
+// MemoryTokenizer keeps tokens in memory. Tokens are stable: a value always gets the same
+// token. Use it for tests and single-replica services; share a token vault otherwise.
+type MemoryTokenizer struct {
+	prefix string
+
+	mu     sync.RWMutex
+	tokens map[string]string // value -> token
+	values map[string]string // token -> value
+}
+
+// NewMemoryTokenizer creates an in-memory tokenizer with tokens like "tok_3f9a..."
+func NewMemoryTokenizer() *MemoryTokenizer {
+	return &MemoryTokenizer{
+		prefix: "tok_",
+		tokens: make(map[string]string),
+		values: make(map[string]string),
+	}
+}
+
+// Tokenize returns the token of a value
+func (m *MemoryTokenizer) Tokenize(ctx context.Context, value string) (string, error) {
+	m.mu.RLock()
+	token, ok := m.tokens[value]
+	m.mu.RUnlock()
+	if ok {
+		return token, nil
+	}
+
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if token, ok := m.tokens[value]; ok {
+		return token, nil
+	}
+	token = m.prefix + hex.EncodeToString(random)
+	m.tokens[value] = token
+	m.values[token] = value
+	return token, nil
+}
+
+// Detokenize returns the value of a token
+func (m *MemoryTokenizer) Detokenize(ctx context.Context, token string) (string, error) {
+	if !strings.HasPrefix(token, m.prefix) {
+		return "", ErrNotToken
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	value, ok := m.values[token]
+	if !ok {
+		return "", errors.New("unknown token")
+	}
+	return value, nil
+}
@@ -0,0 +1,175 @@
+package fieldcrypt
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// VaultTransit encrypts with a key of the HashiCorp Vault transit secrets engine
+type VaultTransit struct {
+	address   string
+	token     string
+	mount     string
+	key       string
+	namespace string
+	derived   bool
+	client    *http.Client
+}
+
+// VaultOption configures the Vault transit KMS
+type VaultOption func(*VaultTransit)
+
+// WithVaultAddress sets the Vault address
+// Default: VAULT_ADDR, or http://127.0.0.1:8200
+func WithVaultAddress(address string) VaultOption {
+	return func(v *VaultTransit) {
+		if address != "" {
+			v.address = strings.TrimSuffix(address, "/")
+		}
+	}
+}
+
+// WithVaultToken sets the Vault token
+// Default: VAULT_TOKEN
+func WithVaultToken(token string) VaultOption {
+	return func(v *VaultTransit) {
+		v.token = token
+	}
+}
+
+// WithVaultMount sets the mount path of the transit engine
+// Default: transit
+func WithVaultMount(mount string) VaultOption {
+	return func(v *VaultTransit) {
+		if mount != "" {
+			v.mount = strings.Trim(mount, "/")
+		}
+	}
+}
+
+// WithVaultNamespace sets the Vault Enterprise namespace
+func WithVaultNamespace(namespace string) VaultOption {
+	return func(v *VaultTransit) {
+		v.namespace = namespace
+	}
+}
+
+// WithVaultDerivedKey sends the additional data as the key derivation context. The
+// transit key must be created with derived=true.
+func WithVaultDerivedKey() VaultOption {
+	return func(v *VaultTransit) {
+		v.derived = true
+	}
+}
+
+// WithVaultHTTPClient sets the HTTP client, e.g. for mTLS
+func WithVaultHTTPClient(client *http.Client) VaultOption {
+	return func(v *VaultTransit) {
+		if client != nil {
+			v.client = client
+		}
+	}
+}
+
+// NewVaultTransit creates a KMS for a transit key
+//
+// Example usage:
+//
+//	kms := fieldcrypt.NewVaultTransit("payments",
+//	    fieldcrypt.WithVaultAddress("https://vault.internal:8200"),
+//	    fieldcrypt.WithVaultToken(token),
+//	)
+func NewVaultTransit(key string, opts ...VaultOption) *VaultTransit {
+	v := &VaultTransit{
+		address: "http://127.0.0.1:8200",
+		token:   os.Getenv("VAULT_TOKEN"),
+		mount:   "transit",
+		key:     key,
+		client:  &http.Client{Timeout: 5 * time.Second},
+	}
+	if address := os.Getenv("VAULT_ADDR"); address != "" {
+		v.address = strings.TrimSuffix(address, "/")
+	}
+
+	for _, opt := range opts {
+		opt(v)
+	}
+
+	return v
+}
+
+// Encrypt encrypts plaintext with the transit key. The ciphertext is Vault's
+// "vault:v<version>:..." string, which names the key version for rotation.
+func (v *VaultTransit) Encrypt(ctx context.Context, plaintext, additionalData []byte) ([]byte, error) {
+	body := map[string]string{"plaintext": base64.StdEncoding.EncodeToString(plaintext)}
+	if v.derived {
+		body["context"] = base64.StdEncoding.EncodeToString(additionalData)
+	}
+
+	var resp struct {
+		Data struct {
+			Ciphertext string `json:"ciphertext"`
+		} `json:"data"`
+	}
+	if err := v.post(ctx, "encrypt", body, &resp); err != nil {
+		return nil, fmt.Errorf("vault encrypt failed: %w", err)
+	}
+	return []byte(resp.Data.Ciphertext), nil
+}
+
+// Decrypt decrypts a ciphertext of the transit key
+func (v *VaultTransit) Decrypt(ctx context.Context, ciphertext, additionalData []byte) ([]byte, error) {
+	body := map[string]string{"ciphertext": string(ciphertext)}
+	if v.derived {
+		body["context"] = base64.StdEncoding.EncodeToString(additionalData)
+	}
+
+	var resp struct {
+		Data struct {
+			Plaintext string `json:"plaintext"`
+		} `json:"data"`
+	}
+	if err := v.post(ctx, "decrypt", body, &resp); err != nil {
+		return nil, fmt.Errorf("%w: vault: %v", ErrDecrypt, err)
+	}
+	return base64.StdEncoding.DecodeString(resp.Data.Plaintext)
+}
+
+// post calls a transit endpoint for the key
+func (v *VaultTransit) post(ctx context.Context, operation string, body interface{}, out interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	url := fmt.Sprintf("%s/v1/%s/%s/%s", v.address, v.mount, operation, v.key)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if v.token != "" {
+		req.Header.Set("X-Vault-Token", v.token)
+	}
+	if v.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", v.namespace)
+	}
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
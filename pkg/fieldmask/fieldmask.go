@@ -0,0 +1,148 @@
+// Package fieldmask prunes proto messages to the fields selected by a
+// google.protobuf.FieldMask, using reflection so that it works for any message type.
+//
+// Paths are dot-separated field names ("name", "address.city"). Both proto names and
+// JSON names are accepted. Paths through repeated and map fields apply to every element
+// or value. "*" selects every field.
+package fieldmask
+
+import (
+	"fmt"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Mask is a field mask compiled against a message type
+type Mask struct {
+	root *node
+}
+
+// node is a field of the mask; a node without children keeps the whole field
+type node struct {
+	children map[protoreflect.Name]*node
+}
+
+// Parse splits a comma-separated list of paths, as sent in metadata
+func Parse(s string) []string {
+	var paths []string
+	for _, path := range strings.Split(s, ",") {
+		if path = strings.TrimSpace(path); path != "" {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// Compile validates the paths against a message type. Unknown fields, and paths through
+// fields that are not messages, are errors.
+//
+// Example usage:
+//
+//	mask, err := fieldmask.Compile(resp.ProtoReflect().Descriptor(), []string{"name", "address.city"})
+//	if err != nil {
+//	    return status.Error(codes.InvalidArgument, err.Error())
+//	}
+//	mask.Prune(resp)
+func Compile(desc protoreflect.MessageDescriptor, paths []string) (*Mask, error) {
+	root := &node{children: make(map[protoreflect.Name]*node)}
+
+	for _, path := range paths {
+		if path == "*" {
+			return &Mask{}, nil
+		}
+
+		current, currentDesc := root, desc
+		segments := strings.Split(path, ".")
+		for i, segment := range segments {
+			if currentDesc == nil {
+				return nil, fmt.Errorf("invalid field mask path %q: %s is not a message", path, strings.Join(segments[:i], "."))
+			}
+			fd := findField(currentDesc, segment)
+			if fd == nil {
+				return nil, fmt.Errorf("invalid field mask path %q: %s has no field %q", path, currentDesc.FullName(), segment)
+			}
+
+			child, ok := current.children[fd.Name()]
+			if !ok {
+				child = &node{}
+				current.children[fd.Name()] = child
+			} else if child.children == nil {
+				// A shorter path already keeps the whole field
+				break
+			}
+
+			if i == len(segments)-1 {
+				child.children = nil
+				break
+			}
+			if child.children == nil {
+				child.children = make(map[protoreflect.Name]*node)
+			}
+			current, currentDesc = child, messageOf(fd)
+		}
+	}
+	return &Mask{root: root}, nil
+}
+
+// IsAll reports whether the mask keeps every field
+func (m *Mask) IsAll() bool {
+	return m.root == nil
+}
+
+// Prune clears every field of msg outside the mask, in place
+func (m *Mask) Prune(msg proto.Message) {
+	if m.root == nil || msg == nil {
+		return
+	}
+	prune(msg.ProtoReflect(), m.root)
+}
+
+func prune(msg protoreflect.Message, mask *node) {
+	var clear []protoreflect.FieldDescriptor
+	msg.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		child, ok := mask.children[fd.Name()]
+		switch {
+		case !ok:
+			clear = append(clear, fd)
+		case child.children == nil:
+			// The whole field is kept
+		case fd.IsList():
+			list := v.List()
+			for i := 0; i < list.Len(); i++ {
+				prune(list.Get(i).Message(), child)
+			}
+		case fd.IsMap():
+			v.Map().Range(func(_ protoreflect.MapKey, value protoreflect.Value) bool {
+				prune(value.Message(), child)
+				return true
+			})
+		default:
+			prune(v.Message(), child)
+		}
+		return true
+	})
+
+	for _, fd := range clear {
+		msg.Clear(fd)
+	}
+}
+
+// findField looks a field up by proto name or JSON name
+func findField(desc protoreflect.MessageDescriptor, name string) protoreflect.FieldDescriptor {
+	fields := desc.Fields()
+	if fd := fields.ByName(protoreflect.Name(name)); fd != nil {
+		return fd
+	}
+	return fields.ByJSONName(name)
+}
+
+// messageOf returns the message type a path can continue into: the field's message, or
+// the value message of a map field
+func messageOf(fd protoreflect.FieldDescriptor) protoreflect.MessageDescriptor {
+	if fd.IsMap() {
+		return fd.MapValue().Message()
+	}
+	return fd.Message()
+}
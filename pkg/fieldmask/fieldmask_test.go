@@ -0,0 +1,109 @@
+package fieldmask
+
+import (
+	"reflect"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func testFile() *descriptorpb.FileDescriptorProto {
+	return &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("shop/orders.proto"),
+		Package: proto.String("shop"),
+		Options: &descriptorpb.FileOptions{GoPackage: proto.String("example.com/shop"), JavaPackage: proto.String("com.example.shop")},
+		MessageType: []*descriptorpb.DescriptorProto{
+			{Name: proto.String("Order"), Field: []*descriptorpb.FieldDescriptorProto{{Name: proto.String("id"), Number: proto.Int32(1)}}},
+			{Name: proto.String("Item"), Field: []*descriptorpb.FieldDescriptorProto{{Name: proto.String("sku"), Number: proto.Int32(1)}}},
+		},
+	}
+}
+
+func TestParse(t *testing.T) {
+	if got := Parse(" name, options.go_package,,"); !reflect.DeepEqual(got, []string{"name", "options.go_package"}) {
+		t.Errorf("Parse() = %q", got)
+	}
+	if got := Parse(""); got != nil {
+		t.Errorf("Parse(\"\") = %q, want nil", got)
+	}
+}
+
+func TestMask_Prune(t *testing.T) {
+	tests := []struct {
+		name  string
+		paths []string
+		want  *descriptorpb.FileDescriptorProto
+	}{
+		{
+			name:  "nested field by JSON name",
+			paths: []string{"name", "options.goPackage"},
+			want:  &descriptorpb.FileDescriptorProto{Name: proto.String("shop/orders.proto"), Options: &descriptorpb.FileOptions{GoPackage: proto.String("example.com/shop")}},
+		},
+		{
+			name:  "every list element",
+			paths: []string{"message_type.name"},
+			want: &descriptorpb.FileDescriptorProto{MessageType: []*descriptorpb.DescriptorProto{
+				{Name: proto.String("Order")}, {Name: proto.String("Item")},
+			}},
+		},
+		{
+			name:  "a shorter path keeps the whole field",
+			paths: []string{"message_type", "message_type.name", "package"},
+			want:  &descriptorpb.FileDescriptorProto{Package: proto.String("shop"), MessageType: testFile().MessageType},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := testFile()
+			mask, err := Compile(msg.ProtoReflect().Descriptor(), tt.paths)
+			if err != nil {
+				t.Fatal(err)
+			}
+			mask.Prune(msg)
+			if !proto.Equal(msg, tt.want) {
+				t.Errorf("Prune() = %v, want %v", msg, tt.want)
+			}
+		})
+	}
+}
+
+func TestMask_PruneMap(t *testing.T) {
+	msg, _ := structpb.NewStruct(map[string]interface{}{
+		"a": map[string]interface{}{"x": 1, "y": 2},
+		"b": 3,
+	})
+	mask, err := Compile(msg.ProtoReflect().Descriptor(), []string{"fields.struct_value"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mask.Prune(msg)
+
+	if msg.Fields["a"].GetStructValue() == nil || len(msg.Fields["a"].GetStructValue().Fields) != 2 {
+		t.Errorf("Expected nested structs to be kept, got %v", msg)
+	}
+	if _, ok := msg.Fields["b"]; !ok || msg.Fields["b"].Kind != nil {
+		t.Errorf("Expected the number of b to be cleared, got %v", msg.Fields["b"])
+	}
+}
+
+func TestCompile(t *testing.T) {
+	desc := (&descriptorpb.FileDescriptorProto{}).ProtoReflect().Descriptor()
+
+	mask, err := Compile(desc, []string{"name", "*"})
+	if err != nil || !mask.IsAll() {
+		t.Fatalf("Expected * to select every field, got %v", err)
+	}
+	msg := testFile()
+	mask.Prune(msg)
+	if !proto.Equal(msg, testFile()) {
+		t.Error("Expected a * mask to keep the message")
+	}
+
+	for _, invalid := range [][]string{{"missing"}, {"name.length"}, {"options.missing"}} {
+		if _, err := Compile(desc, invalid); err == nil {
+			t.Errorf("Compile(%q) succeeded", invalid)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+// Package geoip resolves caller addresses to a country and autonomous system. Providers
+// are pluggable: NewMaxMind adapts MaxMind-format (.mmdb) database readers such as
+// github.com/oschwald/maxminddb-golang without this module depending on them, and
+// StaticProvider maps CIDR ranges to locations for private networks and tests.
+package geoip
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+)
+
+// ErrNotFound is returned by providers that have no data for an address
+var ErrNotFound = errors.New("geoip: address not found")
+
+// Location is what is known about an address. Empty fields are unknown.
+type Location struct {
+	// Country is the ISO 3166-1 alpha-2 country code, e.g. "DE"
+	Country string `json:"country,omitempty"`
+
+	// ASN is the autonomous system number of the network, e.g. 15169
+	ASN uint32 `json:"asn,omitempty"`
+
+	// ASOrg is the organization of the autonomous system
+	ASOrg string `json:"as_org,omitempty"`
+}
+
+// IsZero reports whether nothing is known about the address
+func (l Location) IsZero() bool {
+	return l.Country == "" && l.ASN == 0 && l.ASOrg == ""
+}
+
+// Provider looks up the location of an address
+type Provider interface {
+	Lookup(addr netip.Addr) (Location, error)
+}
+
+// ProviderFunc adapts a function to the Provider interface
+type ProviderFunc func(addr netip.Addr) (Location, error)
+
+// Lookup calls f(addr)
+func (f ProviderFunc) Lookup(addr netip.Addr) (Location, error) {
+	return f(addr)
+}
+
+// MaxMindReader is the lookup method of a MaxMind database reader, as implemented by
+// *maxminddb.Reader of github.com/oschwald/maxminddb-golang
+type MaxMindReader interface {
+	Lookup(ip net.IP, result interface{}) error
+}
+
+// maxMindRecord decodes the fields used from GeoIP2/GeoLite2 Country, City and ASN
+// databases
+type maxMindRecord struct {
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+	ASN   uint32 `maxminddb:"autonomous_system_number"`
+	ASOrg string `maxminddb:"autonomous_system_organization"`
+}
+
+// MaxMind is a Provider reading MaxMind-format databases
+type MaxMind struct {
+	country MaxMindReader
+	asn     MaxMindReader
+}
+
+// NewMaxMind creates a provider from a country (or city) database and an ASN database;
+// either may be nil.
+//
+// Example usage:
+//
+//	countryDB, _ := maxminddb.Open("GeoLite2-Country.mmdb")
+//	asnDB, _ := maxminddb.Open("GeoLite2-ASN.mmdb")
+//	provider := geoip.NewMaxMind(countryDB, asnDB)
+func NewMaxMind(country, asn MaxMindReader) *MaxMind {
+	return &MaxMind{country: country, asn: asn}
+}
+
+// Lookup reads the address from both databases
+func (m *MaxMind) Lookup(addr netip.Addr) (Location, error) {
+	ip := net.IP(addr.Unmap().AsSlice())
+	var location Location
+
+	if m.country != nil {
+		var record maxMindRecord
+		if err := m.country.Lookup(ip, &record); err != nil {
+			return Location{}, fmt.Errorf("geoip: country lookup failed: %w", err)
+		}
+		location.Country = record.Country.ISOCode
+	}
+	if m.asn != nil {
+		var record maxMindRecord
+		if err := m.asn.Lookup(ip, &record); err != nil {
+			return Location{}, fmt.Errorf("geoip: ASN lookup failed: %w", err)
+		}
+		location.ASN, location.ASOrg = record.ASN, record.ASOrg
+	}
+
+	if location.IsZero() {
+		return Location{}, ErrNotFound
+	}
+	return location, nil
+}
+
+// StaticProvider maps CIDR ranges to locations. The most specific range wins.
+type StaticProvider struct {
+	entries []staticEntry
+}
+
+type staticEntry struct {
+	prefix   netip.Prefix
+	location Location
+}
+
+// NewStaticProvider creates a provider from CIDR ranges or single addresses
+//
+// Example usage:
+//
+//	provider, err := geoip.NewStaticProvider(map[string]geoip.Location{
+//	    "10.0.0.0/8":     {Country: "DE", ASOrg: "corp"},
+//	    "203.0.113.0/24": {Country: "US", ASN: 64500},
+//	})
+func NewStaticProvider(entries map[string]Location) (*StaticProvider, error) {
+	p := &StaticProvider{}
+	for cidr, location := range entries {
+		if !strings.Contains(cidr, "/") {
+			addr, err := netip.ParseAddr(cidr)
+			if err != nil {
+				return nil, fmt.Errorf("geoip: invalid range %q: %w", cidr, err)
+			}
+			cidr = netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()).String()
+		}
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("geoip: invalid range %q: %w", cidr, err)
+		}
+		p.entries = append(p.entries, staticEntry{prefix: prefix.Masked(), location: location})
+	}
+	return p, nil
+}
+
+// Lookup returns the location of the most specific range containing the address
+func (p *StaticProvider) Lookup(addr netip.Addr) (Location, error) {
+	addr = addr.Unmap()
+	best := -1
+	for i, entry := range p.entries {
+		if entry.prefix.Contains(addr) && (best < 0 || entry.prefix.Bits() > p.entries[best].prefix.Bits()) {
+			best = i
+		}
+	}
+	if best < 0 {
+		return Location{}, ErrNotFound
+	}
+	return p.entries[best].location, nil
+}
@@ -0,0 +1,95 @@
+package geoip
+
+import (
+	"errors"
+	"net"
+	"net/netip"
+	"testing"
+)
+
+func TestStaticProvider(t *testing.T) {
+	provider, err := NewStaticProvider(map[string]Location{
+		"203.0.113.0/24":   {Country: "US", ASN: 64500},
+		"203.0.113.128/25": {Country: "CA"},
+		"198.51.100.7":     {Country: "DE"},
+		"2001:db8::/32":    {Country: "JP"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		addr string
+		want Location
+	}{
+		{"203.0.113.7", Location{Country: "US", ASN: 64500}},
+		{"203.0.113.200", Location{Country: "CA"}}, // The most specific range wins
+		{"::ffff:203.0.113.7", Location{Country: "US", ASN: 64500}},
+		{"198.51.100.7", Location{Country: "DE"}},
+		{"2001:db8::1", Location{Country: "JP"}},
+	}
+	for _, tt := range tests {
+		if got, err := provider.Lookup(netip.MustParseAddr(tt.addr)); err != nil || got != tt.want {
+			t.Errorf("Lookup(%s) = %+v, %v, want %+v", tt.addr, got, err, tt.want)
+		}
+	}
+
+	for _, addr := range []string{"198.51.100.8", "10.0.0.1"} {
+		if _, err := provider.Lookup(netip.MustParseAddr(addr)); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Lookup(%s) error = %v, want ErrNotFound", addr, err)
+		}
+	}
+
+	for _, invalid := range []string{"203.0.113.0/33", "not-an-ip"} {
+		if _, err := NewStaticProvider(map[string]Location{invalid: {Country: "US"}}); err == nil {
+			t.Errorf("NewStaticProvider(%q) succeeded", invalid)
+		}
+	}
+}
+
+// fakeMaxMindReader decodes fixed records the way a maxminddb reader fills them
+type fakeMaxMindReader struct {
+	records map[string]func(*maxMindRecord)
+	err     error
+}
+
+func (r fakeMaxMindReader) Lookup(ip net.IP, result interface{}) error {
+	if r.err != nil {
+		return r.err
+	}
+	if fill, ok := r.records[ip.String()]; ok {
+		fill(result.(*maxMindRecord))
+	}
+	return nil
+}
+
+func TestMaxMind(t *testing.T) {
+	country := fakeMaxMindReader{records: map[string]func(*maxMindRecord){
+		"203.0.113.7": func(r *maxMindRecord) { r.Country.ISOCode = "US" },
+	}}
+	asn := fakeMaxMindReader{records: map[string]func(*maxMindRecord){
+		"203.0.113.7": func(r *maxMindRecord) { r.ASN, r.ASOrg = 64500, "Example Hosting" },
+		"192.0.2.1":   func(r *maxMindRecord) { r.ASN = 64501 },
+	}}
+	provider := NewMaxMind(country, asn)
+
+	if got, err := provider.Lookup(netip.MustParseAddr("::ffff:203.0.113.7")); err != nil || got != (Location{Country: "US", ASN: 64500, ASOrg: "Example Hosting"}) {
+		t.Errorf("Lookup() = %+v, %v", got, err)
+	}
+	if got, err := provider.Lookup(netip.MustParseAddr("192.0.2.1")); err != nil || got != (Location{ASN: 64501}) {
+		t.Errorf("Expected the ASN without a country, got %+v, %v", got, err)
+	}
+	if _, err := provider.Lookup(netip.MustParseAddr("10.0.0.1")); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for unknown addresses, got %v", err)
+	}
+
+	countryOnly := NewMaxMind(country, nil)
+	if got, err := countryOnly.Lookup(netip.MustParseAddr("203.0.113.7")); err != nil || got != (Location{Country: "US"}) {
+		t.Errorf("Lookup() without an ASN database = %+v, %v", got, err)
+	}
+
+	broken := NewMaxMind(fakeMaxMindReader{err: errors.New("corrupt database")}, asn)
+	if _, err := broken.Lookup(netip.MustParseAddr("203.0.113.7")); err == nil || errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected reader errors to be returned, got %v", err)
+	}
+}
@@ -0,0 +1,268 @@
+// Package health drives the standard grpc.health.v1 serving status from guardian signals,
+// so load balancers and orchestrators stop routing to an instance whose critical circuit
+// breakers are open, that is shedding load, or that is draining.
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/grpc-guardian/grpc-guardian/middleware"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// Check reports a health signal; a non-nil error marks it failing
+type Check func(ctx context.Context) error
+
+// Result is the outcome of one check
+type Result struct {
+	Name string
+	Err  error
+}
+
+// Policy turns check results into a serving status. service is "" for the overall status.
+type Policy func(service string, results []Result) healthpb.HealthCheckResponse_ServingStatus
+
+// RequireAll reports SERVING only when every check passes
+func RequireAll() Policy {
+	return func(service string, results []Result) healthpb.HealthCheckResponse_ServingStatus {
+		for _, result := range results {
+			if result.Err != nil {
+				return healthpb.HealthCheckResponse_NOT_SERVING
+			}
+		}
+		return healthpb.HealthCheckResponse_SERVING
+	}
+}
+
+// RequireFraction reports SERVING while at least the given fraction of checks pass, e.g.
+// to stay in rotation while only one of several dependencies is down
+func RequireFraction(fraction float64) Policy {
+	return func(service string, results []Result) healthpb.HealthCheckResponse_ServingStatus {
+		if len(results) == 0 {
+			return healthpb.HealthCheckResponse_SERVING
+		}
+		passing := 0
+		for _, result := range results {
+			if result.Err == nil {
+				passing++
+			}
+		}
+		if float64(passing)/float64(len(results)) >= fraction {
+			return healthpb.HealthCheckResponse_SERVING
+		}
+		return healthpb.HealthCheckResponse_NOT_SERVING
+	}
+}
+
+// ErrDraining is reported by the drain check while the server is draining
+var ErrDraining = errors.New("server is draining")
+
+// ErrOverloaded is reported by the shedding check while load is being shed
+var ErrOverloaded = errors.New("server is shedding load")
+
+// namedCheck is a registered check
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+// Reporter evaluates checks and publishes the result on a grpc health server. Global
+// checks apply to every service; service checks only to their service. The overall
+// status ("") covers all checks.
+type Reporter struct {
+	server   *grpchealth.Server
+	policy   Policy
+	interval time.Duration
+	onChange func(service string, status healthpb.HealthCheckResponse_ServingStatus, results []Result)
+
+	global   []namedCheck
+	services map[string][]namedCheck
+
+	mu   sync.Mutex
+	last map[string]healthpb.HealthCheckResponse_ServingStatus
+}
+
+// Option configures a Reporter
+type Option func(*Reporter)
+
+// WithCheck adds a check that applies to every service
+func WithCheck(name string, check Check) Option {
+	return func(r *Reporter) {
+		r.global = append(r.global, namedCheck{name: name, check: check})
+	}
+}
+
+// WithServiceCheck adds a check for one service (e.g. "shop.Orders")
+func WithServiceCheck(service, name string, check Check) Option {
+	return func(r *Reporter) {
+		r.services[service] = append(r.services[service], namedCheck{name: name, check: check})
+	}
+}
+
+// WithCircuitBreaker marks a service NOT_SERVING while the breaker guarding its critical
+// methods is open. A half-open breaker counts as serving so probe traffic can close it.
+func WithCircuitBreaker(service string, breaker *middleware.CircuitBreaker) Option {
+	return WithServiceCheck(service, "circuit_breaker", func(ctx context.Context) error {
+		if state := breaker.State(); state == middleware.StateOpen {
+			return fmt.Errorf("circuit breaker is %s", state)
+		}
+		return nil
+	})
+}
+
+// WithDrainer marks every service NOT_SERVING once the drainer starts draining
+func WithDrainer(drainer *middleware.StreamDrainer) Option {
+	return WithCheck("draining", func(ctx context.Context) error {
+		if drainer.Draining() {
+			return ErrDraining
+		}
+		return nil
+	})
+}
+
+// WithShedding marks every service NOT_SERVING while active reports that overload
+// shedding is in effect
+func WithShedding(active func() bool) Option {
+	return WithCheck("overload", func(ctx context.Context) error {
+		if active() {
+			return ErrOverloaded
+		}
+		return nil
+	})
+}
+
+// WithPolicy sets how check results map to a serving status
+// Default: RequireAll()
+func WithPolicy(policy Policy) Option {
+	return func(r *Reporter) {
+		r.policy = policy
+	}
+}
+
+// WithInterval sets how often Run re-evaluates the checks
+// Default: 1s
+func WithInterval(interval time.Duration) Option {
+	return func(r *Reporter) {
+		if interval > 0 {
+			r.interval = interval
+		}
+	}
+}
+
+// WithOnStatusChange sets a callback invoked when a service changes status
+func WithOnStatusChange(fn func(service string, status healthpb.HealthCheckResponse_ServingStatus, results []Result)) Option {
+	return func(r *Reporter) {
+		r.onChange = fn
+	}
+}
+
+// NewReporter creates a reporter publishing to server.
+//
+// Example usage:
+//
+//	healthServer := grpchealth.NewServer()
+//	healthpb.RegisterHealthServer(grpcServer, healthServer)
+//
+//	reporter := health.NewReporter(healthServer,
+//	    health.WithCircuitBreaker("shop.Payments", paymentsBreaker),
+//	    health.WithDrainer(drainer),
+//	    health.WithShedding(shedder.Active),
+//	)
+//	go reporter.Run(ctx)
+func NewReporter(server *grpchealth.Server, opts ...Option) *Reporter {
+	r := &Reporter{
+		server:   server,
+		policy:   RequireAll(),
+		interval: time.Second,
+		services: make(map[string][]namedCheck),
+		last:     make(map[string]healthpb.HealthCheckResponse_ServingStatus),
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
+}
+
+// Run evaluates the checks every interval until ctx is done
+func (r *Reporter) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		r.Update(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Update evaluates the checks once and publishes the statuses. It returns the results per
+// service, with "" holding the results of every check.
+func (r *Reporter) Update(ctx context.Context) map[string][]Result {
+	global := run(ctx, r.global)
+
+	all := append([]Result(nil), global...)
+	results := make(map[string][]Result, len(r.services)+1)
+
+	services := make([]string, 0, len(r.services))
+	for service := range r.services {
+		services = append(services, service)
+	}
+	sort.Strings(services)
+
+	for _, service := range services {
+		own := run(ctx, r.services[service])
+		all = append(all, own...)
+		results[service] = append(append([]Result(nil), global...), own...)
+	}
+	results[""] = all
+
+	r.publish("", results[""])
+	for _, service := range services {
+		r.publish(service, results[service])
+	}
+
+	return results
+}
+
+// Shutdown marks every service NOT_SERVING and ignores later updates, for the start of a
+// graceful shutdown
+func (r *Reporter) Shutdown() {
+	r.server.Shutdown()
+}
+
+// publish sets the status of one service
+func (r *Reporter) publish(service string, results []Result) {
+	status := r.policy(service, results)
+
+	r.mu.Lock()
+	previous, seen := r.last[service]
+	r.last[service] = status
+	r.mu.Unlock()
+
+	r.server.SetServingStatus(service, status)
+
+	if r.onChange != nil && (!seen || previous != status) {
+		r.onChange(service, status, results)
+	}
+}
+
+// run evaluates a list of checks
+func run(ctx context.Context, checks []namedCheck) []Result {
+	results := make([]Result, 0, len(checks))
+	for _, c := range checks {
+		results = append(results, Result{Name: c.name, Err: c.check(ctx)})
+	}
+	return results
+}
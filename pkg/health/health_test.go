@@ -0,0 +1,108 @@
+package health
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+
+	"github.com/grpc-guardian/grpc-guardian/middleware"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+func servingStatus(t *testing.T, server *grpchealth.Server, service string) healthpb.HealthCheckResponse_ServingStatus {
+	t.Helper()
+
+	resp, err := server.Check(context.Background(), &healthpb.HealthCheckRequest{Service: service})
+	if err != nil {
+		t.Fatalf("Check(%q): %v", service, err)
+	}
+	return resp.Status
+}
+
+func TestReporter(t *testing.T) {
+	breaker := middleware.NewCircuitBreaker()
+	drainer := middleware.NewStreamDrainer()
+	var shedding atomic.Bool
+
+	var changes int
+	server := grpchealth.NewServer()
+	reporter := NewReporter(server,
+		WithCircuitBreaker("shop.Payments", breaker),
+		WithServiceCheck("shop.Catalog", "database", func(ctx context.Context) error { return nil }),
+		WithShedding(shedding.Load),
+		WithDrainer(drainer),
+		WithOnStatusChange(func(string, healthpb.HealthCheckResponse_ServingStatus, []Result) { changes++ }),
+	)
+
+	reporter.Update(context.Background())
+	for _, service := range []string{"", "shop.Payments", "shop.Catalog"} {
+		if got := servingStatus(t, server, service); got != healthpb.HealthCheckResponse_SERVING {
+			t.Errorf("%q: expected SERVING, got %v", service, got)
+		}
+	}
+
+	// Open the breaker: only its service and the overall status go down
+	interceptor := breaker.UnaryServerInterceptor()
+	for i := 0; i < 10; i++ {
+		interceptor(context.Background(), "req", &grpc.UnaryServerInfo{FullMethod: "/shop.Payments/Charge"},
+			func(ctx context.Context, req interface{}) (interface{}, error) {
+				return nil, status.Error(codes.Unavailable, "down")
+			})
+	}
+	reporter.Update(context.Background())
+	if got := servingStatus(t, server, "shop.Payments"); got != healthpb.HealthCheckResponse_NOT_SERVING {
+		t.Errorf("Expected shop.Payments NOT_SERVING with open breaker, got %v", got)
+	}
+	if got := servingStatus(t, server, ""); got != healthpb.HealthCheckResponse_NOT_SERVING {
+		t.Errorf("Expected overall NOT_SERVING with open breaker, got %v", got)
+	}
+	if got := servingStatus(t, server, "shop.Catalog"); got != healthpb.HealthCheckResponse_SERVING {
+		t.Errorf("Expected shop.Catalog to stay SERVING, got %v", got)
+	}
+
+	// Shedding is global
+	breaker.Reset()
+	shedding.Store(true)
+	results := reporter.Update(context.Background())
+	if got := servingStatus(t, server, "shop.Catalog"); got != healthpb.HealthCheckResponse_NOT_SERVING {
+		t.Errorf("Expected shop.Catalog NOT_SERVING while shedding, got %v", got)
+	}
+	if len(results["shop.Catalog"]) != 3 {
+		t.Errorf("Expected global and service results for shop.Catalog, got %+v", results["shop.Catalog"])
+	}
+
+	shedding.Store(false)
+	reporter.Update(context.Background())
+	if got := servingStatus(t, server, "shop.Catalog"); got != healthpb.HealthCheckResponse_SERVING {
+		t.Errorf("Expected shop.Catalog SERVING after shedding stops, got %v", got)
+	}
+
+	// Draining
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	drainer.Drain(ctx)
+	reporter.Update(context.Background())
+	if got := servingStatus(t, server, ""); got != healthpb.HealthCheckResponse_NOT_SERVING {
+		t.Errorf("Expected NOT_SERVING while draining, got %v", got)
+	}
+
+	if changes == 0 {
+		t.Error("Expected status change callbacks")
+	}
+}
+
+func TestRequireFraction(t *testing.T) {
+	policy := RequireFraction(0.5)
+	results := []Result{{Name: "a"}, {Name: "b", Err: ErrOverloaded}}
+	if got := policy("", results); got != healthpb.HealthCheckResponse_SERVING {
+		t.Errorf("Expected SERVING with half the checks passing, got %v", got)
+	}
+	results = append(results, Result{Name: "c", Err: ErrDraining})
+	if got := policy("", results); got != healthpb.HealthCheckResponse_NOT_SERVING {
+		t.Errorf("Expected NOT_SERVING with a third of the checks passing, got %v", got)
+	}
+}
@@ -0,0 +1,278 @@
+// Package httpbridge runs a guardian chain as net/http middleware, so HTTP front doors
+// such as grpc-gateway and gRPC-Web proxies enforce the same auth, rate limits, logging and
+// metrics as the gRPC server.
+//
+// Each HTTP request goes through the chain's unary middleware as if it were a gRPC call:
+// request headers become incoming metadata, the remote address becomes the peer and the
+// HTTP outcome becomes the gRPC status code that metrics and logs see. Headers and
+// trailers set by middleware (x-ratelimit-*, x-quota-*, ...) are copied to the HTTP
+// response.
+package httpbridge
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	guardian "github.com/grpc-guardian/grpc-guardian"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// MetadataPrefix is the grpc-gateway header prefix for metadata; it is stripped from
+// header names
+const MetadataPrefix = "Grpc-Metadata-"
+
+// config configures the bridge
+type config struct {
+	method  func(r *http.Request) string
+	headers func(name string) (string, bool)
+}
+
+// Option configures the bridge
+type Option func(*config)
+
+// WithMethodResolver sets how an HTTP request maps to the full gRPC method name the chain
+// sees, e.g. for per-method routing and the method label of metrics. grpc-gateway routes
+// should map to the RPC they are bound to.
+// Default: the URL path, which is the full method for gRPC-Web requests
+func WithMethodResolver(resolve func(r *http.Request) string) Option {
+	return func(c *config) {
+		if resolve != nil {
+			c.method = resolve
+		}
+	}
+}
+
+// WithHeaderMatcher sets which HTTP headers are passed to the chain as metadata, and under
+// which key
+// Default: DefaultHeaderMatcher
+func WithHeaderMatcher(match func(name string) (string, bool)) Option {
+	return func(c *config) {
+		if match != nil {
+			c.headers = match
+		}
+	}
+}
+
+// DefaultHeaderMatcher passes every header as lower-case metadata, with the
+// Grpc-Metadata- prefix stripped, and drops connection-level headers
+func DefaultHeaderMatcher(name string) (string, bool) {
+	name = http.CanonicalHeaderKey(name)
+	switch name {
+	case "Connection", "Keep-Alive", "Proxy-Connection", "Te", "Trailer", "Transfer-Encoding", "Upgrade", "Content-Length":
+		return "", false
+	}
+	return strings.ToLower(strings.TrimPrefix(name, MetadataPrefix)), true
+}
+
+// Middleware wraps an HTTP handler with the unary middleware of a guardian chain.
+// Middleware that rejects a request (auth, rate limits, ...) answers it with the HTTP
+// equivalent of its gRPC status; gRPC-Web requests get a trailers-only gRPC-Web response.
+// The request passed to the middleware is the *http.Request, so middleware that inspects
+// proto messages (validation, caching, field masks) leaves it alone.
+//
+// Example usage:
+//
+//	chain := guardian.NewChain(
+//	    middleware.Logging(),
+//	    middleware.Metrics(),
+//	    middleware.Auth(validator),
+//	    middleware.RateLimit(100, 10),
+//	)
+//
+//	// The gRPC server and the gateway share the chain
+//	grpcServer := grpc.NewServer(chain.ServerOption()...)
+//	gateway := runtime.NewServeMux()
+//	http.ListenAndServe(":8080", httpbridge.Middleware(chain,
+//	    httpbridge.WithMethodResolver(func(r *http.Request) string {
+//	        if strings.HasPrefix(r.URL.Path, "/v1/users") {
+//	            return "/users.v1.UserService/GetUser"
+//	        }
+//	        return r.URL.Path
+//	    }),
+//	)(gateway))
+func Middleware(chain *guardian.Chain, opts ...Option) func(http.Handler) http.Handler {
+	c := &config{
+		method:  func(r *http.Request) string { return r.URL.Path },
+		headers: DefaultHeaderMatcher,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	interceptor := chain.UnaryInterceptor()
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			method := c.method(r)
+			stream := &transportStream{method: method}
+			rw := &responseWriter{ResponseWriter: w, stream: stream}
+
+			ctx := grpc.NewContextWithServerTransportStream(r.Context(), stream)
+			ctx = metadata.NewIncomingContext(ctx, c.metadata(r))
+			if addr, err := net.ResolveTCPAddr("tcp", r.RemoteAddr); err == nil {
+				ctx = peer.NewContext(ctx, &peer.Peer{Addr: addr})
+			}
+
+			info := &grpc.UnaryServerInfo{FullMethod: method}
+			_, err := interceptor(ctx, r, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+				next.ServeHTTP(rw, r.WithContext(ctx))
+				return nil, rw.err()
+			})
+
+			if err != nil && !rw.wroteHeader {
+				writeError(rw, r, err)
+				return
+			}
+			rw.flushMetadata()
+		})
+	}
+}
+
+// metadata converts the request headers to incoming metadata
+func (c *config) metadata(r *http.Request) metadata.MD {
+	md := metadata.MD{}
+	for name, values := range r.Header {
+		if key, ok := c.headers(name); ok {
+			md.Append(key, values...)
+		}
+	}
+	if r.Host != "" {
+		md.Set(":authority", r.Host)
+	}
+	return md
+}
+
+// transportStream collects the headers and trailers middleware sets with grpc.SetHeader
+// and grpc.SetTrailer
+type transportStream struct {
+	method string
+
+	mu      sync.Mutex
+	header  metadata.MD
+	trailer metadata.MD
+}
+
+func (s *transportStream) Method() string {
+	return s.method
+}
+
+func (s *transportStream) SetHeader(md metadata.MD) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.header = metadata.Join(s.header, md)
+	return nil
+}
+
+func (s *transportStream) SendHeader(md metadata.MD) error {
+	return s.SetHeader(md)
+}
+
+func (s *transportStream) SetTrailer(md metadata.MD) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.trailer = metadata.Join(s.trailer, md)
+	return nil
+}
+
+// take returns and clears the collected metadata
+func (s *transportStream) take() metadata.MD {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	md := metadata.Join(s.header, s.trailer)
+	s.header, s.trailer = nil, nil
+	return md
+}
+
+// responseWriter records the response status and copies middleware metadata to the
+// response headers when they are written
+type responseWriter struct {
+	http.ResponseWriter
+	stream *transportStream
+
+	wroteHeader bool
+	status      int
+}
+
+func (w *responseWriter) WriteHeader(code int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	w.status = code
+	for key, values := range w.stream.take() {
+		for _, value := range values {
+			w.Header().Add(key, value)
+		}
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush supports streaming responses, e.g. gRPC-Web server streams
+func (w *responseWriter) Flush() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// flushMetadata sends metadata set after the response headers as HTTP trailers
+func (w *responseWriter) flushMetadata() {
+	md := w.stream.take()
+	if len(md) == 0 {
+		return
+	}
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	for key, values := range md {
+		for _, value := range values {
+			w.Header().Add(http.TrailerPrefix+key, value)
+		}
+	}
+}
+
+// err converts the handler's response to the error middleware sees: the grpc-status of
+// gRPC-Web responses, else the gRPC equivalent of the HTTP status
+func (w *responseWriter) err() error {
+	if value := w.Header().Get("Grpc-Status"); value != "" {
+		return grpcWebStatus(value, w.Header().Get("Grpc-Message"))
+	}
+	if !w.wroteHeader || w.status < 400 {
+		return nil
+	}
+	return status.Error(CodeFromHTTPStatus(w.status), http.StatusText(w.status))
+}
+
+// grpcWebStatus parses a grpc-status header
+func grpcWebStatus(value, message string) error {
+	code, err := strconv.Atoi(value)
+	if err != nil {
+		return status.Error(codes.Unknown, message)
+	}
+	if codes.Code(code) == codes.OK {
+		return nil
+	}
+	return status.Error(codes.Code(code), message)
+}
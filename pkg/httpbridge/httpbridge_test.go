@@ -0,0 +1,121 @@
+package httpbridge
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	guardian "github.com/grpc-guardian/grpc-guardian"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+func TestMiddleware(t *testing.T) {
+	type call struct {
+		method string
+		code   codes.Code
+		peer   string
+	}
+	var calls []call
+
+	chain := guardian.NewChain(
+		// Records what metrics and logging middleware would see
+		func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			resp, err := handler(ctx, req)
+			p, _ := peer.FromContext(ctx)
+			calls = append(calls, call{method: info.FullMethod, code: status.Code(err), peer: p.Addr.String()})
+			return resp, err
+		},
+		// Auth and rate limiting
+		func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			md, _ := metadata.FromIncomingContext(ctx)
+			if got := md.Get("authorization"); len(got) != 1 || got[0] != "Bearer secret" {
+				return nil, status.Error(codes.Unauthenticated, "invalid token")
+			}
+			_ = grpc.SetHeader(ctx, metadata.Pairs("x-ratelimit-remaining", "9"))
+			if len(md.Get("x-fail")) > 0 {
+				_ = grpc.SetTrailer(ctx, metadata.Pairs("retry-after", "1"))
+				return nil, status.Error(codes.ResourceExhausted, "slow down")
+			}
+			return handler(ctx, req)
+		},
+	)
+
+	handler := Middleware(chain, WithMethodResolver(func(r *http.Request) string {
+		if strings.HasPrefix(r.URL.Path, "/v1/users/") {
+			return "/users.v1.UserService/GetUser"
+		}
+		return r.URL.Path
+	}))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if grpc.ServerTransportStreamFromContext(r.Context()) == nil {
+			t.Error("Expected the request context to carry the chain's context")
+		}
+		switch {
+		case r.URL.Path == "/v1/users/missing":
+			http.Error(w, "no such user", http.StatusNotFound)
+		case isGRPCWeb(r):
+			w.Header().Set("Grpc-Status", "0")
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.Write([]byte(`{"name":"ada"}`))
+		}
+	}))
+
+	do := func(method, path string, headers ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		for i := 0; i+1 < len(headers); i += 2 {
+			req.Header.Set(headers[i], headers[i+1])
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := do(http.MethodGet, "/v1/users/ada")
+	if rec.Code != http.StatusUnauthorized || !strings.Contains(rec.Body.String(), `"message":"invalid token"`) {
+		t.Errorf("Expected a 401 JSON error, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = do(http.MethodGet, "/v1/users/ada", "Authorization", "Bearer secret")
+	if rec.Code != http.StatusOK || rec.Body.String() != `{"name":"ada"}` {
+		t.Errorf("Expected the handler's response, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if got := rec.Header().Get("X-Ratelimit-Remaining"); got != "9" {
+		t.Errorf("Expected middleware headers on the response, got %q", got)
+	}
+
+	rec = do(http.MethodGet, "/v1/users/ada", "Grpc-Metadata-Authorization", "Bearer secret", "X-Fail", "1")
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "1" {
+		t.Errorf("Expected a 429 with trailers as headers, got %d: %v", rec.Code, rec.Header())
+	}
+
+	do(http.MethodGet, "/v1/users/missing", "Authorization", "Bearer secret")
+
+	rec = do(http.MethodPost, "/users.v1.UserService/GetUser", "Content-Type", "application/grpc-web+proto", "X-Fail", "1", "Authorization", "Bearer secret")
+	if rec.Code != http.StatusOK || rec.Header().Get("Grpc-Status") != "8" || rec.Header().Get("Grpc-Message") != "slow%20down" {
+		t.Errorf("Expected a trailers-only gRPC-Web error, got %d: %v", rec.Code, rec.Header())
+	}
+	do(http.MethodPost, "/users.v1.UserService/GetUser", "Content-Type", "application/grpc-web+proto", "Authorization", "Bearer secret")
+
+	want := []call{
+		{"/users.v1.UserService/GetUser", codes.Unauthenticated, "192.0.2.1:1234"},
+		{"/users.v1.UserService/GetUser", codes.OK, "192.0.2.1:1234"},
+		{"/users.v1.UserService/GetUser", codes.ResourceExhausted, "192.0.2.1:1234"},
+		{"/users.v1.UserService/GetUser", codes.NotFound, "192.0.2.1:1234"},
+		{"/users.v1.UserService/GetUser", codes.ResourceExhausted, "192.0.2.1:1234"},
+		{"/users.v1.UserService/GetUser", codes.OK, "192.0.2.1:1234"},
+	}
+	if len(calls) != len(want) {
+		t.Fatalf("Expected %d calls, got %v", len(want), calls)
+	}
+	for i := range want {
+		if calls[i] != want[i] {
+			t.Errorf("Call %d: expected %v, got %v", i, want[i], calls[i])
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package httpbridge
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// HTTPStatusFromCode maps a gRPC status code to an HTTP status, the same way grpc-gateway
+// does
+func HTTPStatusFromCode(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.Canceled:
+		return 499
+	case codes.InvalidArgument, codes.FailedPrecondition, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// CodeFromHTTPStatus maps an HTTP error status back to a gRPC status code, so metrics and
+// logs of HTTP requests carry the same codes as gRPC calls
+func CodeFromHTTPStatus(httpStatus int) codes.Code {
+	switch httpStatus {
+	case 499:
+		return codes.Canceled
+	case http.StatusBadRequest:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusConflict:
+		return codes.AlreadyExists
+	case http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case http.StatusNotImplemented:
+		return codes.Unimplemented
+	case http.StatusBadGateway, http.StatusServiceUnavailable:
+		return codes.Unavailable
+	case http.StatusGatewayTimeout:
+		return codes.DeadlineExceeded
+	}
+	if httpStatus >= 500 {
+		return codes.Internal
+	}
+	return codes.Unknown
+}
+
+// isGRPCWeb reports whether the request is a gRPC-Web call
+func isGRPCWeb(r *http.Request) bool {
+	return strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc-web")
+}
+
+// writeError answers a request rejected by the chain. gRPC-Web calls get a trailers-only
+// response; other requests get the grpc-gateway JSON error body
+// ({"code": 16, "message": "...", "details": [...]}) with the matching HTTP status.
+func writeError(w *responseWriter, r *http.Request, err error) {
+	st := status.Convert(err)
+
+	if isGRPCWeb(r) {
+		contentType := r.Header.Get("Content-Type")
+		if strings.HasPrefix(contentType, "application/grpc-web-text") {
+			contentType = "application/grpc-web-text+proto"
+		} else {
+			contentType = "application/grpc-web+proto"
+		}
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Grpc-Status", strconv.Itoa(int(st.Code())))
+		w.Header().Set("Grpc-Message", url.PathEscape(st.Message()))
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	body, marshalErr := protojson.Marshal(st.Proto())
+	if marshalErr != nil {
+		body = []byte(`{"code":13,"message":"failed to marshal error"}`)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(HTTPStatusFromCode(st.Code()))
+	_, _ = w.Write(body)
+}
@@ -0,0 +1,274 @@
+// Package killswitch disables methods or whole services at runtime, without a deploy. A
+// Watcher polls a Provider (memory, file, environment, Redis or your own) for the active
+// switches, and every switch flip is recorded in the audit log.
+package killswitch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grpc-guardian/grpc-guardian/pkg/audit"
+)
+
+// Switch disables the methods matching Target
+type Switch struct {
+	// Target is "/pkg.Service/Method", "/pkg.Service/*" or "*" for maintenance mode
+	Target string
+	// Message is returned to callers; empty uses a generic one
+	Message string
+	// RetryAfter tells callers when to try again; zero sends no hint
+	RetryAfter time.Duration
+	// Actor is who flipped the switch, for the audit log
+	Actor string
+}
+
+// switchJSON is the JSON form of a Switch used by the file and Redis providers
+type switchJSON struct {
+	Target     string `json:"target"`
+	Message    string `json:"message,omitempty"`
+	RetryAfter string `json:"retry_after,omitempty"` // Go duration, e.g. "5m"
+	Actor      string `json:"actor,omitempty"`
+}
+
+// MarshalJSON encodes RetryAfter as a Go duration string
+func (s Switch) MarshalJSON() ([]byte, error) {
+	j := switchJSON{Target: s.Target, Message: s.Message, Actor: s.Actor}
+	if s.RetryAfter > 0 {
+		j.RetryAfter = s.RetryAfter.String()
+	}
+	return json.Marshal(j)
+}
+
+// UnmarshalJSON decodes RetryAfter from a Go duration string
+func (s *Switch) UnmarshalJSON(data []byte) error {
+	var j switchJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	*s = Switch{Target: j.Target, Message: j.Message, Actor: j.Actor}
+	if j.RetryAfter != "" {
+		retryAfter, err := time.ParseDuration(j.RetryAfter)
+		if err != nil {
+			return fmt.Errorf("invalid retry_after of %s: %w", j.Target, err)
+		}
+		s.RetryAfter = retryAfter
+	}
+	return nil
+}
+
+// Provider returns the switches that are currently on
+type Provider interface {
+	Switches(ctx context.Context) ([]Switch, error)
+}
+
+// ProviderFunc adapts a function to Provider
+type ProviderFunc func(ctx context.Context) ([]Switch, error)
+
+// Switches calls f
+func (f ProviderFunc) Switches(ctx context.Context) ([]Switch, error) {
+	return f(ctx)
+}
+
+// Flip is a switch turned on or off
+type Flip struct {
+	Switch Switch
+	On     bool
+	Time   time.Time
+}
+
+// Watcher keeps the active switches of a Provider and matches methods against them
+type Watcher struct {
+	provider Provider
+	interval time.Duration
+	recorder *audit.Recorder
+	onFlip   func(Flip)
+	onError  func(error)
+
+	mu       sync.RWMutex
+	switches map[string]Switch // Active switches by target
+	prefixes []string          // Targets ending in "*", without it, longest first
+}
+
+// WatcherOption configures a Watcher
+type WatcherOption func(*Watcher)
+
+// WithPollInterval sets how often Run polls the provider
+// Default: 5s
+func WithPollInterval(interval time.Duration) WatcherOption {
+	return func(w *Watcher) {
+		if interval > 0 {
+			w.interval = interval
+		}
+	}
+}
+
+// WithAuditRecorder records every flip as an audit event with method
+// "guardian.killswitch/On" or "guardian.killswitch/Off" and the target as resource
+func WithAuditRecorder(recorder *audit.Recorder) WatcherOption {
+	return func(w *Watcher) {
+		w.recorder = recorder
+	}
+}
+
+// WithFlipCallback is called for every flip, e.g. to page the on-call
+func WithFlipCallback(fn func(Flip)) WatcherOption {
+	return func(w *Watcher) {
+		w.onFlip = fn
+	}
+}
+
+// WithErrorHandler is called when polling the provider fails; the last known switches
+// stay in effect
+func WithErrorHandler(fn func(error)) WatcherOption {
+	return func(w *Watcher) {
+		w.onError = fn
+	}
+}
+
+// NewWatcher creates a Watcher. Call Refresh once before serving and Run in the background.
+//
+// Example usage:
+//
+//	watcher := killswitch.NewWatcher(killswitch.NewFileProvider("/etc/guardian/killswitch.json"),
+//	    killswitch.WithAuditRecorder(recorder),
+//	)
+//	if err := watcher.Refresh(ctx); err != nil {
+//	    log.Fatal(err)
+//	}
+//	go watcher.Run(ctx)
+//
+//	chain := guardian.NewChain(middleware.KillSwitch(watcher))
+func NewWatcher(provider Provider, opts ...WatcherOption) *Watcher {
+	w := &Watcher{
+		provider: provider,
+		interval: 5 * time.Second,
+		switches: make(map[string]Switch),
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	return w
+}
+
+// Run polls the provider until ctx is done
+func (w *Watcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := w.Refresh(ctx); err != nil && w.onError != nil {
+				w.onError(err)
+			}
+		}
+	}
+}
+
+// Refresh polls the provider once and applies the switches it returns
+func (w *Watcher) Refresh(ctx context.Context) error {
+	switches, err := w.provider.Switches(ctx)
+	if err != nil {
+		return fmt.Errorf("killswitch: failed to poll provider: %w", err)
+	}
+
+	current := make(map[string]Switch, len(switches))
+	for _, sw := range switches {
+		current[sw.Target] = sw
+	}
+
+	now := time.Now()
+	var flips []Flip
+
+	w.mu.Lock()
+	for target, sw := range current {
+		if _, on := w.switches[target]; !on {
+			flips = append(flips, Flip{Switch: sw, On: true, Time: now})
+		}
+	}
+	for target, sw := range w.switches {
+		if _, on := current[target]; !on {
+			flips = append(flips, Flip{Switch: sw, On: false, Time: now})
+		}
+	}
+
+	w.switches = current
+	w.prefixes = w.prefixes[:0]
+	for target := range current {
+		if strings.HasSuffix(target, "*") {
+			w.prefixes = append(w.prefixes, strings.TrimSuffix(target, "*"))
+		}
+	}
+	sort.Slice(w.prefixes, func(i, j int) bool { return len(w.prefixes[i]) > len(w.prefixes[j]) })
+	w.mu.Unlock()
+
+	sort.Slice(flips, func(i, j int) bool { return flips[i].Switch.Target < flips[j].Switch.Target })
+	for _, flip := range flips {
+		w.record(ctx, flip)
+	}
+	return nil
+}
+
+// record reports a flip to the audit log and callback
+func (w *Watcher) record(ctx context.Context, flip Flip) {
+	if w.recorder != nil {
+		method := "guardian.killswitch/Off"
+		if flip.On {
+			method = "guardian.killswitch/On"
+		}
+		resource := map[string]string{"target": flip.Switch.Target}
+		if flip.Switch.Message != "" {
+			resource["message"] = flip.Switch.Message
+		}
+		_ = w.recorder.Record(ctx, audit.Event{
+			Time:     flip.Time,
+			Actor:    audit.Actor{UserID: flip.Switch.Actor},
+			Method:   method,
+			Resource: resource,
+			Outcome:  audit.Outcome{Success: true, Code: "OK"},
+		})
+	}
+	if w.onFlip != nil {
+		w.onFlip(flip)
+	}
+}
+
+// Match returns the switch disabling method, preferring an exact target over the longest
+// prefix
+func (w *Watcher) Match(method string) (Switch, bool) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	if len(w.switches) == 0 {
+		return Switch{}, false
+	}
+	if sw, ok := w.switches[method]; ok {
+		return sw, true
+	}
+	for _, prefix := range w.prefixes {
+		if strings.HasPrefix(method, prefix) {
+			return w.switches[prefix+"*"], true
+		}
+	}
+	return Switch{}, false
+}
+
+// Active returns the switches that are on, sorted by target
+func (w *Watcher) Active() []Switch {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	active := make([]Switch, 0, len(w.switches))
+	for _, sw := range w.switches {
+		active = append(active, sw)
+	}
+	sort.Slice(active, func(i, j int) bool { return active[i].Target < active[j].Target })
+	return active
+}
@@ -0,0 +1,181 @@
+package killswitch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/grpc-guardian/grpc-guardian/pkg/audit"
+)
+
+// memorySink collects audit events
+type memorySink struct {
+	mu     sync.Mutex
+	events []audit.Event
+}
+
+func (s *memorySink) Write(ctx context.Context, events []audit.Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, events...)
+	return nil
+}
+
+func (s *memorySink) Close() error { return nil }
+
+func TestWatcher_MatchAndFlips(t *testing.T) {
+	provider := NewMemoryProvider()
+	sink := &memorySink{}
+	recorder := audit.NewRecorder(sink)
+	var flips []string
+
+	watcher := NewWatcher(provider,
+		WithAuditRecorder(recorder),
+		WithFlipCallback(func(flip Flip) {
+			state := "off"
+			if flip.On {
+				state = "on"
+			}
+			flips = append(flips, flip.Switch.Target+" "+state)
+		}),
+	)
+
+	provider.Set(Switch{Target: "/orders.Orders/*", Message: "maintenance", Actor: "alice"})
+	provider.Set(Switch{Target: "/orders.Orders/Refund", Message: "refunds paused"})
+	if err := watcher.Refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if sw, ok := watcher.Match("/orders.Orders/Refund"); !ok || sw.Message != "refunds paused" {
+		t.Errorf("Expected the exact target to win, got %+v", sw)
+	}
+	if sw, ok := watcher.Match("/orders.Orders/Get"); !ok || sw.Message != "maintenance" {
+		t.Errorf("Expected the service target to match, got %+v", sw)
+	}
+	if _, ok := watcher.Match("/users.Users/Get"); ok {
+		t.Error("Expected other services to be unaffected")
+	}
+
+	provider.Clear("/orders.Orders/*")
+	if err := watcher.Refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := watcher.Match("/orders.Orders/Get"); ok {
+		t.Error("Expected the cleared switch to be off")
+	}
+
+	want := []string{"/orders.Orders/* on", "/orders.Orders/Refund on", "/orders.Orders/* off"}
+	if !reflect.DeepEqual(flips, want) {
+		t.Errorf("Expected flips %v, got %v", want, flips)
+	}
+
+	if err := recorder.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(sink.events) != 3 {
+		t.Fatalf("Expected 3 audit events, got %d", len(sink.events))
+	}
+	first := sink.events[0]
+	if first.Method != "guardian.killswitch/On" || first.Resource["target"] != "/orders.Orders/*" || first.Actor.UserID != "alice" {
+		t.Errorf("Expected the first flip to be audited, got %+v", first)
+	}
+	if sink.events[2].Method != "guardian.killswitch/Off" {
+		t.Errorf("Expected the last flip to be an Off event, got %+v", sink.events[2])
+	}
+}
+
+func TestWatcher_KeepsSwitchesOnProviderError(t *testing.T) {
+	fail := false
+	watcher := NewWatcher(ProviderFunc(func(ctx context.Context) ([]Switch, error) {
+		if fail {
+			return nil, context.DeadlineExceeded
+		}
+		return []Switch{{Target: "*"}}, nil
+	}))
+
+	if err := watcher.Refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	fail = true
+	if err := watcher.Refresh(context.Background()); err == nil {
+		t.Error("Expected the provider error")
+	}
+	if _, ok := watcher.Match("/any.Service/Method"); !ok {
+		t.Error("Expected the last known switches to stay in effect")
+	}
+}
+
+func TestFileProvider(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "killswitch.json")
+	provider := NewFileProvider(path)
+
+	if switches, err := provider.Switches(context.Background()); err != nil || len(switches) != 0 {
+		t.Fatalf("Expected a missing file to mean no switches, got %v, %v", switches, err)
+	}
+
+	data := `{"switches": [{"target": "/orders.Orders/*", "message": "read-only", "retry_after": "10m"}]}`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	switches, err := provider.Switches(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Switch{{Target: "/orders.Orders/*", Message: "read-only", RetryAfter: 10 * time.Minute}}
+	if !reflect.DeepEqual(switches, want) {
+		t.Errorf("Expected %+v, got %+v", want, switches)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"switches": [{"target": "*", "retry_after": "soon"}]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := provider.Switches(context.Background()); err == nil {
+		t.Error("Expected an invalid retry_after to be rejected")
+	}
+}
+
+func TestEnvProvider(t *testing.T) {
+	t.Setenv("TEST_KILL_SWITCHES", "/orders.Orders/Refund=refunds are paused, /reports.Reports/*")
+
+	switches, err := NewEnvProvider("TEST_KILL_SWITCHES").Switches(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Switch{
+		{Target: "/orders.Orders/Refund", Message: "refunds are paused"},
+		{Target: "/reports.Reports/*"},
+	}
+	if !reflect.DeepEqual(switches, want) {
+		t.Errorf("Expected %+v, got %+v", want, switches)
+	}
+}
+
+// fakeRedis serves a single hash
+type fakeRedis map[string]string
+
+func (r fakeRedis) HGetAll(ctx context.Context, key string) (map[string]string, error) {
+	return r, nil
+}
+
+func TestRedisProvider(t *testing.T) {
+	client := fakeRedis{
+		"/orders.Orders/*": `{"message":"maintenance","retry_after":"30s","actor":"alice"}`,
+		"/users.Users/Get": "",
+	}
+
+	switches, err := NewRedisProvider(client, "guardian:killswitch").Switches(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Switch{
+		{Target: "/orders.Orders/*", Message: "maintenance", RetryAfter: 30 * time.Second, Actor: "alice"},
+		{Target: "/users.Users/Get"},
+	}
+	if !reflect.DeepEqual(switches, want) {
+		t.Errorf("Expected %+v, got %+v", want, switches)
+	}
+}
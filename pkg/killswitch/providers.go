@@ -0,0 +1,172 @@
+package killswitch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryProvider keeps switches in memory, e.g. for an admin endpoint or tests
+type MemoryProvider struct {
+	mu       sync.RWMutex
+	switches map[string]Switch
+}
+
+// NewMemoryProvider creates an empty MemoryProvider
+func NewMemoryProvider() *MemoryProvider {
+	return &MemoryProvider{switches: make(map[string]Switch)}
+}
+
+// Set turns a switch on, replacing any switch with the same target
+func (p *MemoryProvider) Set(sw Switch) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.switches[sw.Target] = sw
+}
+
+// Clear turns the switch of a target off
+func (p *MemoryProvider) Clear(target string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.switches, target)
+}
+
+// Switches returns the switches that are on
+func (p *MemoryProvider) Switches(ctx context.Context) ([]Switch, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	switches := make([]Switch, 0, len(p.switches))
+	for _, sw := range p.switches {
+		switches = append(switches, sw)
+	}
+	return switches, nil
+}
+
+// FileProvider reads switches from a JSON file, e.g. a mounted ConfigMap:
+//
+//	{"switches": [{"target": "/orders.Orders/*", "message": "orders are read-only during the migration", "retry_after": "10m"}]}
+//
+// A missing file means no switch is on. The file is parsed again only when it changes.
+type FileProvider struct {
+	path string
+
+	mu       sync.Mutex
+	modTime  time.Time
+	size     int64
+	switches []Switch
+}
+
+// NewFileProvider creates a FileProvider reading path
+func NewFileProvider(path string) *FileProvider {
+	return &FileProvider{path: path}
+}
+
+// Switches returns the switches of the file
+func (p *FileProvider) Switches(ctx context.Context) ([]Switch, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	info, err := os.Stat(p.path)
+	if errors.Is(err, os.ErrNotExist) {
+		p.modTime, p.size, p.switches = time.Time{}, 0, nil
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if info.ModTime().Equal(p.modTime) && info.Size() == p.size {
+		return p.switches, nil
+	}
+
+	data, err := os.ReadFile(p.path)
+	if err != nil {
+		return nil, err
+	}
+	var file struct {
+		Switches []Switch `json:"switches"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", p.path, err)
+	}
+
+	p.modTime, p.size, p.switches = info.ModTime(), info.Size(), file.Switches
+	return p.switches, nil
+}
+
+// EnvProvider reads switches from an environment variable holding comma-separated targets,
+// each optionally followed by "=" and a message:
+//
+//	GUARDIAN_KILL_SWITCHES="/orders.Orders/Refund=refunds are paused,/reports.Reports/*"
+type EnvProvider struct {
+	name string
+}
+
+// NewEnvProvider creates an EnvProvider reading the variable name
+func NewEnvProvider(name string) *EnvProvider {
+	return &EnvProvider{name: name}
+}
+
+// Switches returns the switches of the variable
+func (p *EnvProvider) Switches(ctx context.Context) ([]Switch, error) {
+	var switches []Switch
+	for _, entry := range strings.Split(os.Getenv(p.name), ",") {
+		target, message, _ := strings.Cut(entry, "=")
+		target = strings.TrimSpace(target)
+		if target == "" {
+			continue
+		}
+		switches = append(switches, Switch{Target: target, Message: strings.TrimSpace(message)})
+	}
+	return switches, nil
+}
+
+// RedisClient is the subset of Redis commands used by RedisProvider.
+// Adapt your Redis client (e.g. go-redis) to this interface.
+type RedisClient interface {
+	// HGetAll returns every field and value of a hash; a missing key is an empty hash
+	HGetAll(ctx context.Context, key string) (map[string]string, error)
+}
+
+// RedisProvider reads switches from a Redis hash shared by every replica. Fields are
+// targets and values are JSON switches, or empty for the defaults:
+//
+//	HSET guardian:killswitch "/orders.Orders/*" '{"message":"maintenance","retry_after":"10m","actor":"alice"}'
+//	HDEL guardian:killswitch "/orders.Orders/*"
+type RedisProvider struct {
+	client RedisClient
+	key    string
+}
+
+// NewRedisProvider creates a RedisProvider reading the hash at key
+func NewRedisProvider(client RedisClient, key string) *RedisProvider {
+	return &RedisProvider{client: client, key: key}
+}
+
+// Switches returns the switches of the hash
+func (p *RedisProvider) Switches(ctx context.Context) ([]Switch, error) {
+	fields, err := p.client.HGetAll(ctx, p.key)
+	if err != nil {
+		return nil, fmt.Errorf("redis hgetall failed: %w", err)
+	}
+
+	switches := make([]Switch, 0, len(fields))
+	for target, value := range fields {
+		sw := Switch{}
+		if strings.TrimSpace(value) != "" {
+			if err := json.Unmarshal([]byte(value), &sw); err != nil {
+				return nil, fmt.Errorf("invalid switch %s: %w", target, err)
+			}
+		}
+		sw.Target = target
+		switches = append(switches, sw)
+	}
+	sort.Slice(switches, func(i, j int) bool { return switches[i].Target < switches[j].Target })
+	return switches, nil
+}
@@ -0,0 +1,146 @@
+package loadgen
+
+import (
+	"math"
+	"time"
+)
+
+// Histogram bounds: buckets grow by histogramGrowth from histogramMin, so a quantile is
+// reported within 10% of the true latency
+const (
+	histogramMin     = 50 * time.Microsecond
+	histogramMax     = 2 * time.Minute
+	histogramGrowth  = 1.1
+	histogramBuckets = 156 // ceil(log(histogramMax/histogramMin) / log(histogramGrowth)) + 1
+)
+
+// Bucket is a histogram bucket
+type Bucket struct {
+	UpperBound time.Duration // Inclusive
+	Count      uint64
+}
+
+// Histogram records a latency distribution in log-scale buckets. It is not safe for
+// concurrent use.
+type Histogram struct {
+	counts [histogramBuckets + 1]uint64 // The last bucket counts latencies above histogramMax
+	count  uint64
+	sum    time.Duration
+	min    time.Duration
+	max    time.Duration
+}
+
+// bucketBound returns the upper bound of bucket i
+func bucketBound(i int) time.Duration {
+	if i >= histogramBuckets {
+		return time.Duration(math.MaxInt64)
+	}
+	return time.Duration(float64(histogramMin) * math.Pow(histogramGrowth, float64(i)))
+}
+
+// bucketOf returns the bucket of a latency
+func bucketOf(d time.Duration) int {
+	if d <= histogramMin {
+		return 0
+	}
+	i := int(math.Ceil(math.Log(float64(d)/float64(histogramMin)) / math.Log(histogramGrowth)))
+	// Correct floating point rounding at the bounds
+	for i > 0 && bucketBound(i-1) >= d {
+		i--
+	}
+	for i < histogramBuckets && bucketBound(i) < d {
+		i++
+	}
+	return i
+}
+
+// Observe records a latency
+func (h *Histogram) Observe(d time.Duration) {
+	h.counts[bucketOf(d)]++
+	if h.count == 0 || d < h.min {
+		h.min = d
+	}
+	if d > h.max {
+		h.max = d
+	}
+	h.count++
+	h.sum += d
+}
+
+// Merge adds the observations of another histogram
+func (h *Histogram) Merge(other *Histogram) {
+	if other.count == 0 {
+		return
+	}
+	for i, c := range other.counts {
+		h.counts[i] += c
+	}
+	if h.count == 0 || other.min < h.min {
+		h.min = other.min
+	}
+	if other.max > h.max {
+		h.max = other.max
+	}
+	h.count += other.count
+	h.sum += other.sum
+}
+
+// Count returns the number of observations
+func (h *Histogram) Count() uint64 {
+	return h.count
+}
+
+// Min returns the lowest latency
+func (h *Histogram) Min() time.Duration {
+	return h.min
+}
+
+// Max returns the highest latency
+func (h *Histogram) Max() time.Duration {
+	return h.max
+}
+
+// Mean returns the average latency
+func (h *Histogram) Mean() time.Duration {
+	if h.count == 0 {
+		return 0
+	}
+	return h.sum / time.Duration(h.count)
+}
+
+// Quantile returns the latency below which a fraction q of observations fall, e.g. 0.99
+// for p99. It is the upper bound of the containing bucket, capped at Max.
+func (h *Histogram) Quantile(q float64) time.Duration {
+	if h.count == 0 {
+		return 0
+	}
+	rank := uint64(math.Ceil(q * float64(h.count)))
+	if rank < 1 {
+		rank = 1
+	}
+	var seen uint64
+	for i, c := range h.counts {
+		seen += c
+		if seen >= rank {
+			if bound := bucketBound(i); bound < h.max {
+				if bound < h.min {
+					return h.min
+				}
+				return bound
+			}
+			return h.max
+		}
+	}
+	return h.max
+}
+
+// Buckets returns the non-empty buckets, lowest first
+func (h *Histogram) Buckets() []Bucket {
+	var buckets []Bucket
+	for i, c := range h.counts {
+		if c > 0 {
+			buckets = append(buckets, Bucket{UpperBound: bucketBound(i), Count: c})
+		}
+	}
+	return buckets
+}
@@ -0,0 +1,202 @@
+package loadgen
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/grpc-guardian/grpc-guardian/pkg/replay"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// fakeConn answers calls after a delay, failing every failEvery-th call
+type fakeConn struct {
+	delay     time.Duration
+	failEvery uint64
+	calls     atomic.Uint64
+	inFlight  atomic.Int64
+	peak      atomic.Int64
+
+	mu       sync.Mutex
+	metadata []metadata.MD
+}
+
+func (c *fakeConn) Invoke(ctx context.Context, method string, args, reply interface{}, opts ...grpc.CallOption) error {
+	n := c.calls.Add(1)
+	if inFlight := c.inFlight.Add(1); inFlight > c.peak.Load() {
+		c.peak.Store(inFlight)
+	}
+	defer c.inFlight.Add(-1)
+
+	md, _ := metadata.FromOutgoingContext(ctx)
+	c.mu.Lock()
+	c.metadata = append(c.metadata, md)
+	c.mu.Unlock()
+
+	time.Sleep(c.delay)
+	if c.failEvery > 0 && n%c.failEvery == 0 {
+		return status.Error(codes.Unavailable, "injected")
+	}
+	if resp, ok := reply.(*wrapperspb.StringValue); ok {
+		resp.Value = args.(*wrapperspb.StringValue).GetValue()
+	}
+	return nil
+}
+
+func (c *fakeConn) NewStream(ctx context.Context, desc *grpc.StreamDesc, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	return nil, errors.New("not supported")
+}
+
+func TestGenerator_Run(t *testing.T) {
+	conn := &fakeConn{delay: time.Millisecond, failEvery: 4}
+	get := Static("/orders.Orders/Get", wrapperspb.String("o-1"), &wrapperspb.StringValue{})
+	get.Weight = 3
+	list := Static("/orders.Orders/List", wrapperspb.String("all"), &wrapperspb.StringValue{})
+
+	gen, err := NewGenerator(conn, []Template{get, list}, WithQPS(0), WithConcurrency(4), WithMaxRequests(200))
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := gen.Run(context.Background())
+
+	if report.Requests != 200 || report.Latency.Count() != 200 {
+		t.Fatalf("Expected 200 requests, got %d", report.Requests)
+	}
+	if report.Errors != 50 || report.Codes[codes.Unavailable] != 50 || report.Codes[codes.OK] != 150 {
+		t.Errorf("Expected every 4th call to fail, got %v", report.Codes)
+	}
+	if report.ErrorRate() != 0.25 {
+		t.Errorf("Expected a 25%% error rate, got %v", report.ErrorRate())
+	}
+	if got := report.Methods["/orders.Orders/Get"].Requests; got != 150 {
+		t.Errorf("Expected 3 of 4 calls to use the weighted template, got %d", got)
+	}
+	if peak := conn.peak.Load(); peak > 4 {
+		t.Errorf("Expected at most 4 calls in flight, got %d", peak)
+	}
+	if report.Latency.Min() < time.Millisecond || report.Latency.Quantile(0.5) < time.Millisecond {
+		t.Errorf("Expected latencies of at least the delay, got min %s, p50 %s", report.Latency.Min(), report.Latency.Quantile(0.5))
+	}
+	if report.QPS() <= 0 {
+		t.Errorf("Expected an achieved rate, got %v", report.QPS())
+	}
+
+	summary := report.String()
+	for _, want := range []string{"200 requests", "25.00% errors", "OK 150, Unavailable 50", "/orders.Orders/List: 50 requests"} {
+		if !strings.Contains(summary, want) {
+			t.Errorf("Expected %q in the report:\n%s", want, summary)
+		}
+	}
+}
+
+func TestGenerator_RateAndWarmup(t *testing.T) {
+	conn := &fakeConn{}
+	gen, err := NewGenerator(conn, []Template{Static("/svc.S/M", wrapperspb.String("x"), &wrapperspb.StringValue{})},
+		WithQPS(200), WithWarmup(100*time.Millisecond), WithDuration(250*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := gen.Run(context.Background())
+
+	// 200/s for 250ms is 50 measured calls; allow for scheduling noise
+	if report.Requests < 30 || report.Requests > 60 {
+		t.Errorf("Expected about 50 measured requests, got %d", report.Requests)
+	}
+	if sent := conn.calls.Load(); sent <= report.Requests {
+		t.Errorf("Expected warmup calls to be sent but not measured, sent %d, measured %d", sent, report.Requests)
+	}
+}
+
+func TestFromRecords(t *testing.T) {
+	var records []replay.Record
+	for _, err := range []error{nil, status.Error(codes.NotFound, "gone")} {
+		record, rerr := replay.NewRecord("/orders.Orders/Get", metadata.Pairs("x-tenant-id", "acme"),
+			wrapperspb.String("o-1"), wrapperspb.String("order"), err, time.Now(), time.Millisecond)
+		if rerr != nil {
+			t.Fatal(rerr)
+		}
+		records = append(records, record)
+	}
+
+	templates, err := FromRecords(records)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(templates) != 2 {
+		t.Fatalf("Expected a template per record, got %d", len(templates))
+	}
+
+	conn := &fakeConn{}
+	gen, err := NewGenerator(conn, templates, WithQPS(0), WithMaxRequests(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report := gen.Run(context.Background()); report.Requests != 4 || report.Errors != 0 {
+		t.Errorf("Expected 4 successful calls, got %d requests, %d errors", report.Requests, report.Errors)
+	}
+	if md := conn.metadata[0]; len(md.Get("x-tenant-id")) != 1 {
+		t.Errorf("Expected the recorded metadata to be sent, got %v", md)
+	}
+
+	bad := records[0]
+	bad.RequestType = "unknown.Message"
+	if _, err := FromRecords([]replay.Record{bad}); err == nil {
+		t.Error("Expected an error for an unknown request type")
+	}
+}
+
+func TestNewGenerator_InvalidTemplates(t *testing.T) {
+	if _, err := NewGenerator(&fakeConn{}, nil); err == nil {
+		t.Error("Expected an error without templates")
+	}
+	if _, err := NewGenerator(&fakeConn{}, []Template{{Method: "/svc.S/M"}}); err == nil {
+		t.Error("Expected an error for a template without Request")
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	var h Histogram
+	for i := 1; i <= 100; i++ {
+		h.Observe(time.Duration(i) * time.Millisecond)
+	}
+
+	if h.Count() != 100 || h.Min() != time.Millisecond || h.Max() != 100*time.Millisecond {
+		t.Errorf("Unexpected count %d, min %s, max %s", h.Count(), h.Min(), h.Max())
+	}
+	if h.Mean() != 50500*time.Microsecond {
+		t.Errorf("Expected a mean of 50.5ms, got %s", h.Mean())
+	}
+	for q, want := range map[float64]time.Duration{0.5: 50 * time.Millisecond, 0.9: 90 * time.Millisecond, 0.99: 99 * time.Millisecond} {
+		got := h.Quantile(q)
+		if got < want || float64(got) > 1.1*float64(want) {
+			t.Errorf("Quantile(%v) = %s, want within 10%% above %s", q, got, want)
+		}
+	}
+	if h.Quantile(1) != h.Max() {
+		t.Errorf("Expected p100 to be the max, got %s", h.Quantile(1))
+	}
+
+	var other Histogram
+	other.Observe(time.Hour)
+	other.Observe(time.Microsecond)
+	h.Merge(&other)
+	if h.Count() != 102 || h.Min() != time.Microsecond || h.Max() != time.Hour {
+		t.Errorf("Unexpected merge: count %d, min %s, max %s", h.Count(), h.Min(), h.Max())
+	}
+
+	var total uint64
+	for _, b := range h.Buckets() {
+		total += b.Count
+	}
+	if total != 102 {
+		t.Errorf("Expected buckets to hold every observation, got %d", total)
+	}
+}
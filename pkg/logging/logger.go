@@ -0,0 +1,63 @@
+// Package logging defines the small structured logger used by the logging middlewares,
+// with adapters for zap, log/slog and logrus so services can plug in their existing logger.
+package logging
+
+import (
+	"time"
+)
+
+// Field is a structured log field
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// String creates a string field
+func String(key, value string) Field {
+	return Field{Key: key, Value: value}
+}
+
+// Int64 creates an integer field
+func Int64(key string, value int64) Field {
+	return Field{Key: key, Value: value}
+}
+
+// Bool creates a boolean field
+func Bool(key string, value bool) Field {
+	return Field{Key: key, Value: value}
+}
+
+// Duration creates a duration field
+func Duration(key string, value time.Duration) Field {
+	return Field{Key: key, Value: value}
+}
+
+// Time creates a timestamp field
+func Time(key string, value time.Time) Field {
+	return Field{Key: key, Value: value}
+}
+
+// Any creates a field with an arbitrary value
+func Any(key string, value interface{}) Field {
+	return Field{Key: key, Value: value}
+}
+
+// Logger is a leveled structured logger
+type Logger interface {
+	Debug(msg string, fields ...Field)
+	Info(msg string, fields ...Field)
+	Warn(msg string, fields ...Field)
+	Error(msg string, fields ...Field)
+}
+
+// Nop returns a logger that discards everything
+func Nop() Logger {
+	return nopLogger{}
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Debug(string, ...Field) {}
+func (nopLogger) Info(string, ...Field)  {}
+func (nopLogger) Warn(string, ...Field)  {}
+func (nopLogger) Error(string, ...Field) {}
@@ -0,0 +1,97 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// fakeLogrusEntry records what the logrus adapter logs
+type fakeLogrusEntry struct {
+	fields map[string]interface{}
+	logged *[]string
+}
+
+func (e fakeLogrusEntry) WithField(key string, value interface{}) fakeLogrusEntry {
+	fields := make(map[string]interface{}, len(e.fields)+1)
+	for k, v := range e.fields {
+		fields[k] = v
+	}
+	fields[key] = value
+	return fakeLogrusEntry{fields: fields, logged: e.logged}
+}
+
+func (e fakeLogrusEntry) Debug(args ...interface{}) { e.log("debug", args) }
+func (e fakeLogrusEntry) Info(args ...interface{})  { e.log("info", args) }
+func (e fakeLogrusEntry) Warn(args ...interface{})  { e.log("warn", args) }
+func (e fakeLogrusEntry) Error(args ...interface{}) { e.log("error", args) }
+
+func (e fakeLogrusEntry) log(level string, args []interface{}) {
+	data, _ := json.Marshal(e.fields)
+	*e.logged = append(*e.logged, level+" "+args[0].(string)+" "+string(data))
+}
+
+func TestZapLogger(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	logger := NewZap(zap.New(core))
+
+	logger.Warn("slow request", String("method", "/svc/M"), Duration("elapsed", 2*time.Second), Int64("size", 10))
+
+	entries := logs.All()
+	if len(entries) != 1 || entries[0].Level != zapcore.WarnLevel || entries[0].Message != "slow request" {
+		t.Fatalf("Unexpected entries %+v", entries)
+	}
+	fields := entries[0].ContextMap()
+	if fields["method"] != "/svc/M" || fields["elapsed"] != 2*time.Second || fields["size"] != int64(10) {
+		t.Errorf("Expected typed fields, got %v", fields)
+	}
+}
+
+func TestSlogLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewSlog(slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo})))
+
+	logger.Debug("dropped")
+	logger.Error("request failed", String("code", "Internal"), Bool("retried", true))
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("Expected a single JSON entry, got %q", buf.String())
+	}
+	if entry["level"] != "ERROR" || entry["msg"] != "request failed" || entry["code"] != "Internal" || entry["retried"] != true {
+		t.Errorf("Unexpected entry %v", entry)
+	}
+}
+
+func TestLogrusLogger(t *testing.T) {
+	var logged []string
+	logger := NewLogrus[fakeLogrusEntry](fakeLogrusEntry{logged: &logged})
+
+	logger.Info("served", String("method", "/svc/M"))
+	logger.Debug("plain")
+
+	want := []string{`info served {"method":"/svc/M"}`, `debug plain null`}
+	if len(logged) != len(want) {
+		t.Fatalf("Expected %v, got %v", want, logged)
+	}
+	for i := range want {
+		if logged[i] != want[i] {
+			t.Errorf("entry %d = %q, want %q", i, logged[i], want[i])
+		}
+	}
+}
+
+func TestNop(t *testing.T) {
+	// Nop accepts every level without output or panics
+	logger := Nop()
+	logger.Debug("d", Any("k", nil))
+	logger.Info("i")
+	logger.Warn("w")
+	logger.Error("e", Time("at", time.Now()))
+}
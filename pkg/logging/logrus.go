@@ -0,0 +1,42 @@
+package logging
+
+// LogrusEntry is the part of *logrus.Entry used by the logrus adapter. It is declared
+// here so that grpc-guardian does not depend on logrus; *logrus.Entry satisfies
+// LogrusEntry[*logrus.Entry].
+type LogrusEntry[E any] interface {
+	WithField(key string, value interface{}) E
+	Debug(args ...interface{})
+	Info(args ...interface{})
+	Warn(args ...interface{})
+	Error(args ...interface{})
+}
+
+// logrusLogger adapts a logrus entry
+type logrusLogger[E LogrusEntry[E]] struct {
+	entry E
+}
+
+// NewLogrus adapts a logrus entry. Wrap a *logrus.Logger with logrus.NewEntry.
+//
+// Example usage:
+//
+//	logger := logrus.New()
+//	logger.SetFormatter(&logrus.JSONFormatter{})
+//	middleware.Logging(middleware.WithStructuredLogger(logging.NewLogrus(logrus.NewEntry(logger))))
+func NewLogrus[E LogrusEntry[E]](entry E) Logger {
+	return &logrusLogger[E]{entry: entry}
+}
+
+func (l *logrusLogger[E]) Debug(msg string, fields ...Field) { l.with(fields).Debug(msg) }
+func (l *logrusLogger[E]) Info(msg string, fields ...Field)  { l.with(fields).Info(msg) }
+func (l *logrusLogger[E]) Warn(msg string, fields ...Field)  { l.with(fields).Warn(msg) }
+func (l *logrusLogger[E]) Error(msg string, fields ...Field) { l.with(fields).Error(msg) }
+
+// with returns the entry with the fields attached
+func (l *logrusLogger[E]) with(fields []Field) E {
+	entry := l.entry
+	for _, f := range fields {
+		entry = entry.WithField(f.Key, f.Value)
+	}
+	return entry
+}
@@ -0,0 +1,384 @@
+package logging
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// DefaultRedactionMask replaces redacted values
+const DefaultRedactionMask = "[REDACTED]"
+
+// Redactor masks sensitive fields of request and response payloads before they are
+// logged. Payloads are converted to maps keyed by proto field name (JSON name for
+// other types); matching fields are replaced by the mask.
+//
+// Field paths are dot-separated. A single name ("password") matches that field at any
+// depth; a dotted path ("card.number", "*.ssn") matches from the message root, where "*"
+// matches any one field. List elements share the path of their field and map keys are
+// path segments.
+type Redactor struct {
+	rules       [][]string
+	exact       map[string][][]string
+	prefixes    map[string][][]string
+	mask        string
+	maxSize     int
+	protoRedact bool
+}
+
+// RedactOption configures a Redactor
+type RedactOption func(*Redactor)
+
+// WithRedactedFields masks the given field paths in every method
+func WithRedactedFields(paths ...string) RedactOption {
+	return func(r *Redactor) {
+		r.rules = append(r.rules, parsePaths(paths)...)
+	}
+}
+
+// WithMethodRedactedFields masks field paths for methods matching a pattern, in addition
+// to the global ones. Patterns are exact methods ("/pkg.Service/Method") or prefixes
+// ending in "*" ("/pkg.Service/*"); the rules of every matching pattern apply.
+func WithMethodRedactedFields(pattern string, paths ...string) RedactOption {
+	return func(r *Redactor) {
+		if strings.HasSuffix(pattern, "*") {
+			prefix := strings.TrimSuffix(pattern, "*")
+			r.prefixes[prefix] = append(r.prefixes[prefix], parsePaths(paths)...)
+			return
+		}
+		r.exact[pattern] = append(r.exact[pattern], parsePaths(paths)...)
+	}
+}
+
+// WithRedactionMask sets the replacement for redacted values
+// Default: DefaultRedactionMask
+func WithRedactionMask(mask string) RedactOption {
+	return func(r *Redactor) {
+		r.mask = mask
+	}
+}
+
+// WithMaxPayloadSize truncates payloads whose JSON encoding exceeds size bytes
+// Default: 0 (no limit)
+func WithMaxPayloadSize(size int) RedactOption {
+	return func(r *Redactor) {
+		r.maxSize = size
+	}
+}
+
+// WithoutProtoRedactOption ignores the debug_redact field option of proto messages
+func WithoutProtoRedactOption() RedactOption {
+	return func(r *Redactor) {
+		r.protoRedact = false
+	}
+}
+
+// NewRedactor creates a redactor. Proto fields declared with [debug_redact = true] are
+// always masked unless WithoutProtoRedactOption is set.
+//
+// Example usage:
+//
+//	redactor := logging.NewRedactor(
+//	    logging.WithRedactedFields("password", "*.ssn"),
+//	    logging.WithMethodRedactedFields("/payments.Payments/*", "card.number"),
+//	    logging.WithMaxPayloadSize(4096),
+//	)
+func NewRedactor(opts ...RedactOption) *Redactor {
+	r := &Redactor{
+		exact:       make(map[string][][]string),
+		prefixes:    make(map[string][][]string),
+		mask:        DefaultRedactionMask,
+		protoRedact: true,
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
+}
+
+// parsePaths splits field paths into segments
+func parsePaths(paths []string) [][]string {
+	rules := make([][]string, 0, len(paths))
+	for _, path := range paths {
+		if path != "" {
+			rules = append(rules, strings.Split(path, "."))
+		}
+	}
+	return rules
+}
+
+// Redact returns a log-safe copy of payload for the given method
+func (r *Redactor) Redact(method string, payload interface{}) interface{} {
+	if payload == nil {
+		return nil
+	}
+
+	rules := r.rulesFor(method)
+
+	var value interface{}
+	if msg, ok := payload.(proto.Message); ok {
+		value = r.message(msg.ProtoReflect(), nil, rules)
+	} else {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			// The rules cannot be applied, so the payload is not logged
+			return fmt.Sprintf("<%T>", payload)
+		}
+		if err := json.Unmarshal(data, &value); err != nil {
+			return fmt.Sprintf("<%T>", payload)
+		}
+		value = r.value(value, nil, rules)
+	}
+
+	return r.truncate(value)
+}
+
+// RedactProto returns a copy of msg with redacted fields masked in place, so it stays a
+// valid message of the same type, e.g. for recordings that are replayed later. String and
+// bytes fields are set to the mask; fields of other kinds are cleared. The payload size
+// limit does not apply.
+func (r *Redactor) RedactProto(method string, msg proto.Message) proto.Message {
+	if msg == nil {
+		return nil
+	}
+	clone := proto.Clone(msg)
+	r.redactInPlace(clone.ProtoReflect(), nil, r.rulesFor(method))
+	return clone
+}
+
+// redactInPlace masks the redacted fields of a message
+func (r *Redactor) redactInPlace(m protoreflect.Message, path []string, rules [][]string) {
+	// Collect the fields first: a message must not be mutated while ranging over it
+	var fields []protoreflect.FieldDescriptor
+	m.Range(func(fd protoreflect.FieldDescriptor, _ protoreflect.Value) bool {
+		fields = append(fields, fd)
+		return true
+	})
+
+	for _, fd := range fields {
+		fieldPath := append(path[:len(path):len(path)], string(fd.Name()))
+		if (r.protoRedact && debugRedact(fd)) || matches(rules, fieldPath) {
+			r.maskField(m, fd)
+			continue
+		}
+
+		switch {
+		case fd.IsList():
+			if kind := fd.Kind(); kind == protoreflect.MessageKind || kind == protoreflect.GroupKind {
+				list := m.Mutable(fd).List()
+				for i := 0; i < list.Len(); i++ {
+					r.redactInPlace(list.Get(i).Message(), fieldPath, rules)
+				}
+			}
+
+		case fd.IsMap():
+			entries := m.Mutable(fd).Map()
+			var keys []protoreflect.MapKey
+			entries.Range(func(k protoreflect.MapKey, _ protoreflect.Value) bool {
+				keys = append(keys, k)
+				return true
+			})
+			for _, k := range keys {
+				keyPath := append(fieldPath[:len(fieldPath):len(fieldPath)], k.String())
+				switch {
+				case !matches(rules, keyPath):
+					if kind := fd.MapValue().Kind(); kind == protoreflect.MessageKind || kind == protoreflect.GroupKind {
+						r.redactInPlace(entries.Get(k).Message(), keyPath, rules)
+					}
+				case fd.MapValue().Kind() == protoreflect.StringKind:
+					entries.Set(k, protoreflect.ValueOfString(r.mask))
+				case fd.MapValue().Kind() == protoreflect.BytesKind:
+					entries.Set(k, protoreflect.ValueOfBytes([]byte(r.mask)))
+				default:
+					entries.Clear(k)
+				}
+			}
+
+		case fd.Kind() == protoreflect.MessageKind || fd.Kind() == protoreflect.GroupKind:
+			r.redactInPlace(m.Mutable(fd).Message(), fieldPath, rules)
+		}
+	}
+}
+
+// maskField replaces a redacted field of a message
+func (r *Redactor) maskField(m protoreflect.Message, fd protoreflect.FieldDescriptor) {
+	switch {
+	case fd.IsMap():
+		m.Clear(fd)
+	case fd.IsList():
+		list := m.Mutable(fd).List()
+		for i := 0; i < list.Len(); i++ {
+			switch fd.Kind() {
+			case protoreflect.StringKind:
+				list.Set(i, protoreflect.ValueOfString(r.mask))
+			case protoreflect.BytesKind:
+				list.Set(i, protoreflect.ValueOfBytes([]byte(r.mask)))
+			default:
+				m.Clear(fd)
+				return
+			}
+		}
+	case fd.Kind() == protoreflect.StringKind:
+		m.Set(fd, protoreflect.ValueOfString(r.mask))
+	case fd.Kind() == protoreflect.BytesKind:
+		m.Set(fd, protoreflect.ValueOfBytes([]byte(r.mask)))
+	default:
+		m.Clear(fd)
+	}
+}
+
+// rulesFor returns the global rules plus those of every pattern matching the method
+func (r *Redactor) rulesFor(method string) [][]string {
+	rules := r.rules
+	if extra, ok := r.exact[method]; ok {
+		rules = append(rules[:len(rules):len(rules)], extra...)
+	}
+
+	prefixes := make([]string, 0, len(r.prefixes))
+	for prefix := range r.prefixes {
+		if strings.HasPrefix(method, prefix) {
+			prefixes = append(prefixes, prefix)
+		}
+	}
+	sort.Strings(prefixes)
+	for _, prefix := range prefixes {
+		rules = append(rules[:len(rules):len(rules)], r.prefixes[prefix]...)
+	}
+
+	return rules
+}
+
+// message converts a proto message, masking redacted fields. Only populated fields are
+// included and enums are logged by name.
+func (r *Redactor) message(m protoreflect.Message, path []string, rules [][]string) map[string]interface{} {
+	out := make(map[string]interface{})
+
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		name := string(fd.Name())
+		fieldPath := append(path[:len(path):len(path)], name)
+
+		if (r.protoRedact && debugRedact(fd)) || matches(rules, fieldPath) {
+			out[name] = r.mask
+			return true
+		}
+
+		switch {
+		case fd.IsList():
+			list := v.List()
+			values := make([]interface{}, list.Len())
+			for i := range values {
+				values[i] = r.scalar(fd, list.Get(i), fieldPath, rules)
+			}
+			out[name] = values
+
+		case fd.IsMap():
+			values := make(map[string]interface{}, v.Map().Len())
+			v.Map().Range(func(k protoreflect.MapKey, mv protoreflect.Value) bool {
+				keyPath := append(fieldPath[:len(fieldPath):len(fieldPath)], k.String())
+				if matches(rules, keyPath) {
+					values[k.String()] = r.mask
+				} else {
+					values[k.String()] = r.scalar(fd.MapValue(), mv, keyPath, rules)
+				}
+				return true
+			})
+			out[name] = values
+
+		default:
+			out[name] = r.scalar(fd, v, fieldPath, rules)
+		}
+		return true
+	})
+
+	return out
+}
+
+// scalar converts a singular proto value
+func (r *Redactor) scalar(fd protoreflect.FieldDescriptor, v protoreflect.Value, path []string, rules [][]string) interface{} {
+	switch fd.Kind() {
+	case protoreflect.EnumKind:
+		if ev := fd.Enum().Values().ByNumber(v.Enum()); ev != nil {
+			return string(ev.Name())
+		}
+		return int64(v.Enum())
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return r.message(v.Message(), path, rules)
+	case protoreflect.BytesKind:
+		return append([]byte(nil), v.Bytes()...)
+	}
+	return v.Interface()
+}
+
+// value masks redacted fields of a decoded JSON value in place
+func (r *Redactor) value(v interface{}, path []string, rules [][]string) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, child := range v {
+			childPath := append(path[:len(path):len(path)], k)
+			if matches(rules, childPath) {
+				v[k] = r.mask
+			} else {
+				v[k] = r.value(child, childPath, rules)
+			}
+		}
+	case []interface{}:
+		for i, child := range v {
+			v[i] = r.value(child, path, rules)
+		}
+	}
+	return v
+}
+
+// truncate replaces payloads over the size limit with their truncated JSON encoding
+func (r *Redactor) truncate(value interface{}) interface{} {
+	if r.maxSize <= 0 {
+		return value
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil || len(data) <= r.maxSize {
+		return value
+	}
+	return strings.ToValidUTF8(string(data[:r.maxSize]), "") +
+		fmt.Sprintf("...(%d bytes truncated)", len(data)-r.maxSize)
+}
+
+// matches reports whether any rule matches the field path
+func matches(rules [][]string, path []string) bool {
+	for _, rule := range rules {
+		if len(rule) == 1 && rule[0] != "*" {
+			if rule[0] == path[len(path)-1] {
+				return true
+			}
+			continue
+		}
+
+		if len(rule) != len(path) {
+			continue
+		}
+		matched := true
+		for i, segment := range rule {
+			if segment != "*" && segment != path[i] {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+// debugRedact reports whether a field is declared with [debug_redact = true]
+func debugRedact(fd protoreflect.FieldDescriptor) bool {
+	opts, ok := fd.Options().(*descriptorpb.FieldOptions)
+	return ok && opts.GetDebugRedact()
+}
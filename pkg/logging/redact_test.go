@@ -0,0 +1,158 @@
+package logging
+
+import (
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// redactTestMessage builds a dynamic message with a debug_redact field
+func redactTestMessage(t *testing.T) *dynamicpb.Message {
+	t.Helper()
+
+	field := func(name string, number int32, typ descriptorpb.FieldDescriptorProto_Type, typeName string, redact bool) *descriptorpb.FieldDescriptorProto {
+		fd := &descriptorpb.FieldDescriptorProto{
+			Name:   proto.String(name),
+			Number: proto.Int32(number),
+			Label:  descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+			Type:   typ.Enum(),
+		}
+		if typeName != "" {
+			fd.TypeName = proto.String(typeName)
+		}
+		if redact {
+			fd.Options = &descriptorpb.FieldOptions{DebugRedact: proto.Bool(true)}
+		}
+		return fd
+	}
+
+	file, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:    proto.String("redacttest/user.proto"),
+		Package: proto.String("redacttest"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{
+			{
+				Name: proto.String("Profile"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					field("ssn", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING, "", false),
+					field("city", 2, descriptorpb.FieldDescriptorProto_TYPE_STRING, "", false),
+				},
+			},
+			{
+				Name: proto.String("User"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					field("name", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING, "", false),
+					field("password", 2, descriptorpb.FieldDescriptorProto_TYPE_STRING, "", false),
+					field("api_token", 3, descriptorpb.FieldDescriptorProto_TYPE_STRING, "", true),
+					field("profile", 4, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, ".redacttest.Profile", false),
+				},
+			},
+		},
+	}, protoregistry.GlobalFiles)
+	if err != nil {
+		t.Fatalf("failed to build descriptor: %v", err)
+	}
+
+	userDesc := file.Messages().ByName("User")
+	profileDesc := file.Messages().ByName("Profile")
+
+	profile := dynamicpb.NewMessage(profileDesc)
+	profile.Set(profileDesc.Fields().ByName("ssn"), protoreflect.ValueOfString("123-45-6789"))
+	profile.Set(profileDesc.Fields().ByName("city"), protoreflect.ValueOfString("Berlin"))
+
+	user := dynamicpb.NewMessage(userDesc)
+	user.Set(userDesc.Fields().ByName("name"), protoreflect.ValueOfString("alice"))
+	user.Set(userDesc.Fields().ByName("password"), protoreflect.ValueOfString("hunter2"))
+	user.Set(userDesc.Fields().ByName("api_token"), protoreflect.ValueOfString("tok-1"))
+	user.Set(userDesc.Fields().ByName("profile"), protoreflect.ValueOfMessage(profile))
+	return user
+}
+
+func TestRedactor(t *testing.T) {
+	user := redactTestMessage(t)
+
+	redactor := NewRedactor(
+		WithRedactedFields("password", "*.ssn"),
+		WithMethodRedactedFields("/users.Users/*", "profile.city"),
+	)
+
+	got := redactor.Redact("/users.Users/Get", user).(map[string]interface{})
+	profile := got["profile"].(map[string]interface{})
+	want := map[string]interface{}{
+		"name":      "alice",
+		"password":  DefaultRedactionMask,
+		"api_token": DefaultRedactionMask, // debug_redact
+		"ssn":       DefaultRedactionMask,
+		"city":      DefaultRedactionMask,
+	}
+	for k, v := range map[string]interface{}{
+		"name": got["name"], "password": got["password"], "api_token": got["api_token"],
+		"ssn": profile["ssn"], "city": profile["city"],
+	} {
+		if v != want[k] {
+			t.Errorf("%s = %v, want %v", k, v, want[k])
+		}
+	}
+
+	// Per-method rules do not leak into other methods
+	other := redactor.Redact("/orders.Orders/Get", user).(map[string]interface{})
+	if city := other["profile"].(map[string]interface{})["city"]; city != "Berlin" {
+		t.Errorf("city = %v, want Berlin", city)
+	}
+
+	// Non-proto payloads are redacted through their JSON form
+	type login struct {
+		User     string `json:"user"`
+		Password string `json:"password"`
+	}
+	plain := redactor.Redact("/auth.Auth/Login", []login{{User: "bob", Password: "secret"}})
+	if entry := plain.([]interface{})[0].(map[string]interface{}); entry["password"] != DefaultRedactionMask || entry["user"] != "bob" {
+		t.Errorf("Unexpected redacted struct: %v", entry)
+	}
+
+	// Truncation
+	truncated := NewRedactor(WithMaxPayloadSize(10)).Redact("/svc/M", map[string]string{"data": strings.Repeat("x", 100)})
+	if s, ok := truncated.(string); !ok || !strings.HasPrefix(s, `{"data":"x`) || !strings.Contains(s, "bytes truncated") {
+		t.Errorf("Unexpected truncated payload: %v", truncated)
+	}
+}
+
+func TestRedactor_RedactProto(t *testing.T) {
+	user := redactTestMessage(t)
+
+	redactor := NewRedactor(WithRedactedFields("password", "*.ssn"))
+	redacted := redactor.RedactProto("/users.Users/Get", user).ProtoReflect()
+	fields := redacted.Descriptor().Fields()
+	profile := redacted.Get(fields.ByName("profile")).Message()
+	profileFields := profile.Descriptor().Fields()
+
+	for name, got := range map[string]string{
+		"name":      redacted.Get(fields.ByName("name")).String(),
+		"password":  redacted.Get(fields.ByName("password")).String(),
+		"api_token": redacted.Get(fields.ByName("api_token")).String(),
+		"ssn":       profile.Get(profileFields.ByName("ssn")).String(),
+		"city":      profile.Get(profileFields.ByName("city")).String(),
+	} {
+		want := DefaultRedactionMask
+		switch name {
+		case "name":
+			want = "alice"
+		case "city":
+			want = "Berlin"
+		}
+		if got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+
+	// The original message is left untouched
+	if password := user.Get(user.Descriptor().Fields().ByName("password")).String(); password != "hunter2" {
+		t.Errorf("Expected the original password to be kept, got %q", password)
+	}
+}
@@ -0,0 +1,32 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+)
+
+// slogLogger adapts a *slog.Logger
+type slogLogger struct {
+	logger *slog.Logger
+}
+
+// NewSlog adapts a log/slog logger. Nil uses slog.Default().
+func NewSlog(logger *slog.Logger) Logger {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &slogLogger{logger: logger}
+}
+
+func (l *slogLogger) Debug(msg string, fields ...Field) { l.log(slog.LevelDebug, msg, fields) }
+func (l *slogLogger) Info(msg string, fields ...Field)  { l.log(slog.LevelInfo, msg, fields) }
+func (l *slogLogger) Warn(msg string, fields ...Field)  { l.log(slog.LevelWarn, msg, fields) }
+func (l *slogLogger) Error(msg string, fields ...Field) { l.log(slog.LevelError, msg, fields) }
+
+func (l *slogLogger) log(level slog.Level, msg string, fields []Field) {
+	attrs := make([]slog.Attr, len(fields))
+	for i, f := range fields {
+		attrs[i] = slog.Any(f.Key, f.Value)
+	}
+	l.logger.LogAttrs(context.Background(), level, msg, attrs...)
+}
@@ -0,0 +1,29 @@
+package logging
+
+import (
+	"go.uber.org/zap"
+)
+
+// zapLogger adapts a *zap.Logger
+type zapLogger struct {
+	logger *zap.Logger
+}
+
+// NewZap adapts a zap logger
+func NewZap(logger *zap.Logger) Logger {
+	return &zapLogger{logger: logger}
+}
+
+func (l *zapLogger) Debug(msg string, fields ...Field) { l.logger.Debug(msg, zapFields(fields)...) }
+func (l *zapLogger) Info(msg string, fields ...Field)  { l.logger.Info(msg, zapFields(fields)...) }
+func (l *zapLogger) Warn(msg string, fields ...Field)  { l.logger.Warn(msg, zapFields(fields)...) }
+func (l *zapLogger) Error(msg string, fields ...Field) { l.logger.Error(msg, zapFields(fields)...) }
+
+// zapFields converts fields; zap.Any keeps durations and timestamps typed
+func zapFields(fields []Field) []zap.Field {
+	out := make([]zap.Field, len(fields))
+	for i, f := range fields {
+		out[i] = zap.Any(f.Key, f.Value)
+	}
+	return out
+}
@@ -0,0 +1,80 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// AdaptiveRateCollector exports the decisions of an adaptive rate limiter's load monitor.
+//
+// Exported metrics (with the default "grpc" namespace):
+//
+//	grpc_adaptive_rate_limit                     current rate limit in requests per second
+//	grpc_adaptive_load_factor                    load factor fed to the limiter (0-1)
+//	grpc_adaptive_load_signal{signal}            signal: cpu, goroutines, latency (0-1)
+type AdaptiveRateCollector struct {
+	limit      prometheus.Gauge
+	loadFactor prometheus.Gauge
+	signals    *prometheus.GaugeVec
+}
+
+// NewAdaptiveRateCollector creates a collector and registers its metrics with the
+// registerer; nil uses prometheus.DefaultRegisterer. Only Namespace and ConstLabels of the
+// config are used.
+func NewAdaptiveRateCollector(registerer prometheus.Registerer, opts ...ConfigOption) (*AdaptiveRateCollector, error) {
+	config := DefaultConfig()
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	if registerer == nil {
+		registerer = prometheus.DefaultRegisterer
+	}
+
+	limit := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace:   config.Namespace,
+		Subsystem:   "adaptive",
+		Name:        "rate_limit",
+		Help:        "Current adaptive rate limit in requests per second",
+		ConstLabels: config.ConstLabels,
+	}, nil)
+	loadFactor := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace:   config.Namespace,
+		Subsystem:   "adaptive",
+		Name:        "load_factor",
+		Help:        "Load factor fed to the adaptive rate limiter",
+		ConstLabels: config.ConstLabels,
+	}, nil)
+	signals := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace:   config.Namespace,
+		Subsystem:   "adaptive",
+		Name:        "load_signal",
+		Help:        "Normalized load signals sampled by the load monitor",
+		ConstLabels: config.ConstLabels,
+	}, []string{"signal"})
+
+	var err error
+	if limit, err = registerGaugeVec(registerer, limit); err != nil {
+		return nil, err
+	}
+	if loadFactor, err = registerGaugeVec(registerer, loadFactor); err != nil {
+		return nil, err
+	}
+	if signals, err = registerGaugeVec(registerer, signals); err != nil {
+		return nil, err
+	}
+
+	return &AdaptiveRateCollector{
+		limit:      limit.WithLabelValues(),
+		loadFactor: loadFactor.WithLabelValues(),
+		signals:    signals,
+	}, nil
+}
+
+// Record records one load monitor decision
+func (c *AdaptiveRateCollector) Record(limit, loadFactor float64, signals map[string]float64) {
+	c.limit.Set(limit)
+	c.loadFactor.Set(loadFactor)
+	for signal, value := range signals {
+		c.signals.WithLabelValues(signal).Set(value)
+	}
+}
@@ -0,0 +1,114 @@
+package metrics
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Admission rejection reason label values
+const (
+	AdmissionRejectQueueFull = "queue_full"
+	AdmissionRejectDeadline  = "deadline"
+	AdmissionRejectTimeout   = "timeout"
+	AdmissionRejectEvicted   = "evicted"
+	AdmissionRejectCanceled  = "canceled"
+)
+
+// AdmissionCollector exports the state of an admission controller queue.
+//
+// Exported metrics (with the default "grpc" namespace):
+//
+//	grpc_admission_queue_depth{priority}
+//	grpc_admission_queue_wait_seconds{priority}           wait of admitted requests
+//	grpc_admission_rejected_total{priority, reason}       reason: queue_full, deadline, timeout, evicted, canceled
+type AdmissionCollector struct {
+	depth    *prometheus.GaugeVec
+	wait     *prometheus.HistogramVec
+	rejected *prometheus.CounterVec
+}
+
+// NewAdmissionCollector creates a collector and registers its metrics with the registerer;
+// nil uses prometheus.DefaultRegisterer. Namespace, ConstLabels and HistogramBuckets of the
+// config are used.
+func NewAdmissionCollector(registerer prometheus.Registerer, opts ...ConfigOption) (*AdmissionCollector, error) {
+	config := DefaultConfig()
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	if registerer == nil {
+		registerer = prometheus.DefaultRegisterer
+	}
+
+	c := &AdmissionCollector{
+		depth: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace:   config.Namespace,
+				Subsystem:   "admission",
+				Name:        "queue_depth",
+				Help:        "Number of requests waiting for admission by priority band",
+				ConstLabels: config.ConstLabels,
+			},
+			[]string{"priority"},
+		),
+		wait: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Namespace:   config.Namespace,
+				Subsystem:   "admission",
+				Name:        "queue_wait_seconds",
+				Help:        "Time admitted requests waited in the queue by priority band",
+				Buckets:     config.HistogramBuckets,
+				ConstLabels: config.ConstLabels,
+			},
+			[]string{"priority"},
+		),
+		rejected: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace:   config.Namespace,
+				Subsystem:   "admission",
+				Name:        "rejected_total",
+				Help:        "Total number of requests rejected by the admission controller",
+				ConstLabels: config.ConstLabels,
+			},
+			[]string{"priority", "reason"},
+		),
+	}
+
+	var err error
+	if c.depth, err = registerGaugeVec(registerer, c.depth); err != nil {
+		return nil, err
+	}
+	if c.rejected, err = registerCounterVec(registerer, c.rejected); err != nil {
+		return nil, err
+	}
+	if err := registerer.Register(c.wait); err != nil {
+		are, ok := err.(prometheus.AlreadyRegisteredError)
+		if !ok {
+			return nil, fmt.Errorf("failed to register metrics: %w", err)
+		}
+		existing, ok := are.ExistingCollector.(*prometheus.HistogramVec)
+		if !ok {
+			return nil, fmt.Errorf("failed to register metrics: %w", err)
+		}
+		c.wait = existing
+	}
+
+	return c, nil
+}
+
+// SetQueueDepth sets the number of queued requests of a priority band
+func (c *AdmissionCollector) SetQueueDepth(priority string, depth int) {
+	c.depth.WithLabelValues(priority).Set(float64(depth))
+}
+
+// RecordAdmitted records the queue wait of an admitted request
+func (c *AdmissionCollector) RecordAdmitted(priority string, wait time.Duration) {
+	c.wait.WithLabelValues(priority).Observe(wait.Seconds())
+}
+
+// RecordRejected records a request rejected before reaching the handler
+func (c *AdmissionCollector) RecordRejected(priority, reason string) {
+	c.rejected.WithLabelValues(priority, reason).Inc()
+}
@@ -0,0 +1,86 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Chaos fault label values
+const (
+	ChaosFaultLatency = "latency"
+	ChaosFaultError   = "error"
+	ChaosFaultTimeout = "timeout"
+
+	ChaosFaultThrottle   = "throttle"
+	ChaosFaultCorruption = "corruption"
+	ChaosFaultMetadata   = "metadata"
+)
+
+// ChaosCollector exports what chaos experiments injected, so an experiment's effect on
+// the service metrics can be told apart from real incidents.
+//
+// Exported metrics (with the default "grpc" namespace):
+//
+//	grpc_chaos_injected_total{fault}                fault: latency, error, timeout, throttle, corruption, metadata
+//	grpc_chaos_injected_latency_seconds_total
+type ChaosCollector struct {
+	injected *prometheus.CounterVec
+	latency  *prometheus.CounterVec
+}
+
+// NewChaosCollector creates a collector and registers its metrics with the registerer;
+// nil uses prometheus.DefaultRegisterer. Namespace and ConstLabels of the config are used.
+func NewChaosCollector(registerer prometheus.Registerer, opts ...ConfigOption) (*ChaosCollector, error) {
+	config := DefaultConfig()
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	if registerer == nil {
+		registerer = prometheus.DefaultRegisterer
+	}
+
+	c := &ChaosCollector{
+		injected: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace:   config.Namespace,
+				Subsystem:   "chaos",
+				Name:        "injected_total",
+				Help:        "Total number of faults injected by chaos experiments",
+				ConstLabels: config.ConstLabels,
+			},
+			[]string{"fault"},
+		),
+		latency: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace:   config.Namespace,
+				Subsystem:   "chaos",
+				Name:        "injected_latency_seconds_total",
+				Help:        "Total latency added by chaos experiments",
+				ConstLabels: config.ConstLabels,
+			},
+			nil,
+		),
+	}
+
+	var err error
+	if c.injected, err = registerCounterVec(registerer, c.injected); err != nil {
+		return nil, err
+	}
+	if c.latency, err = registerCounterVec(registerer, c.latency); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// RecordInjected records an injected fault
+func (c *ChaosCollector) RecordInjected(fault string) {
+	c.injected.WithLabelValues(fault).Inc()
+}
+
+// RecordLatency records injected latency
+func (c *ChaosCollector) RecordLatency(delay time.Duration) {
+	c.latency.WithLabelValues().Add(delay.Seconds())
+}
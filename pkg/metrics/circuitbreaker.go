@@ -0,0 +1,170 @@
+package metrics
+
+import (
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Circuit breaker state label values
+const (
+	BreakerStateClosed   = "closed"
+	BreakerStateOpen     = "open"
+	BreakerStateHalfOpen = "half_open"
+)
+
+// breakerStates lists every state label so the state gauge is one-hot
+var breakerStates = []string{BreakerStateClosed, BreakerStateOpen, BreakerStateHalfOpen}
+
+// CircuitBreakerCollector exports circuit breaker state and outcomes to Prometheus.
+// One collector can be shared by any number of breakers; each is identified by its name.
+//
+// Exported metrics (with the default "grpc" namespace):
+//
+//	grpc_circuit_breaker_state{breaker, state}              1 for the current state, 0 otherwise
+//	grpc_circuit_breaker_transitions_total{breaker, from, to}
+//	grpc_circuit_breaker_requests_total{breaker, result}    result: success, failure, rejected
+//	grpc_circuit_breaker_slow_calls_total{breaker}          calls over the slow-call threshold
+type CircuitBreakerCollector struct {
+	state       *prometheus.GaugeVec
+	transitions *prometheus.CounterVec
+	requests    *prometheus.CounterVec
+	slowCalls   *prometheus.CounterVec
+}
+
+// NewCircuitBreakerCollector creates a collector and registers its metrics with the registerer.
+// Pass PrometheusCollector.GetRegistry() to expose them next to the request metrics;
+// nil uses prometheus.DefaultRegisterer. Only Namespace and ConstLabels of the config are used.
+func NewCircuitBreakerCollector(registerer prometheus.Registerer, opts ...ConfigOption) (*CircuitBreakerCollector, error) {
+	config := DefaultConfig()
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	if registerer == nil {
+		registerer = prometheus.DefaultRegisterer
+	}
+
+	c := &CircuitBreakerCollector{
+		state: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace:   config.Namespace,
+				Subsystem:   "circuit_breaker",
+				Name:        "state",
+				Help:        "Current circuit breaker state (1 for the active state)",
+				ConstLabels: config.ConstLabels,
+			},
+			[]string{"breaker", "state"},
+		),
+		transitions: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace:   config.Namespace,
+				Subsystem:   "circuit_breaker",
+				Name:        "transitions_total",
+				Help:        "Total number of circuit breaker state transitions",
+				ConstLabels: config.ConstLabels,
+			},
+			[]string{"breaker", "from", "to"},
+		),
+		requests: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace:   config.Namespace,
+				Subsystem:   "circuit_breaker",
+				Name:        "requests_total",
+				Help:        "Total number of requests seen by circuit breakers by result",
+				ConstLabels: config.ConstLabels,
+			},
+			[]string{"breaker", "result"},
+		),
+		slowCalls: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace:   config.Namespace,
+				Subsystem:   "circuit_breaker",
+				Name:        "slow_calls_total",
+				Help:        "Total number of calls that took at least the slow-call threshold",
+				ConstLabels: config.ConstLabels,
+			},
+			[]string{"breaker"},
+		),
+	}
+
+	var err error
+	if c.state, err = registerGaugeVec(registerer, c.state); err != nil {
+		return nil, err
+	}
+	if c.transitions, err = registerCounterVec(registerer, c.transitions); err != nil {
+		return nil, err
+	}
+	if c.requests, err = registerCounterVec(registerer, c.requests); err != nil {
+		return nil, err
+	}
+	if c.slowCalls, err = registerCounterVec(registerer, c.slowCalls); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// SetState marks state as the current state of a breaker
+func (c *CircuitBreakerCollector) SetState(breaker, state string) {
+	for _, s := range breakerStates {
+		value := 0.0
+		if s == state {
+			value = 1
+		}
+		c.state.WithLabelValues(breaker, s).Set(value)
+	}
+}
+
+// RecordTransition records a state change and updates the state gauge
+func (c *CircuitBreakerCollector) RecordTransition(breaker, from, to string) {
+	c.transitions.WithLabelValues(breaker, from, to).Inc()
+	c.SetState(breaker, to)
+}
+
+// RecordSuccess records a request that succeeded
+func (c *CircuitBreakerCollector) RecordSuccess(breaker string) {
+	c.requests.WithLabelValues(breaker, "success").Inc()
+}
+
+// RecordFailure records a request counted as a failure
+func (c *CircuitBreakerCollector) RecordFailure(breaker string) {
+	c.requests.WithLabelValues(breaker, "failure").Inc()
+}
+
+// RecordRejected records a request rejected without reaching the handler
+func (c *CircuitBreakerCollector) RecordRejected(breaker string) {
+	c.requests.WithLabelValues(breaker, "rejected").Inc()
+}
+
+// RecordSlowCall records a call that took at least the breaker's slow-call threshold; it is
+// also recorded as a success or failure
+func (c *CircuitBreakerCollector) RecordSlowCall(breaker string) {
+	c.slowCalls.WithLabelValues(breaker).Inc()
+}
+
+// registerGaugeVec registers a gauge, reusing an identical one that is already registered
+func registerGaugeVec(registerer prometheus.Registerer, gauge *prometheus.GaugeVec) (*prometheus.GaugeVec, error) {
+	if err := registerer.Register(gauge); err != nil {
+		if are, ok := err.(prometheus.AlreadyRegisteredError); ok {
+			if existing, ok := are.ExistingCollector.(*prometheus.GaugeVec); ok {
+				return existing, nil
+			}
+		}
+		return nil, fmt.Errorf("failed to register metrics: %w", err)
+	}
+	return gauge, nil
+}
+
+// registerCounterVec registers a counter, reusing an identical one that is already registered
+func registerCounterVec(registerer prometheus.Registerer, counter *prometheus.CounterVec) (*prometheus.CounterVec, error) {
+	if err := registerer.Register(counter); err != nil {
+		if are, ok := err.(prometheus.AlreadyRegisteredError); ok {
+			if existing, ok := are.ExistingCollector.(*prometheus.CounterVec); ok {
+				return existing, nil
+			}
+		}
+		return nil, fmt.Errorf("failed to register metrics: %w", err)
+	}
+	return counter, nil
+}
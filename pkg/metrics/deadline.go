@@ -0,0 +1,119 @@
+package metrics
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Cancellation reasons recorded by DeadlineCollector
+const (
+	CancellationCanceled         = "canceled"
+	CancellationDeadlineExceeded = "deadline_exceeded"
+)
+
+// DefaultDeadlineBuckets are the buckets of the remaining deadline histogram, in seconds.
+// They are finer at the low end, where hopelessly short deadlines show up.
+var DefaultDeadlineBuckets = []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 300}
+
+// DeadlineCollector exports the deadlines clients send and the requests they abandon, so
+// client cancellations and exhausted deadlines can be told apart from server errors.
+//
+// Exported metrics (with the default "grpc" namespace):
+//
+//	grpc_server_deadline_remaining_seconds{method}      time left when the request arrived
+//	grpc_server_requests_without_deadline_total{method}
+//	grpc_server_client_cancellations_total{method, reason}   reason: canceled, deadline_exceeded
+type DeadlineCollector struct {
+	remaining     *prometheus.HistogramVec
+	noDeadline    *prometheus.CounterVec
+	cancellations *prometheus.CounterVec
+}
+
+// NewDeadlineCollector creates a collector and registers its metrics with the registerer;
+// nil uses prometheus.DefaultRegisterer.
+func NewDeadlineCollector(registerer prometheus.Registerer, opts ...ConfigOption) (*DeadlineCollector, error) {
+	config := DefaultConfig()
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	if registerer == nil {
+		registerer = prometheus.DefaultRegisterer
+	}
+
+	c := &DeadlineCollector{
+		remaining: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Namespace:   config.Namespace,
+				Subsystem:   "server",
+				Name:        "deadline_remaining_seconds",
+				Help:        "Time left until the client's deadline when the request arrived",
+				Buckets:     DefaultDeadlineBuckets,
+				ConstLabels: config.ConstLabels,
+			},
+			[]string{"method"},
+		),
+		noDeadline: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace:   config.Namespace,
+				Subsystem:   "server",
+				Name:        "requests_without_deadline_total",
+				Help:        "Total number of requests that arrived without a deadline",
+				ConstLabels: config.ConstLabels,
+			},
+			[]string{"method"},
+		),
+		cancellations: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace:   config.Namespace,
+				Subsystem:   "server",
+				Name:        "client_cancellations_total",
+				Help:        "Total number of requests abandoned by the client, by cancellation or an exhausted deadline",
+				ConstLabels: config.ConstLabels,
+			},
+			[]string{"method", "reason"},
+		),
+	}
+
+	if err := registerer.Register(c.remaining); err != nil {
+		are, ok := err.(prometheus.AlreadyRegisteredError)
+		if !ok {
+			return nil, fmt.Errorf("failed to register metrics: %w", err)
+		}
+		existing, ok := are.ExistingCollector.(*prometheus.HistogramVec)
+		if !ok {
+			return nil, fmt.Errorf("failed to register metrics: %w", err)
+		}
+		c.remaining = existing
+	}
+	var err error
+	if c.noDeadline, err = registerCounterVec(registerer, c.noDeadline); err != nil {
+		return nil, err
+	}
+	if c.cancellations, err = registerCounterVec(registerer, c.cancellations); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// RecordDeadline records the time left until the deadline when a request arrived; an
+// already expired deadline is recorded as zero
+func (c *DeadlineCollector) RecordDeadline(method string, remaining time.Duration) {
+	if remaining < 0 {
+		remaining = 0
+	}
+	c.remaining.WithLabelValues(method).Observe(remaining.Seconds())
+}
+
+// RecordNoDeadline records a request without a deadline
+func (c *DeadlineCollector) RecordNoDeadline(method string) {
+	c.noDeadline.WithLabelValues(method).Inc()
+}
+
+// RecordCancellation records a request abandoned by the client
+func (c *DeadlineCollector) RecordCancellation(method, reason string) {
+	c.cancellations.WithLabelValues(method, reason).Inc()
+}
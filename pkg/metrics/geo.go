@@ -0,0 +1,82 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Geo block reason label values
+const (
+	GeoBlockedCountry = "country"
+	GeoBlockedASN     = "asn"
+)
+
+// GeoCollector exports requests by caller country. Countries are a bounded label (about
+// 250 ISO codes plus "unknown"); ASNs are not used as a label.
+//
+// Exported metrics (with the default "grpc" namespace):
+//
+//	grpc_geo_requests_total{country}
+//	grpc_geo_blocked_total{reason}    reason: country, asn
+type GeoCollector struct {
+	requests *prometheus.CounterVec
+	blocked  *prometheus.CounterVec
+}
+
+// NewGeoCollector creates a collector and registers its metrics with the registerer;
+// nil uses prometheus.DefaultRegisterer. Namespace and ConstLabels of the config are used.
+func NewGeoCollector(registerer prometheus.Registerer, opts ...ConfigOption) (*GeoCollector, error) {
+	config := DefaultConfig()
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	if registerer == nil {
+		registerer = prometheus.DefaultRegisterer
+	}
+
+	c := &GeoCollector{
+		requests: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace:   config.Namespace,
+				Subsystem:   "geo",
+				Name:        "requests_total",
+				Help:        "Total number of requests by caller country",
+				ConstLabels: config.ConstLabels,
+			},
+			[]string{"country"},
+		),
+		blocked: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace:   config.Namespace,
+				Subsystem:   "geo",
+				Name:        "blocked_total",
+				Help:        "Total number of requests blocked by country or ASN rules",
+				ConstLabels: config.ConstLabels,
+			},
+			[]string{"reason"},
+		),
+	}
+
+	var err error
+	if c.requests, err = registerCounterVec(registerer, c.requests); err != nil {
+		return nil, err
+	}
+	if c.blocked, err = registerCounterVec(registerer, c.blocked); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// RecordRequest records a request from a country; empty countries count as "unknown"
+func (c *GeoCollector) RecordRequest(country string) {
+	if country == "" {
+		country = "unknown"
+	}
+	c.requests.WithLabelValues(country).Inc()
+}
+
+// RecordBlocked records a request blocked by a country or ASN rule
+func (c *GeoCollector) RecordBlocked(reason string) {
+	c.blocked.WithLabelValues(reason).Inc()
+}
@@ -0,0 +1,81 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// IP filter block reason label values
+const (
+	IPFilterDenied     = "denied"
+	IPFilterNotAllowed = "not_allowed"
+	IPFilterUnknown    = "unknown_address"
+)
+
+// IPFilterCollector exports IP filter decisions and list sizes. Blocked requests are not
+// labeled by address, which would give every scanner its own series.
+//
+// Exported metrics (with the default "grpc" namespace):
+//
+//	grpc_ip_filter_blocked_total{reason}    reason: denied, not_allowed, unknown_address
+//	grpc_ip_filter_rules{list}              list: allow, deny
+type IPFilterCollector struct {
+	blocked *prometheus.CounterVec
+	rules   *prometheus.GaugeVec
+}
+
+// NewIPFilterCollector creates a collector and registers its metrics with the registerer;
+// nil uses prometheus.DefaultRegisterer. Namespace and ConstLabels of the config are used.
+func NewIPFilterCollector(registerer prometheus.Registerer, opts ...ConfigOption) (*IPFilterCollector, error) {
+	config := DefaultConfig()
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	if registerer == nil {
+		registerer = prometheus.DefaultRegisterer
+	}
+
+	c := &IPFilterCollector{
+		blocked: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace:   config.Namespace,
+				Subsystem:   "ip_filter",
+				Name:        "blocked_total",
+				Help:        "Total number of requests blocked by the IP filter by reason",
+				ConstLabels: config.ConstLabels,
+			},
+			[]string{"reason"},
+		),
+		rules: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace:   config.Namespace,
+				Subsystem:   "ip_filter",
+				Name:        "rules",
+				Help:        "Number of CIDR ranges in the IP filter lists",
+				ConstLabels: config.ConstLabels,
+			},
+			[]string{"list"},
+		),
+	}
+
+	var err error
+	if c.blocked, err = registerCounterVec(registerer, c.blocked); err != nil {
+		return nil, err
+	}
+	if c.rules, err = registerGaugeVec(registerer, c.rules); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// RecordBlocked records a blocked request
+func (c *IPFilterCollector) RecordBlocked(reason string) {
+	c.blocked.WithLabelValues(reason).Inc()
+}
+
+// SetRules sets the sizes of the allow and deny lists
+func (c *IPFilterCollector) SetRules(allow, deny int) {
+	c.rules.WithLabelValues("allow").Set(float64(allow))
+	c.rules.WithLabelValues("deny").Set(float64(deny))
+}
@@ -0,0 +1,73 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// MirrorCollector exports the outcome of calls mirrored to a secondary backend. Mirror
+// failures never affect the primary call, so these metrics are the only place they show.
+//
+// Exported metrics (with the default "grpc" namespace):
+//
+//	grpc_mirror_requests_total{method, code}
+//	grpc_mirror_dropped_total{reason}    reason: max_in_flight, marshal
+type MirrorCollector struct {
+	requests *prometheus.CounterVec
+	dropped  *prometheus.CounterVec
+}
+
+// NewMirrorCollector creates a collector and registers its metrics with the registerer;
+// nil uses prometheus.DefaultRegisterer. Namespace and ConstLabels of the config are used.
+func NewMirrorCollector(registerer prometheus.Registerer, opts ...ConfigOption) (*MirrorCollector, error) {
+	config := DefaultConfig()
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	if registerer == nil {
+		registerer = prometheus.DefaultRegisterer
+	}
+
+	c := &MirrorCollector{
+		requests: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace:   config.Namespace,
+				Subsystem:   "mirror",
+				Name:        "requests_total",
+				Help:        "Total number of mirrored calls by method and status code",
+				ConstLabels: config.ConstLabels,
+			},
+			[]string{"method", "code"},
+		),
+		dropped: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace:   config.Namespace,
+				Subsystem:   "mirror",
+				Name:        "dropped_total",
+				Help:        "Total number of sampled calls that were not mirrored",
+				ConstLabels: config.ConstLabels,
+			},
+			[]string{"reason"},
+		),
+	}
+
+	var err error
+	if c.requests, err = registerCounterVec(registerer, c.requests); err != nil {
+		return nil, err
+	}
+	if c.dropped, err = registerCounterVec(registerer, c.dropped); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// RecordRequest records a mirrored call
+func (c *MirrorCollector) RecordRequest(method, code string) {
+	c.requests.WithLabelValues(method, code).Inc()
+}
+
+// RecordDropped records a sampled call that was not mirrored
+func (c *MirrorCollector) RecordDropped(reason string) {
+	c.dropped.WithLabelValues(reason).Inc()
+}
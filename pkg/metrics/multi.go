@@ -0,0 +1,154 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// MultiCollector fans every measurement out to several collectors, e.g. Prometheus and
+// StatsD side by side while migrating between monitoring systems
+type MultiCollector struct {
+	collectors []MetricsCollector
+}
+
+// Multi returns a collector that records to all given collectors in order.
+// Nil collectors are ignored.
+func Multi(collectors ...MetricsCollector) *MultiCollector {
+	m := &MultiCollector{}
+	for _, c := range collectors {
+		if c != nil {
+			m.collectors = append(m.collectors, c)
+		}
+	}
+	return m
+}
+
+// RecordRequest records the request on every collector
+func (m *MultiCollector) RecordRequest(method string, code string, duration time.Duration) {
+	for _, c := range m.collectors {
+		c.RecordRequest(method, code, duration)
+	}
+}
+
+// RecordRequestWithTraceID records the request on every collector, with the trace ID as an
+// exemplar on collectors that support it
+func (m *MultiCollector) RecordRequestWithTraceID(method string, code string, duration time.Duration, traceID string) {
+	for _, c := range m.collectors {
+		if ec, ok := c.(ExemplarMetricsCollector); ok {
+			ec.RecordRequestWithTraceID(method, code, duration, traceID)
+		} else {
+			c.RecordRequest(method, code, duration)
+		}
+	}
+}
+
+// RecordError records the error on every collector
+func (m *MultiCollector) RecordError(method string, errorType string) {
+	for _, c := range m.collectors {
+		c.RecordError(method, errorType)
+	}
+}
+
+// RecordActiveRequests updates the active requests gauge on every collector
+func (m *MultiCollector) RecordActiveRequests(method string, delta int) {
+	for _, c := range m.collectors {
+		c.RecordActiveRequests(method, delta)
+	}
+}
+
+// RecordOpenConnections updates the open connections gauge on every collector that
+// supports connection metrics
+func (m *MultiCollector) RecordOpenConnections(delta int) {
+	for _, c := range m.collectors {
+		if cc, ok := c.(ConnectionMetricsCollector); ok {
+			cc.RecordOpenConnections(delta)
+		}
+	}
+}
+
+// RecordMessageSize records the message size on every collector
+func (m *MultiCollector) RecordMessageSize(method string, direction string, size int) {
+	for _, c := range m.collectors {
+		c.RecordMessageSize(method, direction, size)
+	}
+}
+
+// RecordWireSize records the wire size on every collector that supports it
+func (m *MultiCollector) RecordWireSize(method string, direction string, size int) {
+	for _, c := range m.collectors {
+		if wc, ok := c.(WireSizeMetricsCollector); ok {
+			wc.RecordWireSize(method, direction, size)
+		}
+	}
+}
+
+// RecordRetryAttempt records the attempt on every collector that supports retry metrics
+func (m *MultiCollector) RecordRetryAttempt(method string, code string) {
+	for _, c := range m.collectors {
+		if rc, ok := c.(RetryMetricsCollector); ok {
+			rc.RecordRetryAttempt(method, code)
+		}
+	}
+}
+
+// RecordRetryResult records the call result on every collector that supports retry metrics
+func (m *MultiCollector) RecordRetryResult(method string, code string, attempts int, exhausted bool) {
+	for _, c := range m.collectors {
+		if rc, ok := c.(RetryMetricsCollector); ok {
+			rc.RecordRetryResult(method, code, attempts, exhausted)
+		}
+	}
+}
+
+// RecordCacheHit records the hit on every collector that supports cache metrics
+func (m *MultiCollector) RecordCacheHit(method string) {
+	for _, c := range m.collectors {
+		if cc, ok := c.(CacheMetricsCollector); ok {
+			cc.RecordCacheHit(method)
+		}
+	}
+}
+
+// RecordCacheMiss records the miss on every collector that supports cache metrics
+func (m *MultiCollector) RecordCacheMiss(method string) {
+	for _, c := range m.collectors {
+		if cc, ok := c.(CacheMetricsCollector); ok {
+			cc.RecordCacheMiss(method)
+		}
+	}
+}
+
+// RecordCacheSet records the set on every collector that supports cache metrics
+func (m *MultiCollector) RecordCacheSet(method string) {
+	for _, c := range m.collectors {
+		if cc, ok := c.(CacheMetricsCollector); ok {
+			cc.RecordCacheSet(method)
+		}
+	}
+}
+
+// RecordCacheEvictions records the evictions on every collector that supports cache metrics
+func (m *MultiCollector) RecordCacheEvictions(method string, count int) {
+	for _, c := range m.collectors {
+		if cc, ok := c.(CacheMetricsCollector); ok {
+			cc.RecordCacheEvictions(method, count)
+		}
+	}
+}
+
+// GetRegistry returns the registry of the first collector that has one, so the
+// Prometheus /metrics endpoint keeps working when other collectors are added
+func (m *MultiCollector) GetRegistry() *prometheus.Registry {
+	for _, c := range m.collectors {
+		if registry := c.GetRegistry(); registry != nil {
+			return registry
+		}
+	}
+	return nil
+}
+
+// Collectors returns the underlying collectors
+func (m *MultiCollector) Collectors() []MetricsCollector {
+	return m.collectors
+}
@@ -0,0 +1,53 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// NoopCollector is a MetricsCollector that discards everything. Use it in tests,
+// benchmarks and binaries that only sometimes export metrics.
+type NoopCollector struct {
+	registry *prometheus.Registry
+}
+
+// Noop returns a collector that records nothing
+func Noop() *NoopCollector {
+	return &NoopCollector{registry: prometheus.NewRegistry()}
+}
+
+// RecordRequest does nothing
+func (n *NoopCollector) RecordRequest(method string, code string, duration time.Duration) {}
+
+// RecordError does nothing
+func (n *NoopCollector) RecordError(method string, errorType string) {}
+
+// RecordActiveRequests does nothing
+func (n *NoopCollector) RecordActiveRequests(method string, delta int) {}
+
+// RecordMessageSize does nothing
+func (n *NoopCollector) RecordMessageSize(method string, direction string, size int) {}
+
+// RecordRetryAttempt does nothing
+func (n *NoopCollector) RecordRetryAttempt(method string, code string) {}
+
+// RecordRetryResult does nothing
+func (n *NoopCollector) RecordRetryResult(method string, code string, attempts int, exhausted bool) {}
+
+// RecordCacheHit does nothing
+func (n *NoopCollector) RecordCacheHit(method string) {}
+
+// RecordCacheMiss does nothing
+func (n *NoopCollector) RecordCacheMiss(method string) {}
+
+// RecordCacheSet does nothing
+func (n *NoopCollector) RecordCacheSet(method string) {}
+
+// RecordCacheEvictions does nothing
+func (n *NoopCollector) RecordCacheEvictions(method string, count int) {}
+
+// GetRegistry returns an empty registry so /metrics handlers keep working
+func (n *NoopCollector) GetRegistry() *prometheus.Registry {
+	return n.registry
+}
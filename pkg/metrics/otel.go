@@ -0,0 +1,188 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// otelInstrumentationName identifies the meter used by OTelCollector
+const otelInstrumentationName = "github.com/grpc-guardian/grpc-guardian/pkg/metrics"
+
+// OTelCollector implements MetricsCollector with the OpenTelemetry metrics API, for teams
+// that export over OTLP instead of running a Prometheus scrape endpoint. Instruments are
+// named after the config namespace and subsystem (e.g. "grpc.server.requests"); const
+// labels become attributes on every measurement.
+type OTelCollector struct {
+	config   *Config
+	registry *prometheus.Registry
+	common   []attribute.KeyValue
+
+	requests        metric.Int64Counter
+	requestDuration metric.Float64Histogram
+	activeRequests  metric.Int64UpDownCounter
+	openConnections metric.Int64UpDownCounter
+	errors          metric.Int64Counter
+	messageSize     metric.Int64Histogram
+	wireSize        metric.Int64Histogram
+}
+
+// NewOTelCollector creates a collector that records through the given meter provider,
+// typically an SDK MeterProvider with an OTLP exporter. Nil uses the global provider
+// (otel.GetMeterProvider). Histogram buckets, namespace, subsystem, const labels and
+// per-method metrics are taken from the config options.
+//
+// Example usage:
+//
+//	exporter, _ := otlpmetricgrpc.New(ctx)
+//	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(sdkmetric.NewPeriodicReader(exporter)))
+//
+//	collector, err := metrics.NewOTelCollector(provider, metrics.WithConstLabels(map[string]string{
+//	    "service": "orders",
+//	}))
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	chain := guardian.NewChain(middleware.MetricsMiddleware(collector))
+func NewOTelCollector(provider metric.MeterProvider, opts ...ConfigOption) (*OTelCollector, error) {
+	config := DefaultConfig()
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	if provider == nil {
+		provider = otel.GetMeterProvider()
+	}
+	meter := provider.Meter(otelInstrumentationName)
+
+	c := &OTelCollector{
+		config:   config,
+		registry: prometheus.NewRegistry(),
+	}
+
+	// Sorted so every measurement carries the attributes in the same order
+	keys := make([]string, 0, len(config.ConstLabels))
+	for k := range config.ConstLabels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		c.common = append(c.common, attribute.String(k, config.ConstLabels[k]))
+	}
+
+	var err error
+	if c.requests, err = meter.Int64Counter(c.name("requests"),
+		metric.WithDescription("Total number of gRPC requests handled")); err != nil {
+		return nil, fmt.Errorf("failed to create requests counter: %w", err)
+	}
+
+	if config.EnableHistogram {
+		if c.requestDuration, err = meter.Float64Histogram(c.name("request.duration"),
+			metric.WithDescription("Duration of gRPC requests"),
+			metric.WithUnit("s"),
+			metric.WithExplicitBucketBoundaries(config.HistogramBuckets...)); err != nil {
+			return nil, fmt.Errorf("failed to create duration histogram: %w", err)
+		}
+	}
+
+	if c.activeRequests, err = meter.Int64UpDownCounter(c.name("active_requests"),
+		metric.WithDescription("Number of active gRPC requests")); err != nil {
+		return nil, fmt.Errorf("failed to create active requests counter: %w", err)
+	}
+
+	if c.openConnections, err = meter.Int64UpDownCounter(c.name("open_connections"),
+		metric.WithDescription("Number of open transport connections")); err != nil {
+		return nil, fmt.Errorf("failed to create open connections counter: %w", err)
+	}
+
+	if c.errors, err = meter.Int64Counter(c.name("errors"),
+		metric.WithDescription("Total number of gRPC errors")); err != nil {
+		return nil, fmt.Errorf("failed to create errors counter: %w", err)
+	}
+
+	if c.messageSize, err = meter.Int64Histogram(c.name("message.size"),
+		metric.WithDescription("Size of gRPC messages"),
+		metric.WithUnit("By")); err != nil {
+		return nil, fmt.Errorf("failed to create message size histogram: %w", err)
+	}
+
+	if c.wireSize, err = meter.Int64Histogram(c.name("message.wire_size"),
+		metric.WithDescription("Size of gRPC messages on the wire, after compression"),
+		metric.WithUnit("By")); err != nil {
+		return nil, fmt.Errorf("failed to create wire size histogram: %w", err)
+	}
+
+	return c, nil
+}
+
+// name builds an instrument name from the namespace and subsystem
+func (c *OTelCollector) name(suffix string) string {
+	name := suffix
+	if c.config.Subsystem != "" {
+		name = c.config.Subsystem + "." + name
+	}
+	if c.config.Namespace != "" {
+		name = c.config.Namespace + "." + name
+	}
+	return name
+}
+
+// attributes returns the measurement option for the const labels plus extra attributes.
+// The method attribute is dropped when per-method metrics are disabled.
+func (c *OTelCollector) attributes(method string, extra ...attribute.KeyValue) metric.MeasurementOption {
+	attrs := make([]attribute.KeyValue, 0, len(c.common)+len(extra)+1)
+	attrs = append(attrs, c.common...)
+	if c.config.EnablePerMethodMetrics && method != "" {
+		attrs = append(attrs, attribute.String("method", method))
+	}
+	attrs = append(attrs, extra...)
+	return metric.WithAttributes(attrs...)
+}
+
+// RecordRequest records a completed request
+func (c *OTelCollector) RecordRequest(method string, code string, duration time.Duration) {
+	ctx := context.Background()
+	attrs := c.attributes(method, attribute.String("code", code))
+
+	c.requests.Add(ctx, 1, attrs)
+	if c.requestDuration != nil {
+		c.requestDuration.Record(ctx, duration.Seconds(), attrs)
+	}
+}
+
+// RecordError records an error occurrence
+func (c *OTelCollector) RecordError(method string, errorType string) {
+	c.errors.Add(context.Background(), 1, c.attributes(method, attribute.String("error_type", errorType)))
+}
+
+// RecordActiveRequests updates the active requests counter
+func (c *OTelCollector) RecordActiveRequests(method string, delta int) {
+	c.activeRequests.Add(context.Background(), int64(delta), c.attributes(method))
+}
+
+// RecordOpenConnections updates the open connections counter
+func (c *OTelCollector) RecordOpenConnections(delta int) {
+	c.openConnections.Add(context.Background(), int64(delta), c.attributes(""))
+}
+
+// RecordMessageSize records request/response message sizes
+func (c *OTelCollector) RecordMessageSize(method string, direction string, size int) {
+	c.messageSize.Record(context.Background(), int64(size), c.attributes(method, attribute.String("direction", direction)))
+}
+
+// RecordWireSize records message sizes on the wire
+func (c *OTelCollector) RecordWireSize(method string, direction string, size int) {
+	c.wireSize.Record(context.Background(), int64(size), c.attributes(method, attribute.String("direction", direction)))
+}
+
+// GetRegistry returns an empty Prometheus registry; measurements are exported by the
+// OpenTelemetry meter provider instead
+func (c *OTelCollector) GetRegistry() *prometheus.Registry {
+	return c.registry
+}
@@ -0,0 +1,80 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Reasons recorded by OutlierCollector for ejections
+const (
+	EjectionConsecutiveFailures = "consecutive_failures" // Too many failures in a row
+	EjectionFailureRate         = "failure_rate"         // Failure rate over the threshold in an interval
+	EjectionLatency             = "latency"              // Mean latency far above the other endpoints
+)
+
+// OutlierCollector exports the endpoints outlier detection ejects from load balancing.
+//
+// Exported metrics (with the default "grpc" namespace):
+//
+//	grpc_outlier_ejections_total{endpoint, reason}   reason: consecutive_failures, failure_rate, latency
+//	grpc_outlier_ejected{endpoint}                   1 while the endpoint is ejected, 0 otherwise
+type OutlierCollector struct {
+	ejections *prometheus.CounterVec
+	ejected   *prometheus.GaugeVec
+}
+
+// NewOutlierCollector creates a collector and registers its metrics with the registerer;
+// nil uses prometheus.DefaultRegisterer.
+func NewOutlierCollector(registerer prometheus.Registerer, opts ...ConfigOption) (*OutlierCollector, error) {
+	config := DefaultConfig()
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	if registerer == nil {
+		registerer = prometheus.DefaultRegisterer
+	}
+
+	c := &OutlierCollector{
+		ejections: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace:   config.Namespace,
+				Subsystem:   "outlier",
+				Name:        "ejections_total",
+				Help:        "Total number of endpoints ejected from load balancing",
+				ConstLabels: config.ConstLabels,
+			},
+			[]string{"endpoint", "reason"},
+		),
+		ejected: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace:   config.Namespace,
+				Subsystem:   "outlier",
+				Name:        "ejected",
+				Help:        "Whether an endpoint is ejected from load balancing (1 while ejected)",
+				ConstLabels: config.ConstLabels,
+			},
+			[]string{"endpoint"},
+		),
+	}
+
+	var err error
+	if c.ejections, err = registerCounterVec(registerer, c.ejections); err != nil {
+		return nil, err
+	}
+	if c.ejected, err = registerGaugeVec(registerer, c.ejected); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// RecordEjection records that an endpoint was ejected
+func (c *OutlierCollector) RecordEjection(endpoint, reason string) {
+	c.ejections.WithLabelValues(endpoint, reason).Inc()
+	c.ejected.WithLabelValues(endpoint).Set(1)
+}
+
+// RecordReturn records that an ejected endpoint is back in load balancing
+func (c *OutlierCollector) RecordReturn(endpoint string) {
+	c.ejected.WithLabelValues(endpoint).Set(0)
+}
@@ -0,0 +1,91 @@
+package metrics
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Priority request result label values
+const (
+	PriorityResultOK    = "ok"
+	PriorityResultShed  = "shed"
+	PriorityResultError = "error"
+)
+
+// PriorityCollector exports request outcomes per priority band, to check that sheddable
+// traffic is dropped first under pressure.
+//
+// Exported metrics (with the default "grpc" namespace):
+//
+//	grpc_priority_requests_total{priority, result}       result: ok, shed, error
+//	grpc_priority_request_duration_seconds{priority}
+type PriorityCollector struct {
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+}
+
+// NewPriorityCollector creates a collector and registers its metrics with the registerer;
+// nil uses prometheus.DefaultRegisterer. Namespace, ConstLabels and HistogramBuckets of the
+// config are used.
+func NewPriorityCollector(registerer prometheus.Registerer, opts ...ConfigOption) (*PriorityCollector, error) {
+	config := DefaultConfig()
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	if registerer == nil {
+		registerer = prometheus.DefaultRegisterer
+	}
+
+	c := &PriorityCollector{
+		requests: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace:   config.Namespace,
+				Subsystem:   "priority",
+				Name:        "requests_total",
+				Help:        "Total number of requests by priority band and result",
+				ConstLabels: config.ConstLabels,
+			},
+			[]string{"priority", "result"},
+		),
+		duration: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Namespace:   config.Namespace,
+				Subsystem:   "priority",
+				Name:        "request_duration_seconds",
+				Help:        "Duration of admitted requests by priority band",
+				Buckets:     config.HistogramBuckets,
+				ConstLabels: config.ConstLabels,
+			},
+			[]string{"priority"},
+		),
+	}
+
+	var err error
+	if c.requests, err = registerCounterVec(registerer, c.requests); err != nil {
+		return nil, err
+	}
+	if err := registerer.Register(c.duration); err != nil {
+		are, ok := err.(prometheus.AlreadyRegisteredError)
+		if !ok {
+			return nil, fmt.Errorf("failed to register metrics: %w", err)
+		}
+		existing, ok := are.ExistingCollector.(*prometheus.HistogramVec)
+		if !ok {
+			return nil, fmt.Errorf("failed to register metrics: %w", err)
+		}
+		c.duration = existing
+	}
+
+	return c, nil
+}
+
+// RecordRequest records the result of a request; shed requests have no duration
+func (c *PriorityCollector) RecordRequest(priority, result string, duration time.Duration) {
+	c.requests.WithLabelValues(priority, result).Inc()
+	if result != PriorityResultShed {
+		c.duration.WithLabelValues(priority).Observe(duration.Seconds())
+	}
+}
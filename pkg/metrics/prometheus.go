@@ -15,6 +15,7 @@ type PrometheusCollector struct {
 	requestsTotal   *prometheus.CounterVec
 	requestDuration *prometheus.HistogramVec
 	activeRequests  *prometheus.GaugeVec
+	openConnections prometheus.Gauge
 
 	// Error metrics
 	errorsTotal *prometheus.CounterVec
@@ -22,6 +23,20 @@ type PrometheusCollector struct {
 	// Message size metrics
 	messageSent     *prometheus.HistogramVec
 	messageReceived *prometheus.HistogramVec
+	wireSent        *prometheus.HistogramVec
+	wireReceived    *prometheus.HistogramVec
+
+	// Retry metrics
+	retryAttempts  *prometheus.CounterVec
+	retryExhausted *prometheus.CounterVec
+	retryRecovered *prometheus.CounterVec
+	callAttempts   *prometheus.HistogramVec
+
+	// Cache metrics
+	cacheHits      *prometheus.CounterVec
+	cacheMisses    *prometheus.CounterVec
+	cacheSets      *prometheus.CounterVec
+	cacheEvictions *prometheus.CounterVec
 }
 
 // NewPrometheusCollector creates a new Prometheus metrics collector
@@ -96,6 +111,16 @@ func (p *PrometheusCollector) initMetrics() error {
 		gaugeLabels,
 	)
 
+	p.openConnections = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace:   p.config.Namespace,
+			Subsystem:   p.config.Subsystem,
+			Name:        "open_connections",
+			Help:        "Number of open transport connections",
+			ConstLabels: p.config.ConstLabels,
+		},
+	)
+
 	// Total errors counter
 	errorLabels := []string{"method", "error_type"}
 	if !p.config.EnablePerMethodMetrics {
@@ -147,13 +172,125 @@ func (p *PrometheusCollector) initMetrics() error {
 		messageLabels,
 	)
 
+	p.wireSent = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace:   p.config.Namespace,
+			Subsystem:   p.config.Subsystem,
+			Name:        "message_sent_wire_bytes",
+			Help:        "Histogram of message sizes sent on the wire, after compression (bytes)",
+			Buckets:     sizeBuckets,
+			ConstLabels: p.config.ConstLabels,
+		},
+		messageLabels,
+	)
+
+	p.wireReceived = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace:   p.config.Namespace,
+			Subsystem:   p.config.Subsystem,
+			Name:        "message_received_wire_bytes",
+			Help:        "Histogram of message sizes received on the wire, before decompression (bytes)",
+			Buckets:     sizeBuckets,
+			ConstLabels: p.config.ConstLabels,
+		},
+		messageLabels,
+	)
+
+	// Retry metrics
+	retryLabels := []string{"method", "code"}
+	recoveredLabels := []string{"method"}
+	if !p.config.EnablePerMethodMetrics {
+		retryLabels = []string{"code"}
+		recoveredLabels = []string{}
+	}
+
+	p.retryAttempts = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace:   p.config.Namespace,
+			Subsystem:   "retry",
+			Name:        "attempts_total",
+			Help:        "Total number of attempts made by the retry middleware, by attempt result",
+			ConstLabels: p.config.ConstLabels,
+		},
+		retryLabels,
+	)
+
+	p.retryExhausted = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace:   p.config.Namespace,
+			Subsystem:   "retry",
+			Name:        "exhausted_total",
+			Help:        "Total number of calls that failed after using all retry attempts",
+			ConstLabels: p.config.ConstLabels,
+		},
+		retryLabels,
+	)
+
+	p.retryRecovered = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace:   p.config.Namespace,
+			Subsystem:   "retry",
+			Name:        "recovered_total",
+			Help:        "Total number of calls that succeeded after at least one retry",
+			ConstLabels: p.config.ConstLabels,
+		},
+		recoveredLabels,
+	)
+
+	p.callAttempts = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace:   p.config.Namespace,
+			Subsystem:   "retry",
+			Name:        "call_attempts",
+			Help:        "Histogram of the number of attempts per call",
+			Buckets:     []float64{1, 2, 3, 4, 5, 7, 10},
+			ConstLabels: p.config.ConstLabels,
+		},
+		retryLabels,
+	)
+
+	// Cache metrics
+	cacheLabels := []string{"method"}
+	if !p.config.EnablePerMethodMetrics {
+		cacheLabels = []string{}
+	}
+
+	newCacheCounter := func(name, help string) *prometheus.CounterVec {
+		return prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace:   p.config.Namespace,
+				Subsystem:   "cache",
+				Name:        name,
+				Help:        help,
+				ConstLabels: p.config.ConstLabels,
+			},
+			cacheLabels,
+		)
+	}
+
+	p.cacheHits = newCacheCounter("hits_total", "Total number of responses served from the cache")
+	p.cacheMisses = newCacheCounter("misses_total", "Total number of cache lookups that called the handler")
+	p.cacheSets = newCacheCounter("sets_total", "Total number of responses stored in the cache")
+	p.cacheEvictions = newCacheCounter("evictions_total", "Total number of cache entries evicted to store new responses")
+
 	// Register all metrics
 	p.registry.MustRegister(
 		p.requestsTotal,
 		p.activeRequests,
+		p.openConnections,
 		p.errorsTotal,
 		p.messageSent,
 		p.messageReceived,
+		p.wireSent,
+		p.wireReceived,
+		p.retryAttempts,
+		p.retryExhausted,
+		p.retryRecovered,
+		p.callAttempts,
+		p.cacheHits,
+		p.cacheMisses,
+		p.cacheSets,
+		p.cacheEvictions,
 	)
 
 	if p.config.EnableHistogram {
@@ -165,17 +302,31 @@ func (p *PrometheusCollector) initMetrics() error {
 
 // RecordRequest records a completed request
 func (p *PrometheusCollector) RecordRequest(method string, code string, duration time.Duration) {
-	if p.config.EnablePerMethodMetrics {
-		p.requestsTotal.WithLabelValues(method, code).Inc()
-		if p.config.EnableHistogram {
-			p.requestDuration.WithLabelValues(method, code).Observe(duration.Seconds())
-		}
-	} else {
-		p.requestsTotal.WithLabelValues(code).Inc()
-		if p.config.EnableHistogram {
-			p.requestDuration.WithLabelValues(code).Observe(duration.Seconds())
+	p.RecordRequestWithTraceID(method, code, duration, "")
+}
+
+// RecordRequestWithTraceID records a completed request and attaches the trace ID as an
+// exemplar on the duration histogram. Exemplars are only exposed in the OpenMetrics format
+// (promhttp.HandlerOpts{EnableOpenMetrics: true}).
+func (p *PrometheusCollector) RecordRequestWithTraceID(method string, code string, duration time.Duration, traceID string) {
+	labels := []string{method, code}
+	if !p.config.EnablePerMethodMetrics {
+		labels = []string{code}
+	}
+
+	p.requestsTotal.WithLabelValues(labels...).Inc()
+	if !p.config.EnableHistogram {
+		return
+	}
+
+	observer := p.requestDuration.WithLabelValues(labels...)
+	if traceID != "" {
+		if eo, ok := observer.(prometheus.ExemplarObserver); ok {
+			eo.ObserveWithExemplar(duration.Seconds(), prometheus.Labels{"trace_id": traceID})
+			return
 		}
 	}
+	observer.Observe(duration.Seconds())
 }
 
 // RecordError records an error occurrence
@@ -197,6 +348,11 @@ func (p *PrometheusCollector) RecordActiveRequests(method string, delta int) {
 	}
 }
 
+// RecordOpenConnections updates the open connections gauge
+func (p *PrometheusCollector) RecordOpenConnections(delta int) {
+	p.openConnections.Add(float64(delta))
+}
+
 // RecordMessageSize records message sizes
 func (p *PrometheusCollector) RecordMessageSize(method string, direction string, size int) {
 	labels := []string{method, direction}
@@ -204,13 +360,83 @@ func (p *PrometheusCollector) RecordMessageSize(method string, direction string,
 		labels = []string{direction}
 	}
 
-	if direction == "sent" {
+	if direction == DirectionSent {
 		p.messageSent.WithLabelValues(labels...).Observe(float64(size))
 	} else {
 		p.messageReceived.WithLabelValues(labels...).Observe(float64(size))
 	}
 }
 
+// RecordWireSize records message sizes on the wire
+func (p *PrometheusCollector) RecordWireSize(method string, direction string, size int) {
+	labels := []string{method, direction}
+	if !p.config.EnablePerMethodMetrics {
+		labels = []string{direction}
+	}
+
+	if direction == DirectionSent {
+		p.wireSent.WithLabelValues(labels...).Observe(float64(size))
+	} else {
+		p.wireReceived.WithLabelValues(labels...).Observe(float64(size))
+	}
+}
+
+// RecordRetryAttempt records a single attempt made by the retry middleware
+func (p *PrometheusCollector) RecordRetryAttempt(method string, code string) {
+	if p.config.EnablePerMethodMetrics {
+		p.retryAttempts.WithLabelValues(method, code).Inc()
+	} else {
+		p.retryAttempts.WithLabelValues(code).Inc()
+	}
+}
+
+// RecordRetryResult records the outcome of a call made through the retry middleware
+func (p *PrometheusCollector) RecordRetryResult(method string, code string, attempts int, exhausted bool) {
+	labels := []string{method, code}
+	recoveredLabels := []string{method}
+	if !p.config.EnablePerMethodMetrics {
+		labels = []string{code}
+		recoveredLabels = []string{}
+	}
+
+	p.callAttempts.WithLabelValues(labels...).Observe(float64(attempts))
+
+	if exhausted {
+		p.retryExhausted.WithLabelValues(labels...).Inc()
+	}
+	if attempts > 1 && code == "OK" {
+		p.retryRecovered.WithLabelValues(recoveredLabels...).Inc()
+	}
+}
+
+// RecordCacheHit records a response served from the cache
+func (p *PrometheusCollector) RecordCacheHit(method string) {
+	p.cacheHits.WithLabelValues(p.cacheLabels(method)...).Inc()
+}
+
+// RecordCacheMiss records a cache lookup that called the handler
+func (p *PrometheusCollector) RecordCacheMiss(method string) {
+	p.cacheMisses.WithLabelValues(p.cacheLabels(method)...).Inc()
+}
+
+// RecordCacheSet records a response stored in the cache
+func (p *PrometheusCollector) RecordCacheSet(method string) {
+	p.cacheSets.WithLabelValues(p.cacheLabels(method)...).Inc()
+}
+
+// RecordCacheEvictions records cache entries evicted to store a response
+func (p *PrometheusCollector) RecordCacheEvictions(method string, count int) {
+	p.cacheEvictions.WithLabelValues(p.cacheLabels(method)...).Add(float64(count))
+}
+
+// cacheLabels returns the label values for cache metrics
+func (p *PrometheusCollector) cacheLabels(method string) []string {
+	if p.config.EnablePerMethodMetrics {
+		return []string{method}
+	}
+	return nil
+}
+
 // GetRegistry returns the Prometheus registry
 func (p *PrometheusCollector) GetRegistry() *prometheus.Registry {
 	return p.registry
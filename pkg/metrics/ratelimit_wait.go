@@ -0,0 +1,94 @@
+package metrics
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Reasons recorded by RateLimitWaitCollector for calls that gave up waiting
+const (
+	WaitRejectedDeadline = "deadline" // The wait would have outlasted the caller's deadline
+	WaitRejectedCanceled = "canceled" // The caller went away while waiting
+)
+
+// DefaultWaitBuckets are the buckets of the rate limit wait histogram, in seconds
+var DefaultWaitBuckets = []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// RateLimitWaitCollector exports how long RateLimitWithWait holds calls back and how many
+// calls it rejects instead of waiting.
+//
+// Exported metrics (with the default "grpc" namespace):
+//
+//	grpc_ratelimit_wait_seconds{method}                  time waited for a token
+//	grpc_ratelimit_wait_rejected_total{method, reason}   reason: deadline, canceled
+type RateLimitWaitCollector struct {
+	waits    *prometheus.HistogramVec
+	rejected *prometheus.CounterVec
+}
+
+// NewRateLimitWaitCollector creates a collector and registers its metrics with the
+// registerer; nil uses prometheus.DefaultRegisterer.
+func NewRateLimitWaitCollector(registerer prometheus.Registerer, opts ...ConfigOption) (*RateLimitWaitCollector, error) {
+	config := DefaultConfig()
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	if registerer == nil {
+		registerer = prometheus.DefaultRegisterer
+	}
+
+	c := &RateLimitWaitCollector{
+		waits: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Namespace:   config.Namespace,
+				Subsystem:   "ratelimit",
+				Name:        "wait_seconds",
+				Help:        "Time calls waited for a rate limit token",
+				Buckets:     DefaultWaitBuckets,
+				ConstLabels: config.ConstLabels,
+			},
+			[]string{"method"},
+		),
+		rejected: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace:   config.Namespace,
+				Subsystem:   "ratelimit",
+				Name:        "wait_rejected_total",
+				Help:        "Total number of calls rejected instead of waiting for a rate limit token",
+				ConstLabels: config.ConstLabels,
+			},
+			[]string{"method", "reason"},
+		),
+	}
+
+	if err := registerer.Register(c.waits); err != nil {
+		are, ok := err.(prometheus.AlreadyRegisteredError)
+		if !ok {
+			return nil, fmt.Errorf("failed to register metrics: %w", err)
+		}
+		existing, ok := are.ExistingCollector.(*prometheus.HistogramVec)
+		if !ok {
+			return nil, fmt.Errorf("failed to register metrics: %w", err)
+		}
+		c.waits = existing
+	}
+	var err error
+	if c.rejected, err = registerCounterVec(registerer, c.rejected); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// RecordWait records the time a call waited for a token, zero if one was available
+func (c *RateLimitWaitCollector) RecordWait(method string, wait time.Duration) {
+	c.waits.WithLabelValues(method).Observe(wait.Seconds())
+}
+
+// RecordRejected records a call that did not wait for its token
+func (c *RateLimitWaitCollector) RecordRejected(method, reason string) {
+	c.rejected.WithLabelValues(method, reason).Inc()
+}
@@ -0,0 +1,115 @@
+package metrics
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Shadow comparison result label values
+const (
+	// ShadowResultMatch means primary and shadow returned the same status code
+	ShadowResultMatch = "match"
+	// ShadowResultShadowError means the shadow failed where the primary succeeded
+	ShadowResultShadowError = "shadow_error"
+	// ShadowResultPrimaryError means the shadow succeeded where the primary failed
+	ShadowResultPrimaryError = "primary_error"
+	// ShadowResultCodeMismatch means both failed, with different codes
+	ShadowResultCodeMismatch = "code_mismatch"
+)
+
+// ShadowCollector exports how mirrored (shadow) requests compare to the primary ones, to
+// validate a new implementation before it serves traffic.
+//
+// Exported metrics (with the default "grpc" namespace):
+//
+//	grpc_shadow_requests_total{method, result}    result: match, shadow_error, primary_error, code_mismatch
+//	grpc_shadow_latency_seconds{method, target}   target: primary, shadow
+//	grpc_shadow_dropped_total{reason}
+type ShadowCollector struct {
+	requests *prometheus.CounterVec
+	latency  *prometheus.HistogramVec
+	dropped  *prometheus.CounterVec
+}
+
+// NewShadowCollector creates a collector and registers its metrics with the registerer;
+// nil uses prometheus.DefaultRegisterer. Namespace, ConstLabels and HistogramBuckets of the
+// config are used.
+func NewShadowCollector(registerer prometheus.Registerer, opts ...ConfigOption) (*ShadowCollector, error) {
+	config := DefaultConfig()
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	if registerer == nil {
+		registerer = prometheus.DefaultRegisterer
+	}
+
+	c := &ShadowCollector{
+		requests: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace:   config.Namespace,
+				Subsystem:   "shadow",
+				Name:        "requests_total",
+				Help:        "Total number of shadowed requests by method and comparison result",
+				ConstLabels: config.ConstLabels,
+			},
+			[]string{"method", "result"},
+		),
+		latency: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Namespace:   config.Namespace,
+				Subsystem:   "shadow",
+				Name:        "latency_seconds",
+				Help:        "Latency of shadowed requests on the primary and the shadow target",
+				Buckets:     config.HistogramBuckets,
+				ConstLabels: config.ConstLabels,
+			},
+			[]string{"method", "target"},
+		),
+		dropped: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace:   config.Namespace,
+				Subsystem:   "shadow",
+				Name:        "dropped_total",
+				Help:        "Total number of sampled requests that were not shadowed",
+				ConstLabels: config.ConstLabels,
+			},
+			[]string{"reason"},
+		),
+	}
+
+	var err error
+	if c.requests, err = registerCounterVec(registerer, c.requests); err != nil {
+		return nil, err
+	}
+	if err := registerer.Register(c.latency); err != nil {
+		are, ok := err.(prometheus.AlreadyRegisteredError)
+		if !ok {
+			return nil, fmt.Errorf("failed to register metrics: %w", err)
+		}
+		existing, ok := are.ExistingCollector.(*prometheus.HistogramVec)
+		if !ok {
+			return nil, fmt.Errorf("failed to register metrics: %w", err)
+		}
+		c.latency = existing
+	}
+	if c.dropped, err = registerCounterVec(registerer, c.dropped); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// RecordComparison records a shadowed request and the latency on both sides
+func (c *ShadowCollector) RecordComparison(method, result string, primary, shadow time.Duration) {
+	c.requests.WithLabelValues(method, result).Inc()
+	c.latency.WithLabelValues(method, "primary").Observe(primary.Seconds())
+	c.latency.WithLabelValues(method, "shadow").Observe(shadow.Seconds())
+}
+
+// RecordDropped records a sampled request that was not shadowed
+func (c *ShadowCollector) RecordDropped(reason string) {
+	c.dropped.WithLabelValues(reason).Inc()
+}
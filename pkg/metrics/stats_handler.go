@@ -0,0 +1,87 @@
+package metrics
+
+import (
+	"context"
+
+	"google.golang.org/grpc/stats"
+)
+
+// SizeStatsHandler is a gRPC stats.Handler recording the codec-level size of every message:
+// its compressed wire size (with collectors implementing WireSizeMetricsCollector) and,
+// optionally, its uncompressed size. Unlike the metrics middleware it sees the bytes
+// actually sent, so it also covers compression and non-proto codecs.
+//
+// Install it on servers with grpc.StatsHandler or on clients with grpc.WithStatsHandler.
+// Sizes are from the perspective of the process: a client's requests are "sent".
+type SizeStatsHandler struct {
+	collector    MetricsCollector
+	uncompressed bool
+}
+
+// SizeStatsOption configures a SizeStatsHandler
+type SizeStatsOption func(*SizeStatsHandler)
+
+// WithUncompressedSizes also records uncompressed sizes through RecordMessageSize. Leave
+// it off when MetricsMiddleware already records them, or messages are counted twice.
+func WithUncompressedSizes() SizeStatsOption {
+	return func(h *SizeStatsHandler) {
+		h.uncompressed = true
+	}
+}
+
+// NewSizeStatsHandler creates a stats handler recording message sizes to the collector
+//
+// Example usage:
+//
+//	collector, _ := metrics.NewPrometheusCollector()
+//	server := grpc.NewServer(
+//	    grpc.StatsHandler(metrics.NewSizeStatsHandler(collector)),
+//	    grpc.UnaryInterceptor(middleware.MetricsMiddleware(collector)),
+//	)
+func NewSizeStatsHandler(collector MetricsCollector, opts ...SizeStatsOption) *SizeStatsHandler {
+	h := &SizeStatsHandler{collector: collector}
+
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	return h
+}
+
+// sizeStatsMethodKey carries the method name from TagRPC to HandleRPC
+type sizeStatsMethodKey struct{}
+
+// TagRPC remembers the method of the RPC
+func (h *SizeStatsHandler) TagRPC(ctx context.Context, info *stats.RPCTagInfo) context.Context {
+	return context.WithValue(ctx, sizeStatsMethodKey{}, info.FullMethodName)
+}
+
+// HandleRPC records the size of received and sent messages
+func (h *SizeStatsHandler) HandleRPC(ctx context.Context, s stats.RPCStats) {
+	method, _ := ctx.Value(sizeStatsMethodKey{}).(string)
+
+	switch s := s.(type) {
+	case *stats.InPayload:
+		h.record(method, DirectionReceived, s.Length, s.WireLength)
+	case *stats.OutPayload:
+		h.record(method, DirectionSent, s.Length, s.WireLength)
+	}
+}
+
+// record records one message
+func (h *SizeStatsHandler) record(method, direction string, length, wireLength int) {
+	if wc, ok := h.collector.(WireSizeMetricsCollector); ok {
+		wc.RecordWireSize(method, direction, wireLength)
+	}
+	if h.uncompressed {
+		h.collector.RecordMessageSize(method, direction, length)
+	}
+}
+
+// TagConn returns the context unchanged
+func (h *SizeStatsHandler) TagConn(ctx context.Context, info *stats.ConnTagInfo) context.Context {
+	return ctx
+}
+
+// HandleConn does nothing; only RPCs are measured
+func (h *SizeStatsHandler) HandleConn(ctx context.Context, s stats.ConnStats) {}
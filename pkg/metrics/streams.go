@@ -0,0 +1,78 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// StreamQuotaCollector exports the per-client stream quota: how many streams each client
+// has open and how many were rejected for exceeding the quota. Clients without open streams
+// are removed from the gauge so that short-lived clients do not accumulate series.
+//
+// Exported metrics (with the default "grpc" namespace):
+//
+//	grpc_streams_open{client}
+//	grpc_streams_rejected_total{client}
+type StreamQuotaCollector struct {
+	open     *prometheus.GaugeVec
+	rejected *prometheus.CounterVec
+}
+
+// NewStreamQuotaCollector creates a collector and registers its metrics with the registerer;
+// nil uses prometheus.DefaultRegisterer. Namespace and ConstLabels of the config are used.
+func NewStreamQuotaCollector(registerer prometheus.Registerer, opts ...ConfigOption) (*StreamQuotaCollector, error) {
+	config := DefaultConfig()
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	if registerer == nil {
+		registerer = prometheus.DefaultRegisterer
+	}
+
+	c := &StreamQuotaCollector{
+		open: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace:   config.Namespace,
+				Subsystem:   "streams",
+				Name:        "open",
+				Help:        "Number of open streams by client",
+				ConstLabels: config.ConstLabels,
+			},
+			[]string{"client"},
+		),
+		rejected: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace:   config.Namespace,
+				Subsystem:   "streams",
+				Name:        "rejected_total",
+				Help:        "Total number of streams rejected by the per-client stream quota",
+				ConstLabels: config.ConstLabels,
+			},
+			[]string{"client"},
+		),
+	}
+
+	var err error
+	if c.open, err = registerGaugeVec(registerer, c.open); err != nil {
+		return nil, err
+	}
+	if c.rejected, err = registerCounterVec(registerer, c.rejected); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// SetOpenStreams sets the number of open streams of a client; zero removes the client
+func (c *StreamQuotaCollector) SetOpenStreams(client string, open int) {
+	if open <= 0 {
+		c.open.DeleteLabelValues(client)
+		return
+	}
+	c.open.WithLabelValues(client).Set(float64(open))
+}
+
+// RecordRejected records a stream rejected for exceeding the quota
+func (c *StreamQuotaCollector) RecordRejected(client string) {
+	c.rejected.WithLabelValues(client).Inc()
+}
@@ -25,6 +25,63 @@ type MetricsCollector interface {
 	GetRegistry() *prometheus.Registry
 }
 
+// Message directions passed to RecordMessageSize and RecordWireSize
+const (
+	DirectionSent     = "sent"
+	DirectionReceived = "received"
+)
+
+// WireSizeMetricsCollector is implemented by collectors that also record message sizes as
+// sent over the wire, i.e. after compression. SizeStatsHandler uses it; comparing with
+// RecordMessageSize shows how well payloads compress.
+type WireSizeMetricsCollector interface {
+	// RecordWireSize records the compressed size of a message, including gRPC framing
+	RecordWireSize(method string, direction string, size int)
+}
+
+// ConnectionMetricsCollector is implemented by collectors that also record transport
+// connections. guardian.StatsHandler uses it on ConnBegin and ConnEnd.
+type ConnectionMetricsCollector interface {
+	// RecordOpenConnections updates the open connections gauge
+	RecordOpenConnections(delta int)
+}
+
+// ExemplarMetricsCollector is implemented by collectors that can link a request to its trace.
+// MetricsMiddleware uses it instead of RecordRequest when the request has a sampled span.
+type ExemplarMetricsCollector interface {
+	// RecordRequestWithTraceID records a completed request like RecordRequest and attaches
+	// the trace ID to the duration observation as an exemplar. An empty trace ID records
+	// the request without an exemplar.
+	RecordRequestWithTraceID(method string, code string, duration time.Duration, traceID string)
+}
+
+// RetryMetricsCollector is implemented by collectors that also record retry behaviour.
+// The Retry middleware uses it when the MetricsCollector passed to WithRetryMetrics supports it.
+type RetryMetricsCollector interface {
+	// RecordRetryAttempt records a single attempt and the status code it ended with
+	RecordRetryAttempt(method string, code string)
+
+	// RecordRetryResult records a finished call: its final status code, how many attempts
+	// it took, and whether it failed because the attempts were exhausted
+	RecordRetryResult(method string, code string, attempts int, exhausted bool)
+}
+
+// CacheMetricsCollector is implemented by collectors that also record response cache activity.
+// The Cache middleware uses it when the MetricsCollector passed to WithCacheMetrics supports it.
+type CacheMetricsCollector interface {
+	// RecordCacheHit records a response served from the cache
+	RecordCacheHit(method string)
+
+	// RecordCacheMiss records a lookup that had to call the handler
+	RecordCacheMiss(method string)
+
+	// RecordCacheSet records a response stored in the cache
+	RecordCacheSet(method string)
+
+	// RecordCacheEvictions records entries evicted to make room for a response of method
+	RecordCacheEvictions(method string, count int)
+}
+
 // Config holds configuration for metrics collection
 type Config struct {
 	// Namespace for metrics (e.g., "grpc_guardian")
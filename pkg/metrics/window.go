@@ -0,0 +1,90 @@
+package metrics
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// HistogramWindow computes quantiles of a Prometheus histogram over the interval between
+// two calls, summed across all label values, e.g. the p95 request latency of the last
+// few seconds from PrometheusCollector's request_duration_seconds histogram.
+type HistogramWindow struct {
+	gatherer prometheus.Gatherer
+	name     string
+	last     map[float64]uint64
+}
+
+// NewHistogramWindow creates a window over the histogram with the given fully qualified
+// name (e.g. "grpc_server_request_duration_seconds")
+func NewHistogramWindow(gatherer prometheus.Gatherer, name string) *HistogramWindow {
+	return &HistogramWindow{
+		gatherer: gatherer,
+		name:     name,
+		last:     make(map[float64]uint64),
+	}
+}
+
+// Quantile returns the q-quantile (0 < q < 1) of the observations made since the previous
+// call, interpolated linearly within a bucket like PromQL's histogram_quantile. It reports
+// false when there were no observations.
+func (w *HistogramWindow) Quantile(q float64) (float64, bool, error) {
+	families, err := w.gatherer.Gather()
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to gather metrics: %w", err)
+	}
+
+	current := make(map[float64]uint64)
+	for _, family := range families {
+		if family.GetName() != w.name {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			histogram := metric.GetHistogram()
+			if histogram == nil {
+				continue
+			}
+			for _, bucket := range histogram.GetBucket() {
+				current[bucket.GetUpperBound()] += bucket.GetCumulativeCount()
+			}
+			current[math.Inf(1)] += histogram.GetSampleCount()
+		}
+	}
+
+	bounds := make([]float64, 0, len(current))
+	deltas := make(map[float64]uint64, len(current))
+	for bound, count := range current {
+		bounds = append(bounds, bound)
+		if count >= w.last[bound] {
+			deltas[bound] = count - w.last[bound]
+		} else {
+			deltas[bound] = count // Counter reset
+		}
+	}
+	sort.Float64s(bounds)
+	w.last = current
+
+	if len(bounds) == 0 || deltas[math.Inf(1)] == 0 {
+		return 0, false, nil
+	}
+
+	rank := q * float64(deltas[math.Inf(1)])
+	lowerBound, lowerCount := 0.0, uint64(0)
+	for _, bound := range bounds {
+		count := deltas[bound]
+		if float64(count) >= rank {
+			if math.IsInf(bound, 1) {
+				// Above the highest bucket: the best estimate is its lower bound
+				return lowerBound, true, nil
+			}
+			if count == lowerCount {
+				return bound, true, nil
+			}
+			return lowerBound + (bound-lowerBound)*(rank-float64(lowerCount))/float64(count-lowerCount), true, nil
+		}
+		lowerBound, lowerCount = bound, count
+	}
+	return lowerBound, true, nil
+}
@@ -0,0 +1,117 @@
+package metrics
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// XDSEndpointCollector exports the EDS health of the endpoints an xDS management server
+// assigns to each cluster.
+//
+// Exported metrics (with the default "grpc" namespace):
+//
+//	grpc_xds_endpoint_health{cluster, endpoint, status}   1 for the endpoint's current status
+//	grpc_xds_endpoints{cluster, status}                   endpoints per health status
+type XDSEndpointCollector struct {
+	health    *prometheus.GaugeVec
+	endpoints *prometheus.GaugeVec
+
+	mu   sync.Mutex
+	last map[string]map[string]string // Exported status of every endpoint by cluster
+}
+
+// NewXDSEndpointCollector creates a collector and registers its metrics with the
+// registerer; nil uses prometheus.DefaultRegisterer.
+func NewXDSEndpointCollector(registerer prometheus.Registerer, opts ...ConfigOption) (*XDSEndpointCollector, error) {
+	config := DefaultConfig()
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	if registerer == nil {
+		registerer = prometheus.DefaultRegisterer
+	}
+
+	c := &XDSEndpointCollector{
+		health: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace:   config.Namespace,
+				Subsystem:   "xds",
+				Name:        "endpoint_health",
+				Help:        "EDS health status of xDS endpoints (1 for the current status)",
+				ConstLabels: config.ConstLabels,
+			},
+			[]string{"cluster", "endpoint", "status"},
+		),
+		endpoints: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace:   config.Namespace,
+				Subsystem:   "xds",
+				Name:        "endpoints",
+				Help:        "Number of xDS endpoints by cluster and EDS health status",
+				ConstLabels: config.ConstLabels,
+			},
+			[]string{"cluster", "status"},
+		),
+		last: make(map[string]map[string]string),
+	}
+
+	var err error
+	if c.health, err = registerGaugeVec(registerer, c.health); err != nil {
+		return nil, err
+	}
+	if c.endpoints, err = registerGaugeVec(registerer, c.endpoints); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// RecordEndpoints replaces the endpoints of a cluster with their EDS health statuses;
+// endpoints and statuses that are gone are removed from the exported series
+func (c *XDSEndpointCollector) RecordEndpoints(cluster string, health map[string]string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	previous := c.last[cluster]
+	for endpoint, status := range previous {
+		if health[endpoint] != status {
+			c.health.DeleteLabelValues(cluster, endpoint, status)
+		}
+	}
+
+	counts := make(map[string]float64)
+	for endpoint, status := range health {
+		c.health.WithLabelValues(cluster, endpoint, status).Set(1)
+		counts[status]++
+	}
+
+	for _, status := range statusesOf(previous) {
+		if _, ok := counts[status]; !ok {
+			c.endpoints.DeleteLabelValues(cluster, status)
+		}
+	}
+	for status, n := range counts {
+		c.endpoints.WithLabelValues(cluster, status).Set(n)
+	}
+
+	current := make(map[string]string, len(health))
+	for endpoint, status := range health {
+		current[endpoint] = status
+	}
+	c.last[cluster] = current
+}
+
+// statusesOf returns the distinct statuses of a cluster's endpoints
+func statusesOf(health map[string]string) []string {
+	seen := make(map[string]bool)
+	var statuses []string
+	for _, status := range health {
+		if !seen[status] {
+			seen[status] = true
+			statuses = append(statuses, status)
+		}
+	}
+	return statuses
+}
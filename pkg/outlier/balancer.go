@@ -0,0 +1,78 @@
+package outlier
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/balancer"
+	"google.golang.org/grpc/balancer/base"
+)
+
+// register registers the load balancing policy of d with gRPC
+func register(d *Detector) {
+	balancer.Register(base.NewBalancerBuilder(d.name, &pickerBuilder{detector: d}, base.Config{HealthCheck: true}))
+}
+
+// ServiceConfig returns a service config selecting the Detector's load balancing policy
+func (d *Detector) ServiceConfig() string {
+	return fmt.Sprintf(`{"loadBalancingConfig":[{%q:{}}]}`, d.name)
+}
+
+// DialOptions returns the options a connection needs for outlier detection: the load
+// balancing policy and the interceptors that feed the Detector. When the connection uses a
+// guardian ClientChain, add the interceptors to the chain instead and dial with
+// grpc.WithDefaultServiceConfig(d.ServiceConfig()).
+func (d *Detector) DialOptions() []grpc.DialOption {
+	return []grpc.DialOption{
+		grpc.WithDefaultServiceConfig(d.ServiceConfig()),
+		grpc.WithChainUnaryInterceptor(d.UnaryClientInterceptor()),
+		grpc.WithChainStreamInterceptor(d.StreamClientInterceptor()),
+	}
+}
+
+// pickerBuilder builds pickers over the ready endpoints of a connection
+type pickerBuilder struct {
+	detector *Detector
+}
+
+func (b *pickerBuilder) Build(info base.PickerBuildInfo) balancer.Picker {
+	if len(info.ReadySCs) == 0 {
+		return base.NewErrPicker(balancer.ErrNoSubConnAvailable)
+	}
+
+	p := &picker{detector: b.detector}
+	for subConn, subConnInfo := range info.ReadySCs {
+		p.subConns = append(p.subConns, subConn)
+		p.endpoints = append(p.endpoints, subConnInfo.Address.Addr)
+	}
+	return p
+}
+
+// picker round-robins over the endpoints that are not ejected. When every endpoint is
+// ejected it round-robins over all of them, since failing calls beat no calls.
+type picker struct {
+	detector  *Detector
+	subConns  []balancer.SubConn
+	endpoints []string
+	next      atomic.Uint32
+}
+
+func (p *picker) Pick(balancer.PickInfo) (balancer.PickResult, error) {
+	n := uint32(len(p.subConns))
+	start := p.next.Add(1)
+
+	p.detector.mu.RLock()
+	now := p.detector.now()
+	for i := uint32(0); i < n; i++ {
+		index := (start + i) % n
+		stats, ok := p.detector.endpoints[p.endpoints[index]]
+		if !ok || !stats.ejected(now) {
+			p.detector.mu.RUnlock()
+			return balancer.PickResult{SubConn: p.subConns[index]}, nil
+		}
+	}
+	p.detector.mu.RUnlock()
+
+	return balancer.PickResult{SubConn: p.subConns[start%n]}, nil
+}
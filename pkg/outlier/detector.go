@@ -0,0 +1,391 @@
+// Package outlier ejects misbehaving endpoints from client-side load balancing, like Envoy's
+// outlier detection. Guardian client interceptors record the outcome and latency of every
+// call per endpoint; a registered gRPC balancer round-robins over the endpoints that are
+// not currently ejected.
+package outlier
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/grpc-guardian/grpc-guardian/pkg/metrics"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// DefaultPolicyName is the load balancing policy name a Detector registers by default
+const DefaultPolicyName = "guardian_outlier_detection"
+
+// Detector tracks per-endpoint errors and latencies and decides which endpoints are ejected
+type Detector struct {
+	name                string
+	consecutiveFailures int
+	failureRate         float64
+	latencyFactor       float64
+	minimumRequests     int
+	interval            time.Duration
+	baseEjectionTime    time.Duration
+	maxEjectionTime     time.Duration
+	maxEjectionPercent  int
+	isFailure           func(codes.Code) bool
+	collector           *metrics.OutlierCollector
+	onEject             func(endpoint, reason string)
+	now                 func() time.Time
+
+	mu        sync.RWMutex
+	endpoints map[string]*endpointStats
+	lastSweep time.Time
+}
+
+// endpointStats are the calls of one endpoint in the current interval and its ejection state
+type endpointStats struct {
+	calls        int
+	failures     int
+	latency      time.Duration // Sum of the latencies of latencyCalls calls
+	latencyCalls int
+	consecutive  int
+	ejections    int // Ejections not yet forgiven; multiplies the ejection time
+	ejectedUntil time.Time
+}
+
+// ejection is an ejection to report once the lock is released
+type ejection struct {
+	endpoint string
+	reason   string
+}
+
+// Option configures a Detector
+type Option func(*Detector)
+
+// WithPolicyName sets the load balancing policy name the Detector registers. Every Detector
+// needs its own name.
+// Default: "guardian_outlier_detection"
+func WithPolicyName(name string) Option {
+	return func(d *Detector) {
+		d.name = name
+	}
+}
+
+// WithConsecutiveFailures ejects an endpoint after n failures in a row; 0 disables it
+// Default: 5
+func WithConsecutiveFailures(n int) Option {
+	return func(d *Detector) {
+		if n >= 0 {
+			d.consecutiveFailures = n
+		}
+	}
+}
+
+// WithFailureRateThreshold ejects an endpoint whose failure rate over an interval exceeds
+// ratio (0.0-1.0); 0 disables it
+// Default: 0.5
+func WithFailureRateThreshold(ratio float64) Option {
+	return func(d *Detector) {
+		if ratio >= 0 && ratio <= 1 {
+			d.failureRate = ratio
+		}
+	}
+}
+
+// WithLatencyFactor ejects an endpoint whose mean latency over an interval exceeds factor
+// times the median of the endpoints' means. It needs at least three endpoints with enough
+// calls; 0 disables it.
+// Default: 0 (disabled)
+func WithLatencyFactor(factor float64) Option {
+	return func(d *Detector) {
+		if factor >= 0 {
+			d.latencyFactor = factor
+		}
+	}
+}
+
+// WithMinimumRequests sets how many calls an endpoint needs in an interval before its
+// failure rate and latency are judged
+// Default: 10
+func WithMinimumRequests(n int) Option {
+	return func(d *Detector) {
+		if n > 0 {
+			d.minimumRequests = n
+		}
+	}
+}
+
+// WithInterval sets how often failure rates and latencies are evaluated and reset
+// Default: 10s
+func WithInterval(interval time.Duration) Option {
+	return func(d *Detector) {
+		if interval > 0 {
+			d.interval = interval
+		}
+	}
+}
+
+// WithEjectionTime sets how long an endpoint stays ejected. Every repeated ejection adds
+// base, up to max; an interval without ejection forgives one.
+// Default: 30s, 5m
+func WithEjectionTime(base, max time.Duration) Option {
+	return func(d *Detector) {
+		if base > 0 {
+			d.baseEjectionTime = base
+		}
+		if max >= base {
+			d.maxEjectionTime = max
+		}
+	}
+}
+
+// WithMaxEjectionPercent caps the share of known endpoints ejected at once, so a failing
+// dependency can't eject every endpoint
+// Default: 50
+func WithMaxEjectionPercent(percent int) Option {
+	return func(d *Detector) {
+		if percent >= 0 && percent <= 100 {
+			d.maxEjectionPercent = percent
+		}
+	}
+}
+
+// WithFailureCodes sets the status codes counted as endpoint failures
+// Default: Unavailable, Internal, Unknown, DataLoss, DeadlineExceeded
+func WithFailureCodes(codeList ...codes.Code) Option {
+	return func(d *Detector) {
+		failures := make(map[codes.Code]bool, len(codeList))
+		for _, code := range codeList {
+			failures[code] = true
+		}
+		d.isFailure = func(code codes.Code) bool { return failures[code] }
+	}
+}
+
+// WithOutlierCollector exports ejections to Prometheus
+func WithOutlierCollector(collector *metrics.OutlierCollector) Option {
+	return func(d *Detector) {
+		d.collector = collector
+	}
+}
+
+// WithEjectionCallback is called whenever an endpoint is ejected
+func WithEjectionCallback(fn func(endpoint, reason string)) Option {
+	return func(d *Detector) {
+		d.onEject = fn
+	}
+}
+
+// NewDetector creates a Detector and registers its load balancing policy with gRPC. Create
+// detectors during initialization, before the connections that use them are dialed.
+//
+// Example usage:
+//
+//	collector, _ := metrics.NewOutlierCollector(nil)
+//	detector := outlier.NewDetector(
+//	    outlier.WithConsecutiveFailures(5),
+//	    outlier.WithLatencyFactor(3),
+//	    outlier.WithOutlierCollector(collector),
+//	)
+//	conn, err := grpc.Dial("dns:///inventory:8080", append(detector.DialOptions(),
+//	    grpc.WithTransportCredentials(creds),
+//	)...)
+func NewDetector(opts ...Option) *Detector {
+	d := &Detector{
+		name:                DefaultPolicyName,
+		consecutiveFailures: 5,
+		failureRate:         0.5,
+		minimumRequests:     10,
+		interval:            10 * time.Second,
+		baseEjectionTime:    30 * time.Second,
+		maxEjectionTime:     5 * time.Minute,
+		maxEjectionPercent:  50,
+		isFailure:           defaultFailure,
+		now:                 time.Now,
+		endpoints:           make(map[string]*endpointStats),
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	d.lastSweep = d.now()
+
+	register(d)
+	return d
+}
+
+// defaultFailure reports whether code points at a broken endpoint rather than a bad request
+func defaultFailure(code codes.Code) bool {
+	switch code {
+	case codes.Unavailable, codes.Internal, codes.Unknown, codes.DataLoss, codes.DeadlineExceeded:
+		return true
+	}
+	return false
+}
+
+// PolicyName returns the load balancing policy name of the Detector
+func (d *Detector) PolicyName() string {
+	return d.name
+}
+
+// Record records the outcome of a call to endpoint ("host:port"); a zero latency is not
+// counted toward the endpoint's mean latency
+func (d *Detector) Record(endpoint string, latency time.Duration, err error) {
+	failed := err != nil && d.isFailure(status.Code(err))
+
+	d.mu.Lock()
+	now := d.now()
+	stats, ok := d.endpoints[endpoint]
+	if !ok {
+		stats = &endpointStats{}
+		d.endpoints[endpoint] = stats
+	}
+
+	stats.calls++
+	if failed {
+		stats.failures++
+		stats.consecutive++
+	} else {
+		stats.consecutive = 0
+	}
+	if latency > 0 {
+		stats.latency += latency
+		stats.latencyCalls++
+	}
+
+	var ejected []ejection
+	if d.consecutiveFailures > 0 && stats.consecutive >= d.consecutiveFailures && !stats.ejected(now) {
+		if d.eject(endpoint, stats, now) {
+			ejected = append(ejected, ejection{endpoint, metrics.EjectionConsecutiveFailures})
+		}
+	}
+	if now.Sub(d.lastSweep) >= d.interval {
+		ejected = append(ejected, d.sweep(now)...)
+	}
+	d.mu.Unlock()
+
+	for _, e := range ejected {
+		if d.collector != nil {
+			d.collector.RecordEjection(e.endpoint, e.reason)
+		}
+		if d.onEject != nil {
+			d.onEject(e.endpoint, e.reason)
+		}
+	}
+}
+
+// IsEjected reports whether endpoint is currently ejected
+func (d *Detector) IsEjected(endpoint string) bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	stats, ok := d.endpoints[endpoint]
+	return ok && stats.ejected(d.now())
+}
+
+// Ejected returns the currently ejected endpoints
+func (d *Detector) Ejected() []string {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	now := d.now()
+	var ejected []string
+	for endpoint, stats := range d.endpoints {
+		if stats.ejected(now) {
+			ejected = append(ejected, endpoint)
+		}
+	}
+	sort.Strings(ejected)
+	return ejected
+}
+
+// ejected reports whether the endpoint is ejected at now
+func (s *endpointStats) ejected(now time.Time) bool {
+	return now.Before(s.ejectedUntil)
+}
+
+// eject ejects an endpoint unless that would exceed the maximum ejection percent. Must be
+// called with the lock held.
+func (d *Detector) eject(endpoint string, stats *endpointStats, now time.Time) bool {
+	ejected := 0
+	for _, other := range d.endpoints {
+		if other.ejected(now) {
+			ejected++
+		}
+	}
+	if (ejected+1)*100 > d.maxEjectionPercent*len(d.endpoints) {
+		return false
+	}
+
+	stats.ejections++
+	duration := time.Duration(stats.ejections) * d.baseEjectionTime
+	if duration > d.maxEjectionTime {
+		duration = d.maxEjectionTime
+	}
+	stats.ejectedUntil = now.Add(duration)
+	stats.consecutive = 0
+	return true
+}
+
+// sweep returns endpoints whose ejection expired, judges the failure rates and latencies of
+// the interval and starts a new one. Must be called with the lock held.
+func (d *Detector) sweep(now time.Time) []ejection {
+	d.lastSweep = now
+
+	for endpoint, stats := range d.endpoints {
+		if stats.ejectedUntil.IsZero() || stats.ejected(now) {
+			continue
+		}
+		stats.ejectedUntil = time.Time{}
+		if d.collector != nil {
+			d.collector.RecordReturn(endpoint)
+		}
+	}
+
+	var ejected []ejection
+	if d.failureRate > 0 {
+		for endpoint, stats := range d.endpoints {
+			if stats.ejected(now) || stats.calls < d.minimumRequests {
+				continue
+			}
+			if float64(stats.failures)/float64(stats.calls) > d.failureRate && d.eject(endpoint, stats, now) {
+				ejected = append(ejected, ejection{endpoint, metrics.EjectionFailureRate})
+			}
+		}
+	}
+	if d.latencyFactor > 0 {
+		ejected = append(ejected, d.latencyOutliers(now)...)
+	}
+
+	for _, stats := range d.endpoints {
+		if !stats.ejected(now) && stats.ejections > 0 {
+			stats.ejections--
+		}
+		stats.calls, stats.failures = 0, 0
+		stats.latency, stats.latencyCalls = 0, 0
+	}
+	return ejected
+}
+
+// latencyOutliers ejects endpoints far slower than the median endpoint. Must be called with
+// the lock held.
+func (d *Detector) latencyOutliers(now time.Time) []ejection {
+	means := make(map[string]time.Duration)
+	var sorted []time.Duration
+	for endpoint, stats := range d.endpoints {
+		if stats.ejected(now) || stats.latencyCalls < d.minimumRequests {
+			continue
+		}
+		mean := stats.latency / time.Duration(stats.latencyCalls)
+		means[endpoint] = mean
+		sorted = append(sorted, mean)
+	}
+	if len(sorted) < 3 {
+		return nil
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	limit := time.Duration(float64(sorted[len(sorted)/2]) * d.latencyFactor)
+
+	var ejected []ejection
+	for endpoint, mean := range means {
+		if mean > limit && d.eject(endpoint, d.endpoints[endpoint], now) {
+			ejected = append(ejected, ejection{endpoint, metrics.EjectionLatency})
+		}
+	}
+	return ejected
+}
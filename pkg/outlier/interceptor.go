@@ -0,0 +1,66 @@
+package outlier
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/peer"
+)
+
+// UnaryClientInterceptor records the outcome and latency of every call against the endpoint
+// that served it
+func (d *Detector) UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		var p peer.Peer
+		start := time.Now()
+		err := invoker(ctx, method, req, reply, cc, append(opts, grpc.Peer(&p))...)
+		if p.Addr != nil {
+			d.Record(p.Addr.String(), time.Since(start), err)
+		}
+		return err
+	}
+}
+
+// StreamClientInterceptor records the outcome of every stream against the endpoint that
+// served it. Stream durations say nothing about endpoint latency, so they are not counted.
+func (d *Detector) StreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		p := &peer.Peer{}
+		stream, err := streamer(ctx, desc, cc, method, append(opts, grpc.Peer(p))...)
+		if err != nil {
+			if p.Addr != nil {
+				d.Record(p.Addr.String(), 0, err)
+			}
+			return nil, err
+		}
+		return &outlierClientStream{ClientStream: stream, detector: d, peer: p}, nil
+	}
+}
+
+// outlierClientStream records the stream's outcome when it ends
+type outlierClientStream struct {
+	grpc.ClientStream
+	detector *Detector
+	peer     *peer.Peer
+	once     sync.Once
+}
+
+func (s *outlierClientStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err != nil {
+		s.once.Do(func() {
+			if s.peer.Addr == nil {
+				return
+			}
+			if err == io.EOF {
+				s.detector.Record(s.peer.Addr.String(), 0, nil)
+			} else {
+				s.detector.Record(s.peer.Addr.String(), 0, err)
+			}
+		})
+	}
+	return err
+}
@@ -0,0 +1,274 @@
+package outlier
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/grpc-guardian/grpc-guardian/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/resolver"
+	"google.golang.org/grpc/resolver/manual"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// fakeClock is a settable clock for detectors
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	c.mu.Unlock()
+}
+
+func newTestDetector(t *testing.T, clock *fakeClock, opts ...Option) *Detector {
+	t.Helper()
+	opts = append([]Option{WithPolicyName("test_" + strings.ReplaceAll(t.Name(), "/", "_"))}, opts...)
+	d := NewDetector(opts...)
+	d.now = clock.Now
+	d.lastSweep = clock.Now()
+	return d
+}
+
+var errUnavailable = status.Error(codes.Unavailable, "down")
+
+func TestDetector_ConsecutiveFailures(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	var ejections []string
+	d := newTestDetector(t, clock,
+		WithConsecutiveFailures(3),
+		WithEjectionTime(time.Second, 10*time.Second),
+		WithEjectionCallback(func(endpoint, reason string) { ejections = append(ejections, endpoint+" "+reason) }),
+	)
+
+	d.Record("10.0.0.1:80", time.Millisecond, nil)
+	d.Record("10.0.0.2:80", time.Millisecond, nil)
+	for i := 0; i < 2; i++ {
+		d.Record("10.0.0.1:80", time.Millisecond, errUnavailable)
+	}
+	// Any answer that is not an endpoint failure breaks the streak
+	d.Record("10.0.0.1:80", time.Millisecond, status.Error(codes.InvalidArgument, "bad"))
+	for i := 0; i < 2; i++ {
+		d.Record("10.0.0.1:80", time.Millisecond, errUnavailable)
+	}
+	if d.IsEjected("10.0.0.1:80") {
+		t.Fatal("Expected no ejection before three failures in a row")
+	}
+	d.Record("10.0.0.1:80", time.Millisecond, errUnavailable)
+
+	if !d.IsEjected("10.0.0.1:80") {
+		t.Fatal("Expected the endpoint to be ejected after three failures in a row")
+	}
+	if want := []string{"10.0.0.1:80 consecutive_failures"}; !reflect.DeepEqual(ejections, want) {
+		t.Errorf("Expected %v, got %v", want, ejections)
+	}
+
+	clock.Advance(time.Second)
+	if d.IsEjected("10.0.0.1:80") {
+		t.Error("Expected the endpoint to return after the ejection time")
+	}
+}
+
+func TestDetector_FailureRate(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	d := newTestDetector(t, clock,
+		WithConsecutiveFailures(0),
+		WithFailureRateThreshold(0.3),
+		WithMinimumRequests(10),
+		WithInterval(10*time.Second),
+	)
+
+	for i := 0; i < 10; i++ {
+		var err error
+		if i%2 == 0 {
+			err = errUnavailable
+		}
+		d.Record("10.0.0.1:80", time.Millisecond, err)
+		d.Record("10.0.0.2:80", time.Millisecond, nil)
+	}
+	if d.IsEjected("10.0.0.1:80") {
+		t.Fatal("Expected failure rates to be judged at the end of the interval")
+	}
+
+	clock.Advance(10 * time.Second)
+	d.Record("10.0.0.2:80", time.Millisecond, nil)
+
+	if got := d.Ejected(); !reflect.DeepEqual(got, []string{"10.0.0.1:80"}) {
+		t.Errorf("Expected only the failing endpoint to be ejected, got %v", got)
+	}
+}
+
+func TestDetector_Latency(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	d := newTestDetector(t, clock,
+		WithLatencyFactor(3),
+		WithMinimumRequests(5),
+		WithInterval(time.Second),
+	)
+
+	for i := 0; i < 5; i++ {
+		d.Record("10.0.0.1:80", 10*time.Millisecond, nil)
+		d.Record("10.0.0.2:80", 12*time.Millisecond, nil)
+		d.Record("10.0.0.3:80", 11*time.Millisecond, nil)
+		d.Record("10.0.0.4:80", 200*time.Millisecond, nil)
+	}
+	clock.Advance(time.Second)
+	d.Record("10.0.0.1:80", 10*time.Millisecond, nil)
+
+	if got := d.Ejected(); !reflect.DeepEqual(got, []string{"10.0.0.4:80"}) {
+		t.Errorf("Expected the slow endpoint to be ejected, got %v", got)
+	}
+}
+
+func TestDetector_MaxEjectionPercent(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	d := newTestDetector(t, clock, WithConsecutiveFailures(1), WithMaxEjectionPercent(50))
+
+	for _, endpoint := range []string{"10.0.0.1:80", "10.0.0.2:80", "10.0.0.3:80", "10.0.0.4:80"} {
+		d.Record(endpoint, time.Millisecond, nil)
+	}
+	for _, endpoint := range []string{"10.0.0.1:80", "10.0.0.2:80", "10.0.0.3:80", "10.0.0.4:80"} {
+		d.Record(endpoint, time.Millisecond, errUnavailable)
+	}
+
+	if got := d.Ejected(); len(got) != 2 {
+		t.Errorf("Expected at most half of the endpoints to be ejected, got %v", got)
+	}
+}
+
+func TestDetector_EjectionBackoff(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	d := newTestDetector(t, clock,
+		WithConsecutiveFailures(1),
+		WithEjectionTime(time.Second, 10*time.Second),
+		WithInterval(time.Hour),
+	)
+	d.Record("10.0.0.2:80", time.Millisecond, nil)
+
+	d.Record("10.0.0.1:80", time.Millisecond, errUnavailable)
+	clock.Advance(time.Second)
+	d.Record("10.0.0.1:80", time.Millisecond, errUnavailable)
+
+	// The second ejection lasts twice as long
+	clock.Advance(1500 * time.Millisecond)
+	if !d.IsEjected("10.0.0.1:80") {
+		t.Error("Expected a repeated ejection to last longer")
+	}
+	clock.Advance(500 * time.Millisecond)
+	if d.IsEjected("10.0.0.1:80") {
+		t.Error("Expected the endpoint to return after twice the base ejection time")
+	}
+}
+
+func TestBalancer_AvoidsEjectedEndpoint(t *testing.T) {
+	var mu sync.Mutex
+	served := make(map[string]int)
+
+	var addresses []resolver.Address
+	var failing string
+	for i := 0; i < 3; i++ {
+		lis, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		address := lis.Addr().String()
+		if i == 0 {
+			failing = address
+		}
+
+		server := grpc.NewServer()
+		server.RegisterService(&grpc.ServiceDesc{
+			ServiceName: "test.Echo",
+			HandlerType: (*interface{})(nil),
+			Methods: []grpc.MethodDesc{{
+				MethodName: "Echo",
+				Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, _ grpc.UnaryServerInterceptor) (interface{}, error) {
+					mu.Lock()
+					served[address]++
+					mu.Unlock()
+					if address == failing {
+						return nil, errUnavailable
+					}
+					return wrapperspb.String(address), nil
+				},
+			}},
+		}, struct{}{})
+		go server.Serve(lis)
+		defer server.Stop()
+
+		addresses = append(addresses, resolver.Address{Addr: address})
+	}
+
+	registry := prometheus.NewRegistry()
+	collector, err := metrics.NewOutlierCollector(registry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	detector := NewDetector(
+		WithPolicyName("test_balancer_avoids_ejected"),
+		WithConsecutiveFailures(3),
+		WithOutlierCollector(collector),
+	)
+
+	r := manual.NewBuilderWithScheme("outliertest")
+	r.InitialState(resolver.State{Addresses: addresses})
+	conn, err := grpc.Dial(r.Scheme()+":///echo", append(detector.DialOptions(),
+		grpc.WithResolvers(r),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for i := 0; i < 30; i++ {
+		_ = conn.Invoke(ctx, "/test.Echo/Echo", wrapperspb.String("hi"), &wrapperspb.StringValue{}, grpc.WaitForReady(true))
+	}
+	if !detector.IsEjected(failing) {
+		t.Fatalf("Expected %s to be ejected, ejected: %v", failing, detector.Ejected())
+	}
+
+	mu.Lock()
+	before := served[failing]
+	mu.Unlock()
+	for i := 0; i < 30; i++ {
+		if err := conn.Invoke(ctx, "/test.Echo/Echo", wrapperspb.String("hi"), &wrapperspb.StringValue{}); err != nil {
+			t.Fatalf("Expected calls to avoid the ejected endpoint, got %v", err)
+		}
+	}
+	mu.Lock()
+	after := served[failing]
+	mu.Unlock()
+	if after != before {
+		t.Errorf("Expected no calls to the ejected endpoint, got %d", after-before)
+	}
+
+	expected := `
+# HELP grpc_outlier_ejections_total Total number of endpoints ejected from load balancing
+# TYPE grpc_outlier_ejections_total counter
+grpc_outlier_ejections_total{endpoint="` + failing + `",reason="consecutive_failures"} 1
+`
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(expected), "grpc_outlier_ejections_total"); err != nil {
+		t.Error(err)
+	}
+}
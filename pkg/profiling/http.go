@@ -0,0 +1,33 @@
+package profiling
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Handler returns an HTTP JSON handler listing the slowest requests:
+//
+//	GET /                   {"<method>": [samples...], ...}
+//	GET /?method=<method>   {"<method>": [samples...]}
+//
+// Samples are sorted slowest first. Mount it on an internal listener only, since samples
+// carry peer addresses.
+func Handler(recorder *Recorder) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var out map[string][]Sample
+		if method := r.URL.Query().Get("method"); method != "" {
+			out = map[string][]Sample{method: recorder.Top(method)}
+		} else {
+			out = recorder.Snapshot()
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(out)
+	})
+}
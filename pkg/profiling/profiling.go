@@ -0,0 +1,233 @@
+// Package profiling captures tail latency outliers: the slowest requests of each method
+// over a sliding window, with their status, trace ID and peer. On-call engineers can see
+// the current worst offenders without a tracing backend.
+//
+// Recording is lock-free, so the recorder can sit on the hot path of every request.
+//
+// The package also pushes CPU and heap profiles to continuous profiling backends (see
+// Pusher); together with middleware.PprofLabels they can be sliced by RPC.
+package profiling
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Sample is a recorded request
+type Sample struct {
+	Method   string        `json:"method"`
+	Duration time.Duration `json:"duration_ns"`
+	Code     string        `json:"code"`
+	TraceID  string        `json:"trace_id,omitempty"`
+	Peer     string        `json:"peer,omitempty"`
+	Time     time.Time     `json:"time"` // When the request finished
+}
+
+// buckets is the number of sub-windows the window is split into. The recorder keeps the
+// slowest N samples of each one, plus the sub-window being filled.
+const buckets = 6
+
+// Recorder keeps the slowest requests per method over a sliding window
+type Recorder struct {
+	topN      int
+	window    time.Duration
+	width     time.Duration // Sub-window width
+	threshold time.Duration
+	now       func() time.Time
+
+	methods sync.Map // method -> *methodBuffer
+}
+
+// Option configures a Recorder
+type Option func(*Recorder)
+
+// WithTopN sets how many of the slowest requests are kept per method
+// Default: 10
+func WithTopN(n int) Option {
+	return func(r *Recorder) {
+		if n > 0 {
+			r.topN = n
+		}
+	}
+}
+
+// WithWindow sets the sliding window the slowest requests are taken from
+// Default: 5m
+func WithWindow(window time.Duration) Option {
+	return func(r *Recorder) {
+		if window > 0 {
+			r.window = window
+		}
+	}
+}
+
+// WithThreshold ignores requests faster than the threshold, keeping the fast path cheap
+// Default: 0 (every request is a candidate)
+func WithThreshold(threshold time.Duration) Option {
+	return func(r *Recorder) {
+		r.threshold = threshold
+	}
+}
+
+// WithClock sets the time source, for tests
+func WithClock(now func() time.Time) Option {
+	return func(r *Recorder) {
+		if now != nil {
+			r.now = now
+		}
+	}
+}
+
+// NewRecorder creates a recorder. Feed it with middleware.Outliers and read it with Top,
+// Snapshot, Handler or the admin endpoint.
+//
+// Example usage:
+//
+//	recorder := profiling.NewRecorder(profiling.WithTopN(20), profiling.WithWindow(10*time.Minute))
+//	chain := guardian.NewChain(middleware.Outliers(recorder), middleware.Logging())
+//
+//	adminServer.AddOutliers("api", recorder)
+//	http.Handle("/outliers", profiling.Handler(recorder))
+func NewRecorder(opts ...Option) *Recorder {
+	r := &Recorder{
+		topN:   10,
+		window: 5 * time.Minute,
+		now:    time.Now,
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+	r.width = r.window / (buckets - 1)
+	if r.width <= 0 {
+		r.width = 1
+	}
+
+	return r
+}
+
+// Record adds a finished request. Samples without a time are stamped with the current time.
+func (r *Recorder) Record(sample Sample) {
+	if sample.Duration < r.threshold {
+		return
+	}
+	if sample.Time.IsZero() {
+		sample.Time = r.now()
+	}
+
+	buffer, ok := r.methods.Load(sample.Method)
+	if !ok {
+		buffer, _ = r.methods.LoadOrStore(sample.Method, newMethodBuffer(r.topN))
+	}
+	buffer.(*methodBuffer).add(&sample, r.epoch(sample.Time))
+}
+
+// Top returns the slowest requests of a method within the window, slowest first
+func (r *Recorder) Top(method string) []Sample {
+	buffer, ok := r.methods.Load(method)
+	if !ok {
+		return nil
+	}
+	return r.top(buffer.(*methodBuffer))
+}
+
+// Snapshot returns the slowest requests of every method with samples in the window
+func (r *Recorder) Snapshot() map[string][]Sample {
+	snapshot := make(map[string][]Sample)
+	r.methods.Range(func(method, buffer interface{}) bool {
+		if samples := r.top(buffer.(*methodBuffer)); len(samples) > 0 {
+			snapshot[method.(string)] = samples
+		}
+		return true
+	})
+	return snapshot
+}
+
+// Reset drops every sample
+func (r *Recorder) Reset() {
+	r.methods.Range(func(method, _ interface{}) bool {
+		r.methods.Delete(method)
+		return true
+	})
+}
+
+// top merges the sub-windows of a method
+func (r *Recorder) top(buffer *methodBuffer) []Sample {
+	now := r.now()
+	since := now.Add(-r.window)
+
+	var samples []Sample
+	for i := range buffer.buckets {
+		for j := range buffer.buckets[i] {
+			if s := buffer.buckets[i][j].Load(); s != nil && !s.Time.Before(since) && !s.Time.After(now) {
+				samples = append(samples, s.Sample)
+			}
+		}
+	}
+
+	sort.Slice(samples, func(i, j int) bool { return samples[i].Duration > samples[j].Duration })
+	if len(samples) > r.topN {
+		samples = samples[:r.topN]
+	}
+	return samples
+}
+
+// epoch numbers the sub-window a time falls in
+func (r *Recorder) epoch(t time.Time) int64 {
+	return t.UnixNano() / int64(r.width)
+}
+
+// methodBuffer holds the slowest samples of each sub-window of a method
+type methodBuffer struct {
+	buckets [buckets][]atomic.Pointer[entry]
+}
+
+// entry is a sample tagged with its sub-window
+type entry struct {
+	Sample
+	epoch int64
+}
+
+func newMethodBuffer(n int) *methodBuffer {
+	b := &methodBuffer{}
+	for i := range b.buckets {
+		b.buckets[i] = make([]atomic.Pointer[entry], n)
+	}
+	return b
+}
+
+// add replaces the fastest sample of the sub-window, or a slot left over from an older
+// sub-window, if the new sample is slower. Slots are swapped with compare-and-swap; a
+// lost race is retried a few times and then the sample is dropped, which only matters
+// under heavy contention between equally slow requests.
+func (b *methodBuffer) add(sample *Sample, epoch int64) {
+	slots := b.buckets[int(epoch%buckets)]
+	e := &entry{Sample: *sample, epoch: epoch}
+
+	for attempt := 0; attempt < 4; attempt++ {
+		var victim *atomic.Pointer[entry]
+		var current *entry
+		for i := range slots {
+			s := slots[i].Load()
+			if s == nil || s.epoch < epoch {
+				victim, current = &slots[i], s
+				break
+			}
+			if s.epoch == epoch && (victim == nil || s.Duration < current.Duration) {
+				victim, current = &slots[i], s
+			}
+		}
+
+		if victim == nil {
+			return // Every slot holds a newer sub-window
+		}
+		if current != nil && current.epoch == epoch && current.Duration >= e.Duration {
+			return // Not among the slowest of its sub-window
+		}
+		if victim.CompareAndSwap(current, e) {
+			return
+		}
+	}
+}
@@ -0,0 +1,252 @@
+package profiling
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"runtime/pprof"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Profile types collected by a Pusher
+const (
+	ProfileCPU  = "cpu"
+	ProfileHeap = "heap"
+)
+
+// Profile is a collected pprof profile. CPU samples carry the labels set by
+// middleware.PprofLabels, so a backend can slice them by RPC.
+type Profile struct {
+	Type   string            // ProfileCPU or ProfileHeap
+	Data   []byte            // gzipped pprof protobuf
+	Start  time.Time         // Start of the collection period
+	End    time.Time         // End of the collection period
+	Labels map[string]string // Static labels, e.g. service and version
+}
+
+// Sink receives collected profiles, e.g. a continuous profiling backend
+type Sink interface {
+	Push(ctx context.Context, profile Profile) error
+}
+
+// SinkFunc adapts a function to a Sink
+type SinkFunc func(ctx context.Context, profile Profile) error
+
+// Push calls f
+func (f SinkFunc) Push(ctx context.Context, profile Profile) error {
+	return f(ctx, profile)
+}
+
+// Pusher periodically collects CPU and heap profiles and pushes them to a Sink, for
+// backends that ingest pushed profiles (Pyroscope) rather than scraping /debug/pprof
+// (Parca scrapes; it sees the same labels).
+type Pusher struct {
+	sink     Sink
+	interval time.Duration
+	types    []string
+	labels   map[string]string
+	onError  func(error)
+}
+
+// PushOption configures a Pusher
+type PushOption func(*Pusher)
+
+// WithPushInterval sets the collection period; the CPU profiler runs for the whole period
+// Default: 10s
+func WithPushInterval(interval time.Duration) PushOption {
+	return func(p *Pusher) {
+		if interval > 0 {
+			p.interval = interval
+		}
+	}
+}
+
+// WithProfileTypes sets the collected profiles
+// Default: ProfileCPU, ProfileHeap
+func WithProfileTypes(types ...string) PushOption {
+	return func(p *Pusher) {
+		p.types = types
+	}
+}
+
+// WithPushLabels sets static labels sent with every profile
+func WithPushLabels(labels map[string]string) PushOption {
+	return func(p *Pusher) {
+		p.labels = labels
+	}
+}
+
+// WithPushErrorHandler is called when a profile can't be collected or pushed
+// Default: errors are ignored
+func WithPushErrorHandler(onError func(error)) PushOption {
+	return func(p *Pusher) {
+		p.onError = onError
+	}
+}
+
+// NewPusher creates a profile pusher. Only one CPU profile can run per process, so don't
+// combine it with another continuous CPU profiler.
+//
+// Example usage:
+//
+//	pusher := profiling.NewPusher(
+//	    profiling.PyroscopeSink("http://pyroscope:4040", "orders", nil),
+//	    profiling.WithPushLabels(map[string]string{"version": version}),
+//	)
+//	go pusher.Run(ctx)
+func NewPusher(sink Sink, opts ...PushOption) *Pusher {
+	p := &Pusher{
+		sink:     sink,
+		interval: 10 * time.Second,
+		types:    []string{ProfileCPU, ProfileHeap},
+	}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	return p
+}
+
+// Run collects and pushes profiles until ctx is done
+func (p *Pusher) Run(ctx context.Context) error {
+	for {
+		start := time.Now()
+		profiles, err := p.collect(ctx)
+		if err != nil {
+			p.fail(err)
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		for _, profile := range profiles {
+			profile.Start, profile.End, profile.Labels = start, time.Now(), p.labels
+			if err := p.sink.Push(ctx, profile); err != nil {
+				p.fail(fmt.Errorf("failed to push %s profile: %w", profile.Type, err))
+			}
+		}
+
+		// Without CPU profiling, collect wasn't blocking for the interval
+		if wait := p.interval - time.Since(start); wait > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(wait):
+			}
+		}
+	}
+}
+
+// collect runs the profilers for one interval
+func (p *Pusher) collect(ctx context.Context) ([]Profile, error) {
+	var profiles []Profile
+	var errs []string
+
+	for _, typ := range p.types {
+		var buf bytes.Buffer
+		switch typ {
+		case ProfileCPU:
+			if err := pprof.StartCPUProfile(&buf); err != nil {
+				errs = append(errs, fmt.Sprintf("failed to start CPU profile: %v", err))
+				continue
+			}
+			select {
+			case <-ctx.Done():
+			case <-time.After(p.interval):
+			}
+			pprof.StopCPUProfile()
+		case ProfileHeap:
+			if err := pprof.Lookup("heap").WriteTo(&buf, 0); err != nil {
+				errs = append(errs, fmt.Sprintf("failed to write heap profile: %v", err))
+				continue
+			}
+		default:
+			errs = append(errs, fmt.Sprintf("unknown profile type %q", typ))
+			continue
+		}
+		profiles = append(profiles, Profile{Type: typ, Data: buf.Bytes()})
+	}
+
+	if len(errs) > 0 {
+		return profiles, fmt.Errorf("profiling: %s", strings.Join(errs, "; "))
+	}
+	return profiles, nil
+}
+
+func (p *Pusher) fail(err error) {
+	if p.onError != nil {
+		p.onError(err)
+	}
+}
+
+// PyroscopeSink pushes profiles to the ingest API of a Pyroscope server, as
+// <app>.<type>{labels}. A nil client uses http.DefaultClient.
+func PyroscopeSink(serverURL, app string, client *http.Client) Sink {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	endpoint := strings.TrimSuffix(serverURL, "/") + "/ingest"
+
+	return SinkFunc(func(ctx context.Context, profile Profile) error {
+		var body bytes.Buffer
+		form := multipart.NewWriter(&body)
+		part, err := form.CreateFormFile("profile", "profile.pprof")
+		if err != nil {
+			return err
+		}
+		if _, err := part.Write(profile.Data); err != nil {
+			return err
+		}
+		if err := form.Close(); err != nil {
+			return err
+		}
+
+		query := url.Values{}
+		query.Set("name", pyroscopeName(app+"."+profile.Type, profile.Labels))
+		query.Set("from", strconv.FormatInt(profile.Start.Unix(), 10))
+		query.Set("until", strconv.FormatInt(profile.End.Unix(), 10))
+		query.Set("format", "pprof")
+		query.Set("spyName", "gospy")
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint+"?"+query.Encode(), &body)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", form.FormDataContentType())
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode >= 300 {
+			return fmt.Errorf("pyroscope returned %s", resp.Status)
+		}
+		return nil
+	})
+}
+
+// pyroscopeName formats an application name with labels: app.cpu{k1=v1,k2=v2}
+func pyroscopeName(name string, labels map[string]string) string {
+	if len(labels) == 0 {
+		return name
+	}
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, len(keys))
+	for i, key := range keys {
+		pairs[i] = key + "=" + labels[key]
+	}
+	return name + "{" + strings.Join(pairs, ",") + "}"
+}
@@ -0,0 +1,54 @@
+package profiling
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPusher(t *testing.T) {
+	var mu sync.Mutex
+	var names []string
+	var sizes []int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		file, _, err := r.FormFile("profile")
+		if err != nil {
+			t.Errorf("Expected a multipart profile: %v", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		data, _ := io.ReadAll(file)
+
+		mu.Lock()
+		names = append(names, r.URL.Query().Get("name"))
+		sizes = append(sizes, len(data))
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	pusher := NewPusher(PyroscopeSink(server.URL, "orders", nil),
+		WithProfileTypes(ProfileHeap),
+		WithPushInterval(20*time.Millisecond),
+		WithPushLabels(map[string]string{"version": "1.2.0", "env": "prod"}),
+		WithPushErrorHandler(func(err error) { t.Errorf("Unexpected push error: %v", err) }),
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := pusher.Run(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Expected Run to stop with the context, got %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(names) < 2 {
+		t.Fatalf("Expected a profile per interval, got %d", len(names))
+	}
+	if names[0] != "orders.heap{env=prod,version=1.2.0}" || sizes[0] == 0 {
+		t.Errorf("Unexpected pushed profile %q (%d bytes)", names[0], sizes[0])
+	}
+}
@@ -0,0 +1,64 @@
+package quota
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// Handler returns an HTTP handler to query and reset usage, for support tools and billing
+// dashboards. Mount it on an internal listener only:
+//
+//	GET  /usage?subject=key:k1                  current usage of a subject
+//	POST /reset?subject=key:k1[&period=day]     reset one period, or all of them
+//
+// Example usage:
+//
+//	mux.Handle("/quota/", http.StripPrefix("/quota", quota.Handler(manager)))
+func Handler(manager *Manager) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		subject := r.URL.Query().Get("subject")
+		if subject == "" {
+			http.Error(w, "missing subject parameter", http.StatusBadRequest)
+			return
+		}
+
+		switch strings.Trim(r.URL.Path, "/") {
+		case "usage":
+			if r.Method != http.MethodGet {
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			usage, err := manager.Usage(r.Context(), subject)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"subject": subject,
+				"usage":   usage,
+			})
+		case "reset":
+			if r.Method != http.MethodPost {
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			var periods []Period
+			if period := Period(r.URL.Query().Get("period")); period != "" {
+				if period != Day && period != Month {
+					http.Error(w, "period must be day or month", http.StatusBadRequest)
+					return
+				}
+				periods = append(periods, period)
+			}
+			if err := manager.Reset(r.Context(), subject, periods...); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+}
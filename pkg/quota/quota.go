@@ -0,0 +1,291 @@
+// Package quota enforces long-term usage quotas, such as requests per day or per month, for
+// users and API keys. Usage counters live in a pluggable Store so that every replica
+// shares them: MemoryStore for single instances and tests, RedisStore and SQLStore for
+// shared deployments.
+package quota
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrExceeded is returned by Consume when a quota is used up
+var ErrExceeded = errors.New("quota exceeded")
+
+// Period is the window a quota is counted over. Windows follow the calendar of the
+// manager's location: days start at midnight, months on the first.
+type Period string
+
+const (
+	// Day resets at midnight
+	Day Period = "day"
+	// Month resets at midnight on the first day of the month
+	Month Period = "month"
+)
+
+// Limit is the number of requests allowed per period
+type Limit struct {
+	Period   Period `json:"period"`
+	Requests int64  `json:"requests"`
+}
+
+// Daily allows n requests per day
+func Daily(n int64) Limit {
+	return Limit{Period: Day, Requests: n}
+}
+
+// Monthly allows n requests per month
+func Monthly(n int64) Limit {
+	return Limit{Period: Month, Requests: n}
+}
+
+// Usage is the state of one quota of a subject
+type Usage struct {
+	Period    Period    `json:"period"`
+	Limit     int64     `json:"limit"`
+	Used      int64     `json:"used"`
+	Remaining int64     `json:"remaining"`
+	ResetAt   time.Time `json:"reset_at"`
+}
+
+// Exceeded reports whether the quota is used up
+func (u Usage) Exceeded() bool {
+	return u.Used >= u.Limit
+}
+
+// Manager counts requests against the quotas of subjects (users, API keys, tenants, ...)
+type Manager struct {
+	store      Store
+	limits     []Limit
+	limitsFunc func(ctx context.Context, subject string) []Limit
+	location   *time.Location
+	now        func() time.Time
+}
+
+// ManagerOption configures a Manager
+type ManagerOption func(*Manager)
+
+// WithLimits sets the quotas of every subject without limits of its own. Each period may
+// appear once.
+func WithLimits(limits ...Limit) ManagerOption {
+	return func(m *Manager) {
+		m.limits = append(m.limits, limits...)
+	}
+}
+
+// WithSubjectLimits looks up the quotas of a subject, e.g. from its plan. Returning nil
+// applies the WithLimits defaults; returning an empty, non-nil slice means unlimited.
+// Invalid limits fail the request with an error.
+func WithSubjectLimits(limits func(ctx context.Context, subject string) []Limit) ManagerOption {
+	return func(m *Manager) {
+		m.limitsFunc = limits
+	}
+}
+
+// WithLocation sets the time zone that periods start in
+// Default: time.UTC
+func WithLocation(location *time.Location) ManagerOption {
+	return func(m *Manager) {
+		if location != nil {
+			m.location = location
+		}
+	}
+}
+
+// WithClock sets the time source periods are computed from, e.g. a fake clock in tests.
+// Stores still expire counters by the wall clock.
+// Default: time.Now
+func WithClock(now func() time.Time) ManagerOption {
+	return func(m *Manager) {
+		if now != nil {
+			m.now = now
+		}
+	}
+}
+
+// NewManager creates a quota manager on top of a Store. It returns an error if a limit has
+// an unknown period, a negative number of requests, or shares its period with another.
+//
+// Example usage:
+//
+//	manager, err := quota.NewManager(quota.NewRedisStore(client, "quota"),
+//	    quota.WithLimits(quota.Daily(10_000), quota.Monthly(200_000)),
+//	    quota.WithSubjectLimits(func(ctx context.Context, subject string) []quota.Limit {
+//	        if plans.IsEnterprise(subject) {
+//	            return []quota.Limit{quota.Monthly(10_000_000)}
+//	        }
+//	        return nil // Defaults
+//	    }),
+//	)
+func NewManager(store Store, opts ...ManagerOption) (*Manager, error) {
+	m := &Manager{
+		store:    store,
+		location: time.UTC,
+		now:      time.Now,
+	}
+
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	if err := validateLimits(m.limits); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// validateLimits checks that every limit has a known period, used by no other limit
+func validateLimits(limits []Limit) error {
+	seen := make(map[Period]bool, len(limits))
+	for _, limit := range limits {
+		if limit.Period != Day && limit.Period != Month {
+			return fmt.Errorf("unknown quota period %q", limit.Period)
+		}
+		if limit.Requests < 0 {
+			return fmt.Errorf("invalid %s quota of %d requests", limit.Period, limit.Requests)
+		}
+		if seen[limit.Period] {
+			return fmt.Errorf("duplicate %s quota", limit.Period)
+		}
+		seen[limit.Period] = true
+	}
+	return nil
+}
+
+// Consume counts one request of the subject against all of its quotas. When any quota is
+// used up, nothing is counted and ErrExceeded is returned along with the usage.
+func (m *Manager) Consume(ctx context.Context, subject string) ([]Usage, error) {
+	limits, err := m.limitsFor(ctx, subject)
+	if err != nil {
+		return nil, err
+	}
+	now := m.now()
+	usage := make([]Usage, 0, len(limits))
+	counted := make([]countedWindow, 0, len(limits))
+	exceeded := false
+
+	for _, limit := range limits {
+		key, resetAt := m.window(subject, limit.Period, now)
+		used, err := m.store.Increment(ctx, key, 1, resetAt)
+		if err != nil {
+			m.rollback(ctx, counted)
+			return nil, fmt.Errorf("failed to count %s quota: %w", limit.Period, err)
+		}
+		counted = append(counted, countedWindow{key: key, resetAt: resetAt})
+
+		if used > limit.Requests {
+			exceeded = true
+		}
+		usage = append(usage, newUsage(limit, used, resetAt))
+	}
+
+	if exceeded {
+		// Rejected requests don't use up quota
+		m.rollback(ctx, counted)
+		for i := range usage {
+			usage[i] = newUsage(limits[i], usage[i].Used-1, usage[i].ResetAt)
+		}
+		return usage, ErrExceeded
+	}
+	return usage, nil
+}
+
+// Usage returns the current usage of the subject without counting a request
+func (m *Manager) Usage(ctx context.Context, subject string) ([]Usage, error) {
+	limits, err := m.limitsFor(ctx, subject)
+	if err != nil {
+		return nil, err
+	}
+	now := m.now()
+	usage := make([]Usage, 0, len(limits))
+
+	for _, limit := range limits {
+		key, resetAt := m.window(subject, limit.Period, now)
+		used, err := m.store.Get(ctx, key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s quota: %w", limit.Period, err)
+		}
+		usage = append(usage, newUsage(limit, used, resetAt))
+	}
+	return usage, nil
+}
+
+// Reset clears the current usage of the subject for the given periods, or for all periods
+// when none are given
+func (m *Manager) Reset(ctx context.Context, subject string, periods ...Period) error {
+	if len(periods) == 0 {
+		periods = []Period{Day, Month}
+	}
+
+	now := m.now()
+	for _, period := range periods {
+		if period != Day && period != Month {
+			return fmt.Errorf("unknown quota period %q", period)
+		}
+		key, _ := m.window(subject, period, now)
+		if err := m.store.Delete(ctx, key); err != nil {
+			return fmt.Errorf("failed to reset %s quota: %w", period, err)
+		}
+	}
+	return nil
+}
+
+// limitsFor returns the quotas of a subject
+func (m *Manager) limitsFor(ctx context.Context, subject string) ([]Limit, error) {
+	if m.limitsFunc != nil {
+		if limits := m.limitsFunc(ctx, subject); limits != nil {
+			if err := validateLimits(limits); err != nil {
+				return nil, fmt.Errorf("invalid quotas of %s: %w", subject, err)
+			}
+			return limits, nil
+		}
+	}
+	return m.limits, nil
+}
+
+// window returns the counter key of the period containing now and when it ends
+func (m *Manager) window(subject string, period Period, now time.Time) (string, time.Time) {
+	now = now.In(m.location)
+	year, month, day := now.Date()
+
+	switch period {
+	case Month:
+		start := time.Date(year, month, 1, 0, 0, 0, 0, m.location)
+		return fmt.Sprintf("%s:month:%s", subject, start.Format("2006-01")), start.AddDate(0, 1, 0)
+	default:
+		start := time.Date(year, month, day, 0, 0, 0, 0, m.location)
+		return fmt.Sprintf("%s:day:%s", subject, start.Format("2006-01-02")), start.AddDate(0, 0, 1)
+	}
+}
+
+// countedWindow is a counter incremented for a request, with the end of its window
+type countedWindow struct {
+	key     string
+	resetAt time.Time
+}
+
+// rollback undoes the increments of a rejected or failed request. The counter may have
+// been removed in the meantime, e.g. by Reset, in which case the decrement recreates it;
+// it then expires with its window instead of crediting the subject forever.
+func (m *Manager) rollback(ctx context.Context, counted []countedWindow) {
+	for _, window := range counted {
+		// Best effort: a lost decrement only over-counts by one request
+		_, _ = m.store.Increment(ctx, window.key, -1, window.resetAt)
+	}
+}
+
+func newUsage(limit Limit, used int64, resetAt time.Time) Usage {
+	remaining := limit.Requests - used
+	if remaining < 0 {
+		remaining = 0
+	}
+	return Usage{
+		Period:    limit.Period,
+		Limit:     limit.Requests,
+		Used:      used,
+		Remaining: remaining,
+		ResetAt:   resetAt,
+	}
+}
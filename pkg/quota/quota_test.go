@@ -0,0 +1,136 @@
+package quota
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// resettingStore calls a hook after each increment, e.g. to reset a quota while a
+// request is being counted
+type resettingStore struct {
+	*MemoryStore
+	afterIncrement func(key string)
+}
+
+func (s *resettingStore) Increment(ctx context.Context, key string, n int64, expireAt time.Time) (int64, error) {
+	value, err := s.MemoryStore.Increment(ctx, key, n, expireAt)
+	if n > 0 && s.afterIncrement != nil {
+		s.afterIncrement(key)
+	}
+	return value, err
+}
+
+func TestManager_ResetDuringRollback(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+
+	memory := NewMemoryStore()
+	memory.now = clock
+	store := &resettingStore{MemoryStore: memory}
+	manager, err := NewManager(store, WithLimits(Daily(1)), WithClock(clock))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := manager.Consume(ctx, "key:k1"); err != nil {
+		t.Fatal(err)
+	}
+
+	// An operator resets the quota between the rejected request's increment and rollback
+	store.afterIncrement = func(key string) {
+		store.afterIncrement = nil
+		if err := manager.Reset(ctx, "key:k1", Day); err != nil {
+			t.Error(err)
+		}
+	}
+	if _, err := manager.Consume(ctx, "key:k1"); !errors.Is(err, ErrExceeded) {
+		t.Fatalf("Expected the second request to be rejected, got %v", err)
+	}
+
+	// The decrement recreates the counter, which must end with its window
+	key, resetAt := manager.window("key:k1", Day, now)
+	counter, ok := memory.counters[key]
+	if !ok || counter.value != -1 {
+		t.Fatalf("Expected the rollback to recreate the counter at -1, got %+v", counter)
+	}
+	if !counter.expireAt.Equal(resetAt) {
+		t.Errorf("Expected the recreated counter to expire at %v, got %v", resetAt, counter.expireAt)
+	}
+
+	now = resetAt
+	if used, _ := memory.Get(ctx, key); used != 0 {
+		t.Errorf("Expected the counter to expire with its window, got %d", used)
+	}
+}
+
+func TestManager_Consume(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 5, 31, 23, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+	store := NewMemoryStore()
+	store.now = clock
+	manager, err := NewManager(store, WithLimits(Daily(2), Monthly(3)), WithClock(clock))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := manager.Consume(ctx, "user:u1"); err != nil {
+			t.Fatalf("Expected request %d to be allowed, got %v", i+1, err)
+		}
+	}
+	usage, err := manager.Consume(ctx, "user:u1")
+	if !errors.Is(err, ErrExceeded) || usage[0].Used != 2 || usage[1].Used != 2 {
+		t.Fatalf("Expected the daily quota to be exceeded without counting, got %+v, %v", usage, err)
+	}
+
+	// Midnight starts new daily and monthly windows
+	now = now.Add(90 * time.Minute)
+	if _, err := manager.Consume(ctx, "user:u1"); err != nil {
+		t.Errorf("Expected June's first request to be allowed, got %v", err)
+	}
+	if usage, _ := manager.Usage(ctx, "user:u1"); usage[0].Used != 1 || usage[1].Used != 1 {
+		t.Errorf("Expected the month to roll over too, got %+v", usage)
+	}
+
+	if err := manager.Reset(ctx, "user:u1"); err != nil {
+		t.Fatal(err)
+	}
+	if usage, _ := manager.Usage(ctx, "user:u1"); usage[0].Used != 0 || usage[1].Used != 0 {
+		t.Errorf("Expected Reset to clear the usage, got %+v", usage)
+	}
+}
+
+func TestNewManager_InvalidLimits(t *testing.T) {
+	tests := []struct {
+		name    string
+		limits  []Limit
+		wantErr string
+	}{
+		{"unknown period", []Limit{{Period: "week", Requests: 10}}, `unknown quota period "week"`},
+		{"negative requests", []Limit{Daily(-1)}, "invalid day quota"},
+		{"duplicate period", []Limit{Daily(100), Monthly(1000), Daily(1000)}, "duplicate day quota"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewManager(NewMemoryStore(), WithLimits(tt.limits...)); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("NewManager() error = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	// Subject limits are checked when they are looked up
+	manager, err := NewManager(NewMemoryStore(), WithSubjectLimits(func(ctx context.Context, subject string) []Limit {
+		return []Limit{Daily(1), Daily(2)}
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := manager.Consume(context.Background(), "user:u1"); err == nil || errors.Is(err, ErrExceeded) {
+		t.Errorf("Expected duplicate subject limits to fail the request, got %v", err)
+	}
+}
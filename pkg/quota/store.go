@@ -0,0 +1,259 @@
+package quota
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Store persists usage counters. Counters are keyed by subject and period window, e.g.
+// "key:k1:day:2024-05-01", and may be dropped once they expire.
+type Store interface {
+	// Increment adds n (which may be negative) to a counter, creating it at zero, and
+	// returns the new value. A non-zero expireAt sets when a new counter expires.
+	Increment(ctx context.Context, key string, n int64, expireAt time.Time) (int64, error)
+
+	// Get returns the value of a counter, or 0 if it doesn't exist
+	Get(ctx context.Context, key string) (int64, error)
+
+	// Delete removes a counter
+	Delete(ctx context.Context, key string) error
+}
+
+// MemoryStore is an in-memory Store, useful for tests and single-instance deployments
+type MemoryStore struct {
+	mu       sync.Mutex
+	counters map[string]*memoryCounter
+	now      func() time.Time
+}
+
+type memoryCounter struct {
+	value    int64
+	expireAt time.Time
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		counters: make(map[string]*memoryCounter),
+		now:      time.Now,
+	}
+}
+
+// Increment adds n to a counter
+func (s *MemoryStore) Increment(ctx context.Context, key string, n int64, expireAt time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counter, ok := s.counters[key]
+	if !ok || s.expired(counter) {
+		counter = &memoryCounter{expireAt: expireAt}
+		s.counters[key] = counter
+		s.evictExpired()
+	}
+	counter.value += n
+	return counter.value, nil
+}
+
+// Get returns the value of a counter
+func (s *MemoryStore) Get(ctx context.Context, key string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counter, ok := s.counters[key]
+	if !ok || s.expired(counter) {
+		return 0, nil
+	}
+	return counter.value, nil
+}
+
+// Delete removes a counter
+func (s *MemoryStore) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.counters, key)
+	return nil
+}
+
+func (s *MemoryStore) expired(counter *memoryCounter) bool {
+	return !counter.expireAt.IsZero() && !s.now().Before(counter.expireAt)
+}
+
+// evictExpired drops counters of past windows; called when a new counter is created, so
+// at most once per subject and window
+func (s *MemoryStore) evictExpired() {
+	for key, counter := range s.counters {
+		if s.expired(counter) {
+			delete(s.counters, key)
+		}
+	}
+}
+
+// RedisClient is the subset of Redis commands used by RedisStore.
+// Adapt your Redis client (e.g. go-redis) to this interface.
+type RedisClient interface {
+	// Eval runs a Lua script atomically and returns its result, e.g. with go-redis:
+	// client.Eval(ctx, script, keys, args...).Result()
+	Eval(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error)
+	// Get returns the value of a key and whether it exists
+	Get(ctx context.Context, key string) (string, bool, error)
+	Del(ctx context.Context, key string) error
+}
+
+// RedisStore is a Store backed by Redis, shared by every replica. Counters expire with
+// their window.
+type RedisStore struct {
+	client RedisClient
+	prefix string
+}
+
+// NewRedisStore creates a Redis-backed store. Counters are stored under "<prefix>:<key>".
+func NewRedisStore(client RedisClient, prefix string) *RedisStore {
+	if prefix == "" {
+		prefix = "quota"
+	}
+	return &RedisStore{
+		client: client,
+		prefix: prefix,
+	}
+}
+
+// redisIncrementScript adds ARGV[1] to a counter and, if the counter has no expiry yet,
+// expires it at the Unix time ARGV[2] (0 for never), in one atomic step so a counter
+// cannot be left without its expiry
+const redisIncrementScript = `local value = redis.call('INCRBY', KEYS[1], ARGV[1])
+if ARGV[2] ~= '0' and redis.call('TTL', KEYS[1]) == -1 then
+	redis.call('EXPIREAT', KEYS[1], ARGV[2])
+end
+return value`
+
+// Increment adds n to a counter
+func (s *RedisStore) Increment(ctx context.Context, key string, n int64, expireAt time.Time) (int64, error) {
+	var expires int64
+	if !expireAt.IsZero() {
+		expires = expireAt.Unix()
+	}
+
+	result, err := s.client.Eval(ctx, redisIncrementScript, []string{s.keyName(key)}, n, expires)
+	if err != nil {
+		return 0, fmt.Errorf("redis increment failed: %w", err)
+	}
+	value, ok := result.(int64)
+	if !ok {
+		return 0, fmt.Errorf("invalid counter %s: unexpected result %T", key, result)
+	}
+	return value, nil
+}
+
+// Get returns the value of a counter
+func (s *RedisStore) Get(ctx context.Context, key string) (int64, error) {
+	value, found, err := s.client.Get(ctx, s.keyName(key))
+	if err != nil {
+		return 0, fmt.Errorf("redis get failed: %w", err)
+	}
+	if !found {
+		return 0, nil
+	}
+
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid counter %s: %w", key, err)
+	}
+	return n, nil
+}
+
+// Delete removes a counter
+func (s *RedisStore) Delete(ctx context.Context, key string) error {
+	if err := s.client.Del(ctx, s.keyName(key)); err != nil {
+		return fmt.Errorf("redis del failed: %w", err)
+	}
+	return nil
+}
+
+func (s *RedisStore) keyName(key string) string {
+	return s.prefix + ":" + key
+}
+
+// SQLStore is a Store backed by a SQL table, for deployments that keep usage next to
+// billing data. The queries use PostgreSQL syntax ($n placeholders, ON CONFLICT and
+// RETURNING), which SQLite 3.35+ also accepts.
+type SQLStore struct {
+	db    *sql.DB
+	table string
+}
+
+// NewSQLStore creates a store on the given table; an empty name uses "guardian_quota".
+// Call CreateTable once to create it.
+func NewSQLStore(db *sql.DB, table string) *SQLStore {
+	if table == "" {
+		table = "guardian_quota"
+	}
+	return &SQLStore{
+		db:    db,
+		table: table,
+	}
+}
+
+// CreateTable creates the counter table if it doesn't exist
+func (s *SQLStore) CreateTable(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	quota_key  VARCHAR(255) PRIMARY KEY,
+	used       BIGINT NOT NULL,
+	expires_at TIMESTAMP NULL
+)`, s.table))
+	if err != nil {
+		return fmt.Errorf("failed to create quota table: %w", err)
+	}
+	return nil
+}
+
+// Increment adds n to a counter
+func (s *SQLStore) Increment(ctx context.Context, key string, n int64, expireAt time.Time) (int64, error) {
+	var expires interface{}
+	if !expireAt.IsZero() {
+		expires = expireAt.UTC()
+	}
+
+	var value int64
+	err := s.db.QueryRowContext(ctx, fmt.Sprintf(`INSERT INTO %[1]s (quota_key, used, expires_at) VALUES ($1, $2, $3)
+ON CONFLICT (quota_key) DO UPDATE SET used = %[1]s.used + excluded.used
+RETURNING used`, s.table), key, n, expires).Scan(&value)
+	if err != nil {
+		return 0, fmt.Errorf("failed to increment quota counter: %w", err)
+	}
+	return value, nil
+}
+
+// Get returns the value of a counter
+func (s *SQLStore) Get(ctx context.Context, key string) (int64, error) {
+	var value int64
+	err := s.db.QueryRowContext(ctx, fmt.Sprintf(`SELECT used FROM %s WHERE quota_key = $1`, s.table), key).Scan(&value)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read quota counter: %w", err)
+	}
+	return value, nil
+}
+
+// Delete removes a counter
+func (s *SQLStore) Delete(ctx context.Context, key string) error {
+	if _, err := s.db.ExecContext(ctx, fmt.Sprintf(`DELETE FROM %s WHERE quota_key = $1`, s.table), key); err != nil {
+		return fmt.Errorf("failed to delete quota counter: %w", err)
+	}
+	return nil
+}
+
+// DeleteExpired removes the counters of past windows; run it periodically, e.g. daily
+func (s *SQLStore) DeleteExpired(ctx context.Context) (int64, error) {
+	result, err := s.db.ExecContext(ctx, fmt.Sprintf(`DELETE FROM %s WHERE expires_at < $1`, s.table), time.Now().UTC())
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired quota counters: %w", err)
+	}
+	return result.RowsAffected()
+}
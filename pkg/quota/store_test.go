@@ -0,0 +1,247 @@
+package quota
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeRedis runs RedisStore's increment script in memory
+type fakeRedis struct {
+	mu       sync.Mutex
+	values   map[string]int64
+	expireAt map[string]int64
+	result   interface{} // Overrides the script result when set
+}
+
+func newFakeRedis() *fakeRedis {
+	return &fakeRedis{values: make(map[string]int64), expireAt: make(map[string]int64)}
+}
+
+func (r *fakeRedis) Eval(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error) {
+	if script != redisIncrementScript || len(keys) != 1 || len(args) != 2 {
+		return nil, errors.New("unexpected script")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := keys[0]
+	r.values[key] += args[0].(int64)
+	if _, ok := r.expireAt[key]; !ok && args[1].(int64) != 0 {
+		r.expireAt[key] = args[1].(int64)
+	}
+	if r.result != nil {
+		return r.result, nil
+	}
+	return r.values[key], nil
+}
+
+func (r *fakeRedis) Get(ctx context.Context, key string) (string, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	value, ok := r.values[key]
+	return strconv.FormatInt(value, 10), ok, nil
+}
+
+func (r *fakeRedis) Del(ctx context.Context, key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.values, key)
+	delete(r.expireAt, key)
+	return nil
+}
+
+func TestRedisStore(t *testing.T) {
+	ctx := context.Background()
+	client := newFakeRedis()
+	store := NewRedisStore(client, "")
+	resetAt := time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC)
+
+	if value, err := store.Increment(ctx, "key:k1:day:2024-05-01", 1, resetAt); err != nil || value != 1 {
+		t.Fatalf("Increment() = %d, %v, want 1", value, err)
+	}
+	if value, err := store.Increment(ctx, "key:k1:day:2024-05-01", 2, resetAt.Add(time.Hour)); err != nil || value != 3 {
+		t.Fatalf("Increment() = %d, %v, want 3", value, err)
+	}
+	if got := client.expireAt["quota:key:k1:day:2024-05-01"]; got != resetAt.Unix() {
+		t.Errorf("Expected the counter to expire with its first window at %d, got %d", resetAt.Unix(), got)
+	}
+	if value, err := store.Get(ctx, "key:k1:day:2024-05-01"); err != nil || value != 3 {
+		t.Errorf("Get() = %d, %v, want 3", value, err)
+	}
+
+	if err := store.Delete(ctx, "key:k1:day:2024-05-01"); err != nil {
+		t.Fatal(err)
+	}
+	if value, err := store.Get(ctx, "key:k1:day:2024-05-01"); err != nil || value != 0 {
+		t.Errorf("Get() after Delete = %d, %v, want 0", value, err)
+	}
+
+	if _, err := store.Increment(ctx, "forever", 1, time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := client.expireAt["quota:forever"]; ok {
+		t.Error("Expected a zero expireAt to leave the counter without expiry")
+	}
+
+	client.result = "1"
+	if _, err := store.Increment(ctx, "forever", 1, time.Time{}); err == nil {
+		t.Error("Expected an error for a non-integer script result")
+	}
+}
+
+// fakeSQL is a database/sql connector that understands SQLStore's queries, keeping rows
+// in memory
+type fakeSQL struct {
+	mu      sync.Mutex
+	used    map[string]int64
+	expires map[string]interface{}
+}
+
+func newFakeSQL() *fakeSQL {
+	return &fakeSQL{used: make(map[string]int64), expires: make(map[string]interface{})}
+}
+
+func (f *fakeSQL) Connect(ctx context.Context) (driver.Conn, error) { return fakeSQLConn{f}, nil }
+func (f *fakeSQL) Driver() driver.Driver                            { return nil }
+
+type fakeSQLConn struct{ db *fakeSQL }
+
+func (c fakeSQLConn) Prepare(query string) (driver.Stmt, error) {
+	if !strings.Contains(query, "guardian_quota") {
+		return nil, errors.New("unexpected table")
+	}
+	return fakeSQLStmt{db: c.db, query: query}, nil
+}
+func (c fakeSQLConn) Close() error              { return nil }
+func (c fakeSQLConn) Begin() (driver.Tx, error) { return nil, errors.New("transactions not supported") }
+
+type fakeSQLStmt struct {
+	db    *fakeSQL
+	query string
+}
+
+func (s fakeSQLStmt) Close() error  { return nil }
+func (s fakeSQLStmt) NumInput() int { return -1 }
+
+func (s fakeSQLStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	switch {
+	case strings.HasPrefix(s.query, "CREATE TABLE"):
+		return driver.RowsAffected(0), nil
+	case strings.Contains(s.query, "WHERE quota_key = $1"):
+		_, ok := s.db.used[args[0].(string)]
+		delete(s.db.used, args[0].(string))
+		delete(s.db.expires, args[0].(string))
+		if ok {
+			return driver.RowsAffected(1), nil
+		}
+		return driver.RowsAffected(0), nil
+	case strings.Contains(s.query, "WHERE expires_at < $1"):
+		var deleted int64
+		for key, expires := range s.db.expires {
+			if at, ok := expires.(time.Time); ok && at.Before(args[0].(time.Time)) {
+				delete(s.db.used, key)
+				delete(s.db.expires, key)
+				deleted++
+			}
+		}
+		return driver.RowsAffected(deleted), nil
+	}
+	return nil, errors.New("unexpected statement: " + s.query)
+}
+
+func (s fakeSQLStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	switch {
+	case strings.HasPrefix(s.query, "INSERT INTO"):
+		key := args[0].(string)
+		if _, ok := s.db.used[key]; !ok {
+			s.db.expires[key] = args[2]
+		}
+		s.db.used[key] += args[1].(int64)
+		return &fakeSQLRows{values: []int64{s.db.used[key]}}, nil
+	case strings.HasPrefix(s.query, "SELECT used"):
+		if value, ok := s.db.used[args[0].(string)]; ok {
+			return &fakeSQLRows{values: []int64{value}}, nil
+		}
+		return &fakeSQLRows{}, nil
+	}
+	return nil, errors.New("unexpected query: " + s.query)
+}
+
+type fakeSQLRows struct{ values []int64 }
+
+func (r *fakeSQLRows) Columns() []string { return []string{"used"} }
+func (r *fakeSQLRows) Close() error      { return nil }
+func (r *fakeSQLRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	dest[0], r.values = r.values[0], r.values[1:]
+	return nil
+}
+
+func TestSQLStore(t *testing.T) {
+	ctx := context.Background()
+	fake := newFakeSQL()
+	db := sql.OpenDB(fake)
+	defer db.Close()
+
+	store := NewSQLStore(db, "")
+	if err := store.CreateTable(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+	if value, err := store.Increment(ctx, "key:k1:day:2024-05-01", 1, past); err != nil || value != 1 {
+		t.Fatalf("Increment() = %d, %v, want 1", value, err)
+	}
+	if value, err := store.Increment(ctx, "key:k1:day:2024-05-01", 2, past); err != nil || value != 3 {
+		t.Fatalf("Increment() = %d, %v, want 3", value, err)
+	}
+	if _, err := store.Increment(ctx, "key:k1:day:2024-05-02", 1, future); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Increment(ctx, "forever", 1, time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	if fake.expires["forever"] != nil {
+		t.Errorf("Expected a zero expireAt to be stored as NULL, got %v", fake.expires["forever"])
+	}
+
+	if value, err := store.Get(ctx, "key:k1:day:2024-05-01"); err != nil || value != 3 {
+		t.Errorf("Get() = %d, %v, want 3", value, err)
+	}
+	if value, err := store.Get(ctx, "missing"); err != nil || value != 0 {
+		t.Errorf("Get() of a missing counter = %d, %v, want 0", value, err)
+	}
+
+	if deleted, err := store.DeleteExpired(ctx); err != nil || deleted != 1 {
+		t.Errorf("DeleteExpired() = %d, %v, want 1", deleted, err)
+	}
+	if value, _ := store.Get(ctx, "key:k1:day:2024-05-02"); value != 1 {
+		t.Errorf("Expected the current window to be kept, got %d", value)
+	}
+
+	if err := store.Delete(ctx, "key:k1:day:2024-05-02"); err != nil {
+		t.Fatal(err)
+	}
+	if value, _ := store.Get(ctx, "key:k1:day:2024-05-02"); value != 0 {
+		t.Errorf("Get() after Delete = %d, want 0", value)
+	}
+}
@@ -0,0 +1,44 @@
+package randutil
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestRand_Reproducible(t *testing.T) {
+	a, b := New(42), New(42)
+	for i := 0; i < 100; i++ {
+		if x, y := a.Float64(), b.Float64(); x != y {
+			t.Fatalf("Draw %d differs: %v != %v", i, x, y)
+		}
+		if x, y := a.Intn(10), b.Intn(10); x != y {
+			t.Fatalf("Draw %d differs: %v != %v", i, x, y)
+		}
+		if x, y := a.Int63n(1000), b.Int63n(1000); x != y {
+			t.Fatalf("Draw %d differs: %v != %v", i, x, y)
+		}
+	}
+
+	if New(1).Float64() == New(2).Float64() {
+		t.Error("Expected different seeds to produce different draws")
+	}
+}
+
+func TestRand_Concurrent(t *testing.T) {
+	r := NewTimeSeeded()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				if f := r.Float64(); f < 0 || f >= 1 {
+					t.Errorf("Draw out of range: %v", f)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,248 @@
+package replay
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// fakeConn answers calls with a function of the request
+type fakeConn struct {
+	mu       sync.Mutex
+	metadata []metadata.MD
+	answer   func(method string, req *structpb.Struct) (*structpb.Struct, error)
+}
+
+func (c *fakeConn) Invoke(ctx context.Context, method string, args, reply interface{}, opts ...grpc.CallOption) error {
+	md, _ := metadata.FromOutgoingContext(ctx)
+	c.mu.Lock()
+	c.metadata = append(c.metadata, md)
+	c.mu.Unlock()
+
+	resp, err := c.answer(method, args.(*structpb.Struct))
+	if err != nil {
+		return err
+	}
+	proto.Merge(reply.(proto.Message), resp)
+	return nil
+}
+
+func (c *fakeConn) NewStream(ctx context.Context, desc *grpc.StreamDesc, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	return nil, errors.New("not supported")
+}
+
+func mustStruct(t *testing.T, fields map[string]interface{}) *structpb.Struct {
+	t.Helper()
+	s, err := structpb.NewStruct(fields)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func mustRecord(t *testing.T, md metadata.MD, req, resp proto.Message, err error) Record {
+	t.Helper()
+	record, rerr := NewRecord("/orders.Orders/Get", md, req, resp, err, time.Now(), 3*time.Millisecond)
+	if rerr != nil {
+		t.Fatal(rerr)
+	}
+	return record
+}
+
+func TestFileSink_RoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "orders.jsonl")
+	sink, err := NewFileSink(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := mustStruct(t, map[string]interface{}{"id": "o-1"})
+	resp := mustStruct(t, map[string]interface{}{"id": "o-1", "total": 12.5})
+	records := []Record{
+		mustRecord(t, metadata.Pairs("x-tenant-id", "acme"), req, resp, nil),
+		mustRecord(t, nil, req, nil, status.Error(codes.NotFound, "no such order")),
+	}
+	if err := sink.Write(context.Background(), records); err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	read, err := ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(read) != 2 {
+		t.Fatalf("Expected 2 records, got %d", len(read))
+	}
+
+	decoded, err := read[0].DecodeRequest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !proto.Equal(decoded, req) {
+		t.Errorf("Expected the recorded request, got %v", decoded)
+	}
+	decoded, err = read[0].DecodeResponse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !proto.Equal(decoded, resp) {
+		t.Errorf("Expected the recorded response, got %v", decoded)
+	}
+	if got := read[0].Metadata["x-tenant-id"]; len(got) != 1 || got[0] != "acme" {
+		t.Errorf("Expected the recorded metadata, got %v", read[0].Metadata)
+	}
+
+	if read[1].Code != "NotFound" || read[1].Message != "no such order" || read[1].ResponseType != "" {
+		t.Errorf("Unexpected failed record %+v", read[1])
+	}
+
+	if _, err := Read(strings.NewReader(`{"schema":"other.v9"}`)); err == nil {
+		t.Error("Expected an error for an unknown schema")
+	}
+}
+
+// memoryStore keeps objects in memory
+type memoryStore struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func (s *memoryStore) Put(ctx context.Context, key string, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.objects[key] = data
+	return nil
+}
+
+func TestObjectStoreSink(t *testing.T) {
+	store := &memoryStore{objects: make(map[string][]byte)}
+	sink := NewObjectStoreSink(store, "recordings/orders")
+
+	req := mustStruct(t, map[string]interface{}{"id": "o-1"})
+	for i := 0; i < 2; i++ {
+		if err := sink.Write(context.Background(), []Record{mustRecord(t, nil, req, req, nil)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := sink.Write(context.Background(), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(store.objects) != 2 {
+		t.Fatalf("Expected one object per batch, got %d", len(store.objects))
+	}
+	for key, data := range store.objects {
+		if !strings.HasPrefix(key, "recordings/orders/") || !strings.HasSuffix(key, ".jsonl") {
+			t.Errorf("Unexpected object key %q", key)
+		}
+		records, err := Read(bytes.NewReader(data))
+		if err != nil || len(records) != 1 {
+			t.Errorf("Expected 1 readable record in %q, got %d (%v)", key, len(records), err)
+		}
+	}
+}
+
+func TestReplayer(t *testing.T) {
+	conn := &fakeConn{answer: func(method string, req *structpb.Struct) (*structpb.Struct, error) {
+		id := req.Fields["id"].GetStringValue()
+		switch id {
+		case "missing":
+			return nil, status.Error(codes.NotFound, "gone")
+		case "changed":
+			return structpb.NewStruct(map[string]interface{}{"id": id, "total": 20.0, "etag": "new"})
+		default:
+			return structpb.NewStruct(map[string]interface{}{"id": id, "total": 10.0, "etag": "new"})
+		}
+	}}
+
+	records := []Record{
+		mustRecord(t, metadata.Pairs("x-tenant-id", "acme"),
+			mustStruct(t, map[string]interface{}{"id": "same"}),
+			mustStruct(t, map[string]interface{}{"id": "same", "total": 10.0, "etag": "old"}), nil),
+		mustRecord(t, nil,
+			mustStruct(t, map[string]interface{}{"id": "changed"}),
+			mustStruct(t, map[string]interface{}{"id": "changed", "total": 10.0, "etag": "old"}), nil),
+		mustRecord(t, nil,
+			mustStruct(t, map[string]interface{}{"id": "missing"}), nil, status.Error(codes.NotFound, "not found")),
+		mustRecord(t, nil,
+			mustStruct(t, map[string]interface{}{"id": "missing"}),
+			mustStruct(t, map[string]interface{}{"id": "missing"}), nil),
+	}
+	unknown := records[0]
+	unknown.RequestType = "unknown.Message"
+	records = append(records, unknown)
+
+	report := NewReplayer(conn, WithIgnoredFields("etag"), WithConcurrency(3)).Replay(context.Background(), records)
+	if report.Total != 5 || report.Matched != 2 || report.Mismatched != 2 || report.Failed != 1 {
+		t.Fatalf("Unexpected report %s", report)
+	}
+
+	if !report.Results[0].Match() || !report.Results[2].Match() {
+		t.Errorf("Expected unchanged calls to match, got %+v and %+v", report.Results[0], report.Results[2])
+	}
+
+	diffs := report.Results[1].Diffs
+	if len(diffs) != 1 || diffs[0].Path != "total" || diffs[0].Expected != "10" || diffs[0].Actual != "20" {
+		t.Errorf("Expected a diff of total only, got %+v", diffs)
+	}
+
+	diffs = report.Results[3].Diffs
+	if len(diffs) != 1 || diffs[0].Path != "status.code" || diffs[0].Expected != `"OK"` || diffs[0].Actual != `"NotFound"` {
+		t.Errorf("Expected a status code diff, got %+v", diffs)
+	}
+
+	if report.Results[4].Err == nil {
+		t.Error("Expected a failure for an unknown request type")
+	}
+
+	sent := false
+	for _, md := range conn.metadata {
+		if len(md.Get("x-tenant-id")) == 1 {
+			sent = true
+		}
+	}
+	if !sent {
+		t.Error("Expected the recorded metadata to be sent")
+	}
+
+	if summary := report.String(); !strings.HasPrefix(summary, "5 replayed: 2 matched, 2 mismatched, 1 failed") || !strings.Contains(summary, "total: 10 -> 20") {
+		t.Errorf("Unexpected summary:\n%s", summary)
+	}
+}
+
+func TestMatches(t *testing.T) {
+	rules := [][]string{{"etag"}, {"order", "id"}, {"*", "updated_at"}}
+
+	tests := []struct {
+		path []string
+		want bool
+	}{
+		{[]string{"etag"}, true},
+		{[]string{"items", "etag"}, true},
+		{[]string{"order", "id"}, true},
+		{[]string{"id"}, false},
+		{[]string{"customer", "order", "id"}, false},
+		{[]string{"order", "updated_at"}, true},
+		{[]string{"updated_at"}, false},
+	}
+	for _, tt := range tests {
+		if got := matches(rules, tt.path); got != tt.want {
+			t.Errorf("matches(%v) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,374 @@
+package replay
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// Diff is a difference between a recorded and a replayed response
+type Diff struct {
+	Path     string // e.g. "items[2].price", or "status.code"
+	Expected string // JSON of the recorded value; empty when missing
+	Actual   string // JSON of the replayed value; empty when missing
+}
+
+// Result is the outcome of replaying one record
+type Result struct {
+	Record   Record
+	Code     string // Status code of the replayed call
+	Duration time.Duration
+	Diffs    []Diff
+	Err      error // The call could not be replayed, e.g. the request type is unknown
+}
+
+// Match reports whether the replayed call behaved like the recorded one
+func (r Result) Match() bool {
+	return r.Err == nil && len(r.Diffs) == 0
+}
+
+// Report is the outcome of a replay
+type Report struct {
+	Total      int
+	Matched    int
+	Mismatched int
+	Failed     int
+	Results    []Result // In the order of the records
+}
+
+// Mismatches returns the results that did not match
+func (r Report) Mismatches() []Result {
+	var mismatches []Result
+	for _, result := range r.Results {
+		if !result.Match() {
+			mismatches = append(mismatches, result)
+		}
+	}
+	return mismatches
+}
+
+// String summarizes the report with every difference, e.g. for CI logs
+func (r Report) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d replayed: %d matched, %d mismatched, %d failed\n", r.Total, r.Matched, r.Mismatched, r.Failed)
+	for _, result := range r.Mismatches() {
+		if result.Err != nil {
+			fmt.Fprintf(&b, "%s (recorded %s): %v\n", result.Record.Method, result.Record.Time.Format(time.RFC3339), result.Err)
+			continue
+		}
+		fmt.Fprintf(&b, "%s (recorded %s):\n", result.Record.Method, result.Record.Time.Format(time.RFC3339))
+		for _, diff := range result.Diffs {
+			fmt.Fprintf(&b, "  %s: %s -> %s\n", diff.Path, orMissing(diff.Expected), orMissing(diff.Actual))
+		}
+	}
+	return b.String()
+}
+
+func orMissing(value string) string {
+	if value == "" {
+		return "<missing>"
+	}
+	return value
+}
+
+// Replayer re-sends recorded calls and compares the responses
+type Replayer struct {
+	conn        grpc.ClientConnInterface
+	ignored     [][]string
+	concurrency int
+	timeout     time.Duration
+	metadata    bool
+}
+
+// ReplayerOption configures a Replayer
+type ReplayerOption func(*Replayer)
+
+// WithIgnoredFields skips response fields that legitimately change between runs, e.g.
+// timestamps and generated IDs. A single name ("created_at") matches the field at any
+// depth; a dotted path ("order.id", "*.etag") matches from the response root, where "*"
+// matches any one field.
+func WithIgnoredFields(paths ...string) ReplayerOption {
+	return func(r *Replayer) {
+		for _, path := range paths {
+			if path != "" {
+				r.ignored = append(r.ignored, strings.Split(path, "."))
+			}
+		}
+	}
+}
+
+// WithConcurrency sets how many calls are replayed at once
+// Default: 1
+func WithConcurrency(n int) ReplayerOption {
+	return func(r *Replayer) {
+		if n > 0 {
+			r.concurrency = n
+		}
+	}
+}
+
+// WithCallTimeout sets the deadline of each replayed call
+// Default: 10s
+func WithCallTimeout(timeout time.Duration) ReplayerOption {
+	return func(r *Replayer) {
+		if timeout > 0 {
+			r.timeout = timeout
+		}
+	}
+}
+
+// WithoutRecordedMetadata doesn't send the recorded request headers
+func WithoutRecordedMetadata() ReplayerOption {
+	return func(r *Replayer) {
+		r.metadata = false
+	}
+}
+
+// NewReplayer creates a Replayer calling conn. Status codes must match; status messages
+// are not compared.
+//
+// Example usage:
+//
+//	records, err := replay.ReadFile("testdata/orders.jsonl")
+//	if err != nil {
+//	    t.Fatal(err)
+//	}
+//	conn, _ := grpc.Dial("orders-canary:50051", grpc.WithTransportCredentials(insecure.NewCredentials()))
+//	report := replay.NewReplayer(conn, replay.WithIgnoredFields("created_at", "order.id")).Replay(ctx, records)
+//	if report.Mismatched+report.Failed > 0 {
+//	    t.Error(report)
+//	}
+func NewReplayer(conn grpc.ClientConnInterface, opts ...ReplayerOption) *Replayer {
+	r := &Replayer{
+		conn:        conn,
+		concurrency: 1,
+		timeout:     10 * time.Second,
+		metadata:    true,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Replay re-sends every record and compares the responses
+func (r *Replayer) Replay(ctx context.Context, records []Record) Report {
+	results := make([]Result, len(records))
+
+	work := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < r.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range work {
+				results[index] = r.ReplayRecord(ctx, records[index])
+			}
+		}()
+	}
+	for i := range records {
+		work <- i
+	}
+	close(work)
+	wg.Wait()
+
+	report := Report{Total: len(records), Results: results}
+	for _, result := range results {
+		switch {
+		case result.Err != nil:
+			report.Failed++
+		case len(result.Diffs) > 0:
+			report.Mismatched++
+		default:
+			report.Matched++
+		}
+	}
+	return report
+}
+
+// ReplayRecord re-sends one record and compares the response
+func (r *Replayer) ReplayRecord(ctx context.Context, record Record) Result {
+	result := Result{Record: record}
+
+	req, err := record.DecodeRequest()
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	// A failed call has no recorded response type; only its status is compared
+	var resp proto.Message = &emptypb.Empty{}
+	if record.ResponseType != "" {
+		if resp, err = newMessage(record.ResponseType); err != nil {
+			result.Err = err
+			return result
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	if r.metadata && len(record.Metadata) > 0 {
+		ctx = metadata.NewOutgoingContext(ctx, metadata.MD(record.Metadata).Copy())
+	}
+
+	start := time.Now()
+	callErr := r.conn.Invoke(ctx, record.Method, req, resp)
+	result.Duration = time.Since(start)
+	result.Code = status.Code(callErr).String()
+
+	if result.Code != record.Code {
+		result.Diffs = append(result.Diffs, Diff{Path: "status.code", Expected: strconv.Quote(record.Code), Actual: strconv.Quote(result.Code)})
+		return result
+	}
+	if callErr != nil {
+		return result
+	}
+
+	expected, err := record.DecodeResponse()
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	result.Diffs, result.Err = r.compare(expected, resp)
+	return result
+}
+
+// compare diffs two responses by their proto field names
+func (r *Replayer) compare(expected, actual proto.Message) ([]Diff, error) {
+	want, err := genericJSON(expected)
+	if err != nil {
+		return nil, err
+	}
+	got, err := genericJSON(actual)
+	if err != nil {
+		return nil, err
+	}
+	var diffs []Diff
+	r.diff(nil, "", want, got, &diffs)
+	return diffs, nil
+}
+
+// genericJSON converts a message to decoded JSON keyed by proto field names
+func genericJSON(msg proto.Message) (interface{}, error) {
+	data, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// diff records the differences between two decoded JSON values
+func (r *Replayer) diff(path []string, display string, expected, actual interface{}, diffs *[]Diff) {
+	wantMap, wantIsMap := expected.(map[string]interface{})
+	gotMap, gotIsMap := actual.(map[string]interface{})
+	if wantIsMap && gotIsMap {
+		keys := make(map[string]bool, len(wantMap)+len(gotMap))
+		for key := range wantMap {
+			keys[key] = true
+		}
+		for key := range gotMap {
+			keys[key] = true
+		}
+		sorted := make([]string, 0, len(keys))
+		for key := range keys {
+			sorted = append(sorted, key)
+		}
+		sort.Strings(sorted)
+
+		for _, key := range sorted {
+			childPath := append(path[:len(path):len(path)], key)
+			if matches(r.ignored, childPath) {
+				continue
+			}
+			childDisplay := key
+			if display != "" {
+				childDisplay = display + "." + key
+			}
+			want, wantOK := wantMap[key]
+			got, gotOK := gotMap[key]
+			switch {
+			case !wantOK:
+				*diffs = append(*diffs, Diff{Path: childDisplay, Actual: jsonText(got)})
+			case !gotOK:
+				*diffs = append(*diffs, Diff{Path: childDisplay, Expected: jsonText(want)})
+			default:
+				r.diff(childPath, childDisplay, want, got, diffs)
+			}
+		}
+		return
+	}
+
+	wantList, wantIsList := expected.([]interface{})
+	gotList, gotIsList := actual.([]interface{})
+	if wantIsList && gotIsList {
+		for i := 0; i < len(wantList) || i < len(gotList); i++ {
+			elementDisplay := fmt.Sprintf("%s[%d]", display, i)
+			switch {
+			case i >= len(gotList):
+				*diffs = append(*diffs, Diff{Path: elementDisplay, Expected: jsonText(wantList[i])})
+			case i >= len(wantList):
+				*diffs = append(*diffs, Diff{Path: elementDisplay, Actual: jsonText(gotList[i])})
+			default:
+				// List elements share the path of their field
+				r.diff(path, elementDisplay, wantList[i], gotList[i], diffs)
+			}
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		*diffs = append(*diffs, Diff{Path: display, Expected: jsonText(expected), Actual: jsonText(actual)})
+	}
+}
+
+// jsonText encodes a decoded JSON value
+func jsonText(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+// matches reports whether any rule matches the field path, with the path rules of
+// logging.Redactor
+func matches(rules [][]string, path []string) bool {
+	for _, rule := range rules {
+		if len(rule) == 1 && rule[0] != "*" {
+			if rule[0] == path[len(path)-1] {
+				return true
+			}
+			continue
+		}
+		if len(rule) != len(path) {
+			continue
+		}
+		matched := true
+		for i, segment := range rule {
+			if segment != "*" && segment != path[i] {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+package replay
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Sink stores batches of records
+type Sink interface {
+	Write(ctx context.Context, records []Record) error
+}
+
+// FileSink appends records to a file as newline-delimited JSON
+type FileSink struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// NewFileSink opens path for appending, creating it if needed
+func NewFileSink(path string) (*FileSink, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return &FileSink{file: file}, nil
+}
+
+// Write appends records to the file
+func (s *FileSink) Write(ctx context.Context, records []Record) error {
+	data, err := encodeLines(records)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.file.Write(data)
+	return err
+}
+
+// Close closes the file
+func (s *FileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.file.Close()
+}
+
+// ObjectStore is the subset of an object storage client used by ObjectStoreSink.
+// Adapt your client (e.g. the S3 or GCS SDK) to this interface.
+type ObjectStore interface {
+	// Put stores data under key, replacing any existing object
+	Put(ctx context.Context, key string, data []byte) error
+}
+
+// ObjectStoreSink uploads every batch of records as a newline-delimited JSON object named
+// <prefix>/<time>-<sequence>.jsonl, so objects sort in recording order
+type ObjectStoreSink struct {
+	store  ObjectStore
+	prefix string
+	seq    atomic.Uint64
+}
+
+// NewObjectStoreSink creates a sink uploading under prefix
+func NewObjectStoreSink(store ObjectStore, prefix string) *ObjectStoreSink {
+	return &ObjectStoreSink{store: store, prefix: prefix}
+}
+
+// Write uploads records as one object
+func (s *ObjectStoreSink) Write(ctx context.Context, records []Record) error {
+	if len(records) == 0 {
+		return nil
+	}
+	data, err := encodeLines(records)
+	if err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%s-%06d.jsonl", time.Now().UTC().Format("20060102T150405.000000000Z"), s.seq.Add(1))
+	if err := s.store.Put(ctx, path.Join(s.prefix, name), data); err != nil {
+		return fmt.Errorf("object store put failed: %w", err)
+	}
+	return nil
+}
+
+// encodeLines encodes records as newline-delimited JSON
+func encodeLines(records []Record) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	for _, record := range records {
+		if err := encoder.Encode(record); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,111 @@
+// Package rollout provides zero-downtime, two-phase rollout of dynamic policies
+// (rate limits, authorization rules, ...) across a fleet of replicas
+package rollout
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// EventType identifies the phase a rollout event belongs to
+type EventType string
+
+const (
+	// EventPrepare asks replicas to validate and stage a policy version
+	EventPrepare EventType = "prepare"
+	// EventReady reports that a replica staged the policy successfully
+	EventReady EventType = "ready"
+	// EventFailed reports that a replica failed to validate, stage, commit, abort or roll back
+	// the policy
+	EventFailed EventType = "failed"
+	// EventCommit asks replicas to activate the staged policy version
+	EventCommit EventType = "commit"
+	// EventCommitted reports that a replica activated the policy
+	EventCommitted EventType = "committed"
+	// EventAbort asks replicas to discard a staged policy version
+	EventAbort EventType = "abort"
+	// EventAborted reports that a replica discarded the staged policy
+	EventAborted EventType = "aborted"
+	// EventRollback asks replicas to revert a committed policy version
+	EventRollback EventType = "rollback"
+	// EventRolledBack reports that a replica reverted to the policy active before the version
+	EventRolledBack EventType = "rolled_back"
+)
+
+// Event is a message exchanged between the coordinator and replicas during a rollout
+type Event struct {
+	Type      EventType `json:"type"`
+	Version   string    `json:"version"`
+	ReplicaID string    `json:"replica_id,omitempty"`
+	Policy    []byte    `json:"policy,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// EventBus distributes rollout events between the coordinator and replicas.
+// Implementations backed by Redis pub/sub, NATS or Kafka can be plugged in for multi-process fleets.
+type EventBus interface {
+	// Publish sends an event to all subscribers
+	Publish(ctx context.Context, event Event) error
+
+	// Subscribe registers a handler for all events and returns a function that removes it
+	Subscribe(handler func(Event)) (unsubscribe func())
+}
+
+// MemoryBus is an in-process EventBus, useful for tests and single-binary deployments.
+// Events are delivered synchronously in publish order.
+type MemoryBus struct {
+	mu       sync.RWMutex
+	handlers map[int]func(Event)
+	nextID   int
+}
+
+// NewMemoryBus creates a new in-memory event bus
+func NewMemoryBus() *MemoryBus {
+	return &MemoryBus{
+		handlers: make(map[int]func(Event)),
+	}
+}
+
+// Publish delivers the event to every subscriber
+func (b *MemoryBus) Publish(ctx context.Context, event Event) error {
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+
+	// Copy handlers so subscribers may publish or unsubscribe from within a handler
+	b.mu.RLock()
+	handlers := make([]func(Event), 0, len(b.handlers))
+	for id := 0; id < b.nextID; id++ {
+		if handler, ok := b.handlers[id]; ok {
+			handlers = append(handlers, handler)
+		}
+	}
+	b.mu.RUnlock()
+
+	for _, handler := range handlers {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		handler(event)
+	}
+
+	return nil
+}
+
+// Subscribe registers a handler for all events
+func (b *MemoryBus) Subscribe(handler func(Event)) func() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	id := b.nextID
+	b.nextID++
+	b.handlers[id] = handler
+
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.handlers, id)
+	}
+}
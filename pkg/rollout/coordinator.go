@@ -0,0 +1,262 @@
+package rollout
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrPrepareFailed is returned when at least one replica could not stage the policy
+	ErrPrepareFailed = errors.New("rollout prepare phase failed")
+
+	// ErrCommitFailed is returned when at least one replica could not activate the policy
+	ErrCommitFailed = errors.New("rollout commit phase failed")
+
+	// ErrRevertFailed is returned, together with ErrPrepareFailed or ErrCommitFailed, when
+	// at least one replica did not confirm that it aborted or rolled back the policy. Those
+	// replicas may still hold, or serve, the new version.
+	ErrRevertFailed = errors.New("rollout abort or rollback failed")
+)
+
+// Coordinator drives a two-phase rollout: every replica validates and stages the policy,
+// and only when all of them report ready is the commit broadcast. If any replica fails to
+// stage the policy it is aborted everywhere; if any replica fails to commit, the committed
+// replicas are rolled back so the fleet never serves a mix of policies for long. Replicas
+// confirm aborts and rollbacks; those that do not are reported through ErrRevertFailed.
+type Coordinator struct {
+	bus             EventBus
+	replicas        []string
+	prepareTimeout  time.Duration
+	commitTimeout   time.Duration
+	rollbackTimeout time.Duration
+	onEvent         func(Event)
+}
+
+// CoordinatorOption configures a Coordinator
+type CoordinatorOption func(*Coordinator)
+
+// WithPrepareTimeout sets how long to wait for all replicas to stage the policy
+// Default: 30s
+func WithPrepareTimeout(d time.Duration) CoordinatorOption {
+	return func(c *Coordinator) {
+		if d > 0 {
+			c.prepareTimeout = d
+		}
+	}
+}
+
+// WithCommitTimeout sets how long to wait for all replicas to activate the policy
+// Default: 30s
+func WithCommitTimeout(d time.Duration) CoordinatorOption {
+	return func(c *Coordinator) {
+		if d > 0 {
+			c.commitTimeout = d
+		}
+	}
+}
+
+// WithRollbackTimeout sets how long to wait for all replicas to confirm an abort or rollback
+// Default: 30s
+func WithRollbackTimeout(d time.Duration) CoordinatorOption {
+	return func(c *Coordinator) {
+		if d > 0 {
+			c.rollbackTimeout = d
+		}
+	}
+}
+
+// WithEventCallback sets a callback invoked for every replica response (for logging/metrics)
+func WithEventCallback(fn func(Event)) CoordinatorOption {
+	return func(c *Coordinator) {
+		c.onEvent = fn
+	}
+}
+
+// NewCoordinator creates a coordinator for the given set of replica IDs
+func NewCoordinator(bus EventBus, replicas []string, opts ...CoordinatorOption) *Coordinator {
+	c := &Coordinator{
+		bus:             bus,
+		replicas:        replicas,
+		prepareTimeout:  30 * time.Second,
+		commitTimeout:   30 * time.Second,
+		rollbackTimeout: 30 * time.Second,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// Rollout stages the policy on all replicas and then commits it.
+// It returns ErrPrepareFailed (policy aborted, previous policy still active everywhere) or
+// ErrCommitFailed (policy rolled back on replicas that had committed). Either also wraps
+// ErrRevertFailed, naming the replicas, if the abort or rollback was not confirmed everywhere.
+func (c *Coordinator) Rollout(ctx context.Context, version string, policy []byte) error {
+	responses := newResponseTracker(version, c.replicas)
+	unsubscribe := c.bus.Subscribe(func(event Event) {
+		if event.Version != version {
+			return
+		}
+		if responses.record(event) && c.onEvent != nil {
+			c.onEvent(event)
+		}
+	})
+	defer unsubscribe()
+
+	// Phase 1: validate + stage
+	responses.expect(EventReady)
+	if err := c.bus.Publish(ctx, Event{Type: EventPrepare, Version: version, Policy: policy}); err != nil {
+		return fmt.Errorf("failed to publish prepare: %w", err)
+	}
+
+	if failed := responses.wait(ctx, c.prepareTimeout); len(failed) > 0 {
+		err := fmt.Errorf("%w: %s", ErrPrepareFailed, strings.Join(failed, "; "))
+		return c.revert(responses, Event{Type: EventAbort, Version: version}, EventAborted, err)
+	}
+
+	// Phase 2: commit
+	responses.expect(EventCommitted)
+	if err := c.bus.Publish(ctx, Event{Type: EventCommit, Version: version}); err != nil {
+		err = fmt.Errorf("failed to publish commit: %w", err)
+		return c.revert(responses, Event{Type: EventRollback, Version: version}, EventRolledBack, err)
+	}
+
+	if failed := responses.wait(ctx, c.commitTimeout); len(failed) > 0 {
+		err := fmt.Errorf("%w: %s", ErrCommitFailed, strings.Join(failed, "; "))
+		return c.revert(responses, Event{Type: EventRollback, Version: version}, EventRolledBack, err)
+	}
+
+	return nil
+}
+
+// revert publishes an abort or rollback and waits for every replica to confirm it. It
+// returns cause, wrapped with ErrRevertFailed if any replica did not confirm.
+func (c *Coordinator) revert(responses *responseTracker, event Event, confirm EventType, cause error) error {
+	// The rollout context may be what ended the rollout, so reverting does not use it
+	responses.expect(confirm)
+	if err := c.bus.Publish(context.Background(), event); err != nil {
+		return fmt.Errorf("%w (%w: failed to publish %s: %v)", cause, ErrRevertFailed, event.Type, err)
+	}
+
+	if failed := responses.wait(context.Background(), c.rollbackTimeout); len(failed) > 0 {
+		return fmt.Errorf("%w (%w: %s)", cause, ErrRevertFailed, strings.Join(failed, "; "))
+	}
+	return cause
+}
+
+// responseTracker collects replica responses for the current phase
+type responseTracker struct {
+	mu       sync.Mutex
+	version  string
+	replicas []string
+	want     EventType
+	ok       map[string]bool
+	failed   map[string]string
+	done     chan struct{}
+}
+
+// newResponseTracker creates a tracker for a rollout version
+func newResponseTracker(version string, replicas []string) *responseTracker {
+	return &responseTracker{
+		version:  version,
+		replicas: replicas,
+	}
+}
+
+// expect resets the tracker for a new phase
+func (t *responseTracker) expect(want EventType) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.want = want
+	t.ok = make(map[string]bool)
+	t.failed = make(map[string]string)
+	t.done = make(chan struct{})
+	if len(t.replicas) == 0 {
+		close(t.done)
+	}
+}
+
+// record stores a replica response and reports whether it was relevant to the current phase
+func (t *responseTracker) record(event Event) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.done == nil || event.ReplicaID == "" {
+		return false
+	}
+
+	switch event.Type {
+	case t.want:
+		t.ok[event.ReplicaID] = true
+	case EventFailed:
+		t.failed[event.ReplicaID] = event.Error
+	default:
+		return false
+	}
+
+	if len(t.failed) > 0 || t.complete() {
+		select {
+		case <-t.done:
+		default:
+			close(t.done)
+		}
+	}
+
+	return true
+}
+
+// complete reports whether every replica has responded successfully
+func (t *responseTracker) complete() bool {
+	for _, replica := range t.replicas {
+		if !t.ok[replica] {
+			return false
+		}
+	}
+	return true
+}
+
+// wait blocks until the phase completes, a replica fails or the timeout elapses.
+// It returns a description of every replica that failed or did not respond.
+func (t *responseTracker) wait(ctx context.Context, timeout time.Duration) []string {
+	t.mu.Lock()
+	done := t.done
+	t.mu.Unlock()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	var reason string
+	select {
+	case <-done:
+	case <-timer.C:
+		reason = "timed out"
+	case <-ctx.Done():
+		reason = ctx.Err().Error()
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var failed []string
+	for replica, errMsg := range t.failed {
+		failed = append(failed, fmt.Sprintf("%s: %s", replica, errMsg))
+	}
+	if len(failed) == 0 && reason != "" {
+		for _, replica := range t.replicas {
+			if !t.ok[replica] {
+				failed = append(failed, fmt.Sprintf("%s: %s", replica, reason))
+			}
+		}
+	}
+	sort.Strings(failed)
+
+	return failed
+}
@@ -0,0 +1,214 @@
+package rollout
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Applier applies policy versions on a single replica
+type Applier interface {
+	// Stage validates the policy and prepares it for activation without affecting traffic
+	Stage(version string, policy []byte) error
+
+	// Commit activates a previously staged version
+	Commit(version string) error
+
+	// Abort discards a staged version that was never committed
+	Abort(version string) error
+
+	// Rollback reverts a committed version to the policy that was active before it
+	Rollback(version string) error
+}
+
+// Participant runs on every replica: it reacts to coordinator events by driving its Applier
+// and reports readiness back through the event bus
+type Participant struct {
+	id      string
+	bus     EventBus
+	applier Applier
+
+	mu          sync.Mutex
+	unsubscribe func()
+}
+
+// NewParticipant creates a participant for the replica with the given ID
+func NewParticipant(id string, bus EventBus, applier Applier) *Participant {
+	return &Participant{
+		id:      id,
+		bus:     bus,
+		applier: applier,
+	}
+}
+
+// Start subscribes the participant to rollout events
+func (p *Participant) Start() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.unsubscribe != nil {
+		return
+	}
+	p.unsubscribe = p.bus.Subscribe(p.handle)
+}
+
+// Stop unsubscribes the participant from rollout events
+func (p *Participant) Stop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.unsubscribe != nil {
+		p.unsubscribe()
+		p.unsubscribe = nil
+	}
+}
+
+// handle processes a single coordinator event
+func (p *Participant) handle(event Event) {
+	var (
+		err   error
+		reply EventType
+	)
+
+	switch event.Type {
+	case EventPrepare:
+		err = p.applier.Stage(event.Version, event.Policy)
+		reply = EventReady
+	case EventCommit:
+		err = p.applier.Commit(event.Version)
+		reply = EventCommitted
+	case EventAbort:
+		err = p.applier.Abort(event.Version)
+		reply = EventAborted
+	case EventRollback:
+		err = p.applier.Rollback(event.Version)
+		reply = EventRolledBack
+	default:
+		return
+	}
+
+	response := Event{Type: reply, Version: event.Version, ReplicaID: p.id}
+	if err != nil {
+		response.Type = EventFailed
+		response.Error = err.Error()
+	}
+
+	_ = p.bus.Publish(context.Background(), response)
+}
+
+// ErrNoActivePolicy is returned by StagedPolicy.Stage before SetActive was called
+var ErrNoActivePolicy = errors.New("no active policy recorded: call SetActive with the startup policy first")
+
+// StagedPolicy is an Applier that keeps the active, staged and previous policy values in
+// memory. Parse validates and decodes the raw policy; Activate swaps the decoded policy into
+// the running middleware (e.g. PerMethodRateLimiter.SetMethodLimit).
+type StagedPolicy struct {
+	parse    func(policy []byte) (interface{}, error)
+	activate func(policy interface{}) error
+
+	mu              sync.Mutex
+	active          interface{}
+	activeVersion   string
+	previous        interface{}
+	previousVersion string
+	staged          map[string]interface{}
+}
+
+// NewStagedPolicy creates a StagedPolicy with the given parse and activate functions
+func NewStagedPolicy(parse func(policy []byte) (interface{}, error), activate func(policy interface{}) error) *StagedPolicy {
+	return &StagedPolicy{
+		parse:    parse,
+		activate: activate,
+		staged:   make(map[string]interface{}),
+	}
+}
+
+// Stage parses the policy and keeps it until it is committed or aborted. It fails until
+// SetActive has recorded the startup policy, since a committed version could not be rolled
+// back without it.
+func (s *StagedPolicy) Stage(version string, policy []byte) error {
+	parsed, err := s.parse(policy)
+	if err != nil {
+		return fmt.Errorf("invalid policy: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.activeVersion == "" {
+		return ErrNoActivePolicy
+	}
+	s.staged[version] = parsed
+	return nil
+}
+
+// Commit activates a staged version
+func (s *StagedPolicy) Commit(version string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	parsed, ok := s.staged[version]
+	if !ok {
+		return fmt.Errorf("version %s is not staged", version)
+	}
+
+	if err := s.activate(parsed); err != nil {
+		return err
+	}
+
+	delete(s.staged, version)
+	s.previous, s.previousVersion = s.active, s.activeVersion
+	s.active, s.activeVersion = parsed, version
+	return nil
+}
+
+// Abort discards a staged version
+func (s *StagedPolicy) Abort(version string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.staged, version)
+	return nil
+}
+
+// Rollback re-activates the policy that was active before the given version was committed
+func (s *StagedPolicy) Rollback(version string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Replicas that never committed the version only need to discard it
+	if s.activeVersion != version {
+		delete(s.staged, version)
+		return nil
+	}
+
+	if s.previousVersion == "" {
+		return errors.New("no previous policy to roll back to")
+	}
+
+	if err := s.activate(s.previous); err != nil {
+		return err
+	}
+
+	s.active, s.activeVersion = s.previous, s.previousVersion
+	s.previous, s.previousVersion = nil, ""
+	return nil
+}
+
+// SetActive records the policy that is active at startup so the first rollout can be rolled
+// back. It must be called with a non-empty version before the first rollout.
+func (s *StagedPolicy) SetActive(version string, policy interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.active, s.activeVersion = policy, version
+}
+
+// Active returns the currently active policy and its version
+func (s *StagedPolicy) Active() (interface{}, string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.active, s.activeVersion
+}
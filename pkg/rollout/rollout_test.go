@@ -0,0 +1,147 @@
+package rollout
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// replica is a participant with a string policy whose parse and activate can be made to fail
+type replica struct {
+	policy      *StagedPolicy
+	failStage   bool
+	failCommit  string // Version whose activation fails
+	failRestore bool   // Re-activating the startup policy fails
+}
+
+// startReplica starts a participant with "v1" active
+func startReplica(t *testing.T, bus EventBus, id string) *replica {
+	r := &replica{}
+	r.policy = NewStagedPolicy(
+		func(raw []byte) (interface{}, error) {
+			if r.failStage {
+				return nil, errors.New("bad policy")
+			}
+			return string(raw), nil
+		},
+		func(policy interface{}) error {
+			if policy == r.failCommit || (r.failRestore && policy == "v1") {
+				return fmt.Errorf("cannot activate %v", policy)
+			}
+			return nil
+		},
+	)
+	r.policy.SetActive("v1", "v1")
+
+	participant := NewParticipant(id, bus, r.policy)
+	participant.Start()
+	t.Cleanup(participant.Stop)
+	return r
+}
+
+// active returns the replica's active policy version
+func (r *replica) active() string {
+	_, version := r.policy.Active()
+	return version
+}
+
+func TestRollout_Success(t *testing.T) {
+	bus := NewMemoryBus()
+	a, b := startReplica(t, bus, "a"), startReplica(t, bus, "b")
+
+	if err := NewCoordinator(bus, []string{"a", "b"}).Rollout(context.Background(), "v2", []byte("v2")); err != nil {
+		t.Fatal(err)
+	}
+	if a.active() != "v2" || b.active() != "v2" {
+		t.Errorf("Expected v2 everywhere, got %s and %s", a.active(), b.active())
+	}
+}
+
+func TestRollout_PrepareFailure(t *testing.T) {
+	bus := NewMemoryBus()
+	a, b := startReplica(t, bus, "a"), startReplica(t, bus, "b")
+	b.failStage = true
+
+	err := NewCoordinator(bus, []string{"a", "b"}).Rollout(context.Background(), "v2", []byte("v2"))
+	if !errors.Is(err, ErrPrepareFailed) || errors.Is(err, ErrRevertFailed) || !strings.Contains(err.Error(), "b: invalid policy") {
+		t.Fatalf("Expected a confirmed prepare failure on b, got %v", err)
+	}
+	if a.active() != "v1" || b.active() != "v1" {
+		t.Errorf("Expected v1 everywhere, got %s and %s", a.active(), b.active())
+	}
+	if err := a.policy.Commit("v2"); err == nil {
+		t.Error("Expected the staged version to be discarded")
+	}
+}
+
+func TestRollout_NoActivePolicy(t *testing.T) {
+	bus := NewMemoryBus()
+	a := startReplica(t, bus, "a")
+	fresh := NewStagedPolicy(func(raw []byte) (interface{}, error) { return string(raw), nil }, func(interface{}) error { return nil })
+	participant := NewParticipant("b", bus, fresh)
+	participant.Start()
+	defer participant.Stop()
+
+	err := NewCoordinator(bus, []string{"a", "b"}).Rollout(context.Background(), "v2", []byte("v2"))
+	if !errors.Is(err, ErrPrepareFailed) || !strings.Contains(err.Error(), ErrNoActivePolicy.Error()) {
+		t.Fatalf("Expected the replica without a startup policy to refuse, got %v", err)
+	}
+	if _, version := fresh.Active(); a.active() != "v1" || version != "" {
+		t.Errorf("Expected no replica to switch, got %s and %q", a.active(), version)
+	}
+}
+
+func TestRollout_CommitFailure(t *testing.T) {
+	bus := NewMemoryBus()
+	a, b := startReplica(t, bus, "a"), startReplica(t, bus, "b")
+	b.failCommit = "v2"
+
+	err := NewCoordinator(bus, []string{"a", "b"}).Rollout(context.Background(), "v2", []byte("v2"))
+	if !errors.Is(err, ErrCommitFailed) || errors.Is(err, ErrRevertFailed) {
+		t.Fatalf("Expected a confirmed commit failure, got %v", err)
+	}
+	if a.active() != "v1" || b.active() != "v1" {
+		t.Errorf("Expected v1 everywhere after the rollback, got %s and %s", a.active(), b.active())
+	}
+}
+
+func TestRollout_RollbackFailure(t *testing.T) {
+	bus := NewMemoryBus()
+	a, b := startReplica(t, bus, "a"), startReplica(t, bus, "b")
+	a.failRestore = true
+	b.failCommit = "v2"
+
+	err := NewCoordinator(bus, []string{"a", "b"}).Rollout(context.Background(), "v2", []byte("v2"))
+	if !errors.Is(err, ErrCommitFailed) || !errors.Is(err, ErrRevertFailed) {
+		t.Fatalf("Expected the failed rollback to be reported, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "a: cannot activate v1") {
+		t.Errorf("Expected the replica that could not roll back to be named, got %v", err)
+	}
+	if a.active() != "v2" {
+		t.Errorf("Expected a to still serve v2, got %s", a.active())
+	}
+}
+
+func TestRollout_Timeout(t *testing.T) {
+	bus := NewMemoryBus()
+	a := startReplica(t, bus, "a") // "b" never subscribes
+
+	coordinator := NewCoordinator(bus, []string{"a", "b"},
+		WithPrepareTimeout(20*time.Millisecond),
+		WithRollbackTimeout(20*time.Millisecond),
+	)
+	err := coordinator.Rollout(context.Background(), "v2", []byte("v2"))
+	if !errors.Is(err, ErrPrepareFailed) || !strings.Contains(err.Error(), "b: timed out") {
+		t.Fatalf("Expected the silent replica to time out, got %v", err)
+	}
+	if !errors.Is(err, ErrRevertFailed) {
+		t.Errorf("Expected the unconfirmed abort to be reported, got %v", err)
+	}
+	if a.active() != "v1" {
+		t.Errorf("Expected a to keep v1, got %s", a.active())
+	}
+}
@@ -0,0 +1,301 @@
+// Package serviceconfig generates a standard gRPC service config from guardian policies, so
+// the native retries and deadlines of gRPC clients match what guardian expects on the server.
+package serviceconfig
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grpc-guardian/grpc-guardian/middleware"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+// maxAttempts is the highest number of attempts gRPC honors; larger values are lowered to it
+const maxAttempts = 5
+
+// statusNames are the service config names of status codes
+var statusNames = map[codes.Code]string{
+	codes.OK:                 "OK",
+	codes.Canceled:           "CANCELLED",
+	codes.Unknown:            "UNKNOWN",
+	codes.InvalidArgument:    "INVALID_ARGUMENT",
+	codes.DeadlineExceeded:   "DEADLINE_EXCEEDED",
+	codes.NotFound:           "NOT_FOUND",
+	codes.AlreadyExists:      "ALREADY_EXISTS",
+	codes.PermissionDenied:   "PERMISSION_DENIED",
+	codes.ResourceExhausted:  "RESOURCE_EXHAUSTED",
+	codes.FailedPrecondition: "FAILED_PRECONDITION",
+	codes.Aborted:            "ABORTED",
+	codes.OutOfRange:         "OUT_OF_RANGE",
+	codes.Unimplemented:      "UNIMPLEMENTED",
+	codes.Internal:           "INTERNAL",
+	codes.Unavailable:        "UNAVAILABLE",
+	codes.DataLoss:           "DATA_LOSS",
+	codes.Unauthenticated:    "UNAUTHENTICATED",
+}
+
+// config collects the policies a service config is generated from
+type config struct {
+	retry        *middleware.RetryPolicy
+	retries      map[string]*middleware.RetryPolicy
+	timeouts     middleware.TimeoutConfig
+	hasTimeouts  bool
+	maxRequest   int
+	maxResponse  int
+	loadBalancer string
+}
+
+// Option configures the generated service config
+type Option func(*config)
+
+// WithRetry applies the policy of retry to every method
+func WithRetry(retry *middleware.Retry) Option {
+	return func(c *config) {
+		policy := retry.Policy()
+		c.retry = &policy
+	}
+}
+
+// WithMethodRetry applies the policy of retry to methods matching pattern:
+// "/pkg.Service/Method" or "/pkg.Service/*". A nil retry disables retries for them, e.g.
+// for writes that are not idempotent.
+func WithMethodRetry(pattern string, retry *middleware.Retry) Option {
+	return func(c *config) {
+		if retry == nil {
+			c.retries[pattern] = nil
+			return
+		}
+		policy := retry.Policy()
+		c.retries[pattern] = &policy
+	}
+}
+
+// WithTimeouts sets client deadlines from the same options as the Timeout middleware, so
+// clients give up when the server does
+func WithTimeouts(opts ...middleware.TimeoutOption) Option {
+	return func(c *config) {
+		c.hasTimeouts = true
+		for _, opt := range opts {
+			opt(&c.timeouts)
+		}
+	}
+}
+
+// WithMaxRequestMessageBytes caps the size of messages clients send, e.g. to the server's
+// grpc.MaxRecvMsgSize
+func WithMaxRequestMessageBytes(n int) Option {
+	return func(c *config) {
+		c.maxRequest = n
+	}
+}
+
+// WithMaxResponseMessageBytes caps the size of messages clients accept
+func WithMaxResponseMessageBytes(n int) Option {
+	return func(c *config) {
+		c.maxResponse = n
+	}
+}
+
+// WithLoadBalancingPolicy sets the load balancing policy, e.g. "round_robin"
+// Default: none (gRPC uses pick_first)
+func WithLoadBalancingPolicy(policy string) Option {
+	return func(c *config) {
+		c.loadBalancer = policy
+	}
+}
+
+// serviceConfig is the JSON form of a gRPC service config
+type serviceConfig struct {
+	LoadBalancingConfig []map[string]struct{} `json:"loadBalancingConfig,omitempty"`
+	MethodConfig        []methodConfig        `json:"methodConfig,omitempty"`
+}
+
+type methodConfig struct {
+	Name                    []methodName `json:"name"`
+	Timeout                 string       `json:"timeout,omitempty"`
+	MaxRequestMessageBytes  int          `json:"maxRequestMessageBytes,omitempty"`
+	MaxResponseMessageBytes int          `json:"maxResponseMessageBytes,omitempty"`
+	RetryPolicy             *retryPolicy `json:"retryPolicy,omitempty"`
+}
+
+type methodName struct {
+	Service string `json:"service,omitempty"`
+	Method  string `json:"method,omitempty"`
+}
+
+type retryPolicy struct {
+	MaxAttempts          int      `json:"maxAttempts"`
+	InitialBackoff       string   `json:"initialBackoff"`
+	MaxBackoff           string   `json:"maxBackoff"`
+	BackoffMultiplier    float64  `json:"backoffMultiplier"`
+	RetryableStatusCodes []string `json:"retryableStatusCodes"`
+}
+
+// Generate returns the service config JSON for the policies. Every method config is
+// complete, since gRPC applies only the most specific one: a method entry, then a service
+// entry, then the default entry.
+//
+// Example usage:
+//
+//	retry := middleware.NewRetry(middleware.WithMaxAttempts(3))
+//	sc, err := serviceconfig.Generate(
+//	    serviceconfig.WithRetry(retry),
+//	    serviceconfig.WithMethodRetry("/orders.v1.Orders/Create", nil),
+//	    serviceconfig.WithTimeouts(middleware.WithPerMethodTimeout(map[string]time.Duration{
+//	        "/orders.v1.Orders/Search": 2 * time.Second,
+//	    })),
+//	    serviceconfig.WithMaxRequestMessageBytes(4<<20),
+//	)
+func Generate(opts ...Option) ([]byte, error) {
+	c := &config{
+		retries:  make(map[string]*middleware.RetryPolicy),
+		timeouts: middleware.TimeoutConfig{PerMethod: make(map[string]time.Duration)},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	// Every pattern with its own settings gets an entry
+	patterns := map[string]bool{"*": true}
+	for pattern := range c.retries {
+		patterns[pattern] = true
+	}
+	if c.hasTimeouts {
+		for method := range c.timeouts.PerMethod {
+			patterns[method] = true
+		}
+	}
+
+	sorted := make([]string, 0, len(patterns))
+	for pattern := range patterns {
+		sorted = append(sorted, pattern)
+	}
+	sort.Strings(sorted)
+
+	var sc serviceConfig
+	if c.loadBalancer != "" {
+		sc.LoadBalancingConfig = []map[string]struct{}{{c.loadBalancer: {}}}
+	}
+	for _, pattern := range sorted {
+		mc, err := c.methodConfig(pattern)
+		if err != nil {
+			return nil, err
+		}
+		if mc.Timeout == "" && mc.RetryPolicy == nil && mc.MaxRequestMessageBytes == 0 && mc.MaxResponseMessageBytes == 0 {
+			continue
+		}
+		sc.MethodConfig = append(sc.MethodConfig, mc)
+	}
+
+	return json.Marshal(sc)
+}
+
+// DialOption returns the service config as the default of a client connection. A service
+// config published by the name resolver takes precedence.
+func DialOption(opts ...Option) (grpc.DialOption, error) {
+	sc, err := Generate(opts...)
+	if err != nil {
+		return nil, err
+	}
+	return grpc.WithDefaultServiceConfig(string(sc)), nil
+}
+
+// methodConfig builds the complete settings of one pattern
+func (c *config) methodConfig(pattern string) (methodConfig, error) {
+	name, err := parsePattern(pattern)
+	if err != nil {
+		return methodConfig{}, err
+	}
+
+	mc := methodConfig{
+		Name:                    []methodName{name},
+		MaxRequestMessageBytes:  c.maxRequest,
+		MaxResponseMessageBytes: c.maxResponse,
+	}
+	if timeout := c.timeoutFor(pattern); timeout > 0 {
+		mc.Timeout = formatDuration(timeout)
+	}
+	if policy := c.retryFor(pattern); policy != nil {
+		mc.RetryPolicy = convertRetry(policy)
+	}
+	return mc, nil
+}
+
+// timeoutFor returns the timeout of a pattern, 0 for none
+func (c *config) timeoutFor(pattern string) time.Duration {
+	if !c.hasTimeouts {
+		return 0
+	}
+	if timeout, ok := c.timeouts.PerMethod[pattern]; ok {
+		return timeout
+	}
+	return c.timeouts.Timeout
+}
+
+// retryFor returns the most specific retry policy of a pattern
+func (c *config) retryFor(pattern string) *middleware.RetryPolicy {
+	if policy, ok := c.retries[pattern]; ok {
+		return policy
+	}
+	if strings.Count(pattern, "/") == 2 && !strings.HasSuffix(pattern, "/*") {
+		service := pattern[:strings.LastIndex(pattern, "/")]
+		if policy, ok := c.retries[service+"/*"]; ok {
+			return policy
+		}
+	}
+	if policy, ok := c.retries["*"]; ok {
+		return policy
+	}
+	return c.retry
+}
+
+// parsePattern converts a method pattern to a service config name
+func parsePattern(pattern string) (methodName, error) {
+	if pattern == "*" {
+		return methodName{}, nil
+	}
+	parts := strings.Split(strings.TrimPrefix(pattern, "/"), "/")
+	if !strings.HasPrefix(pattern, "/") || len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return methodName{}, fmt.Errorf("invalid method pattern %q: expected /pkg.Service/Method or /pkg.Service/*", pattern)
+	}
+	if parts[1] == "*" {
+		return methodName{Service: parts[0]}, nil
+	}
+	return methodName{Service: parts[0], Method: parts[1]}, nil
+}
+
+// convertRetry converts a retry policy; policies gRPC can't express as retries return nil
+func convertRetry(policy *middleware.RetryPolicy) *retryPolicy {
+	if policy.MaxAttempts < 2 || len(policy.RetryableCodes) == 0 {
+		return nil
+	}
+
+	rp := &retryPolicy{
+		MaxAttempts:       policy.MaxAttempts,
+		InitialBackoff:    formatDuration(policy.InitialBackoff),
+		MaxBackoff:        formatDuration(policy.MaxBackoff),
+		BackoffMultiplier: policy.BackoffMultiplier,
+	}
+	if rp.MaxAttempts > maxAttempts {
+		rp.MaxAttempts = maxAttempts
+	}
+	for _, code := range policy.RetryableCodes {
+		if name, ok := statusNames[code]; ok && code != codes.OK {
+			rp.RetryableStatusCodes = append(rp.RetryableStatusCodes, name)
+		}
+	}
+	if len(rp.RetryableStatusCodes) == 0 {
+		return nil
+	}
+	return rp
+}
+
+// formatDuration formats a duration the way protobuf's JSON mapping does, e.g. "0.1s"
+func formatDuration(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', -1, 64) + "s"
+}
@@ -0,0 +1,78 @@
+package serviceconfig
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/grpc-guardian/grpc-guardian/middleware"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+func TestGenerate(t *testing.T) {
+	retry := middleware.NewRetry(
+		middleware.WithMaxAttempts(8),
+		middleware.WithInitialBackoff(100*time.Millisecond),
+		middleware.WithRetryableCodes(codes.Unavailable, codes.ResourceExhausted),
+	)
+
+	data, err := Generate(
+		WithRetry(retry),
+		WithMethodRetry("/orders.v1.Orders/Create", nil),
+		WithTimeouts(middleware.WithPerMethodTimeout(map[string]time.Duration{
+			"/orders.v1.Orders/Search": 2 * time.Second,
+		})),
+		WithMaxRequestMessageBytes(4<<20),
+	)
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	var sc serviceConfig
+	if err := json.Unmarshal(data, &sc); err != nil {
+		t.Fatalf("Invalid JSON %s: %v", data, err)
+	}
+	byName := make(map[methodName]methodConfig)
+	for _, mc := range sc.MethodConfig {
+		byName[mc.Name[0]] = mc
+	}
+
+	def := byName[methodName{}]
+	if def.RetryPolicy == nil || def.RetryPolicy.MaxAttempts != maxAttempts || def.RetryPolicy.InitialBackoff != "0.1s" {
+		t.Errorf("Unexpected default retry policy %+v", def.RetryPolicy)
+	}
+	if got := def.RetryPolicy.RetryableStatusCodes; len(got) != 2 || got[0] != "RESOURCE_EXHAUSTED" || got[1] != "UNAVAILABLE" {
+		t.Errorf("Unexpected retryable codes %v", got)
+	}
+	if def.Timeout != "" || def.MaxRequestMessageBytes != 4<<20 {
+		t.Errorf("Unexpected default method config %+v", def)
+	}
+
+	create := byName[methodName{Service: "orders.v1.Orders", Method: "Create"}]
+	if create.RetryPolicy != nil || create.MaxRequestMessageBytes != 4<<20 {
+		t.Errorf("Expected Create without retries, got %+v", create)
+	}
+	search := byName[methodName{Service: "orders.v1.Orders", Method: "Search"}]
+	if search.Timeout != "2s" || search.RetryPolicy == nil {
+		t.Errorf("Expected Search to have a timeout and the default retries, got %+v", search)
+	}
+
+	// gRPC accepts the generated config
+	option, err := DialOption(WithRetry(retry), WithLoadBalancingPolicy("round_robin"))
+	if err != nil {
+		t.Fatalf("DialOption failed: %v", err)
+	}
+	conn, err := grpc.Dial("passthrough:///localhost:0", option, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("gRPC rejected the service config: %v", err)
+	}
+	conn.Close()
+}
+
+func TestGenerate_InvalidPattern(t *testing.T) {
+	if _, err := Generate(WithMethodRetry("orders.v1.Orders", nil)); err == nil {
+		t.Error("Expected an invalid pattern to be rejected")
+	}
+}
@@ -0,0 +1,466 @@
+package servicemesh
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+// Consul agent defaults
+const (
+	DefaultConsulAddress = "http://127.0.0.1:8500"
+	defaultConsulTimeout = 5 * time.Second
+)
+
+// ConsulMesh implements ServiceMesh interface for Consul Connect
+type ConsulMesh struct {
+	config      *Config
+	address     string
+	token       string
+	datacenter  string
+	trustDomain string
+	client      *http.Client
+}
+
+// ConsulOption configures the Consul integration
+type ConsulOption func(*ConsulMesh)
+
+// WithConsulAddress sets the address of the Consul HTTP API
+// Default: CONSUL_HTTP_ADDR, otherwise http://127.0.0.1:8500
+func WithConsulAddress(address string) ConsulOption {
+	return func(c *ConsulMesh) {
+		c.address = address
+	}
+}
+
+// WithConsulToken sets the ACL token sent to the Consul HTTP API
+// Default: CONSUL_HTTP_TOKEN
+func WithConsulToken(token string) ConsulOption {
+	return func(c *ConsulMesh) {
+		c.token = token
+	}
+}
+
+// WithConsulDatacenter queries the catalog of another datacenter and only accepts
+// certificates issued to services in it
+// Default: the datacenter of the local agent
+func WithConsulDatacenter(datacenter string) ConsulOption {
+	return func(c *ConsulMesh) {
+		c.datacenter = datacenter
+	}
+}
+
+// WithConsulTrustDomain only accepts certificates from the trust domain, e.g.
+// "7f3d5d2a-....consul" (see /v1/connect/ca/roots)
+func WithConsulTrustDomain(trustDomain string) ConsulOption {
+	return func(c *ConsulMesh) {
+		c.trustDomain = trustDomain
+	}
+}
+
+// WithConsulHTTPClient sets the HTTP client used for the Consul API, e.g. for TLS to the agent
+func WithConsulHTTPClient(client *http.Client) ConsulOption {
+	return func(c *ConsulMesh) {
+		if client != nil {
+			c.client = client
+		}
+	}
+}
+
+// NewConsulMesh creates a new Consul Connect service mesh integration
+//
+// Example usage:
+//
+//	mesh, err := servicemesh.NewConsulMesh(&servicemesh.Config{
+//	    ServiceName: "orders",
+//	    Namespace:   "default",
+//	    EnableMTLS:  true,
+//	}, servicemesh.WithConsulDatacenter("dc1"))
+//	endpoints, err := mesh.GetServiceEndpoints("payments")
+func NewConsulMesh(config *Config, opts ...ConsulOption) (*ConsulMesh, error) {
+	if config == nil {
+		return nil, errors.New("config cannot be nil")
+	}
+
+	config.Provider = ProviderConsul
+
+	c := &ConsulMesh{
+		config:  config,
+		address: os.Getenv("CONSUL_HTTP_ADDR"),
+		token:   os.Getenv("CONSUL_HTTP_TOKEN"),
+		client:  http.DefaultClient,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if c.address == "" {
+		c.address = DefaultConsulAddress
+	}
+	if !strings.Contains(c.address, "://") {
+		c.address = "http://" + c.address
+	}
+	c.address = strings.TrimSuffix(c.address, "/")
+
+	return c, nil
+}
+
+// ExtractMetadata extracts Consul Connect metadata from gRPC context. Envoy propagates
+// B3 trace headers and forwards the caller's certificate URI in x-forwarded-client-cert.
+func (c *ConsulMesh) ExtractMetadata(ctx context.Context) (*MeshMetadata, error) {
+	metadata := &MeshMetadata{
+		CustomLabels: make(map[string]string),
+	}
+
+	// Extract trace headers
+	metadata.RequestID = ExtractHeader(ctx, HeaderKeys.XRequestID)
+	metadata.TraceID = ExtractHeader(ctx, HeaderKeys.XB3TraceID)
+	metadata.SpanID = ExtractHeader(ctx, HeaderKeys.XB3SpanID)
+	metadata.ParentSpanID = ExtractHeader(ctx, HeaderKeys.XB3ParentSpanID)
+
+	// Extract the source service from the forwarded client certificate
+	if xfcc := ExtractHeader(ctx, HeaderKeys.XForwardedClientCert); xfcc != "" {
+		if id, err := ParseConnectID(xfccURI(xfcc)); err == nil {
+			metadata.SourceWorkload = id.Service
+			metadata.SourceNamespace = id.Namespace
+			metadata.CustomLabels["datacenter"] = id.Datacenter
+		}
+	}
+
+	metadata.DestinationWorkload = c.config.ServiceName
+	metadata.DestinationNamespace = c.config.Namespace
+
+	// Extract custom headers
+	for _, header := range c.config.CustomHeaders {
+		value := ExtractHeader(ctx, header)
+		if value != "" {
+			metadata.CustomLabels[header] = value
+		}
+	}
+
+	return metadata, nil
+}
+
+// InjectMetadata injects Consul Connect metadata into gRPC context
+func (c *ConsulMesh) InjectMetadata(ctx context.Context, metadata *MeshMetadata) context.Context {
+	headers := make(map[string]string)
+
+	// Inject trace context (B3 propagation, as configured for Envoy by Consul)
+	if metadata.TraceID != "" {
+		headers[HeaderKeys.XB3TraceID] = metadata.TraceID
+	}
+	if metadata.SpanID != "" {
+		headers[HeaderKeys.XB3SpanID] = metadata.SpanID
+	}
+	if metadata.ParentSpanID != "" {
+		headers[HeaderKeys.XB3ParentSpanID] = metadata.ParentSpanID
+	}
+
+	// Inject request ID
+	if metadata.RequestID != "" {
+		headers[HeaderKeys.XRequestID] = metadata.RequestID
+	}
+
+	// Inject custom labels, except the ones derived from the certificate
+	for key, value := range metadata.CustomLabels {
+		if key != "datacenter" {
+			headers[key] = value
+		}
+	}
+
+	return InjectHeaders(ctx, headers)
+}
+
+// ValidateMTLS validates the Connect leaf certificate of the peer
+func (c *ConsulMesh) ValidateMTLS(ctx context.Context) error {
+	if !c.config.EnableMTLS {
+		return nil // mTLS not enabled
+	}
+
+	// Extract peer information
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return errors.New("no peer information in context")
+	}
+
+	// Check TLS auth info
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok {
+		return errors.New("peer not using TLS")
+	}
+
+	// Validate certificate chains
+	if len(tlsInfo.State.VerifiedChains) == 0 {
+		return errors.New("no verified certificate chains")
+	}
+
+	// Only the leaf carries the service identity; the rest of the chain is the Connect CA
+	for _, chain := range tlsInfo.State.VerifiedChains {
+		if len(chain) == 0 {
+			continue
+		}
+		if err := c.ValidateCertificate(chain[0]); err != nil {
+			return fmt.Errorf("Consul Connect identity validation failed: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// ValidateCertificate validates the Connect service identity of a leaf certificate
+func (c *ConsulMesh) ValidateCertificate(cert *x509.Certificate) error {
+	for _, uri := range cert.URIs {
+		id, err := ParseConnectID(uri.String())
+		if err != nil {
+			continue
+		}
+
+		if c.trustDomain != "" && id.TrustDomain != c.trustDomain {
+			return fmt.Errorf("trust domain mismatch: expected %s, got %s", c.trustDomain, id.TrustDomain)
+		}
+		if c.config.Namespace != "" && id.Namespace != c.config.Namespace {
+			return fmt.Errorf("namespace mismatch: expected %s, got %s", c.config.Namespace, id.Namespace)
+		}
+		if c.datacenter != "" && id.Datacenter != c.datacenter {
+			return fmt.Errorf("datacenter mismatch: expected %s, got %s", c.datacenter, id.Datacenter)
+		}
+		return nil
+	}
+
+	return errors.New("no valid Consul Connect service identity found in certificate")
+}
+
+// consulServiceEntry is an entry of the /v1/health/service response
+type consulServiceEntry struct {
+	Node struct {
+		Address string
+	}
+	Service struct {
+		Address string
+		Port    int
+	}
+}
+
+// GetServiceEndpoints returns the address of every healthy instance of a service from the
+// Consul health API
+func (c *ConsulMesh) GetServiceEndpoints(serviceName string) ([]string, error) {
+	query := url.Values{"passing": {"true"}}
+
+	var entries []consulServiceEntry
+	if _, err := c.get("/v1/health/service/"+url.PathEscape(serviceName), query, &entries); err != nil {
+		return nil, err
+	}
+
+	endpoints := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		// Services registered without an address use the address of their node
+		host := entry.Service.Address
+		if host == "" {
+			host = entry.Node.Address
+		}
+		endpoints = append(endpoints, net.JoinHostPort(host, strconv.Itoa(entry.Service.Port)))
+	}
+	return endpoints, nil
+}
+
+// ReportMetrics reports metrics to Consul
+func (c *ConsulMesh) ReportMetrics(ctx context.Context, metrics *Metrics) error {
+	// The Envoy sidecar collects request metrics; Consul itself does not accept them
+	return nil
+}
+
+// ShouldRetry determines if a request should be retried based on Consul Connect policy
+func (c *ConsulMesh) ShouldRetry(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	errStr := strings.ToLower(err.Error())
+
+	// Common retryable errors behind an Envoy sidecar
+	retryableErrors := []string{
+		"unavailable",
+		"deadline exceeded",
+		"connect: connection refused",
+		"connection reset",
+		"upstream connect error",
+	}
+
+	for _, retryable := range retryableErrors {
+		if strings.Contains(errStr, retryable) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// consulServiceSplitter is a service-splitter config entry
+type consulServiceSplitter struct {
+	Splits []struct {
+		Weight        float64
+		Service       string
+		ServiceSubset string
+	}
+}
+
+// GetTrafficSplit returns traffic split configuration from the service-splitter config
+// entry of a service; services without one send all traffic to themselves
+func (c *ConsulMesh) GetTrafficSplit(serviceName string) (*TrafficSplit, error) {
+	if !c.config.EnableTrafficSplitting {
+		return nil, errors.New("traffic splitting not enabled")
+	}
+
+	var splitter consulServiceSplitter
+	found, err := c.get("/v1/config/service-splitter/"+url.PathEscape(serviceName), nil, &splitter)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return &TrafficSplit{
+			Routes: []Route{{Destination: serviceName, Weight: 100, Priority: 1}},
+		}, nil
+	}
+
+	split := &TrafficSplit{}
+	for _, s := range splitter.Splits {
+		destination := s.Service
+		if destination == "" {
+			destination = serviceName
+		}
+		if s.ServiceSubset != "" {
+			destination += "-" + s.ServiceSubset
+		}
+		split.Routes = append(split.Routes, Route{
+			Destination: destination,
+			Weight:      int(math.Round(s.Weight)),
+			Priority:    1,
+		})
+	}
+	return split, nil
+}
+
+// GetConfig returns the Consul configuration
+func (c *ConsulMesh) GetConfig() *Config {
+	return c.config
+}
+
+// get queries the Consul HTTP API and decodes the JSON response into out; a 404 response
+// reports found as false
+func (c *ConsulMesh) get(path string, query url.Values, out interface{}) (found bool, err error) {
+	timeout := c.config.Timeout
+	if timeout <= 0 {
+		timeout = defaultConsulTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if query == nil {
+		query = url.Values{}
+	}
+	if c.datacenter != "" {
+		query.Set("dc", c.datacenter)
+	}
+	if c.config.Namespace != "" {
+		query.Set("ns", c.config.Namespace)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.address+path+"?"+query.Encode(), nil)
+	if err != nil {
+		return false, err
+	}
+	if c.token != "" {
+		req.Header.Set("X-Consul-Token", c.token)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("consul request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return false, nil
+	case resp.StatusCode != http.StatusOK:
+		return false, fmt.Errorf("consul request failed: %s %s", path, resp.Status)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return false, fmt.Errorf("invalid consul response: %w", err)
+	}
+	return true, nil
+}
+
+// ConnectID is a Consul Connect service identity:
+// spiffe://<trust-domain>[/ap/<partition>]/ns/<namespace>/dc/<datacenter>/svc/<service>
+type ConnectID struct {
+	TrustDomain string
+	Partition   string
+	Namespace   string
+	Datacenter  string
+	Service     string
+}
+
+// ParseConnectID parses the SPIFFE URI of a Consul Connect service
+func ParseConnectID(uri string) (ConnectID, error) {
+	rest, ok := strings.CutPrefix(uri, "spiffe://")
+	if !ok {
+		return ConnectID{}, fmt.Errorf("not a SPIFFE ID: %q", uri)
+	}
+
+	parts := strings.Split(rest, "/")
+	id := ConnectID{TrustDomain: parts[0]}
+	parts = parts[1:]
+
+	// Segments come in key/value pairs
+	if len(parts)%2 != 0 {
+		return ConnectID{}, fmt.Errorf("invalid Consul Connect ID: %q", uri)
+	}
+	for i := 0; i < len(parts); i += 2 {
+		switch parts[i] {
+		case "ap":
+			id.Partition = parts[i+1]
+		case "ns":
+			id.Namespace = parts[i+1]
+		case "dc":
+			id.Datacenter = parts[i+1]
+		case "svc":
+			id.Service = parts[i+1]
+		default:
+			return ConnectID{}, fmt.Errorf("invalid Consul Connect ID: %q", uri)
+		}
+	}
+
+	if id.TrustDomain == "" || id.Namespace == "" || id.Datacenter == "" || id.Service == "" {
+		return ConnectID{}, fmt.Errorf("invalid Consul Connect service ID: %q", uri)
+	}
+	return id, nil
+}
+
+// xfccURI returns the URI of the client certificate from an x-forwarded-client-cert
+// header, e.g. "By=spiffe://...;URI=spiffe://.../svc/web"
+func xfccURI(xfcc string) string {
+	// Multiple proxies append comma separated elements; the last one is the direct caller
+	elements := strings.Split(xfcc, ",")
+	for _, field := range strings.Split(elements[len(elements)-1], ";") {
+		if value, ok := strings.CutPrefix(strings.TrimSpace(field), "URI="); ok {
+			return strings.Trim(value, `"`)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,102 @@
+package servicemesh
+
+import (
+	"context"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"google.golang.org/grpc/metadata"
+)
+
+func TestConsulMesh_Endpoints(t *testing.T) {
+	var query url.Values
+	var token string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query, token = r.URL.Query(), r.Header.Get("X-Consul-Token")
+		switch r.URL.Path {
+		case "/v1/health/service/payments":
+			w.Write([]byte(`[
+				{"Node": {"Address": "10.0.0.1"}, "Service": {"Address": "10.0.1.5", "Port": 8080}},
+				{"Node": {"Address": "10.0.0.2"}, "Service": {"Address": "", "Port": 9090}}
+			]`))
+		case "/v1/config/service-splitter/payments":
+			w.Write([]byte(`{"Splits": [{"Weight": 90, "ServiceSubset": "v1"}, {"Weight": 10, "ServiceSubset": "v2"}]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	mesh, err := NewConsulMesh(
+		&Config{ServiceName: "orders", EnableTrafficSplitting: true},
+		WithConsulAddress(server.URL),
+		WithConsulToken("secret"),
+		WithConsulDatacenter("dc1"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	endpoints, err := mesh.GetServiceEndpoints("payments")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(endpoints) != 2 || endpoints[0] != "10.0.1.5:8080" || endpoints[1] != "10.0.0.2:9090" {
+		t.Errorf("Unexpected endpoints: %v", endpoints)
+	}
+	if query.Get("passing") != "true" || query.Get("dc") != "dc1" || token != "secret" {
+		t.Errorf("Expected a token-authenticated query for passing instances in dc1, got %v (token %q)", query, token)
+	}
+
+	split, err := mesh.GetTrafficSplit("payments")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(split.Routes) != 2 || split.Routes[0].Destination != "payments-v1" || split.Routes[1].Weight != 10 {
+		t.Errorf("Unexpected traffic split: %+v", split.Routes)
+	}
+
+	split, err = mesh.GetTrafficSplit("inventory")
+	if err != nil || len(split.Routes) != 1 || split.Routes[0].Weight != 100 {
+		t.Errorf("Expected services without a splitter to get all traffic, got %+v (%v)", split, err)
+	}
+}
+
+func TestConsulMesh_Identity(t *testing.T) {
+	mesh, err := NewConsulMesh(
+		&Config{ServiceName: "orders", Namespace: "default"},
+		WithConsulTrustDomain("11111111-2222.consul"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for uri, valid := range map[string]bool{
+		"spiffe://11111111-2222.consul/ns/default/dc/dc1/svc/web":         true,
+		"spiffe://11111111-2222.consul/ap/team/ns/default/dc/dc1/svc/web": true,
+		"spiffe://11111111-2222.consul/ns/billing/dc/dc1/svc/web":         false,
+		"spiffe://other.consul/ns/default/dc/dc1/svc/web":                 false,
+		"spiffe://cluster.local/ns/default/sa/web":                        false,
+	} {
+		parsed, _ := url.Parse(uri)
+		err := mesh.ValidateCertificate(&x509.Certificate{URIs: []*url.URL{parsed}})
+		if (err == nil) != valid {
+			t.Errorf("%s: valid = %v, want %v (%v)", uri, err == nil, valid, err)
+		}
+	}
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+		"x-b3-traceid", "abc",
+		"x-forwarded-client-cert", `By=spiffe://11111111-2222.consul/ns/default/dc/dc1/svc/orders;URI=spiffe://11111111-2222.consul/ns/default/dc/dc2/svc/web`,
+	))
+	meta, err := mesh.ExtractMetadata(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.TraceID != "abc" || meta.SourceWorkload != "web" || meta.CustomLabels["datacenter"] != "dc2" {
+		t.Errorf("Unexpected metadata: %+v", meta)
+	}
+}
@@ -0,0 +1,482 @@
+package servicemesh
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grpc-guardian/grpc-guardian/pkg/spiffe"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// EnvoyMesh implements ServiceMesh interface for any mesh built on Envoy sidecars, such as
+// Kuma, Kong Mesh, Open Service Mesh or a hand-rolled Envoy setup
+type EnvoyMesh struct {
+	config      *Config
+	xds         *XDSClient
+	trustDomain string
+	retryOn     []string
+}
+
+// EnvoyOption configures the Envoy integration
+type EnvoyOption func(*EnvoyMesh)
+
+// WithEnvoyXDS discovers service endpoints through an xDS management server; services
+// are looked up as clusters of the same name
+func WithEnvoyXDS(client *XDSClient) EnvoyOption {
+	return func(e *EnvoyMesh) {
+		e.xds = client
+	}
+}
+
+// WithEnvoyTrustDomain only accepts certificates with a SPIFFE ID in the trust domain
+// (for Kuma: the mesh name)
+func WithEnvoyTrustDomain(trustDomain string) EnvoyOption {
+	return func(e *EnvoyMesh) {
+		e.trustDomain = trustDomain
+	}
+}
+
+// WithEnvoyRetryOn sets the gRPC retry conditions, in x-envoy-retry-grpc-on syntax: they are
+// sent to the sidecar on outgoing requests and used by ShouldRetry
+// Default: unavailable, deadline-exceeded
+func WithEnvoyRetryOn(conditions ...string) EnvoyOption {
+	return func(e *EnvoyMesh) {
+		e.retryOn = conditions
+	}
+}
+
+// NewEnvoyMesh creates a new integration for a mesh built on Envoy sidecars
+//
+// Example usage:
+//
+//	xds := servicemesh.NewXDSClient("http://control-plane:5678", "orders-1")
+//	go xds.Run(ctx)
+//	mesh, err := servicemesh.NewEnvoyMesh(&servicemesh.Config{ServiceName: "orders"},
+//	    servicemesh.WithEnvoyXDS(xds),
+//	)
+func NewEnvoyMesh(config *Config, opts ...EnvoyOption) (*EnvoyMesh, error) {
+	if config == nil {
+		return nil, errors.New("config cannot be nil")
+	}
+
+	if config.Provider != ProviderKuma {
+		config.Provider = ProviderEnvoy
+	}
+
+	e := &EnvoyMesh{
+		config:  config,
+		retryOn: []string{"unavailable", "deadline-exceeded"},
+	}
+
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	return e, nil
+}
+
+// NewKumaMesh creates a new Kuma service mesh integration. On top of the Envoy headers it
+// reads the source service and tags from x-kuma-tags.
+func NewKumaMesh(config *Config, opts ...EnvoyOption) (*EnvoyMesh, error) {
+	if config == nil {
+		return nil, errors.New("config cannot be nil")
+	}
+
+	config.Provider = ProviderKuma
+	return NewEnvoyMesh(config, opts...)
+}
+
+// ExtractMetadata extracts Envoy metadata from gRPC context
+func (e *EnvoyMesh) ExtractMetadata(ctx context.Context) (*MeshMetadata, error) {
+	metadata := &MeshMetadata{
+		CustomLabels: make(map[string]string),
+	}
+
+	// Extract trace headers
+	metadata.RequestID = ExtractHeader(ctx, HeaderKeys.XRequestID)
+	metadata.TraceID = ExtractHeader(ctx, HeaderKeys.XB3TraceID)
+	metadata.SpanID = ExtractHeader(ctx, HeaderKeys.XB3SpanID)
+	metadata.ParentSpanID = ExtractHeader(ctx, HeaderKeys.XB3ParentSpanID)
+
+	// Extract the source workload from the peer metadata exchanged by the sidecars
+	if encoded := ExtractHeader(ctx, HeaderKeys.EnvoyPeerMetadata); encoded != "" {
+		if peerMeta, err := ParsePeerMetadata(encoded); err == nil {
+			metadata.SourceWorkload = peerMeta.WorkloadName
+			metadata.SourceNamespace = peerMeta.Namespace
+			metadata.ServiceVersion = peerMeta.Version()
+			for k, v := range peerMeta.Labels {
+				metadata.CustomLabels[k] = v
+			}
+		}
+	}
+
+	// Kuma sends the tags of the source data plane proxy instead
+	if e.config.Provider == ProviderKuma {
+		if tags := ParseKumaTags(ExtractHeader(ctx, HeaderKeys.KumaTags)); len(tags) > 0 {
+			metadata.SourceWorkload = tags["kuma.io/service"]
+			metadata.ServiceVersion = tags["version"]
+			for k, v := range tags {
+				metadata.CustomLabels[k] = v
+			}
+		}
+	}
+
+	metadata.DestinationWorkload = e.config.ServiceName
+	metadata.DestinationNamespace = e.config.Namespace
+
+	// Extract custom headers
+	for _, header := range e.config.CustomHeaders {
+		value := ExtractHeader(ctx, header)
+		if value != "" {
+			metadata.CustomLabels[header] = value
+		}
+	}
+
+	return metadata, nil
+}
+
+// InjectMetadata injects Envoy metadata into gRPC context. The remaining deadline is sent
+// as the upstream timeout, and the retry conditions as x-envoy-retry-grpc-on.
+func (e *EnvoyMesh) InjectMetadata(ctx context.Context, metadata *MeshMetadata) context.Context {
+	headers := make(map[string]string)
+
+	// Inject trace context (B3 propagation)
+	if metadata.TraceID != "" {
+		headers[HeaderKeys.XB3TraceID] = metadata.TraceID
+	}
+	if metadata.SpanID != "" {
+		headers[HeaderKeys.XB3SpanID] = metadata.SpanID
+	}
+	if metadata.ParentSpanID != "" {
+		headers[HeaderKeys.XB3ParentSpanID] = metadata.ParentSpanID
+	}
+
+	// Inject request ID
+	if metadata.RequestID != "" {
+		headers[HeaderKeys.XRequestID] = metadata.RequestID
+	}
+
+	// Inject routing headers for the sidecar
+	if deadline, ok := ctx.Deadline(); ok {
+		if remaining := time.Until(deadline); remaining > 0 {
+			headers[HeaderKeys.EnvoyUpstreamTimeout] = strconv.FormatInt(remaining.Milliseconds(), 10)
+		}
+	}
+	if len(e.retryOn) > 0 {
+		headers[HeaderKeys.EnvoyRetryGRPCOn] = strings.Join(e.retryOn, ",")
+	}
+
+	// Inject custom headers only; the other labels describe the source proxy
+	for _, header := range e.config.CustomHeaders {
+		if value, ok := metadata.CustomLabels[header]; ok {
+			headers[header] = value
+		}
+	}
+
+	return InjectHeaders(ctx, headers)
+}
+
+// ValidateMTLS validates the SPIFFE ID of the peer's leaf certificate
+func (e *EnvoyMesh) ValidateMTLS(ctx context.Context) error {
+	if !e.config.EnableMTLS {
+		return nil // mTLS not enabled
+	}
+
+	// Extract peer information
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return errors.New("no peer information in context")
+	}
+
+	// Check TLS auth info
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok {
+		return errors.New("peer not using TLS")
+	}
+
+	// Validate certificate chains
+	if len(tlsInfo.State.VerifiedChains) == 0 {
+		return errors.New("no verified certificate chains")
+	}
+
+	for _, chain := range tlsInfo.State.VerifiedChains {
+		if len(chain) == 0 {
+			continue
+		}
+		if err := e.validateSPIFFEID(chain[0]); err != nil {
+			return fmt.Errorf("SPIFFE ID validation failed: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// validateSPIFFEID validates the SPIFFE ID in a leaf certificate
+func (e *EnvoyMesh) validateSPIFFEID(cert *x509.Certificate) error {
+	id, err := spiffe.IDFromCert(cert)
+	if err != nil {
+		return err
+	}
+	if e.trustDomain != "" && id.TrustDomain != e.trustDomain {
+		return fmt.Errorf("trust domain mismatch: expected %s, got %s", e.trustDomain, id.TrustDomain)
+	}
+	return nil
+}
+
+// GetServiceEndpoints returns the healthy endpoints of a service from the xDS server
+func (e *EnvoyMesh) GetServiceEndpoints(serviceName string) ([]string, error) {
+	if e.xds == nil {
+		return nil, errors.New("service discovery not configured: use WithEnvoyXDS")
+	}
+
+	timeout := e.config.Timeout
+	if timeout <= 0 {
+		timeout = defaultXDSTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	return e.xds.Endpoints(ctx, serviceName)
+}
+
+// ReportMetrics reports metrics to the mesh
+func (e *EnvoyMesh) ReportMetrics(ctx context.Context, metrics *Metrics) error {
+	// The Envoy sidecar collects request metrics and exposes them to the mesh
+	return nil
+}
+
+// ShouldRetry reports whether the status code of err is one of the retry conditions
+func (e *EnvoyMesh) ShouldRetry(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	st, ok := status.FromError(err)
+	if !ok {
+		// Connection level failures, which Envoy retries on "connect-failure" and "reset"
+		errStr := strings.ToLower(err.Error())
+		return strings.Contains(errStr, "connection refused") || strings.Contains(errStr, "connection reset")
+	}
+
+	condition := envoyRetryConditions[st.Code()]
+	for _, retryOn := range e.retryOn {
+		if condition != "" && retryOn == condition {
+			return true
+		}
+	}
+	return false
+}
+
+// envoyRetryConditions maps gRPC codes to x-envoy-retry-grpc-on conditions
+var envoyRetryConditions = map[codes.Code]string{
+	codes.Canceled:          "cancelled",
+	codes.DeadlineExceeded:  "deadline-exceeded",
+	codes.Internal:          "internal",
+	codes.ResourceExhausted: "resource-exhausted",
+	codes.Unavailable:       "unavailable",
+}
+
+// GetTrafficSplit returns traffic split configuration. Envoy meshes split traffic in the
+// control plane, so all traffic is routed to the service.
+func (e *EnvoyMesh) GetTrafficSplit(serviceName string) (*TrafficSplit, error) {
+	if !e.config.EnableTrafficSplitting {
+		return nil, errors.New("traffic splitting not enabled")
+	}
+
+	return &TrafficSplit{
+		Routes: []Route{
+			{
+				Destination: serviceName,
+				Weight:      100,
+				Priority:    1,
+			},
+		},
+	}, nil
+}
+
+// GetConfig returns the Envoy configuration
+func (e *EnvoyMesh) GetConfig() *Config {
+	return e.config
+}
+
+// EnvoyRequest holds the routing headers Envoy adds to a request
+type EnvoyRequest struct {
+	RetryOn         []string      // x-envoy-retry-on
+	GRPCRetryOn     []string      // x-envoy-retry-grpc-on
+	MaxRetries      int           // x-envoy-max-retries (0: not set)
+	UpstreamTimeout time.Duration // x-envoy-upstream-rq-timeout-ms
+	PerTryTimeout   time.Duration // x-envoy-upstream-rq-per-try-timeout-ms
+	ExpectedTimeout time.Duration // x-envoy-expected-rq-timeout-ms: how long the caller's proxy waits
+	OriginalPath    string        // x-envoy-original-path: the path before a route rewrite
+	Attempt         int           // x-envoy-attempt-count: 1 for the first try (0: not set)
+}
+
+// ExtractEnvoyRequest extracts the Envoy routing headers from gRPC context
+func ExtractEnvoyRequest(ctx context.Context) *EnvoyRequest {
+	return &EnvoyRequest{
+		RetryOn:         splitList(ExtractHeader(ctx, HeaderKeys.EnvoyRetryOn)),
+		GRPCRetryOn:     splitList(ExtractHeader(ctx, HeaderKeys.EnvoyRetryGRPCOn)),
+		MaxRetries:      headerInt(ctx, HeaderKeys.EnvoyMaxRetries),
+		UpstreamTimeout: time.Duration(headerInt(ctx, HeaderKeys.EnvoyUpstreamTimeout)) * time.Millisecond,
+		PerTryTimeout:   time.Duration(headerInt(ctx, HeaderKeys.EnvoyPerTryTimeout)) * time.Millisecond,
+		ExpectedTimeout: time.Duration(headerInt(ctx, HeaderKeys.EnvoyExpectedTimeout)) * time.Millisecond,
+		OriginalPath:    ExtractHeader(ctx, HeaderKeys.EnvoyOriginalPath),
+		Attempt:         headerInt(ctx, HeaderKeys.EnvoyAttemptCount),
+	}
+}
+
+// Retry reports whether this is a retried attempt
+func (r *EnvoyRequest) Retry() bool {
+	return r.Attempt > 1
+}
+
+// PeerMetadata is the workload metadata Envoy sidecars exchange in x-envoy-peer-metadata
+type PeerMetadata struct {
+	Name             string            // NAME: the pod or instance name
+	Namespace        string            // NAMESPACE
+	WorkloadName     string            // WORKLOAD_NAME
+	ServiceAccount   string            // SERVICE_ACCOUNT
+	Owner            string            // OWNER: the controlling resource
+	ClusterID        string            // CLUSTER_ID
+	MeshID           string            // MESH_ID
+	ProxyVersion     string            // ISTIO_VERSION
+	InstanceIPs      []string          // INSTANCE_IPS
+	AppContainers    []string          // APP_CONTAINERS
+	Labels           map[string]string // LABELS
+	PlatformMetadata map[string]string // PLATFORM_METADATA
+	Fields           map[string]interface{}
+}
+
+// Version returns the workload version from its labels
+func (m *PeerMetadata) Version() string {
+	for _, key := range []string{"service.istio.io/canonical-revision", "app.kubernetes.io/version", "version"} {
+		if v := m.Labels[key]; v != "" {
+			return v
+		}
+	}
+	if v, ok := m.Fields["VERSION"].(string); ok {
+		return v
+	}
+	return ""
+}
+
+// ParsePeerMetadata parses an x-envoy-peer-metadata header: a base64 encoded
+// google.protobuf.Struct, in binary or JSON form. Fields holds every field, including
+// the ones without a dedicated field in PeerMetadata.
+func ParsePeerMetadata(encoded string) (*PeerMetadata, error) {
+	decoded, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		if decoded, err = base64.RawStdEncoding.DecodeString(encoded); err != nil {
+			return nil, fmt.Errorf("invalid peer metadata encoding: %w", err)
+		}
+	}
+
+	var fields map[string]interface{}
+	if trimmed := strings.TrimSpace(string(decoded)); strings.HasPrefix(trimmed, "{") {
+		if err := json.Unmarshal(decoded, &fields); err != nil {
+			return nil, fmt.Errorf("invalid peer metadata: %w", err)
+		}
+	} else {
+		var st structpb.Struct
+		if err := proto.Unmarshal(decoded, &st); err != nil {
+			return nil, fmt.Errorf("invalid peer metadata: %w", err)
+		}
+		fields = st.AsMap()
+	}
+
+	m := &PeerMetadata{
+		Labels:           stringMap(fields["LABELS"]),
+		PlatformMetadata: stringMap(fields["PLATFORM_METADATA"]),
+		Fields:           fields,
+	}
+	m.Name, _ = fields["NAME"].(string)
+	m.Namespace, _ = fields["NAMESPACE"].(string)
+	m.WorkloadName, _ = fields["WORKLOAD_NAME"].(string)
+	m.ServiceAccount, _ = fields["SERVICE_ACCOUNT"].(string)
+	m.Owner, _ = fields["OWNER"].(string)
+	m.ClusterID, _ = fields["CLUSTER_ID"].(string)
+	m.MeshID, _ = fields["MESH_ID"].(string)
+	m.ProxyVersion, _ = fields["ISTIO_VERSION"].(string)
+	m.InstanceIPs = stringList(fields["INSTANCE_IPS"])
+	m.AppContainers = stringList(fields["APP_CONTAINERS"])
+
+	return m, nil
+}
+
+// ParseKumaTags parses an x-kuma-tags header, e.g. "&kuma.io/service=web&&version=v1&"
+func ParseKumaTags(header string) map[string]string {
+	tags := make(map[string]string)
+	for _, tag := range strings.Split(header, "&") {
+		if key, value, ok := strings.Cut(tag, "="); ok && key != "" {
+			tags[key] = value
+		}
+	}
+	return tags
+}
+
+// stringMap converts a decoded struct field into a map of its string values
+func stringMap(v interface{}) map[string]string {
+	fields, ok := v.(map[string]interface{})
+	if !ok {
+		return map[string]string{}
+	}
+
+	m := make(map[string]string, len(fields))
+	for k, v := range fields {
+		if s, ok := v.(string); ok {
+			m[k] = s
+		}
+	}
+	return m
+}
+
+// stringList converts a decoded struct field, a list or a comma separated string, into a
+// list of strings
+func stringList(v interface{}) []string {
+	switch v := v.(type) {
+	case string:
+		return splitList(v)
+	case []interface{}:
+		list := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				list = append(list, s)
+			}
+		}
+		return list
+	}
+	return nil
+}
+
+// splitList splits a comma separated header value
+func splitList(value string) []string {
+	if value == "" {
+		return nil
+	}
+
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// headerInt returns the integer value of a header, or 0 when missing or invalid
+func headerInt(ctx context.Context, key string) int {
+	n, _ := strconv.Atoi(ExtractHeader(ctx, key))
+	return n
+}
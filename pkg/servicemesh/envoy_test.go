@@ -0,0 +1,57 @@
+package servicemesh
+
+import (
+	"context"
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestEnvoyMesh_Headers(t *testing.T) {
+	mesh, err := NewKumaMesh(&Config{ServiceName: "orders"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	peer := base64.StdEncoding.EncodeToString([]byte(`{
+		"NAME": "web-7d9f", "NAMESPACE": "shop", "WORKLOAD_NAME": "web",
+		"INSTANCE_IPS": "10.0.0.7,fd00::7", "LABELS": {"app": "web", "version": "v3"}
+	}`))
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+		"x-envoy-peer-metadata", peer,
+		"x-envoy-retry-grpc-on", "unavailable, cancelled",
+		"x-envoy-upstream-rq-timeout-ms", "1500",
+		"x-envoy-original-path", "/shop.Orders/Get",
+		"x-envoy-attempt-count", "2",
+		"x-kuma-tags", "&kuma.io/service=web_shop_svc_80&&version=v4&",
+	))
+
+	meta, err := mesh.ExtractMetadata(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.SourceWorkload != "web_shop_svc_80" || meta.SourceNamespace != "shop" || meta.ServiceVersion != "v4" {
+		t.Errorf("Expected Kuma tags to override the peer metadata, got %+v", meta)
+	}
+
+	peerMeta, err := ParsePeerMetadata(peer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if peerMeta.Name != "web-7d9f" || len(peerMeta.InstanceIPs) != 2 || peerMeta.Version() != "v3" {
+		t.Errorf("Unexpected peer metadata: %+v", peerMeta)
+	}
+
+	req := ExtractEnvoyRequest(ctx)
+	if len(req.GRPCRetryOn) != 2 || req.UpstreamTimeout != 1500*time.Millisecond || !req.Retry() || req.OriginalPath != "/shop.Orders/Get" {
+		t.Errorf("Unexpected Envoy request: %+v", req)
+	}
+
+	if !mesh.ShouldRetry(status.Error(codes.Unavailable, "down")) || mesh.ShouldRetry(status.Error(codes.Internal, "bug")) {
+		t.Error("Expected only the default retry conditions to be retried")
+	}
+}
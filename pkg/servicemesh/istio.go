@@ -3,32 +3,55 @@ package servicemesh
 import (
 	"context"
 	"crypto/x509"
-	"encoding/base64"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"strings"
 
+	"github.com/grpc-guardian/grpc-guardian/pkg/spiffe"
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/peer"
 )
 
+// TrafficSplitReader reads the traffic split of a service from the mesh configuration.
+// IstioTrafficReader implements it when built with the istio tag.
+type TrafficSplitReader interface {
+	TrafficSplit(serviceName string) (*TrafficSplit, error)
+}
+
 // IstioMesh implements ServiceMesh interface for Istio
 type IstioMesh struct {
-	config *Config
+	config  *Config
+	traffic TrafficSplitReader
+}
+
+// IstioOption configures the Istio integration
+type IstioOption func(*IstioMesh)
+
+// WithIstioTrafficReader reads traffic splits through the reader, e.g. an
+// IstioTrafficReader watching VirtualService and DestinationRule resources
+func WithIstioTrafficReader(reader TrafficSplitReader) IstioOption {
+	return func(i *IstioMesh) {
+		i.traffic = reader
+	}
 }
 
 // NewIstioMesh creates a new Istio service mesh integration
-func NewIstioMesh(config *Config) (*IstioMesh, error) {
+func NewIstioMesh(config *Config, opts ...IstioOption) (*IstioMesh, error) {
 	if config == nil {
 		return nil, errors.New("config cannot be nil")
 	}
 
 	config.Provider = ProviderIstio
 
-	return &IstioMesh{
+	i := &IstioMesh{
 		config: config,
-	}, nil
+	}
+
+	for _, opt := range opts {
+		opt(i)
+	}
+
+	return i, nil
 }
 
 // ExtractMetadata extracts Istio metadata from gRPC context
@@ -42,6 +65,10 @@ func (i *IstioMesh) ExtractMetadata(ctx context.Context) (*MeshMetadata, error)
 	metadata.TraceID = ExtractHeader(ctx, HeaderKeys.IstioTraceID)
 	metadata.SpanID = ExtractHeader(ctx, HeaderKeys.IstioSpanID)
 	metadata.ParentSpanID = ExtractHeader(ctx, HeaderKeys.IstioParentSpanID)
+	metadata.Sampled = ExtractHeader(ctx, HeaderKeys.XB3Sampled)
+
+	// Fall back to W3C trace context from OpenTelemetry callers
+	extractTraceContext(ctx, metadata)
 
 	// Extract Envoy metadata
 	envoyMeta := ExtractHeader(ctx, HeaderKeys.IstioSourceNamespace)
@@ -65,37 +92,25 @@ func (i *IstioMesh) ExtractMetadata(ctx context.Context) (*MeshMetadata, error)
 
 // parseEnvoyMetadata parses Envoy peer metadata
 func (i *IstioMesh) parseEnvoyMetadata(encodedMeta string, metadata *MeshMetadata) error {
-	// Envoy metadata is base64 encoded JSON
-	decoded, err := base64.StdEncoding.DecodeString(encodedMeta)
+	peerMeta, err := ParsePeerMetadata(encodedMeta)
 	if err != nil {
 		return err
 	}
 
-	var envoyMeta map[string]interface{}
-	if err := json.Unmarshal(decoded, &envoyMeta); err != nil {
-		return err
-	}
-
 	// Extract workload information
-	if workload, ok := envoyMeta["WORKLOAD_NAME"].(string); ok {
-		metadata.SourceWorkload = workload
+	if peerMeta.WorkloadName != "" {
+		metadata.SourceWorkload = peerMeta.WorkloadName
 	}
-
-	if namespace, ok := envoyMeta["NAMESPACE"].(string); ok {
-		metadata.SourceNamespace = namespace
+	if peerMeta.Namespace != "" {
+		metadata.SourceNamespace = peerMeta.Namespace
 	}
-
-	if version, ok := envoyMeta["VERSION"].(string); ok {
+	if version := peerMeta.Version(); version != "" {
 		metadata.ServiceVersion = version
 	}
 
 	// Extract labels
-	if labels, ok := envoyMeta["LABELS"].(map[string]interface{}); ok {
-		for k, v := range labels {
-			if str, ok := v.(string); ok {
-				metadata.CustomLabels[k] = str
-			}
-		}
+	for k, v := range peerMeta.Labels {
+		metadata.CustomLabels[k] = v
 	}
 
 	return nil
@@ -125,7 +140,13 @@ func (i *IstioMesh) InjectMetadata(ctx context.Context, metadata *MeshMetadata)
 	if metadata.ParentSpanID != "" {
 		headers[HeaderKeys.XB3ParentSpanID] = metadata.ParentSpanID
 	}
-	headers[HeaderKeys.XB3Sampled] = "1" // Always sample for now
+	headers[HeaderKeys.XB3Sampled] = "1" // Sample unless the caller decided otherwise
+	if metadata.Sampled != "" {
+		headers[HeaderKeys.XB3Sampled] = metadata.Sampled
+	}
+
+	// Bridge to W3C trace context for OpenTelemetry services
+	injectTraceContext(headers, metadata)
 
 	// Inject request ID
 	if metadata.RequestID != "" {
@@ -164,11 +185,10 @@ func (i *IstioMesh) ValidateMTLS(ctx context.Context) error {
 	}
 
 	// Validate SPIFFE ID (Istio uses SPIFFE for service identity)
+	// Only leaf certificates carry the workload identity
 	for _, chain := range tlsInfo.State.VerifiedChains {
-		for _, cert := range chain {
-			if err := i.validateSPIFFEID(cert); err != nil {
-				return fmt.Errorf("SPIFFE ID validation failed: %w", err)
-			}
+		if err := i.validateSPIFFEID(chain[0]); err != nil {
+			return fmt.Errorf("SPIFFE ID validation failed: %w", err)
 		}
 	}
 
@@ -177,29 +197,19 @@ func (i *IstioMesh) ValidateMTLS(ctx context.Context) error {
 
 // validateSPIFFEID validates SPIFFE ID in certificate
 func (i *IstioMesh) validateSPIFFEID(cert *x509.Certificate) error {
-	// Check for SPIFFE ID in SAN URIs
-	for _, uri := range cert.URIs {
-		if strings.HasPrefix(uri.String(), "spiffe://") {
-			// SPIFFE ID format: spiffe://<trust-domain>/ns/<namespace>/sa/<service-account>
-			parts := strings.Split(uri.String(), "/")
-			if len(parts) >= 7 {
-				namespace := parts[4]
-				serviceAccount := parts[6]
-
-				// Validate namespace if configured
-				if i.config.Namespace != "" && namespace != i.config.Namespace {
-					return fmt.Errorf("namespace mismatch: expected %s, got %s",
-						i.config.Namespace, namespace)
-				}
-
-				// Additional validation can be added here
-				_ = serviceAccount // Use service account for further validation if needed
-			}
-			return nil
-		}
+	// SPIFFE ID format: spiffe://<trust-domain>/ns/<namespace>/sa/<service-account>
+	id, err := spiffe.IDFromCert(cert)
+	if err != nil {
+		return err
+	}
+
+	// Validate namespace if configured
+	if namespace := id.Namespace(); namespace != "" && i.config.Namespace != "" && namespace != i.config.Namespace {
+		return fmt.Errorf("namespace mismatch: expected %s, got %s",
+			i.config.Namespace, namespace)
 	}
 
-	return errors.New("no valid SPIFFE ID found in certificate")
+	return nil
 }
 
 // GetServiceEndpoints returns service endpoints from Istio service registry
@@ -255,27 +265,10 @@ func (i *IstioMesh) GetTrafficSplit(serviceName string) (*TrafficSplit, error) {
 		return nil, errors.New("traffic splitting not enabled")
 	}
 
-	// In a real implementation, this would query Istio's VirtualService configuration
-	// via the Kubernetes API or Istio config API
-	// For now, return a placeholder
-
-	return &TrafficSplit{
-		Routes: []Route{
-			{
-				Destination: serviceName + "-v1",
-				Weight:      90,
-				Priority:    1,
-			},
-			{
-				Destination: serviceName + "-v2",
-				Weight:      10,
-				Headers: map[string]string{
-					"x-canary": "true",
-				},
-				Priority: 2,
-			},
-		},
-	}, nil
+	if i.traffic == nil {
+		return nil, errors.New("traffic split reader not configured: use WithIstioTrafficReader")
+	}
+	return i.traffic.TrafficSplit(serviceName)
 }
 
 // GetConfig returns the Istio configuration
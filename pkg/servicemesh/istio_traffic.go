@@ -0,0 +1,280 @@
+//go:build istio
+
+package servicemesh
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/tools/cache"
+)
+
+// DefaultIstioRefreshInterval is how often cached Istio resources are resynced
+const DefaultIstioRefreshInterval = 5 * time.Minute
+
+// IstioTrafficReader reads traffic splits from VirtualService and DestinationRule
+// resources. Resources are cached by informers, which receive changes as they happen and
+// resync the whole cache every refresh interval.
+//
+// The reader is only built with the istio build tag (go build -tags istio), so services
+// that do not read traffic splits do not link the Kubernetes client.
+type IstioTrafficReader struct {
+	factory          dynamicinformer.DynamicSharedInformerFactory
+	virtualServices  schema.GroupVersionResource
+	destinationRules schema.GroupVersionResource
+	namespace        string
+	refresh          time.Duration
+	apiVersion       string
+}
+
+// IstioTrafficOption configures the Istio traffic reader
+type IstioTrafficOption func(*IstioTrafficReader)
+
+// WithIstioNamespace only reads resources in a namespace
+// Default: all namespaces
+func WithIstioNamespace(namespace string) IstioTrafficOption {
+	return func(r *IstioTrafficReader) {
+		r.namespace = namespace
+	}
+}
+
+// WithIstioRefreshInterval sets how often the cache is resynced
+// Default: 5m
+func WithIstioRefreshInterval(interval time.Duration) IstioTrafficOption {
+	return func(r *IstioTrafficReader) {
+		if interval > 0 {
+			r.refresh = interval
+		}
+	}
+}
+
+// WithIstioAPIVersion sets the version of the networking.istio.io API to read
+// Default: v1beta1
+func WithIstioAPIVersion(version string) IstioTrafficOption {
+	return func(r *IstioTrafficReader) {
+		if version != "" {
+			r.apiVersion = version
+		}
+	}
+}
+
+// NewIstioTrafficReader creates a traffic reader on a Kubernetes dynamic client. Call
+// Start before reading traffic splits.
+//
+// Example usage:
+//
+//	restConfig, _ := rest.InClusterConfig()
+//	client, _ := dynamic.NewForConfig(restConfig)
+//	reader := servicemesh.NewIstioTrafficReader(client, servicemesh.WithIstioNamespace("shop"))
+//	if err := reader.Start(ctx); err != nil {
+//	    log.Fatal(err)
+//	}
+//	mesh, _ := servicemesh.NewIstioMesh(config, servicemesh.WithIstioTrafficReader(reader))
+func NewIstioTrafficReader(client dynamic.Interface, opts ...IstioTrafficOption) *IstioTrafficReader {
+	r := &IstioTrafficReader{
+		refresh:    DefaultIstioRefreshInterval,
+		apiVersion: "v1beta1",
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	r.virtualServices = schema.GroupVersionResource{Group: "networking.istio.io", Version: r.apiVersion, Resource: "virtualservices"}
+	r.destinationRules = schema.GroupVersionResource{Group: "networking.istio.io", Version: r.apiVersion, Resource: "destinationrules"}
+	r.factory = dynamicinformer.NewFilteredDynamicSharedInformerFactory(client, r.refresh, r.namespace, nil)
+
+	// Register the informers before the factory starts
+	r.factory.ForResource(r.virtualServices)
+	r.factory.ForResource(r.destinationRules)
+
+	return r
+}
+
+// Start starts the informers and waits until their caches are filled. The informers stop
+// when ctx is done.
+func (r *IstioTrafficReader) Start(ctx context.Context) error {
+	r.factory.Start(ctx.Done())
+
+	for gvr, synced := range r.factory.WaitForCacheSync(ctx.Done()) {
+		if !synced {
+			return fmt.Errorf("failed to sync %s cache", gvr.Resource)
+		}
+	}
+	return nil
+}
+
+// istioVirtualService holds the fields of a VirtualService the reader uses
+type istioVirtualService struct {
+	Spec struct {
+		Hosts []string `json:"hosts"`
+		HTTP  []struct {
+			Match []struct {
+				Headers map[string]struct {
+					Exact string `json:"exact"`
+				} `json:"headers"`
+			} `json:"match"`
+			Route []struct {
+				Destination istioDestination `json:"destination"`
+				Weight      int              `json:"weight"`
+			} `json:"route"`
+		} `json:"http"`
+	} `json:"spec"`
+}
+
+// istioDestination is the destination of a VirtualService route
+type istioDestination struct {
+	Host   string `json:"host"`
+	Subset string `json:"subset"`
+}
+
+// istioDestinationRule holds the fields of a DestinationRule the reader uses
+type istioDestinationRule struct {
+	Spec struct {
+		Host    string `json:"host"`
+		Subsets []struct {
+			Name   string            `json:"name"`
+			Labels map[string]string `json:"labels"`
+		} `json:"subsets"`
+	} `json:"spec"`
+}
+
+// TrafficSplit translates the VirtualService of a service into a traffic split: each
+// destination of an HTTP route becomes a route, with the header matches of the rule and
+// the labels of its DestinationRule subset. Earlier rules get higher priorities. Services
+// without a VirtualService send all traffic to themselves.
+func (r *IstioTrafficReader) TrafficSplit(serviceName string) (*TrafficSplit, error) {
+	var vs istioVirtualService
+	found, err := r.find(r.virtualServices, &vs, func(obj *unstructured.Unstructured) bool {
+		hosts, _, _ := unstructured.NestedStringSlice(obj.Object, "spec", "hosts")
+		for _, host := range hosts {
+			if hostMatches(host, serviceName) {
+				return true
+			}
+		}
+		return false
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return &TrafficSplit{
+			Routes: []Route{{Destination: serviceName, Weight: 100, Priority: 1}},
+		}, nil
+	}
+
+	split := &TrafficSplit{}
+	for i, rule := range vs.Spec.HTTP {
+		priority := len(vs.Spec.HTTP) - i
+
+		// Each match is an alternative; a rule without matches matches every request
+		matches := []map[string]string{nil}
+		if len(rule.Match) > 0 {
+			matches = matches[:0]
+			for _, match := range rule.Match {
+				headers := make(map[string]string)
+				for name, value := range match.Headers {
+					if value.Exact != "" {
+						headers[name] = value.Exact
+					}
+				}
+				matches = append(matches, headers)
+			}
+		}
+
+		for _, headers := range matches {
+			for _, route := range rule.Route {
+				weight := route.Weight
+				if weight == 0 && len(rule.Route) == 1 {
+					weight = 100
+				}
+
+				destination := route.Destination.Host
+				if route.Destination.Subset != "" {
+					destination += "-" + route.Destination.Subset
+				}
+
+				labels, err := r.subsetLabels(route.Destination)
+				if err != nil {
+					return nil, err
+				}
+
+				split.Routes = append(split.Routes, Route{
+					Destination: destination,
+					Weight:      weight,
+					Headers:     headers,
+					Priority:    priority,
+					Labels:      labels,
+				})
+			}
+		}
+	}
+	return split, nil
+}
+
+// subsetLabels returns the labels of a destination subset from its DestinationRule
+func (r *IstioTrafficReader) subsetLabels(destination istioDestination) (map[string]string, error) {
+	if destination.Subset == "" {
+		return nil, nil
+	}
+
+	var dr istioDestinationRule
+	found, err := r.find(r.destinationRules, &dr, func(obj *unstructured.Unstructured) bool {
+		host, _, _ := unstructured.NestedString(obj.Object, "spec", "host")
+		return hostMatches(host, destination.Host) || hostMatches(destination.Host, host)
+	})
+	if err != nil || !found {
+		return nil, err
+	}
+
+	for _, subset := range dr.Spec.Subsets {
+		if subset.Name == destination.Subset {
+			return subset.Labels, nil
+		}
+	}
+	return nil, nil
+}
+
+// find decodes the first cached resource, by namespace and name, that matches into out
+func (r *IstioTrafficReader) find(gvr schema.GroupVersionResource, out interface{}, matches func(*unstructured.Unstructured) bool) (bool, error) {
+	objects, err := r.factory.ForResource(gvr).Lister().List(labels.Everything())
+	if err != nil {
+		return false, err
+	}
+
+	var candidates []*unstructured.Unstructured
+	for _, object := range objects {
+		if obj, ok := object.(*unstructured.Unstructured); ok && matches(obj) {
+			candidates = append(candidates, obj)
+		}
+	}
+	if len(candidates) == 0 {
+		return false, nil
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		ki, _ := cache.MetaNamespaceKeyFunc(candidates[i])
+		kj, _ := cache.MetaNamespaceKeyFunc(candidates[j])
+		return ki < kj
+	})
+
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(candidates[0].Object, out); err != nil {
+		return false, fmt.Errorf("invalid %s %s: %w", gvr.Resource, candidates[0].GetName(), err)
+	}
+	return true, nil
+}
+
+// hostMatches reports whether a host, short or fully qualified
+// (reviews.shop.svc.cluster.local), names the service
+func hostMatches(host, serviceName string) bool {
+	return host == serviceName || strings.HasPrefix(host, serviceName+".")
+}
@@ -0,0 +1,90 @@
+//go:build istio
+
+package servicemesh
+
+import (
+	"context"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+func TestIstioTrafficReader(t *testing.T) {
+	virtualService := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "networking.istio.io/v1beta1",
+		"kind":       "VirtualService",
+		"metadata":   map[string]interface{}{"name": "reviews", "namespace": "shop"},
+		"spec": map[string]interface{}{
+			"hosts": []interface{}{"reviews.shop.svc.cluster.local"},
+			"http": []interface{}{
+				map[string]interface{}{
+					"match": []interface{}{map[string]interface{}{
+						"headers": map[string]interface{}{"x-canary": map[string]interface{}{"exact": "true"}},
+					}},
+					"route": []interface{}{map[string]interface{}{
+						"destination": map[string]interface{}{"host": "reviews", "subset": "v2"},
+					}},
+				},
+				map[string]interface{}{
+					"route": []interface{}{
+						map[string]interface{}{"destination": map[string]interface{}{"host": "reviews", "subset": "v1"}, "weight": int64(90)},
+						map[string]interface{}{"destination": map[string]interface{}{"host": "reviews", "subset": "v2"}, "weight": int64(10)},
+					},
+				},
+			},
+		},
+	}}
+	destinationRule := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "networking.istio.io/v1beta1",
+		"kind":       "DestinationRule",
+		"metadata":   map[string]interface{}{"name": "reviews", "namespace": "shop"},
+		"spec": map[string]interface{}{
+			"host": "reviews.shop.svc.cluster.local",
+			"subsets": []interface{}{
+				map[string]interface{}{"name": "v1", "labels": map[string]interface{}{"version": "v1"}},
+				map[string]interface{}{"name": "v2", "labels": map[string]interface{}{"version": "v2"}},
+			},
+		},
+	}}
+
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		{Group: "networking.istio.io", Version: "v1beta1", Resource: "virtualservices"}:  "VirtualServiceList",
+		{Group: "networking.istio.io", Version: "v1beta1", Resource: "destinationrules"}: "DestinationRuleList",
+	}, virtualService, destinationRule)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	reader := NewIstioTrafficReader(client, WithIstioNamespace("shop"))
+	if err := reader.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	mesh, err := NewIstioMesh(&Config{EnableTrafficSplitting: true},
+		WithIstioTrafficReader(reader))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	split, err := mesh.GetTrafficSplit("reviews")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(split.Routes) != 3 {
+		t.Fatalf("Expected 3 routes, got %+v", split.Routes)
+	}
+	canary, stable := split.Routes[0], split.Routes[1]
+	if canary.Destination != "reviews-v2" || canary.Weight != 100 || canary.Headers["x-canary"] != "true" || canary.Priority <= stable.Priority {
+		t.Errorf("Unexpected canary route: %+v", canary)
+	}
+	if stable.Destination != "reviews-v1" || stable.Weight != 90 || stable.Labels["version"] != "v1" {
+		t.Errorf("Unexpected stable route: %+v", stable)
+	}
+
+	split, err = mesh.GetTrafficSplit("ratings")
+	if err != nil || len(split.Routes) != 1 || split.Routes[0].Weight != 100 {
+		t.Errorf("Expected services without a VirtualService to get all traffic, got %+v (%v)", split, err)
+	}
+}
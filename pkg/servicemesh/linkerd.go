@@ -41,6 +41,15 @@ func (l *LinkerdMesh) ExtractMetadata(ctx context.Context) (*MeshMetadata, error
 	metadata.SpanID = ExtractHeader(ctx, HeaderKeys.LinkerdSpanID)
 	metadata.ParentSpanID = ExtractHeader(ctx, HeaderKeys.LinkerdParentSpanID)
 
+	// Linkerd proxies propagate B3 and W3C trace context
+	if metadata.TraceID == "" {
+		metadata.TraceID = ExtractHeader(ctx, HeaderKeys.XB3TraceID)
+		metadata.SpanID = ExtractHeader(ctx, HeaderKeys.XB3SpanID)
+		metadata.ParentSpanID = ExtractHeader(ctx, HeaderKeys.XB3ParentSpanID)
+	}
+	metadata.Sampled = ExtractHeader(ctx, HeaderKeys.XB3Sampled)
+	extractTraceContext(ctx, metadata)
+
 	// Extract Linkerd-specific headers
 	l.extractLinkerdHeaders(ctx, metadata)
 
@@ -85,6 +94,9 @@ func (l *LinkerdMesh) InjectMetadata(ctx context.Context, metadata *MeshMetadata
 		headers[HeaderKeys.LinkerdParentSpanID] = metadata.ParentSpanID
 	}
 
+	// Bridge to W3C trace context for OpenTelemetry services
+	injectTraceContext(headers, metadata)
+
 	// Inject context ID (similar to request ID)
 	if metadata.RequestID != "" {
 		headers[HeaderKeys.LinkerdContextID] = metadata.RequestID
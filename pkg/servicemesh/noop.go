@@ -0,0 +1,49 @@
+package servicemesh
+
+import (
+	"context"
+)
+
+// NoopMesh is a ServiceMesh for services running outside a mesh (local development, tests).
+// It extracts empty metadata, accepts every connection and reports nothing.
+type NoopMesh struct{}
+
+// NewNoopMesh creates a mesh integration that does nothing
+func NewNoopMesh() *NoopMesh {
+	return &NoopMesh{}
+}
+
+// ExtractMetadata returns empty metadata
+func (n *NoopMesh) ExtractMetadata(ctx context.Context) (*MeshMetadata, error) {
+	return &MeshMetadata{}, nil
+}
+
+// InjectMetadata returns the context unchanged
+func (n *NoopMesh) InjectMetadata(ctx context.Context, metadata *MeshMetadata) context.Context {
+	return ctx
+}
+
+// ValidateMTLS accepts every connection
+func (n *NoopMesh) ValidateMTLS(ctx context.Context) error {
+	return nil
+}
+
+// GetServiceEndpoints returns no endpoints
+func (n *NoopMesh) GetServiceEndpoints(serviceName string) ([]string, error) {
+	return nil, nil
+}
+
+// ReportMetrics discards the metrics
+func (n *NoopMesh) ReportMetrics(ctx context.Context, metrics *Metrics) error {
+	return nil
+}
+
+// ShouldRetry never retries
+func (n *NoopMesh) ShouldRetry(err error) bool {
+	return false
+}
+
+// GetTrafficSplit returns an empty traffic split
+func (n *NoopMesh) GetTrafficSplit(serviceName string) (*TrafficSplit, error) {
+	return &TrafficSplit{}, nil
+}
@@ -0,0 +1,166 @@
+package servicemesh
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// TraceContext is a W3C trace context (https://www.w3.org/TR/trace-context/), the
+// propagation format of OpenTelemetry
+type TraceContext struct {
+	// TraceID is 32 lowercase hex characters
+	TraceID string
+
+	// SpanID is the parent-id: 16 lowercase hex characters
+	SpanID string
+
+	// Sampled is the sampled trace flag
+	Sampled bool
+
+	// TraceState is the vendor-specific tracestate header, passed through as is
+	TraceState string
+}
+
+// ParseTraceparent parses a traceparent header: version-traceid-parentid-flags
+func ParseTraceparent(traceparent string) (TraceContext, error) {
+	parts := strings.Split(strings.TrimSpace(traceparent), "-")
+	if len(parts) < 4 {
+		return TraceContext{}, fmt.Errorf("invalid traceparent %q", traceparent)
+	}
+
+	version, traceID, spanID, flags := parts[0], parts[1], parts[2], parts[3]
+	switch {
+	case !isHex(version, 2) || version == "ff":
+		return TraceContext{}, fmt.Errorf("invalid traceparent version %q", version)
+	case version == "00" && len(parts) != 4:
+		// Later versions may append fields
+		return TraceContext{}, fmt.Errorf("invalid traceparent %q", traceparent)
+	case !isHex(traceID, 32) || isZero(traceID):
+		return TraceContext{}, fmt.Errorf("invalid trace ID %q", traceID)
+	case !isHex(spanID, 16) || isZero(spanID):
+		return TraceContext{}, fmt.Errorf("invalid parent ID %q", spanID)
+	case !isHex(flags, 2):
+		return TraceContext{}, fmt.Errorf("invalid trace flags %q", flags)
+	}
+
+	flagBits, _ := hex.DecodeString(flags)
+	return TraceContext{
+		TraceID: traceID,
+		SpanID:  spanID,
+		Sampled: flagBits[0]&0x01 == 1,
+	}, nil
+}
+
+// Traceparent formats the trace context as a version 00 traceparent header
+func (tc TraceContext) Traceparent() string {
+	flags := "00"
+	if tc.Sampled {
+		flags = "01"
+	}
+	return "00-" + tc.TraceID + "-" + tc.SpanID + "-" + flags
+}
+
+// B3ToTraceContext converts B3 trace headers to a W3C trace context. 64-bit B3 trace IDs
+// are left-padded with zeros, as Zipkin does when it reads 128-bit IDs.
+func B3ToTraceContext(traceID, spanID, sampled string) (TraceContext, error) {
+	traceID = strings.ToLower(traceID)
+	spanID = strings.ToLower(spanID)
+
+	if len(traceID) == 16 {
+		traceID = strings.Repeat("0", 16) + traceID
+	}
+	if !isHex(traceID, 32) || isZero(traceID) {
+		return TraceContext{}, fmt.Errorf("invalid B3 trace ID %q", traceID)
+	}
+	if !isHex(spanID, 16) || isZero(spanID) {
+		return TraceContext{}, fmt.Errorf("invalid B3 span ID %q", spanID)
+	}
+
+	return TraceContext{
+		TraceID: traceID,
+		SpanID:  spanID,
+		// Unset means the caller defers the decision; Istio samples those, so do we
+		Sampled: sampled != "0" && sampled != "false",
+	}, nil
+}
+
+// B3Sampled returns the x-b3-sampled value of the trace context
+func (tc TraceContext) B3Sampled() string {
+	if tc.Sampled {
+		return "1"
+	}
+	return "0"
+}
+
+// TraceContext returns the trace context of the metadata, converting B3 IDs to W3C
+func (m *MeshMetadata) TraceContext() (TraceContext, error) {
+	if m.TraceID == "" {
+		return TraceContext{}, errors.New("no trace ID")
+	}
+
+	tc, err := B3ToTraceContext(m.TraceID, m.SpanID, m.Sampled)
+	if err != nil {
+		return TraceContext{}, err
+	}
+	tc.TraceState = m.TraceState
+	return tc, nil
+}
+
+// SetTraceContext sets the trace fields of the metadata from a W3C trace context
+func (m *MeshMetadata) SetTraceContext(tc TraceContext) {
+	m.TraceID = tc.TraceID
+	m.SpanID = tc.SpanID
+	m.Sampled = tc.B3Sampled()
+	m.TraceState = tc.TraceState
+}
+
+// extractTraceContext reads the W3C trace context headers. The traceparent only fills the
+// trace fields when the mesh's own headers did not, so both formats can arrive together.
+func extractTraceContext(ctx context.Context, metadata *MeshMetadata) {
+	metadata.TraceState = ExtractHeader(ctx, HeaderKeys.TraceState)
+
+	if metadata.TraceID != "" {
+		return
+	}
+	tc, err := ParseTraceparent(ExtractHeader(ctx, HeaderKeys.TraceParent))
+	if err != nil {
+		return
+	}
+	tc.TraceState = metadata.TraceState
+	metadata.SetTraceContext(tc)
+}
+
+// injectTraceContext adds the W3C trace context headers for the trace fields of the
+// metadata, so OpenTelemetry services continue traces started in the mesh
+func injectTraceContext(headers map[string]string, metadata *MeshMetadata) {
+	tc, err := metadata.TraceContext()
+	if err != nil {
+		return
+	}
+
+	headers[HeaderKeys.TraceParent] = tc.Traceparent()
+	if tc.TraceState != "" {
+		headers[HeaderKeys.TraceState] = tc.TraceState
+	}
+}
+
+// isHex reports whether s is n lowercase hex characters
+func isHex(s string, n int) bool {
+	if len(s) != n {
+		return false
+	}
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// isZero reports whether a hex ID is all zeros, which W3C and B3 reserve as invalid
+func isZero(s string) bool {
+	return strings.Trim(s, "0") == ""
+}
@@ -0,0 +1,61 @@
+package servicemesh
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc/metadata"
+)
+
+func TestMeshTraceContextBridge(t *testing.T) {
+	istio, err := NewIstioMesh(&Config{ServiceName: "orders"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	linkerd, err := NewLinkerdMesh(&Config{ServiceName: "orders"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// OpenTelemetry caller into Istio: W3C in, B3 and W3C out
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+		"traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00",
+		"tracestate", "congo=t61rcWkgMzE",
+	))
+	meta, err := istio.ExtractMetadata(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" || meta.SpanID != "00f067aa0ba902b7" || meta.Sampled != "0" {
+		t.Errorf("Unexpected trace fields from traceparent: %+v", meta)
+	}
+
+	out, _ := metadata.FromOutgoingContext(istio.InjectMetadata(context.Background(), meta))
+	if got := out.Get("x-b3-sampled"); len(got) != 1 || got[0] != "0" {
+		t.Errorf("Expected the sampling decision to be kept, got %v", got)
+	}
+	if got := out.Get("tracestate"); len(got) != 1 || got[0] != "congo=t61rcWkgMzE" {
+		t.Errorf("Expected tracestate to be passed through, got %v", got)
+	}
+
+	// 64-bit B3 caller into Linkerd: W3C out with a padded trace ID
+	ctx = metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+		"x-b3-traceid", "a3ce929d0e0e4736",
+		"x-b3-spanid", "00f067aa0ba902b7",
+		"x-b3-sampled", "1",
+	))
+	meta, err = linkerd.ExtractMetadata(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, _ = metadata.FromOutgoingContext(linkerd.InjectMetadata(context.Background(), meta))
+	if got := out.Get("traceparent"); len(got) != 1 || got[0] != "00-0000000000000000a3ce929d0e0e4736-00f067aa0ba902b7-01" {
+		t.Errorf("Unexpected traceparent %v", got)
+	}
+
+	for _, invalid := range []string{"", "00-00000000000000000000000000000000-00f067aa0ba902b7-01", "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra"} {
+		if _, err := ParseTraceparent(invalid); err == nil {
+			t.Errorf("ParseTraceparent(%q) succeeded", invalid)
+		}
+	}
+}
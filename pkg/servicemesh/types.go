@@ -1,4 +1,4 @@
-// Package servicemesh provides integration with service mesh platforms like Istio and Linkerd
+// Package servicemesh provides integration with service mesh platforms like Istio, Linkerd, Consul Connect and Kuma
 package servicemesh
 
 import (
@@ -19,6 +19,10 @@ const (
 	ProviderLinkerd MeshProvider = "linkerd"
 	// ProviderConsul represents Consul Connect
 	ProviderConsul MeshProvider = "consul"
+	// ProviderEnvoy represents any mesh built on Envoy sidecars
+	ProviderEnvoy MeshProvider = "envoy"
+	// ProviderKuma represents Kuma (and Kong Mesh)
+	ProviderKuma MeshProvider = "kuma"
 )
 
 // Config holds service mesh configuration
@@ -65,6 +69,12 @@ type MeshMetadata struct {
 	// ParentSpanID for distributed tracing
 	ParentSpanID string
 
+	// Sampled is the B3 sampling decision: "1", "0", or "" when deferred
+	Sampled string
+
+	// TraceState is the W3C tracestate header, passed through as is
+	TraceState string
+
 	// ServiceVersion is the version of the calling service
 	ServiceVersion string
 
@@ -140,6 +150,9 @@ type Route struct {
 
 	// Priority for route matching (higher = higher priority)
 	Priority int
+
+	// Labels selecting the instances of the destination, e.g. the pods of an Istio subset
+	Labels map[string]string
 }
 
 // HeaderKeys defines common header keys used by service meshes
@@ -171,6 +184,26 @@ var HeaderKeys = struct {
 	XForwardedFor        string
 	XForwardedHost       string
 	XForwardedProto      string
+	XForwardedClientCert string
+
+	// W3C trace context headers
+	TraceParent string
+	TraceState  string
+
+	// Envoy headers
+	EnvoyRetryOn         string
+	EnvoyRetryGRPCOn     string
+	EnvoyMaxRetries      string
+	EnvoyUpstreamTimeout string
+	EnvoyPerTryTimeout   string
+	EnvoyExpectedTimeout string
+	EnvoyOriginalPath    string
+	EnvoyAttemptCount    string
+	EnvoyPeerMetadata    string
+	EnvoyPeerMetadataID  string
+
+	// Kuma headers
+	KumaTags string
 }{
 	// Istio
 	IstioRequestID:       "x-request-id",
@@ -199,6 +232,28 @@ var HeaderKeys = struct {
 	XForwardedFor:   "x-forwarded-for",
 	XForwardedHost:  "x-forwarded-host",
 	XForwardedProto: "x-forwarded-proto",
+
+	// Envoy (Istio, Consul Connect)
+	XForwardedClientCert: "x-forwarded-client-cert",
+
+	// W3C trace context (OpenTelemetry)
+	TraceParent: "traceparent",
+	TraceState:  "tracestate",
+
+	// Envoy
+	EnvoyRetryOn:         "x-envoy-retry-on",
+	EnvoyRetryGRPCOn:     "x-envoy-retry-grpc-on",
+	EnvoyMaxRetries:      "x-envoy-max-retries",
+	EnvoyUpstreamTimeout: "x-envoy-upstream-rq-timeout-ms",
+	EnvoyPerTryTimeout:   "x-envoy-upstream-rq-per-try-timeout-ms",
+	EnvoyExpectedTimeout: "x-envoy-expected-rq-timeout-ms",
+	EnvoyOriginalPath:    "x-envoy-original-path",
+	EnvoyAttemptCount:    "x-envoy-attempt-count",
+	EnvoyPeerMetadata:    "x-envoy-peer-metadata",
+	EnvoyPeerMetadataID:  "x-envoy-peer-metadata-id",
+
+	// Kuma
+	KumaTags: "x-kuma-tags",
 }
 
 // ExtractHeader extracts a header value from gRPC metadata
@@ -0,0 +1,326 @@
+package servicemesh
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// xDS defaults
+const (
+	DefaultXDSPollInterval = 30 * time.Second
+	defaultXDSTimeout      = 5 * time.Second
+
+	edsTypeURL = "type.googleapis.com/envoy.config.endpoint.v3.ClusterLoadAssignment"
+)
+
+// XDSClient discovers endpoints from an xDS management server (Kuma control plane,
+// go-control-plane, ...) with the REST-JSON transport of the endpoint discovery service.
+// It polls POST /v3/discovery:endpoints for every cluster it has been asked about.
+type XDSClient struct {
+	server   string
+	nodeID   string
+	cluster  string
+	interval time.Duration
+	client   *http.Client
+
+	onHealth func(cluster string, health map[string]string)
+
+	mu        sync.RWMutex
+	clusters  map[string][]string          // Healthy endpoints by cluster; nil until discovered
+	health    map[string]map[string]string // EDS health status of every endpoint by cluster
+	version   string
+	nonce     string
+	lastError error
+}
+
+// XDSOption configures the xDS client
+type XDSOption func(*XDSClient)
+
+// WithXDSPollInterval sets how often Run polls the management server
+// Default: 30s
+func WithXDSPollInterval(interval time.Duration) XDSOption {
+	return func(x *XDSClient) {
+		if interval > 0 {
+			x.interval = interval
+		}
+	}
+}
+
+// WithXDSNodeCluster sets the cluster of the node identifying this client
+func WithXDSNodeCluster(cluster string) XDSOption {
+	return func(x *XDSClient) {
+		x.cluster = cluster
+	}
+}
+
+// WithXDSHTTPClient sets the HTTP client used for the management server, e.g. for mTLS
+func WithXDSHTTPClient(client *http.Client) XDSOption {
+	return func(x *XDSClient) {
+		if client != nil {
+			x.client = client
+		}
+	}
+}
+
+// WithXDSHealthObserver is called after every poll with the EDS health status ("HEALTHY",
+// "UNHEALTHY", "DRAINING", "TIMEOUT", "DEGRADED" or "UNKNOWN") of every endpoint of each
+// updated cluster, including the ones Endpoints leaves out
+func WithXDSHealthObserver(fn func(cluster string, health map[string]string)) XDSOption {
+	return func(x *XDSClient) {
+		x.onHealth = fn
+	}
+}
+
+// NewXDSClient creates an xDS client for the management server at serverURL, identifying
+// itself as nodeID
+func NewXDSClient(serverURL, nodeID string, opts ...XDSOption) *XDSClient {
+	x := &XDSClient{
+		server:   strings.TrimSuffix(serverURL, "/"),
+		nodeID:   nodeID,
+		interval: DefaultXDSPollInterval,
+		client:   http.DefaultClient,
+		clusters: make(map[string][]string),
+		health:   make(map[string]map[string]string),
+	}
+
+	for _, opt := range opts {
+		opt(x)
+	}
+
+	return x
+}
+
+// Watch subscribes to the endpoints of clusters
+func (x *XDSClient) Watch(clusters ...string) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	for _, cluster := range clusters {
+		if _, ok := x.clusters[cluster]; !ok {
+			x.clusters[cluster] = nil
+		}
+	}
+}
+
+// Endpoints returns the healthy endpoints of a cluster. A cluster that is not watched yet
+// is subscribed to and fetched right away.
+func (x *XDSClient) Endpoints(ctx context.Context, cluster string) ([]string, error) {
+	x.mu.RLock()
+	endpoints, watched := x.clusters[cluster]
+	x.mu.RUnlock()
+
+	if !watched {
+		x.Watch(cluster)
+		if err := x.Fetch(ctx); err != nil {
+			return nil, err
+		}
+		x.mu.RLock()
+		endpoints = x.clusters[cluster]
+		x.mu.RUnlock()
+	}
+
+	return append([]string(nil), endpoints...), nil
+}
+
+// EndpointHealth returns the EDS health status of every endpoint of a cluster, healthy or
+// not, as of the last poll
+func (x *XDSClient) EndpointHealth(cluster string) map[string]string {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+
+	health := make(map[string]string, len(x.health[cluster]))
+	for endpoint, status := range x.health[cluster] {
+		health[endpoint] = status
+	}
+	return health
+}
+
+// Run polls the management server until ctx is done
+func (x *XDSClient) Run(ctx context.Context) {
+	ticker := time.NewTicker(x.interval)
+	defer ticker.Stop()
+
+	for {
+		x.Fetch(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// LastError returns the error of the last poll, or nil when it succeeded
+func (x *XDSClient) LastError() error {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	return x.lastError
+}
+
+// xdsNode identifies the client to the management server
+type xdsNode struct {
+	ID      string `json:"id"`
+	Cluster string `json:"cluster,omitempty"`
+}
+
+// xdsRequest is a DiscoveryRequest
+type xdsRequest struct {
+	VersionInfo   string   `json:"version_info,omitempty"`
+	Node          xdsNode  `json:"node"`
+	ResourceNames []string `json:"resource_names"`
+	TypeURL       string   `json:"type_url"`
+	ResponseNonce string   `json:"response_nonce,omitempty"`
+}
+
+// xdsResponse is a DiscoveryResponse of ClusterLoadAssignments. Field names are matched
+// without underscores, as servers send either proto (snake_case) or JSON (camelCase) names.
+type xdsResponse struct {
+	VersionInfo string
+	Nonce       string
+	Resources   []struct {
+		ClusterName string
+		Endpoints   []struct {
+			LbEndpoints []struct {
+				Endpoint struct {
+					Address struct {
+						SocketAddress struct {
+							Address   string
+							PortValue int
+						}
+					}
+				}
+				HealthStatus string
+			}
+		}
+	}
+}
+
+// Fetch polls the management server once
+func (x *XDSClient) Fetch(ctx context.Context) error {
+	err := x.fetch(ctx)
+
+	x.mu.Lock()
+	x.lastError = err
+	x.mu.Unlock()
+
+	return err
+}
+
+// fetch sends a discovery request for every watched cluster and stores the response
+func (x *XDSClient) fetch(ctx context.Context) error {
+	x.mu.RLock()
+	req := xdsRequest{
+		VersionInfo:   x.version,
+		Node:          xdsNode{ID: x.nodeID, Cluster: x.cluster},
+		ResourceNames: make([]string, 0, len(x.clusters)),
+		TypeURL:       edsTypeURL,
+		ResponseNonce: x.nonce,
+	}
+	for cluster := range x.clusters {
+		req.ResourceNames = append(req.ResourceNames, cluster)
+	}
+	x.mu.RUnlock()
+	sort.Strings(req.ResourceNames)
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, x.server+"/v3/discovery:endpoints", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := x.client.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("xds request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified:
+		return nil
+	case resp.StatusCode != http.StatusOK:
+		return fmt.Errorf("xds request failed: %s", resp.Status)
+	}
+
+	var raw interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		return fmt.Errorf("invalid xds response: %w", err)
+	}
+	normalized, err := json.Marshal(stripUnderscores(raw))
+	if err != nil {
+		return err
+	}
+	var discovery xdsResponse
+	if err := json.Unmarshal(normalized, &discovery); err != nil {
+		return fmt.Errorf("invalid xds response: %w", err)
+	}
+
+	updated := make(map[string]map[string]string, len(discovery.Resources))
+
+	x.mu.Lock()
+	x.version = discovery.VersionInfo
+	x.nonce = discovery.Nonce
+	for _, assignment := range discovery.Resources {
+		endpoints := []string{}
+		health := make(map[string]string)
+		for _, locality := range assignment.Endpoints {
+			for _, lb := range locality.LbEndpoints {
+				address := lb.Endpoint.Address.SocketAddress
+				endpoint := net.JoinHostPort(address.Address, strconv.Itoa(address.PortValue))
+				status := lb.HealthStatus
+				if status == "" {
+					status = "UNKNOWN"
+				}
+				health[endpoint] = status
+
+				switch status {
+				case "UNHEALTHY", "DRAINING", "TIMEOUT":
+					continue
+				}
+				endpoints = append(endpoints, endpoint)
+			}
+		}
+		x.clusters[assignment.ClusterName] = endpoints
+		x.health[assignment.ClusterName] = health
+		updated[assignment.ClusterName] = health
+	}
+	x.mu.Unlock()
+
+	if x.onHealth != nil {
+		for cluster, health := range updated {
+			x.onHealth(cluster, health)
+		}
+	}
+	return nil
+}
+
+// stripUnderscores removes underscores from the object keys of decoded JSON
+func stripUnderscores(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			m[strings.ReplaceAll(k, "_", "")] = stripUnderscores(item)
+		}
+		return m
+	case []interface{}:
+		for i, item := range v {
+			v[i] = stripUnderscores(item)
+		}
+	}
+	return v
+}
@@ -0,0 +1,55 @@
+package servicemesh
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestXDSClient_Endpoints(t *testing.T) {
+	var requests []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&req)
+		requests = append(requests, req)
+		if r.URL.Path != "/v3/discovery:endpoints" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{
+			"versionInfo": "7", "nonce": "n1",
+			"resources": [{
+				"@type": "type.googleapis.com/envoy.config.endpoint.v3.ClusterLoadAssignment",
+				"clusterName": "payments",
+				"endpoints": [{"lbEndpoints": [
+					{"endpoint": {"address": {"socketAddress": {"address": "10.0.1.5", "portValue": 8080}}}, "healthStatus": "HEALTHY"},
+					{"endpoint": {"address": {"socket_address": {"address": "10.0.1.6", "port_value": 8080}}}, "health_status": "UNHEALTHY"}
+				]}]
+			}]
+		}`))
+	}))
+	defer server.Close()
+
+	mesh, err := NewEnvoyMesh(&Config{ServiceName: "orders"},
+		WithEnvoyXDS(NewXDSClient(server.URL, "orders-1")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	endpoints, err := mesh.GetServiceEndpoints("payments")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(endpoints) != 1 || endpoints[0] != "10.0.1.5:8080" {
+		t.Errorf("Expected only the healthy endpoint, got %v", endpoints)
+	}
+
+	// Known clusters are served from the last response
+	if _, err := mesh.GetServiceEndpoints("payments"); err != nil || len(requests) != 1 {
+		t.Errorf("Expected one discovery request, got %d (%v)", len(requests), err)
+	}
+	if names := requests[0]["resource_names"].([]interface{}); len(names) != 1 || names[0] != "payments" {
+		t.Errorf("Unexpected resource names: %v", names)
+	}
+}
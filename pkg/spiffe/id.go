@@ -0,0 +1,186 @@
+// Package spiffe provides SPIFFE workload identity for gRPC services: SVIDs from the SPIRE
+// Workload API, mTLS credentials built on them, and SPIFFE ID patterns for authorization.
+package spiffe
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/spiffe/go-spiffe/v2/spiffegrpc/grpccredentials"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+// ID is a SPIFFE ID: spiffe://<trust-domain>/<path>
+type ID struct {
+	TrustDomain string
+	Path        string // Starts with "/", or is empty
+}
+
+// ParseID parses and validates a SPIFFE ID
+func ParseID(s string) (ID, error) {
+	id, err := spiffeid.FromString(s)
+	if err != nil {
+		return ID{}, fmt.Errorf("invalid SPIFFE ID %q: %w", s, err)
+	}
+	return ID{TrustDomain: id.TrustDomain().String(), Path: id.Path()}, nil
+}
+
+// String returns the ID in URI form
+func (id ID) String() string {
+	return "spiffe://" + id.TrustDomain + id.Path
+}
+
+// IsZero reports whether the ID is empty
+func (id ID) IsZero() bool {
+	return id.TrustDomain == ""
+}
+
+// Segments returns the path segments of the ID
+func (id ID) Segments() []string {
+	if id.Path == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimPrefix(id.Path, "/"), "/")
+}
+
+// Namespace returns the namespace of an ID in the Kubernetes form used by Istio and SPIRE,
+// spiffe://<trust-domain>/ns/<namespace>/sa/<service-account>, or "" for other IDs
+func (id ID) Namespace() string {
+	return id.segmentValue("ns")
+}
+
+// ServiceAccount returns the service account of an ID in the Kubernetes form, or "" for
+// other IDs
+func (id ID) ServiceAccount() string {
+	return id.segmentValue("sa")
+}
+
+// segmentValue returns the segment following the first segment named key
+func (id ID) segmentValue(key string) string {
+	segments := id.Segments()
+	for i := 0; i+1 < len(segments); i++ {
+		if segments[i] == key {
+			return segments[i+1]
+		}
+	}
+	return ""
+}
+
+// IDFromCert returns the SPIFFE ID in the URI SANs of a certificate
+func IDFromCert(cert *x509.Certificate) (ID, error) {
+	for _, uri := range cert.URIs {
+		if uri.Scheme != "spiffe" {
+			continue
+		}
+		return ParseID(uri.String())
+	}
+	return ID{}, errors.New("no SPIFFE ID found in certificate")
+}
+
+// PeerID returns the SPIFFE ID of the caller of a gRPC request, from credentials created by
+// this package or a TLS connection whose client certificate was verified
+func PeerID(ctx context.Context) (ID, error) {
+	if id, ok := grpccredentials.PeerIDFromContext(ctx); ok {
+		return ID{TrustDomain: id.TrustDomain().String(), Path: id.Path()}, nil
+	}
+
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return ID{}, errors.New("no peer information in context")
+	}
+
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok {
+		return ID{}, errors.New("peer not using TLS")
+	}
+	if len(tlsInfo.State.VerifiedChains) == 0 || len(tlsInfo.State.VerifiedChains[0]) == 0 {
+		return ID{}, errors.New("no verified certificate chains")
+	}
+	return IDFromCert(tlsInfo.State.VerifiedChains[0][0])
+}
+
+// Pattern matches SPIFFE IDs. In a pattern, "*" matches any single path segment (or any
+// trust domain), and a final "**" matches any number of remaining segments:
+//
+//	spiffe://example.org/ns/*/sa/payments   payments in every namespace
+//	spiffe://example.org/ns/shop/**         everything in the shop namespace
+//	spiffe://*/ns/shop/sa/orders            orders in every trust domain
+type Pattern struct {
+	raw         string
+	trustDomain string
+	segments    []string
+}
+
+// ParsePattern parses a SPIFFE ID pattern
+func ParsePattern(pattern string) (Pattern, error) {
+	rest, ok := strings.CutPrefix(pattern, "spiffe://")
+	if !ok {
+		return Pattern{}, fmt.Errorf("invalid SPIFFE ID pattern %q: missing spiffe:// scheme", pattern)
+	}
+
+	trustDomain, path, _ := strings.Cut(rest, "/")
+	if trustDomain == "" {
+		return Pattern{}, fmt.Errorf("invalid SPIFFE ID pattern %q: missing trust domain", pattern)
+	}
+
+	p := Pattern{raw: pattern, trustDomain: trustDomain}
+	if path != "" {
+		p.segments = strings.Split(path, "/")
+	}
+	for i, segment := range p.segments {
+		if segment == "" {
+			return Pattern{}, fmt.Errorf("invalid SPIFFE ID pattern %q: empty path segment", pattern)
+		}
+		if segment == "**" && i != len(p.segments)-1 {
+			return Pattern{}, fmt.Errorf("invalid SPIFFE ID pattern %q: ** must be the last segment", pattern)
+		}
+	}
+	return p, nil
+}
+
+// MustParsePattern is like ParsePattern but panics on invalid patterns
+func MustParsePattern(pattern string) Pattern {
+	p, err := ParsePattern(pattern)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+// String returns the pattern as written
+func (p Pattern) String() string {
+	return p.raw
+}
+
+// Match reports whether the pattern matches an ID
+func (p Pattern) Match(id ID) bool {
+	if p.trustDomain != "*" && p.trustDomain != id.TrustDomain {
+		return false
+	}
+
+	segments := id.Segments()
+	for i, want := range p.segments {
+		if want == "**" {
+			return true
+		}
+		if i >= len(segments) || (want != "*" && want != segments[i]) {
+			return false
+		}
+	}
+	return len(segments) == len(p.segments)
+}
+
+// MatchAny reports whether any of the patterns matches an ID
+func MatchAny(id ID, patterns ...Pattern) bool {
+	for _, p := range patterns {
+		if p.Match(id) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+package spiffe
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net/url"
+	"testing"
+
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+func TestParseID(t *testing.T) {
+	id, err := ParseID("spiffe://example.org/ns/shop/sa/payments")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id.TrustDomain != "example.org" || id.Namespace() != "shop" || id.ServiceAccount() != "payments" {
+		t.Errorf("Unexpected ID %+v", id)
+	}
+	if id.String() != "spiffe://example.org/ns/shop/sa/payments" {
+		t.Errorf("String() = %q", id.String())
+	}
+
+	other, _ := ParseID("spiffe://example.org/workers/batch")
+	if other.Namespace() != "" || other.ServiceAccount() != "" {
+		t.Errorf("Expected no namespace for a non-Kubernetes ID, got %+v", other)
+	}
+
+	for _, invalid := range []string{"", "https://example.org/ns", "spiffe://Example.org/x", "spiffe://example.org/a//b"} {
+		if _, err := ParseID(invalid); err == nil {
+			t.Errorf("ParseID(%q) succeeded", invalid)
+		}
+	}
+}
+
+func TestPattern(t *testing.T) {
+	id, err := ParseID("spiffe://example.org/ns/shop/sa/payments")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for pattern, want := range map[string]bool{
+		"spiffe://example.org/ns/shop/sa/payments":    true,
+		"spiffe://*/ns/shop/sa/payments":              true,
+		"spiffe://example.org/ns/*/sa/*":              true,
+		"spiffe://example.org/**":                     true,
+		"spiffe://example.org/ns/*":                   false,
+		"spiffe://example.org/ns/shop/sa/payments/v2": false,
+	} {
+		if got := MustParsePattern(pattern).Match(id); got != want {
+			t.Errorf("%s matched = %v, want %v", pattern, got, want)
+		}
+	}
+
+	for _, invalid := range []string{"https://example.org/ns", "spiffe:///ns", "spiffe://example.org/**/sa"} {
+		if _, err := ParsePattern(invalid); err == nil {
+			t.Errorf("ParsePattern(%q) succeeded", invalid)
+		}
+	}
+}
+
+func TestPeerID(t *testing.T) {
+	uri, _ := url.Parse("spiffe://example.org/ns/shop/sa/orders")
+	cert := &x509.Certificate{URIs: []*url.URL{{Scheme: "https", Host: "orders.example.org"}, uri}}
+	ctx := peer.NewContext(context.Background(), &peer.Peer{
+		AuthInfo: credentials.TLSInfo{State: tls.ConnectionState{
+			VerifiedChains: [][]*x509.Certificate{{cert}},
+		}},
+	})
+
+	id, err := PeerID(ctx)
+	if err != nil || id.ServiceAccount() != "orders" {
+		t.Fatalf("PeerID() = %v, %v", id, err)
+	}
+
+	unverified := peer.NewContext(context.Background(), &peer.Peer{
+		AuthInfo: credentials.TLSInfo{State: tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}},
+	})
+	for name, ctx := range map[string]context.Context{
+		"no peer":         context.Background(),
+		"no TLS":          peer.NewContext(context.Background(), &peer.Peer{}),
+		"unverified cert": unverified,
+	} {
+		if _, err := PeerID(ctx); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestAuthorizer(t *testing.T) {
+	authorize := Authorizer(MustParsePattern("spiffe://example.org/ns/shop/**"))
+
+	if err := authorize(spiffeid.RequireFromString("spiffe://example.org/ns/shop/sa/orders"), nil); err != nil {
+		t.Errorf("Expected the shop namespace to be authorized, got %v", err)
+	}
+	if err := authorize(spiffeid.RequireFromString("spiffe://example.org/ns/billing/sa/orders"), nil); err == nil {
+		t.Error("Expected other namespaces to be rejected")
+	}
+}
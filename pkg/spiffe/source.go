@@ -0,0 +1,143 @@
+package spiffe
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+
+	"github.com/spiffe/go-spiffe/v2/spiffegrpc/grpccredentials"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	"github.com/spiffe/go-spiffe/v2/spiffetls/tlsconfig"
+	"github.com/spiffe/go-spiffe/v2/workloadapi"
+	"google.golang.org/grpc/credentials"
+)
+
+// Source keeps the X.509 SVID of the workload and the trust bundles up to date from the
+// SPIRE Workload API. SVIDs are rotated as the agent issues new ones.
+type Source struct {
+	x509 *workloadapi.X509Source
+}
+
+// sourceConfig holds the Workload API client settings
+type sourceConfig struct {
+	socketPath string
+}
+
+// SourceOption configures the Workload API source
+type SourceOption func(*sourceConfig)
+
+// WithSocketPath sets the address of the Workload API, e.g.
+// "unix:///run/spire/sockets/agent.sock"
+// Default: the SPIFFE_ENDPOINT_SOCKET environment variable
+func WithSocketPath(path string) SourceOption {
+	return func(c *sourceConfig) {
+		c.socketPath = path
+	}
+}
+
+// NewSource connects to the Workload API and blocks until the first SVID is received or
+// ctx is done. Close the source when it is no longer needed.
+//
+// Example usage:
+//
+//	source, err := spiffe.NewSource(ctx, spiffe.WithSocketPath("unix:///run/spire/sockets/agent.sock"))
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	defer source.Close()
+//
+//	creds, _ := source.ServerCredentials(spiffe.MustParsePattern("spiffe://example.org/ns/*/sa/payments"))
+//	server := grpc.NewServer(grpc.Creds(creds))
+func NewSource(ctx context.Context, opts ...SourceOption) (*Source, error) {
+	config := &sourceConfig{}
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	var sourceOpts []workloadapi.X509SourceOption
+	if config.socketPath != "" {
+		sourceOpts = append(sourceOpts, workloadapi.WithClientOptions(workloadapi.WithAddr(config.socketPath)))
+	}
+
+	x509Source, err := workloadapi.NewX509Source(ctx, sourceOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch SVID from workload API: %w", err)
+	}
+	return &Source{x509: x509Source}, nil
+}
+
+// ID returns the SPIFFE ID of the workload
+func (s *Source) ID() (ID, error) {
+	svid, err := s.x509.GetX509SVID()
+	if err != nil {
+		return ID{}, err
+	}
+	return ID{TrustDomain: svid.ID.TrustDomain().String(), Path: svid.ID.Path()}, nil
+}
+
+// Certificates returns the current SVID certificate chain, leaf first
+func (s *Source) Certificates() ([]*x509.Certificate, error) {
+	svid, err := s.x509.GetX509SVID()
+	if err != nil {
+		return nil, err
+	}
+	return svid.Certificates, nil
+}
+
+// X509Source returns the underlying go-spiffe source, for TLS configurations this package
+// does not cover
+func (s *Source) X509Source() *workloadapi.X509Source {
+	return s.x509
+}
+
+// ServerCredentials returns mTLS server credentials presenting the workload SVID. Clients
+// must present an SVID matching one of the patterns; without patterns, any SVID from the
+// trust domain of the workload is accepted.
+func (s *Source) ServerCredentials(authorized ...Pattern) (credentials.TransportCredentials, error) {
+	authorizer, err := s.authorizer(authorized)
+	if err != nil {
+		return nil, err
+	}
+	return grpccredentials.MTLSServerCredentials(s.x509, s.x509, authorizer), nil
+}
+
+// ClientCredentials returns mTLS client credentials presenting the workload SVID. Servers
+// must present an SVID matching one of the patterns; without patterns, any SVID from the
+// trust domain of the workload is accepted.
+func (s *Source) ClientCredentials(authorized ...Pattern) (credentials.TransportCredentials, error) {
+	authorizer, err := s.authorizer(authorized)
+	if err != nil {
+		return nil, err
+	}
+	return grpccredentials.MTLSClientCredentials(s.x509, s.x509, authorizer), nil
+}
+
+// Close stops watching the Workload API
+func (s *Source) Close() error {
+	return s.x509.Close()
+}
+
+// authorizer authorizes peers matching any of the patterns, or members of the workload
+// trust domain when there are none
+func (s *Source) authorizer(patterns []Pattern) (tlsconfig.Authorizer, error) {
+	if len(patterns) == 0 {
+		svid, err := s.x509.GetX509SVID()
+		if err != nil {
+			return nil, err
+		}
+		return tlsconfig.AuthorizeMemberOf(svid.ID.TrustDomain()), nil
+	}
+	return Authorizer(patterns...), nil
+}
+
+// Authorizer returns a go-spiffe TLS authorizer accepting peers whose SPIFFE ID matches
+// any of the patterns
+func Authorizer(patterns ...Pattern) tlsconfig.Authorizer {
+	return func(id spiffeid.ID, _ [][]*x509.Certificate) error {
+		peerID := ID{TrustDomain: id.TrustDomain().String(), Path: id.Path()}
+		if !MatchAny(peerID, patterns...) {
+			return fmt.Errorf("unauthorized SPIFFE ID %q", peerID)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,50 @@
+package tap
+
+import (
+	"context"
+	"time"
+
+	"github.com/grpc-guardian/grpc-guardian/middleware"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryServerInterceptor records a summary of every request. Install it after the
+// authentication middleware to include the user of each request.
+func (t *Tap) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		t.Record(newEvent(ctx, info.FullMethod, start, err, false))
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor records a summary of every stream when it ends
+func (t *Tap) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, ss)
+		t.Record(newEvent(ss.Context(), info.FullMethod, start, err, true))
+		return err
+	}
+}
+
+// newEvent summarizes a finished request
+func newEvent(ctx context.Context, method string, start time.Time, err error, stream bool) Event {
+	st := status.Convert(err)
+	e := Event{
+		Time:     start,
+		Method:   method,
+		Code:     st.Code(),
+		Message:  st.Message(),
+		Duration: time.Since(start),
+		Stream:   stream,
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		e.Peer = p.Addr.String()
+	}
+	e.UserID, _ = middleware.GetUserID(ctx)
+	return e
+}
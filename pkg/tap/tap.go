@@ -0,0 +1,314 @@
+// Package tap keeps summaries of the requests a server handles, in a ring buffer and as a
+// live feed, so operators can watch their own service's traffic ("linkerd tap" style)
+// without a mesh. Install the Tap interceptors on the server and expose the feed through
+// the admin endpoint (see admin.WithTap).
+package tap
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc/codes"
+)
+
+// Event summarizes a finished request
+type Event struct {
+	Time     time.Time // When the request started
+	Method   string
+	Code     codes.Code
+	Message  string // Status message of failed requests
+	Duration time.Duration
+	Peer     string // Remote address
+	UserID   string // Authenticated user, if any
+	Stream   bool
+}
+
+// eventJSON is the JSON form of an Event served by the admin endpoint
+type eventJSON struct {
+	Time       time.Time `json:"time"`
+	Method     string    `json:"method"`
+	Code       string    `json:"code"`
+	Message    string    `json:"message,omitempty"`
+	DurationMS float64   `json:"duration_ms"`
+	Peer       string    `json:"peer,omitempty"`
+	UserID     string    `json:"user_id,omitempty"`
+	Stream     bool      `json:"stream,omitempty"`
+}
+
+// MarshalJSON encodes the code by name and the duration in milliseconds
+func (e Event) MarshalJSON() ([]byte, error) {
+	return json.Marshal(eventJSON{
+		Time:       e.Time,
+		Method:     e.Method,
+		Code:       e.Code.String(),
+		Message:    e.Message,
+		DurationMS: float64(e.Duration) / float64(time.Millisecond),
+		Peer:       e.Peer,
+		UserID:     e.UserID,
+		Stream:     e.Stream,
+	})
+}
+
+// Filter selects events. The zero Filter matches every event.
+type Filter struct {
+	Method      string       // "/pkg.Service/Method", or a prefix ending in "*"; empty matches all
+	Codes       []codes.Code // Status codes to keep; empty keeps all
+	MinDuration time.Duration
+}
+
+// Match reports whether an event passes the filter
+func (f Filter) Match(e Event) bool {
+	if f.Method != "" && !matchMethod(f.Method, e.Method) {
+		return false
+	}
+	if e.Duration < f.MinDuration {
+		return false
+	}
+	if len(f.Codes) == 0 {
+		return true
+	}
+	for _, code := range f.Codes {
+		if code == e.Code {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseFilter builds a filter from text, as sent to the admin endpoint. Codes are names in
+// any case, e.g. "Internal" or "DEADLINE_EXCEEDED"; the duration is a Go duration.
+func ParseFilter(method string, codeNames []string, minDuration string) (Filter, error) {
+	filter := Filter{Method: method}
+	for _, name := range codeNames {
+		code, ok := parseCode(name)
+		if !ok {
+			return Filter{}, fmt.Errorf("unknown status code %q", name)
+		}
+		filter.Codes = append(filter.Codes, code)
+	}
+	if minDuration != "" {
+		d, err := time.ParseDuration(minDuration)
+		if err != nil {
+			return Filter{}, fmt.Errorf("invalid min_duration: %w", err)
+		}
+		filter.MinDuration = d
+	}
+	return filter, nil
+}
+
+// parseCode looks up a status code by name, ignoring case and underscores
+func parseCode(name string) (codes.Code, bool) {
+	normalized := strings.ToLower(strings.ReplaceAll(name, "_", ""))
+	for code := codes.OK; code <= codes.Unauthenticated; code++ {
+		if strings.ToLower(code.String()) == normalized {
+			return code, true
+		}
+	}
+	return 0, false
+}
+
+// matchMethod matches a method against "/pkg.Service/Method" or a prefix ending in "*"
+func matchMethod(pattern, method string) bool {
+	if strings.HasSuffix(pattern, "*") {
+		return strings.HasPrefix(method, strings.TrimSuffix(pattern, "*"))
+	}
+	return pattern == method
+}
+
+// subscriber receives live events matching its filter
+type subscriber struct {
+	filter  Filter
+	events  chan Event
+	dropped atomic.Uint64
+}
+
+// Tap records request summaries
+type Tap struct {
+	bufferSize     int
+	subscriberSize int
+	excluded       []string
+
+	mu          sync.Mutex
+	ring        []Event
+	next        int
+	subscribers map[*subscriber]struct{}
+}
+
+// Option configures a Tap
+type Option func(*Tap)
+
+// WithBufferSize sets how many recent events are kept
+// Default: 1024
+func WithBufferSize(size int) Option {
+	return func(t *Tap) {
+		if size > 0 {
+			t.bufferSize = size
+		}
+	}
+}
+
+// WithSubscriberBuffer sets how many events a slow subscriber may lag behind before new
+// events are dropped for it
+// Default: 256
+func WithSubscriberBuffer(size int) Option {
+	return func(t *Tap) {
+		if size > 0 {
+			t.subscriberSize = size
+		}
+	}
+}
+
+// WithExcluded never records methods matching the patterns
+// Default: "/grpc.health.v1.Health/*", "/guardian.admin.v1.Admin/*"
+func WithExcluded(patterns ...string) Option {
+	return func(t *Tap) {
+		t.excluded = append(t.excluded, patterns...)
+	}
+}
+
+// New creates a Tap
+//
+// Example usage:
+//
+//	t := tap.New()
+//	server := grpc.NewServer(
+//	    grpc.ChainUnaryInterceptor(t.UnaryServerInterceptor()),
+//	    grpc.ChainStreamInterceptor(t.StreamServerInterceptor()),
+//	)
+//	admin.New(admin.WithToken(token), admin.WithTap(t)).Register(server)
+func New(opts ...Option) *Tap {
+	t := &Tap{
+		bufferSize:     1024,
+		subscriberSize: 256,
+		excluded:       []string{"/grpc.health.v1.Health/*", "/guardian.admin.v1.Admin/*"},
+		subscribers:    make(map[*subscriber]struct{}),
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	t.ring = make([]Event, 0, t.bufferSize)
+	return t
+}
+
+// excludes reports whether a method is never recorded
+func (t *Tap) excludes(method string) bool {
+	for _, pattern := range t.excluded {
+		if matchMethod(pattern, method) {
+			return true
+		}
+	}
+	return false
+}
+
+// Record adds an event to the buffer and sends it to matching subscribers. Subscribers
+// that fall behind miss events instead of slowing down requests.
+func (t *Tap) Record(e Event) {
+	if t.excludes(e.Method) {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if len(t.ring) < t.bufferSize {
+		t.ring = append(t.ring, e)
+	} else {
+		t.ring[t.next] = e
+	}
+	t.next = (t.next + 1) % t.bufferSize
+
+	for sub := range t.subscribers {
+		if !sub.filter.Match(e) {
+			continue
+		}
+		select {
+		case sub.events <- e:
+		default:
+			sub.dropped.Add(1)
+		}
+	}
+}
+
+// Recent returns up to limit buffered events matching the filter, oldest first; a limit
+// of zero returns every match
+func (t *Tap) Recent(filter Filter, limit int) []Event {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.recent(filter, limit)
+}
+
+// recent returns buffered events matching the filter; t.mu must be held
+func (t *Tap) recent(filter Filter, limit int) []Event {
+	var events []Event
+	start := 0
+	if len(t.ring) == t.bufferSize {
+		start = t.next
+	}
+	for i := 0; i < len(t.ring); i++ {
+		e := t.ring[(start+i)%len(t.ring)]
+		if filter.Match(e) {
+			events = append(events, e)
+		}
+	}
+	if limit > 0 && len(events) > limit {
+		events = events[len(events)-limit:]
+	}
+	return events
+}
+
+// Subscription is a live feed of events
+type Subscription struct {
+	tap *Tap
+	sub *subscriber
+}
+
+// Subscribe starts a live feed of events matching the filter, beginning with up to backlog
+// buffered events so no event is missed or repeated in between. Close it when done.
+func (t *Tap) Subscribe(filter Filter, backlog int) *Subscription {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var recent []Event
+	if backlog > 0 {
+		recent = t.recent(filter, backlog)
+	}
+	sub := &subscriber{filter: filter, events: make(chan Event, t.subscriberSize+len(recent))}
+	for _, e := range recent {
+		sub.events <- e
+	}
+	t.subscribers[sub] = struct{}{}
+
+	return &Subscription{tap: t, sub: sub}
+}
+
+// Events returns the feed; it is closed by Close
+func (s *Subscription) Events() <-chan Event {
+	return s.sub.events
+}
+
+// Dropped returns how many events the subscriber missed because it fell behind
+func (s *Subscription) Dropped() uint64 {
+	return s.sub.dropped.Load()
+}
+
+// Close stops the feed
+func (s *Subscription) Close() {
+	s.tap.mu.Lock()
+	defer s.tap.mu.Unlock()
+
+	if _, ok := s.tap.subscribers[s.sub]; ok {
+		delete(s.tap.subscribers, s.sub)
+		close(s.sub.events)
+	}
+}
+
+// Subscribers returns the number of live feeds
+func (t *Tap) Subscribers() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.subscribers)
+}
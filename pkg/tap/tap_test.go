@@ -0,0 +1,136 @@
+package tap
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+func TestTap_RingBuffer(t *testing.T) {
+	tp := New(WithBufferSize(3))
+	for i := 0; i < 5; i++ {
+		tp.Record(Event{Method: fmt.Sprintf("/svc.S/M%d", i)})
+	}
+
+	recent := tp.Recent(Filter{}, 0)
+	if len(recent) != 3 {
+		t.Fatalf("Expected the buffer to keep 3 events, got %d", len(recent))
+	}
+	for i, want := range []string{"/svc.S/M2", "/svc.S/M3", "/svc.S/M4"} {
+		if recent[i].Method != want {
+			t.Errorf("Expected %s at %d, got %s", want, i, recent[i].Method)
+		}
+	}
+	if got := tp.Recent(Filter{}, 1); len(got) != 1 || got[0].Method != "/svc.S/M4" {
+		t.Errorf("Expected the newest event, got %v", got)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	filter, err := ParseFilter("/orders.Orders/*", []string{"internal", "DEADLINE_EXCEEDED"}, "100ms")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		event Event
+		want  bool
+	}{
+		{Event{Method: "/orders.Orders/Get", Code: codes.Internal, Duration: time.Second}, true},
+		{Event{Method: "/orders.Orders/Get", Code: codes.DeadlineExceeded, Duration: 100 * time.Millisecond}, true},
+		{Event{Method: "/orders.Orders/Get", Code: codes.OK, Duration: time.Second}, false},
+		{Event{Method: "/orders.Orders/Get", Code: codes.Internal, Duration: time.Millisecond}, false},
+		{Event{Method: "/users.Users/Get", Code: codes.Internal, Duration: time.Second}, false},
+	}
+	for _, tt := range tests {
+		if got := filter.Match(tt.event); got != tt.want {
+			t.Errorf("Match(%+v) = %v, want %v", tt.event, got, tt.want)
+		}
+	}
+
+	if _, err := ParseFilter("", []string{"Broken"}, ""); err == nil {
+		t.Error("Expected an error for an unknown code")
+	}
+	if _, err := ParseFilter("", nil, "soon"); err == nil {
+		t.Error("Expected an error for an invalid duration")
+	}
+}
+
+func TestTap_Subscribe(t *testing.T) {
+	tp := New(WithSubscriberBuffer(2))
+	tp.Record(Event{Method: "/svc.S/Old", Code: codes.Internal})
+	tp.Record(Event{Method: "/svc.S/Old", Code: codes.OK})
+
+	sub := tp.Subscribe(Filter{Codes: []codes.Code{codes.Internal}}, 10)
+	if tp.Subscribers() != 1 {
+		t.Errorf("Expected 1 subscriber, got %d", tp.Subscribers())
+	}
+
+	if e := <-sub.Events(); e.Method != "/svc.S/Old" {
+		t.Errorf("Expected the backlog first, got %+v", e)
+	}
+
+	// The feed holds the subscriber buffer plus the backlog: 3 events
+	tp.Record(Event{Method: "/svc.S/New", Code: codes.OK})
+	for i := 0; i < 4; i++ {
+		tp.Record(Event{Method: "/svc.S/New", Code: codes.Internal})
+	}
+	for i := 0; i < 3; i++ {
+		if e := <-sub.Events(); e.Method != "/svc.S/New" || e.Code != codes.Internal {
+			t.Errorf("Expected a live matching event, got %+v", e)
+		}
+	}
+	if sub.Dropped() != 1 {
+		t.Errorf("Expected 1 event dropped for the slow subscriber, got %d", sub.Dropped())
+	}
+
+	sub.Close()
+	sub.Close()
+	if _, ok := <-sub.Events(); ok {
+		t.Error("Expected the feed to be closed")
+	}
+	if tp.Subscribers() != 0 {
+		t.Errorf("Expected no subscribers, got %d", tp.Subscribers())
+	}
+}
+
+func TestTap_Interceptors(t *testing.T) {
+	tp := New()
+	addr := &net.TCPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 4242}
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: addr})
+
+	unary := tp.UnaryServerInterceptor()
+	_, _ = unary(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/orders.Orders/Get"},
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			return nil, status.Error(codes.NotFound, "no such order")
+		})
+	_, _ = unary(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/grpc.health.v1.Health/Check"},
+		func(ctx context.Context, req interface{}) (interface{}, error) { return nil, nil })
+
+	recent := tp.Recent(Filter{}, 0)
+	if len(recent) != 1 {
+		t.Fatalf("Expected health checks to be excluded, got %v", recent)
+	}
+	e := recent[0]
+	if e.Method != "/orders.Orders/Get" || e.Code != codes.NotFound || e.Message != "no such order" || e.Peer != addr.String() || e.Stream {
+		t.Errorf("Unexpected event %+v", e)
+	}
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded map[string]interface{}
+	_ = json.Unmarshal(data, &decoded)
+	if decoded["code"] != "NotFound" || decoded["method"] != "/orders.Orders/Get" {
+		t.Errorf("Unexpected JSON %s", data)
+	}
+}
@@ -0,0 +1,223 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// B3 and Jaeger header names
+const (
+	b3SingleHeader  = "b3"
+	b3TraceIDHeader = "x-b3-traceid"
+	b3SpanIDHeader  = "x-b3-spanid"
+	b3SampledHeader = "x-b3-sampled"
+	b3FlagsHeader   = "x-b3-flags"
+	b3ParentHeader  = "x-b3-parentspanid"
+
+	jaegerHeader = "uber-trace-id"
+)
+
+// B3Encoding selects the header format written by the B3 propagator
+type B3Encoding int
+
+const (
+	// B3MultipleHeader writes X-B3-TraceId, X-B3-SpanId and X-B3-Sampled (Istio/Envoy default)
+	B3MultipleHeader B3Encoding = 1 << iota
+
+	// B3SingleHeader writes a single "b3: {TraceId}-{SpanId}-{SamplingState}" header
+	B3SingleHeader
+)
+
+// B3 propagates trace context in Zipkin B3 headers. Extraction accepts both the single
+// and the multiple header format; InjectEncoding selects what Inject writes (the two
+// encodings can be combined with |).
+// Default: B3MultipleHeader
+type B3 struct {
+	InjectEncoding B3Encoding
+}
+
+var _ propagation.TextMapPropagator = B3{}
+
+// Inject writes the span context of ctx into the carrier
+func (b B3) Inject(ctx context.Context, carrier propagation.TextMapCarrier) {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return
+	}
+
+	sampled := "0"
+	if sc.IsSampled() {
+		sampled = "1"
+	}
+
+	encoding := b.InjectEncoding
+	if encoding == 0 {
+		encoding = B3MultipleHeader
+	}
+
+	if encoding&B3SingleHeader != 0 {
+		carrier.Set(b3SingleHeader, fmt.Sprintf("%s-%s-%s", sc.TraceID(), sc.SpanID(), sampled))
+	}
+	if encoding&B3MultipleHeader != 0 {
+		carrier.Set(b3TraceIDHeader, sc.TraceID().String())
+		carrier.Set(b3SpanIDHeader, sc.SpanID().String())
+		carrier.Set(b3SampledHeader, sampled)
+	}
+}
+
+// Extract reads a remote span context from the carrier. The single header wins when both
+// formats are present.
+func (b B3) Extract(ctx context.Context, carrier propagation.TextMapCarrier) context.Context {
+	if header := carrier.Get(b3SingleHeader); header != "" {
+		if sc, ok := parseB3Single(header); ok {
+			return trace.ContextWithRemoteSpanContext(ctx, sc)
+		}
+		return ctx
+	}
+
+	sc, ok := parseB3Multiple(
+		carrier.Get(b3TraceIDHeader),
+		carrier.Get(b3SpanIDHeader),
+		carrier.Get(b3SampledHeader),
+		carrier.Get(b3FlagsHeader),
+	)
+	if !ok {
+		return ctx
+	}
+	return trace.ContextWithRemoteSpanContext(ctx, sc)
+}
+
+// Fields returns the headers the propagator reads and writes
+func (b B3) Fields() []string {
+	return []string{b3SingleHeader, b3TraceIDHeader, b3SpanIDHeader, b3SampledHeader, b3FlagsHeader, b3ParentHeader}
+}
+
+// parseB3Single parses "{TraceId}-{SpanId}[-{SamplingState}[-{ParentSpanId}]]".
+// A header that only carries a sampling decision has no span context.
+func parseB3Single(header string) (trace.SpanContext, bool) {
+	parts := strings.Split(header, "-")
+	if len(parts) < 2 || len(parts) > 4 {
+		return trace.SpanContext{}, false
+	}
+
+	sampled, debug := "", ""
+	if len(parts) >= 3 {
+		if parts[2] == "d" {
+			debug = "1"
+		} else {
+			sampled = parts[2]
+		}
+	}
+	return parseB3Multiple(parts[0], parts[1], sampled, debug)
+}
+
+// parseB3Multiple builds a span context from the individual B3 values
+func parseB3Multiple(traceID, spanID, sampled, flags string) (trace.SpanContext, bool) {
+	if traceID == "" || spanID == "" {
+		return trace.SpanContext{}, false
+	}
+
+	if len(traceID) != 16 && len(traceID) != 32 {
+		return trace.SpanContext{}, false
+	}
+	tid, err := trace.TraceIDFromHex(leftPad(traceID, 32))
+	if err != nil {
+		return trace.SpanContext{}, false
+	}
+
+	if len(spanID) != 16 {
+		return trace.SpanContext{}, false
+	}
+	sid, err := trace.SpanIDFromHex(spanID)
+	if err != nil {
+		return trace.SpanContext{}, false
+	}
+
+	config := trace.SpanContextConfig{TraceID: tid, SpanID: sid, Remote: true}
+	if flags == "1" || sampled == "1" || strings.EqualFold(sampled, "true") {
+		config.TraceFlags = trace.FlagsSampled
+	}
+
+	sc := trace.NewSpanContext(config)
+	return sc, sc.IsValid()
+}
+
+// Jaeger propagates trace context in the Jaeger "uber-trace-id" header
+// ({trace-id}:{span-id}:{parent-span-id}:{flags})
+type Jaeger struct{}
+
+var _ propagation.TextMapPropagator = Jaeger{}
+
+// Inject writes the span context of ctx into the carrier
+func (Jaeger) Inject(ctx context.Context, carrier propagation.TextMapCarrier) {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return
+	}
+
+	flags := 0
+	if sc.IsSampled() {
+		flags = 1
+	}
+	carrier.Set(jaegerHeader, fmt.Sprintf("%s:%s:0:%d", sc.TraceID(), sc.SpanID(), flags))
+}
+
+// Extract reads a remote span context from the carrier
+func (Jaeger) Extract(ctx context.Context, carrier propagation.TextMapCarrier) context.Context {
+	header := carrier.Get(jaegerHeader)
+	if header == "" {
+		return ctx
+	}
+	// Some clients URL-encode the header value
+	if unescaped, err := url.QueryUnescape(header); err == nil {
+		header = unescaped
+	}
+
+	parts := strings.Split(header, ":")
+	if len(parts) != 4 || len(parts[0]) == 0 || len(parts[0]) > 32 || len(parts[1]) == 0 || len(parts[1]) > 16 {
+		return ctx
+	}
+
+	tid, err := trace.TraceIDFromHex(leftPad(parts[0], 32))
+	if err != nil {
+		return ctx
+	}
+	sid, err := trace.SpanIDFromHex(leftPad(parts[1], 16))
+	if err != nil {
+		return ctx
+	}
+
+	var flags int
+	if _, err := fmt.Sscanf(parts[3], "%x", &flags); err != nil {
+		return ctx
+	}
+
+	config := trace.SpanContextConfig{TraceID: tid, SpanID: sid, Remote: true}
+	if flags&0x1 != 0 || flags&0x2 != 0 { // Sampled or debug
+		config.TraceFlags = trace.FlagsSampled
+	}
+
+	sc := trace.NewSpanContext(config)
+	if !sc.IsValid() {
+		return ctx
+	}
+	return trace.ContextWithRemoteSpanContext(ctx, sc)
+}
+
+// Fields returns the headers the propagator reads and writes
+func (Jaeger) Fields() []string {
+	return []string{jaegerHeader}
+}
+
+// leftPad pads a hex ID with leading zeros
+func leftPad(id string, length int) string {
+	if len(id) >= length {
+		return id
+	}
+	return strings.Repeat("0", length-len(id)) + id
+}
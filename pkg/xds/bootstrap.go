@@ -0,0 +1,212 @@
+// Package xds helps gRPC clients and servers run in a proxyless service mesh: it generates
+// the bootstrap file gRPC's xDS resolver reads, builds xds:// targets and exports the health
+// of the endpoints the management server assigns as guardian metrics.
+//
+// The resolver itself lives in google.golang.org/grpc/xds, which applications import for
+// its side effects:
+//
+//	import _ "google.golang.org/grpc/xds"
+package xds
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Environment variables gRPC reads the bootstrap from: a file path, or the JSON itself
+const (
+	BootstrapFileEnv   = "GRPC_XDS_BOOTSTRAP"
+	BootstrapConfigEnv = "GRPC_XDS_BOOTSTRAP_CONFIG"
+)
+
+// Bootstrap is the xDS bootstrap configuration: which management server to ask and how the
+// node identifies itself to it
+type Bootstrap struct {
+	XDSServers                         []Server                       `json:"xds_servers"`
+	Node                               Node                           `json:"node"`
+	CertificateProviders               map[string]CertificateProvider `json:"certificate_providers,omitempty"`
+	ServerListenerResourceNameTemplate string                         `json:"server_listener_resource_name_template,omitempty"`
+}
+
+// Server is an xDS management server
+type Server struct {
+	ServerURI      string         `json:"server_uri"`
+	ChannelCreds   []ChannelCreds `json:"channel_creds"`
+	ServerFeatures []string       `json:"server_features,omitempty"`
+}
+
+// ChannelCreds are the credentials used to reach a management server: "insecure",
+// "google_default" or "tls"
+type ChannelCreds struct {
+	Type   string          `json:"type"`
+	Config json.RawMessage `json:"config,omitempty"`
+}
+
+// Node identifies the client to the management server
+type Node struct {
+	ID       string                 `json:"id"`
+	Cluster  string                 `json:"cluster,omitempty"`
+	Locality *Locality              `json:"locality,omitempty"`
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// Locality is where the node runs, used for locality-aware load balancing
+type Locality struct {
+	Region  string `json:"region,omitempty"`
+	Zone    string `json:"zone,omitempty"`
+	SubZone string `json:"sub_zone,omitempty"`
+}
+
+// CertificateProvider supplies the certificates of xDS-managed mTLS
+type CertificateProvider struct {
+	PluginName string          `json:"plugin_name"`
+	Config     json.RawMessage `json:"config"`
+}
+
+// BootstrapOption configures a Bootstrap
+type BootstrapOption func(*Bootstrap)
+
+// WithNodeCluster sets the node's cluster, usually the service name
+func WithNodeCluster(cluster string) BootstrapOption {
+	return func(b *Bootstrap) {
+		b.Node.Cluster = cluster
+	}
+}
+
+// WithNodeLocality sets the node's region, zone and sub-zone
+func WithNodeLocality(region, zone, subZone string) BootstrapOption {
+	return func(b *Bootstrap) {
+		b.Node.Locality = &Locality{Region: region, Zone: zone, SubZone: subZone}
+	}
+}
+
+// WithNodeMetadata adds metadata sent to the management server with every request
+func WithNodeMetadata(key string, value interface{}) BootstrapOption {
+	return func(b *Bootstrap) {
+		if b.Node.Metadata == nil {
+			b.Node.Metadata = make(map[string]interface{})
+		}
+		b.Node.Metadata[key] = value
+	}
+}
+
+// WithChannelCreds sets the credentials used to reach the management server
+// Default: "insecure"
+func WithChannelCreds(credsType string) BootstrapOption {
+	return func(b *Bootstrap) {
+		b.XDSServers[0].ChannelCreds = []ChannelCreds{{Type: credsType}}
+	}
+}
+
+// WithIgnoreResourceDeletion keeps using resources the management server deletes, so a
+// misconfigured control plane can't take down traffic
+func WithIgnoreResourceDeletion() BootstrapOption {
+	return func(b *Bootstrap) {
+		b.XDSServers[0].ServerFeatures = append(b.XDSServers[0].ServerFeatures, "ignore_resource_deletion")
+	}
+}
+
+// WithFileWatcherCertificates adds a "file_watcher" certificate provider named instance that
+// reloads the certificate, key and CA files every refresh seconds, as written by SPIFFE or
+// cert-manager agents
+func WithFileWatcherCertificates(instance, certFile, keyFile, caFile string, refreshSeconds int) BootstrapOption {
+	return func(b *Bootstrap) {
+		config, _ := json.Marshal(map[string]interface{}{
+			"certificate_file":    certFile,
+			"private_key_file":    keyFile,
+			"ca_certificate_file": caFile,
+			"refresh_interval":    fmt.Sprintf("%ds", refreshSeconds),
+		})
+		if b.CertificateProviders == nil {
+			b.CertificateProviders = make(map[string]CertificateProvider)
+		}
+		b.CertificateProviders[instance] = CertificateProvider{PluginName: "file_watcher", Config: config}
+	}
+}
+
+// WithServerListenerTemplate sets the Listener resource name xDS-enabled servers request,
+// e.g. "grpc/server?xds.resource.listening_address=%s"
+func WithServerListenerTemplate(template string) BootstrapOption {
+	return func(b *Bootstrap) {
+		b.ServerListenerResourceNameTemplate = template
+	}
+}
+
+// NewBootstrap creates the bootstrap of a node talking to the management server at
+// serverURI, e.g. "istiod.istio-system.svc:15010"
+//
+// Example usage:
+//
+//	bootstrap := xds.NewBootstrap("traffic-director.googleapis.com:443", "projects/123/networks/default/nodes/orders-1",
+//	    xds.WithChannelCreds("google_default"),
+//	    xds.WithNodeLocality("us-central1", "us-central1-a", ""),
+//	)
+//	if err := bootstrap.Install(); err != nil {
+//	    log.Fatal(err)
+//	}
+//	conn, err := grpc.Dial(xds.Target("orders"), grpc.WithTransportCredentials(insecure.NewCredentials()))
+func NewBootstrap(serverURI, nodeID string, opts ...BootstrapOption) *Bootstrap {
+	b := &Bootstrap{
+		XDSServers: []Server{{
+			ServerURI:      serverURI,
+			ChannelCreds:   []ChannelCreds{{Type: "insecure"}},
+			ServerFeatures: []string{"xds_v3"},
+		}},
+		Node: Node{ID: nodeID},
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+	return b
+}
+
+// Validate checks the fields gRPC requires
+func (b *Bootstrap) Validate() error {
+	if len(b.XDSServers) == 0 {
+		return fmt.Errorf("xds: bootstrap has no xds_servers")
+	}
+	for i, server := range b.XDSServers {
+		if server.ServerURI == "" {
+			return fmt.Errorf("xds: xds_servers[%d] has no server_uri", i)
+		}
+		if len(server.ChannelCreds) == 0 {
+			return fmt.Errorf("xds: xds_servers[%d] has no channel_creds", i)
+		}
+	}
+	if b.Node.ID == "" {
+		return fmt.Errorf("xds: bootstrap has no node id")
+	}
+	return nil
+}
+
+// JSON returns the bootstrap in the format gRPC reads
+func (b *Bootstrap) JSON() ([]byte, error) {
+	if err := b.Validate(); err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(b, "", "  ")
+}
+
+// WriteFile writes the bootstrap to path, e.g. from an init container, for processes
+// started with GRPC_XDS_BOOTSTRAP=path
+func (b *Bootstrap) WriteFile(path string) error {
+	data, err := b.JSON()
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("xds: failed to write bootstrap: %w", err)
+	}
+	return nil
+}
+
+// Install sets GRPC_XDS_BOOTSTRAP_CONFIG so gRPC in this process uses the bootstrap. Call it
+// before the first xds:// connection is dialed or xDS server is created.
+func (b *Bootstrap) Install() error {
+	data, err := b.JSON()
+	if err != nil {
+		return err
+	}
+	return os.Setenv(BootstrapConfigEnv, string(data))
+}
@@ -0,0 +1,20 @@
+package xds
+
+import (
+	"github.com/grpc-guardian/grpc-guardian/pkg/metrics"
+	"github.com/grpc-guardian/grpc-guardian/pkg/servicemesh"
+)
+
+// HealthMetrics exports the EDS health of every endpoint an XDSClient discovers
+//
+// Example usage:
+//
+//	collector, _ := metrics.NewXDSEndpointCollector(nil)
+//	client := servicemesh.NewXDSClient("http://istiod:15014", "orders-1",
+//	    xds.HealthMetrics(collector),
+//	)
+//	client.Watch("outbound|8080||inventory.default.svc.cluster.local")
+//	go client.Run(ctx)
+func HealthMetrics(collector *metrics.XDSEndpointCollector) servicemesh.XDSOption {
+	return servicemesh.WithXDSHealthObserver(collector.RecordEndpoints)
+}
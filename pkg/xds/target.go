@@ -0,0 +1,39 @@
+package xds
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Scheme is the resolver scheme of xDS targets
+const Scheme = "xds"
+
+// Target returns the xDS target of a service, resolved through the management server of
+// the bootstrap, e.g. "xds:///orders.example.com:8080"
+func Target(service string) string {
+	return Scheme + ":///" + service
+}
+
+// AuthorityTarget returns the xDS target of a service resolved through a named authority
+// of the bootstrap (xDS federation), e.g. "xds://mesh.example.com/orders"
+func AuthorityTarget(authority, service string) string {
+	return Scheme + "://" + authority + "/" + service
+}
+
+// IsTarget reports whether target is resolved by xDS
+func IsTarget(target string) bool {
+	return strings.HasPrefix(target, Scheme+"://")
+}
+
+// ParseTarget returns the authority and service of an xDS target
+func ParseTarget(target string) (authority, service string, err error) {
+	if !IsTarget(target) {
+		return "", "", fmt.Errorf("xds: %q is not an xds:// target", target)
+	}
+	rest := strings.TrimPrefix(target, Scheme+"://")
+	authority, service, found := strings.Cut(rest, "/")
+	if !found || service == "" {
+		return "", "", fmt.Errorf("xds: %q has no service", target)
+	}
+	return authority, service, nil
+}
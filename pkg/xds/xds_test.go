@@ -0,0 +1,154 @@
+package xds
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/grpc-guardian/grpc-guardian/pkg/metrics"
+	"github.com/grpc-guardian/grpc-guardian/pkg/servicemesh"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestBootstrap_JSON(t *testing.T) {
+	bootstrap := NewBootstrap("istiod.istio-system.svc:15010", "sidecar~10.0.0.1~orders-1.default~default.svc.cluster.local",
+		WithNodeCluster("orders"),
+		WithNodeLocality("us-east1", "us-east1-b", ""),
+		WithNodeMetadata("TRAFFICDIRECTOR_NETWORK_NAME", "default"),
+		WithIgnoreResourceDeletion(),
+		WithFileWatcherCertificates("default", "/certs/cert.pem", "/certs/key.pem", "/certs/ca.pem", 600),
+	)
+
+	data, err := bootstrap.JSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	server := decoded["xds_servers"].([]interface{})[0].(map[string]interface{})
+	if server["server_uri"] != "istiod.istio-system.svc:15010" {
+		t.Errorf("Expected the server URI, got %v", server["server_uri"])
+	}
+	if creds := server["channel_creds"].([]interface{})[0].(map[string]interface{}); creds["type"] != "insecure" {
+		t.Errorf("Expected insecure channel creds by default, got %v", creds)
+	}
+	if features := server["server_features"].([]interface{}); len(features) != 2 || features[0] != "xds_v3" || features[1] != "ignore_resource_deletion" {
+		t.Errorf("Expected xds_v3 and ignore_resource_deletion, got %v", features)
+	}
+
+	node := decoded["node"].(map[string]interface{})
+	if node["cluster"] != "orders" || node["locality"].(map[string]interface{})["zone"] != "us-east1-b" {
+		t.Errorf("Expected the node cluster and locality, got %v", node)
+	}
+	provider := decoded["certificate_providers"].(map[string]interface{})["default"].(map[string]interface{})
+	if provider["plugin_name"] != "file_watcher" || provider["config"].(map[string]interface{})["refresh_interval"] != "600s" {
+		t.Errorf("Expected a file_watcher certificate provider, got %v", provider)
+	}
+}
+
+func TestBootstrap_Validate(t *testing.T) {
+	if _, err := NewBootstrap("", "node").JSON(); err == nil {
+		t.Error("Expected a bootstrap without a server URI to be rejected")
+	}
+	if _, err := NewBootstrap("xds:15010", "").JSON(); err == nil {
+		t.Error("Expected a bootstrap without a node id to be rejected")
+	}
+}
+
+func TestBootstrap_Install(t *testing.T) {
+	t.Setenv(BootstrapConfigEnv, "")
+
+	if err := NewBootstrap("xds:15010", "node-1", WithChannelCreds("google_default")).Install(); err != nil {
+		t.Fatal(err)
+	}
+	config := os.Getenv(BootstrapConfigEnv)
+	if !strings.Contains(config, `"google_default"`) || !strings.Contains(config, `"node-1"`) {
+		t.Errorf("Expected the bootstrap in %s, got %s", BootstrapConfigEnv, config)
+	}
+}
+
+func TestTarget(t *testing.T) {
+	if got := Target("orders:8080"); got != "xds:///orders:8080" {
+		t.Errorf("Expected xds:///orders:8080, got %s", got)
+	}
+
+	authority, service, err := ParseTarget(AuthorityTarget("mesh.example.com", "orders"))
+	if err != nil || authority != "mesh.example.com" || service != "orders" {
+		t.Errorf("Expected mesh.example.com and orders, got %q %q %v", authority, service, err)
+	}
+
+	if IsTarget("dns:///orders:8080") {
+		t.Error("Expected a dns target not to be an xds target")
+	}
+	if _, _, err := ParseTarget("xds:///"); err == nil {
+		t.Error("Expected a target without a service to be rejected")
+	}
+}
+
+func TestHealthMetrics(t *testing.T) {
+	unhealthy := "UNHEALTHY"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{
+			"version_info": "1",
+			"resources": [{
+				"cluster_name": "inventory",
+				"endpoints": [{"lb_endpoints": [
+					{"endpoint": {"address": {"socket_address": {"address": "10.0.0.1", "port_value": 8080}}}, "health_status": "HEALTHY"},
+					{"endpoint": {"address": {"socket_address": {"address": "10.0.0.2", "port_value": 8080}}}, "health_status": "` + unhealthy + `"}
+				]}]
+			}]
+		}`))
+	}))
+	defer server.Close()
+
+	registry := prometheus.NewRegistry()
+	collector, err := metrics.NewXDSEndpointCollector(registry)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := servicemesh.NewXDSClient(server.URL, "orders-1", HealthMetrics(collector))
+	client.Watch("inventory")
+	if err := client.Fetch(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `
+# HELP grpc_xds_endpoints Number of xDS endpoints by cluster and EDS health status
+# TYPE grpc_xds_endpoints gauge
+grpc_xds_endpoints{cluster="inventory",status="HEALTHY"} 1
+grpc_xds_endpoints{cluster="inventory",status="UNHEALTHY"} 1
+`
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(expected), "grpc_xds_endpoints"); err != nil {
+		t.Error(err)
+	}
+	if health := client.EndpointHealth("inventory"); health["10.0.0.2:8080"] != "UNHEALTHY" {
+		t.Errorf("Expected the unhealthy endpoint to be reported, got %v", health)
+	}
+
+	// A recovered endpoint moves to its new status instead of keeping the old series
+	unhealthy = "HEALTHY"
+	if err := client.Fetch(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	expected = `
+# HELP grpc_xds_endpoint_health EDS health status of xDS endpoints (1 for the current status)
+# TYPE grpc_xds_endpoint_health gauge
+grpc_xds_endpoint_health{cluster="inventory",endpoint="10.0.0.1:8080",status="HEALTHY"} 1
+grpc_xds_endpoint_health{cluster="inventory",endpoint="10.0.0.2:8080",status="HEALTHY"} 1
+# HELP grpc_xds_endpoints Number of xDS endpoints by cluster and EDS health status
+# TYPE grpc_xds_endpoints gauge
+grpc_xds_endpoints{cluster="inventory",status="HEALTHY"} 2
+`
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(expected)); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,37 @@
+package guardian
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+)
+
+// unaryPlan is a unary chain resolved once: the middleware that apply to a method are
+// selected when the plan is compiled, not on every call.
+type unaryPlan struct {
+	middlewares []Middleware
+}
+
+// compileUnary creates a plan for the middleware
+func compileUnary(middlewares []Middleware) *unaryPlan {
+	return &unaryPlan{middlewares: middlewares}
+}
+
+// run executes the chain for one call. Each middleware gets a handler capturing the call's
+// info and the rest of the chain, so it may call it any number of times, from any goroutine
+// and with any context, during or after the call. The last middleware gets the final
+// handler itself, which saves a closure per call.
+func (p *unaryPlan) run(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if len(p.middlewares) == 0 {
+		return handler(ctx, req)
+	}
+
+	next := handler
+	for i := len(p.middlewares) - 1; i > 0; i-- {
+		middleware, rest := p.middlewares[i], next
+		next = func(ctx context.Context, req interface{}) (interface{}, error) {
+			return middleware(ctx, req, info, rest)
+		}
+	}
+	return p.middlewares[0](ctx, req, info, next)
+}
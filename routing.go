@@ -0,0 +1,146 @@
+package guardian
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// maxMethodPlans bounds the plans a routed chain caches. Methods beyond it, e.g. the
+// arbitrary names an UnknownServiceHandler receives, are routed on every call.
+const maxMethodPlans = 4096
+
+// methodPlans holds the compiled plan per method
+type methodPlans struct {
+	plans sync.Map
+	size  atomic.Int64
+}
+
+// methodRoute restricts a middleware to the methods it matches
+type methodRoute struct {
+	match       func(fullMethod string) bool
+	description string
+}
+
+// ForMethods adds middleware that only run for methods matching the pattern. Patterns are
+// exact methods ("/pkg.Service/Method"), prefixes ending in "*" ("/pkg.Service/*") or "*".
+//
+// Example usage:
+//
+//	chain := guardian.NewChain(middleware.Logging()).
+//	    ForMethods("/shop.Catalog/*", middleware.Cache()).
+//	    ExceptService("grpc.health.v1.Health", middleware.Auth(validator))
+func (c *Chain) ForMethods(pattern string, middlewares ...Middleware) *Chain {
+	return c.route(&methodRoute{match: matchPattern(pattern), description: pattern}, middlewares)
+}
+
+// ForService adds middleware that only run for methods of the service ("pkg.Service")
+func (c *Chain) ForService(service string, middlewares ...Middleware) *Chain {
+	return c.ForMethods("/"+service+"/*", middlewares...)
+}
+
+// ExceptMethods adds middleware that run for every method except those matching the pattern
+func (c *Chain) ExceptMethods(pattern string, middlewares ...Middleware) *Chain {
+	match := matchPattern(pattern)
+	return c.route(&methodRoute{
+		match:       func(fullMethod string) bool { return !match(fullMethod) },
+		description: "!" + pattern,
+	}, middlewares)
+}
+
+// ExceptService adds middleware that run for every method except those of the service
+func (c *Chain) ExceptService(service string, middlewares ...Middleware) *Chain {
+	return c.ExceptMethods("/"+service+"/*", middlewares...)
+}
+
+// When adds middleware that only run for methods the function accepts, e.g. a
+// classify.Classifier's IsRead. The function is called once per method and the
+// result is cached, so it must not depend on anything but the method name.
+func (c *Chain) When(match func(fullMethod string) bool, middlewares ...Middleware) *Chain {
+	return c.route(&methodRoute{match: match, description: "func"}, middlewares)
+}
+
+// route appends routed middleware to the chain
+func (c *Chain) route(route *methodRoute, middlewares []Middleware) *Chain {
+	c.alignRoutes()
+	for range middlewares {
+		c.routes = append(c.routes, route)
+		c.entries = append(c.entries, chainEntry{unary: true, methods: route.description})
+	}
+	c.middlewares = append(c.middlewares, middlewares...)
+	c.routed = true
+	c.methodCache = new(methodPlans)
+	return c
+}
+
+// resetRoutes keeps the routes in line after middleware were added without a route
+func (c *Chain) resetRoutes(prepended int) {
+	if !c.routed {
+		return
+	}
+	if prepended > 0 {
+		c.routes = append(make([]*methodRoute, prepended), c.routes...)
+	}
+	c.methodCache = new(methodPlans)
+}
+
+// alignRoutes pads the routes so they line up with the middleware
+func (c *Chain) alignRoutes() {
+	for len(c.routes) < len(c.middlewares) {
+		c.routes = append(c.routes, nil)
+	}
+}
+
+// planFor returns the compiled chain for a method. Routing is resolved once per method
+// and cached for up to maxMethodPlans methods; chains without routes share one plan.
+func (c *Chain) planFor(fullMethod string) *unaryPlan {
+	if !c.routed {
+		if plan := c.plan.Load(); plan != nil {
+			return plan
+		}
+		plan := compileUnary(c.middlewares)
+		c.plan.Store(plan)
+		return plan
+	}
+
+	cache := c.methodCache
+	if cached, ok := cache.plans.Load(fullMethod); ok {
+		return cached.(*unaryPlan)
+	}
+	plan := compileUnary(c.middlewaresFor(fullMethod))
+	if cache.size.Load() >= maxMethodPlans {
+		return plan
+	}
+	if cached, loaded := cache.plans.LoadOrStore(fullMethod, plan); loaded {
+		return cached.(*unaryPlan)
+	}
+	cache.size.Add(1)
+	return plan
+}
+
+// middlewaresFor returns the middleware that apply to a method
+func (c *Chain) middlewaresFor(fullMethod string) []Middleware {
+	if !c.routed {
+		return c.middlewares
+	}
+
+	selected := make([]Middleware, 0, len(c.middlewares))
+	for i, middleware := range c.middlewares {
+		if i >= len(c.routes) || c.routes[i] == nil || c.routes[i].match(fullMethod) {
+			selected = append(selected, middleware)
+		}
+	}
+	return selected
+}
+
+// matchPattern compiles a method pattern
+func matchPattern(pattern string) func(string) bool {
+	switch {
+	case pattern == "*":
+		return func(string) bool { return true }
+	case strings.HasSuffix(pattern, "*"):
+		prefix := strings.TrimSuffix(pattern, "*")
+		return func(fullMethod string) bool { return strings.HasPrefix(fullMethod, prefix) }
+	}
+	return func(fullMethod string) bool { return fullMethod == pattern }
+}
@@ -0,0 +1,293 @@
+package guardian
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	grpchealth "google.golang.org/grpc/health"
+	"google.golang.org/grpc/status"
+)
+
+// Server coordinates the graceful shutdown of a grpc.Server. On shutdown it reports
+// NOT_SERVING on the health server, keeps serving for a drain period so load balancers
+// can react, then rejects new requests with Unavailable, waits for in-flight requests
+// with GracefulStop up to a hard deadline and finally runs the shutdown hooks (e.g.
+// flushing tracers and metrics).
+type Server struct {
+	health      *grpchealth.Server
+	drainPeriod time.Duration
+	stopTimeout time.Duration
+	hookTimeout time.Duration
+	signals     []os.Signal
+	hooks       []shutdownHook
+
+	mu         sync.Mutex
+	grpcServer *grpc.Server
+	draining   atomic.Bool
+	shutdown   sync.Once
+	shutdownCh chan struct{}
+	err        error
+}
+
+// shutdownHook is a named function run after the server has stopped
+type shutdownHook struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+// ShutdownOption configures a Server
+type ShutdownOption func(*Server)
+
+// WithHealthServer sets the health server flipped to NOT_SERVING when shutdown starts
+func WithHealthServer(health *grpchealth.Server) ShutdownOption {
+	return func(s *Server) {
+		s.health = health
+	}
+}
+
+// WithDrainPeriod sets how long the server keeps accepting requests after reporting
+// NOT_SERVING, so load balancers and service meshes stop routing to it
+// Default: 5s
+func WithDrainPeriod(d time.Duration) ShutdownOption {
+	return func(s *Server) {
+		if d >= 0 {
+			s.drainPeriod = d
+		}
+	}
+}
+
+// WithStopTimeout sets how long GracefulStop may wait for in-flight requests before the
+// server is stopped forcefully
+// Default: 30s
+func WithStopTimeout(d time.Duration) ShutdownOption {
+	return func(s *Server) {
+		if d > 0 {
+			s.stopTimeout = d
+		}
+	}
+}
+
+// WithShutdownHook adds a function run after the server has stopped, in registration
+// order, e.g. a tracer provider's Shutdown
+func WithShutdownHook(name string, hook func(ctx context.Context) error) ShutdownOption {
+	return func(s *Server) {
+		s.hooks = append(s.hooks, shutdownHook{name: name, fn: hook})
+	}
+}
+
+// WithHookTimeout sets the time the shutdown hooks get in total
+// Default: 10s
+func WithHookTimeout(d time.Duration) ShutdownOption {
+	return func(s *Server) {
+		if d > 0 {
+			s.hookTimeout = d
+		}
+	}
+}
+
+// WithShutdownSignals sets the signals that start the shutdown in Serve
+// (none disables signal handling)
+// Default: SIGINT, SIGTERM
+func WithShutdownSignals(signals ...os.Signal) ShutdownOption {
+	return func(s *Server) {
+		s.signals = signals
+	}
+}
+
+// NewServer creates a shutdown coordinator. Install its interceptors first on the
+// grpc.Server so draining rejects requests before any other middleware runs.
+//
+// Example usage:
+//
+//	healthServer := health.NewServer()
+//	srv := guardian.NewServer(
+//	    guardian.WithHealthServer(healthServer),
+//	    guardian.WithDrainPeriod(10*time.Second),
+//	    guardian.WithShutdownHook("tracing", tracerProvider.Shutdown),
+//	)
+//
+//	grpcServer := grpc.NewServer(append(srv.ServerOptions(), chain.ChainServerOptions()...)...)
+//	healthpb.RegisterHealthServer(grpcServer, healthServer)
+//	pb.RegisterOrdersServer(grpcServer, orders)
+//
+//	// Blocks until SIGINT/SIGTERM has been handled
+//	if err := srv.Serve(grpcServer, lis); err != nil {
+//	    log.Fatal(err)
+//	}
+func NewServer(opts ...ShutdownOption) *Server {
+	s := &Server{
+		drainPeriod: 5 * time.Second,
+		stopTimeout: 30 * time.Second,
+		hookTimeout: 10 * time.Second,
+		signals:     []os.Signal{syscall.SIGINT, syscall.SIGTERM},
+		shutdownCh:  make(chan struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// ServerOptions returns the draining interceptors as chained gRPC server options
+func (s *Server) ServerOptions() []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(s.UnaryServerInterceptor()),
+		grpc.ChainStreamInterceptor(s.StreamServerInterceptor()),
+	}
+}
+
+// UnaryServerInterceptor rejects new unary calls with Unavailable once the drain period is over
+func (s *Server) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if s.draining.Load() {
+			return nil, errDraining
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor rejects new streams with Unavailable once the drain period is over
+func (s *Server) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if s.draining.Load() {
+			return errDraining
+		}
+		return handler(srv, ss)
+	}
+}
+
+// errDraining is returned for requests arriving after the drain period
+var errDraining = status.Error(codes.Unavailable, "server is shutting down\nHint: Retry the request against another instance")
+
+// Draining reports whether new requests are being rejected
+func (s *Server) Draining() bool {
+	return s.draining.Load()
+}
+
+// Serve serves grpcServer on lis until a shutdown signal is received or Shutdown is
+// called, and returns once the shutdown has completed. Errors from serving and from the
+// shutdown hooks are returned.
+func (s *Server) Serve(grpcServer *grpc.Server, lis net.Listener) error {
+	select {
+	case <-s.shutdownCh:
+		return fmt.Errorf("guardian: server is already shutting down")
+	default:
+	}
+
+	s.mu.Lock()
+	s.grpcServer = grpcServer
+	s.mu.Unlock()
+
+	signals := make(chan os.Signal, 1)
+	if len(s.signals) > 0 {
+		signal.Notify(signals, s.signals...)
+		defer signal.Stop(signals)
+	}
+
+	served := make(chan error, 1)
+	go func() {
+		served <- grpcServer.Serve(lis)
+	}()
+
+	select {
+	case <-signals:
+		return s.Shutdown(context.Background())
+	case <-s.shutdownCh:
+		// Shutdown was called directly; wait for it to complete
+		<-served
+		return s.wait()
+	case err := <-served:
+		if err != nil {
+			return fmt.Errorf("guardian: serve: %w", err)
+		}
+		return s.wait()
+	}
+}
+
+// Shutdown runs the shutdown sequence once; later calls wait for the first one and return
+// its result. ctx bounds the drain period; the stop timeout and hook timeout still apply.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.shutdown.Do(func() {
+		close(s.shutdownCh)
+		s.err = s.runShutdown(ctx)
+	})
+	return s.err
+}
+
+// wait returns the shutdown result if a shutdown has started
+func (s *Server) wait() error {
+	select {
+	case <-s.shutdownCh:
+		return s.Shutdown(context.Background())
+	default:
+		return nil
+	}
+}
+
+// runShutdown performs the shutdown phases
+func (s *Server) runShutdown(ctx context.Context) error {
+	// 1. Tell load balancers to stop sending traffic
+	if s.health != nil {
+		s.health.Shutdown()
+	}
+
+	// 2. Keep serving while they react
+	if s.drainPeriod > 0 {
+		timer := time.NewTimer(s.drainPeriod)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+		}
+	}
+
+	// 3. Reject new requests and wait for in-flight ones
+	s.draining.Store(true)
+
+	s.mu.Lock()
+	grpcServer := s.grpcServer
+	s.mu.Unlock()
+
+	var errs []error
+	if grpcServer != nil {
+		stopped := make(chan struct{})
+		go func() {
+			grpcServer.GracefulStop()
+			close(stopped)
+		}()
+
+		timer := time.NewTimer(s.stopTimeout)
+		select {
+		case <-stopped:
+			timer.Stop()
+		case <-timer.C:
+			grpcServer.Stop()
+			<-stopped
+			errs = append(errs, fmt.Errorf("guardian: graceful stop exceeded %s, remaining requests were cancelled", s.stopTimeout))
+		}
+	}
+
+	// 4. Flush tracers, metrics and other resources
+	hookCtx, cancel := context.WithTimeout(context.Background(), s.hookTimeout)
+	defer cancel()
+
+	for _, hook := range s.hooks {
+		if err := hook.fn(hookCtx); err != nil {
+			errs = append(errs, fmt.Errorf("guardian: shutdown hook %q: %w", hook.name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,131 @@
+package guardian_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	guardian "github.com/grpc-guardian/grpc-guardian"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func TestServer_Shutdown(t *testing.T) {
+	healthServer := health.NewServer()
+	var hooks []string
+
+	srv := guardian.NewServer(
+		guardian.WithHealthServer(healthServer),
+		guardian.WithDrainPeriod(50*time.Millisecond),
+		guardian.WithShutdownSignals(),
+		guardian.WithShutdownHook("tracing", func(ctx context.Context) error {
+			hooks = append(hooks, "tracing")
+			return nil
+		}),
+		guardian.WithShutdownHook("metrics", func(ctx context.Context) error {
+			hooks = append(hooks, "metrics")
+			return errors.New("flush failed")
+		}),
+	)
+
+	grpcServer := grpc.NewServer(srv.ServerOptions()...)
+	healthpb.RegisterHealthServer(grpcServer, healthServer)
+
+	served := make(chan error, 1)
+	go func() { served <- srv.Serve(grpcServer, bufconn.Listen(1<<20)) }()
+	time.Sleep(10 * time.Millisecond)
+
+	interceptor := srv.UnaryServerInterceptor()
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) { return "ok", nil }
+	info := &grpc.UnaryServerInfo{FullMethod: "/svc/M"}
+
+	shutdownErr := make(chan error, 1)
+	go func() { shutdownErr <- srv.Shutdown(context.Background()) }()
+	time.Sleep(10 * time.Millisecond)
+
+	// During the drain period health is NOT_SERVING but requests are still served
+	resp, err := healthServer.Check(context.Background(), &healthpb.HealthCheckRequest{})
+	if err != nil || resp.Status != healthpb.HealthCheckResponse_NOT_SERVING {
+		t.Errorf("Expected NOT_SERVING during drain, got %v, %v", resp, err)
+	}
+	if _, err := interceptor(context.Background(), "req", info, handler); err != nil {
+		t.Errorf("Expected requests to be served during the drain period, got %v", err)
+	}
+
+	err = <-shutdownErr
+	if err == nil || len(hooks) != 2 || hooks[0] != "tracing" {
+		t.Errorf("Expected hooks to run in order and report errors, got hooks=%v err=%v", hooks, err)
+	}
+	if serveErr := <-served; serveErr == nil {
+		t.Error("Expected Serve to return the shutdown error")
+	}
+
+	if !srv.Draining() {
+		t.Error("Expected server to be draining")
+	}
+	if _, err := interceptor(context.Background(), "req", info, handler); status.Code(err) != codes.Unavailable {
+		t.Errorf("Expected Unavailable after the drain period, got %v", err)
+	}
+
+	if err := srv.Serve(grpc.NewServer(), bufconn.Listen(1<<20)); err == nil {
+		t.Error("Expected Serve to fail after shutdown")
+	}
+}
+
+func TestServer_StopTimeout(t *testing.T) {
+	srv := guardian.NewServer(
+		guardian.WithDrainPeriod(0),
+		guardian.WithStopTimeout(50*time.Millisecond),
+		guardian.WithShutdownSignals(),
+	)
+
+	release := make(chan struct{})
+	grpcServer := grpc.NewServer(srv.ServerOptions()...)
+	grpcServer.RegisterService(&grpc.ServiceDesc{
+		ServiceName: "test.Slow",
+		HandlerType: (*interface{})(nil),
+		Streams: []grpc.StreamDesc{{
+			StreamName:    "Hang",
+			ServerStreams: true,
+			Handler: func(srv interface{}, stream grpc.ServerStream) error {
+				select {
+				case <-stream.Context().Done():
+				case <-release:
+				}
+				return nil
+			},
+		}},
+	}, struct{}{})
+	defer close(release)
+
+	lis := bufconn.Listen(1 << 20)
+	go srv.Serve(grpcServer, lis)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.NewStream(context.Background(), &grpc.StreamDesc{ServerStreams: true}, "/test.Slow/Hang"); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	start := time.Now()
+	if err := srv.Shutdown(context.Background()); err == nil {
+		t.Error("Expected an error when the stop timeout is exceeded")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected a forced stop after the timeout, took %v", elapsed)
+	}
+}
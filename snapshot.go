@@ -0,0 +1,274 @@
+package guardian
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ConfigSnapshotVersion is the schema version of the document produced by Chain.ConfigSnapshot.
+// It is bumped whenever the layout of the document changes in a way tooling must know about.
+const ConfigSnapshotVersion = 1
+
+// redactedValue replaces secret settings in a config snapshot
+const redactedValue = "[REDACTED]"
+
+// maxSnapshotDepth bounds recursion when walking nested configuration values
+const maxSnapshotDepth = 8
+
+// secretFieldHints are lower-cased substrings of field or map key names that are treated as secrets
+var secretFieldHints = []string{"secret", "password", "passwd", "token", "apikey", "api_key", "privatekey", "private_key", "credential", "pem", "authorization", "cookie"}
+
+// secretFieldSuffixes are lower-cased endings of names that are treated as secrets, e.g.
+// SigningKey, HMACKey, Keys or ClientCert. Plurals are matched too.
+var secretFieldSuffixes = []string{"key", "cert", "certificate"}
+
+// ConfigSnapshot is a machine-readable document describing the effective middleware
+// configuration of a chain. Two replicas running the same configuration produce
+// identical snapshots, so the Checksum can be compared to detect drift.
+type ConfigSnapshot struct {
+	// Version is the snapshot schema version (ConfigSnapshotVersion)
+	Version int `json:"version"`
+
+	// Checksum is the SHA-256 of the serialized middleware settings
+	Checksum string `json:"checksum"`
+
+	// Middleware lists the registered middleware settings in chain order
+	Middleware []MiddlewareConfig `json:"middleware"`
+}
+
+// MiddlewareConfig holds the effective settings of a single middleware
+type MiddlewareConfig struct {
+	Name     string      `json:"name"`
+	Settings interface{} `json:"settings"`
+}
+
+// namedConfig associates a middleware name with its configuration value
+type namedConfig struct {
+	name   string
+	config interface{}
+}
+
+// WithConfig records the effective configuration of a middleware in the chain so it
+// is included in ConfigSnapshot. The config value is usually the middleware's config
+// struct (e.g. middleware.TimeoutConfig or middleware.OAuth2Config).
+//
+// Example usage:
+//
+//	chain := guardian.NewChain(middleware.Auth(middleware.OAuth2Validator(oauthConfig))).
+//	    WithConfig("auth.oauth2", oauthConfig)
+func (c *Chain) WithConfig(name string, config interface{}) *Chain {
+	c.configs = append(c.configs, namedConfig{name: name, config: config})
+	return c
+}
+
+// Snapshot builds the configuration snapshot for the chain.
+// Secret values (fields and map entries named like secrets, such as Password, Token,
+// SigningKey or ClientCert, and fields tagged `guardian:"secret"`) are elided, and
+// functions, channels and other non-serializable values are skipped. Numbers, booleans
+// and durations never hold key material, so MaxTrackedKeys or TokenTTL stay visible.
+func (c *Chain) Snapshot() ConfigSnapshot {
+	snapshot := ConfigSnapshot{
+		Version:    ConfigSnapshotVersion,
+		Middleware: make([]MiddlewareConfig, 0, len(c.configs)),
+	}
+
+	for _, nc := range c.configs {
+		snapshot.Middleware = append(snapshot.Middleware, MiddlewareConfig{
+			Name:     nc.name,
+			Settings: snapshotValue(reflect.ValueOf(nc.config), 0),
+		})
+	}
+
+	// Checksum covers only the settings so it is stable across replicas
+	data, err := json.Marshal(snapshot.Middleware)
+	if err == nil {
+		sum := sha256.Sum256(data)
+		snapshot.Checksum = hex.EncodeToString(sum[:])
+	}
+
+	return snapshot
+}
+
+// ConfigSnapshot returns the chain configuration snapshot as an indented JSON document,
+// suitable for logging at startup or serving from an admin endpoint
+func (c *Chain) ConfigSnapshot() ([]byte, error) {
+	data, err := json.MarshalIndent(c.Snapshot(), "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal config snapshot: %w", err)
+	}
+	return data, nil
+}
+
+// ConfigHandler returns an HTTP handler that serves the chain configuration snapshot as JSON
+func (c *Chain) ConfigHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, err := c.ConfigSnapshot()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(data)
+	})
+}
+
+// snapshotValue converts a configuration value into a JSON-friendly representation
+func snapshotValue(v reflect.Value, depth int) interface{} {
+	if !v.IsValid() {
+		return nil
+	}
+	if depth > maxSnapshotDepth {
+		return v.Type().String()
+	}
+
+	// Durations and timestamps are far more readable as strings
+	switch val := v.Interface().(type) {
+	case time.Duration:
+		return val.String()
+	case time.Time:
+		if val.IsZero() {
+			return nil
+		}
+		return val.Format(time.RFC3339)
+	case fmt.Stringer:
+		if v.Kind() != reflect.Struct && v.Kind() != reflect.Ptr && v.Kind() != reflect.Interface {
+			return val.String()
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Func, reflect.Chan, reflect.UnsafePointer:
+		return nil
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		elem := v.Elem()
+		if elem.Kind() == reflect.Struct && !hasExportedFields(elem.Type()) {
+			// Opaque implementations (loggers, backends, clients) are reported by type
+			return v.Type().String()
+		}
+		if v.Kind() == reflect.Interface && elem.Kind() == reflect.Ptr && elem.Elem().Kind() == reflect.Struct {
+			return elem.Type().String()
+		}
+		return snapshotValue(elem, depth+1)
+	case reflect.Struct:
+		out := make(map[string]interface{})
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if field.PkgPath != "" {
+				continue // unexported
+			}
+			name := field.Name
+			if tag := field.Tag.Get("json"); tag != "" {
+				tagName := strings.Split(tag, ",")[0]
+				if tagName == "-" {
+					continue
+				}
+				if tagName != "" {
+					name = tagName
+				}
+			}
+			fv := v.Field(i)
+			if field.Tag.Get("guardian") == "secret" || ((isSecretName(field.Name) || isSecretName(name)) && mayHoldSecret(fv)) {
+				out[name] = redact(fv)
+				continue
+			}
+			if value := snapshotValue(fv, depth+1); value != nil {
+				out[name] = value
+			}
+		}
+		return out
+	case reflect.Map:
+		if v.IsNil() {
+			return nil
+		}
+		out := make(map[string]interface{}, v.Len())
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+		})
+		for _, key := range keys {
+			name := fmt.Sprint(key.Interface())
+			if isSecretName(name) && mayHoldSecret(v.MapIndex(key)) {
+				out[name] = redact(v.MapIndex(key))
+				continue
+			}
+			out[name] = snapshotValue(v.MapIndex(key), depth+1)
+		}
+		return out
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return fmt.Sprintf("<%d bytes>", v.Len())
+		}
+		out := make([]interface{}, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			out = append(out, snapshotValue(v.Index(i), depth+1))
+		}
+		return out
+	default:
+		return v.Interface()
+	}
+}
+
+// redact returns the placeholder for a secret value, preserving whether it was set
+func redact(v reflect.Value) interface{} {
+	if !v.IsValid() || v.IsZero() {
+		return ""
+	}
+	return redactedValue
+}
+
+// isSecretName reports whether a field or key name looks like it holds a secret
+func isSecretName(name string) bool {
+	lower := strings.ToLower(name)
+	for _, hint := range secretFieldHints {
+		if strings.Contains(lower, hint) {
+			return true
+		}
+	}
+	singular := strings.TrimSuffix(lower, "s")
+	for _, suffix := range secretFieldSuffixes {
+		if strings.HasSuffix(singular, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// mayHoldSecret reports whether a value can carry key material; numbers and booleans cannot
+func mayHoldSecret(v reflect.Value) bool {
+	if v.Kind() == reflect.Interface && !v.IsNil() {
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128:
+		return false
+	}
+	return true
+}
+
+// hasExportedFields reports whether a struct type has at least one exported field
+func hasExportedFields(t reflect.Type) bool {
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).PkgPath == "" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,175 @@
+package guardian
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type snapshotTLS struct {
+	CertFile   string
+	ClientCert string
+	CACerts    []string
+	KeyPEM     []byte
+}
+
+type snapshotConfig struct {
+	Name           string        `json:"name"`
+	Timeout        time.Duration `json:"timeout"`
+	Password       string
+	SigningKey     []byte
+	HMACKey        string
+	Keys           []string
+	MaxTrackedKeys int
+	Region         string `guardian:"secret"`
+	Hidden         string `json:"-"`
+	Signer         string `json:"signing_key"`
+	TLS            *snapshotTLS
+	Labels         map[string]interface{}
+	Handler        func()
+	internal       string
+}
+
+func TestSnapshot_Redaction(t *testing.T) {
+	config := snapshotConfig{
+		Name:           "edge",
+		Timeout:        2 * time.Second,
+		Password:       "hunter2",
+		SigningKey:     []byte("k"),
+		HMACKey:        "hmac",
+		Keys:           []string{"k1", "k2"},
+		MaxTrackedKeys: 100,
+		Region:         "eu-1",
+		Hidden:         "x",
+		Signer:         "s",
+		TLS:            &snapshotTLS{CertFile: "/etc/tls/cert.pem", ClientCert: "-----BEGIN", CACerts: []string{"ca"}, KeyPEM: []byte("k")},
+		Labels: map[string]interface{}{
+			"team":   "payments",
+			"key":    "abc",
+			"Key":    "def",
+			"apiKey": "ghi",
+			"limit":  5,
+			"nested": map[string]string{"token": "t", "zone": "a"},
+			"headers": map[string]string{
+				"Authorization": "Bearer abc.def",
+				"Cookie":        "sid=s3ss10n",
+				"Accept":        "application/grpc",
+			},
+		},
+		Handler:  func() {},
+		internal: "x",
+	}
+
+	settings := NewChain().WithConfig("test", &config).Snapshot().Middleware[0].Settings.(map[string]interface{})
+	tls := settings["TLS"].(map[string]interface{})
+	labels := settings["Labels"].(map[string]interface{})
+	nested := labels["nested"].(map[string]interface{})
+	headers := labels["headers"].(map[string]interface{})
+
+	tests := []struct {
+		name string
+		got  interface{}
+		want interface{}
+	}{
+		{"plain string", settings["name"], "edge"},
+		{"duration", settings["timeout"], "2s"},
+		{"password", settings["Password"], redactedValue},
+		{"signing key bytes", settings["SigningKey"], redactedValue},
+		{"hmac key", settings["HMACKey"], redactedValue},
+		{"key list", settings["Keys"], redactedValue},
+		{"counter named like keys", settings["MaxTrackedKeys"], 100},
+		{"secret tag", settings["Region"], redactedValue},
+		{"json tag name", settings["signing_key"], redactedValue},
+		{"pointer cert file path", tls["CertFile"], "/etc/tls/cert.pem"},
+		{"pointer client cert", tls["ClientCert"], redactedValue},
+		{"pointer cert list", tls["CACerts"], redactedValue},
+		{"pointer pem", tls["KeyPEM"], redactedValue},
+		{"map entry", labels["team"], "payments"},
+		{"map key entry", labels["key"], redactedValue},
+		{"map Key entry", labels["Key"], redactedValue},
+		{"map apiKey entry", labels["apiKey"], redactedValue},
+		{"map number", labels["limit"], 5},
+		{"nested map token", nested["token"], redactedValue},
+		{"nested map entry", nested["zone"], "a"},
+		{"authorization header", headers["Authorization"], redactedValue},
+		{"cookie header", headers["Cookie"], redactedValue},
+		{"other header", headers["Accept"], "application/grpc"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.got != tt.want {
+				t.Errorf("Got %v, want %v", tt.got, tt.want)
+			}
+		})
+	}
+
+	for _, name := range []string{"Hidden", "Handler", "internal"} {
+		if _, ok := settings[name]; ok {
+			t.Errorf("Expected %s to be skipped", name)
+		}
+	}
+
+	data, _ := json.Marshal(settings)
+	for _, leaked := range []string{"hunter2", "hmac", "k1", "eu-1", "BEGIN", "abc", "def", "ghi", `"t"`, "Bearer", "s3ss10n"} {
+		if strings.Contains(string(data), leaked) {
+			t.Errorf("Snapshot leaks %q: %s", leaked, data)
+		}
+	}
+}
+
+func TestSnapshot_UnsetSecret(t *testing.T) {
+	settings := NewChain().WithConfig("test", snapshotConfig{}).Snapshot().Middleware[0].Settings.(map[string]interface{})
+	if settings["Password"] != "" || settings["Keys"] != "" {
+		t.Errorf("Expected unset secrets to be reported as empty, got %v and %v", settings["Password"], settings["Keys"])
+	}
+	if _, ok := settings["TLS"]; ok {
+		t.Error("Expected a nil pointer to be omitted")
+	}
+}
+
+func TestSnapshot_Checksum(t *testing.T) {
+	build := func(password string, labels map[string]interface{}) ConfigSnapshot {
+		return NewChain().
+			WithConfig("a", snapshotConfig{Name: "edge", Password: password, Labels: labels}).
+			WithConfig("b", map[string]int{"x": 1, "y": 2, "z": 3}).
+			Snapshot()
+	}
+
+	first := build("one", map[string]interface{}{"a": 1, "b": 2, "c": 3})
+	if first.Checksum == "" || first.Version != ConfigSnapshotVersion {
+		t.Fatalf("Unexpected snapshot header %+v", first)
+	}
+	for i := 0; i < 20; i++ {
+		// Map iteration order and secret contents do not change the checksum
+		if again := build("two", map[string]interface{}{"c": 3, "b": 2, "a": 1}); again.Checksum != first.Checksum {
+			t.Fatalf("Checksum changed between identical configurations: %s != %s", again.Checksum, first.Checksum)
+		}
+	}
+
+	if changed := build("one", map[string]interface{}{"a": 1, "b": 2, "c": 4}); changed.Checksum == first.Checksum {
+		t.Error("Expected a changed setting to change the checksum")
+	}
+	if unset := build("", map[string]interface{}{"a": 1, "b": 2, "c": 3}); unset.Checksum == first.Checksum {
+		t.Error("Expected removing a secret to change the checksum")
+	}
+}
+
+func TestConfigHandler(t *testing.T) {
+	chain := NewChain().WithConfig("auth", map[string]string{"issuer": "https://id.example.com", "client_secret": "s3cr3t"})
+
+	rec := httptest.NewRecorder()
+	chain.ConfigHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/config", nil))
+
+	var snapshot ConfigSnapshot
+	if err := json.Unmarshal(rec.Body.Bytes(), &snapshot); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Header().Get("Content-Type") != "application/json" || snapshot.Checksum != chain.Snapshot().Checksum {
+		t.Errorf("Unexpected response %s", rec.Body)
+	}
+	if strings.Contains(rec.Body.String(), "s3cr3t") {
+		t.Errorf("Expected the secret to be redacted, got %s", rec.Body)
+	}
+}
@@ -0,0 +1,326 @@
+package guardian
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/grpc-guardian/grpc-guardian/pkg/logging"
+	"github.com/grpc-guardian/grpc-guardian/pkg/metrics"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/stats"
+	"google.golang.org/grpc/status"
+)
+
+// StatsHandler is a grpc stats.Handler feeding the metrics collector, tracing and logging
+// from the transport. Unlike interceptors it sees every RPC, including ones rejected
+// before the interceptor chain runs, the wire size of compressed messages, and
+// connection lifecycle.
+//
+// Use it alongside the interceptor chain or instead of it. Enable each subsystem in one
+// place only: with both WithStatsMetrics and MetricsMiddleware, requests are counted twice.
+type StatsHandler struct {
+	client     bool
+	collector  metrics.MetricsCollector
+	sizes      *metrics.SizeStatsHandler
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+	logger     logging.Logger
+}
+
+// StatsOption configures a StatsHandler
+type StatsOption func(*StatsHandler)
+
+// WithStatsMetrics records requests, errors, active requests, message and wire sizes,
+// and open connections to the collector
+func WithStatsMetrics(collector metrics.MetricsCollector) StatsOption {
+	return func(h *StatsHandler) {
+		h.collector = collector
+	}
+}
+
+// WithStatsTracer starts a span per RPC, continuing the trace of incoming requests and
+// propagating it on outgoing calls
+func WithStatsTracer(tracer trace.Tracer) StatsOption {
+	return func(h *StatsHandler) {
+		h.tracer = tracer
+	}
+}
+
+// WithStatsPropagator sets the trace context format
+// Default: otel.GetTextMapPropagator()
+func WithStatsPropagator(propagator propagation.TextMapPropagator) StatsOption {
+	return func(h *StatsHandler) {
+		h.propagator = propagator
+	}
+}
+
+// WithStatsLogger logs every finished RPC, and connections at debug level
+func WithStatsLogger(logger logging.Logger) StatsOption {
+	return func(h *StatsHandler) {
+		h.logger = logger
+	}
+}
+
+// NewStatsHandler creates a stats handler for servers
+//
+// Example usage:
+//
+//	collector, _ := metrics.NewPrometheusCollector()
+//	server := grpc.NewServer(
+//	    grpc.StatsHandler(guardian.NewStatsHandler(
+//	        guardian.WithStatsMetrics(collector),
+//	        guardian.WithStatsTracer(otel.Tracer("orders")),
+//	        guardian.WithStatsLogger(logging.NewZap(logger)),
+//	    )),
+//	    grpc.ChainUnaryInterceptor(chain.UnaryInterceptor()),
+//	)
+func NewStatsHandler(opts ...StatsOption) *StatsHandler {
+	return newStatsHandler(false, opts)
+}
+
+// NewClientStatsHandler creates a stats handler for clients, installed with
+// grpc.WithStatsHandler
+func NewClientStatsHandler(opts ...StatsOption) *StatsHandler {
+	return newStatsHandler(true, opts)
+}
+
+// newStatsHandler applies options
+func newStatsHandler(client bool, opts []StatsOption) *StatsHandler {
+	h := &StatsHandler{
+		client:     client,
+		propagator: otel.GetTextMapPropagator(),
+		logger:     logging.Nop(),
+	}
+
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	if h.collector != nil {
+		h.sizes = metrics.NewSizeStatsHandler(h.collector, metrics.WithUncompressedSizes())
+	}
+	return h
+}
+
+// statsRPCKey and statsConnKey carry per-RPC and per-connection state between calls
+type (
+	statsRPCKey  struct{}
+	statsConnKey struct{}
+)
+
+// statsRPC is the state of one RPC
+type statsRPC struct {
+	method string
+	start  time.Time
+	span   trace.Span
+}
+
+// statsConn is the state of one connection
+type statsConn struct {
+	remote string
+	local  string
+	start  time.Time
+}
+
+// TagRPC starts the span of an RPC
+func (h *StatsHandler) TagRPC(ctx context.Context, info *stats.RPCTagInfo) context.Context {
+	rpc := &statsRPC{method: info.FullMethodName, start: time.Now()}
+
+	if h.tracer != nil {
+		kind := trace.SpanKindServer
+		if h.client {
+			kind = trace.SpanKindClient
+		} else if md, ok := metadata.FromIncomingContext(ctx); ok {
+			ctx = h.propagator.Extract(ctx, statsMetadataCarrier(md))
+		}
+
+		service, method := splitMethod(info.FullMethodName)
+		ctx, rpc.span = h.tracer.Start(ctx, info.FullMethodName,
+			trace.WithSpanKind(kind),
+			trace.WithAttributes(
+				attribute.String("rpc.system", "grpc"),
+				attribute.String("rpc.service", service),
+				attribute.String("rpc.method", method),
+			),
+		)
+
+		if h.client {
+			md, _ := metadata.FromOutgoingContext(ctx)
+			md = md.Copy()
+			h.propagator.Inject(ctx, statsMetadataCarrier(md))
+			ctx = metadata.NewOutgoingContext(ctx, md)
+		}
+	}
+
+	if h.sizes != nil {
+		ctx = h.sizes.TagRPC(ctx, info)
+	}
+	return context.WithValue(ctx, statsRPCKey{}, rpc)
+}
+
+// HandleRPC records the begin, payloads and end of an RPC
+func (h *StatsHandler) HandleRPC(ctx context.Context, s stats.RPCStats) {
+	rpc, ok := ctx.Value(statsRPCKey{}).(*statsRPC)
+	if !ok {
+		return
+	}
+
+	if h.sizes != nil {
+		h.sizes.HandleRPC(ctx, s)
+	}
+
+	switch s := s.(type) {
+	case *stats.Begin:
+		rpc.start = s.BeginTime
+		if h.collector != nil {
+			h.collector.RecordActiveRequests(rpc.method, 1)
+		}
+
+	case *stats.InPayload:
+		if rpc.span != nil {
+			rpc.span.AddEvent("message", trace.WithAttributes(
+				attribute.String("message.type", "RECEIVED"),
+				attribute.Int("message.uncompressed_size", s.Length),
+				attribute.Int("message.compressed_size", s.CompressedLength),
+			))
+		}
+
+	case *stats.OutPayload:
+		if rpc.span != nil {
+			rpc.span.AddEvent("message", trace.WithAttributes(
+				attribute.String("message.type", "SENT"),
+				attribute.Int("message.uncompressed_size", s.Length),
+				attribute.Int("message.compressed_size", s.CompressedLength),
+			))
+		}
+
+	case *stats.End:
+		h.end(ctx, rpc, s)
+	}
+}
+
+// end records a finished RPC
+func (h *StatsHandler) end(ctx context.Context, rpc *statsRPC, s *stats.End) {
+	st := status.Convert(s.Error)
+	duration := s.EndTime.Sub(rpc.start)
+
+	if rpc.span != nil {
+		rpc.span.SetAttributes(attribute.Int("rpc.grpc.status_code", int(st.Code())))
+		if s.Error != nil {
+			rpc.span.RecordError(s.Error)
+			rpc.span.SetStatus(otelcodes.Error, st.Message())
+		}
+		rpc.span.End()
+	}
+
+	if h.collector != nil {
+		h.collector.RecordActiveRequests(rpc.method, -1)
+		if st.Code() != codes.OK {
+			h.collector.RecordError(rpc.method, st.Code().String())
+		}
+
+		// Link the latency to the trace, as MetricsMiddleware does
+		if ec, ok := h.collector.(metrics.ExemplarMetricsCollector); ok {
+			if sc := trace.SpanContextFromContext(ctx); sc.IsValid() && sc.IsSampled() {
+				ec.RecordRequestWithTraceID(rpc.method, st.Code().String(), duration, sc.TraceID().String())
+			} else {
+				h.collector.RecordRequest(rpc.method, st.Code().String(), duration)
+			}
+		} else {
+			h.collector.RecordRequest(rpc.method, st.Code().String(), duration)
+		}
+	}
+
+	kind := "request"
+	if h.client {
+		kind = "call"
+	}
+	fields := []logging.Field{
+		logging.String("grpc_method", rpc.method),
+		logging.Duration("duration", duration),
+		logging.String("grpc_code", st.Code().String()),
+	}
+	if conn, ok := ctx.Value(statsConnKey{}).(*statsConn); ok {
+		fields = append(fields, logging.String("peer", conn.remote))
+	}
+
+	switch st.Code() {
+	case codes.OK:
+		h.logger.Info("gRPC "+kind+" completed", fields...)
+	case codes.Internal, codes.Unknown, codes.DataLoss:
+		h.logger.Error("gRPC "+kind+" failed", append(fields, logging.String("error", st.Message()))...)
+	default:
+		h.logger.Info("gRPC "+kind+" completed with error", append(fields, logging.String("error", st.Message()))...)
+	}
+}
+
+// TagConn remembers the addresses of a connection
+func (h *StatsHandler) TagConn(ctx context.Context, info *stats.ConnTagInfo) context.Context {
+	conn := &statsConn{start: time.Now()}
+	if info.RemoteAddr != nil {
+		conn.remote = info.RemoteAddr.String()
+	}
+	if info.LocalAddr != nil {
+		conn.local = info.LocalAddr.String()
+	}
+	return context.WithValue(ctx, statsConnKey{}, conn)
+}
+
+// HandleConn records connections opening and closing
+func (h *StatsHandler) HandleConn(ctx context.Context, s stats.ConnStats) {
+	conn, ok := ctx.Value(statsConnKey{}).(*statsConn)
+	if !ok {
+		return
+	}
+
+	delta := 0
+	switch s.(type) {
+	case *stats.ConnBegin:
+		delta = 1
+		h.logger.Debug("gRPC connection opened",
+			logging.String("peer", conn.remote), logging.String("local", conn.local))
+	case *stats.ConnEnd:
+		delta = -1
+		h.logger.Debug("gRPC connection closed",
+			logging.String("peer", conn.remote), logging.Duration("duration", time.Since(conn.start)))
+	}
+
+	if cc, ok := h.collector.(metrics.ConnectionMetricsCollector); ok && delta != 0 {
+		cc.RecordOpenConnections(delta)
+	}
+}
+
+// splitMethod splits "/pkg.Service/Method" into service and method
+func splitMethod(fullMethod string) (string, string) {
+	service, method, _ := strings.Cut(strings.TrimPrefix(fullMethod, "/"), "/")
+	return service, method
+}
+
+// statsMetadataCarrier adapts gRPC metadata to the OpenTelemetry propagators
+type statsMetadataCarrier metadata.MD
+
+func (c statsMetadataCarrier) Get(key string) string {
+	if values := metadata.MD(c).Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+func (c statsMetadataCarrier) Set(key, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+func (c statsMetadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	return keys
+}
@@ -0,0 +1,136 @@
+package guardian_test
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+
+	guardian "github.com/grpc-guardian/grpc-guardian"
+	"github.com/grpc-guardian/grpc-guardian/pkg/logging"
+	"github.com/grpc-guardian/grpc-guardian/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// metricSum adds up the values of a gathered metric; histograms contribute their sums
+func metricSum(t *testing.T, registry *prometheus.Registry, name string) float64 {
+	t.Helper()
+
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("Failed to gather metrics: %v", err)
+	}
+
+	var sum float64
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			sum += metric.GetCounter().GetValue() + metric.GetGauge().GetValue() + metric.GetHistogram().GetSampleSum()
+		}
+	}
+	return sum
+}
+
+func TestStatsHandler(t *testing.T) {
+	collector, err := metrics.NewPrometheusCollector()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sr := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+	core, logs := observer.New(zapcore.InfoLevel)
+
+	server := grpc.NewServer(grpc.StatsHandler(guardian.NewStatsHandler(
+		guardian.WithStatsMetrics(collector),
+		guardian.WithStatsTracer(tp.Tracer("server")),
+		guardian.WithStatsPropagator(propagation.TraceContext{}),
+		guardian.WithStatsLogger(logging.NewZap(zap.New(core))),
+	)))
+	server.RegisterService(&grpc.ServiceDesc{
+		ServiceName: "test.Echo",
+		HandlerType: (*interface{})(nil),
+		Methods: []grpc.MethodDesc{{
+			MethodName: "Echo",
+			Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, _ grpc.UnaryServerInterceptor) (interface{}, error) {
+				req := &wrapperspb.StringValue{}
+				if err := dec(req); err != nil {
+					return nil, err
+				}
+				return req, nil
+			},
+		}},
+	}, struct{}{})
+
+	lis := bufconn.Listen(1 << 20)
+	go server.Serve(lis)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithStatsHandler(guardian.NewClientStatsHandler(
+			guardian.WithStatsTracer(tp.Tracer("client")),
+			guardian.WithStatsPropagator(propagation.TraceContext{}),
+		)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := wrapperspb.String(strings.Repeat("compressible ", 1000))
+	resp := &wrapperspb.StringValue{}
+	if err := conn.Invoke(context.Background(), "/test.Echo/Echo", req, resp, grpc.UseCompressor(gzip.Name)); err != nil {
+		t.Fatal(err)
+	}
+
+	registry := collector.GetRegistry()
+	if got := metricSum(t, registry, "grpc_server_open_connections"); got != 1 {
+		t.Errorf("Expected 1 open connection, got %v", got)
+	}
+
+	// The server records the end of the RPC after the client got its response
+	conn.Close()
+	server.GracefulStop()
+
+	if got := metricSum(t, registry, "grpc_server_requests_total"); got != 1 {
+		t.Errorf("Expected 1 request, got %v", got)
+	}
+	sent, wire := metricSum(t, registry, "grpc_server_message_sent_bytes"), metricSum(t, registry, "grpc_server_message_sent_wire_bytes")
+	if sent == 0 || wire == 0 || wire >= sent {
+		t.Errorf("Expected gzip to shrink the %v byte response on the wire, got %v bytes", sent, wire)
+	}
+
+	// The server span continues the client's trace
+	spans := map[trace.SpanKind]sdktrace.ReadOnlySpan{}
+	for _, span := range sr.Ended() {
+		spans[span.SpanKind()] = span
+	}
+	client, srv := spans[trace.SpanKindClient], spans[trace.SpanKindServer]
+	if client == nil || srv == nil {
+		t.Fatalf("Expected client and server spans, got %d spans", len(sr.Ended()))
+	}
+	if srv.Parent().SpanID() != client.SpanContext().SpanID() || srv.SpanContext().TraceID() != client.SpanContext().TraceID() {
+		t.Error("Expected the server span to be a child of the client span")
+	}
+
+	if entries := logs.FilterMessage("gRPC request completed").All(); len(entries) != 1 || entries[0].ContextMap()["grpc_method"] != "/test.Echo/Echo" {
+		t.Errorf("Expected one completion log, got %v", logs.All())
+	}
+
+	if got := metricSum(t, registry, "grpc_server_open_connections"); got != 0 {
+		t.Errorf("Expected the connection to be closed, got %v", got)
+	}
+}